@@ -0,0 +1,53 @@
+package containerd
+
+import (
+	"context"
+	"time"
+
+	"github.com/bvboe/b2s-go/scanner-core/containers"
+)
+
+// RefreshTrigger implements containers.RefreshTrigger for containerd
+// It performs a full reconciliation of running containers when triggered
+type RefreshTrigger struct {
+	manager *containers.Manager
+	opts    Options
+}
+
+// NewRefreshTrigger creates a new containerd refresh trigger
+func NewRefreshTrigger(manager *containers.Manager, opts Options) *RefreshTrigger {
+	return &RefreshTrigger{
+		manager: manager,
+		opts:    opts,
+	}
+}
+
+// TriggerRefresh performs a full reconciliation of running containerd containers,
+// catching any task events missed while containerd or the agent restarted
+func (t *RefreshTrigger) TriggerRefresh() error {
+	log.Info("starting containerd container reconciliation")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	client, err := connect(ctx, t.opts)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = client.Close() }()
+
+	running, err := listRunningContainers(ctx, client, t.opts)
+	if err != nil {
+		return err
+	}
+
+	// Update the manager with the current container set
+	// This will reconcile with the database and enqueue scans for new images
+	t.manager.SetContainers(running)
+
+	log.Info("reconciliation complete", "running_containers", len(running))
+	return nil
+}
+
+// Ensure RefreshTrigger implements containers.RefreshTrigger
+var _ containers.RefreshTrigger = (*RefreshTrigger)(nil)
@@ -0,0 +1,91 @@
+package containerd
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/bvboe/b2s-go/bjorn2scan-agent/syft"
+	"github.com/bvboe/b2s-go/scanner-core/containers"
+
+	containerd "github.com/containerd/containerd/v2/client"
+	"github.com/containerd/containerd/v2/core/images"
+	"github.com/containerd/containerd/v2/core/images/archive"
+	"github.com/containerd/containerd/v2/pkg/namespaces"
+	"github.com/containerd/platforms"
+)
+
+// matchImage returns the name of the image with the given target digest,
+// or with the given reference when no digest matches
+func matchImage(imgs []images.Image, image containers.ImageID) string {
+	for _, img := range imgs {
+		if image.Digest != "" && img.Target.Digest.String() == image.Digest {
+			return img.Name
+		}
+	}
+	for _, img := range imgs {
+		if image.Reference != "" && img.Name == image.Reference {
+			return img.Name
+		}
+	}
+	return ""
+}
+
+// findImage searches the watched namespaces for an image and returns a context
+// bound to the namespace it was found in along with its name
+func findImage(ctx context.Context, client *containerd.Client, opts Options, image containers.ImageID) (context.Context, string, error) {
+	watched, err := watchedNamespaces(ctx, client, opts)
+	if err != nil {
+		return nil, "", err
+	}
+	for _, ns := range watched {
+		nsCtx := namespaces.WithNamespace(ctx, ns)
+		imgs, err := client.ImageService().List(nsCtx)
+		if err != nil {
+			log.Debug("failed to list images in namespace", "namespace", ns, "error", err)
+			continue
+		}
+		if name := matchImage(imgs, image); name != "" {
+			return nsCtx, name, nil
+		}
+	}
+	return nil, "", fmt.Errorf("image %s (%s) not found in containerd namespaces %v", image.Reference, image.Digest, watched)
+}
+
+// GenerateSBOM generates an SBOM for a containerd image. The image is exported
+// from the content store to a temporary OCI archive for the host's platform,
+// so nothing is pulled from a registry.
+func GenerateSBOM(ctx context.Context, image containers.ImageID, opts Options) ([]byte, error) {
+	client, err := connect(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = client.Close() }()
+
+	nsCtx, name, err := findImage(ctx, client, opts, image)
+	if err != nil {
+		return nil, err
+	}
+
+	archiveFile, err := os.CreateTemp("", "bjorn2scan-image-*.tar")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create image archive: %w", err)
+	}
+	defer func() {
+		if removeErr := os.Remove(archiveFile.Name()); removeErr != nil {
+			log.Warn("failed to remove image archive", "path", archiveFile.Name(), "error", removeErr)
+		}
+	}()
+
+	err = client.Export(nsCtx, archiveFile,
+		archive.WithImage(client.ImageService(), name),
+		archive.WithPlatform(platforms.DefaultStrict()))
+	if closeErr := archiveFile.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to export image %s: %w", name, err)
+	}
+
+	return syft.GenerateArchiveSBOM(ctx, archiveFile.Name(), name)
+}
@@ -0,0 +1,310 @@
+package containerd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/bvboe/b2s-go/scanner-core/containers"
+	"github.com/bvboe/b2s-go/scanner-core/logging"
+
+	apievents "github.com/containerd/containerd/api/events"
+	containerd "github.com/containerd/containerd/v2/client"
+	"github.com/containerd/containerd/v2/pkg/namespaces"
+	"github.com/containerd/typeurl/v2"
+)
+
+var log = logging.For(logging.ComponentContainers)
+
+const (
+	// mobyNamespace holds Docker's containers, which the Docker watcher reports
+	mobyNamespace = "moby"
+	// nerdctlNameLabel is the label nerdctl stores the container name in
+	nerdctlNameLabel = "nerdctl/name"
+)
+
+// SocketPaths are the containerd socket locations searched, in order, when no
+// socket is configured
+var SocketPaths = []string{
+	"/run/containerd/containerd.sock",
+	"/run/k3s/containerd/containerd.sock",
+	"/var/snap/microk8s/common/run/containerd.sock",
+}
+
+// Options configures how the agent connects to containerd
+type Options struct {
+	Socket     string   // socket to use instead of searching SocketPaths
+	Namespaces []string // namespaces to watch; empty watches all but moby
+}
+
+// watches reports whether containers in the namespace are reported
+func (o Options) watches(namespace string) bool {
+	if len(o.Namespaces) > 0 {
+		return slices.Contains(o.Namespaces, namespace)
+	}
+	return namespace != mobyNamespace
+}
+
+// connect returns a client for the first containerd socket that answers
+func connect(ctx context.Context, opts Options) (*containerd.Client, error) {
+	sockets := SocketPaths
+	if opts.Socket != "" {
+		sockets = []string{opts.Socket}
+	}
+
+	var errs []error
+	for _, socket := range sockets {
+		if _, err := os.Stat(socket); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", socket, err))
+			continue
+		}
+		client, err := containerd.New(socket)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", socket, err))
+			continue
+		}
+		versionCtx, cancel := context.WithTimeout(ctx, 2*time.Second)
+		_, err = client.Version(versionCtx)
+		cancel()
+		if err != nil {
+			_ = client.Close()
+			errs = append(errs, fmt.Errorf("%s: %w", socket, err))
+			continue
+		}
+		log.Debug("connected to containerd", "socket", socket)
+		return client, nil
+	}
+	return nil, fmt.Errorf("containerd not reachable: %w", errors.Join(errs...))
+}
+
+// IsContainerdAvailable checks if a containerd daemon is accessible
+func IsContainerdAvailable(opts Options) bool {
+	client, err := connect(context.Background(), opts)
+	if err != nil {
+		return false
+	}
+	_ = client.Close()
+	return true
+}
+
+// watchedNamespaces lists the containerd namespaces to report containers from
+func watchedNamespaces(ctx context.Context, client *containerd.Client, opts Options) ([]string, error) {
+	if len(opts.Namespaces) > 0 {
+		return opts.Namespaces, nil
+	}
+	all, err := client.NamespaceService().List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list namespaces: %w", err)
+	}
+	var watched []string
+	for _, ns := range all {
+		if opts.watches(ns) {
+			watched = append(watched, ns)
+		}
+	}
+	sort.Strings(watched)
+	return watched, nil
+}
+
+// shortID returns the abbreviated container ID shown by nerdctl and docker
+func shortID(id string) string {
+	if len(id) > 12 {
+		return id[:12]
+	}
+	return id
+}
+
+// containerName returns the nerdctl name of a container, falling back to its short ID
+func containerName(id string, labels map[string]string) string {
+	if name := labels[nerdctlNameLabel]; name != "" {
+		return name
+	}
+	return shortID(id)
+}
+
+// containerID identifies a containerd container the same way the Docker watcher does
+func containerID(name string) containers.ContainerID {
+	hostname, _ := os.Hostname()
+	if hostname == "" {
+		hostname = "unknown"
+	}
+	return containers.ContainerID{
+		Namespace: hostname,
+		Pod:       "host", // Indicate this is a host-level container
+		Name:      name,
+	}
+}
+
+// extractContainer creates a Container from a containerd container. The image
+// digest is the digest of the image's target (manifest or index).
+func extractContainer(ctx context.Context, client *containerd.Client, namespace, id string) (containers.Container, error) {
+	nsCtx := namespaces.WithNamespace(ctx, namespace)
+	ctr, err := client.LoadContainer(nsCtx, id)
+	if err != nil {
+		return containers.Container{}, err
+	}
+	info, err := ctr.Info(nsCtx, containerd.WithoutRefreshedMetadata)
+	if err != nil {
+		return containers.Container{}, err
+	}
+	img, err := client.GetImage(nsCtx, info.Image)
+	if err != nil {
+		return containers.Container{}, fmt.Errorf("failed to get image %s: %w", info.Image, err)
+	}
+
+	c := containers.Container{
+		ID: containerID(containerName(id, info.Labels)),
+		Image: containers.ImageID{
+			Reference: info.Image,
+			Digest:    img.Target().Digest.String(),
+		},
+		ContainerRuntime: "containerd",
+	}
+	c.NodeName = c.ID.Namespace // Use hostname as node name for agent deployments
+	return c, nil
+}
+
+// isRunning reports whether the container's task is running
+func isRunning(ctx context.Context, ctr containerd.Container) bool {
+	task, err := ctr.Task(ctx, nil)
+	if err != nil {
+		return false
+	}
+	status, err := task.Status(ctx)
+	return err == nil && status.Status == containerd.Running
+}
+
+// listRunningContainers returns the running containers of the watched namespaces
+func listRunningContainers(ctx context.Context, client *containerd.Client, opts Options) ([]containers.Container, error) {
+	watched, err := watchedNamespaces(ctx, client, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	var running []containers.Container
+	for _, ns := range watched {
+		nsCtx := namespaces.WithNamespace(ctx, ns)
+		ctrs, err := client.Containers(nsCtx)
+		if err != nil {
+			log.Warn("failed to list containers", "namespace", ns, "error", err)
+			continue
+		}
+		for _, ctr := range ctrs {
+			if !isRunning(nsCtx, ctr) {
+				continue
+			}
+			c, err := extractContainer(ctx, client, ns, ctr.ID())
+			if err != nil {
+				log.Warn("failed to extract container", "namespace", ns, "container_id", shortID(ctr.ID()), "error", err)
+				continue
+			}
+			running = append(running, c)
+		}
+	}
+	return running, nil
+}
+
+// WatchContainers watches for containerd task events and updates the container manager
+func WatchContainers(ctx context.Context, manager *containers.Manager, opts Options) error {
+	client, err := connect(ctx, opts)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = client.Close() }()
+
+	log.Info("containerd watcher connected to containerd")
+
+	// Perform initial sync of running containers
+	if err := syncInitialContainers(ctx, client, manager, opts); err != nil {
+		log.Warn("initial container sync failed", "error", err)
+	}
+
+	// Start watching for events
+	for {
+		select {
+		case <-ctx.Done():
+			log.Info("containerd watcher shutting down")
+			return nil
+		default:
+			eventsChan, errChan := client.Subscribe(ctx, `topic=="/tasks/start"`, `topic=="/tasks/exit"`)
+
+			log.Info("containerd watcher started")
+
+		eventLoop:
+			for {
+				select {
+				case <-ctx.Done():
+					log.Info("containerd watcher shutting down")
+					return nil
+
+				case err := <-errChan:
+					if err != nil && !errors.Is(err, context.Canceled) {
+						log.Error("containerd events error", "error", err)
+					}
+					break eventLoop
+
+				case envelope := <-eventsChan:
+					if envelope == nil || !opts.watches(envelope.Namespace) {
+						continue
+					}
+					event, err := typeurl.UnmarshalAny(envelope.Event)
+					if err != nil {
+						log.Warn("failed to decode containerd event", "topic", envelope.Topic, "error", err)
+						continue
+					}
+
+					switch e := event.(type) {
+					case *apievents.TaskStart:
+						// Container started
+						c, err := extractContainer(ctx, client, envelope.Namespace, e.ContainerID)
+						if err != nil {
+							log.Error("failed to extract container", "container_id", shortID(e.ContainerID), "error", err)
+							continue
+						}
+						manager.AddContainer(c)
+
+					case *apievents.TaskExit:
+						// Exits of exec'd processes don't stop the container
+						if e.ID != e.ContainerID {
+							continue
+						}
+						manager.RemoveContainer(exitedContainerID(ctx, client, envelope.Namespace, e.ContainerID))
+					}
+				}
+			}
+
+			log.Info("containerd watcher connection closed, reconnecting")
+			time.Sleep(1 * time.Second)
+		}
+	}
+}
+
+// exitedContainerID identifies a stopped container. Containers run with
+// --rm may already be gone, in which case only the short ID is known.
+func exitedContainerID(ctx context.Context, client *containerd.Client, namespace, id string) containers.ContainerID {
+	nsCtx := namespaces.WithNamespace(ctx, namespace)
+	var labels map[string]string
+	if ctr, err := client.LoadContainer(nsCtx, id); err == nil {
+		labels, _ = ctr.Labels(nsCtx)
+	}
+	return containerID(containerName(id, labels))
+}
+
+// syncInitialContainers performs an initial sync of all running containers
+func syncInitialContainers(ctx context.Context, client *containerd.Client, manager *containers.Manager, opts Options) error {
+	log.Info("containerd watcher performing initial container sync")
+
+	running, err := listRunningContainers(ctx, client, opts)
+	if err != nil {
+		return err
+	}
+
+	manager.SetContainers(running)
+	log.Info("containerd watcher initial sync complete", "container_count", manager.GetContainerCount())
+
+	return nil
+}
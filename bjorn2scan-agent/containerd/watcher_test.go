@@ -0,0 +1,74 @@
+package containerd
+
+import (
+	"testing"
+
+	"github.com/bvboe/b2s-go/scanner-core/containers"
+
+	"github.com/containerd/containerd/v2/core/images"
+	"github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+)
+
+func TestOptionsWatches(t *testing.T) {
+	all := Options{}
+	if !all.watches("default") || !all.watches("k8s.io") || all.watches("moby") {
+		t.Error("default options should watch every namespace except moby")
+	}
+
+	configured := Options{Namespaces: []string{"default", "moby"}}
+	if !configured.watches("moby") || configured.watches("k8s.io") {
+		t.Error("configured namespaces should be watched exactly")
+	}
+}
+
+func TestContainerName(t *testing.T) {
+	id := "0123456789abcdef0123456789abcdef"
+	if name := containerName(id, map[string]string{nerdctlNameLabel: "web"}); name != "web" {
+		t.Errorf("containerName() = %q, want nerdctl name", name)
+	}
+	if name := containerName(id, nil); name != "0123456789ab" {
+		t.Errorf("containerName() = %q, want short ID", name)
+	}
+	if name := containerName("abc", nil); name != "abc" {
+		t.Errorf("containerName() = %q, want the full short ID", name)
+	}
+}
+
+func TestMatchImage(t *testing.T) {
+	imgs := []images.Image{
+		{Name: "docker.io/library/nginx:1.27", Target: ocispec.Descriptor{Digest: digest.Digest("sha256:nginx")}},
+		{Name: "docker.io/library/redis:7", Target: ocispec.Descriptor{Digest: digest.Digest("sha256:redis")}},
+	}
+
+	tests := []struct {
+		name  string
+		image containers.ImageID
+		want  string
+	}{
+		{"by digest", containers.ImageID{Reference: "nginx", Digest: "sha256:nginx"}, "docker.io/library/nginx:1.27"},
+		{"by reference", containers.ImageID{Reference: "docker.io/library/redis:7", Digest: "sha256:old"}, "docker.io/library/redis:7"},
+		{"digest wins", containers.ImageID{Reference: "docker.io/library/redis:7", Digest: "sha256:nginx"}, "docker.io/library/nginx:1.27"},
+		{"not found", containers.ImageID{Reference: "alpine", Digest: "sha256:alpine"}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := matchImage(imgs, tt.image); got != tt.want {
+				t.Errorf("matchImage() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestTriggerRefresh_NoContainerd tests TriggerRefresh behavior when containerd is not reachable
+func TestTriggerRefresh_NoContainerd(t *testing.T) {
+	opts := Options{Socket: t.TempDir() + "/containerd.sock"}
+	if IsContainerdAvailable(opts) {
+		t.Fatal("IsContainerdAvailable() = true for a missing socket")
+	}
+
+	trigger := NewRefreshTrigger(containers.NewManager(), opts)
+	if err := trigger.TriggerRefresh(); err == nil {
+		t.Error("Expected error when containerd is not available")
+	}
+}
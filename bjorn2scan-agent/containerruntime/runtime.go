@@ -0,0 +1,145 @@
+// Package containerruntime detects the container runtime of the host and
+// gives the agent one way to watch, reconcile and scan its containers.
+package containerruntime
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/bvboe/b2s-go/bjorn2scan-agent/containerd"
+	"github.com/bvboe/b2s-go/bjorn2scan-agent/docker"
+	"github.com/bvboe/b2s-go/bjorn2scan-agent/podman"
+	"github.com/bvboe/b2s-go/bjorn2scan-agent/syft"
+	"github.com/bvboe/b2s-go/scanner-core/containers"
+)
+
+// Values of the container_runtime setting
+const (
+	Auto       = "auto"
+	Docker     = "docker"
+	Containerd = "containerd"
+	Podman     = "podman"
+	None       = "none"
+)
+
+// Options configures runtime detection
+type Options struct {
+	// Runtime selects the runtime (auto, docker, podman, containerd or none)
+	Runtime string
+	// Containerd configures the containerd connection
+	Containerd containerd.Options
+	// Podman configures the Podman connection
+	Podman podman.Options
+}
+
+// Runtime is a container runtime the agent discovers and scans containers through
+type Runtime interface {
+	// Name returns the runtime name, as stored in Container.ContainerRuntime
+	Name() string
+	// WatchContainers keeps the manager in sync with the running containers until ctx is done
+	WatchContainers(ctx context.Context, manager *containers.Manager) error
+	// NewRefreshTrigger returns a trigger that reconciles the manager with the running containers
+	NewRefreshTrigger(manager *containers.Manager) containers.RefreshTrigger
+	// GenerateSBOM generates an SBOM for a locally stored image
+	GenerateSBOM(ctx context.Context, image containers.ImageID) ([]byte, error)
+}
+
+// probes are the availability checks Detect uses; replaced in tests
+var probes = struct {
+	docker     func() bool
+	podman     func(podman.Options) bool
+	containerd func(containerd.Options) bool
+}{docker.IsDockerAvailable, podman.IsPodmanAvailable, containerd.IsContainerdAvailable}
+
+// Detect returns the configured runtime, or with auto the first one available:
+// Docker first, then Podman, then containerd. Returns an error if the runtime is unknown,
+// not available, or disabled with none.
+func Detect(opts Options) (Runtime, error) {
+	switch opts.Runtime {
+	case Auto, "":
+		if probes.docker() {
+			return dockerRuntime{}, nil
+		}
+		if probes.podman(opts.Podman) {
+			return podmanRuntime{opts: opts.Podman}, nil
+		}
+		if probes.containerd(opts.Containerd) {
+			return containerdRuntime{opts: opts.Containerd}, nil
+		}
+		return nil, fmt.Errorf("no container runtime available (tried Docker, Podman and containerd)")
+	case Docker:
+		if !probes.docker() {
+			return nil, fmt.Errorf("docker not available or not accessible")
+		}
+		return dockerRuntime{}, nil
+	case Podman:
+		if !probes.podman(opts.Podman) {
+			return nil, fmt.Errorf("podman not available or not accessible (is podman.socket enabled?)")
+		}
+		return podmanRuntime{opts: opts.Podman}, nil
+	case Containerd:
+		if !probes.containerd(opts.Containerd) {
+			return nil, fmt.Errorf("containerd not available or not accessible")
+		}
+		return containerdRuntime{opts: opts.Containerd}, nil
+	case None:
+		return nil, fmt.Errorf("container runtime disabled")
+	default:
+		return nil, fmt.Errorf("unknown container runtime %q (expected %s, %s, %s, %s or %s)", opts.Runtime, Auto, Docker, Podman, Containerd, None)
+	}
+}
+
+// dockerRuntime scans containers of the local Docker daemon
+type dockerRuntime struct{}
+
+func (dockerRuntime) Name() string { return Docker }
+
+func (dockerRuntime) WatchContainers(ctx context.Context, manager *containers.Manager) error {
+	return docker.WatchContainers(ctx, manager)
+}
+
+func (dockerRuntime) NewRefreshTrigger(manager *containers.Manager) containers.RefreshTrigger {
+	return docker.NewRefreshTrigger(manager)
+}
+
+func (dockerRuntime) GenerateSBOM(ctx context.Context, image containers.ImageID) ([]byte, error) {
+	return syft.GenerateSBOM(ctx, image)
+}
+
+// podmanRuntime scans containers of a Podman host, rootful or rootless
+type podmanRuntime struct {
+	opts podman.Options
+}
+
+func (podmanRuntime) Name() string { return Podman }
+
+func (r podmanRuntime) WatchContainers(ctx context.Context, manager *containers.Manager) error {
+	return podman.WatchContainers(ctx, manager, r.opts)
+}
+
+func (r podmanRuntime) NewRefreshTrigger(manager *containers.Manager) containers.RefreshTrigger {
+	return podman.NewRefreshTrigger(manager, r.opts)
+}
+
+func (r podmanRuntime) GenerateSBOM(ctx context.Context, image containers.ImageID) ([]byte, error) {
+	return podman.GenerateSBOM(ctx, image, r.opts)
+}
+
+// containerdRuntime scans containers of a containerd daemon, e.g. run by nerdctl
+type containerdRuntime struct {
+	opts containerd.Options
+}
+
+func (containerdRuntime) Name() string { return Containerd }
+
+func (r containerdRuntime) WatchContainers(ctx context.Context, manager *containers.Manager) error {
+	return containerd.WatchContainers(ctx, manager, r.opts)
+}
+
+func (r containerdRuntime) NewRefreshTrigger(manager *containers.Manager) containers.RefreshTrigger {
+	return containerd.NewRefreshTrigger(manager, r.opts)
+}
+
+func (r containerdRuntime) GenerateSBOM(ctx context.Context, image containers.ImageID) ([]byte, error) {
+	return containerd.GenerateSBOM(ctx, image, r.opts)
+}
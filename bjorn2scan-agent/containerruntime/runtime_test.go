@@ -0,0 +1,60 @@
+package containerruntime
+
+import (
+	"testing"
+
+	"github.com/bvboe/b2s-go/bjorn2scan-agent/containerd"
+	"github.com/bvboe/b2s-go/bjorn2scan-agent/podman"
+)
+
+// fakeProbes makes Detect see the given runtimes as available
+func fakeProbes(t *testing.T, dockerUp, podmanUp, containerdUp bool) {
+	original := probes
+	t.Cleanup(func() { probes = original })
+	probes.docker = func() bool { return dockerUp }
+	probes.podman = func(podman.Options) bool { return podmanUp }
+	probes.containerd = func(containerd.Options) bool { return containerdUp }
+}
+
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		name         string
+		runtime      string
+		dockerUp     bool
+		podmanUp     bool
+		containerdUp bool
+		want         string // empty when Detect should fail
+	}{
+		{"auto prefers docker", Auto, true, true, true, Docker},
+		{"auto prefers podman over containerd", Auto, false, true, true, Podman},
+		{"auto falls back to containerd", Auto, false, false, true, Containerd},
+		{"empty means auto", "", false, false, true, Containerd},
+		{"auto without runtime", Auto, false, false, false, ""},
+		{"docker forced", Docker, true, true, true, Docker},
+		{"docker unavailable", Docker, false, true, true, ""},
+		{"podman forced", Podman, true, true, true, Podman},
+		{"podman unavailable", Podman, true, false, true, ""},
+		{"containerd forced", Containerd, true, true, true, Containerd},
+		{"containerd unavailable", Containerd, true, true, false, ""},
+		{"none", None, true, true, true, ""},
+		{"unknown", "cri-o", true, true, true, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fakeProbes(t, tt.dockerUp, tt.podmanUp, tt.containerdUp)
+			rt, err := Detect(Options{Runtime: tt.runtime})
+			if tt.want == "" {
+				if err == nil {
+					t.Errorf("Detect() = %s, want error", rt.Name())
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Detect() error = %v", err)
+			}
+			if rt.Name() != tt.want {
+				t.Errorf("Detect() = %s, want %s", rt.Name(), tt.want)
+			}
+		})
+	}
+}
@@ -17,22 +17,41 @@ import (
 	"syscall"
 	"time"
 
-	"github.com/bvboe/b2s-go/bjorn2scan-agent/docker"
+	"github.com/bvboe/b2s-go/bjorn2scan-agent/containerd"
+	"github.com/bvboe/b2s-go/bjorn2scan-agent/containerruntime"
+	"github.com/bvboe/b2s-go/bjorn2scan-agent/podman"
 	"github.com/bvboe/b2s-go/bjorn2scan-agent/syft"
 	"github.com/bvboe/b2s-go/bjorn2scan-agent/updater"
+	"github.com/bvboe/b2s-go/scanner-core/adhoc"
+	"github.com/bvboe/b2s-go/scanner-core/cmdb"
+	"github.com/bvboe/b2s-go/scanner-core/columns"
 	"github.com/bvboe/b2s-go/scanner-core/config"
 	"github.com/bvboe/b2s-go/scanner-core/containers"
 	"github.com/bvboe/b2s-go/scanner-core/database"
 	"github.com/bvboe/b2s-go/scanner-core/debug"
 	"github.com/bvboe/b2s-go/scanner-core/deployment"
+	"github.com/bvboe/b2s-go/scanner-core/diskusage"
+	"github.com/bvboe/b2s-go/scanner-core/eol"
+	"github.com/bvboe/b2s-go/scanner-core/fixhints"
 	"github.com/bvboe/b2s-go/scanner-core/grype"
 	"github.com/bvboe/b2s-go/scanner-core/handlers"
 	"github.com/bvboe/b2s-go/scanner-core/jobs"
+	"github.com/bvboe/b2s-go/scanner-core/labels"
 	"github.com/bvboe/b2s-go/scanner-core/logging"
 	"github.com/bvboe/b2s-go/scanner-core/metrics"
 	"github.com/bvboe/b2s-go/scanner-core/nodes"
+	"github.com/bvboe/b2s-go/scanner-core/notify"
+	"github.com/bvboe/b2s-go/scanner-core/policy"
+	"github.com/bvboe/b2s-go/scanner-core/provenance"
+	"github.com/bvboe/b2s-go/scanner-core/registrycrawl"
+	"github.com/bvboe/b2s-go/scanner-core/resultcache"
+	"github.com/bvboe/b2s-go/scanner-core/routes"
+	"github.com/bvboe/b2s-go/scanner-core/sbomformat"
 	"github.com/bvboe/b2s-go/scanner-core/scanning"
 	"github.com/bvboe/b2s-go/scanner-core/scheduler"
+	"github.com/bvboe/b2s-go/scanner-core/severity"
+	"github.com/bvboe/b2s-go/scanner-core/tlsreload"
+	"github.com/bvboe/b2s-go/scanner-core/vex"
 	"github.com/bvboe/b2s-go/scanner-core/vulndb"
 )
 
@@ -48,20 +67,22 @@ type InfoResponse struct {
 }
 
 type AgentInfo struct {
-	port               string
-	webUIEnabled       bool
+	port                string
+	webUIEnabled        bool
 	hostScanningEnabled bool
 	// Cached values computed at startup
 	deploymentIP string
 	consoleURL   string
 	// Grype database status getter (returns RFC3339 timestamp or empty string)
 	grypeDBStatusGetter func() string
+	// Loaded configuration reported by /api/config
+	config *config.Config
 }
 
 func NewAgentInfo(port string, webUIEnabled bool, hostScanningEnabled bool) *AgentInfo {
 	info := &AgentInfo{
-		port:               port,
-		webUIEnabled:       webUIEnabled,
+		port:                port,
+		webUIEnabled:        webUIEnabled,
 		hostScanningEnabled: hostScanningEnabled,
 	}
 
@@ -167,10 +188,20 @@ func (a *AgentInfo) GetGrypeDBBuilt() string {
 	return a.grypeDBStatusGetter()
 }
 
+// SetEffectiveConfig sets the loaded configuration reported by /api/config.
+func (a *AgentInfo) SetEffectiveConfig(cfg *config.Config) {
+	a.config = cfg
+}
+
+// GetEffectiveConfig returns the loaded configuration, or nil if not set.
+func (a *AgentInfo) GetEffectiveConfig() *config.Config {
+	return a.config
+}
+
 // registerUpdaterHandlers registers HTTP handlers for the updater
-func registerUpdaterHandlers(mux *http.ServeMux, u *updater.Updater) {
+func registerUpdaterHandlers(reg *routes.Registry, u *updater.Updater) {
 	// GET /api/update/status - Get current update status
-	mux.HandleFunc("/api/update/status", func(w http.ResponseWriter, r *http.Request) {
+	reg.Handle(routes.Route{Pattern: "/api/update/status", Methods: routes.GET, Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodGet {
 			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 			return
@@ -190,10 +221,10 @@ func registerUpdaterHandlers(mux *http.ServeMux, u *updater.Updater) {
 		if err := json.NewEncoder(w).Encode(response); err != nil {
 			http.Error(w, "Failed to encode response", http.StatusInternalServerError)
 		}
-	})
+	})})
 
 	// POST /api/update/trigger - Manually trigger an update check
-	mux.HandleFunc("/api/update/trigger", func(w http.ResponseWriter, r *http.Request) {
+	reg.Handle(routes.Route{Pattern: "/api/update/trigger", Methods: routes.POST, Role: routes.RoleAdmin, Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodPost {
 			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 			return
@@ -205,10 +236,10 @@ func registerUpdaterHandlers(mux *http.ServeMux, u *updater.Updater) {
 		if err := json.NewEncoder(w).Encode(map[string]string{"message": "Update check triggered"}); err != nil {
 			http.Error(w, "Failed to encode response", http.StatusInternalServerError)
 		}
-	})
+	})})
 
 	// POST /api/update/pause - Pause automatic updates
-	mux.HandleFunc("/api/update/pause", func(w http.ResponseWriter, r *http.Request) {
+	reg.Handle(routes.Route{Pattern: "/api/update/pause", Methods: routes.POST, Role: routes.RoleAdmin, Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodPost {
 			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 			return
@@ -220,10 +251,10 @@ func registerUpdaterHandlers(mux *http.ServeMux, u *updater.Updater) {
 		if err := json.NewEncoder(w).Encode(map[string]string{"message": "Auto-updates paused"}); err != nil {
 			http.Error(w, "Failed to encode response", http.StatusInternalServerError)
 		}
-	})
+	})})
 
 	// POST /api/update/resume - Resume automatic updates
-	mux.HandleFunc("/api/update/resume", func(w http.ResponseWriter, r *http.Request) {
+	reg.Handle(routes.Route{Pattern: "/api/update/resume", Methods: routes.POST, Role: routes.RoleAdmin, Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodPost {
 			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 			return
@@ -235,7 +266,7 @@ func registerUpdaterHandlers(mux *http.ServeMux, u *updater.Updater) {
 		if err := json.NewEncoder(w).Encode(map[string]string{"message": "Auto-updates resumed"}); err != nil {
 			http.Error(w, "Failed to encode response", http.StatusInternalServerError)
 		}
-	})
+	})})
 }
 
 // setupLogging configures logging to write to both stderr (journald) and a log file.
@@ -264,6 +295,11 @@ func setupLogging() *os.File {
 }
 
 func main() {
+	// "bjorn2scan-agent policy test <file>" checks a policy bundle and exits
+	if len(os.Args) > 1 && os.Args[1] == "policy" {
+		os.Exit(policy.RunCommand(os.Args[2:], os.Stdout))
+	}
+
 	// Setup logging to both stderr (journald) and file; logging.Init is called inside
 	if logFile := setupLogging(); logFile != nil {
 		defer func() { _ = logFile.Close() }()
@@ -280,9 +316,13 @@ func main() {
 	dbPath := cfg.DBPath
 
 	// Initialize debug configuration
-	debugConfig := debug.NewDebugConfig(cfg.DebugEnabled)
+	debugConfig, err := debug.NewDebugConfigFromSettings(cfg.DebugEnabled, cfg.DebugCategories)
+	if err != nil {
+		logging.For(logging.ComponentHTTP).Error("invalid debug configuration", "error", err)
+		os.Exit(1)
+	}
 	if debugConfig.IsEnabled() {
-		logging.For(logging.ComponentHTTP).Info("debug mode ENABLED - /debug endpoints available")
+		logging.For(logging.ComponentHTTP).Info("debug mode ENABLED - /api/debug endpoints available", "categories", debugConfig.Categories())
 	}
 
 	logging.For(logging.ComponentHTTP).Info("bjorn2scan-agent starting", "version", version)
@@ -308,13 +348,115 @@ func main() {
 	}
 	defer func() { _ = database.Close(db) }()
 
+	// Binary version history for /api/status/compatibility (downgrade targets)
+	if err := db.RecordBinaryVersion(version); err != nil {
+		logging.For(logging.ComponentDatabase).Warn("failed to record binary version", "error", err)
+	}
+
+	// Merge severities (e.g. Negligible into Low) before any results are read or stored
+	severityMapping, err := severity.ParseMapping(cfg.SeverityMapping)
+	if err != nil {
+		logging.For(logging.ComponentDatabase).Error("invalid severity mapping", "error", err)
+		os.Exit(1)
+	}
+	if err := db.SetSeverityMapping(severityMapping); err != nil {
+		logging.For(logging.ComponentDatabase).Error("failed to apply severity mapping", "error", err)
+		os.Exit(1)
+	}
+	if severityMapping != nil {
+		logging.For(logging.ComponentDatabase).Info("severity mapping configured", "mapping", severityMapping.String(), "levels", severityMapping.Levels())
+	}
+	db.SetSlowQueryThreshold(cfg.SlowQueryThreshold)
+
+	// OpenVEX documents from the configured path; not_affected statements
+	// suppress findings (uploads at /api/vex are kept in the database)
+	vexFiles := map[string][]byte{}
+	if cfg.VEXPath != "" {
+		if vexFiles, err = vex.ReadFiles(cfg.VEXPath); err != nil {
+			logging.For(logging.ComponentDatabase).Error("failed to load VEX documents", "error", err)
+			os.Exit(1)
+		}
+	}
+	if images, err := db.SyncVEXFiles(vexFiles); err != nil {
+		logging.For(logging.ComponentDatabase).Error("failed to apply VEX documents", "error", err)
+		os.Exit(1)
+	} else if len(vexFiles) > 0 {
+		logging.For(logging.ComponentDatabase).Info("VEX documents loaded", "path", cfg.VEXPath, "documents", len(vexFiles), "images_reevaluated", images)
+	}
+
+	// Fault injection is for resilience testing only
+	if cfg.FaultDBWriteErrorRate > 0 {
+		if debugConfig.CategoryEnabled(debug.CategoryFaults) {
+			if err := db.SetWriteFaultRate(cfg.FaultDBWriteErrorRate); err != nil {
+				logging.For(logging.ComponentDatabase).Error("invalid fault injection rate", "error", err)
+			}
+		} else {
+			logging.For(logging.ComponentDatabase).Warn("FAULT_DB_WRITE_ERROR_RATE ignored: the faults debug category is not enabled")
+		}
+	}
+
+	// Static labels identify this host in metrics, exports and reports
+	staticLabels, err := labels.Parse(cfg.StaticLabels)
+	if err != nil {
+		logging.For(logging.ComponentHTTP).Error("invalid static labels", "error", err)
+		os.Exit(1)
+	}
+	if staticLabels != nil {
+		logging.For(logging.ComponentHTTP).Info("static labels configured", "labels", staticLabels.String())
+	}
+
+	// Computed columns on the image and container lists
+	computedColumns, err := columns.Parse(cfg.ComputedColumns)
+	if err != nil {
+		logging.For(logging.ComponentHTTP).Error("invalid computed columns", "error", err)
+		os.Exit(1)
+	}
+	for _, col := range computedColumns {
+		logging.For(logging.ComponentHTTP).Info("computed column configured", "name", col.Name, "expression", col.Expression)
+	}
+
+	// Redaction of file paths in downloaded and exported SBOMs
+	if cfg.SBOMPathRedaction, err = sbomformat.NormalizeRedaction(cfg.SBOMPathRedaction); err != nil {
+		logging.For(logging.ComponentHTTP).Error("invalid SBOM path redaction", "error", err)
+		os.Exit(1)
+	}
+	if cfg.SBOMPathRedaction != "" {
+		logging.For(logging.ComponentHTTP).Info("file paths are redacted in downloaded and exported SBOMs", "mode", cfg.SBOMPathRedaction)
+	}
+
+	// API authentication (static tokens and/or OIDC; off when neither is set)
+	authenticator, err := handlers.NewAuthenticatorFromConfig(cfg)
+	if err != nil {
+		logging.For(logging.ComponentHTTP).Error("invalid authentication settings", "error", err)
+		os.Exit(1)
+	}
+	if authenticator.Enabled() {
+		logging.For(logging.ComponentHTTP).Info("API authentication enabled")
+	}
+
 	// Connect database to manager
 	manager.SetDatabase(db)
 
+	// Detect the container runtime (Docker, then Podman, then containerd) to watch and scan
+	hostRuntime, err := containerruntime.Detect(containerruntime.Options{
+		Runtime: cfg.ContainerRuntime,
+		Containerd: containerd.Options{
+			Socket:     cfg.ContainerdSocket,
+			Namespaces: cfg.ContainerdNamespaces,
+		},
+		Podman: podman.Options{Socket: cfg.PodmanSocket},
+	})
+	if err != nil {
+		logging.For(logging.ComponentContainers).Info("container watching disabled", "reason", err)
+	}
+
 	// Create SBOM retriever using syft library
-	// For the agent, we scan local Docker images directly
+	// For the agent, we scan images of the local container runtime directly
 	sbomRetriever := func(ctx context.Context, image containers.ImageID, nodeName string, runtime string) ([]byte, error) {
-		// nodeName and runtime are ignored for local agent - we scan from local Docker daemon
+		// nodeName and runtime are ignored for local agent - we scan from the detected runtime
+		if hostRuntime != nil {
+			return hostRuntime.GenerateSBOM(ctx, image)
+		}
 		return syft.GenerateSBOM(ctx, image)
 	}
 
@@ -346,8 +488,13 @@ func main() {
 	// Initialize scan queue with SBOM and vulnerability scanning
 	// Using default queue config (unbounded queue with single worker)
 	queueConfig := scanning.QueueConfig{
-		MaxDepth:     0, // Unbounded
-		FullBehavior: scanning.QueueFullDrop,
+		MaxDepth:        0, // Unbounded
+		FullBehavior:    scanning.QueueFullDrop,
+		MaxAttempts:     cfg.ScanMaxAttempts,
+		RetryBackoff:    cfg.ScanRetryBackoff,
+		RetryMaxBackoff: cfg.ScanRetryMaxBackoff,
+
+		PriorityNamespaces: cfg.ScanPriorityNamespaces,
 	}
 	scanQueue := scanning.NewJobQueue(db, sbomRetriever, grypeCfg, queueConfig)
 	defer scanQueue.Shutdown()
@@ -355,9 +502,97 @@ func main() {
 	// Connect scan queue to DB readiness state so it waits for grype DB before processing vuln scans
 	scanQueue.SetDBReadinessChecker(dbReadinessState)
 
+	// Connect the shared result cache (if configured) so identical digests scanned elsewhere are reused
+	resultCache, err := resultcache.New(context.Background(), resultcache.Config{
+		Backend:        cfg.ResultCacheBackend,
+		URL:            cfg.ResultCacheURL,
+		Token:          cfg.ResultCacheToken,
+		S3Bucket:       cfg.ResultCacheS3Bucket,
+		S3Prefix:       cfg.ResultCacheS3Prefix,
+		S3Region:       cfg.ResultCacheS3Region,
+		S3Endpoint:     cfg.ResultCacheS3Endpoint,
+		SigningKey:     cfg.TransferSigningKey,
+		Source:         (&AgentInfo{}).GetClusterName(),
+		DeploymentUUID: deploymentUUID.String(),
+		Labels:         staticLabels,
+	})
+	if err != nil {
+		logging.For(logging.ComponentQueue).Error("failed to initialize result cache", "error", err)
+		os.Exit(1)
+	}
+	if resultCache != nil {
+		scanQueue.SetResultCache(resultCache)
+	}
+
+	// Register external scan lifecycle hooks (if configured)
+	for stage, command := range map[scanning.HookStage]string{
+		scanning.HookPostSBOM:     cfg.ScanHookPostSBOM,
+		scanning.HookPostVulnScan: cfg.ScanHookPostVulnScan,
+		scanning.HookPrePersist:   cfg.ScanHookPrePersist,
+	} {
+		if command != "" {
+			scanQueue.AddHook(stage, scanning.ExecHook(command, cfg.ScanHookTimeout))
+		}
+	}
+
+	// Alert the default destinations when a scan introduces new vulnerabilities
+	// in a running image (if configured)
+	if cfg.NotifyDefault != "" {
+		destinations, err := notify.ParseDestinations(cfg.NotifyDefault)
+		if err != nil {
+			logging.For(logging.ComponentNotify).Error("invalid default notification destination", "error", err)
+			os.Exit(1)
+		}
+		thresholds, err := notify.ParseThresholds(cfg.NotifyThresholds)
+		if err != nil {
+			logging.For(logging.ComponentNotify).Error("invalid notification thresholds", "error", err)
+			os.Exit(1)
+		}
+		notify.NewNotifier(notify.NewRouter(destinations), db, notify.AlertConfig{
+			Thresholds:        thresholds,
+			KnownExploited:    cfg.NotifyKnownExploited,
+			FirstScan:         cfg.NotifyFirstScan,
+			Namespaces:        cfg.NotifyNamespaces,
+			ExcludeNamespaces: cfg.NotifyExcludeNamespaces,
+			SlackWebhookURL:   cfg.NotifySlackWebhookURL,
+			Source:            (&AgentInfo{}).GetClusterName(),
+		}).AddHooks(scanQueue)
+		logging.For(logging.ComponentNotify).Info("vulnerability alerts enabled",
+			"default", cfg.NotifyDefault, "thresholds", cfg.NotifyThresholds, "known_exploited", cfg.NotifyKnownExploited)
+	}
+
+	// Pause or throttle rescans during business hours (if configured)
+	if cfg.ScanQuietHours != "" {
+		quietHours, err := scanning.NewQuietHours(cfg.ScanQuietHours, cfg.ScanQuietHoursTimezone,
+			scanning.QuietMode(cfg.ScanQuietHoursMode), cfg.ScanQuietHoursInterval)
+		if err != nil {
+			logging.For(logging.ComponentQueue).Error("invalid scan quiet hours", "error", err)
+			os.Exit(1)
+		}
+		scanQueue.SetQuietHours(quietHours)
+		logging.For(logging.ComponentQueue).Info("scan quiet hours configured", "windows", cfg.ScanQuietHours,
+			"timezone", quietHours.Location.String(), "mode", quietHours.Mode)
+	}
+
 	// Connect scan queue to manager
 	manager.SetScanQueue(scanQueue)
 
+	// Ad-hoc and registry crawl scans pull the image from its registry
+	scanQueue.SetRegistrySBOMRetriever(func(ctx context.Context, image containers.ImageID) ([]byte, error) {
+		return syft.GenerateRegistrySBOM(ctx, adhoc.PinnedReference(image))
+	})
+
+	// Vendor images use the SBOM their vendor attested, when its signature verifies
+	if len(cfg.VendorSBOMImages) > 0 {
+		vendorSBOMs, err := provenance.NewVendorSBOMs(cfg.VendorSBOMImages, cfg.VendorSBOMPublicKeys)
+		if err != nil {
+			logging.For(logging.ComponentQueue).Error("invalid vendor SBOM settings", "error", err)
+			os.Exit(1)
+		}
+		scanQueue.SetVendorSBOMRetriever(vendorSBOMs.Retrieve)
+		logging.For(logging.ComponentQueue).Info("vendor SBOMs configured", "images", cfg.VendorSBOMImages)
+	}
+
 	// Context for graceful shutdown
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
@@ -367,11 +602,34 @@ func main() {
 	// a restart (which would otherwise trigger the startup TRUNCATE checkpoint).
 	database.StartWALMonitor(ctx, db)
 
+	// Restore the findings of expired vulnerability ignores.
+	db.StartIgnoreExpiry(ctx)
+
 	// Warm the node vulnerability metrics cache so /metrics scrapes don't hit disk.
 	db.StartNodeVulnCacheRefresh(ctx)
 	// Same for the container × image_vulnerability join.
 	db.StartContainerVulnCacheRefresh(ctx)
 
+	// Apply background table rewrites; progress is served at /api/status/migration
+	go func() {
+		if err := db.RunOnlineMigrations(ctx); err != nil && ctx.Err() == nil {
+			logging.For(logging.ComponentDatabase).Error("online migrations failed", "error", err)
+		}
+	}()
+
+	// Watch the data volume; prunes stored SBOMs before it fills up (/api/status/disk)
+	diskMonitor := diskusage.NewMonitor(db, diskusage.Config{
+		DBPath:           dbPath,
+		Dirs:             map[string]string{"grype": filepath.Join(grypeCfg.DBRootDir, "grype")},
+		WarningPercent:   cfg.DiskUsageWarningPercent,
+		HighWaterPercent: cfg.DiskUsageHighWaterPercent,
+		PruneEnabled:     cfg.DiskUsagePruneEnabled,
+		Interval:         cfg.DiskUsageInterval,
+	})
+	diskMonitor.Start(ctx)
+	metrics.RegisterExtraWriter(diskMonitor.WriteMetrics)
+	metrics.RegisterExtraWriter(scanQueue.WriteMetrics)
+
 	// Configure host scanning if enabled
 	if cfg.HostScanningEnabled {
 		logging.For(logging.ComponentNodes).Info("host scanning enabled, configuring host SBOM retriever")
@@ -448,16 +706,14 @@ func main() {
 		}()
 	}
 
-	// Check if Docker is available and start watcher
-	if docker.IsDockerAvailable() {
-		logging.For(logging.ComponentContainers).Info("Docker detected, starting container watcher")
+	// Start the container watcher of the detected runtime
+	if hostRuntime != nil {
+		logging.For(logging.ComponentContainers).Info("container runtime detected, starting container watcher", "runtime", hostRuntime.Name())
 		go func() {
-			if err := docker.WatchContainers(ctx, manager); err != nil {
-				logging.For(logging.ComponentContainers).Error("Docker watcher error", "error", err)
+			if err := hostRuntime.WatchContainers(ctx, manager); err != nil {
+				logging.For(logging.ComponentContainers).Error("container watcher error", "runtime", hostRuntime.Name(), "error", err)
 			}
 		}()
-	} else {
-		logging.For(logging.ComponentContainers).Info("Docker not available or not accessible, container watching disabled")
 	}
 
 	// Initialize auto-updater if enabled
@@ -493,7 +749,11 @@ func main() {
 		} else {
 			// Check if there's a pending update from previous run
 			// This must be done BEFORE starting the HTTP server and before starting the updater
-			installer := updater.NewInstaller("", "", cfg.UpdateHealthCheckTimeout)
+			healthScheme := "http"
+			if cfg.TLSCertFile != "" {
+				healthScheme = "https"
+			}
+			installer := updater.NewInstaller("", fmt.Sprintf("%s://localhost:%s/health", healthScheme, port), cfg.UpdateHealthCheckTimeout)
 			if installer.ShouldCheckRollback() {
 				logging.For(logging.ComponentHTTP).Info("pending update detected from previous run")
 				// Perform health check in background after server starts
@@ -513,6 +773,13 @@ func main() {
 		}
 	}
 
+	// OS end-of-life data: bundled, refreshed by the update-os-eol job
+	osLifecycle, err := eol.NewCatalog(cfg.OSEOLWarningDays)
+	if err != nil {
+		logging.For(logging.ComponentHTTP).Error("failed to load OS end-of-life data", "error", err)
+		os.Exit(1)
+	}
+
 	// Initialize scheduler for periodic jobs
 	var sched *scheduler.Scheduler
 	if cfg.JobsEnabled {
@@ -525,6 +792,7 @@ func main() {
 			// Connect readiness state so db-updater can mark ready after a successful DB update
 			// This fixes the case where initial download fails but db-updater succeeds later
 			rescanJob.SetReadinessSetter(dbReadinessState)
+			rescanJob.SetStuckScanMaxAge(cfg.StuckScanTimeout)
 			// Enable node rescanning on grype DB updates if host scanning is enabled
 			if cfg.HostScanningEnabled {
 				rescanJob.SetNodeScanning(db, scanQueue)
@@ -561,10 +829,29 @@ func main() {
 			logging.For(logging.ComponentJobs).Info("scheduled cleanup-orphaned-images job", "interval", cfg.JobsCleanupInterval, "timeout", cfg.JobsCleanupTimeout)
 		}
 
+		// Add purge job - removes images soft-deleted by the cleanup job
+		if cfg.JobsPurgeEnabled {
+			purgeJob := jobs.NewPurgeDeletedImagesJob(db, cfg.DeletedImageRetention)
+			purgeJob.SetScanHistoryRetention(cfg.ScanHistoryRetention)
+			purgeJob.SetDryRun(cfg.JobsPurgeDryRun)
+			if err := sched.AddJob(
+				purgeJob,
+				scheduler.NewIntervalSchedule(cfg.JobsPurgeInterval),
+				scheduler.JobConfig{
+					Enabled: true,
+					Timeout: cfg.JobsPurgeTimeout,
+				},
+			); err != nil {
+				logging.For(logging.ComponentJobs).Error("failed to add purge job", "error", err)
+				os.Exit(1)
+			}
+			logging.For(logging.ComponentJobs).Info("scheduled purge-deleted-images job", "interval", cfg.JobsPurgeInterval, "retention", cfg.DeletedImageRetention, "scan_history_retention", cfg.ScanHistoryRetention, "dry_run", cfg.JobsPurgeDryRun)
+		}
+
 		// Add refresh images job - periodic container reconciliation
-		// This catches any Docker events that were missed (daemon restart, network issues, etc.)
-		if cfg.JobsRefreshImagesEnabled && docker.IsDockerAvailable() {
-			refreshTrigger := docker.NewRefreshTrigger(manager)
+		// This catches any runtime events that were missed (daemon restart, network issues, etc.)
+		if cfg.JobsRefreshImagesEnabled && hostRuntime != nil {
+			refreshTrigger := hostRuntime.NewRefreshTrigger(manager)
 			refreshJob := jobs.NewRefreshImagesJob(refreshTrigger)
 			if err := sched.AddJob(
 				refreshJob,
@@ -580,16 +867,116 @@ func main() {
 			logging.For(logging.ComponentJobs).Info("scheduled refresh-images job", "interval", cfg.JobsRefreshImagesInterval, "timeout", cfg.JobsRefreshImagesTimeout)
 		}
 
+		// Add stuck scans job - fails and requeues images that stopped making progress
+		if cfg.JobsStuckScansEnabled {
+			if err := sched.AddJob(
+				jobs.NewRecoverStuckScansJob(db, scanQueue, cfg.StuckScanTimeout, cfg.ScanMaxAttempts),
+				scheduler.NewIntervalSchedule(cfg.JobsStuckScansInterval),
+				scheduler.JobConfig{
+					Enabled: true,
+					Timeout: 5 * time.Minute,
+				},
+			); err != nil {
+				logging.For(logging.ComponentJobs).Error("failed to add recover stuck scans job", "error", err)
+				os.Exit(1)
+			}
+			logging.For(logging.ComponentJobs).Info("scheduled recover-stuck-scans job", "interval", cfg.JobsStuckScansInterval, "timeout", cfg.StuckScanTimeout)
+		}
+
+		// Add OS end-of-life data update job
+		if cfg.OSEOLDataURL != "" {
+			if err := sched.AddJob(
+				jobs.NewUpdateOSEOLJob(osLifecycle, cfg.OSEOLDataURL),
+				scheduler.NewIntervalSchedule(cfg.OSEOLUpdateInterval),
+				scheduler.JobConfig{
+					Enabled:        true,
+					Timeout:        5 * time.Minute,
+					RunImmediately: true,
+				},
+			); err != nil {
+				logging.For(logging.ComponentJobs).Error("failed to add OS end-of-life update job", "error", err)
+				os.Exit(1)
+			}
+			logging.For(logging.ComponentJobs).Info("scheduled update-os-eol job", "interval", cfg.OSEOLUpdateInterval, "source", cfg.OSEOLDataURL)
+		}
+
+		// Add image provenance check job
+		if cfg.ProvenanceEnabled {
+			provenanceFetcher, err := provenance.NewVerifyingFetcher(cfg.ProvenancePublicKeys)
+			if err != nil {
+				logging.For(logging.ComponentJobs).Error("invalid provenance public keys", "error", err)
+				os.Exit(1)
+			}
+			if err := sched.AddJob(
+				jobs.NewCheckProvenanceJob(db, provenanceFetcher, cfg.ProvenanceRecheckInterval),
+				scheduler.NewIntervalSchedule(cfg.ProvenanceCheckInterval),
+				scheduler.JobConfig{
+					Enabled:        true,
+					Timeout:        10 * time.Minute,
+					RunImmediately: true,
+				},
+			); err != nil {
+				logging.For(logging.ComponentJobs).Error("failed to add check provenance job", "error", err)
+				os.Exit(1)
+			}
+			logging.For(logging.ComponentJobs).Info("scheduled check-provenance job", "interval", cfg.ProvenanceCheckInterval, "recheck_after", cfg.ProvenanceRecheckInterval, "verify_signatures", cfg.ProvenancePublicKeys != "")
+		}
+
+		// Add registry crawl job
+		if cfg.RegistryCrawlEnabled {
+			crawler := registrycrawl.NewCrawler(db, scanQueue, registrycrawl.Config{
+				Repositories:         cfg.RegistryCrawlRepositories,
+				Tags:                 cfg.RegistryCrawlTags,
+				MaxTagsPerRepository: cfg.RegistryCrawlMaxTagsPerRepository,
+				MaxImages:            cfg.RegistryCrawlMaxImages,
+				MaxScansPerRun:       cfg.RegistryCrawlMaxScansPerRun,
+				// Images survive one failed crawl before they are removed
+				Retention: 2 * cfg.RegistryCrawlInterval,
+			})
+			if err := sched.AddJob(
+				jobs.NewRegistryCrawlJob(crawler),
+				scheduler.NewIntervalSchedule(cfg.RegistryCrawlInterval),
+				scheduler.JobConfig{
+					Enabled:        true,
+					Timeout:        30 * time.Minute,
+					RunImmediately: true,
+				},
+			); err != nil {
+				logging.For(logging.ComponentJobs).Error("failed to add registry crawl job", "error", err)
+				os.Exit(1)
+			}
+			logging.For(logging.ComponentJobs).Info("scheduled registry-crawl job", "interval", cfg.RegistryCrawlInterval, "repositories", cfg.RegistryCrawlRepositories)
+		}
+
+		// Add CMDB sync job
+		if cfg.CMDBSyncURL != "" {
+			if err := sched.AddJob(
+				jobs.NewCMDBSyncJob(cmdb.NewFetcher(cfg.CMDBSyncURL, cfg.CMDBSyncToken), db),
+				scheduler.NewIntervalSchedule(cfg.CMDBSyncInterval),
+				scheduler.JobConfig{
+					Enabled:        true,
+					Timeout:        5 * time.Minute,
+					RunImmediately: true,
+				},
+			); err != nil {
+				logging.For(logging.ComponentJobs).Error("failed to add CMDB sync job", "error", err)
+				os.Exit(1)
+			}
+			logging.For(logging.ComponentJobs).Info("scheduled cmdb-sync job", "interval", cfg.CMDBSyncInterval)
+		}
+
 		// Start scheduler
 		if err := sched.Start(ctx); err != nil {
 			logging.For(logging.ComponentJobs).Error("failed to start scheduler", "error", err)
 			os.Exit(1)
 		}
 		logging.For(logging.ComponentJobs).Info("scheduler started")
+		metrics.RegisterExtraWriter(sched.WriteMetrics)
 	}
 
 	// Setup HTTP server
 	infoProvider := NewAgentInfo(cfg.Port, cfg.WebUIEnabled, cfg.HostScanningEnabled)
+	infoProvider.SetEffectiveConfig(cfg)
 
 	// Wire up grype database status getter for metrics
 	// Uses GetCurrentVersion() which returns cached in-memory value (fast)
@@ -603,32 +990,99 @@ func main() {
 		})
 	}
 
-	mux := http.NewServeMux()
-	handlers.RegisterHandlers(mux, infoProvider, nil)
-	handlers.RegisterDatabaseReadinessHandlers(mux, dbReadinessState)
-	handlers.RegisterDatabaseHandlers(mux, db, nil) // Use all default handlers
+	// "Fix available in tag X" hints on image details
+	var fixHints handlers.FixHintFinder
+	if cfg.FixHintsEnabled {
+		finder := fixhints.NewFinder(db, fixhints.Config{
+			RegistryLookup: cfg.FixHintsRegistryLookup,
+			MaxTags:        cfg.FixHintsMaxTags,
+		})
+		if resultCache != nil {
+			finder.SetResultCache(resultCache)
+		}
+		fixHints = finder
+	}
+
+	// On-demand scans of images not running on this host (POST /api/scan)
+	var adHocScanner handlers.AdHocScanner
+	if cfg.AdHocScanEnabled {
+		adHocScanner = adhoc.NewScanner(db, scanQueue, cfg.AdHocScanRetention)
+		logging.For(logging.ComponentHTTP).Info("ad-hoc scans enabled", "retention", cfg.AdHocScanRetention)
+	}
 
-	// Register static handlers only if web UI is enabled
-	if cfg.WebUIEnabled {
-		handlers.RegisterStaticHandlers(mux)
+	// Pass/fail policy for CI and admission decisions (/api/images/{digest}/policy,
+	// /api/policy/report)
+	imagePolicy := policy.NewBundle(policy.Default())
+	if cfg.PolicyFile != "" {
+		loaded, err := policy.LoadBundle(cfg.PolicyFile)
+		if err != nil {
+			logging.For(logging.ComponentHTTP).Error("failed to load policy", "error", err)
+			os.Exit(1)
+		}
+		imagePolicy = loaded
+		logging.For(logging.ComponentHTTP).Info("policy loaded", "file", cfg.PolicyFile, "policies", imagePolicy.Names())
 	}
 
-	// Register node handlers if host scanning is enabled
+	reg := routes.NewRegistry(handlers.AuthMiddleware(authenticator))
+	handlers.RegisterHandlers(reg, infoProvider, nil)
+	handlers.RegisterAuthHandlers(reg, authenticator)
+	handlers.RegisterDatabaseReadinessHandlers(reg, dbReadinessState)
+	handlers.RegisterAPIHandlers(reg, db, handlers.APIOptions{
+		Transfer: handlers.TransferConfig{
+			SigningKey:     cfg.TransferSigningKey,
+			Source:         infoProvider.GetClusterName(),
+			DeploymentUUID: deploymentUUID.String(),
+			Labels:         staticLabels,
+		},
+		Report: handlers.ReportConfig{
+			Source:         infoProvider.GetClusterName(),
+			DeploymentUUID: deploymentUUID.String(),
+			Labels:         staticLabels,
+		},
+		CoverageLookback: cfg.ScanCoverageLookback,
+		WebUI:            cfg.WebUIEnabled,
+		Version:          version,
+		NodeAPI:          cfg.HostScanningEnabled,
+		FixHints:         fixHints,
+		DiskUsage:        diskMonitor,
+		OSLifecycle:      osLifecycle,
+		AdHocScan:        adHocScanner,
+		DeadLetter:       scanQueue,
+		ScanNow:          scanQueue,
+		Rescan:           scanQueue,
+		ScanQueue:        scanQueue,
+		RegistryCrawl:    cfg.RegistryCrawlEnabled,
+		Policy:           imagePolicy,
+		ReadOnly:         cfg.ReadOnly,
+		ComputedColumns:  computedColumns,
+		PathRedaction:    cfg.SBOMPathRedaction,
+
+		ScanFailureAlertThreshold: cfg.ScanFailureAlertThreshold,
+		StuckScanTimeout:          cfg.StuckScanTimeout,
+		RescanConfirmThreshold:    cfg.RescanConfirmThreshold,
+		DeletedImageRetention:     cfg.DeletedImageRetention,
+	})
 	if cfg.HostScanningEnabled {
-		handlers.RegisterNodeHandlers(mux, db)
 		logging.For(logging.ComponentHTTP).Info("node API endpoints registered: /api/nodes, /api/nodes/{name}, /api/summary/by-node")
 	}
 
 	// Register updater API endpoints if updater is initialized
 	if agentUpdater != nil {
-		registerUpdaterHandlers(mux, agentUpdater)
+		registerUpdaterHandlers(reg, agentUpdater)
 	}
 
 	// Register debug handlers if debug mode is enabled
-	handlers.RegisterDebugHandlers(mux, db, debugConfig, scanQueue)
+	handlers.RegisterDebugHandlers(reg, db, debugConfig, scanQueue)
 
 	// Register jobs debug handlers for listing, triggering, and viewing execution history
-	handlers.RegisterJobsHandlersWithDB(mux, sched, db)
+	handlers.RegisterJobsHandlersWithDB(reg, sched, db)
+
+	// Register vulnerability database status and forced refresh
+	var feedChecker vulndb.FeedChecker
+	if dbUpdater != nil {
+		feedChecker = dbUpdater
+	}
+	handlers.RegisterVulnDBHandlers(reg, feedChecker, sched)
 
 	// Create unified metrics config (shared between /metrics and OTEL)
 	unifiedConfig := metrics.UnifiedConfig{
@@ -644,13 +1098,30 @@ func main() {
 		NodeVulnerabilityRiskEnabled:      cfg.MetricsNodeVulnerabilityRiskEnabled && cfg.HostScanningEnabled,
 		NodeVulnerabilityExploitedEnabled: cfg.MetricsNodeVulnerabilityExploitedEnabled && cfg.HostScanningEnabled,
 		StalenessWindow:                   int64(cfg.MetricsStalenessWindow.Seconds()),
+		StaticLabels:                      staticLabels,
 	}
 
 	staleness := metrics.NewStalenessStore(db, cfg.MetricsStalenessWindow)
 	logging.For(logging.ComponentMetrics).Info("metric staleness tracking enabled", "window", cfg.MetricsStalenessWindow)
 
+	metrics.RegisterCoverageMetrics(db, cfg.ScanCoverageLookback)
+
+	// Export OS end-of-life status on /metrics (bjorn2scan_os_lifecycle_images)
+	metrics.RegisterOSEOLMetrics(db, osLifecycle)
+
+	// Export failing images per node and failure reason on /metrics (bjorn2scan_scan_failure_alert)
+	metrics.RegisterScanFailureMetrics(db, cfg.ScanFailureAlertThreshold)
+
 	// Register Prometheus metrics endpoint
-	metrics.RegisterMetricsHandler(mux, infoProvider, deploymentUUID.String(), db, unifiedConfig, staleness)
+	metrics.RegisterMetricsHandler(reg, infoProvider, deploymentUUID.String(), db, unifiedConfig, staleness)
+
+	// Register namespace-scoped metrics endpoints (/metrics/namespace/{name})
+	namespaceTokens, err := metrics.ParseNamespaceTokens(cfg.MetricsNamespaceTokens)
+	if err != nil {
+		logging.For(logging.ComponentHTTP).Error("invalid metrics namespace tokens", "error", err)
+		os.Exit(1)
+	}
+	metrics.RegisterNamespaceMetricsHandler(reg, infoProvider, deploymentUUID.String(), db, unifiedConfig, staleness, namespaceTokens)
 
 	// Initialize OpenTelemetry metrics exporter if enabled
 	var otelExporter *metrics.OTELExporter
@@ -679,24 +1150,54 @@ func main() {
 		}
 	}
 
+	// Compress JSON responses for clients that accept zstd or gzip
+	var handler http.Handler = handlers.CompressionMiddleware(reg)
+
+	// Reject mutating requests in read-only mode
+	if cfg.ReadOnly {
+		handler = handlers.ReadOnlyMiddleware(handler)
+		logging.For(logging.ComponentHTTP).Info("read-only mode enabled, mutating endpoints are disabled")
+	}
+
 	// Wrap with logging middleware if debug enabled
-	var handler http.Handler = mux
 	if debugConfig.IsEnabled() {
-		handler = debug.LoggingMiddleware(debugConfig, mux)
+		handler = debug.LoggingMiddleware(debugConfig, handler)
 	}
+	handler = metrics.InstrumentHandler(handler)
 
 	server := &http.Server{
-		Addr:    ":" + port,
-		Handler: handler,
+		Addr: ":" + port,
 	}
+	if cfg.TLSCertFile != "" {
+		certs, err := tlsreload.New(tlsreload.Files{CertFile: cfg.TLSCertFile, KeyFile: cfg.TLSKeyFile, CAFile: cfg.TLSClientCAFile})
+		if err != nil {
+			logging.For(logging.ComponentHTTP).Error("failed to load TLS certificate", "error", err)
+			os.Exit(1)
+		}
+		server.TLSConfig = certs.ServerConfig()
+		if cfg.TLSClientCAFile != "" {
+			handler = tlsreload.RequireClientCert(handler, "/health", "/ready")
+		}
+	} else if cfg.TLSClientCAFile != "" {
+		logging.For(logging.ComponentHTTP).Error("tls_client_ca_file requires tls_cert_file and tls_key_file")
+		os.Exit(1)
+	}
+	server.Handler = handler
 
 	// Handle shutdown gracefully
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
 
 	go func() {
-		logging.For(logging.ComponentHTTP).Info("bjorn2scan-agent listening", "port", port)
-		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		logging.For(logging.ComponentHTTP).Info("bjorn2scan-agent listening", "port", port, "tls", server.TLSConfig != nil)
+		var err error
+		if server.TLSConfig != nil {
+			// The certificate comes from TLSConfig, reloaded when renewed
+			err = server.ListenAndServeTLS("", "")
+		} else {
+			err = server.ListenAndServe()
+		}
+		if err != nil && err != http.ErrServerClosed {
 			logging.For(logging.ComponentHTTP).Error("server error", "error", err)
 			os.Exit(1)
 		}
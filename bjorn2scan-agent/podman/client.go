@@ -0,0 +1,236 @@
+package podman
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+)
+
+// apiBase is the libpod REST API prefix (Podman 4 and later)
+const apiBase = "http://podman/v4.0.0/libpod"
+
+// rootfulSocket is where the system podman.socket unit listens
+const rootfulSocket = "/run/podman/podman.sock"
+
+// rootlessSocketPattern matches the podman.socket units of rootless users
+const rootlessSocketPattern = "/run/user/*/podman/podman.sock"
+
+// Options configures how the agent connects to Podman
+type Options struct {
+	Socket string // socket to use instead of searching the standard locations
+}
+
+// SocketPaths returns the Podman sockets searched, in order, when no socket is
+// configured: the rootful socket, the socket of the current user
+// ($XDG_RUNTIME_DIR) and those of other rootless users
+func SocketPaths() []string {
+	paths := []string{rootfulSocket}
+	if dir := os.Getenv("XDG_RUNTIME_DIR"); dir != "" {
+		paths = append(paths, filepath.Join(dir, "podman", "podman.sock"))
+	}
+	rootless, _ := filepath.Glob(rootlessSocketPattern)
+	for _, path := range rootless {
+		if !slices.Contains(paths, path) {
+			paths = append(paths, path)
+		}
+	}
+	return paths
+}
+
+// client talks to the libpod REST API over a unix socket
+type client struct {
+	socket string
+	http   *http.Client
+}
+
+func newClient(socket string) *client {
+	dialer := &net.Dialer{Timeout: 2 * time.Second}
+	return &client{
+		socket: socket,
+		http: &http.Client{Transport: &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				return dialer.DialContext(ctx, "unix", socket)
+			},
+		}},
+	}
+}
+
+// connect returns a client for the first Podman socket that answers
+func connect(ctx context.Context, opts Options) (*client, error) {
+	sockets := SocketPaths()
+	if opts.Socket != "" {
+		sockets = []string{opts.Socket}
+	}
+
+	var errs []error
+	for _, socket := range sockets {
+		if _, err := os.Stat(socket); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", socket, err))
+			continue
+		}
+		c := newClient(socket)
+		pingCtx, cancel := context.WithTimeout(ctx, 2*time.Second)
+		err := c.ping(pingCtx)
+		cancel()
+		if err != nil {
+			c.close()
+			errs = append(errs, fmt.Errorf("%s: %w", socket, err))
+			continue
+		}
+		log.Debug("connected to podman", "socket", socket)
+		return c, nil
+	}
+	return nil, fmt.Errorf("podman not reachable: %w", errors.Join(errs...))
+}
+
+// IsPodmanAvailable checks if a Podman API service is accessible
+func IsPodmanAvailable(opts Options) bool {
+	c, err := connect(context.Background(), opts)
+	if err != nil {
+		return false
+	}
+	c.close()
+	return true
+}
+
+func (c *client) close() {
+	c.http.CloseIdleConnections()
+}
+
+// get sends a GET request for path (relative to the libpod API) and returns
+// the response, which the caller must close, if its status is 200
+func (c *client) get(ctx context.Context, path string, query url.Values) (*http.Response, error) {
+	u := apiBase + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer func() { _ = resp.Body.Close() }()
+		var apiErr struct {
+			Message string `json:"message"`
+		}
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		if json.Unmarshal(body, &apiErr) == nil && apiErr.Message != "" {
+			return nil, fmt.Errorf("GET %s: %s (status %d)", path, apiErr.Message, resp.StatusCode)
+		}
+		return nil, fmt.Errorf("GET %s: status %d", path, resp.StatusCode)
+	}
+	return resp, nil
+}
+
+// getJSON decodes the response to a GET request into v
+func (c *client) getJSON(ctx context.Context, path string, query url.Values, v any) error {
+	resp, err := c.get(ctx, path, query)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("failed to decode %s: %w", path, err)
+	}
+	return nil
+}
+
+func (c *client) ping(ctx context.Context) error {
+	resp, err := c.get(ctx, "/_ping", nil)
+	if err != nil {
+		return err
+	}
+	return resp.Body.Close()
+}
+
+// listedContainer is an entry of GET /libpod/containers/json
+type listedContainer struct {
+	ID      string `json:"Id"`
+	IsInfra bool   `json:"IsInfra"`
+}
+
+// inspectedContainer holds the fields of GET /libpod/containers/{id}/json used
+type inspectedContainer struct {
+	ID          string `json:"Id"`
+	Name        string `json:"Name"`
+	Image       string `json:"Image"`       // image ID
+	ImageDigest string `json:"ImageDigest"` // manifest digest, empty for local builds
+	ImageName   string `json:"ImageName"`   // reference the container was created from
+	IsInfra     bool   `json:"IsInfra"`
+}
+
+// event is a libpod event from GET /libpod/events
+type event struct {
+	Type   string `json:"Type"`
+	Action string `json:"Action"`
+	Actor  struct {
+		ID         string            `json:"ID"`
+		Attributes map[string]string `json:"Attributes"`
+	} `json:"Actor"`
+}
+
+// listRunning lists the running containers
+func (c *client) listRunning(ctx context.Context) ([]listedContainer, error) {
+	var listed []listedContainer
+	if err := c.getJSON(ctx, "/containers/json", nil, &listed); err != nil {
+		return nil, fmt.Errorf("failed to list containers: %w", err)
+	}
+	return listed, nil
+}
+
+// inspect returns the details of a container
+func (c *client) inspect(ctx context.Context, id string) (inspectedContainer, error) {
+	var info inspectedContainer
+	err := c.getJSON(ctx, "/containers/"+url.PathEscape(id)+"/json", nil, &info)
+	return info, err
+}
+
+// events streams container events until ctx is done or the stream ends
+func (c *client) events(ctx context.Context) (<-chan event, <-chan error) {
+	eventsChan := make(chan event)
+	errChan := make(chan error, 1)
+
+	go func() {
+		defer close(errChan)
+		query := url.Values{
+			"stream":  {"true"},
+			"filters": {`{"type":["container"]}`},
+		}
+		resp, err := c.get(ctx, "/events", query)
+		if err != nil {
+			errChan <- err
+			return
+		}
+		defer func() { _ = resp.Body.Close() }()
+
+		decoder := json.NewDecoder(resp.Body)
+		for {
+			var e event
+			if err := decoder.Decode(&e); err != nil {
+				errChan <- err
+				return
+			}
+			select {
+			case eventsChan <- e:
+			case <-ctx.Done():
+				errChan <- ctx.Err()
+				return
+			}
+		}
+	}()
+
+	return eventsChan, errChan
+}
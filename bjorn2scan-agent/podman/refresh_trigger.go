@@ -0,0 +1,53 @@
+package podman
+
+import (
+	"context"
+	"time"
+
+	"github.com/bvboe/b2s-go/scanner-core/containers"
+)
+
+// RefreshTrigger implements containers.RefreshTrigger for Podman
+// It performs a full reconciliation of running containers when triggered
+type RefreshTrigger struct {
+	manager *containers.Manager
+	opts    Options
+}
+
+// NewRefreshTrigger creates a new Podman refresh trigger
+func NewRefreshTrigger(manager *containers.Manager, opts Options) *RefreshTrigger {
+	return &RefreshTrigger{
+		manager: manager,
+		opts:    opts,
+	}
+}
+
+// TriggerRefresh performs a full reconciliation of running Podman containers,
+// catching any events missed while podman.socket or the agent restarted
+func (t *RefreshTrigger) TriggerRefresh() error {
+	log.Info("starting podman container reconciliation")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	c, err := connect(ctx, t.opts)
+	if err != nil {
+		return err
+	}
+	defer c.close()
+
+	running, err := listRunningContainers(ctx, c)
+	if err != nil {
+		return err
+	}
+
+	// Update the manager with the current container set
+	// This will reconcile with the database and enqueue scans for new images
+	t.manager.SetContainers(running)
+
+	log.Info("reconciliation complete", "running_containers", len(running))
+	return nil
+}
+
+// Ensure RefreshTrigger implements containers.RefreshTrigger
+var _ containers.RefreshTrigger = (*RefreshTrigger)(nil)
@@ -0,0 +1,30 @@
+package podman
+
+import (
+	"context"
+	"os"
+
+	"github.com/bvboe/b2s-go/bjorn2scan-agent/syft"
+	"github.com/bvboe/b2s-go/scanner-core/containers"
+)
+
+// containerHostEnv is how syft (stereoscope) locates the Podman service
+const containerHostEnv = "CONTAINER_HOST"
+
+// GenerateSBOM generates an SBOM for an image in Podman's image store, read
+// through the socket the agent watches, so nothing is pulled from a registry
+func GenerateSBOM(ctx context.Context, image containers.ImageID, opts Options) ([]byte, error) {
+	c, err := connect(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	c.close()
+
+	// Without it stereoscope only tries the current user's and the rootful socket
+	if os.Getenv(containerHostEnv) == "" {
+		if err := os.Setenv(containerHostEnv, "unix://"+c.socket); err != nil {
+			return nil, err
+		}
+	}
+	return syft.GeneratePodmanSBOM(ctx, image)
+}
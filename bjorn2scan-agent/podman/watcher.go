@@ -0,0 +1,191 @@
+// Package podman watches the containers of a Podman host (rootful or
+// rootless) through the libpod REST API served by podman.socket, and
+// generates SBOMs from Podman's image store.
+package podman
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/bvboe/b2s-go/scanner-core/containers"
+	"github.com/bvboe/b2s-go/scanner-core/logging"
+)
+
+var log = logging.For(logging.ComponentContainers)
+
+// shortID returns the abbreviated container ID shown by podman ps
+func shortID(id string) string {
+	if len(id) > 12 {
+		return id[:12]
+	}
+	return id
+}
+
+// containerID identifies a Podman container the same way the Docker watcher does
+func containerID(name string) containers.ContainerID {
+	hostname, _ := os.Hostname()
+	if hostname == "" {
+		hostname = "unknown"
+	}
+	return containers.ContainerID{
+		Namespace: hostname,
+		Pod:       "host", // Indicate this is a host-level container
+		Name:      name,
+	}
+}
+
+// imageDigest returns the manifest digest of a container's image, or its image
+// ID for images built locally
+func imageDigest(info inspectedContainer) string {
+	if info.ImageDigest != "" {
+		return info.ImageDigest
+	}
+	if info.Image != "" && !strings.HasPrefix(info.Image, "sha256:") {
+		return "sha256:" + info.Image
+	}
+	return info.Image
+}
+
+// extractContainer creates a Container from inspected Podman container info
+func extractContainer(info inspectedContainer) containers.Container {
+	name := strings.TrimPrefix(info.Name, "/")
+	if name == "" {
+		name = shortID(info.ID)
+	}
+	reference := info.ImageName
+	if reference == "" {
+		reference = info.Image
+	}
+
+	c := containers.Container{
+		ID: containerID(name),
+		Image: containers.ImageID{
+			Reference: reference,
+			Digest:    imageDigest(info),
+		},
+		ContainerRuntime: "podman",
+	}
+	c.NodeName = c.ID.Namespace // Use hostname as node name for agent deployments
+	return c
+}
+
+// listRunningContainers returns the running containers, without the infra
+// containers that hold the namespaces of pods
+func listRunningContainers(ctx context.Context, c *client) ([]containers.Container, error) {
+	listed, err := c.listRunning(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var running []containers.Container
+	for _, lc := range listed {
+		if lc.IsInfra {
+			continue
+		}
+		info, err := c.inspect(ctx, lc.ID)
+		if err != nil {
+			log.Warn("failed to extract container", "container_id", shortID(lc.ID), "error", err)
+			continue
+		}
+		running = append(running, extractContainer(info))
+	}
+	return running, nil
+}
+
+// WatchContainers watches for Podman container events and updates the container manager
+func WatchContainers(ctx context.Context, manager *containers.Manager, opts Options) error {
+	c, err := connect(ctx, opts)
+	if err != nil {
+		return err
+	}
+	defer c.close()
+
+	log.Info("podman watcher connected to podman", "socket", c.socket)
+
+	// Perform initial sync of running containers
+	if err := syncInitialContainers(ctx, c, manager); err != nil {
+		log.Warn("initial container sync failed", "error", err)
+	}
+
+	// Start watching for events
+	for {
+		select {
+		case <-ctx.Done():
+			log.Info("podman watcher shutting down")
+			return nil
+		default:
+			eventsChan, errChan := c.events(ctx)
+
+			log.Info("podman watcher started")
+
+		eventLoop:
+			for {
+				select {
+				case <-ctx.Done():
+					log.Info("podman watcher shutting down")
+					return nil
+
+				case err := <-errChan:
+					if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, context.Canceled) {
+						log.Error("podman events error", "error", err)
+					}
+					break eventLoop
+
+				case e := <-eventsChan:
+					handleEvent(ctx, c, manager, e)
+				}
+			}
+
+			log.Info("podman watcher connection closed, reconnecting")
+			time.Sleep(1 * time.Second)
+		}
+	}
+}
+
+// handleEvent applies a container event to the manager
+func handleEvent(ctx context.Context, c *client, manager *containers.Manager, e event) {
+	if e.Type != "container" {
+		return
+	}
+	switch e.Action {
+	case "start":
+		// Container started
+		info, err := c.inspect(ctx, e.Actor.ID)
+		if err != nil {
+			log.Error("failed to extract container", "container_id", shortID(e.Actor.ID), "error", err)
+			return
+		}
+		if info.IsInfra {
+			return
+		}
+		manager.AddContainer(extractContainer(info))
+
+	case "died", "remove":
+		// The event carries the name, so containers run with --rm that are
+		// already gone are removed too
+		name := e.Actor.Attributes["name"]
+		if name == "" {
+			name = shortID(e.Actor.ID)
+		}
+		manager.RemoveContainer(containerID(name))
+	}
+}
+
+// syncInitialContainers performs an initial sync of all running containers
+func syncInitialContainers(ctx context.Context, c *client, manager *containers.Manager) error {
+	log.Info("podman watcher performing initial container sync")
+
+	running, err := listRunningContainers(ctx, c)
+	if err != nil {
+		return err
+	}
+
+	manager.SetContainers(running)
+	log.Info("podman watcher initial sync complete", "container_count", manager.GetContainerCount())
+
+	return nil
+}
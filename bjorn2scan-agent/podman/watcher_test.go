@@ -0,0 +1,198 @@
+package podman
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/bvboe/b2s-go/scanner-core/containers"
+)
+
+// fakePodman serves the libpod endpoints the watcher uses on a unix socket
+type fakePodman struct {
+	listed    []listedContainer
+	inspected map[string]inspectedContainer
+	events    chan event
+}
+
+func (f *fakePodman) serve(t *testing.T) string {
+	t.Helper()
+	socket := filepath.Join(t.TempDir(), "podman.sock")
+	listener, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /v4.0.0/libpod/_ping", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("OK"))
+	})
+	mux.HandleFunc("GET /v4.0.0/libpod/containers/json", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(f.listed)
+	})
+	mux.HandleFunc("GET /v4.0.0/libpod/containers/{id}/json", func(w http.ResponseWriter, r *http.Request) {
+		info, ok := f.inspected[r.PathValue("id")]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"cause":"no such container","message":"no container with name or ID found","response":404}`))
+			return
+		}
+		_ = json.NewEncoder(w).Encode(info)
+	})
+	mux.HandleFunc("GET /v4.0.0/libpod/events", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("stream") != "true" {
+			t.Errorf("events requested without streaming: %s", r.URL.RawQuery)
+		}
+		w.WriteHeader(http.StatusOK)
+		w.(http.Flusher).Flush()
+		for {
+			select {
+			case e := <-f.events:
+				_ = json.NewEncoder(w).Encode(e)
+				w.(http.Flusher).Flush()
+			case <-r.Context().Done():
+				return
+			}
+		}
+	})
+
+	server := &http.Server{Handler: mux}
+	go func() { _ = server.Serve(listener) }()
+	t.Cleanup(func() { _ = server.Close() })
+	return socket
+}
+
+func newFakePodman() *fakePodman {
+	return &fakePodman{
+		listed: []listedContainer{
+			{ID: "infra0123456789", IsInfra: true},
+			{ID: "web0123456789ab"},
+		},
+		inspected: map[string]inspectedContainer{
+			"infra0123456789": {ID: "infra0123456789", Name: "pod-infra", Image: "aaaa", IsInfra: true},
+			"web0123456789ab": {ID: "web0123456789ab", Name: "web", Image: "bbbb",
+				ImageDigest: "sha256:webdigest", ImageName: "docker.io/library/nginx:1.27"},
+			"api0123456789ab": {ID: "api0123456789ab", Name: "api", Image: "cccc", ImageName: "localhost/api:dev"},
+		},
+		events: make(chan event),
+	}
+}
+
+func TestSocketPaths(t *testing.T) {
+	t.Setenv("XDG_RUNTIME_DIR", "/run/user/4242")
+	paths := SocketPaths()
+	if len(paths) < 2 || paths[0] != "/run/podman/podman.sock" || paths[1] != "/run/user/4242/podman/podman.sock" {
+		t.Errorf("SocketPaths() = %v, want rootful then the current user's socket first", paths)
+	}
+}
+
+func TestExtractContainer(t *testing.T) {
+	fake := newFakePodman()
+
+	web := extractContainer(fake.inspected["web0123456789ab"])
+	if web.ID.Name != "web" || web.ID.Pod != "host" || web.ContainerRuntime != "podman" || web.NodeName != web.ID.Namespace {
+		t.Errorf("unexpected container: %+v", web)
+	}
+	if web.Image.Reference != "docker.io/library/nginx:1.27" || web.Image.Digest != "sha256:webdigest" {
+		t.Errorf("unexpected image: %+v", web.Image)
+	}
+
+	// Locally built images have no manifest digest
+	api := extractContainer(fake.inspected["api0123456789ab"])
+	if api.Image.Digest != "sha256:cccc" {
+		t.Errorf("digest = %q, want the image ID", api.Image.Digest)
+	}
+}
+
+func TestPodmanUnavailable(t *testing.T) {
+	opts := Options{Socket: filepath.Join(t.TempDir(), "podman.sock")}
+	if IsPodmanAvailable(opts) {
+		t.Fatal("IsPodmanAvailable() = true for a missing socket")
+	}
+	if err := NewRefreshTrigger(containers.NewManager(), opts).TriggerRefresh(); err == nil {
+		t.Error("Expected error when podman is not available")
+	}
+}
+
+func TestTriggerRefresh(t *testing.T) {
+	opts := Options{Socket: newFakePodman().serve(t)}
+	if !IsPodmanAvailable(opts) {
+		t.Fatal("IsPodmanAvailable() = false for the fake service")
+	}
+
+	manager := containers.NewManager()
+	if err := NewRefreshTrigger(manager, opts).TriggerRefresh(); err != nil {
+		t.Fatalf("TriggerRefresh() error = %v", err)
+	}
+	// The pod infra container is not reported
+	all := manager.GetAllContainers()
+	if len(all) != 1 || all[0].ID.Name != "web" {
+		t.Errorf("containers = %+v, want only web", all)
+	}
+}
+
+func TestWatchContainers(t *testing.T) {
+	fake := newFakePodman()
+	opts := Options{Socket: fake.serve(t)}
+	manager := containers.NewManager()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- WatchContainers(ctx, manager, opts) }()
+
+	send := func(e event) {
+		t.Helper()
+		select {
+		case fake.events <- e:
+		case <-time.After(5 * time.Second):
+			t.Fatal("watcher did not read the event")
+		}
+	}
+	waitFor := func(want ...string) {
+		t.Helper()
+		deadline := time.Now().Add(5 * time.Second)
+		for {
+			var names []string
+			for _, c := range manager.GetAllContainers() {
+				names = append(names, c.ID.Name)
+			}
+			slices.Sort(names)
+			if slices.Equal(names, want) {
+				return
+			}
+			if time.Now().After(deadline) {
+				t.Fatalf("containers = %v, want %v", names, want)
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+
+	waitFor("web")
+
+	start := event{Type: "container", Action: "start"}
+	start.Actor.ID = "api0123456789ab"
+	send(start)
+	waitFor("api", "web")
+
+	// Exits of removed --rm containers are identified by the event's name
+	died := event{Type: "container", Action: "died"}
+	died.Actor.ID = "gone0123456789a"
+	died.Actor.Attributes = map[string]string{"name": "web"}
+	send(died)
+	waitFor("api")
+
+	cancel()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("WatchContainers() error = %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("watcher did not stop")
+	}
+}
@@ -18,16 +18,28 @@ var log = logging.For(logging.ComponentQueue)
 // GenerateSBOM generates an SBOM for a Docker image using syft library
 // Returns the SBOM as JSON bytes in syft JSON format
 func GenerateSBOM(ctx context.Context, image containers.ImageID) ([]byte, error) {
+	return generateLocalSBOM(ctx, image, "docker")
+}
+
+// GeneratePodmanSBOM generates an SBOM for an image in Podman's image store,
+// located through CONTAINER_HOST or the standard Podman sockets
+func GeneratePodmanSBOM(ctx context.Context, image containers.ImageID) ([]byte, error) {
+	return generateLocalSBOM(ctx, image, "podman")
+}
+
+// generateLocalSBOM generates an SBOM for an image stored by the given daemon
+// source (docker or podman)
+func generateLocalSBOM(ctx context.Context, image containers.ImageID, daemon string) ([]byte, error) {
 	// Use the image reference directly - it's already in the correct format (e.g., "nginx:1.21")
 	// We explicitly avoid digest-based references to prevent any pull attempts from registries
 	// Since we only scan locally running containers, the reference is always available
 	imageRef := image.Reference
 
-	log.Info("generating SBOM for image", "image", imageRef)
+	log.Info("generating SBOM for image", "image", imageRef, "source", daemon)
 
-	// Configure source to use Docker daemon exclusively
+	// Configure source to use the daemon exclusively
 	// This ensures we ONLY scan locally cached images and never attempt registry pulls
-	cfg := syft.DefaultGetSourceConfig().WithSources("docker")
+	cfg := syft.DefaultGetSourceConfig().WithSources(daemon)
 
 	// Parse the image reference and create a source
 	src, err := syft.GetSource(ctx, imageRef, cfg)
@@ -63,6 +75,52 @@ func GenerateSBOM(ctx context.Context, image containers.ImageID) ([]byte, error)
 	return sbomBytes, nil
 }
 
+// GenerateRegistrySBOM generates an SBOM for an image pulled from its
+// registry, for ad-hoc scans of images that are not running locally.
+// Credentials come from the Docker config; public images are pulled anonymously.
+func GenerateRegistrySBOM(ctx context.Context, imageRef string) ([]byte, error) {
+	log.Info("generating SBOM for registry image", "image", imageRef)
+
+	// Pull from the registry only, never from the local Docker daemon
+	cfg := syft.DefaultGetSourceConfig().WithSources("registry")
+
+	src, err := syft.GetSource(ctx, imageRef, cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get source for image %s: %w", imageRef, err)
+	}
+
+	// Ensure cleanup of source (removes the pulled layers)
+	defer func() {
+		if cleanupErr := src.Close(); cleanupErr != nil {
+			log.Warn("failed to cleanup source", "error", cleanupErr)
+		}
+	}()
+
+	return GenerateSBOMFromImageSource(ctx, src)
+}
+
+// GenerateArchiveSBOM generates an SBOM for an image exported to an OCI
+// archive, used for containerd images which syft cannot read directly
+func GenerateArchiveSBOM(ctx context.Context, archivePath string, imageRef string) ([]byte, error) {
+	log.Info("generating SBOM for image archive", "image", imageRef)
+
+	cfg := syft.DefaultGetSourceConfig().WithSources("oci-archive")
+
+	src, err := syft.GetSource(ctx, archivePath, cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get source for image %s: %w", imageRef, err)
+	}
+
+	// Ensure cleanup of source (removes the unpacked layers)
+	defer func() {
+		if cleanupErr := src.Close(); cleanupErr != nil {
+			log.Warn("failed to cleanup source", "error", cleanupErr)
+		}
+	}()
+
+	return GenerateSBOMFromImageSource(ctx, src)
+}
+
 // GenerateSBOMFromImageSource generates SBOM from a pre-created source
 // Useful for testing or when source is already available
 func GenerateSBOMFromImageSource(ctx context.Context, src source.Source) ([]byte, error) {
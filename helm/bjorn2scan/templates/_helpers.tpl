@@ -54,3 +54,21 @@ Create the name of the service account to use
 {{- default "default" .Values.serviceAccount.name }}
 {{- end }}
 {{- end }}
+
+{{/*
+Probe with the HTTPS scheme when TLS is enabled. Call with (dict "probe" <probe> "root" $)
+*/}}
+{{- define "bjorn2scan.probe" -}}
+{{- $probe := deepCopy .probe }}
+{{- if and .root.Values.tls.enabled $probe.httpGet }}
+{{- $_ := set $probe.httpGet "scheme" "HTTPS" }}
+{{- end }}
+{{- toYaml $probe }}
+{{- end }}
+
+{{/*
+Name of the Secret holding the pod-scanner request signing secret
+*/}}
+{{- define "bjorn2scan.podScannerAuthSecret" -}}
+{{- default (printf "%s-pod-scanner-auth" (include "bjorn2scan.fullname" .)) .Values.podScanner.auth.existingSecret }}
+{{- end }}
@@ -0,0 +1,212 @@
+// Package admission implements a Kubernetes validating admission webhook that
+// evaluates the images of new pods against the image policy, so pods running
+// images with known critical vulnerabilities can be rejected before they
+// start.
+//
+// Images are resolved to a digest from a pinned reference (app@sha256:...) or
+// from the containers already seen running under the same reference. Images
+// that cannot be resolved or have no vulnerability scan results yet are
+// allowed with a warning when failing open, and denied when failing closed.
+package admission
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/bvboe/b2s-go/scanner-core/database"
+	"github.com/bvboe/b2s-go/scanner-core/logging"
+	"github.com/bvboe/b2s-go/scanner-core/policy"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var log = logging.For(logging.ComponentAdmission)
+
+// maxRequestSize caps the AdmissionReview body (the API server sends at most a
+// few MB per object)
+const maxRequestSize = 8 << 20
+
+// Provider resolves and describes the images of a pod (implemented by database.DB)
+type Provider interface {
+	GetImageDigestByReference(reference string) (string, error)
+	GetImagePolicyFacts(digest string) (*database.ImagePolicyFacts, error)
+}
+
+// Options configure admission decisions
+type Options struct {
+	FailClosed        bool     // Deny images that are unknown or not scanned yet instead of allowing them with a warning
+	ExcludeNamespaces []string // Pods in these namespaces are always allowed
+}
+
+// Handler creates the HTTP handler for AdmissionReview requests
+func Handler(provider Provider, p policy.Evaluator, opts Options) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		body, err := io.ReadAll(io.LimitReader(r.Body, maxRequestSize))
+		if err != nil {
+			http.Error(w, "Failed to read request", http.StatusBadRequest)
+			return
+		}
+		var review admissionv1.AdmissionReview
+		if err := json.Unmarshal(body, &review); err != nil || review.Request == nil {
+			http.Error(w, "Invalid AdmissionReview", http.StatusBadRequest)
+			return
+		}
+
+		review.Response = Review(provider, p, opts, review.Request)
+		review.Response.UID = review.Request.UID
+		review.Request = nil
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(review); err != nil {
+			log.Error("error encoding admission response", "error", err)
+		}
+	}
+}
+
+// Review decides whether the pod in an admission request is allowed. Requests
+// for other resources are always allowed.
+func Review(provider Provider, p policy.Evaluator, opts Options, req *admissionv1.AdmissionRequest) *admissionv1.AdmissionResponse {
+	allowed := &admissionv1.AdmissionResponse{Allowed: true}
+	if req.Kind.Kind != "Pod" || req.SubResource != "" || slices.Contains(opts.ExcludeNamespaces, req.Namespace) {
+		return allowed
+	}
+	var pod corev1.Pod
+	if err := json.Unmarshal(req.Object.Raw, &pod); err != nil {
+		return deny(fmt.Sprintf("bjorn2scan: failed to decode pod: %v", err))
+	}
+
+	var denials []string
+	for _, image := range podImages(&pod) {
+		reason, err := checkImage(provider, p, req.Namespace, image)
+		if err != nil {
+			log.Error("error evaluating image for admission", "image", image, "error", err)
+			reason = unknownReason(image, "could not be evaluated")
+		}
+		switch {
+		case reason.violation != "":
+			denials = append(denials, reason.violation)
+		case reason.unscanned != "" && opts.FailClosed:
+			denials = append(denials, reason.unscanned)
+		case reason.unscanned != "":
+			allowed.Warnings = append(allowed.Warnings, reason.unscanned)
+		}
+	}
+
+	name := pod.Name
+	if name == "" {
+		name = pod.GenerateName
+	}
+	if len(denials) > 0 {
+		log.Info("denied pod", "namespace", req.Namespace, "pod", name, "reasons", denials)
+		return deny("bjorn2scan: " + strings.Join(denials, "; "))
+	}
+	if len(allowed.Warnings) > 0 {
+		log.Info("allowed pod with unscanned images", "namespace", req.Namespace, "pod", name, "warnings", allowed.Warnings)
+	}
+	return allowed
+}
+
+// imageReason is why an image is denied (violation) or only allowed when
+// failing open (unscanned); both are empty for images that pass
+type imageReason struct {
+	violation string
+	unscanned string
+}
+
+func unknownReason(image, why string) imageReason {
+	return imageReason{unscanned: fmt.Sprintf("image %s %s", image, why)}
+}
+
+// checkImage evaluates one image of a pod being admitted to namespace against
+// the policy
+func checkImage(provider Provider, p policy.Evaluator, namespace, image string) (imageReason, error) {
+	digest := pinnedDigest(image)
+	if digest == "" {
+		var err error
+		if digest, err = provider.GetImageDigestByReference(image); err != nil {
+			return imageReason{}, err
+		}
+		if digest == "" {
+			return unknownReason(image, "has never been scanned"), nil
+		}
+	}
+	facts, err := provider.GetImagePolicyFacts(digest)
+	if err != nil {
+		return imageReason{}, err
+	}
+	if facts == nil {
+		return unknownReason(image, "has never been scanned"), nil
+	}
+
+	// Bundle selectors match the pod being admitted, not where the image
+	// already runs: its first rollout to prod must get the prod policy, and
+	// an image running in prod must not get it when deployed to dev
+	facts.Namespaces = []string{namespace}
+	facts.Reference = image
+
+	result := p.Evaluate(facts)
+	switch result.Verdict {
+	case policy.VerdictNotScanned:
+		return unknownReason(image, "has not been scanned yet"), nil
+	case policy.VerdictFail:
+		messages := make([]string, len(result.Violations))
+		for i, v := range result.Violations {
+			messages[i] = v.Message
+		}
+		return imageReason{violation: fmt.Sprintf("image %s violates policy %s: %s",
+			image, result.Policy, strings.Join(messages, ", "))}, nil
+	}
+	return imageReason{}, nil
+}
+
+// podImages returns the distinct images of all containers of a pod, in order
+func podImages(pod *corev1.Pod) []string {
+	var images []string
+	add := func(image string) {
+		if image != "" && !slices.Contains(images, image) {
+			images = append(images, image)
+		}
+	}
+	for _, c := range pod.Spec.InitContainers {
+		add(c.Image)
+	}
+	for _, c := range pod.Spec.Containers {
+		add(c.Image)
+	}
+	for _, c := range pod.Spec.EphemeralContainers {
+		add(c.Image)
+	}
+	return images
+}
+
+// pinnedDigest returns the digest of a digest-pinned reference
+// Example: "nginx:1.25@sha256:abc..." -> "sha256:abc..."
+// Example: "nginx:1.25" -> ""
+func pinnedDigest(image string) string {
+	if _, digest, ok := strings.Cut(image, "@"); ok && strings.HasPrefix(digest, "sha256:") {
+		return digest
+	}
+	return ""
+}
+
+func deny(message string) *admissionv1.AdmissionResponse {
+	return &admissionv1.AdmissionResponse{
+		Allowed: false,
+		Result: &metav1.Status{
+			Status:  metav1.StatusFailure,
+			Code:    http.StatusForbidden,
+			Reason:  metav1.StatusReasonForbidden,
+			Message: message,
+		},
+	}
+}
@@ -0,0 +1,198 @@
+package admission
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/bvboe/b2s-go/scanner-core/database"
+	"github.com/bvboe/b2s-go/scanner-core/policy"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// mockProvider serves fixed references and policy facts
+type mockProvider struct {
+	references map[string]string
+	facts      map[string]database.ImagePolicyFacts
+}
+
+func (m *mockProvider) GetImageDigestByReference(reference string) (string, error) {
+	return m.references[reference], nil
+}
+
+func (m *mockProvider) GetImagePolicyFacts(digest string) (*database.ImagePolicyFacts, error) {
+	if facts, ok := m.facts[digest]; ok {
+		return &facts, nil
+	}
+	return nil, nil
+}
+
+func newTestProvider() *mockProvider {
+	return &mockProvider{
+		references: map[string]string{
+			"app:1":    "sha256:clean",
+			"legacy:1": "sha256:critical",
+			"app:2":    "sha256:scanning",
+		},
+		facts: map[string]database.ImagePolicyFacts{
+			"sha256:clean":    {Digest: "sha256:clean", Status: database.StatusCompleted},
+			"sha256:critical": {Digest: "sha256:critical", Status: database.StatusCompleted, Critical: 3},
+			"sha256:scanning": {Digest: "sha256:scanning", Status: database.StatusScanningVulnerabilities},
+		},
+	}
+}
+
+func podRequest(t *testing.T, namespace string, images ...string) *admissionv1.AdmissionRequest {
+	t.Helper()
+	pod := corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: namespace}}
+	for i, image := range images {
+		container := corev1.Container{Name: "c" + string(rune('0'+i)), Image: image}
+		if i == 0 && len(images) > 1 {
+			pod.Spec.InitContainers = append(pod.Spec.InitContainers, container)
+		} else {
+			pod.Spec.Containers = append(pod.Spec.Containers, container)
+		}
+	}
+	raw, err := json.Marshal(pod)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &admissionv1.AdmissionRequest{
+		UID:       "uid-1",
+		Kind:      metav1.GroupVersionKind{Version: "v1", Kind: "Pod"},
+		Namespace: namespace,
+		Operation: admissionv1.Create,
+		Object:    runtime.RawExtension{Raw: raw},
+	}
+}
+
+func TestReview(t *testing.T) {
+	provider := newTestProvider()
+	p := policy.Default()
+
+	tests := []struct {
+		name     string
+		opts     Options
+		req      *admissionv1.AdmissionRequest
+		allowed  bool
+		message  string
+		warnings int
+	}{
+		{name: "clean image", req: podRequest(t, "default", "app:1"), allowed: true},
+		{name: "pinned clean image", req: podRequest(t, "default", "registry.example.com/app:1@sha256:clean"), allowed: true},
+		{name: "critical init container", req: podRequest(t, "default", "legacy:1", "app:1"), message: "image legacy:1 violates policy default: 3 critical"},
+		{name: "critical fails closed too", opts: Options{FailClosed: true}, req: podRequest(t, "default", "legacy:1"), message: "legacy:1"},
+		{name: "excluded namespace", opts: Options{ExcludeNamespaces: []string{"kube-system"}}, req: podRequest(t, "kube-system", "legacy:1"), allowed: true},
+		{name: "unknown image fails open", req: podRequest(t, "default", "new:1", "app:2"), allowed: true, warnings: 2},
+		{name: "unknown image fails closed", opts: Options{FailClosed: true}, req: podRequest(t, "default", "app:1", "new:1"), message: "image new:1 has never been scanned"},
+		{name: "scanning image fails closed", opts: Options{FailClosed: true}, req: podRequest(t, "default", "app:2"), message: "image app:2 has not been scanned yet"},
+		{name: "unpinned digest not in database", opts: Options{FailClosed: true}, req: podRequest(t, "default", "app@sha256:other"), message: "never been scanned"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := Review(provider, p, tt.opts, tt.req)
+			if resp.Allowed != tt.allowed || len(resp.Warnings) != tt.warnings {
+				t.Fatalf("Review() = allowed %v with warnings %v, want allowed %v with %d warnings", resp.Allowed, resp.Warnings, tt.allowed, tt.warnings)
+			}
+			if !tt.allowed && (resp.Result == nil || resp.Result.Code != http.StatusForbidden || !strings.Contains(resp.Result.Message, tt.message)) {
+				t.Errorf("Review() result = %+v, want message containing %q", resp.Result, tt.message)
+			}
+		})
+	}
+
+	// Other resources and subresources are not evaluated
+	req := podRequest(t, "default", "legacy:1")
+	req.SubResource = "status"
+	if resp := Review(provider, p, Options{}, req); !resp.Allowed {
+		t.Errorf("pod status update denied: %+v", resp.Result)
+	}
+}
+
+func TestReviewBundleSelectsAdmittedNamespace(t *testing.T) {
+	bundle, err := policy.ParseBundle([]byte(`
+name: production
+include:
+  namespaces: ["prod-*"]
+rules:
+  max_critical: 0
+---
+name: development
+include:
+  namespaces: ["dev-*"]
+rules:
+  max_critical: 10
+`))
+	if err != nil {
+		t.Fatalf("ParseBundle() error = %v", err)
+	}
+
+	// legacy:1 already runs in dev only, app:1 in prod only
+	provider := &mockProvider{
+		references: map[string]string{"legacy:1": "sha256:critical", "app:1": "sha256:critical-prod"},
+		facts: map[string]database.ImagePolicyFacts{
+			"sha256:critical":      {Digest: "sha256:critical", Status: database.StatusCompleted, Critical: 3, Namespaces: []string{"dev-team"}},
+			"sha256:critical-prod": {Digest: "sha256:critical-prod", Status: database.StatusCompleted, Critical: 3, Namespaces: []string{"prod-eu"}},
+		},
+	}
+
+	tests := []struct {
+		name      string
+		namespace string
+		image     string
+		allowed   bool
+	}{
+		{name: "first rollout to prod gets the prod policy", namespace: "prod-eu", image: "legacy:1"},
+		{name: "image running in prod gets the dev policy in dev", namespace: "dev-team", image: "app:1", allowed: true},
+		{name: "image running in dev gets the dev policy", namespace: "dev-team", image: "legacy:1", allowed: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := Review(provider, bundle, Options{}, podRequest(t, tt.namespace, tt.image))
+			if resp.Allowed != tt.allowed {
+				t.Fatalf("Review() allowed = %v, want %v (result %+v)", resp.Allowed, tt.allowed, resp.Result)
+			}
+			if !tt.allowed && !strings.Contains(resp.Result.Message, "policy production") {
+				t.Errorf("Review() message = %q, want the production policy", resp.Result.Message)
+			}
+		})
+	}
+}
+
+func TestHandler(t *testing.T) {
+	handler := Handler(newTestProvider(), policy.Default(), Options{})
+	review := admissionv1.AdmissionReview{
+		TypeMeta: metav1.TypeMeta{APIVersion: "admission.k8s.io/v1", Kind: "AdmissionReview"},
+		Request:  podRequest(t, "default", "legacy:1"),
+	}
+	body, err := json.Marshal(review)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/validate", bytes.NewReader(body)))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200", rec.Code)
+	}
+	var got admissionv1.AdmissionReview
+	if err := json.NewDecoder(rec.Body).Decode(&got); err != nil {
+		t.Fatalf("invalid response: %v", err)
+	}
+	if got.APIVersion != "admission.k8s.io/v1" || got.Kind != "AdmissionReview" || got.Request != nil ||
+		got.Response == nil || got.Response.UID != "uid-1" || got.Response.Allowed {
+		t.Errorf("unexpected review %+v", got)
+	}
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/validate", strings.NewReader(`{"kind": "AdmissionReview"}`)))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("review without request: status = %d, want 400", rec.Code)
+	}
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/bvboe/b2s-go/scanner-core/columns"
+	scannerconfig "github.com/bvboe/b2s-go/scanner-core/config"
+	"github.com/bvboe/b2s-go/scanner-core/database"
+	"github.com/bvboe/b2s-go/scanner-core/deployment"
+	"github.com/bvboe/b2s-go/scanner-core/eol"
+	"github.com/bvboe/b2s-go/scanner-core/grype"
+	corehandlers "github.com/bvboe/b2s-go/scanner-core/handlers"
+	"github.com/bvboe/b2s-go/scanner-core/labels"
+	"github.com/bvboe/b2s-go/scanner-core/logging"
+	"github.com/bvboe/b2s-go/scanner-core/policy"
+	"github.com/bvboe/b2s-go/scanner-core/routes"
+	"github.com/bvboe/b2s-go/scanner-core/vulndb"
+	"k8s.io/client-go/kubernetes"
+)
+
+// followerOpenRetryInterval is how often a follower retries opening a
+// database the leader has not created yet
+const followerOpenRetryInterval = 10 * time.Second
+
+// followerConfig is what a follower needs to serve the read-only API
+type followerConfig struct {
+	port         string
+	dbPath       string
+	cfg          *scannerconfig.Config
+	clientset    kubernetes.Interface
+	policy       policy.Evaluator
+	staticLabels labels.Set
+	columns      []columns.Column
+	auth         *corehandlers.Authenticator
+}
+
+// serveFollower serves the read-only API of a replica that does not hold the
+// write lease from the database the leader writes, until ctx is cancelled
+// because this replica acquired the lease. Scans, jobs, watchers, metrics and
+// the admission webhook only run on the leader, which the Service selects by
+// its k8s.RoleLabel; mutating requests are rejected.
+func serveFollower(ctx context.Context, fc followerConfig) {
+	log := logging.For(logging.ComponentK8s)
+
+	var db *database.DB
+	for {
+		var err error
+		if db, err = database.OpenReadOnly(fc.dbPath); err == nil {
+			break
+		}
+		log.Info("waiting for the leader to create the database", "error", err)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(followerOpenRetryInterval):
+		}
+	}
+	defer func() { _ = database.Close(db) }()
+
+	deploymentUUID, err := deployment.NewUUID(filepath.Dir(fc.dbPath))
+	if err != nil {
+		log.Error("failed to read deployment UUID", "error", err)
+		return
+	}
+	osLifecycle, err := eol.NewCatalog(fc.cfg.OSEOLWarningDays)
+	if err != nil {
+		log.Error("failed to load OS end-of-life data", "error", err)
+		return
+	}
+
+	resultCache, err := newResultCache(fc.cfg, deploymentUUID.String(), fc.staticLabels)
+	if err != nil {
+		log.Error("failed to initialize result cache", "error", err)
+		return
+	}
+	notifyRouter, err := newNotifyRouter(ctx, fc.cfg, fc.clientset)
+	if err != nil {
+		log.Error("failed to configure notification routing", "error", err)
+		return
+	}
+	// The leader prunes stored SBOMs; followers only report the volume
+	diskMonitor := newDiskMonitor(db, fc.dbPath, fc.cfg, false)
+	diskMonitor.Start(ctx)
+
+	servicePort := os.Getenv("SERVICE_PORT")
+	if servicePort == "" {
+		servicePort = "80"
+	}
+	infoProvider := NewK8sScanServerInfo(fc.port, fc.cfg.TLSCertFile != "", fc.cfg.WebUIEnabled, os.Getenv("CONSOLE_URL"), fc.clientset,
+		os.Getenv("SERVICE_NAME"), servicePort)
+	infoProvider.SetEffectiveConfig(fc.cfg)
+
+	// /ready only reports that the server is up; /api/db/status reports the
+	// grype database the leader keeps up to date
+	grypeDBPath := grypeDBRootDir(fc.dbPath)
+	dbReadinessState := corehandlers.NewDatabaseReadinessState(grype.Config{DBRootDir: grypeDBPath})
+	go followVulnerabilityDatabase(ctx, grypeDBPath, fc.cfg.JobsRescanDatabaseInterval, dbReadinessState)
+
+	reg := routes.NewRegistry(corehandlers.AuthMiddleware(fc.auth))
+	corehandlers.RegisterHandlers(reg, infoProvider, db)
+	corehandlers.RegisterAuthHandlers(reg, fc.auth)
+	corehandlers.RegisterDatabaseReadinessHandlers(reg, dbReadinessState)
+	apiOptions := sharedAPIOptions(fc.cfg, infoProvider.GetClusterName(), deploymentUUID.String(), fc.staticLabels, fc.policy, fc.columns)
+	apiOptions.FixHints = newFixHintFinder(db, fc.cfg, resultCache)
+	apiOptions.DiskUsage = diskMonitor
+	apiOptions.OSLifecycle = osLifecycle
+	apiOptions.NotifyRouter = notifyRouter
+	apiOptions.ReadOnly = true
+	corehandlers.RegisterAPIHandlers(reg, db, apiOptions)
+
+	tlsConfig, handler, err := serverTLS(fc.cfg, corehandlers.ReadOnlyMiddleware(corehandlers.CompressionMiddleware(reg)))
+	if err != nil {
+		log.Error("failed to load TLS certificate", "error", err)
+		return
+	}
+	server := &http.Server{
+		Addr:      ":" + fc.port,
+		Handler:   handler,
+		TLSConfig: tlsConfig,
+	}
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if err := server.Shutdown(shutdownCtx); err != nil {
+			log.Error("error shutting down read-only server", "error", err)
+		}
+	}()
+
+	log.Info("serving the read-only API until the write lease is acquired", "port", fc.port)
+	if err := listenAndServe(server); err != nil && err != http.ErrServerClosed {
+		log.Error("read-only server error", "error", err)
+		return
+	}
+	<-stopped
+}
+
+// followVulnerabilityDatabase reads the grype database the leader downloads
+// to the shared volume every interval until ctx is cancelled, without ever
+// updating it, and reports it to state
+func followVulnerabilityDatabase(ctx context.Context, grypeDBPath string, interval time.Duration, state *corehandlers.DatabaseReadinessState) {
+	log := logging.For(logging.ComponentK8s)
+
+	updater, err := vulndb.NewDatabaseUpdaterWithConfig(grypeDBPath, vulndb.DatabaseUpdaterConfig{ReadOnly: true})
+	if err != nil {
+		log.Warn("failed to create read-only vulnerability database updater", "error", err)
+		return
+	}
+	if interval <= 0 {
+		interval = followerOpenRetryInterval
+	}
+	for {
+		if _, err := updater.CheckForUpdates(ctx); err != nil {
+			log.Debug("vulnerability database not readable yet", "error", err)
+		} else if v := updater.GetCurrentVersion(); v != nil {
+			state.SetReady(&grype.DatabaseStatus{Available: true, Built: v.Built, SchemaVersion: v.SchemaVersion, Path: v.Path})
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(interval):
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/bvboe/b2s-go/scanner-core/metrics"
+	clientmetrics "k8s.io/client-go/tools/metrics"
+)
+
+// throttleLatency is how long the client-side rate limiter must delay a
+// request for it to count as throttled; client-go logs throttling from the
+// same delay on
+const throttleLatency = 50 * time.Millisecond
+
+// apiThrottling counts Kubernetes API requests delayed by the client-side
+// rate limiter (KUBE_API_QPS / KUBE_API_BURST) and requests the API server
+// rejected with 429 Too Many Requests, for the /metrics endpoint.
+type apiThrottling struct {
+	mu              sync.Mutex
+	clientThrottled int
+	clientWait      time.Duration
+	serverThrottled int
+}
+
+var apiThrottleStats = &apiThrottling{}
+
+func init() {
+	metrics.RegisterExtraWriter(apiThrottleStats.write)
+	clientmetrics.Register(clientmetrics.RegisterOpts{
+		RateLimiterLatency: rateLimiterLatency{apiThrottleStats},
+		RequestResult:      requestResult{apiThrottleStats},
+	})
+}
+
+// rateLimiterLatency receives the client-side rate limiter delay of every request
+type rateLimiterLatency struct{ stats *apiThrottling }
+
+func (l rateLimiterLatency) Observe(_ context.Context, _ string, _ url.URL, latency time.Duration) {
+	l.stats.observeWait(latency)
+}
+
+// requestResult receives the response code of every request
+type requestResult struct{ stats *apiThrottling }
+
+func (r requestResult) Increment(_ context.Context, code string, _ string, _ string) {
+	if code == "429" {
+		r.stats.serverThrottle()
+	}
+}
+
+func (s *apiThrottling) observeWait(latency time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.clientWait += latency
+	if latency > throttleLatency {
+		s.clientThrottled++
+	}
+}
+
+func (s *apiThrottling) serverThrottle() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.serverThrottled++
+}
+
+func (s *apiThrottling) write(w io.Writer) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, _ = fmt.Fprintf(w, "# HELP bjorn2scan_kube_api_throttled_requests_total Kubernetes API requests throttled by the client-side rate limiter (delayed over 50ms) or rejected by the API server with 429\n")
+	_, _ = fmt.Fprintf(w, "# TYPE bjorn2scan_kube_api_throttled_requests_total counter\n")
+	_, _ = fmt.Fprintf(w, "bjorn2scan_kube_api_throttled_requests_total{limiter=\"client\"} %d\n", s.clientThrottled)
+	_, _ = fmt.Fprintf(w, "bjorn2scan_kube_api_throttled_requests_total{limiter=\"server\"} %d\n", s.serverThrottled)
+	_, _ = fmt.Fprintf(w, "# HELP bjorn2scan_kube_api_rate_limiter_wait_seconds_total Time Kubernetes API requests waited for the client-side rate limiter\n")
+	_, _ = fmt.Fprintf(w, "# TYPE bjorn2scan_kube_api_rate_limiter_wait_seconds_total counter\n")
+	_, _ = fmt.Fprintf(w, "bjorn2scan_kube_api_rate_limiter_wait_seconds_total %g\n", s.clientWait.Seconds())
+}
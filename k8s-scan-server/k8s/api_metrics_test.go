@@ -0,0 +1,33 @@
+package k8s
+
+import (
+	"bytes"
+	"context"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAPIThrottling(t *testing.T) {
+	stats := &apiThrottling{}
+	limiter := rateLimiterLatency{stats}
+	result := requestResult{stats}
+
+	limiter.Observe(context.Background(), "GET", url.URL{}, time.Millisecond)
+	limiter.Observe(context.Background(), "GET", url.URL{}, 2*time.Second)
+	result.Increment(context.Background(), "200", "GET", "api")
+	result.Increment(context.Background(), "429", "GET", "api")
+
+	var buf bytes.Buffer
+	stats.write(&buf)
+	for _, want := range []string{
+		`bjorn2scan_kube_api_throttled_requests_total{limiter="client"} 1` + "\n",
+		`bjorn2scan_kube_api_throttled_requests_total{limiter="server"} 1` + "\n",
+		"bjorn2scan_kube_api_rate_limiter_wait_seconds_total 2.001\n",
+	} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("metrics missing %q:\n%s", want, buf.String())
+		}
+	}
+}
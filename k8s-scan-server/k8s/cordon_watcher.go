@@ -0,0 +1,96 @@
+package k8s
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+)
+
+// NodePauser pauses and resumes the scans on a node (implemented by
+// scanning.JobQueue)
+type NodePauser interface {
+	PauseNode(nodeName, reason string)
+	ResumeNode(nodeName string)
+}
+
+// Taints set on nodes about to be removed, besides the cordon itself
+var drainTaints = map[string]string{
+	"ToBeDeletedByClusterAutoscaler": "being removed by the cluster autoscaler",
+	"karpenter.sh/disrupted":         "being disrupted by Karpenter",
+	"karpenter.sh/disruption":        "being disrupted by Karpenter",
+}
+
+// cordonReason returns why scans on node should be paused, or "" if the node
+// is schedulable. kubectl drain cordons the node before evicting its pods.
+func cordonReason(node *corev1.Node) string {
+	for _, taint := range node.Spec.Taints {
+		if reason, ok := drainTaints[taint.Key]; ok {
+			return reason
+		}
+	}
+	if node.Spec.Unschedulable {
+		return "cordoned"
+	}
+	return ""
+}
+
+// WatchNodeCordons pauses the scans on cordoned and draining nodes, so no
+// SBOM requests are sent to pod-scanners that are about to be evicted, and
+// resumes them once the node is schedulable again. Runs whether or not host
+// scanning is enabled.
+func WatchNodeCordons(ctx context.Context, clientset kubernetes.Interface, pauser NodePauser) {
+	factory := informers.NewSharedInformerFactory(clientset, 5*time.Minute)
+	nodeInformer := factory.Core().V1().Nodes().Informer()
+	instrumentInformer("node_cordons", nodeInformer)
+
+	update := func(obj interface{}) {
+		node, ok := obj.(*corev1.Node)
+		if !ok {
+			log.Warn("unexpected object type in node event", "type", slog.Any("type", obj))
+			return
+		}
+		if reason := cordonReason(node); reason != "" {
+			pauser.PauseNode(node.Name, reason)
+		} else {
+			pauser.ResumeNode(node.Name)
+		}
+	}
+	_, err := nodeInformer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: update,
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			update(newObj)
+		},
+		DeleteFunc: func(obj interface{}) {
+			if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+				obj = tombstone.Obj
+			}
+			node, ok := obj.(*corev1.Node)
+			if !ok {
+				log.Warn("unexpected object type in node delete", "type", slog.Any("type", obj))
+				return
+			}
+			pauser.ResumeNode(node.Name)
+		},
+	})
+	if err != nil {
+		log.Error("failed to add node cordon event handler", slog.Any("error", err))
+		return
+	}
+
+	log.Info("starting node cordon informer")
+	go factory.Start(ctx.Done())
+
+	if !cache.WaitForCacheSync(ctx.Done(), nodeInformer.HasSynced) {
+		log.Error("failed to sync node cordon informer cache")
+		return
+	}
+	log.Info("node cordon informer cache synced")
+
+	<-ctx.Done()
+	log.Info("node cordon watcher shutting down")
+}
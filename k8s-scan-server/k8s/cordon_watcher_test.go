@@ -0,0 +1,95 @@
+package k8s
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+type recordingPauser struct {
+	mu     sync.Mutex
+	paused map[string]string
+}
+
+func (p *recordingPauser) PauseNode(nodeName, reason string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.paused[nodeName] = reason
+}
+
+func (p *recordingPauser) ResumeNode(nodeName string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	delete(p.paused, nodeName)
+}
+
+func (p *recordingPauser) reason(nodeName string) (string, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	reason, ok := p.paused[nodeName]
+	return reason, ok
+}
+
+func TestCordonReason(t *testing.T) {
+	tests := []struct {
+		name string
+		spec corev1.NodeSpec
+		want string
+	}{
+		{"schedulable", corev1.NodeSpec{}, ""},
+		{"cordoned", corev1.NodeSpec{Unschedulable: true}, "cordoned"},
+		{"cluster autoscaler", corev1.NodeSpec{Taints: []corev1.Taint{{Key: "ToBeDeletedByClusterAutoscaler", Effect: corev1.TaintEffectNoSchedule}}},
+			"being removed by the cluster autoscaler"},
+		{"other taint", corev1.NodeSpec{Taints: []corev1.Taint{{Key: "dedicated", Value: "gpu", Effect: corev1.TaintEffectNoSchedule}}}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := cordonReason(&corev1.Node{Spec: tt.spec}); got != tt.want {
+				t.Errorf("cordonReason() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWatchNodeCordons(t *testing.T) {
+	clientset := fake.NewClientset(
+		&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node-1"}, Spec: corev1.NodeSpec{Unschedulable: true}},
+		&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node-2"}},
+	)
+	pauser := &recordingPauser{paused: make(map[string]string)}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	go WatchNodeCordons(ctx, clientset, pauser)
+
+	waitFor := func(nodeName string, wantPaused bool) {
+		t.Helper()
+		for ctx.Err() == nil {
+			if _, paused := pauser.reason(nodeName); paused == wantPaused {
+				return
+			}
+			time.Sleep(20 * time.Millisecond)
+		}
+		t.Fatalf("%s paused state never became %v", nodeName, wantPaused)
+	}
+	waitFor("node-1", true)
+	if _, paused := pauser.reason("node-2"); paused {
+		t.Error("schedulable node-2 was paused")
+	}
+
+	// Uncordoning resumes the node
+	node, err := clientset.CoreV1().Nodes().Get(ctx, "node-1", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	node.Spec.Unschedulable = false
+	if _, err := clientset.CoreV1().Nodes().Update(ctx, node, metav1.UpdateOptions{}); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	waitFor("node-1", false)
+}
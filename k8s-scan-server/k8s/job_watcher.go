@@ -0,0 +1,125 @@
+package k8s
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/bvboe/b2s-go/scanner-core/database"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+)
+
+// jobNameLabel is set by the Job controller on every pod it creates
+const jobNameLabel = "job-name"
+
+// ObservedImageRecorder persists image digests seen in Job pods so that scan
+// coverage includes workloads which are no longer running
+type ObservedImageRecorder interface {
+	RecordObservedImages(images []database.ObservedImage) error
+	PruneObservedImages(before time.Time) (int64, error)
+}
+
+// jobOwnerName returns the name of the Job owning the pod, or "" if the pod
+// was not created by a Job
+func jobOwnerName(pod *corev1.Pod) string {
+	for _, ref := range pod.OwnerReferences {
+		if ref.Kind == "Job" {
+			return ref.Name
+		}
+	}
+	return ""
+}
+
+// extractJobImages returns the observed image digests of a Job pod.
+// Completed pods keep their container statuses, so digests remain available
+// after the Job has finished.
+func extractJobImages(pod *corev1.Pod, now time.Time) []database.ObservedImage {
+	jobName := jobOwnerName(pod)
+	if jobName == "" {
+		return nil
+	}
+
+	var result []database.ObservedImage
+	for _, c := range extractContainers(pod) {
+		result = append(result, database.ObservedImage{
+			Digest:    c.Image.Digest,
+			Reference: c.Image.Reference,
+			Namespace: pod.Namespace,
+			Workload:  "job/" + jobName,
+			SeenAt:    now,
+		})
+	}
+	return result
+}
+
+// WatchJobPods watches pods created by Kubernetes Jobs and records their image
+// digests, including those of Jobs that ran to completion. Records older than
+// the lookback window are pruned periodically.
+func WatchJobPods(ctx context.Context, clientset kubernetes.Interface, recorder ObservedImageRecorder, lookback time.Duration) {
+	resyncPeriod := 5 * time.Minute
+	factory := informers.NewSharedInformerFactoryWithOptions(clientset, resyncPeriod,
+		informers.WithTweakListOptions(func(opts *metav1.ListOptions) {
+			opts.LabelSelector = jobNameLabel
+		}))
+
+	podInformer := factory.Core().V1().Pods().Informer()
+	instrumentInformer("job_pods", podInformer)
+
+	record := func(obj interface{}) {
+		pod, ok := obj.(*corev1.Pod)
+		if !ok {
+			log.Warn("unexpected object type in job pod event", "type", slog.Any("type", obj))
+			return
+		}
+		images := extractJobImages(pod, time.Now())
+		if len(images) == 0 {
+			return
+		}
+		if err := recorder.RecordObservedImages(images); err != nil {
+			log.Error("failed to record job pod images",
+				"namespace", pod.Namespace, "pod", pod.Name, "error", err)
+		}
+	}
+
+	_, err := podInformer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: record,
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			record(newObj)
+		},
+	})
+	if err != nil {
+		log.Error("failed to add job pod event handler", slog.Any("error", err))
+		return
+	}
+
+	log.Info("starting job pod informer", "lookback", lookback)
+	go factory.Start(ctx.Done())
+
+	if !cache.WaitForCacheSync(ctx.Done(), podInformer.HasSynced) {
+		log.Error("failed to sync job pod informer cache")
+		return
+	}
+	log.Info("job pod informer cache synced and ready")
+
+	ticker := time.NewTicker(resyncPeriod)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			log.Info("job pod watcher shutting down")
+			return
+		case <-ticker.C:
+			pruned, err := recorder.PruneObservedImages(time.Now().Add(-lookback))
+			if err != nil {
+				log.Error("failed to prune observed job images", "error", err)
+			} else if pruned > 0 {
+				log.Debug("pruned observed job images", "count", pruned)
+			}
+		}
+	}
+}
@@ -0,0 +1,51 @@
+package k8s
+
+import (
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestExtractJobImages(t *testing.T) {
+	now := time.Now()
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "backup-28391-abcde",
+			Namespace: "ops",
+			Labels:    map[string]string{jobNameLabel: "backup-28391"},
+			OwnerReferences: []metav1.OwnerReference{
+				{Kind: "Job", Name: "backup-28391"},
+			},
+		},
+		Spec: corev1.PodSpec{
+			Containers: []corev1.Container{{Name: "backup", Image: "restic:0.16"}},
+		},
+		Status: corev1.PodStatus{
+			Phase: corev1.PodSucceeded,
+			ContainerStatuses: []corev1.ContainerStatus{{
+				Name:        "backup",
+				ImageID:     "docker.io/library/restic@sha256:abc123",
+				ContainerID: "containerd://c1",
+			}},
+		},
+	}
+
+	images := extractJobImages(pod, now)
+	if len(images) != 1 {
+		t.Fatalf("expected 1 image, got %d", len(images))
+	}
+	img := images[0]
+	if img.Digest != "sha256:abc123" || img.Reference != "restic:0.16" {
+		t.Errorf("unexpected image %+v", img)
+	}
+	if img.Workload != "job/backup-28391" || img.Namespace != "ops" || !img.SeenAt.Equal(now) {
+		t.Errorf("unexpected workload metadata %+v", img)
+	}
+
+	pod.OwnerReferences = nil
+	if images := extractJobImages(pod, now); len(images) != 0 {
+		t.Errorf("expected no images for pod without Job owner, got %d", len(images))
+	}
+}
@@ -0,0 +1,175 @@
+package k8s
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync/atomic"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/leaderelection"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
+)
+
+// RoleLabel is the pod label that tells the leader apart from the followers,
+// so the Service can send writes, metrics scrapes and admission reviews to
+// the replica that serves them
+const RoleLabel = "bjorn2scan.io/role"
+
+// Values of RoleLabel
+const (
+	RoleLeader   = "leader"
+	RoleFollower = "follower"
+)
+
+// LeaseConfig identifies the write lease of a highly available deployment and
+// the replica competing for it
+type LeaseConfig struct {
+	Namespace string
+	Name      string
+	Identity  string // pod name; a restarted container takes its lease back at once
+	// LeaseDuration is how long a leader that stopped renewing keeps the
+	// lease; renewals happen well within it
+	LeaseDuration time.Duration
+	// OnLost is called when the lease is lost without being released (default:
+	// exit, as another replica may already be writing the database)
+	OnLost func()
+}
+
+// WriteLease is the Kubernetes Lease that elects the one k8s-scan-server
+// replica allowed to write the shared SQLite database: the leader scans and
+// runs the jobs, the other replicas serve the read-only API until they
+// acquire the lease.
+type WriteLease struct {
+	elector  *leaderelection.LeaderElector
+	cfg      LeaseConfig
+	acquired chan struct{}
+	stopped  chan struct{}
+	cancel   context.CancelFunc
+	released atomic.Bool
+}
+
+// NewWriteLease creates the lease; nothing happens until Acquire
+func NewWriteLease(client kubernetes.Interface, cfg LeaseConfig) (*WriteLease, error) {
+	if cfg.Namespace == "" || cfg.Name == "" || cfg.Identity == "" {
+		return nil, fmt.Errorf("write lease needs a namespace, name and identity")
+	}
+	if cfg.OnLost == nil {
+		cfg.OnLost = func() {
+			log.Error("fatal: lost the write lease, exiting so another replica can take over")
+			os.Exit(1)
+		}
+	}
+
+	l := &WriteLease{
+		cfg:      cfg,
+		acquired: make(chan struct{}),
+		stopped:  make(chan struct{}),
+	}
+	elector, err := leaderelection.NewLeaderElector(leaderelection.LeaderElectionConfig{
+		Lock: &resourcelock.LeaseLock{
+			LeaseMeta:  metav1.ObjectMeta{Namespace: cfg.Namespace, Name: cfg.Name},
+			Client:     client.CoordinationV1(),
+			LockConfig: resourcelock.ResourceLockConfig{Identity: cfg.Identity},
+		},
+		LeaseDuration:   cfg.LeaseDuration,
+		RenewDeadline:   cfg.LeaseDuration * 2 / 3,
+		RetryPeriod:     cfg.LeaseDuration / 7,
+		ReleaseOnCancel: true,
+		Name:            cfg.Name,
+		Callbacks: leaderelection.LeaderCallbacks{
+			OnStartedLeading: func(context.Context) {
+				log.Info("acquired the write lease", "lease", cfg.Name, "identity", cfg.Identity)
+				close(l.acquired)
+			},
+			// Run only stops before Release once the lease is lost
+			OnStoppedLeading: func() {
+				if !l.released.Load() {
+					cfg.OnLost()
+				}
+			},
+			OnNewLeader: func(identity string) {
+				if identity != cfg.Identity {
+					log.Info("another replica holds the write lease", "lease", cfg.Name, "leader", identity)
+				}
+			},
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("invalid write lease configuration: %w", err)
+	}
+	l.elector = elector
+	return l, nil
+}
+
+// Acquire blocks until this replica holds the lease, running follow until
+// then. follow's context is cancelled when the lease is acquired, and Acquire
+// waits for follow to return before it does. The lease is renewed until
+// Release; Acquire returns ctx's error if ctx ends first.
+func (l *WriteLease) Acquire(ctx context.Context, follow func(ctx context.Context)) error {
+	runCtx, cancel := context.WithCancel(context.Background())
+	l.cancel = cancel
+	go func() {
+		defer close(l.stopped)
+		l.elector.Run(runCtx)
+	}()
+
+	followCtx, stopFollowing := context.WithCancel(ctx)
+	followed := make(chan struct{})
+	go func() {
+		defer close(followed)
+		follow(followCtx)
+	}()
+
+	var err error
+	select {
+	case <-l.acquired:
+	case <-ctx.Done():
+		err = ctx.Err()
+		l.Release()
+	}
+	stopFollowing()
+	<-followed
+	return err
+}
+
+// Release gives up the lease so another replica can take over without
+// waiting for it to expire. Call it once this replica stopped writing.
+func (l *WriteLease) Release() {
+	if l.cancel == nil || !l.released.CompareAndSwap(false, true) {
+		return
+	}
+	l.cancel()
+	<-l.stopped
+	if l.holds() {
+		log.Info("released the write lease", "lease", l.cfg.Name)
+	}
+}
+
+// holds reports whether this replica acquired the lease
+func (l *WriteLease) holds() bool {
+	select {
+	case <-l.acquired:
+		return true
+	default:
+		return false
+	}
+}
+
+// SetPodRole sets RoleLabel on a pod to role (RoleLeader or RoleFollower)
+func SetPodRole(ctx context.Context, client kubernetes.Interface, namespace, pod, role string) error {
+	patch, err := json.Marshal(map[string]any{
+		"metadata": map[string]any{"labels": map[string]string{RoleLabel: role}},
+	})
+	if err != nil {
+		return err
+	}
+	if _, err := client.CoreV1().Pods(namespace).Patch(ctx, pod, types.MergePatchType, patch, metav1.PatchOptions{}); err != nil {
+		return fmt.Errorf("failed to label pod %s/%s as %s: %w", namespace, pod, role, err)
+	}
+	return nil
+}
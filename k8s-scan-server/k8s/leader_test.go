@@ -0,0 +1,120 @@
+package k8s
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestWriteLease(t *testing.T) {
+	clientset := fake.NewClientset()
+	var lost atomic.Int32
+	newLease := func(identity string) *WriteLease {
+		l, err := NewWriteLease(clientset, LeaseConfig{
+			Namespace:     "bjorn2scan",
+			Name:          "scan-server",
+			Identity:      identity,
+			LeaseDuration: 3 * time.Second, // long enough that a loaded test machine renews in time
+			OnLost:        func() { lost.Add(1) },
+		})
+		if err != nil {
+			t.Fatalf("NewWriteLease() error = %v", err)
+		}
+		return l
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	leader := newLease("scan-server-a")
+	if err := leader.Acquire(ctx, func(ctx context.Context) { <-ctx.Done() }); err != nil {
+		t.Fatalf("Acquire() error = %v", err)
+	}
+	lease, err := clientset.CoordinationV1().Leases("bjorn2scan").Get(ctx, "scan-server", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("lease not created: %v", err)
+	}
+	if holder := lease.Spec.HolderIdentity; holder == nil || *holder != "scan-server-a" {
+		t.Errorf("lease holder = %v, want scan-server-a", holder)
+	}
+
+	// The follower serves until the leader releases the lease
+	following := make(chan struct{})
+	acquired := make(chan error, 1)
+	follower := newLease("scan-server-b")
+	go func() {
+		acquired <- follower.Acquire(ctx, func(ctx context.Context) {
+			close(following)
+			<-ctx.Done()
+		})
+	}()
+	<-following
+	select {
+	case err := <-acquired:
+		t.Fatalf("follower acquired a held lease: %v", err)
+	case <-time.After(1500 * time.Millisecond):
+	}
+
+	leader.Release()
+	if err := <-acquired; err != nil {
+		t.Fatalf("follower Acquire() error = %v", err)
+	}
+	follower.Release()
+	if lost.Load() != 0 {
+		t.Errorf("OnLost called %d times for released leases", lost.Load())
+	}
+
+	// Giving up while following returns the context's error
+	waiting := newLease("scan-server-c")
+	holder := newLease("scan-server-d")
+	if err := holder.Acquire(ctx, func(context.Context) {}); err != nil {
+		t.Fatalf("Acquire() error = %v", err)
+	}
+	defer holder.Release()
+	shortCtx, shortCancel := context.WithTimeout(ctx, 200*time.Millisecond)
+	defer shortCancel()
+	if err := waiting.Acquire(shortCtx, func(ctx context.Context) { <-ctx.Done() }); err != context.DeadlineExceeded {
+		t.Errorf("Acquire() with an expired context = %v, want DeadlineExceeded", err)
+	}
+}
+
+func TestNewWriteLeaseValidation(t *testing.T) {
+	clientset := fake.NewClientset()
+	if _, err := NewWriteLease(clientset, LeaseConfig{Namespace: "ns", Name: "lease", LeaseDuration: time.Second}); err == nil {
+		t.Error("NewWriteLease() without an identity succeeded")
+	}
+	if _, err := NewWriteLease(clientset, LeaseConfig{Namespace: "ns", Name: "lease", Identity: "a"}); err == nil {
+		t.Error("NewWriteLease() without a lease duration succeeded")
+	}
+}
+
+func TestSetPodRole(t *testing.T) {
+	ctx := context.Background()
+	clientset := fake.NewClientset(&corev1.Pod{ObjectMeta: metav1.ObjectMeta{
+		Namespace: "bjorn2scan",
+		Name:      "scan-server-a",
+		Labels:    map[string]string{"app.kubernetes.io/component": "scan-server", RoleLabel: RoleLeader},
+	}})
+
+	if err := SetPodRole(ctx, clientset, "bjorn2scan", "scan-server-a", RoleFollower); err != nil {
+		t.Fatalf("SetPodRole() error = %v", err)
+	}
+	pod, err := clientset.CoreV1().Pods("bjorn2scan").Get(ctx, "scan-server-a", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if got := pod.Labels[RoleLabel]; got != RoleFollower {
+		t.Errorf("%s = %q, want %q", RoleLabel, got, RoleFollower)
+	}
+	if got := pod.Labels["app.kubernetes.io/component"]; got != "scan-server" {
+		t.Errorf("other labels changed: component = %q", got)
+	}
+
+	if err := SetPodRole(ctx, clientset, "bjorn2scan", "missing", RoleLeader); err == nil {
+		t.Error("SetPodRole() on a missing pod succeeded")
+	}
+}
@@ -0,0 +1,143 @@
+package k8s
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/bvboe/b2s-go/scanner-core/containers"
+	"github.com/bvboe/b2s-go/scanner-core/notify"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+)
+
+// WatchNamespaces watches namespace annotations and keeps the notification
+// routes of router current, so changing a namespace's bjorn2scan.io/notify
+// annotation reroutes its alerts without a restart. Deleted namespaces fall
+// back to the default destinations.
+func WatchNamespaces(ctx context.Context, clientset kubernetes.Interface, router *notify.Router) {
+	factory := informers.NewSharedInformerFactory(clientset, 5*time.Minute)
+	namespaceInformer := factory.Core().V1().Namespaces().Informer()
+	instrumentInformer("namespaces", namespaceInformer)
+
+	update := func(obj interface{}) {
+		ns, ok := obj.(*corev1.Namespace)
+		if !ok {
+			log.Warn("unexpected object type in namespace event", "type", slog.Any("type", obj))
+			return
+		}
+		router.SetNamespace(ns.Name, ns.Annotations)
+	}
+	_, err := namespaceInformer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: update,
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			update(newObj)
+		},
+		DeleteFunc: func(obj interface{}) {
+			if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+				obj = tombstone.Obj
+			}
+			ns, ok := obj.(*corev1.Namespace)
+			if !ok {
+				log.Warn("unexpected object type in namespace delete", "type", slog.Any("type", obj))
+				return
+			}
+			router.RemoveNamespace(ns.Name)
+		},
+	})
+	if err != nil {
+		log.Error("failed to add namespace event handler", slog.Any("error", err))
+		return
+	}
+
+	log.Info("starting namespace informer")
+	go factory.Start(ctx.Done())
+
+	if !cache.WaitForCacheSync(ctx.Done(), namespaceInformer.HasSynced) {
+		log.Error("failed to sync namespace informer cache")
+		return
+	}
+	log.Info("namespace informer cache synced", "routed_namespaces", len(router.Routes()))
+
+	<-ctx.Done()
+	log.Info("namespace watcher shutting down")
+}
+
+// WatchNamespaceDeletions removes the containers of a namespace in bulk as soon
+// as the namespace starts terminating. Kubernetes then deletes its pods one by
+// one; without this every pod deletion would update the database and flap the
+// summaries, while now those deletions find nothing left to remove. Like
+// terminating pods, a terminating namespace's containers are dropped before
+// they have actually stopped.
+func WatchNamespaceDeletions(ctx context.Context, clientset kubernetes.Interface, manager *containers.Manager) {
+	factory := informers.NewSharedInformerFactory(clientset, 5*time.Minute)
+	namespaceInformer := factory.Core().V1().Namespaces().Informer()
+	instrumentInformer("namespace_deletions", namespaceInformer)
+
+	// Namespaces already removed, so resyncs of a namespace that takes a while
+	// to terminate don't remove it again
+	removed := make(map[string]bool)
+	remove := func(ns *corev1.Namespace) {
+		if removed[ns.Name] {
+			return
+		}
+		removed[ns.Name] = true
+		start := time.Now()
+		count := manager.RemoveNamespace(ns.Name)
+		namespaceDeletionStats.record(count)
+		log.Info("namespace deleted, containers removed",
+			"namespace", ns.Name, "containers", count, "duration", time.Since(start))
+	}
+
+	_, err := namespaceInformer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			// Namespaces already terminating when the watcher starts
+			if ns, ok := obj.(*corev1.Namespace); ok && ns.DeletionTimestamp != nil {
+				remove(ns)
+			}
+		},
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			ns, ok := newObj.(*corev1.Namespace)
+			if !ok {
+				log.Warn("unexpected object type in namespace update", "type", slog.Any("type", newObj))
+				return
+			}
+			if ns.DeletionTimestamp != nil {
+				remove(ns)
+			}
+		},
+		DeleteFunc: func(obj interface{}) {
+			if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+				obj = tombstone.Obj
+			}
+			ns, ok := obj.(*corev1.Namespace)
+			if !ok {
+				log.Warn("unexpected object type in namespace delete", "type", slog.Any("type", obj))
+				return
+			}
+			// Deleted without a terminating update being seen (e.g. while the
+			// watch was reconnecting); a namespace of the same name may be
+			// created again
+			remove(ns)
+			delete(removed, ns.Name)
+		},
+	})
+	if err != nil {
+		log.Error("failed to add namespace deletion event handler", slog.Any("error", err))
+		return
+	}
+
+	log.Info("starting namespace deletion informer")
+	go factory.Start(ctx.Done())
+
+	if !cache.WaitForCacheSync(ctx.Done(), namespaceInformer.HasSynced) {
+		log.Error("failed to sync namespace deletion informer cache")
+		return
+	}
+
+	<-ctx.Done()
+	log.Info("namespace deletion watcher shutting down")
+}
@@ -0,0 +1,108 @@
+package k8s
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/bvboe/b2s-go/scanner-core/containers"
+	"github.com/bvboe/b2s-go/scanner-core/notify"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestWatchNamespaces(t *testing.T) {
+	clientset := fake.NewClientset(&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{
+		Name:        "team-foo",
+		Annotations: map[string]string{notify.AnnotationKey: "slack:#team-foo"},
+	}})
+	router := notify.NewRouter([]notify.Destination{{Kind: notify.KindSlack, Target: "#security"}})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	go WatchNamespaces(ctx, clientset, router)
+
+	waitForRoute := func(namespace, want string) {
+		t.Helper()
+		for ctx.Err() == nil {
+			if got := router.Destinations(namespace); len(got) == 1 && got[0].String() == want {
+				return
+			}
+			time.Sleep(20 * time.Millisecond)
+		}
+		t.Fatalf("%s never routed to %s, got %v", namespace, want, router.Destinations(namespace))
+	}
+	waitForRoute("team-foo", "slack:#team-foo")
+
+	// Annotation changes are picked up without a restart
+	ns, err := clientset.CoreV1().Namespaces().Get(ctx, "team-foo", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	ns.Annotations[notify.AnnotationKey] = "slack:#team-foo-alerts"
+	if _, err := clientset.CoreV1().Namespaces().Update(ctx, ns, metav1.UpdateOptions{}); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	waitForRoute("team-foo", "slack:#team-foo-alerts")
+
+	// Deleted namespaces fall back to the default
+	if err := clientset.CoreV1().Namespaces().Delete(ctx, "team-foo", metav1.DeleteOptions{}); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	waitForRoute("team-foo", "slack:#security")
+}
+
+func TestWatchNamespaceDeletions(t *testing.T) {
+	clientset := fake.NewClientset(
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "team-a"}},
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "team-b"}},
+	)
+	manager := containers.NewManager()
+	for _, id := range []containers.ContainerID{
+		{Namespace: "team-a", Pod: "web-1", Name: "app"},
+		{Namespace: "team-a", Pod: "web-2", Name: "app"},
+		{Namespace: "team-b", Pod: "api-1", Name: "app"},
+	} {
+		manager.AddContainer(containers.Container{ID: id, Image: containers.ImageID{Reference: "nginx:1.21", Digest: "sha256:abc123"}})
+	}
+
+	namespaceDeletionStats.mu.Lock()
+	deletionsBefore := namespaceDeletionStats.total
+	namespaceDeletionStats.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	go WatchNamespaceDeletions(ctx, clientset, manager)
+
+	// The containers are removed as soon as the namespace starts terminating
+	ns, err := clientset.CoreV1().Namespaces().Get(ctx, "team-a", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	now := metav1.Now()
+	ns.DeletionTimestamp = &now
+	ns.Status.Phase = corev1.NamespaceTerminating
+	if _, err := clientset.CoreV1().Namespaces().Update(ctx, ns, metav1.UpdateOptions{}); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	for ctx.Err() == nil && manager.GetContainerCount() != 1 {
+		time.Sleep(20 * time.Millisecond)
+	}
+	if _, ok := manager.GetContainer("team-b", "api-1", "app"); !ok || manager.GetContainerCount() != 1 {
+		t.Fatalf("containers after namespace deletion = %v", manager.GetAllContainers())
+	}
+
+	// The final deletion doesn't count the namespace again
+	if err := clientset.CoreV1().Namespaces().Delete(ctx, "team-a", metav1.DeleteOptions{}); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	time.Sleep(100 * time.Millisecond)
+	namespaceDeletionStats.mu.Lock()
+	deletions := namespaceDeletionStats.total - deletionsBefore
+	namespaceDeletionStats.mu.Unlock()
+	if deletions != 1 {
+		t.Errorf("namespace deletions recorded = %d, want 1", deletions)
+	}
+}
@@ -79,6 +79,7 @@ func WatchNodes(ctx context.Context, clientset kubernetes.Interface, manager *no
 
 	// Get the node informer
 	nodeInformer := factory.Core().V1().Nodes().Informer()
+	instrumentInformer("nodes", nodeInformer)
 
 	// Add event handlers
 	log := log
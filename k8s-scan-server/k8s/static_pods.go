@@ -0,0 +1,151 @@
+package k8s
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// mirrorPodAnnotation is set by the kubelet on the API server copy (mirror
+// pod) of a static pod
+const mirrorPodAnnotation = "kubernetes.io/config.mirror"
+
+const (
+	// staticPodResolveTimeout bounds one digest lookup through a pod-scanner
+	staticPodResolveTimeout = time.Minute
+	// staticPodRetryInterval is how long a failed lookup waits before a pod
+	// event (at the latest the informer resync) may retry it
+	staticPodRetryInterval = 5 * time.Minute
+)
+
+// ImageDigestResolver returns the digest of an image in the container runtime
+// of a node
+type ImageDigestResolver func(ctx context.Context, nodeName, reference string) (string, error)
+
+// isStaticPod reports whether pod mirrors a static pod, which the kubelet runs
+// from a manifest on its node rather than a controller (e.g. kube-apiserver,
+// etcd and kube-scheduler on control-plane nodes). Mirror pods are owned by
+// their Node.
+func isStaticPod(pod *corev1.Pod) bool {
+	if _, ok := pod.Annotations[mirrorPodAnnotation]; ok {
+		return true
+	}
+	for _, owner := range pod.OwnerReferences {
+		if owner.Kind == "Node" {
+			return true
+		}
+	}
+	return false
+}
+
+// nodeImage identifies an image reference on one node
+type nodeImage struct {
+	node      string
+	reference string
+}
+
+// staticPodImages resolves the image digests static pods don't report in
+// their container statuses through the runtime of their node, so their
+// containers are tracked and scanned like any other. Resolved digests are
+// cached per node and image reference.
+type staticPodImages struct {
+	mu       sync.Mutex
+	resolver ImageDigestResolver
+	digests  map[nodeImage]string
+	inFlight map[nodeImage]bool
+	failedAt map[nodeImage]time.Time
+}
+
+var staticPods = &staticPodImages{
+	digests:  make(map[nodeImage]string),
+	inFlight: make(map[nodeImage]bool),
+	failedAt: make(map[nodeImage]time.Time),
+}
+
+// ResolveStaticPodImages makes the pod watcher look up the image digests
+// static pods don't report through resolver. Without it, such containers
+// are skipped until their status has an image ID. Call it before WatchPods.
+func ResolveStaticPodImages(resolver ImageDigestResolver) {
+	staticPods.mu.Lock()
+	defer staticPods.mu.Unlock()
+	staticPods.resolver = resolver
+}
+
+// digest returns the resolved digest of an image on a node, or ""
+func (s *staticPodImages) digest(node, reference string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.digests[nodeImage{node, reference}]
+}
+
+// resolveMissing looks up, in the background, the digests of the containers
+// of a running static pod that extractContainers skipped for lack of one.
+// readd is called after each digest that was resolved, to add the pod again.
+func (s *staticPodImages) resolveMissing(pod *corev1.Pod, readd func()) {
+	if !isStaticPod(pod) || !isPodActive(pod) || pod.Spec.NodeName == "" {
+		return
+	}
+
+	tracked := make(map[string]bool)
+	for _, c := range extractContainers(pod) {
+		tracked[c.ID.Name] = true
+	}
+	allContainers := append([]corev1.Container{}, pod.Spec.Containers...)
+	allContainers = append(allContainers, pod.Spec.InitContainers...)
+	for _, container := range allContainers {
+		if tracked[container.Name] || container.Image == "" {
+			continue
+		}
+		key := nodeImage{pod.Spec.NodeName, container.Image}
+		resolver := s.start(key)
+		if resolver == nil {
+			continue
+		}
+		log.Info("resolving image digest of static pod through pod-scanner",
+			"namespace", pod.Namespace, "pod", pod.Name, "container", container.Name,
+			"image", container.Image, "node", key.node)
+		go func() {
+			ctx, cancel := context.WithTimeout(context.Background(), staticPodResolveTimeout)
+			defer cancel()
+			digest, err := resolver(ctx, key.node, key.reference)
+			if s.finish(key, digest, err) {
+				readd()
+			}
+		}()
+	}
+}
+
+// start claims the lookup of key, returning the resolver to use or nil when
+// no lookup is needed: no resolver is set, the digest is known or being
+// resolved, or the last lookup failed recently
+func (s *staticPodImages) start(key nodeImage) ImageDigestResolver {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.resolver == nil || s.digests[key] != "" || s.inFlight[key] {
+		return nil
+	}
+	if failed, ok := s.failedAt[key]; ok && time.Since(failed) < staticPodRetryInterval {
+		return nil
+	}
+	s.inFlight[key] = true
+	return s.resolver
+}
+
+// finish records the result of a lookup, reporting whether a digest was resolved
+func (s *staticPodImages) finish(key nodeImage, digest string, err error) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.inFlight, key)
+	if err != nil || digest == "" {
+		log.Warn("failed to resolve image digest of static pod",
+			"image", key.reference, "node", key.node, "error", err)
+		s.failedAt[key] = time.Now()
+		return false
+	}
+	delete(s.failedAt, key)
+	s.digests[key] = digest
+	log.Info("resolved image digest of static pod", "image", key.reference, "node", key.node, "digest", digest)
+	return true
+}
@@ -0,0 +1,149 @@
+package k8s
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// newStaticPod returns a running kube-apiserver mirror pod whose container
+// status has no image ID
+func newStaticPod() *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "kube-apiserver-cp1",
+			Namespace:   "kube-system",
+			Annotations: map[string]string{mirrorPodAnnotation: "abc"},
+			OwnerReferences: []metav1.OwnerReference{
+				{APIVersion: "v1", Kind: "Node", Name: "cp1"},
+			},
+		},
+		Spec: corev1.PodSpec{
+			NodeName:   "cp1",
+			Containers: []corev1.Container{{Name: "kube-apiserver", Image: "registry.k8s.io/kube-apiserver:v1.30.0"}},
+		},
+		Status: corev1.PodStatus{
+			Phase: corev1.PodRunning,
+			ContainerStatuses: []corev1.ContainerStatus{
+				{Name: "kube-apiserver", ContainerID: "containerd://abc"},
+			},
+		},
+	}
+}
+
+// useStaticPodImages swaps the static pod digest cache for the test
+func useStaticPodImages(t *testing.T, resolver ImageDigestResolver) *staticPodImages {
+	t.Helper()
+	saved := staticPods
+	staticPods = &staticPodImages{
+		resolver: resolver,
+		digests:  make(map[nodeImage]string),
+		inFlight: make(map[nodeImage]bool),
+		failedAt: make(map[nodeImage]time.Time),
+	}
+	t.Cleanup(func() { staticPods = saved })
+	return staticPods
+}
+
+func TestIsStaticPod(t *testing.T) {
+	if !isStaticPod(newStaticPod()) {
+		t.Error("mirror pod not detected as static")
+	}
+
+	ownedByNode := newStaticPod()
+	ownedByNode.Annotations = nil
+	if !isStaticPod(ownedByNode) {
+		t.Error("pod owned by a Node not detected as static")
+	}
+
+	regular := newStaticPod()
+	regular.Annotations = nil
+	regular.OwnerReferences = []metav1.OwnerReference{{APIVersion: "apps/v1", Kind: "ReplicaSet", Name: "web"}}
+	if isStaticPod(regular) {
+		t.Error("ReplicaSet pod detected as static")
+	}
+}
+
+func TestStaticPodResolveMissing(t *testing.T) {
+	var lookups int
+	resolved := make(chan struct{}, 1)
+	s := useStaticPodImages(t, func(ctx context.Context, nodeName, reference string) (string, error) {
+		lookups++
+		if nodeName != "cp1" || reference != "registry.k8s.io/kube-apiserver:v1.30.0" {
+			t.Errorf("resolver called with (%q, %q)", nodeName, reference)
+		}
+		return "sha256:apiserver", nil
+	})
+
+	pod := newStaticPod()
+	if got := extractContainers(pod); len(got) != 0 {
+		t.Fatalf("extractContainers() before resolving = %v, want none", got)
+	}
+
+	s.resolveMissing(pod, func() { resolved <- struct{}{} })
+	select {
+	case <-resolved:
+	case <-time.After(5 * time.Second):
+		t.Fatal("pod not re-added after its digest was resolved")
+	}
+
+	got := extractContainers(pod)
+	if len(got) != 1 || got[0].Image.Digest != "sha256:apiserver" {
+		t.Fatalf("extractContainers() after resolving = %v, want the kube-apiserver container with the resolved digest", got)
+	}
+
+	// Known digests are not looked up again
+	s.resolveMissing(pod, func() { t.Error("pod re-added without a new digest") })
+	if lookups != 1 {
+		t.Errorf("resolver called %d times, want 1", lookups)
+	}
+}
+
+func TestStaticPodResolveMissingFailure(t *testing.T) {
+	calls := make(chan struct{}, 2)
+	s := useStaticPodImages(t, func(ctx context.Context, nodeName, reference string) (string, error) {
+		calls <- struct{}{}
+		return "", errors.New("no pod-scanner on node")
+	})
+
+	pod := newStaticPod()
+	s.resolveMissing(pod, func() { t.Error("pod re-added after a failed lookup") })
+	<-calls
+	key := nodeImage{"cp1", "registry.k8s.io/kube-apiserver:v1.30.0"}
+	for deadline := time.Now().Add(5 * time.Second); ; {
+		s.mu.Lock()
+		_, failed := s.failedAt[key]
+		s.mu.Unlock()
+		if failed {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("failed lookup not recorded")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// A failed lookup is not retried before the retry interval
+	s.resolveMissing(pod, func() {})
+	select {
+	case <-calls:
+		t.Error("failed lookup retried immediately")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	// Regular pods are never looked up
+	regular := newStaticPod()
+	regular.Annotations = nil
+	regular.OwnerReferences = nil
+	regular.Spec.NodeName = "worker1"
+	s.resolveMissing(regular, func() {})
+	select {
+	case <-calls:
+		t.Error("resolver called for a regular pod")
+	case <-time.After(100 * time.Millisecond):
+	}
+}
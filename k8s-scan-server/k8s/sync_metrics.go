@@ -0,0 +1,246 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/bvboe/b2s-go/scanner-core/metrics"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/client-go/tools/cache"
+)
+
+// syncStats tracks progress of SyncInitialPods for the /metrics endpoint.
+type syncStats struct {
+	mu         sync.Mutex
+	started    bool
+	inProgress bool
+	pages      int
+	pods       int
+	containers int
+	failures   int
+	duration   time.Duration
+}
+
+var initialSync = &syncStats{}
+
+// imageChanges counts containers that restarted in place with a new image
+// digest, for the /metrics endpoint.
+type imageChanges struct {
+	mu    sync.Mutex
+	total int
+}
+
+var imageChangeStats = &imageChanges{}
+
+// namespaceDeletions counts namespaces whose containers were removed in bulk,
+// for the /metrics endpoint.
+type namespaceDeletions struct {
+	mu         sync.Mutex
+	total      int
+	containers int
+}
+
+var namespaceDeletionStats = &namespaceDeletions{}
+
+// informerEvents counts, per informer, the objects redelivered by periodic
+// resyncs and the watches that dropped with an error (after which the
+// informer lists again), for the /metrics endpoint.
+type informerEvents struct {
+	mu          sync.Mutex
+	resyncs     map[string]int
+	watchErrors map[string]int
+}
+
+var informerStats = &informerEvents{resyncs: make(map[string]int), watchErrors: make(map[string]int)}
+
+func init() {
+	metrics.RegisterExtraWriter(initialSync.write)
+	metrics.RegisterExtraWriter(imageChangeStats.write)
+	metrics.RegisterExtraWriter(namespaceDeletionStats.write)
+	metrics.RegisterExtraWriter(informerStats.write)
+}
+
+func (s *syncStats) begin() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.started = true
+	s.inProgress = true
+	s.pages, s.pods, s.containers = 0, 0, 0
+}
+
+func (s *syncStats) page(pods, containers int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.pages++
+	s.pods += pods
+	s.containers += containers
+}
+
+func (s *syncStats) fail() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.inProgress = false
+	s.failures++
+}
+
+func (s *syncStats) finish(d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.inProgress = false
+	s.duration = d
+}
+
+// write emits the initial sync metrics in Prometheus text format.
+// Nothing is written until a sync has been started.
+func (s *syncStats) write(w io.Writer) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.started {
+		return
+	}
+
+	inProgress := 0
+	if s.inProgress {
+		inProgress = 1
+	}
+	_, _ = fmt.Fprintf(w, "# HELP bjorn2scan_initial_sync_in_progress Whether the paginated initial pod sync is running\n")
+	_, _ = fmt.Fprintf(w, "# TYPE bjorn2scan_initial_sync_in_progress gauge\n")
+	_, _ = fmt.Fprintf(w, "bjorn2scan_initial_sync_in_progress %d\n", inProgress)
+	_, _ = fmt.Fprintf(w, "# HELP bjorn2scan_initial_sync_pages Pages listed by the most recent initial pod sync\n")
+	_, _ = fmt.Fprintf(w, "# TYPE bjorn2scan_initial_sync_pages gauge\n")
+	_, _ = fmt.Fprintf(w, "bjorn2scan_initial_sync_pages %d\n", s.pages)
+	_, _ = fmt.Fprintf(w, "# HELP bjorn2scan_initial_sync_pods Pods processed by the most recent initial pod sync\n")
+	_, _ = fmt.Fprintf(w, "# TYPE bjorn2scan_initial_sync_pods gauge\n")
+	_, _ = fmt.Fprintf(w, "bjorn2scan_initial_sync_pods %d\n", s.pods)
+	_, _ = fmt.Fprintf(w, "# HELP bjorn2scan_initial_sync_containers Running containers added by the most recent initial pod sync\n")
+	_, _ = fmt.Fprintf(w, "# TYPE bjorn2scan_initial_sync_containers gauge\n")
+	_, _ = fmt.Fprintf(w, "bjorn2scan_initial_sync_containers %d\n", s.containers)
+	_, _ = fmt.Fprintf(w, "# HELP bjorn2scan_initial_sync_failures_total Initial pod syncs that failed\n")
+	_, _ = fmt.Fprintf(w, "# TYPE bjorn2scan_initial_sync_failures_total counter\n")
+	_, _ = fmt.Fprintf(w, "bjorn2scan_initial_sync_failures_total %d\n", s.failures)
+	_, _ = fmt.Fprintf(w, "# HELP bjorn2scan_initial_sync_duration_seconds Duration of the most recent completed initial pod sync\n")
+	_, _ = fmt.Fprintf(w, "# TYPE bjorn2scan_initial_sync_duration_seconds gauge\n")
+	_, _ = fmt.Fprintf(w, "bjorn2scan_initial_sync_duration_seconds %g\n", s.duration.Seconds())
+}
+
+func (c *imageChanges) record() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.total++
+}
+
+// write emits the image change counter in Prometheus text format.
+func (c *imageChanges) write(w io.Writer) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	_, _ = fmt.Fprintf(w, "# HELP bjorn2scan_container_image_changes_total Containers that restarted with a different image digest and were re-pointed to the new image\n")
+	_, _ = fmt.Fprintf(w, "# TYPE bjorn2scan_container_image_changes_total counter\n")
+	_, _ = fmt.Fprintf(w, "bjorn2scan_container_image_changes_total %d\n", c.total)
+}
+
+func (n *namespaceDeletions) record(containers int) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.total++
+	n.containers += containers
+}
+
+// write emits the namespace deletion counters in Prometheus text format.
+func (n *namespaceDeletions) write(w io.Writer) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	_, _ = fmt.Fprintf(w, "# HELP bjorn2scan_namespace_deletions_total Deleted namespaces whose containers were removed in bulk\n")
+	_, _ = fmt.Fprintf(w, "# TYPE bjorn2scan_namespace_deletions_total counter\n")
+	_, _ = fmt.Fprintf(w, "bjorn2scan_namespace_deletions_total %d\n", n.total)
+	_, _ = fmt.Fprintf(w, "# HELP bjorn2scan_namespace_deletion_containers_total Containers removed by namespace deletions\n")
+	_, _ = fmt.Fprintf(w, "# TYPE bjorn2scan_namespace_deletion_containers_total counter\n")
+	_, _ = fmt.Fprintf(w, "bjorn2scan_namespace_deletion_containers_total %d\n", n.containers)
+}
+
+// instrumentInformer counts the resyncs and watch errors of informer under
+// name. Must be called before the informer is started.
+func instrumentInformer(name string, informer cache.SharedIndexInformer) {
+	informerStats.register(name)
+	_, err := informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			if isResync(oldObj, newObj) {
+				informerStats.resync(name)
+			}
+		},
+	})
+	if err != nil {
+		log.Warn("failed to add informer metrics handler", "informer", name, slog.Any("error", err))
+	}
+	err = informer.SetWatchErrorHandlerWithContext(func(ctx context.Context, r *cache.Reflector, err error) {
+		informerStats.watchError(name)
+		cache.DefaultWatchErrorHandler(ctx, r, err)
+	})
+	if err != nil {
+		log.Warn("failed to set informer watch error handler", "informer", name, slog.Any("error", err))
+	}
+}
+
+// isResync reports whether an update event redelivers an unchanged object,
+// as periodic resyncs do
+func isResync(oldObj, newObj interface{}) bool {
+	oldMeta, err := meta.Accessor(oldObj)
+	if err != nil {
+		return false
+	}
+	newMeta, err := meta.Accessor(newObj)
+	if err != nil {
+		return false
+	}
+	return oldMeta.GetResourceVersion() == newMeta.GetResourceVersion()
+}
+
+// register makes the informer's counters appear at 0 before its first event
+func (e *informerEvents) register(name string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.resyncs[name] += 0
+	e.watchErrors[name] += 0
+}
+
+func (e *informerEvents) resync(name string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.resyncs[name]++
+}
+
+func (e *informerEvents) watchError(name string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.watchErrors[name]++
+}
+
+// write emits the informer counters in Prometheus text format.
+// Nothing is written until an informer has been instrumented.
+func (e *informerEvents) write(w io.Writer) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if len(e.resyncs) == 0 {
+		return
+	}
+	names := make([]string, 0, len(e.resyncs))
+	for name := range e.resyncs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	_, _ = fmt.Fprintf(w, "# HELP bjorn2scan_watcher_resyncs_total Objects redelivered unchanged by the periodic resync of each informer\n")
+	_, _ = fmt.Fprintf(w, "# TYPE bjorn2scan_watcher_resyncs_total counter\n")
+	for _, name := range names {
+		_, _ = fmt.Fprintf(w, "bjorn2scan_watcher_resyncs_total{informer=%q} %d\n", name, e.resyncs[name])
+	}
+	_, _ = fmt.Fprintf(w, "# HELP bjorn2scan_watcher_watch_errors_total Watches of each informer that dropped with an error and were re-established by listing again\n")
+	_, _ = fmt.Fprintf(w, "# TYPE bjorn2scan_watcher_watch_errors_total counter\n")
+	for _, name := range names {
+		_, _ = fmt.Fprintf(w, "bjorn2scan_watcher_watch_errors_total{informer=%q} %d\n", name, e.watchErrors[name])
+	}
+}
@@ -0,0 +1,47 @@
+package k8s
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestInformerEvents(t *testing.T) {
+	pod := func(rv string) *corev1.Pod {
+		return &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "app", ResourceVersion: rv}}
+	}
+	if !isResync(pod("7"), pod("7")) {
+		t.Error("isResync() = false for an unchanged resource version")
+	}
+	if isResync(pod("7"), pod("8")) || isResync("not an object", pod("8")) {
+		t.Error("isResync() = true for a changed object")
+	}
+
+	events := &informerEvents{resyncs: make(map[string]int), watchErrors: make(map[string]int)}
+	var buf bytes.Buffer
+	events.write(&buf)
+	if buf.Len() != 0 {
+		t.Errorf("write() before any informer = %q, want nothing", buf.String())
+	}
+
+	events.register("pods")
+	events.register("nodes")
+	events.resync("pods")
+	events.resync("pods")
+	events.watchError("nodes")
+	events.write(&buf)
+	for _, want := range []string{
+		"# TYPE bjorn2scan_watcher_resyncs_total counter\n" +
+			`bjorn2scan_watcher_resyncs_total{informer="nodes"} 0` + "\n" +
+			`bjorn2scan_watcher_resyncs_total{informer="pods"} 2` + "\n",
+		`bjorn2scan_watcher_watch_errors_total{informer="nodes"} 1` + "\n",
+		`bjorn2scan_watcher_watch_errors_total{informer="pods"} 0` + "\n",
+	} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("metrics missing %q:\n%s", want, buf.String())
+		}
+	}
+}
@@ -106,6 +106,11 @@ func extractContainers(pod *corev1.Pod) []containers.Container {
 		status := statusMap[container.Name]
 		// Extract just the digest part (e.g., "sha256:abc123...")
 		digest := extractDigestFromImageID(status.imageID)
+		if digest == "" && isStaticPod(pod) {
+			// Static pods don't always report an image ID; use the digest
+			// resolved through the node's runtime, if any
+			digest = staticPods.digest(nodeName, container.Image)
+		}
 
 		// Validate that we have complete data before including this container
 		if digest == "" {
@@ -134,6 +139,7 @@ func extractContainers(pod *corev1.Pod) []containers.Container {
 			},
 			NodeName:         nodeName,
 			ContainerRuntime: status.runtime,
+			ImagePullPolicy:  string(container.ImagePullPolicy),
 		}
 		result = append(result, c)
 	}
@@ -141,6 +147,15 @@ func extractContainers(pod *corev1.Pod) []containers.Container {
 	return result
 }
 
+// NewPodInformerFactory returns the informer factory WatchPods runs on. Other
+// components that look up pods (e.g. the pod-scanner client) take their pod
+// informer from the same factory, sharing its cache instead of listing pods
+// from the API server.
+func NewPodInformerFactory(clientset kubernetes.Interface) informers.SharedInformerFactory {
+	// 5-minute resync period ensures we eventually catch up even if watch events are missed
+	return informers.NewSharedInformerFactory(clientset, 5*time.Minute)
+}
+
 // WatchPods watches for pod changes using a SharedIndexInformer and updates the container manager.
 // This implementation provides:
 // - Automatic watch resumption with resourceVersion tracking (no missed events on reconnect)
@@ -148,14 +163,24 @@ func extractContainers(pod *corev1.Pod) []containers.Container {
 // - Built-in exponential backoff on errors
 // - Local cache to reduce API server load
 // - Proper deletion handling even if watch connection drops
-func WatchPods(ctx context.Context, clientset kubernetes.Interface, manager *containers.Manager) {
-	// Create informer factory with 5-minute resync period
-	// Resync ensures we eventually catch up even if watch events are missed
-	resyncPeriod := 5 * time.Minute
-	factory := informers.NewSharedInformerFactory(clientset, resyncPeriod)
-
+func WatchPods(ctx context.Context, factory informers.SharedInformerFactory, manager *containers.Manager) {
 	// Get the pod informer
 	podInformer := factory.Core().V1().Pods().Informer()
+	instrumentInformer("pods", podInformer)
+
+	// Static pods skipped for lack of an image ID are added again, as they
+	// are now, once their digests are resolved
+	readdPod := func(pod *corev1.Pod) func() {
+		return func() {
+			obj, exists, err := podInformer.GetStore().Get(pod)
+			if err != nil || !exists {
+				return
+			}
+			if current, ok := obj.(*corev1.Pod); ok {
+				handlePodAddOrUpdate(current, manager)
+			}
+		}
+	}
 
 	// Add event handlers
 	log := log
@@ -167,6 +192,7 @@ func WatchPods(ctx context.Context, clientset kubernetes.Interface, manager *con
 				return
 			}
 			handlePodAddOrUpdate(pod, manager)
+			staticPods.resolveMissing(pod, readdPod(pod))
 		},
 		UpdateFunc: func(oldObj, newObj interface{}) {
 			pod, ok := newObj.(*corev1.Pod)
@@ -174,7 +200,11 @@ func WatchPods(ctx context.Context, clientset kubernetes.Interface, manager *con
 				log.Warn("unexpected object type in pod update", "type", slog.Any("type", newObj))
 				return
 			}
+			if oldPod, ok := oldObj.(*corev1.Pod); ok {
+				recordImageChanges(oldPod, pod)
+			}
 			handlePodAddOrUpdate(pod, manager)
+			staticPods.resolveMissing(pod, readdPod(pod))
 		},
 		DeleteFunc: func(obj interface{}) {
 			pod, ok := obj.(*corev1.Pod)
@@ -228,10 +258,58 @@ func WatchPods(ctx context.Context, clientset kubernetes.Interface, manager *con
 	log.Info("pod watcher shutting down")
 }
 
+// isPodActive reports whether the containers of a pod should be tracked.
+// Terminating pods are dropped as soon as their deletion starts so that their
+// instances don't linger next to the pods replacing them during a rollout.
+func isPodActive(pod *corev1.Pod) bool {
+	return pod.Status.Phase == corev1.PodRunning && pod.DeletionTimestamp == nil
+}
+
+// imageChange is a container that restarted in place with a different image
+// digest, e.g. because :latest resolved to a newer image
+type imageChange struct {
+	container containers.Container
+	oldDigest string
+}
+
+// changedImages returns the containers whose image digest differs between two
+// versions of a pod. Containers without a digest in either version (not yet
+// started, or restarting) are not reported.
+func changedImages(oldPod, newPod *corev1.Pod) []imageChange {
+	oldDigests := make(map[string]string)
+	for _, c := range extractContainers(oldPod) {
+		oldDigests[c.ID.Name] = c.Image.Digest
+	}
+
+	var changes []imageChange
+	for _, c := range extractContainers(newPod) {
+		if oldDigest, ok := oldDigests[c.ID.Name]; ok && oldDigest != c.Image.Digest {
+			changes = append(changes, imageChange{container: c, oldDigest: oldDigest})
+		}
+	}
+	return changes
+}
+
+// recordImageChanges logs and counts containers of a running pod that
+// restarted with a new image digest. The manager re-points the container to
+// the new image atomically when the updated pod is added.
+func recordImageChanges(oldPod, newPod *corev1.Pod) {
+	if !isPodActive(newPod) {
+		return
+	}
+	for _, change := range changedImages(oldPod, newPod) {
+		c := change.container
+		log.Info("container image changed",
+			"namespace", c.ID.Namespace, "pod", c.ID.Pod, "container", c.ID.Name,
+			"image", c.Image.Reference, "old_digest", change.oldDigest, "new_digest", c.Image.Digest)
+		imageChangeStats.record()
+	}
+}
+
 // handlePodAddOrUpdate processes pod additions and updates
 func handlePodAddOrUpdate(pod *corev1.Pod, manager *containers.Manager) {
 	// Only process running pods
-	if pod.Status.Phase == corev1.PodRunning {
+	if isPodActive(pod) {
 		podContainers := extractContainers(pod)
 		for _, c := range podContainers {
 			manager.AddContainer(c)
@@ -256,30 +334,78 @@ func handlePodDelete(pod *corev1.Pod, manager *containers.Manager) {
 		"namespace", pod.Namespace, "pod", pod.Name, "containers", len(podContainers))
 }
 
+// initialSyncPageSize is the number of pods requested per List call during
+// SyncInitialPods. Large clusters (tens of thousands of pods) are listed in
+// pages so the full pod list never has to be held in memory at once.
+const initialSyncPageSize = 500
+
 // SyncInitialPods performs an initial sync of all existing pods.
 // Note: With the informer-based WatchPods implementation, this function is less critical
 // since the informer automatically performs an initial list and sync (via cache.WaitForCacheSync).
 // This function is kept for explicit synchronization use cases or testing.
+//
+// Pods are listed page by page using continue tokens. Each page is handed to the
+// manager immediately, so memory stays bounded by the page size. Containers that
+// were known to the manager but not seen in any page are removed at the end.
 func SyncInitialPods(ctx context.Context, clientset kubernetes.Interface, manager *containers.Manager) error {
 	log := log
-	log.Info("performing initial pod sync")
+	log.Info("performing initial pod sync", "page_size", initialSyncPageSize)
+
+	start := time.Now()
+	initialSync.begin()
+
+	seen := make(map[containers.ContainerID]bool)
+	opts := metav1.ListOptions{Limit: initialSyncPageSize}
+	pages, pods := 0, 0
+	for {
+		podList, err := clientset.CoreV1().Pods("").List(ctx, opts)
+		if err != nil {
+			initialSync.fail()
+			return err
+		}
+		pages++
+		pods += len(podList.Items)
+
+		var pageContainers []containers.Container
+		for i := range podList.Items {
+			pod := &podList.Items[i]
+			// Only track containers from running pods
+			if !isPodActive(pod) {
+				continue
+			}
+			for _, c := range extractContainers(pod) {
+				seen[c.ID] = true
+				pageContainers = append(pageContainers, c)
+			}
+		}
+		if len(pageContainers) > 0 {
+			manager.AddContainers(pageContainers)
+		}
+		initialSync.page(len(podList.Items), len(pageContainers))
 
-	podList, err := clientset.CoreV1().Pods("").List(ctx, metav1.ListOptions{})
-	if err != nil {
-		return err
+		log.Info("initial sync progress",
+			"page", pages, "pods", pods, "containers", len(seen),
+			"elapsed", time.Since(start).Round(time.Millisecond))
+
+		if podList.Continue == "" {
+			break
+		}
+		opts.Continue = podList.Continue
 	}
 
-	var allContainers []containers.Container
-	for _, pod := range podList.Items {
-		// Only track containers from running pods
-		if pod.Status.Phase == corev1.PodRunning {
-			podContainers := extractContainers(&pod)
-			allContainers = append(allContainers, podContainers...)
+	// Drop containers that no longer exist (only relevant when re-syncing a populated manager)
+	removed := 0
+	for _, id := range manager.GetActiveContainerIDs() {
+		if !seen[id] {
+			manager.RemoveContainer(id)
+			removed++
 		}
 	}
 
-	manager.SetContainers(allContainers)
-	log.Info("initial sync complete", "containers", manager.GetContainerCount())
+	initialSync.finish(time.Since(start))
+	log.Info("initial sync complete",
+		"pages", pages, "pods", pods, "containers", manager.GetContainerCount(),
+		"removed", removed, "duration", time.Since(start).Round(time.Millisecond))
 
 	return nil
 }
@@ -1,7 +1,9 @@
 package k8s
 
 import (
+	"bytes"
 	"context"
+	"strings"
 	"testing"
 	"time"
 
@@ -9,7 +11,9 @@ import (
 
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
 )
 
 func TestExtractImageReference(t *testing.T) {
@@ -278,7 +282,7 @@ func TestWatchPodsInformerIntegration(t *testing.T) {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	go WatchPods(ctx, clientset, manager)
+	go WatchPods(ctx, NewPodInformerFactory(clientset), manager)
 
 	// Wait for informer to sync
 	time.Sleep(500 * time.Millisecond)
@@ -345,7 +349,7 @@ func TestWatchPodsInformerDeletion(t *testing.T) {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	go WatchPods(ctx, clientset, manager)
+	go WatchPods(ctx, NewPodInformerFactory(clientset), manager)
 
 	// Wait for informer to sync and add pod
 	time.Sleep(500 * time.Millisecond)
@@ -413,7 +417,7 @@ func TestWatchPodsInformerUpdate(t *testing.T) {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	go WatchPods(ctx, clientset, manager)
+	go WatchPods(ctx, NewPodInformerFactory(clientset), manager)
 
 	// Wait for informer to sync and add pod
 	time.Sleep(500 * time.Millisecond)
@@ -456,7 +460,7 @@ func TestWatchPodsInformerMultiplePods(t *testing.T) {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	go WatchPods(ctx, clientset, manager)
+	go WatchPods(ctx, NewPodInformerFactory(clientset), manager)
 
 	// Wait for informer to start
 	time.Sleep(300 * time.Millisecond)
@@ -504,3 +508,176 @@ func TestWatchPodsInformerMultiplePods(t *testing.T) {
 		t.Errorf("Expected 3 containers, got %d", count)
 	}
 }
+
+// TestWatchPodsInformerImageChange verifies that a container restarting with a
+// new image digest is re-pointed to the new image, and that terminating pods
+// are dropped before they are deleted
+func TestWatchPodsInformerImageChange(t *testing.T) {
+	testPod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"},
+		Spec: corev1.PodSpec{
+			NodeName:   "node-1",
+			Containers: []corev1.Container{{Name: "app", Image: "registry.example.com/web:latest"}},
+		},
+		Status: corev1.PodStatus{
+			Phase: corev1.PodRunning,
+			ContainerStatuses: []corev1.ContainerStatus{
+				{Name: "app", ImageID: "registry.example.com/web@sha256:old", ContainerID: "containerd://first"},
+			},
+		},
+	}
+
+	clientset := fake.NewClientset(testPod)
+	manager := containers.NewManager()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	go WatchPods(ctx, NewPodInformerFactory(clientset), manager)
+	time.Sleep(500 * time.Millisecond)
+
+	imageChangeStats.mu.Lock()
+	changesBefore := imageChangeStats.total
+	imageChangeStats.mu.Unlock()
+
+	// The container restarts and :latest resolves to a new digest
+	testPod = testPod.DeepCopy()
+	testPod.Status.ContainerStatuses[0].ImageID = "registry.example.com/web@sha256:new"
+	testPod.Status.ContainerStatuses[0].ContainerID = "containerd://second"
+	if _, err := clientset.CoreV1().Pods("default").UpdateStatus(context.Background(), testPod, metav1.UpdateOptions{}); err != nil {
+		t.Fatalf("Failed to update test pod: %v", err)
+	}
+	time.Sleep(500 * time.Millisecond)
+
+	c, exists := manager.GetContainer("default", "web", "app")
+	if !exists || c.Image.Digest != "sha256:new" {
+		t.Fatalf("Expected container re-pointed to sha256:new, got %+v (exists=%v)", c, exists)
+	}
+	if count := manager.GetContainerCount(); count != 1 {
+		t.Errorf("Expected 1 container, got %d", count)
+	}
+	imageChangeStats.mu.Lock()
+	changes := imageChangeStats.total - changesBefore
+	imageChangeStats.mu.Unlock()
+	if changes != 1 {
+		t.Errorf("Expected 1 image change, got %d", changes)
+	}
+
+	// The pod starts terminating during a rollout
+	now := metav1.Now()
+	testPod = testPod.DeepCopy()
+	testPod.DeletionTimestamp = &now
+	if _, err := clientset.CoreV1().Pods("default").Update(context.Background(), testPod, metav1.UpdateOptions{}); err != nil {
+		t.Fatalf("Failed to update test pod: %v", err)
+	}
+	time.Sleep(500 * time.Millisecond)
+
+	if count := manager.GetContainerCount(); count != 0 {
+		t.Errorf("Expected terminating pod to be dropped, got %d containers", count)
+	}
+}
+
+func TestChangedImages(t *testing.T) {
+	newPod := func(digests map[string]string) *corev1.Pod {
+		pod := &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"},
+			Status:     corev1.PodStatus{Phase: corev1.PodRunning},
+		}
+		for _, name := range []string{"app", "sidecar"} {
+			pod.Spec.Containers = append(pod.Spec.Containers, corev1.Container{Name: name, Image: name + ":latest"})
+			if digest := digests[name]; digest != "" {
+				pod.Status.ContainerStatuses = append(pod.Status.ContainerStatuses, corev1.ContainerStatus{
+					Name: name, ImageID: name + "@" + digest, ContainerID: "containerd://" + name,
+				})
+			}
+		}
+		return pod
+	}
+
+	oldPod := newPod(map[string]string{"app": "sha256:old", "sidecar": "sha256:same"})
+
+	changes := changedImages(oldPod, newPod(map[string]string{"app": "sha256:new", "sidecar": "sha256:same"}))
+	if len(changes) != 1 || changes[0].container.ID.Name != "app" ||
+		changes[0].oldDigest != "sha256:old" || changes[0].container.Image.Digest != "sha256:new" {
+		t.Errorf("Unexpected changes: %+v", changes)
+	}
+
+	// A restarting container without a digest yet is not a change
+	if changes := changedImages(oldPod, newPod(map[string]string{"sidecar": "sha256:same"})); len(changes) != 0 {
+		t.Errorf("Expected no changes, got %+v", changes)
+	}
+}
+
+// TestSyncInitialPodsPaginated verifies that SyncInitialPods follows continue tokens
+// and adds containers from every page to the manager
+func TestSyncInitialPodsPaginated(t *testing.T) {
+	newPod := func(name, digest string, phase corev1.PodPhase) corev1.Pod {
+		return corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+			Spec: corev1.PodSpec{
+				NodeName:   "node-1",
+				Containers: []corev1.Container{{Name: "app", Image: "nginx:1.21"}},
+			},
+			Status: corev1.PodStatus{
+				Phase: phase,
+				ContainerStatuses: []corev1.ContainerStatus{
+					{Name: "app", ImageID: "docker.io/library/nginx@" + digest, ContainerID: "containerd://" + name},
+				},
+			},
+		}
+	}
+	pages := map[string]*corev1.PodList{
+		"": {
+			ListMeta: metav1.ListMeta{Continue: "page-2"},
+			Items:    []corev1.Pod{newPod("pod-1", "sha256:aaa", corev1.PodRunning), newPod("pod-2", "sha256:bbb", corev1.PodPending)},
+		},
+		"page-2": {
+			Items: []corev1.Pod{newPod("pod-3", "sha256:ccc", corev1.PodRunning)},
+		},
+	}
+
+	clientset := fake.NewClientset()
+	var limits []int64
+	clientset.PrependReactor("list", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		opts := action.(k8stesting.ListActionImpl).GetListOptions()
+		limits = append(limits, opts.Limit)
+		return true, pages[opts.Continue], nil
+	})
+
+	manager := containers.NewManager()
+	// Stale container from a previous sync that no longer exists in the cluster
+	manager.AddContainer(containers.Container{
+		ID:    containers.ContainerID{Namespace: "default", Pod: "gone", Name: "app"},
+		Image: containers.ImageID{Reference: "nginx:1.20", Digest: "sha256:old"},
+	})
+
+	if err := SyncInitialPods(context.Background(), clientset, manager); err != nil {
+		t.Fatalf("SyncInitialPods failed: %v", err)
+	}
+
+	if len(limits) != 2 {
+		t.Fatalf("Expected 2 List calls, got %d", len(limits))
+	}
+	for _, limit := range limits {
+		if limit != initialSyncPageSize {
+			t.Errorf("Expected page size %d, got %d", initialSyncPageSize, limit)
+		}
+	}
+	if count := manager.GetContainerCount(); count != 2 {
+		t.Errorf("Expected 2 containers, got %d", count)
+	}
+	for _, pod := range []string{"pod-1", "pod-3"} {
+		if _, exists := manager.GetContainer("default", pod, "app"); !exists {
+			t.Errorf("Expected container from %s", pod)
+		}
+	}
+	if _, exists := manager.GetContainer("default", "gone", "app"); exists {
+		t.Error("Expected stale container to be removed")
+	}
+
+	var buf bytes.Buffer
+	initialSync.write(&buf)
+	if !strings.Contains(buf.String(), "bjorn2scan_initial_sync_pods 3") {
+		t.Errorf("Expected sync metrics to report 3 pods, got:\n%s", buf.String())
+	}
+}
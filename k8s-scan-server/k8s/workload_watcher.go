@@ -0,0 +1,192 @@
+package k8s
+
+import (
+	"context"
+	"log/slog"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/bvboe/b2s-go/scanner-core/containers"
+	"github.com/bvboe/b2s-go/scanner-core/database"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+)
+
+// UpcomingImageResolver resolves the images of workload specs to digests and
+// queues registry scans of them (implemented by adhoc.Scanner)
+type UpcomingImageResolver interface {
+	Resolve(ctx context.Context, reference string) (containers.ImageID, error)
+	SubmitImage(image containers.ImageID) error
+}
+
+// templateImages returns the distinct image references of a pod template,
+// including init containers
+func templateImages(template *corev1.PodTemplateSpec) []string {
+	var refs []string
+	for _, list := range [][]corev1.Container{template.Spec.InitContainers, template.Spec.Containers} {
+		for _, c := range list {
+			if c.Image != "" && !slices.Contains(refs, c.Image) {
+				refs = append(refs, c.Image)
+			}
+		}
+	}
+	slices.Sort(refs)
+	return refs
+}
+
+// resolvedWorkload is the last resolved image set of a workload
+type resolvedWorkload struct {
+	refs   []string
+	images []containers.ImageID
+}
+
+// workloadImages resolves the images of Deployments and StatefulSets and
+// records them as observed, so images about to roll out are known before the
+// first pod using them starts
+type workloadImages struct {
+	ctx      context.Context
+	resolver UpcomingImageResolver
+	recorder ObservedImageRecorder
+	scan     bool
+
+	mu        sync.Mutex
+	workloads map[string]*resolvedWorkload // keyed by namespace/kind/name
+}
+
+// update records the images of a workload. References are only resolved again
+// when the template changed, so periodic resyncs refresh when the images were
+// last seen without querying registries.
+func (w *workloadImages) update(kind, namespace, name string, template *corev1.PodTemplateSpec, now time.Time) {
+	key := namespace + "/" + kind + "/" + name
+	refs := templateImages(template)
+
+	w.mu.Lock()
+	resolved, ok := w.workloads[key]
+	w.mu.Unlock()
+
+	if !ok || !slices.Equal(resolved.refs, refs) {
+		resolved = &resolvedWorkload{refs: refs}
+		for _, ref := range refs {
+			image, err := w.resolver.Resolve(w.ctx, ref)
+			if err != nil {
+				log.Warn("failed to resolve upcoming image",
+					"namespace", namespace, "workload", kind+"/"+name, "image", ref, "error", err)
+				continue
+			}
+			resolved.images = append(resolved.images, image)
+			if w.scan {
+				if err := w.resolver.SubmitImage(image); err != nil {
+					log.Error("failed to queue scan of upcoming image",
+						"image", image.Reference, "digest", image.Digest, "error", err)
+				}
+			}
+		}
+		log.Debug("resolved upcoming images", "namespace", namespace, "workload", kind+"/"+name,
+			"references", len(refs), "resolved", len(resolved.images))
+
+		w.mu.Lock()
+		w.workloads[key] = resolved
+		w.mu.Unlock()
+	}
+
+	observed := make([]database.ObservedImage, 0, len(resolved.images))
+	for _, image := range resolved.images {
+		observed = append(observed, database.ObservedImage{
+			Digest:    image.Digest,
+			Reference: image.Reference,
+			Namespace: namespace,
+			Workload:  kind + "/" + name,
+			SeenAt:    now,
+		})
+	}
+	if err := w.recorder.RecordObservedImages(observed); err != nil {
+		log.Error("failed to record upcoming images",
+			"namespace", namespace, "workload", kind+"/"+name, "error", err)
+	}
+}
+
+// remove forgets a deleted workload. Its observed images age out with the
+// scan coverage lookback.
+func (w *workloadImages) remove(kind, namespace, name string) {
+	w.mu.Lock()
+	delete(w.workloads, namespace+"/"+kind+"/"+name)
+	w.mu.Unlock()
+}
+
+// WatchWorkloads watches Deployment and StatefulSet specs and resolves the
+// images in their pod templates to digests, recording them as observed images
+// so scan coverage includes images that have not started yet. With scan set,
+// unscanned images are pulled from their registry and scanned right away,
+// shrinking the window in which a new rollout runs unscanned.
+func WatchWorkloads(ctx context.Context, clientset kubernetes.Interface, resolver UpcomingImageResolver, recorder ObservedImageRecorder, scan bool) {
+	resyncPeriod := 5 * time.Minute
+	factory := informers.NewSharedInformerFactory(clientset, resyncPeriod)
+
+	deploymentInformer := factory.Apps().V1().Deployments().Informer()
+	statefulSetInformer := factory.Apps().V1().StatefulSets().Informer()
+	instrumentInformer("deployments", deploymentInformer)
+	instrumentInformer("statefulsets", statefulSetInformer)
+
+	w := &workloadImages{
+		ctx:       ctx,
+		resolver:  resolver,
+		recorder:  recorder,
+		scan:      scan,
+		workloads: make(map[string]*resolvedWorkload),
+	}
+
+	update := func(obj interface{}) {
+		switch o := obj.(type) {
+		case *appsv1.Deployment:
+			w.update("deployment", o.Namespace, o.Name, &o.Spec.Template, time.Now())
+		case *appsv1.StatefulSet:
+			w.update("statefulset", o.Namespace, o.Name, &o.Spec.Template, time.Now())
+		default:
+			log.Warn("unexpected object type in workload event", "type", slog.Any("type", obj))
+		}
+	}
+	remove := func(obj interface{}) {
+		if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+			obj = tombstone.Obj
+		}
+		switch o := obj.(type) {
+		case *appsv1.Deployment:
+			w.remove("deployment", o.Namespace, o.Name)
+		case *appsv1.StatefulSet:
+			w.remove("statefulset", o.Namespace, o.Name)
+		default:
+			log.Warn("unexpected object type in workload delete", "type", slog.Any("type", obj))
+		}
+	}
+	handler := cache.ResourceEventHandlerFuncs{
+		AddFunc: update,
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			update(newObj)
+		},
+		DeleteFunc: remove,
+	}
+
+	for _, informer := range []cache.SharedIndexInformer{deploymentInformer, statefulSetInformer} {
+		if _, err := informer.AddEventHandler(handler); err != nil {
+			log.Error("failed to add workload event handler", slog.Any("error", err))
+			return
+		}
+	}
+
+	log.Info("starting workload informers", "scan", scan)
+	go factory.Start(ctx.Done())
+
+	if !cache.WaitForCacheSync(ctx.Done(), deploymentInformer.HasSynced, statefulSetInformer.HasSynced) {
+		log.Error("failed to sync workload informer caches")
+		return
+	}
+	log.Info("workload informer caches synced and ready")
+
+	<-ctx.Done()
+	log.Info("workload watcher shutting down")
+}
@@ -0,0 +1,104 @@
+package k8s
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/bvboe/b2s-go/scanner-core/containers"
+	"github.com/bvboe/b2s-go/scanner-core/database"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+type fakeUpcomingResolver struct {
+	resolved  []string
+	submitted []containers.ImageID
+}
+
+func (r *fakeUpcomingResolver) Resolve(ctx context.Context, reference string) (containers.ImageID, error) {
+	r.resolved = append(r.resolved, reference)
+	if reference == "missing:1.0" {
+		return containers.ImageID{}, errors.New("MANIFEST_UNKNOWN")
+	}
+	return containers.ImageID{Reference: reference, Digest: "sha256:" + reference}, nil
+}
+
+func (r *fakeUpcomingResolver) SubmitImage(image containers.ImageID) error {
+	r.submitted = append(r.submitted, image)
+	return nil
+}
+
+type fakeObservedRecorder struct{ images []database.ObservedImage }
+
+func (r *fakeObservedRecorder) RecordObservedImages(images []database.ObservedImage) error {
+	r.images = append(r.images, images...)
+	return nil
+}
+
+func (r *fakeObservedRecorder) PruneObservedImages(before time.Time) (int64, error) {
+	return 0, nil
+}
+
+func podTemplate(images ...string) *corev1.PodTemplateSpec {
+	template := &corev1.PodTemplateSpec{}
+	template.Spec.InitContainers = []corev1.Container{{Name: "init", Image: images[0]}}
+	for _, image := range images[1:] {
+		template.Spec.Containers = append(template.Spec.Containers, corev1.Container{Name: "c", Image: image})
+	}
+	return template
+}
+
+func TestTemplateImages(t *testing.T) {
+	got := templateImages(podTemplate("busybox:1.36", "nginx:1.27", "busybox:1.36"))
+	want := []string{"busybox:1.36", "nginx:1.27"}
+	if !slices.Equal(got, want) {
+		t.Errorf("templateImages() = %v, want %v", got, want)
+	}
+}
+
+func TestWorkloadImagesUpdate(t *testing.T) {
+	resolver, recorder := &fakeUpcomingResolver{}, &fakeObservedRecorder{}
+	w := &workloadImages{
+		ctx:       context.Background(),
+		resolver:  resolver,
+		recorder:  recorder,
+		scan:      true,
+		workloads: make(map[string]*resolvedWorkload),
+	}
+	now := time.Now()
+
+	w.update("deployment", "shop", "web", podTemplate("busybox:1.36", "nginx:1.27", "missing:1.0"), now)
+	if len(resolver.resolved) != 3 {
+		t.Fatalf("expected 3 references resolved, got %v", resolver.resolved)
+	}
+	if len(resolver.submitted) != 2 {
+		t.Errorf("expected 2 resolved images queued for scanning, got %+v", resolver.submitted)
+	}
+	if len(recorder.images) != 2 {
+		t.Fatalf("expected 2 observed images, got %+v", recorder.images)
+	}
+	img := recorder.images[0]
+	if img.Digest != "sha256:busybox:1.36" || img.Workload != "deployment/web" || img.Namespace != "shop" || !img.SeenAt.Equal(now) {
+		t.Errorf("unexpected observed image %+v", img)
+	}
+
+	// A resync with an unchanged template refreshes without resolving again
+	w.update("deployment", "shop", "web", podTemplate("busybox:1.36", "nginx:1.27", "missing:1.0"), now.Add(time.Minute))
+	if len(resolver.resolved) != 3 || len(recorder.images) != 4 {
+		t.Errorf("expected cached digests to be recorded again, resolved %v, recorded %d", resolver.resolved, len(recorder.images))
+	}
+
+	// A new image tag is resolved as soon as the spec changes
+	w.update("deployment", "shop", "web", podTemplate("busybox:1.36", "nginx:1.28"), now)
+	if len(resolver.resolved) != 5 || resolver.resolved[4] != "nginx:1.28" {
+		t.Errorf("expected changed template to be resolved again, resolved %v", resolver.resolved)
+	}
+
+	w.remove("deployment", "shop", "web")
+	if len(w.workloads) != 0 {
+		t.Errorf("expected removed workload to be forgotten, got %v", w.workloads)
+	}
+}
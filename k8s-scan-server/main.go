@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"crypto/tls"
 	"fmt"
 	"net/http"
 	"os"
@@ -11,21 +12,39 @@ import (
 	"syscall"
 	"time"
 
+	"github.com/bvboe/b2s-go/k8s-scan-server/admission"
 	"github.com/bvboe/b2s-go/k8s-scan-server/k8s"
 	"github.com/bvboe/b2s-go/k8s-scan-server/podscanner"
+	"github.com/bvboe/b2s-go/scanner-core/adhoc"
+	"github.com/bvboe/b2s-go/scanner-core/cmdb"
+	"github.com/bvboe/b2s-go/scanner-core/columns"
 	scannerconfig "github.com/bvboe/b2s-go/scanner-core/config"
 	"github.com/bvboe/b2s-go/scanner-core/containers"
 	"github.com/bvboe/b2s-go/scanner-core/database"
 	"github.com/bvboe/b2s-go/scanner-core/debug"
 	"github.com/bvboe/b2s-go/scanner-core/deployment"
+	"github.com/bvboe/b2s-go/scanner-core/diskusage"
+	"github.com/bvboe/b2s-go/scanner-core/eol"
+	"github.com/bvboe/b2s-go/scanner-core/fixhints"
 	"github.com/bvboe/b2s-go/scanner-core/grype"
 	corehandlers "github.com/bvboe/b2s-go/scanner-core/handlers"
 	"github.com/bvboe/b2s-go/scanner-core/jobs"
+	"github.com/bvboe/b2s-go/scanner-core/labels"
 	"github.com/bvboe/b2s-go/scanner-core/logging"
 	"github.com/bvboe/b2s-go/scanner-core/metrics"
 	"github.com/bvboe/b2s-go/scanner-core/nodes"
+	"github.com/bvboe/b2s-go/scanner-core/notify"
+	"github.com/bvboe/b2s-go/scanner-core/policy"
+	"github.com/bvboe/b2s-go/scanner-core/provenance"
+	"github.com/bvboe/b2s-go/scanner-core/registrycrawl"
+	"github.com/bvboe/b2s-go/scanner-core/resultcache"
+	"github.com/bvboe/b2s-go/scanner-core/routes"
+	"github.com/bvboe/b2s-go/scanner-core/sbomformat"
 	"github.com/bvboe/b2s-go/scanner-core/scanning"
 	"github.com/bvboe/b2s-go/scanner-core/scheduler"
+	"github.com/bvboe/b2s-go/scanner-core/severity"
+	"github.com/bvboe/b2s-go/scanner-core/tlsreload"
+	"github.com/bvboe/b2s-go/scanner-core/vex"
 	"github.com/bvboe/b2s-go/scanner-core/vulndb"
 	// SQLite driver is registered by Grype's dependencies
 	_ "github.com/KimMachineGun/automemlimit" // Automatically set GOMEMLIMIT based on cgroup limits
@@ -39,12 +58,48 @@ import (
 // version is set at build time via ldflags
 var version = "dev"
 
-// formatConsoleURL creates a console URL, omitting port 80 for cleaner URLs
-func formatConsoleURL(host, port string) string {
-	if port == "80" {
-		return fmt.Sprintf("http://%s/", host)
+// formatConsoleURL creates a console URL, omitting the scheme's default port
+// for cleaner URLs
+func formatConsoleURL(scheme, host, port string) string {
+	if (scheme == "http" && port == "80") || (scheme == "https" && port == "443") {
+		return fmt.Sprintf("%s://%s/", scheme, host)
 	}
-	return fmt.Sprintf("http://%s:%s/", host, port)
+	return fmt.Sprintf("%s://%s:%s/", scheme, host, port)
+}
+
+// healthPaths are served without a client certificate under mutual TLS, so
+// the kubelet's probes keep working
+var healthPaths = []string{"/health", "/ready"}
+
+// serverTLS returns the TLS configuration of the API server, reloaded when
+// the certificate is renewed, and handler wrapped to require client
+// certificates when a client CA is configured. The configuration is nil
+// without a certificate, for plain HTTP.
+func serverTLS(cfg *scannerconfig.Config, handler http.Handler) (*tls.Config, http.Handler, error) {
+	if cfg.TLSCertFile == "" {
+		if cfg.TLSClientCAFile != "" {
+			return nil, nil, fmt.Errorf("TLS_CLIENT_CA_FILE requires TLS_CERT_FILE and TLS_KEY_FILE")
+		}
+		return nil, handler, nil
+	}
+	certs, err := tlsreload.New(tlsreload.Files{CertFile: cfg.TLSCertFile, KeyFile: cfg.TLSKeyFile, CAFile: cfg.TLSClientCAFile})
+	if err != nil {
+		return nil, nil, err
+	}
+	if cfg.TLSClientCAFile != "" {
+		handler = tlsreload.RequireClientCert(handler, healthPaths...)
+	}
+	return certs.ServerConfig(), handler, nil
+}
+
+// listenAndServe serves HTTPS when the server has a TLS configuration and
+// plain HTTP otherwise
+func listenAndServe(server *http.Server) error {
+	if server.TLSConfig != nil {
+		// The certificate comes from TLSConfig, reloaded when renewed
+		return server.ListenAndServeTLS("", "")
+	}
+	return server.ListenAndServe()
 }
 
 type InfoResponse struct {
@@ -55,6 +110,7 @@ type InfoResponse struct {
 
 type K8sScanServerInfo struct {
 	port         string
+	scheme       string // "https" when the server terminates TLS
 	webUIEnabled bool
 	k8sClient    kubernetes.Interface
 	serviceName  string
@@ -65,16 +121,22 @@ type K8sScanServerInfo struct {
 	consoleMu        sync.RWMutex // Protects cachedConsoleURL
 	// Database readiness state for grype DB status
 	dbReadinessState *corehandlers.DatabaseReadinessState
+	// Loaded configuration reported by /api/config
+	config *scannerconfig.Config
 }
 
-func NewK8sScanServerInfo(port string, webUIEnabled bool, customConsoleURL string, k8sClient kubernetes.Interface, serviceName, servicePort string) *K8sScanServerInfo {
+func NewK8sScanServerInfo(port string, https bool, webUIEnabled bool, customConsoleURL string, k8sClient kubernetes.Interface, serviceName, servicePort string) *K8sScanServerInfo {
 	info := &K8sScanServerInfo{
 		port:         port,
+		scheme:       "http",
 		webUIEnabled: webUIEnabled,
 		k8sClient:    k8sClient,
 		serviceName:  serviceName,
 		servicePort:  servicePort,
 	}
+	if https {
+		info.scheme = "https"
+	}
 
 	// Cache deployment IP (node IP from downward API)
 	info.deploymentIP = os.Getenv("NODE_IP")
@@ -131,6 +193,11 @@ func (k *K8sScanServerInfo) GetInfo() interface{} {
 }
 
 func (k *K8sScanServerInfo) GetClusterName() string {
+	return clusterName()
+}
+
+// clusterName returns CLUSTER_NAME, falling back to the namespace
+func clusterName() string {
 	// Try to get cluster name from environment variable first
 	clusterName := os.Getenv("CLUSTER_NAME")
 	if clusterName != "" {
@@ -169,13 +236,10 @@ func (k *K8sScanServerInfo) GetScanContainers() bool {
 	return true
 }
 
+// GetScanNodes reports whether host (node OS) scanning is enabled, as
+// configured by host_scanning_enabled
 func (k *K8sScanServerInfo) GetScanNodes() bool {
-	// Default to false, can be enabled via env var
-	scanNodes := os.Getenv("SCAN_NODES")
-	if scanNodes == "true" || scanNodes == "1" {
-		return true
-	}
-	return false
+	return k.config != nil && k.config.HostScanningEnabled
 }
 
 // GetDeploymentIP returns the cached deployment IP (node IP).
@@ -195,6 +259,16 @@ func (k *K8sScanServerInfo) SetDBReadinessState(state *corehandlers.DatabaseRead
 	k.dbReadinessState = state
 }
 
+// SetEffectiveConfig sets the loaded configuration reported by /api/config.
+func (k *K8sScanServerInfo) SetEffectiveConfig(cfg *scannerconfig.Config) {
+	k.config = cfg
+}
+
+// GetEffectiveConfig returns the loaded configuration, or nil if not set.
+func (k *K8sScanServerInfo) GetEffectiveConfig() *scannerconfig.Config {
+	return k.config
+}
+
 // GetGrypeDBBuilt returns the grype vulnerability database build timestamp in RFC3339 format.
 // Returns empty string if the database status is unavailable.
 func (k *K8sScanServerInfo) GetGrypeDBBuilt() string {
@@ -236,14 +310,14 @@ func (k *K8sScanServerInfo) detectConsoleURL() string {
 						if port == "" && len(svc.Spec.Ports) > 0 {
 							port = fmt.Sprintf("%d", svc.Spec.Ports[0].Port)
 						}
-						return formatConsoleURL(ingress.IP, port)
+						return formatConsoleURL(k.scheme, ingress.IP, port)
 					}
 					if ingress.Hostname != "" {
 						port := k.servicePort
 						if port == "" && len(svc.Spec.Ports) > 0 {
 							port = fmt.Sprintf("%d", svc.Spec.Ports[0].Port)
 						}
-						return formatConsoleURL(ingress.Hostname, port)
+						return formatConsoleURL(k.scheme, ingress.Hostname, port)
 					}
 				}
 
@@ -251,7 +325,7 @@ func (k *K8sScanServerInfo) detectConsoleURL() string {
 				// Use Node IP + NodePort (NodePort is never 80, so always include it)
 				if k.deploymentIP != "" && len(svc.Spec.Ports) > 0 {
 					nodePort := svc.Spec.Ports[0].NodePort
-					return fmt.Sprintf("http://%s:%d/", k.deploymentIP, nodePort)
+					return fmt.Sprintf("%s://%s:%d/", k.scheme, k.deploymentIP, nodePort)
 				}
 
 			case corev1.ServiceTypeClusterIP:
@@ -260,7 +334,7 @@ func (k *K8sScanServerInfo) detectConsoleURL() string {
 				if port == "" && len(svc.Spec.Ports) > 0 {
 					port = fmt.Sprintf("%d", svc.Spec.Ports[0].Port)
 				}
-				return formatConsoleURL(fmt.Sprintf("%s.%s.svc.cluster.local", k.serviceName, namespace), port)
+				return formatConsoleURL(k.scheme, fmt.Sprintf("%s.%s.svc.cluster.local", k.serviceName, namespace), port)
 			}
 		}
 	}
@@ -271,13 +345,141 @@ func (k *K8sScanServerInfo) detectConsoleURL() string {
 		if port == "" {
 			port = "80"
 		}
-		return formatConsoleURL(fmt.Sprintf("%s.%s.svc.cluster.local", k.serviceName, namespace), port)
+		return formatConsoleURL(k.scheme, fmt.Sprintf("%s.%s.svc.cluster.local", k.serviceName, namespace), port)
 	}
 
 	return ""
 }
 
+// databasePath returns the SQLite database file (DB_PATH)
+func databasePath() string {
+	if path := os.Getenv("DB_PATH"); path != "" {
+		return path
+	}
+	return "/var/lib/bjorn2scan/data/containers.db"
+}
+
+// grypeDBRootDir returns the directory holding the grype database.
+// GRYPE_DB_PATH allows storing the grype database separately from the main data
+// This is important for NFS deployments where grype's in-place database updates
+// can fail due to NFS "silly rename" behavior when files are replaced while open
+func grypeDBRootDir(dbPath string) string {
+	if path := os.Getenv("GRYPE_DB_PATH"); path != "" {
+		return path
+	}
+	// Default: use the same directory as the main database
+	return filepath.Dir(dbPath)
+}
+
+// sharedAPIOptions returns the API options the leader and the followers serve
+// alike. The leader adds the scan queue and the other write paths; followers
+// reject writes.
+func sharedAPIOptions(cfg *scannerconfig.Config, source, deploymentUUID string, staticLabels labels.Set,
+	imagePolicy policy.Evaluator, computedColumns []columns.Column) corehandlers.APIOptions {
+	return corehandlers.APIOptions{
+		Transfer: corehandlers.TransferConfig{
+			SigningKey:     cfg.TransferSigningKey,
+			Source:         source,
+			DeploymentUUID: deploymentUUID,
+			Labels:         staticLabels,
+		},
+		Report: corehandlers.ReportConfig{
+			Source:         source,
+			DeploymentUUID: deploymentUUID,
+			Labels:         staticLabels,
+		},
+		CoverageLookback: cfg.ScanCoverageLookback,
+		WebUI:            cfg.WebUIEnabled,
+		Version:          version,
+		NodeAPI:          cfg.HostScanningEnabled,
+		RegistryCrawl:    cfg.RegistryCrawlEnabled,
+		Policy:           imagePolicy,
+		ReadOnly:         cfg.ReadOnly,
+		ComputedColumns:  computedColumns,
+		PathRedaction:    cfg.SBOMPathRedaction,
+
+		ScanFailureAlertThreshold: cfg.ScanFailureAlertThreshold,
+		StuckScanTimeout:          cfg.StuckScanTimeout,
+		RescanConfirmThreshold:    cfg.RescanConfirmThreshold,
+		DeletedImageRetention:     cfg.DeletedImageRetention,
+	}
+}
+
+// newResultCache connects the shared result cache, nil when none is configured
+func newResultCache(cfg *scannerconfig.Config, deploymentUUID string, staticLabels labels.Set) (*resultcache.Cache, error) {
+	return resultcache.New(context.Background(), resultcache.Config{
+		Backend:        cfg.ResultCacheBackend,
+		URL:            cfg.ResultCacheURL,
+		Token:          cfg.ResultCacheToken,
+		S3Bucket:       cfg.ResultCacheS3Bucket,
+		S3Prefix:       cfg.ResultCacheS3Prefix,
+		S3Region:       cfg.ResultCacheS3Region,
+		S3Endpoint:     cfg.ResultCacheS3Endpoint,
+		SigningKey:     cfg.TransferSigningKey,
+		Source:         (&K8sScanServerInfo{}).GetClusterName(),
+		DeploymentUUID: deploymentUUID,
+		Labels:         staticLabels,
+	})
+}
+
+// newFixHintFinder returns the "fix available in tag X" hints on image
+// details, nil when they are disabled
+func newFixHintFinder(db *database.DB, cfg *scannerconfig.Config, resultCache *resultcache.Cache) corehandlers.FixHintFinder {
+	if !cfg.FixHintsEnabled {
+		return nil
+	}
+	finder := fixhints.NewFinder(db, fixhints.Config{
+		RegistryLookup: cfg.FixHintsRegistryLookup,
+		MaxTags:        cfg.FixHintsMaxTags,
+	})
+	if resultCache != nil {
+		finder.SetResultCache(resultCache)
+	}
+	return finder
+}
+
+// newDiskMonitor watches the data volume holding dbPath (/api/status/disk).
+// Only the leader prunes stored SBOMs.
+func newDiskMonitor(db *database.DB, dbPath string, cfg *scannerconfig.Config, prune bool) *diskusage.Monitor {
+	return diskusage.NewMonitor(db, diskusage.Config{
+		DBPath:           dbPath,
+		Dirs:             map[string]string{"grype": filepath.Join(grypeDBRootDir(dbPath), "grype")},
+		WarningPercent:   cfg.DiskUsageWarningPercent,
+		HighWaterPercent: cfg.DiskUsageHighWaterPercent,
+		PruneEnabled:     prune && cfg.DiskUsagePruneEnabled,
+		Interval:         cfg.DiskUsageInterval,
+	})
+}
+
+// newNotifyRouter returns the alert routing: namespaces pick their
+// destinations with the bjorn2scan.io/notify annotation, others use the
+// default (/api/notify/routes). It is nil when no routing is configured.
+func newNotifyRouter(ctx context.Context, cfg *scannerconfig.Config, clientset kubernetes.Interface) (*notify.Router, error) {
+	if cfg.NotifyDefault == "" && !cfg.NotifyNamespaceRouting {
+		return nil, nil
+	}
+	defaults, err := notify.ParseDestinations(cfg.NotifyDefault)
+	if err != nil {
+		return nil, fmt.Errorf("invalid default notification destination: %w", err)
+	}
+	router := notify.NewRouter(defaults)
+	router.SetAllowedWebhookHosts(cfg.NotifyWebhookAllowedHosts)
+	if cfg.NotifyNamespaceRouting {
+		go k8s.WatchNamespaces(ctx, clientset, router)
+	}
+	return router, nil
+}
+
 func main() {
+	// "k8s-scan-server policy test <file>" checks a policy bundle and exits
+	if len(os.Args) > 1 && os.Args[1] == "policy" {
+		os.Exit(policy.RunCommand(os.Args[2:], os.Stdout))
+	}
+	// "k8s-scan-server migrate -dsn <url>" copies the database to PostgreSQL and exits
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		os.Exit(runMigrate(os.Args[2:], os.Stdout))
+	}
+
 	// Initialize structured logging from environment variables
 	// LOG_LEVEL: debug, info, warn, error (default: info)
 	// LOG_FORMAT: text, json (default: text)
@@ -290,33 +492,151 @@ func main() {
 
 	logging.For(logging.ComponentK8s).Info("k8s-scan-server starting", "version", version)
 
-	// Create Kubernetes client
+	// Create container manager
+	manager := containers.NewManager()
+
+	// Initialize database
+	dbPath := databasePath()
+
+	// Load configuration with environment variable overrides from Helm values
+	cfg, err := scannerconfig.LoadConfig("")
+	if err != nil {
+		logging.For(logging.ComponentK8s).Error("error loading configuration", "error", err)
+		os.Exit(1)
+	}
+
+	// Initialize debug configuration
+	debugConfig, err := debug.NewDebugConfigFromSettings(cfg.DebugEnabled, cfg.DebugCategories)
+	if err != nil {
+		logging.For(logging.ComponentK8s).Error("invalid debug configuration", "error", err)
+		os.Exit(1)
+	}
+	if debugConfig.IsEnabled() {
+		logging.For(logging.ComponentK8s).Info("debug mode enabled", "endpoints", "/api/debug", "categories", debugConfig.Categories())
+	}
+
+	// Create Kubernetes client, rate limited to stay below the API server's throttling
 	config, err := rest.InClusterConfig()
 	if err != nil {
 		logging.For(logging.ComponentK8s).Error("error creating Kubernetes config", "error", err)
 		os.Exit(1)
 	}
+	config.QPS = float32(cfg.KubeAPIQPS)
+	config.Burst = cfg.KubeAPIBurst
 
 	clientset, err := kubernetes.NewForConfig(config)
 	if err != nil {
 		logging.For(logging.ComponentK8s).Error("error creating Kubernetes client", "error", err)
 		os.Exit(1)
 	}
+	logging.For(logging.ComponentK8s).Info("kubernetes client created", "qps", cfg.KubeAPIQPS, "burst", cfg.KubeAPIBurst)
 
-	// Create container manager
-	manager := containers.NewManager()
+	// Static labels identify this cluster in metrics, exports and reports
+	staticLabels, err := labels.Parse(cfg.StaticLabels)
+	if err != nil {
+		logging.For(logging.ComponentK8s).Error("invalid static labels", "error", err)
+		os.Exit(1)
+	}
+	if staticLabels != nil {
+		logging.For(logging.ComponentK8s).Info("static labels configured", "labels", staticLabels.String())
+	}
 
-	// Initialize database
-	dbPath := os.Getenv("DB_PATH")
-	if dbPath == "" {
-		dbPath = "/var/lib/bjorn2scan/data/containers.db"
+	// Computed columns on the image and container lists
+	computedColumns, err := columns.Parse(cfg.ComputedColumns)
+	if err != nil {
+		logging.For(logging.ComponentK8s).Error("invalid computed columns", "error", err)
+		os.Exit(1)
+	}
+	for _, col := range computedColumns {
+		logging.For(logging.ComponentK8s).Info("computed column configured", "name", col.Name, "expression", col.Expression)
 	}
 
-	// Initialize debug configuration
-	debugEnabled := os.Getenv("DEBUG_ENABLED")
-	debugConfig := debug.NewDebugConfig(debugEnabled == "true")
-	if debugConfig.IsEnabled() {
-		logging.For(logging.ComponentK8s).Info("debug mode enabled", "endpoints", "/debug")
+	// Redaction of file paths in downloaded and exported SBOMs
+	if cfg.SBOMPathRedaction, err = sbomformat.NormalizeRedaction(cfg.SBOMPathRedaction); err != nil {
+		logging.For(logging.ComponentK8s).Error("invalid SBOM path redaction", "error", err)
+		os.Exit(1)
+	}
+	if cfg.SBOMPathRedaction != "" {
+		logging.For(logging.ComponentK8s).Info("file paths are redacted in downloaded and exported SBOMs", "mode", cfg.SBOMPathRedaction)
+	}
+
+	// API authentication (static tokens and/or OIDC; off when neither is set)
+	authenticator, err := corehandlers.NewAuthenticatorFromConfig(cfg)
+	if err != nil {
+		logging.For(logging.ComponentK8s).Error("invalid authentication settings", "error", err)
+		os.Exit(1)
+	}
+	if authenticator.Enabled() {
+		logging.For(logging.ComponentK8s).Info("API authentication enabled")
+	}
+
+	// Pass/fail policy for CI and admission decisions (/api/images/{digest}/policy,
+	// /api/policy/report)
+	imagePolicy := policy.NewBundle(policy.Default())
+	if cfg.PolicyFile != "" {
+		loaded, err := policy.LoadBundle(cfg.PolicyFile)
+		if err != nil {
+			logging.For(logging.ComponentK8s).Error("failed to load policy", "error", err)
+			os.Exit(1)
+		}
+		imagePolicy = loaded
+		logging.For(logging.ComponentK8s).Info("policy loaded", "file", cfg.PolicyFile, "policies", imagePolicy.Names())
+	}
+
+	// High availability: the replicas share the database and only the one
+	// holding the write lease opens it for writing. Until it acquires the lease
+	// a replica serves the read-only API from the database the leader writes.
+	if cfg.HAEnabled {
+		identity, err := os.Hostname()
+		if err != nil {
+			logging.For(logging.ComponentK8s).Error("failed to determine pod name", "error", err)
+			os.Exit(1)
+		}
+		namespace := os.Getenv("NAMESPACE")
+		writeLease, err := k8s.NewWriteLease(clientset, k8s.LeaseConfig{
+			Namespace:     namespace,
+			Name:          cfg.HALeaseName,
+			Identity:      identity,
+			LeaseDuration: cfg.HALeaseDuration,
+		})
+		if err != nil {
+			logging.For(logging.ComponentK8s).Error("failed to create write lease", "error", err)
+			os.Exit(1)
+		}
+		// The Service sends writes, metrics scrapes and admission reviews to
+		// the pod labelled leader; a restarted container starts as a follower
+		if err := k8s.SetPodRole(context.Background(), clientset, namespace, identity, k8s.RoleFollower); err != nil {
+			logging.For(logging.ComponentK8s).Error("failed to label pod", "error", err)
+			os.Exit(1)
+		}
+		logging.For(logging.ComponentK8s).Info("high availability enabled, waiting for the write lease",
+			"lease", cfg.HALeaseName, "identity", identity)
+
+		signalCtx, stopSignals := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+		err = writeLease.Acquire(signalCtx, func(ctx context.Context) {
+			serveFollower(ctx, followerConfig{
+				port:         port,
+				dbPath:       dbPath,
+				cfg:          cfg,
+				clientset:    clientset,
+				policy:       imagePolicy,
+				staticLabels: staticLabels,
+				columns:      computedColumns,
+				auth:         authenticator,
+			})
+		})
+		stopSignals()
+		if err != nil {
+			logging.For(logging.ComponentK8s).Info("shutdown signal received while following, k8s-scan-server stopped")
+			return
+		}
+		// Registered before the database is closed, so it runs after it
+		defer writeLease.Release()
+		if err := k8s.SetPodRole(context.Background(), clientset, namespace, identity, k8s.RoleLeader); err != nil {
+			logging.For(logging.ComponentK8s).Error("failed to label pod", "error", err)
+			writeLease.Release()
+			os.Exit(1)
+		}
 	}
 
 	// Initialize database (will auto-delete and recreate if corrupted)
@@ -327,6 +647,11 @@ func main() {
 	}
 	defer func() { _ = database.Close(db) }()
 
+	// Binary version history for /api/status/compatibility (downgrade targets)
+	if err := db.RecordBinaryVersion(version); err != nil {
+		logging.For(logging.ComponentK8s).Warn("failed to record binary version", "error", err)
+	}
+
 	// Reset any nodes/images left in transient states from a previous crash or OOM kill.
 	// Must run before watchers and the scan queue are started.
 	if err := db.ResetInterruptedScans(); err != nil {
@@ -343,12 +668,47 @@ func main() {
 	}
 	logging.For(logging.ComponentK8s).Info("deployment UUID initialized", "uuid", deploymentUUID)
 
-	// Load configuration with environment variable overrides from Helm values
-	cfg, err := scannerconfig.LoadConfig("")
+	// Merge severities (e.g. Negligible into Low) before any results are read or stored
+	severityMapping, err := severity.ParseMapping(cfg.SeverityMapping)
 	if err != nil {
-		logging.For(logging.ComponentK8s).Error("error loading configuration", "error", err)
+		logging.For(logging.ComponentK8s).Error("invalid severity mapping", "error", err)
+		os.Exit(1)
+	}
+	if err := db.SetSeverityMapping(severityMapping); err != nil {
+		logging.For(logging.ComponentK8s).Error("failed to apply severity mapping", "error", err)
 		os.Exit(1)
 	}
+	if severityMapping != nil {
+		logging.For(logging.ComponentK8s).Info("severity mapping configured", "mapping", severityMapping.String(), "levels", severityMapping.Levels())
+	}
+	db.SetSlowQueryThreshold(cfg.SlowQueryThreshold)
+
+	// OpenVEX documents from the configured path; not_affected statements
+	// suppress findings (uploads at /api/vex are kept in the database)
+	vexFiles := map[string][]byte{}
+	if cfg.VEXPath != "" {
+		if vexFiles, err = vex.ReadFiles(cfg.VEXPath); err != nil {
+			logging.For(logging.ComponentK8s).Error("failed to load VEX documents", "error", err)
+			os.Exit(1)
+		}
+	}
+	if images, err := db.SyncVEXFiles(vexFiles); err != nil {
+		logging.For(logging.ComponentK8s).Error("failed to apply VEX documents", "error", err)
+		os.Exit(1)
+	} else if len(vexFiles) > 0 {
+		logging.For(logging.ComponentK8s).Info("VEX documents loaded", "path", cfg.VEXPath, "documents", len(vexFiles), "images_reevaluated", images)
+	}
+
+	// Fault injection is for resilience testing only
+	if cfg.FaultDBWriteErrorRate > 0 {
+		if debugConfig.CategoryEnabled(debug.CategoryFaults) {
+			if err := db.SetWriteFaultRate(cfg.FaultDBWriteErrorRate); err != nil {
+				logging.For(logging.ComponentK8s).Error("invalid fault injection rate", "error", err)
+			}
+		} else {
+			logging.For(logging.ComponentK8s).Warn("FAULT_DB_WRITE_ERROR_RATE ignored: the faults debug category is not enabled")
+		}
+	}
 
 	// Connect database to manager
 	manager.SetDatabase(db)
@@ -357,11 +717,50 @@ func main() {
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
+	// Create pod-scanner client for SBOM routing. Its pod-scanner lookups share
+	// the pod watcher's informer cache, so it is registered before the watcher
+	// starts the informer.
+	podInformers := k8s.NewPodInformerFactory(clientset)
+	podScannerClient := podscanner.NewClient()
+	podScannerClient.UsePodInformer(podInformers.Core().V1().Pods())
+	if cfg.PodScannerAuthSecret != "" {
+		podScannerClient.UseAuthSecret(cfg.PodScannerAuthSecret)
+	} else {
+		logging.For(logging.ComponentK8s).Warn("POD_SCANNER_AUTH_SECRET not set, requests to pod-scanners are unsigned")
+	}
+	if cfg.PodScannerTLSCAFile != "" {
+		podScannerCerts, err := tlsreload.New(tlsreload.Files{
+			CertFile: cfg.PodScannerTLSCertFile,
+			KeyFile:  cfg.PodScannerTLSKeyFile,
+			CAFile:   cfg.PodScannerTLSCAFile,
+		})
+		if err != nil {
+			logging.For(logging.ComponentK8s).Error("failed to load pod-scanner TLS configuration", "error", err)
+			os.Exit(1)
+		}
+		podScannerClient.UseTLS(podScannerCerts.ClientConfig(cfg.PodScannerTLSServerName))
+		logging.For(logging.ComponentK8s).Info("calling pod-scanners over HTTPS",
+			"server_name", cfg.PodScannerTLSServerName, "mtls", cfg.PodScannerTLSCertFile != "")
+	}
+	if cfg.PodScannerGRPCPort != "" {
+		podScannerClient.UseGRPC(cfg.PodScannerGRPCPort)
+		logging.For(logging.ComponentK8s).Info("streaming image SBOMs from pod-scanners over gRPC", "port", cfg.PodScannerGRPCPort)
+	}
+
+	// Static pods (control-plane components) without an image ID in their
+	// status are resolved through the pod-scanner on their node
+	k8s.ResolveStaticPodImages(func(ctx context.Context, nodeName, reference string) (string, error) {
+		return podScannerClient.ResolveImageDigest(ctx, clientset, nodeName, reference)
+	})
+
 	// Start pod watcher - performs initial sync via informer cache then watches for changes
-	go k8s.WatchPods(ctx, clientset, manager)
+	go k8s.WatchPods(ctx, podInformers, manager)
 
-	// Create pod-scanner client for SBOM routing
-	podScannerClient := podscanner.NewClient()
+	// Start namespace deletion watcher - removes the containers of deleted namespaces in bulk
+	go k8s.WatchNamespaceDeletions(ctx, clientset, manager)
+
+	// Start Job pod watcher - records digests of Job pods (including completed ones) for scan coverage
+	go k8s.WatchJobPods(ctx, clientset, db, cfg.ScanCoverageLookback)
 
 	// Initialize node manager for host scanning (if enabled)
 	var nodeManager *nodes.Manager
@@ -375,19 +774,14 @@ func main() {
 	}
 
 	// Create SBOM retriever function that uses pod-scanner
+	// SBOMs for other queued images on the same node are fetched in the same request
+	batchRetriever := podscanner.NewBatchRetriever(podScannerClient, clientset, cfg.SBOMBatchSize)
 	sbomRetriever := func(ctx context.Context, image containers.ImageID, nodeName string, runtime string) ([]byte, error) {
-		return podScannerClient.GetSBOMFromNode(ctx, clientset, nodeName, image.Digest)
+		return batchRetriever.GetSBOM(ctx, nodeName, image.Digest)
 	}
 
 	// Configure Grype database location
-	// GRYPE_DB_PATH allows storing the grype database separately from the main data
-	// This is important for NFS deployments where grype's in-place database updates
-	// can fail due to NFS "silly rename" behavior when files are replaced while open
-	grypeDBPath := os.Getenv("GRYPE_DB_PATH")
-	if grypeDBPath == "" {
-		// Default: use the same directory as the main database
-		grypeDBPath = filepath.Dir(dbPath)
-	}
+	grypeDBPath := grypeDBRootDir(dbPath)
 	grypeCfg := grype.Config{
 		DBRootDir: grypeDBPath,
 	}
@@ -399,18 +793,80 @@ func main() {
 	// Create scan queue for automatic SBOM generation and vulnerability scanning
 	// Using default queue config (unbounded queue with single worker)
 	queueConfig := scanning.QueueConfig{
-		MaxDepth:     0, // Unbounded
-		FullBehavior: scanning.QueueFullDrop,
+		MaxDepth:        0, // Unbounded
+		FullBehavior:    scanning.QueueFullDrop,
+		MaxAttempts:     cfg.ScanMaxAttempts,
+		RetryBackoff:    cfg.ScanRetryBackoff,
+		RetryMaxBackoff: cfg.ScanRetryMaxBackoff,
+
+		PriorityNamespaces: cfg.ScanPriorityNamespaces,
 	}
 	scanQueue := scanning.NewJobQueue(db, sbomRetriever, grypeCfg, queueConfig)
 	defer scanQueue.Shutdown()
 
+	// Let the SBOM retriever look ahead at queued jobs for batching
+	batchRetriever.SetPendingSource(scanQueue)
+
 	// Connect scan queue to DB readiness state so it waits for grype DB before processing vuln scans
 	scanQueue.SetDBReadinessChecker(dbReadinessState)
 
+	// Connect the shared result cache (if configured) so identical digests scanned elsewhere are reused
+	resultCache, err := newResultCache(cfg, deploymentUUID.String(), staticLabels)
+	if err != nil {
+		logging.For(logging.ComponentK8s).Error("failed to initialize result cache", "error", err)
+		os.Exit(1)
+	}
+	if resultCache != nil {
+		scanQueue.SetResultCache(resultCache)
+	}
+
+	// Register external scan lifecycle hooks (if configured)
+	for stage, command := range map[scanning.HookStage]string{
+		scanning.HookPostSBOM:     cfg.ScanHookPostSBOM,
+		scanning.HookPostVulnScan: cfg.ScanHookPostVulnScan,
+		scanning.HookPrePersist:   cfg.ScanHookPrePersist,
+	} {
+		if command != "" {
+			scanQueue.AddHook(stage, scanning.ExecHook(command, cfg.ScanHookTimeout))
+		}
+	}
+
+	// Pause or throttle rescans during business hours (if configured)
+	if cfg.ScanQuietHours != "" {
+		quietHours, err := scanning.NewQuietHours(cfg.ScanQuietHours, cfg.ScanQuietHoursTimezone,
+			scanning.QuietMode(cfg.ScanQuietHoursMode), cfg.ScanQuietHoursInterval)
+		if err != nil {
+			logging.For(logging.ComponentK8s).Error("invalid scan quiet hours", "error", err)
+			os.Exit(1)
+		}
+		scanQueue.SetQuietHours(quietHours)
+		logging.For(logging.ComponentK8s).Info("scan quiet hours configured", "windows", cfg.ScanQuietHours,
+			"timezone", quietHours.Location.String(), "mode", quietHours.Mode)
+	}
+
 	// Connect scan queue to manager
 	manager.SetScanQueue(scanQueue)
 
+	// Pause scans on cordoned and draining nodes; their queued images are
+	// rerouted to other nodes running them
+	go k8s.WatchNodeCordons(ctx, clientset, scanQueue)
+
+	// Ad-hoc and registry crawl scans pull the image from its registry on any pod-scanner
+	scanQueue.SetRegistrySBOMRetriever(func(ctx context.Context, image containers.ImageID) ([]byte, error) {
+		return podScannerClient.GetRegistrySBOM(ctx, clientset, adhoc.PinnedReference(image))
+	})
+
+	// Vendor images use the SBOM their vendor attested, when its signature verifies
+	if len(cfg.VendorSBOMImages) > 0 {
+		vendorSBOMs, err := provenance.NewVendorSBOMs(cfg.VendorSBOMImages, cfg.VendorSBOMPublicKeys)
+		if err != nil {
+			logging.For(logging.ComponentK8s).Error("invalid vendor SBOM settings", "error", err)
+			os.Exit(1)
+		}
+		scanQueue.SetVendorSBOMRetriever(vendorSBOMs.Retrieve)
+		logging.For(logging.ComponentK8s).Info("vendor SBOMs configured", "images", cfg.VendorSBOMImages)
+	}
+
 	// Configure host SBOM retriever and connect node manager (if enabled)
 	if nodeManager != nil {
 		// Create host SBOM retriever that calls pod-scanner on the target node
@@ -422,8 +878,16 @@ func main() {
 		logging.For(logging.ComponentK8s).Info("host scanning configured and ready")
 	}
 
+	// OS end-of-life data: bundled, refreshed by the update-os-eol job
+	osLifecycle, err := eol.NewCatalog(cfg.OSEOLWarningDays)
+	if err != nil {
+		logging.For(logging.ComponentK8s).Error("failed to load OS end-of-life data", "error", err)
+		os.Exit(1)
+	}
+
 	// Initialize scheduler for periodic jobs
 	var sched *scheduler.Scheduler
+	var feedChecker vulndb.FeedChecker // set when the rescan-database job updates the vulnerability database
 	if cfg.JobsEnabled {
 		logging.For(logging.ComponentK8s).Info("initializing scheduled jobs")
 		sched = scheduler.New()
@@ -437,10 +901,12 @@ func main() {
 			if err != nil {
 				logging.For(logging.ComponentK8s).Warn("failed to create database updater", "error", err)
 			} else {
+				feedChecker = dbUpdater
 				rescanJob := jobs.NewRescanDatabaseJob(dbUpdater, db, scanQueue)
 				// Connect readiness state so db-updater can mark pod ready after successful DB update
 				// This fixes the case where initial download fails but db-updater succeeds later
 				rescanJob.SetReadinessSetter(dbReadinessState)
+				rescanJob.SetStuckScanMaxAge(cfg.StuckScanTimeout)
 				// Connect node scanning if enabled - nodes will also be rescanned when grype DB updates
 				if cfg.HostScanningEnabled {
 					rescanJob.SetNodeScanning(db, scanQueue)
@@ -479,12 +945,153 @@ func main() {
 			logging.For(logging.ComponentK8s).Info("scheduled cleanup-orphaned-images job", "interval", cfg.JobsCleanupInterval, "timeout", cfg.JobsCleanupTimeout)
 		}
 
+		// Add purge job - removes images soft-deleted by the cleanup job
+		if cfg.JobsPurgeEnabled {
+			purgeJob := jobs.NewPurgeDeletedImagesJob(db, cfg.DeletedImageRetention)
+			purgeJob.SetScanHistoryRetention(cfg.ScanHistoryRetention)
+			purgeJob.SetDryRun(cfg.JobsPurgeDryRun)
+			if err := sched.AddJob(
+				purgeJob,
+				scheduler.NewIntervalSchedule(cfg.JobsPurgeInterval),
+				scheduler.JobConfig{
+					Enabled: true,
+					Timeout: cfg.JobsPurgeTimeout,
+				},
+			); err != nil {
+				logging.For(logging.ComponentK8s).Error("failed to add purge job", "error", err)
+				os.Exit(1)
+			}
+			logging.For(logging.ComponentK8s).Info("scheduled purge-deleted-images job", "interval", cfg.JobsPurgeInterval, "retention", cfg.DeletedImageRetention, "scan_history_retention", cfg.ScanHistoryRetention, "dry_run", cfg.JobsPurgeDryRun)
+		}
+
+		// Add stuck scans job - fails and requeues images that stopped making progress
+		if cfg.JobsStuckScansEnabled {
+			if err := sched.AddJob(
+				jobs.NewRecoverStuckScansJob(db, scanQueue, cfg.StuckScanTimeout, cfg.ScanMaxAttempts),
+				scheduler.NewIntervalSchedule(cfg.JobsStuckScansInterval),
+				scheduler.JobConfig{
+					Enabled: true,
+					Timeout: 5 * time.Minute,
+				},
+			); err != nil {
+				logging.For(logging.ComponentK8s).Error("failed to add recover stuck scans job", "error", err)
+				os.Exit(1)
+			}
+			logging.For(logging.ComponentK8s).Info("scheduled recover-stuck-scans job", "interval", cfg.JobsStuckScansInterval, "timeout", cfg.StuckScanTimeout)
+		}
+
+		// Add OS end-of-life data update job
+		if cfg.OSEOLDataURL != "" {
+			if err := sched.AddJob(
+				jobs.NewUpdateOSEOLJob(osLifecycle, cfg.OSEOLDataURL),
+				scheduler.NewIntervalSchedule(cfg.OSEOLUpdateInterval),
+				scheduler.JobConfig{
+					Enabled:        true,
+					Timeout:        5 * time.Minute,
+					RunImmediately: true,
+				},
+			); err != nil {
+				logging.For(logging.ComponentK8s).Error("failed to add OS end-of-life update job", "error", err)
+				os.Exit(1)
+			}
+			logging.For(logging.ComponentK8s).Info("scheduled update-os-eol job", "interval", cfg.OSEOLUpdateInterval, "source", cfg.OSEOLDataURL)
+		}
+
+		// Add image provenance check job
+		if cfg.ProvenanceEnabled {
+			provenanceFetcher, err := provenance.NewVerifyingFetcher(cfg.ProvenancePublicKeys)
+			if err != nil {
+				logging.For(logging.ComponentK8s).Error("invalid provenance public keys", "error", err)
+				os.Exit(1)
+			}
+			if err := sched.AddJob(
+				jobs.NewCheckProvenanceJob(db, provenanceFetcher, cfg.ProvenanceRecheckInterval),
+				scheduler.NewIntervalSchedule(cfg.ProvenanceCheckInterval),
+				scheduler.JobConfig{
+					Enabled:        true,
+					Timeout:        10 * time.Minute,
+					RunImmediately: true,
+				},
+			); err != nil {
+				logging.For(logging.ComponentK8s).Error("failed to add check provenance job", "error", err)
+				os.Exit(1)
+			}
+			logging.For(logging.ComponentK8s).Info("scheduled check-provenance job", "interval", cfg.ProvenanceCheckInterval, "recheck_after", cfg.ProvenanceRecheckInterval, "verify_signatures", cfg.ProvenancePublicKeys != "")
+		}
+
+		// Add registry crawl job
+		if cfg.RegistryCrawlEnabled {
+			crawler := registrycrawl.NewCrawler(db, scanQueue, registrycrawl.Config{
+				Repositories:         cfg.RegistryCrawlRepositories,
+				Tags:                 cfg.RegistryCrawlTags,
+				MaxTagsPerRepository: cfg.RegistryCrawlMaxTagsPerRepository,
+				MaxImages:            cfg.RegistryCrawlMaxImages,
+				MaxScansPerRun:       cfg.RegistryCrawlMaxScansPerRun,
+				// Images survive one failed crawl before they are removed
+				Retention: 2 * cfg.RegistryCrawlInterval,
+			})
+			if err := sched.AddJob(
+				jobs.NewRegistryCrawlJob(crawler),
+				scheduler.NewIntervalSchedule(cfg.RegistryCrawlInterval),
+				scheduler.JobConfig{
+					Enabled:        true,
+					Timeout:        30 * time.Minute,
+					RunImmediately: true,
+				},
+			); err != nil {
+				logging.For(logging.ComponentK8s).Error("failed to add registry crawl job", "error", err)
+				os.Exit(1)
+			}
+			logging.For(logging.ComponentK8s).Info("scheduled registry-crawl job", "interval", cfg.RegistryCrawlInterval, "repositories", cfg.RegistryCrawlRepositories)
+		}
+
+		// Add CMDB sync job
+		if cfg.CMDBSyncURL != "" {
+			if err := sched.AddJob(
+				jobs.NewCMDBSyncJob(cmdb.NewFetcher(cfg.CMDBSyncURL, cfg.CMDBSyncToken), db),
+				scheduler.NewIntervalSchedule(cfg.CMDBSyncInterval),
+				scheduler.JobConfig{
+					Enabled:        true,
+					Timeout:        5 * time.Minute,
+					RunImmediately: true,
+				},
+			); err != nil {
+				logging.For(logging.ComponentK8s).Error("failed to add CMDB sync job", "error", err)
+				os.Exit(1)
+			}
+			logging.For(logging.ComponentK8s).Info("scheduled cmdb-sync job", "interval", cfg.CMDBSyncInterval)
+		}
+
+		// Add result export job
+		if cfg.PostgresExportDSN != "" {
+			sink, err := database.NewPostgresSink(ctx, cfg.PostgresExportDSN, deploymentUUID.String(), clusterName())
+			if err != nil {
+				logging.For(logging.ComponentK8s).Error("failed to connect to PostgreSQL export database", "error", err)
+				os.Exit(1)
+			}
+			defer func() { _ = sink.Close() }()
+			if err := sched.AddJob(
+				jobs.NewExportResultsJob(db, sink),
+				scheduler.NewIntervalSchedule(cfg.PostgresExportInterval),
+				scheduler.JobConfig{
+					Enabled:        true,
+					Timeout:        30 * time.Minute,
+					RunImmediately: true,
+				},
+			); err != nil {
+				logging.For(logging.ComponentK8s).Error("failed to add result export job", "error", err)
+				os.Exit(1)
+			}
+			logging.For(logging.ComponentK8s).Info("scheduled export-results job", "interval", cfg.PostgresExportInterval)
+		}
+
 		// Start scheduler
 		if err := sched.Start(ctx); err != nil {
 			logging.For(logging.ComponentK8s).Error("failed to start scheduler", "error", err)
 			os.Exit(1)
 		}
 		logging.For(logging.ComponentK8s).Info("scheduler started")
+		metrics.RegisterExtraWriter(sched.WriteMetrics)
 	}
 
 	// Setup HTTP server
@@ -496,10 +1103,11 @@ func main() {
 		servicePort = "80" // Default service port
 	}
 
-	infoProvider := NewK8sScanServerInfo(port, cfg.WebUIEnabled, consoleURL, clientset, serviceName, servicePort)
+	infoProvider := NewK8sScanServerInfo(port, cfg.TLSCertFile != "", cfg.WebUIEnabled, consoleURL, clientset, serviceName, servicePort)
 
 	// Connect database readiness state for grype DB status reporting in metrics
 	infoProvider.SetDBReadinessState(dbReadinessState)
+	infoProvider.SetEffectiveConfig(cfg)
 
 	// Start periodic refresh for console URL detection (only if auto-detecting)
 	// This handles LoadBalancer IPs that aren't available immediately at startup
@@ -511,36 +1119,117 @@ func main() {
 	// Runs in background every 5 minutes so slow NFS I/O does not affect /health.
 	database.StartWALMonitor(ctx, db)
 
+	// Restore the findings of expired vulnerability ignores.
+	db.StartIgnoreExpiry(ctx)
+
 	// Warm the node vulnerability metrics cache so /metrics scrapes don't hit NFS.
 	db.StartNodeVulnCacheRefresh(ctx)
 	// Same for the container × image_vulnerability join.
 	db.StartContainerVulnCacheRefresh(ctx)
 
-	mux := http.NewServeMux()
+	// Apply background table rewrites; progress is served at /api/status/migration
+	go func() {
+		if err := db.RunOnlineMigrations(ctx); err != nil && ctx.Err() == nil {
+			logging.For(logging.ComponentDatabase).Error("online migrations failed", "error", err)
+		}
+	}()
+
+	// Watch the data volume; prunes stored SBOMs before it fills up (/api/status/disk)
+	diskMonitor := newDiskMonitor(db, dbPath, cfg, true)
+	diskMonitor.Start(ctx)
+	metrics.RegisterExtraWriter(diskMonitor.WriteMetrics)
+	metrics.RegisterExtraWriter(podScannerClient.WriteUsageMetrics)
+	metrics.RegisterExtraWriter(scanQueue.WriteMetrics)
+
+	reg := routes.NewRegistry(corehandlers.AuthMiddleware(authenticator))
 
 	// Register standard handlers
-	corehandlers.RegisterHandlers(mux, infoProvider, db)
+	corehandlers.RegisterHandlers(reg, infoProvider, db)
+	corehandlers.RegisterAuthHandlers(reg, authenticator)
 
 	// Register database readiness handlers (/ready, /api/db/status, /api/debug/db/reinit)
-	corehandlers.RegisterDatabaseReadinessHandlers(mux, dbReadinessState)
+	corehandlers.RegisterDatabaseReadinessHandlers(reg, dbReadinessState)
 
-	// Register database handlers (use all default handlers)
-	corehandlers.RegisterDatabaseHandlers(mux, db, nil)
+	// "Fix available in tag X" hints on image details
+	fixHints := newFixHintFinder(db, cfg, resultCache)
+
+	// On-demand scans of images not running in the cluster (POST /api/scan)
+	var adHocScanner corehandlers.AdHocScanner
+	if cfg.AdHocScanEnabled {
+		adHocScanner = adhoc.NewScanner(db, scanQueue, cfg.AdHocScanRetention)
+		logging.For(logging.ComponentK8s).Info("ad-hoc scans enabled", "retention", cfg.AdHocScanRetention)
+	}
 
-	// Register static file handlers (web UI) only if enabled
-	if cfg.WebUIEnabled {
-		corehandlers.RegisterStaticHandlers(mux)
+	// Start workload watcher - resolves images in Deployment/StatefulSet specs before
+	// their pods start, optionally scanning them from the registry right away
+	if cfg.UpcomingImagesEnabled {
+		upcoming := adhoc.NewScanner(db, scanQueue, cfg.AdHocScanRetention)
+		go k8s.WatchWorkloads(ctx, clientset, upcoming, db, cfg.UpcomingImagesScan)
+		logging.For(logging.ComponentK8s).Info("upcoming image resolution enabled", "scan", cfg.UpcomingImagesScan)
 	}
 
+	// Alert routing: namespaces pick their destinations with the bjorn2scan.io/notify
+	// annotation, others use the default (/api/notify/routes)
+	notifyRouter, err := newNotifyRouter(ctx, cfg, clientset)
+	if err != nil {
+		logging.For(logging.ComponentK8s).Error("failed to configure notification routing", "error", err)
+		os.Exit(1)
+	}
+	if notifyRouter != nil {
+		logging.For(logging.ComponentK8s).Info("notification routing configured",
+			"default", cfg.NotifyDefault, "namespace_routing", cfg.NotifyNamespaceRouting)
+	}
+
+	// Alert the routed destinations when a scan introduces new vulnerabilities
+	// in a running image
+	if notifyRouter != nil {
+		thresholds, err := notify.ParseThresholds(cfg.NotifyThresholds)
+		if err != nil {
+			logging.For(logging.ComponentK8s).Error("invalid notification thresholds", "error", err)
+			os.Exit(1)
+		}
+		notify.NewNotifier(notifyRouter, db, notify.AlertConfig{
+			Thresholds:        thresholds,
+			KnownExploited:    cfg.NotifyKnownExploited,
+			FirstScan:         cfg.NotifyFirstScan,
+			Namespaces:        cfg.NotifyNamespaces,
+			ExcludeNamespaces: cfg.NotifyExcludeNamespaces,
+			SlackWebhookURL:   cfg.NotifySlackWebhookURL,
+			Source:            infoProvider.GetClusterName(),
+		}).AddHooks(scanQueue)
+		logging.For(logging.ComponentK8s).Info("vulnerability alerts enabled",
+			"thresholds", cfg.NotifyThresholds, "known_exploited", cfg.NotifyKnownExploited, "first_scan", cfg.NotifyFirstScan)
+	}
+
+	// Register the database-backed REST API: queries, import/export
+	// (/api/export/images/{digest}, /api/import), badges (/api/badge/...),
+	// offline report (/api/report), coverage (/api/summary/coverage),
+	// the web UI if enabled and node endpoints if host scanning is enabled
+	nodeReporter := podscanner.NewNodeReporter(podScannerClient, clientset)
+	nodeReporter.SetPauseSource(scanQueue)
+	apiOptions := sharedAPIOptions(cfg, infoProvider.GetClusterName(), deploymentUUID.String(), staticLabels, imagePolicy, computedColumns)
+	apiOptions.FixHints = fixHints
+	apiOptions.DiskUsage = diskMonitor
+	apiOptions.OSLifecycle = osLifecycle
+	apiOptions.AdHocScan = adHocScanner
+	apiOptions.DeadLetter = scanQueue
+	apiOptions.ScanNow = scanQueue
+	apiOptions.Rescan = scanQueue
+	apiOptions.ScanQueue = scanQueue
+	apiOptions.NodeScanners = nodeReporter
+	apiOptions.NotifyRouter = notifyRouter
+	corehandlers.RegisterAPIHandlers(reg, db, apiOptions)
+
 	// Register debug handlers if debug mode is enabled
-	corehandlers.RegisterDebugHandlers(mux, db, debugConfig, scanQueue)
+	corehandlers.RegisterDebugHandlers(reg, db, debugConfig, scanQueue)
 
 	// Register jobs debug handlers for listing, triggering, and viewing execution history
-	corehandlers.RegisterJobsHandlersWithDB(mux, sched, db)
+	corehandlers.RegisterJobsHandlersWithDB(reg, sched, db)
+
+	// Register vulnerability database status and forced refresh
+	corehandlers.RegisterVulnDBHandlers(reg, feedChecker, sched)
 
-	// Register node API handlers (if host scanning is enabled)
 	if cfg.HostScanningEnabled {
-		corehandlers.RegisterNodeHandlers(mux, db)
 		logging.For(logging.ComponentK8s).Info("node API endpoints registered", "endpoints", "/api/nodes, /api/nodes/{name}, /api/summary/by-node")
 	}
 
@@ -558,14 +1247,32 @@ func main() {
 		NodeVulnerabilityRiskEnabled:      cfg.MetricsNodeVulnerabilityRiskEnabled && cfg.HostScanningEnabled,
 		NodeVulnerabilityExploitedEnabled: cfg.MetricsNodeVulnerabilityExploitedEnabled && cfg.HostScanningEnabled,
 		StalenessWindow:                   int64(cfg.MetricsStalenessWindow.Seconds()),
+		StaticLabels:                      staticLabels,
 	}
 
 	// Create staleness store (DB-backed, shared between /metrics and OTEL)
 	staleness := metrics.NewStalenessStore(db, cfg.MetricsStalenessWindow)
 	logging.For(logging.ComponentK8s).Info("metric staleness tracking enabled", "window", cfg.MetricsStalenessWindow)
 
+	// Export scan coverage on /metrics (bjorn2scan_scan_coverage_ratio)
+	metrics.RegisterCoverageMetrics(db, cfg.ScanCoverageLookback)
+
+	// Export OS end-of-life status on /metrics (bjorn2scan_os_lifecycle_images)
+	metrics.RegisterOSEOLMetrics(db, osLifecycle)
+
+	// Export failing images per node and failure reason on /metrics (bjorn2scan_scan_failure_alert)
+	metrics.RegisterScanFailureMetrics(db, cfg.ScanFailureAlertThreshold)
+
 	// Register Prometheus metrics endpoint
-	metrics.RegisterMetricsHandler(mux, infoProvider, deploymentUUID.String(), db, unifiedConfig, staleness)
+	metrics.RegisterMetricsHandler(reg, infoProvider, deploymentUUID.String(), db, unifiedConfig, staleness)
+
+	// Register namespace-scoped metrics endpoints (/metrics/namespace/{name})
+	namespaceTokens, err := metrics.ParseNamespaceTokens(cfg.MetricsNamespaceTokens)
+	if err != nil {
+		logging.For(logging.ComponentK8s).Error("invalid metrics namespace tokens", "error", err)
+		os.Exit(1)
+	}
+	metrics.RegisterNamespaceMetricsHandler(reg, infoProvider, deploymentUUID.String(), db, unifiedConfig, staleness, namespaceTokens)
 
 	// Initialize OpenTelemetry metrics exporter if enabled
 	var otelExporter *metrics.OTELExporter
@@ -594,15 +1301,66 @@ func main() {
 		}
 	}
 
+	// Compress JSON responses for clients that accept zstd or gzip
+	var handler http.Handler = corehandlers.CompressionMiddleware(reg)
+
+	// Reject mutating requests in read-only mode
+	if cfg.ReadOnly {
+		handler = corehandlers.ReadOnlyMiddleware(handler)
+		logging.For(logging.ComponentK8s).Info("read-only mode enabled, mutating endpoints are disabled")
+	}
+
 	// Wrap with logging middleware if debug enabled
-	var handler http.Handler = mux
 	if debugConfig.IsEnabled() {
-		handler = debug.LoggingMiddleware(debugConfig, mux)
+		handler = debug.LoggingMiddleware(debugConfig, handler)
 	}
+	handler = metrics.InstrumentHandler(handler)
 
+	serverTLSConfig, handler, err := serverTLS(cfg, handler)
+	if err != nil {
+		logging.For(logging.ComponentK8s).Error("failed to load TLS certificate", "error", err)
+		os.Exit(1)
+	}
 	server := &http.Server{
-		Addr:    ":" + port,
-		Handler: handler,
+		Addr:      ":" + port,
+		Handler:   handler,
+		TLSConfig: serverTLSConfig,
+	}
+
+	// Validating admission webhook, served over HTTPS on its own port since
+	// the API server only calls webhooks with TLS
+	var admissionServer *http.Server
+	if cfg.AdmissionEnabled {
+		if cfg.AdmissionTLSCertFile == "" || cfg.AdmissionTLSKeyFile == "" {
+			logging.For(logging.ComponentK8s).Error("admission webhook needs a TLS certificate and key (ADMISSION_TLS_CERT_FILE, ADMISSION_TLS_KEY_FILE)")
+			os.Exit(1)
+		}
+		admissionMux := http.NewServeMux()
+		admissionMux.Handle("/validate", admission.Handler(db, imagePolicy, admission.Options{
+			FailClosed:        cfg.AdmissionFailClosed,
+			ExcludeNamespaces: cfg.AdmissionExcludeNamespaces,
+		}))
+		admissionCerts, err := tlsreload.New(tlsreload.Files{CertFile: cfg.AdmissionTLSCertFile, KeyFile: cfg.AdmissionTLSKeyFile})
+		if err != nil {
+			logging.For(logging.ComponentK8s).Error("failed to load admission webhook certificate", "error", err)
+			os.Exit(1)
+		}
+		admissionServer = &http.Server{
+			Addr:              ":" + cfg.AdmissionPort,
+			Handler:           admissionMux,
+			TLSConfig:         admissionCerts.ServerConfig(),
+			ReadHeaderTimeout: 10 * time.Second,
+		}
+		go func() {
+			logging.For(logging.ComponentK8s).Info("admission webhook listening",
+				"port", cfg.AdmissionPort,
+				"fail_closed", cfg.AdmissionFailClosed,
+				"policies", imagePolicy.Names())
+			if err := admissionServer.ListenAndServeTLS("", ""); err != nil && err != http.ErrServerClosed {
+				logging.For(logging.ComponentK8s).Error("admission webhook error", "error", err)
+				os.Exit(1)
+			}
+		}()
 	}
 
 	// Handle shutdown gracefully
@@ -610,8 +1368,8 @@ func main() {
 	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
 
 	go func() {
-		logging.For(logging.ComponentK8s).Info("k8s-scan-server listening", "port", port)
-		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		logging.For(logging.ComponentK8s).Info("k8s-scan-server listening", "port", port, "tls", server.TLSConfig != nil)
+		if err := listenAndServe(server); err != nil && err != http.ErrServerClosed {
 			logging.For(logging.ComponentK8s).Error("server error", "error", err)
 			os.Exit(1)
 		}
@@ -639,6 +1397,11 @@ func main() {
 	if err := server.Shutdown(shutdownCtx); err != nil {
 		logging.For(logging.ComponentK8s).Error("error during shutdown", "error", err)
 	}
+	if admissionServer != nil {
+		if err := admissionServer.Shutdown(shutdownCtx); err != nil {
+			logging.For(logging.ComponentK8s).Error("error shutting down admission webhook", "error", err)
+		}
+	}
 
 	logging.For(logging.ComponentK8s).Info("k8s-scan-server stopped")
 }
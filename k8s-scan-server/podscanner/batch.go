@@ -0,0 +1,267 @@
+package podscanner
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"k8s.io/client-go/kubernetes"
+)
+
+// ErrBatchUnsupported is returned when the pod-scanner predates the /sboms endpoint
+var ErrBatchUnsupported = errors.New("pod-scanner does not support batch SBOM requests")
+
+// SBOMResult is the outcome for one digest of a batch SBOM request
+type SBOMResult struct {
+	Digest string
+	SBOM   []byte
+	Err    error
+}
+
+// batchSBOMResult is one NDJSON line of the pod-scanner /sboms response
+type batchSBOMResult struct {
+	Digest string          `json:"digest"`
+	Status int             `json:"status"`
+	SBOM   json.RawMessage `json:"sbom,omitempty"`
+	Error  string          `json:"error,omitempty"`
+	Usage  *ScanUsage      `json:"usage,omitempty"`
+}
+
+// GetSBOMsFromNode requests SBOMs for several digests from the pod-scanner on
+// a node in a single request. fn is called for each result as it is streamed
+// back, in completion order. Digests without a result are left to the caller.
+func (c *Client) GetSBOMsFromNode(ctx context.Context, clientset kubernetes.Interface, nodeName string, digests []string, fn func(SBOMResult)) error {
+	pod, err := c.waitForPodScannerPod(ctx, clientset, nodeName)
+	if err != nil {
+		return err
+	}
+
+	log.Info("requesting SBOM batch from pod-scanner", "node", nodeName, "digests", len(digests))
+	return c.fetchSBOMBatch(ctx, nodeName, c.podURL(pod), digests, fn)
+}
+
+// fetchSBOMBatch posts digests to baseURL/sboms and decodes the streamed results
+func (c *Client) fetchSBOMBatch(ctx context.Context, nodeName, baseURL string, digests []string, fn func(SBOMResult)) error {
+	body, err := json.Marshal(map[string][]string{"digests": digests})
+	if err != nil {
+		return fmt.Errorf("failed to encode batch request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", baseURL+"/sboms", bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	// The whole stream can outlast the per-SBOM client timeout; ctx bounds the batch instead
+	httpClient := &http.Client{Transport: c.httpClient.Transport}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to request SBOM batch from pod-scanner: %w", err)
+	}
+	defer func() {
+		if closeErr := resp.Body.Close(); closeErr != nil {
+			log.Warn("failed to close response body", "error", closeErr)
+		}
+	}()
+
+	if resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusMethodNotAllowed {
+		return ErrBatchUnsupported
+	}
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("pod-scanner returned status %d: %s", resp.StatusCode, string(body))
+	}
+
+	dec := json.NewDecoder(resp.Body)
+	for {
+		var line batchSBOMResult
+		if err := dec.Decode(&line); err == io.EOF {
+			return nil
+		} else if err != nil {
+			return fmt.Errorf("failed to read SBOM batch response: %w", err)
+		}
+
+		result := SBOMResult{Digest: line.Digest}
+		if line.Status == http.StatusOK && len(line.SBOM) > 0 {
+			result.SBOM = line.SBOM
+			c.usage.record(nodeName, scanKindImage, line.Digest, line.Usage)
+		} else {
+			result.Err = fmt.Errorf("pod-scanner returned status %d: %s", line.Status, line.Error)
+		}
+		fn(result)
+	}
+}
+
+// PendingDigestSource lists the digests of queued scan jobs on a node that
+// still need an SBOM (implemented by scanning.JobQueue)
+type PendingDigestSource interface {
+	PendingSBOMDigests(nodeName string) []string
+}
+
+// prefetchedSBOM is an SBOM requested ahead of its scan job. done is closed
+// once the result is in; retry is set when the batch request failed before
+// the pod-scanner returned a result for the digest.
+type prefetchedSBOM struct {
+	done    chan struct{}
+	sbom    []byte
+	err     error
+	retry   bool
+	created time.Time
+}
+
+// prefetchTTL bounds how long an unclaimed prefetched SBOM is kept in memory
+const prefetchTTL = 15 * time.Minute
+
+// BatchRetriever retrieves image SBOMs from pod-scanners. When an SBOM is
+// requested it also asks the same pod-scanner for the SBOMs of other queued
+// jobs on that node, so a backlog is served over a few connections instead
+// of one request per image. Prefetched SBOMs are handed to their jobs when
+// the queue reaches them.
+type BatchRetriever struct {
+	client    *Client
+	clientset kubernetes.Interface
+	batchSize int
+
+	mu         sync.Mutex
+	pending    PendingDigestSource
+	prefetched map[string]*prefetchedSBOM
+}
+
+// NewBatchRetriever creates a BatchRetriever fetching up to batchSize SBOMs per request.
+// A batchSize of 1 or less disables batching.
+func NewBatchRetriever(client *Client, clientset kubernetes.Interface, batchSize int) *BatchRetriever {
+	return &BatchRetriever{
+		client:     client,
+		clientset:  clientset,
+		batchSize:  batchSize,
+		prefetched: make(map[string]*prefetchedSBOM),
+	}
+}
+
+// SetPendingSource sets where upcoming jobs are looked up; without it every
+// SBOM is requested individually
+func (b *BatchRetriever) SetPendingSource(src PendingDigestSource) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.pending = src
+}
+
+// GetSBOM returns the SBOM for digest from the pod-scanner on nodeName
+func (b *BatchRetriever) GetSBOM(ctx context.Context, nodeName string, digest string) ([]byte, error) {
+	b.mu.Lock()
+	b.pruneLocked(time.Now())
+	if entry, ok := b.prefetched[digest]; ok {
+		delete(b.prefetched, digest)
+		b.mu.Unlock()
+
+		select {
+		case <-entry.done:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		if entry.retry {
+			log.Debug("prefetch failed, requesting SBOM individually", "node", nodeName, "digest", digest, "error", entry.err)
+			return b.client.GetSBOMFromNode(ctx, b.clientset, nodeName, digest)
+		}
+		log.Debug("using prefetched SBOM", "node", nodeName, "digest", digest)
+		return entry.sbom, entry.err
+	}
+	pending := b.pending
+	b.mu.Unlock()
+
+	var upcoming []string
+	if pending != nil && b.batchSize > 1 {
+		upcoming = pending.PendingSBOMDigests(nodeName)
+	}
+
+	// Register the batch under the lock so concurrent callers don't request the same digests
+	b.mu.Lock()
+	digests := []string{digest}
+	entries := map[string]*prefetchedSBOM{digest: {done: make(chan struct{})}}
+	now := time.Now()
+	for _, d := range upcoming {
+		if len(digests) >= b.batchSize {
+			break
+		}
+		if _, ok := entries[d]; ok {
+			continue
+		}
+		if _, ok := b.prefetched[d]; ok {
+			continue
+		}
+		entry := &prefetchedSBOM{done: make(chan struct{}), created: now}
+		entries[d] = entry
+		b.prefetched[d] = entry
+		digests = append(digests, d)
+	}
+	b.mu.Unlock()
+
+	if len(digests) == 1 {
+		return b.client.GetSBOMFromNode(ctx, b.clientset, nodeName, digest)
+	}
+
+	// The batch keeps streaming after this job's SBOM arrives, so it must not be
+	// bound to the caller's context
+	primary := entries[digest]
+	go b.fetchBatch(nodeName, digests, entries)
+
+	select {
+	case <-primary.done:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	if primary.retry {
+		return b.client.GetSBOMFromNode(ctx, b.clientset, nodeName, digest)
+	}
+	return primary.sbom, primary.err
+}
+
+// fetchBatch requests digests from the pod-scanner on nodeName and completes their entries
+func (b *BatchRetriever) fetchBatch(nodeName string, digests []string, entries map[string]*prefetchedSBOM) {
+	// Allow each SBOM the same time as an individual request
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(len(digests))*b.client.httpClient.Timeout)
+	defer cancel()
+
+	err := b.client.GetSBOMsFromNode(ctx, b.clientset, nodeName, digests, func(result SBOMResult) {
+		entry, ok := entries[result.Digest]
+		if !ok {
+			return
+		}
+		delete(entries, result.Digest)
+		entry.sbom, entry.err = result.SBOM, result.Err
+		close(entry.done)
+	})
+	if err != nil && !errors.Is(err, ErrBatchUnsupported) {
+		log.Warn("SBOM batch request failed", "node", nodeName, "digests", len(digests), "error", err)
+	}
+	if err == nil {
+		err = fmt.Errorf("pod-scanner returned no SBOM for digest")
+	}
+
+	// Digests without a result are retried individually when their job runs
+	for _, entry := range entries {
+		entry.err, entry.retry = err, true
+		close(entry.done)
+	}
+}
+
+// pruneLocked drops completed prefetched SBOMs that were never claimed
+func (b *BatchRetriever) pruneLocked(now time.Time) {
+	for digest, entry := range b.prefetched {
+		if now.Sub(entry.created) < prefetchTTL {
+			continue
+		}
+		select {
+		case <-entry.done:
+			delete(b.prefetched, digest)
+		default:
+		}
+	}
+}
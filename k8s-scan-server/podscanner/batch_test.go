@@ -0,0 +1,76 @@
+package podscanner
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestFetchSBOMBatch tests decoding of the streamed /sboms response
+func TestFetchSBOMBatch(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/sboms" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+		var req struct {
+			Digests []string `json:"digests"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || len(req.Digests) != 2 {
+			t.Errorf("unexpected request body: %v %v", req, err)
+		}
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		_, _ = w.Write([]byte(`{"digest":"sha256:b","status":404,"error":"image not found"}` + "\n"))
+		_, _ = w.Write([]byte(`{"digest":"sha256:a","status":200,"sbom":{"artifacts":[]},"usage":{"duration_ms":1500,"cpu_ms":900,"files_read":42,"bytes_read":4096,"throttled_ms":250}}` + "\n"))
+	}))
+	defer server.Close()
+
+	client := &Client{httpClient: &http.Client{Timeout: 5 * time.Second}}
+	results := map[string]SBOMResult{}
+	err := client.fetchSBOMBatch(context.Background(), "node-1", server.URL, []string{"sha256:a", "sha256:b"}, func(r SBOMResult) {
+		results[r.Digest] = r
+	})
+	if err != nil {
+		t.Fatalf("fetchSBOMBatch() error = %v", err)
+	}
+
+	if r := results["sha256:a"]; r.Err != nil || string(r.SBOM) != `{"artifacts":[]}` {
+		t.Errorf("result for sha256:a = %+v", r)
+	}
+	if r := results["sha256:b"]; r.Err == nil || r.SBOM != nil {
+		t.Errorf("result for sha256:b = %+v, want error", r)
+	}
+
+	// Reported resource usage is exposed per node
+	var buf bytes.Buffer
+	client.WriteUsageMetrics(&buf)
+	for _, want := range []string{
+		`bjorn2scan_pod_scanner_sbom_generations_total{node="node-1",kind="image"} 1`,
+		`bjorn2scan_pod_scanner_sbom_cpu_seconds_total{node="node-1",kind="image"} 0.9`,
+		`bjorn2scan_pod_scanner_sbom_files_read_total{node="node-1",kind="image"} 42`,
+		`bjorn2scan_pod_scanner_sbom_throttled_seconds_total{node="node-1",kind="image"} 0.25`,
+	} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("metrics missing %q:\n%s", want, buf.String())
+		}
+	}
+}
+
+// TestFetchSBOMBatch_Unsupported tests that older pod-scanners without /sboms are detected
+func TestFetchSBOMBatch_Unsupported(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	defer server.Close()
+
+	client := &Client{httpClient: &http.Client{Timeout: 5 * time.Second}}
+	err := client.fetchSBOMBatch(context.Background(), "node-1", server.URL, []string{"sha256:a"}, func(SBOMResult) {
+		t.Error("unexpected result")
+	})
+	if !errors.Is(err, ErrBatchUnsupported) {
+		t.Errorf("fetchSBOMBatch() error = %v, want ErrBatchUnsupported", err)
+	}
+}
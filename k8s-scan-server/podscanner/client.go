@@ -2,25 +2,47 @@ package podscanner
 
 import (
 	"context"
+	"crypto/tls"
+	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
 	"os"
+	"sync/atomic"
 	"time"
 
+	"github.com/bvboe/b2s-go/k8s-scan-server/reqsign"
 	"github.com/bvboe/b2s-go/scanner-core/logging"
 
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8slabels "k8s.io/apimachinery/pkg/labels"
+	coreinformers "k8s.io/client-go/informers/core/v1"
 	"k8s.io/client-go/kubernetes"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
 )
 
 var log = logging.For(logging.ComponentPodScannerClient)
 
+// podScannerSelector selects the pods of the pod-scanner DaemonSet
+var podScannerSelector = k8slabels.SelectorFromSet(k8slabels.Set{"app.kubernetes.io/component": "pod-scanner"})
+
 // Client handles communication with pod-scanner instances
 type Client struct {
 	httpClient *http.Client
+	tlsConfig  *tls.Config // pod-scanners serve TLS
+	secret     []byte      // requests are signed with it (reqsign)
+	grpcPort   string      // image SBOMs are streamed over gRPC (UseGRPC)
 	namespace  string
+	usage      usageTotals
+	next       atomic.Uint64 // spreads registry pulls across pod-scanners
+
+	// Pod lookups are served from a shared informer cache once it has
+	// synced; without one, every lookup lists pods from the API server
+	pods       corelisters.PodLister
+	podsSynced cache.InformerSynced
 }
 
 // NewClient creates a new pod-scanner client
@@ -33,43 +55,104 @@ func NewClient() *Client {
 	}
 }
 
+// UseTLS calls the pod-scanners over HTTPS with config, which verifies
+// their certificates and presents a client certificate for mutual TLS
+func (c *Client) UseTLS(config *tls.Config) {
+	c.tlsConfig = config
+	c.httpClient.Transport = c.transport()
+}
+
+// UseAuthSecret signs every request with the secret shared with the
+// pod-scanners, which reject unsigned requests
+func (c *Client) UseAuthSecret(secret string) {
+	c.secret = []byte(secret)
+	c.httpClient.Transport = c.transport()
+}
+
+// transport returns the round tripper for the TLS and signing settings
+func (c *Client) transport() http.RoundTripper {
+	var rt http.RoundTripper = http.DefaultTransport
+	if c.tlsConfig != nil {
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		transport.TLSClientConfig = c.tlsConfig
+		rt = transport
+	}
+	if c.secret != nil {
+		rt = &reqsign.Transport{Base: rt, Secret: c.secret}
+	}
+	return rt
+}
+
+// podURL returns the base URL of a pod-scanner pod
+func (c *Client) podURL(pod *corev1.Pod) string {
+	scheme := "http"
+	if c.tlsConfig != nil {
+		scheme = "https"
+	}
+	return fmt.Sprintf("%s://%s:8080", scheme, pod.Status.PodIP)
+}
+
+// UsePodInformer serves pod-scanner lookups from a shared pod informer
+// instead of listing pods from the API server on every scan, which trips
+// API rate limits on clusters with many nodes. Register it before the
+// informer factory is started.
+func (c *Client) UsePodInformer(informer coreinformers.PodInformer) {
+	c.podsSynced = informer.Informer().HasSynced
+	c.pods = informer.Lister()
+}
+
+// listPodScanners returns the pod-scanner pods, from the informer cache when
+// it has synced and from the API server otherwise. Pods from the cache are
+// shared and must not be modified.
+func (c *Client) listPodScanners(ctx context.Context, clientset kubernetes.Interface) ([]*corev1.Pod, error) {
+	namespace := c.namespace
+	if namespace == "" {
+		namespace = "default" // Fallback to default if NAMESPACE env var not set
+	}
+
+	if c.pods != nil && c.podsSynced() {
+		pods, err := c.pods.Pods(namespace).List(podScannerSelector)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list cached pods: %w", err)
+		}
+		return pods, nil
+	}
+
+	list, err := clientset.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{
+		LabelSelector: podScannerSelector.String(),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list pods: %w", err)
+	}
+	pods := make([]*corev1.Pod, len(list.Items))
+	for i := range list.Items {
+		pods[i] = &list.Items[i]
+	}
+	return pods, nil
+}
+
 // GetSBOMFromNode requests SBOM generation from pod-scanner on a specific node
 // Waits for pod-scanner to become available if it's scheduled but not yet ready
 func (c *Client) GetSBOMFromNode(ctx context.Context, clientset kubernetes.Interface, nodeName string, digest string) ([]byte, error) {
-	// Try to find running pod-scanner
-	pod, err := c.findPodScannerPod(ctx, clientset, nodeName)
+	pod, err := c.waitForPodScannerPod(ctx, clientset, nodeName)
 	if err != nil {
-		// Pod-scanner not running, check if we should wait
-		log.Debug("pod-scanner not immediately available on node", "node", nodeName, "error", err)
-
-		// Check if pod-scanner is scheduled (but not ready yet)
-		scheduled, checkErr := c.IsPodScannerScheduledOnNode(ctx, clientset, nodeName)
-		if checkErr != nil {
-			return nil, fmt.Errorf("failed to check if pod-scanner is scheduled: %w", checkErr)
-		}
-
-		if scheduled {
-			// Pod-scanner is scheduled, wait for it to become ready
-			log.Info("pod-scanner scheduled but not ready, waiting", "node", nodeName)
-			waitErr := c.WaitForPodScannerReady(ctx, clientset, nodeName, 2*time.Minute)
-			if waitErr != nil {
-				return nil, fmt.Errorf("pod-scanner did not become ready: %w", waitErr)
-			}
+		return nil, err
+	}
 
-			// Try to find pod again after waiting
-			pod, err = c.findPodScannerPod(ctx, clientset, nodeName)
-			if err != nil {
-				return nil, fmt.Errorf("failed to find pod-scanner after waiting: %w", err)
-			}
-		} else {
-			// Not scheduled - DaemonSet won't run on this node (due to taints, node selectors, etc.)
-			log.Info("no pod-scanner scheduled on node (DaemonSet not configured)", "node", nodeName)
-			return nil, fmt.Errorf("no pod-scanner scheduled on node %s (DaemonSet not configured to run on this node)", nodeName)
+	if c.grpcPort != "" {
+		target := c.grpcTarget(pod.Status.PodIP)
+		log.Info("requesting SBOM from pod-scanner over gRPC", "target", target, "node", nodeName)
+		sbomData, usage, err := c.getSBOMOverGRPC(ctx, target, digest)
+		if err != nil {
+			return nil, err
 		}
+		log.Info("successfully received SBOM from pod-scanner", "node", nodeName, "size", len(sbomData))
+		c.usage.record(nodeName, scanKindImage, digest, usage)
+		return sbomData, nil
 	}
 
 	// Build URL to pod-scanner
-	url := fmt.Sprintf("http://%s:8080/sbom/%s", pod.Status.PodIP, digest)
+	url := c.podURL(pod) + "/sbom/" + digest
 	log.Info("requesting SBOM from pod-scanner", "url", url, "node", nodeName)
 
 	// Create HTTP request with context
@@ -102,27 +185,166 @@ func (c *Client) GetSBOMFromNode(ctx context.Context, clientset kubernetes.Inter
 	}
 
 	log.Info("successfully received SBOM from pod-scanner", "node", nodeName, "size", len(sbomData))
+	c.usage.record(nodeName, scanKindImage, digest, parseScanUsage(resp.Header.Get(scanUsageHeader)))
 	return sbomData, nil
 }
 
-// findPodScannerPod finds the pod-scanner pod running on a specific node
-func (c *Client) findPodScannerPod(ctx context.Context, clientset kubernetes.Interface, nodeName string) (*corev1.Pod, error) {
-	namespace := c.namespace
-	if namespace == "" {
-		namespace = "default" // Fallback to default if NAMESPACE env var not set
+// ResolveImageDigest asks the pod-scanner on a node for the digest of an image
+// in the node's container runtime. Static pods (e.g. kube-apiserver on
+// control-plane nodes) don't always report an image ID in their status; the
+// digest is needed to scan them.
+func (c *Client) ResolveImageDigest(ctx context.Context, clientset kubernetes.Interface, nodeName, imageRef string) (string, error) {
+	pod, err := c.findPodScannerPod(ctx, clientset, nodeName)
+	if err != nil {
+		return "", err
+	}
+
+	reqURL := c.podURL(pod) + "/resolve?image=" + url.QueryEscape(imageRef)
+	req, err := http.NewRequestWithContext(ctx, "GET", reqURL, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %w", err)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve image digest with pod-scanner: %w", err)
+	}
+	defer func() {
+		if closeErr := resp.Body.Close(); closeErr != nil {
+			log.Warn("failed to close response body", "error", closeErr)
+		}
+	}()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return "", fmt.Errorf("pod-scanner returned status %d: %s", resp.StatusCode, string(body))
 	}
 
+	var resolved struct {
+		Digest string `json:"digest"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&resolved); err != nil {
+		return "", fmt.Errorf("failed to decode resolve response: %w", err)
+	}
+	if resolved.Digest == "" {
+		return "", fmt.Errorf("pod-scanner returned no digest for %s", imageRef)
+	}
+	return resolved.Digest, nil
+}
+
+// GetRegistrySBOM requests SBOM generation for an image that is not running in
+// the cluster (an ad-hoc scan). Any running pod-scanner pulls the image from
+// its registry; requests rotate across nodes. imageRef should be pinned to
+// the image digest.
+func (c *Client) GetRegistrySBOM(ctx context.Context, clientset kubernetes.Interface, imageRef string) ([]byte, error) {
+	pod, err := c.findAnyPodScannerPod(ctx, clientset)
+	if err != nil {
+		return nil, err
+	}
+	nodeName := pod.Spec.NodeName
+
+	// Build URL to pod-scanner's registry SBOM endpoint
+	reqURL := c.podURL(pod) + "/registry-sbom?image=" + url.QueryEscape(imageRef)
+	log.Info("requesting registry SBOM from pod-scanner", "image", imageRef, "node", nodeName)
+
+	req, err := http.NewRequestWithContext(ctx, "GET", reqURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to request registry SBOM from pod-scanner: %w", err)
+	}
+	defer func() {
+		if closeErr := resp.Body.Close(); closeErr != nil {
+			log.Warn("failed to close response body", "error", closeErr)
+		}
+	}()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("pod-scanner returned status %d: %s", resp.StatusCode, string(body))
+	}
+
+	sbomData, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read registry SBOM response: %w", err)
+	}
+
+	log.Info("successfully received registry SBOM from pod-scanner", "image", imageRef, "node", nodeName, "size", len(sbomData))
+	c.usage.record(nodeName, scanKindRegistry, imageRef, parseScanUsage(resp.Header.Get(scanUsageHeader)))
+	return sbomData, nil
+}
+
+// findAnyPodScannerPod returns a running pod-scanner on any node, rotating
+// through them on successive calls
+func (c *Client) findAnyPodScannerPod(ctx context.Context, clientset kubernetes.Interface) (*corev1.Pod, error) {
+	pods, err := c.listPodScanners(ctx, clientset)
+	if err != nil {
+		return nil, err
+	}
+
+	var running []*corev1.Pod
+	for _, pod := range pods {
+		if pod.Status.Phase == corev1.PodRunning && pod.Status.PodIP != "" {
+			running = append(running, pod)
+		}
+	}
+	if len(running) == 0 {
+		return nil, fmt.Errorf("no running pod-scanner found")
+	}
+	return running[(c.next.Add(1)-1)%uint64(len(running))], nil
+}
+
+// waitForPodScannerPod finds the running pod-scanner on a node, waiting for it
+// to become ready if it's scheduled but not yet running
+func (c *Client) waitForPodScannerPod(ctx context.Context, clientset kubernetes.Interface, nodeName string) (*corev1.Pod, error) {
+	// Try to find running pod-scanner
+	pod, err := c.findPodScannerPod(ctx, clientset, nodeName)
+	if err == nil {
+		return pod, nil
+	}
+
+	// Pod-scanner not running, check if we should wait
+	log.Debug("pod-scanner not immediately available on node", "node", nodeName, "error", err)
+
+	// Check if pod-scanner is scheduled (but not ready yet)
+	scheduled, checkErr := c.IsPodScannerScheduledOnNode(ctx, clientset, nodeName)
+	if checkErr != nil {
+		return nil, fmt.Errorf("failed to check if pod-scanner is scheduled: %w", checkErr)
+	}
+
+	if !scheduled {
+		// Not scheduled - DaemonSet won't run on this node (due to taints, node selectors, etc.)
+		log.Info("no pod-scanner scheduled on node (DaemonSet not configured)", "node", nodeName)
+		return nil, fmt.Errorf("no pod-scanner scheduled on node %s (DaemonSet not configured to run on this node)", nodeName)
+	}
+
+	// Pod-scanner is scheduled, wait for it to become ready
+	log.Info("pod-scanner scheduled but not ready, waiting", "node", nodeName)
+	if waitErr := c.WaitForPodScannerReady(ctx, clientset, nodeName, 2*time.Minute); waitErr != nil {
+		return nil, fmt.Errorf("pod-scanner did not become ready: %w", waitErr)
+	}
+
+	// Try to find pod again after waiting
+	pod, err = c.findPodScannerPod(ctx, clientset, nodeName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find pod-scanner after waiting: %w", err)
+	}
+	return pod, nil
+}
+
+// findPodScannerPod finds the pod-scanner pod running on a specific node
+func (c *Client) findPodScannerPod(ctx context.Context, clientset kubernetes.Interface, nodeName string) (*corev1.Pod, error) {
 	// List all pod-scanner pods
-	pods, err := clientset.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{
-		LabelSelector: "app.kubernetes.io/component=pod-scanner",
-	})
+	pods, err := c.listPodScanners(ctx, clientset)
 	if err != nil {
-		return nil, fmt.Errorf("failed to list pods: %w", err)
+		return nil, err
 	}
 
 	// Find pod running on target node
-	for i := range pods.Items {
-		pod := &pods.Items[i]
+	for _, pod := range pods {
 		if pod.Spec.NodeName == nodeName && pod.Status.Phase == corev1.PodRunning && pod.Status.PodIP != "" {
 			return pod, nil
 		}
@@ -133,22 +355,14 @@ func (c *Client) findPodScannerPod(ctx context.Context, clientset kubernetes.Int
 
 // IsPodScannerScheduledOnNode checks if a pod-scanner is scheduled (but not yet running) on a node
 func (c *Client) IsPodScannerScheduledOnNode(ctx context.Context, clientset kubernetes.Interface, nodeName string) (bool, error) {
-	namespace := c.namespace
-	if namespace == "" {
-		namespace = "default"
-	}
-
 	// List all pod-scanner pods
-	pods, err := clientset.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{
-		LabelSelector: "app.kubernetes.io/component=pod-scanner",
-	})
+	pods, err := c.listPodScanners(ctx, clientset)
 	if err != nil {
-		return false, fmt.Errorf("failed to list pods: %w", err)
+		return false, err
 	}
 
 	// Check if any pod-scanner is scheduled or starting on this node
-	for i := range pods.Items {
-		pod := &pods.Items[i]
+	for _, pod := range pods {
 		if pod.Spec.NodeName == nodeName {
 			// Pod is scheduled on this node
 			// Check if it's in a transitional state (Pending, ContainerCreating, etc.)
@@ -229,7 +443,7 @@ func (c *Client) GetHostSBOMFromNode(ctx context.Context, clientset kubernetes.I
 	}
 
 	// Build URL to pod-scanner's host SBOM endpoint
-	url := fmt.Sprintf("http://%s:8080/host-sbom", pod.Status.PodIP)
+	url := c.podURL(pod) + "/host-sbom"
 	log.Info("requesting host SBOM from pod-scanner", "url", url, "node", nodeName)
 
 	// Create HTTP request with context
@@ -269,5 +483,6 @@ func (c *Client) GetHostSBOMFromNode(ctx context.Context, clientset kubernetes.I
 	}
 
 	log.Info("successfully received host SBOM from pod-scanner", "node", nodeName, "size", len(sbomData))
+	c.usage.record(nodeName, scanKindHost, "", parseScanUsage(resp.Header.Get(scanUsageHeader)))
 	return sbomData, nil
 }
@@ -2,6 +2,7 @@ package podscanner
 
 import (
 	"context"
+	"crypto/tls"
 	"errors"
 	"fmt"
 	"io"
@@ -11,8 +12,10 @@ import (
 	"testing"
 	"time"
 
+	"github.com/bvboe/b2s-go/k8s-scan-server/reqsign"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/informers"
 	"k8s.io/client-go/kubernetes/fake"
 )
 
@@ -33,6 +36,46 @@ func TestNewClient(t *testing.T) {
 	}
 }
 
+// TestClientUseTLS tests that pod-scanners are called over HTTPS with TLS
+func TestClientUseTLS(t *testing.T) {
+	client := NewClient()
+	pod := &corev1.Pod{Status: corev1.PodStatus{PodIP: "10.0.0.5"}}
+	if got := client.podURL(pod); got != "http://10.0.0.5:8080" {
+		t.Errorf("podURL() = %q, want plain HTTP", got)
+	}
+
+	config := &tls.Config{ServerName: "pod-scanner"}
+	client.UseTLS(config)
+	if got := client.podURL(pod); got != "https://10.0.0.5:8080" {
+		t.Errorf("podURL() with TLS = %q, want HTTPS", got)
+	}
+	transport, ok := client.httpClient.Transport.(*http.Transport)
+	if !ok || transport.TLSClientConfig != config {
+		t.Errorf("transport = %#v, want the TLS configuration", client.httpClient.Transport)
+	}
+	if client.httpClient.Timeout != 6*time.Minute {
+		t.Errorf("Timeout = %v, want 6m kept", client.httpClient.Timeout)
+	}
+}
+
+// TestClientUseAuthSecret tests that requests to pod-scanners are signed
+func TestClientUseAuthSecret(t *testing.T) {
+	secret := "shared-secret"
+	server := httptest.NewServer(reqsign.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"status":"ok"}`))
+	}), []byte(secret)))
+	defer server.Close()
+
+	client := &Client{httpClient: &http.Client{Timeout: 5 * time.Second}}
+	if _, err := client.fetchRuntime(context.Background(), server.URL); err == nil {
+		t.Error("unsigned request succeeded, want it rejected")
+	}
+	client.UseAuthSecret(secret)
+	if _, err := client.fetchRuntime(context.Background(), server.URL); err != nil {
+		t.Errorf("signed request error = %v", err)
+	}
+}
+
 // TestFindPodScannerPod_Success tests finding a running pod
 func TestFindPodScannerPod_Success(t *testing.T) {
 	clientset := fake.NewClientset()
@@ -541,3 +584,89 @@ func TestFindPodScannerPod_DefaultNamespace(t *testing.T) {
 		t.Errorf("Found pod namespace = %v, want default", found.Namespace)
 	}
 }
+
+// TestFindAnyPodScannerPod tests that registry pulls rotate across running pod-scanners
+func TestFindAnyPodScannerPod(t *testing.T) {
+	clientset := fake.NewClientset()
+	client := &Client{
+		httpClient: &http.Client{Timeout: 1 * time.Minute},
+		namespace:  "test-ns",
+	}
+
+	if _, err := client.findAnyPodScannerPod(context.Background(), clientset); err == nil {
+		t.Error("expected error without running pod-scanners")
+	}
+
+	for i, phase := range []corev1.PodPhase{corev1.PodRunning, corev1.PodPending, corev1.PodRunning} {
+		pod := &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      fmt.Sprintf("pod-scanner-%d", i),
+				Namespace: "test-ns",
+				Labels: map[string]string{
+					"app.kubernetes.io/component": "pod-scanner",
+				},
+			},
+			Spec: corev1.PodSpec{
+				NodeName: fmt.Sprintf("worker-%d", i),
+			},
+			Status: corev1.PodStatus{
+				Phase: phase,
+				PodIP: fmt.Sprintf("10.1.2.%d", i),
+			},
+		}
+		if _, err := clientset.CoreV1().Pods("test-ns").Create(context.Background(), pod, metav1.CreateOptions{}); err != nil {
+			t.Fatalf("Failed to create test pod: %v", err)
+		}
+	}
+
+	seen := map[string]int{}
+	for range 4 {
+		pod, err := client.findAnyPodScannerPod(context.Background(), clientset)
+		if err != nil {
+			t.Fatalf("findAnyPodScannerPod failed: %v", err)
+		}
+		seen[pod.Name]++
+	}
+	if seen["pod-scanner-0"] != 2 || seen["pod-scanner-2"] != 2 {
+		t.Errorf("expected pulls spread across running pod-scanners, got %v", seen)
+	}
+}
+
+// TestFindPodScannerPod_Informer tests that lookups use the shared informer
+// cache once it has synced instead of listing pods from the API server
+func TestFindPodScannerPod_Informer(t *testing.T) {
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "pod-scanner-abc",
+			Namespace: "test-ns",
+			Labels: map[string]string{
+				"app.kubernetes.io/component": "pod-scanner",
+			},
+		},
+		Spec:   corev1.PodSpec{NodeName: "worker-1"},
+		Status: corev1.PodStatus{Phase: corev1.PodRunning, PodIP: "10.1.2.3"},
+	}
+	clientset := fake.NewClientset(pod)
+	client := &Client{namespace: "test-ns"}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	factory := informers.NewSharedInformerFactory(clientset, 0)
+	client.UsePodInformer(factory.Core().V1().Pods())
+	factory.Start(ctx.Done())
+	factory.WaitForCacheSync(ctx.Done())
+
+	clientset.ClearActions()
+	for range 3 {
+		found, err := client.findPodScannerPod(ctx, clientset, "worker-1")
+		if err != nil {
+			t.Fatalf("findPodScannerPod failed: %v", err)
+		}
+		if found.Status.PodIP != "10.1.2.3" {
+			t.Errorf("PodIP = %v, want 10.1.2.3", found.Status.PodIP)
+		}
+	}
+	if actions := clientset.Actions(); len(actions) != 0 {
+		t.Errorf("expected no API requests with a synced informer, got %v", actions)
+	}
+}
@@ -0,0 +1,131 @@
+package podscanner
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/bvboe/b2s-go/k8s-scan-server/sbomrpc"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/encoding/gzip"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+// grpcChunkTimeout aborts a stream that stalls between chunks. The transfer
+// as a whole has no timeout, so large SBOMs from congested nodes complete as
+// long as they make progress.
+const grpcChunkTimeout = time.Minute
+
+var errStreamStalled = errors.New("pod-scanner SBOM stream stalled")
+
+// UseGRPC fetches image SBOMs from the pod-scanners' gRPC SBOM service on
+// port, which streams them in chunks, instead of over HTTP. TLS and signing
+// follow UseTLS and UseAuthSecret.
+func (c *Client) UseGRPC(port string) {
+	c.grpcPort = port
+}
+
+// getSBOMOverGRPC fetches the SBOM of digest from the pod-scanner at target.
+// The caller's deadline is propagated to the pod-scanner, which stops
+// generating when it passes. Until the first chunk arrives the call may take
+// as long as an HTTP request; after that it only has to keep making progress.
+func (c *Client) getSBOMOverGRPC(ctx context.Context, target, digest string) ([]byte, *ScanUsage, error) {
+	creds := insecure.NewCredentials()
+	if c.tlsConfig != nil {
+		creds = credentials.NewTLS(c.tlsConfig)
+	}
+	conn, err := grpc.NewClient("passthrough:///"+target,
+		grpc.WithTransportCredentials(creds),
+		grpc.WithDefaultCallOptions(grpc.UseCompressor(gzip.Name)))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to connect to pod-scanner: %w", err)
+	}
+	defer func() { _ = conn.Close() }()
+
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+	idle := c.httpClient.Timeout
+	watchdog := time.AfterFunc(idle, func() { cancel(errStreamStalled) })
+	defer watchdog.Stop()
+
+	if c.secret != nil {
+		auth, err := sbomrpc.Sign(c.secret, sbomrpc.GetSBOMMethod, digest, time.Now())
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to sign request: %w", err)
+		}
+		ctx = metadata.AppendToOutgoingContext(ctx, sbomrpc.AuthMetadata, auth)
+	}
+
+	stream, err := conn.NewStream(ctx, &grpc.StreamDesc{ServerStreams: true}, sbomrpc.GetSBOMMethod)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to request SBOM from pod-scanner: %w", err)
+	}
+	if err := stream.SendMsg(wrapperspb.String(digest)); err != nil {
+		return nil, nil, fmt.Errorf("failed to request SBOM from pod-scanner: %w", err)
+	}
+	if err := stream.CloseSend(); err != nil {
+		return nil, nil, fmt.Errorf("failed to request SBOM from pod-scanner: %w", err)
+	}
+
+	var sbomData bytes.Buffer
+	for {
+		var chunk wrapperspb.BytesValue
+		err := stream.RecvMsg(&chunk)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			if errors.Is(context.Cause(ctx), errStreamStalled) {
+				return nil, nil, fmt.Errorf("%w: no data for %s", errStreamStalled, idle)
+			}
+			return nil, nil, grpcError(err)
+		}
+		idle = grpcChunkTimeout
+		watchdog.Reset(idle)
+		sbomData.Write(chunk.GetValue())
+	}
+	usage := parseScanUsage(strings.Join(stream.Trailer().Get(sbomrpc.UsageTrailer), ""))
+	return sbomData.Bytes(), usage, nil
+}
+
+// grpcError describes a failed GetSBOM call like the HTTP client describes a
+// failed request, so callers see the same messages on either transport
+func grpcError(err error) error {
+	s, ok := status.FromError(err)
+	if !ok {
+		return fmt.Errorf("failed to request SBOM from pod-scanner: %w", err)
+	}
+	httpStatus := http.StatusInternalServerError
+	switch s.Code() {
+	case codes.NotFound:
+		httpStatus = http.StatusNotFound
+	case codes.InvalidArgument:
+		httpStatus = http.StatusBadRequest
+	case codes.Unauthenticated:
+		httpStatus = http.StatusUnauthorized
+	case codes.Unavailable:
+		httpStatus = http.StatusServiceUnavailable
+	case codes.DeadlineExceeded:
+		httpStatus = http.StatusGatewayTimeout
+	case codes.Canceled:
+		return fmt.Errorf("failed to request SBOM from pod-scanner: %w", err)
+	}
+	return fmt.Errorf("pod-scanner returned status %d: %s", httpStatus, s.Message())
+}
+
+// grpcTarget returns the address of a pod-scanner pod's gRPC service
+func (c *Client) grpcTarget(podIP string) string {
+	return net.JoinHostPort(podIP, c.grpcPort)
+}
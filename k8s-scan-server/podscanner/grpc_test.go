@@ -0,0 +1,123 @@
+package podscanner
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bvboe/b2s-go/k8s-scan-server/sbomrpc"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	_ "google.golang.org/grpc/encoding/gzip"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+// startSBOMServer serves a fake gRPC SBOM service on a local port. Calls
+// must be signed with secret; stall delays the SBOM.
+func startSBOMServer(t *testing.T, sbom map[string]string, secret []byte, stall time.Duration) string {
+	t.Helper()
+	server := grpc.NewServer()
+	server.RegisterService(&grpc.ServiceDesc{
+		ServiceName: sbomrpc.ServiceName,
+		HandlerType: (*interface{})(nil),
+		Streams: []grpc.StreamDesc{{
+			StreamName:    "GetSBOM",
+			ServerStreams: true,
+			Handler: func(_ interface{}, stream grpc.ServerStream) error {
+				var req wrapperspb.StringValue
+				if err := stream.RecvMsg(&req); err != nil {
+					return err
+				}
+				md, _ := metadata.FromIncomingContext(stream.Context())
+				auth := strings.Join(md.Get(sbomrpc.AuthMetadata), "")
+				if err := sbomrpc.Verify(auth, secret, sbomrpc.GetSBOMMethod, req.GetValue(), time.Now()); err != nil {
+					return status.Error(codes.Unauthenticated, err.Error())
+				}
+				data, ok := sbom[req.GetValue()]
+				if !ok {
+					return status.Error(codes.NotFound, "Image not found")
+				}
+				select {
+				case <-time.After(stall):
+				case <-stream.Context().Done():
+					return stream.Context().Err()
+				}
+				stream.SetTrailer(metadata.Pairs(sbomrpc.UsageTrailer, `{"duration_ms":1500,"files_read":42}`))
+				half := len(data) / 2
+				if err := stream.SendMsg(wrapperspb.Bytes([]byte(data[:half]))); err != nil {
+					return err
+				}
+				return stream.SendMsg(wrapperspb.Bytes([]byte(data[half:])))
+			},
+		}},
+	}, nil)
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go func() { _ = server.Serve(listener) }()
+	t.Cleanup(server.Stop)
+	return listener.Addr().String()
+}
+
+func TestGetSBOMOverGRPC(t *testing.T) {
+	digest := "sha256:" + strings.Repeat("a", 64)
+	sbom := `{"artifacts": [{"name": "test"}]}`
+	secret := "shared-secret"
+	target := startSBOMServer(t, map[string]string{digest: sbom}, []byte(secret), 0)
+
+	client := &Client{httpClient: &http.Client{Timeout: 5 * time.Second}}
+	client.UseAuthSecret(secret)
+	client.UseGRPC("9090")
+	if got := client.grpcTarget("10.0.0.5"); got != "10.0.0.5:9090" {
+		t.Errorf("grpcTarget() = %q, want 10.0.0.5:9090", got)
+	}
+
+	data, usage, err := client.getSBOMOverGRPC(context.Background(), target, digest)
+	if err != nil {
+		t.Fatalf("getSBOMOverGRPC() error = %v", err)
+	}
+	if string(data) != sbom {
+		t.Errorf("getSBOMOverGRPC() = %q, want %q", data, sbom)
+	}
+	if usage == nil || usage.DurationMillis != 1500 || usage.FilesRead != 42 {
+		t.Errorf("usage = %+v, want the usage trailer", usage)
+	}
+
+	// Errors read like the HTTP client's
+	if _, _, err := client.getSBOMOverGRPC(context.Background(), target, "sha256:"+strings.Repeat("b", 64)); err == nil || !strings.Contains(err.Error(), "status 404") {
+		t.Errorf("missing image error = %v, want status 404", err)
+	}
+	unsigned := &Client{httpClient: &http.Client{Timeout: 5 * time.Second}}
+	if _, _, err := unsigned.getSBOMOverGRPC(context.Background(), target, digest); err == nil || !strings.Contains(err.Error(), "status 401") {
+		t.Errorf("unsigned error = %v, want status 401", err)
+	}
+}
+
+func TestGetSBOMOverGRPC_Stalled(t *testing.T) {
+	digest := "sha256:" + strings.Repeat("a", 64)
+	secret := []byte("shared-secret")
+	target := startSBOMServer(t, map[string]string{digest: `{"artifacts": []}`}, secret, time.Minute)
+
+	// No data within the HTTP timeout aborts the call
+	client := &Client{httpClient: &http.Client{Timeout: 200 * time.Millisecond}, secret: secret}
+	_, _, err := client.getSBOMOverGRPC(context.Background(), target, digest)
+	if !errors.Is(err, errStreamStalled) {
+		t.Errorf("getSBOMOverGRPC() error = %v, want %v", err, errStreamStalled)
+	}
+
+	// The caller's deadline ends the call, reported like an HTTP timeout
+	client.httpClient.Timeout = time.Minute
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	if _, _, err := client.getSBOMOverGRPC(ctx, target, digest); err == nil || !strings.Contains(err.Error(), "status 504") {
+		t.Errorf("getSBOMOverGRPC() past deadline error = %v, want status 504", err)
+	}
+}
@@ -0,0 +1,250 @@
+package podscanner
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	corehandlers "github.com/bvboe/b2s-go/scanner-core/handlers"
+	"github.com/bvboe/b2s-go/scanner-core/scanning"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	// runtimeRequestTimeout bounds each pod-scanner /runtime request
+	runtimeRequestTimeout = 10 * time.Second
+	// maxConcurrentRuntimeRequests bounds the pod-scanners queried at once
+	maxConcurrentRuntimeRequests = 10
+)
+
+// runtimeDiagnostics is the part of the pod-scanner /runtime response the
+// compatibility report uses
+type runtimeDiagnostics struct {
+	Distro struct {
+		ID string `json:"id"`
+	} `json:"distro"`
+	Profile    string   `json:"profile"`
+	Compatible bool     `json:"compatible"`
+	Issues     []string `json:"issues"`
+}
+
+// PauseSource lists the nodes whose scans are paused (implemented by
+// scanning.JobQueue)
+type PauseSource interface {
+	PausedNodes() []scanning.PausedNode
+}
+
+// NodeReporter reports, for every node, whether its pod-scanner can scan
+// images there (implements handlers.NodeScannerReporter)
+type NodeReporter struct {
+	client    *Client
+	clientset kubernetes.Interface
+	pauses    PauseSource
+	baseURL   func(pod *corev1.Pod) string // overridden in tests
+}
+
+// NewNodeReporter creates a node scanner compatibility reporter
+func NewNodeReporter(client *Client, clientset kubernetes.Interface) *NodeReporter {
+	return &NodeReporter{
+		client:    client,
+		clientset: clientset,
+		baseURL:   client.podURL,
+	}
+}
+
+// SetPauseSource adds the scan queue's per-node pause state to the reports
+func (n *NodeReporter) SetPauseSource(source PauseSource) {
+	n.pauses = source
+}
+
+// NodeScannerReports returns one report per node, sorted by node name. Nodes
+// without a pod-scanner are reported as missing; the runtime diagnostics of
+// the others are fetched from their /runtime endpoint.
+func (n *NodeReporter) NodeScannerReports(ctx context.Context) ([]corehandlers.NodeScannerReport, error) {
+	nodeList, err := n.clientset.CoreV1().Nodes().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list nodes: %w", err)
+	}
+
+	pods, err := n.client.listPodScanners(ctx, n.clientset)
+	if err != nil {
+		return nil, err
+	}
+	podsByNode := make(map[string]*corev1.Pod, len(pods))
+	for _, pod := range pods {
+		// Prefer the running pod when a rollout leaves two on a node
+		if existing := podsByNode[pod.Spec.NodeName]; existing == nil || existing.Status.Phase != corev1.PodRunning {
+			podsByNode[pod.Spec.NodeName] = pod
+		}
+	}
+
+	reports := make([]corehandlers.NodeScannerReport, len(nodeList.Items))
+	sem := make(chan struct{}, maxConcurrentRuntimeRequests)
+	var wg sync.WaitGroup
+	for i := range nodeList.Items {
+		node := &nodeList.Items[i]
+		reports[i] = corehandlers.NodeScannerReport{
+			NodeName:         node.Name,
+			OSImage:          node.Status.NodeInfo.OSImage,
+			ContainerRuntime: node.Status.NodeInfo.ContainerRuntimeVersion,
+			Distro:           distroFromOSImage(node.Status.NodeInfo.OSImage),
+			Issues:           []string{},
+		}
+
+		pod := podsByNode[node.Name]
+		if pod == nil {
+			reports[i].Status = corehandlers.NodeScannerMissing
+			reports[i].Issues = missingScannerIssues(reports[i].Distro)
+			continue
+		}
+		reports[i].ScannerPod = pod.Name
+		if pod.Status.Phase != corev1.PodRunning || pod.Status.PodIP == "" {
+			reports[i].Status = corehandlers.NodeScannerUnreachable
+			reports[i].Issues = []string{fmt.Sprintf("pod-scanner pod %s is not running: %s", pod.Name, podState(pod))}
+			continue
+		}
+
+		wg.Add(1)
+		go func(report *corehandlers.NodeScannerReport, baseURL string) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			n.fillRuntimeReport(ctx, report, baseURL)
+		}(&reports[i], n.baseURL(pod))
+	}
+	wg.Wait()
+	n.fillPauseState(reports)
+
+	sort.Slice(reports, func(i, j int) bool { return reports[i].NodeName < reports[j].NodeName })
+	return reports, nil
+}
+
+// fillPauseState marks the reports of nodes whose scans are paused
+func (n *NodeReporter) fillPauseState(reports []corehandlers.NodeScannerReport) {
+	if n.pauses == nil {
+		return
+	}
+	paused := make(map[string]scanning.PausedNode)
+	for _, node := range n.pauses.PausedNodes() {
+		paused[node.NodeName] = node
+	}
+	for i := range reports {
+		node, ok := paused[reports[i].NodeName]
+		if !ok {
+			continue
+		}
+		since := node.Since
+		reports[i].Paused = true
+		reports[i].PauseReason = node.Reason
+		reports[i].PausedSince = &since
+		reports[i].HeldScans = node.HeldJobs
+	}
+}
+
+// fillRuntimeReport fetches the pod-scanner's runtime diagnostics into report
+func (n *NodeReporter) fillRuntimeReport(ctx context.Context, report *corehandlers.NodeScannerReport, baseURL string) {
+	raw, err := n.client.fetchRuntime(ctx, baseURL)
+	if err != nil {
+		log.Debug("failed to get runtime diagnostics from pod-scanner", "node", report.NodeName, "error", err)
+		report.Status = corehandlers.NodeScannerUnreachable
+		report.Issues = []string{err.Error()}
+		return
+	}
+
+	var diag runtimeDiagnostics
+	if err := json.Unmarshal(raw, &diag); err != nil {
+		report.Status = corehandlers.NodeScannerUnreachable
+		report.Issues = []string{fmt.Sprintf("invalid runtime diagnostics: %v", err)}
+		return
+	}
+	report.Runtime = raw
+	report.Profile = diag.Profile
+	if diag.Distro.ID != "" {
+		report.Distro = diag.Distro.ID
+	}
+	if diag.Profile == "" {
+		// pod-scanner predates compatibility reporting
+		report.Status = corehandlers.NodeScannerOK
+		report.Compatible = true
+		return
+	}
+	report.Compatible = diag.Compatible
+	if diag.Issues != nil {
+		report.Issues = diag.Issues
+	}
+	report.Status = corehandlers.NodeScannerOK
+	if !diag.Compatible {
+		report.Status = corehandlers.NodeScannerIncompatible
+	}
+}
+
+// fetchRuntime returns the body of baseURL/runtime
+func (c *Client) fetchRuntime(ctx context.Context, baseURL string) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(ctx, runtimeRequestTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, "GET", baseURL+"/runtime", nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reach pod-scanner: %w", err)
+	}
+	defer func() {
+		if closeErr := resp.Body.Close(); closeErr != nil {
+			log.Warn("failed to close response body", "error", closeErr)
+		}
+	}()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read runtime diagnostics: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("pod-scanner returned status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	return body, nil
+}
+
+// distroFromOSImage recognizes the distributions with dedicated pod-scanner
+// runtime profiles from the node's OS image, e.g. "Bottlerocket OS 1.20.3
+// (aws-k8s-1.29)" or "Talos (v1.7.5)"
+func distroFromOSImage(osImage string) string {
+	lower := strings.ToLower(osImage)
+	for _, distro := range []string{"bottlerocket", "talos"} {
+		if strings.Contains(lower, distro) {
+			return distro
+		}
+	}
+	return ""
+}
+
+// missingScannerIssues explains the usual reasons the DaemonSet has no pod on a node
+func missingScannerIssues(distro string) []string {
+	issues := []string{"no pod-scanner pod on this node; check the DaemonSet's tolerations and node selector"}
+	if distro == "talos" {
+		issues = append(issues, "Talos enforces the baseline Pod Security Standard by default; "+
+			"label the release namespace pod-security.kubernetes.io/enforce=privileged")
+	}
+	return issues
+}
+
+// podState describes why a pod is not running, e.g. "Pending (CrashLoopBackOff)"
+func podState(pod *corev1.Pod) string {
+	for _, status := range pod.Status.ContainerStatuses {
+		if status.State.Waiting != nil && status.State.Waiting.Reason != "" {
+			return fmt.Sprintf("%s (%s)", pod.Status.Phase, status.State.Waiting.Reason)
+		}
+	}
+	return string(pod.Status.Phase)
+}
@@ -0,0 +1,129 @@
+package podscanner
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	corehandlers "github.com/bvboe/b2s-go/scanner-core/handlers"
+	"github.com/bvboe/b2s-go/scanner-core/scanning"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+type staticPauses []scanning.PausedNode
+
+func (p staticPauses) PausedNodes() []scanning.PausedNode { return p }
+
+func testNode(name, osImage string) *corev1.Node {
+	return &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Status: corev1.NodeStatus{NodeInfo: corev1.NodeSystemInfo{
+			OSImage:                 osImage,
+			ContainerRuntimeVersion: "containerd://1.7.20",
+		}},
+	}
+}
+
+func testScannerPod(name, nodeName string, phase corev1.PodPhase, podIP string) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: "default",
+			Labels:    map[string]string{"app.kubernetes.io/component": "pod-scanner"},
+		},
+		Spec:   corev1.PodSpec{NodeName: nodeName},
+		Status: corev1.PodStatus{Phase: phase, PodIP: podIP},
+	}
+}
+
+func TestNodeScannerReports(t *testing.T) {
+	compatible := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/runtime" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		_, _ = w.Write([]byte(`{"active_runtime":"containerd","distro":{"id":"bottlerocket"},"profile":"bottlerocket","compatible":true,"issues":[]}`))
+	}))
+	defer compatible.Close()
+	incompatible := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"active_runtime":"none","distro":{"id":"ubuntu"},"profile":"default","compatible":false,"issues":["no container runtime socket is reachable"]}`))
+	}))
+	defer incompatible.Close()
+
+	pending := testScannerPod("scanner-c", "node-c", corev1.PodPending, "")
+	pending.Status.ContainerStatuses = []corev1.ContainerStatus{{
+		State: corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{Reason: "CrashLoopBackOff"}},
+	}}
+	clientset := fake.NewClientset(
+		testNode("node-a", "Bottlerocket OS 1.20.3 (aws-k8s-1.29)"),
+		testNode("node-b", "Talos (v1.7.5)"),
+		testNode("node-c", "Ubuntu 22.04.4 LTS"),
+		testNode("node-d", "Ubuntu 22.04.4 LTS"),
+		testScannerPod("scanner-a", "node-a", corev1.PodRunning, "10.0.0.1"),
+		pending,
+		testScannerPod("scanner-d", "node-d", corev1.PodRunning, "10.0.0.4"),
+	)
+
+	reporter := NewNodeReporter(&Client{httpClient: &http.Client{Timeout: 5 * time.Second}, namespace: "default"}, clientset)
+	reporter.baseURL = func(pod *corev1.Pod) string {
+		if pod.Name == "scanner-a" {
+			return compatible.URL
+		}
+		return incompatible.URL
+	}
+	reporter.SetPauseSource(staticPauses{{NodeName: "node-d", Reason: "cordoned", Since: time.Now(), HeldJobs: 2}})
+
+	reports, err := reporter.NodeScannerReports(context.Background())
+	if err != nil {
+		t.Fatalf("NodeScannerReports() error = %v", err)
+	}
+	if len(reports) != 4 {
+		t.Fatalf("got %d reports, want 4", len(reports))
+	}
+
+	want := []struct {
+		status     string
+		compatible bool
+		distro     string
+		issue      string
+	}{
+		{corehandlers.NodeScannerOK, true, "bottlerocket", ""},
+		{corehandlers.NodeScannerMissing, false, "talos", "pod-security.kubernetes.io/enforce=privileged"},
+		{corehandlers.NodeScannerUnreachable, false, "", "CrashLoopBackOff"},
+		{corehandlers.NodeScannerIncompatible, false, "ubuntu", "no container runtime socket"},
+	}
+	for i, w := range want {
+		got := reports[i]
+		if got.Status != w.status || got.Compatible != w.compatible || got.Distro != w.distro {
+			t.Errorf("%s: status=%s compatible=%v distro=%q, want %s %v %q",
+				got.NodeName, got.Status, got.Compatible, got.Distro, w.status, w.compatible, w.distro)
+		}
+		if w.issue != "" && !strings.Contains(strings.Join(got.Issues, "\n"), w.issue) {
+			t.Errorf("%s: issues %v do not mention %q", got.NodeName, got.Issues, w.issue)
+		}
+	}
+	if reports[0].Profile != "bottlerocket" || len(reports[0].Runtime) == 0 {
+		t.Errorf("node-a: profile=%q runtime=%s", reports[0].Profile, reports[0].Runtime)
+	}
+	if reports[0].Paused || !reports[3].Paused || reports[3].PauseReason != "cordoned" || reports[3].HeldScans != 2 || reports[3].PausedSince == nil {
+		t.Errorf("pause state: node-a %+v, node-d %+v", reports[0], reports[3])
+	}
+}
+
+func TestDistroFromOSImage(t *testing.T) {
+	tests := map[string]string{
+		"Bottlerocket OS 1.20.3 (aws-k8s-1.29)": "bottlerocket",
+		"Talos (v1.7.5)":                        "talos",
+		"Ubuntu 22.04.4 LTS":                    "",
+	}
+	for osImage, want := range tests {
+		if got := distroFromOSImage(osImage); got != want {
+			t.Errorf("distroFromOSImage(%q) = %q, want %q", osImage, got, want)
+		}
+	}
+}
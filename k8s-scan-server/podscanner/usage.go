@@ -0,0 +1,140 @@
+package podscanner
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+)
+
+// scanUsageHeader carries the resources a pod-scanner used for an SBOM
+const scanUsageHeader = "X-Scan-Usage"
+
+// Scan kinds used as the kind label of the usage metrics
+const (
+	scanKindImage    = "image"
+	scanKindHost     = "host"
+	scanKindRegistry = "registry"
+)
+
+// ScanUsage is the resources a pod-scanner reports for one SBOM generation.
+// CPU time is that of the whole pod-scanner process while the scan ran.
+type ScanUsage struct {
+	DurationMillis  int64 `json:"duration_ms"`
+	CPUMillis       int64 `json:"cpu_ms"`
+	FilesRead       int64 `json:"files_read"`
+	BytesRead       int64 `json:"bytes_read"`
+	ThrottledMillis int64 `json:"throttled_ms"`
+}
+
+// parseScanUsage decodes the usage header; pod-scanners predating it return nil
+func parseScanUsage(header string) *ScanUsage {
+	if header == "" {
+		return nil
+	}
+	var usage ScanUsage
+	if err := json.Unmarshal([]byte(header), &usage); err != nil {
+		log.Debug("ignoring invalid scan usage header", "value", header, "error", err)
+		return nil
+	}
+	return &usage
+}
+
+// usageKey identifies one series of the usage metrics
+type usageKey struct {
+	node string
+	kind string
+}
+
+// usageTotals accumulates reported scan usage per node and scan kind. The
+// zero value is ready to use.
+type usageTotals struct {
+	mu     sync.Mutex
+	totals map[usageKey]*usageTotal
+}
+
+type usageTotal struct {
+	scans int64
+	ScanUsage
+}
+
+// record logs a scan's usage and adds it to the totals
+func (u *usageTotals) record(nodeName, kind, digest string, usage *ScanUsage) {
+	if usage == nil {
+		return
+	}
+	log.Info("pod-scanner scan resource usage", "node", nodeName, "kind", kind, "digest", digest,
+		"duration_ms", usage.DurationMillis, "cpu_ms", usage.CPUMillis, "files_read", usage.FilesRead,
+		"bytes_read", usage.BytesRead, "throttled_ms", usage.ThrottledMillis)
+
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if u.totals == nil {
+		u.totals = make(map[usageKey]*usageTotal)
+	}
+	key := usageKey{node: nodeName, kind: kind}
+	total, ok := u.totals[key]
+	if !ok {
+		total = &usageTotal{}
+		u.totals[key] = total
+	}
+	total.scans++
+	total.DurationMillis += usage.DurationMillis
+	total.CPUMillis += usage.CPUMillis
+	total.FilesRead += usage.FilesRead
+	total.BytesRead += usage.BytesRead
+	total.ThrottledMillis += usage.ThrottledMillis
+}
+
+// WriteUsageMetrics writes the SBOM generation usage reported by pod-scanners
+// as Prometheus counters labelled by node and scan kind
+func (c *Client) WriteUsageMetrics(w io.Writer) {
+	c.usage.mu.Lock()
+	keys := make([]usageKey, 0, len(c.usage.totals))
+	totals := make(map[usageKey]usageTotal, len(c.usage.totals))
+	for key, total := range c.usage.totals {
+		keys = append(keys, key)
+		totals[key] = *total
+	}
+	c.usage.mu.Unlock()
+	if len(keys) == 0 {
+		return
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].node != keys[j].node {
+			return keys[i].node < keys[j].node
+		}
+		return keys[i].kind < keys[j].kind
+	})
+
+	series := []struct {
+		name, help string
+		value      func(usageTotal) string
+	}{
+		{"bjorn2scan_pod_scanner_sbom_generations_total", "SBOM generations that reported resource usage",
+			func(t usageTotal) string { return fmt.Sprint(t.scans) }},
+		{"bjorn2scan_pod_scanner_sbom_duration_seconds_total", "Wall-clock time spent generating SBOMs",
+			func(t usageTotal) string { return seconds(t.DurationMillis) }},
+		{"bjorn2scan_pod_scanner_sbom_cpu_seconds_total", "pod-scanner process CPU time while generating SBOMs",
+			func(t usageTotal) string { return seconds(t.CPUMillis) }},
+		{"bjorn2scan_pod_scanner_sbom_files_read_total", "Files read while generating SBOMs",
+			func(t usageTotal) string { return fmt.Sprint(t.FilesRead) }},
+		{"bjorn2scan_pod_scanner_sbom_read_bytes_total", "Bytes read while generating SBOMs",
+			func(t usageTotal) string { return fmt.Sprint(t.BytesRead) }},
+		{"bjorn2scan_pod_scanner_sbom_throttled_seconds_total", "Time SBOM generation waited for the file read rate limit",
+			func(t usageTotal) string { return seconds(t.ThrottledMillis) }},
+	}
+	for _, s := range series {
+		_, _ = fmt.Fprintf(w, "# HELP %s %s\n", s.name, s.help)
+		_, _ = fmt.Fprintf(w, "# TYPE %s counter\n", s.name)
+		for _, key := range keys {
+			_, _ = fmt.Fprintf(w, "%s{node=%q,kind=%q} %s\n", s.name, key.node, key.kind, s.value(totals[key]))
+		}
+	}
+}
+
+// seconds renders milliseconds as seconds
+func seconds(millis int64) string {
+	return fmt.Sprintf("%g", float64(millis)/1000)
+}
@@ -0,0 +1,148 @@
+// Package reqsign authenticates the scan server's requests to the
+// pod-scanners with a shared secret. Every request carries its own token, an
+// HMAC of the method, URI, body and time, so a token seen on the network
+// cannot be used to request other SBOMs and expires after MaxSkew.
+// This is a copy of the pod-scanner's reqsign package, which verifies the
+// tokens; change them together.
+package reqsign
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// scheme is the Authorization scheme of signed requests
+const scheme = "B2S-HMAC-SHA256"
+
+// MaxSkew is how far the time of a signature may be from the server's clock
+const MaxSkew = 5 * time.Minute
+
+// maxBodySize bounds the request bodies read to verify their signature
+const maxBodySize = 4 << 20
+
+var (
+	errMissing = errors.New("missing request signature")
+	errInvalid = errors.New("invalid request signature")
+	errExpired = errors.New("request signature expired")
+)
+
+// signature returns the hex HMAC of a request made at unix time ts
+func signature(secret []byte, method, uri string, ts int64, body []byte) string {
+	bodySum := sha256.Sum256(body)
+	mac := hmac.New(sha256.New, secret)
+	_, _ = fmt.Fprintf(mac, "%s\n%s\n%d\n%x", method, uri, ts, bodySum)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Sign sets the Authorization header of req to a token for it, made at now
+func Sign(req *http.Request, secret []byte, now time.Time) error {
+	var body []byte
+	if req.Body != nil && req.Body != http.NoBody {
+		var err error
+		if req.GetBody != nil {
+			var rc io.ReadCloser
+			if rc, err = req.GetBody(); err == nil {
+				body, err = io.ReadAll(rc)
+				_ = rc.Close()
+			}
+		} else {
+			body, err = io.ReadAll(req.Body)
+			_ = req.Body.Close()
+			req.Body = io.NopCloser(bytes.NewReader(body))
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read request body: %w", err)
+		}
+	}
+	ts := now.Unix()
+	req.Header.Set("Authorization", fmt.Sprintf("%s t=%d,s=%s", scheme, ts,
+		signature(secret, req.Method, req.URL.RequestURI(), ts, body)))
+	return nil
+}
+
+// Verify checks the token of r, restoring its body for the handler
+func Verify(r *http.Request, secret []byte, now time.Time) error {
+	value, ok := strings.CutPrefix(r.Header.Get("Authorization"), scheme+" ")
+	if !ok {
+		return errMissing
+	}
+	var ts int64
+	var sig string
+	for _, part := range strings.Split(value, ",") {
+		key, val, _ := strings.Cut(strings.TrimSpace(part), "=")
+		switch key {
+		case "t":
+			ts, _ = strconv.ParseInt(val, 10, 64)
+		case "s":
+			sig = val
+		}
+	}
+	if ts == 0 || sig == "" {
+		return errInvalid
+	}
+	if skew := now.Sub(time.Unix(ts, 0)); skew > MaxSkew || skew < -MaxSkew {
+		return errExpired
+	}
+	var body []byte
+	if r.Body != nil {
+		var err error
+		body, err = io.ReadAll(io.LimitReader(r.Body, maxBodySize+1))
+		_ = r.Body.Close()
+		if err != nil || len(body) > maxBodySize {
+			return errInvalid
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+	}
+	want := signature(secret, r.Method, r.URL.RequestURI(), ts, body)
+	if !hmac.Equal([]byte(sig), []byte(want)) {
+		return errInvalid
+	}
+	return nil
+}
+
+// Middleware rejects requests without a valid token, except to the exempt
+// paths (health probes)
+func Middleware(next http.Handler, secret []byte, exempt ...string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for _, path := range exempt {
+			if r.URL.Path == path {
+				next.ServeHTTP(w, r)
+				return
+			}
+		}
+		if err := Verify(r, secret, time.Now()); err != nil {
+			w.Header().Set("WWW-Authenticate", scheme)
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Transport signs the requests it sends
+type Transport struct {
+	Base   http.RoundTripper // http.DefaultTransport when nil
+	Secret []byte
+}
+
+// RoundTrip signs a copy of req and sends it
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	signed := req.Clone(req.Context())
+	if err := Sign(signed, t.Secret, time.Now()); err != nil {
+		return nil, err
+	}
+	return base.RoundTrip(signed)
+}
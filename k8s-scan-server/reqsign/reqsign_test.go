@@ -0,0 +1,99 @@
+package reqsign
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSignVerify(t *testing.T) {
+	secret := []byte("shared-secret")
+	now := time.Now()
+
+	signed := func(method, target, body string, at time.Time) *http.Request {
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		if err := Sign(req, secret, at); err != nil {
+			t.Fatalf("Sign() error = %v", err)
+		}
+		return req
+	}
+
+	req := signed(http.MethodPost, "/sboms", `{"digests":["sha256:aa"]}`, now)
+	if err := Verify(req, secret, now); err != nil {
+		t.Errorf("Verify(signed request) error = %v", err)
+	}
+	if body, _ := io.ReadAll(req.Body); string(body) != `{"digests":["sha256:aa"]}` {
+		t.Errorf("body after Verify = %q, want it restored", body)
+	}
+
+	tests := []struct {
+		name string
+		req  func() *http.Request
+		err  error
+	}{
+		{"unsigned", func() *http.Request { return httptest.NewRequest(http.MethodGet, "/sbom/sha256:aa", nil) }, errMissing},
+		{"other secret", func() *http.Request {
+			req := httptest.NewRequest(http.MethodGet, "/sbom/sha256:aa", nil)
+			_ = Sign(req, []byte("other"), now)
+			return req
+		}, errInvalid},
+		{"other digest", func() *http.Request {
+			req := signed(http.MethodGet, "/sbom/sha256:aa", "", now)
+			req.URL.Path = "/sbom/sha256:bb"
+			return req
+		}, errInvalid},
+		{"other body", func() *http.Request {
+			req := signed(http.MethodPost, "/sboms", `{"digests":["sha256:aa"]}`, now)
+			req.Body = io.NopCloser(strings.NewReader(`{"digests":["sha256:bb"]}`))
+			return req
+		}, errInvalid},
+		{"expired", func() *http.Request {
+			return signed(http.MethodGet, "/sbom/sha256:aa", "", now.Add(-MaxSkew-time.Minute))
+		}, errExpired},
+		{"garbled", func() *http.Request {
+			req := httptest.NewRequest(http.MethodGet, "/sbom/sha256:aa", nil)
+			req.Header.Set("Authorization", scheme+" t=x")
+			return req
+		}, errInvalid},
+	}
+	for _, tt := range tests {
+		if err := Verify(tt.req(), secret, now); err != tt.err {
+			t.Errorf("%s: Verify() error = %v, want %v", tt.name, err, tt.err)
+		}
+	}
+}
+
+func TestTransportAndMiddleware(t *testing.T) {
+	secret := []byte("shared-secret")
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		_, _ = w.Write(body)
+	})
+	ts := httptest.NewServer(Middleware(ok, secret, "/health"))
+	defer ts.Close()
+
+	signing := &http.Client{Transport: &Transport{Secret: secret}}
+	resp, err := signing.Post(ts.URL+"/sboms", "application/json", strings.NewReader(`{"digests":[]}`))
+	if err != nil {
+		t.Fatalf("signed POST error = %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || string(body) != `{"digests":[]}` {
+		t.Errorf("signed POST = %d %q, want 200 with the body", resp.StatusCode, body)
+	}
+
+	for path, want := range map[string]int{"/sbom/sha256:aa": http.StatusUnauthorized, "/health": http.StatusOK} {
+		resp, err := http.Get(ts.URL + path)
+		if err != nil {
+			t.Fatalf("GET %s error = %v", path, err)
+		}
+		_ = resp.Body.Close()
+		if resp.StatusCode != want {
+			t.Errorf("unsigned GET %s = %d, want %d", path, resp.StatusCode, want)
+		}
+	}
+}
@@ -0,0 +1,60 @@
+// Package sbomrpc is the contract of the gRPC SBOM service of the
+// pod-scanners, shared by the server (pod-scanner) and the client
+// (k8s-scan-server). SBOMs of large images run to 150MB, so they are streamed
+// in chunks rather than sent as one response body. This is a copy of the
+// pod-scanner's sbomrpc package, as the modules share no library; change
+// them together.
+//
+// The service has no .proto file: its messages are protobuf well-known types.
+//
+//	service SBOMService {
+//	  // Request: the image digest. Response: the Syft JSON SBOM in chunks of
+//	  // at most ChunkSize bytes, with the scan usage in the UsageTrailer.
+//	  rpc GetSBOM(google.protobuf.StringValue) returns (stream google.protobuf.BytesValue);
+//	}
+package sbomrpc
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/bvboe/b2s-go/k8s-scan-server/reqsign"
+)
+
+const (
+	// ServiceName is the fully qualified name of the service
+	ServiceName = "bjorn2scan.podscanner.v1.SBOMService"
+	// GetSBOMMethod is the full method name of GetSBOM
+	GetSBOMMethod = "/" + ServiceName + "/GetSBOM"
+	// ChunkSize is the largest SBOM chunk sent in one message
+	ChunkSize = 1 << 20
+	// UsageTrailer is the trailer carrying the JSON-encoded scan usage
+	UsageTrailer = "x-scan-usage"
+	// AuthMetadata is the metadata key carrying the request signature
+	AuthMetadata = "authorization"
+)
+
+// signedRequest is the HTTP request standing in for a call in reqsign, so
+// calls are signed like the pod-scanners' HTTP requests: the signature
+// covers the method and digest and expires after reqsign.MaxSkew
+func signedRequest(method, digest string) *http.Request {
+	req, _ := http.NewRequest(http.MethodPost, method, strings.NewReader(digest))
+	return req
+}
+
+// Sign returns the AuthMetadata value of a call of method for digest
+func Sign(secret []byte, method, digest string, now time.Time) (string, error) {
+	req := signedRequest(method, digest)
+	if err := reqsign.Sign(req, secret, now); err != nil {
+		return "", err
+	}
+	return req.Header.Get("Authorization"), nil
+}
+
+// Verify checks the AuthMetadata value of a call of method for digest
+func Verify(auth string, secret []byte, method, digest string, now time.Time) error {
+	req := signedRequest(method, digest)
+	req.Header.Set("Authorization", auth)
+	return reqsign.Verify(req, secret, now)
+}
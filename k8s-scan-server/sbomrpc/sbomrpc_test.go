@@ -0,0 +1,33 @@
+package sbomrpc
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSignVerify(t *testing.T) {
+	secret := []byte("shared-secret")
+	now := time.Now()
+	digest := "sha256:" + "ab"
+
+	auth, err := Sign(secret, GetSBOMMethod, digest, now)
+	if err != nil {
+		t.Fatalf("Sign() error = %v", err)
+	}
+	if err := Verify(auth, secret, GetSBOMMethod, digest, now); err != nil {
+		t.Errorf("Verify(signed call) error = %v", err)
+	}
+
+	tests := map[string]error{
+		"other digest": Verify(auth, secret, GetSBOMMethod, "sha256:cd", now),
+		"other method": Verify(auth, secret, "/"+ServiceName+"/Other", digest, now),
+		"other secret": Verify(auth, []byte("other"), GetSBOMMethod, digest, now),
+		"expired":      Verify(auth, secret, GetSBOMMethod, digest, now.Add(time.Hour)),
+		"unsigned":     Verify("", secret, GetSBOMMethod, digest, now),
+	}
+	for name, err := range tests {
+		if err == nil {
+			t.Errorf("%s: Verify() succeeded, want an error", name)
+		}
+	}
+}
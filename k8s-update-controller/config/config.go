@@ -4,8 +4,12 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"sort"
+	"strconv"
+	"strings"
 	"time"
 
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
@@ -16,6 +20,11 @@ const (
 	defaultConfigMapName      = "bjorn2scan-update-config"
 	defaultConfigMapNamespace = "bjorn2scan"
 	defaultConfigMapKey       = "config.yaml"
+
+	// defaultPolicyConfigMapName is the optional, user-owned ConfigMap whose
+	// keys override the version policy at runtime. It is not managed by Helm,
+	// so edits survive the upgrades the controller performs itself.
+	defaultPolicyConfigMapName = "bjorn2scan-update-policy"
 )
 
 // LoadConfig loads configuration from ConfigMap or environment variables
@@ -57,6 +66,20 @@ func LoadConfig() (*Config, error) {
 		return nil, fmt.Errorf("failed to parse configuration: %w", err)
 	}
 
+	// Apply runtime policy overrides (if the policy ConfigMap exists)
+	policyConfigMapName := getEnv("POLICY_CONFIG_MAP_NAME", defaultPolicyConfigMapName)
+	policy, err := clientset.CoreV1().ConfigMaps(configMapNamespace).Get(ctx, policyConfigMapName, metav1.GetOptions{})
+	switch {
+	case apierrors.IsNotFound(err):
+		// No overrides
+	case err != nil:
+		return nil, fmt.Errorf("failed to get policy ConfigMap %s/%s: %w", configMapNamespace, policyConfigMapName, err)
+	default:
+		if err := applyPolicyOverrides(&cfg, policy.Data); err != nil {
+			return nil, fmt.Errorf("invalid policy ConfigMap %s/%s: %w", configMapNamespace, policyConfigMapName, err)
+		}
+	}
+
 	// Parse duration strings
 	if err := cfg.Rollback.ParseDurations(); err != nil {
 		return nil, fmt.Errorf("failed to parse durations: %w", err)
@@ -73,6 +96,40 @@ func LoadConfig() (*Config, error) {
 	return &cfg, nil
 }
 
+// applyPolicyOverrides overrides the enabled flag and version constraints with
+// the keys present in a policy ConfigMap. A key with an empty value clears
+// string constraints (e.g. pinnedVersion="" removes the pin).
+func applyPolicyOverrides(cfg *Config, data map[string]string) error {
+	boolKeys := map[string]*bool{
+		"enabled":         &cfg.Enabled,
+		"autoUpdateMinor": &cfg.VersionConstraints.AutoUpdateMinor,
+		"autoUpdateMajor": &cfg.VersionConstraints.AutoUpdateMajor,
+	}
+	stringKeys := map[string]*string{
+		"pinnedVersion": &cfg.VersionConstraints.PinnedVersion,
+		"minVersion":    &cfg.VersionConstraints.MinVersion,
+		"maxVersion":    &cfg.VersionConstraints.MaxVersion,
+	}
+
+	for key, value := range data {
+		value = strings.TrimSpace(value)
+		if dest, ok := boolKeys[key]; ok {
+			b, err := strconv.ParseBool(value)
+			if err != nil {
+				return fmt.Errorf("%s: invalid boolean %q", key, value)
+			}
+			*dest = b
+		} else if dest, ok := stringKeys[key]; ok {
+			*dest = value
+		} else {
+			return fmt.Errorf("unknown key %q", key)
+		}
+		cfg.PolicyOverrides = append(cfg.PolicyOverrides, key)
+	}
+	sort.Strings(cfg.PolicyOverrides)
+	return nil
+}
+
 func setDefaults(cfg *Config) {
 	if cfg.Helm.Namespace == "" {
 		cfg.Helm.Namespace = "bjorn2scan"
@@ -93,6 +150,9 @@ func setDefaults(cfg *Config) {
 	if cfg.Verification.ReleaseBaseURL == "" {
 		cfg.Verification.ReleaseBaseURL = "https://github.com/bvboe/b2s-go/releases/download"
 	}
+	if cfg.Metrics.Job == "" {
+		cfg.Metrics.Job = "bjorn2scan-update-controller"
+	}
 }
 
 func validate(cfg *Config) error {
@@ -90,6 +90,7 @@ func TestSetDefaults(t *testing.T) {
 					CosignOIDCIssuer:     "https://token.actions.githubusercontent.com",
 					CosignIdentityRegexp: "https://github.com/bvboe/b2s-go/*",
 				},
+				Metrics: MetricsConfig{Job: "bjorn2scan-update-controller"},
 			},
 		},
 		{
@@ -109,6 +110,7 @@ func TestSetDefaults(t *testing.T) {
 					CosignOIDCIssuer:     "https://token.actions.githubusercontent.com",
 					CosignIdentityRegexp: "https://github.com/bvboe/b2s-go/*",
 				},
+				Metrics: MetricsConfig{Job: "bjorn2scan-update-controller"},
 			},
 		},
 		{
@@ -123,6 +125,7 @@ func TestSetDefaults(t *testing.T) {
 					CosignOIDCIssuer:     "https://custom.issuer",
 					CosignIdentityRegexp: "https://custom/*",
 				},
+				Metrics: MetricsConfig{Job: "custom-job"},
 			},
 			wantCfg: Config{
 				Helm: HelmConfig{
@@ -134,6 +137,7 @@ func TestSetDefaults(t *testing.T) {
 					CosignOIDCIssuer:     "https://custom.issuer",
 					CosignIdentityRegexp: "https://custom/*",
 				},
+				Metrics: MetricsConfig{Job: "custom-job"},
 			},
 		},
 	}
@@ -157,6 +161,9 @@ func TestSetDefaults(t *testing.T) {
 			if tt.cfg.Verification.CosignIdentityRegexp != tt.wantCfg.Verification.CosignIdentityRegexp {
 				t.Errorf("CosignIdentityRegexp = %q, want %q", tt.cfg.Verification.CosignIdentityRegexp, tt.wantCfg.Verification.CosignIdentityRegexp)
 			}
+			if tt.cfg.Metrics.Job != tt.wantCfg.Metrics.Job {
+				t.Errorf("Metrics.Job = %q, want %q", tt.cfg.Metrics.Job, tt.wantCfg.Metrics.Job)
+			}
 		})
 	}
 }
@@ -430,6 +437,99 @@ cosignOIDCIssuer: "https://token.test.com"
 	}
 }
 
+
+func TestApplyPolicyOverrides(t *testing.T) {
+	base := func() Config {
+		return Config{
+			Enabled: true,
+			VersionConstraints: VersionConstraints{
+				AutoUpdateMinor: true,
+				PinnedVersion:   "0.1.30",
+				MaxVersion:      "0.9.0",
+			},
+		}
+	}
+
+	tests := []struct {
+		name          string
+		data          map[string]string
+		wantErr       bool
+		wantEnabled   bool
+		wantMinor     bool
+		wantPinned    string
+		wantMax       string
+		wantOverrides []string
+	}{
+		{
+			name:        "no keys keeps Helm values",
+			data:        map[string]string{},
+			wantEnabled: true, wantMinor: true, wantPinned: "0.1.30", wantMax: "0.9.0",
+		},
+		{
+			name:        "pin and disable minor updates",
+			data:        map[string]string{"pinnedVersion": " 0.1.35 ", "autoUpdateMinor": "false"},
+			wantEnabled: true, wantMinor: false, wantPinned: "0.1.35", wantMax: "0.9.0",
+			wantOverrides: []string{"autoUpdateMinor", "pinnedVersion"},
+		},
+		{
+			name:        "empty value clears pin",
+			data:        map[string]string{"pinnedVersion": "", "maxVersion": ""},
+			wantEnabled: true, wantMinor: true, wantPinned: "", wantMax: "",
+			wantOverrides: []string{"maxVersion", "pinnedVersion"},
+		},
+		{
+			name:        "pause auto-update",
+			data:        map[string]string{"enabled": "false"},
+			wantEnabled: false, wantMinor: true, wantPinned: "0.1.30", wantMax: "0.9.0",
+			wantOverrides: []string{"enabled"},
+		},
+		{
+			name:    "invalid boolean",
+			data:    map[string]string{"enabled": "maybe"},
+			wantErr: true,
+		},
+		{
+			name:    "unknown key",
+			data:    map[string]string{"pinnedVersoin": "0.1.35"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := base()
+			err := applyPolicyOverrides(&cfg, tt.data)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("applyPolicyOverrides() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if cfg.Enabled != tt.wantEnabled {
+				t.Errorf("Enabled = %v, want %v", cfg.Enabled, tt.wantEnabled)
+			}
+			if cfg.VersionConstraints.AutoUpdateMinor != tt.wantMinor {
+				t.Errorf("AutoUpdateMinor = %v, want %v", cfg.VersionConstraints.AutoUpdateMinor, tt.wantMinor)
+			}
+			if cfg.VersionConstraints.PinnedVersion != tt.wantPinned {
+				t.Errorf("PinnedVersion = %q, want %q", cfg.VersionConstraints.PinnedVersion, tt.wantPinned)
+			}
+			if cfg.VersionConstraints.MaxVersion != tt.wantMax {
+				t.Errorf("MaxVersion = %q, want %q", cfg.VersionConstraints.MaxVersion, tt.wantMax)
+			}
+			if len(cfg.PolicyOverrides) != len(tt.wantOverrides) {
+				t.Fatalf("PolicyOverrides = %v, want %v", cfg.PolicyOverrides, tt.wantOverrides)
+			}
+			for i := range tt.wantOverrides {
+				if cfg.PolicyOverrides[i] != tt.wantOverrides[i] {
+					t.Errorf("PolicyOverrides = %v, want %v", cfg.PolicyOverrides, tt.wantOverrides)
+					break
+				}
+			}
+		})
+	}
+}
+
 /*
 Integration Tests Needed (require Kubernetes cluster):
 
@@ -9,6 +9,10 @@ type Config struct {
 	Helm               HelmConfig         `yaml:"helm"`
 	Rollback           RollbackConfig     `yaml:"rollback"`
 	Verification       VerificationConfig `yaml:"verification"`
+	Metrics            MetricsConfig      `yaml:"metrics"`
+
+	// PolicyOverrides lists the keys applied from the runtime policy ConfigMap
+	PolicyOverrides []string `yaml:"-"`
 }
 
 // VersionConstraints defines version update policies
@@ -64,3 +68,15 @@ type VerificationConfig struct {
 	// e.g. "https://github.com/bvboe/b2s-go/releases/download"
 	ReleaseBaseURL string `yaml:"releaseBaseURL"`
 }
+
+// MetricsConfig defines where the result of each run is published for monitoring
+type MetricsConfig struct {
+	// PushgatewayURL is the base URL of a Prometheus Pushgateway
+	// (e.g. "http://pushgateway.monitoring:9091"); empty disables pushing.
+	PushgatewayURL string `yaml:"pushgatewayURL"`
+	// TextfilePath is a file the metrics are written to in Prometheus text
+	// format (e.g. for the node_exporter textfile collector); empty disables it.
+	TextfilePath string `yaml:"textfilePath"`
+	// Job is the Pushgateway job label (default: bjorn2scan-update-controller)
+	Job string `yaml:"job"`
+}
@@ -26,6 +26,7 @@ type UpdateResult struct {
 	UpdateAvailable  bool
 	UpdatePerformed  bool
 	UpdatedToVersion string
+	UpdateFailed     bool // An update was attempted but failed or was rolled back
 	Reason           string
 }
 
@@ -51,7 +52,9 @@ func New(cfg *config.Config) (*Controller, error) {
 	}, nil
 }
 
-// CheckAndUpdate performs a single update check and applies updates if needed
+// CheckAndUpdate performs a single update check and applies updates if needed.
+// On error the partially populated result is returned alongside the error so
+// callers can still report what was known (e.g. the current version).
 func (c *Controller) CheckAndUpdate(ctx context.Context) (*UpdateResult, error) {
 	log := log
 	result := &UpdateResult{}
@@ -60,7 +63,7 @@ func (c *Controller) CheckAndUpdate(ctx context.Context) (*UpdateResult, error)
 	log.Info("step 1: getting current release")
 	currentRelease, err := c.helmClient.GetCurrentRelease()
 	if err != nil {
-		return nil, fmt.Errorf("failed to get current release: %w", err)
+		return result, fmt.Errorf("failed to get current release: %w", err)
 	}
 	result.CurrentVersion = currentRelease.Chart.Metadata.Version
 	log.Info("current version", "version", result.CurrentVersion)
@@ -69,7 +72,7 @@ func (c *Controller) CheckAndUpdate(ctx context.Context) (*UpdateResult, error)
 	log.Info("step 2: querying registry for available versions")
 	versions, err := c.registryClient.ListVersions(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("failed to list versions: %w", err)
+		return result, fmt.Errorf("failed to list versions: %w", err)
 	}
 	log.Info("found versions in registry", "count", len(versions))
 
@@ -77,7 +80,7 @@ func (c *Controller) CheckAndUpdate(ctx context.Context) (*UpdateResult, error)
 	log.Info("step 3: evaluating version constraints")
 	latestVersion, err := c.versionChecker.FindLatestVersion(result.CurrentVersion, versions)
 	if err != nil {
-		return nil, fmt.Errorf("failed to find latest version: %w", err)
+		return result, fmt.Errorf("failed to find latest version: %w", err)
 	}
 	result.LatestVersion = latestVersion
 
@@ -97,7 +100,7 @@ func (c *Controller) CheckAndUpdate(ctx context.Context) (*UpdateResult, error)
 	log.Info("step 4: downloading chart", "version", latestVersion)
 	chartPath, err := c.registryClient.DownloadChart(ctx, latestVersion)
 	if err != nil {
-		return nil, fmt.Errorf("failed to download chart: %w", err)
+		return result, fmt.Errorf("failed to download chart: %w", err)
 	}
 	defer func() {
 		// Clean up downloaded chart (parent directory)
@@ -117,7 +120,7 @@ func (c *Controller) CheckAndUpdate(ctx context.Context) (*UpdateResult, error)
 			c.config.Verification.ReleaseBaseURL,
 			c.config.Verification.CosignIdentityRegexp,
 			c.config.Verification.CosignOIDCIssuer); err != nil {
-			return nil, fmt.Errorf("signature verification failed: %w", err)
+			return result, fmt.Errorf("signature verification failed: %w", err)
 		}
 		log.Info("signature verified")
 	} else {
@@ -127,7 +130,8 @@ func (c *Controller) CheckAndUpdate(ctx context.Context) (*UpdateResult, error)
 	// 7. Perform Helm upgrade
 	log.Info("step 6: upgrading release", "version", latestVersion)
 	if err := c.helmClient.UpgradeRelease(ctx, chartPath, latestVersion); err != nil {
-		return nil, fmt.Errorf("helm upgrade failed: %w", err)
+		result.UpdateFailed = true
+		return result, fmt.Errorf("helm upgrade failed: %w", err)
 	}
 	log.Info("upgrade completed")
 
@@ -143,16 +147,17 @@ func (c *Controller) CheckAndUpdate(ctx context.Context) (*UpdateResult, error)
 		healthy, err := c.helmClient.IsReleaseHealthy()
 		if err != nil || !healthy {
 			log.Error("health check failed", "error", err)
+			result.UpdateFailed = true
 
 			if c.config.Rollback.AutoRollback {
 				log.Info("performing automatic rollback")
 				if rbErr := c.helmClient.RollbackRelease(); rbErr != nil {
-					return nil, fmt.Errorf("rollback failed after upgrade failure: %w", rbErr)
+					return result, fmt.Errorf("rollback failed after upgrade failure: %w", rbErr)
 				}
-				return nil, fmt.Errorf("upgrade rolled back due to health check failure")
+				return result, fmt.Errorf("upgrade rolled back due to health check failure")
 			}
 
-			return nil, fmt.Errorf("health check failed but auto-rollback is disabled")
+			return result, fmt.Errorf("health check failed but auto-rollback is disabled")
 		}
 
 		log.Info("health check passed")
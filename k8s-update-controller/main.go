@@ -9,6 +9,7 @@ import (
 
 	"github.com/bvboe/b2s-go/k8s-update-controller/config"
 	"github.com/bvboe/b2s-go/k8s-update-controller/controller"
+	"github.com/bvboe/b2s-go/k8s-update-controller/metrics"
 )
 
 var version = "dev" // Set via ldflags at build time
@@ -72,7 +73,8 @@ func main() {
 		"chart_registry", cfg.Helm.ChartRegistry,
 		"auto_update_minor", cfg.VersionConstraints.AutoUpdateMinor,
 		"auto_update_major", cfg.VersionConstraints.AutoUpdateMajor,
-		"pinned_version", cfg.VersionConstraints.PinnedVersion)
+		"pinned_version", cfg.VersionConstraints.PinnedVersion,
+		"policy_overrides", cfg.PolicyOverrides)
 
 	// Create controller
 	ctrl, err := controller.New(cfg)
@@ -86,6 +88,13 @@ func main() {
 	result, err := ctrl.CheckAndUpdate(ctx)
 	duration := time.Since(startTime)
 
+	// Publish the outcome for monitoring, including failures
+	report := metrics.Report{Time: startTime, Duration: duration, Result: result, Err: err}
+	instance := cfg.Helm.Namespace + "/" + cfg.Helm.ReleaseName
+	if pubErr := metrics.Publish(ctx, cfg.Metrics, instance, report); pubErr != nil {
+		log.Warn("error publishing metrics", "error", pubErr)
+	}
+
 	if err != nil {
 		log.Error("error during update check", "error", err)
 		os.Exit(1)
@@ -0,0 +1,149 @@
+// Package metrics publishes the outcome of an update check in Prometheus text
+// format, either to a Pushgateway or to a textfile, so monitoring can alert
+// when auto-update stops running or keeps failing.
+package metrics
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/bvboe/b2s-go/k8s-update-controller/config"
+	"github.com/bvboe/b2s-go/k8s-update-controller/controller"
+)
+
+// Report is the outcome of a single update check
+type Report struct {
+	Time     time.Time
+	Duration time.Duration
+	Result   *controller.UpdateResult // may be nil or partial when Err is set
+	Err      error
+}
+
+// Format writes the report as Prometheus text exposition format.
+// The last-success timestamp is only emitted for successful checks; pushed with
+// POST it then keeps its previous value in the Pushgateway when a check fails.
+func Format(w io.Writer, r Report) error {
+	var b bytes.Buffer
+	result := r.Result
+	if result == nil {
+		result = &controller.UpdateResult{}
+	}
+
+	gauge := func(name, help string, value float64) {
+		fmt.Fprintf(&b, "# HELP %s %s\n# TYPE %s gauge\n%s %g\n", name, help, name, name, value)
+	}
+
+	gauge("bjorn2scan_update_controller_last_check_timestamp_seconds",
+		"Unix time of the last update check", float64(r.Time.Unix()))
+	gauge("bjorn2scan_update_controller_check_duration_seconds",
+		"Duration of the last update check", r.Duration.Seconds())
+	gauge("bjorn2scan_update_controller_check_success",
+		"Whether the last update check completed without error", boolValue(r.Err == nil))
+	if r.Err == nil {
+		gauge("bjorn2scan_update_controller_last_success_timestamp_seconds",
+			"Unix time of the last successful update check", float64(r.Time.Unix()))
+	}
+	gauge("bjorn2scan_update_controller_update_available",
+		"Whether a newer chart version matching the constraints was found", boolValue(result.UpdateAvailable))
+	gauge("bjorn2scan_update_controller_update_performed",
+		"Whether the last check upgraded the release", boolValue(result.UpdatePerformed && !result.UpdateFailed))
+	gauge("bjorn2scan_update_controller_update_failed",
+		"Whether the last check attempted an upgrade that failed or was rolled back", boolValue(result.UpdateFailed))
+
+	fmt.Fprintf(&b, "# HELP bjorn2scan_update_controller_version_info Current and latest chart versions seen by the last check\n")
+	fmt.Fprintf(&b, "# TYPE bjorn2scan_update_controller_version_info gauge\n")
+	fmt.Fprintf(&b, "bjorn2scan_update_controller_version_info{current_version=%q,latest_version=%q} 1\n",
+		result.CurrentVersion, result.LatestVersion)
+
+	_, err := w.Write(b.Bytes())
+	return err
+}
+
+func boolValue(v bool) float64 {
+	if v {
+		return 1
+	}
+	return 0
+}
+
+// Push sends the report to a Pushgateway, grouped by job and instance.
+// POST is used so metrics absent from this push (the last-success timestamp
+// after a failure) keep their previous value.
+func Push(ctx context.Context, client *http.Client, gatewayURL, job, instance string, r Report) error {
+	var body bytes.Buffer
+	if err := Format(&body, r); err != nil {
+		return err
+	}
+
+	endpoint := strings.TrimSuffix(gatewayURL, "/") + "/metrics/job/" + url.PathEscape(job)
+	if instance != "" {
+		endpoint += "/instance/" + url.PathEscape(instance)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, &body)
+	if err != nil {
+		return fmt.Errorf("failed to create pushgateway request: %w", err)
+	}
+	req.Header.Set("Content-Type", "text/plain; version=0.0.4")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to push metrics: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("pushgateway returned %s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
+
+// WriteTextfile writes the report to path, replacing it atomically so a
+// collector never reads a partially written file.
+func WriteTextfile(path string, r Report) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp*")
+	if err != nil {
+		return fmt.Errorf("failed to create metrics file: %w", err)
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+
+	if err := Format(tmp, r); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("failed to write metrics file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write metrics file: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to replace metrics file: %w", err)
+	}
+	return nil
+}
+
+// Publish sends the report to every destination enabled in cfg.
+// instance distinguishes releases sharing a Pushgateway (e.g. namespace/release).
+func Publish(ctx context.Context, cfg config.MetricsConfig, instance string, r Report) error {
+	var errs []error
+	if cfg.PushgatewayURL != "" {
+		client := &http.Client{Timeout: 10 * time.Second}
+		if err := Push(ctx, client, cfg.PushgatewayURL, cfg.Job, instance, r); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if cfg.TextfilePath != "" {
+		if err := WriteTextfile(cfg.TextfilePath, r); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
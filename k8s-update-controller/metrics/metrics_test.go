@@ -0,0 +1,158 @@
+package metrics
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bvboe/b2s-go/k8s-update-controller/config"
+	"github.com/bvboe/b2s-go/k8s-update-controller/controller"
+)
+
+func TestFormat(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+
+	tests := []struct {
+		name        string
+		report      Report
+		contains    []string
+		notContains []string
+	}{
+		{
+			name: "successful update",
+			report: Report{
+				Time:     now,
+				Duration: 2 * time.Second,
+				Result: &controller.UpdateResult{
+					CurrentVersion:  "0.1.0",
+					LatestVersion:   "0.2.0",
+					UpdateAvailable: true,
+					UpdatePerformed: true,
+				},
+			},
+			contains: []string{
+				"bjorn2scan_update_controller_last_check_timestamp_seconds 1.7e+09",
+				"bjorn2scan_update_controller_check_success 1",
+				"bjorn2scan_update_controller_last_success_timestamp_seconds 1.7e+09",
+				"bjorn2scan_update_controller_update_performed 1",
+				"bjorn2scan_update_controller_update_failed 0",
+				`bjorn2scan_update_controller_version_info{current_version="0.1.0",latest_version="0.2.0"} 1`,
+			},
+		},
+		{
+			name: "failed upgrade",
+			report: Report{
+				Time: now,
+				Result: &controller.UpdateResult{
+					CurrentVersion:  "0.1.0",
+					LatestVersion:   "0.2.0",
+					UpdateAvailable: true,
+					UpdatePerformed: true,
+					UpdateFailed:    true,
+				},
+				Err: errors.New("upgrade rolled back"),
+			},
+			contains: []string{
+				"bjorn2scan_update_controller_check_success 0",
+				"bjorn2scan_update_controller_update_performed 0",
+				"bjorn2scan_update_controller_update_failed 1",
+			},
+			notContains: []string{"last_success_timestamp_seconds"},
+		},
+		{
+			name:   "failure before any result",
+			report: Report{Time: now, Err: errors.New("registry unreachable")},
+			contains: []string{
+				"bjorn2scan_update_controller_check_success 0",
+				`bjorn2scan_update_controller_version_info{current_version="",latest_version=""} 1`,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := Format(&buf, tt.report); err != nil {
+				t.Fatalf("Format() error = %v", err)
+			}
+			out := buf.String()
+			for _, want := range tt.contains {
+				if !strings.Contains(out, want) {
+					t.Errorf("output missing %q:\n%s", want, out)
+				}
+			}
+			for _, unwanted := range tt.notContains {
+				if strings.Contains(out, unwanted) {
+					t.Errorf("output unexpectedly contains %q", unwanted)
+				}
+			}
+		})
+	}
+}
+
+func TestPush(t *testing.T) {
+	var gotMethod, gotPath, gotBody string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotMethod, gotPath = r.Method, r.URL.EscapedPath()
+		body, _ := io.ReadAll(r.Body)
+		gotBody = string(body)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	report := Report{Time: time.Now(), Result: &controller.UpdateResult{CurrentVersion: "0.1.0"}}
+	if err := Push(context.Background(), server.Client(), server.URL+"/", "update-controller", "bjorn2scan/bjorn2scan", report); err != nil {
+		t.Fatalf("Push() error = %v", err)
+	}
+
+	if gotMethod != http.MethodPost {
+		t.Errorf("method = %s, want POST", gotMethod)
+	}
+	if gotPath != "/metrics/job/update-controller/instance/bjorn2scan%2Fbjorn2scan" {
+		t.Errorf("path = %s", gotPath)
+	}
+	if !strings.Contains(gotBody, "bjorn2scan_update_controller_check_success 1") {
+		t.Errorf("body missing check_success:\n%s", gotBody)
+	}
+}
+
+func TestPushErrorStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "bad metrics", http.StatusBadRequest)
+	}))
+	defer server.Close()
+
+	err := Push(context.Background(), server.Client(), server.URL, "job", "", Report{Time: time.Now()})
+	if err == nil || !strings.Contains(err.Error(), "bad metrics") {
+		t.Errorf("Push() error = %v, want pushgateway error", err)
+	}
+}
+
+func TestPublishTextfile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "update_controller.prom")
+
+	cfg := config.MetricsConfig{TextfilePath: path, Job: "update-controller"}
+	if err := Publish(context.Background(), cfg, "ns/release", Report{Time: time.Now()}); err != nil {
+		t.Fatalf("Publish() error = %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read textfile: %v", err)
+	}
+	if !strings.Contains(string(data), "bjorn2scan_update_controller_check_success 1") {
+		t.Errorf("textfile missing check_success:\n%s", data)
+	}
+	entries, _ := os.ReadDir(filepath.Dir(path))
+	if len(entries) != 1 {
+		t.Errorf("expected only the metrics file, found %d entries", len(entries))
+	}
+}
@@ -0,0 +1,338 @@
+// Package config provides typed configuration for pod-scanner.
+// All values are read from environment variables. Most of them are injected by
+// the Helm chart through the Kubernetes downward API (spec.nodeName,
+// metadata.namespace, limits.memory, ...) so the scanner knows where it runs
+// and how much it is allowed to consume.
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Config holds all configuration options for pod-scanner.
+type Config struct {
+	Port string
+
+	// GRPCPort serves the gRPC SBOM service (see package sbomrpc), which
+	// streams large SBOMs in chunks. Empty disables it.
+	GRPCPort string
+
+	// HTTPS: the SBOM endpoints carry package inventories across the pod
+	// network. The files are reloaded when renewed; with a client CA, every
+	// request except /health needs a client certificate signed by it.
+	TLSCertFile     string
+	TLSKeyFile      string
+	TLSClientCAFile string
+
+	// AuthSecret is shared with the scan server, which signs every request
+	// with it; unsigned requests (except /health) are rejected. Empty leaves
+	// the endpoints open to any pod.
+	AuthSecret string
+
+	// Downward API fields
+	NodeName       string
+	PodName        string
+	Namespace      string
+	PodIP          string
+	ServiceAccount string
+
+	// Runtime socket overrides (empty means auto-detect)
+	ContainerdSocket string
+	DockerHost       string
+
+	// NodeDistro selects the runtime path profile ("bottlerocket", "talos").
+	// Empty means detect it from the node's os-release.
+	NodeDistro string
+
+	// ContainerdNamespaces restricts image lookup to these containerd namespaces,
+	// searched in order (empty means discover all, k8s.io first)
+	ContainerdNamespaces []string
+
+	// Scan resource limits
+	SBOMTimeout        time.Duration
+	HostSBOMTimeout    time.Duration
+	MaxConcurrentScans int
+	CPULimitMillis     int64 // Container CPU limit in millicores (0 = unlimited)
+	MemoryLimitBytes   int64 // Container memory limit in bytes (0 = unlimited)
+
+	// ShutdownDrainTimeout is how long a terminating pod-scanner waits for
+	// in-flight SBOM generations to finish (keep the pod's
+	// terminationGracePeriodSeconds above it)
+	ShutdownDrainTimeout time.Duration
+
+	// Scan throttling, so SBOM generation doesn't starve co-located pods
+	ScanNice              int    // CPU nice value, -20..19 (0 = unchanged)
+	ScanIOClass           string // I/O scheduling class: "best-effort", "idle" or "" (unchanged)
+	ScanIOLevel           int    // Priority within the best-effort class, 0..7
+	ScanMaxFilesPerSecond int    // File reads per second across all scans (0 = unlimited)
+
+	// Host scanning configuration
+	HostScanningExtraExclusions     []string
+	HostScanningAutoDetectNFS       bool
+	HostScanningExtraNetworkFSTypes []string
+
+	// Resilience testing: DebugEnabled exposes /debug/faults and FaultInjection
+	// is the initial fault spec (see package faults), which requires it
+	DebugEnabled   bool
+	FaultInjection string
+}
+
+// defaultConfig returns a Config with hardcoded defaults.
+func defaultConfig() *Config {
+	return &Config{
+		Port:                      "8080",
+		SBOMTimeout:               5 * time.Minute,
+		HostSBOMTimeout:           10 * time.Minute, // Host scans take longer than container scans
+		MaxConcurrentScans:        2,
+		ShutdownDrainTimeout:      2 * time.Minute,
+		HostScanningAutoDetectNFS: true,
+	}
+}
+
+// Load builds the configuration from environment variables and validates it.
+func Load() (*Config, error) {
+	cfg := defaultConfig()
+
+	if port := os.Getenv("PORT"); port != "" {
+		cfg.Port = port
+	}
+	cfg.GRPCPort = os.Getenv("GRPC_PORT")
+	cfg.TLSCertFile = os.Getenv("TLS_CERT_FILE")
+	cfg.TLSKeyFile = os.Getenv("TLS_KEY_FILE")
+	cfg.TLSClientCAFile = os.Getenv("TLS_CLIENT_CA_FILE")
+	cfg.AuthSecret = os.Getenv("POD_SCANNER_AUTH_SECRET")
+
+	// Downward API fields. HOSTNAME is the fallback for the pod name since
+	// Kubernetes sets it to the pod name when POD_NAME is not injected.
+	cfg.NodeName = os.Getenv("NODE_NAME")
+	cfg.PodName = os.Getenv("POD_NAME")
+	if cfg.PodName == "" {
+		cfg.PodName = os.Getenv("HOSTNAME")
+	}
+	cfg.Namespace = os.Getenv("NAMESPACE")
+	cfg.PodIP = os.Getenv("POD_IP")
+	cfg.ServiceAccount = os.Getenv("SERVICE_ACCOUNT")
+
+	// Runtime socket overrides
+	cfg.ContainerdSocket = os.Getenv("CONTAINERD_SOCKET")
+	cfg.NodeDistro = strings.ToLower(strings.TrimSpace(os.Getenv("NODE_DISTRO")))
+	cfg.DockerHost = os.Getenv("DOCKER_HOST")
+	if v := os.Getenv("CONTAINERD_NAMESPACES"); v != "" {
+		cfg.ContainerdNamespaces = parseCommaSeparated(v)
+	}
+
+	// Scan resource limits
+	if v := os.Getenv("SBOM_TIMEOUT"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid SBOM_TIMEOUT %q: %w", v, err)
+		}
+		cfg.SBOMTimeout = d
+	}
+	if v := os.Getenv("HOST_SBOM_TIMEOUT"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid HOST_SBOM_TIMEOUT %q: %w", v, err)
+		}
+		cfg.HostSBOMTimeout = d
+	}
+	if v := os.Getenv("MAX_CONCURRENT_SCANS"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid MAX_CONCURRENT_SCANS %q: %w", v, err)
+		}
+		cfg.MaxConcurrentScans = n
+	}
+	if v := os.Getenv("SHUTDOWN_DRAIN_TIMEOUT"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid SHUTDOWN_DRAIN_TIMEOUT %q: %w", v, err)
+		}
+		cfg.ShutdownDrainTimeout = d
+	}
+	// CPU_LIMIT is injected via resourceFieldRef with divisor 1m, so it is
+	// already expressed in millicores. MEMORY_LIMIT is injected in bytes.
+	if v := os.Getenv("CPU_LIMIT"); v != "" {
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid CPU_LIMIT %q: %w", v, err)
+		}
+		cfg.CPULimitMillis = n
+	}
+	if v := os.Getenv("MEMORY_LIMIT"); v != "" {
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid MEMORY_LIMIT %q: %w", v, err)
+		}
+		cfg.MemoryLimitBytes = n
+	}
+
+	// Scan throttling
+	if v := os.Getenv("SCAN_NICE"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid SCAN_NICE %q: %w", v, err)
+		}
+		cfg.ScanNice = n
+	}
+	if v := os.Getenv("SCAN_IO_CLASS"); v != "" {
+		cfg.ScanIOClass = strings.ToLower(strings.TrimSpace(v))
+	}
+	if v := os.Getenv("SCAN_IO_LEVEL"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid SCAN_IO_LEVEL %q: %w", v, err)
+		}
+		cfg.ScanIOLevel = n
+	}
+	if v := os.Getenv("SCAN_MAX_FILES_PER_SECOND"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid SCAN_MAX_FILES_PER_SECOND %q: %w", v, err)
+		}
+		cfg.ScanMaxFilesPerSecond = n
+	}
+
+	// Host scanning configuration
+	if v := os.Getenv("HOST_SCANNING_EXTRA_EXCLUSIONS"); v != "" {
+		cfg.HostScanningExtraExclusions = parseCommaSeparated(v)
+	}
+	if v := os.Getenv("HOST_SCANNING_AUTO_DETECT_NFS"); v != "" {
+		val := strings.ToLower(v)
+		cfg.HostScanningAutoDetectNFS = val == "true" || val == "1" || val == "yes"
+	}
+	if v := os.Getenv("HOST_SCANNING_EXTRA_NETWORK_FS_TYPES"); v != "" {
+		cfg.HostScanningExtraNetworkFSTypes = parseCommaSeparated(v)
+	}
+
+	// Fault injection for resilience testing
+	if v := os.Getenv("DEBUG_ENABLED"); v != "" {
+		val := strings.ToLower(v)
+		cfg.DebugEnabled = val == "true" || val == "1" || val == "yes"
+	}
+	cfg.FaultInjection = strings.TrimSpace(os.Getenv("FAULT_INJECTION"))
+
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid configuration: %w", err)
+	}
+	return cfg, nil
+}
+
+// Validate checks that the configuration values are usable.
+func (c *Config) Validate() error {
+	port, err := strconv.Atoi(c.Port)
+	if err != nil || port < 1 || port > 65535 {
+		return fmt.Errorf("port must be a number between 1 and 65535, got %q", c.Port)
+	}
+	if c.GRPCPort != "" {
+		grpcPort, err := strconv.Atoi(c.GRPCPort)
+		if err != nil || grpcPort < 1 || grpcPort > 65535 {
+			return fmt.Errorf("gRPC port must be a number between 1 and 65535, got %q", c.GRPCPort)
+		}
+		if c.GRPCPort == c.Port {
+			return fmt.Errorf("gRPC port must differ from the HTTP port %s", c.Port)
+		}
+	}
+	if (c.TLSCertFile == "") != (c.TLSKeyFile == "") {
+		return fmt.Errorf("TLS certificate and key files must be set together")
+	}
+	if c.TLSClientCAFile != "" && c.TLSCertFile == "" {
+		return fmt.Errorf("TLS client CA file requires a TLS certificate")
+	}
+	if c.ContainerdSocket != "" && !filepath.IsAbs(c.ContainerdSocket) {
+		return fmt.Errorf("containerd socket must be an absolute path, got %q", c.ContainerdSocket)
+	}
+	if c.SBOMTimeout <= 0 {
+		return fmt.Errorf("SBOM timeout must be positive, got %s", c.SBOMTimeout)
+	}
+	if c.HostSBOMTimeout <= 0 {
+		return fmt.Errorf("host SBOM timeout must be positive, got %s", c.HostSBOMTimeout)
+	}
+	if c.MaxConcurrentScans < 1 {
+		return fmt.Errorf("max concurrent scans must be at least 1, got %d", c.MaxConcurrentScans)
+	}
+	if c.ShutdownDrainTimeout < 0 {
+		return fmt.Errorf("shutdown drain timeout must not be negative, got %s", c.ShutdownDrainTimeout)
+	}
+	if c.CPULimitMillis < 0 {
+		return fmt.Errorf("CPU limit must not be negative, got %d", c.CPULimitMillis)
+	}
+	if c.MemoryLimitBytes < 0 {
+		return fmt.Errorf("memory limit must not be negative, got %d", c.MemoryLimitBytes)
+	}
+	if c.ScanNice < -20 || c.ScanNice > 19 {
+		return fmt.Errorf("scan nice must be between -20 and 19, got %d", c.ScanNice)
+	}
+	if c.ScanIOClass != "" && c.ScanIOClass != "best-effort" && c.ScanIOClass != "idle" {
+		return fmt.Errorf("scan I/O class must be \"best-effort\" or \"idle\", got %q", c.ScanIOClass)
+	}
+	if c.ScanIOLevel < 0 || c.ScanIOLevel > 7 {
+		return fmt.Errorf("scan I/O level must be between 0 and 7, got %d", c.ScanIOLevel)
+	}
+	if c.ScanMaxFilesPerSecond < 0 {
+		return fmt.Errorf("scan max files per second must not be negative, got %d", c.ScanMaxFilesPerSecond)
+	}
+	if c.FaultInjection != "" && !c.DebugEnabled {
+		return fmt.Errorf("fault injection requires DEBUG_ENABLED=true")
+	}
+	return nil
+}
+
+// Effective returns a JSON-friendly view of the configuration for the /info
+// endpoint. Durations are rendered as strings so they are readable.
+func (c *Config) Effective() map[string]interface{} {
+	return map[string]interface{}{
+		"port":                                 c.Port,
+		"grpc_port":                            c.GRPCPort,
+		"tls_cert_file":                        c.TLSCertFile,
+		"tls_key_file":                         c.TLSKeyFile,
+		"tls_client_ca_file":                   c.TLSClientCAFile,
+		"auth_enabled":                         c.AuthSecret != "",
+		"node_name":                            c.NodeName,
+		"pod_name":                             c.PodName,
+		"namespace":                            c.Namespace,
+		"pod_ip":                               c.PodIP,
+		"service_account":                      c.ServiceAccount,
+		"containerd_socket":                    c.ContainerdSocket,
+		"docker_host":                          c.DockerHost,
+		"node_distro":                          c.NodeDistro,
+		"containerd_namespaces":                c.ContainerdNamespaces,
+		"sbom_timeout":                         c.SBOMTimeout.String(),
+		"host_sbom_timeout":                    c.HostSBOMTimeout.String(),
+		"max_concurrent_scans":                 c.MaxConcurrentScans,
+		"shutdown_drain_timeout":               c.ShutdownDrainTimeout.String(),
+		"cpu_limit_millis":                     c.CPULimitMillis,
+		"memory_limit_bytes":                   c.MemoryLimitBytes,
+		"scan_nice":                            c.ScanNice,
+		"scan_io_class":                        c.ScanIOClass,
+		"scan_io_level":                        c.ScanIOLevel,
+		"scan_max_files_per_second":            c.ScanMaxFilesPerSecond,
+		"host_scanning_extra_exclusions":       c.HostScanningExtraExclusions,
+		"host_scanning_auto_detect_nfs":        c.HostScanningAutoDetectNFS,
+		"host_scanning_extra_network_fs_types": c.HostScanningExtraNetworkFSTypes,
+		"debug_enabled":                        c.DebugEnabled,
+		"fault_injection":                      c.FaultInjection,
+	}
+}
+
+// parseCommaSeparated splits a comma-separated string into a slice of trimmed strings.
+// Empty strings are filtered out.
+func parseCommaSeparated(s string) []string {
+	if s == "" {
+		return nil
+	}
+	parts := strings.Split(s, ",")
+	var result []string
+	for _, p := range parts {
+		p = strings.TrimSpace(p)
+		if p != "" {
+			result = append(result, p)
+		}
+	}
+	return result
+}
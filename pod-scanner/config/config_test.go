@@ -0,0 +1,187 @@
+package config
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLoadDefaults(t *testing.T) {
+	t.Setenv("HOSTNAME", "pod-scanner-abc12")
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	if cfg.Port != "8080" {
+		t.Errorf("Port = %q, want 8080", cfg.Port)
+	}
+	if cfg.PodName != "pod-scanner-abc12" {
+		t.Errorf("PodName = %q, want HOSTNAME fallback", cfg.PodName)
+	}
+	if cfg.SBOMTimeout != 5*time.Minute {
+		t.Errorf("SBOMTimeout = %s, want 5m", cfg.SBOMTimeout)
+	}
+	if cfg.HostSBOMTimeout != 10*time.Minute {
+		t.Errorf("HostSBOMTimeout = %s, want 10m", cfg.HostSBOMTimeout)
+	}
+	if cfg.MaxConcurrentScans != 2 {
+		t.Errorf("MaxConcurrentScans = %d, want 2", cfg.MaxConcurrentScans)
+	}
+	if cfg.ShutdownDrainTimeout != 2*time.Minute {
+		t.Errorf("ShutdownDrainTimeout = %s, want 2m", cfg.ShutdownDrainTimeout)
+	}
+	if !cfg.HostScanningAutoDetectNFS {
+		t.Error("HostScanningAutoDetectNFS should default to true")
+	}
+}
+
+func TestLoadDownwardAPIAndLimits(t *testing.T) {
+	t.Setenv("NODE_NAME", "worker-1")
+	t.Setenv("POD_NAME", "bjorn2scan-pod-scanner-xyz")
+	t.Setenv("NAMESPACE", "b2sv2")
+	t.Setenv("POD_IP", "10.0.0.7")
+	t.Setenv("SERVICE_ACCOUNT", "bjorn2scan")
+	t.Setenv("CONTAINERD_SOCKET", "/run/k3s/containerd/containerd.sock")
+	t.Setenv("CONTAINERD_NAMESPACES", "moby, k8s.io")
+	t.Setenv("NODE_DISTRO", " Talos ")
+	t.Setenv("CPU_LIMIT", "2000")
+	t.Setenv("MEMORY_LIMIT", "2147483648")
+	t.Setenv("SBOM_TIMEOUT", "90s")
+	t.Setenv("MAX_CONCURRENT_SCANS", "4")
+	t.Setenv("SHUTDOWN_DRAIN_TIMEOUT", "45s")
+	t.Setenv("HOST_SCANNING_EXTRA_EXCLUSIONS", "/data/**, /backup/**")
+	t.Setenv("HOST_SCANNING_AUTO_DETECT_NFS", "false")
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	if cfg.NodeName != "worker-1" || cfg.PodName != "bjorn2scan-pod-scanner-xyz" || cfg.Namespace != "b2sv2" {
+		t.Errorf("unexpected downward API fields: %+v", cfg)
+	}
+	if cfg.PodIP != "10.0.0.7" || cfg.ServiceAccount != "bjorn2scan" {
+		t.Errorf("unexpected pod IP/service account: %q/%q", cfg.PodIP, cfg.ServiceAccount)
+	}
+	if cfg.ContainerdSocket != "/run/k3s/containerd/containerd.sock" {
+		t.Errorf("ContainerdSocket = %q", cfg.ContainerdSocket)
+	}
+	if len(cfg.ContainerdNamespaces) != 2 || cfg.ContainerdNamespaces[0] != "moby" || cfg.ContainerdNamespaces[1] != "k8s.io" {
+		t.Errorf("ContainerdNamespaces = %v, want [moby k8s.io]", cfg.ContainerdNamespaces)
+	}
+	if cfg.NodeDistro != "talos" {
+		t.Errorf("NodeDistro = %q, want talos", cfg.NodeDistro)
+	}
+	if cfg.CPULimitMillis != 2000 {
+		t.Errorf("CPULimitMillis = %d, want 2000", cfg.CPULimitMillis)
+	}
+	if cfg.MemoryLimitBytes != 2147483648 {
+		t.Errorf("MemoryLimitBytes = %d, want 2147483648", cfg.MemoryLimitBytes)
+	}
+	if cfg.SBOMTimeout != 90*time.Second {
+		t.Errorf("SBOMTimeout = %s, want 90s", cfg.SBOMTimeout)
+	}
+	if cfg.MaxConcurrentScans != 4 {
+		t.Errorf("MaxConcurrentScans = %d, want 4", cfg.MaxConcurrentScans)
+	}
+	if cfg.ShutdownDrainTimeout != 45*time.Second {
+		t.Errorf("ShutdownDrainTimeout = %s, want 45s", cfg.ShutdownDrainTimeout)
+	}
+	if len(cfg.HostScanningExtraExclusions) != 2 || cfg.HostScanningExtraExclusions[1] != "/backup/**" {
+		t.Errorf("HostScanningExtraExclusions = %v", cfg.HostScanningExtraExclusions)
+	}
+	if cfg.HostScanningAutoDetectNFS {
+		t.Error("HostScanningAutoDetectNFS should be false")
+	}
+
+	effective := cfg.Effective()
+	if effective["sbom_timeout"] != "1m30s" {
+		t.Errorf("effective sbom_timeout = %v, want 1m30s", effective["sbom_timeout"])
+	}
+	if effective["node_name"] != "worker-1" {
+		t.Errorf("effective node_name = %v, want worker-1", effective["node_name"])
+	}
+}
+
+func TestLoadScanThrottling(t *testing.T) {
+	t.Setenv("SCAN_NICE", "10")
+	t.Setenv("SCAN_IO_CLASS", "Best-Effort")
+	t.Setenv("SCAN_IO_LEVEL", "7")
+	t.Setenv("SCAN_MAX_FILES_PER_SECOND", "500")
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if cfg.ScanNice != 10 || cfg.ScanIOClass != "best-effort" || cfg.ScanIOLevel != 7 {
+		t.Errorf("scan priority = %d/%q/%d, want 10/best-effort/7", cfg.ScanNice, cfg.ScanIOClass, cfg.ScanIOLevel)
+	}
+	if cfg.ScanMaxFilesPerSecond != 500 {
+		t.Errorf("ScanMaxFilesPerSecond = %d, want 500", cfg.ScanMaxFilesPerSecond)
+	}
+	if effective := cfg.Effective(); effective["scan_max_files_per_second"] != 500 {
+		t.Errorf("effective scan_max_files_per_second = %v, want 500", effective["scan_max_files_per_second"])
+	}
+}
+
+func TestLoadTLSAndAuth(t *testing.T) {
+	t.Setenv("TLS_CERT_FILE", "/etc/tls/tls.crt")
+	t.Setenv("TLS_KEY_FILE", "/etc/tls/tls.key")
+	t.Setenv("TLS_CLIENT_CA_FILE", "/etc/tls/ca.crt")
+	t.Setenv("POD_SCANNER_AUTH_SECRET", "s3cret")
+	t.Setenv("GRPC_PORT", "9090")
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if cfg.TLSCertFile != "/etc/tls/tls.crt" || cfg.TLSKeyFile != "/etc/tls/tls.key" || cfg.TLSClientCAFile != "/etc/tls/ca.crt" {
+		t.Errorf("TLS files = %q, %q, %q", cfg.TLSCertFile, cfg.TLSKeyFile, cfg.TLSClientCAFile)
+	}
+	if cfg.AuthSecret != "s3cret" {
+		t.Errorf("AuthSecret = %q, want s3cret", cfg.AuthSecret)
+	}
+	if cfg.GRPCPort != "9090" {
+		t.Errorf("GRPCPort = %q, want 9090", cfg.GRPCPort)
+	}
+	if effective := cfg.Effective(); effective["auth_enabled"] != true {
+		t.Errorf("effective auth_enabled = %v, want true without the secret itself", effective["auth_enabled"])
+	}
+}
+
+func TestLoadValidation(t *testing.T) {
+	tests := []struct {
+		name  string
+		key   string
+		value string
+	}{
+		{"non-numeric port", "PORT", "http"},
+		{"port out of range", "PORT", "70000"},
+		{"non-numeric gRPC port", "GRPC_PORT", "grpc"},
+		{"gRPC port on the HTTP port", "GRPC_PORT", "8080"},
+		{"relative containerd socket", "CONTAINERD_SOCKET", "run/containerd.sock"},
+		{"unparsable timeout", "SBOM_TIMEOUT", "five minutes"},
+		{"zero timeout", "HOST_SBOM_TIMEOUT", "0s"},
+		{"zero concurrency", "MAX_CONCURRENT_SCANS", "0"},
+		{"negative drain timeout", "SHUTDOWN_DRAIN_TIMEOUT", "-1s"},
+		{"non-numeric memory limit", "MEMORY_LIMIT", "2Gi"},
+		{"negative cpu limit", "CPU_LIMIT", "-1"},
+		{"nice out of range", "SCAN_NICE", "20"},
+		{"unknown io class", "SCAN_IO_CLASS", "realtime"},
+		{"io level out of range", "SCAN_IO_LEVEL", "8"},
+		{"negative file rate", "SCAN_MAX_FILES_PER_SECOND", "-5"},
+		{"fault injection without debug mode", "FAULT_INJECTION", "*=error:boom"},
+		{"TLS certificate without key", "TLS_CERT_FILE", "/etc/tls/tls.crt"},
+		{"client CA without certificate", "TLS_CLIENT_CA_FILE", "/etc/tls/ca.crt"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv(tt.key, tt.value)
+			if _, err := Load(); err == nil {
+				t.Errorf("Load() with %s=%q should fail", tt.key, tt.value)
+			}
+		})
+	}
+}
@@ -0,0 +1,249 @@
+// Package faults injects SBOM generation failures and delays for specific
+// images, so the scan server's retry, dead-letter and alerting behavior can be
+// tested end to end. It is only enabled with DEBUG_ENABLED and never meant for
+// production.
+//
+// Faults are described by a spec: a comma-separated list of rules
+//
+//	<digest or *>=error:<message>[@<rate>]
+//	<digest or *>=delay:<duration>[@<rate>]
+//
+// where rate is the share (0-1) of matching requests the rule applies to
+// (default 1). For example "sha256:abc...=error:image not found,*=delay:30s@0.2"
+// fails every scan of one image with a 404 and delays a fifth of all scans.
+package faults
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+var log = slog.Default().With("component", "pod-scanner")
+
+// AllImages is the rule digest matching every image
+const AllImages = "*"
+
+// Rule makes SBOM generation of an image fail or slow down
+type Rule struct {
+	Digest string        // Image digest, or AllImages
+	Error  string        // Error message returned instead of the SBOM (empty for none)
+	Delay  time.Duration // Added before generating the SBOM
+	Rate   float64       // Share (0-1) of matching requests the rule applies to
+}
+
+// matches reports whether the rule applies to image, a digest or a reference
+// pinned to a digest (repo@sha256:...)
+func (r Rule) matches(image string) bool {
+	return r.Digest == AllImages || image == r.Digest || strings.HasSuffix(image, "@"+r.Digest)
+}
+
+// String returns the rule in spec form
+func (r Rule) String() string {
+	action := "error:" + r.Error
+	if r.Error == "" {
+		action = "delay:" + r.Delay.String()
+	}
+	if r.Rate < 1 {
+		action += "@" + strconv.FormatFloat(r.Rate, 'g', -1, 64)
+	}
+	return r.Digest + "=" + action
+}
+
+// Parse parses a fault spec into rules
+func Parse(spec string) ([]Rule, error) {
+	var rules []Rule
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		digest, action, ok := strings.Cut(entry, "=")
+		digest = strings.TrimSpace(digest)
+		if !ok || digest == "" {
+			return nil, fmt.Errorf("fault %q must be <digest or *>=<action>", entry)
+		}
+
+		rule := Rule{Digest: digest, Rate: 1}
+		if at := strings.LastIndex(action, "@"); at >= 0 {
+			rate, err := strconv.ParseFloat(action[at+1:], 64)
+			if err != nil || rate < 0 || rate > 1 {
+				return nil, fmt.Errorf("fault %q: rate must be between 0 and 1", entry)
+			}
+			rule.Rate, action = rate, action[:at]
+		}
+
+		kind, value, _ := strings.Cut(action, ":")
+		switch strings.TrimSpace(kind) {
+		case "error":
+			rule.Error = strings.TrimSpace(value)
+			if rule.Error == "" {
+				return nil, fmt.Errorf("fault %q: error message required", entry)
+			}
+		case "delay":
+			delay, err := time.ParseDuration(strings.TrimSpace(value))
+			if err != nil || delay <= 0 {
+				return nil, fmt.Errorf("fault %q: delay must be a positive duration", entry)
+			}
+			rule.Delay = delay
+		default:
+			return nil, fmt.Errorf("fault %q: action must be error:<message> or delay:<duration>", entry)
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+// Injector applies the fault rules to SBOM generations. The zero value
+// injects nothing.
+type Injector struct {
+	mu       sync.RWMutex
+	rules    []Rule
+	injected atomic.Int64
+}
+
+// Set replaces the rules with those of spec; an empty spec clears them
+func (i *Injector) Set(spec string) error {
+	rules, err := Parse(spec)
+	if err != nil {
+		return err
+	}
+	i.mu.Lock()
+	i.rules = rules
+	i.mu.Unlock()
+	if len(rules) > 0 {
+		log.Warn("fault injection: SBOM generation faults configured", "faults", spec)
+	} else {
+		log.Info("fault injection: SBOM generation faults cleared")
+	}
+	return nil
+}
+
+// Rules returns the current rules
+func (i *Injector) Rules() []Rule {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+	return append([]Rule(nil), i.rules...)
+}
+
+// Injected returns the number of faults injected so far
+func (i *Injector) Injected() int64 {
+	return i.injected.Load()
+}
+
+// Apply injects the faults matching image: it waits out the delays (giving up
+// when ctx is done) and returns the first error, if any
+func (i *Injector) Apply(ctx context.Context, image string) error {
+	for _, rule := range i.Rules() {
+		if !rule.matches(image) || rand.Float64() >= rule.Rate {
+			continue
+		}
+		i.injected.Add(1)
+		if rule.Delay > 0 {
+			log.Warn("fault injection: delaying SBOM generation", "image", image, "delay", rule.Delay)
+			timer := time.NewTimer(rule.Delay)
+			select {
+			case <-timer.C:
+			case <-ctx.Done():
+				timer.Stop()
+				return ctx.Err()
+			}
+		}
+		if rule.Error != "" {
+			log.Warn("fault injection: failing SBOM generation", "image", image, "error", rule.Error)
+			return fmt.Errorf("injected fault: %s", rule.Error)
+		}
+	}
+	return nil
+}
+
+// Generator generates an SBOM for an image (matches handlers.SBOMGenerator)
+type Generator interface {
+	GenerateSBOM(ctx context.Context, digest string) ([]byte, error)
+}
+
+// Wrap returns a generator that injects faults before calling g
+func (i *Injector) Wrap(g Generator) Generator {
+	return &faultGenerator{generator: g, injector: i}
+}
+
+// WrapFunc returns a generate func (such as runtime.GenerateRegistrySBOM)
+// that injects faults before calling generate
+func (i *Injector) WrapFunc(generate func(ctx context.Context, image string) ([]byte, error)) func(ctx context.Context, image string) ([]byte, error) {
+	return func(ctx context.Context, image string) ([]byte, error) {
+		if err := i.Apply(ctx, image); err != nil {
+			return nil, err
+		}
+		return generate(ctx, image)
+	}
+}
+
+// faultGenerator injects faults before generating SBOMs
+type faultGenerator struct {
+	generator Generator
+	injector  *Injector
+}
+
+func (g *faultGenerator) GenerateSBOM(ctx context.Context, digest string) ([]byte, error) {
+	if err := g.injector.Apply(ctx, digest); err != nil {
+		return nil, err
+	}
+	return g.generator.GenerateSBOM(ctx, digest)
+}
+
+// maxSpecRequestSize bounds the /debug/faults request body
+const maxSpecRequestSize = 16 << 10
+
+// Handler returns the HTTP handler for the /debug/faults endpoint. GET lists
+// the rules, POST replaces them with a spec (sent as {"faults": "<spec>"} or as
+// the plain request body), DELETE clears them.
+func (i *Injector) Handler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+		case http.MethodPost:
+			body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxSpecRequestSize))
+			if err != nil {
+				http.Error(w, "Invalid request body", http.StatusBadRequest)
+				return
+			}
+			spec := string(body)
+			var req struct {
+				Faults string `json:"faults"`
+			}
+			if json.Unmarshal(body, &req) == nil {
+				spec = req.Faults
+			}
+			if err := i.Set(spec); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+		case http.MethodDelete:
+			_ = i.Set("")
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		rules := []string{}
+		for _, rule := range i.Rules() {
+			rules = append(rules, rule.String())
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(map[string]interface{}{
+			"faults":   rules,
+			"injected": i.Injected(),
+		}); err != nil {
+			log.Error("error encoding fault injection response", "error", err)
+		}
+	}
+}
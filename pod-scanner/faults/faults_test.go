@@ -0,0 +1,117 @@
+package faults
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+type stubGenerator struct{ calls int }
+
+func (g *stubGenerator) GenerateSBOM(ctx context.Context, digest string) ([]byte, error) {
+	g.calls++
+	return []byte("{}"), nil
+}
+
+func TestParse(t *testing.T) {
+	rules, err := Parse("sha256:abc=error:image not found, *=delay:30s@0.5,")
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	want := []Rule{
+		{Digest: "sha256:abc", Error: "image not found", Rate: 1},
+		{Digest: AllImages, Delay: 30 * time.Second, Rate: 0.5},
+	}
+	if len(rules) != len(want) || rules[0] != want[0] || rules[1] != want[1] {
+		t.Errorf("Parse() = %+v, want %+v", rules, want)
+	}
+	if got := rules[1].String(); got != "*=delay:30s@0.5" {
+		t.Errorf("String() = %q", got)
+	}
+
+	for _, spec := range []string{"sha256:abc", "=error:x", "*=error:", "*=delay:soon", "*=delay:-1s", "*=error:x@2", "*=crash"} {
+		if _, err := Parse(spec); err == nil {
+			t.Errorf("Parse(%q) should fail", spec)
+		}
+	}
+}
+
+func TestInjectorWrap(t *testing.T) {
+	var injector Injector
+	if err := injector.Set("sha256:abc=error:image not found"); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+	stub := &stubGenerator{}
+	gen := injector.Wrap(stub)
+
+	if _, err := gen.GenerateSBOM(context.Background(), "sha256:abc"); err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Errorf("GenerateSBOM(sha256:abc) error = %v, want injected not found", err)
+	}
+	if _, err := gen.GenerateSBOM(context.Background(), "sha256:def"); err != nil {
+		t.Errorf("GenerateSBOM(sha256:def) error = %v", err)
+	}
+	if stub.calls != 1 || injector.Injected() != 1 {
+		t.Errorf("generator calls = %d, injected = %d, want 1, 1", stub.calls, injector.Injected())
+	}
+
+	// Registry references pinned to the digest match too
+	generate := injector.WrapFunc(stub.GenerateSBOM)
+	if _, err := generate(context.Background(), "registry.example.com/app@sha256:abc"); err == nil {
+		t.Error("expected the registry reference to match the rule")
+	}
+}
+
+func TestInjectorDelay(t *testing.T) {
+	var injector Injector
+	if err := injector.Set("*=delay:1h"); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := injector.Apply(ctx, "sha256:abc"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Apply() error = %v, want the context deadline", err)
+	}
+}
+
+func TestHandler(t *testing.T) {
+	var injector Injector
+	handler := injector.Handler()
+	serve := func(method, body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		handler(rec, httptest.NewRequest(method, "/debug/faults", strings.NewReader(body)))
+		return rec
+	}
+
+	if rec := serve(http.MethodPost, `{"faults":"*=explode"}`); rec.Code != http.StatusBadRequest {
+		t.Errorf("invalid spec: status = %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+	if rec := serve(http.MethodPost, `{"faults":"sha256:abc=error:boom"}`); rec.Code != http.StatusOK {
+		t.Fatalf("POST: status = %d: %s", rec.Code, rec.Body.String())
+	}
+	if rec := serve(http.MethodPost, `*=delay:5s@0.1`); rec.Code != http.StatusOK {
+		t.Fatalf("POST plain spec: status = %d: %s", rec.Code, rec.Body.String())
+	}
+
+	var resp struct {
+		Faults []string `json:"faults"`
+	}
+	if err := json.NewDecoder(serve(http.MethodGet, "").Body).Decode(&resp); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if len(resp.Faults) != 1 || resp.Faults[0] != "*=delay:5s@0.1" {
+		t.Errorf("GET faults = %v", resp.Faults)
+	}
+
+	serve(http.MethodDelete, "")
+	if rules := injector.Rules(); len(rules) != 0 {
+		t.Errorf("rules after DELETE = %v", rules)
+	}
+	if rec := serve(http.MethodPut, ""); rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("PUT: status = %d, want %d", rec.Code, http.StatusMethodNotAllowed)
+	}
+}
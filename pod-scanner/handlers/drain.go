@@ -0,0 +1,96 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// DrainRetryAfter is the Retry-After sent to requests turned away while
+// draining, roughly the time a replacement pod-scanner needs to start
+const DrainRetryAfter = 30 * time.Second
+
+// Drainer lets a pod-scanner that is shutting down finish the SBOM
+// generations in flight instead of killing them, so a DaemonSet rollout
+// doesn't make the scan server record failures. Once draining, new requests
+// get 503 Service Unavailable with a Retry-After header; /health keeps
+// answering so probes don't restart the pod mid-drain.
+type Drainer struct {
+	mu       sync.Mutex
+	draining bool
+	inFlight int
+	idle     chan struct{} // closed once draining with no request in flight
+}
+
+// Middleware tracks the requests served by next and rejects new ones while
+// draining
+func (d *Drainer) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/health" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if !d.begin() {
+			w.Header().Set("Retry-After", strconv.Itoa(int(DrainRetryAfter.Seconds())))
+			http.Error(w, "pod-scanner is shutting down", http.StatusServiceUnavailable)
+			return
+		}
+		defer d.done()
+		next.ServeHTTP(w, r)
+	})
+}
+
+// begin counts a request in flight, or reports false while draining
+func (d *Drainer) begin() bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.draining {
+		return false
+	}
+	d.inFlight++
+	return true
+}
+
+func (d *Drainer) done() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.inFlight--
+	if d.draining && d.inFlight == 0 {
+		close(d.idle)
+	}
+}
+
+// InFlight returns the number of requests being served
+func (d *Drainer) InFlight() int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.inFlight
+}
+
+// Drain stops accepting requests and waits until those in flight have
+// finished, or returns ctx's error once it is done
+func (d *Drainer) Drain(ctx context.Context) error {
+	d.mu.Lock()
+	if !d.draining {
+		d.draining = true
+		d.idle = make(chan struct{})
+		if d.inFlight == 0 {
+			close(d.idle)
+		}
+	}
+	idle, inFlight := d.idle, d.inFlight
+	d.mu.Unlock()
+
+	if inFlight > 0 {
+		log.Info("draining in-flight requests", "in_flight", inFlight)
+	}
+	select {
+	case <-idle:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,55 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestDrainer(t *testing.T) {
+	var drainer Drainer
+	started, release := make(chan struct{}), make(chan struct{})
+	handler := drainer.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/sbom/sha256:slow" {
+			close(started)
+			<-release
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	serve := func(path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		return rec
+	}
+
+	slow := make(chan *httptest.ResponseRecorder)
+	go func() { slow <- serve("/sbom/sha256:slow") }()
+	<-started
+
+	// The generation in flight holds up the drain
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := drainer.Drain(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Drain() error = %v, want the deadline while a request is in flight", err)
+	}
+
+	// New requests are turned away, probes still answered
+	rec := serve("/sbom/sha256:new")
+	if rec.Code != http.StatusServiceUnavailable || rec.Header().Get("Retry-After") != "30" {
+		t.Errorf("request while draining: status = %d, Retry-After = %q", rec.Code, rec.Header().Get("Retry-After"))
+	}
+	if rec := serve("/health"); rec.Code != http.StatusOK {
+		t.Errorf("/health while draining: status = %d", rec.Code)
+	}
+
+	close(release)
+	if rec := <-slow; rec.Code != http.StatusOK {
+		t.Errorf("in-flight request: status = %d, want it to complete", rec.Code)
+	}
+	if err := drainer.Drain(context.Background()); err != nil || drainer.InFlight() != 0 {
+		t.Errorf("Drain() error = %v, in flight = %d", err, drainer.InFlight())
+	}
+}
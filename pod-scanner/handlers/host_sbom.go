@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"net/http"
 	"time"
@@ -10,6 +11,7 @@ import (
 	"github.com/anchore/syft/syft/format"
 	"github.com/anchore/syft/syft/format/syftjson"
 	"github.com/anchore/syft/syft/source"
+	"github.com/bvboe/b2s-go/pod-scanner/throttle"
 	"github.com/bvboe/b2s-go/sbom-generator-shared/exclusions"
 )
 
@@ -26,6 +28,8 @@ type HostSBOMConfig struct {
 	AutoDetectNFS bool
 	// ExtraNetworkFSTypes are additional network FS types to detect
 	ExtraNetworkFSTypes []string
+	// FileLimiter limits how many files per second scans read (nil for no limit)
+	FileLimiter *throttle.Limiter
 }
 
 // DefaultHostSBOMConfig returns a default configuration for host scanning
@@ -104,6 +108,10 @@ func HostSBOMHandler(cfg HostSBOMConfig) http.HandlerFunc {
 			}
 		}()
 
+		// Count and rate limit file reads
+		scan := throttle.StartScan(cfg.FileLimiter)
+		src = throttle.WrapSource(throttle.WithScan(ctx, scan), src)
+
 		// Create SBOM from the source
 		sbomCfg := syft.DefaultCreateSBOMConfig()
 		sbomCfg.Search.Scope = source.AllLayersScope
@@ -144,6 +152,10 @@ func HostSBOMHandler(cfg HostSBOMConfig) http.HandlerFunc {
 		w.Header().Set("Content-Type", "application/json")
 		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s\"", filename))
 		w.Header().Set("Content-Length", fmt.Sprintf("%d", len(sbomBytes)))
+		usage := scan.Finish()
+		if encoded, err := json.Marshal(usage); err == nil {
+			w.Header().Set(ScanUsageHeader, string(encoded))
+		}
 
 		// Write SBOM data
 		if _, err := w.Write(sbomBytes); err != nil {
@@ -152,7 +164,11 @@ func HostSBOMHandler(cfg HostSBOMConfig) http.HandlerFunc {
 			log.Info("successfully served host SBOM",
 				"node", nodeName,
 				"size", len(sbomBytes),
-				"packages", s.Artifacts.Packages.PackageCount())
+				"packages", s.Artifacts.Packages.PackageCount(),
+				"durationMs", usage.DurationMillis,
+				"cpuMs", usage.CPUMillis,
+				"filesRead", usage.FilesRead,
+				"throttledMs", usage.ThrottledMillis)
 		}
 	}
 }
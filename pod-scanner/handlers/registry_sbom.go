@@ -0,0 +1,63 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// RegistrySBOMGenerator generates an SBOM for an image pulled from its
+// registry (implemented by runtime.GenerateRegistrySBOM)
+type RegistrySBOMGenerator func(ctx context.Context, imageRef string) ([]byte, error)
+
+// RegistryHandler returns the HTTP handler for the /registry-sbom?image={ref}
+// endpoint. It generates the SBOM of an image that is not running on the node
+// by pulling it from its registry, sharing the concurrency limit of /sbom/.
+// The reference should be pinned to a digest (repo@sha256:...).
+func (s *SBOMService) RegistryHandler(generate RegistrySBOMGenerator) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		imageRef := strings.TrimSpace(r.URL.Query().Get("image"))
+		if imageRef == "" {
+			http.Error(w, "Image reference required", http.StatusBadRequest)
+			return
+		}
+
+		log.Info("registry SBOM request received", "image", imageRef)
+
+		ctx, cancel := context.WithTimeout(r.Context(), s.cfg.Timeout)
+		defer cancel()
+
+		select {
+		case s.slots <- struct{}{}:
+		case <-ctx.Done():
+			log.Warn("timed out waiting for SBOM generation slot", "image", imageRef, "maxConcurrent", s.cfg.MaxConcurrent)
+			http.Error(w, "Too many concurrent SBOM requests", http.StatusServiceUnavailable)
+			return
+		}
+		sbomData, usage, status, err := s.generateWith(ctx, "image", imageRef, generate)
+		<-s.slots
+		if err != nil {
+			http.Error(w, capitalize(err.Error()), status)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Content-Length", fmt.Sprintf("%d", len(sbomData)))
+		if encoded, err := json.Marshal(usage); err == nil {
+			w.Header().Set(ScanUsageHeader, string(encoded))
+		}
+
+		if _, err := w.Write(sbomData); err != nil {
+			log.Error("error writing registry SBOM response", "error", err)
+		} else {
+			log.Info("successfully served registry SBOM", "image", imageRef, "size", len(sbomData))
+		}
+	}
+}
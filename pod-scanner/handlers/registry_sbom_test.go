@@ -0,0 +1,47 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestRegistryHandler(t *testing.T) {
+	ref := "registry.example.com/team/app@sha256:abc"
+	svc := NewSBOMService(fakeGenerator{}, SBOMConfig{Timeout: 5 * time.Second, MaxConcurrent: 1})
+	handler := svc.RegistryHandler(func(_ context.Context, imageRef string) ([]byte, error) {
+		if imageRef == ref {
+			return []byte(`{"artifacts":[]}`), nil
+		}
+		return nil, errors.New("MANIFEST_UNKNOWN: manifest not found")
+	})
+
+	get := func(image string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		handler(rec, httptest.NewRequest(http.MethodGet, "/registry-sbom?image="+url.QueryEscape(image), nil))
+		return rec
+	}
+
+	rec := get(ref)
+	if rec.Code != http.StatusOK || rec.Body.String() != `{"artifacts":[]}` {
+		t.Fatalf("status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	if rec.Header().Get(ScanUsageHeader) == "" {
+		t.Error("expected scan usage header")
+	}
+	if rec := get("registry.example.com/team/app@sha256:def"); rec.Code != http.StatusNotFound {
+		t.Errorf("status = %d, want 404", rec.Code)
+	}
+	if rec := get(""); rec.Code != http.StatusBadRequest {
+		t.Errorf("status = %d, want 400", rec.Code)
+	}
+
+	// The slot is released after each request
+	if len(svc.slots) != 0 {
+		t.Errorf("expected all slots free, %d in use", len(svc.slots))
+	}
+}
@@ -0,0 +1,74 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/bvboe/b2s-go/pod-scanner/runtime"
+)
+
+// RuntimeHandler creates an HTTP handler for the /runtime endpoint
+// Reports the active container runtime and, for containerd, the namespaces
+// visible to the scanner and the order they are searched for images
+func RuntimeHandler(runtimeMgr *runtime.Manager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), 30*time.Second)
+		defer cancel()
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(runtimeMgr.Diagnostics(ctx)); err != nil {
+			log.Error("error encoding runtime diagnostics", "error", err)
+		}
+	}
+}
+
+// DigestResolver resolves image references through the node's container runtime
+type DigestResolver interface {
+	ResolveDigest(ctx context.Context, reference string) (string, error)
+}
+
+// ResolveResponse is the response of the /resolve endpoint
+type ResolveResponse struct {
+	Image  string `json:"image"`
+	Digest string `json:"digest"`
+}
+
+// ResolveHandler creates an HTTP handler for the /resolve?image=<reference>
+// endpoint. It returns the digest of an image present on the node, for
+// containers whose pod status doesn't report one (static pods such as
+// kube-apiserver). Images the runtime doesn't have are answered with 404.
+func ResolveHandler(resolver DigestResolver) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		image := r.URL.Query().Get("image")
+		if image == "" {
+			http.Error(w, "image parameter is required", http.StatusBadRequest)
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), 30*time.Second)
+		defer cancel()
+
+		digest, err := resolver.ResolveDigest(ctx, image)
+		if err != nil {
+			log.Warn("failed to resolve image digest", "image", image, "error", err)
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(ResolveResponse{Image: image, Digest: digest}); err != nil {
+			log.Error("error encoding resolve response", "error", err)
+		}
+	}
+}
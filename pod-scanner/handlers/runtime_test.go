@@ -0,0 +1,41 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+type fakeResolver map[string]string
+
+func (f fakeResolver) ResolveDigest(_ context.Context, reference string) (string, error) {
+	if digest, ok := f[reference]; ok {
+		return digest, nil
+	}
+	return "", errors.New("image not found")
+}
+
+func TestResolveHandler(t *testing.T) {
+	handler := ResolveHandler(fakeResolver{"registry.k8s.io/kube-apiserver:v1.31.0": "sha256:abc"})
+	get := func(image string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		handler(rec, httptest.NewRequest(http.MethodGet, "/resolve?image="+url.QueryEscape(image), nil))
+		return rec
+	}
+
+	rec := get("registry.k8s.io/kube-apiserver:v1.31.0")
+	var resp ResolveResponse
+	if rec.Code != http.StatusOK || json.Unmarshal(rec.Body.Bytes(), &resp) != nil || resp.Digest != "sha256:abc" {
+		t.Fatalf("status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	if rec := get("registry.k8s.io/etcd:3.5.15-0"); rec.Code != http.StatusNotFound {
+		t.Errorf("status = %d, want 404", rec.Code)
+	}
+	if rec := get(""); rec.Code != http.StatusBadRequest {
+		t.Errorf("status = %d, want 400", rec.Code)
+	}
+}
@@ -2,20 +2,121 @@ package handlers
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"log/slog"
 	"net/http"
 	"strings"
 	"time"
 
-	"github.com/bvboe/b2s-go/pod-scanner/runtime"
+	"github.com/bvboe/b2s-go/pod-scanner/throttle"
 )
 
 var log = slog.Default().With("component", "pod-scanner")
 
-// SBOMHandler creates an HTTP handler for /sbom/{digest} endpoint
+// ScanUsageHeader carries the JSON-encoded throttle.Usage of an SBOM generation
+const ScanUsageHeader = "X-Scan-Usage"
+
+// SBOMConfig configures the SBOM handler
+type SBOMConfig struct {
+	// Timeout is the maximum duration for SBOM generation
+	Timeout time.Duration
+	// MaxConcurrent is the maximum number of SBOMs generated at the same time
+	MaxConcurrent int
+	// FileLimiter limits how many files per second all scans together read (nil for no limit)
+	FileLimiter *throttle.Limiter
+}
+
+// DefaultSBOMConfig returns a default configuration for image SBOM generation
+func DefaultSBOMConfig() SBOMConfig {
+	return SBOMConfig{
+		Timeout:       5 * time.Minute,
+		MaxConcurrent: 2,
+	}
+}
+
+// SBOMGenerator generates an SBOM for an image digest (implemented by runtime.Manager)
+type SBOMGenerator interface {
+	GenerateSBOM(ctx context.Context, digest string) ([]byte, error)
+}
+
+// SBOMService serves the single (/sbom/{digest}) and batch (/sboms) SBOM
+// endpoints. Both share one limit of cfg.MaxConcurrent concurrent generations.
+type SBOMService struct {
+	generator SBOMGenerator
+	cfg       SBOMConfig
+	slots     chan struct{}
+}
+
+// NewSBOMService creates an SBOMService generating SBOMs with generator
+func NewSBOMService(generator SBOMGenerator, cfg SBOMConfig) *SBOMService {
+	if cfg.MaxConcurrent < 1 {
+		cfg.MaxConcurrent = 1
+	}
+	return &SBOMService{
+		generator: generator,
+		cfg:       cfg,
+		slots:     make(chan struct{}, cfg.MaxConcurrent),
+	}
+}
+
+// generate waits for a free slot and generates the SBOM for digest.
+// On failure it returns the HTTP status describing the error.
+// Requests beyond cfg.MaxConcurrent wait for a free slot until their timeout expires.
+func (s *SBOMService) generate(ctx context.Context, digest string) ([]byte, *throttle.Usage, int, error) {
+	// Set timeout for SBOM generation (including time spent waiting for a slot)
+	ctx, cancel := context.WithTimeout(ctx, s.cfg.Timeout)
+	defer cancel()
+
+	// Limit the number of concurrent SBOM generations
+	select {
+	case s.slots <- struct{}{}:
+		defer func() { <-s.slots }()
+	case <-ctx.Done():
+		log.Warn("timed out waiting for SBOM generation slot", "digest", digest, "maxConcurrent", s.cfg.MaxConcurrent)
+		return nil, nil, http.StatusServiceUnavailable, fmt.Errorf("too many concurrent SBOM requests")
+	}
+
+	return s.generateInSlot(ctx, digest)
+}
+
+// generateInSlot generates the SBOM for digest and reports the resources it
+// used; the caller holds a slot and ctx carries the generation timeout
+func (s *SBOMService) generateInSlot(ctx context.Context, digest string) ([]byte, *throttle.Usage, int, error) {
+	return s.generateWith(ctx, "digest", digest, s.generator.GenerateSBOM)
+}
+
+// generateWith generates the SBOM of image (a digest or a reference, logged
+// as key) with generate and reports the resources it used; the caller holds a
+// slot and ctx carries the generation timeout
+func (s *SBOMService) generateWith(ctx context.Context, key, image string, generate func(context.Context, string) ([]byte, error)) ([]byte, *throttle.Usage, int, error) {
+	scan := throttle.StartScan(s.cfg.FileLimiter)
+	sbomData, err := generate(throttle.WithScan(ctx, scan), image)
+	usage := scan.Finish()
+	if err != nil {
+		log.Error("error generating SBOM", key, image, "error", err)
+
+		// Check if it's a timeout
+		if ctx.Err() == context.DeadlineExceeded {
+			return nil, &usage, http.StatusGatewayTimeout, fmt.Errorf("SBOM generation timed out")
+		}
+
+		// Check if image not found
+		if strings.Contains(err.Error(), "not found") {
+			return nil, &usage, http.StatusNotFound, fmt.Errorf("image not found")
+		}
+
+		return nil, &usage, http.StatusInternalServerError, fmt.Errorf("failed to generate SBOM")
+	}
+	log.Info("SBOM generation resource usage", key, image,
+		"durationMs", usage.DurationMillis, "cpuMs", usage.CPUMillis, "filesRead", usage.FilesRead,
+		"bytesRead", usage.BytesRead, "throttledMs", usage.ThrottledMillis)
+	return sbomData, &usage, http.StatusOK, nil
+}
+
+// Handler returns the HTTP handler for the /sbom/{digest} endpoint
 // Generates SBOM on-demand using the runtime manager
-func SBOMHandler(runtimeMgr *runtime.Manager) http.HandlerFunc {
+func (s *SBOMService) Handler() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		// Extract digest from URL path: /sbom/sha256:abc123...
 		path := r.URL.Path
@@ -23,49 +124,18 @@ func SBOMHandler(runtimeMgr *runtime.Manager) http.HandlerFunc {
 			http.Error(w, "Digest required", http.StatusBadRequest)
 			return
 		}
-		digest := path[6:] // Remove "/sbom/" prefix
 
-		// Validate digest format (should start with sha256: or be a hex string)
-		if digest == "" {
-			http.Error(w, "Digest required", http.StatusBadRequest)
-			return
-		}
-
-		// Normalize digest format (ensure it has sha256: prefix)
-		if !strings.HasPrefix(digest, "sha256:") && len(digest) == 64 {
-			// Looks like a hex digest without prefix
-			digest = "sha256:" + digest
-		}
-
-		if !isValidDigest(digest) {
+		digest, ok := normalizeDigest(path[6:]) // Remove "/sbom/" prefix
+		if !ok {
 			http.Error(w, "Invalid digest format", http.StatusBadRequest)
 			return
 		}
 
 		log.Info("SBOM request received", "digest", digest)
 
-		// Set timeout for SBOM generation (5 minutes)
-		ctx, cancel := context.WithTimeout(r.Context(), 5*time.Minute)
-		defer cancel()
-
-		// Generate SBOM using runtime manager
-		sbomData, err := runtimeMgr.GenerateSBOM(ctx, digest)
+		sbomData, usage, status, err := s.generate(r.Context(), digest)
 		if err != nil {
-			log.Error("error generating SBOM", "digest", digest, "error", err)
-
-			// Check if it's a timeout
-			if ctx.Err() == context.DeadlineExceeded {
-				http.Error(w, "SBOM generation timed out", http.StatusGatewayTimeout)
-				return
-			}
-
-			// Check if image not found
-			if strings.Contains(err.Error(), "not found") {
-				http.Error(w, "Image not found", http.StatusNotFound)
-				return
-			}
-
-			http.Error(w, "Failed to generate SBOM", http.StatusInternalServerError)
+			http.Error(w, capitalize(err.Error()), status)
 			return
 		}
 
@@ -76,6 +146,9 @@ func SBOMHandler(runtimeMgr *runtime.Manager) http.HandlerFunc {
 		w.Header().Set("Content-Type", "application/json")
 		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"sbom_%s.json\"", filename))
 		w.Header().Set("Content-Length", fmt.Sprintf("%d", len(sbomData)))
+		if encoded, err := json.Marshal(usage); err == nil {
+			w.Header().Set(ScanUsageHeader, string(encoded))
+		}
 
 		// Write SBOM data
 		if _, err := w.Write(sbomData); err != nil {
@@ -86,6 +159,24 @@ func SBOMHandler(runtimeMgr *runtime.Manager) http.HandlerFunc {
 	}
 }
 
+// normalizeDigest adds the sha256: prefix to bare hex digests and validates the result
+func normalizeDigest(digest string) (string, bool) {
+	digest = strings.TrimSpace(digest)
+	if !strings.HasPrefix(digest, "sha256:") && len(digest) == 64 {
+		// Looks like a hex digest without prefix
+		digest = "sha256:" + digest
+	}
+	return digest, isValidDigest(digest)
+}
+
+// capitalize upper-cases the first letter of an error message for plain-text responses
+func capitalize(s string) string {
+	if s == "" {
+		return s
+	}
+	return strings.ToUpper(s[:1]) + s[1:]
+}
+
 // isValidDigest checks if the digest has a valid format
 func isValidDigest(digest string) bool {
 	// Should be in format: sha256:abc123... or sha512:...
@@ -0,0 +1,135 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/bvboe/b2s-go/pod-scanner/throttle"
+)
+
+// MaxBatchDigests is the maximum number of digests accepted by one /sboms request
+const MaxBatchDigests = 50
+
+// BatchSBOMRequest is the request body of POST /sboms
+type BatchSBOMRequest struct {
+	Digests []string `json:"digests"`
+}
+
+// BatchSBOMResult is one line of the NDJSON response of POST /sboms.
+// Exactly one result is written per requested digest, in completion order.
+type BatchSBOMResult struct {
+	Digest string          `json:"digest"`
+	Status int             `json:"status"`
+	SBOM   json.RawMessage `json:"sbom,omitempty"`
+	Error  string          `json:"error,omitempty"`
+	Usage  *throttle.Usage `json:"usage,omitempty"`
+}
+
+// BatchHandler returns the HTTP handler for the POST /sboms endpoint.
+// SBOMs are generated concurrently (sharing the limit of the single endpoint)
+// and streamed back as newline-delimited JSON as soon as each one completes,
+// so one connection serves a whole set of images.
+func (s *SBOMService) BatchHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		var req BatchSBOMRequest
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20)).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		if len(req.Digests) == 0 {
+			http.Error(w, "Digests required", http.StatusBadRequest)
+			return
+		}
+		if len(req.Digests) > MaxBatchDigests {
+			http.Error(w, fmt.Sprintf("At most %d digests per request", MaxBatchDigests), http.StatusBadRequest)
+			return
+		}
+
+		digests := make([]string, 0, len(req.Digests))
+		seen := make(map[string]bool, len(req.Digests))
+		for _, d := range req.Digests {
+			digest, ok := normalizeDigest(d)
+			if !ok {
+				http.Error(w, fmt.Sprintf("Invalid digest format: %s", d), http.StatusBadRequest)
+				return
+			}
+			if !seen[digest] {
+				seen[digest] = true
+				digests = append(digests, digest)
+			}
+		}
+
+		log.Info("batch SBOM request received", "digests", len(digests))
+
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		w.WriteHeader(http.StatusOK)
+		flusher, _ := w.(http.Flusher)
+		if flusher != nil {
+			flusher.Flush()
+		}
+
+		// Digests are dispatched in request order as slots free up, so the first
+		// digest is generated first; results are written in completion order.
+		// A digest's timeout starts once it has a slot.
+		results := make(chan BatchSBOMResult)
+		go func() {
+			var wg sync.WaitGroup
+			defer func() {
+				wg.Wait()
+				close(results)
+			}()
+			for _, digest := range digests {
+				select {
+				case s.slots <- struct{}{}:
+				case <-r.Context().Done():
+					return // client gone
+				}
+				wg.Add(1)
+				go func(digest string) {
+					defer wg.Done()
+					defer func() { <-s.slots }()
+
+					ctx, cancel := context.WithTimeout(r.Context(), s.cfg.Timeout)
+					defer cancel()
+
+					sbomData, usage, status, err := s.generateInSlot(ctx, digest)
+					result := BatchSBOMResult{Digest: digest, Status: status, Usage: usage}
+					if err != nil {
+						result.Error = err.Error()
+					} else {
+						result.SBOM = sbomData
+					}
+					results <- result
+				}(digest)
+			}
+		}()
+
+		enc := json.NewEncoder(w)
+		served := 0
+		for result := range results {
+			if r.Context().Err() != nil {
+				continue // client gone; drain remaining results
+			}
+			if err := enc.Encode(result); err != nil {
+				log.Error("error writing batch SBOM result", "digest", result.Digest, "error", err)
+				continue
+			}
+			if flusher != nil {
+				flusher.Flush()
+			}
+			if result.Error == "" {
+				served++
+			}
+		}
+		log.Info("batch SBOM request completed", "digests", len(digests), "served", served)
+	}
+}
@@ -0,0 +1,117 @@
+package handlers
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+type fakeGenerator map[string]string
+
+func (f fakeGenerator) GenerateSBOM(_ context.Context, digest string) ([]byte, error) {
+	if sbom, ok := f[digest]; ok {
+		return []byte(sbom), nil
+	}
+	return nil, errors.New("image not found")
+}
+
+func TestBatchHandler(t *testing.T) {
+	a := "sha256:" + strings.Repeat("a", 64)
+	b := "sha256:" + strings.Repeat("b", 64)
+	missing := "sha256:" + strings.Repeat("c", 64)
+
+	svc := NewSBOMService(fakeGenerator{
+		a: `{"artifacts":["a"]}`,
+		b: `{"artifacts":["b"]}`,
+	}, SBOMConfig{Timeout: 5 * time.Second, MaxConcurrent: 2})
+
+	// Bare hex digests are normalized and duplicates are dropped
+	body := `{"digests":["` + a + `","` + strings.Repeat("b", 64) + `","` + missing + `","` + a + `"]}`
+	req := httptest.NewRequest(http.MethodPost, "/sboms", strings.NewReader(body))
+	rec := httptest.NewRecorder()
+	svc.BatchHandler()(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != "application/x-ndjson" {
+		t.Errorf("Content-Type = %q", ct)
+	}
+
+	results := map[string]BatchSBOMResult{}
+	scanner := bufio.NewScanner(rec.Body)
+	for scanner.Scan() {
+		var r BatchSBOMResult
+		if err := json.Unmarshal(scanner.Bytes(), &r); err != nil {
+			t.Fatalf("invalid line %q: %v", scanner.Text(), err)
+		}
+		results[r.Digest] = r
+	}
+	if len(results) != 3 {
+		t.Fatalf("got %d results, want 3: %v", len(results), results)
+	}
+	if r := results[a]; r.Status != http.StatusOK || string(r.SBOM) != `{"artifacts":["a"]}` {
+		t.Errorf("result for a = %+v", r)
+	}
+	if r := results[b]; r.Status != http.StatusOK || string(r.SBOM) != `{"artifacts":["b"]}` {
+		t.Errorf("result for b = %+v", r)
+	}
+	if r := results[missing]; r.Status != http.StatusNotFound || r.Error == "" || r.SBOM != nil {
+		t.Errorf("result for missing = %+v", r)
+	}
+	for digest, r := range results {
+		if r.Usage == nil {
+			t.Errorf("result for %s has no resource usage", digest)
+		}
+	}
+}
+
+func TestHandlerReportsUsage(t *testing.T) {
+	a := "sha256:" + strings.Repeat("a", 64)
+	svc := NewSBOMService(fakeGenerator{a: `{"artifacts":["a"]}`}, DefaultSBOMConfig())
+
+	rec := httptest.NewRecorder()
+	svc.Handler()(rec, httptest.NewRequest(http.MethodGet, "/sbom/"+a, nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	var usage map[string]int64
+	if err := json.Unmarshal([]byte(rec.Header().Get(ScanUsageHeader)), &usage); err != nil {
+		t.Fatalf("invalid %s header %q: %v", ScanUsageHeader, rec.Header().Get(ScanUsageHeader), err)
+	}
+	for _, key := range []string{"duration_ms", "cpu_ms", "files_read", "bytes_read", "throttled_ms"} {
+		if _, ok := usage[key]; !ok {
+			t.Errorf("usage header missing %q: %v", key, usage)
+		}
+	}
+}
+
+func TestBatchHandlerRejectsBadRequests(t *testing.T) {
+	svc := NewSBOMService(fakeGenerator{}, DefaultSBOMConfig())
+	tests := []struct {
+		name   string
+		method string
+		body   string
+		want   int
+	}{
+		{"wrong method", http.MethodGet, "", http.StatusMethodNotAllowed},
+		{"invalid json", http.MethodPost, "{", http.StatusBadRequest},
+		{"no digests", http.MethodPost, `{"digests":[]}`, http.StatusBadRequest},
+		{"invalid digest", http.MethodPost, `{"digests":["nope"]}`, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			svc.BatchHandler()(rec, httptest.NewRequest(tt.method, "/sboms", strings.NewReader(tt.body)))
+			if rec.Code != tt.want {
+				t.Errorf("status = %d, want %d", rec.Code, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,144 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/bvboe/b2s-go/pod-scanner/sbomrpc"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	_ "google.golang.org/grpc/encoding/gzip" // accept gzip-compressed calls
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+// RegisterGRPC registers the gRPC SBOM service (see package sbomrpc) on
+// server. Calls share the concurrency limit of the HTTP endpoints, and the
+// generation timeout is the shorter of cfg.Timeout and the caller's deadline.
+func (s *SBOMService) RegisterGRPC(server *grpc.Server) {
+	server.RegisterService(&grpc.ServiceDesc{
+		ServiceName: sbomrpc.ServiceName,
+		HandlerType: (*interface{})(nil),
+		Streams: []grpc.StreamDesc{{
+			StreamName:    "GetSBOM",
+			ServerStreams: true,
+			Handler: func(_ interface{}, stream grpc.ServerStream) error {
+				return s.streamSBOM(stream)
+			},
+		}},
+	}, s)
+}
+
+// streamSBOM serves GetSBOM: it generates the SBOM of the requested digest
+// and sends it in chunks of sbomrpc.ChunkSize
+func (s *SBOMService) streamSBOM(stream grpc.ServerStream) error {
+	var req wrapperspb.StringValue
+	if err := stream.RecvMsg(&req); err != nil {
+		return err
+	}
+	digest, ok := normalizeDigest(req.GetValue())
+	if !ok {
+		return status.Error(codes.InvalidArgument, "invalid digest format")
+	}
+
+	log.Info("SBOM stream request received", "digest", digest)
+	sbomData, usage, httpStatus, err := s.generate(stream.Context(), digest)
+	if usage != nil {
+		if encoded, err := json.Marshal(usage); err == nil {
+			stream.SetTrailer(metadata.Pairs(sbomrpc.UsageTrailer, string(encoded)))
+		}
+	}
+	if err != nil {
+		return status.Error(grpcCode(httpStatus), capitalize(err.Error()))
+	}
+
+	for offset := 0; offset < len(sbomData); offset += sbomrpc.ChunkSize {
+		end := min(offset+sbomrpc.ChunkSize, len(sbomData))
+		if err := stream.SendMsg(wrapperspb.Bytes(sbomData[offset:end])); err != nil {
+			log.Error("error streaming SBOM", "digest", digest, "error", err)
+			return err
+		}
+	}
+	log.Info("successfully streamed SBOM", "digest", digest, "size", len(sbomData))
+	return nil
+}
+
+// grpcCode maps the HTTP status of a failed generation to a gRPC code
+func grpcCode(httpStatus int) codes.Code {
+	switch httpStatus {
+	case http.StatusNotFound:
+		return codes.NotFound
+	case http.StatusServiceUnavailable:
+		return codes.Unavailable
+	case http.StatusGatewayTimeout:
+		return codes.DeadlineExceeded
+	default:
+		return codes.Internal
+	}
+}
+
+// GRPCInterceptor applies the HTTP endpoints' protections to gRPC calls:
+// calls are turned away while draining, need a verified client certificate
+// when requireClientCert is set and a valid signature when secret is set
+func GRPCInterceptor(drainer *Drainer, secret []byte, requireClientCert bool) grpc.StreamServerInterceptor {
+	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if requireClientCert && !hasVerifiedClientCert(stream.Context()) {
+			return status.Error(codes.Unauthenticated, "client certificate required")
+		}
+		if drainer != nil {
+			if !drainer.begin() {
+				return status.Error(codes.Unavailable, "pod-scanner is shutting down")
+			}
+			defer drainer.done()
+		}
+		if secret != nil {
+			stream = &verifyingStream{ServerStream: stream, secret: secret, method: info.FullMethod}
+		}
+		return handler(srv, stream)
+	}
+}
+
+// hasVerifiedClientCert reports whether the caller presented a client
+// certificate that verified against the client CA
+func hasVerifiedClientCert(ctx context.Context) bool {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return false
+	}
+	tlsInfo, ok := p.AuthInfo.(credentials.TLSInfo)
+	return ok && len(tlsInfo.State.VerifiedChains) > 0
+}
+
+// verifyingStream checks the signature of a call when its request (the
+// digest the signature covers) is received
+type verifyingStream struct {
+	grpc.ServerStream
+	secret []byte
+	method string
+}
+
+func (s *verifyingStream) RecvMsg(m interface{}) error {
+	if err := s.ServerStream.RecvMsg(m); err != nil {
+		return err
+	}
+	req, ok := m.(*wrapperspb.StringValue)
+	if !ok {
+		return status.Error(codes.InvalidArgument, "unexpected request message")
+	}
+	var auth string
+	if md, ok := metadata.FromIncomingContext(s.Context()); ok {
+		if values := md.Get(sbomrpc.AuthMetadata); len(values) > 0 {
+			auth = values[0]
+		}
+	}
+	if err := sbomrpc.Verify(auth, s.secret, s.method, req.GetValue(), time.Now()); err != nil {
+		return status.Error(codes.Unauthenticated, err.Error())
+	}
+	return nil
+}
@@ -0,0 +1,118 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bvboe/b2s-go/pod-scanner/sbomrpc"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+// getSBOM calls GetSBOM on conn, signed with secret when set, and returns the
+// reassembled SBOM, the number of chunks and the usage trailer
+func getSBOM(t *testing.T, conn *grpc.ClientConn, digest string, secret []byte) ([]byte, int, string, error) {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if secret != nil {
+		auth, err := sbomrpc.Sign(secret, sbomrpc.GetSBOMMethod, digest, time.Now())
+		if err != nil {
+			t.Fatal(err)
+		}
+		ctx = metadata.AppendToOutgoingContext(ctx, sbomrpc.AuthMetadata, auth)
+	}
+	stream, err := conn.NewStream(ctx, &grpc.StreamDesc{ServerStreams: true}, sbomrpc.GetSBOMMethod)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := stream.SendMsg(wrapperspb.String(digest)); err != nil {
+		t.Fatal(err)
+	}
+	if err := stream.CloseSend(); err != nil {
+		t.Fatal(err)
+	}
+	var sbom bytes.Buffer
+	chunks := 0
+	for {
+		var chunk wrapperspb.BytesValue
+		if err := stream.RecvMsg(&chunk); err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, chunks, "", err
+		}
+		chunks++
+		sbom.Write(chunk.GetValue())
+	}
+	return sbom.Bytes(), chunks, strings.Join(stream.Trailer().Get(sbomrpc.UsageTrailer), ""), nil
+}
+
+func TestGRPCGetSBOM(t *testing.T) {
+	large := "sha256:" + strings.Repeat("a", 64)
+	missing := "sha256:" + strings.Repeat("b", 64)
+	largeSBOM := `{"artifacts":"` + strings.Repeat("x", 2*sbomrpc.ChunkSize+10) + `"}`
+	secret := []byte("shared-secret")
+
+	svc := NewSBOMService(fakeGenerator{large: largeSBOM}, SBOMConfig{Timeout: 5 * time.Second, MaxConcurrent: 1})
+	drainer := &Drainer{}
+	server := grpc.NewServer(grpc.StreamInterceptor(GRPCInterceptor(drainer, secret, false)))
+	svc.RegisterGRPC(server)
+	listener := bufconn.Listen(1 << 20)
+	go func() { _ = server.Serve(listener) }()
+	defer server.Stop()
+
+	conn, err := grpc.NewClient("passthrough:///pod-scanner",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return listener.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = conn.Close() }()
+
+	sbom, chunks, usage, err := getSBOM(t, conn, large, secret)
+	if err != nil {
+		t.Fatalf("GetSBOM() error = %v", err)
+	}
+	if string(sbom) != largeSBOM || chunks != 3 {
+		t.Errorf("GetSBOM() = %d bytes in %d chunks, want %d bytes in 3", len(sbom), chunks, len(largeSBOM))
+	}
+	if !strings.Contains(usage, `"duration_ms"`) {
+		t.Errorf("usage trailer = %q, want the scan usage", usage)
+	}
+
+	tests := []struct {
+		name   string
+		digest string
+		secret []byte
+		want   codes.Code
+	}{
+		{"unsigned", large, nil, codes.Unauthenticated},
+		{"other secret", large, []byte("other"), codes.Unauthenticated},
+		{"invalid digest", "latest", secret, codes.InvalidArgument},
+		{"missing image", missing, secret, codes.NotFound},
+	}
+	for _, tt := range tests {
+		if _, _, _, err := getSBOM(t, conn, tt.digest, tt.secret); status.Code(err) != tt.want {
+			t.Errorf("%s: GetSBOM() error = %v, want %s", tt.name, err, tt.want)
+		}
+	}
+
+	// Draining turns new calls away
+	if err := drainer.Drain(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, _, err := getSBOM(t, conn, large, secret); status.Code(err) != codes.Unavailable {
+		t.Errorf("GetSBOM() while draining error = %v, want Unavailable", err)
+	}
+}
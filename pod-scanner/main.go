@@ -5,42 +5,37 @@ import (
 	"encoding/json"
 	"fmt"
 	"log/slog"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
+	"runtime/debug"
 	"strings"
 	"syscall"
 	"time"
 
+	"github.com/bvboe/b2s-go/pod-scanner/config"
+	"github.com/bvboe/b2s-go/pod-scanner/faults"
 	"github.com/bvboe/b2s-go/pod-scanner/handlers"
+	"github.com/bvboe/b2s-go/pod-scanner/reqsign"
 	"github.com/bvboe/b2s-go/pod-scanner/runtime"
+	"github.com/bvboe/b2s-go/pod-scanner/throttle"
+	"github.com/bvboe/b2s-go/pod-scanner/tlsreload"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
 )
 
 // version is set at build time via ldflags
 var version = "dev"
 
-// parseCommaSeparated splits a comma-separated string into a slice of trimmed strings.
-func parseCommaSeparated(s string) []string {
-	if s == "" {
-		return nil
-	}
-	parts := strings.Split(s, ",")
-	var result []string
-	for _, p := range parts {
-		p = strings.TrimSpace(p)
-		if p != "" {
-			result = append(result, p)
-		}
-	}
-	return result
-}
-
 type InfoResponse struct {
-	Component string `json:"component"`
-	Version   string `json:"version"`
-	NodeName  string `json:"node_name"`
-	PodName   string `json:"pod_name"`
-	Namespace string `json:"namespace"`
+	Component string                 `json:"component"`
+	Version   string                 `json:"version"`
+	NodeName  string                 `json:"node_name"`
+	PodName   string                 `json:"pod_name"`
+	Namespace string                 `json:"namespace"`
+	Config    map[string]interface{} `json:"config"`
 }
 
 // healthHandler returns a simple OK response for health checks
@@ -51,20 +46,23 @@ func healthHandler(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
-// infoHandler returns component information as JSON
-func infoHandler(w http.ResponseWriter, r *http.Request) {
-	info := InfoResponse{
-		Component: "pod-scanner",
-		Version:   version,
-		NodeName:  os.Getenv("NODE_NAME"),
-		PodName:   os.Getenv("HOSTNAME"),
-		Namespace: os.Getenv("NAMESPACE"),
-	}
+// infoHandler returns component information and the effective configuration as JSON
+func infoHandler(cfg *config.Config) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		info := InfoResponse{
+			Component: "pod-scanner",
+			Version:   version,
+			NodeName:  cfg.NodeName,
+			PodName:   cfg.PodName,
+			Namespace: cfg.Namespace,
+			Config:    cfg.Effective(),
+		}
 
-	w.Header().Set("Content-Type", "application/json")
-	if err := json.NewEncoder(w).Encode(info); err != nil {
-		slog.Default().With("component", "pod-scanner").Error("error encoding info response", "error", err)
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(info); err != nil {
+			slog.Default().With("component", "pod-scanner").Error("error encoding info response", "error", err)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+		}
 	}
 }
 
@@ -103,16 +101,37 @@ func main() {
 	// Initialize structured logging from environment variables
 	initLogging()
 
-	port := os.Getenv("PORT")
-	if port == "" {
-		port = "8080"
+	cfg, err := config.Load()
+	if err != nil {
+		slog.Default().With("component", "pod-scanner").Error("failed to load configuration", "error", err)
+		os.Exit(1)
+	}
+
+	// Keep the Go heap below the container memory limit so SBOM generation
+	// spikes trigger GC before the kernel OOM killer. GOMEMLIMIT wins if set.
+	if cfg.MemoryLimitBytes > 0 && os.Getenv("GOMEMLIMIT") == "" {
+		softLimit := cfg.MemoryLimitBytes / 10 * 9
+		debug.SetMemoryLimit(softLimit)
+		slog.Default().With("component", "pod-scanner").Info("memory limit configured", "limitBytes", cfg.MemoryLimitBytes, "softLimitBytes", softLimit)
+	}
+
+	// Lower CPU and I/O priority before any scan threads start so SBOM
+	// generation yields to latency-sensitive pods on the node
+	priority := throttle.Priority{Nice: cfg.ScanNice, IOClass: cfg.ScanIOClass, IOLevel: cfg.ScanIOLevel}
+	if err := throttle.Apply(priority); err != nil {
+		slog.Default().With("component", "pod-scanner").Warn("failed to apply scan priority", "error", err)
 	}
 
 	// Initialize runtime manager for SBOM generation
-	runtimeMgr, err := runtime.NewManager()
+	runtimeMgr, err := runtime.NewManager(runtime.Options{
+		ContainerdSocket:     cfg.ContainerdSocket,
+		ContainerdNamespaces: cfg.ContainerdNamespaces,
+		Distro:               cfg.NodeDistro,
+	})
 	if err != nil {
-		slog.Default().With("component", "pod-scanner").Error("failed to initialize container runtime", "error", err)
-		os.Exit(1)
+		// Keep serving so /runtime can report why this node cannot be scanned;
+		// SBOM requests fail until the pod is restarted with a working runtime
+		slog.Default().With("component", "pod-scanner").Error("failed to initialize container runtime, image scanning disabled on this node", "error", err)
 	}
 	defer func() {
 		if err := runtimeMgr.Close(); err != nil {
@@ -122,50 +141,140 @@ func main() {
 
 	slog.Default().With("component", "pod-scanner").Info("using container runtime", "runtime", runtimeMgr.ActiveRuntime())
 
+	sbomCfg := handlers.DefaultSBOMConfig()
+	sbomCfg.Timeout = cfg.SBOMTimeout
+	sbomCfg.MaxConcurrent = cfg.MaxConcurrentScans
+	sbomCfg.FileLimiter = throttle.NewLimiter(cfg.ScanMaxFilesPerSecond)
+	if cfg.ScanMaxFilesPerSecond > 0 {
+		slog.Default().With("component", "pod-scanner").Info("scan file read rate limited", "maxFilesPerSecond", cfg.ScanMaxFilesPerSecond)
+	}
+
+	// Fault injection for resilience testing, only with debug mode enabled
+	var generator handlers.SBOMGenerator = runtimeMgr
+	generateRegistrySBOM := runtime.GenerateRegistrySBOM
+	endpoints := "/health, /info, /sbom/{digest}, /sboms, /registry-sbom, /runtime, /resolve, /host-sbom"
+	if cfg.DebugEnabled {
+		injector := &faults.Injector{}
+		if err := injector.Set(cfg.FaultInjection); err != nil {
+			slog.Default().With("component", "pod-scanner").Error("invalid FAULT_INJECTION", "error", err)
+			os.Exit(1)
+		}
+		generator = injector.Wrap(runtimeMgr)
+		generateRegistrySBOM = injector.WrapFunc(generateRegistrySBOM)
+		http.HandleFunc("/debug/faults", injector.Handler())
+		endpoints += ", /debug/faults"
+	}
+
 	// Register HTTP endpoints
 	http.HandleFunc("/health", healthHandler)
-	http.HandleFunc("/info", infoHandler)
-	http.HandleFunc("/sbom/", handlers.SBOMHandler(runtimeMgr))
+	http.HandleFunc("/info", infoHandler(cfg))
+	sbomService := handlers.NewSBOMService(generator, sbomCfg)
+	http.HandleFunc("/sbom/", sbomService.Handler())
+	http.HandleFunc("/sboms", sbomService.BatchHandler())
+	http.HandleFunc("/registry-sbom", sbomService.RegistryHandler(generateRegistrySBOM))
+	http.HandleFunc("/runtime", handlers.RuntimeHandler(runtimeMgr))
+	http.HandleFunc("/resolve", handlers.ResolveHandler(runtimeMgr))
 
 	// Register host SBOM endpoint for host-level scanning
 	// This scans the host filesystem (mounted at /host) for packages
 	hostSBOMCfg := handlers.DefaultHostSBOMConfig()
-
-	// Apply configuration from environment variables
-	if extraExclusions := os.Getenv("HOST_SCANNING_EXTRA_EXCLUSIONS"); extraExclusions != "" {
-		hostSBOMCfg.ExtraExclusions = parseCommaSeparated(extraExclusions)
+	hostSBOMCfg.Timeout = cfg.HostSBOMTimeout
+	hostSBOMCfg.ExtraExclusions = cfg.HostScanningExtraExclusions
+	hostSBOMCfg.AutoDetectNFS = cfg.HostScanningAutoDetectNFS
+	hostSBOMCfg.ExtraNetworkFSTypes = cfg.HostScanningExtraNetworkFSTypes
+	hostSBOMCfg.FileLimiter = sbomCfg.FileLimiter
+	if len(hostSBOMCfg.ExtraExclusions) > 0 {
 		slog.Default().With("component", "pod-scanner").Info("host scanning extra exclusions configured", "exclusions", hostSBOMCfg.ExtraExclusions)
 	}
-	if autoDetectNFS := os.Getenv("HOST_SCANNING_AUTO_DETECT_NFS"); autoDetectNFS != "" {
-		val := strings.ToLower(autoDetectNFS)
-		hostSBOMCfg.AutoDetectNFS = val == "true" || val == "1" || val == "yes"
-	}
-	if extraNetworkFSTypes := os.Getenv("HOST_SCANNING_EXTRA_NETWORK_FS_TYPES"); extraNetworkFSTypes != "" {
-		hostSBOMCfg.ExtraNetworkFSTypes = parseCommaSeparated(extraNetworkFSTypes)
+	if len(hostSBOMCfg.ExtraNetworkFSTypes) > 0 {
 		slog.Default().With("component", "pod-scanner").Info("host scanning extra network FS types configured", "types", hostSBOMCfg.ExtraNetworkFSTypes)
 	}
 
 	http.HandleFunc("/host-sbom", handlers.HostSBOMHandler(hostSBOMCfg))
 
+	// Let in-flight SBOM generations finish on shutdown, turning new requests away
+	drainer := &handlers.Drainer{}
+	var handler http.Handler = http.DefaultServeMux
+	if cfg.AuthSecret != "" {
+		handler = reqsign.Middleware(handler, []byte(cfg.AuthSecret), "/health")
+	} else {
+		slog.Default().With("component", "pod-scanner").Warn("POD_SCANNER_AUTH_SECRET not set, any pod can request SBOMs")
+	}
+	if cfg.TLSClientCAFile != "" {
+		handler = tlsreload.RequireClientCert(handler, "/health")
+	}
 	server := &http.Server{
-		Addr: ":" + port,
+		Addr:    ":" + cfg.Port,
+		Handler: drainer.Middleware(handler),
+	}
+	if cfg.TLSCertFile != "" {
+		certs, err := tlsreload.New(tlsreload.Files{CertFile: cfg.TLSCertFile, KeyFile: cfg.TLSKeyFile, CAFile: cfg.TLSClientCAFile})
+		if err != nil {
+			slog.Default().With("component", "pod-scanner").Error("failed to load TLS certificate", "error", err)
+			os.Exit(1)
+		}
+		server.TLSConfig = certs.ServerConfig()
 	}
 
-	slog.Default().With("component", "pod-scanner").Info("pod-scanner starting", "version", version, "port", port, "node", os.Getenv("NODE_NAME"))
-	slog.Default().With("component", "pod-scanner").Info("endpoints registered", "endpoints", "/health, /info, /sbom/{digest}, /host-sbom")
+	// The gRPC SBOM service shares the HTTP server's TLS, authentication and drain
+	var grpcServer *grpc.Server
+	if cfg.GRPCPort != "" {
+		var secret []byte
+		if cfg.AuthSecret != "" {
+			secret = []byte(cfg.AuthSecret)
+		}
+		opts := []grpc.ServerOption{grpc.StreamInterceptor(handlers.GRPCInterceptor(drainer, secret, cfg.TLSClientCAFile != ""))}
+		if server.TLSConfig != nil {
+			opts = append(opts, grpc.Creds(credentials.NewTLS(server.TLSConfig)))
+		}
+		grpcServer = grpc.NewServer(opts...)
+		sbomService.RegisterGRPC(grpcServer)
+		endpoints += ", gRPC GetSBOM on port " + cfg.GRPCPort
+	}
+
+	slog.Default().With("component", "pod-scanner").Info("pod-scanner starting", "version", version, "port", cfg.Port, "grpcPort", cfg.GRPCPort, "node", cfg.NodeName,
+		"tls", server.TLSConfig != nil, "mtls", cfg.TLSClientCAFile != "", "auth", cfg.AuthSecret != "")
+	slog.Default().With("component", "pod-scanner").Info("endpoints registered", "endpoints", endpoints)
 
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
 
 	go func() {
-		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		var err error
+		if server.TLSConfig != nil {
+			// The certificate comes from TLSConfig, reloaded when renewed
+			err = server.ListenAndServeTLS("", "")
+		} else {
+			err = server.ListenAndServe()
+		}
+		if err != nil && err != http.ErrServerClosed {
 			slog.Default().With("component", "pod-scanner").Error("server error", "error", err)
 			os.Exit(1)
 		}
 	}()
 
+	if grpcServer != nil {
+		listener, err := net.Listen("tcp", ":"+cfg.GRPCPort)
+		if err != nil {
+			slog.Default().With("component", "pod-scanner").Error("failed to listen for gRPC", "port", cfg.GRPCPort, "error", err)
+			os.Exit(1)
+		}
+		go func() {
+			if err := grpcServer.Serve(listener); err != nil {
+				slog.Default().With("component", "pod-scanner").Error("gRPC server error", "error", err)
+				os.Exit(1)
+			}
+		}()
+	}
+
 	<-sigChan
-	slog.Default().With("component", "pod-scanner").Info("shutdown signal received, shutting down gracefully")
+	slog.Default().With("component", "pod-scanner").Info("shutdown signal received, shutting down gracefully", "drainTimeout", cfg.ShutdownDrainTimeout)
+
+	drainCtx, drainCancel := context.WithTimeout(context.Background(), cfg.ShutdownDrainTimeout)
+	if err := drainer.Drain(drainCtx); err != nil {
+		slog.Default().With("component", "pod-scanner").Warn("drain timeout reached, cancelling in-flight SBOM generations", "inFlight", drainer.InFlight())
+	}
+	drainCancel()
 
 	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer shutdownCancel()
@@ -173,6 +282,18 @@ func main() {
 	if err := server.Shutdown(shutdownCtx); err != nil {
 		slog.Default().With("component", "pod-scanner").Error("error during shutdown", "error", err)
 	}
+	if grpcServer != nil {
+		stopped := make(chan struct{})
+		go func() {
+			grpcServer.GracefulStop()
+			close(stopped)
+		}()
+		select {
+		case <-stopped:
+		case <-shutdownCtx.Done():
+			grpcServer.Stop()
+		}
+	}
 
 	slog.Default().With("component", "pod-scanner").Info("pod-scanner stopped")
 }
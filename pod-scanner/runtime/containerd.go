@@ -6,14 +6,19 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"sort"
+	"strings"
 	"time"
 
 	"github.com/anchore/syft/syft"
 	"github.com/anchore/syft/syft/format"
 	"github.com/anchore/syft/syft/format/syftjson"
+	"github.com/bvboe/b2s-go/pod-scanner/throttle"
 	containerd "github.com/containerd/containerd/v2/client"
+	"github.com/containerd/containerd/v2/core/images"
 	"github.com/containerd/containerd/v2/core/mount"
 	"github.com/containerd/containerd/v2/pkg/namespaces"
+	"github.com/distribution/reference"
 	"github.com/opencontainers/go-digest"
 	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
 
@@ -26,6 +31,10 @@ import (
 const (
 	// Default namespace for Kubernetes
 	k8sNamespace = "k8s.io"
+	// Namespace used by Docker/moby when backed by containerd
+	mobyNamespace = "moby"
+	// Default snapshotter for containerd
+	snapshotterName = "overlayfs"
 )
 
 
@@ -89,6 +98,37 @@ func containsPlatformSuffix(name string) bool {
 type ContainerDClient struct {
 	client     *containerd.Client
 	socketPath string
+	namespaces []string // Configured namespaces to search (empty means discover)
+
+	triedSockets []string // Sockets probed at startup, in order
+}
+
+// NamespaceInfo describes a containerd namespace visible to the scanner
+type NamespaceInfo struct {
+	Name       string `json:"name"`
+	ImageCount int    `json:"image_count"`
+	Error      string `json:"error,omitempty"`
+}
+
+// ContainerdDiagnostics describes how the scanner sees containerd
+type ContainerdDiagnostics struct {
+	Socket               string          `json:"socket"`
+	TriedSockets         []string        `json:"tried_sockets,omitempty"`
+	ConfiguredNamespaces []string        `json:"configured_namespaces,omitempty"`
+	SearchOrder          []string        `json:"search_order"`
+	VisibleNamespaces    []NamespaceInfo `json:"visible_namespaces"`
+	ImageStore           *ImageStoreInfo `json:"image_store,omitempty"`
+	Error                string          `json:"error,omitempty"`
+}
+
+// ImageStoreInfo describes where containerd keeps unpacked image layers and
+// whether the scanner can reach them. Snapshots are mounted by their host
+// path, so the directory must be mounted into the pod at the same path.
+type ImageStoreInfo struct {
+	Snapshotter string `json:"snapshotter"`
+	Root        string `json:"root"`
+	Accessible  bool   `json:"accessible"`
+	Error       string `json:"error,omitempty"`
 }
 
 // tryContainerdSocket attempts to create a working containerd client for the given socket
@@ -119,20 +159,11 @@ func tryContainerdSocket(socketPath string) (*containerd.Client, error) {
 }
 
 // NewContainerDClient creates a new ContainerD runtime client
-// Auto-detects socket location for K3s, MicroK8s, and standard Kubernetes
-// Tries each socket and verifies the connection works
-func NewContainerDClient() *ContainerDClient {
-	var socketPaths []string
-
-	// Check environment variable override first
-	if envSocket := os.Getenv("CONTAINERD_SOCKET"); envSocket != "" {
-		log.Info("CONTAINERD_SOCKET environment variable set", "socket", envSocket)
-		socketPaths = append(socketPaths, envSocket)
-	}
-
-	// Add known socket locations
-	socketPaths = append(socketPaths, containerdSocketPaths...)
-
+// Tries each socket in socketPaths, in order, and uses the first one whose
+// connection works (see socketSearchOrder for the locations per distribution)
+// namespaces restricts image lookup to the given containerd namespaces (in order);
+// when empty, all namespaces are discovered and searched with k8s.io first
+func NewContainerDClient(socketPaths []string, namespaces []string) *ContainerDClient {
 	// Try each socket until we find one that works
 	for _, socketPath := range socketPaths {
 		log.Debug("trying containerd socket", "socket", socketPath)
@@ -143,56 +174,197 @@ func NewContainerDClient() *ContainerDClient {
 		}
 
 		log.Info("successfully connected to containerd", "socket", socketPath)
-		return &ContainerDClient{client: client, socketPath: socketPath}
+		c := &ContainerDClient{client: client, socketPath: socketPath, namespaces: namespaces, triedSockets: socketPaths}
+		if len(namespaces) > 0 {
+			log.Info("containerd namespaces configured", "namespaces", namespaces)
+		} else if visible, err := c.listNamespaces(context.Background()); err == nil {
+			log.Info("containerd namespaces discovered", "namespaces", visible)
+		}
+		return c
 	}
 
 	// No working socket found
 	log.Warn("failed to find any working containerd socket", "tried", socketPaths)
-	return &ContainerDClient{client: nil, socketPath: ""}
+	return &ContainerDClient{client: nil, socketPath: "", namespaces: namespaces, triedSockets: socketPaths}
 }
 
-// IsAvailable checks if ContainerD daemon is accessible
-func (c *ContainerDClient) IsAvailable() bool {
-	if c.client == nil {
-		return false
-	}
-
-	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+// listNamespaces returns all namespaces known to containerd
+func (c *ContainerDClient) listNamespaces(ctx context.Context) ([]string, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
+	return c.client.NamespaceService().List(ctx)
+}
 
-	// Try to get version to check connectivity
-	_, err := c.client.Version(ctx)
-	return err == nil
+// searchNamespaces returns the namespaces to search for images, in order.
+// Configured namespaces are used as-is. Otherwise all visible namespaces are
+// searched, Kubernetes (k8s.io) first, then moby, then the rest alphabetically.
+func (c *ContainerDClient) searchNamespaces(ctx context.Context) []string {
+	if len(c.namespaces) > 0 {
+		return c.namespaces
+	}
+
+	visible, err := c.listNamespaces(ctx)
+	if err != nil || len(visible) == 0 {
+		log.Debug("could not discover containerd namespaces, using default", "namespace", k8sNamespace, "error", err)
+		return []string{k8sNamespace}
+	}
+	return orderNamespaces(visible)
 }
 
-// Name returns the runtime name
-func (c *ContainerDClient) Name() string {
-	return "containerd"
+// orderNamespaces sorts namespaces so the most likely ones for Kubernetes workloads come first
+func orderNamespaces(names []string) []string {
+	priority := func(name string) int {
+		switch name {
+		case k8sNamespace:
+			return 0
+		case mobyNamespace:
+			return 1
+		default:
+			return 2
+		}
+	}
+
+	ordered := append([]string(nil), names...)
+	sort.SliceStable(ordered, func(i, j int) bool {
+		pi, pj := priority(ordered[i]), priority(ordered[j])
+		if pi != pj {
+			return pi < pj
+		}
+		return ordered[i] < ordered[j]
+	})
+	return ordered
 }
 
-// GenerateSBOM generates an SBOM for the given image digest
-// Uses OCI export to handle discard_unpacked_layers=true
-func (c *ContainerDClient) GenerateSBOM(ctx context.Context, digest string) ([]byte, error) {
+// Diagnostics reports the containerd socket and the namespaces visible to the scanner
+func (c *ContainerDClient) Diagnostics(ctx context.Context) ContainerdDiagnostics {
+	diag := ContainerdDiagnostics{
+		Socket:               c.socketPath,
+		TriedSockets:         c.triedSockets,
+		ConfiguredNamespaces: c.namespaces,
+		VisibleNamespaces:    []NamespaceInfo{},
+	}
 	if c.client == nil {
-		return nil, fmt.Errorf("ContainerD client not initialized")
+		diag.Error = "ContainerD client not initialized"
+		return diag
 	}
 
-	// Use k8s.io namespace (Kubernetes uses this)
-	ctx = namespaces.WithNamespace(ctx, k8sNamespace)
+	visible, err := c.listNamespaces(ctx)
+	if err != nil {
+		diag.Error = fmt.Sprintf("failed to list namespaces: %v", err)
+		diag.SearchOrder = c.searchNamespaces(ctx)
+		return diag
+	}
 
-	// Find image by digest
-	imageService := c.client.ImageService()
-	images, err := imageService.List(ctx)
+	for _, ns := range orderNamespaces(visible) {
+		info := NamespaceInfo{Name: ns}
+		imgs, err := c.client.ImageService().List(namespaces.WithNamespace(ctx, ns))
+		if err != nil {
+			info.Error = err.Error()
+		} else {
+			info.ImageCount = len(imgs)
+		}
+		diag.VisibleNamespaces = append(diag.VisibleNamespaces, info)
+	}
+	diag.SearchOrder = c.searchNamespaces(ctx)
+	store := c.imageStore(ctx)
+	diag.ImageStore = &store
+	return diag
+}
+
+// imageStore locates the snapshotter's root directory through the containerd
+// introspection API and checks that it is visible inside the pod. Bottlerocket
+// and Talos keep it on a separate (ephemeral) volume, which is only reachable
+// when the chart mounts it.
+func (c *ContainerDClient) imageStore(ctx context.Context) ImageStoreInfo {
+	info := ImageStoreInfo{Snapshotter: snapshotterName}
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	resp, err := c.client.IntrospectionService().Plugins(ctx,
+		fmt.Sprintf("type==io.containerd.snapshotter.v1,id==%s", snapshotterName))
 	if err != nil {
-		return nil, fmt.Errorf("failed to list ContainerD images: %w", err)
+		info.Error = fmt.Sprintf("failed to query snapshotter plugin: %v", err)
+		return info
 	}
+	for _, plugin := range resp.Plugins {
+		if plugin.GetInitErr() != nil {
+			info.Error = fmt.Sprintf("snapshotter failed to initialize: %s", plugin.GetInitErr().GetMessage())
+			return info
+		}
+		info.Root = plugin.Exports["root"]
+	}
+	if info.Root == "" {
+		info.Error = "snapshotter root not reported by containerd"
+		return info
+	}
+
+	if _, err := os.Stat(info.Root); err != nil {
+		info.Error = fmt.Sprintf("not mounted into the pod: %v", err)
+		return info
+	}
+	info.Accessible = true
+	return info
+}
 
-	var imageRef string
-	var targetDigest string
-	log.Debug("looking for image digest", "digest", digest, "imagesFound", len(images))
+// findImage searches the containerd namespaces for an image with the given digest.
+// Returns a context bound to the namespace the image was found in.
+func (c *ContainerDClient) findImage(ctx context.Context, digest string) (context.Context, string, string, error) {
+	searched := c.searchNamespaces(ctx)
+	total := 0
 
+	for _, ns := range searched {
+		nsCtx := namespaces.WithNamespace(ctx, ns)
+		imgs, err := c.client.ImageService().List(nsCtx)
+		if err != nil {
+			log.Debug("failed to list images in namespace", "namespace", ns, "error", err)
+			continue
+		}
+		total += len(imgs)
+		log.Debug("looking for image digest", "digest", digest, "namespace", ns, "imagesFound", len(imgs))
+
+		if imageRef, targetDigest := resolveImageRef(imgs, digest); imageRef != "" {
+			log.Debug("found image", "namespace", ns, "image", imageRef)
+			return nsCtx, imageRef, targetDigest, nil
+		}
+	}
+
+	log.Warn("image digest not found", "digest", digest, "namespaces", searched, "searched", total)
+	return nil, "", "", fmt.Errorf("image with digest %s not found in ContainerD (namespaces searched: %s)",
+		digest, strings.Join(searched, ", "))
+}
+
+// ResolveDigest returns the manifest digest of the image stored under the
+// given reference, searching the same namespaces as GenerateSBOM. Short names
+// are normalized the way the CRI plugin stores them (nginx:1.27 ->
+// docker.io/library/nginx:1.27).
+func (c *ContainerDClient) ResolveDigest(ctx context.Context, ref string) (string, error) {
+	if c.client == nil {
+		return "", fmt.Errorf("ContainerD client not initialized")
+	}
+
+	name := ref
+	if named, err := reference.ParseNormalizedNamed(ref); err == nil {
+		name = reference.TagNameOnly(named).String()
+	}
+	searched := c.searchNamespaces(ctx)
+	for _, ns := range searched {
+		img, err := c.client.ImageService().Get(namespaces.WithNamespace(ctx, ns), name)
+		if err != nil {
+			continue
+		}
+		log.Debug("resolved image reference", "image", name, "namespace", ns, "digest", img.Target.Digest)
+		return img.Target.Digest.String(), nil
+	}
+	return "", fmt.Errorf("image %s not found in ContainerD (namespaces searched: %s)",
+		name, strings.Join(searched, ", "))
+}
+
+// resolveImageRef finds the image matching the digest and returns the reference to
+// use for it along with its target (manifest) digest. Returns empty strings if no
+// image matches.
+func resolveImageRef(imgs []images.Image, digest string) (imageRef, targetDigest string) {
 	// First pass: find the image by digest and get its Target.Digest
-	for _, img := range images {
+	for _, img := range imgs {
 		imgDigest := img.Target.Digest.String()
 		log.Debug("checking image", "name", img.Name, "digest", imgDigest)
 
@@ -214,8 +386,7 @@ func (c *ContainerDClient) GenerateSBOM(ctx context.Context, digest string) ([]b
 	}
 
 	if imageRef == "" {
-		log.Warn("image digest not found", "digest", digest, "searched", len(images))
-		return nil, fmt.Errorf("image with digest %s not found in ContainerD", digest)
+		return "", ""
 	}
 
 	// If we found a bare digest reference (like "sha256:abc123..."), try to find a named reference
@@ -224,7 +395,7 @@ func (c *ContainerDClient) GenerateSBOM(ctx context.Context, digest string) ([]b
 	if len(imageRef) > 7 && imageRef[:7] == "sha256:" {
 		log.Debug("found digest-only reference, looking for named reference")
 		var fallbackRef string
-		for _, img := range images {
+		for _, img := range imgs {
 			// Look for ANY named image (not starting with sha256:) with the same target digest
 			if img.Target.Digest.String() == targetDigest && len(img.Name) > 7 && img.Name[:7] != "sha256:" {
 				// Prefer platform-specific image names (containing "-arm64", "-amd64", etc.)
@@ -249,7 +420,43 @@ func (c *ContainerDClient) GenerateSBOM(ctx context.Context, digest string) ([]b
 		}
 	}
 
-	log.Info("generating SBOM for ContainerD image", "image", imageRef, "digest", digest)
+	return imageRef, targetDigest
+}
+
+// IsAvailable checks if ContainerD daemon is accessible
+func (c *ContainerDClient) IsAvailable() bool {
+	if c.client == nil {
+		return false
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	// Try to get version to check connectivity
+	_, err := c.client.Version(ctx)
+	return err == nil
+}
+
+// Name returns the runtime name
+func (c *ContainerDClient) Name() string {
+	return "containerd"
+}
+
+// GenerateSBOM generates an SBOM for the given image digest
+// Uses OCI export to handle discard_unpacked_layers=true
+func (c *ContainerDClient) GenerateSBOM(ctx context.Context, digest string) ([]byte, error) {
+	if c.client == nil {
+		return nil, fmt.Errorf("ContainerD client not initialized")
+	}
+
+	// Find the image in whichever namespace holds it (k8s.io for Kubernetes, moby for
+	// Docker-backed containerd, or a distro-specific layout)
+	ctx, imageRef, targetDigest, err := c.findImage(ctx, digest)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Info("generating SBOM for ContainerD image", "image", imageRef, "digest", digest, "namespace", namespaceOf(ctx))
 
 	// Use snapshot-based scanning to avoid export issues with discard_unpacked_layers=true
 	// This scans the actual unpacked filesystem from containerd's snapshots
@@ -260,7 +467,6 @@ func (c *ContainerDClient) GenerateSBOM(ctx context.Context, digest string) ([]b
 
 	// Unpack the image to ensure snapshots exist
 	log.Debug("checking if image needs unpacking", "image", imageRef)
-	snapshotterName := "overlayfs" // Default snapshotter for containerd
 	unpacked, err := img.IsUnpacked(ctx, snapshotterName)
 	if err != nil {
 		return nil, fmt.Errorf("failed to check if image is unpacked: %w", err)
@@ -327,6 +533,8 @@ func (c *ContainerDClient) GenerateSBOM(ctx context.Context, digest string) ([]b
 	if err != nil {
 		return nil, fmt.Errorf("failed to get source for mounted directory %s: %w", mountDir, err)
 	}
+	// Count and rate limit file reads for the scan tracked in ctx, if any
+	src = throttle.WrapSource(ctx, src)
 
 	// Ensure cleanup of source
 	defer func() {
@@ -369,6 +577,12 @@ func (c *ContainerDClient) GenerateSBOM(ctx context.Context, digest string) ([]b
 	return sbomBytes, nil
 }
 
+// namespaceOf returns the containerd namespace bound to the context (empty if none)
+func namespaceOf(ctx context.Context) string {
+	ns, _ := namespaces.Namespace(ctx)
+	return ns
+}
+
 // getImagePlatform extracts architecture and OS from image config
 func (c *ContainerDClient) getImagePlatform(ctx context.Context, img containerd.Image) (arch, os string) {
 	// Get the image config which contains platform info
@@ -2,9 +2,44 @@ package runtime
 
 import (
 	"encoding/json"
+	"reflect"
 	"testing"
+
+	"github.com/containerd/containerd/v2/core/images"
+	"github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
 )
 
+func TestOrderNamespaces(t *testing.T) {
+	got := orderNamespaces([]string{"zeta", "moby", "default", "k8s.io"})
+	want := []string{"k8s.io", "moby", "default", "zeta"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("orderNamespaces() = %v, want %v", got, want)
+	}
+}
+
+func TestResolveImageRef(t *testing.T) {
+	manifest := digest.Digest("sha256:1111111111111111111111111111111111111111111111111111111111111111")
+	image := func(name string, d digest.Digest) images.Image {
+		return images.Image{Name: name, Target: ocispec.Descriptor{Digest: d}}
+	}
+	imgs := []images.Image{
+		image(manifest.String(), manifest),
+		image("docker.io/library/nginx:1.27", manifest),
+		image("docker.io/library/redis:7", "sha256:2222222222222222222222222222222222222222222222222222222222222222"),
+	}
+
+	// Bare digest references resolve to a named reference with the same target
+	ref, target := resolveImageRef(imgs, manifest.String())
+	if ref != "docker.io/library/nginx:1.27" || target != manifest.String() {
+		t.Errorf("resolveImageRef() = %q, %q", ref, target)
+	}
+
+	if ref, _ := resolveImageRef(imgs, "sha256:3333"); ref != "" {
+		t.Errorf("resolveImageRef() for unknown digest = %q, want empty", ref)
+	}
+}
+
 func TestInjectPlatformIntoSBOM(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -0,0 +1,176 @@
+package runtime
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// hostOSReleasePaths are the locations of the node's os-release file inside the
+// pod-scanner container: the file the Helm chart mounts from the host, then the
+// host root filesystem mounted for host scanning
+var hostOSReleasePaths = []string{
+	"/etc/host-os-release",
+	"/host/etc/os-release",
+	"/host/usr/lib/os-release",
+}
+
+// Distro identifies the operating system of the node pod-scanner runs on
+type Distro struct {
+	ID      string `json:"id"`
+	Name    string `json:"name,omitempty"`
+	Version string `json:"version,omitempty"`
+}
+
+// runtimeProfile describes where a node distribution keeps its container runtime
+type runtimeProfile struct {
+	// Containerd sockets to try, in order, before the generic locations
+	Sockets []string
+	// Sockets of containerd instances that never run Kubernetes pods
+	Ignore []string
+}
+
+// defaultProfile is the profile name reported when the node distribution has
+// no specific runtime paths
+const defaultProfile = "default"
+
+// runtimeProfiles holds the distributions whose runtime paths differ from a
+// standard Kubernetes node.
+//
+// Bottlerocket runs a host containerd for its own host containers on
+// /run/host-containerd/containerd.sock next to the Kubernetes one; its image
+// store lives on the ephemeral data volume bind-mounted to /var/lib/containerd.
+//
+// Talos runs a system containerd for its own services on
+// /system/run/containerd/containerd.sock. Pod images are only in the CRI
+// containerd.
+var runtimeProfiles = map[string]runtimeProfile{
+	"bottlerocket": {
+		Sockets: []string{"/run/containerd/containerd.sock"},
+		Ignore:  []string{"/run/host-containerd/containerd.sock"},
+	},
+	"talos": {
+		Sockets: []string{"/run/containerd/containerd.sock"},
+		Ignore:  []string{"/system/run/containerd/containerd.sock"},
+	},
+}
+
+// KnownDistros returns the distributions with a dedicated runtime profile
+func KnownDistros() []string {
+	names := make([]string, 0, len(runtimeProfiles))
+	for name := range runtimeProfiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// DetectDistro reads the node's os-release file. Returns an empty Distro when
+// none of the candidate files is readable.
+func DetectDistro() Distro {
+	for _, path := range hostOSReleasePaths {
+		distro, err := readOSRelease(path)
+		if err != nil {
+			log.Debug("os-release not readable", "path", path, "error", err)
+			continue
+		}
+		log.Debug("node distribution detected", "path", path, "distro", distro.ID, "version", distro.Version)
+		return distro
+	}
+	return Distro{}
+}
+
+// readOSRelease parses an os-release file (see os-release(5))
+func readOSRelease(path string) (Distro, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return Distro{}, err
+	}
+	defer func() { _ = f.Close() }()
+
+	var distro Distro
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		key, value, ok := strings.Cut(strings.TrimSpace(scanner.Text()), "=")
+		if !ok || strings.HasPrefix(key, "#") {
+			continue
+		}
+		value = unquoteOSReleaseValue(value)
+		switch key {
+		case "ID":
+			distro.ID = strings.ToLower(value)
+		case "PRETTY_NAME":
+			distro.Name = value
+		case "NAME":
+			if distro.Name == "" {
+				distro.Name = value
+			}
+		case "VERSION_ID":
+			distro.Version = value
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return Distro{}, err
+	}
+	if distro.ID == "" {
+		return Distro{}, fmt.Errorf("no ID in %s", path)
+	}
+	return distro, nil
+}
+
+// unquoteOSReleaseValue strips the shell-style quoting os-release values may use
+func unquoteOSReleaseValue(value string) string {
+	if len(value) >= 2 && value[0] == '\'' && value[len(value)-1] == '\'' {
+		return value[1 : len(value)-1]
+	}
+	if unquoted, err := strconv.Unquote(value); err == nil {
+		return unquoted
+	}
+	return value
+}
+
+// profileFor returns the runtime profile name and paths for a distribution.
+// override, when non-empty, takes precedence over the detected distribution.
+func profileFor(distro Distro, override string) (string, runtimeProfile) {
+	id := distro.ID
+	if override != "" {
+		id = strings.ToLower(override)
+	}
+	if profile, ok := runtimeProfiles[id]; ok {
+		return id, profile
+	}
+	return defaultProfile, runtimeProfile{}
+}
+
+// socketSearchOrder returns the containerd sockets to try, in order: the
+// configured override, the profile's sockets, then the generic locations.
+// Duplicates and the sockets the profile ignores are dropped; the override is
+// always kept.
+func socketSearchOrder(override string, profile runtimeProfile) []string {
+	var paths []string
+	seen := make(map[string]bool)
+	if override != "" {
+		seen[override] = true
+		paths = append(paths, override)
+	}
+	for _, socket := range profile.Ignore {
+		seen[socket] = true
+	}
+
+	add := func(socket string) {
+		if !seen[socket] {
+			seen[socket] = true
+			paths = append(paths, socket)
+		}
+	}
+	for _, socket := range profile.Sockets {
+		add(socket)
+	}
+	for _, socket := range containerdSocketPaths {
+		add(socket)
+	}
+	return paths
+}
@@ -0,0 +1,128 @@
+package runtime
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestReadOSRelease(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    Distro
+	}{
+		{
+			name: "bottlerocket",
+			content: `NAME=Bottlerocket
+ID=bottlerocket
+VERSION="1.20.3 (aws-k8s-1.29)"
+PRETTY_NAME="Bottlerocket OS 1.20.3 (aws-k8s-1.29)"
+VARIANT_ID=aws-k8s-1.29
+VERSION_ID=1.20.3
+`,
+			want: Distro{ID: "bottlerocket", Name: "Bottlerocket OS 1.20.3 (aws-k8s-1.29)", Version: "1.20.3"},
+		},
+		{
+			name: "talos",
+			content: `NAME="Talos"
+ID=talos
+VERSION_ID=v1.7.5
+PRETTY_NAME="Talos (v1.7.5)"
+# comment
+HOME_URL='https://www.talos.dev/'
+`,
+			want: Distro{ID: "talos", Name: "Talos (v1.7.5)", Version: "v1.7.5"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "os-release")
+			if err := os.WriteFile(path, []byte(tt.content), 0644); err != nil {
+				t.Fatal(err)
+			}
+			got, err := readOSRelease(path)
+			if err != nil {
+				t.Fatalf("readOSRelease() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("readOSRelease() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+
+	path := filepath.Join(t.TempDir(), "os-release")
+	if err := os.WriteFile(path, []byte("NAME=Unknown\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := readOSRelease(path); err == nil {
+		t.Error("expected an error for an os-release without ID")
+	}
+}
+
+func TestSocketSearchOrder(t *testing.T) {
+	_, talos := profileFor(Distro{ID: "talos"}, "")
+	_, bottlerocket := profileFor(Distro{ID: "ubuntu"}, "Bottlerocket")
+	name, generic := profileFor(Distro{ID: "ubuntu"}, "")
+	if name != defaultProfile {
+		t.Errorf("profileFor(ubuntu) = %q, want %q", name, defaultProfile)
+	}
+
+	tests := []struct {
+		name     string
+		override string
+		profile  runtimeProfile
+		want     []string
+	}{
+		{"default", "", generic, containerdSocketPaths},
+		{"bottlerocket", "/custom/containerd.sock", bottlerocket, []string{
+			"/custom/containerd.sock",
+			"/run/containerd/containerd.sock",
+			"/run/k3s/containerd/containerd.sock",
+			"/var/snap/microk8s/common/run/containerd.sock",
+			"/run/dockershim.sock",
+		}},
+		{"talos", "", talos, containerdSocketPaths},
+		{"override kept even if ignored", "/system/run/containerd/containerd.sock", talos,
+			append([]string{"/system/run/containerd/containerd.sock"}, containerdSocketPaths...)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := socketSearchOrder(tt.override, tt.profile)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("socketSearchOrder() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompatibilityIssues(t *testing.T) {
+	noRuntime := RuntimeDiagnostics{
+		ActiveRuntime: "none",
+		Containerd:    &ContainerdDiagnostics{TriedSockets: []string{"/run/containerd/containerd.sock"}},
+	}
+	issues := compatibilityIssues(noRuntime)
+	if len(issues) != 1 || !strings.Contains(issues[0], "/run/containerd/containerd.sock") {
+		t.Errorf("issues without runtime = %v", issues)
+	}
+
+	unmounted := RuntimeDiagnostics{
+		ActiveRuntime: "containerd",
+		Containerd: &ContainerdDiagnostics{ImageStore: &ImageStoreInfo{
+			Snapshotter: "overlayfs",
+			Root:        "/var/lib/containerd/io.containerd.snapshotter.v1.overlayfs",
+		}},
+	}
+	issues = compatibilityIssues(unmounted)
+	if len(issues) != 1 || !strings.Contains(issues[0], "extraHostPaths") {
+		t.Errorf("issues with unmounted image store = %v", issues)
+	}
+
+	unmounted.Containerd.ImageStore.Accessible = true
+	if issues := compatibilityIssues(unmounted); len(issues) != 0 {
+		t.Errorf("issues with mounted image store = %v, want none", issues)
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"github.com/anchore/syft/syft"
 	"github.com/anchore/syft/syft/format"
 	"github.com/anchore/syft/syft/format/syftjson"
+	"github.com/bvboe/b2s-go/pod-scanner/throttle"
 	"github.com/docker/docker/api/types/image"
 	"github.com/docker/docker/client"
 )
@@ -99,6 +100,8 @@ func (d *DockerClient) GenerateSBOM(ctx context.Context, digest string) ([]byte,
 	if err != nil {
 		return nil, fmt.Errorf("failed to get source for image %s: %w", imageRef, err)
 	}
+	// Count and rate limit file reads for the scan tracked in ctx, if any
+	src = throttle.WrapSource(ctx, src)
 
 	// Ensure cleanup of source
 	defer func() {
@@ -128,6 +131,24 @@ func (d *DockerClient) GenerateSBOM(ctx context.Context, digest string) ([]byte,
 	return sbomBytes, nil
 }
 
+// ResolveDigest returns the repository digest of the image with the given
+// reference, or its image ID for images without one (e.g. loaded locally)
+func (d *DockerClient) ResolveDigest(ctx context.Context, reference string) (string, error) {
+	if d.cli == nil {
+		return "", fmt.Errorf("docker client not initialized")
+	}
+	img, err := d.cli.ImageInspect(ctx, reference)
+	if err != nil {
+		return "", fmt.Errorf("image %s not found in Docker: %w", reference, err)
+	}
+	for _, repoDigest := range img.RepoDigests {
+		if idx := strings.LastIndex(repoDigest, "@"); idx != -1 {
+			return repoDigest[idx+1:], nil
+		}
+	}
+	return img.ID, nil
+}
+
 // Close closes the Docker client
 func (d *DockerClient) Close() error {
 	if d.cli != nil {
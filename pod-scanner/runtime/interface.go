@@ -10,6 +10,11 @@ type RuntimeClient interface {
 	// digest should be in the format "sha256:abc123..."
 	GenerateSBOM(ctx context.Context, digest string) ([]byte, error)
 
+	// ResolveDigest returns the digest of the image stored under reference
+	// (e.g. "registry.k8s.io/kube-apiserver:v1.31.0"), in the form GenerateSBOM
+	// accepts. Used for static pods whose status doesn't report the image ID.
+	ResolveDigest(ctx context.Context, reference string) (string, error)
+
 	// IsAvailable checks if this runtime is accessible
 	IsAvailable() bool
 
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
+	"strings"
 )
 
 var log = slog.Default().With("component", "pod-scanner")
@@ -13,12 +14,35 @@ type Manager struct {
 	docker     *DockerClient
 	containerd *ContainerDClient
 	active     RuntimeClient
+
+	distro  Distro
+	profile string
+}
+
+// Options configures runtime detection
+type Options struct {
+	// ContainerdSocket overrides the containerd socket auto-detection when non-empty
+	ContainerdSocket string
+	// ContainerdNamespaces restricts containerd image lookup to the given namespaces when non-empty
+	ContainerdNamespaces []string
+	// Distro selects the runtime profile (e.g. "bottlerocket", "talos") instead
+	// of detecting it from the node's os-release when non-empty
+	Distro string
 }
 
 // NewManager creates a new runtime manager and auto-detects available runtime
-// Tries Docker first, then ContainerD
-func NewManager() (*Manager, error) {
-	mgr := &Manager{}
+// Tries Docker first, then ContainerD, using the socket locations of the node's
+// distribution. When no runtime is available the error is returned along with
+// a manager that reports why through Diagnostics.
+func NewManager(opts Options) (*Manager, error) {
+	mgr := &Manager{distro: DetectDistro()}
+	profileName, profile := profileFor(mgr.distro, opts.Distro)
+	mgr.profile = profileName
+	if opts.Distro != "" && profileName != strings.ToLower(opts.Distro) {
+		log.Warn("unknown node distribution configured, using default runtime paths",
+			"distro", opts.Distro, "known", KnownDistros())
+	}
+	log.Info("node distribution", "distro", mgr.distro.ID, "version", mgr.distro.Version, "runtimeProfile", profileName)
 
 	// Try Docker first
 	mgr.docker = NewDockerClient()
@@ -29,14 +53,17 @@ func NewManager() (*Manager, error) {
 	}
 
 	// Try ContainerD
-	mgr.containerd = NewContainerDClient()
+	if opts.ContainerdSocket != "" {
+		log.Info("containerd socket override configured", "socket", opts.ContainerdSocket)
+	}
+	mgr.containerd = NewContainerDClient(socketSearchOrder(opts.ContainerdSocket, profile), opts.ContainerdNamespaces)
 	if mgr.containerd.IsAvailable() {
 		mgr.active = mgr.containerd
 		log.Info("container runtime detected", "runtime", "ContainerD")
 		return mgr, nil
 	}
 
-	return nil, fmt.Errorf("no container runtime available (tried Docker and ContainerD)")
+	return mgr, fmt.Errorf("no container runtime available (tried Docker and ContainerD)")
 }
 
 // GenerateSBOM generates an SBOM using the active runtime
@@ -47,6 +74,14 @@ func (m *Manager) GenerateSBOM(ctx context.Context, digest string) ([]byte, erro
 	return m.active.GenerateSBOM(ctx, digest)
 }
 
+// ResolveDigest returns the digest of an image reference using the active runtime
+func (m *Manager) ResolveDigest(ctx context.Context, reference string) (string, error) {
+	if m.active == nil {
+		return "", fmt.Errorf("no active container runtime")
+	}
+	return m.active.ResolveDigest(ctx, reference)
+}
+
 // ActiveRuntime returns the name of the active runtime
 func (m *Manager) ActiveRuntime() string {
 	if m.active == nil {
@@ -55,6 +90,56 @@ func (m *Manager) ActiveRuntime() string {
 	return m.active.Name()
 }
 
+// RuntimeDiagnostics describes the active runtime and how images are located
+type RuntimeDiagnostics struct {
+	ActiveRuntime string                 `json:"active_runtime"`
+	Distro        Distro                 `json:"distro"`
+	Profile       string                 `json:"profile"`
+	Compatible    bool                   `json:"compatible"`
+	Issues        []string               `json:"issues"`
+	Containerd    *ContainerdDiagnostics `json:"containerd,omitempty"`
+}
+
+// Diagnostics reports the active runtime, the node distribution and, for
+// containerd, the visible namespaces and image store. Compatible is false when
+// the scanner cannot generate SBOMs on this node; Issues says why.
+func (m *Manager) Diagnostics(ctx context.Context) RuntimeDiagnostics {
+	diag := RuntimeDiagnostics{
+		ActiveRuntime: m.ActiveRuntime(),
+		Distro:        m.distro,
+		Profile:       m.profile,
+		Issues:        []string{},
+	}
+	if m.containerd != nil && (m.active == m.containerd || m.active == nil) {
+		containerdDiag := m.containerd.Diagnostics(ctx)
+		diag.Containerd = &containerdDiag
+	}
+	diag.Issues = compatibilityIssues(diag)
+	diag.Compatible = len(diag.Issues) == 0
+	return diag
+}
+
+// compatibilityIssues lists what prevents SBOM generation on the node
+func compatibilityIssues(diag RuntimeDiagnostics) []string {
+	issues := []string{}
+	if diag.ActiveRuntime == "none" {
+		issue := "no container runtime socket is reachable"
+		if diag.Containerd != nil && len(diag.Containerd.TriedSockets) > 0 {
+			issue += fmt.Sprintf(" (tried %s); set podScanner.config.containerdSocket or mount the runtime socket",
+				strings.Join(diag.Containerd.TriedSockets, ", "))
+		}
+		return append(issues, issue)
+	}
+	if diag.Containerd != nil && diag.Containerd.ImageStore != nil {
+		store := diag.Containerd.ImageStore
+		if store.Root != "" && !store.Accessible {
+			issues = append(issues, fmt.Sprintf("containerd image store %s is not mounted into pod-scanner; add it to podScanner.config.extraHostPaths",
+				store.Root))
+		}
+	}
+	return issues
+}
+
 // Close closes all runtime clients
 func (m *Manager) Close() error {
 	if m.docker != nil {
@@ -0,0 +1,55 @@
+package runtime
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/anchore/syft/syft"
+	"github.com/anchore/syft/syft/format"
+	"github.com/anchore/syft/syft/format/syftjson"
+	"github.com/bvboe/b2s-go/pod-scanner/throttle"
+)
+
+// GenerateRegistrySBOM generates an SBOM for an image pulled from its
+// registry, for images that are not present in any container runtime (ad-hoc
+// and registry crawl scans). Credentials come from the default docker
+// keychain; public images are pulled anonymously.
+func GenerateRegistrySBOM(ctx context.Context, imageRef string) ([]byte, error) {
+	log.Info("generating SBOM for registry image", "image", imageRef)
+
+	// Only pull from the registry, never from the local runtimes
+	cfg := syft.DefaultGetSourceConfig().WithSources("registry")
+	src, err := syft.GetSource(ctx, imageRef, cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get source for image %s: %w", imageRef, err)
+	}
+	// Count and rate limit file reads for the scan tracked in ctx, if any
+	src = throttle.WrapSource(ctx, src)
+
+	// Ensure cleanup of source (removes the pulled layers)
+	defer func() {
+		if cleanupErr := src.Close(); cleanupErr != nil {
+			log.Warn("failed to cleanup source", "error", cleanupErr)
+		}
+	}()
+
+	// Create SBOM from the source
+	s, err := syft.CreateSBOM(ctx, src, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create SBOM for %s: %w", imageRef, err)
+	}
+
+	// Encode to syft JSON format
+	encoder := syftjson.NewFormatEncoder()
+	sbomBytes, err := format.Encode(*s, encoder)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode SBOM to JSON: %w", err)
+	}
+
+	log.Info("successfully generated SBOM",
+		"image", imageRef,
+		"size", len(sbomBytes),
+		"packages", s.Artifacts.Packages.PackageCount())
+
+	return sbomBytes, nil
+}
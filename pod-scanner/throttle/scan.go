@@ -0,0 +1,161 @@
+package throttle
+
+import (
+	"context"
+	"io"
+	"sync/atomic"
+	"time"
+
+	"github.com/anchore/syft/syft/file"
+	"github.com/anchore/syft/syft/source"
+)
+
+// Usage is the resources used by one SBOM generation. CPU time is that of the
+// whole process while the scan ran, so it includes scans running concurrently.
+type Usage struct {
+	DurationMillis  int64 `json:"duration_ms"`
+	CPUMillis       int64 `json:"cpu_ms"`
+	FilesRead       int64 `json:"files_read"`
+	BytesRead       int64 `json:"bytes_read"`
+	ThrottledMillis int64 `json:"throttled_ms"` // time spent waiting for the file rate limit
+}
+
+// Scan tracks the resources of one SBOM generation and applies the file rate limit to it
+type Scan struct {
+	limiter  *Limiter
+	started  time.Time
+	cpuStart time.Duration
+
+	filesRead atomic.Int64
+	bytesRead atomic.Int64
+	throttled atomic.Int64 // nanoseconds
+}
+
+// StartScan starts tracking a scan whose file reads are limited by limiter (nil for no limit)
+func StartScan(limiter *Limiter) *Scan {
+	return &Scan{limiter: limiter, started: time.Now(), cpuStart: processCPUTime()}
+}
+
+// Finish returns the resources used since StartScan
+func (s *Scan) Finish() Usage {
+	return Usage{
+		DurationMillis:  time.Since(s.started).Milliseconds(),
+		CPUMillis:       max(processCPUTime()-s.cpuStart, 0).Milliseconds(),
+		FilesRead:       s.filesRead.Load(),
+		BytesRead:       s.bytesRead.Load(),
+		ThrottledMillis: time.Duration(s.throttled.Load()).Milliseconds(),
+	}
+}
+
+type scanKey struct{}
+
+// WithScan returns a context carrying s, picked up by WrapSource
+func WithScan(ctx context.Context, s *Scan) context.Context {
+	return context.WithValue(ctx, scanKey{}, s)
+}
+
+// scanFrom returns the Scan carried by ctx, if any
+func scanFrom(ctx context.Context) *Scan {
+	s, _ := ctx.Value(scanKey{}).(*Scan)
+	return s
+}
+
+// WrapSource returns src with file reads counted and rate limited by the Scan
+// carried by ctx. Without a Scan in ctx, src is returned unchanged.
+func WrapSource(ctx context.Context, src source.Source) source.Source {
+	s := scanFrom(ctx)
+	if s == nil {
+		return src
+	}
+	return &scanSource{Source: src, ctx: ctx, scan: s}
+}
+
+// scanSource hands out resolvers that report to a Scan
+type scanSource struct {
+	source.Source
+	ctx  context.Context
+	scan *Scan
+}
+
+func (s *scanSource) FileResolver(scope source.Scope) (file.Resolver, error) {
+	resolver, err := s.Source.FileResolver(scope)
+	if err != nil {
+		return nil, err
+	}
+	return &scanResolver{Resolver: resolver, ctx: s.ctx, scan: s.scan}, nil
+}
+
+// scanResolver waits for the rate limit before each file read and counts what is read
+type scanResolver struct {
+	file.Resolver
+	ctx  context.Context
+	scan *Scan
+}
+
+func (r *scanResolver) FileContentsByLocation(location file.Location) (io.ReadCloser, error) {
+	waited, err := r.scan.limiter.Wait(r.ctx)
+	if err != nil {
+		return nil, err
+	}
+	r.scan.throttled.Add(int64(waited))
+
+	rc, err := r.Resolver.FileContentsByLocation(location)
+	if err != nil {
+		return nil, err
+	}
+	r.scan.filesRead.Add(1)
+	return countBytes(rc, &r.scan.bytesRead), nil
+}
+
+// FilesByMediaType keeps OCI artifact lookups working for resolvers that support them
+func (r *scanResolver) FilesByMediaType(types ...string) ([]file.Location, error) {
+	if oci, ok := r.Resolver.(file.OCIMediaTypeResolver); ok {
+		return oci.FilesByMediaType(types...)
+	}
+	return nil, nil
+}
+
+// countBytes wraps rc so bytes read are added to n. Seeking and random
+// access are passed through when rc supports them: binary catalogers buffer
+// whole files in memory for readers that don't.
+func countBytes(rc io.ReadCloser, n *atomic.Int64) io.ReadCloser {
+	c := &countingReader{ReadCloser: rc, n: n}
+	seeker, canSeek := rc.(io.Seeker)
+	readerAt, canReadAt := rc.(io.ReaderAt)
+	switch {
+	case canSeek && canReadAt:
+		return &countingReaderAtSeeker{countingReader: c, Seeker: seeker, readerAt: readerAt}
+	case canSeek:
+		return &countingSeeker{countingReader: c, Seeker: seeker}
+	default:
+		return c
+	}
+}
+
+type countingReader struct {
+	io.ReadCloser
+	n *atomic.Int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.ReadCloser.Read(p)
+	c.n.Add(int64(n))
+	return n, err
+}
+
+type countingSeeker struct {
+	*countingReader
+	io.Seeker
+}
+
+type countingReaderAtSeeker struct {
+	*countingReader
+	io.Seeker
+	readerAt io.ReaderAt
+}
+
+func (c *countingReaderAtSeeker) ReadAt(p []byte, off int64) (int, error) {
+	n, err := c.readerAt.ReadAt(p, off)
+	c.n.Add(int64(n))
+	return n, err
+}
@@ -0,0 +1,112 @@
+// Package throttle keeps SBOM generation from starving co-located workloads.
+// It lowers the CPU and I/O scheduling priority of the pod-scanner process
+// (nice/ionice), limits how many files a scan may read per second, and
+// measures the resources each scan used so they can be reported to the
+// scan server.
+package throttle
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+)
+
+var log = slog.Default().With("component", "pod-scanner")
+
+// I/O scheduling classes (see ionice(1))
+const (
+	IOClassNone       = ""
+	IOClassBestEffort = "best-effort"
+	IOClassIdle       = "idle"
+)
+
+// Priority is the CPU and I/O scheduling priority applied to the process
+type Priority struct {
+	// Nice is the CPU nice value, from -20 (highest) to 19 (lowest); 0 leaves it unchanged
+	Nice int
+	// IOClass is the I/O scheduling class: "best-effort", "idle" or empty to leave it unchanged
+	IOClass string
+	// IOLevel is the priority within the best-effort class, from 0 (highest) to 7 (lowest)
+	IOLevel int
+}
+
+// Validate checks that the priority values are in range
+func (p Priority) Validate() error {
+	if p.Nice < -20 || p.Nice > 19 {
+		return fmt.Errorf("nice must be between -20 and 19, got %d", p.Nice)
+	}
+	switch strings.ToLower(p.IOClass) {
+	case IOClassNone, IOClassBestEffort, IOClassIdle:
+	default:
+		return fmt.Errorf("I/O class must be %q or %q, got %q", IOClassBestEffort, IOClassIdle, p.IOClass)
+	}
+	if p.IOLevel < 0 || p.IOLevel > 7 {
+		return fmt.Errorf("I/O level must be between 0 and 7, got %d", p.IOLevel)
+	}
+	return nil
+}
+
+// Apply sets the priority on every thread of the process. Threads started
+// later inherit it from the thread that creates them, so Apply should run
+// early, before scans start.
+func Apply(p Priority) error {
+	if p.Nice == 0 && p.IOClass == IOClassNone {
+		return nil
+	}
+	if err := p.Validate(); err != nil {
+		return err
+	}
+	if err := applyPriority(p); err != nil {
+		return err
+	}
+	log.Info("scan priority configured", "nice", p.Nice, "ioClass", p.IOClass, "ioLevel", p.IOLevel)
+	return nil
+}
+
+// Limiter spaces out file reads to at most a fixed number per second. One
+// Limiter is shared by all scans so concurrent scans don't multiply the rate.
+// A nil Limiter never waits.
+type Limiter struct {
+	interval time.Duration
+
+	mu   sync.Mutex
+	next time.Time
+}
+
+// NewLimiter returns a Limiter allowing perSecond reads per second, or nil when perSecond is 0 or less
+func NewLimiter(perSecond int) *Limiter {
+	if perSecond <= 0 {
+		return nil
+	}
+	return &Limiter{interval: time.Second / time.Duration(perSecond)}
+}
+
+// Wait blocks until the next read is allowed and returns how long it waited
+func (l *Limiter) Wait(ctx context.Context) (time.Duration, error) {
+	if l == nil {
+		return 0, nil
+	}
+	l.mu.Lock()
+	now := time.Now()
+	if l.next.Before(now) {
+		l.next = now
+	}
+	wait := l.next.Sub(now)
+	l.next = l.next.Add(l.interval)
+	l.mu.Unlock()
+
+	if wait <= 0 {
+		return 0, nil
+	}
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return wait, nil
+	case <-ctx.Done():
+		return 0, ctx.Err()
+	}
+}
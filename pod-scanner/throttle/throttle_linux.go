@@ -0,0 +1,63 @@
+//go:build linux
+
+package throttle
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// ioprio_set(2) constants
+const (
+	ioprioWhoProcess = 1
+	ioprioClassShift = 13
+	ioprioClassBE    = 2
+	ioprioClassIdle  = 3
+)
+
+// applyPriority sets nice and ioprio on each thread; on Linux both are per-thread attributes
+func applyPriority(p Priority) error {
+	entries, err := os.ReadDir("/proc/self/task")
+	if err != nil {
+		return fmt.Errorf("failed to list threads: %w", err)
+	}
+
+	var ioprio int
+	switch strings.ToLower(p.IOClass) {
+	case IOClassBestEffort:
+		ioprio = ioprioClassBE<<ioprioClassShift | p.IOLevel
+	case IOClassIdle:
+		ioprio = ioprioClassIdle << ioprioClassShift
+	}
+
+	for _, entry := range entries {
+		tid, err := strconv.Atoi(entry.Name())
+		if err != nil {
+			continue
+		}
+		if p.Nice != 0 {
+			if err := syscall.Setpriority(syscall.PRIO_PROCESS, tid, p.Nice); err != nil {
+				return fmt.Errorf("failed to set nice %d on thread %d: %w", p.Nice, tid, err)
+			}
+		}
+		if ioprio != 0 {
+			if _, _, errno := syscall.Syscall(syscall.SYS_IOPRIO_SET, ioprioWhoProcess, uintptr(tid), uintptr(ioprio)); errno != 0 {
+				return fmt.Errorf("failed to set I/O priority on thread %d: %w", tid, errno)
+			}
+		}
+	}
+	return nil
+}
+
+// processCPUTime returns the user and system CPU time consumed by the process so far
+func processCPUTime() time.Duration {
+	var ru syscall.Rusage
+	if err := syscall.Getrusage(syscall.RUSAGE_SELF, &ru); err != nil {
+		return 0
+	}
+	return time.Duration(ru.Utime.Nano() + ru.Stime.Nano())
+}
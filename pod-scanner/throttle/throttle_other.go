@@ -0,0 +1,16 @@
+//go:build !linux
+
+package throttle
+
+import (
+	"fmt"
+	"time"
+)
+
+func applyPriority(Priority) error {
+	return fmt.Errorf("scan priority is only supported on Linux")
+}
+
+func processCPUTime() time.Duration {
+	return 0
+}
@@ -0,0 +1,156 @@
+package throttle
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/anchore/syft/syft"
+	"github.com/anchore/syft/syft/file"
+	"github.com/anchore/syft/syft/source"
+)
+
+func TestPriorityValidate(t *testing.T) {
+	valid := []Priority{{}, {Nice: 19}, {Nice: -20, IOClass: IOClassIdle}, {IOClass: IOClassBestEffort, IOLevel: 7}}
+	for _, p := range valid {
+		if err := p.Validate(); err != nil {
+			t.Errorf("Validate(%+v) = %v", p, err)
+		}
+	}
+	invalid := []Priority{{Nice: 20}, {Nice: -21}, {IOClass: "realtime"}, {IOClass: IOClassBestEffort, IOLevel: 8}}
+	for _, p := range invalid {
+		if err := p.Validate(); err == nil {
+			t.Errorf("Validate(%+v) should fail", p)
+		}
+	}
+}
+
+func TestLimiter(t *testing.T) {
+	if NewLimiter(0) != nil {
+		t.Fatal("NewLimiter(0) should disable limiting")
+	}
+	var disabled *Limiter
+	if waited, err := disabled.Wait(context.Background()); waited != 0 || err != nil {
+		t.Errorf("nil limiter Wait = %s, %v", waited, err)
+	}
+
+	// 100/s spaces reads 10ms apart: the first is immediate, the fifth waits ~40ms
+	l := NewLimiter(100)
+	start := time.Now()
+	var total time.Duration
+	for i := 0; i < 5; i++ {
+		waited, err := l.Wait(context.Background())
+		if err != nil {
+			t.Fatalf("Wait() error = %v", err)
+		}
+		total += waited
+	}
+	if elapsed := time.Since(start); elapsed < 35*time.Millisecond {
+		t.Errorf("5 reads at 100/s took %s, want at least 40ms", elapsed)
+	}
+	if total < 35*time.Millisecond {
+		t.Errorf("reported wait %s, want at least 40ms", total)
+	}
+
+	// A cancelled context stops waiting
+	slow := NewLimiter(1)
+	if _, err := slow.Wait(context.Background()); err != nil {
+		t.Fatalf("first Wait() error = %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := slow.Wait(ctx); err == nil {
+		t.Error("Wait() with cancelled context should fail")
+	}
+}
+
+func TestCountBytesKeepsRandomAccess(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "binary")
+	if err := os.WriteFile(path, []byte("0123456789"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var n atomic.Int64
+	rc := countBytes(f, &n)
+	defer func() { _ = rc.Close() }()
+
+	ra, ok := rc.(io.ReaderAt)
+	if !ok {
+		t.Fatal("wrapped file should implement io.ReaderAt")
+	}
+	if _, ok := rc.(io.Seeker); !ok {
+		t.Fatal("wrapped file should implement io.Seeker")
+	}
+	buf := make([]byte, 4)
+	if _, err := ra.ReadAt(buf, 6); err != nil || string(buf) != "6789" {
+		t.Fatalf("ReadAt = %q, %v", buf, err)
+	}
+	if _, err := io.ReadAll(rc); err != nil {
+		t.Fatal(err)
+	}
+	if n.Load() != 14 {
+		t.Errorf("counted %d bytes, want 14", n.Load())
+	}
+
+	// Plain readers stay plain
+	if _, ok := countBytes(io.NopCloser(nil), &n).(io.Seeker); ok {
+		t.Error("non-seekable reader should not gain io.Seeker")
+	}
+}
+
+func TestWrapSource(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"a.txt", "b.txt"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("hello"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	src, err := syft.GetSource(context.Background(), dir, nil)
+	if err != nil {
+		t.Fatalf("GetSource() error = %v", err)
+	}
+	defer func() { _ = src.Close() }()
+
+	if WrapSource(context.Background(), src) != src {
+		t.Error("WrapSource without a scan should return the source unchanged")
+	}
+
+	scan := StartScan(NewLimiter(1000))
+	wrapped := WrapSource(WithScan(context.Background(), scan), src)
+	resolver, err := wrapped.FileResolver(source.SquashedScope)
+	if err != nil {
+		t.Fatalf("FileResolver() error = %v", err)
+	}
+	locations, err := resolver.FilesByGlob("**/*.txt")
+	if err != nil || len(locations) != 2 {
+		t.Fatalf("FilesByGlob = %v, %v", locations, err)
+	}
+	for _, location := range locations {
+		readAll(t, resolver, location)
+	}
+
+	usage := scan.Finish()
+	if usage.FilesRead != 2 || usage.BytesRead != 10 {
+		t.Errorf("usage = %+v, want 2 files and 10 bytes", usage)
+	}
+}
+
+func readAll(t *testing.T, resolver file.Resolver, location file.Location) {
+	t.Helper()
+	rc, err := resolver.FileContentsByLocation(location)
+	if err != nil {
+		t.Fatalf("FileContentsByLocation(%s) error = %v", location.RealPath, err)
+	}
+	defer func() { _ = rc.Close() }()
+	if _, err := io.ReadAll(rc); err != nil {
+		t.Fatal(err)
+	}
+}
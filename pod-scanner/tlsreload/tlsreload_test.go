@@ -0,0 +1,216 @@
+package tlsreload
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// testCA issues certificates for tests
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pem  []byte
+}
+
+func newTestCA(t *testing.T) *testCA {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &testCA{cert: cert, key: key, pem: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})}
+}
+
+// issue writes a certificate for dnsName and its key to dir
+func (ca *testCA) issue(t *testing.T, dir, dnsName string, serial int64) (certFile, keyFile string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: dnsName},
+		DNSNames:     []string{dnsName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.cert, &key.PublicKey, ca.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certFile = filepath.Join(dir, "tls.crt")
+	keyFile = filepath.Join(dir, "tls.key")
+	writeFile(t, certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}))
+	writeFile(t, keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}))
+	return certFile, keyFile
+}
+
+func writeFile(t *testing.T, file string, data []byte) {
+	t.Helper()
+	if err := os.WriteFile(file, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// get requests url with client and returns the status code, or 0 if the
+// request failed
+func get(client *http.Client, url string) int {
+	resp, err := client.Get(url)
+	if err != nil {
+		return 0
+	}
+	_, _ = io.Copy(io.Discard, resp.Body)
+	_ = resp.Body.Close()
+	return resp.StatusCode
+}
+
+func TestMutualTLS(t *testing.T) {
+	ca := newTestCA(t)
+	serverDir, clientDir := t.TempDir(), t.TempDir()
+	caFile := filepath.Join(serverDir, "ca.crt")
+	writeFile(t, caFile, ca.pem)
+	serverCert, serverKey := ca.issue(t, serverDir, "scanner.example", 2)
+	clientCert, clientKey := ca.issue(t, clientDir, "client.example", 3)
+
+	server, err := New(Files{CertFile: serverCert, KeyFile: serverKey, CAFile: caFile})
+	if err != nil {
+		t.Fatalf("New(server) error = %v", err)
+	}
+	ts := httptest.NewUnstartedServer(RequireClientCert(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}), "/health"))
+	ts.TLS = server.ServerConfig()
+	ts.StartTLS()
+	defer ts.Close()
+
+	client, err := New(Files{CertFile: clientCert, KeyFile: clientKey, CAFile: caFile})
+	if err != nil {
+		t.Fatalf("New(client) error = %v", err)
+	}
+	mtls := &http.Client{Transport: &http.Transport{TLSClientConfig: client.ClientConfig("scanner.example")}}
+	if got := get(mtls, ts.URL+"/sboms"); got != http.StatusOK {
+		t.Errorf("request with a client certificate: status = %d, want 200", got)
+	}
+
+	verifyOnly, err := New(Files{CAFile: caFile})
+	if err != nil {
+		t.Fatalf("New(CA only) error = %v", err)
+	}
+	anonymous := &http.Client{Transport: &http.Transport{TLSClientConfig: verifyOnly.ClientConfig("scanner.example")}}
+	if got := get(anonymous, ts.URL+"/sboms"); got != http.StatusUnauthorized {
+		t.Errorf("request without a client certificate: status = %d, want 401", got)
+	}
+	if got := get(anonymous, ts.URL+"/health"); got != http.StatusOK {
+		t.Errorf("health probe without a client certificate: status = %d, want 200", got)
+	}
+
+	wrongName := &http.Client{Transport: &http.Transport{TLSClientConfig: client.ClientConfig("other.example")}}
+	if got := get(wrongName, ts.URL+"/sboms"); got != 0 {
+		t.Errorf("request to a server with another name succeeded with status %d", got)
+	}
+
+	// A client certificate from another CA is rejected during the handshake
+	otherDir := t.TempDir()
+	otherCert, otherKey := newTestCA(t).issue(t, otherDir, "client.example", 4)
+	other, err := New(Files{CertFile: otherCert, KeyFile: otherKey, CAFile: caFile})
+	if err != nil {
+		t.Fatalf("New(other client) error = %v", err)
+	}
+	untrusted := &http.Client{Transport: &http.Transport{TLSClientConfig: other.ClientConfig("scanner.example")}}
+	if got := get(untrusted, ts.URL+"/sboms"); got != 0 {
+		t.Errorf("request with an untrusted client certificate succeeded with status %d", got)
+	}
+}
+
+func TestReload(t *testing.T) {
+	ca := newTestCA(t)
+	dir := t.TempDir()
+	certFile, keyFile := ca.issue(t, dir, "scanner.example", 2)
+
+	r, err := New(Files{CertFile: certFile, KeyFile: keyFile})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	serial := func() int64 {
+		cert, _ := r.current()
+		leaf, err := x509.ParseCertificate(cert.Certificate[0])
+		if err != nil {
+			t.Fatal(err)
+		}
+		return leaf.SerialNumber.Int64()
+	}
+
+	// Renewal writes new files; they are picked up at the next check
+	ca.issue(t, dir, "scanner.example", 5)
+	future := time.Now().Add(time.Minute)
+	for _, file := range []string{certFile, keyFile} {
+		if err := os.Chtimes(file, future, future); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := serial(); got != 2 {
+		t.Errorf("serial before the check interval = %d, want 2", got)
+	}
+	r.lastCheck = time.Time{}
+	if got := serial(); got != 5 {
+		t.Errorf("serial after renewal = %d, want 5", got)
+	}
+
+	// A half-written renewal keeps the previous certificate
+	writeFile(t, keyFile, []byte("garbage"))
+	later := future.Add(time.Minute)
+	if err := os.Chtimes(keyFile, later, later); err != nil {
+		t.Fatal(err)
+	}
+	r.lastCheck = time.Time{}
+	if got := serial(); got != 5 {
+		t.Errorf("serial after a failed reload = %d, want 5", got)
+	}
+}
+
+func TestNewValidation(t *testing.T) {
+	dir := t.TempDir()
+	for name, files := range map[string]Files{
+		"nothing":          {},
+		"cert without key": {CertFile: filepath.Join(dir, "tls.crt")},
+		"missing files":    {CertFile: filepath.Join(dir, "tls.crt"), KeyFile: filepath.Join(dir, "tls.key")},
+		"missing CA":       {CAFile: filepath.Join(dir, "ca.crt")},
+	} {
+		if _, err := New(files); err == nil {
+			t.Errorf("New(%s) succeeded, want an error", name)
+		}
+	}
+}
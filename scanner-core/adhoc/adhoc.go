@@ -0,0 +1,141 @@
+// Package adhoc scans images on demand, before they run in the cluster.
+//
+// A request names an image by reference (nginx:1.27, registry.example.com/app@sha256:...).
+// The digest is resolved against the registry, the image is recorded as
+// "ad-hoc" and a scan job is queued that pulls the image from its registry
+// instead of reading it from a node. Results are stored like those of running
+// images, keyed by digest, and kept for a retention period so they survive
+// the orphaned image cleanup.
+package adhoc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/bvboe/b2s-go/scanner-core/containers"
+	"github.com/bvboe/b2s-go/scanner-core/logging"
+	"github.com/bvboe/b2s-go/scanner-core/scanning"
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+)
+
+var log = logging.For(logging.ComponentAdHoc)
+
+// DefaultRetention is how long ad-hoc results are kept when no retention is configured
+const DefaultRetention = 7 * 24 * time.Hour
+
+// resolveTimeout bounds the registry lookup done while handling a request
+const resolveTimeout = 30 * time.Second
+
+var (
+	// ErrInvalidReference is returned for references that cannot be parsed
+	ErrInvalidReference = errors.New("invalid image reference")
+	// ErrUnresolved is returned when the registry cannot resolve a reference to a digest
+	ErrUnresolved = errors.New("failed to resolve image digest")
+)
+
+// Store records ad-hoc scan requests (implemented by database.DB)
+type Store interface {
+	CreateAdHocScan(image containers.ImageID, retention time.Duration) (bool, error)
+}
+
+// Queue accepts scan jobs (implemented by scanning.JobQueue)
+type Queue interface {
+	Enqueue(job scanning.ScanJob)
+}
+
+// Resolver returns the digest a tag currently points to
+type Resolver func(ctx context.Context, ref name.Reference) (string, error)
+
+// Scanner submits ad-hoc scans
+type Scanner struct {
+	store     Store
+	queue     Queue
+	retention time.Duration
+	resolve   Resolver
+}
+
+// NewScanner creates a Scanner that keeps results for retention
+// (DefaultRetention if zero) and resolves tags against their registries
+func NewScanner(store Store, queue Queue, retention time.Duration) *Scanner {
+	if store == nil {
+		panic("adhoc.Scanner requires a non-nil store")
+	}
+	if queue == nil {
+		panic("adhoc.Scanner requires a non-nil queue")
+	}
+	if retention <= 0 {
+		retention = DefaultRetention
+	}
+	return &Scanner{store: store, queue: queue, retention: retention, resolve: remoteDigest}
+}
+
+// Submit resolves reference to a digest and queues a scan of the image.
+// Images already scanned are not scanned again; their stored results are
+// returned by the status endpoint right away.
+func (s *Scanner) Submit(ctx context.Context, reference string) (containers.ImageID, error) {
+	image, err := s.Resolve(ctx, reference)
+	if err != nil {
+		return containers.ImageID{}, err
+	}
+	if err := s.SubmitImage(image); err != nil {
+		return containers.ImageID{}, err
+	}
+	return image, nil
+}
+
+// Resolve parses reference and resolves it to the digest it currently points
+// to. References pinned to a digest are returned without a registry lookup.
+func (s *Scanner) Resolve(ctx context.Context, reference string) (containers.ImageID, error) {
+	reference = strings.TrimSpace(reference)
+	ref, err := name.ParseReference(reference)
+	if err != nil {
+		return containers.ImageID{}, fmt.Errorf("%w: %v", ErrInvalidReference, err)
+	}
+
+	digest := ref.Identifier()
+	if _, pinned := ref.(name.Digest); !pinned {
+		ctx, cancel := context.WithTimeout(ctx, resolveTimeout)
+		defer cancel()
+		digest, err = s.resolve(ctx, ref)
+		if err != nil {
+			return containers.ImageID{}, fmt.Errorf("%w: %s: %v", ErrUnresolved, reference, err)
+		}
+	}
+	return containers.ImageID{Reference: reference, Digest: digest}, nil
+}
+
+// SubmitImage records an already resolved image as ad-hoc and queues its scan
+func (s *Scanner) SubmitImage(image containers.ImageID) error {
+	created, err := s.store.CreateAdHocScan(image, s.retention)
+	if err != nil {
+		return err
+	}
+	s.queue.Enqueue(scanning.ScanJob{Image: image, AdHoc: true})
+	log.Info("ad-hoc scan requested", "image", image.Reference, "digest", image.Digest, "new_image", created)
+	return nil
+}
+
+// PinnedReference returns the reference to pull an ad-hoc image by, pinned
+// to its digest so a tag moved since the request is not scanned instead
+func PinnedReference(image containers.ImageID) string {
+	ref, err := name.ParseReference(image.Reference)
+	if err != nil {
+		return image.Reference
+	}
+	return ref.Context().Digest(image.Digest).String()
+}
+
+// remoteDigest queries the registry, using credentials from the default
+// keychain (docker config) and anonymous access otherwise
+func remoteDigest(ctx context.Context, ref name.Reference) (string, error) {
+	desc, err := remote.Head(ref, remote.WithContext(ctx), remote.WithAuthFromKeychain(authn.DefaultKeychain))
+	if err != nil {
+		return "", err
+	}
+	return desc.Digest.String(), nil
+}
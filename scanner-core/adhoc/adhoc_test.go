@@ -0,0 +1,101 @@
+package adhoc
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/bvboe/b2s-go/scanner-core/containers"
+	"github.com/bvboe/b2s-go/scanner-core/scanning"
+	"github.com/google/go-containerregistry/pkg/name"
+)
+
+const testDigest = "sha256:1111111111111111111111111111111111111111111111111111111111111111"
+
+type fakeStore struct {
+	images    []containers.ImageID
+	retention time.Duration
+}
+
+func (s *fakeStore) CreateAdHocScan(image containers.ImageID, retention time.Duration) (bool, error) {
+	s.images = append(s.images, image)
+	s.retention = retention
+	return true, nil
+}
+
+type fakeQueue struct{ jobs []scanning.ScanJob }
+
+func (q *fakeQueue) Enqueue(job scanning.ScanJob) { q.jobs = append(q.jobs, job) }
+
+func TestSubmit(t *testing.T) {
+	store, queue := &fakeStore{}, &fakeQueue{}
+	s := NewScanner(store, queue, 0)
+	var resolved []string
+	s.resolve = func(ctx context.Context, ref name.Reference) (string, error) {
+		resolved = append(resolved, ref.Name())
+		return testDigest, nil
+	}
+
+	image, err := s.Submit(context.Background(), " registry.example.com/team/app:v2 ")
+	if err != nil {
+		t.Fatalf("Submit() error = %v", err)
+	}
+	want := containers.ImageID{Reference: "registry.example.com/team/app:v2", Digest: testDigest}
+	if image != want {
+		t.Errorf("Submit() = %+v, want %+v", image, want)
+	}
+	if len(resolved) != 1 || resolved[0] != "registry.example.com/team/app:v2" {
+		t.Errorf("resolved %v", resolved)
+	}
+	if store.retention != DefaultRetention {
+		t.Errorf("retention = %v, want %v", store.retention, DefaultRetention)
+	}
+	if len(queue.jobs) != 1 || !queue.jobs[0].AdHoc || queue.jobs[0].Image != want {
+		t.Errorf("queued jobs = %+v", queue.jobs)
+	}
+
+	// References pinned to a digest are not resolved
+	pinned := "registry.example.com/team/app@" + testDigest
+	if image, err := s.Submit(context.Background(), pinned); err != nil || image.Digest != testDigest {
+		t.Errorf("Submit(%q) = %+v, %v", pinned, image, err)
+	}
+	if len(resolved) != 1 {
+		t.Errorf("expected pinned reference not to be resolved, resolved %v", resolved)
+	}
+}
+
+func TestSubmitErrors(t *testing.T) {
+	store, queue := &fakeStore{}, &fakeQueue{}
+	s := NewScanner(store, queue, time.Hour)
+	s.resolve = func(ctx context.Context, ref name.Reference) (string, error) {
+		return "", errors.New("MANIFEST_UNKNOWN")
+	}
+
+	if _, err := s.Submit(context.Background(), "Not A Reference"); !errors.Is(err, ErrInvalidReference) {
+		t.Errorf("expected ErrInvalidReference, got %v", err)
+	}
+	if _, err := s.Submit(context.Background(), "nginx:does-not-exist"); !errors.Is(err, ErrUnresolved) {
+		t.Errorf("expected ErrUnresolved, got %v", err)
+	}
+	if len(store.images) != 0 || len(queue.jobs) != 0 {
+		t.Errorf("expected nothing recorded or queued, got %v and %v", store.images, queue.jobs)
+	}
+}
+
+func TestPinnedReference(t *testing.T) {
+	tests := []struct {
+		reference string
+		want      string
+	}{
+		{"nginx:1.27", "index.docker.io/library/nginx@" + testDigest},
+		{"registry.example.com/team/app:v2", "registry.example.com/team/app@" + testDigest},
+		{"registry.example.com/team/app@" + testDigest, "registry.example.com/team/app@" + testDigest},
+	}
+	for _, tt := range tests {
+		got := PinnedReference(containers.ImageID{Reference: tt.reference, Digest: testDigest})
+		if got != tt.want {
+			t.Errorf("PinnedReference(%q) = %q, want %q", tt.reference, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,121 @@
+// Package alerts turns individual scan failures into actionable alerts. A
+// broken container runtime socket or expired registry credentials make every
+// image on a node fail the same way; grouping failures by node and root cause
+// reports that as one alert instead of hundreds of per-image failures.
+package alerts
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/bvboe/b2s-go/scanner-core/database"
+)
+
+// Reason is the root-cause code of a scan failure
+type Reason string
+
+const (
+	ReasonRegistryAuth           Reason = "registry_auth"            // registry rejected the credentials
+	ReasonImageNotFound          Reason = "image_not_found"          // image missing from the node runtime or registry
+	ReasonRuntimeUnavailable     Reason = "runtime_unavailable"      // containerd/Docker socket not reachable on the node
+	ReasonNodeScannerUnavailable Reason = "node_scanner_unavailable" // no ready pod-scanner on the node
+	ReasonTimeout                Reason = "timeout"                  // scan or request timed out
+	ReasonDiskFull               Reason = "disk_full"                // no space left for images or SBOMs
+	ReasonSBOMGeneration         Reason = "sbom_generation"          // other SBOM generation failures
+	ReasonVulnerabilityScan      Reason = "vulnerability_scan"       // other vulnerability scan failures
+	ReasonUnknown                Reason = "unknown"
+)
+
+// reasonPatterns maps lowercase error fragments to reason codes. The first
+// matching entry wins, so more specific causes come first (an image missing
+// from containerd is not a runtime outage).
+var reasonPatterns = []struct {
+	reason   Reason
+	patterns []string
+}{
+	{ReasonRegistryAuth, []string{"unauthorized", "authentication required", "denied", "forbidden"}},
+	{ReasonImageNotFound, []string{"not found in containerd", "not found in docker", "manifest unknown", "name unknown", "image not found"}},
+	{ReasonRuntimeUnavailable, []string{"containerd.sock", "docker.sock", "socket file not found", "container runtime", "containerd client not initialized"}},
+	{ReasonNodeScannerUnavailable, []string{"no running pod-scanner", "no pod-scanner scheduled", "pod-scanner did not become ready", "waiting for pod-scanner", "connection refused", "no route to host"}},
+	{ReasonTimeout, []string{"timeout", "timed out", "deadline exceeded"}},
+	{ReasonDiskFull, []string{"no space left on device", "disk full"}},
+}
+
+// ClassifyFailure returns the root-cause code of a failed scan from its
+// status and error message. Unrecognized errors are classified by the scan
+// step that failed.
+func ClassifyFailure(status database.Status, errorMsg string) Reason {
+	msg := strings.ToLower(errorMsg)
+	for _, rp := range reasonPatterns {
+		for _, p := range rp.patterns {
+			if strings.Contains(msg, p) {
+				return rp.reason
+			}
+		}
+	}
+	switch status {
+	case database.StatusSBOMFailed:
+		return ReasonSBOMGeneration
+	case database.StatusVulnScanFailed:
+		return ReasonVulnerabilityScan
+	default:
+		return ReasonUnknown
+	}
+}
+
+// maxExampleDigests bounds the digests listed per failure group
+const maxExampleDigests = 5
+
+// FailureGroup is the set of failing images sharing a node and root cause.
+// Firing is set once Images reaches the alert threshold.
+type FailureGroup struct {
+	Node         string   `json:"node"`
+	Reason       Reason   `json:"reason"`
+	Images       int      `json:"images"`
+	DeadLettered int      `json:"dead_lettered"`
+	Firing       bool     `json:"firing"`
+	Error        string   `json:"error"`   // latest error of the first image, as an example
+	Digests      []string `json:"digests"` // up to maxExampleDigests examples
+}
+
+// GroupFailures groups failing images by the node and root cause of their
+// latest failure, largest groups first. Groups of at least threshold images
+// are firing; threshold <= 0 disables alerting.
+func GroupFailures(failures []database.FailingScan, threshold int) []FailureGroup {
+	type key struct {
+		node   string
+		reason Reason
+	}
+	groups := make(map[key]*FailureGroup)
+	for _, f := range failures {
+		k := key{f.Latest.Node, ClassifyFailure(f.Latest.Status, f.Latest.Error)}
+		g, ok := groups[k]
+		if !ok {
+			g = &FailureGroup{Node: k.node, Reason: k.reason, Error: f.Latest.Error, Digests: []string{}}
+			groups[k] = g
+		}
+		g.Images++
+		if f.DeadLettered {
+			g.DeadLettered++
+		}
+		if len(g.Digests) < maxExampleDigests {
+			g.Digests = append(g.Digests, f.Digest)
+		}
+	}
+
+	result := make([]FailureGroup, 0, len(groups))
+	for _, g := range groups {
+		g.Firing = threshold > 0 && g.Images >= threshold
+		result = append(result, *g)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Images != result[j].Images {
+			return result[i].Images > result[j].Images
+		}
+		if result[i].Node != result[j].Node {
+			return result[i].Node < result[j].Node
+		}
+		return result[i].Reason < result[j].Reason
+	})
+	return result
+}
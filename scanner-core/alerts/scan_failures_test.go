@@ -0,0 +1,73 @@
+package alerts
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/bvboe/b2s-go/scanner-core/database"
+)
+
+func TestClassifyFailure(t *testing.T) {
+	tests := []struct {
+		status database.Status
+		err    string
+		want   Reason
+	}{
+		{database.StatusSBOMFailed, "pod-scanner returned status 500: failed to connect: dial unix /run/containerd/containerd.sock: connect: no such file or directory", ReasonRuntimeUnavailable},
+		{database.StatusSBOMFailed, "pod-scanner returned status 500: image with digest sha256:abc not found in ContainerD (namespaces searched: k8s.io)", ReasonImageNotFound},
+		{database.StatusSBOMFailed, "failed to pull image: UNAUTHORIZED: authentication required", ReasonRegistryAuth},
+		{database.StatusSBOMFailed, "no pod-scanner scheduled on node node-1 (DaemonSet not configured to run on this node)", ReasonNodeScannerUnavailable},
+		{database.StatusSBOMFailed, "failed to request SBOM from pod-scanner: dial tcp 10.0.0.5:8080: connect: connection refused", ReasonNodeScannerUnavailable},
+		{database.StatusSBOMFailed, "context deadline exceeded", ReasonTimeout},
+		{database.StatusVulnScanFailed, "write /tmp/sbom.json: no space left on device", ReasonDiskFull},
+		{database.StatusSBOMFailed, "unexpected EOF", ReasonSBOMGeneration},
+		{database.StatusVulnScanFailed, "grype failed", ReasonVulnerabilityScan},
+		{database.StatusCompleted, "", ReasonUnknown},
+	}
+	for _, tt := range tests {
+		if got := ClassifyFailure(tt.status, tt.err); got != tt.want {
+			t.Errorf("ClassifyFailure(%q, %q) = %q, want %q", tt.status, tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestGroupFailures(t *testing.T) {
+	var failures []database.FailingScan
+	// A broken containerd socket on node-1 fails every image there
+	for i := range 12 {
+		failures = append(failures, database.FailingScan{
+			Digest:       fmt.Sprintf("sha256:%02d", i),
+			DeadLettered: i < 3,
+			Latest: database.ScanFailure{
+				Status: database.StatusSBOMFailed,
+				Error:  "dial unix /run/containerd/containerd.sock: connect: connection refused",
+				Node:   "node-1",
+			},
+		})
+	}
+	failures = append(failures,
+		database.FailingScan{Digest: "sha256:a", Latest: database.ScanFailure{Status: database.StatusSBOMFailed, Error: "unauthorized", Node: "node-2"}},
+		database.FailingScan{Digest: "sha256:b", Latest: database.ScanFailure{Status: database.StatusSBOMFailed, Error: "timeout", Node: "node-2"}},
+	)
+
+	groups := GroupFailures(failures, 10)
+	if len(groups) != 3 {
+		t.Fatalf("expected 3 groups, got %+v", groups)
+	}
+	g := groups[0]
+	if g.Node != "node-1" || g.Reason != ReasonRuntimeUnavailable || g.Images != 12 || g.DeadLettered != 3 || !g.Firing {
+		t.Errorf("unexpected runtime group: %+v", g)
+	}
+	if len(g.Digests) != maxExampleDigests {
+		t.Errorf("expected %d example digests, got %v", maxExampleDigests, g.Digests)
+	}
+	if groups[1].Reason != ReasonRegistryAuth || groups[2].Reason != ReasonTimeout || groups[1].Firing || groups[2].Firing {
+		t.Errorf("unexpected node-2 groups: %+v", groups[1:])
+	}
+
+	for _, g := range GroupFailures(failures, 0) {
+		if g.Firing {
+			t.Errorf("threshold 0 must disable alerting, got firing group %+v", g)
+		}
+	}
+}
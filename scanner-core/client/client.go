@@ -0,0 +1,135 @@
+// Package client is a Go client for the REST API served by k8s-scan-server
+// and bjorn2scan-agent. The operation methods in client_gen.go are generated
+// from handlers.OpenAPIOperations, the catalog /api/openapi.json is built
+// from; run go generate after changing it.
+//
+// Each method decodes the JSON response into out. Pass a *[]byte to receive
+// the raw body instead (CSV, SVG and other non-JSON formats), or nil to
+// discard it.
+package client
+
+//go:generate go run ./gen -o client_gen.go
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// Client calls the API of one scan server
+type Client struct {
+	baseURL    string
+	httpClient *http.Client
+	token      string
+}
+
+// New creates a client for the server at baseURL, e.g.
+// http://bjorn2scan.bjorn2scan.svc:80. A nil httpClient uses http.DefaultClient.
+func New(baseURL string, httpClient *http.Client) *Client {
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	return &Client{baseURL: strings.TrimSuffix(baseURL, "/"), httpClient: httpClient}
+}
+
+// WithToken returns a copy of the client that sends token as a bearer token,
+// for servers with authentication enabled (a static token or an OIDC ID token)
+func (c *Client) WithToken(token string) *Client {
+	copied := *c
+	copied.token = token
+	return &copied
+}
+
+// Error is returned for responses with a status other than 2xx
+type Error struct {
+	StatusCode int
+	Body       string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("server returned status %d: %s", e.StatusCode, strings.TrimSpace(e.Body))
+}
+
+// do sends a request and decodes the response into out
+func (c *Client) do(ctx context.Context, method, path string, query url.Values, body interface{}, out interface{}) error {
+	u := c.baseURL + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+
+	var reqBody io.Reader
+	if body != nil {
+		encoded, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("failed to encode request body: %w", err)
+		}
+		reqBody = bytes.NewReader(encoded)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, u, reqBody)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to call %s %s: %w", method, path, err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return &Error{StatusCode: resp.StatusCode, Body: string(msg)}
+	}
+
+	switch v := out.(type) {
+	case nil:
+		_, err = io.Copy(io.Discard, resp.Body)
+	case *[]byte:
+		*v, err = io.ReadAll(resp.Body)
+	default:
+		err = json.NewDecoder(resp.Body).Decode(out)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read response of %s %s: %w", method, path, err)
+	}
+	return nil
+}
+
+// Helpers used by the generated methods to encode optional query parameters
+
+func setString(q url.Values, name, value string) {
+	if value != "" {
+		q.Set(name, value)
+	}
+}
+
+func setInt(q url.Values, name string, value int) {
+	if value != 0 {
+		q.Set(name, strconv.Itoa(value))
+	}
+}
+
+func setBool(q url.Values, name string, value bool) {
+	if value {
+		q.Set(name, "true")
+	}
+}
+
+func setList(q url.Values, name string, values []string) {
+	if len(values) > 0 {
+		q.Set(name, strings.Join(values, ","))
+	}
+}
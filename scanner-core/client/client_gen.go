@@ -0,0 +1,1119 @@
+// Code generated by go run ./gen; DO NOT EDIT.
+
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+)
+
+// ListImagesParams are the query parameters of ListImages
+type ListImagesParams struct {
+	Namespaces      []string // Only these namespaces
+	VulnStatuses    []string // Only these fix statuses (fixed, not-fixed, wont-fix, unknown)
+	PackageTypes    []string // Only these package types (deb, apk, go-module, ...)
+	OSNames         []string // Only these OS distributions
+	Page            int      // Page number, starting at 1
+	PageSize        int      // Rows per page
+	SortBy          string   // Column to sort by
+	SortOrder       string   // Sort direction
+	Delimiter       string   // CSV field separator (comma, semicolon, tab, pipe or the character)
+	Columns         []string // CSV columns to export, in order
+	Decimal         string   // CSV decimal separator
+	BOM             bool     // Prefix CSV with a UTF-8 byte order mark
+	Tz              string   // IANA time zone of exported timestamps (default UTC)
+	Search          string   // Substring of the image reference
+	Registries      []string // Only images from these registries
+	SlsaLevels      []string // Only images whose provenance supports these SLSA build levels (0 = checked, none found)
+	Builders        []string // Only images built by these provenance builder IDs
+	Sources         []string // Only images whose org.opencontainers.image.source label is one of these repositories
+	Vendors         []string // Only images whose org.opencontainers.image.vendor label is one of these vendors
+	MinSizeMB       string   // Only images of at least this size in MB
+	MaxSizeMB       string   // Only images of at most this size in MB
+	MinLayers       int      // Only images with at least this many layers
+	MaxLayers       int      // Only images with at most this many layers
+	Applications    []string // Only containers whose CMDB application ID is one of these
+	Owners          []string // Only containers whose CMDB owner is one of these
+	Classifications []string // Only containers whose CMDB data classification is one of these
+	IncludeDeleted  bool     // Include soft-deleted images
+	Format          string   // Response format
+}
+
+// ListImages calls GET /api/images: list images with vulnerability counts and scan status
+func (c *Client) ListImages(ctx context.Context, params ListImagesParams, out interface{}) error {
+	q := url.Values{}
+	setList(q, "namespaces", params.Namespaces)
+	setList(q, "vulnStatuses", params.VulnStatuses)
+	setList(q, "packageTypes", params.PackageTypes)
+	setList(q, "osNames", params.OSNames)
+	setInt(q, "page", params.Page)
+	setInt(q, "pageSize", params.PageSize)
+	setString(q, "sortBy", params.SortBy)
+	setString(q, "sortOrder", params.SortOrder)
+	setString(q, "delimiter", params.Delimiter)
+	setList(q, "columns", params.Columns)
+	setString(q, "decimal", params.Decimal)
+	setBool(q, "bom", params.BOM)
+	setString(q, "tz", params.Tz)
+	setString(q, "search", params.Search)
+	setList(q, "registries", params.Registries)
+	setList(q, "slsaLevels", params.SlsaLevels)
+	setList(q, "builders", params.Builders)
+	setList(q, "sources", params.Sources)
+	setList(q, "vendors", params.Vendors)
+	setString(q, "minSizeMB", params.MinSizeMB)
+	setString(q, "maxSizeMB", params.MaxSizeMB)
+	setInt(q, "minLayers", params.MinLayers)
+	setInt(q, "maxLayers", params.MaxLayers)
+	setList(q, "applications", params.Applications)
+	setList(q, "owners", params.Owners)
+	setList(q, "classifications", params.Classifications)
+	setBool(q, "includeDeleted", params.IncludeDeleted)
+	setString(q, "format", params.Format)
+	return c.do(ctx, http.MethodGet, "/api/images", q, nil, out)
+}
+
+// GetImageParams are the query parameters of GetImage
+type GetImageParams struct {
+	IncludeDeleted bool // Return the image even if it was soft-deleted
+}
+
+// GetImage calls GET /api/images/{digest}: get an image with its references and containers
+func (c *Client) GetImage(ctx context.Context, digest string, params GetImageParams, out interface{}) error {
+	q := url.Values{}
+	setBool(q, "includeDeleted", params.IncludeDeleted)
+	return c.do(ctx, http.MethodGet, "/api/images/"+url.PathEscape(digest), q, nil, out)
+}
+
+// ListImageVulnerabilitiesParams are the query parameters of ListImageVulnerabilities
+type ListImageVulnerabilitiesParams struct {
+	Severity      []string // Only these severities
+	FixStatus     []string // Only these fix statuses
+	PackageType   []string // Only these package types
+	Vulnerability string   // Substring of the vulnerability ID
+	Format        string   // Response format
+	Page          int      // Page number, starting at 1
+	PageSize      int      // Rows per page
+	SortBy        string   // Column to sort by
+	SortOrder     string   // Sort direction
+	Delimiter     string   // CSV field separator (comma, semicolon, tab, pipe or the character)
+	Columns       []string // CSV columns to export, in order
+	Decimal       string   // CSV decimal separator
+	BOM           bool     // Prefix CSV with a UTF-8 byte order mark
+	Tz            string   // IANA time zone of exported timestamps (default UTC)
+}
+
+// ListImageVulnerabilities calls GET /api/images/{digest}/vulnerabilities: list the vulnerabilities of an image
+func (c *Client) ListImageVulnerabilities(ctx context.Context, digest string, params ListImageVulnerabilitiesParams, out interface{}) error {
+	q := url.Values{}
+	setList(q, "severity", params.Severity)
+	setList(q, "fixStatus", params.FixStatus)
+	setList(q, "packageType", params.PackageType)
+	setString(q, "vulnerability", params.Vulnerability)
+	setString(q, "format", params.Format)
+	setInt(q, "page", params.Page)
+	setInt(q, "pageSize", params.PageSize)
+	setString(q, "sortBy", params.SortBy)
+	setString(q, "sortOrder", params.SortOrder)
+	setString(q, "delimiter", params.Delimiter)
+	setList(q, "columns", params.Columns)
+	setString(q, "decimal", params.Decimal)
+	setBool(q, "bom", params.BOM)
+	setString(q, "tz", params.Tz)
+	return c.do(ctx, http.MethodGet, "/api/images/"+url.PathEscape(digest)+"/vulnerabilities", q, nil, out)
+}
+
+// ListImagePackagesParams are the query parameters of ListImagePackages
+type ListImagePackagesParams struct {
+	Type      []string // Only these package types
+	Format    string   // Response format
+	Page      int      // Page number, starting at 1
+	PageSize  int      // Rows per page
+	SortBy    string   // Column to sort by
+	SortOrder string   // Sort direction
+	Delimiter string   // CSV field separator (comma, semicolon, tab, pipe or the character)
+	Columns   []string // CSV columns to export, in order
+	Decimal   string   // CSV decimal separator
+	BOM       bool     // Prefix CSV with a UTF-8 byte order mark
+	Tz        string   // IANA time zone of exported timestamps (default UTC)
+}
+
+// ListImagePackages calls GET /api/images/{digest}/packages: list the packages of an image
+func (c *Client) ListImagePackages(ctx context.Context, digest string, params ListImagePackagesParams, out interface{}) error {
+	q := url.Values{}
+	setList(q, "type", params.Type)
+	setString(q, "format", params.Format)
+	setInt(q, "page", params.Page)
+	setInt(q, "pageSize", params.PageSize)
+	setString(q, "sortBy", params.SortBy)
+	setString(q, "sortOrder", params.SortOrder)
+	setString(q, "delimiter", params.Delimiter)
+	setList(q, "columns", params.Columns)
+	setString(q, "decimal", params.Decimal)
+	setBool(q, "bom", params.BOM)
+	setString(q, "tz", params.Tz)
+	return c.do(ctx, http.MethodGet, "/api/images/"+url.PathEscape(digest)+"/packages", q, nil, out)
+}
+
+// GetImageStatsParams are the query parameters of GetImageStats
+type GetImageStatsParams struct {
+	Severity    []string // Only these severities
+	FixStatus   []string // Only these fix statuses
+	PackageType []string // Only these package types
+}
+
+// GetImageStats calls GET /api/images/{digest}/stats: get vulnerability statistics of an image
+func (c *Client) GetImageStats(ctx context.Context, digest string, params GetImageStatsParams, out interface{}) error {
+	q := url.Values{}
+	setList(q, "severity", params.Severity)
+	setList(q, "fixStatus", params.FixStatus)
+	setList(q, "packageType", params.PackageType)
+	return c.do(ctx, http.MethodGet, "/api/images/"+url.PathEscape(digest)+"/stats", q, nil, out)
+}
+
+// GetImagePolicy calls GET /api/images/{digest}/policy: evaluate an image against the configured policy: pass, fail or not_scanned, with the violated rules
+func (c *Client) GetImagePolicy(ctx context.Context, digest string, out interface{}) error {
+	return c.do(ctx, http.MethodGet, "/api/images/"+url.PathEscape(digest)+"/policy", nil, nil, out)
+}
+
+// GetImageScanHistoryParams are the query parameters of GetImageScanHistory
+type GetImageScanHistoryParams struct {
+	Lookback string // Only scans within this window, e.g. 720h (default: all)
+}
+
+// GetImageScanHistory calls GET /api/images/{digest}/history: get the severity counts and risk score of an image at each scan, oldest first
+func (c *Client) GetImageScanHistory(ctx context.Context, digest string, params GetImageScanHistoryParams, out interface{}) error {
+	q := url.Values{}
+	setString(q, "lookback", params.Lookback)
+	return c.do(ctx, http.MethodGet, "/api/images/"+url.PathEscape(digest)+"/history", q, nil, out)
+}
+
+// ListContainersParams are the query parameters of ListContainers
+type ListContainersParams struct {
+	Namespaces   []string // Only these namespaces
+	VulnStatuses []string // Only these fix statuses (fixed, not-fixed, wont-fix, unknown)
+	PackageTypes []string // Only these package types (deb, apk, go-module, ...)
+	OSNames      []string // Only these OS distributions
+	Page         int      // Page number, starting at 1
+	PageSize     int      // Rows per page
+	SortBy       string   // Column to sort by
+	SortOrder    string   // Sort direction
+	Delimiter    string   // CSV field separator (comma, semicolon, tab, pipe or the character)
+	Columns      []string // CSV columns to export, in order
+	Decimal      string   // CSV decimal separator
+	BOM          bool     // Prefix CSV with a UTF-8 byte order mark
+	Tz           string   // IANA time zone of exported timestamps (default UTC)
+	Search       string   // Substring of the container, pod or image
+	Registries   []string // Only images from these registries
+	Detail       string   // Include image reference, repository, tag, OS version and first seen time (always in CSV)
+	Format       string   // Response format
+}
+
+// ListContainers calls GET /api/containers: list running containers with the scan results of their images
+func (c *Client) ListContainers(ctx context.Context, params ListContainersParams, out interface{}) error {
+	q := url.Values{}
+	setList(q, "namespaces", params.Namespaces)
+	setList(q, "vulnStatuses", params.VulnStatuses)
+	setList(q, "packageTypes", params.PackageTypes)
+	setList(q, "osNames", params.OSNames)
+	setInt(q, "page", params.Page)
+	setInt(q, "pageSize", params.PageSize)
+	setString(q, "sortBy", params.SortBy)
+	setString(q, "sortOrder", params.SortOrder)
+	setString(q, "delimiter", params.Delimiter)
+	setList(q, "columns", params.Columns)
+	setString(q, "decimal", params.Decimal)
+	setBool(q, "bom", params.BOM)
+	setString(q, "tz", params.Tz)
+	setString(q, "search", params.Search)
+	setList(q, "registries", params.Registries)
+	setString(q, "detail", params.Detail)
+	setString(q, "format", params.Format)
+	return c.do(ctx, http.MethodGet, "/api/containers", q, nil, out)
+}
+
+// DownloadSBOMParams are the query parameters of DownloadSBOM
+type DownloadSBOMParams struct {
+	Format string // Response format
+}
+
+// DownloadSBOM calls GET /api/sbom/{digest}: download the SBOM of an image as Syft JSON, CycloneDX JSON or SPDX JSON
+func (c *Client) DownloadSBOM(ctx context.Context, digest string, params DownloadSBOMParams, out interface{}) error {
+	q := url.Values{}
+	setString(q, "format", params.Format)
+	return c.do(ctx, http.MethodGet, "/api/sbom/"+url.PathEscape(digest), q, nil, out)
+}
+
+// DownloadVulnerabilities calls GET /api/vulnerabilities/{digest}: download the Grype vulnerability report of an image
+func (c *Client) DownloadVulnerabilities(ctx context.Context, digest string, out interface{}) error {
+	return c.do(ctx, http.MethodGet, "/api/vulnerabilities/"+url.PathEscape(digest), nil, nil, out)
+}
+
+// GetVulnerabilityDetails calls GET /api/vulnerabilities/{id}/details: get the Grype match details of an image vulnerability
+func (c *Client) GetVulnerabilityDetails(ctx context.Context, id string, out interface{}) error {
+	return c.do(ctx, http.MethodGet, "/api/vulnerabilities/"+url.PathEscape(id)+"/details", nil, nil, out)
+}
+
+// GetVulnerabilityOccurrences calls GET /api/vulnerabilities/{id}/occurrences: list the paths at which the package of an image vulnerability was found
+func (c *Client) GetVulnerabilityOccurrences(ctx context.Context, id string, out interface{}) error {
+	return c.do(ctx, http.MethodGet, "/api/vulnerabilities/"+url.PathEscape(id)+"/occurrences", nil, nil, out)
+}
+
+// GetPackageDetails calls GET /api/packages/{id}/details: get the Syft details of an image package
+func (c *Client) GetPackageDetails(ctx context.Context, id string, out interface{}) error {
+	return c.do(ctx, http.MethodGet, "/api/packages/"+url.PathEscape(id)+"/details", nil, nil, out)
+}
+
+// GetBadge calls GET /api/badge/{image}: get an SVG vulnerability badge for an image digest or reference (append .svg)
+func (c *Client) GetBadge(ctx context.Context, image string, out interface{}) error {
+	return c.do(ctx, http.MethodGet, "/api/badge/"+url.PathEscape(image), nil, nil, out)
+}
+
+// ListVulnerabilitiesParams are the query parameters of ListVulnerabilities
+type ListVulnerabilitiesParams struct {
+	Namespaces     []string // Only these namespaces
+	VulnStatuses   []string // Only these fix statuses (fixed, not-fixed, wont-fix, unknown)
+	PackageTypes   []string // Only these package types (deb, apk, go-module, ...)
+	OSNames        []string // Only these OS distributions
+	Page           int      // Page number, starting at 1
+	PageSize       int      // Rows per page
+	SortBy         string   // Column to sort by
+	SortOrder      string   // Sort direction
+	Delimiter      string   // CSV field separator (comma, semicolon, tab, pipe or the character)
+	Columns        []string // CSV columns to export, in order
+	Decimal        string   // CSV decimal separator
+	BOM            bool     // Prefix CSV with a UTF-8 byte order mark
+	Tz             string   // IANA time zone of exported timestamps (default UTC)
+	Severity       []string // Only these severities
+	Vulnerability  string   // Vulnerability ID or alias
+	KnownExploited bool     // Only vulnerabilities in the CISA KEV catalog
+	FixAvailable   bool     // Only vulnerabilities with a fix for at least one package
+	Format         string   // Response format
+}
+
+// ListVulnerabilities calls GET /api/vulnerabilities: list vulnerabilities with the images, pods and namespaces they affect
+func (c *Client) ListVulnerabilities(ctx context.Context, params ListVulnerabilitiesParams, out interface{}) error {
+	q := url.Values{}
+	setList(q, "namespaces", params.Namespaces)
+	setList(q, "vulnStatuses", params.VulnStatuses)
+	setList(q, "packageTypes", params.PackageTypes)
+	setList(q, "osNames", params.OSNames)
+	setInt(q, "page", params.Page)
+	setInt(q, "pageSize", params.PageSize)
+	setString(q, "sortBy", params.SortBy)
+	setString(q, "sortOrder", params.SortOrder)
+	setString(q, "delimiter", params.Delimiter)
+	setList(q, "columns", params.Columns)
+	setString(q, "decimal", params.Decimal)
+	setBool(q, "bom", params.BOM)
+	setString(q, "tz", params.Tz)
+	setList(q, "severity", params.Severity)
+	setString(q, "vulnerability", params.Vulnerability)
+	setBool(q, "knownExploited", params.KnownExploited)
+	setBool(q, "fixAvailable", params.FixAvailable)
+	setString(q, "format", params.Format)
+	return c.do(ctx, http.MethodGet, "/api/vulnerabilities", q, nil, out)
+}
+
+// GetVulnerability calls GET /api/vulnerabilities/{cve}: get the packages, images and workloads affected by a vulnerability
+func (c *Client) GetVulnerability(ctx context.Context, cve string, out interface{}) error {
+	return c.do(ctx, http.MethodGet, "/api/vulnerabilities/"+url.PathEscape(cve), nil, nil, out)
+}
+
+// ListContainerCVEsParams are the query parameters of ListContainerCVEs
+type ListContainerCVEsParams struct {
+	Namespaces    []string // Only these namespaces
+	VulnStatuses  []string // Only these fix statuses (fixed, not-fixed, wont-fix, unknown)
+	PackageTypes  []string // Only these package types (deb, apk, go-module, ...)
+	OSNames       []string // Only these OS distributions
+	Page          int      // Page number, starting at 1
+	PageSize      int      // Rows per page
+	SortBy        string   // Column to sort by
+	SortOrder     string   // Sort direction
+	Delimiter     string   // CSV field separator (comma, semicolon, tab, pipe or the character)
+	Columns       []string // CSV columns to export, in order
+	Decimal       string   // CSV decimal separator
+	BOM           bool     // Prefix CSV with a UTF-8 byte order mark
+	Tz            string   // IANA time zone of exported timestamps (default UTC)
+	Severity      []string // Only these severities
+	Vulnerability string   // Substring of the vulnerability ID
+	Format        string   // Response format
+}
+
+// ListContainerCVEs calls GET /api/container-cves: list vulnerabilities across running containers
+func (c *Client) ListContainerCVEs(ctx context.Context, params ListContainerCVEsParams, out interface{}) error {
+	q := url.Values{}
+	setList(q, "namespaces", params.Namespaces)
+	setList(q, "vulnStatuses", params.VulnStatuses)
+	setList(q, "packageTypes", params.PackageTypes)
+	setList(q, "osNames", params.OSNames)
+	setInt(q, "page", params.Page)
+	setInt(q, "pageSize", params.PageSize)
+	setString(q, "sortBy", params.SortBy)
+	setString(q, "sortOrder", params.SortOrder)
+	setString(q, "delimiter", params.Delimiter)
+	setList(q, "columns", params.Columns)
+	setString(q, "decimal", params.Decimal)
+	setBool(q, "bom", params.BOM)
+	setString(q, "tz", params.Tz)
+	setList(q, "severity", params.Severity)
+	setString(q, "vulnerability", params.Vulnerability)
+	setString(q, "format", params.Format)
+	return c.do(ctx, http.MethodGet, "/api/container-cves", q, nil, out)
+}
+
+// ListContainerCVEAffectedParams are the query parameters of ListContainerCVEAffected
+type ListContainerCVEAffectedParams struct {
+	CVE     string // Vulnerability ID
+	Name    string // Package name
+	Version string // Package version
+	Type    string // Package type
+}
+
+// ListContainerCVEAffected calls GET /api/container-cves/affected: list the containers affected by a vulnerability
+func (c *Client) ListContainerCVEAffected(ctx context.Context, params ListContainerCVEAffectedParams, out interface{}) error {
+	q := url.Values{}
+	setString(q, "cve", params.CVE)
+	setString(q, "name", params.Name)
+	setString(q, "version", params.Version)
+	setString(q, "type", params.Type)
+	return c.do(ctx, http.MethodGet, "/api/container-cves/affected", q, nil, out)
+}
+
+// ListContainerCVEDetailsParams are the query parameters of ListContainerCVEDetails
+type ListContainerCVEDetailsParams struct {
+	CVE     string // Vulnerability ID
+	Name    string // Package name
+	Version string // Package version
+	Type    string // Package type
+}
+
+// ListContainerCVEDetails calls GET /api/container-cves/details: list the match variants of a vulnerability
+func (c *Client) ListContainerCVEDetails(ctx context.Context, params ListContainerCVEDetailsParams, out interface{}) error {
+	q := url.Values{}
+	setString(q, "cve", params.CVE)
+	setString(q, "name", params.Name)
+	setString(q, "version", params.Version)
+	setString(q, "type", params.Type)
+	return c.do(ctx, http.MethodGet, "/api/container-cves/details", q, nil, out)
+}
+
+// GetBlastRadiusParams are the query parameters of GetBlastRadius
+type GetBlastRadiusParams struct {
+	Package string // Package name
+	Version string // Package version
+	Type    string // Package type
+}
+
+// GetBlastRadius calls GET /api/analytics/blast-radius: get the images, workloads and namespaces affected by a package version
+func (c *Client) GetBlastRadius(ctx context.Context, params GetBlastRadiusParams, out interface{}) error {
+	q := url.Values{}
+	setString(q, "package", params.Package)
+	setString(q, "version", params.Version)
+	setString(q, "type", params.Type)
+	return c.do(ctx, http.MethodGet, "/api/analytics/blast-radius", q, nil, out)
+}
+
+// GetFixCoverageParams are the query parameters of GetFixCoverage
+type GetFixCoverageParams struct {
+	Namespaces   []string // Only these namespaces
+	Severity     []string // Only these severities
+	PackageTypes []string // Only these package types
+}
+
+// GetFixCoverage calls GET /api/analytics/fix-coverage: get the findings fixable by upgrades, awaiting vendor fixes and the top package upgrades, overall and per namespace
+func (c *Client) GetFixCoverage(ctx context.Context, params GetFixCoverageParams, out interface{}) error {
+	q := url.Values{}
+	setList(q, "namespaces", params.Namespaces)
+	setList(q, "severity", params.Severity)
+	setList(q, "packageTypes", params.PackageTypes)
+	return c.do(ctx, http.MethodGet, "/api/analytics/fix-coverage", q, nil, out)
+}
+
+// GetImageSizeAnalyticsParams are the query parameters of GetImageSizeAnalytics
+type GetImageSizeAnalyticsParams struct {
+	Namespaces []string // Only images running in these namespaces
+	Limit      int      // Largest images listed (1-100, default 10)
+}
+
+// GetImageSizeAnalytics calls GET /api/analytics/image-size: get running images by size bucket, the largest images and how size correlates with scan duration and vulnerabilities
+func (c *Client) GetImageSizeAnalytics(ctx context.Context, params GetImageSizeAnalyticsParams, out interface{}) error {
+	q := url.Values{}
+	setList(q, "namespaces", params.Namespaces)
+	setInt(q, "limit", params.Limit)
+	return c.do(ctx, http.MethodGet, "/api/analytics/image-size", q, nil, out)
+}
+
+// GetFilterOptions calls GET /api/filter-options: list the values available for the list filters
+func (c *Client) GetFilterOptions(ctx context.Context, out interface{}) error {
+	return c.do(ctx, http.MethodGet, "/api/filter-options", nil, nil, out)
+}
+
+// ListCMDBMetadata calls GET /api/cmdb: list the application, owner and data classification synced from the CMDB by namespace and image label
+func (c *Client) ListCMDBMetadata(ctx context.Context, out interface{}) error {
+	return c.do(ctx, http.MethodGet, "/api/cmdb", nil, nil, out)
+}
+
+// SyncCMDBMetadata calls POST /api/cmdb/sync: replace the CMDB entries of a source with the pushed entries
+func (c *Client) SyncCMDBMetadata(ctx context.Context, body interface{}, out interface{}) error {
+	return c.do(ctx, http.MethodPost, "/api/cmdb/sync", nil, body, out)
+}
+
+// GetSeverities calls GET /api/severities: get the configured severity scale
+func (c *Client) GetSeverities(ctx context.Context, out interface{}) error {
+	return c.do(ctx, http.MethodGet, "/api/severities", nil, nil, out)
+}
+
+// ListCVEAnnotations calls GET /api/cve-annotations: list the analyst notes attached to vulnerabilities
+func (c *Client) ListCVEAnnotations(ctx context.Context, out interface{}) error {
+	return c.do(ctx, http.MethodGet, "/api/cve-annotations", nil, nil, out)
+}
+
+// SaveCVEAnnotation calls POST /api/cve-annotations/save: attach a cluster-wide note to a vulnerability, replacing any previous note
+func (c *Client) SaveCVEAnnotation(ctx context.Context, body interface{}, out interface{}) error {
+	return c.do(ctx, http.MethodPost, "/api/cve-annotations/save", nil, body, out)
+}
+
+// DeleteCVEAnnotation calls POST /api/cve-annotations/delete: remove the note on a vulnerability
+func (c *Client) DeleteCVEAnnotation(ctx context.Context, body interface{}, out interface{}) error {
+	return c.do(ctx, http.MethodPost, "/api/cve-annotations/delete", nil, body, out)
+}
+
+// ListVEXDocuments calls GET /api/vex: list the OpenVEX documents in effect, loaded from the configured path or uploaded
+func (c *Client) ListVEXDocuments(ctx context.Context, out interface{}) error {
+	return c.do(ctx, http.MethodGet, "/api/vex", nil, nil, out)
+}
+
+// UploadVEXDocument calls POST /api/vex/upload: upload an OpenVEX document whose not_affected statements suppress findings
+func (c *Client) UploadVEXDocument(ctx context.Context, body interface{}, out interface{}) error {
+	return c.do(ctx, http.MethodPost, "/api/vex/upload", nil, body, out)
+}
+
+// DeleteVEXDocument calls POST /api/vex/delete: remove an uploaded OpenVEX document, restoring the findings it suppressed
+func (c *Client) DeleteVEXDocument(ctx context.Context, body interface{}, out interface{}) error {
+	return c.do(ctx, http.MethodPost, "/api/vex/delete", nil, body, out)
+}
+
+// ListVEXSuppressionsParams are the query parameters of ListVEXSuppressions
+type ListVEXSuppressionsParams struct {
+	Digest string // Only the findings of this image
+}
+
+// ListVEXSuppressions calls GET /api/vex/suppressions: list the findings suppressed by OpenVEX statements, with their justification
+func (c *Client) ListVEXSuppressions(ctx context.Context, params ListVEXSuppressionsParams, out interface{}) error {
+	q := url.Values{}
+	setString(q, "digest", params.Digest)
+	return c.do(ctx, http.MethodGet, "/api/vex/suppressions", q, nil, out)
+}
+
+// ListIgnores calls GET /api/ignores: list the accepted-risk vulnerability ignores, with whether they are active
+func (c *Client) ListIgnores(ctx context.Context, out interface{}) error {
+	return c.do(ctx, http.MethodGet, "/api/ignores", nil, nil, out)
+}
+
+// SaveIgnore calls POST /api/ignores/save: create or update an ignore accepting the risk of a vulnerability until its expiry
+func (c *Client) SaveIgnore(ctx context.Context, body interface{}, out interface{}) error {
+	return c.do(ctx, http.MethodPost, "/api/ignores/save", nil, body, out)
+}
+
+// DeleteIgnore calls POST /api/ignores/delete: remove an ignore, restoring the findings it suppressed
+func (c *Client) DeleteIgnore(ctx context.Context, body interface{}, out interface{}) error {
+	return c.do(ctx, http.MethodPost, "/api/ignores/delete", nil, body, out)
+}
+
+// ListIgnoreEventsParams are the query parameters of ListIgnoreEvents
+type ListIgnoreEventsParams struct {
+	ID int // Only the events of this ignore
+}
+
+// ListIgnoreEvents calls GET /api/ignores/audit: list who created, changed or deleted ignores and when they expired
+func (c *Client) ListIgnoreEvents(ctx context.Context, params ListIgnoreEventsParams, out interface{}) error {
+	q := url.Values{}
+	setInt(q, "id", params.ID)
+	return c.do(ctx, http.MethodGet, "/api/ignores/audit", q, nil, out)
+}
+
+// GetDeploymentMetricsParams are the query parameters of GetDeploymentMetrics
+type GetDeploymentMetricsParams struct {
+	Namespaces   []string // Only these namespaces
+	VulnStatuses []string // Only these fix statuses (fixed, not-fixed, wont-fix, unknown)
+	PackageTypes []string // Only these package types (deb, apk, go-module, ...)
+	OSNames      []string // Only these OS distributions
+	Severity     []string // Only these severities
+}
+
+// GetDeploymentMetrics calls GET /api/summary/deployment-metrics: get cluster-wide vulnerability metrics
+func (c *Client) GetDeploymentMetrics(ctx context.Context, params GetDeploymentMetricsParams, out interface{}) error {
+	q := url.Values{}
+	setList(q, "namespaces", params.Namespaces)
+	setList(q, "vulnStatuses", params.VulnStatuses)
+	setList(q, "packageTypes", params.PackageTypes)
+	setList(q, "osNames", params.OSNames)
+	setList(q, "severity", params.Severity)
+	return c.do(ctx, http.MethodGet, "/api/summary/deployment-metrics", q, nil, out)
+}
+
+// GetNodeMetricsParams are the query parameters of GetNodeMetrics
+type GetNodeMetricsParams struct {
+	VulnStatuses []string // Only these fix statuses
+	PackageTypes []string // Only these package types
+	OSNames      []string // Only these OS distributions
+	Severity     []string // Only these severities
+}
+
+// GetNodeMetrics calls GET /api/summary/node-metrics: get node-wide vulnerability metrics
+func (c *Client) GetNodeMetrics(ctx context.Context, params GetNodeMetricsParams, out interface{}) error {
+	q := url.Values{}
+	setList(q, "vulnStatuses", params.VulnStatuses)
+	setList(q, "packageTypes", params.PackageTypes)
+	setList(q, "osNames", params.OSNames)
+	setList(q, "severity", params.Severity)
+	return c.do(ctx, http.MethodGet, "/api/summary/node-metrics", q, nil, out)
+}
+
+// GetNamespaceSummaryParams are the query parameters of GetNamespaceSummary
+type GetNamespaceSummaryParams struct {
+	Namespaces   []string // Only these namespaces
+	VulnStatuses []string // Only these fix statuses (fixed, not-fixed, wont-fix, unknown)
+	PackageTypes []string // Only these package types (deb, apk, go-module, ...)
+	OSNames      []string // Only these OS distributions
+	Page         int      // Page number, starting at 1
+	PageSize     int      // Rows per page
+	SortBy       string   // Column to sort by
+	SortOrder    string   // Sort direction
+	Delimiter    string   // CSV field separator (comma, semicolon, tab, pipe or the character)
+	Columns      []string // CSV columns to export, in order
+	Decimal      string   // CSV decimal separator
+	BOM          bool     // Prefix CSV with a UTF-8 byte order mark
+	Tz           string   // IANA time zone of exported timestamps (default UTC)
+	Format       string   // Response format
+}
+
+// GetNamespaceSummary calls GET /api/summary/by-namespace: get average vulnerability counts per namespace
+func (c *Client) GetNamespaceSummary(ctx context.Context, params GetNamespaceSummaryParams, out interface{}) error {
+	q := url.Values{}
+	setList(q, "namespaces", params.Namespaces)
+	setList(q, "vulnStatuses", params.VulnStatuses)
+	setList(q, "packageTypes", params.PackageTypes)
+	setList(q, "osNames", params.OSNames)
+	setInt(q, "page", params.Page)
+	setInt(q, "pageSize", params.PageSize)
+	setString(q, "sortBy", params.SortBy)
+	setString(q, "sortOrder", params.SortOrder)
+	setString(q, "delimiter", params.Delimiter)
+	setList(q, "columns", params.Columns)
+	setString(q, "decimal", params.Decimal)
+	setBool(q, "bom", params.BOM)
+	setString(q, "tz", params.Tz)
+	setString(q, "format", params.Format)
+	return c.do(ctx, http.MethodGet, "/api/summary/by-namespace", q, nil, out)
+}
+
+// GetDistributionSummaryParams are the query parameters of GetDistributionSummary
+type GetDistributionSummaryParams struct {
+	Namespaces   []string // Only these namespaces
+	VulnStatuses []string // Only these fix statuses (fixed, not-fixed, wont-fix, unknown)
+	PackageTypes []string // Only these package types (deb, apk, go-module, ...)
+	OSNames      []string // Only these OS distributions
+	Page         int      // Page number, starting at 1
+	PageSize     int      // Rows per page
+	SortBy       string   // Column to sort by
+	SortOrder    string   // Sort direction
+	Delimiter    string   // CSV field separator (comma, semicolon, tab, pipe or the character)
+	Columns      []string // CSV columns to export, in order
+	Decimal      string   // CSV decimal separator
+	BOM          bool     // Prefix CSV with a UTF-8 byte order mark
+	Tz           string   // IANA time zone of exported timestamps (default UTC)
+	Format       string   // Response format
+}
+
+// GetDistributionSummary calls GET /api/summary/by-distribution: get average vulnerability counts per OS distribution
+func (c *Client) GetDistributionSummary(ctx context.Context, params GetDistributionSummaryParams, out interface{}) error {
+	q := url.Values{}
+	setList(q, "namespaces", params.Namespaces)
+	setList(q, "vulnStatuses", params.VulnStatuses)
+	setList(q, "packageTypes", params.PackageTypes)
+	setList(q, "osNames", params.OSNames)
+	setInt(q, "page", params.Page)
+	setInt(q, "pageSize", params.PageSize)
+	setString(q, "sortBy", params.SortBy)
+	setString(q, "sortOrder", params.SortOrder)
+	setString(q, "delimiter", params.Delimiter)
+	setList(q, "columns", params.Columns)
+	setString(q, "decimal", params.Decimal)
+	setBool(q, "bom", params.BOM)
+	setString(q, "tz", params.Tz)
+	setString(q, "format", params.Format)
+	return c.do(ctx, http.MethodGet, "/api/summary/by-distribution", q, nil, out)
+}
+
+// GetScanCoverageParams are the query parameters of GetScanCoverage
+type GetScanCoverageParams struct {
+	Lookback string // Window of completed Jobs counted, e.g. 24h
+}
+
+// GetScanCoverage calls GET /api/summary/coverage: get the share of observed images with a completed scan
+func (c *Client) GetScanCoverage(ctx context.Context, params GetScanCoverageParams, out interface{}) error {
+	q := url.Values{}
+	setString(q, "lookback", params.Lookback)
+	return c.do(ctx, http.MethodGet, "/api/summary/coverage", q, nil, out)
+}
+
+// GetScanTrendsParams are the query parameters of GetScanTrends
+type GetScanTrendsParams struct {
+	Interval   string   // Trend interval (default week)
+	Lookback   string   // Window of scans included, e.g. 720h (default 2016h)
+	Namespaces []string // Only images running in these namespaces
+}
+
+// GetScanTrends calls GET /api/summary/trends: get the combined severity counts and risk score of running images per day or week
+func (c *Client) GetScanTrends(ctx context.Context, params GetScanTrendsParams, out interface{}) error {
+	q := url.Values{}
+	setString(q, "interval", params.Interval)
+	setString(q, "lookback", params.Lookback)
+	setList(q, "namespaces", params.Namespaces)
+	return c.do(ctx, http.MethodGet, "/api/summary/trends", q, nil, out)
+}
+
+// GetOSEOLSummary calls GET /api/summary/os-eol: get the end-of-life status of the OS releases of running images
+func (c *Client) GetOSEOLSummary(ctx context.Context, out interface{}) error {
+	return c.do(ctx, http.MethodGet, "/api/summary/os-eol", nil, nil, out)
+}
+
+// GetLastUpdatedParams are the query parameters of GetLastUpdated
+type GetLastUpdatedParams struct {
+	Datatype string // Data type (all or image)
+}
+
+// GetLastUpdated calls GET /api/lastupdated: get the time scan data last changed (RFC 3339, plain text)
+func (c *Client) GetLastUpdated(ctx context.Context, params GetLastUpdatedParams, out interface{}) error {
+	q := url.Values{}
+	setString(q, "datatype", params.Datatype)
+	return c.do(ctx, http.MethodGet, "/api/lastupdated", q, nil, out)
+}
+
+// GetReportParams are the query parameters of GetReport
+type GetReportParams struct {
+	MaxSizeMB int    // Maximum report size (1-500, default 50)
+	Tz        string // IANA time zone of exported timestamps (default UTC)
+}
+
+// GetReport calls GET /api/report: download a self-contained HTML report of the current scan state
+func (c *Client) GetReport(ctx context.Context, params GetReportParams, out interface{}) error {
+	q := url.Values{}
+	setInt(q, "maxSizeMB", params.MaxSizeMB)
+	setString(q, "tz", params.Tz)
+	return c.do(ctx, http.MethodGet, "/api/report", q, nil, out)
+}
+
+// GetPolicy calls GET /api/policy: get the policy, or bundle of policies, images are evaluated against
+func (c *Client) GetPolicy(ctx context.Context, out interface{}) error {
+	return c.do(ctx, http.MethodGet, "/api/policy", nil, nil, out)
+}
+
+// GetPolicyReportParams are the query parameters of GetPolicyReport
+type GetPolicyReportParams struct {
+	Namespaces []string // Only images running in these namespaces
+	Verdict    []string // Only list images with these verdicts (pass, fail, not_scanned, no_policy)
+}
+
+// GetPolicyReport calls GET /api/policy/report: evaluate all running images against the configured policy, failing images first
+func (c *Client) GetPolicyReport(ctx context.Context, params GetPolicyReportParams, out interface{}) error {
+	q := url.Values{}
+	setList(q, "namespaces", params.Namespaces)
+	setList(q, "verdict", params.Verdict)
+	return c.do(ctx, http.MethodGet, "/api/policy/report", q, nil, out)
+}
+
+// ListNodes calls GET /api/nodes: list nodes with their host scan status
+func (c *Client) ListNodes(ctx context.Context, out interface{}) error {
+	return c.do(ctx, http.MethodGet, "/api/nodes", nil, nil, out)
+}
+
+// GetNode calls GET /api/nodes/{name}: get a node
+func (c *Client) GetNode(ctx context.Context, name string, out interface{}) error {
+	return c.do(ctx, http.MethodGet, "/api/nodes/"+url.PathEscape(name), nil, nil, out)
+}
+
+// ListNodePackagesParams are the query parameters of ListNodePackages
+type ListNodePackagesParams struct {
+	Format    string   // Response format
+	Delimiter string   // CSV field separator (comma, semicolon, tab, pipe or the character)
+	Columns   []string // CSV columns to export, in order
+	Decimal   string   // CSV decimal separator
+	BOM       bool     // Prefix CSV with a UTF-8 byte order mark
+	Tz        string   // IANA time zone of exported timestamps (default UTC)
+}
+
+// ListNodePackages calls GET /api/nodes/{name}/packages: list the packages of a node
+func (c *Client) ListNodePackages(ctx context.Context, name string, params ListNodePackagesParams, out interface{}) error {
+	q := url.Values{}
+	setString(q, "format", params.Format)
+	setString(q, "delimiter", params.Delimiter)
+	setList(q, "columns", params.Columns)
+	setString(q, "decimal", params.Decimal)
+	setBool(q, "bom", params.BOM)
+	setString(q, "tz", params.Tz)
+	return c.do(ctx, http.MethodGet, "/api/nodes/"+url.PathEscape(name)+"/packages", q, nil, out)
+}
+
+// ListNodeVulnerabilitiesParams are the query parameters of ListNodeVulnerabilities
+type ListNodeVulnerabilitiesParams struct {
+	Format    string   // Response format
+	Delimiter string   // CSV field separator (comma, semicolon, tab, pipe or the character)
+	Columns   []string // CSV columns to export, in order
+	Decimal   string   // CSV decimal separator
+	BOM       bool     // Prefix CSV with a UTF-8 byte order mark
+	Tz        string   // IANA time zone of exported timestamps (default UTC)
+}
+
+// ListNodeVulnerabilities calls GET /api/nodes/{name}/vulnerabilities: list the vulnerabilities of a node
+func (c *Client) ListNodeVulnerabilities(ctx context.Context, name string, params ListNodeVulnerabilitiesParams, out interface{}) error {
+	q := url.Values{}
+	setString(q, "format", params.Format)
+	setString(q, "delimiter", params.Delimiter)
+	setList(q, "columns", params.Columns)
+	setString(q, "decimal", params.Decimal)
+	setBool(q, "bom", params.BOM)
+	setString(q, "tz", params.Tz)
+	return c.do(ctx, http.MethodGet, "/api/nodes/"+url.PathEscape(name)+"/vulnerabilities", q, nil, out)
+}
+
+// ListNodeScanners calls GET /api/nodes/scanners: report the node scanner compatibility of each node
+func (c *Client) ListNodeScanners(ctx context.Context, out interface{}) error {
+	return c.do(ctx, http.MethodGet, "/api/nodes/scanners", nil, nil, out)
+}
+
+// GetNodeSummaryParams are the query parameters of GetNodeSummary
+type GetNodeSummaryParams struct {
+	VulnStatuses []string // Only these fix statuses
+	PackageTypes []string // Only these package types
+	OSNames      []string // Only these OS distributions
+	Delimiter    string   // CSV field separator (comma, semicolon, tab, pipe or the character)
+	Columns      []string // CSV columns to export, in order
+	Decimal      string   // CSV decimal separator
+	BOM          bool     // Prefix CSV with a UTF-8 byte order mark
+	Tz           string   // IANA time zone of exported timestamps (default UTC)
+	Format       string   // Response format
+}
+
+// GetNodeSummary calls GET /api/summary/by-node: get vulnerability counts per node
+func (c *Client) GetNodeSummary(ctx context.Context, params GetNodeSummaryParams, out interface{}) error {
+	q := url.Values{}
+	setList(q, "vulnStatuses", params.VulnStatuses)
+	setList(q, "packageTypes", params.PackageTypes)
+	setList(q, "osNames", params.OSNames)
+	setString(q, "delimiter", params.Delimiter)
+	setList(q, "columns", params.Columns)
+	setString(q, "decimal", params.Decimal)
+	setBool(q, "bom", params.BOM)
+	setString(q, "tz", params.Tz)
+	setString(q, "format", params.Format)
+	return c.do(ctx, http.MethodGet, "/api/summary/by-node", q, nil, out)
+}
+
+// GetNodeDistributionSummaryParams are the query parameters of GetNodeDistributionSummary
+type GetNodeDistributionSummaryParams struct {
+	Delimiter string   // CSV field separator (comma, semicolon, tab, pipe or the character)
+	Columns   []string // CSV columns to export, in order
+	Decimal   string   // CSV decimal separator
+	BOM       bool     // Prefix CSV with a UTF-8 byte order mark
+	Tz        string   // IANA time zone of exported timestamps (default UTC)
+	Format    string   // Response format
+}
+
+// GetNodeDistributionSummary calls GET /api/summary/by-node-distro: get average vulnerability counts per node OS distribution
+func (c *Client) GetNodeDistributionSummary(ctx context.Context, params GetNodeDistributionSummaryParams, out interface{}) error {
+	q := url.Values{}
+	setString(q, "delimiter", params.Delimiter)
+	setList(q, "columns", params.Columns)
+	setString(q, "decimal", params.Decimal)
+	setBool(q, "bom", params.BOM)
+	setString(q, "tz", params.Tz)
+	setString(q, "format", params.Format)
+	return c.do(ctx, http.MethodGet, "/api/summary/by-node-distro", q, nil, out)
+}
+
+// GetNodeFilterOptions calls GET /api/node-filter-options: list the values available for the node list filters
+func (c *Client) GetNodeFilterOptions(ctx context.Context, out interface{}) error {
+	return c.do(ctx, http.MethodGet, "/api/node-filter-options", nil, nil, out)
+}
+
+// GetNodeVulnerabilityDetails calls GET /api/node-vulnerabilities/{id}/details: get the Grype match details of a node vulnerability
+func (c *Client) GetNodeVulnerabilityDetails(ctx context.Context, id string, out interface{}) error {
+	return c.do(ctx, http.MethodGet, "/api/node-vulnerabilities/"+url.PathEscape(id)+"/details", nil, nil, out)
+}
+
+// GetNodeVulnerabilityOccurrences calls GET /api/node-vulnerabilities/{id}/occurrences: list the paths at which the package of a node vulnerability was found
+func (c *Client) GetNodeVulnerabilityOccurrences(ctx context.Context, id string, out interface{}) error {
+	return c.do(ctx, http.MethodGet, "/api/node-vulnerabilities/"+url.PathEscape(id)+"/occurrences", nil, nil, out)
+}
+
+// GetNodePackageDetails calls GET /api/node-packages/{id}/details: get the Syft details of a node package
+func (c *Client) GetNodePackageDetails(ctx context.Context, id string, out interface{}) error {
+	return c.do(ctx, http.MethodGet, "/api/node-packages/"+url.PathEscape(id)+"/details", nil, nil, out)
+}
+
+// ListNodeCVEsParams are the query parameters of ListNodeCVEs
+type ListNodeCVEsParams struct {
+	VulnStatuses  []string // Only these fix statuses
+	PackageTypes  []string // Only these package types
+	OSNames       []string // Only these OS distributions
+	Page          int      // Page number, starting at 1
+	PageSize      int      // Rows per page
+	SortBy        string   // Column to sort by
+	SortOrder     string   // Sort direction
+	Delimiter     string   // CSV field separator (comma, semicolon, tab, pipe or the character)
+	Columns       []string // CSV columns to export, in order
+	Decimal       string   // CSV decimal separator
+	BOM           bool     // Prefix CSV with a UTF-8 byte order mark
+	Tz            string   // IANA time zone of exported timestamps (default UTC)
+	Severity      []string // Only these severities
+	Vulnerability string   // Substring of the vulnerability ID
+	Format        string   // Response format
+}
+
+// ListNodeCVEs calls GET /api/node-cves: list vulnerabilities across nodes
+func (c *Client) ListNodeCVEs(ctx context.Context, params ListNodeCVEsParams, out interface{}) error {
+	q := url.Values{}
+	setList(q, "vulnStatuses", params.VulnStatuses)
+	setList(q, "packageTypes", params.PackageTypes)
+	setList(q, "osNames", params.OSNames)
+	setInt(q, "page", params.Page)
+	setInt(q, "pageSize", params.PageSize)
+	setString(q, "sortBy", params.SortBy)
+	setString(q, "sortOrder", params.SortOrder)
+	setString(q, "delimiter", params.Delimiter)
+	setList(q, "columns", params.Columns)
+	setString(q, "decimal", params.Decimal)
+	setBool(q, "bom", params.BOM)
+	setString(q, "tz", params.Tz)
+	setList(q, "severity", params.Severity)
+	setString(q, "vulnerability", params.Vulnerability)
+	setString(q, "format", params.Format)
+	return c.do(ctx, http.MethodGet, "/api/node-cves", q, nil, out)
+}
+
+// ListNodeCVEAffectedParams are the query parameters of ListNodeCVEAffected
+type ListNodeCVEAffectedParams struct {
+	CVE string // Vulnerability ID
+}
+
+// ListNodeCVEAffected calls GET /api/node-cves/affected: list the nodes affected by a vulnerability
+func (c *Client) ListNodeCVEAffected(ctx context.Context, params ListNodeCVEAffectedParams, out interface{}) error {
+	q := url.Values{}
+	setString(q, "cve", params.CVE)
+	return c.do(ctx, http.MethodGet, "/api/node-cves/affected", q, nil, out)
+}
+
+// ListNodeCVEDetailsParams are the query parameters of ListNodeCVEDetails
+type ListNodeCVEDetailsParams struct {
+	CVE string // Vulnerability ID
+}
+
+// ListNodeCVEDetails calls GET /api/node-cves/details: list the match variants of a node vulnerability
+func (c *Client) ListNodeCVEDetails(ctx context.Context, params ListNodeCVEDetailsParams, out interface{}) error {
+	q := url.Values{}
+	setString(q, "cve", params.CVE)
+	return c.do(ctx, http.MethodGet, "/api/node-cves/details", q, nil, out)
+}
+
+// SubmitScan calls POST /api/scan: scan an image reference on demand
+func (c *Client) SubmitScan(ctx context.Context, body interface{}, out interface{}) error {
+	return c.do(ctx, http.MethodPost, "/api/scan", nil, body, out)
+}
+
+// GetScan calls GET /api/scan/{digest}: get the state and results of an on-demand scan
+func (c *Client) GetScan(ctx context.Context, digest string, out interface{}) error {
+	return c.do(ctx, http.MethodGet, "/api/scan/"+url.PathEscape(digest), nil, nil, out)
+}
+
+// GetScanQueueStatus calls GET /api/scan-queue: get the scan queue depth, the scan in progress and its phase, and the estimated time to drain the queue
+func (c *Client) GetScanQueueStatus(ctx context.Context, out interface{}) error {
+	return c.do(ctx, http.MethodGet, "/api/scan-queue", nil, nil, out)
+}
+
+// ListDeadLetteredScans calls GET /api/scan-queue/dead-letter: list images that failed to scan too many times to be retried
+func (c *Client) ListDeadLetteredScans(ctx context.Context, out interface{}) error {
+	return c.do(ctx, http.MethodGet, "/api/scan-queue/dead-letter", nil, nil, out)
+}
+
+// RequeueDeadLetteredScans calls POST /api/scan-queue/dead-letter/requeue: requeue dead-lettered images
+func (c *Client) RequeueDeadLetteredScans(ctx context.Context, body interface{}, out interface{}) error {
+	return c.do(ctx, http.MethodPost, "/api/scan-queue/dead-letter/requeue", nil, body, out)
+}
+
+// ScanNow calls POST /api/scan-queue/scan-now: scan running images ahead of the rest of the scan queue
+func (c *Client) ScanNow(ctx context.Context, body interface{}, out interface{}) error {
+	return c.do(ctx, http.MethodPost, "/api/scan-queue/scan-now", nil, body, out)
+}
+
+// RescanImageParams are the query parameters of RescanImage
+type RescanImageParams struct {
+	Force bool // Regenerate the SBOM instead of rescanning the stored one
+}
+
+// RescanImage calls POST /api/images/{digest}/rescan: rescan a running image ahead of the rest of the scan queue
+func (c *Client) RescanImage(ctx context.Context, digest string, params RescanImageParams, out interface{}) error {
+	q := url.Values{}
+	setBool(q, "force", params.Force)
+	return c.do(ctx, http.MethodPost, "/api/images/"+url.PathEscape(digest)+"/rescan", q, nil, out)
+}
+
+// RescanAllImagesParams are the query parameters of RescanAllImages
+type RescanAllImagesParams struct {
+	Force   bool // Regenerate the SBOMs instead of rescanning the stored ones
+	Confirm bool // Start a rescan above the confirmation threshold (409 with the estimate otherwise)
+}
+
+// RescanAllImages calls POST /api/rescan-all: rescan every running image
+func (c *Client) RescanAllImages(ctx context.Context, params RescanAllImagesParams, out interface{}) error {
+	q := url.Values{}
+	setBool(q, "force", params.Force)
+	setBool(q, "confirm", params.Confirm)
+	return c.do(ctx, http.MethodPost, "/api/rescan-all", q, nil, out)
+}
+
+// GetRescanAllEstimateParams are the query parameters of GetRescanAllEstimate
+type GetRescanAllEstimateParams struct {
+	Force bool // Estimate regenerating the SBOMs too
+}
+
+// GetRescanAllEstimate calls GET /api/rescan-all/estimate: estimate the images, duration and completion of a rescan of every running image
+func (c *Client) GetRescanAllEstimate(ctx context.Context, params GetRescanAllEstimateParams, out interface{}) error {
+	q := url.Values{}
+	setBool(q, "force", params.Force)
+	return c.do(ctx, http.MethodGet, "/api/rescan-all/estimate", q, nil, out)
+}
+
+// ListRegistryImages calls GET /api/registry/images: list the images found by the registry crawl with their scan status
+func (c *Client) ListRegistryImages(ctx context.Context, out interface{}) error {
+	return c.do(ctx, http.MethodGet, "/api/registry/images", nil, nil, out)
+}
+
+// GetNotifyRoutes calls GET /api/notify/routes: list the alert destinations of namespaces with a bjorn2scan.io/notify annotation and the default
+func (c *Client) GetNotifyRoutes(ctx context.Context, out interface{}) error {
+	return c.do(ctx, http.MethodGet, "/api/notify/routes", nil, nil, out)
+}
+
+// ListScanFailures calls GET /api/scan-queue/failures: list failing images grouped by node and failure reason
+func (c *Client) ListScanFailures(ctx context.Context, out interface{}) error {
+	return c.do(ctx, http.MethodGet, "/api/scan-queue/failures", nil, nil, out)
+}
+
+// ExportImage calls GET /api/export/images/{digest}: export the scan results of an image as a signed bundle
+func (c *Client) ExportImage(ctx context.Context, digest string, out interface{}) error {
+	return c.do(ctx, http.MethodGet, "/api/export/images/"+url.PathEscape(digest), nil, nil, out)
+}
+
+// ImportImageParams are the query parameters of ImportImage
+type ImportImageParams struct {
+	Force bool // Replace complete scan data
+}
+
+// ImportImage calls POST /api/import: import a signed scan result bundle
+func (c *Client) ImportImage(ctx context.Context, params ImportImageParams, body interface{}, out interface{}) error {
+	q := url.Values{}
+	setBool(q, "force", params.Force)
+	return c.do(ctx, http.MethodPost, "/api/import", q, body, out)
+}
+
+// GetHealth calls GET /health: health check
+func (c *Client) GetHealth(ctx context.Context, out interface{}) error {
+	return c.do(ctx, http.MethodGet, "/health", nil, nil, out)
+}
+
+// GetReady calls GET /ready: readiness check
+func (c *Client) GetReady(ctx context.Context, out interface{}) error {
+	return c.do(ctx, http.MethodGet, "/ready", nil, nil, out)
+}
+
+// GetInfo calls GET /info: get deployment information
+func (c *Client) GetInfo(ctx context.Context, out interface{}) error {
+	return c.do(ctx, http.MethodGet, "/info", nil, nil, out)
+}
+
+// GetConfig calls GET /api/config: get the UI configuration and the effective runtime configuration (secrets redacted) with the source of each value
+func (c *Client) GetConfig(ctx context.Context, out interface{}) error {
+	return c.do(ctx, http.MethodGet, "/api/config", nil, nil, out)
+}
+
+// GetUIConfig calls GET /api/ui-config: get which web UI controls are enabled (hidden in read-only mode and from viewers)
+func (c *Client) GetUIConfig(ctx context.Context, out interface{}) error {
+	return c.do(ctx, http.MethodGet, "/api/ui-config", nil, nil, out)
+}
+
+// GetWhoAmI calls GET /api/auth/whoami: get the caller's identity and role (only registered with authentication enabled)
+func (c *Client) GetWhoAmI(ctx context.Context, out interface{}) error {
+	return c.do(ctx, http.MethodGet, "/api/auth/whoami", nil, nil, out)
+}
+
+// GetDatabaseStatus calls GET /api/db/status: get the vulnerability database status
+func (c *Client) GetDatabaseStatus(ctx context.Context, out interface{}) error {
+	return c.do(ctx, http.MethodGet, "/api/db/status", nil, nil, out)
+}
+
+// GetVulnDBStatus calls GET /api/vulndb: get the vulnerability database build date and schema version, the last feed check and the next scheduled check
+func (c *Client) GetVulnDBStatus(ctx context.Context, out interface{}) error {
+	return c.do(ctx, http.MethodGet, "/api/vulndb", nil, nil, out)
+}
+
+// RefreshVulnDB calls POST /api/vulndb/refresh: check the vulnerability database feed now and download a newer database (409 if a check is already running)
+func (c *Client) RefreshVulnDB(ctx context.Context, out interface{}) error {
+	return c.do(ctx, http.MethodPost, "/api/vulndb/refresh", nil, nil, out)
+}
+
+// GetScanHealth calls GET /api/status: get scan pipeline health and images stuck in intermediate states
+func (c *Client) GetScanHealth(ctx context.Context, out interface{}) error {
+	return c.do(ctx, http.MethodGet, "/api/status", nil, nil, out)
+}
+
+// GetMigrationStatus calls GET /api/status/migration: get the schema migration status
+func (c *Client) GetMigrationStatus(ctx context.Context, out interface{}) error {
+	return c.do(ctx, http.MethodGet, "/api/status/migration", nil, nil, out)
+}
+
+// GetCompatibility calls GET /api/status/compatibility: get the binary and schema versions, the oldest version a downgrade can go back to and whether migrations run on the next restart
+func (c *Client) GetCompatibility(ctx context.Context, out interface{}) error {
+	return c.do(ctx, http.MethodGet, "/api/status/compatibility", nil, nil, out)
+}
+
+// GetDiskUsage calls GET /api/status/disk: get data volume usage
+func (c *Client) GetDiskUsage(ctx context.Context, out interface{}) error {
+	return c.do(ctx, http.MethodGet, "/api/status/disk", nil, nil, out)
+}
+
+// PreviewGCParams are the query parameters of PreviewGC
+type PreviewGCParams struct {
+	Retention string // Soft-deleted image retention to preview, e.g. 168h (default: the configured retention)
+}
+
+// PreviewGC calls GET /api/gc/preview: list the images, packages, vulnerabilities and SBOM documents the purge job would remove on its next run
+func (c *Client) PreviewGC(ctx context.Context, params PreviewGCParams, out interface{}) error {
+	q := url.Values{}
+	setString(q, "retention", params.Retention)
+	return c.do(ctx, http.MethodGet, "/api/gc/preview", q, nil, out)
+}
+
+// ListJobs calls GET /api/jobs: list the scheduled jobs with their schedule, last run, last success, duration and consecutive failures
+func (c *Client) ListJobs(ctx context.Context, out interface{}) error {
+	return c.do(ctx, http.MethodGet, "/api/jobs", nil, nil, out)
+}
+
+// RunJob calls POST /api/jobs/{name}/run: run a scheduled job now (409 if it is already running)
+func (c *Client) RunJob(ctx context.Context, name string, out interface{}) error {
+	return c.do(ctx, http.MethodPost, "/api/jobs/"+url.PathEscape(name)+"/run", nil, nil, out)
+}
+
+// GetSchemaParams are the query parameters of GetSchema
+type GetSchemaParams struct {
+	Format string // Response format
+}
+
+// GetSchema calls GET /api/admin/schema: get the database schema
+func (c *Client) GetSchema(ctx context.Context, params GetSchemaParams, out interface{}) error {
+	q := url.Values{}
+	setString(q, "format", params.Format)
+	return c.do(ctx, http.MethodGet, "/api/admin/schema", q, nil, out)
+}
@@ -0,0 +1,64 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestClientListImages(t *testing.T) {
+	var gotPath, gotQuery string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath, gotQuery = r.URL.Path, r.URL.RawQuery
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"totalCount": 1}`))
+	}))
+	defer server.Close()
+
+	var resp struct {
+		TotalCount int `json:"totalCount"`
+	}
+	c := New(server.URL+"/", nil)
+	err := c.ListImages(context.Background(), ListImagesParams{
+		Namespaces: []string{"default", "shop"},
+		Page:       2,
+		BOM:        true,
+	}, &resp)
+	if err != nil {
+		t.Fatalf("ListImages() error = %v", err)
+	}
+	if gotPath != "/api/images" || gotQuery != "bom=true&namespaces=default%2Cshop&page=2" {
+		t.Errorf("unexpected request %s?%s", gotPath, gotQuery)
+	}
+	if resp.TotalCount != 1 {
+		t.Errorf("unexpected response %+v", resp)
+	}
+}
+
+func TestClientRawAndErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/images/sha256:abc/packages" {
+			http.Error(w, "Not found", http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write([]byte("name,version\n"))
+	}))
+	defer server.Close()
+	c := New(server.URL, server.Client())
+
+	var csv []byte
+	if err := c.ListImagePackages(context.Background(), "sha256:abc", ListImagePackagesParams{Format: "csv"}, &csv); err != nil {
+		t.Fatalf("ListImagePackages() error = %v", err)
+	}
+	if string(csv) != "name,version\n" {
+		t.Errorf("unexpected raw body %q", csv)
+	}
+
+	var apiErr *Error
+	err := c.GetNode(context.Background(), "node-1", nil)
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusNotFound {
+		t.Errorf("GetNode() error = %v, want 404 *Error", err)
+	}
+}
@@ -0,0 +1,152 @@
+// Command gen generates the operation methods of package client from
+// handlers.OpenAPIOperations.
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"go/format"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/bvboe/b2s-go/scanner-core/handlers"
+)
+
+// initialisms are parameter names rendered in upper case in Go field names
+var initialisms = map[string]string{
+	"bom":     "BOM",
+	"cve":     "CVE",
+	"id":      "ID",
+	"osNames": "OSNames",
+}
+
+var pathParamPattern = regexp.MustCompile(`\{([A-Za-z]+)\}`)
+
+// fieldName returns the exported Go name of a parameter
+func fieldName(param string) string {
+	if name, ok := initialisms[param]; ok {
+		return name
+	}
+	return strings.ToUpper(param[:1]) + param[1:]
+}
+
+// goType returns the Go type of a parameter type
+func goType(typ string) string {
+	switch typ {
+	case "integer":
+		return "int"
+	case "boolean":
+		return "bool"
+	case "list":
+		return "[]string"
+	default:
+		return "string"
+	}
+}
+
+// setter returns the query helper of a parameter type
+func setter(typ string) string {
+	switch typ {
+	case "integer":
+		return "setInt"
+	case "boolean":
+		return "setBool"
+	case "list":
+		return "setList"
+	default:
+		return "setString"
+	}
+}
+
+// pathExpr returns a Go expression building the path of op from its path
+// parameters
+func pathExpr(path string) string {
+	var parts []string
+	last := 0
+	for _, m := range pathParamPattern.FindAllStringSubmatchIndex(path, -1) {
+		parts = append(parts, fmt.Sprintf("%q", path[last:m[0]]))
+		parts = append(parts, fmt.Sprintf("url.PathEscape(%s)", path[m[2]:m[3]]))
+		last = m[1]
+	}
+	if last < len(path) {
+		parts = append(parts, fmt.Sprintf("%q", path[last:]))
+	}
+	return strings.Join(parts, " + ")
+}
+
+// generate renders the client methods of ops as formatted Go source
+func generate(ops []handlers.APIOperation) ([]byte, error) {
+	var b bytes.Buffer
+	b.WriteString("// Code generated by go run ./gen; DO NOT EDIT.\n\n")
+	b.WriteString("package client\n\n")
+	b.WriteString("import (\n\t\"context\"\n\t\"net/http\"\n\t\"net/url\"\n)\n")
+
+	for _, op := range ops {
+		var pathParams, queryParams []handlers.APIParam
+		for _, p := range op.Params {
+			if p.In == "path" {
+				pathParams = append(pathParams, p)
+			} else {
+				queryParams = append(queryParams, p)
+			}
+		}
+
+		if len(queryParams) > 0 {
+			fmt.Fprintf(&b, "\n// %sParams are the query parameters of %s\n", op.ID, op.ID)
+			fmt.Fprintf(&b, "type %sParams struct {\n", op.ID)
+			for _, p := range queryParams {
+				fmt.Fprintf(&b, "\t%s %s // %s\n", fieldName(p.Name), goType(p.Type), p.Description)
+			}
+			b.WriteString("}\n")
+		}
+
+		args := []string{"ctx context.Context"}
+		for _, p := range pathParams {
+			args = append(args, p.Name+" string")
+		}
+		if len(queryParams) > 0 {
+			args = append(args, fmt.Sprintf("params %sParams", op.ID))
+		}
+		if op.Body {
+			args = append(args, "body interface{}")
+		}
+		args = append(args, "out interface{}")
+
+		summary := strings.ToLower(op.Summary[:1]) + op.Summary[1:]
+		fmt.Fprintf(&b, "\n// %s calls %s %s: %s\n", op.ID, op.Method, op.Path, summary)
+		fmt.Fprintf(&b, "func (c *Client) %s(%s) error {\n", op.ID, strings.Join(args, ", "))
+		query := "nil"
+		if len(queryParams) > 0 {
+			query = "q"
+			b.WriteString("\tq := url.Values{}\n")
+			for _, p := range queryParams {
+				fmt.Fprintf(&b, "\t%s(q, %q, params.%s)\n", setter(p.Type), p.Name, fieldName(p.Name))
+			}
+		}
+		body := "nil"
+		if op.Body {
+			body = "body"
+		}
+		method := "http.Method" + strings.ToUpper(op.Method[:1]) + strings.ToLower(op.Method[1:])
+		fmt.Fprintf(&b, "\treturn c.do(ctx, %s, %s, %s, %s, out)\n}\n", method, pathExpr(op.Path), query, body)
+	}
+
+	return format.Source(b.Bytes())
+}
+
+func main() {
+	output := flag.String("o", "client_gen.go", "output file")
+	flag.Parse()
+
+	src, err := generate(handlers.OpenAPIOperations())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to generate client: %v\n", err)
+		os.Exit(1)
+	}
+	if err := os.WriteFile(*output, src, 0644); err != nil {
+		fmt.Fprintf(os.Stderr, "failed to write %s: %v\n", *output, err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"testing"
+
+	"github.com/bvboe/b2s-go/scanner-core/handlers"
+)
+
+// TestGeneratedClientUpToDate fails when the API catalog changed without
+// regenerating the client
+func TestGeneratedClientUpToDate(t *testing.T) {
+	want, err := generate(handlers.OpenAPIOperations())
+	if err != nil {
+		t.Fatalf("generate() error = %v", err)
+	}
+	got, err := os.ReadFile("../client_gen.go")
+	if err != nil {
+		t.Fatalf("failed to read generated client: %v", err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Error("client_gen.go is out of date, run go generate ./client/...")
+	}
+}
+
+func TestPathExpr(t *testing.T) {
+	tests := map[string]string{
+		"/api/images":                       `"/api/images"`,
+		"/api/images/{digest}":              `"/api/images/" + url.PathEscape(digest)`,
+		"/api/nodes/{name}/vulnerabilities": `"/api/nodes/" + url.PathEscape(name) + "/vulnerabilities"`,
+	}
+	for path, want := range tests {
+		if got := pathExpr(path); got != want {
+			t.Errorf("pathExpr(%q) = %s, want %s", path, got, want)
+		}
+	}
+}
@@ -0,0 +1,78 @@
+// Package cmdb pulls workload attributes (application ID, owner, data
+// classification) from an external CMDB. The CMDB serves the same document
+// it would push to POST /api/cmdb/sync:
+//
+//	{"entries": [
+//	  {"namespace": "payments", "application_id": "APP-1042", "owner": "team-payments", "data_classification": "confidential"},
+//	  {"label": "org.opencontainers.image.source", "label_value": "https://github.com/acme/ledger", "owner": "team-ledger"}
+//	]}
+//
+// Entries are keyed by namespace or by an image label and value (see
+// database.CMDBEntry); the attributes are used in image filters, reports and
+// policy selectors.
+package cmdb
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/bvboe/b2s-go/scanner-core/database"
+)
+
+// Source is the source name pulled entries are stored under
+const Source = "pull"
+
+// maxDocumentSize bounds the document read from the CMDB
+const maxDocumentSize = 16 << 20
+
+// Document is the JSON document a CMDB serves
+type Document struct {
+	Entries []database.CMDBEntry `json:"entries"`
+}
+
+// Fetcher reads the CMDB document from a URL
+type Fetcher struct {
+	url    string
+	token  string
+	client *http.Client
+}
+
+// NewFetcher creates a Fetcher reading url, sending token as a bearer token
+// if it is not empty
+func NewFetcher(url, token string) *Fetcher {
+	return &Fetcher{url: url, token: token, client: &http.Client{Timeout: 30 * time.Second}}
+}
+
+// Fetch reads and validates the CMDB document
+func (f *Fetcher) Fetch(ctx context.Context) ([]database.CMDBEntry, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, f.url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create CMDB request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	if f.token != "" {
+		req.Header.Set("Authorization", "Bearer "+f.token)
+	}
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch CMDB document: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch CMDB document: %s", resp.Status)
+	}
+
+	var doc Document
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxDocumentSize)).Decode(&doc); err != nil {
+		return nil, fmt.Errorf("failed to parse CMDB document: %w", err)
+	}
+	if err := database.ValidateCMDBEntries(doc.Entries); err != nil {
+		return nil, err
+	}
+	return doc.Entries, nil
+}
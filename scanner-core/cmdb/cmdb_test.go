@@ -0,0 +1,45 @@
+package cmdb
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestFetch(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		_, _ = w.Write([]byte(`{"entries": [
+			{"namespace": " payments ", "owner": "team-payments"},
+			{"label": "org.opencontainers.image.source", "label_value": "https://github.com/acme/ledger", "application_id": "APP-1042"}
+		]}`))
+	}))
+	defer server.Close()
+
+	entries, err := NewFetcher(server.URL, "secret").Fetch(context.Background())
+	if err != nil {
+		t.Fatalf("Fetch() error = %v", err)
+	}
+	if len(entries) != 2 || entries[0].Namespace != "payments" || entries[1].ApplicationID != "APP-1042" {
+		t.Errorf("Fetch() = %+v", entries)
+	}
+
+	if _, err := NewFetcher(server.URL, "wrong").Fetch(context.Background()); err == nil {
+		t.Error("Fetch() with a rejected token succeeded, want error")
+	}
+}
+
+func TestFetchRejectsInvalidEntries(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"entries": [{"label": "team", "label_value": "payments", "owner": "team-payments"}]}`))
+	}))
+	defer server.Close()
+
+	if _, err := NewFetcher(server.URL, "").Fetch(context.Background()); err == nil {
+		t.Error("Fetch() of an entry keyed by an unsupported label succeeded, want error")
+	}
+}
@@ -0,0 +1,277 @@
+// Package columns defines computed columns: named arithmetic expressions over
+// the numeric columns of the image and container lists, e.g.
+// "weighted_score=critical*10+high*3", configured by an administrator.
+//
+// A computed column is compiled to SQL and added to the list query, so it
+// appears in the JSON rows and CSV exports of /api/images and /api/containers
+// and can be sorted on like any other column. Expressions may use numbers,
+// column names, + - * / and parentheses; a severity on its own (critical,
+// high, ...) stands for its count column. Division is real division and
+// yields null when dividing by zero.
+package columns
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// namePattern is the syntax of column names
+var namePattern = regexp.MustCompile(`^[a-z_][a-z0-9_]*$`)
+
+// Numeric lists the columns expressions may use. Which ones a list provides
+// is up to the list: container_count, image_size and layer_count are only
+// known per image.
+var Numeric = []string{
+	"critical_count", "high_count", "medium_count", "low_count", "negligible_count", "unknown_count",
+	"total_cves", "unique_cves", "total_risk", "exploit_count", "package_count",
+	"container_count", "image_size", "layer_count",
+}
+
+// Column is a computed column
+type Column struct {
+	Name       string // result column name
+	Expression string // expression as configured
+	root       node
+}
+
+// Parse parses a comma-separated list of name=expression pairs, e.g.
+// "weighted_score=critical*10+high*3,fixable_share=...". Names must be lower
+// case identifiers that do not shadow a numeric column. An empty spec returns
+// no columns.
+func Parse(spec string) ([]Column, error) {
+	if strings.TrimSpace(spec) == "" {
+		return nil, nil
+	}
+	var cols []Column
+	seen := map[string]bool{}
+	for _, pair := range strings.Split(spec, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		name, expr, ok := strings.Cut(pair, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid computed column %q: expected name=expression", pair)
+		}
+		name, expr = strings.TrimSpace(name), strings.TrimSpace(expr)
+		if !namePattern.MatchString(name) {
+			return nil, fmt.Errorf("invalid computed column name %q: must be a lower case identifier", name)
+		}
+		if resolveName(name) != "" {
+			return nil, fmt.Errorf("invalid computed column name %q: shadows a column", name)
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("computed column %q is defined more than once", name)
+		}
+		root, err := parseExpression(expr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid computed column %s: %w", name, err)
+		}
+		seen[name] = true
+		cols = append(cols, Column{Name: name, Expression: expr, root: root})
+	}
+	return cols, nil
+}
+
+// SQL returns the column's expression in SQL, with each column replaced by
+// its SQL expression from columns. Reports false if the expression uses a
+// column missing from columns.
+func (c Column) SQL(columns map[string]string) (string, bool) {
+	if c.root == nil {
+		return "", false
+	}
+	return c.root.sql(columns)
+}
+
+// resolveName returns the numeric column an identifier names ("critical" for
+// critical_count), or "" if it names none
+func resolveName(ident string) string {
+	for _, col := range Numeric {
+		if ident == col || ident+"_count" == col {
+			return col
+		}
+	}
+	return ""
+}
+
+// node is a parsed expression
+type node interface {
+	sql(columns map[string]string) (string, bool)
+}
+
+type number string
+
+func (n number) sql(map[string]string) (string, bool) { return string(n), true }
+
+type column string
+
+func (c column) sql(columns map[string]string) (string, bool) {
+	expr, ok := columns[string(c)]
+	return expr, ok
+}
+
+type negate struct{ operand node }
+
+func (n negate) sql(columns map[string]string) (string, bool) {
+	operand, ok := n.operand.sql(columns)
+	return "(-" + operand + ")", ok
+}
+
+type binary struct {
+	op          byte
+	left, right node
+}
+
+func (b binary) sql(columns map[string]string) (string, bool) {
+	left, okLeft := b.left.sql(columns)
+	right, okRight := b.right.sql(columns)
+	if !okLeft || !okRight {
+		return "", false
+	}
+	if b.op == '/' {
+		// Real division, null instead of an error when dividing by zero
+		return "(" + left + " * 1.0 / NULLIF(" + right + ", 0))", true
+	}
+	return "(" + left + " " + string(b.op) + " " + right + ")", true
+}
+
+// parser is a recursive descent parser of expressions:
+//
+//	expr   = term { ("+" | "-") term }
+//	term   = factor { ("*" | "/") factor }
+//	factor = number | identifier | "(" expr ")" | "-" factor
+type parser struct {
+	tokens []string
+	pos    int
+}
+
+// parseExpression parses an expression, checking its identifiers
+func parseExpression(expr string) (node, error) {
+	tokens, err := tokenize(expr)
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens) == 0 {
+		return nil, fmt.Errorf("empty expression")
+	}
+	p := &parser{tokens: tokens}
+	root, err := p.expr()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.tokens) {
+		return nil, fmt.Errorf("unexpected %q", p.tokens[p.pos])
+	}
+	return root, nil
+}
+
+func (p *parser) peek() string {
+	if p.pos < len(p.tokens) {
+		return p.tokens[p.pos]
+	}
+	return ""
+}
+
+func (p *parser) expr() (node, error) {
+	left, err := p.term()
+	if err != nil {
+		return nil, err
+	}
+	for op := p.peek(); op == "+" || op == "-"; op = p.peek() {
+		p.pos++
+		right, err := p.term()
+		if err != nil {
+			return nil, err
+		}
+		left = binary{op[0], left, right}
+	}
+	return left, nil
+}
+
+func (p *parser) term() (node, error) {
+	left, err := p.factor()
+	if err != nil {
+		return nil, err
+	}
+	for op := p.peek(); op == "*" || op == "/"; op = p.peek() {
+		p.pos++
+		right, err := p.factor()
+		if err != nil {
+			return nil, err
+		}
+		left = binary{op[0], left, right}
+	}
+	return left, nil
+}
+
+func (p *parser) factor() (node, error) {
+	tok := p.peek()
+	p.pos++
+	switch {
+	case tok == "":
+		return nil, fmt.Errorf("unexpected end of expression")
+	case tok == "-":
+		operand, err := p.factor()
+		if err != nil {
+			return nil, err
+		}
+		return negate{operand}, nil
+	case tok == "(":
+		inner, err := p.expr()
+		if err != nil {
+			return nil, err
+		}
+		if p.peek() != ")" {
+			return nil, fmt.Errorf("missing )")
+		}
+		p.pos++
+		return inner, nil
+	case tok[0] >= '0' && tok[0] <= '9' || tok[0] == '.':
+		if _, err := strconv.ParseFloat(tok, 64); err != nil {
+			return nil, fmt.Errorf("invalid number %q", tok)
+		}
+		return number(tok), nil
+	case namePattern.MatchString(tok):
+		col := resolveName(tok)
+		if col == "" {
+			return nil, fmt.Errorf("unknown column %q (known: %s)", tok, strings.Join(Numeric, ", "))
+		}
+		return column(col), nil
+	}
+	return nil, fmt.Errorf("unexpected %q", tok)
+}
+
+// tokenize splits an expression into numbers, identifiers and operators
+func tokenize(expr string) ([]string, error) {
+	var tokens []string
+	for i := 0; i < len(expr); {
+		c := expr[i]
+		switch {
+		case c == ' ' || c == '\t':
+			i++
+		case strings.IndexByte("+-*/()", c) >= 0:
+			tokens = append(tokens, string(c))
+			i++
+		case c >= '0' && c <= '9' || c == '.':
+			j := i
+			for j < len(expr) && (expr[j] >= '0' && expr[j] <= '9' || expr[j] == '.') {
+				j++
+			}
+			tokens = append(tokens, expr[i:j])
+			i = j
+		case c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c == '_':
+			j := i
+			for j < len(expr) && (expr[j] >= 'a' && expr[j] <= 'z' || expr[j] >= 'A' && expr[j] <= 'Z' ||
+				expr[j] >= '0' && expr[j] <= '9' || expr[j] == '_') {
+				j++
+			}
+			tokens = append(tokens, strings.ToLower(expr[i:j]))
+			i = j
+		default:
+			return nil, fmt.Errorf("unexpected character %q", c)
+		}
+	}
+	return tokens, nil
+}
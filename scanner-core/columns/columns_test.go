@@ -0,0 +1,61 @@
+package columns
+
+import "testing"
+
+func TestParse(t *testing.T) {
+	cols, err := Parse("weighted_score = critical*10 + HIGH*3, fixable_share=(total_cves - low) / container_count, neg=-exploit_count")
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if len(cols) != 3 || cols[0].Name != "weighted_score" || cols[0].Expression != "critical*10 + HIGH*3" {
+		t.Fatalf("Parse() = %+v", cols)
+	}
+
+	sqlOf := map[string]string{
+		"critical_count": "c", "high_count": "h", "low_count": "l", "total_cves": "t", "exploit_count": "e",
+	}
+	tests := []struct {
+		col  Column
+		want string
+		ok   bool
+	}{
+		{cols[0], "((c * 10) + (h * 3))", true},
+		{cols[1], "", false}, // container_count is not in the list
+		{cols[2], "(-e)", true},
+	}
+	for _, tt := range tests {
+		got, ok := tt.col.SQL(sqlOf)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("%s.SQL() = %q, %v, want %q, %v", tt.col.Name, got, ok, tt.want, tt.ok)
+		}
+	}
+	sqlOf["container_count"] = "n"
+	if got, _ := cols[1].SQL(sqlOf); got != "((t - l) * 1.0 / NULLIF(n, 0))" {
+		t.Errorf("division SQL = %q", got)
+	}
+
+	if cols, err := Parse("  "); err != nil || cols != nil {
+		t.Errorf("Parse(empty) = %v, %v", cols, err)
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	for _, spec := range []string{
+		"score",                    // no expression
+		"Score=critical",           // upper case name
+		"critical=high*2",          // shadows a column
+		"a=critical,a=high",        // duplicate
+		"a=",                       // empty expression
+		"a=critical*",              // dangling operator
+		"a=(critical+high",         // unbalanced
+		"a=critical high",          // missing operator
+		"a=secret_column",          // unknown column
+		"a=critical; DROP TABLE x", // not an expression
+		"a=1.2.3",                  // bad number
+		"a=critical_count % 2",     // unsupported operator
+	} {
+		if _, err := Parse(spec); err == nil {
+			t.Errorf("Parse(%q) succeeded, want an error", spec)
+		}
+	}
+}
@@ -3,7 +3,9 @@ package config
 import (
 	"os"
 	"path/filepath"
+	"reflect"
 	"testing"
+	"time"
 )
 
 func TestDefaultConfig(t *testing.T) {
@@ -22,6 +24,15 @@ func TestDefaultConfig(t *testing.T) {
 	}
 }
 
+func TestDefaultIgnoresEnvironment(t *testing.T) {
+	t.Setenv("PORT", "1234")
+
+	cfg := Default()
+	if cfg.Port != "9999" {
+		t.Errorf("Expected Default() to ignore PORT, got %s", cfg.Port)
+	}
+}
+
 func TestLoadConfigFromFile(t *testing.T) {
 	// Create temporary config file
 	tmpDir := t.TempDir()
@@ -582,3 +593,508 @@ func TestParseCommaSeparated(t *testing.T) {
 		})
 	}
 }
+
+func TestResultCacheConfig(t *testing.T) {
+	tmpDir := t.TempDir()
+	configPath := filepath.Join(tmpDir, "test.conf")
+
+	configContent := `transfer_signing_key=file-secret
+result_cache_backend=s3
+result_cache_s3_bucket=scan-results
+result_cache_s3_prefix=bjorn2scan
+result_cache_s3_region=eu-north-1
+`
+	if err := os.WriteFile(configPath, []byte(configContent), 0644); err != nil {
+		t.Fatalf("Failed to create test config file: %v", err)
+	}
+
+	t.Setenv("TRANSFER_SIGNING_KEY", "env-secret")
+	t.Setenv("RESULT_CACHE_S3_ENDPOINT", "https://minio.example:9000")
+
+	cfg, err := LoadConfig(configPath)
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+
+	if cfg.TransferSigningKey != "env-secret" {
+		t.Errorf("TransferSigningKey = %q, want env override", cfg.TransferSigningKey)
+	}
+	if cfg.ResultCacheBackend != "s3" || cfg.ResultCacheS3Bucket != "scan-results" || cfg.ResultCacheS3Prefix != "bjorn2scan" {
+		t.Errorf("unexpected S3 cache config: backend=%q bucket=%q prefix=%q",
+			cfg.ResultCacheBackend, cfg.ResultCacheS3Bucket, cfg.ResultCacheS3Prefix)
+	}
+	if cfg.ResultCacheS3Region != "eu-north-1" {
+		t.Errorf("ResultCacheS3Region = %q, want eu-north-1", cfg.ResultCacheS3Region)
+	}
+	if cfg.ResultCacheS3Endpoint != "https://minio.example:9000" {
+		t.Errorf("ResultCacheS3Endpoint = %q, want env value", cfg.ResultCacheS3Endpoint)
+	}
+}
+
+func TestContainerRuntimeConfig(t *testing.T) {
+	if cfg := defaultConfig(); cfg.ContainerRuntime != "auto" || cfg.ContainerdSocket != "" || cfg.ContainerdNamespaces != nil || cfg.PodmanSocket != "" {
+		t.Errorf("unexpected runtime defaults: runtime=%q socket=%q namespaces=%v podman=%q",
+			cfg.ContainerRuntime, cfg.ContainerdSocket, cfg.ContainerdNamespaces, cfg.PodmanSocket)
+	}
+
+	tmpDir := t.TempDir()
+	configPath := filepath.Join(tmpDir, "test.conf")
+
+	configContent := `container_runtime=Containerd
+containerd_socket=/run/containerd/containerd.sock
+containerd_namespaces=default
+podman_socket=/run/podman/podman.sock
+`
+	if err := os.WriteFile(configPath, []byte(configContent), 0644); err != nil {
+		t.Fatalf("Failed to create test config file: %v", err)
+	}
+
+	t.Setenv("CONTAINERD_NAMESPACES", "default, buildkit")
+	t.Setenv("PODMAN_SOCKET", "/run/user/1000/podman/podman.sock")
+
+	cfg, err := LoadConfig(configPath)
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+
+	if cfg.ContainerRuntime != "containerd" {
+		t.Errorf("ContainerRuntime = %q, want containerd", cfg.ContainerRuntime)
+	}
+	if cfg.ContainerdSocket != "/run/containerd/containerd.sock" {
+		t.Errorf("ContainerdSocket = %q, want file value", cfg.ContainerdSocket)
+	}
+	if len(cfg.ContainerdNamespaces) != 2 || cfg.ContainerdNamespaces[0] != "default" || cfg.ContainerdNamespaces[1] != "buildkit" {
+		t.Errorf("ContainerdNamespaces = %v, want env override", cfg.ContainerdNamespaces)
+	}
+	if cfg.PodmanSocket != "/run/user/1000/podman/podman.sock" {
+		t.Errorf("PodmanSocket = %q, want env override", cfg.PodmanSocket)
+	}
+}
+
+func TestRegistryCrawlConfig(t *testing.T) {
+	tmpDir := t.TempDir()
+	configPath := filepath.Join(tmpDir, "test.conf")
+
+	configContent := `registry_crawl_enabled=true
+registry_crawl_repositories=registry.example.com/golden/*, ghcr.io/org/base
+registry_crawl_tags=v*,latest
+registry_crawl_max_tags_per_repository=3
+registry_crawl_max_images=bad
+`
+	if err := os.WriteFile(configPath, []byte(configContent), 0644); err != nil {
+		t.Fatalf("Failed to create test config file: %v", err)
+	}
+
+	t.Setenv("REGISTRY_CRAWL_INTERVAL", "6h")
+	t.Setenv("REGISTRY_CRAWL_MAX_SCANS_PER_RUN", "5")
+
+	cfg, err := LoadConfig(configPath)
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+
+	if !cfg.RegistryCrawlEnabled || cfg.RegistryCrawlInterval != 6*time.Hour {
+		t.Errorf("RegistryCrawlEnabled = %v, RegistryCrawlInterval = %v", cfg.RegistryCrawlEnabled, cfg.RegistryCrawlInterval)
+	}
+	if len(cfg.RegistryCrawlRepositories) != 2 || cfg.RegistryCrawlRepositories[1] != "ghcr.io/org/base" {
+		t.Errorf("RegistryCrawlRepositories = %v", cfg.RegistryCrawlRepositories)
+	}
+	if len(cfg.RegistryCrawlTags) != 2 || cfg.RegistryCrawlTags[0] != "v*" {
+		t.Errorf("RegistryCrawlTags = %v", cfg.RegistryCrawlTags)
+	}
+	if cfg.RegistryCrawlMaxTagsPerRepository != 3 || cfg.RegistryCrawlMaxScansPerRun != 5 {
+		t.Errorf("quotas: max tags = %d, max scans = %d", cfg.RegistryCrawlMaxTagsPerRepository, cfg.RegistryCrawlMaxScansPerRun)
+	}
+	// Invalid values keep the default
+	if cfg.RegistryCrawlMaxImages != 200 {
+		t.Errorf("RegistryCrawlMaxImages = %d, want default 200", cfg.RegistryCrawlMaxImages)
+	}
+}
+
+func TestSettings(t *testing.T) {
+	tmpDir := t.TempDir()
+	configPath := filepath.Join(tmpDir, "test.conf")
+
+	configContent := `port=9090
+scan_coverage_lookback=2h
+notify_slack_webhook_url=https://hooks.slack.example/secret
+notify_namespaces=prod
+notify_webhook_allowed_hosts=hooks.example.com, *.corp.example
+`
+	if err := os.WriteFile(configPath, []byte(configContent), 0644); err != nil {
+		t.Fatalf("Failed to create test config file: %v", err)
+	}
+
+	t.Setenv("PORT", "7070")
+	t.Setenv("NOTIFY_NAMESPACES", "")
+	t.Setenv("METRICS_NODE_SCANNED_ENABLED", "false")
+
+	cfg, err := LoadConfig(configPath)
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	if cfg.File() != configPath {
+		t.Errorf("File() = %q, want %q", cfg.File(), configPath)
+	}
+
+	settings := make(map[string]Setting)
+	for _, s := range cfg.Settings() {
+		settings[s.Name] = s
+	}
+	tests := []struct {
+		name   string
+		value  any
+		source Source
+	}{
+		{"port", "7070", SourceEnv},
+		{"scan_coverage_lookback", "2h0m0s", SourceFile},
+		{"notify_slack_webhook_url", "[redacted]", SourceFile},
+		{"notify_namespaces", []string{}, SourceEnv}, // an empty value clears the file's list
+		{"notify_webhook_allowed_hosts", []string{"hooks.example.com", "*.corp.example"}, SourceFile},
+		{"metrics_node_scanned_enabled", false, SourceEnv},
+		{"db_path", cfg.DBPath, SourceDefault},
+		{"transfer_signing_key", "", SourceDefault},
+	}
+	for _, tt := range tests {
+		got, ok := settings[tt.name]
+		if !ok {
+			t.Errorf("setting %q missing", tt.name)
+			continue
+		}
+		if !reflect.DeepEqual(got.Value, tt.value) || got.Source != tt.source {
+			t.Errorf("setting %q = %v from %s, want %v from %s", tt.name, got.Value, got.Source, tt.value, tt.source)
+		}
+	}
+}
+
+func TestSettingsCoverEveryField(t *testing.T) {
+	// New options need an ini and/or env tag to show up in Settings
+	configType := reflect.TypeOf(Config{})
+	for i := 0; i < configType.NumField(); i++ {
+		field := configType.Field(i)
+		if field.IsExported() && field.Tag.Get("ini") == "" && field.Tag.Get("env") == "" {
+			t.Errorf("Config.%s has no ini or env tag", field.Name)
+		}
+	}
+}
+
+func TestScanPriorityNamespacesConfig(t *testing.T) {
+	if cfg := defaultConfig(); cfg.ScanPriorityNamespaces != nil {
+		t.Errorf("ScanPriorityNamespaces = %v, want none by default", cfg.ScanPriorityNamespaces)
+	}
+
+	t.Setenv("SCAN_PRIORITY_NAMESPACES", "production, payments")
+	cfg, err := LoadConfig("")
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	if !reflect.DeepEqual(cfg.ScanPriorityNamespaces, []string{"production", "payments"}) {
+		t.Errorf("ScanPriorityNamespaces = %v", cfg.ScanPriorityNamespaces)
+	}
+}
+
+func TestKubeAPIRateLimitConfig(t *testing.T) {
+	if cfg := defaultConfig(); cfg.KubeAPIQPS != 5 || cfg.KubeAPIBurst != 10 {
+		t.Errorf("unexpected defaults: qps=%v burst=%d", cfg.KubeAPIQPS, cfg.KubeAPIBurst)
+	}
+
+	configPath := filepath.Join(t.TempDir(), "test.conf")
+	if err := os.WriteFile(configPath, []byte("kube_api_qps=2.5\nkube_api_burst=0\n"), 0644); err != nil {
+		t.Fatalf("Failed to create test config file: %v", err)
+	}
+	t.Setenv("KUBE_API_BURST", "20")
+
+	cfg, err := LoadConfig(configPath)
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	if cfg.KubeAPIQPS != 2.5 {
+		t.Errorf("KubeAPIQPS = %v, want file value", cfg.KubeAPIQPS)
+	}
+	if cfg.KubeAPIBurst != 20 {
+		t.Errorf("KubeAPIBurst = %d, want env override", cfg.KubeAPIBurst)
+	}
+}
+
+func TestPostgresExportConfig(t *testing.T) {
+	if cfg := defaultConfig(); cfg.PostgresExportDSN != "" || cfg.PostgresExportInterval != 15*time.Minute {
+		t.Errorf("unexpected defaults: dsn=%q interval=%v", cfg.PostgresExportDSN, cfg.PostgresExportInterval)
+	}
+
+	configPath := filepath.Join(t.TempDir(), "test.conf")
+	if err := os.WriteFile(configPath, []byte("postgres_export_dsn=postgres://file@db/b2s\npostgres_export_interval=1h\n"), 0644); err != nil {
+		t.Fatalf("Failed to create test config file: %v", err)
+	}
+	t.Setenv("POSTGRES_EXPORT_DSN", "postgres://env@db/b2s")
+
+	cfg, err := LoadConfig(configPath)
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	if cfg.PostgresExportDSN != "postgres://env@db/b2s" {
+		t.Errorf("PostgresExportDSN = %q, want env override", cfg.PostgresExportDSN)
+	}
+	if cfg.PostgresExportInterval != time.Hour {
+		t.Errorf("PostgresExportInterval = %v, want file value", cfg.PostgresExportInterval)
+	}
+}
+
+func TestCMDBSyncConfig(t *testing.T) {
+	if cfg := defaultConfig(); cfg.CMDBSyncURL != "" || cfg.CMDBSyncInterval != time.Hour {
+		t.Errorf("unexpected defaults: url=%q interval=%v", cfg.CMDBSyncURL, cfg.CMDBSyncInterval)
+	}
+
+	configPath := filepath.Join(t.TempDir(), "test.conf")
+	if err := os.WriteFile(configPath, []byte("cmdb_sync_url=https://cmdb.example.com/file\ncmdb_sync_interval=15m\n"), 0644); err != nil {
+		t.Fatalf("Failed to create test config file: %v", err)
+	}
+	t.Setenv("CMDB_SYNC_URL", "https://cmdb.example.com/env")
+	t.Setenv("CMDB_SYNC_TOKEN", "secret")
+
+	cfg, err := LoadConfig(configPath)
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	if cfg.CMDBSyncURL != "https://cmdb.example.com/env" || cfg.CMDBSyncToken != "secret" {
+		t.Errorf("CMDBSyncURL = %q, CMDBSyncToken = %q, want env overrides", cfg.CMDBSyncURL, cfg.CMDBSyncToken)
+	}
+	if cfg.CMDBSyncInterval != 15*time.Minute {
+		t.Errorf("CMDBSyncInterval = %v, want file value", cfg.CMDBSyncInterval)
+	}
+}
+
+func TestHAConfig(t *testing.T) {
+	if cfg := defaultConfig(); cfg.HAEnabled || cfg.HALeaseName != "bjorn2scan-scan-server" || cfg.HALeaseDuration != 15*time.Second {
+		t.Errorf("unexpected defaults: enabled=%v lease=%q duration=%v", cfg.HAEnabled, cfg.HALeaseName, cfg.HALeaseDuration)
+	}
+
+	configPath := filepath.Join(t.TempDir(), "test.conf")
+	if err := os.WriteFile(configPath, []byte("ha_enabled=true\nha_lease_name=scan-server\nha_lease_duration=0s\n"), 0644); err != nil {
+		t.Fatalf("Failed to create test config file: %v", err)
+	}
+	t.Setenv("HA_LEASE_DURATION", "30s")
+
+	cfg, err := LoadConfig(configPath)
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	if !cfg.HAEnabled || cfg.HALeaseName != "scan-server" {
+		t.Errorf("HAEnabled = %v, HALeaseName = %q, want file values", cfg.HAEnabled, cfg.HALeaseName)
+	}
+	if cfg.HALeaseDuration != 30*time.Second {
+		t.Errorf("HALeaseDuration = %v, want env override", cfg.HALeaseDuration)
+	}
+}
+
+func TestRescanConfirmThresholdConfig(t *testing.T) {
+	if cfg := defaultConfig(); cfg.RescanConfirmThreshold != 500 {
+		t.Errorf("RescanConfirmThreshold default = %d, want 500", cfg.RescanConfirmThreshold)
+	}
+
+	t.Setenv("RESCAN_CONFIRM_THRESHOLD", "0")
+	cfg, err := LoadConfig("")
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	if cfg.RescanConfirmThreshold != 0 {
+		t.Errorf("RescanConfirmThreshold = %d, want 0 from env", cfg.RescanConfirmThreshold)
+	}
+}
+
+func TestVEXPathConfig(t *testing.T) {
+	if cfg := defaultConfig(); cfg.VEXPath != "" {
+		t.Errorf("VEXPath default = %q, want empty", cfg.VEXPath)
+	}
+
+	t.Setenv("VEX_PATH", "/etc/bjorn2scan/vex")
+	cfg, err := LoadConfig("")
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	if cfg.VEXPath != "/etc/bjorn2scan/vex" {
+		t.Errorf("VEXPath = %q, want env value", cfg.VEXPath)
+	}
+}
+
+func TestVendorSBOMConfig(t *testing.T) {
+	if cfg := defaultConfig(); len(cfg.VendorSBOMImages) != 0 || cfg.VendorSBOMPublicKeys != "" {
+		t.Errorf("vendor SBOM defaults = %v, %q, want none", cfg.VendorSBOMImages, cfg.VendorSBOMPublicKeys)
+	}
+
+	configPath := filepath.Join(t.TempDir(), "test.conf")
+	if err := os.WriteFile(configPath, []byte("vendor_sbom_images=registry.vendor.com/product, ghcr.io/acme/*\nvendor_sbom_public_keys=/etc/keys.pem\n"), 0644); err != nil {
+		t.Fatalf("Failed to create test config file: %v", err)
+	}
+	t.Setenv("VENDOR_SBOM_PUBLIC_KEYS", "/etc/bjorn2scan/vendor-sbom/keys.pem")
+	cfg, err := LoadConfig(configPath)
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	if len(cfg.VendorSBOMImages) != 2 || cfg.VendorSBOMImages[1] != "ghcr.io/acme/*" {
+		t.Errorf("VendorSBOMImages = %v", cfg.VendorSBOMImages)
+	}
+	if cfg.VendorSBOMPublicKeys != "/etc/bjorn2scan/vendor-sbom/keys.pem" {
+		t.Errorf("VendorSBOMPublicKeys = %q, want env value", cfg.VendorSBOMPublicKeys)
+	}
+}
+
+func TestSBOMPathRedactionConfig(t *testing.T) {
+	if cfg := defaultConfig(); cfg.SBOMPathRedaction != "" {
+		t.Errorf("SBOMPathRedaction default = %q, want paths kept", cfg.SBOMPathRedaction)
+	}
+
+	configPath := filepath.Join(t.TempDir(), "test.conf")
+	if err := os.WriteFile(configPath, []byte("sbom_path_redaction=strip\n"), 0644); err != nil {
+		t.Fatalf("Failed to create test config file: %v", err)
+	}
+	cfg, err := LoadConfig(configPath)
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	if cfg.SBOMPathRedaction != "strip" {
+		t.Errorf("SBOMPathRedaction = %q, want file value", cfg.SBOMPathRedaction)
+	}
+
+	t.Setenv("SBOM_PATH_REDACTION", "hash")
+	if cfg, err = LoadConfig(configPath); err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	if cfg.SBOMPathRedaction != "hash" {
+		t.Errorf("SBOMPathRedaction = %q, want env value", cfg.SBOMPathRedaction)
+	}
+}
+
+func TestTLSConfig(t *testing.T) {
+	if cfg := defaultConfig(); cfg.TLSCertFile != "" || cfg.PodScannerTLSCAFile != "" || cfg.PodScannerTLSServerName != "pod-scanner" {
+		t.Errorf("TLS defaults = %q, %q, %q", cfg.TLSCertFile, cfg.PodScannerTLSCAFile, cfg.PodScannerTLSServerName)
+	}
+
+	configPath := filepath.Join(t.TempDir(), "test.conf")
+	if err := os.WriteFile(configPath, []byte("tls_cert_file=/etc/tls/tls.crt\ntls_key_file=/etc/tls/tls.key\ntls_client_ca_file=/etc/tls/ca.crt\npod_scanner_tls_ca_file=/etc/tls/ca.crt\npod_scanner_grpc_port=9090\n"), 0644); err != nil {
+		t.Fatalf("Failed to create test config file: %v", err)
+	}
+	t.Setenv("POD_SCANNER_TLS_SERVER_NAME", "scanner.bjorn2scan.svc")
+	t.Setenv("POD_SCANNER_AUTH_SECRET", "s3cret")
+	cfg, err := LoadConfig(configPath)
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	if cfg.TLSCertFile != "/etc/tls/tls.crt" || cfg.TLSKeyFile != "/etc/tls/tls.key" || cfg.TLSClientCAFile != "/etc/tls/ca.crt" {
+		t.Errorf("server TLS files = %q, %q, %q", cfg.TLSCertFile, cfg.TLSKeyFile, cfg.TLSClientCAFile)
+	}
+	if cfg.PodScannerTLSCAFile != "/etc/tls/ca.crt" || cfg.PodScannerTLSServerName != "scanner.bjorn2scan.svc" {
+		t.Errorf("pod-scanner TLS = %q, %q", cfg.PodScannerTLSCAFile, cfg.PodScannerTLSServerName)
+	}
+	if cfg.PodScannerAuthSecret != "s3cret" {
+		t.Errorf("PodScannerAuthSecret = %q, want env value", cfg.PodScannerAuthSecret)
+	}
+	if cfg.PodScannerGRPCPort != "9090" {
+		t.Errorf("PodScannerGRPCPort = %q, want 9090", cfg.PodScannerGRPCPort)
+	}
+}
+
+func TestComputedColumnsConfig(t *testing.T) {
+	if cfg := defaultConfig(); cfg.ComputedColumns != "" {
+		t.Errorf("ComputedColumns default = %q, want empty", cfg.ComputedColumns)
+	}
+
+	configPath := filepath.Join(t.TempDir(), "test.conf")
+	if err := os.WriteFile(configPath, []byte("computed_columns=weighted_score=critical*10+high*3\n"), 0644); err != nil {
+		t.Fatalf("Failed to create test config file: %v", err)
+	}
+	cfg, err := LoadConfig(configPath)
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	if cfg.ComputedColumns != "weighted_score=critical*10+high*3" {
+		t.Errorf("ComputedColumns = %q, want file value", cfg.ComputedColumns)
+	}
+
+	t.Setenv("COMPUTED_COLUMNS", "")
+	if cfg, err = LoadConfig(configPath); err != nil || cfg.ComputedColumns != "" {
+		t.Errorf("ComputedColumns = %q, %v, want the empty env value to win", cfg.ComputedColumns, err)
+	}
+}
+
+func TestAuthConfig(t *testing.T) {
+	cfg := defaultConfig()
+	if cfg.AuthTokens != "" || cfg.AuthOIDCIssuerURL != "" || cfg.AuthOIDCGroupsClaim != "groups" ||
+		!reflect.DeepEqual(cfg.AuthOIDCScopes, []string{"openid", "email", "profile"}) {
+		t.Errorf("unexpected defaults: %+v", cfg)
+	}
+
+	configPath := filepath.Join(t.TempDir(), "test.conf")
+	if err := os.WriteFile(configPath, []byte("auth_tokens=admin=s3cret\n"+
+		"auth_oidc_issuer_url=https://idp.example.com\nauth_oidc_client_id=bjorn2scan\n"+
+		"auth_oidc_admin_groups=platform, security\nauth_oidc_groups_claim=\n"), 0644); err != nil {
+		t.Fatalf("Failed to create test config file: %v", err)
+	}
+	t.Setenv("AUTH_OIDC_VIEWER_GROUPS", "developers")
+	t.Setenv("AUTH_OIDC_SCOPES", "openid,groups")
+
+	cfg, err := LoadConfig(configPath)
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	if cfg.AuthTokens != "admin=s3cret" || cfg.AuthOIDCIssuerURL != "https://idp.example.com" || cfg.AuthOIDCClientID != "bjorn2scan" {
+		t.Errorf("file values not loaded: %+v", cfg)
+	}
+	if !reflect.DeepEqual(cfg.AuthOIDCAdminGroups, []string{"platform", "security"}) || cfg.AuthOIDCGroupsClaim != "groups" {
+		t.Errorf("AuthOIDCAdminGroups = %v, AuthOIDCGroupsClaim = %q", cfg.AuthOIDCAdminGroups, cfg.AuthOIDCGroupsClaim)
+	}
+	if !reflect.DeepEqual(cfg.AuthOIDCViewerGroups, []string{"developers"}) || !reflect.DeepEqual(cfg.AuthOIDCScopes, []string{"openid", "groups"}) {
+		t.Errorf("env overrides not applied: viewer groups %v, scopes %v", cfg.AuthOIDCViewerGroups, cfg.AuthOIDCScopes)
+	}
+}
+
+func TestPurgeDryRunConfig(t *testing.T) {
+	if cfg := defaultConfig(); cfg.JobsPurgeDryRun {
+		t.Error("purge dry run enabled by default")
+	}
+
+	configPath := filepath.Join(t.TempDir(), "test.conf")
+	if err := os.WriteFile(configPath, []byte("jobs_purge_dry_run=true\n"), 0644); err != nil {
+		t.Fatalf("Failed to create test config file: %v", err)
+	}
+	cfg, err := LoadConfig(configPath)
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	if !cfg.JobsPurgeDryRun {
+		t.Error("JobsPurgeDryRun = false, want file value")
+	}
+
+	t.Setenv("JOBS_PURGE_DRY_RUN", "false")
+	if cfg, err = LoadConfig(configPath); err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	if cfg.JobsPurgeDryRun {
+		t.Error("JobsPurgeDryRun = true, want env override")
+	}
+}
+
+func TestDebugCategoriesConfig(t *testing.T) {
+	if cfg := defaultConfig(); len(cfg.DebugCategories) != 0 {
+		t.Errorf("DebugCategories = %v by default, want none", cfg.DebugCategories)
+	}
+
+	configPath := filepath.Join(t.TempDir(), "test.conf")
+	if err := os.WriteFile(configPath, []byte("debug_categories=queue, log-level\n"), 0644); err != nil {
+		t.Fatalf("Failed to create test config file: %v", err)
+	}
+	cfg, err := LoadConfig(configPath)
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	if want := []string{"queue", "log-level"}; !reflect.DeepEqual(cfg.DebugCategories, want) {
+		t.Errorf("DebugCategories = %v, want %v", cfg.DebugCategories, want)
+	}
+
+	t.Setenv("DEBUG_CATEGORIES", "faults")
+	if cfg, err = LoadConfig(configPath); err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	if want := []string{"faults"}; !reflect.DeepEqual(cfg.DebugCategories, want) {
+		t.Errorf("DebugCategories = %v, want env override %v", cfg.DebugCategories, want)
+	}
+}
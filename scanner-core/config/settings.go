@@ -0,0 +1,118 @@
+package config
+
+import (
+	"os"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// Source identifies where the effective value of a setting came from.
+type Source string
+
+const (
+	SourceDefault Source = "default"
+	SourceFile    Source = "file"
+	SourceEnv     Source = "env"
+)
+
+// redacted replaces the value of secret settings that are set
+const redacted = "[redacted]"
+
+// Setting is the effective value of one configuration option.
+type Setting struct {
+	Name   string `json:"name"`          // Config file key
+	Env    string `json:"env,omitempty"` // Environment variable, if it can be set from the environment
+	Value  any    `json:"value"`
+	Source Source `json:"source"`
+}
+
+// settingTags returns the config file key, environment variable and whether
+// the environment variable also applies when set to "" for a Config field.
+// Env-only fields are named after their lowercased environment variable.
+func settingTags(field reflect.StructField) (name, env string, allowEmpty bool) {
+	env, opts, _ := strings.Cut(field.Tag.Get("env"), ",")
+	name = field.Tag.Get("ini")
+	if name == "" {
+		name = strings.ToLower(env)
+	}
+	return name, env, opts == "allowempty"
+}
+
+// resolveSources works out, for every configurable field, whether its value
+// was set by the environment, the config file or left at its default. The
+// environment overrides the file, matching the order LoadConfig applies them.
+func resolveSources(fileKeys map[string]bool) map[string]Source {
+	sources := make(map[string]Source)
+	t := reflect.TypeOf(Config{})
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		_, env, allowEmpty := settingTags(field)
+		key := field.Tag.Get("ini")
+		value, set := os.LookupEnv(env)
+		switch {
+		case env != "" && set && (allowEmpty || value != ""):
+			sources[field.Name] = SourceEnv
+		case key != "" && fileKeys[key]:
+			sources[field.Name] = SourceFile
+		default:
+			sources[field.Name] = SourceDefault
+		}
+	}
+	return sources
+}
+
+// File returns the config file LoadConfig read, or "" if none was found.
+func (c *Config) File() string {
+	return c.file
+}
+
+// Settings returns the effective value and source of every configuration
+// option, in declaration order. Secrets are redacted and durations are
+// formatted the way they are written in the config file.
+func (c *Config) Settings() []Setting {
+	v := reflect.ValueOf(c).Elem()
+	t := v.Type()
+	settings := make([]Setting, 0, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name, env, _ := settingTags(field)
+		source, ok := c.sources[field.Name]
+		if !ok {
+			source = SourceDefault
+		}
+		settings = append(settings, Setting{
+			Name:   name,
+			Env:    env,
+			Value:  settingValue(v.Field(i).Interface(), field.Tag.Get("secret") == "true"),
+			Source: source,
+		})
+	}
+	return settings
+}
+
+// settingValue converts a field value to its JSON form; all secrets are strings
+func settingValue(value any, secret bool) any {
+	switch value := value.(type) {
+	case time.Duration:
+		return value.String()
+	case []string:
+		if value == nil {
+			return []string{}
+		}
+		return value
+	case string:
+		if secret && value != "" {
+			return redacted
+		}
+		return value
+	default:
+		return value
+	}
+}
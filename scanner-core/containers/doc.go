@@ -0,0 +1,6 @@
+// Package containers tracks running containers and the images they use.
+//
+// A Manager receives container add/remove events from a runtime watcher,
+// persists them through a DatabaseInterface and enqueues scans for new
+// images through a ScanQueueInterface.
+package containers
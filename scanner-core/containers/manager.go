@@ -27,6 +27,12 @@ type DatabaseInterface interface {
 	IsScanDataCompleteBulk(digests []string) (map[string]bool, error)
 }
 
+// NamespaceRemover is implemented by databases that can remove all containers
+// of a namespace in a single operation
+type NamespaceRemover interface {
+	RemoveNamespaceContainers(namespace string) (int, error)
+}
+
 // ScanQueueInterface defines the interface for enqueuing scan jobs
 type ScanQueueInterface interface {
 	EnqueueScan(image ImageID, nodeName string, containerRuntime string)
@@ -47,12 +53,16 @@ type Manager struct {
 	containers map[string]Container // key: namespace/pod/name
 	db         DatabaseInterface    // optional database persistence
 	scanQueue  ScanQueueInterface   // optional scan queue for SBOM generation
+	// removedNamespaces were removed in bulk; removals of their containers
+	// that are already gone are ignored until the namespace has containers again
+	removedNamespaces map[string]bool
 }
 
 // NewManager creates a new container manager
 func NewManager() *Manager {
 	return &Manager{
-		containers: make(map[string]Container),
+		containers:        make(map[string]Container),
+		removedNamespaces: make(map[string]bool),
 	}
 }
 
@@ -181,6 +191,7 @@ func (m *Manager) AddContainer(c Container) {
 
 	key := makeKey(c.ID.Namespace, c.ID.Pod, c.ID.Name)
 	m.containers[key] = c
+	delete(m.removedNamespaces, c.ID.Namespace)
 
 	log.Info("add container",
 		"namespace", c.ID.Namespace, "pod", c.ID.Pod, "name", c.ID.Name,
@@ -201,12 +212,52 @@ func (m *Manager) AddContainer(c Container) {
 	}
 }
 
+// AddContainers adds a batch of containers without touching containers that are
+// not part of the batch. Used for incremental (paginated) syncs where SetContainers
+// would drop everything outside the current page.
+// Scan checks are performed once per unique digest in the batch.
+func (m *Manager) AddContainers(batch []Container) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, c := range batch {
+		key := makeKey(c.ID.Namespace, c.ID.Pod, c.ID.Name)
+		m.containers[key] = c
+		delete(m.removedNamespaces, c.ID.Namespace)
+	}
+
+	log.Debug("add containers", "containers", len(batch))
+
+	if m.db == nil {
+		return
+	}
+
+	seenDigests := make(map[string]bool)
+	for _, c := range batch {
+		if _, err := m.db.AddContainer(c); err != nil {
+			log.Error("failed to add container to database",
+				"container", c.ID.Name, slog.Any("error", err))
+			continue
+		}
+
+		if m.scanQueue == nil || c.Image.Digest == "" || seenDigests[c.Image.Digest] {
+			continue
+		}
+		seenDigests[c.Image.Digest] = true
+		m.checkAndEnqueueScan(c)
+	}
+}
+
 // RemoveContainer removes a single container from the manager
 func (m *Manager) RemoveContainer(id ContainerID) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
 	key := makeKey(id.Namespace, id.Pod, id.Name)
+	if _, ok := m.containers[key]; !ok && m.removedNamespaces[id.Namespace] {
+		// Pod deletions trailing a namespace deletion that was already handled
+		return
+	}
 	delete(m.containers, key)
 
 	log.Info("remove container",
@@ -221,6 +272,47 @@ func (m *Manager) RemoveContainer(id ContainerID) {
 	}
 }
 
+// RemoveNamespace removes all containers of a deleted namespace at once.
+// Deleting a namespace deletes its pods one by one; handling the namespace as
+// a whole updates the database (and the summaries and metrics derived from
+// it) once, and the pod deletions that follow are ignored. Returns the number
+// of containers removed.
+func (m *Manager) RemoveNamespace(namespace string) int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var ids []ContainerID
+	for key, c := range m.containers {
+		if c.ID.Namespace == namespace {
+			ids = append(ids, c.ID)
+			delete(m.containers, key)
+		}
+	}
+	m.removedNamespaces[namespace] = true
+
+	removed := len(ids)
+	if m.db != nil {
+		if remover, ok := m.db.(NamespaceRemover); ok {
+			n, err := remover.RemoveNamespaceContainers(namespace)
+			if err != nil {
+				log.Error("failed to remove namespace containers from database",
+					"namespace", namespace, slog.Any("error", err))
+			}
+			removed = max(removed, n)
+		} else {
+			for _, id := range ids {
+				if err := m.db.RemoveContainer(id); err != nil {
+					log.Error("failed to remove container from database",
+						"container", id.Name, slog.Any("error", err))
+				}
+			}
+		}
+	}
+
+	log.Info("remove namespace", "namespace", namespace, "containers", removed)
+	return removed
+}
+
 // SetContainers replaces the entire collection of containers
 func (m *Manager) SetContainers(containers []Container) {
 	m.mu.Lock()
@@ -228,6 +320,7 @@ func (m *Manager) SetContainers(containers []Container) {
 
 	// Clear existing containers
 	m.containers = make(map[string]Container)
+	m.removedNamespaces = make(map[string]bool)
 
 	// Add all new containers
 	for _, c := range containers {
@@ -317,3 +317,115 @@ func TestUpdateExistingContainer(t *testing.T) {
 		t.Errorf("Container not updated correctly: %+v", retrieved)
 	}
 }
+
+func TestAddContainersKeepsExisting(t *testing.T) {
+	m := NewManager()
+
+	m.AddContainer(Container{
+		ID:    ContainerID{Namespace: "default", Pod: "pod-1", Name: "app"},
+		Image: ImageID{Reference: "nginx:1.21", Digest: "sha256:abc123"},
+	})
+
+	m.AddContainers([]Container{
+		{
+			ID:    ContainerID{Namespace: "default", Pod: "pod-2", Name: "app"},
+			Image: ImageID{Reference: "nginx:1.21", Digest: "sha256:abc123"},
+		},
+		{
+			ID:    ContainerID{Namespace: "kube-system", Pod: "pod-3", Name: "proxy"},
+			Image: ImageID{Reference: "envoy:v1.20", Digest: "sha256:def456"},
+		},
+	})
+
+	if m.GetContainerCount() != 3 {
+		t.Errorf("Expected 3 containers, got %d", m.GetContainerCount())
+	}
+	if _, exists := m.GetContainer("default", "pod-1", "app"); !exists {
+		t.Error("AddContainers should not remove containers outside the batch")
+	}
+}
+
+// recordingDB records container removals
+type recordingDB struct {
+	removed           []ContainerID
+	namespacesRemoved []string
+}
+
+func (d *recordingDB) AddContainer(c Container) (bool, error) { return false, nil }
+func (d *recordingDB) RemoveContainer(id ContainerID) error {
+	d.removed = append(d.removed, id)
+	return nil
+}
+func (d *recordingDB) SetContainers(containers []Container) (*ReconciliationStats, error) {
+	return &ReconciliationStats{}, nil
+}
+func (d *recordingDB) GetImageScanStatus(digest string) (string, error) { return "", nil }
+func (d *recordingDB) GetImageScanStatusBulk(digests []string) (map[string]string, error) {
+	return nil, nil
+}
+func (d *recordingDB) IsScanDataComplete(digest string) (bool, error) { return true, nil }
+func (d *recordingDB) IsScanDataCompleteBulk(digests []string) (map[string]bool, error) {
+	return nil, nil
+}
+
+// bulkDB also removes namespaces in one operation
+type bulkDB struct {
+	recordingDB
+}
+
+func (d *bulkDB) RemoveNamespaceContainers(namespace string) (int, error) {
+	d.namespacesRemoved = append(d.namespacesRemoved, namespace)
+	return 3, nil
+}
+
+func TestRemoveNamespace(t *testing.T) {
+	populate := func(m *Manager) {
+		m.AddContainers([]Container{
+			{ID: ContainerID{Namespace: "team-a", Pod: "pod-1", Name: "app"}, Image: ImageID{Reference: "nginx:1.21", Digest: "sha256:abc123"}},
+			{ID: ContainerID{Namespace: "team-a", Pod: "pod-2", Name: "app"}, Image: ImageID{Reference: "nginx:1.21", Digest: "sha256:abc123"}},
+			{ID: ContainerID{Namespace: "team-b", Pod: "pod-3", Name: "app"}, Image: ImageID{Reference: "nginx:1.21", Digest: "sha256:abc123"}},
+		})
+	}
+
+	t.Run("bulk database removal", func(t *testing.T) {
+		db := &bulkDB{}
+		m := NewManager()
+		m.SetDatabase(db)
+		populate(m)
+
+		// The database may hold containers the manager has not seen yet
+		if removed := m.RemoveNamespace("team-a"); removed != 3 {
+			t.Errorf("RemoveNamespace() = %d, want 3", removed)
+		}
+		if m.GetContainerCount() != 1 || len(db.namespacesRemoved) != 1 || len(db.removed) != 0 {
+			t.Errorf("containers = %d, namespaces removed = %v, containers removed = %v",
+				m.GetContainerCount(), db.namespacesRemoved, db.removed)
+		}
+
+		// Trailing pod deletions of the namespace are ignored
+		m.RemoveContainer(ContainerID{Namespace: "team-a", Pod: "pod-1", Name: "app"})
+		if len(db.removed) != 0 {
+			t.Errorf("trailing pod deletion reached the database: %v", db.removed)
+		}
+		// Until the namespace has containers again
+		m.AddContainer(Container{ID: ContainerID{Namespace: "team-a", Pod: "pod-4", Name: "app"}, Image: ImageID{Reference: "nginx:1.21", Digest: "sha256:abc123"}})
+		m.RemoveContainer(ContainerID{Namespace: "team-a", Pod: "pod-1", Name: "app"})
+		if len(db.removed) != 1 {
+			t.Errorf("removal after the namespace was recreated was ignored")
+		}
+	})
+
+	t.Run("per container fallback", func(t *testing.T) {
+		db := &recordingDB{}
+		m := NewManager()
+		m.SetDatabase(db)
+		populate(m)
+
+		if removed := m.RemoveNamespace("team-a"); removed != 2 {
+			t.Errorf("RemoveNamespace() = %d, want 2", removed)
+		}
+		if m.GetContainerCount() != 1 || len(db.removed) != 2 {
+			t.Errorf("containers = %d, containers removed = %v", m.GetContainerCount(), db.removed)
+		}
+	})
+}
@@ -0,0 +1,55 @@
+package containers
+
+import (
+	"strings"
+
+	"github.com/google/go-containerregistry/pkg/name"
+)
+
+// dockerHubRegistry is how Docker Hub is reported, whichever alias the reference used
+const dockerHubRegistry = "docker.io"
+
+// RegistryHost returns the registry host an image reference is pulled from,
+// e.g. "ghcr.io" for ghcr.io/org/app:1.0 and "docker.io" for nginx:1.25.
+// Returns "" when the reference can't be parsed (e.g. a bare image ID).
+func RegistryHost(reference string) string {
+	if reference == "" || strings.HasPrefix(reference, "sha256:") {
+		return ""
+	}
+	ref, err := name.ParseReference(reference)
+	if err != nil {
+		return ""
+	}
+	host := ref.Context().RegistryStr()
+	if host == name.DefaultRegistry || host == "index.docker.io" || host == "registry-1.docker.io" {
+		return dockerHubRegistry
+	}
+	return host
+}
+
+// RepositoryAndTag splits an image reference into its repository path within
+// the registry and its tag, e.g. "library/nginx" and "1.25" for nginx:1.25.
+// The tag is empty for references pinned by digest without a tag; both are
+// empty when the reference can't be parsed.
+func RepositoryAndTag(reference string) (repository, tag string) {
+	if reference == "" || strings.HasPrefix(reference, "sha256:") {
+		return "", ""
+	}
+	named, _, pinned := strings.Cut(reference, "@")
+	if pinned {
+		// app:1.0@sha256:... keeps its tag, app@sha256:... has none
+		if t, err := name.NewTag(named, name.StrictValidation); err == nil {
+			return t.RepositoryStr(), t.TagStr()
+		}
+		repo, err := name.NewRepository(named)
+		if err != nil {
+			return "", ""
+		}
+		return repo.RepositoryStr(), ""
+	}
+	t, err := name.NewTag(named)
+	if err != nil {
+		return "", ""
+	}
+	return t.RepositoryStr(), t.TagStr()
+}
@@ -0,0 +1,48 @@
+package containers
+
+import "testing"
+
+func TestRegistryHost(t *testing.T) {
+	tests := []struct {
+		reference string
+		want      string
+	}{
+		{"nginx:1.25", "docker.io"},
+		{"docker.io/library/nginx:1.25", "docker.io"},
+		{"index.docker.io/bitnami/redis:7", "docker.io"},
+		{"ghcr.io/org/app:1.0", "ghcr.io"},
+		{"registry.example.com:5000/team/app@sha256:0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef", "registry.example.com:5000"},
+		{"localhost:5000/app", "localhost:5000"},
+		{"sha256:0123456789abcdef", ""},
+		{"", ""},
+		{"Invalid Reference", ""},
+	}
+	for _, tt := range tests {
+		if got := RegistryHost(tt.reference); got != tt.want {
+			t.Errorf("RegistryHost(%q) = %q, want %q", tt.reference, got, tt.want)
+		}
+	}
+}
+
+func TestRepositoryAndTag(t *testing.T) {
+	const digest = "sha256:0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"
+	tests := []struct {
+		reference string
+		repo, tag string
+	}{
+		{"nginx:1.25", "library/nginx", "1.25"},
+		{"nginx", "library/nginx", "latest"},
+		{"ghcr.io/org/app:1.0", "org/app", "1.0"},
+		{"localhost:5000/team/app:v2@" + digest, "team/app", "v2"},
+		{"registry.example.com:5000/team/app@" + digest, "team/app", ""},
+		{"sha256:0123456789abcdef", "", ""},
+		{"", "", ""},
+		{"Invalid Reference", "", ""},
+	}
+	for _, tt := range tests {
+		repo, tag := RepositoryAndTag(tt.reference)
+		if repo != tt.repo || tag != tt.tag {
+			t.Errorf("RepositoryAndTag(%q) = %q, %q, want %q, %q", tt.reference, repo, tag, tt.repo, tt.tag)
+		}
+	}
+}
@@ -19,6 +19,7 @@ type Container struct {
 	Image            ImageID     `json:"image"`
 	NodeName         string      `json:"node_name"`         // K8s node name (empty for agent)
 	ContainerRuntime string      `json:"container_runtime"` // "docker" or "containerd"
+	ImagePullPolicy  string      `json:"image_pull_policy"` // K8s imagePullPolicy: "Always", "IfNotPresent" or "Never" (empty for agent)
 }
 
 // ContainerCollection represents a collection of containers
@@ -0,0 +1,90 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/bvboe/b2s-go/scanner-core/containers"
+)
+
+// AdHocScan is an image scanned on demand rather than discovered running in
+// the cluster
+type AdHocScan struct {
+	Digest      string `json:"digest"`
+	Reference   string `json:"reference"`
+	Status      Status `json:"status"`
+	Error       string `json:"error,omitempty"`
+	RequestedAt string `json:"requested_at"`
+	ExpiresAt   string `json:"expires_at"`
+}
+
+// CreateAdHocScan records an on-demand scan request for image, creating the
+// image if it is unknown. The image is kept for at least retention after the
+// request even when no container runs it. Returns whether the image was new.
+func (db *DB) CreateAdHocScan(image containers.ImageID, retention time.Duration) (bool, error) {
+	now := time.Now().UTC()
+	expires := now.Add(retention)
+
+	done := db.beginWrite("create_ad_hoc_scan")
+	defer done()
+	tx, err := db.conn.Begin()
+	if err != nil {
+		exitOnCorruption(err)
+		return false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	_, created, err := db.getOrCreateImageTx(tx, image)
+	if err != nil {
+		exitOnCorruption(err)
+		return false, err
+	}
+
+	// Extend, never shorten, the retention of an image requested before
+	_, err = tx.Exec(`
+		UPDATE images
+		SET ad_hoc = 1,
+		    ad_hoc_reference = ?,
+		    ad_hoc_requested_at = ?,
+		    ad_hoc_expires_at = MAX(COALESCE(ad_hoc_expires_at, ''), ?)
+		WHERE digest = ?
+	`, image.Reference, formatTimestamp(now), formatTimestamp(expires), image.Digest)
+	if err != nil {
+		exitOnCorruption(err)
+		return false, fmt.Errorf("failed to flag ad-hoc image: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		exitOnCorruption(err)
+		return false, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	db.notifyWrite()
+	return created, nil
+}
+
+// GetAdHocScan returns the on-demand scan of an image.
+// Returns nil if the image was never requested ad-hoc or its results were
+// deleted after the retention period.
+func (db *DB) GetAdHocScan(digest string) (*AdHocScan, error) {
+	scan := AdHocScan{Digest: digest}
+	var status string
+	err := db.conn.QueryRow(`
+		SELECT status,
+		       COALESCE(status_error, ''),
+		       COALESCE(ad_hoc_reference, ''),
+		       COALESCE(ad_hoc_requested_at, ''),
+		       COALESCE(ad_hoc_expires_at, '')
+		FROM images
+		WHERE digest = ? AND ad_hoc = 1 AND deleted_at IS NULL
+	`, digest).Scan(&status, &scan.Error, &scan.Reference, &scan.RequestedAt, &scan.ExpiresAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get ad-hoc scan: %w", err)
+	}
+	scan.Status = Status(status)
+	return &scan, nil
+}
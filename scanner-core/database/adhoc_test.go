@@ -0,0 +1,85 @@
+package database
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/bvboe/b2s-go/scanner-core/containers"
+)
+
+// TestAdHocScanRetention verifies that ad-hoc images survive orphan cleanup
+// until their retention period ends.
+func TestAdHocScanRetention(t *testing.T) {
+	db, err := New(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("failed to create database: %v", err)
+	}
+	defer func() { _ = Close(db) }()
+
+	retained := containers.ImageID{Reference: "registry.example.com/app:v2", Digest: "sha256:retained"}
+	expired := containers.ImageID{Reference: "registry.example.com/app:v1", Digest: "sha256:expired"}
+
+	created, err := db.CreateAdHocScan(retained, time.Hour)
+	if err != nil {
+		t.Fatalf("CreateAdHocScan failed: %v", err)
+	}
+	if !created {
+		t.Error("expected a new image to be created")
+	}
+	if _, err := db.CreateAdHocScan(expired, -time.Hour); err != nil {
+		t.Fatalf("CreateAdHocScan failed: %v", err)
+	}
+
+	scan, err := db.GetAdHocScan(retained.Digest)
+	if err != nil {
+		t.Fatalf("GetAdHocScan failed: %v", err)
+	}
+	if scan == nil {
+		t.Fatal("expected ad-hoc scan")
+	}
+	if scan.Reference != retained.Reference || scan.Status != StatusPending || scan.ExpiresAt <= scan.RequestedAt {
+		t.Errorf("unexpected ad-hoc scan: %+v", scan)
+	}
+
+	// A repeated request keeps the longer retention
+	if created, err := db.CreateAdHocScan(retained, -time.Hour); err != nil || created {
+		t.Fatalf("CreateAdHocScan again = %v, %v; want false, nil", created, err)
+	}
+
+	stats, err := db.CleanupOrphanedImages()
+	if err != nil {
+		t.Fatalf("CleanupOrphanedImages failed: %v", err)
+	}
+	if stats.ImagesSoftDeleted != 1 {
+		t.Errorf("expected 1 image deleted, got %d", stats.ImagesSoftDeleted)
+	}
+
+	if scan, err := db.GetAdHocScan(retained.Digest); err != nil || scan == nil {
+		t.Errorf("expected retained image to survive cleanup, got %v, %v", scan, err)
+	}
+	if scan, err := db.GetAdHocScan(expired.Digest); err != nil || scan != nil {
+		t.Errorf("expected expired image to be removed, got %v, %v", scan, err)
+	}
+}
+
+// TestGetAdHocScanNotAdHoc verifies that images discovered in the cluster are
+// not reported as ad-hoc scans.
+func TestGetAdHocScanNotAdHoc(t *testing.T) {
+	db, err := New(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("failed to create database: %v", err)
+	}
+	defer func() { _ = Close(db) }()
+
+	if _, _, err := db.GetOrCreateImage(containers.ImageID{Reference: "nginx:1.25", Digest: "sha256:running"}); err != nil {
+		t.Fatalf("GetOrCreateImage failed: %v", err)
+	}
+	scan, err := db.GetAdHocScan("sha256:running")
+	if err != nil {
+		t.Fatalf("GetAdHocScan failed: %v", err)
+	}
+	if scan != nil {
+		t.Errorf("expected nil for an image not requested ad-hoc, got %+v", scan)
+	}
+}
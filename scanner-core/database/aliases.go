@@ -0,0 +1,67 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+)
+
+// vulnerabilityAlias maps a vulnerability identifier reported by Grype (e.g. an
+// RHSA or ALAS advisory) to a related identifier (usually the CVE it fixes)
+type vulnerabilityAlias struct {
+	vulnerabilityID string
+	aliasID         string
+}
+
+// collectVulnerabilityAliases records the related vulnerabilities of a match as
+// aliases of its primary identifier
+func collectVulnerabilityAliases(aliases map[vulnerabilityAlias]struct{}, id string, related []GrypeRelatedVuln) {
+	for _, r := range related {
+		if r.ID == "" || r.ID == id {
+			continue
+		}
+		aliases[vulnerabilityAlias{vulnerabilityID: id, aliasID: r.ID}] = struct{}{}
+	}
+}
+
+// insertVulnerabilityAliases stores alias mappings inside an open transaction.
+// Mappings are global facts from the vulnerability database, so existing rows
+// are kept and duplicates are ignored.
+func insertVulnerabilityAliases(tx *sql.Tx, aliases map[vulnerabilityAlias]struct{}) error {
+	rows := make([]any, 0, len(aliases)*2)
+	for a := range aliases {
+		rows = append(rows, a.vulnerabilityID, a.aliasID)
+	}
+	// 2 cols → 400 rows per batch = 800 params
+	return batchInsert(tx,
+		`INSERT OR IGNORE INTO vulnerability_aliases (vulnerability_id, alias_id)`,
+		rows, 2, 400)
+}
+
+// GetVulnerabilityAliases returns all identifiers known to refer to the same
+// vulnerability as id, in either direction (advisory → CVE and CVE → advisory)
+func (db *DB) GetVulnerabilityAliases(id string) ([]string, error) {
+	rows, err := db.conn.Query(`
+		SELECT alias_id FROM vulnerability_aliases WHERE vulnerability_id = ?
+		UNION
+		SELECT vulnerability_id FROM vulnerability_aliases WHERE alias_id = ?
+	`, id, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query vulnerability aliases: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	aliases := []string{}
+	for rows.Next() {
+		var alias string
+		if err := rows.Scan(&alias); err != nil {
+			return nil, fmt.Errorf("failed to scan vulnerability alias: %w", err)
+		}
+		aliases = append(aliases, alias)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate vulnerability aliases: %w", err)
+	}
+	sort.Strings(aliases)
+	return aliases, nil
+}
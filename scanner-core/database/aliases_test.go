@@ -0,0 +1,67 @@
+package database
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/bvboe/b2s-go/scanner-core/containers"
+)
+
+// TestVulnerabilityAliasesFromGrypeMatches verifies that advisory → CVE mappings
+// are taken from the relatedVulnerabilities of Grype matches and can be looked
+// up from either identifier.
+func TestVulnerabilityAliasesFromGrypeMatches(t *testing.T) {
+	db, err := New(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("failed to create database: %v", err)
+	}
+	defer func() { _ = Close(db) }()
+
+	digest := "sha256:rhel"
+	if _, _, err := db.GetOrCreateImage(containers.ImageID{Reference: "registry.access.redhat.com/ubi9:latest", Digest: digest}); err != nil {
+		t.Fatalf("GetOrCreateImage() error = %v", err)
+	}
+
+	vulnJSON := []byte(`{"matches": [
+		{
+			"vulnerability": {"id": "RHSA-2024:1234", "severity": "High", "fix": {"state": "fixed", "versions": ["1.1.1k-12"]}},
+			"relatedVulnerabilities": [{"id": "CVE-2024-0001", "namespace": "nvd:cpe"}, {"id": "CVE-2024-0002", "namespace": "nvd:cpe"}],
+			"artifact": {"name": "openssl", "version": "1.1.1k-9", "type": "rpm"}
+		},
+		{
+			"vulnerability": {"id": "CVE-2024-0003", "severity": "Low", "fix": {"state": "not-fixed"}},
+			"relatedVulnerabilities": [{"id": "CVE-2024-0003", "namespace": "nvd:cpe"}],
+			"artifact": {"name": "bash", "version": "5.1", "type": "rpm"}
+		}
+	]}`)
+	if err := db.StoreVulnerabilities(digest, vulnJSON, time.Now()); err != nil {
+		t.Fatalf("StoreVulnerabilities() error = %v", err)
+	}
+
+	aliases, err := db.GetVulnerabilityAliases("RHSA-2024:1234")
+	if err != nil {
+		t.Fatalf("GetVulnerabilityAliases() error = %v", err)
+	}
+	if want := []string{"CVE-2024-0001", "CVE-2024-0002"}; !reflect.DeepEqual(aliases, want) {
+		t.Errorf("aliases of advisory = %v, want %v", aliases, want)
+	}
+
+	aliases, err = db.GetVulnerabilityAliases("CVE-2024-0002")
+	if err != nil {
+		t.Fatalf("GetVulnerabilityAliases() error = %v", err)
+	}
+	if want := []string{"RHSA-2024:1234"}; !reflect.DeepEqual(aliases, want) {
+		t.Errorf("aliases of CVE = %v, want %v", aliases, want)
+	}
+
+	// A match whose only related vulnerability is itself has no aliases
+	aliases, err = db.GetVulnerabilityAliases("CVE-2024-0003")
+	if err != nil {
+		t.Fatalf("GetVulnerabilityAliases() error = %v", err)
+	}
+	if len(aliases) != 0 {
+		t.Errorf("aliases of CVE-2024-0003 = %v, want none", aliases)
+	}
+}
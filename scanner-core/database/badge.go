@@ -0,0 +1,76 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// ImageSeverityCounts holds the number of unique vulnerabilities (by CVE ID) per
+// severity for an image, along with its scan status
+type ImageSeverityCounts struct {
+	Digest     string
+	Status     Status
+	Critical   int
+	High       int
+	Medium     int
+	Low        int
+	Negligible int
+	Unknown    int
+}
+
+// Total returns the number of unique vulnerabilities across all severities
+func (c *ImageSeverityCounts) Total() int {
+	return c.Critical + c.High + c.Medium + c.Low + c.Negligible + c.Unknown
+}
+
+// GetImageSeverityCounts returns per-severity vulnerability counts for an image.
+// Returns nil if the image is unknown.
+func (db *DB) GetImageSeverityCounts(digest string) (*ImageSeverityCounts, error) {
+	counts := ImageSeverityCounts{Digest: digest}
+	var status string
+
+	err := db.conn.QueryRow(`
+		SELECT
+			img.status,
+			COUNT(DISTINCT CASE WHEN v.severity = 'Critical' THEN v.cve_id END),
+			COUNT(DISTINCT CASE WHEN v.severity = 'High' THEN v.cve_id END),
+			COUNT(DISTINCT CASE WHEN v.severity = 'Medium' THEN v.cve_id END),
+			COUNT(DISTINCT CASE WHEN v.severity = 'Low' THEN v.cve_id END),
+			COUNT(DISTINCT CASE WHEN v.severity = 'Negligible' THEN v.cve_id END),
+			COUNT(DISTINCT CASE WHEN v.severity NOT IN ('Critical', 'High', 'Medium', 'Low', 'Negligible') OR v.severity IS NULL THEN v.cve_id END)
+		FROM images img
+		LEFT JOIN image_vulnerabilities v ON v.image_id = img.id
+		WHERE img.digest = ?
+		GROUP BY img.id
+	`, digest).Scan(&status, &counts.Critical, &counts.High, &counts.Medium, &counts.Low, &counts.Negligible, &counts.Unknown)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get image severity counts: %w", err)
+	}
+	counts.Status = Status(status)
+	return &counts, nil
+}
+
+// GetImageDigestByReference returns the digest of the image most recently seen
+// running under the given reference (e.g. nginx:1.25).
+// Returns an empty string if no running container uses the reference.
+func (db *DB) GetImageDigestByReference(reference string) (string, error) {
+	var digest string
+	err := db.conn.QueryRow(`
+		SELECT img.digest
+		FROM containers c
+		JOIN images img ON c.image_id = img.id
+		WHERE c.reference = ?
+		ORDER BY c.created_at DESC, c.id DESC
+		LIMIT 1
+	`, reference).Scan(&digest)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve image reference: %w", err)
+	}
+	return digest, nil
+}
@@ -0,0 +1,89 @@
+package database
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/bvboe/b2s-go/scanner-core/containers"
+)
+
+func TestCompressLegacyBlobs(t *testing.T) {
+	db, err := New(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("failed to create database: %v", err)
+	}
+	defer func() { _ = Close(db) }()
+
+	legacy, rescanned := "sha256:legacy", "sha256:rescanned"
+	for _, digest := range []string{legacy, rescanned} {
+		if _, err := db.AddContainer(containers.Container{
+			ID:    containers.ContainerID{Namespace: "default", Pod: digest, Name: "app"},
+			Image: containers.ImageID{Reference: "app:" + digest, Digest: digest},
+		}); err != nil {
+			t.Fatalf("AddContainer() error = %v", err)
+		}
+	}
+	if err := db.StoreSBOM(rescanned, []byte(imageSizeSBOM)); err != nil {
+		t.Fatalf("StoreSBOM() error = %v", err)
+	}
+	if err := db.StoreVulnerabilities(rescanned, []byte(`{"matches": []}`), time.Now()); err != nil {
+		t.Fatalf("StoreVulnerabilities() error = %v", err)
+	}
+
+	// Documents as written before v47: uncompressed, and kept next to the
+	// compressed copy of a rescan
+	exec := func(query string, args ...interface{}) {
+		t.Helper()
+		if _, err := db.conn.Exec(query, args...); err != nil {
+			t.Fatalf("exec failed: %v\nquery: %s", err, query)
+		}
+	}
+	exec(`UPDATE images SET sbom = ?, vulnerabilities = ? WHERE digest = ?`, `{"artifacts": ["legacy"]}`, `{"matches": ["legacy"]}`, legacy)
+	exec(`UPDATE images SET sbom = 'stale', vulnerabilities = '' WHERE digest = ?`, rescanned)
+	exec(`INSERT INTO nodes (id, name, os_release, status, sbom) VALUES (1, 'node-a', 'wolfi', 'completed', '{"artifacts": ["node"]}')`)
+
+	// Interrupted after the first row, then resumed
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := db.runOnlineMigrations(ctx, onlineMigrations, 1); err == nil {
+		t.Fatal("expected the cancelled run to return an error")
+	}
+	if err := db.runOnlineMigrations(context.Background(), onlineMigrations, 1); err != nil {
+		t.Fatalf("runOnlineMigrations() error = %v", err)
+	}
+
+	var uncompressed int
+	if err := db.conn.QueryRow(`
+		SELECT (SELECT COUNT(*) FROM images WHERE sbom IS NOT NULL OR vulnerabilities IS NOT NULL)
+		     + (SELECT COUNT(*) FROM nodes WHERE sbom IS NOT NULL OR vulnerabilities IS NOT NULL)
+	`).Scan(&uncompressed); err != nil {
+		t.Fatal(err)
+	}
+	if uncompressed != 0 {
+		t.Errorf("%d rows still hold uncompressed documents", uncompressed)
+	}
+
+	for _, tt := range []struct {
+		name string
+		get  func() ([]byte, error)
+		want string
+	}{
+		{"legacy SBOM", func() ([]byte, error) { return db.GetSBOM(legacy) }, `{"artifacts": ["legacy"]}`},
+		{"legacy vulnerabilities", func() ([]byte, error) { return db.GetVulnerabilities(legacy) }, `{"matches": ["legacy"]}`},
+		{"rescanned SBOM", func() ([]byte, error) { return db.GetSBOM(rescanned) }, imageSizeSBOM},
+		{"rescanned vulnerabilities", func() ([]byte, error) { return db.GetVulnerabilities(rescanned) }, `{"matches": []}`},
+		{"node SBOM", func() ([]byte, error) { return db.GetNodeSBOM("node-a") }, `{"artifacts": ["node"]}`},
+	} {
+		got, err := tt.get()
+		if err != nil || string(got) != tt.want {
+			t.Errorf("%s = %q, %v; want %q", tt.name, got, err, tt.want)
+		}
+	}
+
+	status, err := db.GetMigrationStatus()
+	if err != nil || status.InProgress {
+		t.Errorf("GetMigrationStatus() = %+v, %v; want all online migrations completed", status, err)
+	}
+}
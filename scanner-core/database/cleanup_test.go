@@ -1,7 +1,9 @@
 package database
 
 import (
+	"database/sql"
 	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
@@ -119,27 +121,73 @@ func TestCleanupOrphanedImages(t *testing.T) {
 	}
 
 	// Verify stats
-	if stats.ImagesRemoved != 2 {
-		t.Errorf("Expected 2 images removed, got %d", stats.ImagesRemoved)
+	if stats.ImagesSoftDeleted != 2 {
+		t.Errorf("Expected 2 images deleted, got %d", stats.ImagesSoftDeleted)
 	}
 
-	// Verify only 1 image remains (the one with an active instance)
-	err = db.conn.QueryRow("SELECT COUNT(*) FROM images").Scan(&imageCount)
+	// Deleted images are kept until they are purged
+	var deletedCount int
+	err = db.conn.QueryRow("SELECT COUNT(*), COUNT(deleted_at) FROM images").Scan(&imageCount, &deletedCount)
 	if err != nil {
 		t.Fatalf("Failed to count images after cleanup: %v", err)
 	}
-	if imageCount != 1 {
-		t.Errorf("Expected 1 image after cleanup, got %d", imageCount)
+	if imageCount != 3 || deletedCount != 2 {
+		t.Errorf("Expected 3 images with 2 deleted after cleanup, got %d with %d deleted", imageCount, deletedCount)
 	}
 
-	// Verify the remaining image is image2 (redis)
-	var digest string
-	err = db.conn.QueryRow("SELECT digest FROM images LIMIT 1").Scan(&digest)
+	// Running again doesn't delete them twice
+	stats, err = db.CleanupOrphanedImages()
+	if err != nil {
+		t.Fatalf("Cleanup failed: %v", err)
+	}
+	if stats.ImagesSoftDeleted != 0 {
+		t.Errorf("Expected no images deleted by second cleanup, got %d", stats.ImagesSoftDeleted)
+	}
+
+	// An image that runs again is restored
+	if _, err := db.AddContainer(instance3); err != nil {
+		t.Fatalf("Failed to re-add instance3: %v", err)
+	}
+	var deletedAt sql.NullString
+	if err := db.conn.QueryRow("SELECT deleted_at FROM images WHERE digest = ?", image3.Digest).Scan(&deletedAt); err != nil {
+		t.Fatalf("Failed to query image3: %v", err)
+	}
+	if deletedAt.Valid {
+		t.Errorf("Expected image3 to be restored, deleted_at = %s", deletedAt.String)
+	}
+	if err := db.RemoveContainer(instance3.ID); err != nil {
+		t.Fatalf("Failed to remove instance3: %v", err)
+	}
+
+	// Images deleted within the retention window are not purged
+	stats, err = db.PurgeDeletedImages(time.Hour)
+	if err != nil {
+		t.Fatalf("Purge failed: %v", err)
+	}
+	if stats.ImagesRemoved != 0 {
+		t.Errorf("Expected no images purged within retention, got %d", stats.ImagesRemoved)
+	}
+
+	stats, err = db.PurgeDeletedImages(0)
+	if err != nil {
+		t.Fatalf("Purge failed: %v", err)
+	}
+	if stats.ImagesRemoved != 1 {
+		t.Errorf("Expected 1 image purged, got %d", stats.ImagesRemoved)
+	}
+
+	// image2 (running) and image3 (restored, not yet deleted again) remain
+	err = db.conn.QueryRow("SELECT COUNT(*) FROM images").Scan(&imageCount)
 	if err != nil {
-		t.Fatalf("Failed to get remaining image: %v", err)
+		t.Fatalf("Failed to count images after purge: %v", err)
 	}
-	if digest != image2.Digest {
-		t.Errorf("Expected remaining image to be %s, got %s", image2.Digest, digest)
+	if imageCount != 2 {
+		t.Errorf("Expected 2 images after purge, got %d", imageCount)
+	}
+	var digest string
+	err = db.conn.QueryRow("SELECT digest FROM images WHERE digest = ?", image1.Digest).Scan(&digest)
+	if err != sql.ErrNoRows {
+		t.Errorf("Expected %s to be purged, got %v", image1.Digest, err)
 	}
 }
 
@@ -183,8 +231,8 @@ func TestCleanupOrphanedImages_NoOrphans(t *testing.T) {
 	}
 
 	// Verify no images were removed
-	if stats.ImagesRemoved != 0 {
-		t.Errorf("Expected 0 images removed, got %d", stats.ImagesRemoved)
+	if stats.ImagesSoftDeleted != 0 {
+		t.Errorf("Expected 0 images deleted, got %d", stats.ImagesSoftDeleted)
 	}
 	if stats.PackagesRemoved != 0 {
 		t.Errorf("Expected 0 packages removed, got %d", stats.PackagesRemoved)
@@ -212,7 +260,67 @@ func TestCleanupOrphanedImages_EmptyDatabase(t *testing.T) {
 	}
 
 	// Verify no errors and zero stats
-	if stats.ImagesRemoved != 0 {
-		t.Errorf("Expected 0 images removed, got %d", stats.ImagesRemoved)
+	if stats.ImagesSoftDeleted != 0 {
+		t.Errorf("Expected 0 images deleted, got %d", stats.ImagesSoftDeleted)
+	}
+}
+
+func TestPreviewPurgeDeletedImages(t *testing.T) {
+	db, err := New(filepath.Join(t.TempDir(), "preview.db"))
+	if err != nil {
+		t.Fatalf("failed to create database: %v", err)
+	}
+	defer func() { _ = Close(db) }()
+
+	// a and b share an SBOM document, c has its own
+	for digest, sbom := range map[string]string{
+		"sha256:a": `{"artifacts":[],"source":{"id":"shared"}}`,
+		"sha256:b": `{"artifacts":[],"source":{"id":"shared"}}`,
+		"sha256:c": `{"artifacts":[],"source":{"id":"c"}}`,
+	} {
+		if _, err := db.AddContainer(containers.Container{
+			ID:    containers.ContainerID{Namespace: "default", Pod: digest, Name: "app"},
+			Image: containers.ImageID{Reference: "app:" + digest[7:], Digest: digest},
+		}); err != nil {
+			t.Fatalf("AddContainer() error = %v", err)
+		}
+		if err := db.StoreSBOM(digest, []byte(sbom)); err != nil {
+			t.Fatalf("StoreSBOM() error = %v", err)
+		}
+	}
+	if _, err := db.conn.Exec(`DELETE FROM containers WHERE pod IN ('sha256:a', 'sha256:c')`); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.CleanupOrphanedImages(); err != nil {
+		t.Fatalf("CleanupOrphanedImages() error = %v", err)
+	}
+	if _, err := db.conn.Exec(`UPDATE images SET deleted_at = '2024-01-01T00:00:00Z' WHERE digest = 'sha256:a'`); err != nil {
+		t.Fatal(err)
+	}
+
+	// Only a was deleted over an hour ago; b still uses the shared document
+	preview, err := db.PreviewPurgeDeletedImages(time.Hour)
+	if err != nil {
+		t.Fatalf("PreviewPurgeDeletedImages() error = %v", err)
+	}
+	if len(preview.Images) != 1 || preview.Images[0].Digest != "sha256:a" || preview.Images[0].Reference != "app:a" || preview.SBOMsRemoved != 0 {
+		t.Errorf("PreviewPurgeDeletedImages(1h) = %+v, want sha256:a freeing no document", preview)
+	}
+
+	preview, err = db.PreviewPurgeDeletedImages(0)
+	if err != nil {
+		t.Fatalf("PreviewPurgeDeletedImages() error = %v", err)
+	}
+	if len(preview.Images) != 2 || preview.SBOMsRemoved != 1 {
+		t.Errorf("PreviewPurgeDeletedImages(0) = %+v, want 2 images freeing 1 document", preview)
+	}
+
+	// The preview removes nothing and matches what the purge removes
+	stats, err := db.PurgeDeletedImages(0)
+	if err != nil {
+		t.Fatalf("PurgeDeletedImages() error = %v", err)
+	}
+	if stats.ImagesRemoved != len(preview.Images) || stats.SBOMsRemoved != preview.SBOMsRemoved {
+		t.Errorf("PurgeDeletedImages() = %+v, want the previewed %+v", stats, preview)
 	}
 }
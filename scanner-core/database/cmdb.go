@@ -0,0 +1,295 @@
+package database
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// MaxCMDBValueLength bounds the keys and attribute values of CMDB entries
+const MaxCMDBValueLength = 256
+
+// CMDB attribute names, as used in policy selectors and filters
+const (
+	CMDBApplicationID      = "application_id"
+	CMDBOwner              = "owner"
+	CMDBDataClassification = "data_classification"
+)
+
+// CMDBLabelColumns maps the image labels CMDB entries can be keyed by to the
+// images column recording them. Only these OCI labels are stored per image.
+var CMDBLabelColumns = map[string]string{
+	ociSourceLabel:  "oci_source",
+	ociVersionLabel: "oci_version",
+	ociVendorLabel:  "oci_vendor",
+}
+
+// CMDBAttributes are the attributes a CMDB assigns to workloads
+type CMDBAttributes struct {
+	ApplicationID      string `json:"application_id,omitempty"`
+	Owner              string `json:"owner,omitempty"`
+	DataClassification string `json:"data_classification,omitempty"`
+}
+
+// Map returns the attributes that are set, by attribute name
+func (a CMDBAttributes) Map() map[string]string {
+	m := make(map[string]string)
+	for name, value := range map[string]string{
+		CMDBApplicationID:      a.ApplicationID,
+		CMDBOwner:              a.Owner,
+		CMDBDataClassification: a.DataClassification,
+	} {
+		if value != "" {
+			m[name] = value
+		}
+	}
+	if len(m) == 0 {
+		return nil
+	}
+	return m
+}
+
+// empty reports whether no attribute is set
+func (a CMDBAttributes) empty() bool {
+	return a.ApplicationID == "" && a.Owner == "" && a.DataClassification == ""
+}
+
+// fill sets the attributes a leaves empty from b
+func (a *CMDBAttributes) fill(b CMDBAttributes) {
+	if a.ApplicationID == "" {
+		a.ApplicationID = b.ApplicationID
+	}
+	if a.Owner == "" {
+		a.Owner = b.Owner
+	}
+	if a.DataClassification == "" {
+		a.DataClassification = b.DataClassification
+	}
+}
+
+// CMDBEntry assigns attributes to the workloads of a namespace or to the
+// images carrying a label (one of CMDBLabelColumns) with the given value.
+// Label entries are more specific and win over namespace entries, attribute
+// by attribute.
+type CMDBEntry struct {
+	Namespace  string `json:"namespace,omitempty"`
+	Label      string `json:"label,omitempty"`
+	LabelValue string `json:"label_value,omitempty"`
+	CMDBAttributes
+	Source    string `json:"source,omitempty"`     // sync that stored the entry
+	UpdatedAt string `json:"updated_at,omitempty"` // when the entry was last synced
+}
+
+// key identifies the workloads an entry applies to
+func (e CMDBEntry) key() string {
+	if e.Namespace != "" {
+		return "namespace\x00" + e.Namespace
+	}
+	return "label\x00" + e.Label + "\x00" + e.LabelValue
+}
+
+// normalize trims the entry and checks that it has exactly one key and at
+// least one attribute
+func (e *CMDBEntry) normalize() error {
+	for _, field := range []*string{&e.Namespace, &e.Label, &e.LabelValue,
+		&e.ApplicationID, &e.Owner, &e.DataClassification} {
+		*field = strings.TrimSpace(*field)
+		if len(*field) > MaxCMDBValueLength {
+			return fmt.Errorf("value exceeds %d characters", MaxCMDBValueLength)
+		}
+	}
+	switch {
+	case e.Namespace != "" && e.Label != "":
+		return fmt.Errorf("entry for namespace %q also sets a label", e.Namespace)
+	case e.Namespace == "" && e.Label == "":
+		return fmt.Errorf("entry sets neither namespace nor label")
+	case e.Label != "":
+		if _, ok := CMDBLabelColumns[e.Label]; !ok {
+			return fmt.Errorf("unsupported label %q (want %s)", e.Label, strings.Join(cmdbLabels(), ", "))
+		}
+		if e.LabelValue == "" {
+			return fmt.Errorf("entry for label %s has no label_value", e.Label)
+		}
+	default:
+		e.LabelValue = ""
+	}
+	if e.CMDBAttributes.empty() {
+		return fmt.Errorf("entry sets no attributes")
+	}
+	return nil
+}
+
+// cmdbLabels returns the supported label keys, sorted
+func cmdbLabels() []string {
+	keys := make([]string, 0, len(CMDBLabelColumns))
+	for key := range CMDBLabelColumns {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// ValidateCMDBEntries trims the entries in place and checks that each has
+// exactly one key, used by no other entry, and at least one attribute
+func ValidateCMDBEntries(entries []CMDBEntry) error {
+	seen := make(map[string]bool, len(entries))
+	for i := range entries {
+		if err := entries[i].normalize(); err != nil {
+			return fmt.Errorf("invalid CMDB entry %d: %w", i, err)
+		}
+		if seen[entries[i].key()] {
+			return fmt.Errorf("invalid CMDB entry %d: duplicate key", i)
+		}
+		seen[entries[i].key()] = true
+	}
+	return nil
+}
+
+// CMDBSyncResult summarizes a sync
+type CMDBSyncResult struct {
+	Source  string `json:"source"`
+	Entries int    `json:"entries"` // entries stored
+	Removed int    `json:"removed"` // entries of the source no longer present
+}
+
+// SyncCMDBMetadata replaces the entries previously synced from source with
+// entries. Entries of other sources are kept, unless an entry has the same
+// key, in which case the latest sync wins. Invalid entries fail the whole
+// sync, naming the first one.
+func (db *DB) SyncCMDBMetadata(source string, entries []CMDBEntry) (*CMDBSyncResult, error) {
+	source = strings.TrimSpace(source)
+	if source == "" {
+		return nil, fmt.Errorf("source is required")
+	}
+	if err := ValidateCMDBEntries(entries); err != nil {
+		return nil, err
+	}
+	seen := make(map[string]bool, len(entries))
+	for _, e := range entries {
+		seen[e.key()] = true
+	}
+
+	previous, err := db.queryCMDBMetadata(`WHERE source = ?`, source)
+	if err != nil {
+		return nil, err
+	}
+	result := &CMDBSyncResult{Source: source, Entries: len(entries)}
+	for _, e := range previous {
+		if !seen[e.key()] {
+			result.Removed++
+		}
+	}
+
+	done := db.beginWrite("sync_cmdb_metadata")
+	defer done()
+	tx, err := db.conn.Begin()
+	if err != nil {
+		exitOnCorruption(err)
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if _, err := tx.Exec(`DELETE FROM cmdb_metadata WHERE source = ?`, source); err != nil {
+		exitOnCorruption(err)
+		return nil, fmt.Errorf("failed to delete CMDB metadata: %w", err)
+	}
+	for _, e := range entries {
+		_, err := tx.Exec(`
+			INSERT INTO cmdb_metadata (namespace, label, label_value, application_id, owner, data_classification, source)
+			VALUES (?, ?, ?, ?, ?, ?, ?)
+			ON CONFLICT(namespace, label, label_value) DO UPDATE SET
+				application_id = excluded.application_id,
+				owner = excluded.owner,
+				data_classification = excluded.data_classification,
+				source = excluded.source,
+				updated_at = `+sqlNow+`
+		`, e.Namespace, e.Label, e.LabelValue, e.ApplicationID, e.Owner, e.DataClassification, source)
+		if err != nil {
+			exitOnCorruption(err)
+			return nil, fmt.Errorf("failed to store CMDB metadata: %w", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		exitOnCorruption(err)
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	db.notifyWrite()
+
+	log.Info("synced CMDB metadata", "source", source, "entries", result.Entries, "removed", result.Removed)
+	return result, nil
+}
+
+// GetCMDBMetadata returns all CMDB entries, namespace entries first
+func (db *DB) GetCMDBMetadata() ([]CMDBEntry, error) {
+	entries, err := db.queryCMDBMetadata("")
+	if err != nil {
+		return nil, err
+	}
+	if entries == nil {
+		entries = []CMDBEntry{}
+	}
+	return entries, nil
+}
+
+func (db *DB) queryCMDBMetadata(where string, args ...interface{}) ([]CMDBEntry, error) {
+	rows, err := db.conn.Query(`
+		SELECT namespace, label, label_value, application_id, owner, data_classification, source, updated_at
+		FROM cmdb_metadata `+where+`
+		ORDER BY label, namespace, label_value`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query CMDB metadata: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var entries []CMDBEntry
+	for rows.Next() {
+		var e CMDBEntry
+		if err := rows.Scan(&e.Namespace, &e.Label, &e.LabelValue, &e.ApplicationID, &e.Owner,
+			&e.DataClassification, &e.Source, &e.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan CMDB metadata: %w", err)
+		}
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
+
+// cmdbIndex resolves the CMDB attributes of images
+type cmdbIndex struct {
+	namespaces map[string]CMDBAttributes
+	labels     map[string]CMDBAttributes // by label + "\x00" + value
+}
+
+// loadCMDBIndex loads all CMDB entries for resolving image attributes
+func (db *DB) loadCMDBIndex() (*cmdbIndex, error) {
+	entries, err := db.queryCMDBMetadata("")
+	if err != nil {
+		return nil, err
+	}
+	index := &cmdbIndex{namespaces: make(map[string]CMDBAttributes), labels: make(map[string]CMDBAttributes)}
+	for _, e := range entries {
+		if e.Namespace != "" {
+			index.namespaces[e.Namespace] = e.CMDBAttributes
+		} else {
+			index.labels[e.Label+"\x00"+e.LabelValue] = e.CMDBAttributes
+		}
+	}
+	return index, nil
+}
+
+// resolve returns the attributes of an image with the given OCI labels that
+// runs in the given namespaces. Label entries win over namespace entries;
+// among several labels or namespaces the first in sorted order wins.
+func (x *cmdbIndex) resolve(namespaces []string, labels map[string]string) CMDBAttributes {
+	var attrs CMDBAttributes
+	for _, key := range cmdbLabels() {
+		if value := labels[key]; value != "" {
+			attrs.fill(x.labels[key+"\x00"+value])
+		}
+	}
+	sorted := append([]string(nil), namespaces...)
+	sort.Strings(sorted)
+	for _, ns := range sorted {
+		attrs.fill(x.namespaces[ns])
+	}
+	return attrs
+}
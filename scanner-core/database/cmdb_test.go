@@ -0,0 +1,122 @@
+package database
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/bvboe/b2s-go/scanner-core/containers"
+)
+
+func TestSyncCMDBMetadata(t *testing.T) {
+	db, err := New(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("failed to create database: %v", err)
+	}
+	defer func() { _ = Close(db) }()
+
+	owner := func(o string) CMDBAttributes { return CMDBAttributes{Owner: o} }
+	if _, err := db.SyncCMDBMetadata("push", []CMDBEntry{
+		{Namespace: "shared", CMDBAttributes: owner("team-push")},
+	}); err != nil {
+		t.Fatalf("SyncCMDBMetadata(push) error = %v", err)
+	}
+	if _, err := db.SyncCMDBMetadata("pull", []CMDBEntry{
+		{Namespace: "payments", CMDBAttributes: owner("team-payments")},
+		{Namespace: "old", CMDBAttributes: owner("team-old")},
+	}); err != nil {
+		t.Fatalf("SyncCMDBMetadata(pull) error = %v", err)
+	}
+
+	// The next pull replaces the pulled entries and takes over the shared key
+	result, err := db.SyncCMDBMetadata("pull", []CMDBEntry{
+		{Namespace: "payments", CMDBAttributes: CMDBAttributes{Owner: "team-payments", DataClassification: "restricted"}},
+		{Namespace: "shared", CMDBAttributes: owner("team-pull")},
+	})
+	if err != nil {
+		t.Fatalf("SyncCMDBMetadata(pull) error = %v", err)
+	}
+	if result.Entries != 2 || result.Removed != 1 {
+		t.Errorf("SyncCMDBMetadata() = %+v, want 2 entries and 1 removed", result)
+	}
+
+	entries, err := db.GetCMDBMetadata()
+	if err != nil {
+		t.Fatalf("GetCMDBMetadata() error = %v", err)
+	}
+	got := map[string]string{}
+	for _, e := range entries {
+		got[e.Namespace] = e.Owner + "/" + e.Source
+	}
+	if want := map[string]string{"payments": "team-payments/pull", "shared": "team-pull/pull"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("GetCMDBMetadata() = %v, want %v", got, want)
+	}
+
+	opts, err := db.GetFilterOptions()
+	if err != nil {
+		t.Fatalf("GetFilterOptions() error = %v", err)
+	}
+	if !reflect.DeepEqual(opts.Classifications, []string{"restricted"}) {
+		t.Errorf("Classifications = %v, want [restricted]", opts.Classifications)
+	}
+}
+
+func TestValidateCMDBEntries(t *testing.T) {
+	attrs := CMDBAttributes{Owner: "team-a"}
+	for name, entries := range map[string][]CMDBEntry{
+		"no key":            {{CMDBAttributes: attrs}},
+		"two keys":          {{Namespace: "a", Label: ociSourceLabel, LabelValue: "x", CMDBAttributes: attrs}},
+		"unsupported label": {{Label: "team", LabelValue: "a", CMDBAttributes: attrs}},
+		"no label value":    {{Label: ociSourceLabel, CMDBAttributes: attrs}},
+		"no attributes":     {{Namespace: "a"}},
+		"duplicate key":     {{Namespace: "a", CMDBAttributes: attrs}, {Namespace: " a ", CMDBAttributes: attrs}},
+		"too long":          {{Namespace: strings.Repeat("a", MaxCMDBValueLength+1), CMDBAttributes: attrs}},
+	} {
+		if err := ValidateCMDBEntries(entries); err == nil {
+			t.Errorf("%s: ValidateCMDBEntries() succeeded, want error", name)
+		}
+	}
+}
+
+func TestPolicyFactsIncludeCMDBMetadata(t *testing.T) {
+	db, err := New(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("failed to create database: %v", err)
+	}
+	defer func() { _ = Close(db) }()
+
+	if _, err := db.AddContainer(containers.Container{
+		ID:    containers.ContainerID{Namespace: "payments", Pod: "ledger-1", Name: "ledger"},
+		Image: containers.ImageID{Reference: "ledger:1", Digest: "sha256:ledger"},
+	}); err != nil {
+		t.Fatalf("AddContainer() error = %v", err)
+	}
+	if _, err := db.conn.Exec(`UPDATE images SET oci_source = 'https://github.com/acme/ledger' WHERE digest = 'sha256:ledger'`); err != nil {
+		t.Fatal(err)
+	}
+
+	// The label entry wins for the owner; the namespace fills in the rest
+	if _, err := db.SyncCMDBMetadata("pull", []CMDBEntry{
+		{Namespace: "payments", CMDBAttributes: CMDBAttributes{ApplicationID: "APP-1", Owner: "team-payments"}},
+		{Label: ociSourceLabel, LabelValue: "https://github.com/acme/ledger", CMDBAttributes: CMDBAttributes{Owner: "team-ledger"}},
+	}); err != nil {
+		t.Fatalf("SyncCMDBMetadata() error = %v", err)
+	}
+
+	facts, err := db.GetImagePolicyFacts("sha256:ledger")
+	if err != nil || facts == nil {
+		t.Fatalf("GetImagePolicyFacts() = %v, %v", facts, err)
+	}
+	if want := map[string]string{CMDBApplicationID: "APP-1", CMDBOwner: "team-ledger"}; !reflect.DeepEqual(facts.Metadata, want) {
+		t.Errorf("Metadata = %v, want %v", facts.Metadata, want)
+	}
+
+	images, err := db.GetReportImages()
+	if err != nil || len(images) != 1 {
+		t.Fatalf("GetReportImages() = %v, %v", images, err)
+	}
+	if images[0].Metadata.Owner != "team-ledger" || images[0].Metadata.ApplicationID != "APP-1" {
+		t.Errorf("report Metadata = %+v", images[0].Metadata)
+	}
+}
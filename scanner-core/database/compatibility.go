@@ -0,0 +1,157 @@
+package database
+
+import "fmt"
+
+// BinaryVersion is a binary version that ran against the database as the
+// writer, with the schema versions it requires
+type BinaryVersion struct {
+	Version                    string `json:"version"`
+	SchemaVersion              int    `json:"schema_version"`
+	MinCompatibleSchemaVersion int    `json:"min_compatible_schema_version"`
+	FirstStartedAt             string `json:"first_started_at"`
+	LastStartedAt              string `json:"last_started_at"`
+}
+
+// Compatibility reports what the database schema allows when upgrading or
+// downgrading the binary, so rollouts across many deployments can be
+// sequenced
+type Compatibility struct {
+	BinaryVersion       string `json:"binary_version"`
+	SchemaVersion       int    `json:"schema_version"`        // version of the database
+	TargetSchemaVersion int    `json:"target_schema_version"` // version this binary migrates to
+	// Oldest schema version whose binaries can run against the database
+	// schema; 0 when the binary that migrated the database did not record it
+	MinCompatibleSchemaVersion int `json:"min_compatible_schema_version"`
+	// Oldest binary version seen on this database that can run against its
+	// schema, i.e. how far a downgrade can go; empty when none is known
+	MinDowngradeVersion string `json:"min_downgrade_version,omitempty"`
+	// Compatible reports whether this binary can run against the database
+	// schema: always for older schemas, which it migrates, and for newer
+	// schemas when it is at least MinCompatibleSchemaVersion
+	Compatible bool `json:"compatible"`
+	// MigrationOnRestart reports whether migrations run on the next start of
+	// this binary as the writer: schema migrations (the database is behind,
+	// e.g. on a read-only follower) or unfinished online migrations
+	MigrationOnRestart      bool              `json:"migration_on_restart"`
+	PendingMigrations       []SchemaMigration `json:"pending_migrations"`
+	PendingOnlineMigrations []string          `json:"pending_online_migrations"`
+	Binaries                []BinaryVersion   `json:"binaries"` // oldest first
+}
+
+// RecordBinaryVersion records that binaryVersion started as the writer of
+// the database. Development builds are not recorded, since they do not
+// identify a release to downgrade to.
+func (db *DB) RecordBinaryVersion(binaryVersion string) error {
+	if binaryVersion == "" || binaryVersion == "dev" {
+		return nil
+	}
+	done := db.beginWrite("record_binary_version")
+	defer done()
+	_, err := db.conn.Exec(`
+		INSERT INTO binary_versions (version, schema_version, min_compatible_schema_version)
+		VALUES (?, ?, ?)
+		ON CONFLICT(version) DO UPDATE SET
+			schema_version = excluded.schema_version,
+			min_compatible_schema_version = excluded.min_compatible_schema_version,
+			last_started_at = `+sqlNow+`
+	`, binaryVersion, currentSchemaVersion, minCompatibleSchemaVersion)
+	if err != nil {
+		exitOnCorruption(err)
+		return fmt.Errorf("failed to record binary version: %w", err)
+	}
+	return nil
+}
+
+// GetCompatibility reports the compatibility of binaryVersion (this binary)
+// with the database schema
+func (db *DB) GetCompatibility(binaryVersion string) (*Compatibility, error) {
+	status, err := db.GetMigrationStatus()
+	if err != nil {
+		return nil, err
+	}
+	binaries, err := db.getBinaryVersions()
+	if err != nil {
+		return nil, err
+	}
+
+	result := &Compatibility{
+		BinaryVersion:           binaryVersion,
+		SchemaVersion:           status.SchemaVersion,
+		TargetSchemaVersion:     currentSchemaVersion,
+		PendingMigrations:       []SchemaMigration{},
+		PendingOnlineMigrations: []string{},
+		Binaries:                binaries,
+	}
+
+	// The floor of the database schema is known to this binary for its own
+	// schema version, and otherwise recorded by the binaries that ran it
+	if status.SchemaVersion == currentSchemaVersion {
+		result.MinCompatibleSchemaVersion = minCompatibleSchemaVersion
+	} else {
+		for _, b := range binaries {
+			if b.SchemaVersion == status.SchemaVersion && b.MinCompatibleSchemaVersion > result.MinCompatibleSchemaVersion {
+				result.MinCompatibleSchemaVersion = b.MinCompatibleSchemaVersion
+			}
+		}
+	}
+	if result.MinCompatibleSchemaVersion > 0 {
+		for _, b := range binaries {
+			if b.SchemaVersion >= result.MinCompatibleSchemaVersion && b.SchemaVersion <= status.SchemaVersion {
+				result.MinDowngradeVersion = b.Version
+				break
+			}
+		}
+	}
+
+	if status.SchemaVersion <= currentSchemaVersion {
+		result.Compatible = true
+	} else {
+		result.Compatible = result.MinCompatibleSchemaVersion > 0 && currentSchemaVersion >= result.MinCompatibleSchemaVersion
+	}
+
+	for _, m := range migrations {
+		if m.version > status.SchemaVersion {
+			result.PendingMigrations = append(result.PendingMigrations, SchemaMigration{Version: m.version, Name: m.name})
+		}
+	}
+	for _, m := range status.Online {
+		if m.State != OnlineMigrationCompleted {
+			result.PendingOnlineMigrations = append(result.PendingOnlineMigrations, m.Name)
+		}
+	}
+	result.MigrationOnRestart = len(result.PendingMigrations) > 0 || len(result.PendingOnlineMigrations) > 0
+	return result, nil
+}
+
+// getBinaryVersions returns the recorded binary versions, oldest first. A
+// database migrated by a binary older than the history has none.
+func (db *DB) getBinaryVersions() ([]BinaryVersion, error) {
+	binaries := []BinaryVersion{}
+	var exists int
+	if err := db.conn.QueryRow(`
+		SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = 'binary_versions'
+	`).Scan(&exists); err != nil {
+		return nil, fmt.Errorf("failed to check binary version history: %w", err)
+	}
+	if exists == 0 {
+		return binaries, nil
+	}
+
+	rows, err := db.conn.Query(`
+		SELECT version, schema_version, min_compatible_schema_version, first_started_at, last_started_at
+		FROM binary_versions
+		ORDER BY first_started_at, schema_version, version
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query binary versions: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+	for rows.Next() {
+		var b BinaryVersion
+		if err := rows.Scan(&b.Version, &b.SchemaVersion, &b.MinCompatibleSchemaVersion, &b.FirstStartedAt, &b.LastStartedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan binary version: %w", err)
+		}
+		binaries = append(binaries, b)
+	}
+	return binaries, rows.Err()
+}
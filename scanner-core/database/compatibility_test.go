@@ -0,0 +1,73 @@
+package database
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+)
+
+func TestCompatibility(t *testing.T) {
+	db, err := New(filepath.Join(t.TempDir(), "compat.db"))
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	defer func() { _ = Close(db) }()
+	// The writer runs the online migrations after startup
+	if err := db.RunOnlineMigrations(context.Background()); err != nil {
+		t.Fatalf("RunOnlineMigrations() error = %v", err)
+	}
+
+	for _, version := range []string{"dev", "v0.1.163", "v0.1.164", "v0.1.164"} {
+		if err := db.RecordBinaryVersion(version); err != nil {
+			t.Fatalf("RecordBinaryVersion(%s) error = %v", version, err)
+		}
+	}
+
+	got, err := db.GetCompatibility("v0.1.164")
+	if err != nil {
+		t.Fatalf("GetCompatibility() error = %v", err)
+	}
+	if got.SchemaVersion != currentSchemaVersion || got.TargetSchemaVersion != currentSchemaVersion ||
+		got.MinCompatibleSchemaVersion != minCompatibleSchemaVersion || !got.Compatible || got.MigrationOnRestart {
+		t.Errorf("GetCompatibility() = %+v, want an up-to-date compatible schema", got)
+	}
+	if len(got.Binaries) != 2 || got.Binaries[0].Version != "v0.1.163" || got.MinDowngradeVersion != "v0.1.163" {
+		t.Errorf("binaries = %+v, min downgrade = %q; want v0.1.163 and v0.1.164 without dev", got.Binaries, got.MinDowngradeVersion)
+	}
+
+	// A newer release raised the floor: this binary's schema is too old to
+	// run against it, and the older releases are no downgrade target
+	if _, err := db.conn.Exec(`INSERT INTO binary_versions (version, schema_version, min_compatible_schema_version, first_started_at)
+		VALUES ('v0.2.0', ?, ?, '2999-01-01T00:00:00Z')`, currentSchemaVersion+1, currentSchemaVersion+1); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.conn.Exec(`INSERT INTO schema_migrations (version, name) VALUES (?, 'future')`, currentSchemaVersion+1); err != nil {
+		t.Fatal(err)
+	}
+	if got, err = db.GetCompatibility("v0.1.164"); err != nil {
+		t.Fatalf("GetCompatibility() error = %v", err)
+	}
+	if got.Compatible || got.MinCompatibleSchemaVersion != currentSchemaVersion+1 || got.MinDowngradeVersion != "v0.2.0" {
+		t.Errorf("GetCompatibility() on a newer schema = %+v, want incompatible with v0.2.0 as the floor", got)
+	}
+}
+
+func TestCompatibilityPendingMigrations(t *testing.T) {
+	db, err := New(filepath.Join(t.TempDir(), "compat.db"))
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	defer func() { _ = Close(db) }()
+
+	// A database left behind by an older writer, as a follower sees it
+	if _, err := db.conn.Exec(`DELETE FROM schema_migrations WHERE version = ?`, currentSchemaVersion); err != nil {
+		t.Fatal(err)
+	}
+	got, err := db.GetCompatibility("v0.1.164")
+	if err != nil {
+		t.Fatalf("GetCompatibility() error = %v", err)
+	}
+	if !got.Compatible || !got.MigrationOnRestart || len(got.PendingMigrations) != 1 || got.PendingMigrations[0].Version != currentSchemaVersion {
+		t.Errorf("GetCompatibility() = %+v, want migration %d pending", got, currentSchemaVersion)
+	}
+}
@@ -0,0 +1,167 @@
+package database
+
+import (
+	"fmt"
+	"time"
+)
+
+// maxUnscannedImages limits the unscanned images listed in ScanCoverage
+const maxUnscannedImages = 100
+
+// ObservedImage is an image digest seen in a workload that may no longer be
+// running, such as a completed Kubernetes Job, or not be running yet, such as
+// the image of a Deployment about to roll out
+type ObservedImage struct {
+	Digest    string
+	Reference string
+	Namespace string
+	Workload  string // e.g. job/nightly-backup, deployment/web
+	SeenAt    time.Time
+}
+
+// UnscannedImage is an observed digest without a completed scan
+type UnscannedImage struct {
+	Digest    string `json:"digest"`
+	Reference string `json:"reference"`
+	Status    string `json:"status"`
+}
+
+// ScanCoverage reports which fraction of the digests observed in the cluster
+// has a completed scan
+type ScanCoverage struct {
+	Since            time.Time        `json:"since"`
+	ObservedDigests  int              `json:"observed_digests"`
+	ScannedDigests   int              `json:"scanned_digests"`
+	RunningDigests   int              `json:"running_digests"`
+	JobOnlyDigests   int              `json:"job_only_digests"`
+	CoveragePercent  float64          `json:"coverage_percent"`
+	UnscannedDigests []UnscannedImage `json:"unscanned_digests"`
+}
+
+// RecordObservedImages upserts observed digests, updating when they were last seen
+func (db *DB) RecordObservedImages(images []ObservedImage) error {
+	if len(images) == 0 {
+		return nil
+	}
+
+	done := db.beginWrite("record_observed_images")
+	defer done()
+
+	tx, err := db.conn.Begin()
+	if err != nil {
+		exitOnCorruption(err)
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	stmt, err := tx.Prepare(`
+		INSERT INTO observed_images (digest, reference, namespace, workload, first_seen_at, last_seen_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT(digest) DO UPDATE SET
+			reference = excluded.reference,
+			namespace = excluded.namespace,
+			workload = excluded.workload,
+			last_seen_at = MAX(last_seen_at, excluded.last_seen_at)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to prepare observed image upsert: %w", err)
+	}
+	defer func() { _ = stmt.Close() }()
+
+	for _, img := range images {
+		seenAt := img.SeenAt.UTC().Format(time.RFC3339)
+		if _, err := stmt.Exec(img.Digest, img.Reference, img.Namespace, img.Workload, seenAt, seenAt); err != nil {
+			exitOnCorruption(err)
+			return fmt.Errorf("failed to record observed image: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		exitOnCorruption(err)
+		return fmt.Errorf("failed to commit observed images: %w", err)
+	}
+	db.notifyWrite()
+	return nil
+}
+
+// PruneObservedImages deletes observed digests last seen before the given time
+func (db *DB) PruneObservedImages(before time.Time) (int64, error) {
+	done := db.beginWrite("prune_observed_images")
+	defer done()
+
+	result, err := db.conn.Exec(`DELETE FROM observed_images WHERE last_seen_at < ?`, before.UTC().Format(time.RFC3339))
+	if err != nil {
+		exitOnCorruption(err)
+		return 0, fmt.Errorf("failed to prune observed images: %w", err)
+	}
+	return result.RowsAffected()
+}
+
+// GetScanCoverage computes scan coverage over all digests of running containers
+// plus digests observed (e.g. in completed Jobs) since the given time.
+// A digest counts as scanned when its image has status completed.
+func (db *DB) GetScanCoverage(since time.Time) (*ScanCoverage, error) {
+	coverage := &ScanCoverage{Since: since.UTC(), UnscannedDigests: []UnscannedImage{}}
+
+	rows, err := db.conn.Query(`
+		WITH observed AS (
+			SELECT img.digest AS digest, MIN(c.reference) AS reference, 1 AS running
+			FROM containers c
+			JOIN images img ON c.image_id = img.id
+			GROUP BY img.digest
+			UNION ALL
+			SELECT digest, reference, 0 AS running
+			FROM observed_images
+			WHERE last_seen_at >= ?
+		),
+		digests AS (
+			SELECT digest, MIN(reference) AS reference, MAX(running) AS running
+			FROM observed
+			GROUP BY digest
+		)
+		SELECT d.digest, d.reference, d.running, COALESCE(img.status, '')
+		FROM digests d
+		LEFT JOIN images img ON img.digest = d.digest
+		ORDER BY d.reference, d.digest
+	`, since.UTC().Format(time.RFC3339))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query scan coverage: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	for rows.Next() {
+		var digest, reference, status string
+		var running int
+		if err := rows.Scan(&digest, &reference, &running, &status); err != nil {
+			return nil, fmt.Errorf("failed to scan coverage row: %w", err)
+		}
+		coverage.ObservedDigests++
+		if running == 1 {
+			coverage.RunningDigests++
+		} else {
+			coverage.JobOnlyDigests++
+		}
+		if Status(status) == StatusCompleted {
+			coverage.ScannedDigests++
+			continue
+		}
+		if status == "" {
+			status = "not_tracked"
+		}
+		if len(coverage.UnscannedDigests) < maxUnscannedImages {
+			coverage.UnscannedDigests = append(coverage.UnscannedDigests, UnscannedImage{
+				Digest: digest, Reference: reference, Status: status,
+			})
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate scan coverage: %w", err)
+	}
+
+	if coverage.ObservedDigests > 0 {
+		coverage.CoveragePercent = float64(coverage.ScannedDigests) * 100 / float64(coverage.ObservedDigests)
+	} else {
+		coverage.CoveragePercent = 100
+	}
+	return coverage, nil
+}
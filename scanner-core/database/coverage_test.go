@@ -0,0 +1,73 @@
+package database
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/bvboe/b2s-go/scanner-core/containers"
+)
+
+// TestGetScanCoverage verifies that coverage counts running containers and
+// recently observed Job digests, and ignores Job digests outside the lookback.
+func TestGetScanCoverage(t *testing.T) {
+	db, err := New(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("failed to create database: %v", err)
+	}
+	defer func() { _ = Close(db) }()
+
+	coverage, err := db.GetScanCoverage(time.Now().Add(-time.Hour))
+	if err != nil {
+		t.Fatalf("GetScanCoverage() error = %v", err)
+	}
+	if coverage.ObservedDigests != 0 || coverage.CoveragePercent != 100 {
+		t.Errorf("empty cluster coverage = %+v, want 0 observed at 100%%", coverage)
+	}
+
+	running := containers.Container{
+		ID:    containers.ContainerID{Namespace: "default", Pod: "web", Name: "nginx"},
+		Image: containers.ImageID{Reference: "nginx:1.25", Digest: "sha256:running"},
+	}
+	if _, err := db.AddContainer(running); err != nil {
+		t.Fatalf("AddContainer() error = %v", err)
+	}
+	if err := db.UpdateStatus("sha256:running", StatusCompleted, ""); err != nil {
+		t.Fatalf("UpdateStatus() error = %v", err)
+	}
+
+	now := time.Now()
+	if err := db.RecordObservedImages([]ObservedImage{
+		{Digest: "sha256:job", Reference: "restic:0.16", Namespace: "ops", Workload: "job/backup", SeenAt: now},
+		{Digest: "sha256:old", Reference: "busybox:1", Namespace: "ops", Workload: "job/old", SeenAt: now.Add(-48 * time.Hour)},
+		{Digest: "sha256:running", Reference: "nginx:1.25", Namespace: "default", Workload: "job/warmup", SeenAt: now},
+	}); err != nil {
+		t.Fatalf("RecordObservedImages() error = %v", err)
+	}
+
+	coverage, err = db.GetScanCoverage(now.Add(-24 * time.Hour))
+	if err != nil {
+		t.Fatalf("GetScanCoverage() error = %v", err)
+	}
+	if coverage.ObservedDigests != 2 || coverage.ScannedDigests != 1 {
+		t.Errorf("observed/scanned = %d/%d, want 2/1", coverage.ObservedDigests, coverage.ScannedDigests)
+	}
+	if coverage.RunningDigests != 1 || coverage.JobOnlyDigests != 1 {
+		t.Errorf("running/job-only = %d/%d, want 1/1", coverage.RunningDigests, coverage.JobOnlyDigests)
+	}
+	if coverage.CoveragePercent != 50 {
+		t.Errorf("CoveragePercent = %v, want 50", coverage.CoveragePercent)
+	}
+	if len(coverage.UnscannedDigests) != 1 || coverage.UnscannedDigests[0].Digest != "sha256:job" ||
+		coverage.UnscannedDigests[0].Status != "not_tracked" {
+		t.Errorf("UnscannedDigests = %+v, want sha256:job not_tracked", coverage.UnscannedDigests)
+	}
+
+	pruned, err := db.PruneObservedImages(now.Add(-24 * time.Hour))
+	if err != nil {
+		t.Fatalf("PruneObservedImages() error = %v", err)
+	}
+	if pruned != 1 {
+		t.Errorf("PruneObservedImages() = %d, want 1", pruned)
+	}
+}
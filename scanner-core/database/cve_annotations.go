@@ -0,0 +1,162 @@
+package database
+
+import (
+	"fmt"
+	"strings"
+)
+
+// MaxCVEAnnotationLength bounds the note an analyst can attach to a vulnerability
+const MaxCVEAnnotationLength = 4096
+
+// CVEAnnotation is a cluster-wide analyst note on a vulnerability (e.g.
+// "mitigated by WAF rule 123"). It is keyed by vulnerability ID, matched
+// case-insensitively, so it shows up in every image the vulnerability appears in.
+type CVEAnnotation struct {
+	CVEID     string `json:"cve_id"`
+	Note      string `json:"note"`
+	Author    string `json:"author,omitempty"`
+	CreatedAt string `json:"created_at,omitempty"`
+	UpdatedAt string `json:"updated_at,omitempty"`
+}
+
+// SetCVEAnnotation creates or replaces the note on a vulnerability
+func (db *DB) SetCVEAnnotation(cveID, note, author string) (*CVEAnnotation, error) {
+	cveID, note = strings.TrimSpace(cveID), strings.TrimSpace(note)
+	if cveID == "" || note == "" {
+		return nil, fmt.Errorf("vulnerability ID and note are required")
+	}
+	if len(note) > MaxCVEAnnotationLength {
+		return nil, fmt.Errorf("note exceeds %d characters", MaxCVEAnnotationLength)
+	}
+
+	done := db.beginWrite("set_cve_annotation")
+	defer done()
+	_, err := db.conn.Exec(`
+		INSERT INTO cve_annotations (cve_id, note, author)
+		VALUES (?, ?, ?)
+		ON CONFLICT(cve_id) DO UPDATE SET
+			note = excluded.note,
+			author = excluded.author,
+			updated_at = ` + sqlNow + `
+	`, cveID, note, strings.TrimSpace(author))
+	if err != nil {
+		exitOnCorruption(err)
+		return nil, fmt.Errorf("failed to save CVE annotation: %w", err)
+	}
+	db.notifyWrite()
+	return db.GetCVEAnnotation(cveID)
+}
+
+// DeleteCVEAnnotation removes the note on a vulnerability. Reports whether
+// there was one.
+func (db *DB) DeleteCVEAnnotation(cveID string) (bool, error) {
+	done := db.beginWrite("delete_cve_annotation")
+	defer done()
+	result, err := db.conn.Exec(`DELETE FROM cve_annotations WHERE cve_id = ?`, strings.TrimSpace(cveID))
+	if err != nil {
+		exitOnCorruption(err)
+		return false, fmt.Errorf("failed to delete CVE annotation: %w", err)
+	}
+	deleted, _ := result.RowsAffected()
+	if deleted > 0 {
+		db.notifyWrite()
+	}
+	return deleted > 0, nil
+}
+
+// GetCVEAnnotation returns the note on a vulnerability, or nil if it has none
+func (db *DB) GetCVEAnnotation(cveID string) (*CVEAnnotation, error) {
+	annotations, err := db.queryCVEAnnotations(`WHERE cve_id = ?`, strings.TrimSpace(cveID))
+	if err != nil || len(annotations) == 0 {
+		return nil, err
+	}
+	return &annotations[0], nil
+}
+
+// GetCVEAnnotations returns all vulnerability notes ordered by vulnerability ID
+func (db *DB) GetCVEAnnotations() ([]CVEAnnotation, error) {
+	return db.queryCVEAnnotations("")
+}
+
+// GetImageCVEAnnotations returns the notes on the vulnerabilities found in an
+// image, for exporting them along with its scan results
+func (db *DB) GetImageCVEAnnotations(digest string) ([]CVEAnnotation, error) {
+	return db.queryCVEAnnotations(`
+		WHERE cve_id IN (
+			SELECT v.cve_id FROM image_vulnerabilities v
+			JOIN images i ON v.image_id = i.id
+			WHERE i.digest = ?
+		)`, digest)
+}
+
+// ImportCVEAnnotations adds notes exported by another deployment. Notes on
+// vulnerabilities already annotated here are kept. Returns the number added.
+func (db *DB) ImportCVEAnnotations(annotations []CVEAnnotation) (int, error) {
+	if len(annotations) == 0 {
+		return 0, nil
+	}
+
+	done := db.beginWrite("import_cve_annotations")
+	defer done()
+	tx, err := db.conn.Begin()
+	if err != nil {
+		exitOnCorruption(err)
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	added := 0
+	for _, a := range annotations {
+		cveID, note := strings.TrimSpace(a.CVEID), strings.TrimSpace(a.Note)
+		if cveID == "" || note == "" || len(note) > MaxCVEAnnotationLength {
+			continue
+		}
+		result, err := tx.Exec(`
+			INSERT INTO cve_annotations (cve_id, note, author)
+			VALUES (?, ?, ?)
+			ON CONFLICT(cve_id) DO NOTHING
+		`, cveID, note, strings.TrimSpace(a.Author))
+		if err != nil {
+			exitOnCorruption(err)
+			return 0, fmt.Errorf("failed to import CVE annotation: %w", err)
+		}
+		if n, _ := result.RowsAffected(); n > 0 {
+			added++
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		exitOnCorruption(err)
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	if added > 0 {
+		db.notifyWrite()
+	}
+	return added, nil
+}
+
+// queryCVEAnnotations returns the annotations matching the where clause
+func (db *DB) queryCVEAnnotations(where string, args ...interface{}) ([]CVEAnnotation, error) {
+	rows, err := db.conn.Query(`
+		SELECT cve_id, note, author, created_at, updated_at
+		FROM cve_annotations `+where+`
+		ORDER BY cve_id
+	`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query CVE annotations: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	annotations := []CVEAnnotation{}
+	for rows.Next() {
+		var a CVEAnnotation
+		if err := rows.Scan(&a.CVEID, &a.Note, &a.Author, &a.CreatedAt, &a.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan CVE annotation: %w", err)
+		}
+		annotations = append(annotations, a)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate CVE annotations: %w", err)
+	}
+	return annotations, nil
+}
@@ -0,0 +1,72 @@
+package database
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestCVEAnnotations(t *testing.T) {
+	db, err := New(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("Failed to create database: %v", err)
+	}
+	defer func() { _ = Close(db) }()
+
+	digest := "sha256:annotated"
+	vulns := []byte(`{"matches":[{"vulnerability":{"id":"CVE-2024-0001","severity":"High"},"artifact":{"name":"openssl","version":"3.0.0","type":"apk"}}]}`)
+	if err := db.ImportScanResults(digest, []byte(`{"artifacts":[]}`), vulns, time.Time{}); err != nil {
+		t.Fatalf("ImportScanResults() error = %v", err)
+	}
+
+	if _, err := db.SetCVEAnnotation("CVE-2024-0001", "  ", "alice"); err == nil {
+		t.Error("SetCVEAnnotation() accepted an empty note")
+	}
+	if _, err := db.SetCVEAnnotation("CVE-2024-0001", "under review", "alice"); err != nil {
+		t.Fatalf("SetCVEAnnotation() error = %v", err)
+	}
+	// IDs match case-insensitively, so this replaces the note
+	saved, err := db.SetCVEAnnotation("cve-2024-0001", "mitigated by WAF rule 123", "bob")
+	if err != nil {
+		t.Fatalf("SetCVEAnnotation() error = %v", err)
+	}
+	if saved.CVEID != "CVE-2024-0001" || saved.Note != "mitigated by WAF rule 123" || saved.Author != "bob" || saved.UpdatedAt == "" {
+		t.Errorf("saved annotation = %+v", saved)
+	}
+	if _, err := db.SetCVEAnnotation("CVE-2023-9999", "not deployed here", ""); err != nil {
+		t.Fatalf("SetCVEAnnotation() error = %v", err)
+	}
+
+	all, err := db.GetCVEAnnotations()
+	if err != nil || len(all) != 2 {
+		t.Fatalf("GetCVEAnnotations() = %+v, %v", all, err)
+	}
+	image, err := db.GetImageCVEAnnotations(digest)
+	if err != nil || len(image) != 1 || image[0].CVEID != "CVE-2024-0001" {
+		t.Errorf("GetImageCVEAnnotations() = %+v, %v", image, err)
+	}
+
+	// Imports keep local notes
+	added, err := db.ImportCVEAnnotations([]CVEAnnotation{
+		{CVEID: "CVE-2024-0001", Note: "not affected"},
+		{CVEID: "CVE-2022-0001", Note: "accepted risk", Author: "carol"},
+		{CVEID: "CVE-2022-0002"},
+	})
+	if err != nil || added != 1 {
+		t.Fatalf("ImportCVEAnnotations() = %d, %v, want 1 added", added, err)
+	}
+	if a, _ := db.GetCVEAnnotation("CVE-2024-0001"); a == nil || a.Note != "mitigated by WAF rule 123" {
+		t.Errorf("local note replaced by import: %+v", a)
+	}
+
+	deleted, err := db.DeleteCVEAnnotation("CVE-2022-0001")
+	if err != nil || !deleted {
+		t.Errorf("DeleteCVEAnnotation() = %v, %v", deleted, err)
+	}
+	if deleted, _ := db.DeleteCVEAnnotation("CVE-2022-0001"); deleted {
+		t.Error("DeleteCVEAnnotation() deleted a missing annotation")
+	}
+	if a, err := db.GetCVEAnnotation("CVE-2022-0001"); a != nil || err != nil {
+		t.Errorf("GetCVEAnnotation() after delete = %+v, %v", a, err)
+	}
+}
@@ -15,6 +15,8 @@ import (
 	"time"
 
 	"github.com/bvboe/b2s-go/scanner-core/logging"
+	"github.com/bvboe/b2s-go/scanner-core/severity"
+	"github.com/bvboe/b2s-go/scanner-core/vex"
 )
 
 var log = logging.For(logging.ComponentDatabase)
@@ -26,6 +28,11 @@ var log = logging.For(logging.ComponentDatabase)
 type DB struct {
 	writeMu sync.Mutex
 	conn    *sql.DB
+	faults  *writeFault // injected write failures, see SetWriteFaultRate
+
+	// readOnly is set for databases opened with OpenReadOnly. Another process
+	// writes to them, so the write-invalidated caches below are bypassed.
+	readOnly bool
 
 	// in-memory caches — updated by notifyWrite() after every successful write
 	cachesMu       sync.RWMutex
@@ -46,6 +53,19 @@ type DB struct {
 	// (too frequent: ~5k/5d on kubeadm); the TTL bounds staleness instead.
 	containerVulnRows     []ContainerVulnerability
 	containerVulnBuilding atomic.Bool
+
+	// severities maps reported severities to stored ones (see SetSeverityMapping)
+	severities atomic.Pointer[severity.Mapping]
+
+	// vex holds the OpenVEX statements suppressing findings (see SyncVEXFiles)
+	vex atomic.Pointer[vex.Index]
+
+	// ignores holds the active vulnerability ignores (see SaveVulnerabilityIgnore)
+	ignores atomic.Pointer[ignoreSet]
+
+	// slowQueryThreshold is the duration (ns) above which ExecuteQuery logs a
+	// query; 0 disables the slow query log (see SetSlowQueryThreshold)
+	slowQueryThreshold atomic.Int64
 }
 
 // StartNodeVulnCacheRefresh warms the node vulnerability cache immediately and
@@ -152,6 +172,7 @@ func (db *DB) notifyWrite() {
 
 // seedLastUpdated reads the current last-updated signature from the DB once
 // so that the in-memory cache is non-empty before any write occurs on this run.
+// Read-only databases call it on every poll instead.
 func (db *DB) seedLastUpdated() {
 	var sig sql.NullString
 	_ = db.conn.QueryRow(`
@@ -224,7 +245,8 @@ func deleteDatabase(dbPath string) error {
 // If the database is corrupted, it will be deleted and recreated
 func New(dbPath string) (*DB, error) {
 	// Try to open the database
-	conn, err := sql.Open("sqlite", dbPath)
+	faults := &writeFault{}
+	conn, err := openWithFaults(dbPath, faults)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open database: %w", err)
 	}
@@ -242,7 +264,7 @@ func New(dbPath string) (*DB, error) {
 			}
 
 			// Try again with fresh database
-			conn, err = sql.Open("sqlite", dbPath)
+			conn, err = openWithFaults(dbPath, faults)
 			if err != nil {
 				return nil, fmt.Errorf("failed to create new database: %w", err)
 			}
@@ -280,7 +302,7 @@ func New(dbPath string) (*DB, error) {
 		return nil, fmt.Errorf("failed to configure database: %w", err)
 	}
 
-	db := &DB{conn: conn}
+	db := &DB{conn: conn, faults: faults}
 
 	// Checkpoint WAL on startup to merge any writes from before an unclean shutdown
 	// (e.g. OOM kill). Without this the WAL can grow to the same size as the main DB
@@ -339,6 +361,40 @@ func New(dbPath string) (*DB, error) {
 	return db, nil
 }
 
+// OpenReadOnly opens a database another process writes to, for serving reads
+// next to it: it neither migrates nor repairs the database, which is the
+// writer's job, and fails if the writer has not created the schema yet.
+func OpenReadOnly(dbPath string) (*DB, error) {
+	if _, err := os.Stat(dbPath); err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+	faults := &writeFault{}
+	conn, err := openWithFaults("file:"+dbPath+"?mode=ro&_pragma=busy_timeout(30000)", faults)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+	conn.SetMaxOpenConns(5)
+	conn.SetMaxIdleConns(5)
+
+	db := &DB{conn: conn, faults: faults, readOnly: true}
+	version, err := db.getCurrentVersion()
+	if err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+	if version == 0 {
+		_ = conn.Close()
+		return nil, fmt.Errorf("database %s has no schema yet", dbPath)
+	}
+	if version != currentSchemaVersion {
+		log.Warn("read-only database has a different schema version, some queries may fail until the writer is upgraded",
+			"current_version", version, "target_version", currentSchemaVersion)
+	}
+
+	log.Info("database opened read-only", "path", dbPath)
+	return db, nil
+}
+
 // ResetInterruptedScans resets any nodes or images left in transient scan states
 // back to pending. This happens when the server crashes or is OOM-killed mid-scan.
 // Should be called once at startup, before the scan queue and watchers are started.
@@ -357,7 +413,7 @@ func (db *DB) ResetInterruptedScans() error {
 	nodeRows, _ := res.RowsAffected()
 
 	res, err = db.conn.Exec(`
-		UPDATE images SET status = 'pending'
+		UPDATE images SET status = 'pending', status_changed_at = ` + sqlNow + `
 		WHERE status IN ('generating_sbom', 'scanning_vulnerabilities')
 	`)
 	if err != nil {
@@ -392,7 +448,7 @@ func (db *DB) ReapStuckScans(maxAge time.Duration) (nodeRows, imageRows int64, e
 	done := db.beginWrite("reap_stuck_scans")
 	defer done()
 
-	cutoff := time.Now().UTC().Add(-maxAge).Format("2006-01-02 15:04:05")
+	cutoff := formatTimestamp(time.Now().Add(-maxAge))
 	const reapErr = "scan exceeded maximum duration and was reset by the stuck-scan reaper"
 
 	res, err := db.conn.Exec(`
@@ -407,7 +463,7 @@ func (db *DB) ReapStuckScans(maxAge time.Duration) (nodeRows, imageRows int64, e
 	nodeRows, _ = res.RowsAffected()
 
 	res, err = db.conn.Exec(`
-		UPDATE images SET status = 'vuln_scan_failed', status_error = ?
+		UPDATE images SET status = 'vuln_scan_failed', status_error = ?, status_changed_at = ` + sqlNow + `
 		WHERE status IN ('generating_sbom', 'scanning_vulnerabilities')
 		  AND updated_at < ?
 	`, reapErr, cutoff)
@@ -430,6 +486,9 @@ func Close(db *DB) error {
 	if db == nil || db.conn == nil {
 		return nil
 	}
+	if db.readOnly {
+		return db.conn.Close()
+	}
 
 	// Checkpoint WAL to ensure all data is written to main database
 	log.Info("checkpointing WAL before closing database")
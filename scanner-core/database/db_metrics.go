@@ -5,6 +5,7 @@ import (
 	"io"
 	"sort"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -101,6 +102,8 @@ var (
 	dbWriteWait = newDBHistogramVec() // time waiting to acquire writeMu
 	dbWriteExec = newDBHistogramVec() // time holding writeMu (transaction execution)
 	dbReadDur   = newDBHistogramVec() // time for read operations
+
+	slowQueries atomic.Uint64 // queries logged by the slow query log
 )
 
 // beginWrite acquires the write lock, records wait time, and returns a done
@@ -156,4 +159,10 @@ func WriteOpMetrics(w io.Writer) {
 		fpf(w, "# TYPE bjorn2scan_db_read_seconds histogram\n")
 		dbReadDur.write(w, "bjorn2scan_db_read_seconds", "operation")
 	}
+
+	if n := slowQueries.Load(); n > 0 {
+		fpf(w, "# HELP bjorn2scan_db_slow_queries_total API queries slower than the slow query threshold\n")
+		fpf(w, "# TYPE bjorn2scan_db_slow_queries_total counter\n")
+		fpf(w, "bjorn2scan_db_slow_queries_total %d\n", n)
+	}
 }
@@ -0,0 +1,269 @@
+package database
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// maxScanFailureLog bounds the error chain kept per image
+const maxScanFailureLog = 20
+
+// ScanFailure is one failed attempt to scan an image. Node is the node the
+// attempt ran on (empty for the agent and for attempts recorded before nodes
+// were tracked).
+type ScanFailure struct {
+	Status   Status `json:"status"`
+	Error    string `json:"error"`
+	Node     string `json:"node,omitempty"`
+	FailedAt string `json:"failed_at"`
+}
+
+// DeadLetteredScan is an image that failed to scan too many times in a row to
+// be retried automatically. NodeName and ContainerRuntime locate a container
+// currently running the image (empty if none does).
+type DeadLetteredScan struct {
+	Digest           string        `json:"digest"`
+	Reference        string        `json:"reference"`
+	NodeName         string        `json:"node_name,omitempty"`
+	ContainerRuntime string        `json:"container_runtime,omitempty"`
+	AdHoc            bool          `json:"ad_hoc"`
+	Registry         bool          `json:"registry"` // found by the registry crawl and not running
+	Status           Status        `json:"status"`
+	Attempts         int           `json:"attempts"`
+	DeadLetteredAt   string        `json:"dead_lettered_at"`
+	Failures         []ScanFailure `json:"failures"`
+}
+
+// RecordScanFailure appends a failed attempt on nodeName to the error chain of
+// an image. Once the image has failed maxAttempts times in a row it is
+// dead-lettered and its status becomes scan_failed_permanent (maxAttempts <= 0
+// never dead-letters). Returns whether this failure dead-lettered the image.
+func (db *DB) RecordScanFailure(digest, nodeName string, status Status, errorMsg string, maxAttempts int) (bool, error) {
+	done := db.beginWrite("record_scan_failure")
+	defer done()
+	tx, err := db.conn.Begin()
+	if err != nil {
+		exitOnCorruption(err)
+		return false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	var failures int
+	var failureLog sql.NullString
+	var deadLetteredAt sql.NullString
+	err = tx.QueryRow(`
+		SELECT scan_failures, scan_failure_log, dead_lettered_at FROM images WHERE digest = ?
+	`, digest).Scan(&failures, &failureLog, &deadLetteredAt)
+	if err == sql.ErrNoRows {
+		return false, nil // Image removed while it was being scanned
+	}
+	if err != nil {
+		exitOnCorruption(err)
+		return false, fmt.Errorf("failed to read scan failures: %w", err)
+	}
+
+	var chain []ScanFailure
+	if failureLog.Valid && failureLog.String != "" {
+		if err := json.Unmarshal([]byte(failureLog.String), &chain); err != nil {
+			log.Warn("discarding unreadable scan failure log", "digest", digest, "error", err)
+			chain = nil
+		}
+	}
+	chain = append(chain, ScanFailure{
+		Status:   status,
+		Error:    errorMsg,
+		Node:     nodeName,
+		FailedAt: time.Now().UTC().Format(time.RFC3339),
+	})
+	if len(chain) > maxScanFailureLog {
+		chain = chain[len(chain)-maxScanFailureLog:]
+	}
+	encoded, err := json.Marshal(chain)
+	if err != nil {
+		return false, fmt.Errorf("failed to encode scan failure log: %w", err)
+	}
+
+	failures++
+	deadLetter := maxAttempts > 0 && failures >= maxAttempts && !deadLetteredAt.Valid
+	_, err = tx.Exec(`
+		UPDATE images
+		SET scan_failures = ?,
+		    scan_failure_log = ?,
+		    dead_lettered_at = CASE WHEN ? THEN ` + sqlNow + ` ELSE dead_lettered_at END,
+		    status = CASE WHEN ? OR dead_lettered_at IS NOT NULL THEN ? ELSE status END
+		WHERE digest = ?
+	`, failures, string(encoded), deadLetter, deadLetter, StatusScanFailedPermanent, digest)
+	if err != nil {
+		exitOnCorruption(err)
+		return false, fmt.Errorf("failed to record scan failure: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		exitOnCorruption(err)
+		return false, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	db.notifyWrite()
+	return deadLetter, nil
+}
+
+// ClearScanFailures resets the failure count and error chain of an image and
+// takes it out of the dead-letter list, after a successful scan or when it is
+// requeued. A scan_failed_permanent image goes back to pending.
+func (db *DB) ClearScanFailures(digest string) error {
+	done := db.beginWrite("clear_scan_failures")
+	defer done()
+	result, err := db.conn.Exec(`
+		UPDATE images
+		SET scan_failures = 0,
+		    scan_failure_log = NULL,
+		    dead_lettered_at = NULL,
+		    status = CASE WHEN status = ? THEN ? ELSE status END
+		WHERE digest = ? AND (scan_failures > 0 OR dead_lettered_at IS NOT NULL)
+	`, StatusScanFailedPermanent, StatusPending, digest)
+	if err != nil {
+		exitOnCorruption(err)
+		return fmt.Errorf("failed to clear scan failures: %w", err)
+	}
+
+	if rows, _ := result.RowsAffected(); rows > 0 {
+		db.notifyWrite()
+	}
+	return nil
+}
+
+// IsDeadLettered reports whether an image is in the dead-letter list
+func (db *DB) IsDeadLettered(digest string) (bool, error) {
+	var deadLettered bool
+	err := db.conn.QueryRow(`
+		SELECT dead_lettered_at IS NOT NULL FROM images WHERE digest = ?
+	`, digest).Scan(&deadLettered)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to check dead-letter state: %w", err)
+	}
+	return deadLettered, nil
+}
+
+// GetScanFailureCount returns the number of consecutive failed scans of an
+// image (0 if its last scan succeeded or it is unknown)
+func (db *DB) GetScanFailureCount(digest string) (int, error) {
+	var failures int
+	err := db.conn.QueryRow(`SELECT scan_failures FROM images WHERE digest = ?`, digest).Scan(&failures)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to read scan failures: %w", err)
+	}
+	return failures, nil
+}
+
+// GetDeadLetteredScans returns the dead-lettered images, most recently
+// dead-lettered first, with their error chains
+func (db *DB) GetDeadLetteredScans() ([]DeadLetteredScan, error) {
+	rows, err := db.conn.Query(`
+		SELECT images.digest,
+		       COALESCE(c.reference, images.ad_hoc_reference, images.registry_reference, images.digest),
+		       COALESCE(c.node_name, ''),
+		       COALESCE(c.container_runtime, ''),
+		       images.ad_hoc,
+		       images.registry_reference IS NOT NULL AND c.id IS NULL,
+		       images.status,
+		       images.scan_failures,
+		       images.dead_lettered_at,
+		       COALESCE(images.scan_failure_log, '')
+		FROM images
+		LEFT JOIN containers c ON c.id = (
+		    SELECT id FROM containers WHERE image_id = images.id ORDER BY id LIMIT 1
+		)
+		WHERE images.dead_lettered_at IS NOT NULL
+		ORDER BY images.dead_lettered_at DESC, images.digest
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query dead-lettered scans: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	scans := []DeadLetteredScan{}
+	for rows.Next() {
+		var scan DeadLetteredScan
+		var status, failureLog string
+		if err := rows.Scan(&scan.Digest, &scan.Reference, &scan.NodeName, &scan.ContainerRuntime,
+			&scan.AdHoc, &scan.Registry, &status, &scan.Attempts, &scan.DeadLetteredAt, &failureLog); err != nil {
+			return nil, fmt.Errorf("failed to scan dead-lettered scan: %w", err)
+		}
+		scan.Status = Status(status)
+		scan.Failures = []ScanFailure{}
+		if failureLog != "" {
+			if err := json.Unmarshal([]byte(failureLog), &scan.Failures); err != nil {
+				log.Warn("unreadable scan failure log", "digest", scan.Digest, "error", err)
+			}
+		}
+		scans = append(scans, scan)
+	}
+	return scans, rows.Err()
+}
+
+// FailingScan is an image whose most recent scan failed, with the number of
+// consecutive failed attempts and the latest failure. Latest.Node falls back
+// to a node currently running the image when the attempt did not record one.
+type FailingScan struct {
+	Digest       string      `json:"digest"`
+	Reference    string      `json:"reference"`
+	Attempts     int         `json:"attempts"`
+	DeadLettered bool        `json:"dead_lettered"`
+	Latest       ScanFailure `json:"latest"`
+}
+
+// GetFailingScans returns the images whose error chain is not empty, i.e.
+// whose last scan attempt failed, ordered by digest
+func (db *DB) GetFailingScans() ([]FailingScan, error) {
+	rows, err := db.conn.Query(`
+		SELECT images.digest,
+		       COALESCE(c.reference, images.ad_hoc_reference, images.registry_reference, images.digest),
+		       COALESCE(c.node_name, ''),
+		       images.status,
+		       images.scan_failures,
+		       images.dead_lettered_at IS NOT NULL,
+		       COALESCE(images.scan_failure_log, '')
+		FROM images
+		LEFT JOIN containers c ON c.id = (
+		    SELECT id FROM containers WHERE image_id = images.id ORDER BY id LIMIT 1
+		)
+		WHERE images.scan_failures > 0
+		ORDER BY images.digest
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query failing scans: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	scans := []FailingScan{}
+	for rows.Next() {
+		var scan FailingScan
+		var nodeName, status, failureLog string
+		if err := rows.Scan(&scan.Digest, &scan.Reference, &nodeName, &status,
+			&scan.Attempts, &scan.DeadLettered, &failureLog); err != nil {
+			return nil, fmt.Errorf("failed to scan failing scan: %w", err)
+		}
+		scan.Latest.Status = Status(status)
+		if failureLog != "" {
+			var chain []ScanFailure
+			if err := json.Unmarshal([]byte(failureLog), &chain); err != nil {
+				log.Warn("unreadable scan failure log", "digest", scan.Digest, "error", err)
+			} else if len(chain) > 0 {
+				scan.Latest = chain[len(chain)-1]
+			}
+		}
+		if scan.Latest.Node == "" {
+			scan.Latest.Node = nodeName
+		}
+		scans = append(scans, scan)
+	}
+	return scans, rows.Err()
+}
@@ -0,0 +1,135 @@
+package database
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/bvboe/b2s-go/scanner-core/containers"
+)
+
+// TestScanDeadLetter verifies that an image is dead-lettered after
+// consecutive failures and that clearing the failures takes it out again.
+func TestScanDeadLetter(t *testing.T) {
+	db, err := New(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("failed to create database: %v", err)
+	}
+	defer func() { _ = Close(db) }()
+
+	image := containers.ImageID{Reference: "registry.example.com/app:v1", Digest: "sha256:broken"}
+	if _, err := db.AddContainer(containers.Container{
+		ID:               containers.ContainerID{Namespace: "default", Pod: "app", Name: "app"},
+		Image:            image,
+		NodeName:         "node-1",
+		ContainerRuntime: "containerd",
+	}); err != nil {
+		t.Fatalf("AddContainer failed: %v", err)
+	}
+
+	for i, msg := range []string{"node unreachable", "node unreachable", "timeout"} {
+		deadLettered, err := db.RecordScanFailure(image.Digest, "node-1", StatusSBOMFailed, msg, 3)
+		if err != nil {
+			t.Fatalf("RecordScanFailure failed: %v", err)
+		}
+		if want := i == 2; deadLettered != want {
+			t.Errorf("failure %d: dead-lettered = %v, want %v", i+1, deadLettered, want)
+		}
+	}
+	// Further failures don't dead-letter it again
+	if deadLettered, err := db.RecordScanFailure(image.Digest, "node-1", StatusSBOMFailed, "timeout", 3); err != nil || deadLettered {
+		t.Errorf("RecordScanFailure after dead-letter = %v, %v; want false, nil", deadLettered, err)
+	}
+
+	if deadLettered, err := db.IsDeadLettered(image.Digest); err != nil || !deadLettered {
+		t.Fatalf("IsDeadLettered = %v, %v; want true, nil", deadLettered, err)
+	}
+	if status, err := db.GetImageStatus(image.Digest); err != nil || status != StatusScanFailedPermanent {
+		t.Errorf("status after dead-letter = %v, %v; want %s", status, err, StatusScanFailedPermanent)
+	}
+	if failures, err := db.GetScanFailureCount(image.Digest); err != nil || failures != 4 {
+		t.Errorf("GetScanFailureCount = %d, %v; want 4", failures, err)
+	}
+	scans, err := db.GetDeadLetteredScans()
+	if err != nil {
+		t.Fatalf("GetDeadLetteredScans failed: %v", err)
+	}
+	if len(scans) != 1 {
+		t.Fatalf("expected 1 dead-lettered scan, got %d", len(scans))
+	}
+	scan := scans[0]
+	if scan.Reference != image.Reference || scan.NodeName != "node-1" || scan.ContainerRuntime != "containerd" || scan.Attempts != 4 {
+		t.Errorf("unexpected dead-lettered scan: %+v", scan)
+	}
+	if len(scan.Failures) != 4 || scan.Failures[0].Error != "node unreachable" || scan.Failures[3].Status != StatusSBOMFailed {
+		t.Errorf("unexpected error chain: %+v", scan.Failures)
+	}
+
+	if err := db.ClearScanFailures(image.Digest); err != nil {
+		t.Fatalf("ClearScanFailures failed: %v", err)
+	}
+	if deadLettered, err := db.IsDeadLettered(image.Digest); err != nil || deadLettered {
+		t.Errorf("IsDeadLettered after clear = %v, %v; want false, nil", deadLettered, err)
+	}
+	if status, err := db.GetImageStatus(image.Digest); err != nil || status != StatusPending {
+		t.Errorf("status after clear = %v, %v; want %s", status, err, StatusPending)
+	}
+	if scans, err := db.GetDeadLetteredScans(); err != nil || len(scans) != 0 {
+		t.Errorf("GetDeadLetteredScans after clear = %v, %v; want none", scans, err)
+	}
+
+	// Without a limit, failures are recorded but never dead-letter
+	for range 5 {
+		if deadLettered, err := db.RecordScanFailure(image.Digest, "node-1", StatusVulnScanFailed, "grype failed", 0); err != nil || deadLettered {
+			t.Fatalf("RecordScanFailure without limit = %v, %v; want false, nil", deadLettered, err)
+		}
+	}
+
+	// Unknown images are ignored
+	if deadLettered, err := db.RecordScanFailure("sha256:unknown", "", StatusSBOMFailed, "gone", 1); err != nil || deadLettered {
+		t.Errorf("RecordScanFailure for unknown image = %v, %v; want false, nil", deadLettered, err)
+	}
+}
+
+// TestGetFailingScans verifies that images whose last scan failed are listed
+// with their latest failure and the node it happened on.
+func TestGetFailingScans(t *testing.T) {
+	db, err := New(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("failed to create database: %v", err)
+	}
+	defer func() { _ = Close(db) }()
+
+	for i, digest := range []string{"sha256:a", "sha256:b", "sha256:c"} {
+		if _, err := db.AddContainer(containers.Container{
+			ID:       containers.ContainerID{Namespace: "default", Pod: "app", Name: digest},
+			Image:    containers.ImageID{Reference: "app:" + digest[7:], Digest: digest},
+			NodeName: []string{"node-1", "node-2", "node-2"}[i],
+		}); err != nil {
+			t.Fatalf("AddContainer failed: %v", err)
+		}
+	}
+
+	if _, err := db.RecordScanFailure("sha256:a", "", StatusSBOMFailed, "dial unix /run/containerd/containerd.sock: connect: no such file or directory", 0); err != nil {
+		t.Fatalf("RecordScanFailure failed: %v", err)
+	}
+	for _, msg := range []string{"timeout", "unauthorized"} {
+		if _, err := db.RecordScanFailure("sha256:b", "node-3", StatusSBOMFailed, msg, 0); err != nil {
+			t.Fatalf("RecordScanFailure failed: %v", err)
+		}
+	}
+
+	scans, err := db.GetFailingScans()
+	if err != nil {
+		t.Fatalf("GetFailingScans failed: %v", err)
+	}
+	if len(scans) != 2 {
+		t.Fatalf("expected 2 failing scans, got %+v", scans)
+	}
+	// Attempts without a node fall back to a node running the image
+	if scans[0].Digest != "sha256:a" || scans[0].Latest.Node != "node-1" || scans[0].Attempts != 1 {
+		t.Errorf("unexpected failing scan: %+v", scans[0])
+	}
+	if scans[1].Latest.Node != "node-3" || scans[1].Latest.Error != "unauthorized" || scans[1].Attempts != 2 {
+		t.Errorf("unexpected failing scan: %+v", scans[1])
+	}
+}
@@ -0,0 +1,117 @@
+package database
+
+import (
+	"fmt"
+	"strings"
+)
+
+// SBOMPruneStats reports the result of PruneOldestSBOMs
+type SBOMPruneStats struct {
+	Images     int   `json:"images"`
+	BytesFreed int64 `json:"bytes_freed"`
+}
+
+// GetReclaimableBytes returns the size of the free pages inside the database
+// file. Deleted data is not returned to the filesystem (that would need a
+// VACUUM, which temporarily needs as much free space as the database itself)
+// but those pages are reused by later writes.
+func (db *DB) GetReclaimableBytes() (int64, error) {
+	var freePages, pageSize int64
+	if err := db.conn.QueryRow("PRAGMA freelist_count").Scan(&freePages); err != nil {
+		return 0, fmt.Errorf("failed to read freelist count: %w", err)
+	}
+	if err := db.conn.QueryRow("PRAGMA page_size").Scan(&pageSize); err != nil {
+		return 0, fmt.Errorf("failed to read page size: %w", err)
+	}
+	return freePages * pageSize, nil
+}
+
+// PruneOldestSBOMs drops the stored SBOM documents of completed images, oldest
+// SBOM first, until at least targetBytes have been freed or no SBOMs are left.
+// Packages and vulnerabilities parsed from the SBOM are kept; a later rescan of
+// a pruned image retrieves its SBOM again (see HasStoredSBOM). A document
+// shared by several images is only freed, and counted, once the last of them
+// is pruned.
+func (db *DB) PruneOldestSBOMs(targetBytes int64) (*SBOMPruneStats, error) {
+	stats := &SBOMPruneStats{}
+	if targetBytes <= 0 {
+		return stats, nil
+	}
+
+	rows, err := db.conn.Query(`
+		SELECT i.id, COALESCE(i.sbom_hash, ''), COALESCE(b.ref_count, 0), COALESCE(LENGTH(b.sbom_compressed), 0),
+		       COALESCE(LENGTH(i.sbom_compressed), 0) + COALESCE(LENGTH(i.sbom), 0)
+		FROM images i LEFT JOIN sbom_blobs b ON b.hash = i.sbom_hash
+		WHERE i.status = ?
+		  AND (i.sbom_hash IS NOT NULL OR i.sbom_compressed IS NOT NULL OR i.sbom IS NOT NULL)
+		ORDER BY i.sbom_scanned_at ASC, i.id ASC
+	`, StatusCompleted.String())
+	if err != nil {
+		return nil, fmt.Errorf("failed to query stored SBOMs: %w", err)
+	}
+	var ids []any
+	released := make(map[string]int64)
+	for rows.Next() && stats.BytesFreed < targetBytes {
+		var id, refs, blobSize, ownSize int64
+		var hash string
+		if err := rows.Scan(&id, &hash, &refs, &blobSize, &ownSize); err != nil {
+			_ = rows.Close()
+			return nil, fmt.Errorf("failed to scan stored SBOM: %w", err)
+		}
+		ids = append(ids, id)
+		stats.BytesFreed += ownSize
+		if hash != "" {
+			released[hash]++
+			if released[hash] == refs {
+				stats.BytesFreed += blobSize
+			}
+		}
+	}
+	_ = rows.Close()
+	if len(ids) == 0 {
+		return stats, nil
+	}
+	inIDs := strings.TrimSuffix(strings.Repeat("?,", len(ids)), ",")
+
+	done := db.beginWrite("prune_sboms")
+	defer done()
+	tx, err := db.conn.Begin()
+	if err != nil {
+		exitOnCorruption(err)
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if err := releaseSBOMBlobs(tx, `SELECT sbom_hash FROM images WHERE sbom_hash IS NOT NULL AND id IN (`+inIDs+`)`, ids...); err != nil {
+		exitOnCorruption(err)
+		return nil, err
+	}
+	if _, err = tx.Exec(`
+		UPDATE images SET sbom_hash = NULL, sbom_compressed = NULL, sbom = NULL
+		WHERE id IN (`+inIDs+`)
+	`, ids...); err != nil {
+		exitOnCorruption(err)
+		return nil, fmt.Errorf("failed to prune SBOMs: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		exitOnCorruption(err)
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	stats.Images = len(ids)
+
+	log.Info("pruned stored SBOMs", "images", stats.Images, "bytes_freed", stats.BytesFreed)
+	return stats, nil
+}
+
+// HasStoredSBOM reports whether the SBOM document of an image is still stored
+func (db *DB) HasStoredSBOM(digest string) (bool, error) {
+	var stored bool
+	err := db.conn.QueryRow(`
+		SELECT sbom_hash IS NOT NULL OR sbom_compressed IS NOT NULL OR (sbom IS NOT NULL AND sbom != '')
+		FROM images WHERE digest = ?
+	`, digest).Scan(&stored)
+	if err != nil {
+		return false, fmt.Errorf("failed to check stored SBOM: %w", err)
+	}
+	return stored, nil
+}
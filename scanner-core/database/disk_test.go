@@ -0,0 +1,68 @@
+package database
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/bvboe/b2s-go/scanner-core/containers"
+)
+
+func TestPruneOldestSBOMs(t *testing.T) {
+	db, err := New(filepath.Join(t.TempDir(), "prune.db"))
+	if err != nil {
+		t.Fatalf("Failed to create database: %v", err)
+	}
+	defer func() { _ = Close(db) }()
+
+	for _, digest := range []string{"sha256:old", "sha256:new", "sha256:scanning"} {
+		sbomJSON := []byte(`{"artifacts":[],"source":{"type":"image","id":"` + digest + `"}}`)
+		if _, err := db.AddContainer(containers.Container{
+			ID:    containers.ContainerID{Namespace: "default", Pod: digest, Name: "app"},
+			Image: containers.ImageID{Reference: "app:1", Digest: digest},
+		}); err != nil {
+			t.Fatalf("Failed to add container: %v", err)
+		}
+		if err := db.StoreSBOM(digest, sbomJSON); err != nil {
+			t.Fatalf("Failed to store SBOM: %v", err)
+		}
+	}
+	for _, digest := range []string{"sha256:old", "sha256:new"} {
+		if err := db.UpdateStatus(digest, StatusCompleted, ""); err != nil {
+			t.Fatalf("Failed to update status: %v", err)
+		}
+	}
+
+	// Any positive target prunes at least the oldest completed SBOM
+	stats, err := db.PruneOldestSBOMs(1)
+	if err != nil {
+		t.Fatalf("PruneOldestSBOMs() error = %v", err)
+	}
+	if stats.Images != 1 || stats.BytesFreed <= 0 {
+		t.Errorf("Expected one pruned SBOM, got %+v", stats)
+	}
+	for digest, want := range map[string]bool{"sha256:old": false, "sha256:new": true, "sha256:scanning": true} {
+		stored, err := db.HasStoredSBOM(digest)
+		if err != nil {
+			t.Fatalf("HasStoredSBOM(%s) error = %v", digest, err)
+		}
+		if stored != want {
+			t.Errorf("HasStoredSBOM(%s) = %v, want %v", digest, stored, want)
+		}
+	}
+
+	// Images still being scanned keep their SBOM
+	stats, err = db.PruneOldestSBOMs(1 << 30)
+	if err != nil {
+		t.Fatalf("PruneOldestSBOMs() error = %v", err)
+	}
+	if stats.Images != 1 {
+		t.Errorf("Expected only the remaining completed SBOM to be pruned, got %+v", stats)
+	}
+	if stored, _ := db.HasStoredSBOM("sha256:scanning"); !stored {
+		t.Error("SBOM of an image that is still being scanned was pruned")
+	}
+
+	if _, err := db.GetReclaimableBytes(); err != nil {
+		t.Errorf("GetReclaimableBytes() error = %v", err)
+	}
+}
@@ -0,0 +1,8 @@
+// Package database is the SQLite-backed store for images, containers, nodes,
+// SBOMs and vulnerability results.
+//
+// New opens (and migrates) a database file; the returned *DB satisfies the
+// narrow interfaces consumed elsewhere in scanner-core, such as
+// containers.DatabaseInterface and handlers.DatabaseProvider, so callers can
+// substitute their own implementations in tests or alternative stores.
+package database
@@ -0,0 +1,37 @@
+package database
+
+import "context"
+
+// ResultSource is the read side of a result export: the images that are not
+// soft-deleted, the running containers, the vulnerabilities of an image and
+// the scan history. *DB implements it with the queries behind the offline
+// report.
+type ResultSource interface {
+	GetReportImages() ([]ReportImage, error)
+	GetReportContainers() ([]ReportContainer, error)
+	GetReportVulnerabilities(imageID int64) ([]ReportVulnerability, error)
+	GetScanHistoryRows(afterID int64, limit int) ([]ScanHistoryRow, error)
+}
+
+// ResultSink is an external database scan results are exported to, in
+// addition to the local SQLite store, e.g. for centralized long-term storage
+// shared by several clusters. See PostgresSink. Sinks are write-only copies:
+// the local store remains the only database the server reads and writes.
+type ResultSink interface {
+	// Export writes the current results of src. Sinks only need to copy the
+	// vulnerabilities of images whose vulnerabilities changed since the last
+	// export: by a rescan, or by VEX statements, ignore rules or severity
+	// mapping applied to the stored results. The scan history is appended.
+	Export(ctx context.Context, src ResultSource) (ExportStats, error)
+	// Close releases the connections of the sink
+	Close() error
+}
+
+// ExportStats summarizes one export to a ResultSink
+type ExportStats struct {
+	Images          int // Images exported
+	Containers      int // Running containers exported
+	RescannedImages int // Images whose vulnerabilities were (re)written
+	Vulnerabilities int // Vulnerability rows written for those images
+	ScanHistory     int // scan_history rows appended
+}
@@ -0,0 +1,201 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"strings"
+	"sync/atomic"
+)
+
+// Debug-only fault injection: SetWriteFaultRate makes a share of write
+// transactions fail, so the retry, dead-letter and alerting paths can be
+// exercised end to end. The connections of the database are wrapped so that
+// a transaction fails at commit (after rolling it back) and a statement run
+// outside a transaction fails before it runs. Either way the database is left
+// unchanged and the caller sees an ordinary write error. Queries, PRAGMAs and
+// read-only transactions are never failed.
+
+// ErrInjectedWriteFault is returned by writes failed by SetWriteFaultRate
+var ErrInjectedWriteFault = errors.New("injected fault: database write failed")
+
+// writeFault is the injected write failure state of a database
+type writeFault struct {
+	rate     atomic.Uint64 // math.Float64bits of the share of failing writes
+	injected atomic.Int64  // writes failed so far
+}
+
+// inject reports whether the next write should fail
+func (f *writeFault) inject() bool {
+	rate := math.Float64frombits(f.rate.Load())
+	if rate <= 0 || rand.Float64() >= rate {
+		return false
+	}
+	f.injected.Add(1)
+	return true
+}
+
+// openWithFaults opens the database at dsn with connections SetWriteFaultRate
+// can fail writes on. Works with whichever driver registered as "sqlite".
+func openWithFaults(dsn string, fault *writeFault) (*sql.DB, error) {
+	probe, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, err
+	}
+	drv := probe.Driver()
+	_ = probe.Close()
+	return sql.OpenDB(&faultConnector{driver: drv, dsn: dsn, fault: fault}), nil
+}
+
+// faultConnector opens faultConns on the wrapped driver
+type faultConnector struct {
+	driver driver.Driver
+	dsn    string
+	fault  *writeFault
+}
+
+func (c *faultConnector) Connect(context.Context) (driver.Conn, error) {
+	conn, err := c.driver.Open(c.dsn)
+	if err != nil {
+		return nil, err
+	}
+	return &faultConn{Conn: conn, fault: c.fault}, nil
+}
+
+func (c *faultConnector) Driver() driver.Driver {
+	return c.driver
+}
+
+// faultConn passes everything through to the driver connection, failing
+// commits and statements outside a transaction when a fault is injected.
+// database/sql uses a connection from one goroutine at a time.
+type faultConn struct {
+	driver.Conn
+	fault *writeFault
+	inTx  bool
+}
+
+func (c *faultConn) Begin() (driver.Tx, error) {
+	return c.BeginTx(context.Background(), driver.TxOptions{})
+}
+
+func (c *faultConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	var tx driver.Tx
+	var err error
+	if b, ok := c.Conn.(driver.ConnBeginTx); ok {
+		tx, err = b.BeginTx(ctx, opts)
+	} else {
+		tx, err = c.Conn.Begin() //nolint:staticcheck // fallback for drivers without BeginTx
+	}
+	if err != nil {
+		return nil, err
+	}
+	c.inTx = true
+	return &faultTx{Tx: tx, conn: c, readOnly: opts.ReadOnly}, nil
+}
+
+func (c *faultConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	if p, ok := c.Conn.(driver.ConnPrepareContext); ok {
+		return p.PrepareContext(ctx, query)
+	}
+	return c.Conn.Prepare(query)
+}
+
+func (c *faultConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	e, ok := c.Conn.(driver.ExecerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	if !c.inTx && isWriteStatement(query) && c.fault.inject() {
+		return nil, ErrInjectedWriteFault
+	}
+	return e.ExecContext(ctx, query, args)
+}
+
+func (c *faultConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	if q, ok := c.Conn.(driver.QueryerContext); ok {
+		return q.QueryContext(ctx, query, args)
+	}
+	return nil, driver.ErrSkip
+}
+
+func (c *faultConn) Ping(ctx context.Context) error {
+	if p, ok := c.Conn.(driver.Pinger); ok {
+		return p.Ping(ctx)
+	}
+	return nil
+}
+
+func (c *faultConn) ResetSession(ctx context.Context) error {
+	if r, ok := c.Conn.(driver.SessionResetter); ok {
+		return r.ResetSession(ctx)
+	}
+	return nil
+}
+
+func (c *faultConn) IsValid() bool {
+	if v, ok := c.Conn.(driver.Validator); ok {
+		return v.IsValid()
+	}
+	return true
+}
+
+// faultTx fails the commit of write transactions when a fault is injected
+type faultTx struct {
+	driver.Tx
+	conn     *faultConn
+	readOnly bool
+}
+
+func (t *faultTx) Commit() error {
+	t.conn.inTx = false
+	if !t.readOnly && t.conn.fault.inject() {
+		_ = t.Tx.Rollback()
+		return ErrInjectedWriteFault
+	}
+	return t.Tx.Commit()
+}
+
+func (t *faultTx) Rollback() error {
+	t.conn.inTx = false
+	return t.Tx.Rollback()
+}
+
+// isWriteStatement reports whether a statement run outside a transaction may
+// write. Queries and PRAGMAs (WAL checkpoints, settings) are left alone.
+func isWriteStatement(query string) bool {
+	query = strings.ToUpper(strings.TrimSpace(query))
+	return !strings.HasPrefix(query, "SELECT") && !strings.HasPrefix(query, "PRAGMA")
+}
+
+// SetWriteFaultRate makes the given share (0-1) of write transactions fail.
+// 0 turns fault injection off. Only meant for resilience testing with debug
+// mode enabled.
+func (db *DB) SetWriteFaultRate(rate float64) error {
+	if math.IsNaN(rate) || rate < 0 || rate > 1 {
+		return fmt.Errorf("write fault rate must be between 0 and 1, got %v", rate)
+	}
+	if db.faults == nil {
+		return fmt.Errorf("database does not support fault injection")
+	}
+	db.faults.rate.Store(math.Float64bits(rate))
+	if rate > 0 {
+		log.Warn("fault injection: database writes will fail", "rate", rate)
+	} else {
+		log.Info("fault injection: database write failures turned off")
+	}
+	return nil
+}
+
+// WriteFaults returns the injected write failure rate and the number of
+// writes failed so far
+func (db *DB) WriteFaults() (rate float64, injected int64) {
+	if db.faults == nil {
+		return 0, 0
+	}
+	return math.Float64frombits(db.faults.rate.Load()), db.faults.injected.Load()
+}
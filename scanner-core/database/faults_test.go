@@ -0,0 +1,46 @@
+package database
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+func TestWriteFaults(t *testing.T) {
+	db, err := New(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("Failed to create database: %v", err)
+	}
+	defer func() { _ = Close(db) }()
+
+	if err := db.SetWriteFaultRate(1.5); err == nil {
+		t.Error("SetWriteFaultRate(1.5) should fail")
+	}
+	if err := db.SetWriteFaultRate(1); err != nil {
+		t.Fatalf("SetWriteFaultRate() error = %v", err)
+	}
+
+	_, err = db.SetCVEAnnotation("CVE-2024-0001", "mitigated", "")
+	if !errors.Is(err, ErrInjectedWriteFault) {
+		t.Fatalf("write error = %v, want ErrInjectedWriteFault", err)
+	}
+	// Transactions fail at commit and are rolled back
+	_, err = db.ImportCVEAnnotations([]CVEAnnotation{{CVEID: "CVE-2024-0001", Note: "imported"}})
+	if !errors.Is(err, ErrInjectedWriteFault) {
+		t.Fatalf("transaction error = %v, want ErrInjectedWriteFault", err)
+	}
+	// Reads still work and the failed write was rolled back
+	if a, err := db.GetCVEAnnotation("CVE-2024-0001"); err != nil || a != nil {
+		t.Errorf("GetCVEAnnotation() = %+v, %v after a failed write", a, err)
+	}
+	if rate, injected := db.WriteFaults(); rate != 1 || injected != 2 {
+		t.Errorf("WriteFaults() = %v, %d, want 1, 2", rate, injected)
+	}
+
+	if err := db.SetWriteFaultRate(0); err != nil {
+		t.Fatalf("SetWriteFaultRate() error = %v", err)
+	}
+	if _, err := db.SetCVEAnnotation("CVE-2024-0001", "mitigated", ""); err != nil {
+		t.Errorf("write with fault injection off: %v", err)
+	}
+}
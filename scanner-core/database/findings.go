@@ -0,0 +1,85 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// Finding is a vulnerability of a package in an image, as compared between
+// scans to find newly introduced vulnerabilities
+type Finding struct {
+	VulnerabilityID string `json:"vulnerability_id"`
+	PackageName     string `json:"package_name"`
+	PackageVersion  string `json:"package_version"`
+	Severity        string `json:"severity"`
+	KnownExploited  bool   `json:"known_exploited"`
+	FixedVersion    string `json:"fixed_version,omitempty"`
+}
+
+// Key identifies the finding across scans of the same image
+func (f Finding) Key() string {
+	return f.VulnerabilityID + "|" + f.PackageName + "|" + f.PackageVersion
+}
+
+// GetImageFindings returns the stored vulnerabilities of an image. scanned is
+// false if the image is unknown or its vulnerabilities were never stored.
+func (db *DB) GetImageFindings(digest string) (findings []Finding, scanned bool, err error) {
+	var imageID int64
+	err = db.conn.QueryRow(`SELECT id, vulns_scanned_at IS NOT NULL FROM images WHERE digest = ?`, digest).Scan(&imageID, &scanned)
+	if err == sql.ErrNoRows {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to get image: %w", err)
+	}
+
+	rows, err := db.conn.Query(`
+		SELECT cve_id, package_name, package_version, COALESCE(severity, ''),
+		       COALESCE(known_exploited, 0) > 0, COALESCE(fixed_version, '')
+		FROM image_vulnerabilities
+		WHERE image_id = ?
+		ORDER BY cve_id, package_name, package_version
+	`, imageID)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to get image findings: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	for rows.Next() {
+		var f Finding
+		if err := rows.Scan(&f.VulnerabilityID, &f.PackageName, &f.PackageVersion, &f.Severity, &f.KnownExploited, &f.FixedVersion); err != nil {
+			return nil, false, fmt.Errorf("failed to scan image finding: %w", err)
+		}
+		findings = append(findings, f)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, false, fmt.Errorf("failed to iterate image findings: %w", err)
+	}
+	return findings, scanned, nil
+}
+
+// GetImageNamespaces returns the namespaces the image is running in, sorted.
+// Returns nil if no container runs the image.
+func (db *DB) GetImageNamespaces(digest string) ([]string, error) {
+	rows, err := db.conn.Query(`
+		SELECT DISTINCT c.namespace
+		FROM containers c
+		JOIN images img ON c.image_id = img.id
+		WHERE img.digest = ?
+		ORDER BY c.namespace
+	`, digest)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get image namespaces: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var namespaces []string
+	for rows.Next() {
+		var ns string
+		if err := rows.Scan(&ns); err != nil {
+			return nil, fmt.Errorf("failed to scan namespace: %w", err)
+		}
+		namespaces = append(namespaces, ns)
+	}
+	return namespaces, rows.Err()
+}
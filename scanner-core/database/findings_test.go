@@ -0,0 +1,69 @@
+package database
+
+import (
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/bvboe/b2s-go/scanner-core/containers"
+)
+
+func TestGetImageFindings(t *testing.T) {
+	db, err := New(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("failed to create database: %v", err)
+	}
+	defer func() { _ = Close(db) }()
+
+	digest := "sha256:app"
+	for i, ns := range []string{"team-b", "team-a", "team-b"} {
+		if _, err := db.AddContainer(containers.Container{
+			ID:    containers.ContainerID{Namespace: ns, Pod: fmt.Sprintf("app-%d", i), Name: "app"},
+			Image: containers.ImageID{Reference: "app:1", Digest: digest},
+		}); err != nil {
+			t.Fatalf("AddContainer() error = %v", err)
+		}
+	}
+
+	findings, scanned, err := db.GetImageFindings(digest)
+	if err != nil || scanned || len(findings) != 0 {
+		t.Fatalf("before scan: GetImageFindings() = %v, %v, %v; want no findings, not scanned", findings, scanned, err)
+	}
+
+	vulnJSON := []byte(`{"matches": [
+		{"vulnerability": {"id": "CVE-2", "severity": "Critical", "fix": {"state": "fixed", "versions": ["3.0.1"]},
+		  "knownExploited": [{"cve": "CVE-2"}]},
+		 "artifact": {"name": "openssl", "version": "3.0", "type": "deb"}},
+		{"vulnerability": {"id": "CVE-1", "severity": "High", "fix": {"state": "not-fixed"}},
+		 "artifact": {"name": "bash", "version": "5.1", "type": "deb"}}
+	]}`)
+	if err := db.StoreVulnerabilities(digest, vulnJSON, time.Now()); err != nil {
+		t.Fatalf("StoreVulnerabilities() error = %v", err)
+	}
+
+	findings, scanned, err = db.GetImageFindings(digest)
+	if err != nil {
+		t.Fatalf("GetImageFindings() error = %v", err)
+	}
+	want := []Finding{
+		{VulnerabilityID: "CVE-1", PackageName: "bash", PackageVersion: "5.1", Severity: "High"},
+		{VulnerabilityID: "CVE-2", PackageName: "openssl", PackageVersion: "3.0", Severity: "Critical", KnownExploited: true, FixedVersion: "3.0.1"},
+	}
+	if !scanned || !reflect.DeepEqual(findings, want) {
+		t.Errorf("GetImageFindings() = %+v, %v; want %+v, true", findings, scanned, want)
+	}
+
+	namespaces, err := db.GetImageNamespaces(digest)
+	if err != nil {
+		t.Fatalf("GetImageNamespaces() error = %v", err)
+	}
+	if !reflect.DeepEqual(namespaces, []string{"team-a", "team-b"}) {
+		t.Errorf("GetImageNamespaces() = %v, want [team-a team-b]", namespaces)
+	}
+
+	if findings, scanned, err := db.GetImageFindings("sha256:unknown"); err != nil || scanned || findings != nil {
+		t.Errorf("unknown image: GetImageFindings() = %v, %v, %v", findings, scanned, err)
+	}
+}
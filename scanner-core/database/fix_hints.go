@@ -0,0 +1,118 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// ImageReference is a reference under which a scanned image was observed
+type ImageReference struct {
+	Digest       string
+	Reference    string
+	GrypeDBBuilt time.Time
+}
+
+// GetImageCVEs returns the distinct CVE IDs found in an image, optionally
+// limited to one severity (e.g. "Critical"); an empty severity returns all
+func (db *DB) GetImageCVEs(digest string, severity string) ([]string, error) {
+	rows, err := db.conn.Query(`
+		SELECT DISTINCT v.cve_id
+		FROM image_vulnerabilities v
+		JOIN images i ON v.image_id = i.id
+		WHERE i.digest = ? AND (? = '' OR v.severity = ?)
+		ORDER BY v.cve_id
+	`, digest, severity, severity)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query image CVEs: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var cves []string
+	for rows.Next() {
+		var cve string
+		if err := rows.Scan(&cve); err != nil {
+			return nil, fmt.Errorf("failed to scan image CVE: %w", err)
+		}
+		cves = append(cves, cve)
+	}
+	return cves, rows.Err()
+}
+
+// GetImageReferences returns the references an image was observed under,
+// from running containers and completed Jobs
+func (db *DB) GetImageReferences(digest string) ([]string, error) {
+	rows, err := db.conn.Query(`
+		SELECT c.reference FROM containers c JOIN images i ON c.image_id = i.id WHERE i.digest = ?
+		UNION
+		SELECT reference FROM observed_images WHERE digest = ? AND reference != ''
+		ORDER BY 1
+	`, digest, digest)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query image references: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var refs []string
+	for rows.Next() {
+		var ref string
+		if err := rows.Scan(&ref); err != nil {
+			return nil, fmt.Errorf("failed to scan image reference: %w", err)
+		}
+		refs = append(refs, ref)
+	}
+	return refs, rows.Err()
+}
+
+// GetScannedImageReferences returns every reference of images with a
+// completed vulnerability scan, from running containers and completed Jobs
+func (db *DB) GetScannedImageReferences() ([]ImageReference, error) {
+	rows, err := db.conn.Query(`
+		SELECT i.digest, r.reference, i.grype_db_built
+		FROM (
+			SELECT image_id, reference FROM containers
+			UNION
+			SELECT i2.id, o.reference FROM observed_images o JOIN images i2 ON i2.digest = o.digest
+			WHERE o.reference != ''
+		) r
+		JOIN images i ON r.image_id = i.id
+		WHERE i.status = ?
+		ORDER BY i.digest, r.reference
+	`, StatusCompleted.String())
+	if err != nil {
+		return nil, fmt.Errorf("failed to query scanned image references: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var refs []ImageReference
+	for rows.Next() {
+		var ref ImageReference
+		var built sql.NullString
+		if err := rows.Scan(&ref.Digest, &ref.Reference, &built); err != nil {
+			return nil, fmt.Errorf("failed to scan image reference: %w", err)
+		}
+		if built.Valid {
+			ref.GrypeDBBuilt, _ = time.Parse(time.RFC3339, built.String)
+		}
+		refs = append(refs, ref)
+	}
+	return refs, rows.Err()
+}
+
+// GetImageGrypeDBBuilt returns the build time of the grype DB an image was last
+// scanned with, or the zero time if unknown
+func (db *DB) GetImageGrypeDBBuilt(digest string) (time.Time, error) {
+	var built sql.NullString
+	err := db.conn.QueryRow(`SELECT grype_db_built FROM images WHERE digest = ?`, digest).Scan(&built)
+	if err == sql.ErrNoRows {
+		return time.Time{}, nil
+	}
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to query grype DB build: %w", err)
+	}
+	if !built.Valid {
+		return time.Time{}, nil
+	}
+	t, _ := time.Parse(time.RFC3339, built.String)
+	return t, nil
+}
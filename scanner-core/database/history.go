@@ -0,0 +1,264 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Trend intervals accepted by GetScanTrends
+const (
+	TrendIntervalDay  = "day"
+	TrendIntervalWeek = "week"
+)
+
+// ScanSnapshot is the vulnerability posture of an image as of one scan.
+// Snapshots are kept in scan_history, which outlives the image itself, so
+// trends still include images that have since been replaced.
+type ScanSnapshot struct {
+	ScannedAt      string   `json:"scanned_at"`
+	GrypeDBBuilt   string   `json:"grype_db_built,omitempty"`
+	Namespaces     []string `json:"namespaces"` // namespaces running the image at scan time
+	Critical       int      `json:"critical"`   // unique CVEs per severity
+	High           int      `json:"high"`
+	Medium         int      `json:"medium"`
+	Low            int      `json:"low"`
+	Negligible     int      `json:"negligible"`
+	Unknown        int      `json:"unknown"`
+	Total          int      `json:"total"`
+	KnownExploited int      `json:"known_exploited"`
+	RiskScore      float64  `json:"risk_score"` // sum of the risk of all findings
+}
+
+// TrendPoint is the combined posture of the running images in one interval:
+// for each image scanned in the interval, its last snapshot of the interval
+type TrendPoint struct {
+	Period         string  `json:"period"` // first day of the interval (YYYY-MM-DD)
+	Images         int     `json:"images"`
+	Critical       int     `json:"critical"`
+	High           int     `json:"high"`
+	Medium         int     `json:"medium"`
+	Low            int     `json:"low"`
+	Negligible     int     `json:"negligible"`
+	Unknown        int     `json:"unknown"`
+	Total          int     `json:"total"`
+	KnownExploited int     `json:"known_exploited"`
+	RiskScore      float64 `json:"risk_score"`
+}
+
+// scanSnapshotInsert records the current vulnerability counts of images; the
+// first %s is the scan time and the second the WHERE clause
+const scanSnapshotInsert = `
+	INSERT INTO scan_history (digest, namespaces, running, scanned_at, grype_db_built,
+		critical, high, medium, low, negligible, unknown, total, known_exploited, risk_score)
+	SELECT img.digest,
+	       COALESCE((SELECT GROUP_CONCAT(namespace, ',') FROM
+	                 (SELECT DISTINCT namespace FROM containers WHERE image_id = img.id ORDER BY namespace)), ''),
+	       EXISTS (SELECT 1 FROM containers WHERE image_id = img.id),
+	       %s, img.grype_db_built,
+	       COUNT(DISTINCT CASE WHEN v.severity = 'Critical' THEN v.cve_id END),
+	       COUNT(DISTINCT CASE WHEN v.severity = 'High' THEN v.cve_id END),
+	       COUNT(DISTINCT CASE WHEN v.severity = 'Medium' THEN v.cve_id END),
+	       COUNT(DISTINCT CASE WHEN v.severity = 'Low' THEN v.cve_id END),
+	       COUNT(DISTINCT CASE WHEN v.severity = 'Negligible' THEN v.cve_id END),
+	       COUNT(DISTINCT CASE WHEN v.severity NOT IN ('Critical', 'High', 'Medium', 'Low', 'Negligible') OR v.severity IS NULL THEN v.cve_id END),
+	       COUNT(DISTINCT v.cve_id),
+	       COUNT(DISTINCT CASE WHEN v.known_exploited > 0 THEN v.cve_id END),
+	       COALESCE(SUM(v.risk * v.count), 0)
+	FROM images img
+	LEFT JOIN image_vulnerabilities v ON v.image_id = img.id
+	WHERE %s
+	GROUP BY img.id`
+
+// recordScanSnapshot adds a scan_history entry for an image that was just scanned
+func (db *DB) recordScanSnapshot(imageID int64, scannedAt string) error {
+	done := db.beginWrite("record_scan_snapshot")
+	defer done()
+	if _, err := db.conn.Exec(fmt.Sprintf(scanSnapshotInsert, "?", "img.id = ?"), scannedAt, imageID); err != nil {
+		exitOnCorruption(err)
+		return fmt.Errorf("failed to record scan snapshot: %w", err)
+	}
+	return nil
+}
+
+// GetImageScanHistory returns the scan snapshots of an image taken since the
+// given time, oldest first. Returns nil if the digest has never been scanned.
+func (db *DB) GetImageScanHistory(digest string, since time.Time) ([]ScanSnapshot, error) {
+	var scanned bool
+	err := db.conn.QueryRow(`SELECT EXISTS (SELECT 1 FROM scan_history WHERE digest = ?)`, digest).Scan(&scanned)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query scan history: %w", err)
+	}
+	if !scanned {
+		return nil, nil
+	}
+
+	rows, err := db.conn.Query(`
+		SELECT scanned_at, COALESCE(grype_db_built, ''), namespaces,
+		       critical, high, medium, low, negligible, unknown, total, known_exploited, risk_score
+		FROM scan_history
+		WHERE digest = ? AND scanned_at >= ?
+		ORDER BY scanned_at, id
+	`, digest, since.UTC().Format(time.RFC3339))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query scan history: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	history := []ScanSnapshot{}
+	for rows.Next() {
+		var s ScanSnapshot
+		var namespaces string
+		if err := rows.Scan(&s.ScannedAt, &s.GrypeDBBuilt, &namespaces,
+			&s.Critical, &s.High, &s.Medium, &s.Low, &s.Negligible, &s.Unknown,
+			&s.Total, &s.KnownExploited, &s.RiskScore); err != nil {
+			return nil, fmt.Errorf("failed to scan snapshot: %w", err)
+		}
+		s.Namespaces = []string{}
+		if namespaces != "" {
+			s.Namespaces = strings.Split(namespaces, ",")
+		}
+		history = append(history, s)
+	}
+	return history, rows.Err()
+}
+
+// GetScanTrends sums the snapshots of running images per day or week since the
+// given time, oldest first. Images are rescanned whenever the vulnerability
+// database updates, so each interval covers the images running at the time.
+// With namespaces, only images running in one of them when scanned count.
+func (db *DB) GetScanTrends(since time.Time, interval string, namespaces []string) ([]TrendPoint, error) {
+	var period string
+	switch interval {
+	case TrendIntervalDay:
+		period = `date(scanned_at)`
+	case TrendIntervalWeek:
+		// Weeks start on Monday: the Sunday ending the week, minus six days
+		period = `date(scanned_at, 'weekday 0', '-6 days')`
+	default:
+		return nil, fmt.Errorf("invalid trend interval: %s", interval)
+	}
+
+	where := []string{"running = 1", "scanned_at >= ?"}
+	args := []any{since.UTC().Format(time.RFC3339)}
+	if len(namespaces) > 0 {
+		matches := make([]string, len(namespaces))
+		for i, ns := range namespaces {
+			matches[i] = `instr(',' || namespaces || ',', ?) > 0`
+			args = append(args, ","+ns+",")
+		}
+		where = append(where, "("+strings.Join(matches, " OR ")+")")
+	}
+
+	rows, err := db.conn.Query(fmt.Sprintf(`
+		WITH latest AS (
+			SELECT %[1]s AS period, critical, high, medium, low, negligible, unknown, total, known_exploited, risk_score,
+			       ROW_NUMBER() OVER (PARTITION BY %[1]s, digest ORDER BY scanned_at DESC, id DESC) AS rn
+			FROM scan_history
+			WHERE %[2]s
+		)
+		SELECT period, COUNT(*), SUM(critical), SUM(high), SUM(medium), SUM(low), SUM(negligible), SUM(unknown),
+		       SUM(total), SUM(known_exploited), SUM(risk_score)
+		FROM latest
+		WHERE rn = 1
+		GROUP BY period
+		ORDER BY period
+	`, period, strings.Join(where, " AND ")), args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query scan trends: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	trends := []TrendPoint{}
+	for rows.Next() {
+		var p TrendPoint
+		if err := rows.Scan(&p.Period, &p.Images, &p.Critical, &p.High, &p.Medium, &p.Low, &p.Negligible,
+			&p.Unknown, &p.Total, &p.KnownExploited, &p.RiskScore); err != nil {
+			return nil, fmt.Errorf("failed to scan trend: %w", err)
+		}
+		trends = append(trends, p)
+	}
+	return trends, rows.Err()
+}
+
+// PruneScanHistory deletes scan snapshots taken before the given time
+func (db *DB) PruneScanHistory(before time.Time) (int64, error) {
+	done := db.beginWrite("prune_scan_history")
+	defer done()
+
+	result, err := db.conn.Exec(`DELETE FROM scan_history WHERE scanned_at < ?`, before.UTC().Format(time.RFC3339))
+	if err != nil {
+		exitOnCorruption(err)
+		return 0, fmt.Errorf("failed to prune scan history: %w", err)
+	}
+	return result.RowsAffected()
+}
+
+// ScanHistoryRow is a scan_history row as exported to a ResultSink
+type ScanHistoryRow struct {
+	ID             int64
+	Digest         string
+	Namespaces     string // comma-separated
+	Running        bool
+	ScannedAt      string
+	GrypeDBBuilt   string
+	Critical       int
+	High           int
+	Medium         int
+	Low            int
+	Negligible     int
+	Unknown        int
+	Total          int
+	KnownExploited int
+	RiskScore      float64
+}
+
+// GetScanHistoryRows returns up to limit scan_history rows with an ID above
+// afterID, in ID order
+func (db *DB) GetScanHistoryRows(afterID int64, limit int) ([]ScanHistoryRow, error) {
+	rows, err := db.conn.Query(`
+		SELECT id, digest, namespaces, running, scanned_at, COALESCE(grype_db_built, ''),
+		       critical, high, medium, low, negligible, unknown, total, known_exploited, risk_score
+		FROM scan_history
+		WHERE id > ?
+		ORDER BY id
+		LIMIT ?
+	`, afterID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query scan history rows: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var result []ScanHistoryRow
+	for rows.Next() {
+		var r ScanHistoryRow
+		if err := rows.Scan(&r.ID, &r.Digest, &r.Namespaces, &r.Running, &r.ScannedAt, &r.GrypeDBBuilt,
+			&r.Critical, &r.High, &r.Medium, &r.Low, &r.Negligible, &r.Unknown,
+			&r.Total, &r.KnownExploited, &r.RiskScore); err != nil {
+			return nil, fmt.Errorf("failed to scan scan history row: %w", err)
+		}
+		result = append(result, r)
+	}
+	return result, rows.Err()
+}
+
+// GetScanHistoryRange returns the number of scan_history rows with an ID up to
+// maxID and the lowest ID among them (0 when there are none)
+func (db *DB) GetScanHistoryRange(maxID int64) (count int, minID int64, err error) {
+	err = db.conn.QueryRow(`SELECT COUNT(*), COALESCE(MIN(id), 0) FROM scan_history WHERE id <= ?`, maxID).Scan(&count, &minID)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to count scan history: %w", err)
+	}
+	return count, minID, nil
+}
+
+// backfillScanHistory records one snapshot per scanned image as of its last scan
+func backfillScanHistory(conn *sql.DB) (int64, error) {
+	result, err := conn.Exec(fmt.Sprintf(scanSnapshotInsert,
+		"img.vulns_scanned_at", "img.vulns_scanned_at IS NOT NULL AND img.vulns_scanned_at != ''"))
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
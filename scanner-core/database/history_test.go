@@ -0,0 +1,122 @@
+package database
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/bvboe/b2s-go/scanner-core/containers"
+)
+
+func TestScanHistory(t *testing.T) {
+	db, err := New(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("failed to create database: %v", err)
+	}
+	defer func() { _ = Close(db) }()
+
+	for _, c := range []containers.Container{
+		{ID: containers.ContainerID{Namespace: "team-a", Pod: "app-1", Name: "app"}, Image: containers.ImageID{Reference: "app:1", Digest: "sha256:app"}},
+		{ID: containers.ContainerID{Namespace: "team-b", Pod: "db-1", Name: "db"}, Image: containers.ImageID{Reference: "db:1", Digest: "sha256:db"}},
+	} {
+		if _, err := db.AddContainer(c); err != nil {
+			t.Fatalf("AddContainer() error = %v", err)
+		}
+	}
+
+	first := []byte(`{"matches": [
+		{"vulnerability": {"id": "CVE-1", "severity": "Critical", "risk": 10, "knownExploited": [{"cve": "CVE-1"}]},
+		 "artifact": {"name": "openssl", "version": "3.0", "type": "deb"}},
+		{"vulnerability": {"id": "CVE-2", "severity": "High", "risk": 2.5},
+		 "artifact": {"name": "bash", "version": "5.1", "type": "deb"}}
+	]}`)
+	// The rescan after upgrading openssl only finds the high CVE
+	second := []byte(`{"matches": [
+		{"vulnerability": {"id": "CVE-2", "severity": "High", "risk": 2.5},
+		 "artifact": {"name": "bash", "version": "5.1", "type": "deb"}}
+	]}`)
+	for _, scan := range []struct {
+		digest string
+		json   []byte
+	}{{"sha256:app", first}, {"sha256:app", second}, {"sha256:db", first}} {
+		if err := db.StoreVulnerabilities(scan.digest, scan.json, time.Now()); err != nil {
+			t.Fatalf("StoreVulnerabilities() error = %v", err)
+		}
+	}
+
+	history, err := db.GetImageScanHistory("sha256:app", time.Time{})
+	if err != nil || len(history) != 2 {
+		t.Fatalf("GetImageScanHistory() = %+v, %v", history, err)
+	}
+	if h := history[0]; h.Critical != 1 || h.High != 1 || h.Total != 2 || h.KnownExploited != 1 || h.RiskScore != 12.5 ||
+		!reflect.DeepEqual(h.Namespaces, []string{"team-a"}) {
+		t.Errorf("first snapshot = %+v", h)
+	}
+	if h := history[1]; h.Critical != 0 || h.High != 1 || h.Total != 1 || h.RiskScore != 2.5 {
+		t.Errorf("second snapshot = %+v", h)
+	}
+	if history, err := db.GetImageScanHistory("sha256:unknown", time.Time{}); err != nil || history != nil {
+		t.Errorf("unknown image: GetImageScanHistory() = %v, %v", history, err)
+	}
+	if history, err := db.GetImageScanHistory("sha256:app", time.Now().Add(time.Hour)); err != nil || history == nil || len(history) != 0 {
+		t.Errorf("future window: GetImageScanHistory() = %v, %v", history, err)
+	}
+
+	// Only the latest snapshot of each image counts per interval
+	since := time.Now().Add(-24 * time.Hour)
+	for _, interval := range []string{TrendIntervalDay, TrendIntervalWeek} {
+		trends, err := db.GetScanTrends(since, interval, nil)
+		if err != nil || len(trends) != 1 {
+			t.Fatalf("GetScanTrends(%s) = %+v, %v", interval, trends, err)
+		}
+		if p := trends[0]; p.Images != 2 || p.Critical != 1 || p.High != 2 || p.Total != 3 || p.RiskScore != 15 {
+			t.Errorf("GetScanTrends(%s) = %+v", interval, p)
+		}
+	}
+	week, _ := db.GetScanTrends(since, TrendIntervalWeek, nil)
+	if start, err := time.Parse(time.DateOnly, week[0].Period); err != nil || start.Weekday() != time.Monday {
+		t.Errorf("week period = %s, want a Monday", week[0].Period)
+	}
+
+	trends, err := db.GetScanTrends(since, TrendIntervalDay, []string{"team-b", "team-c"})
+	if err != nil || len(trends) != 1 || trends[0].Images != 1 || trends[0].Critical != 1 {
+		t.Errorf("GetScanTrends(team-b) = %+v, %v", trends, err)
+	}
+	if _, err := db.GetScanTrends(since, "month", nil); err == nil {
+		t.Error("GetScanTrends(month) did not fail")
+	}
+
+	// Exported in ID order, in batches
+	rows, err := db.GetScanHistoryRows(0, 2)
+	if err != nil || len(rows) != 2 {
+		t.Fatalf("GetScanHistoryRows(0, 2) = %+v, %v", rows, err)
+	}
+	if r := rows[0]; r.Digest != "sha256:app" || r.Namespaces != "team-a" || !r.Running || r.Critical != 1 || r.RiskScore != 12.5 {
+		t.Errorf("first row = %+v", r)
+	}
+	rest, err := db.GetScanHistoryRows(rows[1].ID, 10)
+	if err != nil || len(rest) != 1 || rest[0].Digest != "sha256:db" {
+		t.Errorf("GetScanHistoryRows(after %d) = %+v, %v", rows[1].ID, rest, err)
+	}
+	if count, minID, err := db.GetScanHistoryRange(rows[1].ID); err != nil || count != 2 || minID != rows[0].ID {
+		t.Errorf("GetScanHistoryRange(%d) = %d, %d, %v", rows[1].ID, count, minID, err)
+	}
+
+	pruned, err := db.PruneScanHistory(time.Now().Add(time.Hour))
+	if err != nil || pruned != 3 {
+		t.Errorf("PruneScanHistory() = %d, %v, want 3", pruned, err)
+	}
+
+	// The migration backfills one snapshot per scanned image from its current results
+	if _, err := db.conn.Exec(`DROP TABLE scan_history`); err != nil {
+		t.Fatal(err)
+	}
+	if err := migrateToV63(db.conn); err != nil {
+		t.Fatalf("migrateToV63() error = %v", err)
+	}
+	history, err = db.GetImageScanHistory("sha256:app", time.Time{})
+	if err != nil || len(history) != 1 || history[0].Total != 1 || history[0].ScannedAt == "" {
+		t.Errorf("backfilled GetImageScanHistory() = %+v, %v", history, err)
+	}
+}
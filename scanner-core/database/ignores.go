@@ -0,0 +1,445 @@
+package database
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bvboe/b2s-go/scanner-core/vex"
+)
+
+// IgnoreSourcePrefix is the suppression source of findings accepted by an
+// ignore, followed by its ID
+const IgnoreSourcePrefix = "ignore:"
+
+// StatusAcceptedRisk is the suppression status of ignored findings
+const StatusAcceptedRisk = "accepted_risk"
+
+// MaxIgnoreReasonLength bounds the reason recorded for an ignore
+const MaxIgnoreReasonLength = 4096
+
+// Actions recorded in the audit trail of ignores
+const (
+	IgnoreCreated = "created"
+	IgnoreUpdated = "updated"
+	IgnoreDeleted = "deleted"
+	IgnoreExpired = "expired"
+)
+
+// ErrInvalidIgnore is returned for an ignore missing a required field or with
+// an unreadable expiry date
+var ErrInvalidIgnore = errors.New("invalid ignore")
+
+// VulnerabilityIgnore accepts the risk of a vulnerability, optionally only in
+// one package and/or image, until it expires. Findings it covers are left out
+// of the API, summaries and metrics like those VEX statements suppress, and
+// reappear once it expires or is deleted.
+type VulnerabilityIgnore struct {
+	ID          int64  `json:"id"`
+	CVEID       string `json:"cve_id"`
+	PackageName string `json:"package_name,omitempty"` // package name or purl; empty for every package
+	Image       string `json:"image,omitempty"`        // digest, reference or repository; empty for every image
+	Reason      string `json:"reason"`
+	Owner       string `json:"owner"`
+	ExpiresAt   string `json:"expires_at,omitempty"` // RFC 3339; empty for no expiry
+	Active      bool   `json:"active"`
+	Suppressed  int    `json:"suppressed"` // findings currently ignored
+	CreatedAt   string `json:"created_at"`
+	UpdatedAt   string `json:"updated_at"`
+}
+
+// VulnerabilityIgnoreEvent is an entry of the audit trail of ignores
+type VulnerabilityIgnoreEvent struct {
+	ID          int64  `json:"id"`
+	IgnoreID    int64  `json:"ignore_id"`
+	Action      string `json:"action"` // created, updated, deleted or expired
+	CVEID       string `json:"cve_id"`
+	PackageName string `json:"package_name,omitempty"`
+	Image       string `json:"image,omitempty"`
+	Reason      string `json:"reason,omitempty"`
+	Owner       string `json:"owner,omitempty"`
+	ExpiresAt   string `json:"expires_at,omitempty"`
+	Actor       string `json:"actor,omitempty"`
+	At          string `json:"at"`
+}
+
+// ignoreSet holds the active ignores applied when vulnerabilities are stored
+type ignoreSet struct {
+	ignores     []VulnerabilityIgnore
+	fingerprint string
+}
+
+// empty reports whether no ignore is active
+func (s *ignoreSet) empty() bool {
+	return s == nil || len(s.ignores) == 0
+}
+
+// cveIDs returns the upper case vulnerability IDs of the active ignores
+func (s *ignoreSet) cveIDs() []string {
+	if s == nil {
+		return nil
+	}
+	ids := make([]string, 0, len(s.ignores))
+	for _, ig := range s.ignores {
+		ids = append(ids, strings.ToUpper(ig.CVEID))
+	}
+	return ids
+}
+
+// match returns the active ignore covering a finding
+func (s *ignoreSet) match(f vex.Finding) (vex.Match, bool) {
+	if s == nil {
+		return vex.Match{}, false
+	}
+	for _, ig := range s.ignores {
+		if !strings.EqualFold(ig.CVEID, f.Vulnerability) && !containsFold(f.Aliases, ig.CVEID) {
+			continue
+		}
+		if ig.PackageName != "" && !vex.MatchesPackage(ig.PackageName, f) {
+			continue
+		}
+		if ig.Image != "" && !vex.MatchesImage(ig.Image, f) {
+			continue
+		}
+		updated, _ := time.Parse(time.RFC3339, ig.UpdatedAt)
+		return vex.Match{
+			Source:        IgnoreSourcePrefix + strconv.FormatInt(ig.ID, 10),
+			Vulnerability: ig.CVEID,
+			Status:        StatusAcceptedRisk,
+			Justification: ig.Reason,
+			Timestamp:     updated,
+		}, true
+	}
+	return vex.Match{}, false
+}
+
+// ParseIgnoreExpiry reads an expiry given as RFC 3339 or as a date, which
+// expires at the end of that day (UTC). Empty means no expiry.
+func ParseIgnoreExpiry(value string) (string, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return "", nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t.UTC().Format(time.RFC3339), nil
+	}
+	if d, err := time.Parse(time.DateOnly, value); err == nil {
+		return d.AddDate(0, 0, 1).UTC().Format(time.RFC3339), nil
+	}
+	return "", fmt.Errorf("%w: expires_at must be a date (2006-01-02) or RFC 3339 time", ErrInvalidIgnore)
+}
+
+// SaveVulnerabilityIgnore creates an ignore (ID 0) or replaces one, records the
+// change in the audit trail as done by actor, and applies it to the stored
+// vulnerabilities. Returns the ignore (nil if there is none with the ID) and
+// the number of images whose findings were re-evaluated.
+func (db *DB) SaveVulnerabilityIgnore(ig VulnerabilityIgnore, actor string) (*VulnerabilityIgnore, int, error) {
+	ig.CVEID, ig.PackageName, ig.Image = strings.TrimSpace(ig.CVEID), strings.TrimSpace(ig.PackageName), strings.TrimSpace(ig.Image)
+	ig.Reason, ig.Owner = strings.TrimSpace(ig.Reason), strings.TrimSpace(ig.Owner)
+	switch {
+	case ig.CVEID == "":
+		return nil, 0, fmt.Errorf("%w: cve_id is required", ErrInvalidIgnore)
+	case ig.Reason == "":
+		return nil, 0, fmt.Errorf("%w: reason is required", ErrInvalidIgnore)
+	case len(ig.Reason) > MaxIgnoreReasonLength:
+		return nil, 0, fmt.Errorf("%w: reason exceeds %d characters", ErrInvalidIgnore, MaxIgnoreReasonLength)
+	case ig.Owner == "":
+		return nil, 0, fmt.Errorf("%w: owner is required", ErrInvalidIgnore)
+	}
+	expiresAt, err := ParseIgnoreExpiry(ig.ExpiresAt)
+	if err != nil {
+		return nil, 0, err
+	}
+	if expiresAt != "" && expiresAt <= time.Now().UTC().Format(time.RFC3339) {
+		return nil, 0, fmt.Errorf("%w: expires_at is in the past", ErrInvalidIgnore)
+	}
+	ig.ExpiresAt = expiresAt
+	if actor = strings.TrimSpace(actor); actor == "" {
+		actor = ig.Owner
+	}
+
+	done := db.beginWrite("save_vulnerability_ignore")
+	tx, err := db.conn.Begin()
+	if err != nil {
+		done()
+		exitOnCorruption(err)
+		return nil, 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	rollback := func() { _ = tx.Rollback() }
+
+	action := IgnoreCreated
+	if ig.ID == 0 {
+		result, err := tx.Exec(`
+			INSERT INTO vulnerability_ignores (cve_id, package_name, image, reason, owner, expires_at)
+			VALUES (?, ?, ?, ?, ?, ?)
+		`, ig.CVEID, ig.PackageName, ig.Image, ig.Reason, ig.Owner, ig.ExpiresAt)
+		if err != nil {
+			rollback()
+			done()
+			exitOnCorruption(err)
+			return nil, 0, fmt.Errorf("failed to create ignore: %w", err)
+		}
+		ig.ID, _ = result.LastInsertId()
+	} else {
+		action = IgnoreUpdated
+		result, err := tx.Exec(`
+			UPDATE vulnerability_ignores
+			SET cve_id = ?, package_name = ?, image = ?, reason = ?, owner = ?, expires_at = ?,
+			    expiry_recorded = 0, updated_at = `+sqlNow+`
+			WHERE id = ?
+		`, ig.CVEID, ig.PackageName, ig.Image, ig.Reason, ig.Owner, ig.ExpiresAt, ig.ID)
+		if err != nil {
+			rollback()
+			done()
+			exitOnCorruption(err)
+			return nil, 0, fmt.Errorf("failed to update ignore: %w", err)
+		}
+		if n, _ := result.RowsAffected(); n == 0 {
+			rollback()
+			done()
+			return nil, 0, nil
+		}
+	}
+	if err := recordIgnoreEvent(tx, action, ig, actor); err != nil {
+		rollback()
+		done()
+		return nil, 0, err
+	}
+	if err := tx.Commit(); err != nil {
+		done()
+		exitOnCorruption(err)
+		return nil, 0, fmt.Errorf("failed to commit ignore: %w", err)
+	}
+	done()
+
+	images, err := db.applyVEX()
+	if err != nil {
+		return nil, 0, err
+	}
+	saved, err := db.queryVulnerabilityIgnores(`WHERE id = ?`, ig.ID)
+	if err != nil || len(saved) == 0 {
+		return nil, images, err
+	}
+	return &saved[0], images, nil
+}
+
+// DeleteVulnerabilityIgnore removes an ignore, records the deletion as done by
+// actor and restores the findings it covered. Reports whether there was one
+// and the number of images re-evaluated.
+func (db *DB) DeleteVulnerabilityIgnore(id int64, actor string) (bool, int, error) {
+	existing, err := db.queryVulnerabilityIgnores(`WHERE id = ?`, id)
+	if err != nil {
+		return false, 0, err
+	}
+	if len(existing) == 0 {
+		return false, 0, nil
+	}
+
+	done := db.beginWrite("delete_vulnerability_ignore")
+	tx, err := db.conn.Begin()
+	if err != nil {
+		done()
+		exitOnCorruption(err)
+		return false, 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	if _, err := tx.Exec(`DELETE FROM vulnerability_ignores WHERE id = ?`, id); err != nil {
+		_ = tx.Rollback()
+		done()
+		exitOnCorruption(err)
+		return false, 0, fmt.Errorf("failed to delete ignore: %w", err)
+	}
+	if err := recordIgnoreEvent(tx, IgnoreDeleted, existing[0], strings.TrimSpace(actor)); err != nil {
+		_ = tx.Rollback()
+		done()
+		return false, 0, err
+	}
+	if err := tx.Commit(); err != nil {
+		done()
+		exitOnCorruption(err)
+		return false, 0, fmt.Errorf("failed to commit ignore deletion: %w", err)
+	}
+	done()
+
+	images, err := db.applyVEX()
+	return true, images, err
+}
+
+// GetVulnerabilityIgnores returns all ignores, expired ones included, ordered
+// by vulnerability ID
+func (db *DB) GetVulnerabilityIgnores() ([]VulnerabilityIgnore, error) {
+	return db.queryVulnerabilityIgnores("")
+}
+
+// GetVulnerabilityIgnoreEvents returns the audit trail of one ignore, or of all
+// ignores if ignoreID is 0, oldest first
+func (db *DB) GetVulnerabilityIgnoreEvents(ignoreID int64) ([]VulnerabilityIgnoreEvent, error) {
+	where, args := "", []any{}
+	if ignoreID > 0 {
+		where, args = "WHERE ignore_id = ?", append(args, ignoreID)
+	}
+	rows, err := db.conn.Query(`
+		SELECT id, ignore_id, action, cve_id, package_name, image, reason, owner, expires_at, actor, at
+		FROM vulnerability_ignore_events `+where+`
+		ORDER BY id
+	`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query ignore events: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	events := []VulnerabilityIgnoreEvent{}
+	for rows.Next() {
+		var e VulnerabilityIgnoreEvent
+		if err := rows.Scan(&e.ID, &e.IgnoreID, &e.Action, &e.CVEID, &e.PackageName, &e.Image,
+			&e.Reason, &e.Owner, &e.ExpiresAt, &e.Actor, &e.At); err != nil {
+			return nil, fmt.Errorf("failed to scan ignore event: %w", err)
+		}
+		events = append(events, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate ignore events: %w", err)
+	}
+	return events, nil
+}
+
+// ExpireVulnerabilityIgnores records the ignores that expired since the last
+// call in the audit trail and restores the findings they covered. Returns the
+// number of images re-evaluated.
+func (db *DB) ExpireVulnerabilityIgnores() (int, error) {
+	now := time.Now().UTC().Format(time.RFC3339)
+	expired, err := db.queryVulnerabilityIgnores(`WHERE expires_at != '' AND expires_at <= ? AND expiry_recorded = 0`, now)
+	if err != nil {
+		return 0, err
+	}
+	if len(expired) > 0 {
+		done := db.beginWrite("expire_vulnerability_ignores")
+		tx, err := db.conn.Begin()
+		if err != nil {
+			done()
+			exitOnCorruption(err)
+			return 0, fmt.Errorf("failed to begin transaction: %w", err)
+		}
+		for _, ig := range expired {
+			if _, err := tx.Exec(`UPDATE vulnerability_ignores SET expiry_recorded = 1 WHERE id = ?`, ig.ID); err != nil {
+				_ = tx.Rollback()
+				done()
+				exitOnCorruption(err)
+				return 0, fmt.Errorf("failed to mark ignore expired: %w", err)
+			}
+			if err := recordIgnoreEvent(tx, IgnoreExpired, ig, ""); err != nil {
+				_ = tx.Rollback()
+				done()
+				return 0, err
+			}
+		}
+		if err := tx.Commit(); err != nil {
+			done()
+			exitOnCorruption(err)
+			return 0, fmt.Errorf("failed to commit expired ignores: %w", err)
+		}
+		done()
+		for _, ig := range expired {
+			log.Info("vulnerability ignore expired", "id", ig.ID, "cve", ig.CVEID, "owner", ig.Owner, "expires_at", ig.ExpiresAt)
+		}
+	}
+	return db.applyVEX()
+}
+
+// StartIgnoreExpiry checks every minute for expired ignores, so the findings
+// they covered reappear soon after the expiry date. Should be called once at
+// startup by the process writing the database.
+func (db *DB) StartIgnoreExpiry(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(time.Minute)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				if _, err := db.ExpireVulnerabilityIgnores(); err != nil {
+					log.Error("failed to expire vulnerability ignores", slog.Any("error", err))
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+// loadIgnoreSet reads the ignores active now
+func (db *DB) loadIgnoreSet() (*ignoreSet, error) {
+	active, err := db.queryVulnerabilityIgnores(`WHERE expires_at = '' OR expires_at > ?`,
+		time.Now().UTC().Format(time.RFC3339))
+	if err != nil {
+		return nil, err
+	}
+	set := &ignoreSet{ignores: active}
+	if len(active) > 0 {
+		h := sha256.New()
+		for _, ig := range active {
+			fmt.Fprintf(h, "%d\n%s\n%s\n%s\n%s\n%s\n", ig.ID, ig.CVEID, ig.PackageName, ig.Image, ig.Reason, ig.ExpiresAt)
+		}
+		set.fingerprint = hex.EncodeToString(h.Sum(nil))
+	}
+	return set, nil
+}
+
+// queryVulnerabilityIgnores returns the ignores matching the where clause
+func (db *DB) queryVulnerabilityIgnores(where string, args ...any) ([]VulnerabilityIgnore, error) {
+	rows, err := db.conn.Query(`
+		SELECT g.id, g.cve_id, g.package_name, g.image, g.reason, g.owner, g.expires_at, g.created_at, g.updated_at,
+			(SELECT COUNT(*) FROM vex_suppressions s WHERE s.source = '`+IgnoreSourcePrefix+`' || g.id)
+		FROM vulnerability_ignores g `+where+`
+		ORDER BY g.cve_id, g.id
+	`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query ignores: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	now := time.Now().UTC().Format(time.RFC3339)
+	ignores := []VulnerabilityIgnore{}
+	for rows.Next() {
+		var ig VulnerabilityIgnore
+		if err := rows.Scan(&ig.ID, &ig.CVEID, &ig.PackageName, &ig.Image, &ig.Reason, &ig.Owner,
+			&ig.ExpiresAt, &ig.CreatedAt, &ig.UpdatedAt, &ig.Suppressed); err != nil {
+			return nil, fmt.Errorf("failed to scan ignore: %w", err)
+		}
+		ig.Active = ig.ExpiresAt == "" || ig.ExpiresAt > now
+		ignores = append(ignores, ig)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate ignores: %w", err)
+	}
+	return ignores, nil
+}
+
+// recordIgnoreEvent appends a change of an ignore to the audit trail
+func recordIgnoreEvent(tx *sql.Tx, action string, ig VulnerabilityIgnore, actor string) error {
+	_, err := tx.Exec(`
+		INSERT INTO vulnerability_ignore_events
+			(ignore_id, action, cve_id, package_name, image, reason, owner, expires_at, actor)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, ig.ID, action, ig.CVEID, ig.PackageName, ig.Image, ig.Reason, ig.Owner, ig.ExpiresAt, actor)
+	if err != nil {
+		exitOnCorruption(err)
+		return fmt.Errorf("failed to record ignore event: %w", err)
+	}
+	return nil
+}
+
+// containsFold reports whether list contains s, ignoring case
+func containsFold(list []string, s string) bool {
+	for _, v := range list {
+		if strings.EqualFold(v, s) {
+			return true
+		}
+	}
+	return false
+}
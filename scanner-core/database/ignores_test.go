@@ -0,0 +1,132 @@
+package database
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestVulnerabilityIgnores(t *testing.T) {
+	db, err := New(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("Failed to create database: %v", err)
+	}
+	defer func() { _ = Close(db) }()
+
+	vulns := []byte(`{"matches":[
+		{"vulnerability":{"id":"CVE-2024-0001","severity":"High"},"artifact":{"name":"openssl","version":"3.0.0","type":"apk"}},
+		{"vulnerability":{"id":"CVE-2024-0002","severity":"Low"},"artifact":{"name":"zlib","version":"1.3","type":"apk"}}]}`)
+	for _, digest := range []string{"sha256:app", "sha256:other"} {
+		if err := db.ImportScanResults(digest, []byte(`{"artifacts":[]}`), vulns, time.Time{}); err != nil {
+			t.Fatalf("ImportScanResults() error = %v", err)
+		}
+	}
+	findings := func(digest string) int {
+		t.Helper()
+		var n int
+		if err := db.conn.QueryRow(`
+			SELECT COUNT(*) FROM image_vulnerabilities v JOIN images i ON v.image_id = i.id WHERE i.digest = ?
+		`, digest).Scan(&n); err != nil {
+			t.Fatalf("failed to count findings: %v", err)
+		}
+		return n
+	}
+
+	for _, invalid := range []VulnerabilityIgnore{
+		{Reason: "r", Owner: "o"},
+		{CVEID: "CVE-2024-0001", Owner: "o"},
+		{CVEID: "CVE-2024-0001", Reason: "r"},
+		{CVEID: "CVE-2024-0001", Reason: "r", Owner: "o", ExpiresAt: "next week"},
+		{CVEID: "CVE-2024-0001", Reason: "r", Owner: "o", ExpiresAt: "2020-01-01"},
+	} {
+		if _, _, err := db.SaveVulnerabilityIgnore(invalid, ""); !errors.Is(err, ErrInvalidIgnore) {
+			t.Errorf("SaveVulnerabilityIgnore(%+v) error = %v, want ErrInvalidIgnore", invalid, err)
+		}
+	}
+
+	// An ignore scoped to one package and image leaves the other image alone
+	saved, images, err := db.SaveVulnerabilityIgnore(VulnerabilityIgnore{
+		CVEID: "cve-2024-0001", PackageName: "openssl", Image: "sha256:app",
+		Reason: "not reachable", Owner: "platform-team", ExpiresAt: "2999-12-31",
+	}, "alice")
+	if err != nil {
+		t.Fatalf("SaveVulnerabilityIgnore() error = %v", err)
+	}
+	if images != 2 || !saved.Active || saved.Suppressed != 1 || saved.ExpiresAt != "3000-01-01T00:00:00Z" {
+		t.Errorf("SaveVulnerabilityIgnore() = %+v, %d images re-evaluated", saved, images)
+	}
+	if app, other := findings("sha256:app"), findings("sha256:other"); app != 1 || other != 2 {
+		t.Errorf("findings after ignore = %d and %d, want 1 and 2", app, other)
+	}
+	suppressed, err := db.GetVEXSuppressions("sha256:app")
+	if err != nil || len(suppressed) != 1 {
+		t.Fatalf("GetVEXSuppressions() = %+v, %v", suppressed, err)
+	}
+	if s := suppressed[0]; s.Status != StatusAcceptedRisk || s.Justification != "not reachable" || s.Source != "ignore:1" {
+		t.Errorf("suppression = %+v", s)
+	}
+
+	// A rescan keeps the finding ignored
+	if err := db.ImportScanResults("sha256:app", []byte(`{"artifacts":[]}`), vulns, time.Time{}); err != nil {
+		t.Fatalf("ImportScanResults() error = %v", err)
+	}
+	if n := findings("sha256:app"); n != 1 {
+		t.Errorf("findings after rescan = %d, want 1", n)
+	}
+
+	// Widening the ignore to every image applies it to the other one
+	saved.Image = ""
+	if saved, _, err = db.SaveVulnerabilityIgnore(*saved, "bob"); err != nil || saved == nil {
+		t.Fatalf("SaveVulnerabilityIgnore() update = %+v, %v", saved, err)
+	}
+	if n := findings("sha256:other"); n != 1 {
+		t.Errorf("findings after widening = %d, want 1", n)
+	}
+	if missing, _, err := db.SaveVulnerabilityIgnore(VulnerabilityIgnore{ID: 99, CVEID: "CVE-1", Reason: "r", Owner: "o"}, ""); err != nil || missing != nil {
+		t.Errorf("updating a missing ignore = %+v, %v, want nil", missing, err)
+	}
+
+	// Once expired the findings reappear and the expiry is audited once
+	if _, err := db.conn.Exec(`UPDATE vulnerability_ignores SET expires_at = '2000-01-01T00:00:00Z'`); err != nil {
+		t.Fatalf("failed to backdate expiry: %v", err)
+	}
+	if _, err := db.ExpireVulnerabilityIgnores(); err != nil {
+		t.Fatalf("ExpireVulnerabilityIgnores() error = %v", err)
+	}
+	if app, other := findings("sha256:app"), findings("sha256:other"); app != 2 || other != 2 {
+		t.Errorf("findings after expiry = %d and %d, want 2 and 2", app, other)
+	}
+	if _, err := db.ExpireVulnerabilityIgnores(); err != nil {
+		t.Fatalf("ExpireVulnerabilityIgnores() error = %v", err)
+	}
+	ignores, err := db.GetVulnerabilityIgnores()
+	if err != nil || len(ignores) != 1 || ignores[0].Active || ignores[0].Suppressed != 0 {
+		t.Errorf("GetVulnerabilityIgnores() = %+v, %v, want one inactive ignore", ignores, err)
+	}
+
+	if found, _, err := db.DeleteVulnerabilityIgnore(saved.ID, "carol"); err != nil || !found {
+		t.Fatalf("DeleteVulnerabilityIgnore() = %v, %v", found, err)
+	}
+	if found, _, err := db.DeleteVulnerabilityIgnore(saved.ID, "carol"); err != nil || found {
+		t.Errorf("deleting a deleted ignore = %v, %v, want not found", found, err)
+	}
+	events, err := db.GetVulnerabilityIgnoreEvents(saved.ID)
+	if err != nil {
+		t.Fatalf("GetVulnerabilityIgnoreEvents() error = %v", err)
+	}
+	var trail []string
+	for _, e := range events {
+		trail = append(trail, e.Action+" by "+e.Actor)
+	}
+	want := []string{"created by alice", "updated by bob", "expired by ", "deleted by carol"}
+	if len(trail) != len(want) {
+		t.Fatalf("audit trail = %q, want %q", trail, want)
+	}
+	for i := range want {
+		if trail[i] != want[i] {
+			t.Errorf("audit trail = %q, want %q", trail, want)
+			break
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package database
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/bvboe/b2s-go/scanner-core/containers"
+)
+
+// imageSizeSBOM has image metadata for 3 layers without a reported image size
+const imageSizeSBOM = `{"artifacts": [{"name": "busybox", "version": "1.36", "type": "apk"}],
+	"source": {"type": "image", "metadata": {"architecture": "arm64",
+	  "layers": [{"digest": "sha256:l1", "size": 1000}, {"digest": "sha256:l2", "size": 200}, {"digest": "sha256:l3", "size": 30}]}}}`
+
+func TestImageSizeAndScanDurations(t *testing.T) {
+	db, err := New(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("failed to create database: %v", err)
+	}
+	defer func() { _ = Close(db) }()
+
+	digest := "sha256:app"
+	if _, err := db.AddContainer(containers.Container{
+		ID:    containers.ContainerID{Namespace: "default", Pod: "app", Name: "app"},
+		Image: containers.ImageID{Reference: "app:1", Digest: digest},
+	}); err != nil {
+		t.Fatalf("AddContainer() error = %v", err)
+	}
+	if err := db.StoreSBOM(digest, []byte(imageSizeSBOM)); err != nil {
+		t.Fatalf("StoreSBOM() error = %v", err)
+	}
+
+	check := func(wantSize, wantLayers, wantSBOMMs, wantVulnMs int64) {
+		t.Helper()
+		var size, layers, sbomMs, vulnMs *int64
+		if err := db.conn.QueryRow(`SELECT image_size, layer_count, sbom_duration_ms, vuln_scan_duration_ms FROM images WHERE digest = ?`,
+			digest).Scan(&size, &layers, &sbomMs, &vulnMs); err != nil {
+			t.Fatal(err)
+		}
+		value := func(v *int64) int64 {
+			if v == nil {
+				return -1
+			}
+			return *v
+		}
+		if value(size) != wantSize || value(layers) != wantLayers || value(sbomMs) != wantSBOMMs || value(vulnMs) != wantVulnMs {
+			t.Errorf("size %d, layers %d, SBOM %dms, scan %dms; want %d, %d, %d, %d",
+				value(size), value(layers), value(sbomMs), value(vulnMs), wantSize, wantLayers, wantSBOMMs, wantVulnMs)
+		}
+	}
+
+	// The size is the sum of the layers when Syft does not report it
+	check(1230, 3, -1, -1)
+
+	if err := db.RecordScanDurations(digest, 4*time.Second, 1500*time.Millisecond); err != nil {
+		t.Fatalf("RecordScanDurations() error = %v", err)
+	}
+	// A rescan of the stored SBOM keeps the SBOM duration
+	if err := db.RecordScanDurations(digest, 0, 700*time.Millisecond); err != nil {
+		t.Fatalf("RecordScanDurations() error = %v", err)
+	}
+	check(1230, 3, 4000, 700)
+
+	// The migration populates sizes from stored SBOMs
+	unshareSBOMs(t, db)
+	for _, column := range []string{"image_size", "layer_count", "sbom_duration_ms", "vuln_scan_duration_ms"} {
+		if _, err := db.conn.Exec(`ALTER TABLE images DROP COLUMN ` + column); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := migrateToV62(db.conn); err != nil {
+		t.Fatalf("migrateToV62() error = %v", err)
+	}
+	check(1230, 3, -1, -1)
+}
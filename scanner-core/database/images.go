@@ -35,13 +35,28 @@ func (db *DB) getOrCreateImageTx(exec interface {
 
 	// Try to get existing image by digest
 	var id int64
+	var deleted bool
+	var lastReference sql.NullString
 	err := exec.QueryRow(`
-		SELECT id FROM images
+		SELECT id, deleted_at IS NOT NULL, last_reference FROM images
 		WHERE digest = ?
-	`, image.Digest).Scan(&id)
+	`, image.Digest).Scan(&id, &deleted, &lastReference)
 
 	if err == nil {
-		// Image already exists
+		// Image already exists. Seeing it again restores a soft-deleted image.
+		// The reference is only recorded when missing or on restore, so images
+		// running under several tags don't cause a write on every sync.
+		if deleted || (image.Reference != "" && !lastReference.Valid) {
+			if _, err := exec.Exec(`
+				UPDATE images SET deleted_at = NULL, last_reference = COALESCE(NULLIF(?, ''), last_reference) WHERE id = ?
+			`, image.Reference, id); err != nil {
+				return 0, false, fmt.Errorf("failed to update image: %w", err)
+			}
+			if deleted {
+				log.Info("restored soft-deleted image",
+					"reference", image.Reference, "digest", image.Digest, "id", id)
+			}
+		}
 		return id, false, nil
 	}
 
@@ -51,9 +66,9 @@ func (db *DB) getOrCreateImageTx(exec interface {
 
 	// Image doesn't exist, create it
 	result, err := exec.Exec(`
-		INSERT INTO images (digest)
-		VALUES (?)
-	`, image.Digest)
+		INSERT INTO images (digest, last_reference)
+		VALUES (?, NULLIF(?, ''))
+	`, image.Digest, image.Reference)
 
 	if err != nil {
 		return 0, false, fmt.Errorf("failed to insert image: %w", err)
@@ -70,11 +85,13 @@ func (db *DB) getOrCreateImageTx(exec interface {
 	return id, true, nil
 }
 
-// GetAllImages returns all container images from the database
+// GetAllImages returns all container images from the database, except
+// soft-deleted ones
 func (db *DB) GetAllImages() (interface{}, error) {
 	rows, err := db.conn.Query(`
 		SELECT id, digest, status, created_at, updated_at
 		FROM images
+		WHERE deleted_at IS NULL
 		ORDER BY created_at DESC
 	`)
 	if err != nil {
@@ -3,6 +3,7 @@ package database
 import (
 	"database/sql"
 	"fmt"
+	"time"
 
 	"github.com/bvboe/b2s-go/scanner-core/containers"
 )
@@ -19,6 +20,8 @@ type ContainerRow struct {
 	CreatedAt        string `json:"created_at"`
 	NodeName         string `json:"node_name"`
 	ContainerRuntime string `json:"container_runtime"`
+	ImagePullPolicy  string `json:"image_pull_policy"`
+	Registry         string `json:"registry"`
 }
 
 // AddContainer adds a container to the database
@@ -85,9 +88,11 @@ func (db *DB) AddContainer(c containers.Container) (bool, error) {
 		if existingImageID != imageID {
 			_, err = tx.Exec(`
 				UPDATE containers
-				SET image_id = ?, reference = ?, node_name = ?, container_runtime = ?
+				SET image_id = ?, reference = ?, node_name = ?, container_runtime = ?,
+				    image_pull_policy = ?, registry = ?
 				WHERE id = ?
-			`, imageID, c.Image.Reference, c.NodeName, c.ContainerRuntime, existingID)
+			`, imageID, c.Image.Reference, c.NodeName, c.ContainerRuntime,
+				c.ImagePullPolicy, containers.RegistryHost(c.Image.Reference), existingID)
 			if err != nil {
 				exitOnCorruption(err)
 				return false, fmt.Errorf("failed to update container: %w", err)
@@ -97,7 +102,10 @@ func (db *DB) AddContainer(c containers.Container) (bool, error) {
 				return false, fmt.Errorf("failed to commit transaction: %w", err)
 			}
 			log.Info("updated container in database",
-				"namespace", c.ID.Namespace, "pod", c.ID.Pod, "name", c.ID.Name, "image_id", imageID)
+				"namespace", c.ID.Namespace, "pod", c.ID.Pod, "name", c.ID.Name,
+				"image_id", imageID, "previous_image_id", existingImageID)
+			// Counts and filter options still reflect the previous image
+			db.notifyWrite()
 			return true, nil
 		}
 		if err := tx.Commit(); err != nil {
@@ -113,10 +121,11 @@ func (db *DB) AddContainer(c containers.Container) (bool, error) {
 
 	// Container doesn't exist, insert it.
 	_, err = tx.Exec(`
-		INSERT INTO containers (namespace, pod, name, reference, image_id, node_name, container_runtime)
-		VALUES (?, ?, ?, ?, ?, ?, ?)
+		INSERT INTO containers (namespace, pod, name, reference, image_id, node_name, container_runtime, image_pull_policy, registry)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
 	`, c.ID.Namespace, c.ID.Pod, c.ID.Name,
-		c.Image.Reference, imageID, c.NodeName, c.ContainerRuntime)
+		c.Image.Reference, imageID, c.NodeName, c.ContainerRuntime,
+		c.ImagePullPolicy, containers.RegistryHost(c.Image.Reference))
 	if err != nil {
 		exitOnCorruption(err)
 		return false, fmt.Errorf("failed to insert container: %w", err)
@@ -159,6 +168,33 @@ func (db *DB) RemoveContainer(id containers.ContainerID) error {
 	return nil
 }
 
+// RemoveNamespaceContainers removes all containers of a namespace in one write,
+// so deleting a namespace refreshes summaries and metrics once instead of
+// once per pod. Returns the number of containers removed.
+func (db *DB) RemoveNamespaceContainers(namespace string) (int, error) {
+	done := db.beginWrite("remove_namespace_containers")
+	defer done()
+	result, err := db.conn.Exec(`DELETE FROM containers WHERE namespace = ?`, namespace)
+	if err != nil {
+		exitOnCorruption(err)
+		return 0, fmt.Errorf("failed to delete namespace containers: %w", err)
+	}
+
+	removed, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if removed > 0 {
+		log.Info("namespace containers removed from database", "namespace", namespace, "containers", removed)
+		db.notifyWrite()
+		// Invalidate and rebuild the container vulnerability metrics cache.
+		go db.rebuildContainerVulnCache()
+	}
+
+	return int(removed), nil
+}
+
 // SetContainers replaces all containers with the given set and returns reconciliation statistics
 func (db *DB) SetContainers(containerList []containers.Container) (*containers.ReconciliationStats, error) {
 	// Validate all containers before starting transaction
@@ -222,10 +258,11 @@ func (db *DB) SetContainers(containerList []containers.Container) (*containers.R
 
 		// Insert container
 		_, err = tx.Exec(`
-			INSERT INTO containers (namespace, pod, name, reference, image_id, node_name, container_runtime)
-			VALUES (?, ?, ?, ?, ?, ?, ?)
+			INSERT INTO containers (namespace, pod, name, reference, image_id, node_name, container_runtime, image_pull_policy, registry)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
 		`, c.ID.Namespace, c.ID.Pod, c.ID.Name,
-			c.Image.Reference, imageID, c.NodeName, c.ContainerRuntime)
+			c.Image.Reference, imageID, c.NodeName, c.ContainerRuntime,
+			c.ImagePullPolicy, containers.RegistryHost(c.Image.Reference))
 
 		if err != nil {
 			exitOnCorruption(err)
@@ -253,7 +290,8 @@ func (db *DB) GetAllContainers() (interface{}, error) {
 		SELECT
 			c.id, c.namespace, c.pod, c.name,
 			c.reference, c.image_id, img.digest,
-			c.created_at, c.node_name, c.container_runtime
+			c.created_at, c.node_name, c.container_runtime,
+			c.image_pull_policy, c.registry
 		FROM containers c
 		JOIN images img ON c.image_id = img.id
 		ORDER BY c.created_at DESC
@@ -269,7 +307,7 @@ func (db *DB) GetAllContainers() (interface{}, error) {
 		var nodeName, containerRuntime sql.NullString
 		err := rows.Scan(&row.ID, &row.Namespace, &row.Pod, &row.Name,
 			&row.Reference, &row.ImageID, &row.Digest, &row.CreatedAt,
-			&nodeName, &containerRuntime)
+			&nodeName, &containerRuntime, &row.ImagePullPolicy, &row.Registry)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan container: %w", err)
 		}
@@ -288,11 +326,13 @@ func (db *DB) GetAllContainers() (interface{}, error) {
 // CleanupStats holds statistics about a cleanup operation
 type CleanupStats struct {
 	ContainersRemoved           int // Number of stale container entries deleted
-	ImagesRemoved               int // Number of container images deleted
+	ImagesSoftDeleted           int // Number of orphaned container images soft-deleted
+	ImagesRemoved               int // Number of container images purged
 	PackagesRemoved             int // Number of package entries deleted
 	VulnerabilitiesRemoved      int // Number of vulnerability entries deleted
 	PackageDetailsRemoved       int // Number of image_package_details entries deleted
 	VulnerabilityDetailsRemoved int // Number of image_vulnerability_details entries deleted
+	SBOMsRemoved                int // Number of shared SBOM documents deleted
 }
 
 // CleanupStaleContainers removes container entries whose (namespace, pod, name) triplet
@@ -363,213 +403,144 @@ func (db *DB) CleanupStaleContainers(activeIDs []containers.ContainerID) (int, e
 	return len(stale), nil
 }
 
-// CleanupOrphanedImages removes images that have no associated containers
-// This also cascades to delete related packages and vulnerabilities
+// orphanedImagesCondition matches images without containers. Ad-hoc scanned
+// images are kept until their retention period ends, images found by the
+// registry crawl until the crawl stops finding them.
+const orphanedImagesCondition = `
+	NOT EXISTS (SELECT 1 FROM containers c WHERE c.image_id = images.id)
+	AND (images.ad_hoc_expires_at IS NULL OR images.ad_hoc_expires_at <= ` + sqlNow + `)
+	AND (images.registry_expires_at IS NULL OR images.registry_expires_at <= ` + sqlNow + `)`
+
+// CleanupOrphanedImages soft-deletes images that have no associated containers.
+// Their packages and vulnerabilities are kept for audits until the image is
+// purged by PurgeDeletedImages; an image that runs again is restored.
+// Ad-hoc scanned and registry crawled images are kept until their retention
+// period ends.
 func (db *DB) CleanupOrphanedImages() (*CleanupStats, error) {
 	done := db.beginWrite("cleanup_orphaned_images")
 	defer done()
-	// Start a transaction
-	tx, err := db.conn.Begin()
+	result, err := db.conn.Exec(`
+		UPDATE images SET deleted_at = ` + sqlNow + `
+		WHERE deleted_at IS NULL AND` + orphanedImagesCondition)
 	if err != nil {
 		exitOnCorruption(err)
-		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+		return nil, fmt.Errorf("failed to soft-delete orphaned images: %w", err)
 	}
-	defer func() { _ = tx.Rollback() }()
 
-	// Count orphaned images before deletion
-	var orphanedCount int
-	err = tx.QueryRow(`
-		SELECT COUNT(*)
-		FROM images img
-		WHERE NOT EXISTS (
-			SELECT 1
-			FROM containers c
-			WHERE c.image_id = img.id
-		)
-	`).Scan(&orphanedCount)
-	if err != nil {
-		return nil, fmt.Errorf("failed to count orphaned images: %w", err)
-	}
-
-	if orphanedCount == 0 {
-		// No cleanup needed
+	deleted, _ := result.RowsAffected()
+	if deleted == 0 {
 		log.Info("cleanup: no orphaned images found")
 		return &CleanupStats{}, nil
 	}
 
-	// Count related data before deletion
-	var packagesCount, vulnerabilitiesCount int
+	log.Info("cleanup complete", "images_soft_deleted", deleted)
+	db.notifyWrite()
+	return &CleanupStats{ImagesSoftDeleted: int(deleted)}, nil
+}
 
-	err = tx.QueryRow(`
-		SELECT COUNT(*)
-		FROM image_packages p
-		WHERE p.image_id IN (
-			SELECT img.id
-			FROM images img
-			WHERE NOT EXISTS (
-				SELECT 1
-				FROM containers c
-				WHERE c.image_id = img.id
-			)
-		)
-	`).Scan(&packagesCount)
-	if err != nil {
-		return nil, fmt.Errorf("failed to count orphaned packages: %w", err)
-	}
+// purgeableImagesQuery selects the images deleted before the cutoff bound as
+// a sqlNowShifted modifier that haven't run again since
+const purgeableImagesQuery = `
+	SELECT images.id FROM images
+	WHERE images.deleted_at IS NOT NULL
+	AND images.deleted_at <= ` + sqlNowShifted + `
+	AND` + orphanedImagesCondition
+
+// purgeCutoff returns the sqlNowShifted modifier for images deleted more than
+// olderThan ago
+func purgeCutoff(olderThan time.Duration) string {
+	return fmt.Sprintf("-%d seconds", int64(max(olderThan, 0).Seconds()))
+}
 
-	err = tx.QueryRow(`
-		SELECT COUNT(*)
-		FROM image_vulnerabilities v
-		WHERE v.image_id IN (
-			SELECT img.id
-			FROM images img
-			WHERE NOT EXISTS (
-				SELECT 1
-				FROM containers c
-				WHERE c.image_id = img.id
-			)
-		)
-	`).Scan(&vulnerabilitiesCount)
-	if err != nil {
-		return nil, fmt.Errorf("failed to count orphaned vulnerabilities: %w", err)
-	}
+// rowQuerier is implemented by *sql.DB and *sql.Tx
+type rowQuerier interface {
+	QueryRow(query string, args ...any) *sql.Row
+}
 
-	// Delete image_vulnerability_details for orphaned images (must be done before vulnerabilities)
-	var vulnerabilityDetailsCount int
-	err = tx.QueryRow(`
-		SELECT COUNT(*)
-		FROM image_vulnerability_details vd
-		WHERE vd.vulnerability_id IN (
-			SELECT v.id
-			FROM image_vulnerabilities v
-			WHERE v.image_id IN (
-				SELECT img.id
-				FROM images img
-				WHERE NOT EXISTS (
-					SELECT 1
-					FROM containers c
-					WHERE c.image_id = img.id
-				)
-			)
-		)
-	`).Scan(&vulnerabilityDetailsCount)
-	if err != nil {
-		return nil, fmt.Errorf("failed to count orphaned image_vulnerability_details: %w", err)
+// countPurgeable counts the images purgeable at the cutoff and the rows and
+// SBOM documents that go with them
+func countPurgeable(q rowQuerier, cutoff string) (*CleanupStats, error) {
+	stats := &CleanupStats{}
+	counts := []struct {
+		query string
+		count *int
+		name  string
+	}{
+		{`SELECT COUNT(*) FROM (` + purgeableImagesQuery + `)`, &stats.ImagesRemoved, "images"},
+		{`SELECT COUNT(*) FROM image_packages WHERE image_id IN (` + purgeableImagesQuery + `)`, &stats.PackagesRemoved, "packages"},
+		{`SELECT COUNT(*) FROM image_vulnerabilities WHERE image_id IN (` + purgeableImagesQuery + `)`, &stats.VulnerabilitiesRemoved, "vulnerabilities"},
+		{`SELECT COUNT(*) FROM image_package_details WHERE package_id IN (
+			SELECT id FROM image_packages WHERE image_id IN (` + purgeableImagesQuery + `))`, &stats.PackageDetailsRemoved, "image_package_details"},
+		{`SELECT COUNT(*) FROM image_vulnerability_details WHERE vulnerability_id IN (
+			SELECT id FROM image_vulnerabilities WHERE image_id IN (` + purgeableImagesQuery + `))`, &stats.VulnerabilityDetailsRemoved, "image_vulnerability_details"},
+		// Documents referenced by purgeable images only
+		{`SELECT COUNT(*) FROM sbom_blobs WHERE ref_count <= (
+			SELECT COUNT(*) FROM images WHERE images.sbom_hash = sbom_blobs.hash AND images.id IN (` + purgeableImagesQuery + `))`, &stats.SBOMsRemoved, "SBOM documents"},
+	}
+	for _, c := range counts {
+		if err := q.QueryRow(c.query, cutoff).Scan(c.count); err != nil {
+			return nil, fmt.Errorf("failed to count deleted %s: %w", c.name, err)
+		}
 	}
+	return stats, nil
+}
 
-	_, err = tx.Exec(`
-		DELETE FROM image_vulnerability_details
-		WHERE vulnerability_id IN (
-			SELECT v.id
-			FROM image_vulnerabilities v
-			WHERE v.image_id IN (
-				SELECT img.id
-				FROM images img
-				WHERE NOT EXISTS (
-					SELECT 1
-					FROM containers c
-					WHERE c.image_id = img.id
-				)
-			)
-		)
-	`)
+// PurgeDeletedImages permanently removes images that were soft-deleted more
+// than olderThan ago, cascading to their packages, vulnerabilities and SBOM
+// documents no other image shares. With olderThan <= 0 all soft-deleted
+// images are purged.
+func (db *DB) PurgeDeletedImages(olderThan time.Duration) (*CleanupStats, error) {
+	done := db.beginWrite("purge_deleted_images")
+	defer done()
+	// Start a transaction
+	tx, err := db.conn.Begin()
 	if err != nil {
 		exitOnCorruption(err)
-		return nil, fmt.Errorf("failed to delete image_vulnerability_details: %w", err)
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
 	}
+	defer func() { _ = tx.Rollback() }()
 
-	// Delete vulnerabilities for orphaned images
-	_, err = tx.Exec(`
-		DELETE FROM image_vulnerabilities
-		WHERE image_id IN (
-			SELECT img.id
-			FROM images img
-			WHERE NOT EXISTS (
-				SELECT 1
-				FROM containers c
-				WHERE c.image_id = img.id
-			)
-		)
-	`)
+	// Count what is purged before deletion
+	cutoff := purgeCutoff(olderThan)
+	stats, err := countPurgeable(tx, cutoff)
 	if err != nil {
-		exitOnCorruption(err)
-		return nil, fmt.Errorf("failed to delete vulnerabilities: %w", err)
+		return nil, err
 	}
-
-	// Delete image_package_details for orphaned images (must be done before packages)
-	var packageDetailsCount int
-	err = tx.QueryRow(`
-		SELECT COUNT(*)
-		FROM image_package_details pd
-		WHERE pd.package_id IN (
-			SELECT p.id
-			FROM image_packages p
-			WHERE p.image_id IN (
-				SELECT img.id
-				FROM images img
-				WHERE NOT EXISTS (
-					SELECT 1
-					FROM containers c
-					WHERE c.image_id = img.id
-				)
-			)
-		)
-	`).Scan(&packageDetailsCount)
-	if err != nil {
-		return nil, fmt.Errorf("failed to count orphaned image_package_details: %w", err)
+	if stats.ImagesRemoved == 0 {
+		// No purge needed
+		log.Info("purge: no deleted images past retention")
+		return &CleanupStats{}, nil
 	}
 
-	_, err = tx.Exec(`
-		DELETE FROM image_package_details
-		WHERE package_id IN (
-			SELECT p.id
-			FROM image_packages p
-			WHERE p.image_id IN (
-				SELECT img.id
-				FROM images img
-				WHERE NOT EXISTS (
-					SELECT 1
-					FROM containers c
-					WHERE c.image_id = img.id
-				)
-			)
-		)
-	`)
-	if err != nil {
-		exitOnCorruption(err)
-		return nil, fmt.Errorf("failed to delete image_package_details: %w", err)
+	// Details must be deleted before the packages and vulnerabilities they belong to
+	deletes := []struct {
+		query string
+		name  string
+	}{
+		{`DELETE FROM image_vulnerability_details WHERE vulnerability_id IN (
+			SELECT id FROM image_vulnerabilities WHERE image_id IN (` + purgeableImagesQuery + `))`, "image_vulnerability_details"},
+		{`DELETE FROM image_vulnerabilities WHERE image_id IN (` + purgeableImagesQuery + `)`, "vulnerabilities"},
+		{`DELETE FROM vex_suppressions WHERE image_id IN (` + purgeableImagesQuery + `)`, "vex_suppressions"},
+		{`DELETE FROM image_package_details WHERE package_id IN (
+			SELECT id FROM image_packages WHERE image_id IN (` + purgeableImagesQuery + `))`, "image_package_details"},
+		{`DELETE FROM image_packages WHERE image_id IN (` + purgeableImagesQuery + `)`, "packages"},
+	}
+	for _, d := range deletes {
+		if _, err := tx.Exec(d.query, cutoff); err != nil {
+			exitOnCorruption(err)
+			return nil, fmt.Errorf("failed to delete %s: %w", d.name, err)
+		}
 	}
 
-	// Delete packages for orphaned images
-	_, err = tx.Exec(`
-		DELETE FROM image_packages
-		WHERE image_id IN (
-			SELECT img.id
-			FROM images img
-			WHERE NOT EXISTS (
-				SELECT 1
-				FROM containers c
-				WHERE c.image_id = img.id
-			)
-		)
-	`)
-	if err != nil {
+	// Release the SBOM documents of the images before the images themselves,
+	// deleting documents no other image shares
+	if err := releaseSBOMBlobs(tx, `SELECT sbom_hash FROM images WHERE sbom_hash IS NOT NULL AND id IN (`+purgeableImagesQuery+`)`, cutoff); err != nil {
 		exitOnCorruption(err)
-		return nil, fmt.Errorf("failed to delete packages: %w", err)
+		return nil, err
 	}
-
-	// Delete orphaned images
-	_, err = tx.Exec(`
-		DELETE FROM images
-		WHERE NOT EXISTS (
-			SELECT 1
-			FROM containers c
-			WHERE c.image_id = images.id
-		)
-	`)
-	if err != nil {
+	if _, err := tx.Exec(`DELETE FROM images WHERE id IN (`+purgeableImagesQuery+`)`, cutoff); err != nil {
 		exitOnCorruption(err)
-		return nil, fmt.Errorf("failed to delete orphaned images: %w", err)
+		return nil, fmt.Errorf("failed to delete images: %w", err)
 	}
 
 	// Commit transaction
@@ -578,23 +549,80 @@ func (db *DB) CleanupOrphanedImages() (*CleanupStats, error) {
 		return nil, fmt.Errorf("failed to commit transaction: %w", err)
 	}
 
-	stats := &CleanupStats{
-		ImagesRemoved:               orphanedCount,
-		PackagesRemoved:             packagesCount,
-		VulnerabilitiesRemoved:      vulnerabilitiesCount,
-		PackageDetailsRemoved:       packageDetailsCount,
-		VulnerabilityDetailsRemoved: vulnerabilityDetailsCount,
-	}
-
-	log.Info("cleanup complete",
+	log.Info("purge complete",
 		"images_removed", stats.ImagesRemoved,
 		"packages_removed", stats.PackagesRemoved,
 		"image_package_details_removed", stats.PackageDetailsRemoved,
 		"vulnerabilities_removed", stats.VulnerabilitiesRemoved,
-		"image_vulnerability_details_removed", stats.VulnerabilityDetailsRemoved)
+		"image_vulnerability_details_removed", stats.VulnerabilityDetailsRemoved,
+		"sbom_documents_removed", stats.SBOMsRemoved)
 
+	db.notifyWrite()
 	// Invalidate and rebuild the container vulnerability metrics cache.
 	go db.rebuildContainerVulnCache()
 
 	return stats, nil
 }
+
+// PurgeCandidate is an image the next purge removes
+type PurgeCandidate struct {
+	Digest    string `json:"digest"`
+	Reference string `json:"reference,omitempty"` // last reference the image ran as
+	DeletedAt string `json:"deleted_at"`          // when its last container went away
+}
+
+// PurgePreview lists what PurgeDeletedImages would remove without removing it
+type PurgePreview struct {
+	Images                      []PurgeCandidate `json:"images"`
+	PackagesRemoved             int              `json:"packages_removed"`
+	VulnerabilitiesRemoved      int              `json:"vulnerabilities_removed"`
+	PackageDetailsRemoved       int              `json:"package_details_removed"`
+	VulnerabilityDetailsRemoved int              `json:"vulnerability_details_removed"`
+	SBOMsRemoved                int              `json:"sbom_documents_removed"`
+}
+
+// PreviewPurgeDeletedImages returns the images and data PurgeDeletedImages
+// would remove with the same olderThan, oldest deletion first
+func (db *DB) PreviewPurgeDeletedImages(olderThan time.Duration) (*PurgePreview, error) {
+	tx, err := db.conn.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	// Read the list and the counts from the same snapshot
+	cutoff := purgeCutoff(olderThan)
+	stats, err := countPurgeable(tx, cutoff)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := tx.Query(`
+		SELECT digest, COALESCE(last_reference, ''), deleted_at FROM images
+		WHERE id IN (`+purgeableImagesQuery+`)
+		ORDER BY deleted_at, digest
+	`, cutoff)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query purgeable images: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	preview := &PurgePreview{
+		Images:                      []PurgeCandidate{},
+		PackagesRemoved:             stats.PackagesRemoved,
+		VulnerabilitiesRemoved:      stats.VulnerabilitiesRemoved,
+		PackageDetailsRemoved:       stats.PackageDetailsRemoved,
+		VulnerabilityDetailsRemoved: stats.VulnerabilityDetailsRemoved,
+		SBOMsRemoved:                stats.SBOMsRemoved,
+	}
+	for rows.Next() {
+		var c PurgeCandidate
+		if err := rows.Scan(&c.Digest, &c.Reference, &c.DeletedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan purgeable image: %w", err)
+		}
+		preview.Images = append(preview.Images, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate purgeable images: %w", err)
+	}
+	return preview, nil
+}
@@ -2,6 +2,7 @@ package database
 
 import (
 	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
@@ -143,6 +144,7 @@ func TestAddContainerWithImageUpdate(t *testing.T) {
 	container.Image.Reference = "nginx:1.21"
 	container.Image.Digest = "sha256:new456"
 
+	before, _ := db.GetLastUpdatedTimestamp("images")
 	isNew, err = db.AddContainer(container)
 	if err != nil {
 		t.Fatalf("Failed to update container: %v", err)
@@ -150,6 +152,9 @@ func TestAddContainerWithImageUpdate(t *testing.T) {
 	if !isNew {
 		t.Error("Expected image update to return true")
 	}
+	if after, _ := db.GetLastUpdatedTimestamp("images"); after == before {
+		t.Error("Expected image update to change the last updated signature")
+	}
 
 	// Verify the container was updated
 	allContainers, err := db.GetAllContainers()
@@ -305,6 +310,43 @@ func TestRemoveContainer(t *testing.T) {
 	}
 }
 
+func TestRemoveNamespaceContainers(t *testing.T) {
+	db, err := New(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("Failed to create database: %v", err)
+	}
+	defer func() { _ = Close(db) }()
+
+	for _, id := range []containers.ContainerID{
+		{Namespace: "team-a", Pod: "web-1", Name: "app"},
+		{Namespace: "team-a", Pod: "web-2", Name: "app"},
+		{Namespace: "team-b", Pod: "api-1", Name: "app"},
+	} {
+		if _, err := db.AddContainer(containers.Container{ID: id, Image: containers.ImageID{Reference: "nginx:1.21", Digest: "sha256:abc123"}}); err != nil {
+			t.Fatalf("Failed to add container: %v", err)
+		}
+	}
+
+	before := db.lastUpdatedSig
+	removed, err := db.RemoveNamespaceContainers("team-a")
+	if err != nil || removed != 2 {
+		t.Fatalf("RemoveNamespaceContainers() = %d, %v, want 2", removed, err)
+	}
+	if db.lastUpdatedSig == before {
+		t.Error("RemoveNamespaceContainers() did not update the last-updated signature")
+	}
+	var remaining int
+	if err := db.conn.QueryRow(`SELECT COUNT(*) FROM containers`).Scan(&remaining); err != nil || remaining != 1 {
+		t.Errorf("remaining containers = %d, %v, want 1", remaining, err)
+	}
+
+	// Removing it again is a no-op
+	before = db.lastUpdatedSig
+	if removed, err := db.RemoveNamespaceContainers("team-a"); err != nil || removed != 0 || db.lastUpdatedSig != before {
+		t.Errorf("second RemoveNamespaceContainers() = %d, %v", removed, err)
+	}
+}
+
 func TestSetContainers(t *testing.T) {
 	dbPath := "/tmp/test_containers_set_" + time.Now().Format("20060102150405") + ".db"
 	defer func() { _ = os.Remove(dbPath) }()
@@ -461,3 +503,61 @@ func TestSetContainersValidation(t *testing.T) {
 		t.Errorf("Expected 0 containers after failed SetContainers, got %d", len(containerRows))
 	}
 }
+
+func TestContainerProvenance(t *testing.T) {
+	db, err := New(filepath.Join(t.TempDir(), "provenance.db"))
+	if err != nil {
+		t.Fatalf("Failed to create database: %v", err)
+	}
+	defer func() { _ = Close(db) }()
+
+	_, err = db.AddContainer(containers.Container{
+		ID:              containers.ContainerID{Namespace: "default", Pod: "web", Name: "nginx"},
+		Image:           containers.ImageID{Reference: "nginx:1.25", Digest: "sha256:web"},
+		NodeName:        "worker-1",
+		ImagePullPolicy: "Always",
+	})
+	if err != nil {
+		t.Fatalf("Failed to add container: %v", err)
+	}
+	_, err = db.SetContainers([]containers.Container{
+		{
+			ID:              containers.ContainerID{Namespace: "default", Pod: "web", Name: "nginx"},
+			Image:           containers.ImageID{Reference: "nginx:1.25", Digest: "sha256:web"},
+			NodeName:        "worker-1",
+			ImagePullPolicy: "Always",
+		},
+		{
+			ID:              containers.ContainerID{Namespace: "team", Pod: "app", Name: "app"},
+			Image:           containers.ImageID{Reference: "ghcr.io/org/app:1.0", Digest: "sha256:app"},
+			NodeName:        "worker-2",
+			ImagePullPolicy: "IfNotPresent",
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to set containers: %v", err)
+	}
+
+	all, err := db.GetAllContainers()
+	if err != nil {
+		t.Fatalf("Failed to get all containers: %v", err)
+	}
+	got := make(map[string]ContainerRow)
+	for _, row := range all.([]ContainerRow) {
+		got[row.Pod] = row
+	}
+	if row := got["web"]; row.Registry != "docker.io" || row.ImagePullPolicy != "Always" || row.NodeName != "worker-1" {
+		t.Errorf("Unexpected provenance for web: %+v", row)
+	}
+	if row := got["app"]; row.Registry != "ghcr.io" || row.ImagePullPolicy != "IfNotPresent" || row.NodeName != "worker-2" {
+		t.Errorf("Unexpected provenance for app: %+v", row)
+	}
+
+	opts, err := db.GetFilterOptions()
+	if err != nil {
+		t.Fatalf("Failed to get filter options: %v", err)
+	}
+	if len(opts.Registries) != 2 || opts.Registries[0] != "docker.io" || opts.Registries[1] != "ghcr.io" {
+		t.Errorf("Expected registries [docker.io ghcr.io], got %v", opts.Registries)
+	}
+}
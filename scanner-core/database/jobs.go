@@ -24,7 +24,7 @@ func (db *DB) RecordJobStart(jobName string) (int64, error) {
 	result, err := db.conn.Exec(`
 		INSERT INTO job_executions (job_name, started_at, status)
 		VALUES (?, ?, 'running')
-	`, jobName, time.Now().UTC())
+	`, jobName, formatTimestamp(time.Now()))
 	if err != nil {
 		exitOnCorruption(err)
 		return 0, fmt.Errorf("failed to record job start: %w", err)
@@ -42,7 +42,7 @@ func (db *DB) RecordJobStart(jobName string) (int64, error) {
 func (db *DB) RecordJobSuccess(executionID int64) error {
 	done := db.beginWrite("record_job_success")
 	defer done()
-	completedAt := time.Now().UTC()
+	completedAt := formatTimestamp(time.Now())
 
 	_, err := db.conn.Exec(`
 		UPDATE job_executions
@@ -63,7 +63,7 @@ func (db *DB) RecordJobSuccess(executionID int64) error {
 func (db *DB) RecordJobFailure(executionID int64, errorMsg string) error {
 	done := db.beginWrite("record_job_failure")
 	defer done()
-	completedAt := time.Now().UTC()
+	completedAt := formatTimestamp(time.Now())
 
 	_, err := db.conn.Exec(`
 		UPDATE job_executions
@@ -180,8 +180,8 @@ func (db *DB) CleanupOldJobExecutions(daysToKeep int) (int64, error) {
 	defer done()
 	result, err := db.conn.Exec(`
 		DELETE FROM job_executions
-		WHERE started_at < datetime('now', '-' || ? || ' days')
-	`, daysToKeep)
+		WHERE started_at < ` + sqlNowShifted + `
+	`, fmt.Sprintf("-%d days", daysToKeep))
 	if err != nil {
 		exitOnCorruption(err)
 		return 0, fmt.Errorf("failed to cleanup old job executions: %w", err)
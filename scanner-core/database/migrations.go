@@ -1,12 +1,22 @@
 package database
 
 import (
+	"context"
 	"database/sql"
 	"encoding/json"
 	"fmt"
+
+	"github.com/bvboe/b2s-go/scanner-core/containers"
 )
 
-const currentSchemaVersion = 50
+const currentSchemaVersion = 76
+
+// minCompatibleSchemaVersion is the oldest schema version whose binaries can
+// still run against the current schema after a downgrade: the migrations
+// since only add tables and nullable columns. Raise it to the version of
+// every migration that older binaries cannot run against (renames, dropped
+// columns, rewritten data).
+const minCompatibleSchemaVersion = 75
 
 type migration struct {
 	version int
@@ -265,6 +275,136 @@ var migrations = []migration{
 		name:    "node_cve_listing_indexes",
 		up:      migrateToV50,
 	},
+	{
+		version: 51,
+		name:    "add_vulnerability_aliases",
+		up:      migrateToV51,
+	},
+	{
+		version: 52,
+		name:    "add_observed_images",
+		up:      migrateToV52,
+	},
+	{
+		version: 53,
+		name:    "add_online_migrations",
+		up:      migrateToV53,
+	},
+	{
+		version: 54,
+		name:    "add_container_provenance",
+		up:      migrateToV54,
+	},
+	{
+		version: 55,
+		name:    "add_source_severity",
+		up:      migrateToV55,
+	},
+	{
+		version: 56,
+		name:    "add_ad_hoc_images",
+		up:      migrateToV56,
+	},
+	{
+		version: 57,
+		name:    "add_scan_dead_letter",
+		up:      migrateToV57,
+	},
+	{
+		version: 58,
+		name:    "add_image_soft_delete",
+		up:      migrateToV58,
+	},
+	{
+		version: 59,
+		name:    "add_vulnerability_occurrences",
+		up:      migrateToV59,
+	},
+	{
+		version: 60,
+		name:    "add_image_status_changed_at",
+		up:      migrateToV60,
+	},
+	{
+		version: 61,
+		name:    "add_image_provenance",
+		up:      migrateToV61,
+	},
+	{
+		version: 62,
+		name:    "add_image_size_and_scan_durations",
+		up:      migrateToV62,
+	},
+	{
+		version: 63,
+		name:    "add_scan_history",
+		up:      migrateToV63,
+	},
+	{
+		version: 64,
+		name:    "add_registry_targets",
+		up:      migrateToV64,
+	},
+	{
+		version: 65,
+		name:    "add_scan_queue",
+		up:      migrateToV65,
+	},
+	{
+		version: 66,
+		name:    "add_cve_annotations",
+		up:      migrateToV66,
+	},
+	{
+		version: 67,
+		name:    "add_scan_retries",
+		up:      migrateToV67,
+	},
+	{
+		version: 68,
+		name:    "add_oci_labels",
+		up:      migrateToV68,
+	},
+	{
+		version: 69,
+		name:    "normalize_timestamps",
+		up:      migrateToV69,
+	},
+	{
+		version: 70,
+		name:    "add_vex",
+		up:      migrateToV70,
+	},
+	{
+		version: 71,
+		name:    "add_vulnerability_ignores",
+		up:      migrateToV71,
+	},
+	{
+		version: 72,
+		name:    "add_sbom_source",
+		up:      migrateToV72,
+	},
+	{
+		version: 73,
+		name:    "add_binary_versions",
+		up:      migrateToV73,
+	},
+	{
+		version: 74,
+		name:    "compress_legacy_blobs",
+		up:      migrateToV74,
+	},
+	{
+		version: 75,
+		name:    "add_sbom_blobs",
+		up:      migrateToV75,
+	},
+	{
+		version: 76,
+		name:    "add_cmdb_metadata",
+		up:      migrateToV76,
+	},
 }
 
 // ensureSchemaVersion checks the current schema version and applies necessary migrations
@@ -274,7 +414,7 @@ func (db *DB) ensureSchemaVersion() error {
 		CREATE TABLE IF NOT EXISTS schema_migrations (
 			version INTEGER PRIMARY KEY,
 			name TEXT NOT NULL,
-			applied_at DATETIME DEFAULT CURRENT_TIMESTAMP
+			applied_at DATETIME DEFAULT (` + sqlNow + `)
 		)
 	`)
 	if err != nil {
@@ -2924,3 +3064,937 @@ func migrateToV50(conn *sql.DB) error {
 	log.Info("migration v50: node CVE listing indexes created")
 	return nil
 }
+
+// migrateToV51 adds the vulnerability_aliases table mapping vendor advisory
+// identifiers (RHSA, ALAS, ...) reported by Grype to their related CVEs, taken
+// from the relatedVulnerabilities of each match. Mappings are not tied to an
+// image or node; they are populated as images and nodes are (re)scanned.
+func migrateToV51(conn *sql.DB) error {
+	log.Info("migration v51: adding vulnerability_aliases table")
+	_, err := conn.Exec(`
+		CREATE TABLE IF NOT EXISTS vulnerability_aliases (
+			vulnerability_id TEXT NOT NULL,
+			alias_id TEXT NOT NULL,
+			PRIMARY KEY (vulnerability_id, alias_id)
+		);
+		CREATE INDEX IF NOT EXISTS idx_vulnerability_aliases_alias
+			ON vulnerability_aliases(alias_id);
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create vulnerability_aliases table: %w", err)
+	}
+	log.Info("migration v51: vulnerability_aliases table created")
+	return nil
+}
+
+// migrateToV52 adds the observed_images table, recording image digests seen in
+// short-lived workloads (completed Kubernetes Jobs) that are no longer in the
+// containers table. Together with running containers it forms the set of
+// digests the scan coverage percentage is computed over.
+func migrateToV52(conn *sql.DB) error {
+	log.Info("migration v52: adding observed_images table")
+	_, err := conn.Exec(`
+		CREATE TABLE IF NOT EXISTS observed_images (
+			digest TEXT PRIMARY KEY,
+			reference TEXT NOT NULL DEFAULT '',
+			namespace TEXT NOT NULL DEFAULT '',
+			workload TEXT NOT NULL DEFAULT '',
+			first_seen_at DATETIME NOT NULL,
+			last_seen_at DATETIME NOT NULL
+		);
+		CREATE INDEX IF NOT EXISTS idx_observed_images_last_seen
+			ON observed_images(last_seen_at);
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create observed_images table: %w", err)
+	}
+	log.Info("migration v52: observed_images table created")
+	return nil
+}
+
+// migrateToV53 adds the online_migrations table tracking background table
+// rewrites (see online_migrations.go). Progress is persisted per chunk so an
+// interrupted rewrite resumes where it left off after a restart.
+func migrateToV53(conn *sql.DB) error {
+	log.Info("migration v53: adding online_migrations table")
+	_, err := conn.Exec(`
+		CREATE TABLE IF NOT EXISTS online_migrations (
+			name TEXT PRIMARY KEY,
+			table_name TEXT NOT NULL,
+			state TEXT NOT NULL,
+			last_rowid INTEGER NOT NULL DEFAULT 0,
+			rows_copied INTEGER NOT NULL DEFAULT 0,
+			total_rows INTEGER NOT NULL DEFAULT 0,
+			error TEXT NOT NULL DEFAULT '',
+			started_at DATETIME NOT NULL,
+			updated_at DATETIME NOT NULL,
+			completed_at DATETIME
+		)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create online_migrations table: %w", err)
+	}
+	log.Info("migration v53: online_migrations table created")
+	return nil
+}
+
+// migrateToV54 records image provenance per container: the imagePullPolicy and
+// the registry host the image is pulled from (derived from the reference, so
+// existing rows are backfilled). The pulling node is the existing node_name.
+func migrateToV54(conn *sql.DB) error {
+	log.Info("migration v54: adding container provenance columns")
+	stmts := []string{
+		`ALTER TABLE containers ADD COLUMN image_pull_policy TEXT NOT NULL DEFAULT ''`,
+		`ALTER TABLE containers ADD COLUMN registry TEXT NOT NULL DEFAULT ''`,
+		`CREATE INDEX IF NOT EXISTS idx_containers_registry ON containers(registry)`,
+	}
+	for _, s := range stmts {
+		if _, err := conn.Exec(s); err != nil {
+			return fmt.Errorf("migration v54: %w", err)
+		}
+	}
+
+	rows, err := conn.Query(`SELECT DISTINCT reference FROM containers`)
+	if err != nil {
+		return fmt.Errorf("failed to query container references: %w", err)
+	}
+	var references []string
+	for rows.Next() {
+		var ref string
+		if err := rows.Scan(&ref); err != nil {
+			_ = rows.Close()
+			return fmt.Errorf("failed to scan container reference: %w", err)
+		}
+		references = append(references, ref)
+	}
+	if err := rows.Close(); err != nil {
+		return fmt.Errorf("failed to close container references: %w", err)
+	}
+
+	for _, ref := range references {
+		if _, err := conn.Exec(`UPDATE containers SET registry = ? WHERE reference = ?`,
+			containers.RegistryHost(ref), ref); err != nil {
+			return fmt.Errorf("failed to backfill container registry: %w", err)
+		}
+	}
+	log.Info("migration v54: container provenance columns added", "references", len(references))
+	return nil
+}
+
+// migrateToV55 adds source_severity to image and node vulnerabilities: the
+// severity Grype reported when a severity mapping changed the stored one
+// (NULL means severity is unmapped), so a changed mapping can be re-applied
+func migrateToV55(conn *sql.DB) error {
+	log.Info("migration v55: adding source severity columns")
+	stmts := []string{
+		`ALTER TABLE image_vulnerabilities ADD COLUMN source_severity TEXT`,
+		`ALTER TABLE node_vulnerabilities ADD COLUMN source_severity TEXT`,
+	}
+	for _, s := range stmts {
+		if _, err := conn.Exec(s); err != nil {
+			return fmt.Errorf("migration v55: %w", err)
+		}
+	}
+	log.Info("migration v55: source severity columns added")
+	return nil
+}
+
+// migrateToV56 flags images scanned on demand (POST /api/scan) rather than
+// discovered running in the cluster. ad_hoc_expires_at keeps them from being
+// removed as orphans until their retention period ends.
+func migrateToV56(conn *sql.DB) error {
+	log.Info("migration v56: adding ad-hoc image columns")
+	stmts := []string{
+		`ALTER TABLE images ADD COLUMN ad_hoc INTEGER NOT NULL DEFAULT 0`,
+		`ALTER TABLE images ADD COLUMN ad_hoc_reference TEXT`,
+		`ALTER TABLE images ADD COLUMN ad_hoc_requested_at DATETIME`,
+		`ALTER TABLE images ADD COLUMN ad_hoc_expires_at DATETIME`,
+	}
+	for _, s := range stmts {
+		if _, err := conn.Exec(s); err != nil {
+			return fmt.Errorf("migration v56: %w", err)
+		}
+	}
+	log.Info("migration v56: ad-hoc image columns added")
+	return nil
+}
+
+// migrateToV57 tracks consecutive failed scans of an image. scan_failure_log
+// holds the error chain as JSON; dead_lettered_at is set once the image has
+// failed too often to be retried automatically.
+func migrateToV57(conn *sql.DB) error {
+	log.Info("migration v57: adding scan dead-letter columns")
+	stmts := []string{
+		`ALTER TABLE images ADD COLUMN scan_failures INTEGER NOT NULL DEFAULT 0`,
+		`ALTER TABLE images ADD COLUMN scan_failure_log TEXT`,
+		`ALTER TABLE images ADD COLUMN dead_lettered_at DATETIME`,
+	}
+	for _, s := range stmts {
+		if _, err := conn.Exec(s); err != nil {
+			return fmt.Errorf("migration v57: %w", err)
+		}
+	}
+	log.Info("migration v57: scan dead-letter columns added")
+	return nil
+}
+
+// migrateToV58 soft-deletes images removed by the cleanup job: deleted_at is
+// set instead of deleting the row, and the image is purged once it has been
+// deleted for longer than the retention window. last_reference keeps the
+// reference the image last ran as, since deleted images have no containers
+// (backfilled from the current containers).
+func migrateToV58(conn *sql.DB) error {
+	log.Info("migration v58: adding image soft-delete columns")
+	stmts := []string{
+		`ALTER TABLE images ADD COLUMN deleted_at DATETIME`,
+		`ALTER TABLE images ADD COLUMN last_reference TEXT`,
+		`CREATE INDEX IF NOT EXISTS idx_images_deleted_at ON images(deleted_at)`,
+		`UPDATE images SET last_reference = (
+			SELECT MAX(reference) FROM containers WHERE containers.image_id = images.id
+		)`,
+	}
+	for _, s := range stmts {
+		if _, err := conn.Exec(s); err != nil {
+			return fmt.Errorf("migration v58: %w", err)
+		}
+	}
+	log.Info("migration v58: image soft-delete columns added")
+	return nil
+}
+
+// migrateToV59 merges matches of the same vulnerable package found at several
+// paths into one finding. count becomes 1 per finding, so the SUM(count) used by
+// every summary counts findings rather than match locations, and the former
+// count is kept as occurrences. Stored occurrences count matches until the
+// image or node is next scanned, when they are recomputed as distinct paths.
+func migrateToV59(conn *sql.DB) error {
+	log.Info("migration v59: merging vulnerability matches by package path")
+	stmts := []string{
+		`ALTER TABLE image_vulnerabilities ADD COLUMN occurrences INTEGER NOT NULL DEFAULT 1`,
+		`UPDATE image_vulnerabilities SET occurrences = MAX(COALESCE(count, 1), 1), count = 1`,
+		`ALTER TABLE node_vulnerabilities ADD COLUMN occurrences INTEGER NOT NULL DEFAULT 1`,
+		`UPDATE node_vulnerabilities SET occurrences = MAX(COALESCE(count, 1), 1), count = 1`,
+	}
+	for _, s := range stmts {
+		if _, err := conn.Exec(s); err != nil {
+			return fmt.Errorf("migration v59: %w", err)
+		}
+	}
+	log.Info("migration v59: vulnerability occurrences added")
+	return nil
+}
+
+// migrateToV60 records when the status of an image last changed. updated_at is
+// bumped by unrelated writes (e.g. a new reference), so it cannot tell how long
+// an image has been sitting in generating_sbom. Existing images are backfilled
+// with updated_at, the best estimate available.
+func migrateToV60(conn *sql.DB) error {
+	log.Info("migration v60: adding image status transition timestamp")
+	stmts := []string{
+		`ALTER TABLE images ADD COLUMN status_changed_at DATETIME`,
+		`UPDATE images SET status_changed_at = updated_at`,
+	}
+	for _, s := range stmts {
+		if _, err := conn.Exec(s); err != nil {
+			return fmt.Errorf("migration v60: %w", err)
+		}
+	}
+	log.Info("migration v60: image status transition timestamp added")
+	return nil
+}
+
+// migrateToV61 records the SLSA build provenance of images read from their
+// registry. provenance_checked_at is NULL until the image has been checked;
+// a checked image without provenance has level 0, and a failed check keeps the
+// level NULL and records the error so it is retried later.
+func migrateToV61(conn *sql.DB) error {
+	log.Info("migration v61: adding image provenance columns")
+	stmts := []string{
+		`ALTER TABLE images ADD COLUMN provenance_builder TEXT`,
+		`ALTER TABLE images ADD COLUMN provenance_slsa_level INTEGER`,
+		`ALTER TABLE images ADD COLUMN provenance_source TEXT`,
+		`ALTER TABLE images ADD COLUMN provenance_error TEXT`,
+		`ALTER TABLE images ADD COLUMN provenance_checked_at DATETIME`,
+	}
+	for _, s := range stmts {
+		if _, err := conn.Exec(s); err != nil {
+			return fmt.Errorf("migration v61: %w", err)
+		}
+	}
+	log.Info("migration v61: image provenance columns added")
+	return nil
+}
+
+// migrateToV62 records the size and layer count of images, read from the
+// image metadata in their SBOM, and how long their last SBOM generation and
+// vulnerability scan took. Sizes are populated from the SBOMs already stored;
+// durations are only known for scans after the upgrade.
+func migrateToV62(conn *sql.DB) error {
+	log.Info("migration v62: adding image size and scan duration columns")
+	stmts := []string{
+		`ALTER TABLE images ADD COLUMN image_size INTEGER`,
+		`ALTER TABLE images ADD COLUMN layer_count INTEGER`,
+		`ALTER TABLE images ADD COLUMN sbom_duration_ms INTEGER`,
+		`ALTER TABLE images ADD COLUMN vuln_scan_duration_ms INTEGER`,
+	}
+	for _, s := range stmts {
+		if _, err := conn.Exec(s); err != nil {
+			return fmt.Errorf("migration v62: %w", err)
+		}
+	}
+
+	// Collect the IDs first and read one SBOM at a time, so the blobs of all
+	// images are never held in memory together
+	rows, err := conn.Query(`
+		SELECT id FROM images
+		WHERE (sbom_compressed IS NOT NULL AND LENGTH(sbom_compressed) > 0) OR (sbom IS NOT NULL AND sbom != '')
+	`)
+	if err != nil {
+		return fmt.Errorf("migration v62: failed to query images: %w", err)
+	}
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			_ = rows.Close()
+			return fmt.Errorf("migration v62: failed to scan image ID: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	_ = rows.Close()
+
+	updated := 0
+	for _, id := range ids {
+		var compressed []byte
+		var raw sql.NullString
+		if err := conn.QueryRow(`SELECT sbom_compressed, sbom FROM images WHERE id = ?`, id).Scan(&compressed, &raw); err != nil {
+			log.Warn("migration v62: failed to read SBOM", "image_id", id, "error", err)
+			continue
+		}
+		sbomJSON := []byte(raw.String)
+		if len(compressed) > 0 {
+			if sbomJSON, err = decompressGzip(compressed); err != nil {
+				log.Warn("migration v62: failed to decompress SBOM", "image_id", id, "error", err)
+				continue
+			}
+		}
+
+		var doc struct {
+			Source SyftSource `json:"source"`
+		}
+		if err := json.Unmarshal(sbomJSON, &doc); err != nil {
+			log.Warn("migration v62: failed to parse SBOM", "image_id", id, "error", err)
+			continue
+		}
+		size, layers, ok := doc.Source.Metadata.sizeAndLayers()
+		if !ok {
+			continue
+		}
+		if _, err := conn.Exec(`UPDATE images SET image_size = ?, layer_count = ? WHERE id = ?`, size, layers, id); err != nil {
+			log.Warn("migration v62: failed to update image size", "image_id", id, "error", err)
+			continue
+		}
+		updated++
+	}
+
+	log.Info("migration v62: image size and scan duration columns added", "images_updated", updated)
+	return nil
+}
+
+// migrateToV63 adds the scan_history table, a snapshot of the severity counts
+// and total risk of an image at each scan. Rows are keyed by digest rather than
+// image ID so history outlives purged images, and each existing scan result is
+// backfilled as the first snapshot of its image.
+func migrateToV63(conn *sql.DB) error {
+	log.Info("migration v63: adding scan_history table")
+	_, err := conn.Exec(`
+		CREATE TABLE IF NOT EXISTS scan_history (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			digest TEXT NOT NULL,
+			namespaces TEXT NOT NULL DEFAULT '',
+			running INTEGER NOT NULL DEFAULT 0,
+			scanned_at DATETIME NOT NULL,
+			grype_db_built DATETIME,
+			critical INTEGER NOT NULL DEFAULT 0,
+			high INTEGER NOT NULL DEFAULT 0,
+			medium INTEGER NOT NULL DEFAULT 0,
+			low INTEGER NOT NULL DEFAULT 0,
+			negligible INTEGER NOT NULL DEFAULT 0,
+			unknown INTEGER NOT NULL DEFAULT 0,
+			total INTEGER NOT NULL DEFAULT 0,
+			known_exploited INTEGER NOT NULL DEFAULT 0,
+			risk_score REAL NOT NULL DEFAULT 0
+		);
+		CREATE INDEX IF NOT EXISTS idx_scan_history_digest
+			ON scan_history(digest, scanned_at);
+		CREATE INDEX IF NOT EXISTS idx_scan_history_scanned_at
+			ON scan_history(scanned_at);
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create scan_history table: %w", err)
+	}
+
+	backfilled, err := backfillScanHistory(conn)
+	if err != nil {
+		return fmt.Errorf("migration v63: failed to backfill scan history: %w", err)
+	}
+	log.Info("migration v63: scan_history table created", "snapshots_backfilled", backfilled)
+	return nil
+}
+
+// migrateToV64 adds the target type of images: "container" for images
+// discovered running (or requested ad-hoc) and "registry" for images first
+// found by the registry crawl. registry_expires_at keeps crawled images from
+// being removed as orphans while the crawl still finds them.
+func migrateToV64(conn *sql.DB) error {
+	log.Info("migration v64: adding registry target columns")
+	stmts := []string{
+		`ALTER TABLE images ADD COLUMN target_type TEXT NOT NULL DEFAULT 'container'`,
+		`ALTER TABLE images ADD COLUMN registry_reference TEXT`,
+		`ALTER TABLE images ADD COLUMN registry_crawled_at DATETIME`,
+		`ALTER TABLE images ADD COLUMN registry_expires_at DATETIME`,
+		`CREATE INDEX IF NOT EXISTS idx_images_target_type ON images(target_type)`,
+	}
+	for _, s := range stmts {
+		if _, err := conn.Exec(s); err != nil {
+			return fmt.Errorf("migration v64: %w", err)
+		}
+	}
+	log.Info("migration v64: registry target columns added")
+	return nil
+}
+
+// migrateToV65 adds the scan_queue table, which persists queued scans so the
+// scan queue picks up pending and interrupted scans after a restart
+func migrateToV65(conn *sql.DB) error {
+	log.Info("migration v65: adding scan_queue table")
+	_, err := conn.Exec(`
+		CREATE TABLE IF NOT EXISTS scan_queue (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			kind TEXT NOT NULL,
+			digest TEXT NOT NULL DEFAULT '',
+			reference TEXT NOT NULL DEFAULT '',
+			node_name TEXT NOT NULL DEFAULT '',
+			container_runtime TEXT NOT NULL DEFAULT '',
+			force_scan INTEGER NOT NULL DEFAULT 0,
+			full_rescan INTEGER NOT NULL DEFAULT 0,
+			ad_hoc INTEGER NOT NULL DEFAULT 0,
+			registry INTEGER NOT NULL DEFAULT 0,
+			manual INTEGER NOT NULL DEFAULT 0,
+			priority INTEGER NOT NULL DEFAULT 0,
+			state TEXT NOT NULL DEFAULT 'pending',
+			enqueued_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			started_at DATETIME,
+			finished_at DATETIME
+		);
+		CREATE INDEX IF NOT EXISTS idx_scan_queue_state ON scan_queue(state);
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create scan_queue table: %w", err)
+	}
+	log.Info("migration v65: scan_queue table created")
+	return nil
+}
+
+// migrateToV66 adds the cve_annotations table: cluster-wide analyst notes on
+// vulnerabilities, keyed by vulnerability ID rather than by finding so a note
+// applies to every image the vulnerability appears in
+func migrateToV66(conn *sql.DB) error {
+	log.Info("migration v66: adding cve_annotations table")
+	_, err := conn.Exec(`
+		CREATE TABLE IF NOT EXISTS cve_annotations (
+			cve_id TEXT PRIMARY KEY COLLATE NOCASE,
+			note TEXT NOT NULL,
+			author TEXT NOT NULL DEFAULT '',
+			created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+		)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create cve_annotations table: %w", err)
+	}
+	log.Info("migration v66: cve_annotations table created")
+	return nil
+}
+
+// migrateToV67 adds the scan_failed_permanent status of dead-lettered images
+// and the time a queued automatic retry may run
+func migrateToV67(conn *sql.DB) error {
+	log.Info("migration v67: adding scan retries")
+	tx, err := conn.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if _, err := tx.Exec(`
+		INSERT OR IGNORE INTO scan_status (status, description, sort_order) VALUES
+			('scan_failed_permanent', 'Scan failed permanently', 7)
+	`); err != nil {
+		return fmt.Errorf("failed to add scan_failed_permanent status: %w", err)
+	}
+	if _, err := tx.Exec(`ALTER TABLE scan_queue ADD COLUMN retry_at TEXT NOT NULL DEFAULT ''`); err != nil {
+		return fmt.Errorf("failed to add retry_at column: %w", err)
+	}
+	result, err := tx.Exec(`
+		UPDATE images SET status = 'scan_failed_permanent'
+		WHERE dead_lettered_at IS NOT NULL
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to mark dead-lettered images: %w", err)
+	}
+	marked, _ := result.RowsAffected()
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	log.Info("migration v67: scan retries added", "dead_lettered_images", marked)
+	return nil
+}
+
+// migrateToV68 records the source repository, version and vendor images
+// declare in their OCI labels, read from the stored SBOMs like v62
+func migrateToV68(conn *sql.DB) error {
+	log.Info("migration v68: adding OCI label columns")
+	stmts := []string{
+		`ALTER TABLE images ADD COLUMN oci_source TEXT`,
+		`ALTER TABLE images ADD COLUMN oci_version TEXT`,
+		`ALTER TABLE images ADD COLUMN oci_vendor TEXT`,
+		`CREATE INDEX IF NOT EXISTS idx_images_oci_source ON images(oci_source)`,
+	}
+	for _, s := range stmts {
+		if _, err := conn.Exec(s); err != nil {
+			return fmt.Errorf("migration v68: %w", err)
+		}
+	}
+
+	rows, err := conn.Query(`
+		SELECT id FROM images
+		WHERE (sbom_compressed IS NOT NULL AND LENGTH(sbom_compressed) > 0) OR (sbom IS NOT NULL AND sbom != '')
+	`)
+	if err != nil {
+		return fmt.Errorf("migration v68: failed to query images: %w", err)
+	}
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			_ = rows.Close()
+			return fmt.Errorf("migration v68: failed to scan image ID: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	_ = rows.Close()
+
+	updated := 0
+	for _, id := range ids {
+		var compressed []byte
+		var raw sql.NullString
+		if err := conn.QueryRow(`SELECT sbom_compressed, sbom FROM images WHERE id = ?`, id).Scan(&compressed, &raw); err != nil {
+			log.Warn("migration v68: failed to read SBOM", "image_id", id, "error", err)
+			continue
+		}
+		sbomJSON := []byte(raw.String)
+		if len(compressed) > 0 {
+			if sbomJSON, err = decompressGzip(compressed); err != nil {
+				log.Warn("migration v68: failed to decompress SBOM", "image_id", id, "error", err)
+				continue
+			}
+		}
+
+		var doc struct {
+			Source SyftSource `json:"source"`
+		}
+		if err := json.Unmarshal(sbomJSON, &doc); err != nil {
+			log.Warn("migration v68: failed to parse SBOM", "image_id", id, "error", err)
+			continue
+		}
+		source, version, vendor := doc.Source.Metadata.ociLabels()
+		if source == "" && version == "" && vendor == "" {
+			continue
+		}
+		if _, err := conn.Exec(`
+			UPDATE images SET oci_source = NULLIF(?, ''), oci_version = NULLIF(?, ''), oci_vendor = NULLIF(?, '')
+			WHERE id = ?
+		`, source, version, vendor, id); err != nil {
+			log.Warn("migration v68: failed to update OCI labels", "image_id", id, "error", err)
+			continue
+		}
+		updated++
+	}
+
+	log.Info("migration v68: OCI label columns added", "images_updated", updated)
+	return nil
+}
+
+// migrateToV69 normalizes stored timestamps to RFC3339 in UTC. Column
+// defaults written as CURRENT_TIMESTAMP are rewritten in the schema (SQLite
+// cannot alter a column default) and existing values are converted, so
+// timestamps written before and after the upgrade sort together.
+func migrateToV69(conn *sql.DB) error {
+	log.Info("migration v69: normalizing timestamps to RFC3339 UTC")
+
+	if err := rewriteTimestampDefaults(conn); err != nil {
+		return fmt.Errorf("migration v69: %w", err)
+	}
+
+	// Timestamp columns: declared DATETIME, plus the grype database build
+	// time stored as TEXT
+	rows, err := conn.Query(`
+		SELECT m.name, p.name
+		FROM sqlite_schema m, pragma_table_info(m.name) p
+		WHERE m.type = 'table' AND m.name NOT LIKE 'sqlite_%'
+		  AND (upper(p.type) IN ('DATETIME', 'TIMESTAMP') OR p.name = 'grype_db_built')
+		ORDER BY m.name, p.cid
+	`)
+	if err != nil {
+		return fmt.Errorf("migration v69: failed to list timestamp columns: %w", err)
+	}
+	type column struct{ table, name string }
+	var columns []column
+	for rows.Next() {
+		var c column
+		if err := rows.Scan(&c.table, &c.name); err != nil {
+			_ = rows.Close()
+			return fmt.Errorf("migration v69: failed to scan timestamp column: %w", err)
+		}
+		columns = append(columns, c)
+	}
+	_ = rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("migration v69: failed to list timestamp columns: %w", err)
+	}
+
+	// Values SQLite cannot parse as a time are left as they are
+	converted := int64(0)
+	for _, c := range columns {
+		normalized := fmt.Sprintf(`strftime('%%Y-%%m-%%dT%%H:%%M:%%SZ', "%s")`, c.name)
+		result, err := conn.Exec(fmt.Sprintf(`
+			UPDATE "%[1]s" SET "%[2]s" = %[3]s
+			WHERE typeof("%[2]s") = 'text' AND %[3]s IS NOT NULL AND "%[2]s" != %[3]s
+		`, c.table, c.name, normalized))
+		if err != nil {
+			return fmt.Errorf("migration v69: failed to convert %s.%s: %w", c.table, c.name, err)
+		}
+		n, _ := result.RowsAffected()
+		converted += n
+	}
+
+	log.Info("migration v69: timestamps normalized", "columns", len(columns), "values_converted", converted)
+	return nil
+}
+
+// rewriteTimestampDefaults replaces DEFAULT CURRENT_TIMESTAMP in the table
+// definitions with the RFC3339 equivalent, following SQLite's procedure for
+// schema changes ALTER TABLE does not support. writable_schema is a
+// connection setting, so all statements run on one connection.
+func rewriteTimestampDefaults(db *sql.DB) error {
+	ctx := context.Background()
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get connection: %w", err)
+	}
+	defer func() { _ = conn.Close() }()
+
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	var schemaVersion int64
+	if err := tx.QueryRow(`PRAGMA schema_version`).Scan(&schemaVersion); err != nil {
+		return fmt.Errorf("failed to read schema version: %w", err)
+	}
+	if _, err := tx.Exec(`PRAGMA writable_schema = ON`); err != nil {
+		return fmt.Errorf("failed to enable writable schema: %w", err)
+	}
+	result, err := tx.Exec(`
+		UPDATE sqlite_schema SET sql = replace(sql, 'DEFAULT CURRENT_TIMESTAMP', ?)
+		WHERE type = 'table' AND sql LIKE '%DEFAULT CURRENT_TIMESTAMP%'
+	`, "DEFAULT ("+sqlNow+")")
+	if err != nil {
+		return fmt.Errorf("failed to rewrite column defaults: %w", err)
+	}
+	// Other connections reload the schema once its version changes
+	if _, err := tx.Exec(fmt.Sprintf(`PRAGMA schema_version = %d`, schemaVersion+1)); err != nil {
+		return fmt.Errorf("failed to bump schema version: %w", err)
+	}
+	if _, err := tx.Exec(`PRAGMA writable_schema = OFF`); err != nil {
+		return fmt.Errorf("failed to disable writable schema: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit column defaults: %w", err)
+	}
+
+	var check string
+	if err := conn.QueryRowContext(ctx, `PRAGMA quick_check`).Scan(&check); err != nil {
+		return fmt.Errorf("failed to check schema: %w", err)
+	}
+	if check != "ok" {
+		return fmt.Errorf("schema check failed after rewriting column defaults: %s", check)
+	}
+	tables, _ := result.RowsAffected()
+	log.Info("migration v69: column defaults rewritten", "tables", tables)
+	return nil
+}
+
+// migrateToV70 adds the OpenVEX documents and the audit trail of the findings
+// their not_affected statements suppress. Suppressed findings are kept out of
+// image_vulnerabilities, so every query, summary and metric leaves them out.
+func migrateToV70(conn *sql.DB) error {
+	log.Info("migration v70: adding vex_documents and vex_suppressions tables")
+	_, err := conn.Exec(`
+		CREATE TABLE IF NOT EXISTS vex_documents (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			name TEXT NOT NULL UNIQUE,
+			document_id TEXT NOT NULL DEFAULT '',
+			author TEXT NOT NULL DEFAULT '',
+			statements INTEGER NOT NULL DEFAULT 0,
+			content TEXT NOT NULL,
+			created_at DATETIME NOT NULL DEFAULT (` + sqlNow + `),
+			updated_at DATETIME NOT NULL DEFAULT (` + sqlNow + `)
+		);
+		CREATE TABLE IF NOT EXISTS vex_suppressions (
+			image_id INTEGER NOT NULL,
+			cve_id TEXT NOT NULL,
+			package_name TEXT NOT NULL,
+			package_version TEXT NOT NULL,
+			package_type TEXT NOT NULL,
+			severity TEXT NOT NULL DEFAULT '',
+			status TEXT NOT NULL,
+			justification TEXT NOT NULL DEFAULT '',
+			impact_statement TEXT NOT NULL DEFAULT '',
+			source TEXT NOT NULL,
+			document_id TEXT NOT NULL DEFAULT '',
+			statement_timestamp TEXT NOT NULL DEFAULT '',
+			suppressed_at DATETIME NOT NULL DEFAULT (` + sqlNow + `),
+			PRIMARY KEY (image_id, cve_id, package_name, package_version, package_type)
+		);
+		CREATE INDEX IF NOT EXISTS idx_vex_suppressions_cve ON vex_suppressions(cve_id);
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create VEX tables: %w", err)
+	}
+	log.Info("migration v70: VEX tables created")
+	return nil
+}
+
+// migrateToV71 adds accepted-risk ignores of findings and the audit trail of
+// their changes. Ignored findings are recorded in vex_suppressions with an
+// ignore:<id> source, like the findings VEX statements suppress.
+func migrateToV71(conn *sql.DB) error {
+	log.Info("migration v71: adding vulnerability_ignores and vulnerability_ignore_events tables")
+	_, err := conn.Exec(`
+		CREATE TABLE IF NOT EXISTS vulnerability_ignores (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			cve_id TEXT NOT NULL,
+			package_name TEXT NOT NULL DEFAULT '',
+			image TEXT NOT NULL DEFAULT '',
+			reason TEXT NOT NULL,
+			owner TEXT NOT NULL,
+			expires_at TEXT NOT NULL DEFAULT '',
+			expiry_recorded INTEGER NOT NULL DEFAULT 0,
+			created_at DATETIME NOT NULL DEFAULT (` + sqlNow + `),
+			updated_at DATETIME NOT NULL DEFAULT (` + sqlNow + `)
+		);
+		CREATE TABLE IF NOT EXISTS vulnerability_ignore_events (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			ignore_id INTEGER NOT NULL,
+			action TEXT NOT NULL,
+			cve_id TEXT NOT NULL,
+			package_name TEXT NOT NULL DEFAULT '',
+			image TEXT NOT NULL DEFAULT '',
+			reason TEXT NOT NULL DEFAULT '',
+			owner TEXT NOT NULL DEFAULT '',
+			expires_at TEXT NOT NULL DEFAULT '',
+			actor TEXT NOT NULL DEFAULT '',
+			at DATETIME NOT NULL DEFAULT (` + sqlNow + `)
+		);
+		CREATE INDEX IF NOT EXISTS idx_vulnerability_ignore_events_ignore ON vulnerability_ignore_events(ignore_id);
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create vulnerability ignore tables: %w", err)
+	}
+	log.Info("migration v71: vulnerability ignore tables created")
+	return nil
+}
+
+// migrateToV72 records where each image's SBOM came from: generated by Syft
+// or a vendor's verified attestation. All SBOMs stored so far were generated.
+func migrateToV72(conn *sql.DB) error {
+	log.Info("migration v72: adding SBOM source columns")
+	stmts := []string{
+		`ALTER TABLE images ADD COLUMN sbom_source TEXT`,
+		`ALTER TABLE images ADD COLUMN sbom_signer TEXT`,
+		`UPDATE images SET sbom_source = 'generated' WHERE sbom_scanned_at IS NOT NULL`,
+	}
+	for _, s := range stmts {
+		if _, err := conn.Exec(s); err != nil {
+			return fmt.Errorf("migration v72: %w", err)
+		}
+	}
+	log.Info("migration v72: SBOM source columns added")
+	return nil
+}
+
+// migrateToV73 adds the history of the binary versions that ran against the
+// database, with the schema versions they require, so the compatibility of
+// downgrades can be reported
+func migrateToV73(conn *sql.DB) error {
+	log.Info("migration v73: adding binary_versions table")
+	_, err := conn.Exec(`
+		CREATE TABLE IF NOT EXISTS binary_versions (
+			version TEXT PRIMARY KEY,
+			schema_version INTEGER NOT NULL,
+			min_compatible_schema_version INTEGER NOT NULL,
+			first_started_at DATETIME NOT NULL DEFAULT (` + sqlNow + `),
+			last_started_at DATETIME NOT NULL DEFAULT (` + sqlNow + `)
+		)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create binary_versions table: %w", err)
+	}
+	log.Info("migration v73: binary_versions table created")
+	return nil
+}
+
+// migrateToV74 used to compress the SBOM and vulnerability documents still
+// stored uncompressed by scanners predating v47 at startup. Rewriting every
+// document blocked startup for as long as that took on large databases, so
+// it is now done in the background by the compress_legacy_*_blobs online
+// migrations; readers handle both columns meanwhile. There is no schema change.
+func migrateToV74(conn *sql.DB) error {
+	log.Info("migration v74: legacy documents are compressed by an online migration")
+	return nil
+}
+
+// legacyBlobsWhere selects the images and nodes rows still holding an
+// uncompressed document
+const legacyBlobsWhere = `sbom IS NOT NULL OR vulnerabilities IS NOT NULL`
+
+// legacyBlobsChunkSize is the number of rows whose documents are compressed
+// per transaction, small so the WAL stays small
+const legacyBlobsChunkSize = 20
+
+// compressLegacyBlobs returns the online migration backfill moving the
+// uncompressed documents of a row of table (images or nodes) into the
+// *_compressed columns. Empty documents and those that already have a
+// compressed copy (left by rescans since v47) are dropped. The freed pages
+// are reused by later writes; the file itself only shrinks with a VACUUM.
+func compressLegacyBlobs(table string) func(tx *sql.Tx, rowid int64) error {
+	return func(tx *sql.Tx, rowid int64) error {
+		for _, column := range []string{"sbom", "vulnerabilities"} {
+			var raw sql.NullString
+			var existing []byte
+			if err := tx.QueryRow(fmt.Sprintf(`SELECT %[2]s, %[2]s_compressed FROM %[1]s WHERE rowid = ?`, table, column), rowid).
+				Scan(&raw, &existing); err != nil {
+				return fmt.Errorf("failed to read %s.%s: %w", table, column, err)
+			}
+			if !raw.Valid {
+				continue
+			}
+			if raw.String == "" || len(existing) > 0 {
+				if _, err := tx.Exec(fmt.Sprintf(`UPDATE %s SET %s = NULL WHERE rowid = ?`, table, column), rowid); err != nil {
+					return fmt.Errorf("failed to clear duplicate %s.%s: %w", table, column, err)
+				}
+				continue
+			}
+			data, err := compressGzip([]byte(raw.String))
+			if err != nil {
+				log.Warn("failed to compress legacy document", "table", table, "column", column, "rowid", rowid, "error", err)
+				continue
+			}
+			if _, err := tx.Exec(fmt.Sprintf(`UPDATE %[1]s SET %[2]s_compressed = ?, %[2]s = NULL WHERE rowid = ?`, table, column), data, rowid); err != nil {
+				return fmt.Errorf("failed to store compressed %s.%s: %w", table, column, err)
+			}
+		}
+		return nil
+	}
+}
+
+// migrateToV75 adds sbom_blobs, which stores the SBOM documents of images
+// once per distinct content, keyed by the SHA-256 of the uncompressed JSON,
+// with a count of the images referencing each. Images reference their
+// document by images.sbom_hash. Existing documents are moved out of images by
+// the move_sboms_to_blobs online migration; until then GetSBOM falls back to
+// images.sbom_compressed. Binaries predating v75 would find no SBOMs once
+// they are moved, hence the raised minimum compatible schema version.
+func migrateToV75(conn *sql.DB) error {
+	log.Info("migration v75: adding sbom_blobs table")
+	var hasColumn bool
+	if err := conn.QueryRow(`SELECT COUNT(*) > 0 FROM pragma_table_info('images') WHERE name = 'sbom_hash'`).Scan(&hasColumn); err != nil {
+		return fmt.Errorf("migration v75: failed to read images columns: %w", err)
+	}
+	stmts := []string{
+		`CREATE TABLE IF NOT EXISTS sbom_blobs (
+			hash TEXT PRIMARY KEY,
+			sbom_compressed BLOB NOT NULL,
+			ref_count INTEGER NOT NULL DEFAULT 0,
+			created_at DATETIME NOT NULL DEFAULT (` + sqlNow + `)
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_images_sbom_hash ON images(sbom_hash)`,
+	}
+	if !hasColumn {
+		stmts = append([]string{`ALTER TABLE images ADD COLUMN sbom_hash TEXT`}, stmts...)
+	}
+	for _, stmt := range stmts {
+		if _, err := conn.Exec(stmt); err != nil {
+			return fmt.Errorf("migration v75: %w", err)
+		}
+	}
+	return nil
+}
+
+// unsharedSBOMsWhere selects the images whose SBOM is not yet in sbom_blobs
+const unsharedSBOMsWhere = `sbom_hash IS NULL
+	AND ((sbom_compressed IS NOT NULL AND LENGTH(sbom_compressed) > 0) OR (sbom IS NOT NULL AND sbom != ''))`
+
+// moveSBOMToBlob is the online migration backfill moving the SBOM document
+// of an image into sbom_blobs. Documents that cannot be read are left in place.
+func moveSBOMToBlob(tx *sql.Tx, imageID int64) error {
+	var compressed []byte
+	var raw sql.NullString
+	if err := tx.QueryRow(`SELECT sbom_compressed, sbom FROM images WHERE id = ?`, imageID).Scan(&compressed, &raw); err != nil {
+		return fmt.Errorf("failed to read SBOM: %w", err)
+	}
+	sbomJSON := []byte(raw.String)
+	var err error
+	if len(compressed) > 0 {
+		if sbomJSON, err = decompressGzip(compressed); err != nil {
+			log.Warn("failed to decompress SBOM", "image_id", imageID, "error", err)
+			return nil
+		}
+	} else if compressed, err = compressGzip(sbomJSON); err != nil {
+		log.Warn("failed to compress SBOM", "image_id", imageID, "error", err)
+		return nil
+	}
+	return setImageSBOMBlob(tx, imageID, sbomHash(sbomJSON), compressed)
+}
+
+// migrateToV76 adds the cmdb_metadata table: attributes (application ID,
+// owner, data classification) synced from an external CMDB, keyed by
+// namespace or by an image label and value. Unused key columns are empty
+// rather than NULL so the unique constraint applies.
+func migrateToV76(conn *sql.DB) error {
+	log.Info("migration v76: adding cmdb_metadata table")
+	_, err := conn.Exec(`
+		CREATE TABLE IF NOT EXISTS cmdb_metadata (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			namespace TEXT NOT NULL DEFAULT '',
+			label TEXT NOT NULL DEFAULT '',
+			label_value TEXT NOT NULL DEFAULT '',
+			application_id TEXT NOT NULL DEFAULT '',
+			owner TEXT NOT NULL DEFAULT '',
+			data_classification TEXT NOT NULL DEFAULT '',
+			source TEXT NOT NULL,
+			updated_at DATETIME NOT NULL DEFAULT (` + sqlNow + `),
+			UNIQUE(namespace, label, label_value)
+		)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create cmdb_metadata table: %w", err)
+	}
+	log.Info("migration v76: cmdb_metadata table created")
+	return nil
+}
@@ -66,7 +66,7 @@ func (db *DB) AddNode(n nodes.Node) (bool, error) {
 				architecture = ?,
 				container_runtime = ?,
 				kubelet_version = ?,
-				updated_at = CURRENT_TIMESTAMP
+				updated_at = ` + sqlNow + `
 			WHERE name = ?
 		`, n.Hostname, n.OSRelease, n.KernelVersion, n.Architecture,
 			n.ContainerRuntime, n.KubeletVersion, n.Name)
@@ -113,7 +113,7 @@ func (db *DB) UpdateNode(n nodes.Node) error {
 			architecture = ?,
 			container_runtime = ?,
 			kubelet_version = ?,
-			updated_at = CURRENT_TIMESTAMP
+			updated_at = ` + sqlNow + `
 		WHERE name = ?
 	`, n.Hostname, n.OSRelease, n.KernelVersion, n.Architecture,
 		n.ContainerRuntime, n.KubeletVersion, n.Name)
@@ -486,7 +486,7 @@ func (db *DB) UpdateNodeStatus(name string, status Status, errorMsg string) erro
 		UPDATE nodes SET
 			status = ?,
 			status_error = ?,
-			updated_at = CURRENT_TIMESTAMP
+			updated_at = ` + sqlNow + `
 		WHERE name = ?
 	`, status.String(), errorMsg, name)
 	done()
@@ -677,8 +677,8 @@ func (db *DB) StoreNodeSBOM(name string, sbomJSON []byte) error {
 	if _, err = tx.Exec(`
 		UPDATE nodes SET
 			status = ?,
-			sbom_scanned_at = CURRENT_TIMESTAMP,
-			updated_at = CURRENT_TIMESTAMP
+			sbom_scanned_at = ` + sqlNow + `,
+			updated_at = ` + sqlNow + `
 		WHERE id = ?
 	`, StatusScanningVulnerabilities.String(), nodeID); err != nil {
 		rollback()
@@ -699,7 +699,7 @@ func (db *DB) StoreNodeSBOM(name string, sbomJSON []byte) error {
 	// structured-data lock and limits lock hold time to a single small write).
 	blobStart := time.Now()
 	blobDone := db.beginWrite("store_node_sbom_blob")
-	_, err = db.conn.Exec(`UPDATE nodes SET sbom_compressed = ? WHERE id = ?`, sbomCompressed, nodeID)
+	_, err = db.conn.Exec(`UPDATE nodes SET sbom_compressed = ?, sbom = NULL WHERE id = ?`, sbomCompressed, nodeID)
 	blobDone()
 	if err != nil {
 		exitOnCorruption(err)
@@ -761,11 +761,8 @@ func (db *DB) StoreNodeVulnerabilities(name string, vulnJSON []byte, grypeDBBuil
 				Versions []string `json:"versions"`
 			} `json:"fix"`
 		} `json:"vulnerability"`
-		Artifact struct {
-			Name    string `json:"name"`
-			Version string `json:"version"`
-			Type    string `json:"type"`
-		} `json:"artifact"`
+		Artifact               GrypeArtifact      `json:"artifact"`
+		RelatedVulnerabilities []GrypeRelatedVuln `json:"relatedVulnerabilities"`
 	}
 
 	type vulnKey struct {
@@ -783,8 +780,10 @@ func (db *DB) StoreNodeVulnerabilities(name string, vulnJSON []byte, grypeDBBuil
 		FixVersion     string
 		KnownExploited int
 		Instances      []json.RawMessage
+		Locations      [][]GrypeLocation
 	}
 	vulnGroups := make(map[vulnKey]*vulnData)
+	aliases := make(map[vulnerabilityAlias]struct{})
 
 	for _, matchRaw := range report.Matches {
 		var pm parsedMatch
@@ -799,6 +798,7 @@ func (db *DB) StoreNodeVulnerabilities(name string, vulnJSON []byte, grypeDBBuil
 			PackageType:    pm.Artifact.Type,
 			CVEID:          pm.Vulnerability.ID,
 		}
+		collectVulnerabilityAliases(aliases, pm.Vulnerability.ID, pm.RelatedVulnerabilities)
 
 		epssScore, epssPercentile := 0.0, 0.0
 		if len(pm.Vulnerability.EPSS) > 0 {
@@ -808,6 +808,7 @@ func (db *DB) StoreNodeVulnerabilities(name string, vulnJSON []byte, grypeDBBuil
 
 		if existing, ok := vulnGroups[key]; ok {
 			existing.Instances = append(existing.Instances, matchRaw)
+			existing.Locations = append(existing.Locations, pm.Artifact.Locations)
 			if pm.Vulnerability.Risk > existing.Risk {
 				existing.Risk = pm.Vulnerability.Risk
 			}
@@ -832,6 +833,7 @@ func (db *DB) StoreNodeVulnerabilities(name string, vulnJSON []byte, grypeDBBuil
 				FixVersion:     fixVersion,
 				KnownExploited: len(pm.Vulnerability.KnownExploited),
 				Instances:      []json.RawMessage{matchRaw},
+				Locations:      [][]GrypeLocation{pm.Artifact.Locations},
 			}
 		}
 	}
@@ -872,24 +874,27 @@ func (db *DB) StoreNodeVulnerabilities(name string, vulnJSON []byte, grypeDBBuil
 	}
 	deleteMs := time.Since(t0).Milliseconds()
 
-	// Batch INSERT vulnerabilities (13 cols → 50 rows per batch = 650 params).
+	// Batch INSERT vulnerabilities (15 cols → 40 rows per batch = 600 params).
+	// Matches of the same package at different paths are one finding (count 1)
+	// with the distinct paths as its occurrences.
 	type vulnRowData struct {
 		key  vulnKey
 		data *vulnData
 	}
 	orderedVulns := make([]vulnRowData, 0, len(vulnGroups))
-	vulnRows := make([]any, 0, len(vulnGroups)*13)
+	vulnRows := make([]any, 0, len(vulnGroups)*15)
 	for k, d := range vulnGroups {
 		orderedVulns = append(orderedVulns, vulnRowData{key: k, data: d})
+		severity, sourceSeverity := db.mapSeverity(d.Severity)
 		vulnRows = append(vulnRows,
 			nodeID, k.CVEID, k.PackageName, k.PackageVersion, k.PackageType,
-			d.Severity, d.Risk, d.EPSSScore, d.EPSSPercentile,
-			d.FixStatus, d.FixVersion, d.KnownExploited, len(d.Instances),
+			severity, sourceSeverity, d.Risk, d.EPSSScore, d.EPSSPercentile,
+			d.FixStatus, d.FixVersion, d.KnownExploited, 1, len(mergeOccurrences(d.Locations)),
 		)
 	}
 	if err = batchInsert(tx,
-		`INSERT INTO node_vulnerabilities (node_id, cve_id, package_name, package_version, package_type, severity, risk, epss_score, epss_percentile, fix_status, fix_version, known_exploited, count)`,
-		vulnRows, 13, 50); err != nil {
+		`INSERT INTO node_vulnerabilities (node_id, cve_id, package_name, package_version, package_type, severity, source_severity, risk, epss_score, epss_percentile, fix_status, fix_version, known_exploited, count, occurrences)`,
+		vulnRows, 15, 40); err != nil {
 		rollback()
 		done()
 		exitOnCorruption(err)
@@ -948,17 +953,23 @@ func (db *DB) StoreNodeVulnerabilities(name string, vulnJSON []byte, grypeDBBuil
 		exitOnCorruption(err)
 		return fmt.Errorf("failed to batch insert vulnerability details: %w", err)
 	}
+	if err = insertVulnerabilityAliases(tx, aliases); err != nil {
+		rollback()
+		done()
+		exitOnCorruption(err)
+		return fmt.Errorf("failed to insert vulnerability aliases: %w", err)
+	}
 	insertDetailsMs := time.Since(t0).Milliseconds() - deleteMs - insertVulnsMs
 
 	// Update node status.
 	if _, err = tx.Exec(`
 		UPDATE nodes SET
 			status = ?,
-			vulns_scanned_at = CURRENT_TIMESTAMP,
+			vulns_scanned_at = ` + sqlNow + `,
 			grype_db_built = ?,
-			updated_at = CURRENT_TIMESTAMP
+			updated_at = ` + sqlNow + `
 		WHERE id = ?
-	`, StatusCompleted.String(), grypeDBBuilt.Format(time.RFC3339), nodeID); err != nil {
+	`, StatusCompleted.String(), formatTimestamp(grypeDBBuilt), nodeID); err != nil {
 		rollback()
 		done()
 		exitOnCorruption(err)
@@ -976,7 +987,7 @@ func (db *DB) StoreNodeVulnerabilities(name string, vulnJSON []byte, grypeDBBuil
 	// Step 5: Write compressed blob in a separate write.
 	blobStart := time.Now()
 	blobDone := db.beginWrite("store_node_vulnerabilities_blob")
-	_, err = db.conn.Exec(`UPDATE nodes SET vulnerabilities_compressed = ? WHERE id = ?`, vulnCompressed, nodeID)
+	_, err = db.conn.Exec(`UPDATE nodes SET vulnerabilities_compressed = ?, vulnerabilities = NULL WHERE id = ?`, vulnCompressed, nodeID)
 	blobDone()
 	if err != nil {
 		exitOnCorruption(err)
@@ -1091,7 +1102,8 @@ func (db *DB) GetNodeVulnerabilities(name string) ([]nodes.NodeVulnerability, er
 			COALESCE(nv.epss_percentile, 0) as epss_percentile,
 			nv.fix_status, nv.fix_version, nv.known_exploited, nv.created_at,
 			nv.package_name, nv.package_version, nv.package_type,
-			COALESCE(nv.count, 1) as count
+			COALESCE(nv.count, 1) as count,
+			nv.occurrences
 		FROM node_vulnerabilities nv
 		JOIN nodes n ON nv.node_id = n.id
 		WHERE n.name = ?
@@ -1119,7 +1131,7 @@ func (db *DB) GetNodeVulnerabilities(name string) ([]nodes.NodeVulnerability, er
 			&vuln.ID, &vuln.NodeID, &vuln.CVEID, &vuln.Severity,
 			&vuln.Risk, &vuln.EPSSScore, &vuln.EPSSPercentile,
 			&fixStatus, &fixVersion, &vuln.KnownExploited, &vuln.CreatedAt,
-			&vuln.PackageName, &vuln.PackageVersion, &vuln.PackageType, &vuln.Count,
+			&vuln.PackageName, &vuln.PackageVersion, &vuln.PackageType, &vuln.Count, &vuln.Occurrences,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan vulnerability row: %w", err)
@@ -1385,7 +1397,7 @@ func (db *DB) GetNodeDistributionSummary() ([]nodes.NodeDistributionSummary, err
 // failed precisely because of a stale or broken grype DB, and retrying with the
 // fresh DB is exactly what we want. Mirrors the same fix in GetImagesNeedingRescan.
 func (db *DB) GetNodesNeedingRescan(currentGrypeDBBuilt time.Time) ([]nodes.NodeWithStatus, error) {
-	ts := currentGrypeDBBuilt.Format(time.RFC3339)
+	ts := formatTimestamp(currentGrypeDBBuilt)
 	rows, err := db.conn.Query(`
 		SELECT id, name, hostname, os_release, kernel_version, architecture,
 			container_runtime, kubelet_version, status, status_error,
@@ -1680,7 +1692,7 @@ func (db *DB) GetNodeFilterOptions() (*NodeFilterOptions, error) {
 	db.cachesMu.RLock()
 	cached := db.nodeFilterOpts
 	db.cachesMu.RUnlock()
-	if cached != nil {
+	if cached != nil && !db.readOnly {
 		return cached, nil
 	}
 
@@ -978,7 +978,7 @@ func TestStoreNodeVulnerabilities_DeduplicatesWithCount(t *testing.T) {
 		t.Fatalf("StoreNodeVulnerabilities failed: %v", err)
 	}
 
-	// Should have 1 unique vulnerability with count=3
+	// Should have 1 unique vulnerability, counted once, at one location
 	vulns, err := db.GetNodeVulnerabilities("test-node-1")
 	if err != nil {
 		t.Fatalf("GetNodeVulnerabilities failed: %v", err)
@@ -986,8 +986,8 @@ func TestStoreNodeVulnerabilities_DeduplicatesWithCount(t *testing.T) {
 	if len(vulns) != 1 {
 		t.Fatalf("Expected 1 unique vulnerability (deduplicated), got %d", len(vulns))
 	}
-	if vulns[0].Count != 3 {
-		t.Errorf("Expected count=3, got %d", vulns[0].Count)
+	if vulns[0].Count != 1 || vulns[0].Occurrences != 1 {
+		t.Errorf("Expected count=1 and occurrences=1, got %d and %d", vulns[0].Count, vulns[0].Occurrences)
 	}
 
 	// Details should contain all 3 instances
@@ -1275,7 +1275,7 @@ func TestGetNodeVulnerabilitiesForMetrics_Empty(t *testing.T) {
 	}
 }
 
-// TestGetNodeVulnerabilitiesForMetrics_DeduplicatedCount tests that count is preserved
+// TestGetNodeVulnerabilitiesForMetrics_DeduplicatedCount tests that duplicate matches are counted once
 func TestGetNodeVulnerabilitiesForMetrics_DeduplicatedCount(t *testing.T) {
 	db, cleanup := createTestDB(t)
 	defer cleanup()
@@ -1303,8 +1303,8 @@ func TestGetNodeVulnerabilitiesForMetrics_DeduplicatedCount(t *testing.T) {
 		t.Fatalf("Expected 1 deduplicated vulnerability, got %d", len(vulns))
 	}
 
-	if vulns[0].Count != 3 {
-		t.Errorf("Expected Count=3, got %d", vulns[0].Count)
+	if vulns[0].Count != 1 {
+		t.Errorf("Expected Count=1, got %d", vulns[0].Count)
 	}
 }
 
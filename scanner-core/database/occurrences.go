@@ -0,0 +1,69 @@
+package database
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+)
+
+// VulnerabilityOccurrence is one location at which a vulnerable package was
+// found. Grype reports a match per package location, and sometimes several per
+// location (one per matcher), so a library bundled at several paths used to be
+// counted once per match. Findings are now stored once per (vulnerability,
+// package name, version, type) with their occurrences listed separately.
+type VulnerabilityOccurrence struct {
+	Path    string `json:"path"`
+	Matches int    `json:"matches"`
+}
+
+// GrypeLocation is a file an artifact was found in
+type GrypeLocation struct {
+	Path string `json:"path"`
+}
+
+// occurrencePath returns the path identifying where a matched artifact was
+// found. Packages with several evidence files (e.g. a dpkg status entry and its
+// copyright file) are one occurrence, identified by their first location.
+func occurrencePath(locations []GrypeLocation) string {
+	if len(locations) == 0 {
+		return ""
+	}
+	return locations[0].Path
+}
+
+// mergeOccurrences groups the locations of the matches of one finding by
+// path, so matches differing only in matcher collapse into one occurrence.
+// The result is sorted by path.
+func mergeOccurrences(locations [][]GrypeLocation) []VulnerabilityOccurrence {
+	byPath := make(map[string]int)
+	for _, l := range locations {
+		byPath[occurrencePath(l)]++
+	}
+
+	occurrences := make([]VulnerabilityOccurrence, 0, len(byPath))
+	for path, matches := range byPath {
+		occurrences = append(occurrences, VulnerabilityOccurrence{Path: path, Matches: matches})
+	}
+	sort.Slice(occurrences, func(i, j int) bool { return occurrences[i].Path < occurrences[j].Path })
+	return occurrences
+}
+
+// OccurrencesFromDetails returns the occurrences of a finding from its stored
+// details, the JSON array of Grype matches kept in image_vulnerability_details
+// and node_vulnerability_details
+func OccurrencesFromDetails(details string) ([]VulnerabilityOccurrence, error) {
+	var matches []struct {
+		Artifact struct {
+			Locations []GrypeLocation `json:"locations"`
+		} `json:"artifact"`
+	}
+	if err := json.Unmarshal([]byte(details), &matches); err != nil {
+		return nil, fmt.Errorf("failed to parse vulnerability details: %w", err)
+	}
+
+	locations := make([][]GrypeLocation, len(matches))
+	for i, m := range matches {
+		locations[i] = m.Artifact.Locations
+	}
+	return mergeOccurrences(locations), nil
+}
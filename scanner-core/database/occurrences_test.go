@@ -0,0 +1,68 @@
+package database
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestOccurrencesFromDetails(t *testing.T) {
+	// log4j bundled in two applications, matched twice (cpe and java matchers)
+	// in the first
+	details := `[
+		{"artifact": {"name": "log4j-core", "locations": [{"path": "/app/a/lib/log4j-core.jar"}]}, "matchDetails": [{"matcher": "java-matcher"}]},
+		{"artifact": {"name": "log4j-core", "locations": [{"path": "/app/a/lib/log4j-core.jar"}]}, "matchDetails": [{"matcher": "cpe-matcher"}]},
+		{"artifact": {"name": "log4j-core", "locations": [{"path": "/app/b/log4j-core.jar"}, {"path": "/app/b/META-INF/MANIFEST.MF"}]}}
+	]`
+
+	occurrences, err := OccurrencesFromDetails(details)
+	if err != nil {
+		t.Fatalf("OccurrencesFromDetails() error = %v", err)
+	}
+	want := []VulnerabilityOccurrence{
+		{Path: "/app/a/lib/log4j-core.jar", Matches: 2},
+		{Path: "/app/b/log4j-core.jar", Matches: 1},
+	}
+	if len(occurrences) != len(want) {
+		t.Fatalf("OccurrencesFromDetails() = %+v, want %+v", occurrences, want)
+	}
+	for i := range want {
+		if occurrences[i] != want[i] {
+			t.Errorf("occurrence %d = %+v, want %+v", i, occurrences[i], want[i])
+		}
+	}
+
+	if _, err := OccurrencesFromDetails("not json"); err == nil {
+		t.Error("expected error for invalid details")
+	}
+}
+
+func TestParseVulnerabilityDataMergesPaths(t *testing.T) {
+	db, err := New(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("Failed to create database: %v", err)
+	}
+	defer func() { _ = Close(db) }()
+
+	if _, err := db.conn.Exec(`INSERT INTO images (id, digest) VALUES (1, 'sha256:merge')`); err != nil {
+		t.Fatalf("Failed to insert test image: %v", err)
+	}
+
+	match := func(path string) string {
+		return `{"vulnerability": {"id": "CVE-2021-44228", "severity": "Critical", "fix": {"versions": ["2.17.1"], "state": "fixed"}},
+			"artifact": {"name": "log4j-core", "version": "2.14.1", "type": "java-archive", "locations": [{"path": "` + path + `"}]}}`
+	}
+	vulnJSON := `{"matches": [` + match("/app/a/log4j-core.jar") + `,` + match("/app/b/log4j-core.jar") + `,` +
+		match("/app/c/log4j-core.jar") + `]}`
+	if err := parseVulnerabilityData(db, 1, []byte(vulnJSON)); err != nil {
+		t.Fatalf("parseVulnerabilityData failed: %v", err)
+	}
+
+	var rows, total, occurrences int
+	if err := db.conn.QueryRow(`SELECT COUNT(*), SUM(count), SUM(occurrences) FROM image_vulnerabilities WHERE image_id = 1`).
+		Scan(&rows, &total, &occurrences); err != nil {
+		t.Fatalf("Failed to query vulnerabilities: %v", err)
+	}
+	if rows != 1 || total != 1 || occurrences != 3 {
+		t.Errorf("rows = %d, counted findings = %d, occurrences = %d, want 1, 1 and 3", rows, total, occurrences)
+	}
+}
@@ -0,0 +1,63 @@
+package database
+
+import (
+	"database/sql"
+	"path/filepath"
+	"testing"
+
+	"github.com/bvboe/b2s-go/scanner-core/containers"
+)
+
+// ociLabelsSBOM has image metadata with OCI labels for source and version but
+// not vendor
+const ociLabelsSBOM = `{"artifacts": [{"name": "busybox", "version": "1.36", "type": "apk"}],
+	"source": {"type": "image", "metadata": {"architecture": "amd64",
+	  "labels": {"org.opencontainers.image.source": "https://github.com/example/app",
+	             "org.opencontainers.image.version": " 1.4.2 ", "maintainer": "ops"}}}}`
+
+func TestOCILabels(t *testing.T) {
+	db, err := New(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("failed to create database: %v", err)
+	}
+	defer func() { _ = Close(db) }()
+
+	digest := "sha256:app"
+	if _, err := db.AddContainer(containers.Container{
+		ID:    containers.ContainerID{Namespace: "default", Pod: "app", Name: "app"},
+		Image: containers.ImageID{Reference: "app:1", Digest: digest},
+	}); err != nil {
+		t.Fatalf("AddContainer() error = %v", err)
+	}
+	if err := db.StoreSBOM(digest, []byte(ociLabelsSBOM)); err != nil {
+		t.Fatalf("StoreSBOM() error = %v", err)
+	}
+
+	check := func() {
+		t.Helper()
+		var source, version, vendor sql.NullString
+		if err := db.conn.QueryRow(`SELECT oci_source, oci_version, oci_vendor FROM images WHERE digest = ?`,
+			digest).Scan(&source, &version, &vendor); err != nil {
+			t.Fatal(err)
+		}
+		if source.String != "https://github.com/example/app" || version.String != "1.4.2" || vendor.Valid {
+			t.Errorf("source %v, version %v, vendor %v; want the repository, 1.4.2 and NULL", source, version, vendor)
+		}
+	}
+	check()
+
+	// The migration populates the labels from stored SBOMs
+	unshareSBOMs(t, db)
+	if _, err := db.conn.Exec(`DROP INDEX idx_images_oci_source`); err != nil {
+		t.Fatal(err)
+	}
+	for _, column := range []string{"oci_source", "oci_version", "oci_vendor"} {
+		if _, err := db.conn.Exec(`ALTER TABLE images DROP COLUMN ` + column); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := migrateToV68(db.conn); err != nil {
+		t.Fatalf("migrateToV68() error = %v", err)
+	}
+	check()
+}
@@ -0,0 +1,521 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Online migration states recorded in online_migrations.state
+const (
+	OnlineMigrationPending   = "pending"
+	OnlineMigrationCopying   = "copying"
+	OnlineMigrationCompleted = "completed"
+)
+
+const (
+	// onlineMigrationChunkSize is the number of rows copied per write transaction
+	onlineMigrationChunkSize = 1000
+	// onlineMigrationPause is the delay between chunks so API writes can interleave
+	onlineMigrationPause = 50 * time.Millisecond
+)
+
+// onlineMigration rewrites a table without holding the write lock for the
+// whole copy. Regular migrations run synchronously at startup inside one
+// transaction; a table rewrite that way locks the database for as long as the
+// copy takes. An online migration instead runs in the background after startup:
+//
+//  1. setup: the new table is created as <table>_new and triggers on the old
+//     table mirror every insert, update and delete into it (dual-write window)
+//  2. copy: existing rows are copied in rowid order, one short write
+//     transaction per chunk, with progress persisted in online_migrations so an
+//     interrupted copy resumes after a restart
+//  3. switchover: in one short transaction the old table (and its triggers
+//     and indexes) is dropped, the new table renamed into place and indexes
+//     created
+//
+// Rows keep their rowid, so the new table's INTEGER PRIMARY KEY (if any) must
+// be the rowid and must not appear in columns. Until switchover the running
+// code keeps using the old table, so an online migration may only make
+// changes the code tolerates on both shapes (e.g. adding a column with a
+// default, rebuilding for new constraints, dropping an unused column).
+//
+// A migration with a backfill function instead rewrites rows in place (e.g.
+// moving documents between columns): there is no new table, the rows of table
+// matching where are passed to backfill in rowid order, one write transaction
+// per chunk, and switchover only records completion. The running code must
+// read both the old and the new layout of a row until the backfill completes.
+type onlineMigration struct {
+	name    string   // unique name recorded in online_migrations
+	table   string   // table being rewritten
+	create  string   // CREATE TABLE statement for <table>_new
+	columns []string // columns of the new table copied from the old one
+	selects []string // expression per column, evaluated against the old row aliased src
+	indexes []string // statements run at switchover, after the rename
+
+	where     string                              // backfill: condition selecting the rows still to rewrite
+	backfill  func(tx *sql.Tx, rowid int64) error // backfill: rewrites one row
+	chunkSize int                                 // rows per transaction, if below onlineMigrationChunkSize (e.g. for large documents)
+}
+
+// onlineMigrations lists background table rewrites, applied in order after
+// the regular migrations. Entries must never be removed or reordered.
+var onlineMigrations = []onlineMigration{
+	{
+		name:      "compress_legacy_image_blobs",
+		table:     "images",
+		where:     legacyBlobsWhere,
+		backfill:  compressLegacyBlobs("images"),
+		chunkSize: legacyBlobsChunkSize,
+	},
+	{
+		name:      "compress_legacy_node_blobs",
+		table:     "nodes",
+		where:     legacyBlobsWhere,
+		backfill:  compressLegacyBlobs("nodes"),
+		chunkSize: legacyBlobsChunkSize,
+	},
+	{
+		name:      "move_sboms_to_blobs",
+		table:     "images",
+		where:     unsharedSBOMsWhere,
+		backfill:  moveSBOMToBlob,
+		chunkSize: legacyBlobsChunkSize,
+	},
+}
+
+// OnlineMigrationStatus reports the progress of one online migration
+type OnlineMigrationStatus struct {
+	Name        string     `json:"name"`
+	Table       string     `json:"table"`
+	State       string     `json:"state"`
+	RowsCopied  int64      `json:"rows_copied"`
+	TotalRows   int64      `json:"total_rows"`
+	Percent     float64    `json:"percent"`
+	Error       string     `json:"error,omitempty"`
+	StartedAt   *time.Time `json:"started_at,omitempty"`
+	UpdatedAt   *time.Time `json:"updated_at,omitempty"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+}
+
+// MigrationStatus reports the schema version and online migration progress
+type MigrationStatus struct {
+	SchemaVersion int                     `json:"schema_version"`
+	TargetVersion int                     `json:"target_version"`
+	InProgress    bool                    `json:"in_progress"`
+	Online        []OnlineMigrationStatus `json:"online_migrations"`
+}
+
+func (m onlineMigration) shadowTable() string {
+	return m.table + "_new"
+}
+
+// copySQL returns the statement copying the old rows selected by where into the new table
+func (m onlineMigration) copySQL(where string) string {
+	return fmt.Sprintf(`INSERT OR REPLACE INTO %s (rowid, %s) SELECT src.rowid, %s FROM %s AS src WHERE %s`,
+		m.shadowTable(), strings.Join(m.columns, ", "), strings.Join(m.selects, ", "), m.table, where)
+}
+
+// triggerSQL returns the statements creating the dual-write triggers
+func (m onlineMigration) triggerSQL() []string {
+	shadow := m.shadowTable()
+	return []string{
+		fmt.Sprintf(`CREATE TRIGGER IF NOT EXISTS %[1]s_online_ins AFTER INSERT ON %[1]s BEGIN %[2]s; END`,
+			m.table, m.copySQL("src.rowid = NEW.rowid")),
+		fmt.Sprintf(`CREATE TRIGGER IF NOT EXISTS %[1]s_online_upd AFTER UPDATE ON %[1]s BEGIN DELETE FROM %[2]s WHERE rowid = OLD.rowid; %[3]s; END`,
+			m.table, shadow, m.copySQL("src.rowid = NEW.rowid")),
+		fmt.Sprintf(`CREATE TRIGGER IF NOT EXISTS %[1]s_online_del AFTER DELETE ON %[1]s BEGIN DELETE FROM %[2]s WHERE rowid = OLD.rowid; END`,
+			m.table, shadow),
+	}
+}
+
+// RunOnlineMigrations applies pending online migrations in order. It blocks
+// until all are completed or ctx is cancelled; progress is persisted, so a
+// cancelled run resumes on the next call. Should be run in a goroutine once
+// at startup, after the database is initialised.
+func (db *DB) RunOnlineMigrations(ctx context.Context) error {
+	return db.runOnlineMigrations(ctx, onlineMigrations, onlineMigrationChunkSize)
+}
+
+func (db *DB) runOnlineMigrations(ctx context.Context, list []onlineMigration, chunkSize int) error {
+	for _, m := range list {
+		if err := db.runOnlineMigration(ctx, m, chunkSize); err != nil {
+			db.recordOnlineMigrationError(m, err)
+			return fmt.Errorf("online migration %s: %w", m.name, err)
+		}
+	}
+	return nil
+}
+
+// runOnlineMigration runs all phases of one online migration
+func (db *DB) runOnlineMigration(ctx context.Context, m onlineMigration, chunkSize int) error {
+	if m.chunkSize > 0 && m.chunkSize < chunkSize {
+		chunkSize = m.chunkSize
+	}
+	copyChunk := db.copyOnlineMigrationChunk
+	if m.backfill != nil {
+		copyChunk = db.backfillOnlineMigrationChunk
+	}
+
+	state, _, err := db.onlineMigrationState(m.name)
+	if err != nil {
+		return err
+	}
+	if state == OnlineMigrationCompleted {
+		return nil
+	}
+
+	if state == OnlineMigrationPending {
+		if err := db.setupOnlineMigration(m); err != nil {
+			return err
+		}
+	}
+	log.Info("online migration copying rows", "name", m.name, "table", m.table)
+
+	start := time.Now()
+	lastLogged := -1
+	for {
+		done, err := copyChunk(m, chunkSize)
+		if err != nil {
+			return err
+		}
+		if done {
+			break
+		}
+
+		if status, err := db.onlineMigrationStatus(m); err == nil && int(status.Percent)/10 > lastLogged {
+			lastLogged = int(status.Percent) / 10
+			log.Info("online migration progress", "name", m.name,
+				"rows_copied", status.RowsCopied, "total_rows", status.TotalRows,
+				"percent", fmt.Sprintf("%.1f", status.Percent))
+		}
+
+		select {
+		case <-ctx.Done():
+			log.Info("online migration paused, will resume on next start", "name", m.name)
+			return ctx.Err()
+		case <-time.After(onlineMigrationPause):
+		}
+	}
+
+	if err := db.switchOnlineMigration(m); err != nil {
+		return err
+	}
+	log.Info("online migration completed", "name", m.name, "table", m.table,
+		"duration", time.Since(start).Round(time.Millisecond))
+	return nil
+}
+
+// onlineMigrationState returns the recorded state and copy position of a migration
+func (db *DB) onlineMigrationState(name string) (string, int64, error) {
+	var state string
+	var lastRowID int64
+	err := db.conn.QueryRow(`SELECT state, last_rowid FROM online_migrations WHERE name = ?`, name).
+		Scan(&state, &lastRowID)
+	if err == sql.ErrNoRows {
+		return OnlineMigrationPending, 0, nil
+	}
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to read online migration state: %w", err)
+	}
+	return state, lastRowID, nil
+}
+
+// setupOnlineMigration creates the new table and the dual-write triggers, or
+// for a backfill only records the number of rows to rewrite
+func (db *DB) setupOnlineMigration(m onlineMigration) error {
+	done := db.beginWrite("online_migration")
+	defer done()
+
+	tx, err := db.conn.Begin()
+	if err != nil {
+		exitOnCorruption(err)
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	countQuery := fmt.Sprintf(`SELECT COUNT(*) FROM %s`, m.table)
+	if m.backfill != nil {
+		countQuery += " WHERE " + m.where
+	} else {
+		if _, err := tx.Exec(fmt.Sprintf(`DROP TABLE IF EXISTS %s`, m.shadowTable())); err != nil {
+			return fmt.Errorf("failed to drop stale %s: %w", m.shadowTable(), err)
+		}
+		if _, err := tx.Exec(m.create); err != nil {
+			return fmt.Errorf("failed to create %s: %w", m.shadowTable(), err)
+		}
+		for _, stmt := range m.triggerSQL() {
+			if _, err := tx.Exec(stmt); err != nil {
+				return fmt.Errorf("failed to create dual-write trigger: %w", err)
+			}
+		}
+	}
+
+	var total int64
+	if err := tx.QueryRow(countQuery).Scan(&total); err != nil {
+		return fmt.Errorf("failed to count rows in %s: %w", m.table, err)
+	}
+
+	now := time.Now().UTC().Format(time.RFC3339)
+	_, err = tx.Exec(`
+		INSERT INTO online_migrations (name, table_name, state, last_rowid, rows_copied, total_rows, error, started_at, updated_at)
+		VALUES (?, ?, ?, 0, 0, ?, '', ?, ?)
+		ON CONFLICT(name) DO UPDATE SET
+			state = excluded.state, last_rowid = 0, rows_copied = 0, total_rows = excluded.total_rows,
+			error = '', started_at = excluded.started_at, updated_at = excluded.updated_at
+	`, m.name, m.table, OnlineMigrationCopying, total, now, now)
+	if err != nil {
+		return fmt.Errorf("failed to record online migration: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		exitOnCorruption(err)
+		return fmt.Errorf("failed to commit online migration setup: %w", err)
+	}
+	log.Info("online migration started", "name", m.name, "table", m.table, "total_rows", total)
+	return nil
+}
+
+// copyOnlineMigrationChunk copies the next chunk of rows into the new table.
+// Returns true once all rows have been copied.
+func (db *DB) copyOnlineMigrationChunk(m onlineMigration, chunkSize int) (bool, error) {
+	done := db.beginWrite("online_migration")
+	defer done()
+
+	tx, err := db.conn.Begin()
+	if err != nil {
+		exitOnCorruption(err)
+		return false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	var lastRowID int64
+	if err := tx.QueryRow(`SELECT last_rowid FROM online_migrations WHERE name = ?`, m.name).Scan(&lastRowID); err != nil {
+		return false, fmt.Errorf("failed to read online migration position: %w", err)
+	}
+
+	var chunkEnd sql.NullInt64
+	var chunkRows int64
+	err = tx.QueryRow(fmt.Sprintf(`
+		SELECT MAX(rowid), COUNT(*) FROM (
+			SELECT rowid FROM %s WHERE rowid > ? ORDER BY rowid LIMIT ?
+		)`, m.table), lastRowID, chunkSize).Scan(&chunkEnd, &chunkRows)
+	if err != nil {
+		return false, fmt.Errorf("failed to find next chunk of %s: %w", m.table, err)
+	}
+	if !chunkEnd.Valid {
+		return true, nil
+	}
+
+	if _, err := tx.Exec(m.copySQL("src.rowid > ? AND src.rowid <= ?"), lastRowID, chunkEnd.Int64); err != nil {
+		exitOnCorruption(err)
+		return false, fmt.Errorf("failed to copy rows of %s: %w", m.table, err)
+	}
+
+	_, err = tx.Exec(`
+		UPDATE online_migrations
+		SET last_rowid = ?, rows_copied = rows_copied + ?, updated_at = ?
+		WHERE name = ?
+	`, chunkEnd.Int64, chunkRows, time.Now().UTC().Format(time.RFC3339), m.name)
+	if err != nil {
+		return false, fmt.Errorf("failed to record online migration progress: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		exitOnCorruption(err)
+		return false, fmt.Errorf("failed to commit online migration chunk: %w", err)
+	}
+	return false, nil
+}
+
+// backfillOnlineMigrationChunk rewrites the next chunk of rows matching the
+// backfill condition. Returns true once no rows are left.
+func (db *DB) backfillOnlineMigrationChunk(m onlineMigration, chunkSize int) (bool, error) {
+	done := db.beginWrite("online_migration")
+	defer done()
+
+	tx, err := db.conn.Begin()
+	if err != nil {
+		exitOnCorruption(err)
+		return false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	var lastRowID int64
+	if err := tx.QueryRow(`SELECT last_rowid FROM online_migrations WHERE name = ?`, m.name).Scan(&lastRowID); err != nil {
+		return false, fmt.Errorf("failed to read online migration position: %w", err)
+	}
+
+	rows, err := tx.Query(fmt.Sprintf(`SELECT rowid FROM %s WHERE rowid > ? AND (%s) ORDER BY rowid LIMIT ?`,
+		m.table, m.where), lastRowID, chunkSize)
+	if err != nil {
+		return false, fmt.Errorf("failed to find next chunk of %s: %w", m.table, err)
+	}
+	var rowIDs []int64
+	for rows.Next() {
+		var rowID int64
+		if err := rows.Scan(&rowID); err != nil {
+			_ = rows.Close()
+			return false, fmt.Errorf("failed to scan rowid of %s: %w", m.table, err)
+		}
+		rowIDs = append(rowIDs, rowID)
+	}
+	_ = rows.Close()
+	if len(rowIDs) == 0 {
+		return true, nil
+	}
+
+	for _, rowID := range rowIDs {
+		if err := m.backfill(tx, rowID); err != nil {
+			exitOnCorruption(err)
+			return false, fmt.Errorf("failed to rewrite row %d of %s: %w", rowID, m.table, err)
+		}
+	}
+
+	_, err = tx.Exec(`
+		UPDATE online_migrations
+		SET last_rowid = ?, rows_copied = rows_copied + ?, updated_at = ?
+		WHERE name = ?
+	`, rowIDs[len(rowIDs)-1], len(rowIDs), time.Now().UTC().Format(time.RFC3339), m.name)
+	if err != nil {
+		return false, fmt.Errorf("failed to record online migration progress: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		exitOnCorruption(err)
+		return false, fmt.Errorf("failed to commit online migration chunk: %w", err)
+	}
+	db.notifyWrite()
+	return false, nil
+}
+
+// switchOnlineMigration replaces the old table with the fully copied new one.
+// A backfill has no new table; only its indexes are created.
+func (db *DB) switchOnlineMigration(m onlineMigration) error {
+	done := db.beginWrite("online_migration")
+	defer done()
+
+	tx, err := db.conn.Begin()
+	if err != nil {
+		exitOnCorruption(err)
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if m.backfill == nil {
+		// Dropping the old table also drops its triggers and indexes
+		if _, err := tx.Exec(fmt.Sprintf(`DROP TABLE %s`, m.table)); err != nil {
+			return fmt.Errorf("failed to drop %s: %w", m.table, err)
+		}
+		if _, err := tx.Exec(fmt.Sprintf(`ALTER TABLE %s RENAME TO %s`, m.shadowTable(), m.table)); err != nil {
+			return fmt.Errorf("failed to rename %s: %w", m.shadowTable(), err)
+		}
+	}
+	for _, stmt := range m.indexes {
+		if _, err := tx.Exec(stmt); err != nil {
+			return fmt.Errorf("failed to create index on %s: %w", m.table, err)
+		}
+	}
+
+	now := time.Now().UTC().Format(time.RFC3339)
+	_, err = tx.Exec(`
+		UPDATE online_migrations SET state = ?, error = '', updated_at = ?, completed_at = ? WHERE name = ?
+	`, OnlineMigrationCompleted, now, now, m.name)
+	if err != nil {
+		return fmt.Errorf("failed to record online migration completion: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		exitOnCorruption(err)
+		return fmt.Errorf("failed to commit online migration switchover: %w", err)
+	}
+	db.notifyWrite()
+	return nil
+}
+
+// recordOnlineMigrationError stores the last error of a failed online migration
+func (db *DB) recordOnlineMigrationError(m onlineMigration, err error) {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return
+	}
+	log.Error("online migration failed", "name", m.name, "table", m.table, "error", err)
+
+	done := db.beginWrite("online_migration")
+	defer done()
+	_, _ = db.conn.Exec(`UPDATE online_migrations SET error = ?, updated_at = ? WHERE name = ?`,
+		err.Error(), time.Now().UTC().Format(time.RFC3339), m.name)
+}
+
+// onlineMigrationStatus reads the progress of one online migration
+func (db *DB) onlineMigrationStatus(m onlineMigration) (OnlineMigrationStatus, error) {
+	status := OnlineMigrationStatus{Name: m.name, Table: m.table, State: OnlineMigrationPending}
+
+	var startedAt, updatedAt string
+	var completedAt sql.NullString
+	err := db.conn.QueryRow(`
+		SELECT state, rows_copied, total_rows, error, started_at, updated_at, completed_at
+		FROM online_migrations WHERE name = ?
+	`, m.name).Scan(&status.State, &status.RowsCopied, &status.TotalRows, &status.Error,
+		&startedAt, &updatedAt, &completedAt)
+	if err == sql.ErrNoRows {
+		return status, nil
+	}
+	if err != nil {
+		return status, fmt.Errorf("failed to read online migration status: %w", err)
+	}
+
+	status.StartedAt = parseMigrationTime(startedAt)
+	status.UpdatedAt = parseMigrationTime(updatedAt)
+	if completedAt.Valid {
+		status.CompletedAt = parseMigrationTime(completedAt.String)
+	}
+
+	switch {
+	case status.State == OnlineMigrationCompleted:
+		status.Percent = 100
+	case status.TotalRows > 0:
+		// Rows inserted during the copy can push rows_copied past the initial count
+		status.Percent = min(99.9, float64(status.RowsCopied)*100/float64(status.TotalRows))
+	}
+	return status, nil
+}
+
+func parseMigrationTime(s string) *time.Time {
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return nil
+	}
+	return &t
+}
+
+// GetMigrationStatus returns the schema version and the progress of all online migrations
+func (db *DB) GetMigrationStatus() (*MigrationStatus, error) {
+	return db.getMigrationStatus(onlineMigrations)
+}
+
+func (db *DB) getMigrationStatus(list []onlineMigration) (*MigrationStatus, error) {
+	version, err := db.getCurrentVersion()
+	if err != nil {
+		return nil, err
+	}
+
+	result := &MigrationStatus{
+		SchemaVersion: version,
+		TargetVersion: currentSchemaVersion,
+		Online:        make([]OnlineMigrationStatus, 0, len(list)),
+	}
+	for _, m := range list {
+		status, err := db.onlineMigrationStatus(m)
+		if err != nil {
+			return nil, err
+		}
+		if status.State != OnlineMigrationCompleted {
+			result.InProgress = true
+		}
+		result.Online = append(result.Online, status)
+	}
+	return result, nil
+}
@@ -0,0 +1,139 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"testing"
+)
+
+// testOnlineMigration rewrites widgets, adding a computed column
+var testOnlineMigration = onlineMigration{
+	name:  "widgets_add_label",
+	table: "widgets",
+	create: `CREATE TABLE widgets_new (
+		id INTEGER PRIMARY KEY,
+		name TEXT NOT NULL,
+		label TEXT NOT NULL DEFAULT ''
+	)`,
+	columns: []string{"name", "label"},
+	selects: []string{"src.name", "upper(src.name)"},
+	indexes: []string{`CREATE INDEX idx_widgets_label ON widgets(label)`},
+}
+
+func newOnlineMigrationTestDB(t *testing.T, rows int) *DB {
+	t.Helper()
+	db, err := New(filepath.Join(t.TempDir(), "online.db"))
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	t.Cleanup(func() { _ = Close(db) })
+
+	if _, err := db.conn.Exec(`CREATE TABLE widgets (id INTEGER PRIMARY KEY, name TEXT NOT NULL)`); err != nil {
+		t.Fatalf("create widgets: %v", err)
+	}
+	for i := 1; i <= rows; i++ {
+		if _, err := db.conn.Exec(`INSERT INTO widgets (id, name) VALUES (?, ?)`, i, fmt.Sprintf("w%d", i)); err != nil {
+			t.Fatalf("insert widget: %v", err)
+		}
+	}
+	return db
+}
+
+// TestOnlineMigrationDualWrite tests that writes made during the copy reach the new table
+func TestOnlineMigrationDualWrite(t *testing.T) {
+	db := newOnlineMigrationTestDB(t, 25)
+	m := testOnlineMigration
+
+	if err := db.setupOnlineMigration(m); err != nil {
+		t.Fatalf("setupOnlineMigration() error = %v", err)
+	}
+	if done, err := db.copyOnlineMigrationChunk(m, 10); err != nil || done {
+		t.Fatalf("copyOnlineMigrationChunk() = %v, %v", done, err)
+	}
+
+	// Writes during the dual-write window, both in the copied and uncopied ranges
+	for _, stmt := range []string{
+		`UPDATE widgets SET name = 'renamed' WHERE id = 3`,
+		`DELETE FROM widgets WHERE id = 5`,
+		`DELETE FROM widgets WHERE id = 20`,
+		`INSERT INTO widgets (id, name) VALUES (100, 'late')`,
+	} {
+		if _, err := db.conn.Exec(stmt); err != nil {
+			t.Fatalf("%s: %v", stmt, err)
+		}
+	}
+
+	status, err := db.getMigrationStatus([]onlineMigration{m})
+	if err != nil {
+		t.Fatalf("getMigrationStatus() error = %v", err)
+	}
+	if !status.InProgress || status.Online[0].State != OnlineMigrationCopying || status.Online[0].RowsCopied != 10 {
+		t.Errorf("status during copy = %+v", status.Online[0])
+	}
+
+	if err := db.runOnlineMigration(context.Background(), m, 10); err != nil {
+		t.Fatalf("runOnlineMigration() error = %v", err)
+	}
+
+	var count int
+	if err := db.conn.QueryRow(`SELECT COUNT(*) FROM widgets`).Scan(&count); err != nil {
+		t.Fatalf("count: %v", err)
+	}
+	if count != 24 {
+		t.Errorf("rows after switchover = %d, want 24", count)
+	}
+	for id, want := range map[int]string{1: "W1", 3: "RENAMED", 25: "W25", 100: "LATE"} {
+		var label string
+		if err := db.conn.QueryRow(`SELECT label FROM widgets WHERE id = ?`, id).Scan(&label); err != nil {
+			t.Fatalf("label of %d: %v", id, err)
+		}
+		if label != want {
+			t.Errorf("label of %d = %q, want %q", id, label, want)
+		}
+	}
+
+	var triggers, indexes int
+	_ = db.conn.QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE type = 'trigger' AND tbl_name = 'widgets'`).Scan(&triggers)
+	_ = db.conn.QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE type = 'index' AND name = 'idx_widgets_label'`).Scan(&indexes)
+	if triggers != 0 || indexes != 1 {
+		t.Errorf("triggers = %d, indexes = %d after switchover, want 0 and 1", triggers, indexes)
+	}
+
+	status, err = db.getMigrationStatus([]onlineMigration{m})
+	if err != nil {
+		t.Fatalf("getMigrationStatus() error = %v", err)
+	}
+	if status.InProgress || status.Online[0].State != OnlineMigrationCompleted || status.Online[0].Percent != 100 {
+		t.Errorf("status after completion = %+v", status.Online[0])
+	}
+}
+
+// TestOnlineMigrationResume tests that a cancelled migration resumes from its last chunk
+func TestOnlineMigrationResume(t *testing.T) {
+	db := newOnlineMigrationTestDB(t, 30)
+	m := testOnlineMigration
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := db.runOnlineMigrations(ctx, []onlineMigration{m}, 10); err == nil {
+		t.Fatal("expected cancelled run to return an error")
+	}
+	_, lastRowID, err := db.onlineMigrationState(m.name)
+	if err != nil || lastRowID != 10 {
+		t.Fatalf("position after cancel = %d, %v; want 10", lastRowID, err)
+	}
+
+	if err := db.runOnlineMigrations(context.Background(), []onlineMigration{m}, 10); err != nil {
+		t.Fatalf("runOnlineMigrations() error = %v", err)
+	}
+	var count int
+	if err := db.conn.QueryRow(`SELECT COUNT(*) FROM widgets WHERE label != ''`).Scan(&count); err != nil || count != 30 {
+		t.Errorf("migrated rows = %d, %v; want 30", count, err)
+	}
+
+	// Completed migrations are skipped
+	if err := db.runOnlineMigrations(context.Background(), []onlineMigration{m}, 10); err != nil {
+		t.Errorf("re-run error = %v", err)
+	}
+}
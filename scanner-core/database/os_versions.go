@@ -0,0 +1,39 @@
+package database
+
+import "fmt"
+
+// OSVersionCount is the number of running images and containers with one OS release
+type OSVersionCount struct {
+	OSName     string `json:"os_name"`
+	OSVersion  string `json:"os_version"`
+	Images     int    `json:"image_count"`
+	Containers int    `json:"container_count"`
+}
+
+// GetOSVersionCounts counts the images and containers currently running per
+// OS name and version, for images whose OS has been detected
+func (db *DB) GetOSVersionCounts() ([]OSVersionCount, error) {
+	rows, err := db.conn.Query(`
+		SELECT img.os_name, COALESCE(img.os_version, ''),
+		       COUNT(DISTINCT img.id), COUNT(*)
+		FROM containers c
+		JOIN images img ON c.image_id = img.id
+		WHERE img.os_name IS NOT NULL AND img.os_name != ''
+		GROUP BY img.os_name, COALESCE(img.os_version, '')
+		ORDER BY img.os_name, COALESCE(img.os_version, '')
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query OS versions: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	counts := []OSVersionCount{}
+	for rows.Next() {
+		var c OSVersionCount
+		if err := rows.Scan(&c.OSName, &c.OSVersion, &c.Images, &c.Containers); err != nil {
+			return nil, fmt.Errorf("failed to scan OS version row: %w", err)
+		}
+		counts = append(counts, c)
+	}
+	return counts, rows.Err()
+}
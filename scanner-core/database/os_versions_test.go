@@ -0,0 +1,62 @@
+package database
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/bvboe/b2s-go/scanner-core/containers"
+)
+
+// TestGetOSVersionCounts verifies images and containers are counted per OS
+// release and images without a detected OS are left out.
+func TestGetOSVersionCounts(t *testing.T) {
+	db, err := New(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("failed to create database: %v", err)
+	}
+	defer func() { _ = Close(db) }()
+
+	running := []containers.Container{
+		{ID: containers.ContainerID{Namespace: "default", Pod: "web-1", Name: "app"},
+			Image: containers.ImageID{Reference: "app:1", Digest: "sha256:stretch"}},
+		{ID: containers.ContainerID{Namespace: "default", Pod: "web-2", Name: "app"},
+			Image: containers.ImageID{Reference: "app:1", Digest: "sha256:stretch"}},
+		{ID: containers.ContainerID{Namespace: "default", Pod: "worker", Name: "app"},
+			Image: containers.ImageID{Reference: "worker:2", Digest: "sha256:stretch-2"}},
+		{ID: containers.ContainerID{Namespace: "default", Pod: "proxy", Name: "nginx"},
+			Image: containers.ImageID{Reference: "nginx:1.25", Digest: "sha256:alpine"}},
+		{ID: containers.ContainerID{Namespace: "default", Pod: "pending", Name: "scan"},
+			Image: containers.ImageID{Reference: "new:1", Digest: "sha256:unscanned"}},
+	}
+	for _, c := range running {
+		if _, err := db.AddContainer(c); err != nil {
+			t.Fatalf("AddContainer() error = %v", err)
+		}
+	}
+	for digest, os := range map[string][2]string{
+		"sha256:stretch":   {"debian", "9"},
+		"sha256:stretch-2": {"debian", "9"},
+		"sha256:alpine":    {"alpine", "3.18.4"},
+	} {
+		if _, err := db.conn.Exec(`UPDATE images SET os_name = ?, os_version = ? WHERE digest = ?`, os[0], os[1], digest); err != nil {
+			t.Fatalf("failed to set OS: %v", err)
+		}
+	}
+
+	counts, err := db.GetOSVersionCounts()
+	if err != nil {
+		t.Fatalf("GetOSVersionCounts() error = %v", err)
+	}
+	want := []OSVersionCount{
+		{OSName: "alpine", OSVersion: "3.18.4", Images: 1, Containers: 1},
+		{OSName: "debian", OSVersion: "9", Images: 2, Containers: 3},
+	}
+	if len(counts) != len(want) {
+		t.Fatalf("GetOSVersionCounts() = %+v, want %+v", counts, want)
+	}
+	for i := range want {
+		if counts[i] != want[i] {
+			t.Errorf("counts[%d] = %+v, want %+v", i, counts[i], want[i])
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// ImagePolicyFacts is what a policy verdict for an image is computed from
+type ImagePolicyFacts struct {
+	Digest         string            `json:"digest"`
+	Reference      string            `json:"reference"`
+	Status         Status            `json:"status"`
+	OSName         string            `json:"os_name"`
+	OSVersion      string            `json:"os_version"`
+	Namespaces     []string          `json:"namespaces,omitempty"`
+	Labels         map[string]string `json:"labels,omitempty"`   // OCI source, version and vendor labels by key
+	Metadata       map[string]string `json:"metadata,omitempty"` // CMDB attributes by name (see CMDBAttributes)
+	Critical       int               `json:"critical"`           // unique critical CVEs
+	KnownExploited int               `json:"known_exploited"`    // unique CVEs in the CISA KEV catalog
+	RiskScore      float64           `json:"risk_score"`         // sum of the risk of all findings
+}
+
+// policyFactsQuery selects the policy facts of images; %s is the WHERE clause
+const policyFactsQuery = `
+	SELECT img.digest,
+	       COALESCE((SELECT MIN(reference) FROM containers WHERE image_id = img.id),
+	                img.last_reference, img.ad_hoc_reference, ''),
+	       img.status, COALESCE(img.os_name, ''), COALESCE(img.os_version, ''),
+	       COALESCE((SELECT GROUP_CONCAT(namespace, ',') FROM
+	                 (SELECT DISTINCT namespace FROM containers WHERE image_id = img.id ORDER BY namespace)), ''),
+	       COALESCE(img.oci_source, ''), COALESCE(img.oci_version, ''), COALESCE(img.oci_vendor, ''),
+	       COUNT(DISTINCT CASE WHEN v.severity = 'Critical' THEN v.cve_id END),
+	       COUNT(DISTINCT CASE WHEN v.known_exploited > 0 THEN v.cve_id END),
+	       COALESCE(SUM(v.risk * v.count), 0)
+	FROM images img
+	LEFT JOIN image_vulnerabilities v ON v.image_id = img.id
+	WHERE %s
+	GROUP BY img.id
+	ORDER BY img.digest`
+
+// GetImagePolicyFacts returns the policy facts of an image.
+// Returns nil if the image is unknown.
+func (db *DB) GetImagePolicyFacts(digest string) (*ImagePolicyFacts, error) {
+	facts, err := db.queryPolicyFacts(fmt.Sprintf(policyFactsQuery, "img.digest = ?"), digest)
+	if err != nil {
+		return nil, err
+	}
+	if len(facts) == 0 {
+		return nil, nil
+	}
+	return &facts[0], nil
+}
+
+// GetRunningImagePolicyFacts returns the policy facts of every image running
+// in the given namespaces (all namespaces if empty), ordered by digest
+func (db *DB) GetRunningImagePolicyFacts(namespaces []string) ([]ImagePolicyFacts, error) {
+	where := "img.id IN (SELECT image_id FROM containers"
+	args := make([]interface{}, 0, len(namespaces))
+	if len(namespaces) > 0 {
+		where += " WHERE namespace IN (?" + strings.Repeat(", ?", len(namespaces)-1) + ")"
+		for _, ns := range namespaces {
+			args = append(args, ns)
+		}
+	}
+	where += ")"
+	facts, err := db.queryPolicyFacts(fmt.Sprintf(policyFactsQuery, where), args...)
+	if err != nil {
+		return nil, err
+	}
+	if facts == nil {
+		facts = []ImagePolicyFacts{}
+	}
+	return facts, nil
+}
+
+func (db *DB) queryPolicyFacts(query string, args ...interface{}) ([]ImagePolicyFacts, error) {
+	cmdb, err := db.loadCMDBIndex()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := db.conn.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query image policy facts: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var facts []ImagePolicyFacts
+	for rows.Next() {
+		var f ImagePolicyFacts
+		var status, namespaces, ociSource, ociVersion, ociVendor string
+		var risk sql.NullFloat64
+		if err := rows.Scan(&f.Digest, &f.Reference, &status, &f.OSName, &f.OSVersion, &namespaces,
+			&ociSource, &ociVersion, &ociVendor, &f.Critical, &f.KnownExploited, &risk); err != nil {
+			return nil, fmt.Errorf("failed to scan image policy facts: %w", err)
+		}
+		f.Status = Status(status)
+		f.RiskScore = risk.Float64
+		if namespaces != "" {
+			f.Namespaces = strings.Split(namespaces, ",")
+		}
+		for key, value := range map[string]string{ociSourceLabel: ociSource, ociVersionLabel: ociVersion, ociVendorLabel: ociVendor} {
+			if value != "" {
+				if f.Labels == nil {
+					f.Labels = make(map[string]string)
+				}
+				f.Labels[key] = value
+			}
+		}
+		f.Metadata = cmdb.resolve(f.Namespaces, f.Labels).Map()
+		facts = append(facts, f)
+	}
+	return facts, rows.Err()
+}
@@ -0,0 +1,66 @@
+package database
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/bvboe/b2s-go/scanner-core/containers"
+)
+
+func TestGetImagePolicyFacts(t *testing.T) {
+	db, err := New(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("failed to create database: %v", err)
+	}
+	defer func() { _ = Close(db) }()
+
+	for _, c := range []containers.Container{
+		{ID: containers.ContainerID{Namespace: "team-b", Pod: "app-1", Name: "app"}, Image: containers.ImageID{Reference: "app:1", Digest: "sha256:app"}},
+		{ID: containers.ContainerID{Namespace: "team-a", Pod: "app-2", Name: "app"}, Image: containers.ImageID{Reference: "app:1", Digest: "sha256:app"}},
+		{ID: containers.ContainerID{Namespace: "team-c", Pod: "db-1", Name: "db"}, Image: containers.ImageID{Reference: "db:1", Digest: "sha256:db"}},
+	} {
+		if _, err := db.AddContainer(c); err != nil {
+			t.Fatalf("AddContainer() error = %v", err)
+		}
+	}
+
+	// The same critical CVE in two packages counts once
+	vulnJSON := []byte(`{"matches": [
+		{"vulnerability": {"id": "CVE-1", "severity": "Critical", "risk": 10, "knownExploited": [{"cve": "CVE-1"}]},
+		 "artifact": {"name": "openssl", "version": "3.0", "type": "deb"}},
+		{"vulnerability": {"id": "CVE-1", "severity": "Critical", "risk": 10},
+		 "artifact": {"name": "libssl", "version": "3.0", "type": "deb"}},
+		{"vulnerability": {"id": "CVE-2", "severity": "High", "risk": 2.5},
+		 "artifact": {"name": "bash", "version": "5.1", "type": "deb"}}
+	]}`)
+	if err := db.StoreVulnerabilities("sha256:app", vulnJSON, time.Now()); err != nil {
+		t.Fatalf("StoreVulnerabilities() error = %v", err)
+	}
+	if err := db.UpdateStatus("sha256:app", StatusCompleted, ""); err != nil {
+		t.Fatalf("UpdateStatus() error = %v", err)
+	}
+
+	facts, err := db.GetImagePolicyFacts("sha256:app")
+	if err != nil || facts == nil {
+		t.Fatalf("GetImagePolicyFacts() = %v, %v", facts, err)
+	}
+	if facts.Reference != "app:1" || facts.Status != StatusCompleted || facts.Critical != 1 || facts.KnownExploited != 1 ||
+		facts.RiskScore != 22.5 || !reflect.DeepEqual(facts.Namespaces, []string{"team-a", "team-b"}) {
+		t.Errorf("GetImagePolicyFacts() = %+v", facts)
+	}
+
+	if facts, err := db.GetImagePolicyFacts("sha256:unknown"); err != nil || facts != nil {
+		t.Errorf("unknown image: GetImagePolicyFacts() = %v, %v", facts, err)
+	}
+
+	all, err := db.GetRunningImagePolicyFacts(nil)
+	if err != nil || len(all) != 2 || all[0].Digest != "sha256:app" || all[1].Digest != "sha256:db" {
+		t.Errorf("GetRunningImagePolicyFacts(nil) = %+v, %v", all, err)
+	}
+	filtered, err := db.GetRunningImagePolicyFacts([]string{"team-c"})
+	if err != nil || len(filtered) != 1 || filtered[0].Digest != "sha256:db" || filtered[0].Critical != 0 {
+		t.Errorf("GetRunningImagePolicyFacts([team-c]) = %+v, %v", filtered, err)
+	}
+}
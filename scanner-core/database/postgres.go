@@ -0,0 +1,408 @@
+package database
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// postgresSchema creates the export tables. Every row carries the UUID of the
+// exporting deployment, so several clusters can share one database. Images
+// that stopped running are kept (running = false) for long-term history, as
+// are scan_history rows pruned from the local store.
+const postgresSchema = `
+CREATE TABLE IF NOT EXISTS b2s_images (
+	deployment_uuid  TEXT NOT NULL,
+	deployment_name  TEXT NOT NULL,
+	digest           TEXT NOT NULL,
+	image_references TEXT NOT NULL DEFAULT '',
+	os_name          TEXT NOT NULL DEFAULT '',
+	os_version       TEXT NOT NULL DEFAULT '',
+	status           TEXT NOT NULL DEFAULT '',
+	vulns_scanned_at TIMESTAMPTZ,
+	vulns_hash       TEXT NOT NULL DEFAULT '',
+	containers       INTEGER NOT NULL DEFAULT 0,
+	critical         INTEGER NOT NULL DEFAULT 0,
+	high             INTEGER NOT NULL DEFAULT 0,
+	medium           INTEGER NOT NULL DEFAULT 0,
+	low              INTEGER NOT NULL DEFAULT 0,
+	negligible       INTEGER NOT NULL DEFAULT 0,
+	unknown          INTEGER NOT NULL DEFAULT 0,
+	running          BOOLEAN NOT NULL DEFAULT TRUE,
+	first_seen       TIMESTAMPTZ NOT NULL,
+	last_seen        TIMESTAMPTZ NOT NULL,
+	PRIMARY KEY (deployment_uuid, digest)
+);
+ALTER TABLE b2s_images ADD COLUMN IF NOT EXISTS vulns_hash TEXT NOT NULL DEFAULT '';
+CREATE TABLE IF NOT EXISTS b2s_containers (
+	deployment_uuid TEXT NOT NULL,
+	namespace       TEXT NOT NULL,
+	pod             TEXT NOT NULL,
+	name            TEXT NOT NULL,
+	reference       TEXT NOT NULL DEFAULT '',
+	node_name       TEXT NOT NULL DEFAULT '',
+	digest          TEXT NOT NULL,
+	PRIMARY KEY (deployment_uuid, namespace, pod, name)
+);
+CREATE TABLE IF NOT EXISTS b2s_vulnerabilities (
+	deployment_uuid TEXT NOT NULL,
+	digest          TEXT NOT NULL,
+	cve_id          TEXT NOT NULL,
+	aliases         TEXT NOT NULL DEFAULT '',
+	severity        TEXT NOT NULL DEFAULT '',
+	package_name    TEXT NOT NULL DEFAULT '',
+	package_version TEXT NOT NULL DEFAULT '',
+	package_type    TEXT NOT NULL DEFAULT '',
+	fix_status      TEXT NOT NULL DEFAULT '',
+	fixed_version   TEXT NOT NULL DEFAULT '',
+	risk            DOUBLE PRECISION NOT NULL DEFAULT 0,
+	known_exploited BOOLEAN NOT NULL DEFAULT FALSE
+);
+CREATE INDEX IF NOT EXISTS b2s_vulnerabilities_image ON b2s_vulnerabilities (deployment_uuid, digest);
+CREATE INDEX IF NOT EXISTS b2s_vulnerabilities_cve ON b2s_vulnerabilities (cve_id);
+CREATE TABLE IF NOT EXISTS b2s_scan_history (
+	deployment_uuid TEXT NOT NULL,
+	id              BIGINT NOT NULL,
+	digest          TEXT NOT NULL,
+	namespaces      TEXT NOT NULL DEFAULT '',
+	running         BOOLEAN NOT NULL DEFAULT FALSE,
+	scanned_at      TIMESTAMPTZ,
+	grype_db_built  TIMESTAMPTZ,
+	critical        INTEGER NOT NULL DEFAULT 0,
+	high            INTEGER NOT NULL DEFAULT 0,
+	medium          INTEGER NOT NULL DEFAULT 0,
+	low             INTEGER NOT NULL DEFAULT 0,
+	negligible      INTEGER NOT NULL DEFAULT 0,
+	unknown         INTEGER NOT NULL DEFAULT 0,
+	total           INTEGER NOT NULL DEFAULT 0,
+	known_exploited INTEGER NOT NULL DEFAULT 0,
+	risk_score      DOUBLE PRECISION NOT NULL DEFAULT 0,
+	PRIMARY KEY (deployment_uuid, id)
+);
+CREATE TABLE IF NOT EXISTS b2s_exports (
+	deployment_uuid  TEXT NOT NULL,
+	exported_at      TIMESTAMPTZ NOT NULL,
+	images           INTEGER NOT NULL,
+	containers       INTEGER NOT NULL,
+	rescanned_images INTEGER NOT NULL,
+	vulnerabilities  INTEGER NOT NULL
+);
+ALTER TABLE b2s_exports ADD COLUMN IF NOT EXISTS scan_history INTEGER NOT NULL DEFAULT 0;
+`
+
+// historyBatchSize is the number of scan_history rows read from the local
+// store at a time
+const historyBatchSize = 1000
+
+// pgQuerier is a PostgreSQL transaction or connection pool
+type pgQuerier interface {
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
+// PostgresSink exports scan results to a PostgreSQL database. Each export runs
+// in one transaction: images are upserted, the running containers replaced,
+// the vulnerabilities of images whose exported rows changed since the
+// previous export rewritten and the scan history recorded since appended, so
+// readers always see a consistent snapshot.
+//
+// It is a one-way copy, not a storage backend: the scan server keeps reading
+// and writing its local SQLite store, whose queries and migrations are SQLite
+// specific, and PostgreSQL only receives the results for reporting.
+//
+// Changes are detected from a hash of each image's exported vulnerability
+// rows rather than its scan time, since VEX statements, ignore rules and
+// severity mapping rewrite the stored vulnerabilities without a rescan.
+type PostgresSink struct {
+	pool           *pgxpool.Pool
+	deploymentUUID string
+	deploymentName string
+	now            func() time.Time
+}
+
+// NewPostgresSink connects to the PostgreSQL database at dsn (a URL or
+// key=value connection string) and creates the export tables if needed.
+// Results are recorded under the UUID and name of the exporting deployment.
+func NewPostgresSink(ctx context.Context, dsn, deploymentUUID, deploymentName string) (*PostgresSink, error) {
+	pool, err := pgxpool.New(ctx, dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open PostgreSQL connection: %w", err)
+	}
+	if _, err := pool.Exec(ctx, postgresSchema); err != nil {
+		pool.Close()
+		return nil, fmt.Errorf("failed to create PostgreSQL export schema: %w", err)
+	}
+	return &PostgresSink{
+		pool:           pool,
+		deploymentUUID: deploymentUUID,
+		deploymentName: deploymentName,
+		now:            time.Now,
+	}, nil
+}
+
+// Close closes the connection pool
+func (s *PostgresSink) Close() error {
+	s.pool.Close()
+	return nil
+}
+
+// Export writes the current results of src to the database
+func (s *PostgresSink) Export(ctx context.Context, src ResultSource) (ExportStats, error) {
+	var stats ExportStats
+
+	images, err := src.GetReportImages()
+	if err != nil {
+		return stats, err
+	}
+	containerRows, err := src.GetReportContainers()
+	if err != nil {
+		return stats, err
+	}
+	hashes := make(map[string]string, len(images))
+	for _, img := range images {
+		vulns, err := src.GetReportVulnerabilities(img.ID)
+		if err != nil {
+			return stats, err
+		}
+		hashes[img.Digest] = vulnerabilitiesHash(vulns)
+	}
+
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return stats, fmt.Errorf("failed to begin export transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	exported, err := s.exportedHashes(ctx, tx)
+	if err != nil {
+		return stats, err
+	}
+	now := s.now().UTC()
+
+	batch := &pgx.Batch{}
+	for _, img := range images {
+		s.queueImage(batch, img, hashes[img.Digest], now)
+	}
+	if err := tx.SendBatch(ctx, batch).Close(); err != nil {
+		return stats, fmt.Errorf("failed to export images: %w", err)
+	}
+	stats.Images = len(images)
+	if err := s.markRemovedImages(ctx, tx, now); err != nil {
+		return stats, err
+	}
+
+	if err := s.replaceContainers(ctx, tx, containerRows); err != nil {
+		return stats, err
+	}
+	stats.Containers = len(containerRows)
+
+	for _, img := range changedImages(images, hashes, exported) {
+		vulns, err := src.GetReportVulnerabilities(img.ID)
+		if err != nil {
+			return stats, err
+		}
+		if err := s.replaceVulnerabilities(ctx, tx, img.Digest, vulns); err != nil {
+			return stats, err
+		}
+		stats.RescannedImages++
+		stats.Vulnerabilities += len(vulns)
+	}
+
+	lastID, err := s.lastExportedHistoryID(ctx, tx)
+	if err != nil {
+		return stats, err
+	}
+	for {
+		rows, err := src.GetScanHistoryRows(lastID, historyBatchSize)
+		if err != nil {
+			return stats, err
+		}
+		if len(rows) == 0 {
+			break
+		}
+		if err := s.copyScanHistory(ctx, tx, rows); err != nil {
+			return stats, err
+		}
+		stats.ScanHistory += len(rows)
+		lastID = rows[len(rows)-1].ID
+	}
+
+	if _, err := tx.Exec(ctx, `
+		INSERT INTO b2s_exports (deployment_uuid, exported_at, images, containers, rescanned_images, vulnerabilities, scan_history)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+	`, s.deploymentUUID, now, stats.Images, stats.Containers, stats.RescannedImages, stats.Vulnerabilities, stats.ScanHistory); err != nil {
+		return stats, fmt.Errorf("failed to record export: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return stats, fmt.Errorf("failed to commit export: %w", err)
+	}
+	return stats, nil
+}
+
+// queueImage queues the upsert of an image seen running at now, with the
+// hash of its exported vulnerabilities
+func (s *PostgresSink) queueImage(batch *pgx.Batch, img ReportImage, hash string, now time.Time) {
+	batch.Queue(`
+		INSERT INTO b2s_images (
+			deployment_uuid, deployment_name, digest, image_references, os_name, os_version, status,
+			vulns_scanned_at, vulns_hash, containers, critical, high, medium, low, negligible, unknown,
+			running, first_seen, last_seen
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, TRUE, $17, $17)
+		ON CONFLICT (deployment_uuid, digest) DO UPDATE SET
+			deployment_name = EXCLUDED.deployment_name,
+			image_references = EXCLUDED.image_references,
+			os_name = EXCLUDED.os_name,
+			os_version = EXCLUDED.os_version,
+			status = EXCLUDED.status,
+			vulns_scanned_at = EXCLUDED.vulns_scanned_at,
+			vulns_hash = EXCLUDED.vulns_hash,
+			containers = EXCLUDED.containers,
+			critical = EXCLUDED.critical,
+			high = EXCLUDED.high,
+			medium = EXCLUDED.medium,
+			low = EXCLUDED.low,
+			negligible = EXCLUDED.negligible,
+			unknown = EXCLUDED.unknown,
+			running = TRUE,
+			last_seen = EXCLUDED.last_seen
+	`, s.deploymentUUID, s.deploymentName, img.Digest, img.References, img.OSName, img.OSVersion, img.Status,
+		parseScanTime(img.VulnsScannedAt), hash, img.Containers, img.Critical, img.High, img.Medium, img.Low,
+		img.Negligible, img.Unknown, now)
+}
+
+// markRemovedImages keeps the images not seen since now as history
+func (s *PostgresSink) markRemovedImages(ctx context.Context, tx pgx.Tx, now time.Time) error {
+	if _, err := tx.Exec(ctx, `
+		UPDATE b2s_images SET running = FALSE
+		WHERE deployment_uuid = $1 AND last_seen < $2 AND running
+	`, s.deploymentUUID, now); err != nil {
+		return fmt.Errorf("failed to mark removed images: %w", err)
+	}
+	return nil
+}
+
+// replaceContainers replaces the exported running containers
+func (s *PostgresSink) replaceContainers(ctx context.Context, tx pgx.Tx, containerRows []ReportContainer) error {
+	if _, err := tx.Exec(ctx, `DELETE FROM b2s_containers WHERE deployment_uuid = $1`, s.deploymentUUID); err != nil {
+		return fmt.Errorf("failed to clear exported containers: %w", err)
+	}
+	if _, err := tx.CopyFrom(ctx, pgx.Identifier{"b2s_containers"},
+		[]string{"deployment_uuid", "namespace", "pod", "name", "reference", "node_name", "digest"},
+		pgx.CopyFromSlice(len(containerRows), func(i int) ([]any, error) {
+			c := containerRows[i]
+			return []any{s.deploymentUUID, c.Namespace, c.Pod, c.Name, c.Reference, c.NodeName, c.Digest}, nil
+		})); err != nil {
+		return fmt.Errorf("failed to export containers: %w", err)
+	}
+	return nil
+}
+
+// replaceVulnerabilities replaces the exported vulnerabilities of an image
+func (s *PostgresSink) replaceVulnerabilities(ctx context.Context, tx pgx.Tx, digest string, vulns []ReportVulnerability) error {
+	if _, err := tx.Exec(ctx, `DELETE FROM b2s_vulnerabilities WHERE deployment_uuid = $1 AND digest = $2`,
+		s.deploymentUUID, digest); err != nil {
+		return fmt.Errorf("failed to clear exported vulnerabilities of %s: %w", digest, err)
+	}
+	if _, err := tx.CopyFrom(ctx, pgx.Identifier{"b2s_vulnerabilities"},
+		[]string{"deployment_uuid", "digest", "cve_id", "aliases", "severity", "package_name", "package_version",
+			"package_type", "fix_status", "fixed_version", "risk", "known_exploited"},
+		pgx.CopyFromSlice(len(vulns), func(i int) ([]any, error) {
+			v := vulns[i]
+			return []any{s.deploymentUUID, digest, v.CVEID, v.Aliases, v.Severity, v.PackageName, v.PackageVersion,
+				v.PackageType, v.FixStatus, v.FixedVersion, v.Risk, v.KnownExploited}, nil
+		})); err != nil {
+		return fmt.Errorf("failed to export vulnerabilities of %s: %w", digest, err)
+	}
+	return nil
+}
+
+// copyScanHistory appends scan_history rows
+func (s *PostgresSink) copyScanHistory(ctx context.Context, tx pgx.Tx, rows []ScanHistoryRow) error {
+	if _, err := tx.CopyFrom(ctx, pgx.Identifier{"b2s_scan_history"},
+		[]string{"deployment_uuid", "id", "digest", "namespaces", "running", "scanned_at", "grype_db_built",
+			"critical", "high", "medium", "low", "negligible", "unknown", "total", "known_exploited", "risk_score"},
+		pgx.CopyFromSlice(len(rows), func(i int) ([]any, error) {
+			r := rows[i]
+			return []any{s.deploymentUUID, r.ID, r.Digest, r.Namespaces, r.Running, parseScanTime(r.ScannedAt),
+				parseScanTime(r.GrypeDBBuilt), r.Critical, r.High, r.Medium, r.Low, r.Negligible, r.Unknown,
+				r.Total, r.KnownExploited, r.RiskScore}, nil
+		})); err != nil {
+		return fmt.Errorf("failed to export scan history: %w", err)
+	}
+	return nil
+}
+
+// lastExportedHistoryID returns the ID of the last scan_history row exported
+// by this deployment, 0 if none was
+func (s *PostgresSink) lastExportedHistoryID(ctx context.Context, q pgQuerier) (int64, error) {
+	var id int64
+	if err := q.QueryRow(ctx, `SELECT COALESCE(MAX(id), 0) FROM b2s_scan_history WHERE deployment_uuid = $1`,
+		s.deploymentUUID).Scan(&id); err != nil {
+		return 0, fmt.Errorf("failed to query exported scan history: %w", err)
+	}
+	return id, nil
+}
+
+// exportedHashes returns the vulnerability hash of every image exported by
+// this deployment
+func (s *PostgresSink) exportedHashes(ctx context.Context, q pgQuerier) (map[string]string, error) {
+	rows, err := q.Query(ctx, `SELECT digest, vulns_hash FROM b2s_images WHERE deployment_uuid = $1`, s.deploymentUUID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query exported images: %w", err)
+	}
+	defer rows.Close()
+
+	exported := make(map[string]string)
+	for rows.Next() {
+		var digest, hash string
+		if err := rows.Scan(&digest, &hash); err != nil {
+			return nil, fmt.Errorf("failed to scan exported image: %w", err)
+		}
+		exported[digest] = hash
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate exported images: %w", err)
+	}
+	return exported, nil
+}
+
+// changedImages returns the images whose vulnerabilities must be written:
+// those never exported and those whose vulnerability hash differs from the
+// exported one. hashes and exported map digests to the current and exported
+// hashes.
+func changedImages(images []ReportImage, hashes, exported map[string]string) []ReportImage {
+	var result []ReportImage
+	for _, img := range images {
+		if previous, ok := exported[img.Digest]; !ok || previous != hashes[img.Digest] {
+			result = append(result, img)
+		}
+	}
+	return result
+}
+
+// vulnerabilitiesHash returns the SHA-256 of the exported columns of an
+// image's vulnerability rows, in the order GetReportVulnerabilities returns them
+func vulnerabilitiesHash(vulns []ReportVulnerability) string {
+	h := sha256.New()
+	for _, v := range vulns {
+		_, _ = fmt.Fprintf(h, "%s\x00%s\x00%s\x00%s\x00%s\x00%s\x00%s\x00%s\x00%g\x00%t\n",
+			v.CVEID, v.Aliases, v.Severity, v.PackageName, v.PackageVersion, v.PackageType,
+			v.FixStatus, v.FixedVersion, v.Risk, v.KnownExploited)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// parseScanTime parses a stored timestamp, nil if it is empty or invalid
+func parseScanTime(value string) *time.Time {
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return nil
+	}
+	return &t
+}
@@ -0,0 +1,190 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// DefaultMigrateBatchSize is the number of images or scan_history rows a
+// migration commits at a time
+const DefaultMigrateBatchSize = 100
+
+// MigrationSource is the local store a migration to PostgreSQL copies: the
+// results and the whole scan history. *DB implements it.
+type MigrationSource interface {
+	ResultSource
+	GetScanHistoryRange(maxID int64) (count int, minID int64, err error)
+}
+
+// MigrateProgress is the progress of one table of a migration
+type MigrateProgress struct {
+	Table  string
+	Copied int // rows copied or found up to date so far
+	Total  int
+}
+
+// MigrateCount compares the rows of one table in the local store with the
+// rows PostgreSQL holds for the deployment after a migration
+type MigrateCount struct {
+	Table  string
+	Source int
+	Target int
+}
+
+// Matches reports whether both sides hold the same number of rows
+func (c MigrateCount) Matches() bool {
+	return c.Source == c.Target
+}
+
+// Migrate copies the results and the whole scan history of src to the export
+// tables, moving an existing deployment's data into PostgreSQL before the
+// export job keeps it up to date. Unlike Export it commits every batchSize
+// images or scan_history rows, so a large store is copied without one long
+// transaction and an interrupted migration resumes where it stopped: images
+// whose exported vulnerability hash matches are skipped and the scan history
+// continues after the last exported row. progress is called after every
+// batch. It returns the row counts of both sides of every table.
+func (s *PostgresSink) Migrate(ctx context.Context, src MigrationSource, batchSize int, progress func(MigrateProgress)) ([]MigrateCount, error) {
+	if batchSize <= 0 {
+		batchSize = DefaultMigrateBatchSize
+	}
+
+	images, err := src.GetReportImages()
+	if err != nil {
+		return nil, err
+	}
+	containerRows, err := src.GetReportContainers()
+	if err != nil {
+		return nil, err
+	}
+	hashes := make(map[string]string, len(images))
+	vulnerabilities := 0
+	for _, img := range images {
+		vulns, err := src.GetReportVulnerabilities(img.ID)
+		if err != nil {
+			return nil, err
+		}
+		hashes[img.Digest] = vulnerabilitiesHash(vulns)
+		vulnerabilities += len(vulns)
+	}
+	exported, err := s.exportedHashes(ctx, s.pool)
+	if err != nil {
+		return nil, err
+	}
+	now := s.now().UTC()
+
+	for start := 0; start < len(images); start += batchSize {
+		batch := images[start:min(start+batchSize, len(images))]
+		if err := s.migrateImages(ctx, src, batch, hashes, exported, now); err != nil {
+			return nil, err
+		}
+		progress(MigrateProgress{Table: "b2s_images", Copied: start + len(batch), Total: len(images)})
+	}
+
+	if err := pgx.BeginFunc(ctx, s.pool, func(tx pgx.Tx) error {
+		if err := s.markRemovedImages(ctx, tx, now); err != nil {
+			return err
+		}
+		return s.replaceContainers(ctx, tx, containerRows)
+	}); err != nil {
+		return nil, err
+	}
+	progress(MigrateProgress{Table: "b2s_containers", Copied: len(containerRows), Total: len(containerRows)})
+
+	lastID, err := s.lastExportedHistoryID(ctx, s.pool)
+	if err != nil {
+		return nil, err
+	}
+	total, _, err := src.GetScanHistoryRange(math.MaxInt64)
+	if err != nil {
+		return nil, err
+	}
+	copied, _, err := src.GetScanHistoryRange(lastID)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		rows, err := src.GetScanHistoryRows(lastID, batchSize)
+		if err != nil {
+			return nil, err
+		}
+		if len(rows) == 0 {
+			break
+		}
+		if err := pgx.BeginFunc(ctx, s.pool, func(tx pgx.Tx) error {
+			return s.copyScanHistory(ctx, tx, rows)
+		}); err != nil {
+			return nil, err
+		}
+		lastID = rows[len(rows)-1].ID
+		copied += len(rows)
+		progress(MigrateProgress{Table: "b2s_scan_history", Copied: copied, Total: max(total, copied)})
+	}
+
+	return s.migrateCounts(ctx, src, len(images), len(containerRows), vulnerabilities, lastID)
+}
+
+// migrateImages upserts a batch of images and rewrites the vulnerabilities of
+// those whose exported hash differs, in one transaction
+func (s *PostgresSink) migrateImages(ctx context.Context, src ResultSource, images []ReportImage,
+	hashes, exported map[string]string, now time.Time) error {
+	return pgx.BeginFunc(ctx, s.pool, func(tx pgx.Tx) error {
+		batch := &pgx.Batch{}
+		for _, img := range images {
+			s.queueImage(batch, img, hashes[img.Digest], now)
+		}
+		if err := tx.SendBatch(ctx, batch).Close(); err != nil {
+			return fmt.Errorf("failed to migrate images: %w", err)
+		}
+		for _, img := range changedImages(images, hashes, exported) {
+			vulns, err := src.GetReportVulnerabilities(img.ID)
+			if err != nil {
+				return err
+			}
+			if err := s.replaceVulnerabilities(ctx, tx, img.Digest, vulns); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// migrateCounts returns the row counts of both sides of a migration whose
+// scan history was copied up to lastHistoryID. Scan history pruned from the
+// local store before the migration is not counted on either side.
+func (s *PostgresSink) migrateCounts(ctx context.Context, src MigrationSource, images, containers, vulnerabilities int,
+	lastHistoryID int64) ([]MigrateCount, error) {
+	history, minHistoryID, err := src.GetScanHistoryRange(lastHistoryID)
+	if err != nil {
+		return nil, err
+	}
+	counts := []MigrateCount{
+		{Table: "b2s_images", Source: images},
+		{Table: "b2s_containers", Source: containers},
+		{Table: "b2s_vulnerabilities", Source: vulnerabilities},
+		{Table: "b2s_scan_history", Source: history},
+	}
+	queries := []struct {
+		sql  string
+		args []any
+	}{
+		{`SELECT COUNT(*) FROM b2s_images WHERE deployment_uuid = $1 AND running`, nil},
+		{`SELECT COUNT(*) FROM b2s_containers WHERE deployment_uuid = $1`, nil},
+		{`SELECT COUNT(*) FROM b2s_vulnerabilities v
+			JOIN b2s_images i ON i.deployment_uuid = v.deployment_uuid AND i.digest = v.digest
+			WHERE v.deployment_uuid = $1 AND i.running`, nil},
+		{`SELECT COUNT(*) FROM b2s_scan_history WHERE deployment_uuid = $1 AND id BETWEEN $2 AND $3`,
+			[]any{minHistoryID, lastHistoryID}},
+	}
+	for i, q := range queries {
+		args := append([]any{s.deploymentUUID}, q.args...)
+		if err := s.pool.QueryRow(ctx, q.sql, args...).Scan(&counts[i].Target); err != nil {
+			return nil, fmt.Errorf("failed to count %s: %w", counts[i].Table, err)
+		}
+	}
+	return counts, nil
+}
@@ -0,0 +1,94 @@
+package database
+
+import (
+	"fmt"
+	"time"
+)
+
+// ImageProvenance is the build provenance of an image read from its registry
+type ImageProvenance struct {
+	BuilderID string `json:"builder_id"` // builder that produced the image, as attested
+	SLSALevel int    `json:"slsa_level"` // SLSA build level the provenance supports (1-3)
+	Source    string `json:"source"`     // attestation layout it was found in (cosign, buildkit)
+}
+
+// ProvenanceCandidate is an image whose provenance is due to be checked and a
+// reference locating its repository
+type ProvenanceCandidate struct {
+	Digest    string
+	Reference string
+}
+
+// GetImagesForProvenanceCheck returns up to limit scanned images whose
+// provenance was never checked or last checked before checkedBefore, never
+// checked first. Images without a reference (e.g. imported by digest only)
+// are skipped, since their registry is unknown.
+func (db *DB) GetImagesForProvenanceCheck(checkedBefore time.Time, limit int) ([]ProvenanceCandidate, error) {
+	cutoff := formatTimestamp(checkedBefore)
+	rows, err := db.conn.Query(`
+		SELECT images.digest,
+		       COALESCE((SELECT MIN(reference) FROM containers WHERE image_id = images.id),
+		                images.last_reference, images.ad_hoc_reference) AS reference
+		FROM images
+		WHERE images.status = ?
+		  AND images.deleted_at IS NULL
+		  AND (images.provenance_checked_at IS NULL OR images.provenance_checked_at < ?)
+		  AND reference IS NOT NULL AND reference != ''
+		ORDER BY images.provenance_checked_at IS NOT NULL, images.provenance_checked_at, images.id
+		LIMIT ?
+	`, StatusCompleted.String(), cutoff, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query images for provenance check: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var candidates []ProvenanceCandidate
+	for rows.Next() {
+		var c ProvenanceCandidate
+		if err := rows.Scan(&c.Digest, &c.Reference); err != nil {
+			return nil, fmt.Errorf("failed to scan provenance candidate: %w", err)
+		}
+		candidates = append(candidates, c)
+	}
+	return candidates, rows.Err()
+}
+
+// SetImageProvenance records the outcome of a provenance check. A nil
+// provenance records that the image has none (level 0). A non-empty checkErr
+// records a failed check: the previous result is kept and the image is
+// checked again once it is due.
+func (db *DB) SetImageProvenance(digest string, provenance *ImageProvenance, checkErr string) error {
+	done := db.beginWrite("set_image_provenance")
+	defer done()
+
+	var err error
+	switch {
+	case checkErr != "":
+		_, err = db.conn.Exec(`
+			UPDATE images
+			SET provenance_error = ?, provenance_checked_at = ` + sqlNow + `
+			WHERE digest = ?
+		`, checkErr, digest)
+	case provenance == nil:
+		_, err = db.conn.Exec(`
+			UPDATE images
+			SET provenance_builder = NULL, provenance_slsa_level = 0, provenance_source = NULL,
+			    provenance_error = NULL, provenance_checked_at = ` + sqlNow + `
+			WHERE digest = ?
+		`, digest)
+	default:
+		_, err = db.conn.Exec(`
+			UPDATE images
+			SET provenance_builder = ?, provenance_slsa_level = ?, provenance_source = ?,
+			    provenance_error = NULL, provenance_checked_at = ` + sqlNow + `
+			WHERE digest = ?
+		`, provenance.BuilderID, provenance.SLSALevel, provenance.Source, digest)
+	}
+	if err != nil {
+		exitOnCorruption(err)
+		return fmt.Errorf("failed to record image provenance: %w", err)
+	}
+
+	db.notifyWrite()
+	return nil
+}
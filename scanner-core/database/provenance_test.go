@@ -0,0 +1,92 @@
+package database
+
+import (
+	"database/sql"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/bvboe/b2s-go/scanner-core/containers"
+)
+
+func TestImageProvenance(t *testing.T) {
+	db, err := New(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("failed to create database: %v", err)
+	}
+	defer func() { _ = Close(db) }()
+
+	for _, digest := range []string{"sha256:signed", "sha256:none", "sha256:pending"} {
+		if _, err := db.AddContainer(containers.Container{
+			ID:    containers.ContainerID{Namespace: "default", Pod: digest, Name: "app"},
+			Image: containers.ImageID{Reference: "ghcr.io/team/" + digest[7:] + ":1", Digest: digest},
+		}); err != nil {
+			t.Fatalf("AddContainer failed: %v", err)
+		}
+	}
+	for _, digest := range []string{"sha256:signed", "sha256:none"} {
+		if err := db.UpdateStatus(digest, StatusCompleted, ""); err != nil {
+			t.Fatalf("UpdateStatus failed: %v", err)
+		}
+	}
+
+	due := func() []string {
+		t.Helper()
+		candidates, err := db.GetImagesForProvenanceCheck(time.Now().Add(-24*time.Hour), 10)
+		if err != nil {
+			t.Fatalf("GetImagesForProvenanceCheck failed: %v", err)
+		}
+		var digests []string
+		for _, c := range candidates {
+			digests = append(digests, c.Digest)
+			if c.Reference != "ghcr.io/team/"+c.Digest[7:]+":1" {
+				t.Errorf("%s: reference = %q", c.Digest, c.Reference)
+			}
+		}
+		return digests
+	}
+
+	// Only scanned images are checked
+	if got := due(); len(got) != 2 {
+		t.Fatalf("expected 2 images due, got %v", got)
+	}
+
+	if err := db.SetImageProvenance("sha256:signed", &ImageProvenance{BuilderID: "https://github.com/slsa-framework/slsa-github-generator/", SLSALevel: 3, Source: "cosign"}, ""); err != nil {
+		t.Fatalf("SetImageProvenance failed: %v", err)
+	}
+	if err := db.SetImageProvenance("sha256:none", nil, ""); err != nil {
+		t.Fatalf("SetImageProvenance failed: %v", err)
+	}
+	if got := due(); len(got) != 0 {
+		t.Errorf("expected no images due after checking, got %v", got)
+	}
+
+	// A failed check keeps the previous result
+	if err := db.SetImageProvenance("sha256:signed", nil, "UNAUTHORIZED"); err != nil {
+		t.Fatalf("SetImageProvenance failed: %v", err)
+	}
+	var builder sql.NullString
+	var level sql.NullInt64
+	var checkErr sql.NullString
+	if err := db.conn.QueryRow(`SELECT provenance_builder, provenance_slsa_level, provenance_error FROM images WHERE digest = ?`,
+		"sha256:signed").Scan(&builder, &level, &checkErr); err != nil {
+		t.Fatal(err)
+	}
+	if builder.String == "" || level.Int64 != 3 || checkErr.String != "UNAUTHORIZED" {
+		t.Errorf("after failed check: builder %q, level %d, error %q", builder.String, level.Int64, checkErr.String)
+	}
+	if err := db.conn.QueryRow(`SELECT provenance_slsa_level FROM images WHERE digest = ?`, "sha256:none").Scan(&level); err != nil {
+		t.Fatal(err)
+	}
+	if !level.Valid || level.Int64 != 0 {
+		t.Errorf("image without provenance: level = %v, want 0", level)
+	}
+
+	// Images are due again after the recheck interval
+	if _, err := db.conn.Exec(`UPDATE images SET provenance_checked_at = datetime('now', '-2 days') WHERE digest = ?`, "sha256:none"); err != nil {
+		t.Fatal(err)
+	}
+	if got := due(); len(got) != 1 || got[0] != "sha256:none" {
+		t.Errorf("expected sha256:none due again, got %v", got)
+	}
+}
@@ -52,6 +52,9 @@ type ImageDetails struct {
 	CreatedAt string `json:"created_at"`
 	UpdatedAt string `json:"updated_at"`
 
+	// Last status transition, for debugging images that stop making progress
+	StatusChangedAt string `json:"status_changed_at"`
+
 	// Deprecated: Use Status instead
 	ScanStatus          string `json:"scan_status,omitempty"`
 	VulnerabilityStatus string `json:"vulnerability_status,omitempty"`
@@ -66,6 +69,9 @@ type ImageDetails struct {
 	LowCount           int    `json:"low_count"`
 	OSName             string `json:"os_name,omitempty"`
 	OSVersion          string `json:"os_version,omitempty"`
+
+	// Set when the image was soft-deleted by the cleanup job
+	DeletedAt string `json:"deleted_at,omitempty"`
 }
 
 // GetPackagesByImage returns all packages for a specific image
@@ -180,15 +186,16 @@ func (db *DB) GetImageDetails(digest string) (interface{}, error) {
 	err := db.conn.QueryRow(`
 		SELECT
 			img.id, img.digest, img.status,
-			img.created_at, img.updated_at, img.sbom_scanned_at,
+			img.created_at, img.updated_at,
+			COALESCE(img.status_changed_at, img.created_at), img.sbom_scanned_at,
 			(SELECT COUNT(*) FROM image_packages WHERE image_id = img.id),
 			COALESCE(img.os_name, ''),
 			COALESCE(img.os_version, '')
 		FROM images img
 		WHERE img.digest = ?
 	`, digest).Scan(&details.ID, &details.Digest, &details.Status,
-		&details.CreatedAt, &details.UpdatedAt, &scannedAt, &details.PackageCount,
-		&osName, &osVersion)
+		&details.CreatedAt, &details.UpdatedAt, &details.StatusChangedAt, &scannedAt,
+		&details.PackageCount, &osName, &osVersion)
 
 	if err == sql.ErrNoRows {
 		return nil, fmt.Errorf("image not found")
@@ -251,26 +258,48 @@ func (db *DB) GetImageDetails(digest string) (interface{}, error) {
 	return &details, nil
 }
 
-// GetAllImageDetails returns detailed information for all images
-func (db *DB) GetAllImageDetails() (interface{}, error) {
+// GetAllImageDetails returns detailed information for all images.
+// Package and vulnerability counts are aggregated per image before being joined,
+// so the query reads each table once instead of producing a packages x
+// vulnerabilities row product per image. Soft-deleted images are only
+// included when includeDeleted is set.
+func (db *DB) GetAllImageDetails(includeDeleted bool) (interface{}, error) {
 	rows, err := db.conn.Query(`
+		WITH pkg AS (
+			SELECT image_id, COUNT(*) AS package_count
+			FROM image_packages
+			GROUP BY image_id
+		),
+		vuln AS (
+			SELECT
+				image_id,
+				SUM(CASE WHEN LOWER(severity) = 'critical' THEN count ELSE 0 END) AS critical,
+				SUM(CASE WHEN LOWER(severity) = 'high' THEN count ELSE 0 END) AS high,
+				SUM(CASE WHEN LOWER(severity) = 'medium' THEN count ELSE 0 END) AS medium,
+				SUM(CASE WHEN LOWER(severity) IN ('low', 'negligible') THEN count ELSE 0 END) AS low,
+				SUM(count) AS total
+			FROM image_vulnerabilities
+			GROUP BY image_id
+		)
 		SELECT
 			img.id, img.digest, img.status,
-			img.created_at, img.updated_at, img.sbom_scanned_at,
-			COUNT(DISTINCT p.id) as package_count,
+			img.created_at, img.updated_at,
+			COALESCE(img.status_changed_at, img.created_at), img.sbom_scanned_at,
+			COALESCE(pkg.package_count, 0),
 			COALESCE(img.os_name, ''),
 			COALESCE(img.os_version, ''),
-			COALESCE(SUM(CASE WHEN LOWER(v.severity) = 'critical' THEN v.count ELSE 0 END), 0),
-			COALESCE(SUM(CASE WHEN LOWER(v.severity) = 'high' THEN v.count ELSE 0 END), 0),
-			COALESCE(SUM(CASE WHEN LOWER(v.severity) = 'medium' THEN v.count ELSE 0 END), 0),
-			COALESCE(SUM(CASE WHEN LOWER(v.severity) IN ('low', 'negligible') THEN v.count ELSE 0 END), 0),
-			COALESCE(SUM(v.count), 0) as vuln_count
+			COALESCE(vuln.critical, 0),
+			COALESCE(vuln.high, 0),
+			COALESCE(vuln.medium, 0),
+			COALESCE(vuln.low, 0),
+			COALESCE(vuln.total, 0),
+			img.deleted_at
 		FROM images img
-		LEFT JOIN image_packages p ON p.image_id = img.id
-		LEFT JOIN image_vulnerabilities v ON v.image_id = img.id
-		GROUP BY img.id
+		LEFT JOIN pkg ON pkg.image_id = img.id
+		LEFT JOIN vuln ON vuln.image_id = img.id
+		WHERE ? OR img.deleted_at IS NULL
 		ORDER BY img.created_at DESC
-	`)
+	`, includeDeleted)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query images: %w", err)
 	}
@@ -283,15 +312,15 @@ func (db *DB) GetAllImageDetails() (interface{}, error) {
 	var images []ImageDetails
 	for rows.Next() {
 		var details ImageDetails
-		var scannedAt sql.NullString
+		var scannedAt, deletedAt sql.NullString
 		var osName, osVersion sql.NullString
 
 		err := rows.Scan(
 			&details.ID, &details.Digest, &details.Status,
-			&details.CreatedAt, &details.UpdatedAt, &scannedAt,
+			&details.CreatedAt, &details.UpdatedAt, &details.StatusChangedAt, &scannedAt,
 			&details.PackageCount, &osName, &osVersion,
 			&details.CriticalCount, &details.HighCount, &details.MediumCount,
-			&details.LowCount, &details.VulnerabilityCount,
+			&details.LowCount, &details.VulnerabilityCount, &deletedAt,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan image row: %w", err)
@@ -306,9 +335,15 @@ func (db *DB) GetAllImageDetails() (interface{}, error) {
 		if osVersion.Valid {
 			details.OSVersion = osVersion.String
 		}
+		if deletedAt.Valid {
+			details.DeletedAt = deletedAt.String
+		}
 
 		images = append(images, details)
 	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate images: %w", err)
+	}
 
 	return images, nil
 }
@@ -318,6 +353,9 @@ func (db *DB) GetAllImageDetails() (interface{}, error) {
 // by notifyWrite() after every write operation, so this call never touches the DB.
 // dataType is accepted for interface compatibility but is not used.
 func (db *DB) GetLastUpdatedTimestamp(_ string) (string, error) {
+	if db.readOnly {
+		db.seedLastUpdated()
+	}
 	db.cachesMu.RLock()
 	sig := db.lastUpdatedSig
 	db.cachesMu.RUnlock()
@@ -330,6 +368,11 @@ type FilterOptions struct {
 	OSNames      []string
 	VulnStatuses []string
 	PackageTypes []string
+	Registries   []string
+	// Attribute values synced from the CMDB
+	Applications    []string
+	Owners          []string
+	Classifications []string
 }
 
 // GetFilterOptions returns image filter options, serving from in-memory cache
@@ -338,15 +381,19 @@ func (db *DB) GetFilterOptions() (*FilterOptions, error) {
 	db.cachesMu.RLock()
 	cached := db.filterOpts
 	db.cachesMu.RUnlock()
-	if cached != nil {
+	if cached != nil && !db.readOnly {
 		return cached, nil
 	}
 
 	opts := &FilterOptions{
-		Namespaces:   make([]string, 0),
-		OSNames:      make([]string, 0),
-		VulnStatuses: make([]string, 0),
-		PackageTypes: make([]string, 0),
+		Namespaces:      make([]string, 0),
+		OSNames:         make([]string, 0),
+		VulnStatuses:    make([]string, 0),
+		PackageTypes:    make([]string, 0),
+		Registries:      make([]string, 0),
+		Applications:    make([]string, 0),
+		Owners:          make([]string, 0),
+		Classifications: make([]string, 0),
 	}
 
 	type querySpec struct {
@@ -358,6 +405,10 @@ func (db *DB) GetFilterOptions() (*FilterOptions, error) {
 		{"SELECT DISTINCT os_name FROM images WHERE os_name IS NOT NULL AND os_name != '' ORDER BY os_name", &opts.OSNames},
 		{"SELECT DISTINCT fix_status FROM image_vulnerabilities WHERE fix_status IS NOT NULL AND fix_status != '' ORDER BY fix_status", &opts.VulnStatuses},
 		{"SELECT DISTINCT type FROM image_packages WHERE type IS NOT NULL AND type != '' ORDER BY type", &opts.PackageTypes},
+		{"SELECT DISTINCT registry FROM containers WHERE registry != '' ORDER BY registry", &opts.Registries},
+		{"SELECT DISTINCT application_id FROM cmdb_metadata WHERE application_id != '' ORDER BY application_id", &opts.Applications},
+		{"SELECT DISTINCT owner FROM cmdb_metadata WHERE owner != '' ORDER BY owner", &opts.Owners},
+		{"SELECT DISTINCT data_classification FROM cmdb_metadata WHERE data_classification != '' ORDER BY data_classification", &opts.Classifications},
 	}
 
 	for _, q := range queries {
@@ -483,7 +534,6 @@ type ContainerVulnerability struct {
 	Risk           float64 `json:"risk"`
 }
 
-
 // LoadMetricStaleness loads the metric staleness data from the database
 // Returns empty string if no data exists
 func (db *DB) LoadMetricStaleness(key string) (string, error) {
@@ -505,7 +555,7 @@ func (db *DB) SaveMetricStaleness(key string, data string) error {
 	defer done()
 	_, err := db.conn.Exec(`
 		INSERT OR REPLACE INTO app_state (key, data, updated_at)
-		VALUES (?, ?, CURRENT_TIMESTAMP)
+		VALUES (?, ?, `+sqlNow+`)
 	`, key, data)
 	if err != nil {
 		exitOnCorruption(err)
@@ -878,7 +928,7 @@ func (db *DB) SaveGrypeDBTimestamp(t time.Time) error {
 	defer done()
 	_, err := db.conn.Exec(`
 		INSERT OR REPLACE INTO app_state (key, data, updated_at)
-		VALUES (?, ?, CURRENT_TIMESTAMP)
+		VALUES (?, ?, `+sqlNow+`)
 	`, grypeDBTimestampKey, t.Format(time.RFC3339))
 	if err != nil {
 		exitOnCorruption(err)
@@ -908,7 +958,7 @@ func (db *DB) GetImagesNeedingRescan(currentGrypeDBBuilt time.Time) ([]Container
 		FROM images i
 		INNER JOIN containers c ON c.image_id = i.id
 		WHERE i.status IN (?, ?)
-		  AND (i.sbom_compressed IS NOT NULL OR (i.sbom IS NOT NULL AND i.sbom != ''))
+		  AND (i.sbom_hash IS NOT NULL OR i.sbom_compressed IS NOT NULL OR (i.sbom IS NOT NULL AND i.sbom != ''))
 		  AND (i.grype_db_built IS NULL OR i.grype_db_built < ?)
 		ORDER BY i.created_at DESC
 	`, StatusCompleted.String(), StatusVulnScanFailed.String(), currentTimestamp)
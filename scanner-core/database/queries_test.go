@@ -1,7 +1,9 @@
 package database
 
 import (
+	"fmt"
 	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
@@ -945,3 +947,101 @@ func TestGetImagesNeedingRescan_ZeroTimestamp(t *testing.T) {
 		t.Errorf("Expected nil for zero timestamp, got %d images", len(images))
 	}
 }
+
+// seedImageDetails inserts images with the given number of packages and
+// vulnerabilities each, bypassing SBOM/vulnerability parsing.
+func seedImageDetails(tb testing.TB, db *DB, images, packages, vulns int) {
+	tb.Helper()
+	tx, err := db.conn.Begin()
+	if err != nil {
+		tb.Fatalf("failed to begin transaction: %v", err)
+	}
+	severities := []string{"Critical", "High", "Medium", "Low", "Negligible", "Unknown"}
+	for i := 0; i < images; i++ {
+		res, err := tx.Exec(`INSERT INTO images (digest, status) VALUES (?, 'completed')`, fmt.Sprintf("sha256:%064d", i))
+		if err != nil {
+			tb.Fatalf("failed to insert image: %v", err)
+		}
+		imageID, _ := res.LastInsertId()
+		for p := 0; p < packages; p++ {
+			if _, err := tx.Exec(`INSERT INTO image_packages (image_id, name, version, type) VALUES (?, ?, '1.0', 'deb')`,
+				imageID, fmt.Sprintf("pkg-%d", p)); err != nil {
+				tb.Fatalf("failed to insert package: %v", err)
+			}
+		}
+		for v := 0; v < vulns; v++ {
+			if _, err := tx.Exec(`INSERT INTO image_vulnerabilities (image_id, cve_id, package_name, package_version, package_type, severity, count)
+				VALUES (?, ?, 'pkg-0', '1.0', 'deb', ?, 2)`,
+				imageID, fmt.Sprintf("CVE-2024-%04d", v), severities[v%len(severities)]); err != nil {
+				tb.Fatalf("failed to insert vulnerability: %v", err)
+			}
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		tb.Fatalf("failed to commit seed data: %v", err)
+	}
+}
+
+// TestGetAllImageDetails_Counts verifies that package and vulnerability counts
+// are not multiplied by each other when an image has both.
+func TestGetAllImageDetails_Counts(t *testing.T) {
+	db, err := New(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("Failed to create database: %v", err)
+	}
+	defer func() { _ = Close(db) }()
+
+	seedImageDetails(t, db, 2, 3, 6)
+	if _, err := db.conn.Exec(`INSERT INTO images (digest, status) VALUES ('sha256:empty', 'pending')`); err != nil {
+		t.Fatalf("failed to insert empty image: %v", err)
+	}
+
+	result, err := db.GetAllImageDetails(false)
+	if err != nil {
+		t.Fatalf("GetAllImageDetails() error = %v", err)
+	}
+	images := result.([]ImageDetails)
+	if len(images) != 3 {
+		t.Fatalf("expected 3 images, got %d", len(images))
+	}
+
+	for _, img := range images {
+		if img.Digest == "sha256:empty" {
+			if img.PackageCount != 0 || img.VulnerabilityCount != 0 {
+				t.Errorf("empty image has counts %+v", img)
+			}
+			continue
+		}
+		if img.PackageCount != 3 {
+			t.Errorf("%s: PackageCount = %d, want 3", img.Digest, img.PackageCount)
+		}
+		// Six vulnerabilities with count 2, one per severity
+		if img.CriticalCount != 2 || img.HighCount != 2 || img.MediumCount != 2 || img.LowCount != 4 {
+			t.Errorf("%s: severity counts = %d/%d/%d/%d, want 2/2/2/4",
+				img.Digest, img.CriticalCount, img.HighCount, img.MediumCount, img.LowCount)
+		}
+		if img.VulnerabilityCount != 12 {
+			t.Errorf("%s: VulnerabilityCount = %d, want 12", img.Digest, img.VulnerabilityCount)
+		}
+	}
+}
+
+func BenchmarkGetAllImageDetails(b *testing.B) {
+	for _, n := range []int{100, 1000, 3000} {
+		b.Run(fmt.Sprintf("images=%d", n), func(b *testing.B) {
+			db, err := New(filepath.Join(b.TempDir(), "bench.db"))
+			if err != nil {
+				b.Fatalf("Failed to create database: %v", err)
+			}
+			defer func() { _ = Close(db) }()
+			seedImageDetails(b, db, n, 50, 30)
+
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, err := db.GetAllImageDetails(false); err != nil {
+					b.Fatalf("GetAllImageDetails() error = %v", err)
+				}
+			}
+		})
+	}
+}
@@ -34,17 +34,96 @@ func (db *DB) ExecuteQuery(query string) (*QueryResult, error) {
 		strings.HasPrefix(trimmed, "PRAGMA") ||
 		strings.HasPrefix(trimmed, "EXPLAIN")
 
+	var result *QueryResult
+	var err error
 	if isSelect {
-		return db.executeSelectQuery(query, start)
+		result, err = db.executeSelectQuery(query, nil, start)
+	} else {
+		result, err = db.executeWriteQuery(query, start)
 	}
-	return db.executeWriteQuery(query, start)
+	db.logSlowQuery(query, time.Since(start), err)
+	return result, err
+}
+
+// SetSlowQueryThreshold sets the duration above which queries run through
+// ExecuteQuery (the dashboard and API views) are logged with their SQL, to
+// find the views that load the database. 0 disables the slow query log.
+func (db *DB) SetSlowQueryThreshold(threshold time.Duration) {
+	db.slowQueryThreshold.Store(int64(threshold))
+}
+
+// logSlowQuery logs a query that took longer than the slow query threshold
+func (db *DB) logSlowQuery(query string, duration time.Duration, err error) {
+	threshold := time.Duration(db.slowQueryThreshold.Load())
+	if threshold <= 0 || duration < threshold {
+		return
+	}
+	slowQueries.Add(1)
+
+	attrs := []any{"duration", duration, "threshold", threshold, "query", strings.Join(strings.Fields(query), " ")}
+	if err != nil {
+		attrs = append(attrs, "error", err)
+	}
+	log.Warn("slow query", attrs...)
 }
 
 // executeSelectQuery handles SELECT queries and returns row data.
-func (db *DB) executeSelectQuery(query string, start time.Time) (*QueryResult, error) {
-	rows, err := db.conn.Query(query)
+func (db *DB) executeSelectQuery(query string, args []interface{}, start time.Time) (*QueryResult, error) {
+	var columns []string
+	var results []map[string]interface{}
+	err := db.streamRows(query, args,
+		func(cols []string) error {
+			columns = cols
+			return nil
+		},
+		func(row map[string]interface{}) error {
+			results = append(results, row)
+			return nil
+		})
 	if err != nil {
-		return nil, fmt.Errorf("query execution failed: %w", err)
+		return nil, err
+	}
+
+	// Log completion with row count and duration
+	duration := time.Since(start)
+	log.Debug("query completed", "rows_returned", len(results), "duration", duration)
+
+	return &QueryResult{
+		Columns: columns,
+		Rows:    results,
+	}, nil
+}
+
+// StreamQuery executes a read-only SQL query and calls row for each result
+// row as it is read, so large result sets are never held in memory. columns
+// is called once with the column names (in database order) before the first
+// row. An error returned by a callback stops the query and is returned.
+//
+// Like ExecuteQuery, this method does NOT validate the SQL query; values
+// taken from requests are passed as args and bound to its placeholders.
+func (db *DB) StreamQuery(query string, columns func([]string) error, row func(map[string]interface{}) error, args ...interface{}) error {
+	log.Debug("streaming query", "query", query, "args", len(args))
+	start := time.Now()
+
+	rowCount := 0
+	err := db.streamRows(query, args, columns, func(r map[string]interface{}) error {
+		rowCount++
+		return row(r)
+	})
+	duration := time.Since(start)
+	db.logSlowQuery(query, duration, err)
+	if err == nil {
+		log.Debug("query completed", "rows_streamed", rowCount, "duration", duration)
+	}
+	return err
+}
+
+// streamRows runs a SELECT query with args bound to its placeholders and
+// passes each row, as a map of column name to value, to row.
+func (db *DB) streamRows(query string, args []interface{}, columns func([]string) error, row func(map[string]interface{}) error) error {
+	rows, err := db.conn.Query(query, args...)
+	if err != nil {
+		return fmt.Errorf("query execution failed: %w", err)
 	}
 	defer func() {
 		if err := rows.Close(); err != nil {
@@ -54,56 +133,50 @@ func (db *DB) executeSelectQuery(query string, start time.Time) (*QueryResult, e
 	}()
 
 	// Get column names (preserves database order)
-	columns, err := rows.Columns()
+	cols, err := rows.Columns()
 	if err != nil {
-		return nil, fmt.Errorf("failed to get columns: %w", err)
+		return fmt.Errorf("failed to get columns: %w", err)
+	}
+	if err := columns(cols); err != nil {
+		return err
 	}
-
-	// Build result set
-	var results []map[string]interface{}
 
 	for rows.Next() {
 		// Create slice for scanning
-		values := make([]interface{}, len(columns))
-		valuePtrs := make([]interface{}, len(columns))
+		values := make([]interface{}, len(cols))
+		valuePtrs := make([]interface{}, len(cols))
 		for i := range values {
 			valuePtrs[i] = &values[i]
 		}
 
 		// Scan row into value pointers
 		if err := rows.Scan(valuePtrs...); err != nil {
-			return nil, fmt.Errorf("failed to scan row: %w", err)
+			return fmt.Errorf("failed to scan row: %w", err)
 		}
 
 		// Build map for this row
-		row := make(map[string]interface{})
-		for i, col := range columns {
+		result := make(map[string]interface{}, len(cols))
+		for i, col := range cols {
 			val := values[i]
 
 			// Convert []byte to string for better JSON serialization
 			if b, ok := val.([]byte); ok {
-				row[col] = string(b)
+				result[col] = string(b)
 			} else {
-				row[col] = val
+				result[col] = val
 			}
 		}
 
-		results = append(results, row)
+		if err := row(result); err != nil {
+			return err
+		}
 	}
 
 	// Check for errors from iteration
 	if err := rows.Err(); err != nil {
-		return nil, fmt.Errorf("error iterating rows: %w", err)
+		return fmt.Errorf("error iterating rows: %w", err)
 	}
-
-	// Log completion with row count and duration
-	duration := time.Since(start)
-	log.Debug("query completed", "rows_returned", len(results), "duration", duration)
-
-	return &QueryResult{
-		Columns: columns,
-		Rows:    results,
-	}, nil
+	return nil
 }
 
 // executeWriteQuery handles INSERT/UPDATE/DELETE queries and returns rows affected.
@@ -134,3 +207,15 @@ func (db *DB) executeWriteQuery(query string, start time.Time) (*QueryResult, er
 func (db *DB) ExecuteReadOnlyQuery(query string) (*QueryResult, error) {
 	return db.ExecuteQuery(query)
 }
+
+// ExecuteReadOnlyQueryArgs executes a SELECT query with args bound to its
+// placeholders (? or ?NNN) and returns results with column order preserved.
+// API handlers use it so values taken from requests never become part of the
+// SQL text.
+func (db *DB) ExecuteReadOnlyQueryArgs(query string, args ...interface{}) (*QueryResult, error) {
+	log.Debug("executing query", "query", query, "args", len(args))
+	start := time.Now()
+	result, err := db.executeSelectQuery(query, args, start)
+	db.logSlowQuery(query, time.Since(start), err)
+	return result, err
+}
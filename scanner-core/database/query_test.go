@@ -0,0 +1,121 @@
+package database
+
+import (
+	"bytes"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestExecuteQuerySlowQueryLog(t *testing.T) {
+	db, err := New(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("Failed to create database: %v", err)
+	}
+	defer func() { _ = Close(db) }()
+
+	before := slowQueries.Load()
+
+	// Disabled by default
+	if _, err := db.ExecuteReadOnlyQuery("SELECT COUNT(*) FROM images"); err != nil {
+		t.Fatalf("ExecuteReadOnlyQuery() error = %v", err)
+	}
+	if got := slowQueries.Load() - before; got != 0 {
+		t.Errorf("slow queries = %d with the log disabled, want 0", got)
+	}
+
+	// Every query is slower than 1ns
+	db.SetSlowQueryThreshold(time.Nanosecond)
+	if _, err := db.ExecuteReadOnlyQuery("SELECT COUNT(*) FROM images"); err != nil {
+		t.Fatalf("ExecuteReadOnlyQuery() error = %v", err)
+	}
+	if got := slowQueries.Load() - before; got != 1 {
+		t.Errorf("slow queries = %d, want 1", got)
+	}
+
+	var buf bytes.Buffer
+	WriteOpMetrics(&buf)
+	if !strings.Contains(buf.String(), "bjorn2scan_db_slow_queries_total ") {
+		t.Errorf("expected slow query counter in metrics output:\n%s", buf.String())
+	}
+
+	db.SetSlowQueryThreshold(time.Hour)
+	if _, err := db.ExecuteReadOnlyQuery("SELECT COUNT(*) FROM images"); err != nil {
+		t.Fatalf("ExecuteReadOnlyQuery() error = %v", err)
+	}
+	if got := slowQueries.Load() - before; got != 1 {
+		t.Errorf("slow queries = %d after a fast query, want 1", got)
+	}
+}
+
+func TestStreamQuery(t *testing.T) {
+	db, err := New(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("Failed to create database: %v", err)
+	}
+	defer func() { _ = Close(db) }()
+
+	var columns []string
+	var values []interface{}
+	err = db.StreamQuery("SELECT 1 AS n, 'a' AS s UNION ALL SELECT 2, 'b' ORDER BY n",
+		func(cols []string) error {
+			columns = cols
+			return nil
+		},
+		func(row map[string]interface{}) error {
+			values = append(values, row["s"])
+			return nil
+		})
+	if err != nil {
+		t.Fatalf("StreamQuery() error = %v", err)
+	}
+	if strings.Join(columns, ",") != "n,s" {
+		t.Errorf("columns = %v, want [n s]", columns)
+	}
+	if len(values) != 2 || values[0] != "a" || values[1] != "b" {
+		t.Errorf("streamed values = %v, want [a b]", values)
+	}
+
+	// A callback error stops the query
+	stop := errors.New("client went away")
+	rows := 0
+	err = db.StreamQuery("SELECT 1 UNION ALL SELECT 2",
+		func([]string) error { return nil },
+		func(map[string]interface{}) error {
+			rows++
+			return stop
+		})
+	if !errors.Is(err, stop) || rows != 1 {
+		t.Errorf("StreamQuery() = %v after %d rows, want callback error after 1 row", err, rows)
+	}
+}
+
+func TestExecuteReadOnlyQueryArgs(t *testing.T) {
+	db, err := New(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("Failed to create database: %v", err)
+	}
+	defer func() { _ = Close(db) }()
+
+	// Numbered placeholders may repeat, and quotes in values need no escaping
+	result, err := db.ExecuteReadOnlyQueryArgs("SELECT ?1 AS a, ?2 AS b, ?1 || ?2 AS ab", "it's", "x")
+	if err != nil {
+		t.Fatalf("ExecuteReadOnlyQueryArgs() error = %v", err)
+	}
+	if len(result.Rows) != 1 || result.Rows[0]["a"] != "it's" || result.Rows[0]["ab"] != "it'sx" {
+		t.Errorf("unexpected result: %+v", result.Rows)
+	}
+
+	var values []interface{}
+	err = db.StreamQuery("SELECT ? AS v",
+		func([]string) error { return nil },
+		func(row map[string]interface{}) error {
+			values = append(values, row["v"])
+			return nil
+		}, "streamed")
+	if err != nil || len(values) != 1 || values[0] != "streamed" {
+		t.Errorf("StreamQuery() with args = %v, %v; want [streamed]", values, err)
+	}
+}
@@ -0,0 +1,55 @@
+package database
+
+import (
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/bvboe/b2s-go/scanner-core/containers"
+)
+
+func TestOpenReadOnly(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.db")
+	if _, err := OpenReadOnly(path); err == nil {
+		t.Error("OpenReadOnly() of a missing database succeeded")
+	}
+
+	writer, err := New(path)
+	if err != nil {
+		t.Fatalf("Failed to create database: %v", err)
+	}
+	defer func() { _ = Close(writer) }()
+	reader, err := OpenReadOnly(path)
+	if err != nil {
+		t.Fatalf("OpenReadOnly() error = %v", err)
+	}
+	defer func() { _ = Close(reader) }()
+
+	before, _ := reader.GetLastUpdatedTimestamp("")
+	if _, err := reader.GetFilterOptions(); err != nil {
+		t.Fatalf("GetFilterOptions() error = %v", err)
+	}
+
+	if _, err := writer.AddContainer(containers.Container{
+		ID:    containers.ContainerID{Namespace: "web", Pod: "nginx-1", Name: "nginx"},
+		Image: containers.ImageID{Reference: "nginx:1.25", Digest: "sha256:nginx"},
+	}); err != nil {
+		t.Fatalf("AddContainer() error = %v", err)
+	}
+
+	// Writes of the other process are visible without a notification
+	if after, _ := reader.GetLastUpdatedTimestamp(""); after == before {
+		t.Errorf("GetLastUpdatedTimestamp() = %q after a write, want a change", after)
+	}
+	opts, err := reader.GetFilterOptions()
+	if err != nil {
+		t.Fatalf("GetFilterOptions() error = %v", err)
+	}
+	if !slices.Contains(opts.Namespaces, "web") {
+		t.Errorf("filter namespaces = %v, want web", opts.Namespaces)
+	}
+
+	if _, err := reader.SetCVEAnnotation("CVE-2024-0001", "mitigated", ""); err == nil {
+		t.Error("write to a read-only database succeeded")
+	}
+}
@@ -0,0 +1,123 @@
+package database
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/bvboe/b2s-go/scanner-core/containers"
+)
+
+// Image target types
+const (
+	TargetTypeContainer = "container" // discovered running, or requested ad-hoc
+	TargetTypeRegistry  = "registry"  // first found by the registry crawl
+)
+
+// RegistryImage is an image tracked by the registry crawl
+type RegistryImage struct {
+	Reference  string `json:"reference"`
+	Digest     string `json:"digest"`
+	TargetType string `json:"target_type"`
+	Running    bool   `json:"running"` // whether a container runs the image
+	Status     Status `json:"status"`
+	CrawledAt  string `json:"crawled_at"`
+	ExpiresAt  string `json:"expires_at"`
+	Critical   int    `json:"critical"`
+	High       int    `json:"high"`
+	Medium     int    `json:"medium"`
+	Low        int    `json:"low"`
+	Negligible int    `json:"negligible"`
+	Unknown    int    `json:"unknown"`
+}
+
+// TrackRegistryImage records that the registry crawl found image, creating it
+// as a registry target if it is unknown. The image is kept until at least
+// expires even when no container runs it. Returns the scan status of the image
+// and whether it was new.
+func (db *DB) TrackRegistryImage(image containers.ImageID, expires time.Time) (Status, bool, error) {
+	now := time.Now().UTC()
+
+	done := db.beginWrite("track_registry_image")
+	defer done()
+	tx, err := db.conn.Begin()
+	if err != nil {
+		exitOnCorruption(err)
+		return "", false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	_, created, err := db.getOrCreateImageTx(tx, image)
+	if err != nil {
+		exitOnCorruption(err)
+		return "", false, err
+	}
+
+	// Images already known from the cluster keep their target type
+	var status string
+	err = tx.QueryRow(`
+		UPDATE images
+		SET target_type = CASE WHEN ? THEN ? ELSE target_type END,
+		    registry_reference = ?,
+		    registry_crawled_at = ?,
+		    registry_expires_at = MAX(COALESCE(registry_expires_at, ''), ?)
+		WHERE digest = ?
+		RETURNING status
+	`, created, TargetTypeRegistry, image.Reference, formatTimestamp(now), formatTimestamp(expires), image.Digest).Scan(&status)
+	if err != nil {
+		exitOnCorruption(err)
+		return "", false, fmt.Errorf("failed to flag registry image: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		exitOnCorruption(err)
+		return "", false, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	db.notifyWrite()
+	return Status(status), created, nil
+}
+
+// GetRegistryImages returns the images found by the registry crawl whose
+// retention has not ended, by reference, with their vulnerability counts
+func (db *DB) GetRegistryImages() ([]RegistryImage, error) {
+	rows, err := db.conn.Query(`
+		SELECT images.registry_reference,
+		       images.digest,
+		       images.target_type,
+		       EXISTS (SELECT 1 FROM containers c WHERE c.image_id = images.id),
+		       images.status,
+		       COALESCE(images.registry_crawled_at, ''),
+		       COALESCE(images.registry_expires_at, ''),
+		       COALESCE(SUM(CASE WHEN v.severity = 'Critical' THEN v.count END), 0),
+		       COALESCE(SUM(CASE WHEN v.severity = 'High' THEN v.count END), 0),
+		       COALESCE(SUM(CASE WHEN v.severity = 'Medium' THEN v.count END), 0),
+		       COALESCE(SUM(CASE WHEN v.severity = 'Low' THEN v.count END), 0),
+		       COALESCE(SUM(CASE WHEN v.severity = 'Negligible' THEN v.count END), 0),
+		       COALESCE(SUM(CASE WHEN v.severity NOT IN ('Critical', 'High', 'Medium', 'Low', 'Negligible') THEN v.count END), 0)
+		FROM images
+		LEFT JOIN image_vulnerabilities v ON v.image_id = images.id
+		WHERE images.registry_reference IS NOT NULL
+		  AND images.deleted_at IS NULL
+		  AND images.registry_expires_at > ` + sqlNow + `
+		GROUP BY images.id
+		ORDER BY images.registry_reference, images.digest
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query registry images: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	images := []RegistryImage{}
+	for rows.Next() {
+		var img RegistryImage
+		var status string
+		if err := rows.Scan(&img.Reference, &img.Digest, &img.TargetType, &img.Running, &status,
+			&img.CrawledAt, &img.ExpiresAt, &img.Critical, &img.High, &img.Medium, &img.Low,
+			&img.Negligible, &img.Unknown); err != nil {
+			return nil, fmt.Errorf("failed to scan registry image: %w", err)
+		}
+		img.Status = Status(status)
+		images = append(images, img)
+	}
+	return images, rows.Err()
+}
@@ -0,0 +1,76 @@
+package database
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/bvboe/b2s-go/scanner-core/containers"
+)
+
+// TestTrackRegistryImage verifies that crawled images are registry targets
+// unless the cluster knew them first, and that they survive orphan cleanup
+// until their retention ends.
+func TestTrackRegistryImage(t *testing.T) {
+	db, err := New(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("failed to create database: %v", err)
+	}
+	defer func() { _ = Close(db) }()
+
+	crawled := containers.ImageID{Reference: "registry.example.com/team/app:v2", Digest: "sha256:crawled"}
+	expired := containers.ImageID{Reference: "registry.example.com/team/app:v1", Digest: "sha256:expired"}
+	running := containers.ImageID{Reference: "registry.example.com/team/api:v3", Digest: "sha256:running"}
+
+	if _, err := db.AddContainer(containers.Container{
+		ID:    containers.ContainerID{Namespace: "default", Pod: "api", Name: "api"},
+		Image: running,
+	}); err != nil {
+		t.Fatalf("AddContainer failed: %v", err)
+	}
+
+	status, created, err := db.TrackRegistryImage(crawled, time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatalf("TrackRegistryImage failed: %v", err)
+	}
+	if !created || status != StatusPending {
+		t.Errorf("TrackRegistryImage() = %v, %v; want %v, true", status, created, StatusPending)
+	}
+	if _, created, err := db.TrackRegistryImage(running, time.Now().Add(time.Hour)); err != nil || created {
+		t.Fatalf("TrackRegistryImage(running) created = %v, err = %v; want false, nil", created, err)
+	}
+	if _, _, err := db.TrackRegistryImage(expired, time.Now().Add(-time.Hour)); err != nil {
+		t.Fatalf("TrackRegistryImage failed: %v", err)
+	}
+
+	// A later crawl keeps the longer retention
+	if _, created, err := db.TrackRegistryImage(crawled, time.Now().Add(-time.Hour)); err != nil || created {
+		t.Fatalf("TrackRegistryImage again created = %v, err = %v; want false, nil", created, err)
+	}
+
+	images, err := db.GetRegistryImages()
+	if err != nil {
+		t.Fatalf("GetRegistryImages failed: %v", err)
+	}
+	if len(images) != 2 {
+		t.Fatalf("expected 2 unexpired registry images, got %+v", images)
+	}
+	byDigest := map[string]RegistryImage{}
+	for _, img := range images {
+		byDigest[img.Digest] = img
+	}
+	if img := byDigest[crawled.Digest]; img.TargetType != TargetTypeRegistry || img.Running || img.Reference != crawled.Reference {
+		t.Errorf("unexpected crawled image: %+v", img)
+	}
+	if img := byDigest[running.Digest]; img.TargetType != TargetTypeContainer || !img.Running {
+		t.Errorf("expected running image to stay a container target: %+v", img)
+	}
+
+	stats, err := db.CleanupOrphanedImages()
+	if err != nil {
+		t.Fatalf("CleanupOrphanedImages failed: %v", err)
+	}
+	if stats.ImagesSoftDeleted != 1 {
+		t.Errorf("expected only the expired image to be deleted, got %d", stats.ImagesSoftDeleted)
+	}
+}
@@ -0,0 +1,181 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// ReportImage is an image row of the offline report
+type ReportImage struct {
+	ID             int64
+	Digest         string
+	References     string // Comma-separated references of containers running the image
+	OSName         string
+	OSVersion      string
+	Status         string
+	VulnsScannedAt string
+	Containers     int
+	Critical       int
+	High           int
+	Medium         int
+	Low            int
+	Negligible     int
+	Unknown        int
+	Metadata       CMDBAttributes // Attributes synced from the CMDB
+}
+
+// ReportContainer is a running container row of the offline report
+type ReportContainer struct {
+	Namespace string
+	Pod       string
+	Name      string
+	Reference string
+	NodeName  string
+	Digest    string
+}
+
+// ReportVulnerability is a vulnerability finding of an image in the offline report
+type ReportVulnerability struct {
+	CVEID          string
+	Aliases        string // Comma-separated related identifiers (e.g. CVEs behind an RHSA advisory)
+	Annotation     string // Cluster-wide analyst note on the vulnerability (see CVEAnnotation)
+	Severity       string
+	PackageName    string
+	PackageVersion string
+	PackageType    string
+	FixStatus      string
+	FixedVersion   string
+	Risk           float64
+	KnownExploited bool
+}
+
+// GetReportImages returns all images that are not soft-deleted with their
+// unique vulnerability counts per severity, most severe first
+func (db *DB) GetReportImages() ([]ReportImage, error) {
+	cmdb, err := db.loadCMDBIndex()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := db.conn.Query(`
+		SELECT
+			img.id, img.digest, img.status,
+			COALESCE(img.os_name, ''), COALESCE(img.os_version, ''), COALESCE(img.vulns_scanned_at, ''),
+			COALESCE((SELECT GROUP_CONCAT(reference, ', ') FROM (SELECT DISTINCT reference FROM containers WHERE image_id = img.id ORDER BY reference)), ''),
+			(SELECT COUNT(*) FROM containers WHERE image_id = img.id),
+			COUNT(DISTINCT CASE WHEN v.severity = 'Critical' THEN v.cve_id END),
+			COUNT(DISTINCT CASE WHEN v.severity = 'High' THEN v.cve_id END),
+			COUNT(DISTINCT CASE WHEN v.severity = 'Medium' THEN v.cve_id END),
+			COUNT(DISTINCT CASE WHEN v.severity = 'Low' THEN v.cve_id END),
+			COUNT(DISTINCT CASE WHEN v.severity = 'Negligible' THEN v.cve_id END),
+			COUNT(DISTINCT CASE WHEN v.severity NOT IN ('Critical', 'High', 'Medium', 'Low', 'Negligible') OR v.severity IS NULL THEN v.cve_id END) AS unknown,
+			COALESCE((SELECT GROUP_CONCAT(DISTINCT namespace) FROM containers WHERE image_id = img.id), ''),
+			COALESCE(img.oci_source, ''), COALESCE(img.oci_version, ''), COALESCE(img.oci_vendor, '')
+		FROM images img
+		LEFT JOIN image_vulnerabilities v ON v.image_id = img.id
+		WHERE img.deleted_at IS NULL
+		GROUP BY img.id
+		ORDER BY 9 DESC, 10 DESC, 11 DESC, 12 DESC, img.digest
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query report images: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var images []ReportImage
+	for rows.Next() {
+		var img ReportImage
+		var namespaces, ociSource, ociVersion, ociVendor string
+		if err := rows.Scan(&img.ID, &img.Digest, &img.Status, &img.OSName, &img.OSVersion, &img.VulnsScannedAt,
+			&img.References, &img.Containers,
+			&img.Critical, &img.High, &img.Medium, &img.Low, &img.Negligible, &img.Unknown,
+			&namespaces, &ociSource, &ociVersion, &ociVendor); err != nil {
+			return nil, fmt.Errorf("failed to scan report image: %w", err)
+		}
+		img.Metadata = cmdb.resolve(strings.Split(namespaces, ","), map[string]string{
+			ociSourceLabel: ociSource, ociVersionLabel: ociVersion, ociVendorLabel: ociVendor})
+		images = append(images, img)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate report images: %w", err)
+	}
+	return images, nil
+}
+
+// GetReportContainers returns all running containers ordered by namespace, pod and name
+func (db *DB) GetReportContainers() ([]ReportContainer, error) {
+	rows, err := db.conn.Query(`
+		SELECT c.namespace, c.pod, c.name, c.reference, COALESCE(c.node_name, ''), img.digest
+		FROM containers c
+		JOIN images img ON c.image_id = img.id
+		ORDER BY c.namespace, c.pod, c.name
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query report containers: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var result []ReportContainer
+	for rows.Next() {
+		var c ReportContainer
+		if err := rows.Scan(&c.Namespace, &c.Pod, &c.Name, &c.Reference, &c.NodeName, &c.Digest); err != nil {
+			return nil, fmt.Errorf("failed to scan report container: %w", err)
+		}
+		result = append(result, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate report containers: %w", err)
+	}
+	return result, nil
+}
+
+// GetReportVulnerabilities returns the vulnerability findings of an image,
+// most severe first
+func (db *DB) GetReportVulnerabilities(imageID int64) ([]ReportVulnerability, error) {
+	rows, err := db.conn.Query(`
+		SELECT
+			v.cve_id,
+			COALESCE((SELECT GROUP_CONCAT(alias, ', ') FROM (
+				SELECT alias_id AS alias FROM vulnerability_aliases WHERE vulnerability_id = v.cve_id
+				UNION
+				SELECT vulnerability_id FROM vulnerability_aliases WHERE alias_id = v.cve_id
+			)), ''),
+			COALESCE((SELECT note FROM cve_annotations WHERE cve_id = v.cve_id), ''),
+			COALESCE(v.severity, ''), COALESCE(v.package_name, ''), COALESCE(v.package_version, ''),
+			COALESCE(v.package_type, ''), COALESCE(v.fix_status, ''), COALESCE(v.fixed_version, ''),
+			COALESCE(v.risk, 0), COALESCE(v.known_exploited, 0)
+		FROM image_vulnerabilities v
+		WHERE v.image_id = ?
+		ORDER BY
+			CASE v.severity
+				WHEN 'Critical' THEN 1
+				WHEN 'High' THEN 2
+				WHEN 'Medium' THEN 3
+				WHEN 'Low' THEN 4
+				WHEN 'Negligible' THEN 5
+				ELSE 6
+			END,
+			v.cve_id, v.package_name
+	`, imageID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query report vulnerabilities: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var result []ReportVulnerability
+	for rows.Next() {
+		var v ReportVulnerability
+		var knownExploited sql.NullInt64
+		if err := rows.Scan(&v.CVEID, &v.Aliases, &v.Annotation, &v.Severity, &v.PackageName, &v.PackageVersion,
+			&v.PackageType, &v.FixStatus, &v.FixedVersion, &v.Risk, &knownExploited); err != nil {
+			return nil, fmt.Errorf("failed to scan report vulnerability: %w", err)
+		}
+		v.KnownExploited = knownExploited.Int64 > 0
+		result = append(result, v)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate report vulnerabilities: %w", err)
+	}
+	return result, nil
+}
@@ -0,0 +1,175 @@
+package database
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+)
+
+// SBOM documents are stored once per distinct content in sbom_blobs, keyed by
+// the SHA-256 of the uncompressed JSON. images.sbom_hash references the blob
+// and ref_count counts the images referencing it, so images with identical
+// SBOMs share one copy. A blob is deleted as soon as its last reference is
+// dropped (rescan with different content, pruning or purge).
+
+// errSBOMBlobMissing is returned when an image is pointed at a blob that is
+// not stored without the document to store it
+var errSBOMBlobMissing = errors.New("SBOM blob not stored")
+
+// SBOMBlobStats summarizes the shared SBOM storage
+type SBOMBlobStats struct {
+	Blobs       int64 `json:"blobs"`        // distinct SBOM documents stored
+	References  int64 `json:"references"`   // images referencing them
+	StoredBytes int64 `json:"stored_bytes"` // compressed size of the stored documents
+	SavedBytes  int64 `json:"saved_bytes"`  // compressed size saved by sharing documents
+}
+
+// sbomHash returns the content address of an SBOM document
+func sbomHash(sbomJSON []byte) string {
+	sum := sha256.Sum256(sbomJSON)
+	return hex.EncodeToString(sum[:])
+}
+
+// sbomBlobExists reports whether a document with the hash is already stored
+func (db *DB) sbomBlobExists(hash string) (bool, error) {
+	var exists bool
+	err := db.conn.QueryRow(`SELECT EXISTS(SELECT 1 FROM sbom_blobs WHERE hash = ?)`, hash).Scan(&exists)
+	if err != nil {
+		return false, fmt.Errorf("failed to look up SBOM blob: %w", err)
+	}
+	return exists, nil
+}
+
+// storeSBOMBlob points an image at the blob with the hash in its own write,
+// see setImageSBOMBlob
+func (db *DB) storeSBOMBlob(imageID int64, hash string, compressed []byte) error {
+	done := db.beginWrite("store_sbom_blob")
+	defer done()
+
+	tx, err := db.conn.Begin()
+	if err != nil {
+		exitOnCorruption(err)
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if err := setImageSBOMBlob(tx, imageID, hash, compressed); err != nil {
+		exitOnCorruption(err)
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		exitOnCorruption(err)
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// setImageSBOMBlob points an image at the blob with the hash, storing the
+// compressed document if no image references it yet (compressed may be nil
+// when the blob is known to exist). The reference of the image's previous
+// blob is released, deleting that blob if it was the last one. The caller
+// owns the transaction.
+func setImageSBOMBlob(tx *sql.Tx, imageID int64, hash string, compressed []byte) error {
+	var previous sql.NullString
+	if err := tx.QueryRow(`SELECT sbom_hash FROM images WHERE id = ?`, imageID).Scan(&previous); err != nil {
+		return fmt.Errorf("failed to read SBOM reference: %w", err)
+	}
+	if previous.String == hash {
+		if _, err := tx.Exec(`UPDATE images SET sbom_compressed = NULL, sbom = NULL WHERE id = ?`, imageID); err != nil {
+			return fmt.Errorf("failed to update SBOM reference: %w", err)
+		}
+		return nil
+	}
+
+	result, err := tx.Exec(`UPDATE sbom_blobs SET ref_count = ref_count + 1 WHERE hash = ?`, hash)
+	if err != nil {
+		return fmt.Errorf("failed to reference SBOM blob: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		if compressed == nil {
+			return errSBOMBlobMissing
+		}
+		if _, err := tx.Exec(`INSERT INTO sbom_blobs (hash, sbom_compressed, ref_count) VALUES (?, ?, 1)`,
+			hash, compressed); err != nil {
+			return fmt.Errorf("failed to store SBOM blob: %w", err)
+		}
+	}
+	if _, err := tx.Exec(`UPDATE images SET sbom_hash = ?, sbom_compressed = NULL, sbom = NULL WHERE id = ?`,
+		hash, imageID); err != nil {
+		return fmt.Errorf("failed to update SBOM reference: %w", err)
+	}
+	if previous.Valid {
+		return releaseSBOMBlobs(tx, `SELECT ? AS sbom_hash`, previous.String)
+	}
+	return nil
+}
+
+// releaseSBOMBlobs drops one reference per image from the blobs whose hashes
+// the hashQuery subquery returns (one row per referencing image), then deletes
+// the blobs left without references
+func releaseSBOMBlobs(tx *sql.Tx, hashQuery string, args ...any) error {
+	if _, err := tx.Exec(`
+		UPDATE sbom_blobs SET ref_count = ref_count - (
+			SELECT COUNT(*) FROM (`+hashQuery+`) released WHERE released.sbom_hash = sbom_blobs.hash
+		)
+		WHERE hash IN (`+hashQuery+`)
+	`, append(args, args...)...); err != nil {
+		return fmt.Errorf("failed to release SBOM blobs: %w", err)
+	}
+	if _, err := tx.Exec(`DELETE FROM sbom_blobs WHERE ref_count <= 0`); err != nil {
+		return fmt.Errorf("failed to delete unreferenced SBOM blobs: %w", err)
+	}
+	return nil
+}
+
+// VacuumSBOMBlobs recounts the references of every stored SBOM document from
+// images.sbom_hash and deletes the documents no image references. References
+// are maintained as images change, so this only repairs counts that drifted,
+// e.g. after rows were edited by hand. It returns the number of documents
+// deleted.
+func (db *DB) VacuumSBOMBlobs() (int64, error) {
+	done := db.beginWrite("vacuum_sbom_blobs")
+	defer done()
+
+	tx, err := db.conn.Begin()
+	if err != nil {
+		exitOnCorruption(err)
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if _, err := tx.Exec(`
+		UPDATE sbom_blobs SET ref_count = (SELECT COUNT(*) FROM images WHERE images.sbom_hash = sbom_blobs.hash)
+	`); err != nil {
+		exitOnCorruption(err)
+		return 0, fmt.Errorf("failed to recount SBOM blob references: %w", err)
+	}
+	result, err := tx.Exec(`DELETE FROM sbom_blobs WHERE ref_count <= 0`)
+	if err != nil {
+		exitOnCorruption(err)
+		return 0, fmt.Errorf("failed to delete unreferenced SBOM blobs: %w", err)
+	}
+	deleted, _ := result.RowsAffected()
+	if err := tx.Commit(); err != nil {
+		exitOnCorruption(err)
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return deleted, nil
+}
+
+// GetSBOMBlobStats returns the number and size of the stored SBOM documents
+// and how much sharing them saves
+func (db *DB) GetSBOMBlobStats() (*SBOMBlobStats, error) {
+	stats := &SBOMBlobStats{}
+	err := db.conn.QueryRow(`
+		SELECT COUNT(*), COALESCE(SUM(ref_count), 0), COALESCE(SUM(LENGTH(sbom_compressed)), 0),
+		       COALESCE(SUM((ref_count - 1) * LENGTH(sbom_compressed)), 0)
+		FROM sbom_blobs
+	`).Scan(&stats.Blobs, &stats.References, &stats.StoredBytes, &stats.SavedBytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get SBOM blob stats: %w", err)
+	}
+	return stats, nil
+}
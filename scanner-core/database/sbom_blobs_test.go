@@ -0,0 +1,199 @@
+package database
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/bvboe/b2s-go/scanner-core/containers"
+)
+
+// unshareSBOMs moves the SBOM documents back into images.sbom_compressed,
+// the layout before v75, for tests of earlier migrations that read them there
+func unshareSBOMs(t *testing.T, db *DB) {
+	t.Helper()
+	if _, err := db.conn.Exec(`
+		UPDATE images SET sbom_compressed = (SELECT sbom_compressed FROM sbom_blobs WHERE hash = images.sbom_hash),
+		                  sbom_hash = NULL
+		WHERE sbom_hash IS NOT NULL
+	`); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.conn.Exec(`DELETE FROM sbom_blobs`); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSBOMBlobsShareIdenticalDocuments(t *testing.T) {
+	db, err := New(filepath.Join(t.TempDir(), "blobs.db"))
+	if err != nil {
+		t.Fatalf("failed to create database: %v", err)
+	}
+	defer func() { _ = Close(db) }()
+
+	shared := `{"artifacts":[],"source":{"type":"image","id":"shared"}}`
+	other := `{"artifacts":[],"source":{"type":"image","id":"other"}}`
+	digests := []string{"sha256:a", "sha256:b", "sha256:c"}
+	for _, digest := range digests {
+		if _, err := db.AddContainer(containers.Container{
+			ID:    containers.ContainerID{Namespace: "default", Pod: digest, Name: "app"},
+			Image: containers.ImageID{Reference: "app:" + digest, Digest: digest},
+		}); err != nil {
+			t.Fatalf("AddContainer() error = %v", err)
+		}
+		if err := db.StoreSBOM(digest, []byte(shared)); err != nil {
+			t.Fatalf("StoreSBOM() error = %v", err)
+		}
+	}
+
+	blobs := func() map[string]int64 {
+		t.Helper()
+		rows, err := db.conn.Query(`SELECT hash, ref_count FROM sbom_blobs`)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer func() { _ = rows.Close() }()
+		counts := make(map[string]int64)
+		for rows.Next() {
+			var hash string
+			var refs int64
+			if err := rows.Scan(&hash, &refs); err != nil {
+				t.Fatal(err)
+			}
+			counts[hash] = refs
+		}
+		return counts
+	}
+	if got := blobs(); len(got) != 1 || got[sbomHash([]byte(shared))] != 3 {
+		t.Fatalf("blobs = %v, want one shared by 3 images", got)
+	}
+	stats, err := db.GetSBOMBlobStats()
+	if err != nil || stats.Blobs != 1 || stats.References != 3 || stats.SavedBytes != 2*stats.StoredBytes {
+		t.Errorf("GetSBOMBlobStats() = %+v, %v", stats, err)
+	}
+
+	// Storing the same document again keeps one reference per image
+	if err := db.StoreSBOM("sha256:a", []byte(shared)); err != nil {
+		t.Fatalf("StoreSBOM() error = %v", err)
+	}
+	// A rescan with different content moves the reference
+	if err := db.StoreSBOM("sha256:b", []byte(other)); err != nil {
+		t.Fatalf("StoreSBOM() error = %v", err)
+	}
+	if got := blobs(); len(got) != 2 || got[sbomHash([]byte(shared))] != 2 || got[sbomHash([]byte(other))] != 1 {
+		t.Fatalf("blobs after rescan = %v", got)
+	}
+	for digest, want := range map[string]string{"sha256:a": shared, "sha256:b": other, "sha256:c": shared} {
+		if got, err := db.GetSBOM(digest); err != nil || string(got) != want {
+			t.Errorf("GetSBOM(%s) = %q, %v; want %q", digest, got, err, want)
+		}
+	}
+
+	// Pruning one of two images sharing a document frees nothing; pruning
+	// the last reference deletes the document
+	for _, digest := range digests {
+		if err := db.UpdateStatus(digest, StatusCompleted, ""); err != nil {
+			t.Fatalf("UpdateStatus() error = %v", err)
+		}
+	}
+	if _, err := db.conn.Exec(`UPDATE images SET sbom_scanned_at = CASE digest
+		WHEN 'sha256:a' THEN '2024-01-01T00:00:00Z' WHEN 'sha256:b' THEN '2024-01-03T00:00:00Z' ELSE '2024-01-02T00:00:00Z' END`); err != nil {
+		t.Fatal(err)
+	}
+	pruned, err := db.PruneOldestSBOMs(1)
+	if err != nil {
+		t.Fatalf("PruneOldestSBOMs() error = %v", err)
+	}
+	// sha256:a frees nothing, sha256:c frees the shared document
+	if pruned.Images != 2 || pruned.BytesFreed <= 0 {
+		t.Errorf("PruneOldestSBOMs() = %+v, want 2 images and the shared document freed", pruned)
+	}
+	if got := blobs(); len(got) != 1 || got[sbomHash([]byte(other))] != 1 {
+		t.Errorf("blobs after pruning = %v, want only the unshared document", got)
+	}
+
+	// Purging the last image deletes its document
+	if _, err := db.conn.Exec(`DELETE FROM containers`); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.CleanupOrphanedImages(); err != nil {
+		t.Fatalf("CleanupOrphanedImages() error = %v", err)
+	}
+	if _, err := db.PurgeDeletedImages(0); err != nil {
+		t.Fatalf("PurgeDeletedImages() error = %v", err)
+	}
+	if got := blobs(); len(got) != 0 {
+		t.Errorf("blobs after purge = %v, want none", got)
+	}
+}
+
+func TestMigrateToV75MovesSBOMsIntoBlobs(t *testing.T) {
+	db, err := New(filepath.Join(t.TempDir(), "v75.db"))
+	if err != nil {
+		t.Fatalf("failed to create database: %v", err)
+	}
+	defer func() { _ = Close(db) }()
+
+	shared := `{"artifacts":[],"source":{"type":"image"}}`
+	for _, digest := range []string{"sha256:a", "sha256:b"} {
+		if _, err := db.AddContainer(containers.Container{
+			ID:    containers.ContainerID{Namespace: "default", Pod: digest, Name: "app"},
+			Image: containers.ImageID{Reference: "app:" + digest, Digest: digest},
+		}); err != nil {
+			t.Fatalf("AddContainer() error = %v", err)
+		}
+		if err := db.StoreSBOM(digest, []byte(shared)); err != nil {
+			t.Fatalf("StoreSBOM() error = %v", err)
+		}
+	}
+	unshareSBOMs(t, db)
+	if _, err := db.conn.Exec(`UPDATE images SET sbom_compressed = NULL, sbom = '{"legacy":true}' WHERE digest = 'sha256:b'`); err != nil {
+		t.Fatal(err)
+	}
+
+	// Until the online migration moves them, SBOMs are read from images
+	if got, err := db.GetSBOM("sha256:a"); err != nil || string(got) != shared {
+		t.Errorf("GetSBOM() before the move = %q, %v; want %q", got, err, shared)
+	}
+
+	// The schema change is rerunnable, and an interrupted move resumes
+	if err := migrateToV75(db.conn); err != nil {
+		t.Fatalf("migrateToV75() error = %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := db.runOnlineMigrations(ctx, onlineMigrations, 1); err == nil {
+		t.Fatal("expected the cancelled run to return an error")
+	}
+	if err := db.runOnlineMigrations(context.Background(), onlineMigrations, 1); err != nil {
+		t.Fatalf("runOnlineMigrations() error = %v", err)
+	}
+
+	var own, blobs, refs int
+	if err := db.conn.QueryRow(`
+		SELECT (SELECT COUNT(*) FROM images WHERE sbom_compressed IS NOT NULL OR sbom IS NOT NULL),
+		       (SELECT COUNT(*) FROM sbom_blobs), (SELECT SUM(ref_count) FROM sbom_blobs)
+	`).Scan(&own, &blobs, &refs); err != nil {
+		t.Fatal(err)
+	}
+	if own != 0 || blobs != 2 || refs != 2 {
+		t.Errorf("%d images with their own copy, %d blobs, %d references; want 0, 2, 2", own, blobs, refs)
+	}
+	for digest, want := range map[string]string{"sha256:a": shared, "sha256:b": `{"legacy":true}`} {
+		if got, err := db.GetSBOM(digest); err != nil || string(got) != want {
+			t.Errorf("GetSBOM(%s) = %q, %v; want %q", digest, got, err, want)
+		}
+	}
+
+	// Vacuuming repairs drifted reference counts
+	if _, err := db.conn.Exec(`UPDATE sbom_blobs SET ref_count = 5`); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.conn.Exec(`UPDATE images SET sbom_hash = NULL WHERE digest = 'sha256:b'`); err != nil {
+		t.Fatal(err)
+	}
+	deleted, err := db.VacuumSBOMBlobs()
+	if err != nil || deleted != 1 {
+		t.Errorf("VacuumSBOMBlobs() = %d, %v; want 1 deleted", deleted, err)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"database/sql"
 	"encoding/json"
 	"fmt"
+	"strings"
 )
 
 // SyftPackage represents a package from Syft SBOM
@@ -44,8 +45,46 @@ func (p SyftPackage) MarshalJSON() ([]byte, error) {
 
 // SyftImageMetadata represents the image metadata from Syft SBOM
 type SyftImageMetadata struct {
-	Architecture string `json:"architecture"`
-	OS           string `json:"os"`
+	Architecture string            `json:"architecture"`
+	OS           string            `json:"os"`
+	ImageSize    int64             `json:"imageSize"`
+	Layers       []SyftLayer       `json:"layers"`
+	Labels       map[string]string `json:"labels"`
+}
+
+// OCI annotation keys of the image labels recorded per image
+const (
+	ociSourceLabel  = "org.opencontainers.image.source"
+	ociVersionLabel = "org.opencontainers.image.version"
+	ociVendorLabel  = "org.opencontainers.image.vendor"
+)
+
+// ociLabels returns the source repository, version and vendor the image
+// declares in its OCI labels, empty for labels it does not set
+func (m SyftImageMetadata) ociLabels() (source, version, vendor string) {
+	return strings.TrimSpace(m.Labels[ociSourceLabel]),
+		strings.TrimSpace(m.Labels[ociVersionLabel]),
+		strings.TrimSpace(m.Labels[ociVendorLabel])
+}
+
+// SyftLayer represents a layer of the scanned image in Syft SBOM metadata
+type SyftLayer struct {
+	MediaType string `json:"mediaType"`
+	Digest    string `json:"digest"`
+	Size      int64  `json:"size"`
+}
+
+// sizeAndLayers returns the size of the image in bytes and its layer count.
+// The size is the sum of the layer sizes if Syft did not report it. ok is
+// false if the SBOM has no image metadata (e.g. a directory scan).
+func (m SyftImageMetadata) sizeAndLayers() (size int64, layers int, ok bool) {
+	size = m.ImageSize
+	if size == 0 {
+		for _, l := range m.Layers {
+			size += l.Size
+		}
+	}
+	return size, len(m.Layers), size > 0 || len(m.Layers) > 0
 }
 
 // SyftSource represents the source metadata from Syft SBOM
@@ -64,8 +103,9 @@ type SyftSBOM struct {
 // We store the complete raw JSON to preserve ALL fields (current and future) and original field order
 type GrypeMatch struct {
 	// Fields extracted for indexing (not marshaled back)
-	Vulnerability GrypeVulnerability `json:"-"`
-	Artifact      GrypeArtifact      `json:"-"`
+	Vulnerability          GrypeVulnerability `json:"-"`
+	Artifact               GrypeArtifact      `json:"-"`
+	RelatedVulnerabilities []GrypeRelatedVuln `json:"-"`
 
 	// Complete raw JSON with original field order
 	Raw json.RawMessage `json:"-"`
@@ -78,8 +118,9 @@ func (m *GrypeMatch) UnmarshalJSON(data []byte) error {
 
 	// Extract only the fields we need for indexing
 	var temp struct {
-		Vulnerability GrypeVulnerability `json:"vulnerability"`
-		Artifact      GrypeArtifact      `json:"artifact"`
+		Vulnerability          GrypeVulnerability `json:"vulnerability"`
+		Artifact               GrypeArtifact      `json:"artifact"`
+		RelatedVulnerabilities []GrypeRelatedVuln `json:"relatedVulnerabilities"`
 	}
 	if err := json.Unmarshal(data, &temp); err != nil {
 		return err
@@ -87,6 +128,7 @@ func (m *GrypeMatch) UnmarshalJSON(data []byte) error {
 
 	m.Vulnerability = temp.Vulnerability
 	m.Artifact = temp.Artifact
+	m.RelatedVulnerabilities = temp.RelatedVulnerabilities
 	return nil
 }
 
@@ -98,9 +140,11 @@ func (m GrypeMatch) MarshalJSON() ([]byte, error) {
 
 // GrypeArtifact represents the artifact (package) that has a vulnerability
 type GrypeArtifact struct {
-	Name    string `json:"name"`
-	Version string `json:"version"`
-	Type    string `json:"type"`
+	Name      string          `json:"name"`
+	Version   string          `json:"version"`
+	Type      string          `json:"type"`
+	PURL      string          `json:"purl"`
+	Locations []GrypeLocation `json:"locations"`
 }
 
 // GrypeVulnerability represents vulnerability details
@@ -134,9 +178,10 @@ type GrypeKnownExploit struct {
 	CWEs                       []string `json:"cwes"`
 }
 
-// GrypeRelatedVuln represents related vulnerabilities
+// GrypeRelatedVuln represents related vulnerabilities (e.g. the CVEs an RHSA advisory fixes)
 type GrypeRelatedVuln struct {
-	ID string `json:"id"`
+	ID        string `json:"id"`
+	Namespace string `json:"namespace"`
 }
 
 // GrypeFix represents fix information
@@ -310,6 +355,25 @@ func parseSBOMData(db *DB, imageID int64, sbomJSON []byte) error {
 		}
 	}
 
+	// Update image size and layer count if available.
+	if size, layers, ok := sbom.Source.Metadata.sizeAndLayers(); ok {
+		if _, err = tx.Exec(`UPDATE images SET image_size = ?, layer_count = ? WHERE id = ?`,
+			size, layers, imageID); err != nil {
+			exitOnCorruption(err)
+			log.Warn("failed to update images with size info", "error", err)
+		}
+	}
+
+	// Record the OCI labels; an image that sets none gets NULLs so it is
+	// told apart from images whose labels were never read
+	source, version, vendor := sbom.Source.Metadata.ociLabels()
+	if _, err = tx.Exec(`
+		UPDATE images SET oci_source = NULLIF(?, ''), oci_version = NULLIF(?, ''), oci_vendor = NULLIF(?, '')
+		WHERE id = ?`, source, version, vendor, imageID); err != nil {
+		exitOnCorruption(err)
+		log.Warn("failed to update images with OCI labels", "error", err)
+	}
+
 	if err = tx.Commit(); err != nil {
 		done()
 		exitOnCorruption(err)
@@ -336,8 +400,10 @@ func parseVulnerabilityData(db *DB, imageID int64, vulnJSON []byte) error {
 		packageVersion string
 		packageType    string
 	}
-	vulnCounts := make(map[vulnKey]int)
+	// Matches of the same package at different paths are one finding; the
+	// paths are kept as its occurrences
 	vulnInfo := make(map[vulnKey][]GrypeMatch) // Changed to store ALL matches, not just first
+	aliases := make(map[vulnerabilityAlias]struct{})
 
 	for _, match := range doc.Matches {
 		// Get package info from artifact field
@@ -348,10 +414,13 @@ func parseVulnerabilityData(db *DB, imageID int64, vulnJSON []byte) error {
 			packageType:    match.Artifact.Type,
 		}
 
-		vulnCounts[key]++
 		// Store ALL matches for this vulnerability, not just the first one
 		vulnInfo[key] = append(vulnInfo[key], match)
+		collectVulnerabilityAliases(aliases, match.Vulnerability.ID, match.RelatedVulnerabilities)
 	}
+	// Findings a not_affected VEX statement covers are recorded as suppressed
+	// instead of being stored
+	suppressedBy := db.vexFindings(imageID)
 
 	// Write under the write lock.
 	done := db.beginWrite("store_image_vulnerabilities")
@@ -377,6 +446,12 @@ func parseVulnerabilityData(db *DB, imageID int64, vulnJSON []byte) error {
 		exitOnCorruption(err)
 		return fmt.Errorf("failed to delete existing image vulnerabilities: %w", err)
 	}
+	if _, err = tx.Exec(`DELETE FROM vex_suppressions WHERE image_id = ?`, imageID); err != nil {
+		rollback()
+		done()
+		exitOnCorruption(err)
+		return fmt.Errorf("failed to delete existing VEX suppressions: %w", err)
+	}
 
 	// Collect ordered vuln list for deterministic details inserts.
 	type vulnEntry struct {
@@ -384,19 +459,30 @@ func parseVulnerabilityData(db *DB, imageID int64, vulnJSON []byte) error {
 		matches []GrypeMatch
 		// summary fields
 		severity, fixStatus, fixedVersion string
-		count                             int
+		occurrences                       int
 		risk, epssScore, epssPercentile   float64
 		knownExploited                    int
 	}
-	entries := make([]vulnEntry, 0, len(vulnCounts))
-	vulnRows := make([]any, 0, len(vulnCounts)*13)
-	for key, count := range vulnCounts {
-		matches := vulnInfo[key]
-		if len(matches) == 0 {
-			log.Warn("no matches found for vulnerability", "cve_id", key.cveID)
-			continue
-		}
+	entries := make([]vulnEntry, 0, len(vulnInfo))
+	vulnRows := make([]any, 0, len(vulnInfo)*15)
+	var suppressedRows []any
+	for key, matches := range vulnInfo {
 		m := matches[0]
+		if suppressedBy != nil {
+			if s, ok := suppressedBy(key.cveID, matches); ok {
+				severity, _ := db.mapSeverity(m.Vulnerability.Severity)
+				suppressedRows = append(suppressedRows,
+					imageID, key.cveID, key.packageName, key.packageVersion, key.packageType, severity,
+					s.Status, s.Justification, s.ImpactStatement, s.Source, s.DocumentID, formatStatementTime(s.Timestamp),
+				)
+				continue
+			}
+		}
+		locations := make([][]GrypeLocation, len(matches))
+		for i, match := range matches {
+			locations[i] = match.Artifact.Locations
+		}
+		occurrences := len(mergeOccurrences(locations))
 		fixStatus := m.Vulnerability.Fix.State
 		if fixStatus == "" {
 			if len(m.Vulnerability.Fix.Versions) > 0 {
@@ -416,24 +502,37 @@ func parseVulnerabilityData(db *DB, imageID int64, vulnJSON []byte) error {
 		}
 		knownExploited := len(m.Vulnerability.KnownExploited)
 
-		entries = append(entries, vulnEntry{key, matches, m.Vulnerability.Severity, fixStatus, fixedVersion, count, m.Vulnerability.Risk, epssScore, epssPercentile, knownExploited})
+		severity, sourceSeverity := db.mapSeverity(m.Vulnerability.Severity)
+
+		entries = append(entries, vulnEntry{key, matches, severity, fixStatus, fixedVersion, occurrences, m.Vulnerability.Risk, epssScore, epssPercentile, knownExploited})
 		vulnRows = append(vulnRows,
 			imageID, key.cveID, key.packageName, key.packageVersion, key.packageType,
-			m.Vulnerability.Severity, fixStatus, fixedVersion, count,
+			severity, sourceSeverity, fixStatus, fixedVersion, 1, occurrences,
 			m.Vulnerability.Risk, epssScore, epssPercentile, knownExploited,
 		)
 	}
 
-	// Batch INSERT vulnerabilities (13 cols → 50 rows per batch = 650 params).
+	// Batch INSERT vulnerabilities (15 cols → 40 rows per batch = 600 params).
+	// count is 1 per finding, so summing it counts findings, not match locations.
 	if err = batchInsert(tx,
-		`INSERT INTO image_vulnerabilities (image_id, cve_id, package_name, package_version, package_type, severity, fix_status, fixed_version, count, risk, epss_score, epss_percentile, known_exploited)`,
-		vulnRows, 13, 50); err != nil {
+		`INSERT INTO image_vulnerabilities (image_id, cve_id, package_name, package_version, package_type, severity, source_severity, fix_status, fixed_version, count, occurrences, risk, epss_score, epss_percentile, known_exploited)`,
+		vulnRows, 15, 40); err != nil {
 		rollback()
 		done()
 		exitOnCorruption(err)
 		return fmt.Errorf("failed to batch insert image vulnerabilities: %w", err)
 	}
 
+	// Batch INSERT suppressed findings (12 cols → 50 rows per batch = 600 params).
+	if err = batchInsert(tx,
+		`INSERT INTO vex_suppressions (image_id, cve_id, package_name, package_version, package_type, severity, status, justification, impact_statement, source, document_id, statement_timestamp)`,
+		suppressedRows, 12, 50); err != nil {
+		rollback()
+		done()
+		exitOnCorruption(err)
+		return fmt.Errorf("failed to batch insert VEX suppressions: %w", err)
+	}
+
 	// Query back IDs for details inserts.
 	idRows, err := tx.Query(`SELECT id, cve_id, package_name, package_version, package_type FROM image_vulnerabilities WHERE image_id = ?`, imageID)
 	if err != nil {
@@ -484,6 +583,13 @@ func parseVulnerabilityData(db *DB, imageID int64, vulnJSON []byte) error {
 		return fmt.Errorf("failed to batch insert image vulnerability details: %w", err)
 	}
 
+	if err = insertVulnerabilityAliases(tx, aliases); err != nil {
+		rollback()
+		done()
+		exitOnCorruption(err)
+		return fmt.Errorf("failed to insert vulnerability aliases: %w", err)
+	}
+
 	// Update distro info if available.
 	if doc.Distro != nil {
 		if _, err = tx.Exec(`UPDATE images SET os_name = ?, os_version = ? WHERE id = ?`,
@@ -505,7 +611,7 @@ func parseVulnerabilityData(db *DB, imageID int64, vulnJSON []byte) error {
 	go db.rebuildContainerVulnCache()
 
 	log.Info("parsed vulnerabilities",
-		"image_id", imageID, "unique_vulnerabilities", len(entries))
+		"image_id", imageID, "unique_vulnerabilities", len(entries), "vex_suppressed", len(suppressedRows)/12)
 	return nil
 }
 
@@ -515,8 +621,9 @@ func (db *DB) ParseAndStoreImageData(imageID int64) error {
 	var sbomCompressed, vulnCompressed []byte
 	var sbomRaw, vulnRaw sql.NullString
 	err := db.conn.QueryRow(`
-		SELECT sbom_compressed, sbom, vulnerabilities_compressed, vulnerabilities
-		FROM images WHERE id = ?
+		SELECT COALESCE(b.sbom_compressed, i.sbom_compressed), i.sbom, i.vulnerabilities_compressed, i.vulnerabilities
+		FROM images i LEFT JOIN sbom_blobs b ON b.hash = i.sbom_hash
+		WHERE i.id = ?
 	`, imageID).Scan(&sbomCompressed, &sbomRaw, &vulnCompressed, &vulnRaw)
 	if err != nil {
 		return fmt.Errorf("failed to query image data: %w", err)
@@ -413,19 +413,20 @@ func TestParseVulnerabilityData_MultipleMatches(t *testing.T) {
 		t.Fatalf("parseVulnerabilityData failed: %v", err)
 	}
 
-	// Verify count = 3
-	var count int
+	// The 3 matches (same package and location, different matchers) are one
+	// finding with one occurrence
+	var count, occurrences int
 	err = db.conn.QueryRow(`
-		SELECT count
+		SELECT count, occurrences
 		FROM image_vulnerabilities
 		WHERE image_id = ? AND cve_id = ? AND package_name = ?`,
-		imageID, "CVE-2024-1234", "curl").Scan(&count)
+		imageID, "CVE-2024-1234", "curl").Scan(&count, &occurrences)
 	if err != nil {
 		t.Fatalf("Failed to query vulnerability: %v", err)
 	}
 
-	if count != 3 {
-		t.Errorf("Vulnerability count = %d, want 3", count)
+	if count != 1 || occurrences != 1 {
+		t.Errorf("Vulnerability count = %d, occurrences = %d, want 1 and 1", count, occurrences)
 	}
 
 	// Verify all 3 matches are in details
@@ -0,0 +1,193 @@
+package database
+
+import (
+	"fmt"
+)
+
+// QueueState is the state of a persisted scan queue entry
+type QueueState string
+
+const (
+	QueueStatePending    QueueState = "pending"     // waiting in the queue
+	QueueStateInProgress QueueState = "in_progress" // picked by the scan worker
+	QueueStateDone       QueueState = "done"        // processed, successfully or not
+)
+
+// Scan queue entry kinds
+const (
+	QueuedScanImage = "image"
+	QueuedScanHost  = "host"
+)
+
+// doneQueueRetention is how long processed entries are kept for inspection
+const doneQueueRetention = "-1 hour"
+
+// QueuedScan is a persisted scan queue entry, so queued scans survive
+// restarts. Host entries only use NodeName, ForceScan and FullRescan. RetryAt
+// (RFC 3339) is set on automatic retries of failed scans, which wait in the
+// queue until then.
+type QueuedScan struct {
+	ID               int64      `json:"id"`
+	Kind             string     `json:"kind"`
+	Digest           string     `json:"digest,omitempty"`
+	Reference        string     `json:"reference,omitempty"`
+	NodeName         string     `json:"node_name,omitempty"`
+	ContainerRuntime string     `json:"container_runtime,omitempty"`
+	ForceScan        bool       `json:"force_scan"`
+	FullRescan       bool       `json:"full_rescan,omitempty"`
+	AdHoc            bool       `json:"ad_hoc,omitempty"`
+	Registry         bool       `json:"registry,omitempty"`
+	Manual           bool       `json:"manual,omitempty"`
+	Priority         int        `json:"priority"`
+	RetryAt          string     `json:"retry_at,omitempty"`
+	State            QueueState `json:"state"`
+}
+
+// AddQueuedScan persists a newly queued scan as pending and returns its ID
+func (db *DB) AddQueuedScan(scan QueuedScan) (int64, error) {
+	done := db.beginWrite("add_queued_scan")
+	defer done()
+	result, err := db.conn.Exec(`
+		INSERT INTO scan_queue (kind, digest, reference, node_name, container_runtime,
+		                        force_scan, full_rescan, ad_hoc, registry, manual, priority, retry_at, state)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, scan.Kind, scan.Digest, scan.Reference, scan.NodeName, scan.ContainerRuntime,
+		scan.ForceScan, scan.FullRescan, scan.AdHoc, scan.Registry, scan.Manual, scan.Priority, scan.RetryAt, QueueStatePending)
+	if err != nil {
+		exitOnCorruption(err)
+		return 0, fmt.Errorf("failed to persist queued scan: %w", err)
+	}
+	return result.LastInsertId()
+}
+
+// SetQueuedScanState moves a persisted scan to state. Marking a scan done
+// also removes entries that finished more than an hour ago.
+func (db *DB) SetQueuedScanState(id int64, state QueueState) error {
+	done := db.beginWrite("set_queued_scan_state")
+	defer done()
+	_, err := db.conn.Exec(`
+		UPDATE scan_queue
+		SET state = ?,
+		    started_at = CASE WHEN ? = 'in_progress' THEN ` + sqlNow + ` ELSE started_at END,
+		    finished_at = CASE WHEN ? = 'done' THEN ` + sqlNow + ` ELSE finished_at END
+		WHERE id = ?
+	`, state, state, state, id)
+	if err != nil {
+		exitOnCorruption(err)
+		return fmt.Errorf("failed to update queued scan: %w", err)
+	}
+
+	if state == QueueStateDone {
+		_, err = db.conn.Exec(`
+			DELETE FROM scan_queue WHERE state = 'done' AND finished_at < ` + sqlNowShifted + `
+		`, doneQueueRetention)
+		if err != nil {
+			exitOnCorruption(err)
+			return fmt.Errorf("failed to prune processed queued scans: %w", err)
+		}
+	}
+	return nil
+}
+
+// SetQueuedScanManual raises a persisted scan to a manual request, which no
+// longer waits for its retry time
+func (db *DB) SetQueuedScanManual(id int64, priority int) error {
+	done := db.beginWrite("set_queued_scan_manual")
+	defer done()
+	if _, err := db.conn.Exec(`UPDATE scan_queue SET manual = 1, priority = ?, retry_at = '' WHERE id = ?`, priority, id); err != nil {
+		exitOnCorruption(err)
+		return fmt.Errorf("failed to update queued scan: %w", err)
+	}
+	return nil
+}
+
+// SetQueuedScanNode moves a persisted image scan to another node running the
+// image, when the queue reroutes it away from a paused node
+func (db *DB) SetQueuedScanNode(id int64, nodeName, containerRuntime, reference string) error {
+	done := db.beginWrite("set_queued_scan_node")
+	defer done()
+	if _, err := db.conn.Exec(`UPDATE scan_queue SET node_name = ?, container_runtime = ?, reference = ? WHERE id = ?`,
+		nodeName, containerRuntime, reference, id); err != nil {
+		exitOnCorruption(err)
+		return fmt.Errorf("failed to update queued scan: %w", err)
+	}
+	return nil
+}
+
+// RemoveQueuedScan deletes a persisted scan that was dropped from the queue
+func (db *DB) RemoveQueuedScan(id int64) error {
+	done := db.beginWrite("remove_queued_scan")
+	defer done()
+	if _, err := db.conn.Exec(`DELETE FROM scan_queue WHERE id = ?`, id); err != nil {
+		exitOnCorruption(err)
+		return fmt.Errorf("failed to remove queued scan: %w", err)
+	}
+	return nil
+}
+
+// RecoverQueuedScans returns the scans that were queued when the process
+// stopped, in the order they were queued, for the scan queue to pick up on
+// startup. Scans that were in progress were interrupted: they go back to
+// pending, image scans as forced rescans since a partial SBOM or status may
+// have been written. Processed entries are removed. Returns the number of
+// interrupted scans.
+func (db *DB) RecoverQueuedScans() ([]QueuedScan, int, error) {
+	done := db.beginWrite("recover_queued_scans")
+	defer done()
+	tx, err := db.conn.Begin()
+	if err != nil {
+		exitOnCorruption(err)
+		return nil, 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if _, err := tx.Exec(`DELETE FROM scan_queue WHERE state = 'done'`); err != nil {
+		exitOnCorruption(err)
+		return nil, 0, fmt.Errorf("failed to remove processed queued scans: %w", err)
+	}
+	result, err := tx.Exec(`
+		UPDATE scan_queue
+		SET state = 'pending',
+		    started_at = NULL,
+		    force_scan = CASE WHEN kind = 'image' THEN 1 ELSE force_scan END
+		WHERE state = 'in_progress'
+	`)
+	if err != nil {
+		exitOnCorruption(err)
+		return nil, 0, fmt.Errorf("failed to reset interrupted queued scans: %w", err)
+	}
+	interrupted, _ := result.RowsAffected()
+
+	rows, err := tx.Query(`
+		SELECT id, kind, digest, reference, node_name, container_runtime,
+		       force_scan, full_rescan, ad_hoc, registry, manual, priority, retry_at, state
+		FROM scan_queue
+		ORDER BY id
+	`)
+	if err != nil {
+		exitOnCorruption(err)
+		return nil, 0, fmt.Errorf("failed to query queued scans: %w", err)
+	}
+	var scans []QueuedScan
+	for rows.Next() {
+		var scan QueuedScan
+		var state string
+		if err := rows.Scan(&scan.ID, &scan.Kind, &scan.Digest, &scan.Reference, &scan.NodeName, &scan.ContainerRuntime,
+			&scan.ForceScan, &scan.FullRescan, &scan.AdHoc, &scan.Registry, &scan.Manual, &scan.Priority, &scan.RetryAt, &state); err != nil {
+			_ = rows.Close()
+			return nil, 0, fmt.Errorf("failed to scan queued scan: %w", err)
+		}
+		scan.State = QueueState(state)
+		scans = append(scans, scan)
+	}
+	_ = rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("failed to iterate queued scans: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		exitOnCorruption(err)
+		return nil, 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return scans, int(interrupted), nil
+}
@@ -0,0 +1,72 @@
+package database
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+// TestRecoverQueuedScans verifies that interrupted scans are retried, dropped
+// and processed scans are not recovered, and manual requests keep their priority.
+func TestRecoverQueuedScans(t *testing.T) {
+	db, err := New(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("failed to create database: %v", err)
+	}
+	defer func() { _ = Close(db) }()
+
+	add := func(scan QueuedScan) int64 {
+		t.Helper()
+		id, err := db.AddQueuedScan(scan)
+		if err != nil {
+			t.Fatalf("AddQueuedScan failed: %v", err)
+		}
+		return id
+	}
+	interrupted := add(QueuedScan{Kind: QueuedScanImage, Digest: "sha256:a", NodeName: "node-1", Priority: 1})
+	processed := add(QueuedScan{Kind: QueuedScanImage, Digest: "sha256:b"})
+	dropped := add(QueuedScan{Kind: QueuedScanImage, Digest: "sha256:c"})
+	raised := add(QueuedScan{Kind: QueuedScanImage, Digest: "sha256:d", ForceScan: true})
+	node := add(QueuedScan{Kind: QueuedScanHost, NodeName: "node-1", FullRescan: true})
+
+	for _, step := range []struct {
+		id    int64
+		state QueueState
+	}{
+		{interrupted, QueueStateInProgress},
+		{processed, QueueStateInProgress},
+		{processed, QueueStateDone},
+		{node, QueueStateInProgress},
+	} {
+		if err := db.SetQueuedScanState(step.id, step.state); err != nil {
+			t.Fatalf("SetQueuedScanState failed: %v", err)
+		}
+	}
+	if err := db.RemoveQueuedScan(dropped); err != nil {
+		t.Fatalf("RemoveQueuedScan failed: %v", err)
+	}
+	if err := db.SetQueuedScanManual(raised, 3); err != nil {
+		t.Fatalf("SetQueuedScanManual failed: %v", err)
+	}
+
+	scans, count, err := db.RecoverQueuedScans()
+	if err != nil {
+		t.Fatalf("RecoverQueuedScans failed: %v", err)
+	}
+	if count != 2 || len(scans) != 3 {
+		t.Fatalf("recovered %d scans with %d interrupted, want 3 with 2: %+v", len(scans), count, scans)
+	}
+	if s := scans[0]; s.ID != interrupted || !s.ForceScan || s.State != QueueStatePending || s.Priority != 1 {
+		t.Errorf("interrupted scan = %+v, want a pending forced rescan", s)
+	}
+	if s := scans[1]; s.ID != raised || !s.Manual || s.Priority != 3 {
+		t.Errorf("raised scan = %+v, want manual", s)
+	}
+	if s := scans[2]; s.ID != node || s.Kind != QueuedScanHost || s.ForceScan || !s.FullRescan {
+		t.Errorf("host scan = %+v, want its flags unchanged", s)
+	}
+
+	// Processed entries were removed; a second recovery finds the same scans
+	if again, count, err := db.RecoverQueuedScans(); err != nil || len(again) != 3 || count != 0 {
+		t.Errorf("second recovery = %d scans, %d interrupted, %v", len(again), count, err)
+	}
+}
@@ -42,6 +42,7 @@ func TestScanStatusTable(t *testing.T) {
 		{"generating_sbom", "Retrieving SBOM", 4},
 		{"sbom_unavailable", "Unable to scan", 5},
 		{"vuln_scan_failed", "Scan failed", 6},
+		{"scan_failed_permanent", "Scan failed permanently", 7},
 	}
 
 	var got []struct {
@@ -3,6 +3,7 @@ package database
 import (
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"time"
 )
@@ -108,7 +109,7 @@ func (db *DB) IsScanDataCompleteBulk(digests []string) (map[string]bool, error)
 		SELECT
 			digest,
 			status,
-			(sbom_compressed IS NOT NULL OR (sbom IS NOT NULL AND LENGTH(sbom) > 0)),
+			(sbom_hash IS NOT NULL OR sbom_compressed IS NOT NULL OR (sbom IS NOT NULL AND LENGTH(sbom) > 0)),
 			(vulnerabilities_compressed IS NOT NULL OR (vulnerabilities IS NOT NULL AND LENGTH(vulnerabilities) > 0))
 		FROM images
 		WHERE digest IN (%s)
@@ -177,7 +178,7 @@ func (db *DB) IsScanDataComplete(digest string) (bool, error) {
 	err := db.conn.QueryRow(`
 		SELECT
 			status,
-			(sbom_compressed IS NOT NULL OR (sbom IS NOT NULL AND LENGTH(sbom) > 0)),
+			(sbom_hash IS NOT NULL OR sbom_compressed IS NOT NULL OR (sbom IS NOT NULL AND LENGTH(sbom) > 0)),
 			(vulnerabilities_compressed IS NOT NULL OR (vulnerabilities IS NOT NULL AND LENGTH(vulnerabilities) > 0))
 		FROM images
 		WHERE digest = ?
@@ -217,7 +218,8 @@ func (db *DB) UpdateStatus(digest string, status Status, errorMsg string) error
 		    status_error = ?,
 		    sbom_scanned_at = COALESCE(sbom_scanned_at, ?),
 		    vulns_scanned_at = COALESCE(vulns_scanned_at, ?),
-		    updated_at = CURRENT_TIMESTAMP
+		    status_changed_at = ` + sqlNow + `,
+		    updated_at = ` + sqlNow + `
 		WHERE digest = ?
 	`, status.String(), errorMsg, sbomScannedAt, vulnsScannedAt, digest)
 
@@ -230,6 +232,33 @@ func (db *DB) UpdateStatus(digest string, status Status, errorMsg string) error
 	return nil
 }
 
+// RecordScanDurations records how long SBOM generation and the vulnerability
+// scan of an image took. A zero duration leaves the recorded value unchanged,
+// so a rescan of a stored SBOM keeps the duration of its generation.
+func (db *DB) RecordScanDurations(digest string, sbom, vulnScan time.Duration) error {
+	var sbomMs, vulnScanMs interface{}
+	if sbom > 0 {
+		sbomMs = sbom.Milliseconds()
+	}
+	if vulnScan > 0 {
+		vulnScanMs = vulnScan.Milliseconds()
+	}
+
+	done := db.beginWrite("record_scan_durations")
+	defer done()
+	_, err := db.conn.Exec(`
+		UPDATE images
+		SET sbom_duration_ms = COALESCE(?, sbom_duration_ms),
+		    vuln_scan_duration_ms = COALESCE(?, vuln_scan_duration_ms)
+		WHERE digest = ?
+	`, sbomMs, vulnScanMs, digest)
+	if err != nil {
+		exitOnCorruption(err)
+		return fmt.Errorf("failed to record scan durations: %w", err)
+	}
+	return nil
+}
+
 // UpdateScanStatus is deprecated, use UpdateStatus instead
 // Provided for backward compatibility during migration
 func (db *DB) UpdateScanStatus(digest string, status string, errorMsg string) error {
@@ -249,8 +278,24 @@ func (db *DB) UpdateScanStatus(digest string, status string, errorMsg string) er
 	return db.UpdateStatus(digest, newStatus, errorMsg)
 }
 
+// Sources of stored SBOMs, recorded per image as sbom_source
+const (
+	SBOMSourceGenerated      = "generated"       // generated from the image by Syft
+	SBOMSourceVendorAttested = "vendor-attested" // the vendor's SBOM from an attestation whose signature verified
+)
+
 // StoreSBOM stores the SBOM JSON for an image and marks it as scanned
 func (db *DB) StoreSBOM(digest string, sbomJSON []byte) error {
+	return db.storeSBOM(digest, sbomJSON, SBOMSourceGenerated, "")
+}
+
+// StoreVendorSBOM stores an SBOM read from a vendor attestation like
+// StoreSBOM, recording the key that verified its signature
+func (db *DB) StoreVendorSBOM(digest string, sbomJSON []byte, signer string) error {
+	return db.storeSBOM(digest, sbomJSON, SBOMSourceVendorAttested, signer)
+}
+
+func (db *DB) storeSBOM(digest string, sbomJSON []byte, source, signer string) error {
 	// Get image ID first (read-only, outside any lock).
 	var imageID int64
 	err := db.conn.QueryRow(`SELECT id FROM images WHERE digest = ?`, digest).Scan(&imageID)
@@ -258,11 +303,19 @@ func (db *DB) StoreSBOM(digest string, sbomJSON []byte) error {
 		return fmt.Errorf("failed to get image ID: %w", err)
 	}
 
-	// Compress blob before acquiring any lock.
+	// Images with identical SBOMs share one stored copy: compress only
+	// content not stored yet, before acquiring any lock.
+	hash := sbomHash(sbomJSON)
 	compressStart := time.Now()
-	sbomCompressed, err := compressGzip(sbomJSON)
+	var sbomCompressed []byte
+	exists, err := db.sbomBlobExists(hash)
 	if err != nil {
-		return fmt.Errorf("failed to compress SBOM: %w", err)
+		return err
+	}
+	if !exists {
+		if sbomCompressed, err = compressGzip(sbomJSON); err != nil {
+			return fmt.Errorf("failed to compress SBOM: %w", err)
+		}
 	}
 	compressMs := time.Since(compressStart).Milliseconds()
 
@@ -273,9 +326,12 @@ func (db *DB) StoreSBOM(digest string, sbomJSON []byte) error {
 		SET status = ?,
 		    status_error = NULL,
 		    sbom_scanned_at = ?,
-		    updated_at = CURRENT_TIMESTAMP
+		    sbom_source = ?,
+		    sbom_signer = NULLIF(?, ''),
+		    status_changed_at = ` + sqlNow + `,
+		    updated_at = ` + sqlNow + `
 		WHERE digest = ?
-	`, StatusScanningVulnerabilities.String(), time.Now().UTC().Format(time.RFC3339), digest)
+	`, StatusScanningVulnerabilities.String(), time.Now().UTC().Format(time.RFC3339), source, signer, digest)
 	storeDone()
 	if err != nil {
 		exitOnCorruption(err)
@@ -287,19 +343,26 @@ func (db *DB) StoreSBOM(digest string, sbomJSON []byte) error {
 		log.Warn("failed to parse SBOM data", "digest", digest, "error", err)
 	}
 
-	// Write compressed blob in its own separate write.
+	// Write the blob reference (and the blob if new) in its own separate write.
 	blobStart := time.Now()
-	blobDone := db.beginWrite("store_sbom_blob")
-	_, err = db.conn.Exec(`UPDATE images SET sbom_compressed = ? WHERE id = ?`, sbomCompressed, imageID)
-	blobDone()
+	err = db.storeSBOMBlob(imageID, hash, sbomCompressed)
+	if errors.Is(err, errSBOMBlobMissing) {
+		// The shared copy was deleted since the lookup; store our own
+		if sbomCompressed, err = compressGzip(sbomJSON); err != nil {
+			return fmt.Errorf("failed to compress SBOM: %w", err)
+		}
+		err = db.storeSBOMBlob(imageID, hash, sbomCompressed)
+	}
 	if err != nil {
-		exitOnCorruption(err)
 		return fmt.Errorf("failed to store compressed SBOM: %w", err)
 	}
 	blobMs := time.Since(blobStart).Milliseconds()
 
 	log.Info("stored SBOM for image",
 		"digest", digest[:min(16, len(digest))],
+		"source", source,
+		"sbom_hash", hash[:16],
+		"deduplicated", sbomCompressed == nil,
 		"compress_ms", compressMs,
 		"blob_compressed_kb", len(sbomCompressed)/1024,
 		"blob_write_ms", blobMs,
@@ -309,12 +372,17 @@ func (db *DB) StoreSBOM(digest string, sbomJSON []byte) error {
 }
 
 // GetSBOM retrieves the SBOM JSON for an image by digest.
-// Returns the gzip-compressed version if available, otherwise falls back to the
-// uncompressed column (written by older scanner versions).
+// Returns the shared blob the image references if any, otherwise falls back to
+// the image's own compressed or uncompressed column (written by older scanner
+// versions).
 func (db *DB) GetSBOM(digest string) ([]byte, error) {
 	var compressed []byte
 	var raw sql.NullString
-	err := db.conn.QueryRow(`SELECT sbom_compressed, sbom FROM images WHERE digest = ?`, digest).Scan(&compressed, &raw)
+	err := db.conn.QueryRow(`
+		SELECT COALESCE(b.sbom_compressed, i.sbom_compressed), i.sbom
+		FROM images i LEFT JOIN sbom_blobs b ON b.hash = i.sbom_hash
+		WHERE i.digest = ?
+	`, digest).Scan(&compressed, &raw)
 	if err == sql.ErrNoRows {
 		return nil, fmt.Errorf("image not found")
 	}
@@ -346,7 +414,7 @@ func (db *DB) GetImagesByScanStatus(status string) ([]ContainerImage, error) {
 		statusFilter = "status IN ('scanning_vulnerabilities', 'vuln_scan_failed', 'completed')"
 	case "failed":
 		// Include all failure statuses
-		statusFilter = "status IN ('sbom_failed', 'sbom_unavailable', 'vuln_scan_failed')"
+		statusFilter = "status IN ('sbom_failed', 'sbom_unavailable', 'vuln_scan_failed', 'scan_failed_permanent')"
 	default:
 		return nil, fmt.Errorf("unknown scan status: %s", status)
 	}
@@ -354,7 +422,7 @@ func (db *DB) GetImagesByScanStatus(status string) ([]ContainerImage, error) {
 	rows, err := db.conn.Query(`
 		SELECT id, digest, created_at, updated_at
 		FROM images
-		WHERE `+statusFilter+`
+		WHERE `+statusFilter+` AND deleted_at IS NULL
 		ORDER BY created_at DESC
 	`)
 	if err != nil {
@@ -375,12 +443,13 @@ func (db *DB) GetImagesByScanStatus(status string) ([]ContainerImage, error) {
 	return images, nil
 }
 
-// GetImagesByStatus returns all images with a specific unified status
+// GetImagesByStatus returns all images with a specific unified status.
+// Soft-deleted images are left out.
 func (db *DB) GetImagesByStatus(status Status) ([]ContainerImage, error) {
 	rows, err := db.conn.Query(`
 		SELECT id, digest, created_at, updated_at
 		FROM images
-		WHERE status = ?
+		WHERE status = ? AND deleted_at IS NULL
 		ORDER BY created_at DESC
 	`, status.String())
 	if err != nil {
@@ -429,6 +498,39 @@ func (db *DB) GetFirstContainerForImage(digest string) (*ContainerRow, error) {
 	return &row, nil
 }
 
+// GetContainersForImage returns every container running an image, oldest
+// first. The scan queue uses it to find another node to read the image from.
+func (db *DB) GetContainersForImage(digest string) ([]ContainerRow, error) {
+	rows, err := db.conn.Query(`
+		SELECT
+			c.id, c.namespace, c.pod, c.name,
+			c.reference, c.image_id, img.digest,
+			c.created_at, c.node_name, c.container_runtime
+		FROM containers c
+		JOIN images img ON c.image_id = img.id
+		WHERE img.digest = ?
+		ORDER BY c.created_at ASC, c.id ASC
+	`, digest)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query containers for image: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var result []ContainerRow
+	for rows.Next() {
+		var row ContainerRow
+		var nodeName, runtime sql.NullString
+		if err := rows.Scan(&row.ID, &row.Namespace, &row.Pod, &row.Name,
+			&row.Reference, &row.ImageID, &row.Digest,
+			&row.CreatedAt, &nodeName, &runtime); err != nil {
+			return nil, fmt.Errorf("failed to scan container: %w", err)
+		}
+		row.NodeName, row.ContainerRuntime = nodeName.String, runtime.String
+		result = append(result, row)
+	}
+	return result, rows.Err()
+}
+
 // GetImageVulnerabilityStatus is deprecated, use GetImageStatus instead
 // Provided for backward compatibility during migration
 func (db *DB) GetImageVulnerabilityStatus(digest string) (string, error) {
@@ -506,6 +608,7 @@ func (db *DB) StoreVulnerabilities(digest string, vulnJSON []byte, grypeDBBuilt
 	compressMs := time.Since(compressStart).Milliseconds()
 
 	// Update image status — tiny write, no blob.
+	scannedAt := time.Now().UTC().Format(time.RFC3339)
 	vulnDone := db.beginWrite("store_vulnerabilities")
 	_, err = db.conn.Exec(`
 		UPDATE images
@@ -513,9 +616,10 @@ func (db *DB) StoreVulnerabilities(digest string, vulnJSON []byte, grypeDBBuilt
 		    status_error = NULL,
 		    vulns_scanned_at = ?,
 		    grype_db_built = ?,
-		    updated_at = CURRENT_TIMESTAMP
+		    status_changed_at = ` + sqlNow + `,
+		    updated_at = ` + sqlNow + `
 		WHERE digest = ?
-	`, StatusCompleted.String(), time.Now().UTC().Format(time.RFC3339), grypeDBBuiltStr, digest)
+	`, StatusCompleted.String(), scannedAt, grypeDBBuiltStr, digest)
 	vulnDone()
 	if err != nil {
 		exitOnCorruption(err)
@@ -525,12 +629,15 @@ func (db *DB) StoreVulnerabilities(digest string, vulnJSON []byte, grypeDBBuilt
 	// Parse and store vulnerability data with batch inserts (acquires writeMu internally).
 	if err = parseVulnerabilityData(db, imageID, vulnJSON); err != nil {
 		log.Warn("failed to parse vulnerability data", "digest", digest, "error", err)
+	} else if err := db.recordScanSnapshot(imageID, scannedAt); err != nil {
+		// History is best effort; the scan result itself is stored
+		log.Warn("failed to record scan history", "digest", digest, "error", err)
 	}
 
 	// Write compressed blob in its own separate write.
 	blobStart := time.Now()
 	blobDone := db.beginWrite("store_vulnerabilities_blob")
-	_, err = db.conn.Exec(`UPDATE images SET vulnerabilities_compressed = ? WHERE id = ?`, vulnCompressed, imageID)
+	_, err = db.conn.Exec(`UPDATE images SET vulnerabilities_compressed = ?, vulnerabilities = NULL WHERE id = ?`, vulnCompressed, imageID)
 	blobDone()
 	if err != nil {
 		exitOnCorruption(err)
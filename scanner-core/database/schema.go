@@ -0,0 +1,211 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// Schema describes the database data model: tables with their columns,
+// indexes and foreign keys, and the migrations that produced it
+type Schema struct {
+	SchemaVersion int               `json:"schema_version"`
+	TargetVersion int               `json:"target_version"`
+	Tables        []SchemaTable     `json:"tables"`
+	Migrations    []SchemaMigration `json:"migrations"`
+}
+
+// SchemaTable describes a table or view
+type SchemaTable struct {
+	Name        string             `json:"name"`
+	Type        string             `json:"type"` // table or view
+	Columns     []SchemaColumn     `json:"columns"`
+	Indexes     []SchemaIndex      `json:"indexes"`
+	ForeignKeys []SchemaForeignKey `json:"foreign_keys"`
+	SQL         string             `json:"sql"`
+}
+
+// SchemaColumn describes a column of a table
+type SchemaColumn struct {
+	Name       string  `json:"name"`
+	Type       string  `json:"type"`
+	NotNull    bool    `json:"not_null"`
+	Default    *string `json:"default,omitempty"`
+	PrimaryKey int     `json:"primary_key,omitempty"` // position in the primary key, 0 if not part of it
+}
+
+// SchemaIndex describes an index; automatic indexes of UNIQUE and PRIMARY KEY
+// constraints are included with Origin "u" or "pk"
+type SchemaIndex struct {
+	Name    string   `json:"name"`
+	Unique  bool     `json:"unique"`
+	Origin  string   `json:"origin"` // c (CREATE INDEX), u (UNIQUE) or pk (PRIMARY KEY)
+	Partial bool     `json:"partial"`
+	Columns []string `json:"columns"`
+}
+
+// SchemaForeignKey describes a reference from a column to another table
+type SchemaForeignKey struct {
+	ID       int    `json:"id"` // columns of a composite key share the same id
+	Column   string `json:"column"`
+	Table    string `json:"references_table"`
+	To       string `json:"references_column,omitempty"` // empty when referencing the primary key
+	OnDelete string `json:"on_delete"`
+	OnUpdate string `json:"on_update"`
+}
+
+// SchemaMigration is one applied schema migration
+type SchemaMigration struct {
+	Version   int    `json:"version"`
+	Name      string `json:"name"`
+	AppliedAt string `json:"applied_at"`
+}
+
+// GetSchema returns the current schema read from SQLite's catalog, so tables
+// added by migrations and the shadow tables of running online migrations are
+// always included. SQLite's internal tables are left out.
+func (db *DB) GetSchema() (*Schema, error) {
+	version, err := db.getCurrentVersion()
+	if err != nil {
+		return nil, err
+	}
+	schema := &Schema{
+		SchemaVersion: version,
+		TargetVersion: currentSchemaVersion,
+		Tables:        []SchemaTable{},
+		Migrations:    []SchemaMigration{},
+	}
+
+	rows, err := db.conn.Query(`
+		SELECT name, type, COALESCE(sql, '') FROM sqlite_master
+		WHERE type IN ('table', 'view') AND name NOT LIKE 'sqlite_%'
+		ORDER BY name
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tables: %w", err)
+	}
+	for rows.Next() {
+		var table SchemaTable
+		if err := rows.Scan(&table.Name, &table.Type, &table.SQL); err != nil {
+			_ = rows.Close()
+			return nil, fmt.Errorf("failed to scan table: %w", err)
+		}
+		schema.Tables = append(schema.Tables, table)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, fmt.Errorf("failed to list tables: %w", err)
+	}
+
+	for i := range schema.Tables {
+		table := &schema.Tables[i]
+		if table.Columns, err = db.schemaColumns(table.Name); err != nil {
+			return nil, err
+		}
+		if table.Indexes, err = db.schemaIndexes(table.Name); err != nil {
+			return nil, err
+		}
+		if table.ForeignKeys, err = db.schemaForeignKeys(table.Name); err != nil {
+			return nil, err
+		}
+	}
+
+	if schema.Migrations, err = db.schemaMigrations(); err != nil {
+		return nil, err
+	}
+	return schema, nil
+}
+
+// schemaColumns reads the columns of a table
+func (db *DB) schemaColumns(table string) ([]SchemaColumn, error) {
+	rows, err := db.conn.Query(`SELECT name, type, "notnull", dflt_value, pk FROM pragma_table_info(?)`, table)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read columns of %s: %w", table, err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	columns := []SchemaColumn{}
+	for rows.Next() {
+		var col SchemaColumn
+		var dflt sql.NullString
+		if err := rows.Scan(&col.Name, &col.Type, &col.NotNull, &dflt, &col.PrimaryKey); err != nil {
+			return nil, fmt.Errorf("failed to scan column of %s: %w", table, err)
+		}
+		if dflt.Valid {
+			col.Default = &dflt.String
+		}
+		columns = append(columns, col)
+	}
+	return columns, rows.Err()
+}
+
+// schemaIndexes reads the indexes of a table with their columns
+func (db *DB) schemaIndexes(table string) ([]SchemaIndex, error) {
+	// Expression columns have no name and are reported as ""
+	rows, err := db.conn.Query(`
+		SELECT il.name, il."unique", il.origin, il.partial, COALESCE(ii.name, '')
+		FROM pragma_index_list(?) AS il
+		JOIN pragma_index_info(il.name) AS ii
+		ORDER BY il.name, ii.seqno
+	`, table)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read indexes of %s: %w", table, err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	indexes := []SchemaIndex{}
+	for rows.Next() {
+		var idx SchemaIndex
+		var column string
+		if err := rows.Scan(&idx.Name, &idx.Unique, &idx.Origin, &idx.Partial, &column); err != nil {
+			return nil, fmt.Errorf("failed to scan index of %s: %w", table, err)
+		}
+		if n := len(indexes); n > 0 && indexes[n-1].Name == idx.Name {
+			indexes[n-1].Columns = append(indexes[n-1].Columns, column)
+			continue
+		}
+		idx.Columns = []string{column}
+		indexes = append(indexes, idx)
+	}
+	return indexes, rows.Err()
+}
+
+// schemaForeignKeys reads the foreign keys of a table
+func (db *DB) schemaForeignKeys(table string) ([]SchemaForeignKey, error) {
+	rows, err := db.conn.Query(`
+		SELECT id, "from", "table", COALESCE("to", ''), on_delete, on_update
+		FROM pragma_foreign_key_list(?)
+		ORDER BY id, seq
+	`, table)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read foreign keys of %s: %w", table, err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	keys := []SchemaForeignKey{}
+	for rows.Next() {
+		var fk SchemaForeignKey
+		if err := rows.Scan(&fk.ID, &fk.Column, &fk.Table, &fk.To, &fk.OnDelete, &fk.OnUpdate); err != nil {
+			return nil, fmt.Errorf("failed to scan foreign key of %s: %w", table, err)
+		}
+		keys = append(keys, fk)
+	}
+	return keys, rows.Err()
+}
+
+// schemaMigrations reads the applied migrations, oldest first
+func (db *DB) schemaMigrations() ([]SchemaMigration, error) {
+	rows, err := db.conn.Query(`SELECT version, name, COALESCE(applied_at, '') FROM schema_migrations ORDER BY version`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read migration history: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	migrations := []SchemaMigration{}
+	for rows.Next() {
+		var m SchemaMigration
+		if err := rows.Scan(&m.Version, &m.Name, &m.AppliedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan migration: %w", err)
+		}
+		migrations = append(migrations, m)
+	}
+	return migrations, rows.Err()
+}
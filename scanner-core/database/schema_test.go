@@ -0,0 +1,72 @@
+package database
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestGetSchema(t *testing.T) {
+	db, err := New(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("Failed to create database: %v", err)
+	}
+	defer func() { _ = Close(db) }()
+
+	schema, err := db.GetSchema()
+	if err != nil {
+		t.Fatalf("GetSchema() error = %v", err)
+	}
+
+	if schema.SchemaVersion != currentSchemaVersion || schema.TargetVersion != currentSchemaVersion {
+		t.Errorf("versions = %d/%d, want %d", schema.SchemaVersion, schema.TargetVersion, currentSchemaVersion)
+	}
+	if len(schema.Migrations) != len(migrations) {
+		t.Errorf("got %d migrations, want %d", len(schema.Migrations), len(migrations))
+	}
+	if last := schema.Migrations[len(schema.Migrations)-1]; last.Version != currentSchemaVersion || last.AppliedAt == "" {
+		t.Errorf("last migration = %+v", last)
+	}
+
+	tables := make(map[string]SchemaTable)
+	for _, table := range schema.Tables {
+		tables[table.Name] = table
+	}
+	images, ok := tables["images"]
+	if !ok {
+		t.Fatal("images table missing from schema")
+	}
+	columns := make(map[string]SchemaColumn)
+	for _, col := range images.Columns {
+		columns[col.Name] = col
+	}
+	if columns["id"].PrimaryKey != 1 {
+		t.Errorf("images.id primary key = %d, want 1", columns["id"].PrimaryKey)
+	}
+	if _, ok := columns["deleted_at"]; !ok {
+		t.Error("images.deleted_at missing from schema")
+	}
+	if len(images.Indexes) == 0 {
+		t.Error("expected indexes on images")
+	}
+	for _, idx := range images.Indexes {
+		if len(idx.Columns) == 0 {
+			t.Errorf("index %s has no columns", idx.Name)
+		}
+	}
+
+	foundFK := false
+	for _, table := range schema.Tables {
+		for _, fk := range table.ForeignKeys {
+			if fk.Table == "" || fk.Column == "" {
+				t.Errorf("%s: incomplete foreign key %+v", table.Name, fk)
+			}
+			foundFK = true
+		}
+	}
+	if !foundFK {
+		t.Error("expected at least one foreign key in the schema")
+	}
+	if _, ok := tables["sqlite_sequence"]; ok {
+		t.Error("SQLite internal tables should be left out")
+	}
+}
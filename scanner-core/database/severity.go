@@ -0,0 +1,118 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/bvboe/b2s-go/scanner-core/severity"
+)
+
+// severityMappingKey is the app_state key of the severity mapping applied to stored vulnerabilities
+const severityMappingKey = "severity_mapping"
+
+// SeverityMapping returns the severity mapping applied when vulnerabilities are stored
+func (db *DB) SeverityMapping() severity.Mapping {
+	if m := db.severities.Load(); m != nil {
+		return *m
+	}
+	return nil
+}
+
+// mapSeverity returns the severity to store for a reported severity, and the
+// reported severity for source_severity (nil when the mapping leaves it unchanged)
+func (db *DB) mapSeverity(reported string) (string, any) {
+	mapped := db.SeverityMapping().Apply(reported)
+	if mapped == reported {
+		return reported, nil
+	}
+	return mapped, reported
+}
+
+// SetSeverityMapping sets the severity mapping applied when vulnerabilities
+// are stored. If it differs from the mapping the stored vulnerabilities were
+// written with, they are rewritten from their reported severities so that
+// every query sees the new scale. Should be called once at startup.
+func (db *DB) SetSeverityMapping(m severity.Mapping) error {
+	db.severities.Store(&m)
+
+	var applied string
+	err := db.conn.QueryRow(`SELECT data FROM app_state WHERE key = ?`, severityMappingKey).Scan(&applied)
+	if err != nil && err != sql.ErrNoRows {
+		return fmt.Errorf("failed to load applied severity mapping: %w", err)
+	}
+	if applied == m.String() {
+		return nil
+	}
+
+	start := time.Now()
+	done := db.beginWrite("apply_severity_mapping")
+	tx, err := db.conn.Begin()
+	if err != nil {
+		done()
+		exitOnCorruption(err)
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	rollback := func() { _ = tx.Rollback() }
+
+	updated := map[string]int64{}
+	for _, table := range []string{"image_vulnerabilities", "node_vulnerabilities"} {
+		result, err := tx.Exec(remapSeveritySQL(table, m))
+		if err != nil {
+			rollback()
+			done()
+			exitOnCorruption(err)
+			return fmt.Errorf("failed to re-map %s severities: %w", table, err)
+		}
+		updated[table], _ = result.RowsAffected()
+	}
+	if _, err := tx.Exec(`
+		INSERT OR REPLACE INTO app_state (key, data, updated_at)
+		VALUES (?, ?, ` + sqlNow + `)
+	`, severityMappingKey, m.String()); err != nil {
+		rollback()
+		done()
+		exitOnCorruption(err)
+		return fmt.Errorf("failed to save applied severity mapping: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		done()
+		exitOnCorruption(err)
+		return fmt.Errorf("failed to commit severity mapping: %w", err)
+	}
+	done()
+
+	db.notifyWrite()
+	go db.rebuildContainerVulnCache()
+	go db.rebuildNodeVulnCache()
+	log.Info("applied severity mapping to stored vulnerabilities",
+		"previous", applied, "mapping", m.String(),
+		"image_vulnerabilities", updated["image_vulnerabilities"],
+		"node_vulnerabilities", updated["node_vulnerabilities"],
+		"duration", time.Since(start).Round(time.Millisecond))
+	return nil
+}
+
+// remapSeveritySQL returns the statement rewriting a vulnerability table's
+// severities from their reported severities using m. Severity names come from
+// the fixed severity scale, so they are inlined.
+func remapSeveritySQL(table string, m severity.Mapping) string {
+	reported := "COALESCE(source_severity, severity)"
+	target := reported
+	if len(m) > 0 {
+		var b strings.Builder
+		b.WriteString("CASE " + reported)
+		for _, from := range severity.All {
+			if to, ok := m[from]; ok {
+				fmt.Fprintf(&b, " WHEN '%s' THEN '%s'", from, to)
+			}
+		}
+		b.WriteString(" ELSE " + reported + " END")
+		target = b.String()
+	}
+	return fmt.Sprintf(`UPDATE %[1]s
+		SET severity = %[2]s,
+		    source_severity = CASE WHEN %[2]s = %[3]s THEN NULL ELSE %[3]s END
+		WHERE severity IS NOT %[2]s`, table, target, reported)
+}
@@ -0,0 +1,97 @@
+package database
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/bvboe/b2s-go/scanner-core/containers"
+	"github.com/bvboe/b2s-go/scanner-core/severity"
+)
+
+// storedSeverities returns the stored severity per CVE of an image
+func storedSeverities(t *testing.T, db *DB, digest string) map[string]string {
+	t.Helper()
+	rows, err := db.conn.Query(`
+		SELECT v.cve_id, v.severity FROM image_vulnerabilities v
+		JOIN images i ON v.image_id = i.id WHERE i.digest = ?`, digest)
+	if err != nil {
+		t.Fatalf("failed to query severities: %v", err)
+	}
+	defer func() { _ = rows.Close() }()
+	severities := map[string]string{}
+	for rows.Next() {
+		var cve, sev string
+		if err := rows.Scan(&cve, &sev); err != nil {
+			t.Fatalf("failed to scan severity: %v", err)
+		}
+		severities[cve] = sev
+	}
+	return severities
+}
+
+// TestSeverityMapping verifies that the mapping is applied to newly stored
+// vulnerabilities and that stored vulnerabilities are re-mapped from their
+// reported severities when the mapping changes.
+func TestSeverityMapping(t *testing.T) {
+	db, err := New(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("failed to create database: %v", err)
+	}
+	defer func() { _ = Close(db) }()
+
+	digest := "sha256:app"
+	if _, _, err := db.GetOrCreateImage(containers.ImageID{Reference: "app:1", Digest: digest}); err != nil {
+		t.Fatalf("GetOrCreateImage() error = %v", err)
+	}
+	vulnJSON := []byte(`{"matches": [
+		{"vulnerability": {"id": "CVE-1", "severity": "High", "fix": {"state": "not-fixed"}},
+		 "artifact": {"name": "openssl", "version": "3.0", "type": "deb"}},
+		{"vulnerability": {"id": "CVE-2", "severity": "Negligible", "fix": {"state": "not-fixed"}},
+		 "artifact": {"name": "bash", "version": "5.1", "type": "deb"}},
+		{"vulnerability": {"id": "CVE-3", "severity": "Unknown", "fix": {"state": "not-fixed"}},
+		 "artifact": {"name": "zlib", "version": "1.2", "type": "deb"}}
+	]}`)
+
+	mapping, err := severity.ParseMapping("negligible=low")
+	if err != nil {
+		t.Fatalf("ParseMapping() error = %v", err)
+	}
+	if err := db.SetSeverityMapping(mapping); err != nil {
+		t.Fatalf("SetSeverityMapping() error = %v", err)
+	}
+	if err := db.StoreVulnerabilities(digest, vulnJSON, time.Now()); err != nil {
+		t.Fatalf("StoreVulnerabilities() error = %v", err)
+	}
+	got := storedSeverities(t, db, digest)
+	if got["CVE-1"] != "High" || got["CVE-2"] != "Low" || got["CVE-3"] != "Unknown" {
+		t.Errorf("stored severities = %v, want Negligible stored as Low", got)
+	}
+
+	counts, err := db.GetImageSeverityCounts(digest)
+	if err != nil {
+		t.Fatalf("GetImageSeverityCounts() error = %v", err)
+	}
+	if counts.Low != 1 || counts.Negligible != 0 {
+		t.Errorf("badge counts low/negligible = %d/%d, want 1/0", counts.Low, counts.Negligible)
+	}
+
+	// Changing the mapping re-maps stored results from the reported severity
+	mapping, _ = severity.ParseMapping("unknown=low")
+	if err := db.SetSeverityMapping(mapping); err != nil {
+		t.Fatalf("SetSeverityMapping() error = %v", err)
+	}
+	got = storedSeverities(t, db, digest)
+	if got["CVE-1"] != "High" || got["CVE-2"] != "Negligible" || got["CVE-3"] != "Low" {
+		t.Errorf("re-mapped severities = %v, want Negligible restored and Unknown as Low", got)
+	}
+
+	// Removing the mapping restores the reported severities
+	if err := db.SetSeverityMapping(nil); err != nil {
+		t.Fatalf("SetSeverityMapping(nil) error = %v", err)
+	}
+	got = storedSeverities(t, db, digest)
+	if got["CVE-2"] != "Negligible" || got["CVE-3"] != "Unknown" {
+		t.Errorf("unmapped severities = %v, want reported severities", got)
+	}
+}
@@ -25,6 +25,11 @@ const (
 
 	// StatusCompleted indicates both SBOM generation and vulnerability scanning completed successfully
 	StatusCompleted Status = "completed"
+
+	// StatusScanFailedPermanent indicates the image failed to scan too many
+	// times in a row and is no longer retried until it is requeued
+	// (see RecordScanFailure)
+	StatusScanFailedPermanent Status = "scan_failed_permanent"
 )
 
 // String returns the string representation of the status
@@ -35,7 +40,7 @@ func (s Status) String() string {
 // IsTerminal returns true if the status is terminal (no further processing needed)
 func (s Status) IsTerminal() bool {
 	switch s {
-	case StatusSBOMFailed, StatusSBOMUnavailable, StatusVulnScanFailed, StatusCompleted:
+	case StatusSBOMFailed, StatusSBOMUnavailable, StatusVulnScanFailed, StatusCompleted, StatusScanFailedPermanent:
 		return true
 	default:
 		return false
@@ -45,7 +50,7 @@ func (s Status) IsTerminal() bool {
 // IsError returns true if the status represents an error condition
 func (s Status) IsError() bool {
 	switch s {
-	case StatusSBOMFailed, StatusVulnScanFailed:
+	case StatusSBOMFailed, StatusVulnScanFailed, StatusScanFailedPermanent:
 		return true
 	default:
 		return false
@@ -0,0 +1,60 @@
+package database
+
+import (
+	"fmt"
+	"time"
+)
+
+// StuckScan is an image that has not left an intermediate scan state
+// (generating_sbom, scanning_vulnerabilities) for longer than expected, e.g.
+// because the worker scanning it crashed. NodeName and ContainerRuntime locate
+// a container currently running the image (empty if none does).
+type StuckScan struct {
+	Digest           string `json:"digest"`
+	Reference        string `json:"reference"`
+	NodeName         string `json:"node_name,omitempty"`
+	ContainerRuntime string `json:"container_runtime,omitempty"`
+	Status           Status `json:"status"`
+	StatusChangedAt  string `json:"status_changed_at"`
+}
+
+// GetStuckScans returns the images whose status is generating_sbom or
+// scanning_vulnerabilities and has not changed for longer than maxAge, oldest
+// transition first. Images created before status transitions were tracked
+// fall back to their creation time.
+func (db *DB) GetStuckScans(maxAge time.Duration) ([]StuckScan, error) {
+	cutoff := formatTimestamp(time.Now().Add(-maxAge))
+	rows, err := db.conn.Query(`
+		SELECT images.digest,
+		       COALESCE(c.reference, images.ad_hoc_reference, images.last_reference, images.digest),
+		       COALESCE(c.node_name, ''),
+		       COALESCE(c.container_runtime, ''),
+		       images.status,
+		       COALESCE(images.status_changed_at, images.created_at)
+		FROM images
+		LEFT JOIN containers c ON c.id = (
+		    SELECT id FROM containers WHERE image_id = images.id ORDER BY id LIMIT 1
+		)
+		WHERE images.status IN (?, ?)
+		  AND images.deleted_at IS NULL
+		  AND COALESCE(images.status_changed_at, images.created_at) < ?
+		ORDER BY COALESCE(images.status_changed_at, images.created_at), images.digest
+	`, StatusGeneratingSBOM.String(), StatusScanningVulnerabilities.String(), cutoff)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query stuck scans: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	scans := []StuckScan{}
+	for rows.Next() {
+		var scan StuckScan
+		var status string
+		if err := rows.Scan(&scan.Digest, &scan.Reference, &scan.NodeName, &scan.ContainerRuntime,
+			&status, &scan.StatusChangedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan stuck scan: %w", err)
+		}
+		scan.Status = Status(status)
+		scans = append(scans, scan)
+	}
+	return scans, rows.Err()
+}
@@ -0,0 +1,69 @@
+package database
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/bvboe/b2s-go/scanner-core/containers"
+)
+
+// TestGetStuckScans verifies that only images sitting in an intermediate state
+// since before maxAge are reported, and that status writes reset the clock.
+func TestGetStuckScans(t *testing.T) {
+	db, err := New(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("failed to create database: %v", err)
+	}
+	defer func() { _ = Close(db) }()
+
+	for _, digest := range []string{"sha256:stuck", "sha256:fresh", "sha256:done"} {
+		if _, err := db.AddContainer(containers.Container{
+			ID:               containers.ContainerID{Namespace: "default", Pod: digest, Name: "app"},
+			Image:            containers.ImageID{Reference: "app:" + digest[7:], Digest: digest},
+			NodeName:         "node-1",
+			ContainerRuntime: "containerd",
+		}); err != nil {
+			t.Fatalf("AddContainer failed: %v", err)
+		}
+	}
+	for digest, status := range map[string]Status{
+		"sha256:stuck": StatusGeneratingSBOM,
+		"sha256:fresh": StatusScanningVulnerabilities,
+		"sha256:done":  StatusCompleted,
+	} {
+		if err := db.UpdateStatus(digest, status, ""); err != nil {
+			t.Fatalf("UpdateStatus failed: %v", err)
+		}
+	}
+	for _, digest := range []string{"sha256:stuck", "sha256:done"} {
+		if _, err := db.conn.Exec(`UPDATE images SET status_changed_at = datetime('now', '-2 hours') WHERE digest = ?`, digest); err != nil {
+			t.Fatalf("failed to backdate status_changed_at: %v", err)
+		}
+	}
+	// Unrelated writes don't count as progress
+	if _, err := db.conn.Exec(`UPDATE images SET updated_at = CURRENT_TIMESTAMP`); err != nil {
+		t.Fatalf("failed to touch images: %v", err)
+	}
+
+	scans, err := db.GetStuckScans(30 * time.Minute)
+	if err != nil {
+		t.Fatalf("GetStuckScans failed: %v", err)
+	}
+	if len(scans) != 1 {
+		t.Fatalf("expected 1 stuck scan, got %+v", scans)
+	}
+	scan := scans[0]
+	if scan.Digest != "sha256:stuck" || scan.Status != StatusGeneratingSBOM || scan.Reference != "app:stuck" ||
+		scan.NodeName != "node-1" || scan.ContainerRuntime != "containerd" || scan.StatusChangedAt == "" {
+		t.Errorf("unexpected stuck scan: %+v", scan)
+	}
+
+	// A status write is progress
+	if err := db.UpdateStatus("sha256:stuck", StatusScanningVulnerabilities, ""); err != nil {
+		t.Fatalf("UpdateStatus failed: %v", err)
+	}
+	if scans, err := db.GetStuckScans(30 * time.Minute); err != nil || len(scans) != 0 {
+		t.Errorf("GetStuckScans after transition = %+v, %v; want none", scans, err)
+	}
+}
@@ -0,0 +1,21 @@
+package database
+
+import "time"
+
+// Stored timestamps are RFC3339 in UTC with second precision
+// ("2006-01-02T15:04:05Z"), so they sort correctly as text, compare against
+// each other in SQL and parse the same way in every client. Go code stores
+// times with formatTimestamp and SQL uses sqlNow instead of
+// CURRENT_TIMESTAMP, whose "2006-01-02 15:04:05" layout sorts before every
+// RFC3339 value of the same day.
+
+// sqlNow is the current time in the stored timestamp format
+const sqlNow = `strftime('%Y-%m-%dT%H:%M:%SZ', 'now')`
+
+// sqlNowShifted is sqlNow shifted by a bound SQLite modifier such as '-3 days'
+const sqlNowShifted = `strftime('%Y-%m-%dT%H:%M:%SZ', 'now', ?)`
+
+// formatTimestamp formats t in the stored timestamp format
+func formatTimestamp(t time.Time) string {
+	return t.UTC().Format(time.RFC3339)
+}
@@ -0,0 +1,70 @@
+package database
+
+import (
+	"path/filepath"
+	"regexp"
+	"testing"
+
+	"github.com/bvboe/b2s-go/scanner-core/containers"
+)
+
+var rfc3339UTC = regexp.MustCompile(`^\d{4}-\d{2}-\d{2}T\d{2}:\d{2}:\d{2}Z$`)
+
+func TestNormalizeTimestamps(t *testing.T) {
+	db, err := New(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("failed to create database: %v", err)
+	}
+	defer func() { _ = Close(db) }()
+
+	rawValue := func(query string, args ...any) string {
+		t.Helper()
+		var v string
+		if err := db.conn.QueryRow(query, args...).Scan(&v); err != nil {
+			t.Fatal(err)
+		}
+		return v
+	}
+
+	// Column defaults produce RFC3339 on a new database
+	if _, err := db.AddContainer(containers.Container{
+		ID:    containers.ContainerID{Namespace: "default", Pod: "app", Name: "app"},
+		Image: containers.ImageID{Reference: "app:1", Digest: "sha256:app"},
+	}); err != nil {
+		t.Fatalf("AddContainer() error = %v", err)
+	}
+	if v := rawValue(`SELECT created_at || '' FROM images WHERE digest = 'sha256:app'`); !rfc3339UTC.MatchString(v) {
+		t.Errorf("images.created_at = %q, want RFC3339 UTC", v)
+	}
+
+	// Data and defaults written before the migration
+	if _, err := db.conn.Exec(`CREATE TABLE legacy (id INTEGER PRIMARY KEY, created_at DATETIME DEFAULT CURRENT_TIMESTAMP)`); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.conn.Exec(`
+		UPDATE images SET created_at = '2026-01-02 03:04:05', deleted_at = 'not a time',
+		                  grype_db_built = '2026-01-01T08:00:00+02:00'
+		WHERE digest = 'sha256:app'`); err != nil {
+		t.Fatal(err)
+	}
+	if err := migrateToV69(db.conn); err != nil {
+		t.Fatalf("migrateToV69() error = %v", err)
+	}
+
+	for column, want := range map[string]string{
+		"created_at":     "2026-01-02T03:04:05Z",
+		"deleted_at":     "not a time",
+		"grype_db_built": "2026-01-01T06:00:00Z",
+	} {
+		if got := rawValue(`SELECT ` + column + ` || '' FROM images WHERE digest = 'sha256:app'`); got != want {
+			t.Errorf("images.%s = %q, want %q", column, got, want)
+		}
+	}
+
+	if _, err := db.conn.Exec(`INSERT INTO legacy (id) VALUES (1)`); err != nil {
+		t.Fatal(err)
+	}
+	if v := rawValue(`SELECT created_at || '' FROM legacy`); !rfc3339UTC.MatchString(v) {
+		t.Errorf("legacy.created_at default = %q, want RFC3339 UTC", v)
+	}
+}
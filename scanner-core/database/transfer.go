@@ -0,0 +1,78 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/bvboe/b2s-go/scanner-core/containers"
+)
+
+// ImageTransferRecord holds the scan metadata for an image that travels with an
+// export bundle, so the importing server can see where the results came from.
+type ImageTransferRecord struct {
+	Digest         string   `json:"digest"`
+	Status         string   `json:"status"`
+	References     []string `json:"references,omitempty"`
+	GrypeDBBuilt   string   `json:"grype_db_built,omitempty"`
+	SBOMScannedAt  string   `json:"sbom_scanned_at,omitempty"`
+	VulnsScannedAt string   `json:"vulns_scanned_at,omitempty"`
+}
+
+// GetImageTransferRecord returns the scan metadata for an image by digest.
+// References are the distinct image references of containers currently running the image.
+func (db *DB) GetImageTransferRecord(digest string) (*ImageTransferRecord, error) {
+	var record ImageTransferRecord
+	var grypeDBBuilt, sbomScannedAt, vulnsScannedAt sql.NullString
+
+	err := db.conn.QueryRow(`
+		SELECT digest, status, grype_db_built, sbom_scanned_at, vulns_scanned_at
+		FROM images
+		WHERE digest = ?
+	`, digest).Scan(&record.Digest, &record.Status, &grypeDBBuilt, &sbomScannedAt, &vulnsScannedAt)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("image not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get image: %w", err)
+	}
+	record.GrypeDBBuilt = grypeDBBuilt.String
+	record.SBOMScannedAt = sbomScannedAt.String
+	record.VulnsScannedAt = vulnsScannedAt.String
+
+	rows, err := db.conn.Query(`
+		SELECT DISTINCT c.reference
+		FROM containers c
+		JOIN images img ON c.image_id = img.id
+		WHERE img.digest = ? AND c.reference != ''
+		ORDER BY c.reference
+	`, digest)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get image references: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	for rows.Next() {
+		var reference string
+		if err := rows.Scan(&reference); err != nil {
+			return nil, fmt.Errorf("failed to scan reference: %w", err)
+		}
+		record.References = append(record.References, reference)
+	}
+
+	return &record, rows.Err()
+}
+
+// ImportScanResults stores externally produced scan results for an image.
+// The image row is created if it does not exist yet, then the SBOM and the
+// vulnerability report are stored exactly as if they had been produced locally,
+// leaving the image in the completed state.
+func (db *DB) ImportScanResults(digest string, sbomJSON, vulnJSON []byte, grypeDBBuilt time.Time) error {
+	if _, _, err := db.GetOrCreateImage(containers.ImageID{Digest: digest}); err != nil {
+		return err
+	}
+	if err := db.StoreSBOM(digest, sbomJSON); err != nil {
+		return err
+	}
+	return db.StoreVulnerabilities(digest, vulnJSON, grypeDBBuilt)
+}
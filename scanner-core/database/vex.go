@@ -0,0 +1,442 @@
+package database
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/bvboe/b2s-go/scanner-core/vex"
+)
+
+// vexAppliedKey is the app_state key of the fingerprint of the VEX statements
+// applied to the stored vulnerabilities
+const vexAppliedKey = "vex_applied"
+
+// Name prefixes of VEX documents by origin
+const (
+	VEXFilePrefix   = "file:" // loaded from the configured path; replaced at every start
+	VEXUploadPrefix = "api:"  // uploaded through the API; kept until deleted
+)
+
+// VEXDocument is a stored OpenVEX document
+type VEXDocument struct {
+	ID         int64  `json:"id"`
+	Name       string `json:"name"` // file:<file name> or api:<document @id>
+	DocumentID string `json:"document_id,omitempty"`
+	Author     string `json:"author,omitempty"`
+	Statements int    `json:"statements"`
+	CreatedAt  string `json:"created_at"`
+	UpdatedAt  string `json:"updated_at"`
+}
+
+// VEXSuppression is a finding a not_affected statement removed from an image's
+// vulnerabilities, with the statement that did
+type VEXSuppression struct {
+	Digest             string `json:"digest"`
+	CVEID              string `json:"cve_id"`
+	PackageName        string `json:"package_name"`
+	PackageVersion     string `json:"package_version"`
+	PackageType        string `json:"package_type"`
+	Severity           string `json:"severity"`
+	Status             string `json:"status"`
+	Justification      string `json:"justification,omitempty"`
+	ImpactStatement    string `json:"impact_statement,omitempty"`
+	Source             string `json:"source"`
+	DocumentID         string `json:"document_id,omitempty"`
+	StatementTimestamp string `json:"statement_timestamp,omitempty"`
+	SuppressedAt       string `json:"suppressed_at"`
+}
+
+// VEX returns the statements applied when vulnerabilities are stored
+func (db *DB) VEX() *vex.Index {
+	return db.vex.Load()
+}
+
+// SyncVEXFiles replaces the documents loaded from files with files (name to
+// content) and applies the stored documents. Should be called once at startup,
+// with no files when no VEX path is configured, so documents removed from the
+// path stop applying. Returns the number of images whose findings were
+// re-evaluated.
+func (db *DB) SyncVEXFiles(files map[string][]byte) (int, error) {
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	done := db.beginWrite("sync_vex_files")
+	tx, err := db.conn.Begin()
+	if err != nil {
+		done()
+		exitOnCorruption(err)
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	rollback := func() { _ = tx.Rollback() }
+
+	if _, err := tx.Exec(`DELETE FROM vex_documents WHERE name LIKE ?`, VEXFilePrefix+"%"); err != nil {
+		rollback()
+		done()
+		exitOnCorruption(err)
+		return 0, fmt.Errorf("failed to remove VEX file documents: %w", err)
+	}
+	for _, name := range names {
+		doc, err := vex.Parse(files[name])
+		if err != nil {
+			rollback()
+			done()
+			return 0, fmt.Errorf("%s: %w", name, err)
+		}
+		if err := insertVEXDocument(tx, VEXFilePrefix+name, doc, files[name]); err != nil {
+			rollback()
+			done()
+			return 0, err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		done()
+		exitOnCorruption(err)
+		return 0, fmt.Errorf("failed to commit VEX file documents: %w", err)
+	}
+	done()
+
+	return db.applyVEX()
+}
+
+// AddVEXDocument stores an uploaded OpenVEX document, replacing an earlier
+// upload with the same @id, and applies it to the stored vulnerabilities.
+// Returns the document and the number of images whose findings were
+// re-evaluated.
+func (db *DB) AddVEXDocument(data []byte) (*VEXDocument, int, error) {
+	doc, err := vex.Parse(data)
+	if err != nil {
+		return nil, 0, err
+	}
+	key := doc.ID
+	if key == "" {
+		sum := sha256.Sum256(data)
+		key = "sha256:" + hex.EncodeToString(sum[:])
+	}
+	name := VEXUploadPrefix + key
+
+	done := db.beginWrite("add_vex_document")
+	_, err = db.conn.Exec(`
+		INSERT INTO vex_documents (name, document_id, author, statements, content)
+		VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT(name) DO UPDATE SET
+			author = excluded.author,
+			statements = excluded.statements,
+			content = excluded.content,
+			updated_at = `+sqlNow+`
+	`, name, doc.ID, doc.Author, len(doc.Statements), string(data))
+	done()
+	if err != nil {
+		exitOnCorruption(err)
+		return nil, 0, fmt.Errorf("failed to store VEX document: %w", err)
+	}
+
+	images, err := db.applyVEX()
+	if err != nil {
+		return nil, 0, err
+	}
+	documents, err := db.queryVEXDocuments(`WHERE name = ?`, name)
+	if err != nil || len(documents) == 0 {
+		return nil, images, err
+	}
+	return &documents[0], images, nil
+}
+
+// DeleteVEXDocument removes an uploaded document and re-evaluates the findings
+// its statements applied to. Documents loaded from files are removed from the
+// configured path instead. Reports whether there was such a document and the
+// number of images re-evaluated.
+func (db *DB) DeleteVEXDocument(id int64) (bool, int, error) {
+	done := db.beginWrite("delete_vex_document")
+	result, err := db.conn.Exec(`DELETE FROM vex_documents WHERE id = ? AND name LIKE ?`, id, VEXUploadPrefix+"%")
+	done()
+	if err != nil {
+		exitOnCorruption(err)
+		return false, 0, fmt.Errorf("failed to delete VEX document: %w", err)
+	}
+	if deleted, _ := result.RowsAffected(); deleted == 0 {
+		return false, 0, nil
+	}
+	images, err := db.applyVEX()
+	return true, images, err
+}
+
+// GetVEXDocuments returns the stored documents ordered by name
+func (db *DB) GetVEXDocuments() ([]VEXDocument, error) {
+	return db.queryVEXDocuments("")
+}
+
+// GetVEXSuppressions returns the findings suppressed by VEX statements, of one
+// image or of all images if digest is empty
+func (db *DB) GetVEXSuppressions(digest string) ([]VEXSuppression, error) {
+	where, args := "", []any{}
+	if digest != "" {
+		where, args = "WHERE i.digest = ?", append(args, digest)
+	}
+	rows, err := db.conn.Query(`
+		SELECT i.digest, s.cve_id, s.package_name, s.package_version, s.package_type, s.severity,
+			s.status, s.justification, s.impact_statement, s.source, s.document_id,
+			s.statement_timestamp, s.suppressed_at
+		FROM vex_suppressions s
+		JOIN images i ON s.image_id = i.id
+		`+where+`
+		ORDER BY i.digest, s.cve_id, s.package_name, s.package_version
+	`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query VEX suppressions: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	suppressions := []VEXSuppression{}
+	for rows.Next() {
+		var s VEXSuppression
+		if err := rows.Scan(&s.Digest, &s.CVEID, &s.PackageName, &s.PackageVersion, &s.PackageType, &s.Severity,
+			&s.Status, &s.Justification, &s.ImpactStatement, &s.Source, &s.DocumentID,
+			&s.StatementTimestamp, &s.SuppressedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan VEX suppression: %w", err)
+		}
+		suppressions = append(suppressions, s)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate VEX suppressions: %w", err)
+	}
+	return suppressions, nil
+}
+
+// queryVEXDocuments returns the documents matching the where clause, without
+// their content
+func (db *DB) queryVEXDocuments(where string, args ...any) ([]VEXDocument, error) {
+	rows, err := db.conn.Query(`
+		SELECT id, name, document_id, author, statements, created_at, updated_at
+		FROM vex_documents `+where+`
+		ORDER BY name
+	`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query VEX documents: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	documents := []VEXDocument{}
+	for rows.Next() {
+		var d VEXDocument
+		if err := rows.Scan(&d.ID, &d.Name, &d.DocumentID, &d.Author, &d.Statements, &d.CreatedAt, &d.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan VEX document: %w", err)
+		}
+		documents = append(documents, d)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate VEX documents: %w", err)
+	}
+	return documents, nil
+}
+
+// insertVEXDocument stores a parsed document inside an open transaction
+func insertVEXDocument(tx *sql.Tx, name string, doc *vex.Document, data []byte) error {
+	if _, err := tx.Exec(`
+		INSERT INTO vex_documents (name, document_id, author, statements, content)
+		VALUES (?, ?, ?, ?, ?)
+	`, name, doc.ID, doc.Author, len(doc.Statements), string(data)); err != nil {
+		exitOnCorruption(err)
+		return fmt.Errorf("failed to store VEX document %s: %w", name, err)
+	}
+	return nil
+}
+
+// applyVEX indexes the stored documents and the active ignores and, when they
+// differ from the ones the stored vulnerabilities were written with,
+// re-evaluates the images with findings they name or findings suppressed
+// before. Returns the number of images re-evaluated.
+func (db *DB) applyVEX() (int, error) {
+	ix, err := db.loadVEXIndex()
+	if err != nil {
+		return 0, err
+	}
+	ignores, err := db.loadIgnoreSet()
+	if err != nil {
+		return 0, err
+	}
+	db.vex.Store(ix)
+	db.ignores.Store(ignores)
+	fingerprint := ix.Fingerprint()
+	if !ignores.empty() {
+		fingerprint += "+" + ignores.fingerprint
+	}
+
+	var applied string
+	err = db.conn.QueryRow(`SELECT data FROM app_state WHERE key = ?`, vexAppliedKey).Scan(&applied)
+	if err != nil && err != sql.ErrNoRows {
+		return 0, fmt.Errorf("failed to load applied VEX fingerprint: %w", err)
+	}
+	if applied == fingerprint {
+		return 0, nil
+	}
+
+	start := time.Now()
+	imageIDs, err := db.vexAffectedImages(append(ix.Vulnerabilities(), ignores.cveIDs()...))
+	if err != nil {
+		return 0, err
+	}
+	reevaluated := 0
+	for _, id := range imageIDs {
+		vulnJSON, err := db.storedVulnerabilities(id)
+		if err != nil {
+			log.Warn("failed to read vulnerabilities for VEX", "image_id", id, "error", err)
+			continue
+		}
+		if vulnJSON == nil {
+			continue
+		}
+		if err := parseVulnerabilityData(db, id, vulnJSON); err != nil {
+			log.Warn("failed to apply VEX statements", "image_id", id, "error", err)
+			continue
+		}
+		reevaluated++
+	}
+
+	done := db.beginWrite("save_vex_applied")
+	_, err = db.conn.Exec(`
+		INSERT OR REPLACE INTO app_state (key, data, updated_at)
+		VALUES (?, ?, `+sqlNow+`)
+	`, vexAppliedKey, fingerprint)
+	done()
+	if err != nil {
+		exitOnCorruption(err)
+		return reevaluated, fmt.Errorf("failed to save applied VEX fingerprint: %w", err)
+	}
+	db.notifyWrite()
+	log.Info("applied VEX statements to stored vulnerabilities",
+		"vulnerabilities", len(ix.Vulnerabilities()), "ignores", len(ignores.ignores), "images", reevaluated,
+		"duration", time.Since(start).Round(time.Millisecond))
+	return reevaluated, nil
+}
+
+// loadVEXIndex parses the stored documents into an index
+func (db *DB) loadVEXIndex() (*vex.Index, error) {
+	rows, err := db.conn.Query(`SELECT name, content FROM vex_documents ORDER BY id`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query VEX documents: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var sources []vex.Source
+	for rows.Next() {
+		var name, content string
+		if err := rows.Scan(&name, &content); err != nil {
+			return nil, fmt.Errorf("failed to scan VEX document: %w", err)
+		}
+		doc, err := vex.Parse([]byte(content))
+		if err != nil {
+			log.Warn("skipping stored VEX document", "name", name, "error", err)
+			continue
+		}
+		sources = append(sources, vex.Source{Name: name, Document: doc})
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate VEX documents: %w", err)
+	}
+	return vex.NewIndex(sources...), nil
+}
+
+// vexAffectedImages returns the images with findings of the vulnerabilities
+// (or of vulnerabilities they are aliases of) and the images with suppressed
+// findings, which a changed statement may no longer suppress
+func (db *DB) vexAffectedImages(vulnerabilities []string) ([]int64, error) {
+	ids, err := json.Marshal(vulnerabilities)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode VEX vulnerabilities: %w", err)
+	}
+	rows, err := db.conn.Query(`
+		WITH named(id) AS (SELECT value FROM json_each(?))
+		SELECT DISTINCT v.image_id FROM image_vulnerabilities v
+		WHERE UPPER(v.cve_id) IN (SELECT id FROM named)
+		   OR v.cve_id IN (SELECT vulnerability_id FROM vulnerability_aliases WHERE UPPER(alias_id) IN (SELECT id FROM named))
+		UNION
+		SELECT image_id FROM vex_suppressions
+		ORDER BY 1
+	`, string(ids))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query images affected by VEX statements: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var images []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan image ID: %w", err)
+		}
+		images = append(images, id)
+	}
+	return images, rows.Err()
+}
+
+// storedVulnerabilities returns the Grype JSON stored for an image, nil if it
+// has none
+func (db *DB) storedVulnerabilities(imageID int64) ([]byte, error) {
+	var compressed []byte
+	var raw sql.NullString
+	if err := db.conn.QueryRow(`SELECT vulnerabilities_compressed, vulnerabilities FROM images WHERE id = ?`,
+		imageID).Scan(&compressed, &raw); err != nil {
+		return nil, fmt.Errorf("failed to query vulnerabilities: %w", err)
+	}
+	if len(compressed) > 0 {
+		return decompressGzip(compressed)
+	}
+	if raw.Valid && raw.String != "" {
+		return []byte(raw.String), nil
+	}
+	return nil, nil
+}
+
+// formatStatementTime formats a statement timestamp for storage; "" if unset
+func formatStatementTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+// vexFindings prepares the lookup of an image's findings in the active ignores
+// and the VEX statements; nil when there are neither. An ignore takes
+// precedence over a statement.
+func (db *DB) vexFindings(imageID int64) func(cveID string, matches []GrypeMatch) (vex.Match, bool) {
+	ix, ignores := db.VEX(), db.ignores.Load()
+	if ix.Empty() && ignores.empty() {
+		return nil
+	}
+	var digest string
+	if err := db.conn.QueryRow(`SELECT digest FROM images WHERE id = ?`, imageID).Scan(&digest); err != nil {
+		log.Warn("failed to read image digest for VEX", "image_id", imageID, "error", err)
+		return nil
+	}
+	refs, err := db.GetImageReferences(digest)
+	if err != nil {
+		log.Warn("failed to read image references for VEX", "digest", digest, "error", err)
+	}
+	return func(cveID string, matches []GrypeMatch) (vex.Match, bool) {
+		finding := vex.Finding{Vulnerability: cveID, Digest: digest, References: refs}
+		for _, m := range matches {
+			finding.Package = m.Artifact.Name
+			if finding.PURL == "" {
+				finding.PURL = m.Artifact.PURL
+			}
+			for _, r := range m.RelatedVulnerabilities {
+				if r.ID != "" && !strings.EqualFold(r.ID, cveID) {
+					finding.Aliases = append(finding.Aliases, r.ID)
+				}
+			}
+		}
+		if m, ok := ignores.match(finding); ok {
+			return m, true
+		}
+		return ix.Suppresses(finding)
+	}
+}
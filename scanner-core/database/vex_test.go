@@ -0,0 +1,123 @@
+package database
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestVEXSuppression(t *testing.T) {
+	db, err := New(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("Failed to create database: %v", err)
+	}
+	defer func() { _ = Close(db) }()
+
+	vulns := []byte(`{"matches":[
+		{"vulnerability":{"id":"CVE-2024-0001","severity":"High"},"artifact":{"name":"openssl","version":"3.0.0","type":"apk","purl":"pkg:apk/alpine/openssl@3.0.0"}},
+		{"vulnerability":{"id":"GHSA-xxxx-yyyy-zzzz","severity":"Critical"},"relatedVulnerabilities":[{"id":"CVE-2024-0002"}],
+		 "artifact":{"name":"lib","version":"1.0.0","type":"go-module"}}]}`)
+	for _, digest := range []string{"sha256:app", "sha256:other"} {
+		if err := db.ImportScanResults(digest, []byte(`{"artifacts":[]}`), vulns, time.Time{}); err != nil {
+			t.Fatalf("ImportScanResults() error = %v", err)
+		}
+	}
+	findings := func(digest string) int {
+		t.Helper()
+		var n int
+		if err := db.conn.QueryRow(`
+			SELECT COUNT(*) FROM image_vulnerabilities v JOIN images i ON v.image_id = i.id WHERE i.digest = ?
+		`, digest).Scan(&n); err != nil {
+			t.Fatalf("failed to count findings: %v", err)
+		}
+		return n
+	}
+
+	// The package statement applies to every image, the image statement to
+	// the GHSA through its related CVE
+	doc := []byte(`{"@context":"https://openvex.dev/ns/v0.2.0","@id":"https://example.com/vex/1","author":"vendor",
+		"timestamp":"2024-05-01T00:00:00Z","statements":[
+		{"vulnerability":{"name":"CVE-2024-0001"},"products":[{"@id":"pkg:apk/alpine/openssl@3.0.0"}],
+		 "status":"not_affected","justification":"vulnerable_code_not_present"},
+		{"vulnerability":{"name":"CVE-2024-0002"},"products":[{"@id":"pkg:oci/app@sha256%3Aapp"}],
+		 "status":"not_affected","impact_statement":"never loaded"}]}`)
+	if _, _, err := db.AddVEXDocument([]byte(`{"@context":"https://example.com"}`)); err == nil {
+		t.Error("AddVEXDocument() accepted an invalid document")
+	}
+	added, images, err := db.AddVEXDocument(doc)
+	if err != nil {
+		t.Fatalf("AddVEXDocument() error = %v", err)
+	}
+	if added.Name != VEXUploadPrefix+"https://example.com/vex/1" || added.Statements != 2 || images != 2 {
+		t.Errorf("AddVEXDocument() = %+v, %d images re-evaluated", added, images)
+	}
+	if app, other := findings("sha256:app"), findings("sha256:other"); app != 0 || other != 1 {
+		t.Errorf("findings after VEX = %d and %d, want 0 and 1", app, other)
+	}
+	suppressed, err := db.GetVEXSuppressions("sha256:app")
+	if err != nil || len(suppressed) != 2 {
+		t.Fatalf("GetVEXSuppressions() = %+v, %v", suppressed, err)
+	}
+	if s := suppressed[0]; s.CVEID != "CVE-2024-0001" || s.Status != "not_affected" || s.Justification != "vulnerable_code_not_present" ||
+		s.Severity != "High" || s.DocumentID != "https://example.com/vex/1" || s.StatementTimestamp != "2024-05-01T00:00:00Z" {
+		t.Errorf("suppression = %+v", s)
+	}
+	if all, _ := db.GetVEXSuppressions(""); len(all) != 3 {
+		t.Errorf("GetVEXSuppressions(\"\") = %d suppressions, want 3", len(all))
+	}
+
+	// A rescan keeps the suppression; re-uploading the same @id replaces it
+	if err := db.ImportScanResults("sha256:app", []byte(`{"artifacts":[]}`), vulns, time.Time{}); err != nil {
+		t.Fatalf("ImportScanResults() error = %v", err)
+	}
+	if n := findings("sha256:app"); n != 0 {
+		t.Errorf("findings after rescan = %d, want 0", n)
+	}
+	if _, images, err = db.AddVEXDocument(doc); err != nil || images != 0 {
+		t.Errorf("re-uploading the same document = %d images, %v, want nothing re-evaluated", images, err)
+	}
+	documents, err := db.GetVEXDocuments()
+	if err != nil || len(documents) != 1 {
+		t.Fatalf("GetVEXDocuments() = %+v, %v", documents, err)
+	}
+
+	if documents[0].ID != added.ID || documents[0].UpdatedAt == "" {
+		t.Errorf("re-uploaded document = %+v, want the same ID as %d", documents[0], added.ID)
+	}
+
+	// File documents are replaced at every sync and cannot be deleted
+	if _, err := db.SyncVEXFiles(map[string][]byte{"bad.json": []byte(`{}`)}); err == nil {
+		t.Error("SyncVEXFiles() accepted an invalid document")
+	}
+	if _, err := db.SyncVEXFiles(map[string][]byte{"vendor.json": []byte(`{"@context":"https://openvex.dev/ns/v0.2.0",
+		"statements":[{"vulnerability":"CVE-2024-0002","products":["sha256:other"],"status":"not_affected","justification":"component_not_present"}]}`)}); err != nil {
+		t.Fatalf("SyncVEXFiles() error = %v", err)
+	}
+	if n := findings("sha256:other"); n != 0 {
+		t.Errorf("findings after file VEX = %d, want 0", n)
+	}
+	documents, _ = db.GetVEXDocuments()
+	var fileID int64
+	for _, d := range documents {
+		if d.Name == VEXFilePrefix+"vendor.json" {
+			fileID = d.ID
+		}
+	}
+	if deleted, _, _ := db.DeleteVEXDocument(fileID); fileID == 0 || deleted {
+		t.Errorf("file document %d deleted through DeleteVEXDocument", fileID)
+	}
+
+	// Removing the statements restores the findings
+	if deleted, images, err := db.DeleteVEXDocument(added.ID); err != nil || !deleted || images != 2 {
+		t.Errorf("DeleteVEXDocument() = %v, %d images, %v", deleted, images, err)
+	}
+	if _, err := db.SyncVEXFiles(nil); err != nil {
+		t.Fatalf("SyncVEXFiles() error = %v", err)
+	}
+	if app, other := findings("sha256:app"), findings("sha256:other"); app != 2 || other != 2 {
+		t.Errorf("findings without VEX = %d and %d, want 2 and 2", app, other)
+	}
+	if all, _ := db.GetVEXSuppressions(""); len(all) != 0 || !db.VEX().Empty() {
+		t.Errorf("%d suppressions left without VEX documents", len(all))
+	}
+}
@@ -3,15 +3,50 @@
 package debug
 
 import (
+	"fmt"
+	"strings"
 	"sync"
 	"time"
 )
 
+// Category is a group of debug endpoints that is enabled on its own.
+type Category string
+
+const (
+	CategoryQueue    Category = "queue"     // Queue inspection, request metrics and forced rescans
+	CategorySQL      Category = "sql"       // Ad-hoc database queries
+	CategoryLogLevel Category = "log-level" // Changing the log level at runtime
+	CategoryFaults   Category = "faults"    // Fault injection
+)
+
+// AllCategories lists every debug category, in the order they are reported.
+var AllCategories = []Category{CategoryQueue, CategorySQL, CategoryLogLevel, CategoryFaults}
+
+// ParseCategories validates a list of category names (case-insensitive).
+func ParseCategories(names []string) ([]Category, error) {
+	var categories []Category
+	for _, name := range names {
+		category := Category(strings.ToLower(strings.TrimSpace(name)))
+		known := false
+		for _, c := range AllCategories {
+			if c == category {
+				known = true
+				break
+			}
+		}
+		if !known {
+			return nil, fmt.Errorf("unknown debug category %q (want queue, sql, log-level or faults)", name)
+		}
+		categories = append(categories, category)
+	}
+	return categories, nil
+}
+
 // DebugConfig holds debug mode configuration and metrics.
 type DebugConfig struct {
-	enabled bool
-	mu      sync.RWMutex
-	metrics *Metrics
+	categories map[Category]bool
+	mu         sync.RWMutex
+	metrics    *Metrics
 }
 
 // Metrics holds performance and request statistics.
@@ -30,22 +65,73 @@ type EndpointMetrics struct {
 	LastAccess    time.Time
 }
 
-// NewDebugConfig creates a new DebugConfig with the specified enabled state.
+// NewDebugConfig creates a new DebugConfig with either every category or
+// none enabled.
 func NewDebugConfig(enabled bool) *DebugConfig {
+	if enabled {
+		return NewDebugConfigForCategories(AllCategories...)
+	}
+	return NewDebugConfigForCategories()
+}
+
+// NewDebugConfigForCategories creates a new DebugConfig with only the given
+// categories enabled.
+func NewDebugConfigForCategories(categories ...Category) *DebugConfig {
+	enabled := make(map[Category]bool, len(categories))
+	for _, c := range categories {
+		enabled[c] = true
+	}
 	return &DebugConfig{
-		enabled: enabled,
+		categories: enabled,
 		metrics: &Metrics{
 			EndpointMetrics: make(map[string]*EndpointMetrics),
 		},
 	}
 }
 
-// IsEnabled returns whether debug mode is enabled.
-// This method is thread-safe.
+// NewDebugConfigFromSettings creates a DebugConfig from the debug_enabled
+// and debug_categories settings. debug_enabled turns on every category.
+func NewDebugConfigFromSettings(enabled bool, categories []string) (*DebugConfig, error) {
+	if enabled {
+		return NewDebugConfig(true), nil
+	}
+	parsed, err := ParseCategories(categories)
+	if err != nil {
+		return nil, err
+	}
+	return NewDebugConfigForCategories(parsed...), nil
+}
+
+// IsEnabled returns whether debug mode is enabled, i.e. whether at least one
+// category is. This method is thread-safe.
 func (d *DebugConfig) IsEnabled() bool {
 	d.mu.RLock()
 	defer d.mu.RUnlock()
-	return d.enabled
+	return len(d.categories) > 0
+}
+
+// CategoryEnabled returns whether the endpoints of category are enabled.
+// This method is thread-safe.
+func (d *DebugConfig) CategoryEnabled(category Category) bool {
+	if d == nil {
+		return false
+	}
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return d.categories[category]
+}
+
+// Categories returns the enabled categories in AllCategories order.
+func (d *DebugConfig) Categories() []Category {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	var categories []Category
+	for _, c := range AllCategories {
+		if d.categories[c] {
+			categories = append(categories, c)
+		}
+	}
+	return categories
 }
 
 // RecordRequest records a request's metrics.
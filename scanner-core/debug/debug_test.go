@@ -19,6 +19,41 @@ func TestNewDebugConfig(t *testing.T) {
 	}
 }
 
+func TestDebugCategories(t *testing.T) {
+	cfg := NewDebugConfig(true)
+	for _, c := range AllCategories {
+		if !cfg.CategoryEnabled(c) {
+			t.Errorf("category %s disabled with debug enabled", c)
+		}
+	}
+
+	cfg, err := NewDebugConfigFromSettings(false, []string{"Queue", " faults"})
+	if err != nil {
+		t.Fatalf("NewDebugConfigFromSettings: %v", err)
+	}
+	if !cfg.IsEnabled() {
+		t.Error("Expected debug to be enabled with categories")
+	}
+	if !cfg.CategoryEnabled(CategoryQueue) || !cfg.CategoryEnabled(CategoryFaults) {
+		t.Errorf("Categories() = %v, want queue and faults", cfg.Categories())
+	}
+	if cfg.CategoryEnabled(CategorySQL) || cfg.CategoryEnabled(CategoryLogLevel) {
+		t.Errorf("Categories() = %v, want only queue and faults", cfg.Categories())
+	}
+
+	if cfg, err = NewDebugConfigFromSettings(true, []string{"bogus"}); err != nil || len(cfg.Categories()) != len(AllCategories) {
+		t.Errorf("debug enabled: categories = %v, err = %v, want all", cfg.Categories(), err)
+	}
+	if _, err := NewDebugConfigFromSettings(false, []string{"bogus"}); err == nil {
+		t.Error("expected an error for an unknown category")
+	}
+
+	var nilCfg *DebugConfig
+	if nilCfg.CategoryEnabled(CategorySQL) {
+		t.Error("nil config reports a category enabled")
+	}
+}
+
 func TestRecordRequest(t *testing.T) {
 	cfg := NewDebugConfig(true)
 
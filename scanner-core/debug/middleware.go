@@ -29,6 +29,11 @@ func (rw *responseWriter) Write(b []byte) (int, error) {
 	return size, err
 }
 
+// Unwrap lets http.ResponseController reach the underlying writer (e.g. to flush streamed responses)
+func (rw *responseWriter) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
+
 // LoggingMiddleware provides verbose HTTP request/response logging and metrics collection
 // when debug mode is enabled. When disabled, it passes through with zero overhead.
 //
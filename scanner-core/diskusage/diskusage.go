@@ -0,0 +1,350 @@
+// Package diskusage monitors the volume holding the scanner's data directory
+// (SQLite database, Grype database, caches), publishes its usage as metrics
+// and API warnings, and prunes stored SBOM documents (oldest first) when usage
+// crosses a high-water mark, before a full volume makes scans fail.
+//
+// Pruned space stays inside the SQLite file as free pages that later writes
+// reuse, so usage is judged after subtracting those reclaimable bytes.
+package diskusage
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/bvboe/b2s-go/scanner-core/database"
+	"github.com/bvboe/b2s-go/scanner-core/logging"
+)
+
+var log = logging.For(logging.ComponentDiskUsage)
+
+// Volume status levels
+const (
+	StatusOK       = "ok"
+	StatusWarning  = "warning"
+	StatusCritical = "critical"
+)
+
+// Store is the database side of the monitor (implemented by database.DB)
+type Store interface {
+	GetReclaimableBytes() (int64, error)
+	PruneOldestSBOMs(targetBytes int64) (*database.SBOMPruneStats, error)
+}
+
+// SBOMStatsStore is implemented by stores that share SBOM documents between
+// images; its stats are included in the report
+type SBOMStatsStore interface {
+	GetSBOMBlobStats() (*database.SBOMBlobStats, error)
+}
+
+// Config configures a Monitor
+type Config struct {
+	// DBPath is the SQLite database file; the volume holding it is monitored
+	DBPath string
+	// Dirs are additional directories whose size is reported, by name (e.g. "grype")
+	Dirs map[string]string
+	// WarningPercent is the usage at which the API and logs start warning
+	WarningPercent int
+	// HighWaterPercent is the usage at which stored SBOMs are pruned
+	HighWaterPercent int
+	// PruneEnabled enables pruning at the high-water mark
+	PruneEnabled bool
+	// Interval is how often usage is checked by Start
+	Interval time.Duration
+}
+
+// Component is the on-disk size of one part of the data directory
+type Component struct {
+	Name  string `json:"name"`
+	Path  string `json:"path"`
+	Bytes int64  `json:"bytes"`
+}
+
+// Prune describes a pruning run
+type Prune struct {
+	At         time.Time `json:"at"`
+	Images     int       `json:"images"`
+	BytesFreed int64     `json:"bytes_freed"`
+}
+
+// Report is the result of a usage check
+type Report struct {
+	Path             string                  `json:"path"`
+	TotalBytes       int64                   `json:"total_bytes"`
+	UsedBytes        int64                   `json:"used_bytes"`
+	AvailableBytes   int64                   `json:"available_bytes"`
+	ReclaimableBytes int64                   `json:"reclaimable_bytes"`
+	UsedPercent      float64                 `json:"used_percent"` // excluding reclaimable bytes
+	Status           string                  `json:"status"`
+	Warnings         []string                `json:"warnings"`
+	WarningPercent   int                     `json:"warning_percent"`
+	HighWaterPercent int                     `json:"high_water_percent"`
+	PruneEnabled     bool                    `json:"prune_enabled"`
+	Components       []Component             `json:"components"`
+	SBOMs            *database.SBOMBlobStats `json:"sboms,omitempty"`
+	LastPrune        *Prune                  `json:"last_prune,omitempty"`
+	CheckedAt        time.Time               `json:"checked_at"`
+}
+
+// Monitor periodically checks the data volume and prunes when it fills up
+type Monitor struct {
+	store  Store
+	cfg    Config
+	statfs func(path string) (total, available int64, err error)
+
+	mu           sync.RWMutex
+	report       *Report
+	lastPrune    *Prune
+	prunedImages int64
+	prunedBytes  int64
+}
+
+// NewMonitor creates a Monitor for the volume holding cfg.DBPath
+func NewMonitor(store Store, cfg Config) *Monitor {
+	if cfg.WarningPercent <= 0 {
+		cfg.WarningPercent = 80
+	}
+	if cfg.HighWaterPercent <= 0 {
+		cfg.HighWaterPercent = 90
+	}
+	if cfg.Interval <= 0 {
+		cfg.Interval = 5 * time.Minute
+	}
+	return &Monitor{store: store, cfg: cfg, statfs: statfs}
+}
+
+// Start checks usage immediately and then every cfg.Interval until ctx is cancelled
+func (m *Monitor) Start(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(m.cfg.Interval)
+		defer ticker.Stop()
+		for {
+			if _, err := m.Check(); err != nil {
+				log.Warn("failed to check data volume usage", "error", err)
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Report returns the latest usage report, or nil before the first check
+func (m *Monitor) Report() *Report {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.report
+}
+
+// Check measures the data volume, prunes stored SBOMs when usage is at or
+// above the high-water mark and returns the resulting report
+func (m *Monitor) Check() (*Report, error) {
+	dir := filepath.Dir(m.cfg.DBPath)
+	total, available, err := m.statfs(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat %s: %w", dir, err)
+	}
+	reclaimable, err := m.store.GetReclaimableBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	var pruneErr error
+	if m.cfg.PruneEnabled && usedPercent(total, available, reclaimable) >= float64(m.cfg.HighWaterPercent) {
+		// Free enough to get back under the warning threshold
+		target := (total - available - reclaimable) - total*int64(m.cfg.WarningPercent)/100
+		log.Warn("data volume above high-water mark, pruning stored SBOMs",
+			"used_percent", usedPercent(total, available, reclaimable),
+			"high_water_percent", m.cfg.HighWaterPercent, "target_bytes", target)
+		var stats *database.SBOMPruneStats
+		if stats, pruneErr = m.store.PruneOldestSBOMs(target); pruneErr == nil {
+			m.recordPrune(stats)
+			if total, available, err = m.statfs(dir); err != nil {
+				return nil, fmt.Errorf("failed to stat %s: %w", dir, err)
+			}
+			if reclaimable, err = m.store.GetReclaimableBytes(); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	report := &Report{
+		Path:             dir,
+		TotalBytes:       total,
+		UsedBytes:        total - available,
+		AvailableBytes:   available,
+		ReclaimableBytes: reclaimable,
+		UsedPercent:      usedPercent(total, available, reclaimable),
+		Status:           StatusOK,
+		Warnings:         []string{},
+		WarningPercent:   m.cfg.WarningPercent,
+		HighWaterPercent: m.cfg.HighWaterPercent,
+		PruneEnabled:     m.cfg.PruneEnabled,
+		Components:       m.components(),
+		CheckedAt:        time.Now().UTC(),
+	}
+	if store, ok := m.store.(SBOMStatsStore); ok {
+		if report.SBOMs, err = store.GetSBOMBlobStats(); err != nil {
+			log.Warn("failed to get SBOM storage stats", "error", err)
+		}
+	}
+	m.evaluate(report, pruneErr)
+
+	m.mu.Lock()
+	report.LastPrune = m.lastPrune
+	m.report = report
+	m.mu.Unlock()
+
+	if report.Status != StatusOK {
+		log.Warn("data volume filling up", "path", dir, "used_percent", report.UsedPercent,
+			"available_bytes", available, "status", report.Status)
+	}
+	return report, pruneErr
+}
+
+// evaluate sets the status and warnings of a report
+func (m *Monitor) evaluate(report *Report, pruneErr error) {
+	if report.UsedPercent >= float64(m.cfg.WarningPercent) {
+		report.Status = StatusWarning
+		report.Warnings = append(report.Warnings, fmt.Sprintf(
+			"data volume is %.1f%% full (%s available); scans will fail once it is full",
+			report.UsedPercent, formatBytes(report.AvailableBytes)))
+	}
+	if report.UsedPercent < float64(m.cfg.HighWaterPercent) {
+		return
+	}
+	report.Status = StatusCritical
+	switch {
+	case !m.cfg.PruneEnabled:
+		report.Warnings = append(report.Warnings, fmt.Sprintf(
+			"data volume is above the %d%% high-water mark and automatic pruning is disabled; increase the volume size",
+			m.cfg.HighWaterPercent))
+	case pruneErr != nil:
+		report.Warnings = append(report.Warnings, "pruning stored SBOMs failed: "+pruneErr.Error())
+	default:
+		report.Warnings = append(report.Warnings, fmt.Sprintf(
+			"data volume is still above the %d%% high-water mark after pruning stored SBOMs; increase the volume size",
+			m.cfg.HighWaterPercent))
+	}
+}
+
+func (m *Monitor) recordPrune(stats *database.SBOMPruneStats) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.lastPrune = &Prune{At: time.Now().UTC(), Images: stats.Images, BytesFreed: stats.BytesFreed}
+	m.prunedImages += int64(stats.Images)
+	m.prunedBytes += stats.BytesFreed
+}
+
+// components returns the on-disk size of the database and configured directories
+func (m *Monitor) components() []Component {
+	var dbBytes int64
+	for _, suffix := range []string{"", "-wal", "-shm"} {
+		if info, err := os.Stat(m.cfg.DBPath + suffix); err == nil {
+			dbBytes += info.Size()
+		}
+	}
+	components := []Component{{Name: "database", Path: m.cfg.DBPath, Bytes: dbBytes}}
+
+	for name, dir := range m.cfg.Dirs {
+		components = append(components, Component{Name: name, Path: dir, Bytes: dirSize(dir)})
+	}
+	sort.Slice(components[1:], func(i, j int) bool {
+		return components[1+i].Name < components[1+j].Name
+	})
+	return components
+}
+
+// WriteMetrics writes the data volume gauges in Prometheus text format.
+// Nothing is written before the first check.
+func (m *Monitor) WriteMetrics(w io.Writer) {
+	m.mu.RLock()
+	report, prunedImages, prunedBytes := m.report, m.prunedImages, m.prunedBytes
+	m.mu.RUnlock()
+	if report == nil {
+		return
+	}
+
+	_, _ = fmt.Fprintf(w, "# HELP bjorn2scan_data_volume_size_bytes Size of the volume holding the data directory\n")
+	_, _ = fmt.Fprintf(w, "# TYPE bjorn2scan_data_volume_size_bytes gauge\n")
+	_, _ = fmt.Fprintf(w, "bjorn2scan_data_volume_size_bytes %d\n", report.TotalBytes)
+	_, _ = fmt.Fprintf(w, "# HELP bjorn2scan_data_volume_available_bytes Free space on the volume holding the data directory\n")
+	_, _ = fmt.Fprintf(w, "# TYPE bjorn2scan_data_volume_available_bytes gauge\n")
+	_, _ = fmt.Fprintf(w, "bjorn2scan_data_volume_available_bytes %d\n", report.AvailableBytes)
+	_, _ = fmt.Fprintf(w, "# HELP bjorn2scan_data_volume_reclaimable_bytes Free pages inside the database file, reused by later writes\n")
+	_, _ = fmt.Fprintf(w, "# TYPE bjorn2scan_data_volume_reclaimable_bytes gauge\n")
+	_, _ = fmt.Fprintf(w, "bjorn2scan_data_volume_reclaimable_bytes %d\n", report.ReclaimableBytes)
+	_, _ = fmt.Fprintf(w, "# HELP bjorn2scan_data_volume_used_ratio Fraction of the data volume in use, excluding reclaimable database pages\n")
+	_, _ = fmt.Fprintf(w, "# TYPE bjorn2scan_data_volume_used_ratio gauge\n")
+	_, _ = fmt.Fprintf(w, "bjorn2scan_data_volume_used_ratio %g\n", report.UsedPercent/100)
+	_, _ = fmt.Fprintf(w, "# HELP bjorn2scan_data_volume_high_water_ratio Usage at which stored SBOMs are pruned\n")
+	_, _ = fmt.Fprintf(w, "# TYPE bjorn2scan_data_volume_high_water_ratio gauge\n")
+	_, _ = fmt.Fprintf(w, "bjorn2scan_data_volume_high_water_ratio %g\n", float64(report.HighWaterPercent)/100)
+	_, _ = fmt.Fprintf(w, "# HELP bjorn2scan_data_component_bytes On-disk size of parts of the data directory\n")
+	_, _ = fmt.Fprintf(w, "# TYPE bjorn2scan_data_component_bytes gauge\n")
+	for _, c := range report.Components {
+		_, _ = fmt.Fprintf(w, "bjorn2scan_data_component_bytes{component=%q} %d\n", c.Name, c.Bytes)
+	}
+	_, _ = fmt.Fprintf(w, "# HELP bjorn2scan_sbom_pruned_images_total Stored SBOMs pruned to free disk space\n")
+	_, _ = fmt.Fprintf(w, "# TYPE bjorn2scan_sbom_pruned_images_total counter\n")
+	_, _ = fmt.Fprintf(w, "bjorn2scan_sbom_pruned_images_total %d\n", prunedImages)
+	_, _ = fmt.Fprintf(w, "# HELP bjorn2scan_sbom_pruned_bytes_total Bytes of stored SBOMs pruned to free disk space\n")
+	_, _ = fmt.Fprintf(w, "# TYPE bjorn2scan_sbom_pruned_bytes_total counter\n")
+	_, _ = fmt.Fprintf(w, "bjorn2scan_sbom_pruned_bytes_total %d\n", prunedBytes)
+	if report.SBOMs != nil {
+		_, _ = fmt.Fprintf(w, "# HELP bjorn2scan_sbom_documents Distinct SBOM documents stored, shared by images with identical content\n")
+		_, _ = fmt.Fprintf(w, "# TYPE bjorn2scan_sbom_documents gauge\n")
+		_, _ = fmt.Fprintf(w, "bjorn2scan_sbom_documents %d\n", report.SBOMs.Blobs)
+		_, _ = fmt.Fprintf(w, "# HELP bjorn2scan_sbom_deduplicated_bytes Compressed SBOM bytes not stored because images share identical documents\n")
+		_, _ = fmt.Fprintf(w, "# TYPE bjorn2scan_sbom_deduplicated_bytes gauge\n")
+		_, _ = fmt.Fprintf(w, "bjorn2scan_sbom_deduplicated_bytes %d\n", report.SBOMs.SavedBytes)
+	}
+}
+
+// usedPercent is the share of the volume in use, not counting reclaimable bytes
+func usedPercent(total, available, reclaimable int64) float64 {
+	if total <= 0 {
+		return 0
+	}
+	used := max(total-available-reclaimable, 0)
+	return float64(used) * 100 / float64(total)
+}
+
+// dirSize sums the sizes of the regular files below dir
+func dirSize(dir string) int64 {
+	var size int64
+	_ = filepath.WalkDir(dir, func(_ string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if d.Type().IsRegular() {
+			if info, err := d.Info(); err == nil {
+				size += info.Size()
+			}
+		}
+		return nil
+	})
+	return size
+}
+
+// formatBytes renders a byte count in binary units
+func formatBytes(b int64) string {
+	const unit = 1024
+	if b < unit {
+		return fmt.Sprintf("%d B", b)
+	}
+	div, exp := int64(unit), 0
+	for n := b / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(b)/float64(div), "KMGTPE"[exp])
+}
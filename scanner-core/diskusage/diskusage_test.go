@@ -0,0 +1,170 @@
+package diskusage
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/bvboe/b2s-go/scanner-core/database"
+)
+
+// fakeStore frees what it is asked to, up to its stored SBOM bytes, and turns
+// the freed bytes into reclaimable pages
+type fakeStore struct {
+	reclaimable int64
+	storedSBOMs int64
+	targets     []int64
+}
+
+func (s *fakeStore) GetReclaimableBytes() (int64, error) { return s.reclaimable, nil }
+
+func (s *fakeStore) PruneOldestSBOMs(target int64) (*database.SBOMPruneStats, error) {
+	s.targets = append(s.targets, target)
+	freed := min(target, s.storedSBOMs)
+	s.storedSBOMs -= freed
+	s.reclaimable += freed
+	return &database.SBOMPruneStats{Images: 1, BytesFreed: freed}, nil
+}
+
+func newTestMonitor(t *testing.T, store Store, used int64, prune bool) *Monitor {
+	t.Helper()
+	dir := t.TempDir()
+	dbPath := filepath.Join(dir, "containers.db")
+	if err := os.WriteFile(dbPath, make([]byte, 100), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Join(dir, "grype"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "grype", "vulnerability.db"), make([]byte, 50), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	m := NewMonitor(store, Config{
+		DBPath:       dbPath,
+		Dirs:         map[string]string{"grype": filepath.Join(dir, "grype")},
+		PruneEnabled: prune,
+	})
+	m.statfs = func(string) (int64, int64, error) { return 1000, 1000 - used, nil }
+	return m
+}
+
+func TestMonitorStatus(t *testing.T) {
+	tests := []struct {
+		name   string
+		used   int64
+		status string
+	}{
+		{"ok", 500, StatusOK},
+		{"warning", 850, StatusWarning},
+		{"critical", 950, StatusCritical},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report, err := newTestMonitor(t, &fakeStore{}, tt.used, false).Check()
+			if err != nil {
+				t.Fatalf("Check() error = %v", err)
+			}
+			if report.Status != tt.status {
+				t.Errorf("Status = %q, want %q", report.Status, tt.status)
+			}
+			if (len(report.Warnings) > 0) != (tt.status != StatusOK) {
+				t.Errorf("Unexpected warnings %v for status %q", report.Warnings, report.Status)
+			}
+		})
+	}
+}
+
+func TestMonitorPrunesAboveHighWater(t *testing.T) {
+	store := &fakeStore{reclaimable: 20, storedSBOMs: 500}
+	m := newTestMonitor(t, store, 950, true)
+
+	report, err := m.Check()
+	if err != nil {
+		t.Fatalf("Check() error = %v", err)
+	}
+	// 950 used - 20 reclaimable, back down to the 80% warning level
+	if len(store.targets) != 1 || store.targets[0] != 130 {
+		t.Fatalf("Expected one prune of 130 bytes, got %v", store.targets)
+	}
+	if report.LastPrune == nil || report.LastPrune.BytesFreed != 130 {
+		t.Errorf("LastPrune = %+v", report.LastPrune)
+	}
+	if report.Status != StatusWarning || report.UsedPercent != 80 {
+		t.Errorf("Status = %q at %.1f%%, want warning at 80%%", report.Status, report.UsedPercent)
+	}
+
+	// Reclaimed pages are reused, so the next check doesn't prune again
+	if _, err := m.Check(); err != nil {
+		t.Fatalf("Check() error = %v", err)
+	}
+	if len(store.targets) != 1 {
+		t.Errorf("Expected no further pruning, got %v", store.targets)
+	}
+
+	var buf bytes.Buffer
+	m.WriteMetrics(&buf)
+	for _, want := range []string{
+		"bjorn2scan_data_volume_used_ratio 0.8\n",
+		"bjorn2scan_data_volume_available_bytes 50\n",
+		`bjorn2scan_data_component_bytes{component="database"} 100`,
+		`bjorn2scan_data_component_bytes{component="grype"} 50`,
+		"bjorn2scan_sbom_pruned_bytes_total 130\n",
+	} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("Expected %q in metrics:\n%s", want, buf.String())
+		}
+	}
+}
+
+func TestMonitorCriticalWhenPruningIsNotEnough(t *testing.T) {
+	store := &fakeStore{storedSBOMs: 10}
+	report, err := newTestMonitor(t, store, 990, true).Check()
+	if err != nil {
+		t.Fatalf("Check() error = %v", err)
+	}
+	if report.Status != StatusCritical {
+		t.Errorf("Status = %q, want critical", report.Status)
+	}
+	if !strings.Contains(strings.Join(report.Warnings, "\n"), "after pruning") {
+		t.Errorf("Expected a warning that pruning was not enough, got %v", report.Warnings)
+	}
+}
+
+func TestMonitorWritesNothingBeforeCheck(t *testing.T) {
+	var buf bytes.Buffer
+	NewMonitor(&fakeStore{}, Config{}).WriteMetrics(&buf)
+	if buf.Len() != 0 {
+		t.Errorf("Expected no metrics before the first check, got:\n%s", buf.String())
+	}
+}
+
+// blobStore also shares SBOM documents between images
+type blobStore struct {
+	fakeStore
+}
+
+func (s *blobStore) GetSBOMBlobStats() (*database.SBOMBlobStats, error) {
+	return &database.SBOMBlobStats{Blobs: 3, References: 5, StoredBytes: 300, SavedBytes: 200}, nil
+}
+
+func TestMonitorReportsSharedSBOMs(t *testing.T) {
+	m := newTestMonitor(t, &blobStore{}, 500, false)
+	report, err := m.Check()
+	if err != nil {
+		t.Fatalf("Check() error = %v", err)
+	}
+	if report.SBOMs == nil || report.SBOMs.SavedBytes != 200 {
+		t.Errorf("SBOMs = %+v, want the store's stats", report.SBOMs)
+	}
+
+	var buf bytes.Buffer
+	m.WriteMetrics(&buf)
+	for _, want := range []string{"bjorn2scan_sbom_documents 3", "bjorn2scan_sbom_deduplicated_bytes 200"} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("Expected %q in metrics, got:\n%s", want, buf.String())
+		}
+	}
+}
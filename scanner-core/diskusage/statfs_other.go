@@ -0,0 +1,10 @@
+//go:build !linux && !darwin
+
+package diskusage
+
+import "errors"
+
+// statfs is not supported on this platform
+func statfs(string) (total, available int64, err error) {
+	return 0, 0, errors.New("disk usage monitoring is not supported on this platform")
+}
@@ -0,0 +1,15 @@
+//go:build linux || darwin
+
+package diskusage
+
+import "syscall"
+
+// statfs returns the size and the space available to unprivileged users of
+// the filesystem holding path
+func statfs(path string) (total, available int64, err error) {
+	var st syscall.Statfs_t
+	if err := syscall.Statfs(path, &st); err != nil {
+		return 0, 0, err
+	}
+	return int64(st.Blocks) * int64(st.Bsize), int64(st.Bavail) * int64(st.Bsize), nil
+}
@@ -0,0 +1,382 @@
+// Package eol detects images running operating system releases that are past
+// (or close to) their end of life. Such images need attention even when they
+// have no known vulnerabilities, because their distribution no longer ships
+// security fixes.
+//
+// Lifecycle data is bundled (a snapshot in the endoflife.date format) and can
+// be refreshed at runtime from an endoflife.date compatible API.
+package eol
+
+import (
+	"bytes"
+	"context"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bvboe/b2s-go/scanner-core/database"
+	"github.com/bvboe/b2s-go/scanner-core/logging"
+)
+
+var log = logging.For(logging.ComponentOSEOL)
+
+//go:embed data.json
+var bundledData []byte
+
+// Lifecycle statuses of an OS release
+const (
+	StatusSupported       = "supported"
+	StatusApproachingEOL  = "approaching_eol"  // end of life within the warning window
+	StatusExtendedSupport = "extended_support" // past end of life, within extended (LTS/ESM) support
+	StatusEOL             = "eol"
+	StatusUnknown         = "unknown" // distro or release not in the dataset
+)
+
+// DefaultWarningDays is how long before end of life a release is reported as approaching it
+const DefaultWarningDays = 90
+
+// dateLayout is the endoflife.date date format
+const dateLayout = "2006-01-02"
+
+// Date is an endoflife.date lifecycle value: a date, false or true. For end
+// of life, true means it has already passed; for extended support, that
+// extended support is available. Either way without a known date.
+type Date struct {
+	Time time.Time
+	True bool
+}
+
+// UnmarshalJSON accepts "YYYY-MM-DD", true and false
+func (d *Date) UnmarshalJSON(b []byte) error {
+	*d = Date{}
+	switch s := string(bytes.TrimSpace(b)); s {
+	case "null", "false":
+		return nil
+	case "true":
+		d.True = true
+		return nil
+	}
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return fmt.Errorf("invalid lifecycle date %s", b)
+	}
+	t, err := time.Parse(dateLayout, s)
+	if err != nil {
+		return fmt.Errorf("invalid lifecycle date %q: %w", s, err)
+	}
+	d.Time = t
+	return nil
+}
+
+// MarshalJSON writes the date in the format it was read
+func (d Date) MarshalJSON() ([]byte, error) {
+	switch {
+	case !d.Time.IsZero():
+		return json.Marshal(d.Time.Format(dateLayout))
+	case d.True:
+		return []byte("true"), nil
+	default:
+		return []byte("false"), nil
+	}
+}
+
+// passed reports whether an end of life date is at or before now
+func (d Date) passed(now time.Time) bool {
+	return d.True || (!d.Time.IsZero() && !now.Before(d.Time))
+}
+
+// active reports whether an extended support date is still ahead of now
+func (d Date) active(now time.Time) bool {
+	return d.True || (!d.Time.IsZero() && now.Before(d.Time))
+}
+
+// String returns the date as YYYY-MM-DD, or "" when there is none
+func (d Date) String() string {
+	if d.Time.IsZero() {
+		return ""
+	}
+	return d.Time.Format(dateLayout)
+}
+
+// cycleName is a release cycle, which endoflife.date returns as a string or a number
+type cycleName string
+
+func (c *cycleName) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err == nil {
+		*c = cycleName(s)
+		return nil
+	}
+	var n json.Number
+	if err := json.Unmarshal(b, &n); err != nil {
+		return fmt.Errorf("invalid release cycle %s", b)
+	}
+	*c = cycleName(n.String())
+	return nil
+}
+
+// Cycle is one release cycle of a product, as served by endoflife.date
+type Cycle struct {
+	Cycle           cycleName `json:"cycle"`
+	ReleaseDate     string    `json:"releaseDate,omitempty"`
+	EOL             Date      `json:"eol"`
+	ExtendedSupport Date      `json:"extendedSupport"`
+}
+
+// Product is the lifecycle of one distro, keyed by its endoflife.date product name
+type Product struct {
+	Product string  `json:"product"`
+	Cycles  []Cycle `json:"cycles"`
+}
+
+// dataset is the bundled data file
+type dataset struct {
+	Updated string             `json:"updated"`
+	Distros map[string]Product `json:"distros"` // keyed by the distro name Grype reports
+}
+
+// Status is the lifecycle status of one OS release
+type Status struct {
+	OSName          string `json:"os_name"`
+	OSVersion       string `json:"os_version"`
+	Cycle           string `json:"cycle,omitempty"`
+	Status          string `json:"status"`
+	EOLDate         string `json:"eol_date,omitempty"`
+	ExtendedSupport string `json:"extended_support_until,omitempty"`
+	DaysRemaining   *int   `json:"days_remaining,omitempty"` // until end of life; negative once passed
+}
+
+// IsEOL reports whether the release no longer receives security fixes
+func (s Status) IsEOL() bool {
+	return s.Status == StatusEOL
+}
+
+// DatasetInfo describes where the lifecycle data in use came from
+type DatasetInfo struct {
+	Source    string    `json:"source"` // "bundled" or the API base URL
+	UpdatedAt string    `json:"updated_at"`
+	Distros   int       `json:"distros"`
+	CheckedAt time.Time `json:"checked_at,omitzero"`
+	LastError string    `json:"last_error,omitempty"`
+}
+
+// Catalog holds the lifecycle data and answers status lookups. It is safe for
+// concurrent use.
+type Catalog struct {
+	warning time.Duration
+	client  *http.Client
+
+	mu      sync.RWMutex
+	distros map[string]Product
+	info    DatasetInfo
+}
+
+// NewCatalog creates a Catalog from the bundled data. warningDays is how long
+// before end of life a release is reported as approaching it (0 uses DefaultWarningDays).
+func NewCatalog(warningDays int) (*Catalog, error) {
+	var data dataset
+	if err := json.Unmarshal(bundledData, &data); err != nil {
+		return nil, fmt.Errorf("failed to parse bundled lifecycle data: %w", err)
+	}
+	if warningDays <= 0 {
+		warningDays = DefaultWarningDays
+	}
+	c := &Catalog{
+		warning: time.Duration(warningDays) * 24 * time.Hour,
+		client:  &http.Client{Timeout: 30 * time.Second},
+		distros: data.Distros,
+		info:    DatasetInfo{Source: "bundled", UpdatedAt: data.Updated, Distros: len(data.Distros)},
+	}
+	return c, nil
+}
+
+// Info describes the lifecycle data in use
+func (c *Catalog) Info() DatasetInfo {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.info
+}
+
+// Lookup returns the lifecycle status of an OS release as reported by Grype
+// (e.g. "debian" "12", "alpine" "3.18.4")
+func (c *Catalog) Lookup(osName, osVersion string, now time.Time) Status {
+	status := Status{OSName: osName, OSVersion: osVersion, Status: StatusUnknown}
+
+	c.mu.RLock()
+	product, ok := c.distros[strings.ToLower(osName)]
+	c.mu.RUnlock()
+	if !ok {
+		return status
+	}
+	cycle, ok := matchCycle(product.Cycles, osVersion)
+	if !ok {
+		return status
+	}
+
+	status.Cycle = string(cycle.Cycle)
+	status.EOLDate = cycle.EOL.String()
+	status.ExtendedSupport = cycle.ExtendedSupport.String()
+	if !cycle.EOL.Time.IsZero() {
+		days := int(cycle.EOL.Time.Sub(now).Hours() / 24)
+		status.DaysRemaining = &days
+	}
+
+	switch {
+	case cycle.EOL.passed(now) && cycle.ExtendedSupport.active(now):
+		status.Status = StatusExtendedSupport
+	case cycle.EOL.passed(now):
+		status.Status = StatusEOL
+	case !cycle.EOL.Time.IsZero() && cycle.EOL.Time.Sub(now) <= c.warning:
+		status.Status = StatusApproachingEOL
+	default:
+		status.Status = StatusSupported
+	}
+	return status
+}
+
+// matchCycle finds the cycle a version belongs to: the longest cycle equal to
+// the version or a dot-separated prefix of it ("3.18.4" is in cycle "3.18",
+// "8.9" in cycle "8")
+func matchCycle(cycles []Cycle, version string) (Cycle, bool) {
+	var best Cycle
+	found := false
+	for _, cycle := range cycles {
+		name := string(cycle.Cycle)
+		if name == "" || (version != name && !strings.HasPrefix(version, name+".")) {
+			continue
+		}
+		if !found || len(name) > len(best.Cycle) {
+			best, found = cycle, true
+		}
+	}
+	return best, found
+}
+
+// Update refreshes the lifecycle data of every known distro from an
+// endoflife.date compatible API (baseURL/<product>.json). Distros whose
+// request fails keep their current data.
+func (c *Catalog) Update(ctx context.Context, baseURL string) error {
+	baseURL = strings.TrimSuffix(baseURL, "/")
+	c.mu.RLock()
+	products := make(map[string]string, len(c.distros))
+	for distro, product := range c.distros {
+		products[distro] = product.Product
+	}
+	c.mu.RUnlock()
+
+	updated := make(map[string][]Cycle, len(products))
+	var errs []string
+	for distro, product := range products {
+		cycles, err := c.fetch(ctx, baseURL+"/"+product+".json")
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", product, err))
+			continue
+		}
+		updated[distro] = cycles
+	}
+	sort.Strings(errs)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for distro, cycles := range updated {
+		product := c.distros[distro]
+		product.Cycles = cycles
+		c.distros[distro] = product
+	}
+	c.info.CheckedAt = time.Now().UTC()
+	c.info.LastError = strings.Join(errs, "; ")
+	if len(updated) > 0 {
+		c.info.Source = baseURL
+		c.info.UpdatedAt = c.info.CheckedAt.Format(dateLayout)
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("failed to update lifecycle data for %d of %d distros: %s", len(errs), len(products), c.info.LastError)
+	}
+	log.Info("OS lifecycle data updated", "source", baseURL, "distros", len(updated))
+	return nil
+}
+
+// fetch downloads and parses the cycles of one product
+func (c *Catalog) fetch(ctx context.Context, url string) ([]Cycle, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+
+	var cycles []Cycle
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 4<<20)).Decode(&cycles); err != nil {
+		return nil, fmt.Errorf("invalid response: %w", err)
+	}
+	if len(cycles) == 0 {
+		return nil, fmt.Errorf("no release cycles in response")
+	}
+	return cycles, nil
+}
+
+// DistroSummary is the lifecycle status of one OS release with how many
+// running images and containers use it
+type DistroSummary struct {
+	Status
+	Images     int `json:"image_count"`
+	Containers int `json:"container_count"`
+}
+
+// Summary is the lifecycle status of every OS release in use
+type Summary struct {
+	Distributions []DistroSummary `json:"distributions"`
+	Images        map[string]int  `json:"images_by_status"`
+	Containers    map[string]int  `json:"containers_by_status"`
+	Dataset       DatasetInfo     `json:"dataset"`
+}
+
+// Summarize looks up the lifecycle status of each OS release in counts.
+// Distributions are ordered by urgency: end of life first, supported last.
+func (c *Catalog) Summarize(counts []database.OSVersionCount, now time.Time) *Summary {
+	summary := &Summary{
+		Distributions: make([]DistroSummary, 0, len(counts)),
+		Images:        map[string]int{},
+		Containers:    map[string]int{},
+		Dataset:       c.Info(),
+	}
+	for _, status := range []string{StatusEOL, StatusExtendedSupport, StatusApproachingEOL, StatusSupported, StatusUnknown} {
+		summary.Images[status] = 0
+		summary.Containers[status] = 0
+	}
+	for _, count := range counts {
+		status := c.Lookup(count.OSName, count.OSVersion, now)
+		summary.Distributions = append(summary.Distributions, DistroSummary{
+			Status: status, Images: count.Images, Containers: count.Containers,
+		})
+		summary.Images[status.Status] += count.Images
+		summary.Containers[status.Status] += count.Containers
+	}
+	sort.SliceStable(summary.Distributions, func(i, j int) bool {
+		a, b := summary.Distributions[i], summary.Distributions[j]
+		if urgency[a.Status.Status] != urgency[b.Status.Status] {
+			return urgency[a.Status.Status] < urgency[b.Status.Status]
+		}
+		return a.Containers > b.Containers
+	})
+	return summary
+}
+
+// urgency orders statuses for Summarize
+var urgency = map[string]int{
+	StatusEOL: 0, StatusExtendedSupport: 1, StatusApproachingEOL: 2, StatusUnknown: 3, StatusSupported: 4,
+}
@@ -0,0 +1,141 @@
+package eol
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bvboe/b2s-go/scanner-core/database"
+)
+
+func date(s string) time.Time {
+	t, err := time.Parse(dateLayout, s)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func newTestCatalog(t *testing.T) *Catalog {
+	t.Helper()
+	c, err := NewCatalog(90)
+	if err != nil {
+		t.Fatalf("NewCatalog() error = %v", err)
+	}
+	return c
+}
+
+func TestLookup(t *testing.T) {
+	c := newTestCatalog(t)
+	now := date("2026-10-16")
+
+	tests := []struct {
+		name      string
+		osName    string
+		osVersion string
+		want      string
+		wantCycle string
+	}{
+		{"end of life", "debian", "9", StatusEOL, "9"},
+		{"extended support", "debian", "12", StatusExtendedSupport, "12"},
+		{"supported", "debian", "13", StatusSupported, "13"},
+		{"patch version matches minor cycle", "alpine", "3.18.4", StatusEOL, "3.18"},
+		{"approaching end of life", "alpine", "3.21.2", StatusApproachingEOL, "3.21"},
+		{"minor version matches major cycle", "redhat", "9.4", StatusSupported, "9"},
+		{"case insensitive distro", "Ubuntu", "22.04", StatusSupported, "22.04"},
+		{"unknown release", "debian", "99", StatusUnknown, ""},
+		{"unknown distro", "plan9", "4", StatusUnknown, ""},
+		{"prefix is not a cycle", "alpine", "3.1", StatusUnknown, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := c.Lookup(tt.osName, tt.osVersion, now)
+			if got.Status != tt.want || got.Cycle != tt.wantCycle {
+				t.Errorf("Lookup(%q, %q) = %s (cycle %q), want %s (cycle %q)",
+					tt.osName, tt.osVersion, got.Status, got.Cycle, tt.want, tt.wantCycle)
+			}
+		})
+	}
+
+	status := c.Lookup("debian", "9", now)
+	if !status.IsEOL() || status.EOLDate != "2020-07-18" || status.DaysRemaining == nil || *status.DaysRemaining >= 0 {
+		t.Errorf("Lookup(debian 9) = %+v, want eol since 2020-07-18 with negative days remaining", status)
+	}
+}
+
+func TestUpdate(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/debian.json":
+			_, _ = w.Write([]byte(`[
+				{"cycle": "13", "eol": "2028-08-09", "extendedSupport": "2030-06-30"},
+				{"cycle": "12", "eol": "2026-06-10", "extendedSupport": false},
+				{"cycle": "9", "eol": true}
+			]`))
+		case "/api/amazon-linux.json":
+			_, _ = w.Write([]byte(`[{"cycle": 2023, "eol": "2029-06-30"}]`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	c := newTestCatalog(t)
+	err := c.Update(context.Background(), server.URL+"/api/")
+	if err == nil || !strings.Contains(err.Error(), "alpine-linux") {
+		t.Errorf("Update() error = %v, want failures for distros the server doesn't know", err)
+	}
+
+	now := date("2026-10-16")
+	if got := c.Lookup("debian", "12", now).Status; got != StatusEOL {
+		t.Errorf("updated debian 12 = %s, want %s (no extended support)", got, StatusEOL)
+	}
+	if got := c.Lookup("debian", "9", now).Status; got != StatusEOL {
+		t.Errorf("updated debian 9 = %s, want %s", got, StatusEOL)
+	}
+	if got := c.Lookup("debian", "11", now).Status; got != StatusUnknown {
+		t.Errorf("debian 11 after update = %s, want %s (not in updated data)", got, StatusUnknown)
+	}
+	if got := c.Lookup("amazonlinux", "2023", now).Status; got != StatusSupported {
+		t.Errorf("numeric cycle amazonlinux 2023 = %s, want %s", got, StatusSupported)
+	}
+	if got := c.Lookup("alpine", "3.18.4", now).Status; got != StatusEOL {
+		t.Errorf("alpine kept bundled data = %s, want %s", got, StatusEOL)
+	}
+
+	info := c.Info()
+	if info.Source != server.URL+"/api" || info.LastError == "" || info.CheckedAt.IsZero() {
+		t.Errorf("Info() = %+v, want source %s with last error", info, server.URL+"/api")
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	c := newTestCatalog(t)
+	summary := c.Summarize([]database.OSVersionCount{
+		{OSName: "alpine", OSVersion: "3.22.1", Images: 4, Containers: 10},
+		{OSName: "debian", OSVersion: "9", Images: 2, Containers: 3},
+		{OSName: "debian", OSVersion: "12", Images: 1, Containers: 1},
+		{OSName: "wolfi", OSVersion: "20230201", Images: 1, Containers: 2},
+	}, date("2026-10-16"))
+
+	var order []string
+	for _, d := range summary.Distributions {
+		order = append(order, d.OSName+" "+d.OSVersion)
+	}
+	want := "debian 9,debian 12,wolfi 20230201,alpine 3.22.1"
+	if strings.Join(order, ",") != want {
+		t.Errorf("order = %s, want %s", strings.Join(order, ","), want)
+	}
+	if summary.Images[StatusEOL] != 2 || summary.Containers[StatusEOL] != 3 {
+		t.Errorf("eol images/containers = %d/%d, want 2/3", summary.Images[StatusEOL], summary.Containers[StatusEOL])
+	}
+	if summary.Containers[StatusSupported] != 10 || summary.Containers[StatusApproachingEOL] != 0 {
+		t.Errorf("containers by status = %v", summary.Containers)
+	}
+	if summary.Dataset.Source != "bundled" {
+		t.Errorf("Dataset.Source = %q, want bundled", summary.Dataset.Source)
+	}
+}
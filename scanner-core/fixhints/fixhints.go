@@ -0,0 +1,365 @@
+// Package fixhints finds newer tags of an image's repository whose digest
+// resolves the image's critical vulnerabilities ("fix available in tag X").
+//
+// Candidate digests come from images already scanned in this cluster, and
+// optionally from the registry's tag list. A candidate's findings are read
+// from the local database when it has been scanned here, or from the shared
+// result cache otherwise; candidates without scan results are skipped.
+package fixhints
+
+import (
+	"context"
+	"encoding/json"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bvboe/b2s-go/scanner-core/database"
+	"github.com/bvboe/b2s-go/scanner-core/logging"
+	"github.com/bvboe/b2s-go/scanner-core/transfer"
+	"github.com/google/go-containerregistry/pkg/name"
+)
+
+var log = logging.For(logging.ComponentFixHints)
+
+// Hint sources
+const (
+	SourceCluster = "cluster" // digest scanned in this cluster
+	SourceCache   = "cache"   // scan results found in the shared result cache
+)
+
+// severityCritical is the severity whose findings hints are computed for
+const severityCritical = "Critical"
+
+// Hint is a newer tag of the image's repository whose digest resolves some of
+// the image's critical findings
+type Hint struct {
+	Tag           string   `json:"tag"`
+	Reference     string   `json:"reference"`
+	Digest        string   `json:"digest"`
+	Source        string   `json:"source"`
+	ResolvedCVEs  []string `json:"resolved_cves"`
+	RemainingCVEs []string `json:"remaining_cves"`
+}
+
+// ResultCache looks up scan results by digest (implemented by resultcache.Cache)
+type ResultCache interface {
+	Lookup(ctx context.Context, digest string, grypeDBBuilt time.Time) *transfer.Bundle
+}
+
+// Registry lists the tags of a repository and resolves tags to digests
+type Registry interface {
+	ListTags(ctx context.Context, repo name.Repository) ([]string, error)
+	Digest(ctx context.Context, tag name.Tag) (string, error)
+}
+
+// Config configures a Finder
+type Config struct {
+	// RegistryLookup also checks the registry for newer tags not seen in the cluster
+	RegistryLookup bool
+	// MaxTags limits how many newer registry tags are resolved per repository
+	MaxTags int
+}
+
+// Finder computes fix hints for images
+type Finder struct {
+	db       *database.DB
+	registry Registry
+	maxTags  int
+
+	mu    sync.RWMutex
+	cache ResultCache
+}
+
+// NewFinder creates a Finder reading scan results from db
+func NewFinder(db *database.DB, cfg Config) *Finder {
+	f := &Finder{db: db, maxTags: cfg.MaxTags}
+	if f.maxTags <= 0 {
+		f.maxTags = 5
+	}
+	if cfg.RegistryLookup {
+		f.registry = newCachingRegistry(remoteRegistry{}, registryCacheTTL)
+	}
+	return f
+}
+
+// SetResultCache sets the shared result cache consulted for digests not scanned locally
+func (f *Finder) SetResultCache(cache ResultCache) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.cache = cache
+}
+
+// candidate is a newer tag of the image's repository and its digest
+type candidate struct {
+	tag       string
+	reference string
+	digest    string
+	version   version
+}
+
+// Find returns fix hints for the image with the given digest, best first.
+// Images without critical findings or without a versioned tag get no hints.
+func (f *Finder) Find(ctx context.Context, digest string) ([]Hint, error) {
+	critical, err := f.db.GetImageCVEs(digest, severityCritical)
+	if err != nil || len(critical) == 0 {
+		return nil, err
+	}
+	refs, err := f.db.GetImageReferences(digest)
+	if err != nil {
+		return nil, err
+	}
+	scanned, err := f.db.GetScannedImageReferences()
+	if err != nil {
+		return nil, err
+	}
+	grypeDBBuilt, err := f.db.GetImageGrypeDBBuilt(digest)
+	if err != nil {
+		return nil, err
+	}
+
+	candidates := f.candidates(ctx, digest, refs, scanned)
+
+	localDigests := make(map[string]bool, len(scanned))
+	for _, s := range scanned {
+		localDigests[s.Digest] = true
+	}
+
+	var hints []Hint
+	seenDigests := make(map[string]bool)
+	for _, c := range candidates {
+		if seenDigests[c.digest] {
+			continue
+		}
+		seenDigests[c.digest] = true
+
+		cves, source, ok := f.candidateCVEs(ctx, c.digest, localDigests[c.digest], grypeDBBuilt)
+		if !ok {
+			continue
+		}
+		resolved, remaining := compareFindings(critical, cves)
+		if len(resolved) == 0 {
+			continue
+		}
+		hints = append(hints, Hint{
+			Tag:           c.tag,
+			Reference:     c.reference,
+			Digest:        c.digest,
+			Source:        source,
+			ResolvedCVEs:  resolved,
+			RemainingCVEs: remaining,
+		})
+	}
+
+	// Most resolved findings first; among equals the lowest (least disruptive) version
+	sort.SliceStable(hints, func(i, j int) bool {
+		return len(hints[i].ResolvedCVEs) > len(hints[j].ResolvedCVEs)
+	})
+	return hints, nil
+}
+
+// candidates returns newer tags of the image's repositories with their digests,
+// ordered from lowest to highest version
+func (f *Finder) candidates(ctx context.Context, digest string, refs []string, scanned []database.ImageReference) []candidate {
+	var result []candidate
+	seen := make(map[string]bool)
+	add := func(c candidate) {
+		key := c.reference + "@" + c.digest
+		if c.digest == digest || seen[key] {
+			return
+		}
+		seen[key] = true
+		result = append(result, c)
+	}
+
+	for _, ref := range refs {
+		tag, err := name.NewTag(ref)
+		if err != nil {
+			continue
+		}
+		current, ok := parseVersion(tag.TagStr())
+		if !ok {
+			continue
+		}
+		repo := tag.Context().Name()
+
+		// Newer tags of the same repository already scanned in the cluster
+		known := make(map[string]bool)
+		for _, s := range scanned {
+			other, err := name.NewTag(s.Reference)
+			if err != nil || other.Context().Name() != repo {
+				continue
+			}
+			if v, ok := parseVersion(other.TagStr()); ok && v.newerThan(current) {
+				known[other.TagStr()] = true
+				add(candidate{tag: other.TagStr(), reference: s.Reference, digest: s.Digest, version: v})
+			}
+		}
+
+		// Newer tags published in the registry
+		if f.registry != nil {
+			for _, c := range f.registryCandidates(ctx, tag, current, known) {
+				add(c)
+			}
+		}
+	}
+
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[j].version.newerThan(result[i].version)
+	})
+	return result
+}
+
+// registryCandidates resolves the lowest f.maxTags registry tags newer than current
+func (f *Finder) registryCandidates(ctx context.Context, tag name.Tag, current version, known map[string]bool) []candidate {
+	tags, err := f.registry.ListTags(ctx, tag.Context())
+	if err != nil {
+		log.Debug("failed to list registry tags", "repository", tag.Context().Name(), "error", err)
+		return nil
+	}
+
+	var newer []candidate
+	for _, t := range tags {
+		if known[t] {
+			continue
+		}
+		if v, ok := parseVersion(t); ok && v.newerThan(current) {
+			newer = append(newer, candidate{tag: t, reference: tag.Context().Tag(t).String(), version: v})
+		}
+	}
+	sort.SliceStable(newer, func(i, j int) bool {
+		return newer[j].version.newerThan(newer[i].version)
+	})
+	if len(newer) > f.maxTags {
+		newer = newer[:f.maxTags]
+	}
+
+	result := newer[:0]
+	for _, c := range newer {
+		d, err := f.registry.Digest(ctx, tag.Context().Tag(c.tag))
+		if err != nil {
+			log.Debug("failed to resolve registry tag", "reference", c.reference, "error", err)
+			continue
+		}
+		c.digest = d
+		result = append(result, c)
+	}
+	return result
+}
+
+// candidateCVEs returns all CVE IDs of a candidate digest and where they came from
+func (f *Finder) candidateCVEs(ctx context.Context, digest string, local bool, grypeDBBuilt time.Time) ([]string, string, bool) {
+	if local {
+		cves, err := f.db.GetImageCVEs(digest, "")
+		if err != nil {
+			log.Warn("failed to read candidate CVEs", "digest", digest, "error", err)
+			return nil, "", false
+		}
+		return cves, SourceCluster, true
+	}
+
+	f.mu.RLock()
+	cache := f.cache
+	f.mu.RUnlock()
+	if cache == nil {
+		return nil, "", false
+	}
+	bundle := cache.Lookup(ctx, digest, grypeDBBuilt)
+	if bundle == nil {
+		return nil, "", false
+	}
+	cves, err := grypeCVEs(bundle.Vulnerabilities)
+	if err != nil {
+		log.Warn("failed to parse cached vulnerabilities", "digest", digest, "error", err)
+		return nil, "", false
+	}
+	return cves, SourceCache, true
+}
+
+// grypeCVEs extracts the vulnerability IDs from a grype JSON document
+func grypeCVEs(vulnJSON []byte) ([]string, error) {
+	var doc struct {
+		Matches []struct {
+			Vulnerability struct {
+				ID string `json:"id"`
+			} `json:"vulnerability"`
+		} `json:"matches"`
+	}
+	if err := json.Unmarshal(vulnJSON, &doc); err != nil {
+		return nil, err
+	}
+	cves := make([]string, 0, len(doc.Matches))
+	for _, m := range doc.Matches {
+		cves = append(cves, m.Vulnerability.ID)
+	}
+	return cves, nil
+}
+
+// compareFindings splits critical into the CVEs absent from (resolved) and
+// present in (remaining) the candidate's CVEs
+func compareFindings(critical []string, candidate []string) (resolved, remaining []string) {
+	present := make(map[string]bool, len(candidate))
+	for _, cve := range candidate {
+		present[cve] = true
+	}
+	resolved, remaining = []string{}, []string{}
+	for _, cve := range critical {
+		if present[cve] {
+			remaining = append(remaining, cve)
+		} else {
+			resolved = append(resolved, cve)
+		}
+	}
+	return resolved, remaining
+}
+
+// version is a parsed tag such as v1.21.3-alpine
+type version struct {
+	parts  []int
+	suffix string
+}
+
+var versionPattern = regexp.MustCompile(`^v?(\d+(?:\.\d+)*)(.*)$`)
+
+// parseVersion parses tags made of dotted numbers with an optional suffix.
+// Tags such as "latest" are not versions.
+func parseVersion(tag string) (version, bool) {
+	m := versionPattern.FindStringSubmatch(tag)
+	if m == nil {
+		return version{}, false
+	}
+	var v version
+	for _, p := range strings.Split(m[1], ".") {
+		n, err := strconv.Atoi(p)
+		if err != nil {
+			return version{}, false
+		}
+		v.parts = append(v.parts, n)
+	}
+	v.suffix = m[2]
+	return v, true
+}
+
+// newerThan reports whether v is a higher version than other with the same
+// suffix (so 3.19-alpine is only compared with other -alpine tags)
+func (v version) newerThan(other version) bool {
+	if v.suffix != other.suffix {
+		return false
+	}
+	for i := 0; i < max(len(v.parts), len(other.parts)); i++ {
+		var a, b int
+		if i < len(v.parts) {
+			a = v.parts[i]
+		}
+		if i < len(other.parts) {
+			b = other.parts[i]
+		}
+		if a != b {
+			return a > b
+		}
+	}
+	return false
+}
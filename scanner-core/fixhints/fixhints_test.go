@@ -0,0 +1,150 @@
+package fixhints
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/bvboe/b2s-go/scanner-core/containers"
+	"github.com/bvboe/b2s-go/scanner-core/database"
+	_ "github.com/bvboe/b2s-go/scanner-core/sqlitedriver"
+	"github.com/bvboe/b2s-go/scanner-core/transfer"
+	"github.com/google/go-containerregistry/pkg/name"
+)
+
+// addScannedImage adds a running container for the image, marks it scanned and stores its findings
+func addScannedImage(t *testing.T, db *database.DB, pod, reference, digest string, cves map[string]string) {
+	t.Helper()
+	_, err := db.AddContainer(containers.Container{
+		ID:       containers.ContainerID{Namespace: "default", Pod: pod, Name: "app"},
+		Image:    containers.ImageID{Reference: reference, Digest: digest},
+		NodeName: "node-1",
+	})
+	if err != nil {
+		t.Fatalf("AddContainer() error = %v", err)
+	}
+	conn := db.GetConnection()
+	if _, err := conn.Exec(`UPDATE images SET status = 'completed' WHERE digest = ?`, digest); err != nil {
+		t.Fatalf("update status: %v", err)
+	}
+	for cve, severity := range cves {
+		_, err := conn.Exec(`
+			INSERT INTO image_vulnerabilities (image_id, cve_id, package_name, package_version, package_type, severity, fix_status, fixed_version, count)
+			SELECT id, ?, 'openssl', '3.0.0', 'apk', ?, 'fixed', '3.0.1', 1 FROM images WHERE digest = ?
+		`, cve, severity, digest)
+		if err != nil {
+			t.Fatalf("insert vulnerability: %v", err)
+		}
+	}
+}
+
+type fakeRegistry struct {
+	tags    []string
+	digests map[string]string
+}
+
+func (r fakeRegistry) ListTags(ctx context.Context, repo name.Repository) ([]string, error) {
+	return r.tags, nil
+}
+
+func (r fakeRegistry) Digest(ctx context.Context, tag name.Tag) (string, error) {
+	if d, ok := r.digests[tag.TagStr()]; ok {
+		return d, nil
+	}
+	return "", errors.New("not found")
+}
+
+type fakeCache map[string]string
+
+func (c fakeCache) Lookup(ctx context.Context, digest string, grypeDBBuilt time.Time) *transfer.Bundle {
+	vulns, ok := c[digest]
+	if !ok {
+		return nil
+	}
+	return &transfer.Bundle{Vulnerabilities: []byte(vulns)}
+}
+
+func TestFind(t *testing.T) {
+	db, err := database.New(filepath.Join(t.TempDir(), "hints.db"))
+	if err != nil {
+		t.Fatalf("database.New() error = %v", err)
+	}
+	defer func() { _ = database.Close(db) }()
+
+	addScannedImage(t, db, "old", "nginx:1.21.0", "sha256:old",
+		map[string]string{"CVE-1": "Critical", "CVE-2": "Critical", "CVE-3": "High"})
+	// Newer tag in the cluster that fixes one critical finding
+	addScannedImage(t, db, "patch", "docker.io/library/nginx:1.21.1", "sha256:patch",
+		map[string]string{"CVE-2": "Critical"})
+	// Other repository and older tag are not candidates
+	addScannedImage(t, db, "other", "redis:7.0.0", "sha256:redis", nil)
+	addScannedImage(t, db, "older", "nginx:1.20.0", "sha256:older", nil)
+
+	finder := NewFinder(db, Config{MaxTags: 2})
+	finder.registry = fakeRegistry{
+		tags: []string{"latest", "1.20.0", "1.21.1", "1.22.0", "1.23.0", "1.24.0", "1.22.0-alpine"},
+		digests: map[string]string{
+			"1.22.0": "sha256:minor",
+			"1.23.0": "sha256:unscanned",
+		},
+	}
+	// 1.22.0 was scanned elsewhere and fixes everything; 1.23.0 has no results
+	finder.SetResultCache(fakeCache{"sha256:minor": `{"matches":[{"vulnerability":{"id":"CVE-3"}}]}`})
+
+	hints, err := finder.Find(context.Background(), "sha256:old")
+	if err != nil {
+		t.Fatalf("Find() error = %v", err)
+	}
+
+	want := []Hint{
+		{
+			Tag: "1.22.0", Reference: "index.docker.io/library/nginx:1.22.0", Digest: "sha256:minor", Source: SourceCache,
+			ResolvedCVEs: []string{"CVE-1", "CVE-2"}, RemainingCVEs: []string{},
+		},
+		{
+			Tag: "1.21.1", Reference: "docker.io/library/nginx:1.21.1", Digest: "sha256:patch", Source: SourceCluster,
+			ResolvedCVEs: []string{"CVE-1"}, RemainingCVEs: []string{"CVE-2"},
+		},
+	}
+	if !reflect.DeepEqual(hints, want) {
+		t.Errorf("Find() =\n%+v\nwant\n%+v", hints, want)
+	}
+
+	// Images without critical findings get no hints
+	hints, err = finder.Find(context.Background(), "sha256:older")
+	if err != nil || len(hints) != 0 {
+		t.Errorf("Find() for image without criticals = %v, %v", hints, err)
+	}
+}
+
+func TestVersionNewerThan(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want bool
+	}{
+		{"1.21.1", "1.21.0", true},
+		{"1.21", "1.21.0", false},
+		{"1.21.0.1", "1.21", true},
+		{"v2.0", "1.9.9", true},
+		{"1.10", "1.9", true},
+		{"3.19-alpine", "3.18-alpine", true},
+		{"3.19-alpine", "3.18", false},
+		{"1.0", "1.0", false},
+	}
+	for _, tt := range tests {
+		a, okA := parseVersion(tt.a)
+		b, okB := parseVersion(tt.b)
+		if !okA || !okB {
+			t.Fatalf("parseVersion(%q, %q) failed", tt.a, tt.b)
+		}
+		if got := a.newerThan(b); got != tt.want {
+			t.Errorf("%s newerThan %s = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+	}
+	if _, ok := parseVersion("latest"); ok {
+		t.Error("parseVersion(latest) should fail")
+	}
+}
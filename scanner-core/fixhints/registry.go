@@ -0,0 +1,98 @@
+package fixhints
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+)
+
+// registryCacheTTL is how long tag lists and tag digests are reused, so
+// viewing image details doesn't query the registry every time
+const registryCacheTTL = time.Hour
+
+// remoteRegistry queries registries over the network, using credentials from
+// the default keychain (docker config) and anonymous access otherwise
+type remoteRegistry struct{}
+
+func (remoteRegistry) ListTags(ctx context.Context, repo name.Repository) ([]string, error) {
+	return remote.List(repo, remote.WithContext(ctx), remote.WithAuthFromKeychain(authn.DefaultKeychain))
+}
+
+func (remoteRegistry) Digest(ctx context.Context, tag name.Tag) (string, error) {
+	desc, err := remote.Head(tag, remote.WithContext(ctx), remote.WithAuthFromKeychain(authn.DefaultKeychain))
+	if err != nil {
+		return "", err
+	}
+	return desc.Digest.String(), nil
+}
+
+type cachedTags struct {
+	tags    []string
+	err     error
+	expires time.Time
+}
+
+type cachedDigest struct {
+	digest  string
+	err     error
+	expires time.Time
+}
+
+// cachingRegistry caches the results (including failures) of another Registry
+type cachingRegistry struct {
+	next Registry
+	ttl  time.Duration
+
+	mu      sync.Mutex
+	tags    map[string]cachedTags
+	digests map[string]cachedDigest
+}
+
+func newCachingRegistry(next Registry, ttl time.Duration) *cachingRegistry {
+	return &cachingRegistry{
+		next:    next,
+		ttl:     ttl,
+		tags:    make(map[string]cachedTags),
+		digests: make(map[string]cachedDigest),
+	}
+}
+
+func (r *cachingRegistry) ListTags(ctx context.Context, repo name.Repository) ([]string, error) {
+	key := repo.Name()
+	r.mu.Lock()
+	if c, ok := r.tags[key]; ok && time.Now().Before(c.expires) {
+		r.mu.Unlock()
+		return c.tags, c.err
+	}
+	r.mu.Unlock()
+
+	tags, err := r.next.ListTags(ctx, repo)
+	if ctx.Err() == nil {
+		r.mu.Lock()
+		r.tags[key] = cachedTags{tags: tags, err: err, expires: time.Now().Add(r.ttl)}
+		r.mu.Unlock()
+	}
+	return tags, err
+}
+
+func (r *cachingRegistry) Digest(ctx context.Context, tag name.Tag) (string, error) {
+	key := tag.Name()
+	r.mu.Lock()
+	if c, ok := r.digests[key]; ok && time.Now().Before(c.expires) {
+		r.mu.Unlock()
+		return c.digest, c.err
+	}
+	r.mu.Unlock()
+
+	digest, err := r.next.Digest(ctx, tag)
+	if ctx.Err() == nil {
+		r.mu.Lock()
+		r.digests[key] = cachedDigest{digest: digest, err: err, expires: time.Now().Add(r.ttl)}
+		r.mu.Unlock()
+	}
+	return digest, err
+}
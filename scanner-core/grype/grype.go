@@ -11,6 +11,7 @@ import (
 	"time"
 
 	"github.com/bvboe/b2s-go/scanner-core/logging"
+	"github.com/bvboe/b2s-go/scanner-core/vulndb"
 
 	"github.com/anchore/clio"
 	"github.com/anchore/grype/grype"
@@ -112,6 +113,14 @@ func InitializeDatabase(cfg Config) (*DatabaseStatus, error) {
 		}
 		installCfg.DBRootDir = dbDir
 		log.Info("using database directory", "dir", dbDir)
+
+		// Don't race an update of a database shared with other replicas
+		unlock, err := vulndb.LockUpdates(context.Background(), cfg.DBRootDir)
+		if err != nil {
+			return &DatabaseStatus{Available: false, Error: err.Error()},
+				fmt.Errorf("failed to lock vulnerability database for update: %w", err)
+		}
+		defer unlock()
 	}
 
 	// Log directory contents before initialization
@@ -239,6 +248,19 @@ func CheckDatabase(cfg Config) (*DatabaseStatus, error) {
 	return &DatabaseStatus{Available: true, Path: dbPath}, nil
 }
 
+// CurrentDBBuilt returns the build timestamp of the vulnerability database on disk.
+// Used to key cached scan results to the database version that produced them.
+func CurrentDBBuilt(cfg Config) (time.Time, error) {
+	status, err := CheckDatabase(cfg)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if !status.Available {
+		return time.Time{}, fmt.Errorf("vulnerability database not available: %s", status.Error)
+	}
+	return readActualDBTimestamp(status.Path)
+}
+
 // isNumericDir checks if a directory name is numeric (schema version like "5", "6")
 func isNumericDir(name string) bool {
 	if name == "" {
@@ -264,6 +286,14 @@ func DeleteDatabase(cfg Config) error {
 		dbDir = filepath.Join(homeDir, ".cache", "grype", "db")
 	}
 
+	if cfg.DBRootDir != "" {
+		unlock, err := vulndb.LockUpdates(context.Background(), cfg.DBRootDir)
+		if err != nil {
+			return fmt.Errorf("failed to lock vulnerability database for update: %w", err)
+		}
+		defer unlock()
+	}
+
 	log.Info("deleting database directory", "dir", dbDir)
 
 	// Log contents before deletion
@@ -398,6 +428,14 @@ func ScanVulnerabilitiesWithConfig(ctx context.Context, sbomJSON []byte, cfg Con
 	if err != nil {
 		return nil, fmt.Errorf("failed to create curator: %w", err)
 	}
+	// Don't open the database while an update swaps in a new one
+	if cfg.DBRootDir != "" {
+		unlock, err := vulndb.LockForReading(ctx, cfg.DBRootDir)
+		if err != nil {
+			return nil, fmt.Errorf("failed to lock vulnerability database for reading: %w", err)
+		}
+		defer unlock()
+	}
 	rdr, err := curator.Reader()
 	if err != nil {
 		log.Error("failed to open vulnerability database reader", "duration", time.Since(startTime).Round(time.Millisecond), slog.Any("error", err))
@@ -0,0 +1,186 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/bvboe/b2s-go/scanner-core/adhoc"
+	"github.com/bvboe/b2s-go/scanner-core/containers"
+	"github.com/bvboe/b2s-go/scanner-core/database"
+	"github.com/bvboe/b2s-go/scanner-core/routes"
+)
+
+// AdHocScanner submits on-demand scans of images (implemented by adhoc.Scanner)
+type AdHocScanner interface {
+	Submit(ctx context.Context, reference string) (containers.ImageID, error)
+}
+
+// AdHocScanProvider reads the state and results of on-demand scans
+type AdHocScanProvider interface {
+	GetAdHocScan(digest string) (*database.AdHocScan, error)
+	GetImageSeverityCounts(digest string) (*database.ImageSeverityCounts, error)
+}
+
+// AdHocScanRequest is the body of POST /api/scan
+type AdHocScanRequest struct {
+	Image string `json:"image"`
+}
+
+// AdHocScanResponse describes an on-demand scan. ID is the image digest and
+// doubles as the job handle for GET /api/scan/{id}.
+type AdHocScanResponse struct {
+	ID              string            `json:"id"`
+	Image           string            `json:"image"`
+	Digest          string            `json:"digest"`
+	AdHoc           bool              `json:"ad_hoc"`
+	Status          database.Status   `json:"status"`
+	Error           string            `json:"error,omitempty"`
+	RequestedAt     string            `json:"requested_at,omitempty"`
+	ExpiresAt       string            `json:"expires_at,omitempty"`
+	Vulnerabilities map[string]int    `json:"vulnerabilities,omitempty"`
+	Links           map[string]string `json:"links"`
+}
+
+// maxAdHocScanRequestSize bounds the POST /api/scan body
+const maxAdHocScanRequestSize = 64 << 10
+
+// RegisterAdHocScanHandlers registers the on-demand scan endpoints
+func RegisterAdHocScanHandlers(reg *routes.Registry, scanner AdHocScanner, provider AdHocScanProvider) {
+	reg.Handle(
+		routes.Route{Pattern: "/api/scan", Methods: routes.POST, Handler: AdHocScanSubmitHandler(scanner, provider), Role: routes.RoleAdmin},
+		routes.Route{Pattern: "/api/scan/", Methods: routes.GET, Handler: AdHocScanStatusHandler(provider), CacheControl: routes.NoStore},
+	)
+}
+
+// AdHocScanSubmitHandler creates an HTTP handler for POST /api/scan.
+// Scans an image that is not (yet) running in the cluster, e.g. before a
+// deployment. The reference is resolved to a digest against its registry and
+// the image is pulled from there.
+//
+// Request: {"image": "registry.example.com/team/app:v2"}
+// Response (202): the scan, with links.status to poll for results
+func AdHocScanSubmitHandler(scanner AdHocScanner, provider AdHocScanProvider) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		var req AdHocScanRequest
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxAdHocScanRequestSize)).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		if strings.TrimSpace(req.Image) == "" {
+			http.Error(w, "Image reference required", http.StatusBadRequest)
+			return
+		}
+
+		image, err := scanner.Submit(r.Context(), req.Image)
+		switch {
+		case errors.Is(err, adhoc.ErrInvalidReference):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		case errors.Is(err, adhoc.ErrUnresolved):
+			log.Warn("ad-hoc scan image not resolved", "image", req.Image, "error", err)
+			http.Error(w, err.Error(), http.StatusBadGateway)
+			return
+		case err != nil:
+			log.Error("error submitting ad-hoc scan", "image", req.Image, "error", err)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+
+		scan, err := provider.GetAdHocScan(image.Digest)
+		if err != nil || scan == nil {
+			log.Error("error reading submitted ad-hoc scan", "digest", image.Digest, "error", err)
+			scan = &database.AdHocScan{Digest: image.Digest, Reference: image.Reference, Status: database.StatusPending}
+		}
+
+		response := adHocScanResponse(scan)
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Location", response.Links["status"])
+		w.WriteHeader(http.StatusAccepted)
+		if err := json.NewEncoder(w).Encode(response); err != nil {
+			log.Error("error encoding ad-hoc scan response", "error", err)
+		}
+	}
+}
+
+// AdHocScanStatusHandler creates an HTTP handler for GET /api/scan/{digest}.
+// Returns the state of an on-demand scan and, once completed, its
+// vulnerability counts per severity.
+func AdHocScanStatusHandler(provider AdHocScanProvider) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		digest := strings.TrimPrefix(r.URL.Path, "/api/scan/")
+		if digest == "" {
+			http.Error(w, "Scan ID required", http.StatusBadRequest)
+			return
+		}
+
+		scan, err := provider.GetAdHocScan(digest)
+		if err != nil {
+			log.Error("error querying ad-hoc scan", "digest", digest, "error", err)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		if scan == nil {
+			http.Error(w, "Scan not found", http.StatusNotFound)
+			return
+		}
+
+		response := adHocScanResponse(scan)
+		if scan.Status.HasVulnerabilities() {
+			counts, err := provider.GetImageSeverityCounts(digest)
+			if err != nil {
+				log.Error("error querying ad-hoc scan vulnerability counts", "digest", digest, "error", err)
+				http.Error(w, "Internal server error", http.StatusInternalServerError)
+				return
+			}
+			if counts != nil {
+				response.Vulnerabilities = map[string]int{
+					"critical":   counts.Critical,
+					"high":       counts.High,
+					"medium":     counts.Medium,
+					"low":        counts.Low,
+					"negligible": counts.Negligible,
+					"unknown":    counts.Unknown,
+					"total":      counts.Total(),
+				}
+			}
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(response); err != nil {
+			log.Error("error encoding ad-hoc scan response", "error", err)
+		}
+	}
+}
+
+// adHocScanResponse describes scan, linking to the endpoints serving its results
+func adHocScanResponse(scan *database.AdHocScan) AdHocScanResponse {
+	return AdHocScanResponse{
+		ID:          scan.Digest,
+		Image:       scan.Reference,
+		Digest:      scan.Digest,
+		AdHoc:       true,
+		Status:      scan.Status,
+		Error:       scan.Error,
+		RequestedAt: scan.RequestedAt,
+		ExpiresAt:   scan.ExpiresAt,
+		Links: map[string]string{
+			"status":          "/api/scan/" + scan.Digest,
+			"image":           "/api/images/" + scan.Digest,
+			"vulnerabilities": "/api/images/" + scan.Digest + "/vulnerabilities",
+			"sbom":            "/api/sbom/" + scan.Digest,
+		},
+	}
+}
@@ -0,0 +1,130 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/bvboe/b2s-go/scanner-core/adhoc"
+	"github.com/bvboe/b2s-go/scanner-core/containers"
+	"github.com/bvboe/b2s-go/scanner-core/database"
+)
+
+type mockAdHocScanner struct {
+	err       error
+	submitted []string
+}
+
+func (m *mockAdHocScanner) Submit(ctx context.Context, reference string) (containers.ImageID, error) {
+	m.submitted = append(m.submitted, reference)
+	if m.err != nil {
+		return containers.ImageID{}, m.err
+	}
+	return containers.ImageID{Reference: reference, Digest: "sha256:abc"}, nil
+}
+
+type mockAdHocScanProvider struct {
+	scans  map[string]*database.AdHocScan
+	counts *database.ImageSeverityCounts
+}
+
+func (m *mockAdHocScanProvider) GetAdHocScan(digest string) (*database.AdHocScan, error) {
+	return m.scans[digest], nil
+}
+
+func (m *mockAdHocScanProvider) GetImageSeverityCounts(digest string) (*database.ImageSeverityCounts, error) {
+	return m.counts, nil
+}
+
+func TestAdHocScanSubmitHandler(t *testing.T) {
+	provider := &mockAdHocScanProvider{scans: map[string]*database.AdHocScan{
+		"sha256:abc": {Digest: "sha256:abc", Reference: "nginx:1.27", Status: database.StatusPending},
+	}}
+
+	tests := []struct {
+		name       string
+		method     string
+		body       string
+		err        error
+		wantStatus int
+	}{
+		{"accepted", http.MethodPost, `{"image": "nginx:1.27"}`, nil, http.StatusAccepted},
+		{"wrong method", http.MethodGet, "", nil, http.StatusMethodNotAllowed},
+		{"invalid body", http.MethodPost, `nginx:1.27`, nil, http.StatusBadRequest},
+		{"missing image", http.MethodPost, `{}`, nil, http.StatusBadRequest},
+		{"invalid reference", http.MethodPost, `{"image": "Not Valid"}`, fmt.Errorf("%w: bad", adhoc.ErrInvalidReference), http.StatusBadRequest},
+		{"unresolved", http.MethodPost, `{"image": "nginx:nope"}`, fmt.Errorf("%w: nope", adhoc.ErrUnresolved), http.StatusBadGateway},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scanner := &mockAdHocScanner{err: tt.err}
+			rec := httptest.NewRecorder()
+			req := httptest.NewRequest(tt.method, "/api/scan", strings.NewReader(tt.body))
+			AdHocScanSubmitHandler(scanner, provider)(rec, req)
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			if tt.wantStatus != http.StatusAccepted {
+				return
+			}
+
+			var response AdHocScanResponse
+			if err := json.NewDecoder(rec.Body).Decode(&response); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			if response.ID != "sha256:abc" || response.Image != "nginx:1.27" || !response.AdHoc || response.Status != database.StatusPending {
+				t.Errorf("unexpected response: %+v", response)
+			}
+			if loc := rec.Header().Get("Location"); loc != "/api/scan/sha256:abc" {
+				t.Errorf("Location = %q", loc)
+			}
+		})
+	}
+}
+
+func TestAdHocScanStatusHandler(t *testing.T) {
+	provider := &mockAdHocScanProvider{
+		scans: map[string]*database.AdHocScan{
+			"sha256:done":    {Digest: "sha256:done", Reference: "app:v2", Status: database.StatusCompleted},
+			"sha256:running": {Digest: "sha256:running", Reference: "app:v3", Status: database.StatusGeneratingSBOM},
+		},
+		counts: &database.ImageSeverityCounts{Digest: "sha256:done", Status: database.StatusCompleted, Critical: 1, High: 2},
+	}
+	handler := AdHocScanStatusHandler(provider)
+
+	get := func(path string) (*httptest.ResponseRecorder, AdHocScanResponse) {
+		rec := httptest.NewRecorder()
+		handler(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		var response AdHocScanResponse
+		if rec.Code == http.StatusOK {
+			if err := json.NewDecoder(rec.Body).Decode(&response); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+		}
+		return rec, response
+	}
+
+	rec, response := get("/api/scan/sha256:done")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200", rec.Code)
+	}
+	if response.Vulnerabilities["critical"] != 1 || response.Vulnerabilities["total"] != 3 {
+		t.Errorf("vulnerabilities = %v", response.Vulnerabilities)
+	}
+	if response.Links["vulnerabilities"] != "/api/images/sha256:done/vulnerabilities" {
+		t.Errorf("links = %v", response.Links)
+	}
+
+	rec, response = get("/api/scan/sha256:running")
+	if rec.Code != http.StatusOK || response.Vulnerabilities != nil {
+		t.Errorf("expected no vulnerabilities while scanning, got %d %v", rec.Code, response.Vulnerabilities)
+	}
+
+	if rec, _ := get("/api/scan/sha256:unknown"); rec.Code != http.StatusNotFound {
+		t.Errorf("status = %d, want 404", rec.Code)
+	}
+}
@@ -0,0 +1,630 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// BlastRadiusHandler creates an HTTP handler for the /api/analytics/blast-radius
+// endpoint. Given a package (name, optionally version and type) it reports how
+// many running images, containers, pods, namespaces and nodes contain it, and
+// how much of that is fixable, to prioritize library upgrade campaigns.
+//
+// An image counts as vulnerable when any of its findings is in the package and
+// as fixable when at least one of those findings has a fix available. Only
+// images with >=1 running container are included (enforced by the JOIN on the
+// containers table). Without a version the response also breaks the totals
+// down per installed version.
+func BlastRadiusHandler(provider ImageQueryProvider) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		params := r.URL.Query()
+
+		pkg := params.Get("package")
+		if pkg == "" {
+			http.Error(w, "package required", http.StatusBadRequest)
+			return
+		}
+		version := params.Get("version")
+		ptype := params.Get("type")
+
+		totalsQuery, versionsQuery, args := buildBlastRadiusQueries(pkg, version, ptype)
+
+		totals, err := provider.ExecuteReadOnlyQueryArgs(totalsQuery, args...)
+		if err != nil {
+			log.Error("error executing blast radius query", "error", err)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+
+		response := map[string]interface{}{
+			"package": pkg,
+			"version": version,
+			"type":    ptype,
+		}
+		counts := []string{"images", "containers", "pods", "namespaces", "nodes",
+			"vulnerable_images", "fixable_images", "fixable_containers"}
+		for _, col := range counts {
+			response[col] = int64(0)
+		}
+		if len(totals.Rows) > 0 {
+			for _, col := range counts {
+				if v, ok := totals.Rows[0][col].(int64); ok {
+					response[col] = v
+				}
+			}
+		}
+
+		// Share of running containers whose image has a fix available for the package
+		fixableShare := 0.0
+		if containers := response["containers"].(int64); containers > 0 {
+			fixableShare = float64(response["fixable_containers"].(int64)) / float64(containers)
+		}
+		response["fixable_share"] = fixableShare
+
+		if versionsQuery != "" {
+			versions, err := provider.ExecuteReadOnlyQueryArgs(versionsQuery, args...)
+			if err != nil {
+				log.Error("error executing blast radius versions query", "error", err)
+				http.Error(w, "Internal server error", http.StatusInternalServerError)
+				return
+			}
+			response["versions"] = versions.Rows
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(response); err != nil {
+			log.Error("error encoding blast radius response", "error", err)
+		}
+	}
+}
+
+// buildBlastRadiusQueries builds the totals query and, when no version is
+// given, the per-version breakdown query for a package. Both queries share
+// their arguments.
+func buildBlastRadiusQueries(pkg, version, ptype string) (string, string, queryArgs) {
+	var args queryArgs
+	name := args.bind(pkg)
+	pkgConditions := []string{"p.name = " + name}
+	vulnConditions := []string{"v.package_name = " + name}
+	if version != "" {
+		v := args.bind(version)
+		pkgConditions = append(pkgConditions, "p.version = "+v)
+		vulnConditions = append(vulnConditions, "v.package_version = "+v)
+	}
+	if ptype != "" {
+		t := args.bind(ptype)
+		pkgConditions = append(pkgConditions, "p.type = "+t)
+		vulnConditions = append(vulnConditions, "v.package_type = "+t)
+	}
+
+	// One row per (image, installed version); vulnerable/fixable are matched on the same version
+	base := fmt.Sprintf(`
+WITH pkg_images AS (
+    SELECT DISTINCT p.image_id, p.version
+    FROM image_packages p
+    WHERE %s
+),
+pkg_vulns AS (
+    SELECT v.image_id, v.package_version,
+           MAX(CASE WHEN v.fix_status = 'fixed' THEN 1 ELSE 0 END) as fixable
+    FROM image_vulnerabilities v
+    WHERE %s
+    GROUP BY v.image_id, v.package_version
+)`, strings.Join(pkgConditions, " AND "), strings.Join(vulnConditions, " AND "))
+
+	from := `
+FROM pkg_images pi
+JOIN containers c ON c.image_id = pi.image_id
+LEFT JOIN pkg_vulns pv ON pv.image_id = pi.image_id AND pv.package_version = pi.version`
+
+	totalsQuery := base + `
+SELECT
+    COUNT(DISTINCT c.image_id) as images,
+    COUNT(DISTINCT c.id) as containers,
+    COUNT(DISTINCT c.namespace || '/' || c.pod) as pods,
+    COUNT(DISTINCT c.namespace) as namespaces,
+    COUNT(DISTINCT NULLIF(c.node_name, '')) as nodes,
+    COUNT(DISTINCT CASE WHEN pv.image_id IS NOT NULL THEN c.image_id END) as vulnerable_images,
+    COUNT(DISTINCT CASE WHEN pv.fixable = 1 THEN c.image_id END) as fixable_images,
+    COUNT(DISTINCT CASE WHEN pv.fixable = 1 THEN c.id END) as fixable_containers` + from
+
+	if version != "" {
+		return totalsQuery, "", args
+	}
+
+	versionsQuery := base + `
+SELECT
+    pi.version as version,
+    COUNT(DISTINCT c.image_id) as images,
+    COUNT(DISTINCT c.id) as containers,
+    COUNT(DISTINCT CASE WHEN pv.image_id IS NOT NULL THEN c.image_id END) as vulnerable_images,
+    COUNT(DISTINCT CASE WHEN pv.fixable = 1 THEN c.image_id END) as fixable_images` + from + `
+GROUP BY pi.version
+ORDER BY containers DESC, pi.version ASC`
+
+	return totalsQuery, versionsQuery, args
+}
+
+// maxFixCoverageUpgrades is the number of package upgrades reported per scope
+const maxFixCoverageUpgrades = 10
+
+// fixCoverage summarizes how many findings of a scope (all running images or
+// one namespace) can be fixed by upgrading packages today
+type fixCoverage struct {
+	Namespace    string           `json:"namespace,omitempty"`
+	Images       int64            `json:"images"`
+	Findings     int64            `json:"findings"`
+	Fixable      int64            `json:"fixable"`
+	AwaitingFix  int64            `json:"awaiting_fix"`
+	WontFix      int64            `json:"wont_fix"`
+	Unknown      int64            `json:"unknown"`
+	FixableShare float64          `json:"fixable_share"`
+	TopUpgrades  []packageUpgrade `json:"top_upgrades"`
+}
+
+// packageUpgrade is an upgrade of an installed package version that resolves
+// all of its fixable findings
+type packageUpgrade struct {
+	PackageName      string   `json:"package_name"`
+	PackageType      string   `json:"package_type"`
+	InstalledVersion string   `json:"installed_version"`
+	UpgradeTo        string   `json:"upgrade_to"`
+	FixedVersions    []string `json:"fixed_versions"`
+	FindingsResolved int64    `json:"findings_resolved"`
+	Vulnerabilities  int64    `json:"vulnerabilities"`
+	Images           int64    `json:"images"`
+}
+
+// FixCoverageHandler creates an HTTP handler for the /api/analytics/fix-coverage
+// endpoint. It reports, overall and per namespace, how many findings of
+// running images are fixable by upgrades today, how many await a vendor fix
+// (not-fixed) or will not be fixed, and the package upgrades resolving the
+// most findings, to plan patch sprints.
+//
+// A finding is a vulnerability of a package version in an image, counted once
+// per image however many containers run it; an image running in several
+// namespaces counts in each of them. namespaces, severity and packageTypes
+// narrow the findings considered.
+func FixCoverageHandler(provider ImageQueryProvider) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		params := r.URL.Query()
+		totalsQuery, upgradesQuery, args := buildFixCoverageQueries(
+			parseMultiSelect(params.Get("namespaces")),
+			parseMultiSelect(params.Get("severity")),
+			parseMultiSelect(params.Get("packageTypes")))
+
+		totals, err := provider.ExecuteReadOnlyQueryArgs(totalsQuery, args...)
+		if err != nil {
+			log.Error("error executing fix coverage query", "error", err)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		upgrades, err := provider.ExecuteReadOnlyQueryArgs(upgradesQuery, args...)
+		if err != nil {
+			log.Error("error executing fix coverage upgrades query", "error", err)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+
+		// The overall row comes first, followed by one row per namespace
+		overall := &fixCoverage{TopUpgrades: []packageUpgrade{}}
+		namespaces := []*fixCoverage{}
+		byNamespace := map[string]*fixCoverage{}
+		for _, row := range totals.Rows {
+			scope := &fixCoverage{
+				Namespace:   getStringValue(row, "namespace"),
+				Images:      getInt64Value(row, "images"),
+				Findings:    getInt64Value(row, "findings"),
+				Fixable:     getInt64Value(row, "fixable"),
+				AwaitingFix: getInt64Value(row, "awaiting_fix"),
+				WontFix:     getInt64Value(row, "wont_fix"),
+				TopUpgrades: []packageUpgrade{},
+			}
+			scope.Unknown = scope.Findings - scope.Fixable - scope.AwaitingFix - scope.WontFix
+			if scope.Findings > 0 {
+				scope.FixableShare = float64(scope.Fixable) / float64(scope.Findings)
+			}
+			if getInt64Value(row, "overall") == 1 {
+				overall = scope
+				continue
+			}
+			namespaces = append(namespaces, scope)
+			byNamespace[scope.Namespace] = scope
+		}
+
+		for _, row := range upgrades.Rows {
+			fixedVersions := strings.Split(getStringValue(row, "fixed_versions"), ",")
+			sort.Slice(fixedVersions, func(i, j int) bool { return compareVersions(fixedVersions[i], fixedVersions[j]) < 0 })
+			upgrade := packageUpgrade{
+				PackageName:      getStringValue(row, "package_name"),
+				PackageType:      getStringValue(row, "package_type"),
+				InstalledVersion: getStringValue(row, "package_version"),
+				UpgradeTo:        fixedVersions[len(fixedVersions)-1],
+				FixedVersions:    fixedVersions,
+				FindingsResolved: getInt64Value(row, "findings_resolved"),
+				Vulnerabilities:  getInt64Value(row, "vulnerabilities"),
+				Images:           getInt64Value(row, "images"),
+			}
+			if getInt64Value(row, "overall") == 1 {
+				overall.TopUpgrades = append(overall.TopUpgrades, upgrade)
+			} else if scope := byNamespace[getStringValue(row, "namespace")]; scope != nil {
+				scope.TopUpgrades = append(scope.TopUpgrades, upgrade)
+			}
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(map[string]interface{}{
+			"overall":    overall,
+			"namespaces": namespaces,
+		}); err != nil {
+			log.Error("error encoding fix coverage response", "error", err)
+		}
+	}
+}
+
+// buildFixCoverageQueries builds the totals query and the top upgrades query.
+// Both return one set of rows per namespace plus the overall rows (overall = 1),
+// and share their arguments.
+func buildFixCoverageQueries(namespaces, severities, packageTypes []string) (string, string, queryArgs) {
+	var args queryArgs
+	runningConditions := appendCondition(nil, args.in("namespace", namespaces))
+	running := "SELECT DISTINCT image_id, namespace FROM containers"
+	if len(runningConditions) > 0 {
+		running += " WHERE " + strings.Join(runningConditions, " AND ")
+	}
+
+	vulnConditions := appendCondition(nil, args.in("v.severity", severities))
+	vulnConditions = appendCondition(vulnConditions, args.in("v.package_type", packageTypes))
+	vulnWhere := ""
+	if len(vulnConditions) > 0 {
+		vulnWhere = "WHERE " + strings.Join(vulnConditions, " AND ")
+	}
+
+	// findings: one row per finding and namespace its image runs in
+	base := fmt.Sprintf(`
+WITH running AS (%s),
+findings AS (
+    SELECT r.namespace, v.image_id, v.cve_id, v.package_name, v.package_version, v.package_type,
+           v.fix_status, v.fixed_version
+    FROM image_vulnerabilities v
+    JOIN running r ON r.image_id = v.image_id
+    %s
+)`, running, vulnWhere)
+
+	counts := `
+    COUNT(DISTINCT image_id) as images,
+    COUNT(DISTINCT image_id || '|' || cve_id || '|' || package_name || '|' || package_version) as findings,
+    COUNT(DISTINCT CASE WHEN fix_status = 'fixed' THEN image_id || '|' || cve_id || '|' || package_name || '|' || package_version END) as fixable,
+    COUNT(DISTINCT CASE WHEN fix_status = 'not-fixed' THEN image_id || '|' || cve_id || '|' || package_name || '|' || package_version END) as awaiting_fix,
+    COUNT(DISTINCT CASE WHEN fix_status = 'wont-fix' THEN image_id || '|' || cve_id || '|' || package_name || '|' || package_version END) as wont_fix`
+
+	totalsQuery := base + `
+SELECT 1 as overall, '' as namespace,` + counts + `
+FROM findings
+UNION ALL
+SELECT 0 as overall, namespace,` + counts + `
+FROM findings
+GROUP BY namespace
+ORDER BY overall DESC, namespace ASC`
+
+	upgradeColumns := `package_name, package_type, package_version,
+    COUNT(DISTINCT image_id || '|' || cve_id) as findings_resolved,
+    COUNT(DISTINCT cve_id) as vulnerabilities,
+    COUNT(DISTINCT image_id) as images,
+    GROUP_CONCAT(DISTINCT fixed_version) as fixed_versions`
+	upgradesQuery := base + fmt.Sprintf(`,
+upgrades AS (
+    SELECT 1 as overall, '' as namespace, %[1]s
+    FROM findings
+    WHERE fix_status = 'fixed' AND fixed_version != ''
+    GROUP BY package_name, package_type, package_version
+    UNION ALL
+    SELECT 0 as overall, namespace, %[1]s
+    FROM findings
+    WHERE fix_status = 'fixed' AND fixed_version != ''
+    GROUP BY namespace, package_name, package_type, package_version
+),
+ranked AS (
+    SELECT *, ROW_NUMBER() OVER (
+        PARTITION BY overall, namespace
+        ORDER BY findings_resolved DESC, images DESC, package_name ASC, package_version ASC
+    ) as upgrade_rank
+    FROM upgrades
+)
+SELECT overall, namespace, package_name, package_type, package_version,
+       findings_resolved, vulnerabilities, images, fixed_versions
+FROM ranked
+WHERE upgrade_rank <= %[2]d
+ORDER BY overall DESC, namespace ASC, upgrade_rank ASC`, upgradeColumns, maxFixCoverageUpgrades)
+
+	return totalsQuery, upgradesQuery, args
+}
+
+// compareVersions orders package versions, comparing runs of digits
+// numerically and everything else lexically (1.2.10 > 1.2.9, 1.1.1w > 1.1.1k)
+func compareVersions(a, b string) int {
+	for a != "" && b != "" {
+		aRun, aRest := versionRun(a)
+		bRun, bRest := versionRun(b)
+		aNum, aErr := strconv.ParseUint(aRun, 10, 64)
+		bNum, bErr := strconv.ParseUint(bRun, 10, 64)
+		switch {
+		case aErr == nil && bErr == nil && aNum != bNum:
+			if aNum < bNum {
+				return -1
+			}
+			return 1
+		case (aErr != nil || bErr != nil) && aRun != bRun:
+			return strings.Compare(aRun, bRun)
+		}
+		a, b = aRest, bRest
+	}
+	return strings.Compare(a, b)
+}
+
+// versionRun splits off the leading run of digits or non-digits of a version
+func versionRun(v string) (run, rest string) {
+	digit := v[0] >= '0' && v[0] <= '9'
+	i := 1
+	for i < len(v) && (v[i] >= '0' && v[i] <= '9') == digit {
+		i++
+	}
+	return v[:i], v[i:]
+}
+
+// imageSizeBucketsMB are the upper bounds of the image size buckets in MB; the
+// last bucket has no upper bound
+var imageSizeBucketsMB = []int64{50, 200, 500, 1000}
+
+// imageSizeBucket summarizes the running images within a size range. Averages
+// cover the images the value is known for (durations are recorded from the
+// first scan after upgrading).
+type imageSizeBucket struct {
+	Label                 string   `json:"label"`
+	MinBytes              int64    `json:"min_bytes"`
+	MaxBytes              *int64   `json:"max_bytes"`
+	Images                int64    `json:"images"`
+	TotalBytes            int64    `json:"total_bytes"`
+	AvgLayerCount         float64  `json:"avg_layer_count"`
+	AvgPackageCount       float64  `json:"avg_package_count"`
+	AvgUniqueCVEs         float64  `json:"avg_unique_cves"`
+	AvgSBOMDurationMs     *float64 `json:"avg_sbom_duration_ms"`
+	AvgVulnScanDurationMs *float64 `json:"avg_vuln_scan_duration_ms"`
+}
+
+// imageSizeRow is a running image with its size, contents and scan durations
+type imageSizeRow struct {
+	Image              string `json:"image"`
+	Digest             string `json:"digest"`
+	ImageSize          int64  `json:"image_size"`
+	LayerCount         int64  `json:"layer_count"`
+	PackageCount       int64  `json:"package_count"`
+	UniqueCVEs         int64  `json:"unique_cves"`
+	SBOMDurationMs     *int64 `json:"sbom_duration_ms"`
+	VulnScanDurationMs *int64 `json:"vuln_scan_duration_ms"`
+}
+
+// ImageSizeHandler creates an HTTP handler for the /api/analytics/image-size
+// endpoint. It groups running images (optionally only those in ?namespaces=)
+// into size buckets with their average layer count, packages, vulnerabilities
+// and scan durations, lists the largest images (?limit=, default 10) and
+// reports how strongly size correlates with scan duration and vulnerability
+// count, to justify and track image diet efforts.
+//
+// Sizes and layer counts come from the image metadata of the SBOM; images
+// whose SBOM has none (or was not generated yet) are only counted in
+// unknown_size. Correlations are Pearson coefficients, null with fewer than
+// three images or no variation.
+func ImageSizeHandler(provider ImageQueryProvider) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		params := r.URL.Query()
+		limit, err := strconv.Atoi(params.Get("limit"))
+		if err != nil || limit < 1 || limit > 100 {
+			limit = 10
+		}
+
+		query, args := buildImageSizeQuery(parseMultiSelect(params.Get("namespaces")))
+		result, err := provider.ExecuteReadOnlyQueryArgs(query, args...)
+		if err != nil {
+			log.Error("error executing image size query", "error", err)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+
+		var images []imageSizeRow
+		unknown := int64(0)
+		for _, row := range result.Rows {
+			if row["image_size"] == nil {
+				unknown++
+				continue
+			}
+			images = append(images, imageSizeRow{
+				Image:              getStringValue(row, "image"),
+				Digest:             getStringValue(row, "digest"),
+				ImageSize:          getInt64Value(row, "image_size"),
+				LayerCount:         getInt64Value(row, "layer_count"),
+				PackageCount:       getInt64Value(row, "package_count"),
+				UniqueCVEs:         getInt64Value(row, "unique_cves"),
+				SBOMDurationMs:     optionalInt64(row, "sbom_duration_ms"),
+				VulnScanDurationMs: optionalInt64(row, "vuln_scan_duration_ms"),
+			})
+		}
+
+		totalBytes := int64(0)
+		for _, img := range images {
+			totalBytes += img.ImageSize
+		}
+		largest := images[:min(limit, len(images))]
+		if largest == nil {
+			largest = []imageSizeRow{}
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(map[string]interface{}{
+			"images":       len(images),
+			"unknown_size": unknown,
+			"total_bytes":  totalBytes,
+			"buckets":      imageSizeBuckets(images),
+			"largest":      largest,
+			"correlation": map[string]*float64{
+				"size_sbom_duration":      correlate(images, imageSizeOf, sbomDurationOf),
+				"size_vuln_scan_duration": correlate(images, imageSizeOf, vulnScanDurationOf),
+				"layers_sbom_duration":    correlate(images, layerCountOf, sbomDurationOf),
+				"size_unique_cves":        correlate(images, imageSizeOf, uniqueCVEsOf),
+			},
+		}); err != nil {
+			log.Error("error encoding image size response", "error", err)
+		}
+	}
+}
+
+// buildImageSizeQuery builds the query returning one row per running image,
+// largest first; images of unknown size have a NULL image_size
+func buildImageSizeQuery(namespaces []string) (string, queryArgs) {
+	var args queryArgs
+	running := "SELECT DISTINCT image_id FROM containers"
+	if condition := args.in("namespace", namespaces); condition != "" {
+		running += " WHERE " + condition
+	}
+	return fmt.Sprintf(`
+WITH running AS (%s)
+SELECT
+    COALESCE((SELECT MIN(reference) FROM containers WHERE image_id = images.id), images.digest) as image,
+    images.digest,
+    images.image_size,
+    COALESCE(images.layer_count, 0) as layer_count,
+    COALESCE((SELECT SUM(number_of_instances) FROM image_packages WHERE image_id = images.id), 0) as package_count,
+    (SELECT COUNT(DISTINCT cve_id) FROM image_vulnerabilities WHERE image_id = images.id) as unique_cves,
+    images.sbom_duration_ms,
+    images.vuln_scan_duration_ms
+FROM images
+JOIN running ON running.image_id = images.id
+ORDER BY images.image_size IS NULL, images.image_size DESC, images.digest ASC`, running), args
+}
+
+// imageSizeBuckets groups images (largest first) into the size buckets
+func imageSizeBuckets(images []imageSizeRow) []imageSizeBucket {
+	const mb = 1024 * 1024
+	buckets := make([]imageSizeBucket, 0, len(imageSizeBucketsMB)+1)
+	lower := int64(0)
+	for _, upper := range append(imageSizeBucketsMB, 0) {
+		bucket := imageSizeBucket{MinBytes: lower * mb}
+		if upper > 0 {
+			maxBytes := upper * mb
+			bucket.MaxBytes = &maxBytes
+			bucket.Label = fmt.Sprintf("%d-%dMB", lower, upper)
+			if lower == 0 {
+				bucket.Label = fmt.Sprintf("<%dMB", upper)
+			}
+		} else {
+			bucket.Label = fmt.Sprintf(">=%dMB", lower)
+		}
+
+		var layers, packages, cves int64
+		var sbomMs, sbomN, scanMs, scanN int64
+		for _, img := range images {
+			if img.ImageSize < bucket.MinBytes || (bucket.MaxBytes != nil && img.ImageSize >= *bucket.MaxBytes) {
+				continue
+			}
+			bucket.Images++
+			bucket.TotalBytes += img.ImageSize
+			layers += img.LayerCount
+			packages += img.PackageCount
+			cves += img.UniqueCVEs
+			if img.SBOMDurationMs != nil {
+				sbomMs += *img.SBOMDurationMs
+				sbomN++
+			}
+			if img.VulnScanDurationMs != nil {
+				scanMs += *img.VulnScanDurationMs
+				scanN++
+			}
+		}
+		if bucket.Images > 0 {
+			n := float64(bucket.Images)
+			bucket.AvgLayerCount = float64(layers) / n
+			bucket.AvgPackageCount = float64(packages) / n
+			bucket.AvgUniqueCVEs = float64(cves) / n
+		}
+		if sbomN > 0 {
+			avg := float64(sbomMs) / float64(sbomN)
+			bucket.AvgSBOMDurationMs = &avg
+		}
+		if scanN > 0 {
+			avg := float64(scanMs) / float64(scanN)
+			bucket.AvgVulnScanDurationMs = &avg
+		}
+		buckets = append(buckets, bucket)
+		lower = upper
+	}
+	return buckets
+}
+
+// Values of an image correlated by the image size endpoint; ok is false if unknown
+func imageSizeOf(img imageSizeRow) (float64, bool)  { return float64(img.ImageSize), true }
+func layerCountOf(img imageSizeRow) (float64, bool) { return float64(img.LayerCount), true }
+func uniqueCVEsOf(img imageSizeRow) (float64, bool) { return float64(img.UniqueCVEs), true }
+func sbomDurationOf(img imageSizeRow) (float64, bool) {
+	if img.SBOMDurationMs == nil {
+		return 0, false
+	}
+	return float64(*img.SBOMDurationMs), true
+}
+func vulnScanDurationOf(img imageSizeRow) (float64, bool) {
+	if img.VulnScanDurationMs == nil {
+		return 0, false
+	}
+	return float64(*img.VulnScanDurationMs), true
+}
+
+// correlate returns the Pearson correlation coefficient of two values over the
+// images both are known for, or nil with fewer than three such images or if
+// either value does not vary
+func correlate(images []imageSizeRow, xOf, yOf func(imageSizeRow) (float64, bool)) *float64 {
+	var xs, ys []float64
+	for _, img := range images {
+		x, okX := xOf(img)
+		y, okY := yOf(img)
+		if okX && okY {
+			xs = append(xs, x)
+			ys = append(ys, y)
+		}
+	}
+	if len(xs) < 3 {
+		return nil
+	}
+
+	var meanX, meanY float64
+	for i := range xs {
+		meanX += xs[i]
+		meanY += ys[i]
+	}
+	meanX /= float64(len(xs))
+	meanY /= float64(len(ys))
+
+	var cov, varX, varY float64
+	for i := range xs {
+		dx, dy := xs[i]-meanX, ys[i]-meanY
+		cov += dx * dy
+		varX += dx * dx
+		varY += dy * dy
+	}
+	if varX == 0 || varY == 0 {
+		return nil
+	}
+	r := math.Round(cov/math.Sqrt(varX*varY)*1000) / 1000
+	return &r
+}
+
+// optionalInt64 returns an integer column that may be NULL
+func optionalInt64(row map[string]interface{}, key string) *int64 {
+	if v, ok := row[key].(int64); ok {
+		return &v
+	}
+	return nil
+}
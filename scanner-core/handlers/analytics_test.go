@@ -0,0 +1,338 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/bvboe/b2s-go/scanner-core/containers"
+)
+
+func TestBlastRadiusHandler(t *testing.T) {
+	db := createTransferTestDB(t, "blast-radius")
+
+	// Three images ship openssl: two 1.1.1 (one fixable), one 3.0.0 (not vulnerable)
+	addContainer := func(namespace, pod, node, reference, digest string) {
+		t.Helper()
+		if _, err := db.AddContainer(containers.Container{
+			ID:       containers.ContainerID{Namespace: namespace, Pod: pod, Name: "app"},
+			Image:    containers.ImageID{Reference: reference, Digest: digest},
+			NodeName: node,
+		}); err != nil {
+			t.Fatalf("AddContainer() error = %v", err)
+		}
+	}
+	addContainer("team-a", "web-1", "node-1", "web:1", "sha256:web")
+	addContainer("team-a", "web-2", "node-2", "web:1", "sha256:web")
+	addContainer("team-b", "api", "node-1", "api:1", "sha256:api")
+	addContainer("team-c", "new", "node-3", "new:1", "sha256:new")
+	addContainer("team-c", "other", "node-3", "other:1", "sha256:other")
+
+	conn := db.GetConnection()
+	exec := func(query string, args ...interface{}) {
+		t.Helper()
+		if _, err := conn.Exec(query, args...); err != nil {
+			t.Fatalf("seed failed: %v", err)
+		}
+	}
+	for digest, version := range map[string]string{"sha256:web": "1.1.1", "sha256:api": "1.1.1", "sha256:new": "3.0.0"} {
+		exec(`INSERT INTO image_packages (image_id, name, version, type, number_of_instances)
+			SELECT id, 'openssl', ?, 'apk', 1 FROM images WHERE digest = ?`, version, digest)
+	}
+	exec(`INSERT INTO image_vulnerabilities (image_id, cve_id, package_name, package_version, package_type, severity, fix_status, fixed_version, count)
+		SELECT id, 'CVE-1', 'openssl', '1.1.1', 'apk', 'High', 'fixed', '1.1.1w', 1 FROM images WHERE digest = 'sha256:web'`)
+	exec(`INSERT INTO image_vulnerabilities (image_id, cve_id, package_name, package_version, package_type, severity, fix_status, fixed_version, count)
+		SELECT id, 'CVE-2', 'openssl', '1.1.1', 'apk', 'Low', 'wont-fix', '', 1 FROM images WHERE digest = 'sha256:api'`)
+
+	t.Run("requires package param", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		BlastRadiusHandler(db)(rec, httptest.NewRequest(http.MethodGet, "/api/analytics/blast-radius", nil))
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("expected 400 without package param, got %d", rec.Code)
+		}
+	})
+
+	t.Run("package and version", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		BlastRadiusHandler(db)(rec, httptest.NewRequest(http.MethodGet, "/api/analytics/blast-radius?package=openssl&version=1.1.1", nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+		}
+		var resp map[string]interface{}
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("invalid JSON: %v", err)
+		}
+		want := map[string]float64{
+			"images": 2, "containers": 3, "pods": 3, "namespaces": 2, "nodes": 2,
+			"vulnerable_images": 2, "fixable_images": 1, "fixable_containers": 2,
+		}
+		for k, v := range want {
+			if resp[k] != v {
+				t.Errorf("%s = %v, want %v", k, resp[k], v)
+			}
+		}
+		if share, _ := resp["fixable_share"].(float64); share < 0.66 || share > 0.67 {
+			t.Errorf("fixable_share = %v, want 2/3", resp["fixable_share"])
+		}
+		if _, ok := resp["versions"]; ok {
+			t.Error("expected no per-version breakdown when version is given")
+		}
+	})
+
+	t.Run("package only breaks down versions", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		BlastRadiusHandler(db)(rec, httptest.NewRequest(http.MethodGet, "/api/analytics/blast-radius?package=openssl&type=apk", nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+		}
+		var resp struct {
+			Images   int64                    `json:"images"`
+			Nodes    int64                    `json:"nodes"`
+			Versions []map[string]interface{} `json:"versions"`
+		}
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("invalid JSON: %v", err)
+		}
+		if resp.Images != 3 || resp.Nodes != 3 {
+			t.Errorf("images = %d, nodes = %d, want 3 and 3", resp.Images, resp.Nodes)
+		}
+		if len(resp.Versions) != 2 || resp.Versions[0]["version"] != "1.1.1" || resp.Versions[1]["vulnerable_images"] != float64(0) {
+			t.Errorf("unexpected versions breakdown: %v", resp.Versions)
+		}
+	})
+
+	t.Run("quoted package name is bound, not interpolated", func(t *testing.T) {
+		exec(`INSERT INTO image_packages (image_id, name, version, type, number_of_instances)
+			SELECT id, 'o''brien-utils', '1.0', 'npm', 1 FROM images WHERE digest = 'sha256:other'`)
+		for query, want := range map[string]float64{
+			"package=o%27brien-utils&type=npm":                       1,
+			"package=x%27%20OR%20%271%27%3D%271":                     0,
+			"package=openssl&version=1.1.1%27%20OR%20%271%27%3D%271": 0,
+		} {
+			rec := httptest.NewRecorder()
+			BlastRadiusHandler(db)(rec, httptest.NewRequest(http.MethodGet, "/api/analytics/blast-radius?"+query, nil))
+			if rec.Code != http.StatusOK {
+				t.Fatalf("%s: expected 200, got %d: %s", query, rec.Code, rec.Body.String())
+			}
+			var resp map[string]interface{}
+			if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("invalid JSON: %v", err)
+			}
+			if resp["images"] != want {
+				t.Errorf("%s: images = %v, want %v", query, resp["images"], want)
+			}
+		}
+	})
+}
+
+func TestFixCoverageHandler(t *testing.T) {
+	db := createTransferTestDB(t, "fix-coverage")
+
+	// web runs in team-a and team-b, api in team-b; old is not running
+	for _, c := range []struct{ namespace, pod, reference, digest string }{
+		{"team-a", "web-1", "web:1", "sha256:web"},
+		{"team-a", "web-2", "web:1", "sha256:web"},
+		{"team-b", "web-3", "web:1", "sha256:web"},
+		{"team-b", "api", "api:1", "sha256:api"},
+	} {
+		if _, err := db.AddContainer(containers.Container{
+			ID:    containers.ContainerID{Namespace: c.namespace, Pod: c.pod, Name: "app"},
+			Image: containers.ImageID{Reference: c.reference, Digest: c.digest},
+		}); err != nil {
+			t.Fatalf("AddContainer() error = %v", err)
+		}
+	}
+	if _, _, err := db.GetOrCreateImage(containers.ImageID{Reference: "old:1", Digest: "sha256:old"}); err != nil {
+		t.Fatalf("GetOrCreateImage() error = %v", err)
+	}
+
+	conn := db.GetConnection()
+	for _, v := range []struct{ digest, cve, pkg, version, severity, status, fixed string }{
+		{"sha256:web", "CVE-1", "openssl", "1.1.1", "High", "fixed", "1.1.1k"},
+		{"sha256:web", "CVE-2", "openssl", "1.1.1", "Critical", "fixed", "1.1.1w"},
+		{"sha256:web", "CVE-3", "bash", "5.1", "Low", "not-fixed", ""},
+		{"sha256:api", "CVE-1", "openssl", "1.1.1", "High", "fixed", "1.1.1k"},
+		{"sha256:api", "CVE-4", "zlib", "1.2.9", "Medium", "fixed", "1.2.12"},
+		{"sha256:api", "CVE-5", "zlib", "1.2.9", "Medium", "wont-fix", ""},
+		{"sha256:old", "CVE-6", "curl", "7.0", "Critical", "fixed", "7.1"},
+	} {
+		if _, err := conn.Exec(`INSERT INTO image_vulnerabilities (image_id, cve_id, package_name, package_version, package_type, severity, fix_status, fixed_version, count)
+			SELECT id, ?, ?, ?, 'apk', ?, ?, ?, 1 FROM images WHERE digest = ?`,
+			v.cve, v.pkg, v.version, v.severity, v.status, v.fixed, v.digest); err != nil {
+			t.Fatalf("seed failed: %v", err)
+		}
+	}
+
+	get := func(t *testing.T, query string) (resp struct {
+		Overall    fixCoverage   `json:"overall"`
+		Namespaces []fixCoverage `json:"namespaces"`
+	}) {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		FixCoverageHandler(db)(rec, httptest.NewRequest(http.MethodGet, "/api/analytics/fix-coverage"+query, nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+		}
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("invalid JSON: %v", err)
+		}
+		return resp
+	}
+
+	t.Run("overall and per namespace", func(t *testing.T) {
+		resp := get(t, "")
+		o := resp.Overall
+		if o.Images != 2 || o.Findings != 6 || o.Fixable != 4 || o.AwaitingFix != 1 || o.WontFix != 1 || o.Unknown != 0 {
+			t.Errorf("overall = %+v, want 2 images, 6 findings: 4 fixable, 1 awaiting, 1 wont-fix", o)
+		}
+		if len(o.TopUpgrades) != 2 {
+			t.Fatalf("overall upgrades = %+v, want openssl and zlib", o.TopUpgrades)
+		}
+		openssl := o.TopUpgrades[0]
+		if openssl.PackageName != "openssl" || openssl.FindingsResolved != 3 || openssl.Images != 2 ||
+			openssl.UpgradeTo != "1.1.1w" || len(openssl.FixedVersions) != 2 {
+			t.Errorf("top upgrade = %+v, want openssl to 1.1.1w resolving 3 findings in 2 images", openssl)
+		}
+		if o.TopUpgrades[1].UpgradeTo != "1.2.12" {
+			t.Errorf("zlib upgrade_to = %q, want 1.2.12", o.TopUpgrades[1].UpgradeTo)
+		}
+
+		if len(resp.Namespaces) != 2 || resp.Namespaces[0].Namespace != "team-a" || resp.Namespaces[1].Namespace != "team-b" {
+			t.Fatalf("namespaces = %+v, want team-a and team-b", resp.Namespaces)
+		}
+		teamA, teamB := resp.Namespaces[0], resp.Namespaces[1]
+		if teamA.Findings != 3 || teamA.Fixable != 2 || len(teamA.TopUpgrades) != 1 || teamA.TopUpgrades[0].FindingsResolved != 2 {
+			t.Errorf("team-a = %+v", teamA)
+		}
+		if teamB.Findings != 6 || teamB.Images != 2 || len(teamB.TopUpgrades) != 2 {
+			t.Errorf("team-b = %+v", teamB)
+		}
+	})
+
+	t.Run("filters", func(t *testing.T) {
+		resp := get(t, "?namespaces=team-a&severity=Critical")
+		if resp.Overall.Findings != 1 || resp.Overall.Fixable != 1 || len(resp.Namespaces) != 1 {
+			t.Errorf("filtered = %+v, want the critical openssl finding of team-a", resp)
+		}
+	})
+}
+
+func TestCompareVersions(t *testing.T) {
+	for _, tt := range []struct {
+		a, b string
+		want int
+	}{
+		{"1.2.10", "1.2.9", 1},
+		{"1.1.1k", "1.1.1w", -1},
+		{"2.0", "2.0", 0},
+		{"1.0", "1.0.1", -1},
+		{"1:2.3-1", "1:2.3-10", -1},
+	} {
+		if got := compareVersions(tt.a, tt.b); got != tt.want {
+			t.Errorf("compareVersions(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestImageSizeHandler(t *testing.T) {
+	db := createTransferTestDB(t, "image-size")
+
+	// small, medium and large run in team-a, huge in team-b; unsized has no SBOM yet
+	const mb = 1024 * 1024
+	conn := db.GetConnection()
+	for _, img := range []struct {
+		namespace, name string
+		size            int64
+		layers          int
+		sbomMs, vulnMs  int64
+		cves            int
+	}{
+		{"team-a", "small", 20 * mb, 2, 1000, 500, 1},
+		{"team-a", "medium", 150 * mb, 6, 3000, 900, 4},
+		{"team-a", "large", 400 * mb, 12, 7000, 1500, 9},
+		{"team-b", "huge", 1200 * mb, 30, 15000, 2500, 20},
+		{"team-b", "unsized", 0, 0, 0, 0, 0},
+	} {
+		digest := "sha256:" + img.name
+		if _, err := db.AddContainer(containers.Container{
+			ID:    containers.ContainerID{Namespace: img.namespace, Pod: img.name, Name: "app"},
+			Image: containers.ImageID{Reference: img.name + ":1", Digest: digest},
+		}); err != nil {
+			t.Fatalf("AddContainer() error = %v", err)
+		}
+		if img.size == 0 {
+			continue
+		}
+		if _, err := conn.Exec(`UPDATE images SET image_size = ?, layer_count = ? WHERE digest = ?`, img.size, img.layers, digest); err != nil {
+			t.Fatalf("seed failed: %v", err)
+		}
+		if err := db.RecordScanDurations(digest, time.Duration(img.sbomMs)*time.Millisecond, time.Duration(img.vulnMs)*time.Millisecond); err != nil {
+			t.Fatalf("RecordScanDurations() error = %v", err)
+		}
+		for i := 0; i < img.cves; i++ {
+			if _, err := conn.Exec(`INSERT INTO image_vulnerabilities (image_id, cve_id, package_name, package_version, package_type, severity, count)
+				SELECT id, ?, 'pkg', '1.0', 'apk', 'High', 1 FROM images WHERE digest = ?`, fmt.Sprintf("CVE-%d", i), digest); err != nil {
+				t.Fatalf("seed failed: %v", err)
+			}
+		}
+	}
+
+	type response struct {
+		Images      int                 `json:"images"`
+		UnknownSize int                 `json:"unknown_size"`
+		TotalBytes  int64               `json:"total_bytes"`
+		Buckets     []imageSizeBucket   `json:"buckets"`
+		Largest     []imageSizeRow      `json:"largest"`
+		Correlation map[string]*float64 `json:"correlation"`
+	}
+	get := func(t *testing.T, query string) (resp response) {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		ImageSizeHandler(db)(rec, httptest.NewRequest(http.MethodGet, "/api/analytics/image-size"+query, nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+		}
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("invalid JSON: %v", err)
+		}
+		return resp
+	}
+
+	t.Run("buckets, largest and correlation", func(t *testing.T) {
+		resp := get(t, "?limit=2")
+		if resp.Images != 4 || resp.UnknownSize != 1 || resp.TotalBytes != 1770*mb {
+			t.Errorf("totals = %d images, %d unknown, %d bytes", resp.Images, resp.UnknownSize, resp.TotalBytes)
+		}
+		var counts []string
+		for _, b := range resp.Buckets {
+			counts = append(counts, fmt.Sprintf("%s:%d", b.Label, b.Images))
+		}
+		if want := []string{"<50MB:1", "50-200MB:1", "200-500MB:1", "500-1000MB:0", ">=1000MB:1"}; !reflect.DeepEqual(counts, want) {
+			t.Errorf("buckets = %v, want %v", counts, want)
+		}
+		if b := resp.Buckets[2]; b.AvgLayerCount != 12 || b.AvgUniqueCVEs != 9 || b.AvgSBOMDurationMs == nil || *b.AvgSBOMDurationMs != 7000 {
+			t.Errorf("200-500MB bucket = %+v", b)
+		}
+		if b := resp.Buckets[3]; b.AvgSBOMDurationMs != nil || b.MaxBytes == nil || *b.MaxBytes != 1000*mb {
+			t.Errorf("empty bucket = %+v", b)
+		}
+		if len(resp.Largest) != 2 || resp.Largest[0].Image != "huge:1" || resp.Largest[1].Image != "large:1" ||
+			resp.Largest[0].VulnScanDurationMs == nil || *resp.Largest[0].VulnScanDurationMs != 2500 {
+			t.Errorf("largest = %+v", resp.Largest)
+		}
+		if r := resp.Correlation["size_sbom_duration"]; r == nil || *r < 0.9 {
+			t.Errorf("size/SBOM duration correlation = %v, want strongly positive", r)
+		}
+	})
+
+	t.Run("namespace filter", func(t *testing.T) {
+		resp := get(t, "?namespaces=team-b")
+		if resp.Images != 1 || resp.UnknownSize != 1 || resp.Correlation["size_sbom_duration"] != nil {
+			t.Errorf("team-b = %d images, %d unknown, correlation %v", resp.Images, resp.UnknownSize, resp.Correlation)
+		}
+	})
+}
@@ -0,0 +1,138 @@
+package handlers
+
+import (
+	"time"
+
+	"github.com/bvboe/b2s-go/scanner-core/columns"
+	"github.com/bvboe/b2s-go/scanner-core/database"
+	"github.com/bvboe/b2s-go/scanner-core/notify"
+	"github.com/bvboe/b2s-go/scanner-core/policy"
+	"github.com/bvboe/b2s-go/scanner-core/routes"
+)
+
+// DefaultCoverageLookback is used when APIOptions.CoverageLookback is zero
+const DefaultCoverageLookback = 24 * time.Hour
+
+// APIOptions configures RegisterAPIHandlers
+type APIOptions struct {
+	Transfer         TransferConfig
+	Report           ReportConfig
+	CoverageLookback time.Duration           // completed Jobs within this window count towards coverage
+	WebUI            bool                    // serve the embedded web UI
+	Version          string                  // build version; busts web UI asset caches on upgrade
+	NodeAPI          bool                    // serve /api/nodes (host scanning)
+	FixHints         FixHintFinder           // optional "fix available in tag X" hints on image details
+	DiskUsage        DiskUsageReporter       // optional data volume usage at /api/status/disk
+	OSLifecycle      OSLifecycle             // optional OS end-of-life status on /api/images and /api/summary/os-eol
+	AdHocScan        AdHocScanner            // optional on-demand scans of images at /api/scan
+	DeadLetter       DeadLetterQueue         // optional dead-lettered scans at /api/scan-queue/dead-letter
+	ScanNow          ScanNowQueue            // optional "scan now" requests that jump the queue at /api/scan-queue/scan-now
+	Rescan           RescanQueue             // optional manual rescans at /api/images/{digest}/rescan and /api/rescan-all
+	ScanQueue        ScanQueueStatusProvider // optional scan queue progress at /api/scan-queue
+	NodeScanners     NodeScannerReporter     // optional node scanner compatibility at /api/nodes/scanners
+	NotifyRouter     *notify.Router          // optional alert routing per namespace at /api/notify/routes
+	Policy           policy.Evaluator        // optional pass/fail verdicts at /api/images/{digest}/policy and /api/policy/report
+	RegistryCrawl    bool                    // serve the images found by the registry crawl at /api/registry/images
+	ReadOnly         bool                    // hide mutating controls at /api/ui-config (wrap the server handler with ReadOnlyMiddleware)
+	ComputedColumns  []columns.Column        // optional computed columns on /api/images and /api/containers
+	PathRedaction    string                  // redact file paths in SBOM and vulnerability downloads and exports (sbomformat.Redact*)
+
+	// Failing images per node and failure reason that fire an alert at
+	// /api/scan-queue/failures (0 lists the groups without alerting)
+	ScanFailureAlertThreshold int
+
+	// Time without a status change after which an image in generating_sbom
+	// or scanning_vulnerabilities is reported as stuck at /api/status
+	StuckScanTimeout time.Duration
+
+	// Images above which /api/rescan-all must be confirmed (0 = never)
+	RescanConfirmThreshold int
+
+	// How long soft-deleted images are kept before they are purged, as
+	// previewed at /api/gc/preview (0 purges them on the next run)
+	DeletedImageRetention time.Duration
+}
+
+// RegisterAPIHandlers registers the database-backed REST API shared by all
+// scanner-core based servers: image, container and vulnerability queries,
+// import/export, badges, reports, coverage, the purge preview, migration status and schema
+// compatibility, the schema, the severity scale, vulnerability annotations,
+// grouped scan failures, scan pipeline health, the OpenAPI spec, the web UI control settings and
+// optionally disk usage, OS end-of-life status, on-demand scans, the scan
+// dead-letter list, scan now requests, manual rescans, scan queue progress,
+// registry crawl results, node scanner compatibility, notification routes, policy verdicts,
+// the web UI and node endpoints. Programs embedding scanner-core can call this instead of
+// registering each handler group individually.
+func RegisterAPIHandlers(reg *routes.Registry, db *database.DB, opts APIOptions) {
+	if opts.CoverageLookback == 0 {
+		opts.CoverageLookback = DefaultCoverageLookback
+	}
+	if opts.StuckScanTimeout == 0 {
+		opts.StuckScanTimeout = DefaultStuckScanTimeout
+	}
+
+	opts.Transfer.PathRedaction = opts.PathRedaction
+
+	var overrides *HandlerOverrides
+	if opts.FixHints != nil || opts.OSLifecycle != nil || opts.Policy != nil || len(opts.ComputedColumns) > 0 || opts.PathRedaction != "" {
+		overrides = &HandlerOverrides{FixHints: opts.FixHints, OSLifecycle: opts.OSLifecycle, Policy: opts.Policy,
+			ComputedColumns: opts.ComputedColumns, PathRedaction: opts.PathRedaction}
+	}
+	RegisterDatabaseHandlers(reg, db, overrides)
+	RegisterTransferHandlers(reg, db, opts.Transfer)
+	RegisterBadgeHandlers(reg, db)
+	RegisterReportHandlers(reg, db, opts.Report)
+	RegisterCoverageHandlers(reg, db, opts.CoverageLookback)
+	RegisterGCHandlers(reg, db, opts.DeletedImageRetention)
+	RegisterMigrationHandlers(reg, db, opts.Version)
+	RegisterSchemaHandlers(reg, db)
+	RegisterSeverityHandlers(reg, db)
+	RegisterCVEAnnotationHandlers(reg, db)
+	RegisterCMDBHandlers(reg, db)
+	RegisterVEXHandlers(reg, db)
+	RegisterIgnoreHandlers(reg, db)
+	RegisterScanFailureHandlers(reg, db, opts.ScanFailureAlertThreshold)
+	RegisterStatusHandlers(reg, db, opts.StuckScanTimeout)
+	RegisterOpenAPIHandlers(reg, opts.Version)
+	RegisterUIConfigHandlers(reg, newUIConfig(opts))
+	if opts.DiskUsage != nil {
+		RegisterDiskUsageHandlers(reg, opts.DiskUsage)
+	}
+	if opts.OSLifecycle != nil {
+		RegisterOSEOLHandlers(reg, db, opts.OSLifecycle)
+	}
+	if opts.AdHocScan != nil {
+		RegisterAdHocScanHandlers(reg, opts.AdHocScan, db)
+	}
+	if opts.DeadLetter != nil {
+		RegisterDeadLetterHandlers(reg, opts.DeadLetter, db)
+	}
+	if opts.ScanNow != nil {
+		RegisterScanNowHandlers(reg, opts.ScanNow)
+	}
+	if opts.Rescan != nil {
+		RegisterRescanHandlers(reg, opts.Rescan, opts.RescanConfirmThreshold)
+	}
+	if opts.ScanQueue != nil {
+		RegisterScanQueueHandlers(reg, opts.ScanQueue)
+	}
+	if opts.RegistryCrawl {
+		RegisterRegistryHandlers(reg, db)
+	}
+	if opts.NodeScanners != nil {
+		RegisterNodeScannerHandlers(reg, opts.NodeScanners)
+	}
+	if opts.NotifyRouter != nil {
+		RegisterNotifyHandlers(reg, opts.NotifyRouter)
+	}
+	if opts.Policy != nil {
+		RegisterPolicyHandlers(reg, db, opts.Policy)
+	}
+
+	if opts.WebUI {
+		RegisterStaticHandlers(reg, opts.Version)
+	}
+	if opts.NodeAPI {
+		RegisterNodeHandlers(reg, db)
+	}
+}
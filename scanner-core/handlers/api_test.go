@@ -0,0 +1,59 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/bvboe/b2s-go/scanner-core/notify"
+	"github.com/bvboe/b2s-go/scanner-core/policy"
+	"github.com/bvboe/b2s-go/scanner-core/routes"
+)
+
+func TestRegisterAPIHandlers(t *testing.T) {
+	db := createTransferTestDB(t, "api")
+
+	tests := []struct {
+		name   string
+		opts   APIOptions
+		path   string
+		wantOK bool
+	}{
+		{name: "images", path: "/api/images", wantOK: true},
+		{name: "coverage uses default lookback", path: "/api/summary/coverage", wantOK: true},
+		{name: "scan trends", path: "/api/summary/trends?interval=day", wantOK: true},
+		{name: "scan health", path: "/api/status", wantOK: true},
+		{name: "migration status", path: "/api/status/migration", wantOK: true},
+		{name: "schema compatibility", opts: APIOptions{Version: "v0.1.164"}, path: "/api/status/compatibility", wantOK: true},
+		{name: "schema", path: "/api/admin/schema", wantOK: true},
+		{name: "vulnerabilities", path: "/api/vulnerabilities", wantOK: true},
+		{name: "cve annotations", path: "/api/cve-annotations", wantOK: true},
+		{name: "blast radius", path: "/api/analytics/blast-radius?package=openssl", wantOK: true},
+		{name: "fix coverage", path: "/api/analytics/fix-coverage", wantOK: true},
+		{name: "image size", path: "/api/analytics/image-size", wantOK: true},
+		{name: "openapi spec", path: "/api/openapi.json", wantOK: true},
+		{name: "ui config", path: "/api/ui-config", wantOK: true},
+		{name: "notify routes disabled", path: "/api/notify/routes", wantOK: false},
+		{name: "notify routes", opts: APIOptions{NotifyRouter: notify.NewRouter(nil)}, path: "/api/notify/routes", wantOK: true},
+		{name: "policy disabled", path: "/api/policy/report", wantOK: false},
+		{name: "policy report", opts: APIOptions{Policy: policy.Default()}, path: "/api/policy/report", wantOK: true},
+		{name: "registry crawl disabled", path: "/api/registry/images", wantOK: false},
+		{name: "registry crawl", opts: APIOptions{RegistryCrawl: true}, path: "/api/registry/images", wantOK: true},
+		{name: "nodes disabled", path: "/api/nodes", wantOK: false},
+		{name: "nodes enabled", opts: APIOptions{NodeAPI: true}, path: "/api/nodes", wantOK: true},
+		{name: "node scanners", opts: APIOptions{NodeAPI: true, NodeScanners: &mockNodeScannerReporter{}}, path: "/api/nodes/scanners", wantOK: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mux := routes.NewRegistry()
+			RegisterAPIHandlers(mux, db, tt.opts)
+
+			w := httptest.NewRecorder()
+			mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.path, nil))
+			if gotOK := w.Code == http.StatusOK; gotOK != tt.wantOK {
+				t.Errorf("GET %s status = %d, want OK = %v", tt.path, w.Code, tt.wantOK)
+			}
+		})
+	}
+}
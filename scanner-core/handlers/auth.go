@@ -0,0 +1,465 @@
+package handlers
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/bvboe/b2s-go/scanner-core/config"
+	"github.com/bvboe/b2s-go/scanner-core/routes"
+)
+
+// Authentication is off unless static tokens or an OIDC issuer are
+// configured. When on, every route but the public ones (probes, badges,
+// metrics, web UI assets, login) needs a bearer token or a session cookie,
+// and admin routes need the admin role. The web UI logs in at
+// /api/auth/login, which sets the session cookie: through the OIDC provider
+// when a redirect URL is configured, otherwise by pasting a token.
+//
+// The session cookie never holds the token itself: it holds the caller's
+// identity and expiry, signed with an HMAC key derived from the configured
+// secrets (static tokens and OIDC client secret), so all replicas with the
+// same settings accept it and changing a secret ends every session.
+
+// sessionCookie holds the signed identity of a web UI session
+const sessionCookie = "bjorn2scan_session"
+
+// stateCookie holds the state, nonce, PKCE verifier and return path of an
+// OIDC login in progress
+const stateCookie = "bjorn2scan_oidc_state"
+
+// tokenSessionLifetime is how long a session started with a static token lasts
+const tokenSessionLifetime = 12 * time.Hour
+
+var (
+	// errNoCredentials is returned when a request carries no token
+	errNoCredentials = errors.New("no credentials")
+	// errInvalidToken is returned for a token that is neither a static token
+	// nor a valid ID token
+	errInvalidToken = errors.New("invalid token")
+	// errNoRole is returned for an authenticated user outside the viewer and
+	// admin groups
+	errNoRole = errors.New("user has no role")
+	// errInvalidSession is returned for a session cookie that is not signed
+	// with the session key, or has expired
+	errInvalidSession = errors.New("invalid or expired session")
+)
+
+// Identity is the authenticated caller of a request
+type Identity struct {
+	Subject string      `json:"subject"` // token name, or the user's email, username or subject
+	Role    routes.Role `json:"role"`    // viewer or admin
+	Method  string      `json:"method"`  // "token" or "oidc"
+	Expires time.Time   `json:"expires,omitzero"`
+}
+
+// identityKey is the context key of the request's Identity
+type identityKey struct{}
+
+// IdentityFromRequest returns the authenticated caller of a request, nil when
+// authentication is off
+func IdentityFromRequest(r *http.Request) *Identity {
+	id, _ := r.Context().Value(identityKey{}).(*Identity)
+	return id
+}
+
+// staticToken is a configured bearer token
+type staticToken struct {
+	name  string
+	token string
+	role  routes.Role
+}
+
+// AuthTokens are the static bearer tokens accepted by the API
+type AuthTokens []staticToken
+
+// ParseAuthTokens parses a list of role=token entries separated by commas or
+// newlines, where role is viewer or admin. A role may have several tokens;
+// a token may only appear once.
+// each is named after its role and position (admin-1, viewer-2) in logs and
+// audit trails.
+func ParseAuthTokens(spec string) (AuthTokens, error) {
+	var tokens AuthTokens
+	counts := map[routes.Role]int{}
+	for _, entry := range strings.FieldsFunc(spec, func(r rune) bool { return r == ',' || r == '\n' }) {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		roleName, token, ok := strings.Cut(entry, "=")
+		roleName, token = strings.ToLower(strings.TrimSpace(roleName)), strings.TrimSpace(token)
+		if !ok || token == "" {
+			return nil, fmt.Errorf("invalid auth token entry: expected role=token")
+		}
+		var role routes.Role
+		switch roleName {
+		case "viewer":
+			role = routes.RoleViewer
+		case "admin":
+			role = routes.RoleAdmin
+		default:
+			return nil, fmt.Errorf("invalid auth token role %q: expected viewer or admin", roleName)
+		}
+		if tokens.lookup(token) != nil {
+			return nil, fmt.Errorf("duplicate auth token")
+		}
+		counts[role]++
+		tokens = append(tokens, staticToken{name: fmt.Sprintf("%s-%d", role, counts[role]), token: token, role: role})
+	}
+	return tokens, nil
+}
+
+// lookup returns the identity of a static token
+func (t AuthTokens) lookup(token string) *Identity {
+	var found *Identity
+	for _, candidate := range t {
+		// Compare against every token so timing does not reveal which matched
+		if subtle.ConstantTimeCompare([]byte(candidate.token), []byte(token)) == 1 && found == nil {
+			found = &Identity{Subject: candidate.name, Role: candidate.role, Method: "token"}
+		}
+	}
+	return found
+}
+
+// Authenticator checks the credentials of API requests
+type Authenticator struct {
+	tokens     AuthTokens
+	oidc       *OIDCProvider
+	sessionKey []byte // HMAC key of session cookies
+}
+
+// NewAuthenticator returns an authenticator accepting the static tokens and,
+// with a non-nil provider, the provider's ID tokens
+func NewAuthenticator(tokens AuthTokens, oidc *OIDCProvider) *Authenticator {
+	return &Authenticator{tokens: tokens, oidc: oidc, sessionKey: sessionKey(tokens, oidc)}
+}
+
+// sessionKey derives the session HMAC key from the configured secrets. With
+// no secret to derive it from (an OIDC public client without static tokens),
+// a random key is used, so sessions end when the server restarts.
+func sessionKey(tokens AuthTokens, oidc *OIDCProvider) []byte {
+	mac := hmac.New(sha256.New, []byte("bjorn2scan session key"))
+	secrets := 0
+	for _, t := range tokens {
+		_, _ = mac.Write([]byte(t.token + "\x00"))
+		secrets++
+	}
+	if oidc != nil && oidc.cfg.ClientSecret != "" {
+		_, _ = mac.Write([]byte(oidc.cfg.ClientSecret + "\x00"))
+		secrets++
+	}
+	if secrets == 0 {
+		key := make([]byte, sha256.Size)
+		_, _ = rand.Read(key) // crypto/rand.Read never returns an error
+		log.Info("no secret to derive the session key from; web UI sessions end when the server restarts")
+		return key
+	}
+	return mac.Sum(nil)
+}
+
+// NewAuthenticatorFromConfig returns the authenticator for the auth_* settings
+func NewAuthenticatorFromConfig(cfg *config.Config) (*Authenticator, error) {
+	tokens, err := ParseAuthTokens(cfg.AuthTokens)
+	if err != nil {
+		return nil, err
+	}
+	var oidc *OIDCProvider
+	if cfg.AuthOIDCIssuerURL != "" {
+		oidc, err = NewOIDCProvider(OIDCConfig{
+			IssuerURL:    cfg.AuthOIDCIssuerURL,
+			ClientID:     cfg.AuthOIDCClientID,
+			ClientSecret: cfg.AuthOIDCClientSecret,
+			RedirectURL:  cfg.AuthOIDCRedirectURL,
+			Scopes:       cfg.AuthOIDCScopes,
+			GroupsClaim:  cfg.AuthOIDCGroupsClaim,
+			AdminGroups:  cfg.AuthOIDCAdminGroups,
+			ViewerGroups: cfg.AuthOIDCViewerGroups,
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return NewAuthenticator(tokens, oidc), nil
+}
+
+// Enabled reports whether any credentials are configured; without them
+// authentication is off
+func (a *Authenticator) Enabled() bool {
+	return a != nil && (len(a.tokens) > 0 || a.oidc != nil)
+}
+
+// Authenticate returns the caller of a request, from its bearer token or
+// session cookie
+func (a *Authenticator) Authenticate(r *http.Request) (*Identity, error) {
+	if header := r.Header.Get("Authorization"); header != "" {
+		scheme, value, _ := strings.Cut(header, " ")
+		if !strings.EqualFold(scheme, "Bearer") {
+			return nil, errInvalidToken
+		}
+		token := strings.TrimSpace(value)
+		if token == "" {
+			return nil, errNoCredentials
+		}
+		return a.authenticateToken(r.Context(), token)
+	}
+	if cookie, err := r.Cookie(sessionCookie); err == nil && cookie.Value != "" {
+		return a.verifySession(cookie.Value, time.Now())
+	}
+	return nil, errNoCredentials
+}
+
+// session is the signed content of a session cookie
+type session struct {
+	Subject string `json:"sub"`
+	Role    string `json:"role"` // viewer or admin
+	Method  string `json:"method"`
+	Expires int64  `json:"exp"` // Unix seconds
+}
+
+// signSession returns the session cookie value of id, valid until expires:
+// the base64url JSON session and its base64url HMAC-SHA256, joined by a dot
+func (a *Authenticator) signSession(id *Identity, expires time.Time) (string, error) {
+	payload, err := json.Marshal(session{Subject: id.Subject, Role: id.Role.String(), Method: id.Method, Expires: expires.Unix()})
+	if err != nil {
+		return "", err
+	}
+	encoded := base64.RawURLEncoding.EncodeToString(payload)
+	mac := hmac.New(sha256.New, a.sessionKey)
+	_, _ = mac.Write([]byte(encoded))
+	return encoded + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil)), nil
+}
+
+// verifySession returns the identity of a session cookie value, rejecting
+// values not signed with the session key and sessions expired at now
+func (a *Authenticator) verifySession(value string, now time.Time) (*Identity, error) {
+	encoded, signature, ok := strings.Cut(value, ".")
+	if !ok {
+		return nil, errInvalidSession
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(signature)
+	if err != nil {
+		return nil, errInvalidSession
+	}
+	mac := hmac.New(sha256.New, a.sessionKey)
+	_, _ = mac.Write([]byte(encoded))
+	if !hmac.Equal(sig, mac.Sum(nil)) {
+		return nil, errInvalidSession
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, errInvalidSession
+	}
+	var s session
+	if err := json.Unmarshal(payload, &s); err != nil {
+		return nil, errInvalidSession
+	}
+	expires := time.Unix(s.Expires, 0)
+	if !now.Before(expires) {
+		return nil, errInvalidSession
+	}
+	var role routes.Role
+	switch s.Role {
+	case routes.RoleViewer.String():
+		role = routes.RoleViewer
+	case routes.RoleAdmin.String():
+		role = routes.RoleAdmin
+	default:
+		return nil, errInvalidSession
+	}
+	return &Identity{Subject: s.Subject, Role: role, Method: s.Method, Expires: expires}, nil
+}
+
+// authenticateToken returns the identity of a static token or ID token
+func (a *Authenticator) authenticateToken(ctx context.Context, token string) (*Identity, error) {
+	if id := a.tokens.lookup(token); id != nil {
+		return id, nil
+	}
+	if a.oidc != nil && strings.Count(token, ".") == 2 {
+		return a.oidc.Verify(ctx, token)
+	}
+	return nil, errInvalidToken
+}
+
+// AuthMiddleware rejects requests to non-public routes without valid
+// credentials (401) and requests to admin routes from viewers (403), and
+// passes the caller on to handlers (see IdentityFromRequest). A nil or
+// disabled authenticator leaves every route open.
+func AuthMiddleware(a *Authenticator) routes.Middleware {
+	return func(route routes.Route, next http.Handler) http.Handler {
+		if !a.Enabled() || route.Role == routes.RolePublic {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			id, err := a.Authenticate(r)
+			switch {
+			case errors.Is(err, errNoRole):
+				http.Error(w, "Forbidden", http.StatusForbidden)
+				return
+			case err != nil:
+				if !errors.Is(err, errNoCredentials) {
+					log.Debug("rejected request with invalid credentials", "path", r.URL.Path, "error", err)
+				}
+				w.Header().Set("WWW-Authenticate", `Bearer realm="bjorn2scan"`)
+				http.Error(w, "Unauthorized", http.StatusUnauthorized)
+				return
+			}
+			if route.Role == routes.RoleAdmin && id.Role != routes.RoleAdmin {
+				log.Info("rejected admin request from viewer", "path", r.URL.Path, "subject", id.Subject)
+				http.Error(w, "Admin role required", http.StatusForbidden)
+				return
+			}
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), identityKey{}, id)))
+		})
+	}
+}
+
+// RegisterAuthHandlers registers the login, logout and current user
+// endpoints. Does nothing when authentication is off.
+func RegisterAuthHandlers(reg *routes.Registry, a *Authenticator) {
+	if !a.Enabled() {
+		return
+	}
+	reg.Handle(
+		routes.Route{Pattern: "/api/auth/login", Methods: []string{http.MethodGet, http.MethodPost}, Handler: AuthLoginHandler(a), Role: routes.RolePublic, CacheControl: routes.NoStore},
+		routes.Route{Pattern: "/api/auth/callback", Methods: routes.GET, Handler: AuthCallbackHandler(a), Role: routes.RolePublic, CacheControl: routes.NoStore},
+		routes.Route{Pattern: "/api/auth/logout", Methods: routes.POST, Handler: AuthLogoutHandler(), Role: routes.RolePublic},
+		routes.Route{Pattern: "/api/auth/whoami", Methods: routes.GET, Handler: AuthWhoAmIHandler(), CacheControl: routes.NoStore},
+	)
+}
+
+// loginPage is the token form of /api/auth/login
+var loginPage = template.Must(template.New("login").Parse(`<!DOCTYPE html>
+<html><head><meta charset="utf-8"><title>bjorn2scan login</title><link rel="stylesheet" href="/styles.css"></head>
+<body><main class="container" style="max-width: 28rem; margin-top: 4rem">
+<h1>bjorn2scan</h1>
+{{if .Error}}<p class="error">{{.Error}}</p>{{end}}
+<form method="post" action="/api/auth/login">
+<input type="hidden" name="redirect" value="{{.Redirect}}">
+<label for="token">API token</label>
+<input type="password" id="token" name="token" autocomplete="current-password" autofocus required style="width: 100%">
+<button type="submit">Log in</button>
+</form>
+{{if .OIDC}}<p><a href="/api/auth/login?sso=1&amp;redirect={{.Redirect}}">Log in with single sign-on</a></p>{{end}}
+</main></body></html>
+`))
+
+// AuthLoginHandler creates an HTTP handler for /api/auth/login. GET starts
+// an OIDC login when one is configured (and no static tokens are, or ?sso=1)
+// and otherwise shows a form for a token; POST checks the token and starts a
+// session. ?redirect= is the web UI path to return to.
+func AuthLoginHandler(a *Authenticator) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		redirect := safeRedirect(r.FormValue("redirect"))
+		sso := a.oidc != nil && a.oidc.LoginEnabled()
+
+		if r.Method == http.MethodGet {
+			if sso && (len(a.tokens) == 0 || r.URL.Query().Get("sso") != "") {
+				startOIDCLogin(w, r, a.oidc, redirect)
+				return
+			}
+			renderLoginPage(w, http.StatusOK, redirect, "", sso)
+			return
+		}
+
+		r.Body = http.MaxBytesReader(w, r.Body, 16<<10)
+		token := strings.TrimSpace(r.FormValue("token"))
+		id, err := a.authenticateToken(r.Context(), token)
+		if err != nil {
+			log.Info("failed web UI login", "remote", r.RemoteAddr, "error", err)
+			renderLoginPage(w, http.StatusUnauthorized, redirect, "Invalid token", sso)
+			return
+		}
+		expires := time.Now().Add(tokenSessionLifetime)
+		if !id.Expires.IsZero() && id.Expires.Before(expires) {
+			expires = id.Expires
+		}
+		if err := a.setSessionCookie(w, r, id, expires); err != nil {
+			log.Error("error starting web UI session", "error", err)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		log.Info("web UI login", "subject", id.Subject, "role", id.Role, "method", id.Method)
+		http.Redirect(w, r, redirect, http.StatusSeeOther)
+	}
+}
+
+// renderLoginPage writes the token form
+func renderLoginPage(w http.ResponseWriter, status int, redirect, message string, sso bool) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+	if err := loginPage.Execute(w, map[string]interface{}{"Redirect": redirect, "Error": message, "OIDC": sso}); err != nil {
+		log.Error("error rendering login page", "error", err)
+	}
+}
+
+// AuthLogoutHandler creates an HTTP handler for POST /api/auth/logout, which
+// ends the web UI session
+func AuthLogoutHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		http.SetCookie(w, &http.Cookie{Name: sessionCookie, Value: "", Path: "/", MaxAge: -1, HttpOnly: true,
+			Secure: isHTTPS(r), SameSite: http.SameSiteLaxMode})
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// AuthWhoAmIHandler creates an HTTP handler for GET /api/auth/whoami, which
+// returns the caller's identity so the web UI can show who is logged in and
+// hide admin controls from viewers
+func AuthWhoAmIHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := IdentityFromRequest(r)
+		if id == nil {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(id); err != nil {
+			log.Error("error encoding identity", "error", err)
+		}
+	}
+}
+
+// setSessionCookie starts a web UI session of id, ending at expires
+func (a *Authenticator) setSessionCookie(w http.ResponseWriter, r *http.Request, id *Identity, expires time.Time) error {
+	value, err := a.signSession(id, expires)
+	if err != nil {
+		return err
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     sessionCookie,
+		Value:    value,
+		Path:     "/",
+		Expires:  expires,
+		HttpOnly: true,
+		Secure:   isHTTPS(r),
+		// Lax keeps the cookie off cross-site POSTs, so other sites cannot
+		// trigger admin actions with it
+		SameSite: http.SameSiteLaxMode,
+	})
+	return nil
+}
+
+// isHTTPS reports whether the client reached the server over HTTPS, directly
+// or through a TLS-terminating proxy
+func isHTTPS(r *http.Request) bool {
+	return r.TLS != nil || strings.EqualFold(r.Header.Get("X-Forwarded-Proto"), "https")
+}
+
+// safeRedirect returns path if it is a local path, "/" otherwise, so login
+// cannot be used to redirect to another site
+func safeRedirect(path string) string {
+	if !strings.HasPrefix(path, "/") || strings.HasPrefix(path, "//") || strings.Contains(path, `\`) {
+		return "/"
+	}
+	return path
+}
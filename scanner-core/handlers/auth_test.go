@@ -0,0 +1,407 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-jose/go-jose/v4"
+	"github.com/go-jose/go-jose/v4/jwt"
+
+	"github.com/bvboe/b2s-go/scanner-core/routes"
+)
+
+func TestParseAuthTokens(t *testing.T) {
+	tokens, err := ParseAuthTokens("admin=s3cret,\nviewer=0ther, viewer=third")
+	if err != nil {
+		t.Fatalf("ParseAuthTokens() error = %v", err)
+	}
+	if id := tokens.lookup("s3cret"); id == nil || id.Role != routes.RoleAdmin || id.Subject != "admin-1" {
+		t.Errorf("lookup(admin token) = %+v", id)
+	}
+	if id := tokens.lookup("third"); id == nil || id.Role != routes.RoleViewer || id.Subject != "viewer-2" {
+		t.Errorf("lookup(viewer token) = %+v", id)
+	}
+	if id := tokens.lookup("nope"); id != nil {
+		t.Errorf("lookup(unknown) = %+v, want nil", id)
+	}
+
+	for _, spec := range []string{"s3cret", "root=s3cret", "admin=", "admin=a,viewer=a"} {
+		if _, err := ParseAuthTokens(spec); err == nil {
+			t.Errorf("ParseAuthTokens(%q) succeeded, want an error", spec)
+		}
+	}
+}
+
+// newAuthTestRegistry registers a viewer, an admin and a public route behind
+// the auth middleware
+func newAuthTestRegistry(a *Authenticator) *routes.Registry {
+	ok := func(w http.ResponseWriter, r *http.Request) {
+		if id := IdentityFromRequest(r); id != nil {
+			_, _ = w.Write([]byte(id.Subject))
+		}
+	}
+	reg := routes.NewRegistry(AuthMiddleware(a))
+	reg.Handle(
+		routes.Route{Pattern: "/api/images", Methods: routes.GET, Handler: http.HandlerFunc(ok)},
+		routes.Route{Pattern: "/api/rescan", Methods: routes.POST, Handler: http.HandlerFunc(ok), Role: routes.RoleAdmin},
+		routes.Route{Pattern: "/health", Handler: http.HandlerFunc(ok), Role: routes.RolePublic},
+	)
+	RegisterAuthHandlers(reg, a)
+	return reg
+}
+
+func TestAuthMiddlewareStaticTokens(t *testing.T) {
+	tokens, err := ParseAuthTokens("admin=admintoken,viewer=viewertoken")
+	if err != nil {
+		t.Fatalf("ParseAuthTokens() error = %v", err)
+	}
+	reg := newAuthTestRegistry(NewAuthenticator(tokens, nil))
+
+	tests := []struct {
+		method, path, token string
+		want                int
+	}{
+		{http.MethodGet, "/health", "", http.StatusOK},
+		{http.MethodGet, "/api/images", "", http.StatusUnauthorized},
+		{http.MethodGet, "/api/images", "wrong", http.StatusUnauthorized},
+		{http.MethodGet, "/api/images", "viewertoken", http.StatusOK},
+		{http.MethodPost, "/api/rescan", "viewertoken", http.StatusForbidden},
+		{http.MethodPost, "/api/rescan", "admintoken", http.StatusOK},
+		{http.MethodGet, "/api/auth/whoami", "", http.StatusUnauthorized},
+		{http.MethodGet, "/api/auth/login", "", http.StatusOK},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(tt.method, tt.path, nil)
+		if tt.token != "" {
+			req.Header.Set("Authorization", "Bearer "+tt.token)
+		}
+		rec := httptest.NewRecorder()
+		reg.ServeHTTP(rec, req)
+		if rec.Code != tt.want {
+			t.Errorf("%s %s with %q: status = %d, want %d", tt.method, tt.path, tt.token, rec.Code, tt.want)
+		}
+		if rec.Code == http.StatusUnauthorized && rec.Header().Get("WWW-Authenticate") == "" {
+			t.Errorf("%s %s: 401 without WWW-Authenticate", tt.method, tt.path)
+		}
+	}
+
+	// Web UI login sets a session cookie that authenticates later requests
+	form := url.Values{"token": {"viewertoken"}, "redirect": {"/images.html"}}
+	req := httptest.NewRequest(http.MethodPost, "/api/auth/login", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rec := httptest.NewRecorder()
+	reg.ServeHTTP(rec, req)
+	if rec.Code != http.StatusSeeOther || rec.Header().Get("Location") != "/images.html" {
+		t.Fatalf("login: status = %d, location = %q", rec.Code, rec.Header().Get("Location"))
+	}
+	cookies := rec.Result().Cookies()
+	if len(cookies) != 1 || cookies[0].Name != sessionCookie || !cookies[0].HttpOnly {
+		t.Fatalf("login cookies = %+v", cookies)
+	}
+	if strings.Contains(cookies[0].Value, "viewertoken") {
+		t.Errorf("session cookie %q holds the token", cookies[0].Value)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/auth/whoami", nil)
+	req.AddCookie(cookies[0])
+	rec = httptest.NewRecorder()
+	reg.ServeHTTP(rec, req)
+	var id struct {
+		Subject string `json:"subject"`
+		Role    string `json:"role"`
+		Method  string `json:"method"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&id); err != nil || id.Role != "viewer" || id.Method != "token" {
+		t.Errorf("whoami = %+v, %v", id, err)
+	}
+
+	form.Set("token", "wrong")
+	form.Set("redirect", "//evil.example.com")
+	req = httptest.NewRequest(http.MethodPost, "/api/auth/login", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rec = httptest.NewRecorder()
+	reg.ServeHTTP(rec, req)
+	if rec.Code != http.StatusUnauthorized || len(rec.Result().Cookies()) != 0 {
+		t.Errorf("login with a wrong token: status = %d, cookies = %v", rec.Code, rec.Result().Cookies())
+	}
+	if strings.Contains(rec.Body.String(), "evil.example.com") {
+		t.Error("login page kept an off-site redirect")
+	}
+}
+
+func TestAuthSessions(t *testing.T) {
+	tokens, err := ParseAuthTokens("admin=admintoken,viewer=viewertoken")
+	if err != nil {
+		t.Fatalf("ParseAuthTokens() error = %v", err)
+	}
+	a := NewAuthenticator(tokens, nil)
+	now := time.Now()
+	viewer := &Identity{Subject: "viewer-1", Role: routes.RoleViewer, Method: "token"}
+
+	valid, err := a.signSession(viewer, now.Add(tokenSessionLifetime))
+	if err != nil {
+		t.Fatalf("signSession() error = %v", err)
+	}
+	if id, err := a.verifySession(valid, now); err != nil || id.Subject != "viewer-1" || id.Role != routes.RoleViewer {
+		t.Errorf("verifySession(valid) = %+v, %v", id, err)
+	}
+	if _, err := a.verifySession(valid, now.Add(tokenSessionLifetime)); !errors.Is(err, errInvalidSession) {
+		t.Errorf("verifySession() after the session lifetime error = %v, want errInvalidSession", err)
+	}
+
+	// A viewer session edited to claim the admin role
+	payload, signature, _ := strings.Cut(valid, ".")
+	claims, _ := base64.RawURLEncoding.DecodeString(payload)
+	forged := base64.RawURLEncoding.EncodeToString([]byte(strings.Replace(string(claims), `"viewer"`, `"admin"`, 1))) + "." + signature
+
+	// Sessions signed before the tokens changed
+	rotated, _ := ParseAuthTokens("admin=newadmintoken,viewer=viewertoken")
+	for name, value := range map[string]string{
+		"raw token":       "admintoken",
+		"forged role":     forged,
+		"unsigned":        payload,
+		"other secrets":   valid,
+		"empty signature": payload + ".",
+		"garbage":         "not.a-session",
+	} {
+		authenticator := a
+		if name == "other secrets" {
+			authenticator = NewAuthenticator(rotated, nil)
+		}
+		if _, err := authenticator.verifySession(value, now); !errors.Is(err, errInvalidSession) {
+			t.Errorf("verifySession(%s) error = %v, want errInvalidSession", name, err)
+		}
+	}
+
+	// The middleware rejects the raw token as a cookie
+	reg := newAuthTestRegistry(a)
+	req := httptest.NewRequest(http.MethodGet, "/api/images", nil)
+	req.AddCookie(&http.Cookie{Name: sessionCookie, Value: "admintoken"})
+	rec := httptest.NewRecorder()
+	reg.ServeHTTP(rec, req)
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("request with the token as session cookie: status = %d, want 401", rec.Code)
+	}
+}
+
+func TestAuthMiddlewareDisabled(t *testing.T) {
+	reg := newAuthTestRegistry(NewAuthenticator(nil, nil))
+	rec := httptest.NewRecorder()
+	reg.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/rescan", nil))
+	if rec.Code != http.StatusOK {
+		t.Errorf("admin route without authentication: status = %d, want 200", rec.Code)
+	}
+	rec = httptest.NewRecorder()
+	reg.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/auth/login", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("login without authentication: status = %d, want 404", rec.Code)
+	}
+}
+
+// testIssuer is an OIDC provider serving discovery and signing keys, and
+// tokens from its token handler
+type testIssuer struct {
+	server       *httptest.Server
+	signer       jose.Signer
+	tokenHandler http.HandlerFunc
+}
+
+func newTestIssuer(t *testing.T) *testIssuer {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("GenerateKey() error = %v", err)
+	}
+	signer, err := jose.NewSigner(jose.SigningKey{Algorithm: jose.RS256, Key: key},
+		(&jose.SignerOptions{}).WithType("JWT").WithHeader("kid", "k1"))
+	if err != nil {
+		t.Fatalf("NewSigner() error = %v", err)
+	}
+	issuer := &testIssuer{signer: signer}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 issuer.server.URL,
+			"authorization_endpoint": issuer.server.URL + "/authorize",
+			"token_endpoint":         issuer.server.URL + "/token",
+			"jwks_uri":               issuer.server.URL + "/keys",
+		})
+	})
+	mux.HandleFunc("/keys", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(jose.JSONWebKeySet{Keys: []jose.JSONWebKey{
+			{Key: &key.PublicKey, KeyID: "k1", Algorithm: string(jose.RS256), Use: "sig"},
+		}})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		if issuer.tokenHandler == nil {
+			http.NotFound(w, r)
+			return
+		}
+		issuer.tokenHandler(w, r)
+	})
+	issuer.server = httptest.NewServer(mux)
+	t.Cleanup(issuer.server.Close)
+	return issuer
+}
+
+// token signs an ID token for audience with extra claims
+func (i *testIssuer) token(t *testing.T, audience string, expiry time.Time, extra map[string]interface{}) string {
+	t.Helper()
+	raw, err := jwt.Signed(i.signer).Claims(jwt.Claims{
+		Issuer:   i.server.URL,
+		Subject:  "user-1",
+		Audience: jwt.Audience{audience},
+		IssuedAt: jwt.NewNumericDate(time.Now()),
+		Expiry:   jwt.NewNumericDate(expiry),
+	}).Claims(extra).Serialize()
+	if err != nil {
+		t.Fatalf("failed to sign token: %v", err)
+	}
+	return raw
+}
+
+func TestOIDCProviderVerify(t *testing.T) {
+	issuer := newTestIssuer(t)
+	provider, err := NewOIDCProvider(OIDCConfig{
+		IssuerURL:    issuer.server.URL,
+		ClientID:     "bjorn2scan",
+		AdminGroups:  []string{"platform"},
+		ViewerGroups: []string{"dev", "platform"},
+	})
+	if err != nil {
+		t.Fatalf("NewOIDCProvider() error = %v", err)
+	}
+	hour := time.Now().Add(time.Hour)
+
+	tests := []struct {
+		name     string
+		token    string
+		wantRole routes.Role
+		wantErr  error
+	}{
+		{"admin group", issuer.token(t, "bjorn2scan", hour, map[string]interface{}{"groups": []string{"platform"}, "email": "a@example.com"}), routes.RoleAdmin, nil},
+		{"viewer group as string", issuer.token(t, "bjorn2scan", hour, map[string]interface{}{"groups": "dev"}), routes.RoleViewer, nil},
+		{"no matching group", issuer.token(t, "bjorn2scan", hour, map[string]interface{}{"groups": []string{"sales"}}), 0, errNoRole},
+		{"wrong audience", issuer.token(t, "other", hour, nil), 0, errInvalidToken},
+		{"expired", issuer.token(t, "bjorn2scan", time.Now().Add(-time.Hour), nil), 0, errInvalidToken},
+		{"garbage", "a.b.c", 0, errInvalidToken},
+	}
+	for _, tt := range tests {
+		id, err := provider.Verify(t.Context(), tt.token)
+		if tt.wantErr != nil {
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("%s: Verify() error = %v, want %v", tt.name, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil || id.Role != tt.wantRole || id.Method != "oidc" {
+			t.Errorf("%s: Verify() = %+v, %v", tt.name, id, err)
+		}
+	}
+
+	// The authenticator hands tokens with three segments to the provider
+	reg := newAuthTestRegistry(NewAuthenticator(nil, provider))
+	req := httptest.NewRequest(http.MethodGet, "/api/images", nil)
+	req.Header.Set("Authorization", "Bearer "+tests[0].token)
+	rec := httptest.NewRecorder()
+	reg.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK || rec.Body.String() != "a@example.com" {
+		t.Errorf("OIDC bearer request: status = %d, body = %q", rec.Code, rec.Body.String())
+	}
+	req = httptest.NewRequest(http.MethodGet, "/api/images", nil)
+	req.Header.Set("Authorization", "Bearer "+tests[2].token)
+	rec = httptest.NewRecorder()
+	reg.ServeHTTP(rec, req)
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("OIDC user without a role: status = %d, want 403", rec.Code)
+	}
+}
+
+func TestOIDCLogin(t *testing.T) {
+	issuer := newTestIssuer(t)
+	provider, err := NewOIDCProvider(OIDCConfig{
+		IssuerURL:    issuer.server.URL,
+		ClientID:     "bjorn2scan",
+		ClientSecret: "secret",
+		RedirectURL:  "https://scanner.example/api/auth/callback",
+	})
+	if err != nil {
+		t.Fatalf("NewOIDCProvider() error = %v", err)
+	}
+	reg := newAuthTestRegistry(NewAuthenticator(nil, provider))
+
+	// login starts the flow and returns the authorization URL and state cookie
+	login := func() (*url.URL, *http.Cookie) {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		reg.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/auth/login?redirect=/images.html", nil))
+		if rec.Code != http.StatusFound {
+			t.Fatalf("login: status = %d, want 302", rec.Code)
+		}
+		target, err := url.Parse(rec.Header().Get("Location"))
+		if err != nil {
+			t.Fatalf("invalid authorization URL: %v", err)
+		}
+		return target, rec.Result().Cookies()[0]
+	}
+	callback := func(target *url.URL, cookie *http.Cookie) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodGet, "/api/auth/callback?code=c1&state="+target.Query().Get("state"), nil)
+		req.AddCookie(cookie)
+		rec := httptest.NewRecorder()
+		reg.ServeHTTP(rec, req)
+		return rec
+	}
+
+	// The provider checks the PKCE verifier against the challenge of the
+	// login and issues an ID token with nonce
+	var challenge, nonce string
+	issuer.tokenHandler = func(w http.ResponseWriter, r *http.Request) {
+		sum := sha256.Sum256([]byte(r.PostFormValue("code_verifier")))
+		if base64.RawURLEncoding.EncodeToString(sum[:]) != challenge {
+			http.Error(w, "invalid_grant", http.StatusBadRequest)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]string{
+			"id_token": issuer.token(t, "bjorn2scan", time.Now().Add(time.Hour), map[string]interface{}{"nonce": nonce}),
+		})
+	}
+
+	target, cookie := login()
+	q := target.Query()
+	if q.Get("nonce") == "" || q.Get("code_challenge_method") != "S256" || q.Get("code_challenge") == "" {
+		t.Fatalf("authorization URL lacks nonce or PKCE: %s", target)
+	}
+	challenge, nonce = q.Get("code_challenge"), q.Get("nonce")
+	if rec := callback(target, cookie); rec.Code != http.StatusSeeOther || rec.Header().Get("Location") != "/images.html" {
+		t.Fatalf("callback: status = %d, location = %q", rec.Code, rec.Header().Get("Location"))
+	}
+
+	// An ID token issued for another login is rejected
+	firstNonce := nonce
+	target, cookie = login()
+	challenge = target.Query().Get("code_challenge")
+	if rec := callback(target, cookie); rec.Code != http.StatusUnauthorized {
+		t.Errorf("callback with another login's nonce: status = %d, want 401", rec.Code)
+	}
+
+	// A code intercepted from another login cannot be exchanged without its verifier
+	target, cookie = login()
+	nonce = target.Query().Get("nonce")
+	if rec := callback(target, cookie); rec.Code != http.StatusBadGateway {
+		t.Errorf("callback with another login's verifier: status = %d, want 502", rec.Code)
+	}
+	if firstNonce == nonce {
+		t.Error("logins share a nonce")
+	}
+}
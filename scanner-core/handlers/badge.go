@@ -0,0 +1,168 @@
+package handlers
+
+import (
+	"fmt"
+	"html"
+	"net/http"
+	"strings"
+
+	"github.com/bvboe/b2s-go/scanner-core/database"
+	"github.com/bvboe/b2s-go/scanner-core/routes"
+)
+
+// Badge colors, matching the shields.io palette
+const (
+	badgeColorCritical = "#e05d44"
+	badgeColorHigh     = "#fe7d37"
+	badgeColorMedium   = "#dfb317"
+	badgeColorLow      = "#a4a61d"
+	badgeColorClean    = "#4c1"
+	badgeColorGrey     = "#9f9f9f"
+	badgeLabelColor    = "#555"
+	badgeLabel         = "vulnerabilities"
+)
+
+// BadgeHandler creates an HTTP handler for /api/badge/{image}.svg
+// {image} is either an image digest (sha256:...) or a reference such as
+// nginx:1.25 or registry.example.com/team/app:v2, resolved to the image most
+// recently seen running under that reference. The response is a shields.io-style
+// SVG showing the worst severity and vulnerability counts, for embedding in
+// wikis and READMEs.
+func BadgeHandler(db *database.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		image := strings.TrimPrefix(r.URL.Path, "/api/badge/")
+		image = strings.TrimSuffix(image, ".svg")
+		if image == "" {
+			http.Error(w, "Image digest or reference required", http.StatusBadRequest)
+			return
+		}
+
+		digest := image
+		if !strings.HasPrefix(image, "sha256:") {
+			resolved, err := db.GetImageDigestByReference(image)
+			if err != nil {
+				log.Error("error resolving badge image reference", "reference", image, "error", err)
+				writeBadge(w, http.StatusInternalServerError, "error", badgeColorGrey)
+				return
+			}
+			if resolved == "" {
+				writeBadge(w, http.StatusNotFound, "image not found", badgeColorGrey)
+				return
+			}
+			digest = resolved
+		}
+
+		counts, err := db.GetImageSeverityCounts(digest)
+		if err != nil {
+			log.Error("error retrieving badge vulnerability counts", "digest", digest, "error", err)
+			writeBadge(w, http.StatusInternalServerError, "error", badgeColorGrey)
+			return
+		}
+		if counts == nil {
+			writeBadge(w, http.StatusNotFound, "image not found", badgeColorGrey)
+			return
+		}
+
+		message, color := badgeMessage(counts)
+		writeBadge(w, http.StatusOK, message, color)
+	}
+}
+
+// badgeMessage returns the badge text and color for an image: the count of the
+// worst severity present, followed by the total when other severities exist
+func badgeMessage(counts *database.ImageSeverityCounts) (string, string) {
+	if !counts.Status.HasVulnerabilities() {
+		if counts.Status.IsError() || counts.Status == database.StatusSBOMUnavailable {
+			return "scan failed", badgeColorGrey
+		}
+		return "not scanned", badgeColorGrey
+	}
+
+	total := counts.Total()
+	if total == 0 {
+		return "none", badgeColorClean
+	}
+
+	worst := []struct {
+		count int
+		name  string
+		color string
+	}{
+		{counts.Critical, "critical", badgeColorCritical},
+		{counts.High, "high", badgeColorHigh},
+		{counts.Medium, "medium", badgeColorMedium},
+		{counts.Low, "low", badgeColorLow},
+		{counts.Negligible, "negligible", badgeColorGrey},
+		{counts.Unknown, "unknown", badgeColorGrey},
+	}
+	for _, s := range worst {
+		if s.count == 0 {
+			continue
+		}
+		if s.count == total {
+			return fmt.Sprintf("%d %s", s.count, s.name), s.color
+		}
+		return fmt.Sprintf("%d %s | %d total", s.count, s.name, total), s.color
+	}
+	return "none", badgeColorClean
+}
+
+// writeBadge writes a flat shields.io-style badge. Badges are always served as
+// SVG (even for errors) so embedded images render, and are never cached so they
+// reflect the latest scan.
+func writeBadge(w http.ResponseWriter, status int, message, color string) {
+	w.Header().Set("Content-Type", "image/svg+xml")
+	w.Header().Set("Cache-Control", "no-cache, no-store, must-revalidate")
+	w.WriteHeader(status)
+	if _, err := w.Write([]byte(renderBadge(badgeLabel, message, color))); err != nil {
+		log.Error("error writing badge response", "error", err)
+	}
+}
+
+// renderBadge renders a flat badge with a label and message section.
+// Text widths are approximated for 11px Verdana, as shields.io does.
+func renderBadge(label, message, color string) string {
+	labelWidth := badgeTextWidth(label) + 10
+	messageWidth := badgeTextWidth(message) + 10
+	totalWidth := labelWidth + messageWidth
+	label = html.EscapeString(label)
+	message = html.EscapeString(message)
+
+	return fmt.Sprintf(`<svg xmlns="http://www.w3.org/2000/svg" width="%[1]d" height="20" role="img" aria-label="%[4]s: %[5]s">`+
+		`<title>%[4]s: %[5]s</title>`+
+		`<linearGradient id="s" x2="0" y2="100%%"><stop offset="0" stop-color="#bbb" stop-opacity=".1"/><stop offset="1" stop-opacity=".1"/></linearGradient>`+
+		`<clipPath id="r"><rect width="%[1]d" height="20" rx="3" fill="#fff"/></clipPath>`+
+		`<g clip-path="url(#r)"><rect width="%[2]d" height="20" fill="%[7]s"/><rect x="%[2]d" width="%[3]d" height="20" fill="%[6]s"/><rect width="%[1]d" height="20" fill="url(#s)"/></g>`+
+		`<g fill="#fff" text-anchor="middle" font-family="Verdana,Geneva,DejaVu Sans,sans-serif" font-size="11">`+
+		`<text x="%[8]d" y="15" fill="#010101" fill-opacity=".3">%[4]s</text><text x="%[8]d" y="14">%[4]s</text>`+
+		`<text x="%[9]d" y="15" fill="#010101" fill-opacity=".3">%[5]s</text><text x="%[9]d" y="14">%[5]s</text>`+
+		`</g></svg>`,
+		totalWidth, labelWidth, messageWidth, label, message, color, badgeLabelColor,
+		labelWidth/2, labelWidth+messageWidth/2)
+}
+
+// badgeTextWidth approximates the rendered width of text in 11px Verdana
+func badgeTextWidth(text string) int {
+	width := 0
+	for _, c := range text {
+		switch {
+		case strings.ContainsRune("iljt|!.,:; ", c):
+			width += 4
+		case c >= 'A' && c <= 'Z', c == 'm', c == 'w':
+			width += 9
+		default:
+			width += 7
+		}
+	}
+	return width
+}
+
+// RegisterBadgeHandlers registers the vulnerability badge endpoint
+func RegisterBadgeHandlers(reg *routes.Registry, db *database.DB) {
+	reg.Handle(routes.Route{Pattern: "/api/badge/", Methods: routes.GET, Handler: BadgeHandler(db), Role: routes.RolePublic, CacheControl: routes.NoStore})
+}
@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bvboe/b2s-go/scanner-core/containers"
+	"github.com/bvboe/b2s-go/scanner-core/database"
+)
+
+func TestBadgeMessage(t *testing.T) {
+	tests := []struct {
+		name        string
+		counts      database.ImageSeverityCounts
+		wantMessage string
+		wantColor   string
+	}{
+		{"not scanned", database.ImageSeverityCounts{Status: database.StatusPending}, "not scanned", badgeColorGrey},
+		{"scan failed", database.ImageSeverityCounts{Status: database.StatusSBOMFailed}, "scan failed", badgeColorGrey},
+		{"clean", database.ImageSeverityCounts{Status: database.StatusCompleted}, "none", badgeColorClean},
+		{"only worst severity", database.ImageSeverityCounts{Status: database.StatusCompleted, High: 3}, "3 high", badgeColorHigh},
+		{"worst plus total", database.ImageSeverityCounts{Status: database.StatusCompleted, Critical: 2, Medium: 5, Low: 1}, "2 critical | 8 total", badgeColorCritical},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			message, color := badgeMessage(&tt.counts)
+			if message != tt.wantMessage || color != tt.wantColor {
+				t.Errorf("badgeMessage() = %q, %q; want %q, %q", message, color, tt.wantMessage, tt.wantColor)
+			}
+		})
+	}
+}
+
+func TestBadgeHandler(t *testing.T) {
+	db := createTransferTestDB(t, "badge")
+
+	vulns := []byte(`{"matches": [
+		{"vulnerability": {"id": "CVE-2024-0001", "severity": "Critical"}, "artifact": {"name": "openssl", "version": "3.0.0", "type": "apk"}},
+		{"vulnerability": {"id": "CVE-2024-0002", "severity": "Low"}, "artifact": {"name": "busybox", "version": "1.36", "type": "apk"}}
+	]}`)
+	if err := db.ImportScanResults(testTransferDigest, []byte(`{"artifacts":[]}`), vulns, time.Time{}); err != nil {
+		t.Fatalf("Failed to seed scan results: %v", err)
+	}
+	if _, err := db.AddContainer(containers.Container{
+		ID:    containers.ContainerID{Namespace: "default", Pod: "web", Name: "nginx"},
+		Image: containers.ImageID{Reference: "registry.example.com/team/nginx:1.25", Digest: testTransferDigest},
+	}); err != nil {
+		t.Fatalf("Failed to add container: %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		path       string
+		wantStatus int
+		wantText   string
+	}{
+		{"by digest", "/api/badge/" + testTransferDigest + ".svg", http.StatusOK, "1 critical | 2 total"},
+		{"by reference", "/api/badge/registry.example.com/team/nginx:1.25.svg", http.StatusOK, "1 critical | 2 total"},
+		{"unknown reference", "/api/badge/nginx:9.99.svg", http.StatusNotFound, "image not found"},
+		{"unknown digest", "/api/badge/sha256:ffff.svg", http.StatusNotFound, "image not found"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			BadgeHandler(db)(w, httptest.NewRequest(http.MethodGet, tt.path, nil))
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", w.Code, tt.wantStatus)
+			}
+			if ct := w.Header().Get("Content-Type"); ct != "image/svg+xml" {
+				t.Errorf("Content-Type = %q", ct)
+			}
+			if body := w.Body.String(); !strings.HasPrefix(body, "<svg") || !strings.Contains(body, tt.wantText) {
+				t.Errorf("badge missing %q: %s", tt.wantText, body)
+			}
+		})
+	}
+}
@@ -0,0 +1,101 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/bvboe/b2s-go/scanner-core/database"
+	"github.com/bvboe/b2s-go/scanner-core/routes"
+)
+
+// CMDBStore stores the workload attributes synced from an external CMDB
+type CMDBStore interface {
+	GetCMDBMetadata() ([]database.CMDBEntry, error)
+	SyncCMDBMetadata(source string, entries []database.CMDBEntry) (*database.CMDBSyncResult, error)
+}
+
+// CMDBSyncRequest is the body of POST /api/cmdb/sync
+type CMDBSyncRequest struct {
+	Source  string               `json:"source"`
+	Entries []database.CMDBEntry `json:"entries"`
+}
+
+// maxCMDBSyncRequestSize bounds the sync request body
+const maxCMDBSyncRequestSize = 16 << 20
+
+// RegisterCMDBHandlers registers the CMDB metadata endpoints
+func RegisterCMDBHandlers(reg *routes.Registry, store CMDBStore) {
+	reg.Handle(
+		routes.Route{Pattern: "/api/cmdb", Methods: routes.GET, Handler: CMDBListHandler(store)},
+		routes.Route{Pattern: "/api/cmdb/sync", Methods: routes.POST, Handler: CMDBSyncHandler(store), Role: routes.RoleAdmin},
+	)
+}
+
+// CMDBListHandler creates an HTTP handler for GET /api/cmdb.
+// Returns the attributes synced from the CMDB by namespace and image label.
+func CMDBListHandler(store CMDBStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		entries, err := store.GetCMDBMetadata()
+		if err != nil {
+			log.Error("error querying CMDB metadata", "error", err)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(map[string]interface{}{
+			"entries": entries,
+			"count":   len(entries),
+		}); err != nil {
+			log.Error("error encoding CMDB metadata", "error", err)
+		}
+	}
+}
+
+// CMDBSyncHandler creates an HTTP handler for POST /api/cmdb/sync. A CMDB
+// pushes its full set of entries; entries previously pushed with the same
+// source and left out are removed.
+//
+// Request: {"source": "servicenow", "entries": [{"namespace": "payments", "owner": "team-payments"},
+// {"label": "org.opencontainers.image.source", "label_value": "https://github.com/acme/ledger", "application_id": "APP-1042"}]}
+// Response: {"source": "servicenow", "entries": 2, "removed": 0}
+func CMDBSyncHandler(store CMDBStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		var req CMDBSyncRequest
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxCMDBSyncRequestSize)).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		if strings.TrimSpace(req.Source) == "" {
+			http.Error(w, "source is required", http.StatusBadRequest)
+			return
+		}
+		if err := database.ValidateCMDBEntries(req.Entries); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		result, err := store.SyncCMDBMetadata(req.Source, req.Entries)
+		if err != nil {
+			log.Error("error syncing CMDB metadata", "source", req.Source, "error", err)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(result); err != nil {
+			log.Error("error encoding CMDB sync result", "error", err)
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/bvboe/b2s-go/scanner-core/routes"
+)
+
+func TestCMDBHandlers(t *testing.T) {
+	db := createVulnerabilityExplorerTestDB(t)
+	if _, err := db.GetConnection().Exec(`UPDATE images SET oci_source = 'https://github.com/acme/web' WHERE digest = 'sha256:web'`); err != nil {
+		t.Fatal(err)
+	}
+	mux := routes.NewRegistry()
+	RegisterDatabaseHandlers(mux, db, nil)
+	RegisterCMDBHandlers(mux, db)
+
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(method, path, strings.NewReader(body)))
+		return rec
+	}
+
+	for _, body := range []string{
+		`not json`,
+		`{"entries": [{"namespace": "team-a", "owner": "ops"}]}`,
+		`{"source": "servicenow", "entries": [{"namespace": "team-a"}]}`,
+	} {
+		if rec := do(http.MethodPost, "/api/cmdb/sync", body); rec.Code != http.StatusBadRequest {
+			t.Errorf("sync %s: status = %d, want 400", body, rec.Code)
+		}
+	}
+
+	rec := do(http.MethodPost, "/api/cmdb/sync", `{"source": "servicenow", "entries": [
+		{"namespace": "team-a", "owner": "ops"},
+		{"namespace": "team-b", "owner": "ops", "data_classification": "restricted"},
+		{"label": "org.opencontainers.image.source", "label_value": "https://github.com/acme/web", "owner": "web-team"}
+	]}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("sync: status = %d: %s", rec.Code, rec.Body.String())
+	}
+
+	var listed struct {
+		Entries []map[string]interface{} `json:"entries"`
+		Count   int                      `json:"count"`
+	}
+	if err := json.NewDecoder(do(http.MethodGet, "/api/cmdb", "").Body).Decode(&listed); err != nil {
+		t.Fatalf("failed to decode list: %v", err)
+	}
+	if listed.Count != 3 || listed.Entries[0]["source"] != "servicenow" {
+		t.Errorf("unexpected entries: %+v", listed)
+	}
+
+	// The label entry overrides the namespace owner of the web image
+	images := func(query string) []string {
+		t.Helper()
+		var response struct {
+			Images []map[string]interface{} `json:"images"`
+		}
+		if err := json.NewDecoder(do(http.MethodGet, "/api/images?"+query, "").Body).Decode(&response); err != nil {
+			t.Fatalf("failed to decode images: %v", err)
+		}
+		var refs []string
+		for _, img := range response.Images {
+			refs = append(refs, img["image"].(string))
+		}
+		return refs
+	}
+	for query, want := range map[string]string{
+		"owners=ops":                 "api:1",
+		"owners=web-team":            "web:1",
+		"classifications=restricted": "api:1",
+		"applications=APP-1":         "",
+	} {
+		if got := strings.Join(images(query), ","); got != want {
+			t.Errorf("images?%s = %q, want %q", query, got, want)
+		}
+	}
+}
@@ -0,0 +1,208 @@
+package handlers
+
+import (
+	"compress/gzip"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"sync"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// compressedResponseTypes are the response content types CompressionMiddleware
+// compresses: the JSON API and the streamed exports
+var compressedResponseTypes = map[string]bool{
+	"application/json": true,
+	ndjsonContentType:  true,
+	"text/csv":         true,
+}
+
+// zstdWindowSize bounds the zstd window; browsers reject larger windows for
+// HTTP content encoding (RFC 8878 allows 8 MB)
+const zstdWindowSize = 1 << 20
+
+// responseEncoder is a pooled gzip or zstd writer
+type responseEncoder interface {
+	io.WriteCloser
+	Flush() error
+	Reset(w io.Writer)
+}
+
+var encoderPools = map[string]*sync.Pool{
+	"zstd": {New: func() any {
+		enc, err := zstd.NewWriter(nil, zstd.WithEncoderConcurrency(1), zstd.WithWindowSize(zstdWindowSize))
+		if err != nil {
+			panic(err) // only fails on invalid options
+		}
+		return enc
+	}},
+	"gzip": {New: func() any { return gzip.NewWriter(nil) }},
+}
+
+// negotiateEncoding returns the response encoding to use for an
+// Accept-Encoding header: zstd, which compresses faster and smaller, if the
+// client supports it, then gzip, or "" for none
+func negotiateEncoding(accept string) string {
+	switch {
+	case acceptsEncoding(accept, "zstd"):
+		return "zstd"
+	case acceptsEncoding(accept, "gzip"):
+		return "gzip"
+	}
+	return ""
+}
+
+// CompressionMiddleware compresses JSON and export responses with zstd or
+// gzip, whichever the client accepts. Large listings such as /api/images
+// shrink roughly tenfold, which dominates load time over slow links.
+// Responses below minCompressSize, other content types, responses already
+// encoded or carrying an ETag (the web UI serves its own pre-compressed
+// assets) and anything but 200 OK are passed through unchanged.
+func CompressionMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		encoding := negotiateEncoding(r.Header.Get("Accept-Encoding"))
+		if encoding == "" || r.Method == http.MethodHead {
+			next.ServeHTTP(w, r)
+			return
+		}
+		cw := &compressWriter{ResponseWriter: w, encoding: encoding}
+		defer cw.close()
+		next.ServeHTTP(cw, r)
+	})
+}
+
+// compressWriter holds back the start of a response until it knows whether
+// compressing it pays off, then either encodes or passes the body through
+type compressWriter struct {
+	http.ResponseWriter
+	encoding string
+	status   int    // status written by the handler, sent once decided
+	buf      []byte // body written before deciding
+	decided  bool
+	enc      responseEncoder // nil when passing through
+}
+
+func (cw *compressWriter) WriteHeader(status int) {
+	if cw.decided || status < http.StatusOK {
+		cw.ResponseWriter.WriteHeader(status)
+		return
+	}
+	if cw.status == 0 {
+		cw.status = status
+	}
+}
+
+func (cw *compressWriter) Write(p []byte) (int, error) {
+	if cw.decided {
+		if cw.enc != nil {
+			return cw.enc.Write(p)
+		}
+		return cw.ResponseWriter.Write(p)
+	}
+	if cw.status == 0 {
+		cw.status = http.StatusOK
+	}
+	if !cw.compressible() {
+		if err := cw.decide(false); err != nil {
+			return 0, err
+		}
+		return cw.ResponseWriter.Write(p)
+	}
+
+	cw.buf = append(cw.buf, p...)
+	if len(cw.buf) >= minCompressSize {
+		if err := cw.decide(true); err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
+
+// compressible reports whether the response may be compressed, judging by
+// the headers the handler has set
+func (cw *compressWriter) compressible() bool {
+	h := cw.Header()
+	if cw.status != http.StatusOK || h.Get("Content-Encoding") != "" || h.Get("ETag") != "" {
+		return false
+	}
+	mediaType, _, err := mime.ParseMediaType(h.Get("Content-Type"))
+	return err == nil && compressedResponseTypes[mediaType]
+}
+
+// decide sends the status and headers, compressing the rest of the response
+// if compress is set, and writes the held back body
+func (cw *compressWriter) decide(compress bool) error {
+	cw.decided = true
+	h := cw.Header()
+	if cw.compressible() {
+		h.Add("Vary", "Accept-Encoding")
+	}
+	if compress {
+		h.Set("Content-Encoding", cw.encoding)
+		h.Del("Content-Length")
+		cw.enc = encoderPools[cw.encoding].Get().(responseEncoder)
+		cw.enc.Reset(cw.ResponseWriter)
+	}
+	if cw.status != 0 {
+		cw.ResponseWriter.WriteHeader(cw.status)
+	}
+
+	buf := cw.buf
+	cw.buf = nil
+	if len(buf) == 0 {
+		return nil
+	}
+	var err error
+	if cw.enc != nil {
+		_, err = cw.enc.Write(buf)
+	} else {
+		_, err = cw.ResponseWriter.Write(buf)
+	}
+	return err
+}
+
+// FlushError sends what the handler has written so far (http.ResponseController
+// calls it for streamed responses). A response flushed before it reached
+// minCompressSize is still compressed, since more is likely to follow.
+func (cw *compressWriter) FlushError() error {
+	if !cw.decided && (cw.status != 0 || len(cw.buf) > 0) {
+		if err := cw.decide(len(cw.buf) > 0 && cw.compressible()); err != nil {
+			return err
+		}
+	}
+	if cw.enc != nil {
+		if err := cw.enc.Flush(); err != nil {
+			return err
+		}
+	}
+	err := http.NewResponseController(cw.ResponseWriter).Flush()
+	if errors.Is(err, http.ErrNotSupported) {
+		return nil // The data is sent when the handler returns
+	}
+	return err
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer
+func (cw *compressWriter) Unwrap() http.ResponseWriter {
+	return cw.ResponseWriter
+}
+
+// close sends a response still held back and finishes the encoding
+func (cw *compressWriter) close() {
+	if !cw.decided && (cw.status != 0 || len(cw.buf) > 0) {
+		if err := cw.decide(false); err != nil {
+			log.Debug("error writing response", "error", err)
+		}
+	}
+	if cw.enc == nil {
+		return
+	}
+	if err := cw.enc.Close(); err != nil {
+		log.Debug("error finishing compressed response", "encoding", cw.encoding, "error", err)
+	}
+	cw.enc.Reset(nil)
+	encoderPools[cw.encoding].Put(cw.enc)
+	cw.enc = nil
+}
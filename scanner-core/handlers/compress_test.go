@@ -0,0 +1,118 @@
+package handlers
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+func TestNegotiateEncoding(t *testing.T) {
+	tests := map[string]string{
+		"gzip, deflate, br, zstd": "zstd",
+		"gzip, zstd;q=0":          "gzip",
+		"br":                      "",
+		"":                        "",
+	}
+	for accept, want := range tests {
+		if got := negotiateEncoding(accept); got != want {
+			t.Errorf("negotiateEncoding(%q) = %q, want %q", accept, got, want)
+		}
+	}
+}
+
+func TestCompressionMiddleware(t *testing.T) {
+	body := `{"images": [` + strings.Repeat(`{"reference": "nginx:1.21"},`, 200) + `{}]}`
+	handler := CompressionMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/images":
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = io.WriteString(w, body[:100])
+			_, _ = io.WriteString(w, body[100:])
+		case "/api/small":
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = io.WriteString(w, `{"ok": true}`)
+		case "/api/stream":
+			w.Header().Set("Content-Type", ndjsonContentType)
+			_, _ = io.WriteString(w, "{}\n")
+			_ = http.NewResponseController(w).Flush()
+			_, _ = io.WriteString(w, "{}\n")
+		case "/api/error":
+			http.Error(w, strings.Repeat("x", 2000), http.StatusInternalServerError)
+		case "/app.js":
+			w.Header().Set("Content-Type", "application/json")
+			w.Header().Set("ETag", `"abc"`)
+			_, _ = io.WriteString(w, body)
+		}
+	}))
+
+	serve := func(path, accept string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set("Accept-Encoding", accept)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+	decode := func(rec *httptest.ResponseRecorder) string {
+		t.Helper()
+		var r io.Reader = rec.Body
+		switch rec.Header().Get("Content-Encoding") {
+		case "zstd":
+			dec, err := zstd.NewReader(rec.Body)
+			if err != nil {
+				t.Fatalf("zstd.NewReader() error = %v", err)
+			}
+			defer dec.Close()
+			r = dec
+		case "gzip":
+			dec, err := gzip.NewReader(rec.Body)
+			if err != nil {
+				t.Fatalf("gzip.NewReader() error = %v", err)
+			}
+			r = dec
+		}
+		data, err := io.ReadAll(r)
+		if err != nil {
+			t.Fatalf("failed to decode %s response: %v", rec.Header().Get("Content-Encoding"), err)
+		}
+		return string(data)
+	}
+
+	for _, encoding := range []string{"zstd", "gzip"} {
+		rec := serve("/api/images", encoding)
+		if rec.Header().Get("Content-Encoding") != encoding || rec.Header().Get("Vary") != "Accept-Encoding" {
+			t.Errorf("%s: headers = %v", encoding, rec.Header())
+		}
+		if rec.Body.Len() >= len(body) {
+			t.Errorf("%s: compressed %d bytes to %d", encoding, len(body), rec.Body.Len())
+		}
+		if got := decode(rec); got != body {
+			t.Errorf("%s: decoded body differs from the response", encoding)
+		}
+	}
+
+	// Flushed streams are compressed as they go
+	if rec := serve("/api/stream", "gzip"); rec.Header().Get("Content-Encoding") != "gzip" || decode(rec) != "{}\n{}\n" {
+		t.Errorf("stream: encoding %q, body %q", rec.Header().Get("Content-Encoding"), decode(rec))
+	}
+
+	// Passed through unchanged
+	for _, tt := range []struct{ path, accept string }{
+		{"/api/images", ""},
+		{"/api/small", "zstd"},
+		{"/api/error", "zstd"},
+		{"/app.js", "gzip"},
+	} {
+		rec := serve(tt.path, tt.accept)
+		if encoding := rec.Header().Get("Content-Encoding"); encoding != "" {
+			t.Errorf("%s with Accept-Encoding %q: Content-Encoding = %q, want none", tt.path, tt.accept, encoding)
+		}
+		if tt.path == "/api/error" && rec.Code != http.StatusInternalServerError {
+			t.Errorf("%s: status = %d", tt.path, rec.Code)
+		}
+	}
+}
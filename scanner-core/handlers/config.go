@@ -4,9 +4,9 @@ import (
 	"encoding/json"
 	"net/http"
 
+	"github.com/bvboe/b2s-go/scanner-core/config"
 )
 
-
 // ConfigProvider is an interface for components to provide scanner configuration
 type ConfigProvider interface {
 	GetClusterName() string
@@ -15,27 +15,44 @@ type ConfigProvider interface {
 	GetScanNodes() bool
 }
 
+// EffectiveConfigProvider is implemented by providers that can report the
+// loaded configuration, so operators can check what the config file, Helm
+// values and environment actually produced
+type EffectiveConfigProvider interface {
+	GetEffectiveConfig() *config.Config
+}
+
 // ConfigResponse represents the scanner configuration returned by /api/config
 type ConfigResponse struct {
 	ClusterName    string `json:"clusterName"`
 	Version        string `json:"version"`
 	ScanContainers bool   `json:"scanContainers"`
 	ScanNodes      bool   `json:"scanNodes"`
+	// Effective settings with secrets redacted and the source of each value
+	ConfigFile string           `json:"configFile,omitempty"`
+	Settings   []config.Setting `json:"settings,omitempty"`
 }
 
 // ConfigHandler creates an HTTP handler for the /api/config endpoint
-// It returns scanner configuration including cluster name, version, and scan settings
+// It returns scanner configuration including cluster name, version, and scan settings,
+// plus the effective runtime configuration when the provider exposes it
 func ConfigHandler(provider ConfigProvider) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		config := ConfigResponse{
+		response := ConfigResponse{
 			ClusterName:    provider.GetClusterName(),
 			Version:        provider.GetVersion(),
 			ScanContainers: provider.GetScanContainers(),
 			ScanNodes:      provider.GetScanNodes(),
 		}
+		if effective, ok := provider.(EffectiveConfigProvider); ok {
+			if cfg := effective.GetEffectiveConfig(); cfg != nil {
+				response.ConfigFile = cfg.File()
+				response.Settings = cfg.Settings()
+			}
+		}
 
 		w.Header().Set("Content-Type", "application/json")
-		if err := json.NewEncoder(w).Encode(config); err != nil {
+		if err := json.NewEncoder(w).Encode(response); err != nil {
 			log.Error("error encoding config response", "error", err)
 			http.Error(w, "Internal server error", http.StatusInternalServerError)
 		}
@@ -50,6 +50,8 @@ func ContainerCVEsHandler(provider ImageQueryProvider) http.HandlerFunc {
 		severities := parseMultiSelect(params.Get("severity"))
 		fixStatuses := parseMultiSelect(params.Get("vulnStatuses"))
 		packageTypes := parseMultiSelect(params.Get("packageTypes"))
+		// Vulnerability search matches the identifier or any of its aliases (CVE ↔ RHSA/ALAS)
+		vulnerability := params.Get("vulnerability")
 
 		// Sorting
 		sortBy := params.Get("sortBy")
@@ -59,10 +61,10 @@ func ContainerCVEsHandler(provider ImageQueryProvider) http.HandlerFunc {
 		}
 
 		// Build query
-		query, countQuery := buildContainerCVEsQuery(namespaces, osNames, severities, fixStatuses, packageTypes, sortBy, sortOrder, pageSize, offset)
+		query, countQuery, args := buildContainerCVEsQuery(namespaces, osNames, severities, fixStatuses, packageTypes, vulnerability, sortBy, sortOrder, pageSize, offset)
 
 		// Execute count query for pagination
-		countResult, err := provider.ExecuteReadOnlyQuery(countQuery)
+		countResult, err := provider.ExecuteReadOnlyQueryArgs(countQuery, args...)
 		if err != nil {
 			log.Error("error executing container CVE count query", "error", err)
 			http.Error(w, "Internal server error", http.StatusInternalServerError)
@@ -77,7 +79,7 @@ func ContainerCVEsHandler(provider ImageQueryProvider) http.HandlerFunc {
 		}
 
 		// Execute main query
-		result, err := provider.ExecuteReadOnlyQuery(query)
+		result, err := provider.ExecuteReadOnlyQueryArgs(query, args...)
 		if err != nil {
 			log.Error("error executing container CVE query", "error", err)
 			http.Error(w, "Internal server error", http.StatusInternalServerError)
@@ -86,7 +88,7 @@ func ContainerCVEsHandler(provider ImageQueryProvider) http.HandlerFunc {
 
 		// Handle CSV export
 		if format == "csv" {
-			exportQueryResultAsCSV(w, result, "container_cves.csv")
+			exportQueryResultAsCSV(w, r, result, "container_cves.csv")
 			return
 		}
 
@@ -116,15 +118,17 @@ func ContainerCVEsHandler(provider ImageQueryProvider) http.HandlerFunc {
 // fix_status, package_type, severity); vulnerability_count reports the number of
 // distinct affected container instances. The column aliases match the per-image
 // vulnerabilities listing (image.html / buildImageVulnerabilitiesQuery) so the
-// frontend table can be shared.
-func buildContainerCVEsQuery(namespaces, osNames, severities, fixStatuses, packageTypes []string, sortBy, sortOrder string, limit, offset int) (string, string) {
+// frontend table can be shared. Both queries share the returned arguments.
+func buildContainerCVEsQuery(namespaces, osNames, severities, fixStatuses, packageTypes []string, vulnerability, sortBy, sortOrder string, limit, offset int) (string, string, queryArgs) {
 	// Build WHERE conditions
+	var args queryArgs
 	var conditions []string
-	conditions = appendCondition(conditions, buildINClause("c.namespace", namespaces))
-	conditions = appendCondition(conditions, buildINClause("i.os_name", osNames))
-	conditions = appendCondition(conditions, buildINClause("v.severity", severities))
-	conditions = appendCondition(conditions, buildINClause("v.fix_status", fixStatuses))
-	conditions = appendCondition(conditions, buildINClause("v.package_type", packageTypes))
+	conditions = appendCondition(conditions, args.in("c.namespace", namespaces))
+	conditions = appendCondition(conditions, args.in("i.os_name", osNames))
+	conditions = appendCondition(conditions, args.in("v.severity", severities))
+	conditions = appendCondition(conditions, args.in("v.fix_status", fixStatuses))
+	conditions = appendCondition(conditions, args.in("v.package_type", packageTypes))
+	conditions = appendCondition(conditions, args.vulnerabilityID("v.cve_id", vulnerability))
 	whereClause := buildWhereClause(conditions)
 
 	// Base query: every CVE row that is present in an image with >=1 running
@@ -153,7 +157,9 @@ GROUP BY v.cve_id, v.package_name, v.package_version, v.fixed_version, v.fix_sta
     v.severity as vulnerability_severity,
     MAX(v.risk) as vulnerability_risk,
     MAX(v.known_exploited) as vulnerability_known_exploits,
-    COUNT(DISTINCT c.id) as vulnerability_count`
+    COUNT(DISTINCT c.id) as vulnerability_count,
+    ` + vulnerabilityAliasesColumn("v.cve_id") + `,
+    ` + cveAnnotationColumn("v.cve_id")
 
 	mainQuery := selectClause + baseQuery + groupBy
 
@@ -201,7 +207,7 @@ GROUP BY v.cve_id, v.package_name, v.package_version, v.fixed_version, v.fix_sta
 		mainQuery += fmt.Sprintf("\nLIMIT %d OFFSET %d", limit, offset)
 	}
 
-	return mainQuery, countQuery
+	return mainQuery, countQuery, args
 }
 
 // ContainerCVEAffectedHandler creates an HTTP handler for the
@@ -221,15 +227,16 @@ func ContainerCVEAffectedHandler(provider ImageQueryProvider) http.HandlerFunc {
 
 		// Identify the specific finding. cve is required; the package fields
 		// narrow it to the exact grouped row the user clicked.
-		conditions := []string{fmt.Sprintf("v.cve_id = '%s'", escapeSQL(cve))}
+		var args queryArgs
+		conditions := []string{"v.cve_id = " + args.bind(cve)}
 		if name := params.Get("name"); name != "" {
-			conditions = append(conditions, fmt.Sprintf("v.package_name = '%s'", escapeSQL(name)))
+			conditions = append(conditions, "v.package_name = "+args.bind(name))
 		}
 		if version := params.Get("version"); version != "" {
-			conditions = append(conditions, fmt.Sprintf("v.package_version = '%s'", escapeSQL(version)))
+			conditions = append(conditions, "v.package_version = "+args.bind(version))
 		}
 		if ptype := params.Get("type"); ptype != "" {
-			conditions = append(conditions, fmt.Sprintf("v.package_type = '%s'", escapeSQL(ptype)))
+			conditions = append(conditions, "v.package_type = "+args.bind(ptype))
 		}
 
 		query := fmt.Sprintf(`
@@ -245,7 +252,7 @@ WHERE %s
 GROUP BY c.reference, i.digest, c.namespace
 ORDER BY container_count DESC, c.reference ASC, c.namespace ASC`, strings.Join(conditions, " AND "))
 
-		result, err := provider.ExecuteReadOnlyQuery(query)
+		result, err := provider.ExecuteReadOnlyQueryArgs(query, args...)
 		if err != nil {
 			log.Error("error executing container CVE affected query", "error", err)
 			http.Error(w, "Internal server error", http.StatusInternalServerError)
@@ -282,15 +289,16 @@ func ContainerCVEDetailVariantsHandler(provider ImageQueryProvider) http.Handler
 			return
 		}
 
-		conditions := []string{fmt.Sprintf("v.cve_id = '%s'", escapeSQL(cve))}
+		var args queryArgs
+		conditions := []string{"v.cve_id = " + args.bind(cve)}
 		if name := params.Get("name"); name != "" {
-			conditions = append(conditions, fmt.Sprintf("v.package_name = '%s'", escapeSQL(name)))
+			conditions = append(conditions, "v.package_name = "+args.bind(name))
 		}
 		if version := params.Get("version"); version != "" {
-			conditions = append(conditions, fmt.Sprintf("v.package_version = '%s'", escapeSQL(version)))
+			conditions = append(conditions, "v.package_version = "+args.bind(version))
 		}
 		if ptype := params.Get("type"); ptype != "" {
-			conditions = append(conditions, fmt.Sprintf("v.package_type = '%s'", escapeSQL(ptype)))
+			conditions = append(conditions, "v.package_type = "+args.bind(ptype))
 		}
 
 		// One detail row per affected image (UNIQUE(image_id, cve_id, package_*)
@@ -308,7 +316,7 @@ WHERE %s
   AND EXISTS (SELECT 1 FROM containers c WHERE c.image_id = i.id)
 ORDER BY i.digest`, strings.Join(conditions, " AND "))
 
-		result, err := provider.ExecuteReadOnlyQuery(query)
+		result, err := provider.ExecuteReadOnlyQueryArgs(query, args...)
 		if err != nil {
 			log.Error("error executing container CVE detail variants query", "error", err)
 			http.Error(w, "Internal server error", http.StatusInternalServerError)
@@ -4,15 +4,18 @@ import (
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"reflect"
 	"strings"
 	"testing"
+	"time"
 
+	"github.com/bvboe/b2s-go/scanner-core/containers"
 	"github.com/bvboe/b2s-go/scanner-core/database"
 )
 
 func TestBuildContainerCVEsQuery(t *testing.T) {
 	t.Run("groups and counts affected containers", func(t *testing.T) {
-		mainQuery, countQuery := buildContainerCVEsQuery(nil, nil, nil, nil, nil, "", "ASC", 100, 0)
+		mainQuery, countQuery, _ := buildContainerCVEsQuery(nil, nil, nil, nil, nil, "", "", "ASC", 100, 0)
 
 		for _, frag := range []string{
 			"FROM image_vulnerabilities v",
@@ -35,39 +38,43 @@ func TestBuildContainerCVEsQuery(t *testing.T) {
 	})
 
 	t.Run("applies all filters", func(t *testing.T) {
-		mainQuery, _ := buildContainerCVEsQuery(
+		mainQuery, _, args := buildContainerCVEsQuery(
 			[]string{"default"}, []string{"wolfi"}, []string{"Critical"},
-			[]string{"fixed"}, []string{"apk"}, "", "ASC", 100, 0)
+			[]string{"fixed"}, []string{"apk"}, "", "", "ASC", 100, 0)
 
 		for _, frag := range []string{
-			"c.namespace IN ('default')",
-			"i.os_name IN ('wolfi')",
-			"v.severity IN ('Critical')",
-			"v.fix_status IN ('fixed')",
-			"v.package_type IN ('apk')",
+			"c.namespace IN (?1)",
+			"i.os_name IN (?2)",
+			"v.severity IN (?3)",
+			"v.fix_status IN (?4)",
+			"v.package_type IN (?5)",
 		} {
 			if !strings.Contains(mainQuery, frag) {
 				t.Errorf("query missing filter %q\nquery: %s", frag, mainQuery)
 			}
 		}
+		want := queryArgs{"default", "wolfi", "Critical", "fixed", "apk"}
+		if !reflect.DeepEqual(args, want) {
+			t.Errorf("args = %v, want %v", args, want)
+		}
 	})
 
 	t.Run("export omits LIMIT", func(t *testing.T) {
-		mainQuery, _ := buildContainerCVEsQuery(nil, nil, nil, nil, nil, "", "ASC", -1, 0)
+		mainQuery, _, _ := buildContainerCVEsQuery(nil, nil, nil, nil, nil, "", "", "ASC", -1, 0)
 		if strings.Contains(mainQuery, "LIMIT") {
 			t.Errorf("export query should not contain LIMIT: %s", mainQuery)
 		}
 	})
 
 	t.Run("severity sort uses priority CASE", func(t *testing.T) {
-		mainQuery, _ := buildContainerCVEsQuery(nil, nil, nil, nil, nil, "vulnerability_severity", "DESC", 100, 0)
+		mainQuery, _, _ := buildContainerCVEsQuery(nil, nil, nil, nil, nil, "", "vulnerability_severity", "DESC", 100, 0)
 		if !strings.Contains(mainQuery, "CASE v.severity") {
 			t.Errorf("expected severity CASE ordering, got: %s", mainQuery)
 		}
 	})
 
 	t.Run("aggregate column sort uses alias", func(t *testing.T) {
-		mainQuery, _ := buildContainerCVEsQuery(nil, nil, nil, nil, nil, "vulnerability_count", "DESC", 100, 0)
+		mainQuery, _, _ := buildContainerCVEsQuery(nil, nil, nil, nil, nil, "", "vulnerability_count", "DESC", 100, 0)
 		if !strings.Contains(mainQuery, "vulnerability_count DESC") {
 			t.Errorf("expected order by vulnerability_count alias, got: %s", mainQuery)
 		}
@@ -152,16 +159,19 @@ func TestContainerCVEAffectedHandler(t *testing.T) {
 			t.Fatalf("expected 200, got %d", rec.Code)
 		}
 		for _, frag := range []string{
-			"v.cve_id = 'CVE-2024-0001'",
-			"v.package_name = 'openssl'",
-			"v.package_version = '1.1'",
-			"v.package_type = 'apk'",
+			"v.cve_id = ?1",
+			"v.package_name = ?2",
+			"v.package_version = ?3",
+			"v.package_type = ?4",
 			"COUNT(DISTINCT c.id) as container_count",
 		} {
 			if !strings.Contains(captured, frag) {
 				t.Errorf("affected query missing %q\nquery: %s", frag, captured)
 			}
 		}
+		if want := []interface{}{"CVE-2024-0001", "openssl", "1.1", "apk"}; !reflect.DeepEqual(provider.args, want) {
+			t.Errorf("args = %v, want %v", provider.args, want)
+		}
 	})
 }
 
@@ -175,13 +185,13 @@ func TestContainerCVEQueriesExecuteAgainstRealSchema(t *testing.T) {
 	defer cleanup()
 
 	// Fully filtered + aggregate-column sort.
-	mainQuery, countQuery := buildContainerCVEsQuery(
+	mainQuery, countQuery, args := buildContainerCVEsQuery(
 		[]string{"default"}, []string{"wolfi"}, []string{"Critical"},
-		[]string{"fixed"}, []string{"apk"}, "vulnerability_count", "DESC", 100, 0)
-	if _, err := db.ExecuteReadOnlyQuery(countQuery); err != nil {
+		[]string{"fixed"}, []string{"apk"}, "RHSA-2024:1234", "vulnerability_count", "DESC", 100, 0)
+	if _, err := db.ExecuteReadOnlyQueryArgs(countQuery, args...); err != nil {
 		t.Fatalf("count query failed against real schema: %v\n%s", err, countQuery)
 	}
-	if _, err := db.ExecuteReadOnlyQuery(mainQuery); err != nil {
+	if _, err := db.ExecuteReadOnlyQueryArgs(mainQuery, args...); err != nil {
 		t.Fatalf("main query failed against real schema: %v\n%s", err, mainQuery)
 	}
 
@@ -191,8 +201,8 @@ func TestContainerCVEQueriesExecuteAgainstRealSchema(t *testing.T) {
 		"vulnerability_fix_versions", "vulnerability_fix_state", "artifact_type",
 		"vulnerability_risk", "vulnerability_known_exploits", "vulnerability_count", "",
 	} {
-		q, _ := buildContainerCVEsQuery(nil, nil, nil, nil, nil, col, "ASC", 50, 0)
-		if _, err := db.ExecuteReadOnlyQuery(q); err != nil {
+		q, _, args := buildContainerCVEsQuery(nil, nil, nil, nil, nil, "", col, "ASC", 50, 0)
+		if _, err := db.ExecuteReadOnlyQueryArgs(q, args...); err != nil {
 			t.Errorf("query with sortBy=%q failed against real schema: %v\n%s", col, err, q)
 		}
 	}
@@ -261,13 +271,16 @@ func TestContainerCVEDetailVariantsHandler(t *testing.T) {
 		for _, frag := range []string{
 			"JOIN image_vulnerability_details vd ON vd.vulnerability_id = v.id",
 			"EXISTS (SELECT 1 FROM containers c WHERE c.image_id = i.id)",
-			"v.cve_id = 'CVE-1'",
-			"v.package_name = 'openssl'",
+			"v.cve_id = ?1",
+			"v.package_name = ?2",
 		} {
 			if !strings.Contains(captured, frag) {
 				t.Errorf("query missing %q\nquery: %s", frag, captured)
 			}
 		}
+		if len(provider.args) != 4 || provider.args[0] != "CVE-1" || provider.args[1] != "openssl" {
+			t.Errorf("unexpected args: %v", provider.args)
+		}
 
 		var resp struct {
 			VariantCount int `json:"variant_count"`
@@ -296,3 +309,50 @@ func TestContainerCVEDetailVariantsHandler(t *testing.T) {
 		}
 	})
 }
+
+// TestVulnerabilitySearchMatchesAliases verifies that an advisory finding
+// (RHSA) is found when searching by the CVE it fixes and vice versa, and that
+// the listing exposes the alias alongside the reported identifier.
+func TestVulnerabilitySearchMatchesAliases(t *testing.T) {
+	db, cleanup := createTestDBForHandlers(t)
+	defer cleanup()
+
+	digest := "sha256:rhel"
+	if _, _, err := db.GetOrCreateImage(containers.ImageID{Reference: "ubi9:latest", Digest: digest}); err != nil {
+		t.Fatalf("GetOrCreateImage() error = %v", err)
+	}
+	vulnJSON := []byte(`{"matches": [{
+		"vulnerability": {"id": "RHSA-2024:1234", "severity": "High", "fix": {"state": "fixed", "versions": ["1.1.1k-12"]}},
+		"relatedVulnerabilities": [{"id": "CVE-2024-0001"}],
+		"artifact": {"name": "openssl", "version": "1.1.1k-9", "type": "rpm"}
+	}]}`)
+	if err := db.StoreVulnerabilities(digest, vulnJSON, time.Now()); err != nil {
+		t.Fatalf("StoreVulnerabilities() error = %v", err)
+	}
+
+	for _, search := range []string{"CVE-2024-0001", "rhsa-2024:1234"} {
+		query, _, args := buildImageVulnerabilitiesQuery(digest, nil, nil, nil, search, "", "ASC", 100, 0)
+		result, err := db.ExecuteReadOnlyQueryArgs(query, args...)
+		if err != nil {
+			t.Fatalf("query failed: %v\n%s", err, query)
+		}
+		if len(result.Rows) != 1 {
+			t.Fatalf("search %q returned %d rows, want 1", search, len(result.Rows))
+		}
+		row := result.Rows[0]
+		if row["vulnerability_id"] != "RHSA-2024:1234" || row["vulnerability_aliases"] != "CVE-2024-0001" {
+			t.Errorf("search %q returned id=%v aliases=%v", search, row["vulnerability_id"], row["vulnerability_aliases"])
+		}
+	}
+
+	for _, search := range []string{"CVE-2024-9999", "x' OR '1'='1"} {
+		query, _, args := buildImageVulnerabilitiesQuery(digest, nil, nil, nil, search, "", "ASC", 100, 0)
+		result, err := db.ExecuteReadOnlyQueryArgs(query, args...)
+		if err != nil {
+			t.Fatalf("query failed: %v\n%s", err, query)
+		}
+		if len(result.Rows) != 0 {
+			t.Errorf("search %q returned %d rows, want 0", search, len(result.Rows))
+		}
+	}
+}
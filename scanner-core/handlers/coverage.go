@@ -0,0 +1,55 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/bvboe/b2s-go/scanner-core/database"
+	"github.com/bvboe/b2s-go/scanner-core/routes"
+)
+
+// ScanCoverageHandler creates an HTTP handler for /api/summary/coverage endpoint
+// Reports which fraction of the image digests observed in the cluster has a
+// completed scan. Observed digests are those of running containers plus those
+// seen in completed Jobs within the lookback window (?lookback=72h overrides the
+// configured default).
+func ScanCoverageHandler(db *database.DB, lookback time.Duration) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		window := lookback
+		if v := r.URL.Query().Get("lookback"); v != "" {
+			d, err := time.ParseDuration(v)
+			if err != nil || d < 0 {
+				http.Error(w, "Invalid lookback duration", http.StatusBadRequest)
+				return
+			}
+			window = d
+		}
+
+		coverage, err := db.GetScanCoverage(time.Now().Add(-window))
+		if err != nil {
+			log.Error("error computing scan coverage", "error", err)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+
+		response := struct {
+			*database.ScanCoverage
+			Lookback string `json:"lookback"`
+		}{coverage, window.String()}
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(response); err != nil {
+			log.Error("error encoding scan coverage response", "error", err)
+		}
+	}
+}
+
+// RegisterCoverageHandlers registers the scan coverage endpoint
+func RegisterCoverageHandlers(reg *routes.Registry, db *database.DB, lookback time.Duration) {
+	reg.Handle(routes.Route{Pattern: "/api/summary/coverage", Methods: routes.GET, Handler: ScanCoverageHandler(db, lookback)})
+}
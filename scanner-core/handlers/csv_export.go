@@ -0,0 +1,203 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+	_ "time/tzdata" // tz= works in container images without a zoneinfo database
+	"unicode/utf8"
+)
+
+// utf8BOM is the UTF-8 byte order mark, which makes Excel read a CSV file as
+// UTF-8 instead of the system code page
+const utf8BOM = "\ufeff"
+
+// csvDelimiters are the named values of the delimiter query parameter
+var csvDelimiters = map[string]rune{
+	"comma":     ',',
+	"semicolon": ';',
+	"tab":       '\t',
+	"pipe":      '|',
+}
+
+// CSVOptions controls how CSV exports are written. The defaults (comma
+// delimiter, all columns, decimal point, no byte order mark, UTC timestamps)
+// match the original export format.
+type CSVOptions struct {
+	Delimiter    rune           // field separator
+	Columns      []string       // header names to export, in this order (empty = all)
+	DecimalComma bool           // write decimal numbers with a comma, e.g. 7,5
+	BOM          bool           // prefix the file with a UTF-8 byte order mark
+	Location     *time.Location // time zone of exported timestamps (nil = UTC as stored)
+}
+
+// ParseCSVOptions reads the CSV export options shared by all format=csv
+// endpoints:
+//
+//	delimiter=comma|semicolon|tab|pipe (or the character itself)
+//	columns=name,severity             only these columns, matched case-insensitively
+//	decimal=comma|point               decimal separator of numbers
+//	bom=true                          prefix a UTF-8 byte order mark
+//	tz=Europe/Stockholm               show timestamps in this IANA time zone
+//
+// Semicolons with decimal=comma and bom=true open correctly in Excel with
+// European regional settings.
+func ParseCSVOptions(r *http.Request) (CSVOptions, error) {
+	params := r.URL.Query()
+	opts := CSVOptions{Delimiter: ','}
+
+	if d := params.Get("delimiter"); d != "" {
+		if named, ok := csvDelimiters[strings.ToLower(d)]; ok {
+			opts.Delimiter = named
+		} else if utf8.RuneCountInString(d) == 1 {
+			opts.Delimiter, _ = utf8.DecodeRuneInString(d)
+		} else {
+			return opts, fmt.Errorf("invalid delimiter %q", d)
+		}
+		if opts.Delimiter == '"' || opts.Delimiter == '\r' || opts.Delimiter == '\n' || opts.Delimiter == utf8.RuneError {
+			return opts, fmt.Errorf("invalid delimiter %q", d)
+		}
+	}
+
+	opts.Columns = parseCommaSeparated(params.Get("columns"))
+
+	switch strings.ToLower(params.Get("decimal")) {
+	case "", "point":
+	case "comma":
+		opts.DecimalComma = true
+	default:
+		return opts, fmt.Errorf("invalid decimal separator %q", params.Get("decimal"))
+	}
+
+	opts.BOM = params.Get("bom") == "true"
+
+	loc, err := parseTimeZone(r)
+	if err != nil {
+		return opts, err
+	}
+	opts.Location = loc
+	return opts, nil
+}
+
+// parseTimeZone reads the ?tz= display time zone (an IANA name such as
+// Europe/Stockholm, or UTC). Timestamps are stored in UTC, which is also
+// returned when tz is not set.
+func parseTimeZone(r *http.Request) (*time.Location, error) {
+	tz := r.URL.Query().Get("tz")
+	if tz == "" {
+		return time.UTC, nil
+	}
+	loc, err := time.LoadLocation(tz)
+	if err != nil {
+		return nil, fmt.Errorf("invalid time zone %q", tz)
+	}
+	return loc, nil
+}
+
+// csvExport writes a CSV response with the options of the request. Callers
+// set the Content-Type and Content-Disposition headers before the first row.
+type csvExport struct {
+	w       http.ResponseWriter
+	writer  *csv.Writer
+	opts    CSVOptions
+	columns []int // header indexes written, in order; nil writes all
+}
+
+// newCSVExport parses the CSV options of r. Invalid options are answered with
+// 400 Bad Request and nil is returned.
+func newCSVExport(w http.ResponseWriter, r *http.Request) *csvExport {
+	opts, err := ParseCSVOptions(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return nil
+	}
+	writer := csv.NewWriter(w)
+	writer.Comma = opts.Delimiter
+	return &csvExport{w: w, writer: writer, opts: opts}
+}
+
+// WriteHeader writes the byte order mark (if requested) and the header row,
+// keeping the selected columns. Requested columns missing from header are
+// ignored; if none match, all columns are written.
+func (e *csvExport) WriteHeader(header []string) error {
+	e.columns = nil
+	for _, want := range e.opts.Columns {
+		for i, name := range header {
+			if strings.EqualFold(name, want) {
+				e.columns = append(e.columns, i)
+				break
+			}
+		}
+	}
+
+	if e.opts.BOM {
+		if _, err := e.w.Write([]byte(utf8BOM)); err != nil {
+			return err
+		}
+	}
+	return e.WriteRow(header)
+}
+
+// WriteRow writes a row, keeping the columns selected by WriteHeader
+func (e *csvExport) WriteRow(row []string) error {
+	if e.columns == nil {
+		return e.writer.Write(row)
+	}
+	selected := make([]string, len(e.columns))
+	for i, col := range e.columns {
+		if col < len(row) {
+			selected[i] = row[col]
+		}
+	}
+	return e.writer.Write(selected)
+}
+
+// Flush writes buffered rows to the response
+func (e *csvExport) Flush() error {
+	e.writer.Flush()
+	return e.writer.Error()
+}
+
+// Value formats a query result value as it has always been exported, using
+// the requested decimal separator for floating point numbers and time zone
+// for timestamps
+func (e *csvExport) Value(v interface{}) string {
+	switch t := v.(type) {
+	case float32, float64:
+		return e.localize(fmt.Sprintf("%v", v))
+	case time.Time:
+		return e.timestamp(t)
+	case string:
+		// Timestamps stored as text are RFC3339 in UTC
+		if e.opts.Location != nil && e.opts.Location != time.UTC {
+			if ts, err := time.Parse(time.RFC3339, t); err == nil {
+				return e.timestamp(ts)
+			}
+		}
+	}
+	return fmt.Sprintf("%v", v)
+}
+
+// timestamp formats t as RFC3339 in the requested time zone
+func (e *csvExport) timestamp(t time.Time) string {
+	loc := e.opts.Location
+	if loc == nil {
+		loc = time.UTC
+	}
+	return t.In(loc).Format(time.RFC3339)
+}
+
+// Decimal formats a number with prec decimals and the requested decimal separator
+func (e *csvExport) Decimal(v interface{}, prec int) string {
+	return e.localize(fmt.Sprintf("%.*f", prec, v))
+}
+
+// localize replaces the decimal point of a formatted number
+func (e *csvExport) localize(number string) string {
+	if !e.opts.DecimalComma {
+		return number
+	}
+	return strings.Replace(number, ".", ",", 1)
+}
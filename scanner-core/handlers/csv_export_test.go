@@ -0,0 +1,114 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/bvboe/b2s-go/scanner-core/database"
+)
+
+func TestParseCSVOptions(t *testing.T) {
+	tests := []struct {
+		query   string
+		want    CSVOptions
+		wantErr bool
+	}{
+		{query: "", want: CSVOptions{Delimiter: ','}},
+		{query: "delimiter=semicolon&decimal=comma&bom=true", want: CSVOptions{Delimiter: ';', DecimalComma: true, BOM: true}},
+		{query: "delimiter=tab&decimal=point", want: CSVOptions{Delimiter: '\t'}},
+		{query: "delimiter=%3B", want: CSVOptions{Delimiter: ';'}},
+		{query: "delimiter=%22", wantErr: true},
+		{query: "delimiter=xx", wantErr: true},
+		{query: "decimal=dot", wantErr: true},
+		{query: "tz=Mars/Olympus_Mons", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := ParseCSVOptions(httptest.NewRequest(http.MethodGet, "/api/images?"+tt.query, nil))
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseCSVOptions(%q) error = %v, wantErr %v", tt.query, err, tt.wantErr)
+			continue
+		}
+		if err == nil && (got.Delimiter != tt.want.Delimiter || got.DecimalComma != tt.want.DecimalComma || got.BOM != tt.want.BOM) {
+			t.Errorf("ParseCSVOptions(%q) = %+v, want %+v", tt.query, got, tt.want)
+		}
+	}
+
+	got, _ := ParseCSVOptions(httptest.NewRequest(http.MethodGet, "/api/images?columns=risk,+name", nil))
+	if len(got.Columns) != 2 || got.Columns[0] != "risk" || got.Columns[1] != "name" {
+		t.Errorf("unexpected columns %v", got.Columns)
+	}
+
+	got, _ = ParseCSVOptions(httptest.NewRequest(http.MethodGet, "/api/images?tz=Europe/Stockholm", nil))
+	if got.Location == nil || got.Location.String() != "Europe/Stockholm" {
+		t.Errorf("unexpected location %v", got.Location)
+	}
+}
+
+func TestCSVTimestamps(t *testing.T) {
+	scanned := time.Date(2026, 7, 1, 10, 30, 0, 0, time.UTC)
+	result := &database.QueryResult{
+		Columns: []string{"name", "created_at", "scanned_at"},
+		Rows: []map[string]interface{}{
+			{"name": "openssl", "created_at": scanned, "scanned_at": "2026-01-15T08:00:00Z"},
+		},
+	}
+
+	tests := []struct {
+		name  string
+		query string
+		want  string
+	}{
+		{"utc", "", "name,created_at,scanned_at\nopenssl,2026-07-01T10:30:00Z,2026-01-15T08:00:00Z\n"},
+		{"time zone", "tz=Europe/Stockholm", "name,created_at,scanned_at\nopenssl,2026-07-01T12:30:00+02:00,2026-01-15T09:00:00+01:00\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			exportQueryResultAsCSV(rec, httptest.NewRequest(http.MethodGet, "/api/images?format=csv&"+tt.query, nil), result, "images.csv")
+			if got := rec.Body.String(); got != tt.want {
+				t.Errorf("CSV = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExportQueryResultAsCSVOptions(t *testing.T) {
+	result := &database.QueryResult{
+		Columns: []string{"name", "version", "risk", "count"},
+		Rows: []map[string]interface{}{
+			{"name": "openssl", "version": "3.0.2", "risk": 7.5, "count": int64(2)},
+		},
+	}
+
+	tests := []struct {
+		name  string
+		query string
+		want  string
+	}{
+		{"defaults", "", "name,version,risk,count\nopenssl,3.0.2,7.5,2\n"},
+		{"european excel", "delimiter=semicolon&decimal=comma&bom=true", "\ufeffname;version;risk;count\nopenssl;3.0.2;7,5;2\n"},
+		{"decimal comma quoted", "decimal=comma", "name,version,risk,count\nopenssl,3.0.2,\"7,5\",2\n"},
+		{"selected columns", "columns=RISK,name,unknown", "risk,name\n7.5,openssl\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, "/api/images?format=csv&"+tt.query, nil)
+			exportQueryResultAsCSV(rec, req, result, "images.csv")
+			if rec.Code != http.StatusOK {
+				t.Fatalf("expected status 200, got %d", rec.Code)
+			}
+			if got := rec.Body.String(); got != tt.want {
+				t.Errorf("CSV = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	rec := httptest.NewRecorder()
+	exportQueryResultAsCSV(rec, httptest.NewRequest(http.MethodGet, "/api/images?format=csv&delimiter=xx", nil), result, "images.csv")
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("invalid delimiter: expected status 400, got %d", rec.Code)
+	}
+}
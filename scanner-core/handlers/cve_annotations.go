@@ -0,0 +1,147 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/bvboe/b2s-go/scanner-core/database"
+	"github.com/bvboe/b2s-go/scanner-core/routes"
+)
+
+// CVEAnnotationStore stores the analyst notes on vulnerabilities
+type CVEAnnotationStore interface {
+	GetCVEAnnotations() ([]database.CVEAnnotation, error)
+	SetCVEAnnotation(cveID, note, author string) (*database.CVEAnnotation, error)
+	DeleteCVEAnnotation(cveID string) (bool, error)
+}
+
+// CVEAnnotationRequest is the body of POST /api/cve-annotations/save and
+// POST /api/cve-annotations/delete (which only uses CVEID)
+type CVEAnnotationRequest struct {
+	CVEID  string `json:"cve_id"`
+	Note   string `json:"note,omitempty"`
+	Author string `json:"author,omitempty"`
+}
+
+// maxCVEAnnotationRequestSize bounds the annotation request body
+const maxCVEAnnotationRequestSize = 64 << 10
+
+// RegisterCVEAnnotationHandlers registers the vulnerability annotation endpoints
+func RegisterCVEAnnotationHandlers(reg *routes.Registry, store CVEAnnotationStore) {
+	reg.Handle(
+		routes.Route{Pattern: "/api/cve-annotations", Methods: routes.GET, Handler: CVEAnnotationListHandler(store)},
+		routes.Route{Pattern: "/api/cve-annotations/save", Methods: routes.POST, Handler: CVEAnnotationSaveHandler(store), Role: routes.RoleAdmin},
+		routes.Route{Pattern: "/api/cve-annotations/delete", Methods: routes.POST, Handler: CVEAnnotationDeleteHandler(store), Role: routes.RoleAdmin},
+	)
+}
+
+// CVEAnnotationListHandler creates an HTTP handler for GET /api/cve-annotations.
+// Returns all analyst notes on vulnerabilities. The notes are also shown as
+// cve_annotation wherever the vulnerability is listed.
+func CVEAnnotationListHandler(store CVEAnnotationStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		annotations, err := store.GetCVEAnnotations()
+		if err != nil {
+			log.Error("error querying CVE annotations", "error", err)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(map[string]interface{}{
+			"annotations": annotations,
+			"count":       len(annotations),
+		}); err != nil {
+			log.Error("error encoding CVE annotations", "error", err)
+		}
+	}
+}
+
+// CVEAnnotationSaveHandler creates an HTTP handler for POST
+// /api/cve-annotations/save. Attaches a note to a vulnerability once for the
+// whole cluster, replacing any previous note.
+//
+// Request: {"cve_id": "CVE-2024-1234", "note": "mitigated by WAF rule 123", "author": "alice"}
+// Response: the saved annotation
+func CVEAnnotationSaveHandler(store CVEAnnotationStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		var req CVEAnnotationRequest
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxCVEAnnotationRequestSize)).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		if strings.TrimSpace(req.CVEID) == "" || strings.TrimSpace(req.Note) == "" {
+			http.Error(w, "cve_id and note are required", http.StatusBadRequest)
+			return
+		}
+		if len(strings.TrimSpace(req.Note)) > database.MaxCVEAnnotationLength {
+			http.Error(w, "note is too long", http.StatusBadRequest)
+			return
+		}
+
+		annotation, err := store.SetCVEAnnotation(req.CVEID, req.Note, req.Author)
+		if err != nil {
+			log.Error("error saving CVE annotation", "cve", req.CVEID, "error", err)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		log.Info("saved CVE annotation", "cve", annotation.CVEID, "author", annotation.Author)
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(annotation); err != nil {
+			log.Error("error encoding CVE annotation", "error", err)
+		}
+	}
+}
+
+// CVEAnnotationDeleteHandler creates an HTTP handler for POST
+// /api/cve-annotations/delete. Removes the note on a vulnerability.
+//
+// Request: {"cve_id": "CVE-2024-1234"}
+// Response: {"deleted": true}
+func CVEAnnotationDeleteHandler(store CVEAnnotationStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		var req CVEAnnotationRequest
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxCVEAnnotationRequestSize)).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		if strings.TrimSpace(req.CVEID) == "" {
+			http.Error(w, "cve_id is required", http.StatusBadRequest)
+			return
+		}
+
+		deleted, err := store.DeleteCVEAnnotation(req.CVEID)
+		if err != nil {
+			log.Error("error deleting CVE annotation", "cve", req.CVEID, "error", err)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		if !deleted {
+			http.Error(w, "Annotation not found", http.StatusNotFound)
+			return
+		}
+		log.Info("deleted CVE annotation", "cve", req.CVEID)
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(map[string]interface{}{"deleted": true}); err != nil {
+			log.Error("error encoding CVE annotation response", "error", err)
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/bvboe/b2s-go/scanner-core/routes"
+)
+
+func TestCVEAnnotationHandlers(t *testing.T) {
+	db := createVulnerabilityExplorerTestDB(t)
+	mux := routes.NewRegistry()
+	RegisterDatabaseHandlers(mux, db, nil)
+	RegisterCVEAnnotationHandlers(mux, db)
+
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(method, path, strings.NewReader(body)))
+		return rec
+	}
+
+	for _, body := range []string{`{"cve_id": "CVE-2024-3094"}`, `{"note": "orphan"}`, `not json`} {
+		if rec := do(http.MethodPost, "/api/cve-annotations/save", body); rec.Code != http.StatusBadRequest {
+			t.Errorf("save %s: status = %d, want 400", body, rec.Code)
+		}
+	}
+
+	// Notes on an alias show up on the vulnerability too
+	rec := do(http.MethodPost, "/api/cve-annotations/save", `{"cve_id": "GHSA-XZ", "note": "mitigated by WAF rule 123", "author": "secops"}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("save: status = %d: %s", rec.Code, rec.Body.String())
+	}
+	if rec := do(http.MethodPost, "/api/cve-annotations/save", `{"cve_id": "CVE-2024-3094", "note": "patched in base image 2.1"}`); rec.Code != http.StatusOK {
+		t.Fatalf("save: status = %d: %s", rec.Code, rec.Body.String())
+	}
+
+	var listed struct {
+		Annotations []map[string]interface{} `json:"annotations"`
+		Count       int                      `json:"count"`
+	}
+	if err := json.NewDecoder(do(http.MethodGet, "/api/cve-annotations", "").Body).Decode(&listed); err != nil {
+		t.Fatalf("failed to decode list: %v", err)
+	}
+	if listed.Count != 2 || listed.Annotations[1]["cve_id"] != "GHSA-XZ" || listed.Annotations[1]["author"] != "secops" {
+		t.Errorf("unexpected annotations: %+v", listed)
+	}
+
+	// Listed next to the vulnerability wherever it appears
+	var list struct {
+		Vulnerabilities []map[string]interface{} `json:"vulnerabilities"`
+	}
+	if err := json.NewDecoder(do(http.MethodGet, "/api/vulnerabilities", "").Body).Decode(&list); err != nil {
+		t.Fatalf("failed to decode vulnerabilities: %v", err)
+	}
+	if list.Vulnerabilities[0]["cve_annotation"] != "patched in base image 2.1" || list.Vulnerabilities[1]["cve_annotation"] != nil {
+		t.Errorf("unexpected cve_annotation columns: %v / %v", list.Vulnerabilities[0]["cve_annotation"], list.Vulnerabilities[1]["cve_annotation"])
+	}
+
+	var detail struct {
+		Annotations []map[string]interface{} `json:"annotations"`
+	}
+	if err := json.NewDecoder(do(http.MethodGet, "/api/vulnerabilities/CVE-2024-3094", "").Body).Decode(&detail); err != nil {
+		t.Fatalf("failed to decode detail: %v", err)
+	}
+	if len(detail.Annotations) != 2 || detail.Annotations[1]["note"] != "mitigated by WAF rule 123" {
+		t.Errorf("detail annotations = %v, want the notes on the CVE and its alias", detail.Annotations)
+	}
+
+	if rec := do(http.MethodPost, "/api/cve-annotations/delete", `{"cve_id": "ghsa-xz"}`); rec.Code != http.StatusOK {
+		t.Errorf("delete: status = %d: %s", rec.Code, rec.Body.String())
+	}
+	if rec := do(http.MethodPost, "/api/cve-annotations/delete", `{"cve_id": "GHSA-XZ"}`); rec.Code != http.StatusNotFound {
+		t.Errorf("second delete: status = %d, want 404", rec.Code)
+	}
+	if rec := do(http.MethodGet, "/api/cve-annotations/delete", ""); rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("GET delete: status = %d, want 405", rec.Code)
+	}
+}
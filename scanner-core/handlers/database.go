@@ -4,7 +4,12 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"strings"
 
+	"github.com/bvboe/b2s-go/scanner-core/columns"
+	"github.com/bvboe/b2s-go/scanner-core/policy"
+	"github.com/bvboe/b2s-go/scanner-core/routes"
+	"github.com/bvboe/b2s-go/scanner-core/sbomformat"
 )
 
 
@@ -12,7 +17,7 @@ import (
 type DatabaseProvider interface {
 	GetAllContainers() (interface{}, error)
 	GetAllImages() (interface{}, error)
-	GetAllImageDetails() (interface{}, error)
+	GetAllImageDetails(includeDeleted bool) (interface{}, error)
 	GetImageDetails(digest string) (interface{}, error)
 	GetPackagesByImage(digest string) (interface{}, error)
 	GetVulnerabilitiesByImage(digest string) (interface{}, error)
@@ -57,8 +62,10 @@ func DatabaseImagesHandler(provider DatabaseProvider) http.HandlerFunc {
 }
 
 // SBOMDownloadHandler creates an HTTP handler for /api/sbom/{digest} endpoint
-// Downloads SBOM as a JSON file
-func SBOMDownloadHandler(provider DatabaseProvider) http.HandlerFunc {
+// Downloads SBOM as a JSON file, converted to the format given by the optional
+// ?format=syft-json|cyclonedx-json|spdx-json parameter (default: syft-json),
+// with its file paths redacted according to redaction (sbomformat.Redact*)
+func SBOMDownloadHandler(provider DatabaseProvider, redaction string) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		// Extract digest from URL path
 		// Expected format: /api/sbom/sha256:abc123...
@@ -74,6 +81,12 @@ func SBOMDownloadHandler(provider DatabaseProvider) http.HandlerFunc {
 			return
 		}
 
+		// Reject unknown formats before fetching the SBOM
+		if _, err := sbomformat.Normalize(r.URL.Query().Get("format")); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
 		// Get SBOM from database
 		sbomData, err := provider.GetSBOM(digest)
 		if err != nil {
@@ -82,29 +95,58 @@ func SBOMDownloadHandler(provider DatabaseProvider) http.HandlerFunc {
 			return
 		}
 
-		// Create a safe filename from digest
-		filename := digest
-		if len(filename) > 20 {
-			// Use shortened version for filename: sha256_abc123.json
-			filename = filename[:7] + "_" + filename[7:19]
-		}
-		filename += ".json"
+		WriteSBOMDownload(w, r, digest, sbomData, redaction)
+	}
+}
 
-		// Set headers for file download
-		w.Header().Set("Content-Type", "application/json")
-		w.Header().Set("Content-Disposition", "attachment; filename=\"sbom_"+filename+"\"")
-		w.Header().Set("Content-Length", fmt.Sprintf("%d", len(sbomData)))
+// WriteSBOMDownload writes a stored Syft JSON SBOM as a file download in the
+// format requested by the ?format= parameter. SBOM handler overrides that
+// retrieve SBOMs elsewhere use it to support the same formats and redaction.
+func WriteSBOMDownload(w http.ResponseWriter, r *http.Request, digest string, syftJSON []byte, redaction string) {
+	format, err := sbomformat.Normalize(r.URL.Query().Get("format"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 
-		// Write SBOM data
-		if _, err := w.Write(sbomData); err != nil {
-			log.Error("error writing SBOM response", "error", err)
-		}
+	// Paths are redacted before conversion so every format inherits it
+	syftJSON, err = sbomformat.RedactPaths(syftJSON, redaction)
+	if err != nil {
+		log.Error("error redacting SBOM", "digest", digest, "error", err)
+		http.Error(w, "Failed to redact SBOM", http.StatusInternalServerError)
+		return
+	}
+
+	sbomData, err := sbomformat.Convert(syftJSON, format)
+	if err != nil {
+		log.Error("error converting SBOM", "digest", digest, "format", format, "error", err)
+		http.Error(w, "Failed to convert SBOM", http.StatusInternalServerError)
+		return
+	}
+
+	// Create a safe filename from digest
+	filename := digest
+	if len(filename) > 20 {
+		// Use shortened version for filename: sha256_abc123.json
+		filename = filename[:7] + "_" + filename[7:19]
+	}
+	filename += sbomformat.FileExtension(format)
+
+	// Set headers for file download
+	w.Header().Set("Content-Type", sbomformat.ContentType(format))
+	w.Header().Set("Content-Disposition", "attachment; filename=\"sbom_"+filename+"\"")
+	w.Header().Set("Content-Length", fmt.Sprintf("%d", len(sbomData)))
+
+	// Write SBOM data
+	if _, err := w.Write(sbomData); err != nil {
+		log.Error("error writing SBOM response", "error", err)
 	}
 }
 
 // VulnerabilitiesDownloadHandler creates an HTTP handler for /api/vulnerabilities/{digest} endpoint
-// Downloads vulnerability report as a JSON file
-func VulnerabilitiesDownloadHandler(provider DatabaseProvider) http.HandlerFunc {
+// Downloads vulnerability report as a JSON file, with the file paths of the
+// matched packages redacted according to redaction (sbomformat.Redact*)
+func VulnerabilitiesDownloadHandler(provider DatabaseProvider, redaction string) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		// Extract digest from URL path
 		// Expected format: /api/vulnerabilities/sha256:abc123...
@@ -127,6 +169,12 @@ func VulnerabilitiesDownloadHandler(provider DatabaseProvider) http.HandlerFunc
 			http.Error(w, "Vulnerabilities not found", http.StatusNotFound)
 			return
 		}
+		vulnData, err = sbomformat.RedactPaths(vulnData, redaction)
+		if err != nil {
+			log.Error("error redacting vulnerabilities", "digest", digest, "error", err)
+			http.Error(w, "Failed to redact vulnerabilities", http.StatusInternalServerError)
+			return
+		}
 
 		// Create a safe filename from digest
 		filename := digest
@@ -149,10 +197,11 @@ func VulnerabilitiesDownloadHandler(provider DatabaseProvider) http.HandlerFunc
 }
 
 // ImageDetailsHandler creates an HTTP handler for /api/images endpoint
-// Returns detailed image information including vulnerability counts
+// Returns detailed image information including vulnerability counts.
+// Soft-deleted images are only listed with ?includeDeleted=true.
 func ImageDetailsHandler(provider DatabaseProvider) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		images, err := provider.GetAllImageDetails()
+		images, err := provider.GetAllImageDetails(includeDeletedParam(r))
 		if err != nil {
 			log.Error("error querying image details", "error", err)
 			http.Error(w, "Internal server error", http.StatusInternalServerError)
@@ -283,29 +332,46 @@ func VulnerabilitiesHandler(provider DatabaseProvider) http.HandlerFunc {
 // HandlerOverrides allows customization of specific handlers during registration
 type HandlerOverrides struct {
 	// SBOMHandler optionally overrides the default SBOM download handler
-	// Used by k8s-scan-server to route SBOM requests to pod-scanner
+	// Used by k8s-scan-server to route SBOM requests to pod-scanner; it should
+	// write the SBOM with WriteSBOMDownload to support ?format=
 	SBOMHandler http.HandlerFunc
 	// VulnerabilitiesHandler optionally overrides the default vulnerabilities download handler
 	VulnerabilitiesHandler http.HandlerFunc
+	// FixHints optionally adds fix hints to the image detail endpoint
+	FixHints FixHintFinder
+	// OSLifecycle optionally adds OS end-of-life status to the images endpoint
+	OSLifecycle OSLifecycle
+	// Policy optionally serves pass/fail verdicts at /api/images/{digest}/policy
+	Policy policy.Evaluator
+	// ComputedColumns optionally adds computed columns to the image and
+	// container lists
+	ComputedColumns []columns.Column
+	// PathRedaction redacts file paths in downloaded SBOMs and vulnerability
+	// reports (sbomformat.RedactStrip or RedactHash; empty keeps them)
+	PathRedaction string
 }
 
-// RegisterDatabaseHandlers registers database query endpoints on the provided mux
+// RegisterDatabaseHandlers registers database query endpoints on the registry
 // Pass nil for overrides to use all default handlers
-func RegisterDatabaseHandlers(mux *http.ServeMux, provider DatabaseProvider, overrides *HandlerOverrides) {
+func RegisterDatabaseHandlers(reg *routes.Registry, provider DatabaseProvider, overrides *HandlerOverrides) {
 	// Register legacy endpoints under /api/
-	mux.HandleFunc("/api/containers/images", ImageDetailsHandler(provider))
+	reg.Handle(routes.Route{Pattern: "/api/containers/images", Methods: routes.GET, Handler: ImageDetailsHandler(provider)})
 
 	// Register download endpoints under /api/ with optional overrides
+	var redaction string
+	if overrides != nil {
+		redaction = overrides.PathRedaction
+	}
 	if overrides != nil && overrides.SBOMHandler != nil {
-		mux.HandleFunc("/api/sbom/", overrides.SBOMHandler)
+		reg.Handle(routes.Route{Pattern: "/api/sbom/", Methods: routes.GET, Handler: overrides.SBOMHandler})
 	} else {
-		mux.HandleFunc("/api/sbom/", SBOMDownloadHandler(provider))
+		reg.Handle(routes.Route{Pattern: "/api/sbom/", Methods: routes.GET, Handler: SBOMDownloadHandler(provider, redaction)})
 	}
 
 	if overrides != nil && overrides.VulnerabilitiesHandler != nil {
-		mux.HandleFunc("/api/vulnerabilities/", overrides.VulnerabilitiesHandler)
+		reg.Handle(routes.Route{Pattern: "/api/vulnerabilities/", Methods: routes.GET, Handler: overrides.VulnerabilitiesHandler})
 	} else {
-		mux.HandleFunc("/api/vulnerabilities/", func(w http.ResponseWriter, r *http.Request) {
+		reg.Handle(routes.Route{Pattern: "/api/vulnerabilities/", Methods: routes.GET, Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			// Check if this is a request for vulnerability details
 			path := r.URL.Path
 			log.Debug("vulnerability route handler", "path", path)
@@ -319,38 +385,65 @@ func RegisterDatabaseHandlers(mux *http.ServeMux, provider DatabaseProvider, ove
 				}
 				log.Debug("ImageQueryProvider not available, falling through")
 			}
+			// Paths the package of a finding was found at
+			if len(path) > 12 && path[len(path)-12:] == "/occurrences" {
+				if queryProvider, ok := provider.(ImageQueryProvider); ok {
+					VulnerabilityOccurrencesHandler(queryProvider)(w, r)
+					return
+				}
+			}
+			// A vulnerability ID rather than an image digest (sha256:...)
+			if id := path[len("/api/vulnerabilities/"):]; id != "" && !strings.ContainsAny(id, "/:") {
+				if queryProvider, ok := provider.(ImageQueryProvider); ok {
+					VulnerabilityDetailHandler(queryProvider)(w, r)
+					return
+				}
+			}
 			// Otherwise, use the download handler
 			log.Debug("routing to VulnerabilitiesDownloadHandler")
-			VulnerabilitiesDownloadHandler(provider)(w, r)
-		})
+			VulnerabilitiesDownloadHandler(provider, redaction)(w, r)
+		})})
 	}
 
 	// Register new API endpoints for aggregated data
 	// Note: ImagesHandler provides filtering, pagination, sorting, and CSV export
 	// It requires the provider to implement ImageQueryProvider interface
 	if queryProvider, ok := provider.(ImageQueryProvider); ok {
-		mux.HandleFunc("/api/images", ImagesHandler(queryProvider))
-		mux.HandleFunc("/api/containers", ContainersHandler(queryProvider))
-		mux.HandleFunc("/api/container-cves", ContainerCVEsHandler(queryProvider))
-		mux.HandleFunc("/api/container-cves/affected", ContainerCVEAffectedHandler(queryProvider))
-		mux.HandleFunc("/api/container-cves/details", ContainerCVEDetailVariantsHandler(queryProvider))
+		var lifecycle OSLifecycle
+		var computed []columns.Column
+		if overrides != nil {
+			lifecycle = overrides.OSLifecycle
+			computed = overrides.ComputedColumns
+		}
+		reg.Handle(routes.Route{Pattern: "/api/images", Methods: routes.GET, Handler: ImagesHandler(queryProvider, lifecycle, computed)})
+		reg.Handle(routes.Route{Pattern: "/api/containers", Methods: routes.GET, Handler: ContainersHandler(queryProvider, computed)})
+		reg.Handle(routes.Route{Pattern: "/api/vulnerabilities", Methods: routes.GET, Handler: VulnerabilityListHandler(queryProvider)})
+		reg.Handle(routes.Route{Pattern: "/api/container-cves", Methods: routes.GET, Handler: ContainerCVEsHandler(queryProvider)})
+		reg.Handle(routes.Route{Pattern: "/api/container-cves/affected", Methods: routes.GET, Handler: ContainerCVEAffectedHandler(queryProvider)})
+		reg.Handle(routes.Route{Pattern: "/api/container-cves/details", Methods: routes.GET, Handler: ContainerCVEDetailVariantsHandler(queryProvider)})
+		reg.Handle(routes.Route{Pattern: "/api/analytics/blast-radius", Methods: routes.GET, Handler: BlastRadiusHandler(queryProvider)})
+		reg.Handle(routes.Route{Pattern: "/api/analytics/fix-coverage", Methods: routes.GET, Handler: FixCoverageHandler(queryProvider)})
+		reg.Handle(routes.Route{Pattern: "/api/analytics/image-size", Methods: routes.GET, Handler: ImageSizeHandler(queryProvider)})
 		if filterProvider, ok := provider.(FilterOptionsProvider); ok {
-			mux.HandleFunc("/api/filter-options", FilterOptionsHandler(filterProvider))
+			reg.Handle(routes.Route{Pattern: "/api/filter-options", Methods: routes.GET, Handler: FilterOptionsHandler(filterProvider)})
+		}
+		reg.Handle(routes.Route{Pattern: "/api/summary/deployment-metrics", Methods: routes.GET, Handler: DeploymentMetricsHandler(queryProvider)})
+		reg.Handle(routes.Route{Pattern: "/api/summary/node-metrics", Methods: routes.GET, Handler: NodeMetricsSummaryHandler(queryProvider)})
+		reg.Handle(routes.Route{Pattern: "/api/summary/by-namespace", Methods: routes.GET, Handler: NamespaceSummaryHandler(queryProvider)})
+		reg.Handle(routes.Route{Pattern: "/api/summary/by-distribution", Methods: routes.GET, Handler: DistributionSummaryHandler(queryProvider)})
+		if historyProvider, ok := provider.(ScanHistoryProvider); ok {
+			reg.Handle(routes.Route{Pattern: "/api/summary/trends", Methods: routes.GET, Handler: ScanTrendsHandler(historyProvider)})
 		}
-		mux.HandleFunc("/api/summary/deployment-metrics", DeploymentMetricsHandler(queryProvider))
-		mux.HandleFunc("/api/summary/node-metrics", NodeMetricsSummaryHandler(queryProvider))
-		mux.HandleFunc("/api/summary/by-namespace", NamespaceSummaryHandler(queryProvider))
-		mux.HandleFunc("/api/summary/by-distribution", DistributionSummaryHandler(queryProvider))
 	} else {
 		// Fallback to basic handler if provider doesn't support ExecuteReadOnlyQuery
-		mux.HandleFunc("/api/images", ImageDetailsHandler(provider))
+		reg.Handle(routes.Route{Pattern: "/api/images", Methods: routes.GET, Handler: ImageDetailsHandler(provider)})
 	}
 
 	// Register last updated endpoint for auto-refresh functionality
 	if lastUpdatedProvider, ok := provider.(LastUpdatedProvider); ok {
-		mux.HandleFunc("/api/lastupdated", LastUpdatedHandler(lastUpdatedProvider))
+		reg.Handle(routes.Route{Pattern: "/api/lastupdated", Methods: routes.GET, Handler: LastUpdatedHandler(lastUpdatedProvider), CacheControl: routes.NoStore})
 	}
-	mux.HandleFunc("/api/images/", func(w http.ResponseWriter, r *http.Request) {
+	reg.Handle(routes.Route{Pattern: "/api/images/", Methods: routes.GET, Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		// Route to appropriate handler based on path suffix
 		path := r.URL.Path
 		log.Debug("routing /api/images/", "path", path)
@@ -377,6 +470,22 @@ func RegisterDatabaseHandlers(mux *http.ServeMux, provider DatabaseProvider, ove
 					ImageVulnerabilitiesDetailHandler(queryProvider)(w, r)
 					return
 				}
+				// Check for /policy suffix
+				if overrides != nil && overrides.Policy != nil && strings.HasSuffix(pathWithoutPrefix, "/policy") {
+					if policyProvider, ok := provider.(PolicyProvider); ok {
+						log.Debug("routing to ImagePolicyHandler")
+						ImagePolicyHandler(policyProvider, overrides.Policy)(w, r)
+						return
+					}
+				}
+				// Check for /history suffix
+				if strings.HasSuffix(pathWithoutPrefix, "/history") {
+					if historyProvider, ok := provider.(ScanHistoryProvider); ok {
+						log.Debug("routing to ImageScanHistoryHandler")
+						ImageScanHistoryHandler(historyProvider)(w, r)
+						return
+					}
+				}
 				// Check for /stats suffix
 				if len(pathWithoutPrefix) > 6 && pathWithoutPrefix[len(pathWithoutPrefix)-6:] == "/stats" {
 					log.Debug("routing to ImageStatsHandler")
@@ -385,7 +494,11 @@ func RegisterDatabaseHandlers(mux *http.ServeMux, provider DatabaseProvider, ove
 				}
 				// Single image detail with full info (references and containers)
 				log.Debug("routing to ImageDetailFullHandler")
-				ImageDetailFullHandler(queryProvider)(w, r)
+				var hints FixHintFinder
+				if overrides != nil {
+					hints = overrides.FixHints
+				}
+				ImageDetailFullHandler(queryProvider, hints)(w, r)
 			} else {
 				// Fallback to basic handlers if provider doesn't support ExecuteReadOnlyQuery
 				if len(pathWithoutPrefix) > 9 && pathWithoutPrefix[len(pathWithoutPrefix)-9:] == "/packages" {
@@ -399,11 +512,11 @@ func RegisterDatabaseHandlers(mux *http.ServeMux, provider DatabaseProvider, ove
 				ImageDetailHandler(provider)(w, r)
 			}
 		}
-	})
+	})})
 
 	// Route for package details (separate from image packages)
 	// This handles /api/packages/{id}/details for showing individual package JSON details
-	mux.HandleFunc("/api/packages/", func(w http.ResponseWriter, r *http.Request) {
+	reg.Handle(routes.Route{Pattern: "/api/packages/", Methods: routes.GET, Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		// Check if this is a request for package details
 		path := r.URL.Path
 		if len(path) > 8 && path[len(path)-8:] == "/details" {
@@ -415,5 +528,5 @@ func RegisterDatabaseHandlers(mux *http.ServeMux, provider DatabaseProvider, ove
 			}
 		}
 		http.Error(w, "Not found", http.StatusNotFound)
-	})
+	})})
 }
@@ -7,6 +7,7 @@ import (
 	"sync"
 
 	"github.com/bvboe/b2s-go/scanner-core/grype"
+	"github.com/bvboe/b2s-go/scanner-core/routes"
 )
 
 
@@ -153,8 +154,10 @@ func DatabaseReinitHandler(state *DatabaseReadinessState) http.HandlerFunc {
 }
 
 // RegisterDatabaseReadinessHandlers registers the database readiness endpoints
-func RegisterDatabaseReadinessHandlers(mux *http.ServeMux, state *DatabaseReadinessState) {
-	mux.HandleFunc("/ready", ReadinessHandler(state))
-	mux.HandleFunc("/api/db/status", DatabaseStatusHandler(state))
-	mux.HandleFunc("/api/debug/db/reinit", DatabaseReinitHandler(state))
+func RegisterDatabaseReadinessHandlers(reg *routes.Registry, state *DatabaseReadinessState) {
+	reg.Handle(
+		routes.Route{Pattern: "/ready", Handler: ReadinessHandler(state), Role: routes.RolePublic, CacheControl: routes.NoStore},
+		routes.Route{Pattern: "/api/db/status", Methods: routes.GET, Handler: DatabaseStatusHandler(state), CacheControl: routes.NoStore},
+		routes.Route{Pattern: "/api/debug/db/reinit", Methods: routes.POST, Handler: DatabaseReinitHandler(state), Role: routes.RoleAdmin},
+	)
 }
@@ -9,13 +9,15 @@ import (
 	"testing"
 
 	"github.com/bvboe/b2s-go/scanner-core/database"
+	"github.com/bvboe/b2s-go/scanner-core/routes"
+	"github.com/bvboe/b2s-go/scanner-core/sbomformat"
 )
 
 // mockDatabaseProvider implements DatabaseProvider for testing
 type mockDatabaseProvider struct {
 	getAllInstancesFunc           func() (interface{}, error)
 	getAllImagesFunc              func() (interface{}, error)
-	getAllImageDetailsFunc        func() (interface{}, error)
+	getAllImageDetailsFunc        func(includeDeleted bool) (interface{}, error)
 	getImageDetailsFunc           func(digest string) (interface{}, error)
 	getPackagesByImageFunc        func(digest string) (interface{}, error)
 	getVulnerabilitiesByImageFunc func(digest string) (interface{}, error)
@@ -38,9 +40,9 @@ func (m *mockDatabaseProvider) GetAllImages() (interface{}, error) {
 	return []map[string]interface{}{}, nil
 }
 
-func (m *mockDatabaseProvider) GetAllImageDetails() (interface{}, error) {
+func (m *mockDatabaseProvider) GetAllImageDetails(includeDeleted bool) (interface{}, error) {
 	if m.getAllImageDetailsFunc != nil {
-		return m.getAllImageDetailsFunc()
+		return m.getAllImageDetailsFunc(includeDeleted)
 	}
 	return []map[string]interface{}{}, nil
 }
@@ -214,6 +216,34 @@ func TestSBOMDownloadHandler(t *testing.T) {
 			path:           "/api/sbom/",
 			expectedStatus: http.StatusBadRequest,
 		},
+		{
+			name: "CycloneDX format",
+			path: "/api/sbom/sha256:abc123?format=cyclonedx-json",
+			mockFunc: func(digest string) ([]byte, error) {
+				return []byte(`{"artifacts":[{"id":"a1","name":"openssl","version":"3.0.0","type":"apk"}],"source":{"type":"image"},"schema":{"version":"16.0.0","url":"https://raw.githubusercontent.com/anchore/syft/main/schema/json/schema-16.0.0.json"}}`), nil
+			},
+			expectedStatus: http.StatusOK,
+			checkResponse: func(t *testing.T, rec *httptest.ResponseRecorder) {
+				if ct := rec.Header().Get("Content-Type"); ct != "application/vnd.cyclonedx+json" {
+					t.Errorf("Expected CycloneDX Content-Type, got '%s'", ct)
+				}
+				if disposition := rec.Header().Get("Content-Disposition"); !strings.Contains(disposition, ".cdx.json") {
+					t.Errorf("Expected .cdx.json filename, got '%s'", disposition)
+				}
+				if !strings.Contains(rec.Body.String(), `"bomFormat":"CycloneDX"`) || !strings.Contains(rec.Body.String(), "openssl") {
+					t.Errorf("Expected CycloneDX BOM with openssl, got %s", rec.Body.String())
+				}
+			},
+		},
+		{
+			name: "unsupported format",
+			path: "/api/sbom/sha256:abc123?format=cyclonedx-xml",
+			mockFunc: func(digest string) ([]byte, error) {
+				t.Error("SBOM fetched for an unsupported format")
+				return nil, nil
+			},
+			expectedStatus: http.StatusBadRequest,
+		},
 	}
 
 	for _, tt := range tests {
@@ -222,7 +252,7 @@ func TestSBOMDownloadHandler(t *testing.T) {
 				getSBOMFunc: tt.mockFunc,
 			}
 
-			handler := SBOMDownloadHandler(provider)
+			handler := SBOMDownloadHandler(provider, "")
 			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
 			rec := httptest.NewRecorder()
 
@@ -289,7 +319,7 @@ func TestVulnerabilitiesDownloadHandler(t *testing.T) {
 				getVulnerabilitiesFunc: tt.mockFunc,
 			}
 
-			handler := VulnerabilitiesDownloadHandler(provider)
+			handler := VulnerabilitiesDownloadHandler(provider, "")
 			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
 			rec := httptest.NewRecorder()
 
@@ -306,6 +336,32 @@ func TestVulnerabilitiesDownloadHandler(t *testing.T) {
 	}
 }
 
+func TestDownloadPathRedaction(t *testing.T) {
+	located := []byte(`{"artifacts":[{"id":"a1","name":"internal-lib","version":"1.2.3","type":"java-archive","locations":[{"path":"/opt/project-falcon/lib/internal-lib.jar"}]}],"source":{"type":"image"},"schema":{"version":"16.0.0","url":"https://raw.githubusercontent.com/anchore/syft/main/schema/json/schema-16.0.0.json"}}`)
+	provider := &mockDatabaseProvider{
+		getSBOMFunc:            func(digest string) ([]byte, error) { return located, nil },
+		getVulnerabilitiesFunc: func(digest string) ([]byte, error) { return located, nil },
+	}
+
+	for _, tt := range []struct {
+		path    string
+		handler http.HandlerFunc
+	}{
+		{"/api/sbom/sha256:abc123", SBOMDownloadHandler(provider, sbomformat.RedactHash)},
+		{"/api/sbom/sha256:abc123?format=spdx-json", SBOMDownloadHandler(provider, sbomformat.RedactHash)},
+		{"/api/vulnerabilities/sha256:abc123", VulnerabilitiesDownloadHandler(provider, sbomformat.RedactStrip)},
+	} {
+		rec := httptest.NewRecorder()
+		tt.handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.path, nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("GET %s status = %d, body: %s", tt.path, rec.Code, rec.Body.String())
+		}
+		if body := rec.Body.String(); strings.Contains(body, "project-falcon") || !strings.Contains(body, "internal-lib") {
+			t.Errorf("GET %s = %s, want paths redacted and packages kept", tt.path, body)
+		}
+	}
+}
+
 func TestImageDetailsHandler(t *testing.T) {
 	tests := []struct {
 		name           string
@@ -344,7 +400,7 @@ func TestImageDetailsHandler(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			provider := &mockDatabaseProvider{
-				getAllImageDetailsFunc: tt.mockFunc,
+				getAllImageDetailsFunc: func(bool) (interface{}, error) { return tt.mockFunc() },
 			}
 
 			handler := ImageDetailsHandler(provider)
@@ -364,6 +420,29 @@ func TestImageDetailsHandler(t *testing.T) {
 	}
 }
 
+func TestImageDetailsHandlerIncludeDeleted(t *testing.T) {
+	var got []bool
+	provider := &mockDatabaseProvider{
+		getAllImageDetailsFunc: func(includeDeleted bool) (interface{}, error) {
+			got = append(got, includeDeleted)
+			return []map[string]interface{}{}, nil
+		},
+	}
+
+	handler := ImageDetailsHandler(provider)
+	for _, path := range []string{"/api/images", "/api/images?includeDeleted=true"} {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%s: expected status 200, got %d", path, rec.Code)
+		}
+	}
+
+	if len(got) != 2 || got[0] || !got[1] {
+		t.Errorf("includeDeleted = %v, want [false true]", got)
+	}
+}
+
 func TestImageDetailHandler(t *testing.T) {
 	tests := []struct {
 		name           string
@@ -592,7 +671,7 @@ func TestVulnerabilitiesHandler(t *testing.T) {
 
 func TestRegisterDatabaseHandlers(t *testing.T) {
 	t.Run("registers handlers without overrides", func(t *testing.T) {
-		mux := http.NewServeMux()
+		mux := routes.NewRegistry()
 		provider := &mockDatabaseProvider{}
 
 		RegisterDatabaseHandlers(mux, provider, nil)
@@ -619,7 +698,7 @@ func TestRegisterDatabaseHandlers(t *testing.T) {
 	})
 
 	t.Run("uses custom overrides", func(t *testing.T) {
-		mux := http.NewServeMux()
+		mux := routes.NewRegistry()
 		provider := &mockDatabaseProvider{}
 
 		customSBOMCalled := false
@@ -656,7 +735,7 @@ func TestRegisterDatabaseHandlers(t *testing.T) {
 	})
 
 	t.Run("uses ImageQueryProvider when available", func(t *testing.T) {
-		mux := http.NewServeMux()
+		mux := routes.NewRegistry()
 
 		provider := &combinedTestProvider{
 			mockDatabaseProvider: &mockDatabaseProvider{},
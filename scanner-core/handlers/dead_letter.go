@@ -0,0 +1,104 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/bvboe/b2s-go/scanner-core/database"
+	"github.com/bvboe/b2s-go/scanner-core/routes"
+)
+
+// DeadLetterQueue requeues dead-lettered scans (implemented by scanning.JobQueue)
+type DeadLetterQueue interface {
+	RequeueDeadLettered(digests []string, nodeName string) ([]database.DeadLetteredScan, error)
+}
+
+// DeadLetterProvider lists dead-lettered scans
+type DeadLetterProvider interface {
+	GetDeadLetteredScans() ([]database.DeadLetteredScan, error)
+}
+
+// DeadLetterRequeueRequest is the body of POST /api/scan-queue/dead-letter/requeue.
+// Without digests all dead-lettered images are requeued; NodeName limits them
+// to images running on that node.
+type DeadLetterRequeueRequest struct {
+	Digests  []string `json:"digests,omitempty"`
+	NodeName string   `json:"node_name,omitempty"`
+}
+
+// maxDeadLetterRequestSize bounds the requeue request body
+const maxDeadLetterRequestSize = 1 << 20
+
+// RegisterDeadLetterHandlers registers the dead-letter endpoints of the scan queue
+func RegisterDeadLetterHandlers(reg *routes.Registry, queue DeadLetterQueue, provider DeadLetterProvider) {
+	reg.Handle(
+		routes.Route{Pattern: "/api/scan-queue/dead-letter", Methods: routes.GET, Handler: DeadLetterListHandler(provider)},
+		routes.Route{Pattern: "/api/scan-queue/dead-letter/requeue", Methods: routes.POST, Handler: DeadLetterRequeueHandler(queue), Role: routes.RoleAdmin},
+	)
+}
+
+// DeadLetterListHandler creates an HTTP handler for GET /api/scan-queue/dead-letter.
+// Returns the images that failed to scan too many times in a row to be retried
+// automatically, with the error of each failed attempt.
+func DeadLetterListHandler(provider DeadLetterProvider) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		scans, err := provider.GetDeadLetteredScans()
+		if err != nil {
+			log.Error("error querying dead-lettered scans", "error", err)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(map[string]interface{}{
+			"scans": scans,
+			"count": len(scans),
+		}); err != nil {
+			log.Error("error encoding dead-lettered scans", "error", err)
+		}
+	}
+}
+
+// DeadLetterRequeueHandler creates an HTTP handler for POST
+// /api/scan-queue/dead-letter/requeue. Takes the selected images out of the
+// dead-letter list and scans them again, e.g. after a broken node is fixed.
+//
+// Request (optional): {"digests": ["sha256:..."], "node_name": "node-1"}
+// Response: {"requeued": 2, "scans": [...]}
+func DeadLetterRequeueHandler(queue DeadLetterQueue) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		var req DeadLetterRequeueRequest
+		err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxDeadLetterRequestSize)).Decode(&req)
+		if err != nil && !errors.Is(err, io.EOF) {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+
+		requeued, err := queue.RequeueDeadLettered(req.Digests, req.NodeName)
+		if err != nil {
+			log.Error("error requeueing dead-lettered scans", "error", err)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(map[string]interface{}{
+			"requeued": len(requeued),
+			"scans":    requeued,
+		}); err != nil {
+			log.Error("error encoding requeue response", "error", err)
+		}
+	}
+}
@@ -0,0 +1,106 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/bvboe/b2s-go/scanner-core/database"
+)
+
+type mockDeadLetterQueue struct {
+	err      error
+	digests  []string
+	nodeName string
+}
+
+func (m *mockDeadLetterQueue) RequeueDeadLettered(digests []string, nodeName string) ([]database.DeadLetteredScan, error) {
+	m.digests, m.nodeName = digests, nodeName
+	if m.err != nil {
+		return nil, m.err
+	}
+	return []database.DeadLetteredScan{{Digest: "sha256:abc", Attempts: 5}}, nil
+}
+
+type mockDeadLetterProvider struct {
+	scans []database.DeadLetteredScan
+}
+
+func (m *mockDeadLetterProvider) GetDeadLetteredScans() ([]database.DeadLetteredScan, error) {
+	return m.scans, nil
+}
+
+func TestDeadLetterListHandler(t *testing.T) {
+	provider := &mockDeadLetterProvider{scans: []database.DeadLetteredScan{{
+		Digest:    "sha256:abc",
+		Reference: "nginx:1.27",
+		Attempts:  5,
+		Failures:  []database.ScanFailure{{Status: database.StatusSBOMFailed, Error: "node unreachable"}},
+	}}}
+
+	rec := httptest.NewRecorder()
+	DeadLetterListHandler(provider)(rec, httptest.NewRequest(http.MethodGet, "/api/scan-queue/dead-letter", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200", rec.Code)
+	}
+	var response struct {
+		Scans []database.DeadLetteredScan `json:"scans"`
+		Count int                         `json:"count"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&response); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if response.Count != 1 || len(response.Scans[0].Failures) != 1 || response.Scans[0].Failures[0].Error != "node unreachable" {
+		t.Errorf("unexpected response: %+v", response)
+	}
+
+	rec = httptest.NewRecorder()
+	DeadLetterListHandler(provider)(rec, httptest.NewRequest(http.MethodPost, "/api/scan-queue/dead-letter", nil))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("status = %d, want 405", rec.Code)
+	}
+}
+
+func TestDeadLetterRequeueHandler(t *testing.T) {
+	tests := []struct {
+		name         string
+		method       string
+		body         string
+		err          error
+		wantStatus   int
+		wantDigests  []string
+		wantNodeName string
+	}{
+		{"all", http.MethodPost, "", nil, http.StatusOK, nil, ""},
+		{"selected", http.MethodPost, `{"digests": ["sha256:abc"], "node_name": "node-1"}`, nil, http.StatusOK, []string{"sha256:abc"}, "node-1"},
+		{"wrong method", http.MethodGet, "", nil, http.StatusMethodNotAllowed, nil, ""},
+		{"invalid body", http.MethodPost, `sha256:abc`, nil, http.StatusBadRequest, nil, ""},
+		{"requeue error", http.MethodPost, `{}`, errors.New("database locked"), http.StatusInternalServerError, nil, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			queue := &mockDeadLetterQueue{err: tt.err}
+			rec := httptest.NewRecorder()
+			req := httptest.NewRequest(tt.method, "/api/scan-queue/dead-letter/requeue", strings.NewReader(tt.body))
+			DeadLetterRequeueHandler(queue)(rec, req)
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+			if strings.Join(queue.digests, ",") != strings.Join(tt.wantDigests, ",") || queue.nodeName != tt.wantNodeName {
+				t.Errorf("requeued digests %v on %q, want %v on %q", queue.digests, queue.nodeName, tt.wantDigests, tt.wantNodeName)
+			}
+			var response struct {
+				Requeued int `json:"requeued"`
+			}
+			if err := json.NewDecoder(rec.Body).Decode(&response); err != nil || response.Requeued != 1 {
+				t.Errorf("unexpected response: %+v, %v", response, err)
+			}
+		})
+	}
+}
@@ -9,10 +9,20 @@ import (
 	"github.com/bvboe/b2s-go/scanner-core/containers"
 	"github.com/bvboe/b2s-go/scanner-core/database"
 	"github.com/bvboe/b2s-go/scanner-core/debug"
+	"github.com/bvboe/b2s-go/scanner-core/routes"
 	"github.com/bvboe/b2s-go/scanner-core/scanning"
 )
 
 
+// debugCategoryEnabled rejects the request with 403 unless category is enabled
+func debugCategoryEnabled(w http.ResponseWriter, debugConfig *debug.DebugConfig, category debug.Category) bool {
+	if !debugConfig.CategoryEnabled(category) {
+		http.Error(w, fmt.Sprintf("Debug category %q not enabled", category), http.StatusForbidden)
+		return false
+	}
+	return true
+}
+
 // DebugSQLHandler handles POST /debug/sql requests to execute SQL queries.
 //
 // WARNING: This endpoint allows ALL SQL statements including INSERT, UPDATE, DELETE, DROP, etc.
@@ -47,9 +57,7 @@ import (
 //	}
 func DebugSQLHandler(db *database.DB, debugConfig *debug.DebugConfig) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		// Check if debug mode is enabled
-		if !debugConfig.IsEnabled() {
-			http.Error(w, "Debug mode not enabled", http.StatusForbidden)
+		if !debugCategoryEnabled(w, debugConfig, debug.CategorySQL) {
 			return
 		}
 
@@ -145,9 +153,7 @@ func DebugSQLHandler(db *database.DB, debugConfig *debug.DebugConfig) http.Handl
 //	}
 func DebugMetricsHandler(debugConfig *debug.DebugConfig, scanQueue *scanning.JobQueue) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		// Check if debug mode is enabled
-		if !debugConfig.IsEnabled() {
-			http.Error(w, "Debug mode not enabled", http.StatusForbidden)
+		if !debugCategoryEnabled(w, debugConfig, debug.CategoryQueue) {
 			return
 		}
 
@@ -216,8 +222,7 @@ func DebugMetricsHandler(debugConfig *debug.DebugConfig, scanQueue *scanning.Job
 //	}
 func DebugQueueHandler(debugConfig *debug.DebugConfig, scanQueue *scanning.JobQueue) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		if !debugConfig.IsEnabled() {
-			http.Error(w, "Debug mode not enabled", http.StatusForbidden)
+		if !debugCategoryEnabled(w, debugConfig, debug.CategoryQueue) {
 			return
 		}
 
@@ -250,8 +255,7 @@ func DebugQueueHandler(debugConfig *debug.DebugConfig, scanQueue *scanning.JobQu
 // Response: {"status": "queued", "node": "worker-1"}
 func DebugRescanNodeHandler(debugConfig *debug.DebugConfig, scanQueue *scanning.JobQueue) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		if !debugConfig.IsEnabled() {
-			http.Error(w, "Debug mode not enabled", http.StatusForbidden)
+		if !debugCategoryEnabled(w, debugConfig, debug.CategoryQueue) {
 			return
 		}
 
@@ -313,8 +317,7 @@ func DebugRescanNodeHandler(debugConfig *debug.DebugConfig, scanQueue *scanning.
 // Response: {"status": "queued", "digest": "sha256:..."}
 func DebugRescanImageHandler(debugConfig *debug.DebugConfig, db *database.DB, scanQueue *scanning.JobQueue) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		if !debugConfig.IsEnabled() {
-			http.Error(w, "Debug mode not enabled", http.StatusForbidden)
+		if !debugCategoryEnabled(w, debugConfig, debug.CategoryQueue) {
 			return
 		}
 
@@ -384,8 +387,7 @@ func DebugRescanImageHandler(debugConfig *debug.DebugConfig, db *database.DB, sc
 // Response: {"status": "queued", "count": 5}
 func DebugRescanAllNodesHandler(debugConfig *debug.DebugConfig, db *database.DB, scanQueue *scanning.JobQueue) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		if !debugConfig.IsEnabled() {
-			http.Error(w, "Debug mode not enabled", http.StatusForbidden)
+		if !debugCategoryEnabled(w, debugConfig, debug.CategoryQueue) {
 			return
 		}
 
@@ -430,8 +432,7 @@ func DebugRescanAllNodesHandler(debugConfig *debug.DebugConfig, db *database.DB,
 // Response: {"status": "queued", "count": 50}
 func DebugRescanAllImagesHandler(debugConfig *debug.DebugConfig, db *database.DB, scanQueue *scanning.JobQueue) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		if !debugConfig.IsEnabled() {
-			http.Error(w, "Debug mode not enabled", http.StatusForbidden)
+		if !debugCategoryEnabled(w, debugConfig, debug.CategoryQueue) {
 			return
 		}
 
@@ -484,30 +485,45 @@ func DebugRescanAllImagesHandler(debugConfig *debug.DebugConfig, db *database.DB
 	}
 }
 
-// RegisterDebugHandlers registers debug endpoints on the provided mux.
-// If debug mode is not enabled, handlers are not registered (zero overhead).
+// RegisterDebugHandlers registers the debug endpoints of each enabled
+// category. All of them require the admin role. If no category is enabled,
+// handlers are not registered (zero overhead).
 //
 // Endpoints:
-//   - POST /api/debug/sql - Execute SQL queries (SELECT, INSERT, UPDATE, DELETE, etc.)
-//   - GET /api/debug/metrics - Retrieve performance metrics
-//   - GET /api/debug/queue - Get current queue contents
-//   - POST /api/debug/rescan/node/{name} - Rescan a specific node
-//   - POST /api/debug/rescan/image/{digest} - Rescan a specific image
-//   - POST /api/debug/rescan/all-nodes - Rescan all nodes
-//   - POST /api/debug/rescan/all-images - Rescan all images
-func RegisterDebugHandlers(mux *http.ServeMux, db *database.DB, debugConfig *debug.DebugConfig, scanQueue *scanning.JobQueue) {
+//   - queue: GET /api/debug/metrics - Retrieve performance metrics
+//   - queue: GET /api/debug/queue - Get current queue contents
+//   - queue: POST /api/debug/rescan/node/{name} - Rescan a specific node
+//   - queue: POST /api/debug/rescan/image/{digest} - Rescan a specific image
+//   - queue: POST /api/debug/rescan/all-nodes - Rescan all nodes
+//   - queue: POST /api/debug/rescan/all-images - Rescan all images
+//   - sql: POST /api/debug/sql - Execute SQL queries (SELECT, INSERT, UPDATE, DELETE, etc.)
+//   - log-level: GET/POST /api/debug/log-level - Report or change the log level
+//   - faults: GET/POST /api/debug/faults - Report or change injected database write failures
+func RegisterDebugHandlers(reg *routes.Registry, db *database.DB, debugConfig *debug.DebugConfig, scanQueue *scanning.JobQueue) {
 	if debugConfig == nil || !debugConfig.IsEnabled() {
 		// Don't register handlers if debug not enabled
 		return
 	}
 
-	mux.HandleFunc("/api/debug/sql", DebugSQLHandler(db, debugConfig))
-	mux.HandleFunc("/api/debug/metrics", DebugMetricsHandler(debugConfig, scanQueue))
-	mux.HandleFunc("/api/debug/queue", DebugQueueHandler(debugConfig, scanQueue))
-	mux.HandleFunc("/api/debug/rescan/node/", DebugRescanNodeHandler(debugConfig, scanQueue))
-	mux.HandleFunc("/api/debug/rescan/image/", DebugRescanImageHandler(debugConfig, db, scanQueue))
-	mux.HandleFunc("/api/debug/rescan/all-nodes", DebugRescanAllNodesHandler(debugConfig, db, scanQueue))
-	mux.HandleFunc("/api/debug/rescan/all-images", DebugRescanAllImagesHandler(debugConfig, db, scanQueue))
+	if debugConfig.CategoryEnabled(debug.CategoryQueue) {
+		reg.Handle(
+			routes.Route{Pattern: "/api/debug/metrics", Methods: routes.GET, Handler: DebugMetricsHandler(debugConfig, scanQueue), Role: routes.RoleAdmin},
+			routes.Route{Pattern: "/api/debug/queue", Methods: routes.GET, Handler: DebugQueueHandler(debugConfig, scanQueue), Role: routes.RoleAdmin},
+			routes.Route{Pattern: "/api/debug/rescan/node/", Methods: routes.POST, Handler: DebugRescanNodeHandler(debugConfig, scanQueue), Role: routes.RoleAdmin},
+			routes.Route{Pattern: "/api/debug/rescan/image/", Methods: routes.POST, Handler: DebugRescanImageHandler(debugConfig, db, scanQueue), Role: routes.RoleAdmin},
+			routes.Route{Pattern: "/api/debug/rescan/all-nodes", Methods: routes.POST, Handler: DebugRescanAllNodesHandler(debugConfig, db, scanQueue), Role: routes.RoleAdmin},
+			routes.Route{Pattern: "/api/debug/rescan/all-images", Methods: routes.POST, Handler: DebugRescanAllImagesHandler(debugConfig, db, scanQueue), Role: routes.RoleAdmin},
+		)
+	}
+	if debugConfig.CategoryEnabled(debug.CategorySQL) {
+		reg.Handle(routes.Route{Pattern: "/api/debug/sql", Methods: routes.POST, Handler: DebugSQLHandler(db, debugConfig), Role: routes.RoleAdmin})
+	}
+	if debugConfig.CategoryEnabled(debug.CategoryLogLevel) {
+		reg.Handle(routes.Route{Pattern: "/api/debug/log-level", Methods: []string{http.MethodGet, http.MethodPost}, Handler: DebugLogLevelHandler(debugConfig), Role: routes.RoleAdmin})
+	}
+	if debugConfig.CategoryEnabled(debug.CategoryFaults) {
+		reg.Handle(routes.Route{Pattern: "/api/debug/faults", Methods: []string{http.MethodGet, http.MethodPost}, Handler: DebugFaultsHandler(db, debugConfig), Role: routes.RoleAdmin})
+	}
 
-	log.Info("debug handlers registered", "endpoints", "/api/debug/sql, /api/debug/metrics, /api/debug/queue, /api/debug/rescan/*")
+	log.Info("debug handlers registered", "categories", debugConfig.Categories())
 }
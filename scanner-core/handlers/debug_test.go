@@ -9,6 +9,7 @@ import (
 	"time"
 
 	"github.com/bvboe/b2s-go/scanner-core/debug"
+	"github.com/bvboe/b2s-go/scanner-core/routes"
 	"github.com/bvboe/b2s-go/scanner-core/scanning"
 )
 
@@ -206,7 +207,7 @@ func TestDebugMetricsHandler(t *testing.T) {
 
 func TestRegisterDebugHandlers(t *testing.T) {
 	t.Run("registers handlers when debug enabled", func(t *testing.T) {
-		mux := http.NewServeMux()
+		mux := routes.NewRegistry()
 		debugConfig := debug.NewDebugConfig(true)
 
 		RegisterDebugHandlers(mux, nil, debugConfig, nil)
@@ -233,7 +234,7 @@ func TestRegisterDebugHandlers(t *testing.T) {
 	})
 
 	t.Run("does not register handlers when debug disabled", func(t *testing.T) {
-		mux := http.NewServeMux()
+		mux := routes.NewRegistry()
 		debugConfig := debug.NewDebugConfig(false)
 
 		RegisterDebugHandlers(mux, nil, debugConfig, nil)
@@ -249,8 +250,36 @@ func TestRegisterDebugHandlers(t *testing.T) {
 		}
 	})
 
+	t.Run("registers only enabled categories", func(t *testing.T) {
+		mux := routes.NewRegistry()
+		debugConfig := debug.NewDebugConfigForCategories(debug.CategoryLogLevel)
+
+		RegisterDebugHandlers(mux, nil, debugConfig, nil)
+
+		tests := []struct {
+			path       string
+			method     string
+			registered bool
+		}{
+			{"/api/debug/log-level", http.MethodGet, true},
+			{"/api/debug/sql", http.MethodPost, false},
+			{"/api/debug/queue", http.MethodGet, false},
+			{"/api/debug/faults", http.MethodGet, false},
+		}
+
+		for _, tt := range tests {
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			rec := httptest.NewRecorder()
+			mux.ServeHTTP(rec, req)
+
+			if registered := rec.Code != http.StatusNotFound; registered != tt.registered {
+				t.Errorf("%s %s registered = %v, want %v", tt.method, tt.path, registered, tt.registered)
+			}
+		}
+	})
+
 	t.Run("handles nil debug config", func(t *testing.T) {
-		mux := http.NewServeMux()
+		mux := routes.NewRegistry()
 
 		// Should not panic
 		RegisterDebugHandlers(mux, nil, nil, nil)
@@ -21,6 +21,10 @@ func (m *mockImageQueryProvider) ExecuteReadOnlyQuery(query string) (*database.Q
 	return &database.QueryResult{Rows: []map[string]interface{}{}}, nil
 }
 
+func (m *mockImageQueryProvider) ExecuteReadOnlyQueryArgs(query string, _ ...interface{}) (*database.QueryResult, error) {
+	return m.ExecuteReadOnlyQuery(query)
+}
+
 func (m *mockImageQueryProvider) GetSBOM(digest string) ([]byte, error) {
 	return nil, fmt.Errorf("SBOM not available")
 }
@@ -0,0 +1,42 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/bvboe/b2s-go/scanner-core/diskusage"
+	"github.com/bvboe/b2s-go/scanner-core/routes"
+)
+
+// DiskUsageReporter provides the latest data volume report (implemented by diskusage.Monitor)
+type DiskUsageReporter interface {
+	Report() *diskusage.Report
+}
+
+// DiskUsageHandler creates an HTTP handler for /api/status/disk endpoint
+// Reports data volume usage, the size of the database and Grype database, the
+// last SBOM pruning run and warnings while the volume is filling up.
+func DiskUsageHandler(reporter DiskUsageReporter) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		report := reporter.Report()
+		if report == nil {
+			http.Error(w, "Disk usage not checked yet", http.StatusServiceUnavailable)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(report); err != nil {
+			log.Error("error encoding disk usage response", "error", err)
+		}
+	}
+}
+
+// RegisterDiskUsageHandlers registers the disk usage status endpoint
+func RegisterDiskUsageHandlers(reg *routes.Registry, reporter DiskUsageReporter) {
+	reg.Handle(routes.Route{Pattern: "/api/status/disk", Methods: routes.GET, Handler: DiskUsageHandler(reporter), CacheControl: routes.NoStore})
+}
@@ -0,0 +1,52 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/bvboe/b2s-go/scanner-core/diskusage"
+)
+
+type mockDiskUsageReporter struct {
+	report *diskusage.Report
+}
+
+func (m *mockDiskUsageReporter) Report() *diskusage.Report { return m.report }
+
+func TestDiskUsageHandler(t *testing.T) {
+	reporter := &mockDiskUsageReporter{}
+	handler := DiskUsageHandler(reporter)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/status/disk", nil)
+	w := httptest.NewRecorder()
+	handler(w, req)
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected 503 before the first check, got %d", w.Code)
+	}
+
+	reporter.report = &diskusage.Report{
+		UsedPercent: 91.5,
+		Status:      diskusage.StatusCritical,
+		Warnings:    []string{"data volume is 91.5% full"},
+	}
+	w = httptest.NewRecorder()
+	handler(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", w.Code)
+	}
+	var got diskusage.Report
+	if err := json.NewDecoder(w.Body).Decode(&got); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if got.Status != diskusage.StatusCritical || len(got.Warnings) != 1 {
+		t.Errorf("Unexpected report %+v", got)
+	}
+
+	w = httptest.NewRecorder()
+	handler(w, httptest.NewRequest(http.MethodPost, "/api/status/disk", nil))
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("Expected 405 for POST, got %d", w.Code)
+	}
+}
@@ -0,0 +1,10 @@
+// Package handlers provides the HTTP API and web UI served by scanner-core
+// based programs.
+//
+// RegisterAPIHandlers registers the complete database-backed API on a
+// routes.Registry; the individual Register* functions remain available for
+// programs that only need part of it. Every route declares its methods,
+// required role and caching, so middleware added to the registry (e.g.
+// authentication) applies to all of them consistently. Handlers take their settings from option structs
+// (APIOptions, TransferConfig, ReportConfig) rather than the environment.
+package handlers
@@ -0,0 +1,64 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/bvboe/b2s-go/scanner-core/debug"
+)
+
+// WriteFaultInjector makes database writes fail on purpose (implemented by database.DB)
+type WriteFaultInjector interface {
+	SetWriteFaultRate(rate float64) error
+	WriteFaults() (rate float64, injected int64)
+}
+
+// DebugFaultsRequest is the body of POST /api/debug/faults
+type DebugFaultsRequest struct {
+	// DBWriteErrorRate is the share (0-1) of database write transactions that fail
+	DBWriteErrorRate float64 `json:"db_write_error_rate"`
+}
+
+// maxDebugFaultsRequestSize bounds the fault injection request body
+const maxDebugFaultsRequestSize = 4 << 10
+
+// DebugFaultsHandler creates an HTTP handler for /api/debug/faults. GET reports
+// the injected faults, POST changes them, so the retry, dead-letter and
+// alerting behavior can be tested end to end. SBOM errors and delays for
+// specific digests are injected in pod-scanner (FAULT_INJECTION).
+//
+// Request: {"db_write_error_rate": 0.2} (0 turns injection off)
+// Response: {"db_write_error_rate": 0.2, "db_write_errors_injected": 17}
+func DebugFaultsHandler(injector WriteFaultInjector, debugConfig *debug.DebugConfig) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !debugCategoryEnabled(w, debugConfig, debug.CategoryFaults) {
+			return
+		}
+
+		switch r.Method {
+		case http.MethodGet:
+		case http.MethodPost:
+			var req DebugFaultsRequest
+			if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxDebugFaultsRequestSize)).Decode(&req); err != nil {
+				http.Error(w, "Invalid request body", http.StatusBadRequest)
+				return
+			}
+			if err := injector.SetWriteFaultRate(req.DBWriteErrorRate); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		rate, injected := injector.WriteFaults()
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(map[string]interface{}{
+			"db_write_error_rate":      rate,
+			"db_write_errors_injected": injected,
+		}); err != nil {
+			log.Error("error encoding fault injection response", "error", err)
+		}
+	}
+}
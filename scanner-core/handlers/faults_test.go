@@ -0,0 +1,62 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/bvboe/b2s-go/scanner-core/database"
+	"github.com/bvboe/b2s-go/scanner-core/debug"
+)
+
+func TestDebugFaultsHandler(t *testing.T) {
+	db, err := database.New(filepath.Join(t.TempDir(), "faults.db"))
+	if err != nil {
+		t.Fatalf("Failed to create database: %v", err)
+	}
+	defer func() { _ = database.Close(db) }()
+
+	serve := func(debugEnabled bool, method, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/api/debug/faults", strings.NewReader(body))
+		rec := httptest.NewRecorder()
+		DebugFaultsHandler(db, debug.NewDebugConfig(debugEnabled))(rec, req)
+		return rec
+	}
+
+	if rec := serve(false, http.MethodPost, `{"db_write_error_rate":1}`); rec.Code != http.StatusForbidden {
+		t.Errorf("debug disabled: status = %d, want %d", rec.Code, http.StatusForbidden)
+	}
+	if rec := serve(true, http.MethodPost, `{"db_write_error_rate":1.5}`); rec.Code != http.StatusBadRequest {
+		t.Errorf("rate above 1: status = %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+	if rec := serve(true, http.MethodDelete, ""); rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("DELETE: status = %d, want %d", rec.Code, http.StatusMethodNotAllowed)
+	}
+
+	if rec := serve(true, http.MethodPost, `{"db_write_error_rate":1}`); rec.Code != http.StatusOK {
+		t.Fatalf("POST: status = %d: %s", rec.Code, rec.Body.String())
+	}
+	if _, err := db.SetCVEAnnotation("CVE-2024-1234", "note", ""); err == nil {
+		t.Error("expected the write to fail with fault injection enabled")
+	}
+
+	rec := serve(true, http.MethodGet, "")
+	var resp struct {
+		Rate     float64 `json:"db_write_error_rate"`
+		Injected int64   `json:"db_write_errors_injected"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if resp.Rate != 1 || resp.Injected != 1 {
+		t.Errorf("GET = %+v, want rate 1 with 1 injected fault", resp)
+	}
+
+	serve(true, http.MethodPost, `{"db_write_error_rate":0}`)
+	if _, err := db.SetCVEAnnotation("CVE-2024-1234", "note", ""); err != nil {
+		t.Errorf("write failed with fault injection off: %v", err)
+	}
+}
@@ -0,0 +1,60 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/bvboe/b2s-go/scanner-core/database"
+	"github.com/bvboe/b2s-go/scanner-core/routes"
+)
+
+// PurgePreviewer lists what purging soft-deleted images would remove
+// (implemented by database.DB)
+type PurgePreviewer interface {
+	PreviewPurgeDeletedImages(olderThan time.Duration) (*database.PurgePreview, error)
+}
+
+// GCPreviewHandler creates an HTTP handler for /api/gc/preview endpoint
+// Reports the images the purge job would remove on its next run, with their
+// packages, vulnerabilities and SBOM documents, without removing anything.
+// ?retention=168h previews another retention than the configured one.
+func GCPreviewHandler(store PurgePreviewer, retention time.Duration) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		window := retention
+		if v := r.URL.Query().Get("retention"); v != "" {
+			d, err := time.ParseDuration(v)
+			if err != nil || d < 0 {
+				http.Error(w, "Invalid retention duration", http.StatusBadRequest)
+				return
+			}
+			window = d
+		}
+
+		preview, err := store.PreviewPurgeDeletedImages(window)
+		if err != nil {
+			log.Error("error previewing purge", "error", err)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+
+		response := struct {
+			*database.PurgePreview
+			Retention string `json:"retention"`
+		}{preview, window.String()}
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(response); err != nil {
+			log.Error("error encoding purge preview response", "error", err)
+		}
+	}
+}
+
+// RegisterGCHandlers registers the purge preview endpoint
+func RegisterGCHandlers(reg *routes.Registry, store PurgePreviewer, retention time.Duration) {
+	reg.Handle(routes.Route{Pattern: "/api/gc/preview", Methods: routes.GET, Handler: GCPreviewHandler(store, retention)})
+}
@@ -0,0 +1,60 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/bvboe/b2s-go/scanner-core/database"
+)
+
+type mockPurgePreviewer struct {
+	olderThan time.Duration
+}
+
+func (m *mockPurgePreviewer) PreviewPurgeDeletedImages(olderThan time.Duration) (*database.PurgePreview, error) {
+	m.olderThan = olderThan
+	return &database.PurgePreview{
+		Images:          []database.PurgeCandidate{{Digest: "sha256:old", DeletedAt: "2024-01-01T00:00:00Z"}},
+		PackagesRemoved: 42,
+	}, nil
+}
+
+func TestGCPreviewHandler(t *testing.T) {
+	store := &mockPurgePreviewer{}
+	handler := GCPreviewHandler(store, 720*time.Hour)
+
+	w := httptest.NewRecorder()
+	handler(w, httptest.NewRequest(http.MethodGet, "/api/gc/preview", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", w.Code)
+	}
+	var got struct {
+		Images          []database.PurgeCandidate `json:"images"`
+		PackagesRemoved int                       `json:"packages_removed"`
+		Retention       string                    `json:"retention"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&got); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if store.olderThan != 720*time.Hour || got.Retention != "720h0m0s" || len(got.Images) != 1 || got.PackagesRemoved != 42 {
+		t.Errorf("Unexpected preview %+v of retention %v", got, store.olderThan)
+	}
+
+	// The retention can be overridden to see what a shorter one would remove
+	w = httptest.NewRecorder()
+	handler(w, httptest.NewRequest(http.MethodGet, "/api/gc/preview?retention=24h", nil))
+	if w.Code != http.StatusOK || store.olderThan != 24*time.Hour {
+		t.Errorf("Expected preview of 24h retention, got %d with %v", w.Code, store.olderThan)
+	}
+
+	for _, query := range []string{"retention=soon", "retention=-1h"} {
+		w = httptest.NewRecorder()
+		handler(w, httptest.NewRequest(http.MethodGet, "/api/gc/preview?"+query, nil))
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", query, w.Code)
+		}
+	}
+}
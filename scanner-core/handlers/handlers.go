@@ -7,6 +7,7 @@ import (
 
 	"github.com/bvboe/b2s-go/scanner-core/database"
 	"github.com/bvboe/b2s-go/scanner-core/logging"
+	"github.com/bvboe/b2s-go/scanner-core/routes"
 )
 
 var log = logging.For(logging.ComponentHTTP)
@@ -62,16 +63,18 @@ func InfoHandler(provider InfoProvider) http.HandlerFunc {
 	}
 }
 
-// RegisterHandlers registers the standard scanner endpoints (/health, /info, and /api/config) on the provided mux.
+// RegisterHandlers registers the standard scanner endpoints (/health, /info, and /api/config) on the registry.
 // If db is non-nil, /health performs a database liveness check and returns 503 if the DB is stuck.
-func RegisterHandlers(mux *http.ServeMux, provider AppInfoProvider, db *database.DB) {
+func RegisterHandlers(reg *routes.Registry, provider AppInfoProvider, db *database.DB) {
+	health := http.HandlerFunc(HealthHandler)
 	if db != nil {
-		mux.HandleFunc("/health", HealthHandlerWithDB(db))
-	} else {
-		mux.HandleFunc("/health", HealthHandler)
+		health = HealthHandlerWithDB(db)
 	}
-	mux.HandleFunc("/info", InfoHandler(provider))
-	mux.HandleFunc("/api/config", ConfigHandler(provider))
+	reg.Handle(
+		routes.Route{Pattern: "/health", Handler: health, Role: routes.RolePublic, CacheControl: routes.NoStore},
+		routes.Route{Pattern: "/info", Methods: routes.GET, Handler: InfoHandler(provider)},
+		routes.Route{Pattern: "/api/config", Methods: routes.GET, Handler: ConfigHandler(provider)},
+	)
 }
 
 // RegisterDefaultHandlers registers the standard scanner endpoints (/health and /info) on the default mux
@@ -5,6 +5,9 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+
+	"github.com/bvboe/b2s-go/scanner-core/config"
+	"github.com/bvboe/b2s-go/scanner-core/routes"
 )
 
 type testInfoProvider struct {
@@ -92,7 +95,7 @@ func TestRegisterHandlers(t *testing.T) {
 		Version:   "1.0.0",
 	}
 
-	mux := http.NewServeMux()
+	mux := routes.NewRegistry()
 	RegisterHandlers(mux, provider, nil)
 
 	// Test health endpoint
@@ -126,3 +129,59 @@ func TestRegisterHandlers(t *testing.T) {
 		t.Errorf("Info endpoint: expected component %q, got %q", "test-component", response["component"])
 	}
 }
+
+type testEffectiveConfigProvider struct {
+	testInfoProvider
+	cfg *config.Config
+}
+
+func (t *testEffectiveConfigProvider) GetEffectiveConfig() *config.Config {
+	return t.cfg
+}
+
+func TestConfigHandler(t *testing.T) {
+	get := func(provider ConfigProvider) map[string]json.RawMessage {
+		t.Helper()
+		w := httptest.NewRecorder()
+		ConfigHandler(provider)(w, httptest.NewRequest(http.MethodGet, "/api/config", nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status code %d, got %d", http.StatusOK, w.Code)
+		}
+		var response map[string]json.RawMessage
+		if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+			t.Fatalf("Failed to decode JSON response: %v", err)
+		}
+		return response
+	}
+
+	// Providers without the loaded configuration keep the UI fields only
+	if response := get(&testInfoProvider{Version: "1.0.0"}); response["settings"] != nil {
+		t.Errorf("Expected no settings, got %s", response["settings"])
+	}
+
+	t.Setenv("TRANSFER_SIGNING_KEY", "do-not-leak")
+	cfg, err := config.LoadConfig("")
+	if err != nil {
+		t.Fatalf("LoadConfig() error = %v", err)
+	}
+	response := get(&testEffectiveConfigProvider{testInfoProvider: testInfoProvider{Version: "1.0.0"}, cfg: cfg})
+	if string(response["clusterName"]) != `"test-cluster"` {
+		t.Errorf("clusterName = %s", response["clusterName"])
+	}
+	var settings []config.Setting
+	if err := json.Unmarshal(response["settings"], &settings); err != nil {
+		t.Fatalf("Failed to decode settings: %v", err)
+	}
+	found := false
+	for _, s := range settings {
+		if s.Name == "transfer_signing_key" {
+			found = true
+			if s.Value != "[redacted]" || s.Source != config.SourceEnv || s.Env != "TRANSFER_SIGNING_KEY" {
+				t.Errorf("unexpected setting %+v", s)
+			}
+		}
+	}
+	if !found {
+		t.Error("transfer_signing_key missing from settings")
+	}
+}
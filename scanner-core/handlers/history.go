@@ -0,0 +1,123 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/bvboe/b2s-go/scanner-core/database"
+)
+
+// defaultTrendLookback is how far back /api/summary/trends looks by default
+const defaultTrendLookback = 12 * 7 * 24 * time.Hour
+
+// ScanHistoryProvider provides per-scan vulnerability snapshots
+// (implemented by database.DB)
+type ScanHistoryProvider interface {
+	GetImageScanHistory(digest string, since time.Time) ([]database.ScanSnapshot, error)
+	GetScanTrends(since time.Time, interval string, namespaces []string) ([]database.TrendPoint, error)
+}
+
+// parseLookback reads the ?lookback= duration, defaulting to def
+func parseLookback(r *http.Request, def time.Duration) (time.Duration, bool) {
+	v := r.URL.Query().Get("lookback")
+	if v == "" {
+		return def, true
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil || d < 0 {
+		return 0, false
+	}
+	return d, true
+}
+
+// ImageScanHistoryHandler creates an HTTP handler for /api/images/{digest}/history.
+// Returns the severity counts and risk of the image at each scan, oldest
+// first; ?lookback=720h limits it to recent scans.
+func ImageScanHistoryHandler(provider ScanHistoryProvider) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		digest := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/api/images/"), "/history")
+		if digest == "" {
+			http.Error(w, "Digest required", http.StatusBadRequest)
+			return
+		}
+		lookback, ok := parseLookback(r, 0)
+		if !ok {
+			http.Error(w, "Invalid lookback duration", http.StatusBadRequest)
+			return
+		}
+		var since time.Time
+		if lookback > 0 {
+			since = time.Now().Add(-lookback)
+		}
+
+		history, err := provider.GetImageScanHistory(digest, since)
+		if err != nil {
+			log.Error("error querying image scan history", "digest", digest, "error", err)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		if history == nil {
+			http.Error(w, "Image not found", http.StatusNotFound)
+			return
+		}
+
+		response := struct {
+			Digest string                  `json:"digest"`
+			Scans  []database.ScanSnapshot `json:"scans"`
+		}{digest, history}
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(response); err != nil {
+			log.Error("error encoding image scan history", "error", err)
+		}
+	}
+}
+
+// ScanTrendsHandler creates an HTTP handler for /api/summary/trends endpoint
+// Returns the combined severity counts and risk of the running images per
+// ?interval= (week by default, or day) over the ?lookback= window (12 weeks by
+// default), optionally only for images running in ?namespaces=.
+func ScanTrendsHandler(provider ScanHistoryProvider) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		params := r.URL.Query()
+		interval := params.Get("interval")
+		if interval == "" {
+			interval = database.TrendIntervalWeek
+		}
+		if interval != database.TrendIntervalDay && interval != database.TrendIntervalWeek {
+			http.Error(w, "Invalid interval: "+interval, http.StatusBadRequest)
+			return
+		}
+		lookback, ok := parseLookback(r, defaultTrendLookback)
+		if !ok {
+			http.Error(w, "Invalid lookback duration", http.StatusBadRequest)
+			return
+		}
+
+		trends, err := provider.GetScanTrends(time.Now().Add(-lookback), interval, parseMultiSelect(params.Get("namespaces")))
+		if err != nil {
+			log.Error("error querying scan trends", "error", err)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+
+		response := struct {
+			Interval string                `json:"interval"`
+			Lookback string                `json:"lookback"`
+			Trends   []database.TrendPoint `json:"trends"`
+		}{interval, lookback.String(), trends}
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(response); err != nil {
+			log.Error("error encoding scan trends", "error", err)
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/bvboe/b2s-go/scanner-core/database"
+)
+
+// mockScanHistoryProvider serves fixed snapshots and records trend queries
+type mockScanHistoryProvider struct {
+	history    map[string][]database.ScanSnapshot
+	since      time.Time
+	interval   string
+	namespaces []string
+}
+
+func (m *mockScanHistoryProvider) GetImageScanHistory(digest string, since time.Time) ([]database.ScanSnapshot, error) {
+	m.since = since
+	return m.history[digest], nil
+}
+
+func (m *mockScanHistoryProvider) GetScanTrends(since time.Time, interval string, namespaces []string) ([]database.TrendPoint, error) {
+	m.since, m.interval, m.namespaces = since, interval, namespaces
+	return []database.TrendPoint{{Period: "2026-10-12", Images: 2, Critical: 1}}, nil
+}
+
+func TestImageScanHistoryHandler(t *testing.T) {
+	provider := &mockScanHistoryProvider{history: map[string][]database.ScanSnapshot{
+		"sha256:app": {{ScannedAt: "2026-10-01T00:00:00Z", Critical: 2}, {ScannedAt: "2026-10-08T00:00:00Z"}},
+	}}
+	handler := ImageScanHistoryHandler(provider)
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/images/sha256:app/history?lookback=720h", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200", rec.Code)
+	}
+	var response struct {
+		Digest string                  `json:"digest"`
+		Scans  []database.ScanSnapshot `json:"scans"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&response); err != nil {
+		t.Fatalf("invalid response: %v", err)
+	}
+	if response.Digest != "sha256:app" || len(response.Scans) != 2 || response.Scans[0].Critical != 2 {
+		t.Errorf("unexpected response %+v", response)
+	}
+	if ago := time.Since(provider.since); ago < 719*time.Hour || ago > 721*time.Hour {
+		t.Errorf("since = %v, want 720h ago", provider.since)
+	}
+
+	for path, want := range map[string]int{
+		"/api/images/sha256:unknown/history":          http.StatusNotFound,
+		"/api/images/sha256:app/history?lookback=bad": http.StatusBadRequest,
+	} {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		if rec.Code != want {
+			t.Errorf("GET %s status = %d, want %d", path, rec.Code, want)
+		}
+	}
+}
+
+func TestScanTrendsHandler(t *testing.T) {
+	provider := &mockScanHistoryProvider{}
+	handler := ScanTrendsHandler(provider)
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/summary/trends?namespaces=team-a,team-b", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200", rec.Code)
+	}
+	var response struct {
+		Interval string                `json:"interval"`
+		Lookback string                `json:"lookback"`
+		Trends   []database.TrendPoint `json:"trends"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&response); err != nil {
+		t.Fatalf("invalid response: %v", err)
+	}
+	if response.Interval != database.TrendIntervalWeek || response.Lookback != "2016h0m0s" ||
+		len(response.Trends) != 1 || response.Trends[0].Images != 2 {
+		t.Errorf("unexpected response %+v", response)
+	}
+	if provider.interval != database.TrendIntervalWeek || !reflect.DeepEqual(provider.namespaces, []string{"team-a", "team-b"}) {
+		t.Errorf("trends queried with interval %q, namespaces %v", provider.interval, provider.namespaces)
+	}
+
+	for path, want := range map[string]int{
+		"/api/summary/trends?interval=day&lookback=168h": http.StatusOK,
+		"/api/summary/trends?interval=month":             http.StatusBadRequest,
+		"/api/summary/trends?lookback=-1h":               http.StatusBadRequest,
+	} {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		if rec.Code != want {
+			t.Errorf("GET %s status = %d, want %d", path, rec.Code, want)
+		}
+	}
+}
@@ -0,0 +1,217 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/bvboe/b2s-go/scanner-core/database"
+	"github.com/bvboe/b2s-go/scanner-core/routes"
+)
+
+// IgnoreStore stores accepted-risk vulnerability ignores and their audit trail
+type IgnoreStore interface {
+	GetVulnerabilityIgnores() ([]database.VulnerabilityIgnore, error)
+	SaveVulnerabilityIgnore(ig database.VulnerabilityIgnore, actor string) (*database.VulnerabilityIgnore, int, error)
+	DeleteVulnerabilityIgnore(id int64, actor string) (bool, int, error)
+	GetVulnerabilityIgnoreEvents(ignoreID int64) ([]database.VulnerabilityIgnoreEvent, error)
+}
+
+// maxIgnoreRequestSize bounds the body of an ignore request
+const maxIgnoreRequestSize = 64 << 10
+
+// RegisterIgnoreHandlers registers the vulnerability ignore endpoints
+func RegisterIgnoreHandlers(reg *routes.Registry, store IgnoreStore) {
+	reg.Handle(
+		routes.Route{Pattern: "/api/ignores", Methods: routes.GET, Handler: IgnoreListHandler(store)},
+		routes.Route{Pattern: "/api/ignores/save", Methods: routes.POST, Handler: IgnoreSaveHandler(store), Role: routes.RoleAdmin},
+		routes.Route{Pattern: "/api/ignores/delete", Methods: routes.POST, Handler: IgnoreDeleteHandler(store), Role: routes.RoleAdmin},
+		routes.Route{Pattern: "/api/ignores/audit", Methods: routes.GET, Handler: IgnoreAuditHandler(store)},
+	)
+}
+
+// IgnoreListHandler creates an HTTP handler for GET /api/ignores. Returns all
+// ignores with whether they are active and how many findings they currently
+// suppress; expired ignores stay listed until deleted.
+func IgnoreListHandler(store IgnoreStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		ignores, err := store.GetVulnerabilityIgnores()
+		if err != nil {
+			log.Error("error querying vulnerability ignores", "error", err)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(map[string]interface{}{
+			"ignores": ignores,
+			"count":   len(ignores),
+		}); err != nil {
+			log.Error("error encoding vulnerability ignores", "error", err)
+		}
+	}
+}
+
+// IgnoreSaveRequest is the body of POST /api/ignores/save
+type IgnoreSaveRequest struct {
+	ID          int64  `json:"id"` // 0 creates an ignore
+	CVEID       string `json:"cve_id"`
+	PackageName string `json:"package_name"`
+	Image       string `json:"image"`
+	Reason      string `json:"reason"`
+	Owner       string `json:"owner"`
+	ExpiresAt   string `json:"expires_at"` // date or RFC 3339 time; empty for no expiry
+	Actor       string `json:"actor"`      // recorded in the audit trail; defaults to owner, ignored for authenticated callers
+}
+
+// IgnoreSaveHandler creates an HTTP handler for POST /api/ignores/save.
+// Creates or replaces an ignore accepting the risk of a vulnerability,
+// optionally only in one package (name or purl) and/or image (digest,
+// reference or repository). Covered findings are left out of the API,
+// summaries and metrics at once, listed at /api/vex/suppressions with status
+// accepted_risk, and reappear when the ignore expires.
+//
+// Request: {"cve_id": "CVE-2024-1234", "package_name": "openssl", "reason": "...", "owner": "platform", "expires_at": "2025-06-30"}
+// Response: {"ignore": {...}, "images_reevaluated": 3}
+func IgnoreSaveHandler(store IgnoreStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		var req IgnoreSaveRequest
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxIgnoreRequestSize)).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		if id := IdentityFromRequest(r); id != nil {
+			req.Actor = id.Subject
+		}
+
+		ignore, images, err := store.SaveVulnerabilityIgnore(database.VulnerabilityIgnore{
+			ID:          req.ID,
+			CVEID:       req.CVEID,
+			PackageName: req.PackageName,
+			Image:       req.Image,
+			Reason:      req.Reason,
+			Owner:       req.Owner,
+			ExpiresAt:   req.ExpiresAt,
+		}, req.Actor)
+		if errors.Is(err, database.ErrInvalidIgnore) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err != nil {
+			log.Error("error saving vulnerability ignore", "cve", req.CVEID, "error", err)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		if ignore == nil {
+			http.Error(w, "Ignore not found", http.StatusNotFound)
+			return
+		}
+		log.Info("saved vulnerability ignore", "id", ignore.ID, "cve", ignore.CVEID, "owner", ignore.Owner,
+			"expires_at", ignore.ExpiresAt, "images_reevaluated", images)
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(map[string]interface{}{
+			"ignore":             ignore,
+			"images_reevaluated": images,
+		}); err != nil {
+			log.Error("error encoding vulnerability ignore", "error", err)
+		}
+	}
+}
+
+// IgnoreDeleteRequest is the body of POST /api/ignores/delete
+type IgnoreDeleteRequest struct {
+	ID    int64  `json:"id"`
+	Actor string `json:"actor"`
+}
+
+// IgnoreDeleteHandler creates an HTTP handler for POST /api/ignores/delete.
+// Removes an ignore; the findings it suppressed are restored.
+//
+// Request: {"id": 3, "actor": "alice"}
+// Response: {"deleted": true, "images_reevaluated": 3}
+func IgnoreDeleteHandler(store IgnoreStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		var req IgnoreDeleteRequest
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 4096)).Decode(&req); err != nil || req.ID <= 0 {
+			http.Error(w, "id is required", http.StatusBadRequest)
+			return
+		}
+		if id := IdentityFromRequest(r); id != nil {
+			req.Actor = id.Subject
+		}
+
+		deleted, images, err := store.DeleteVulnerabilityIgnore(req.ID, req.Actor)
+		if err != nil {
+			log.Error("error deleting vulnerability ignore", "id", req.ID, "error", err)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		if !deleted {
+			http.Error(w, "Ignore not found", http.StatusNotFound)
+			return
+		}
+		log.Info("deleted vulnerability ignore", "id", req.ID, "actor", req.Actor, "images_reevaluated", images)
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(map[string]interface{}{
+			"deleted":            true,
+			"images_reevaluated": images,
+		}); err != nil {
+			log.Error("error encoding vulnerability ignore response", "error", err)
+		}
+	}
+}
+
+// IgnoreAuditHandler creates an HTTP handler for GET /api/ignores/audit: who
+// created, changed or deleted ignores and when they expired, oldest first.
+// ?id= limits it to one ignore.
+func IgnoreAuditHandler(store IgnoreStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		var id int64
+		if v := r.URL.Query().Get("id"); v != "" {
+			parsed, err := strconv.ParseInt(v, 10, 64)
+			if err != nil || parsed <= 0 {
+				http.Error(w, "id must be a positive integer", http.StatusBadRequest)
+				return
+			}
+			id = parsed
+		}
+
+		events, err := store.GetVulnerabilityIgnoreEvents(id)
+		if err != nil {
+			log.Error("error querying vulnerability ignore events", "error", err)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(map[string]interface{}{
+			"events": events,
+			"count":  len(events),
+		}); err != nil {
+			log.Error("error encoding vulnerability ignore events", "error", err)
+		}
+	}
+}
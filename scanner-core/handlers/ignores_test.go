@@ -0,0 +1,119 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bvboe/b2s-go/scanner-core/containers"
+	"github.com/bvboe/b2s-go/scanner-core/routes"
+)
+
+func TestIgnoreHandlers(t *testing.T) {
+	db := createTransferTestDB(t, "ignores")
+	if _, err := db.AddContainer(containers.Container{
+		ID:    containers.ContainerID{Namespace: "team-a", Pod: "web", Name: "app"},
+		Image: containers.ImageID{Reference: "registry.example.com/web:1", Digest: "sha256:web"},
+	}); err != nil {
+		t.Fatalf("AddContainer() error = %v", err)
+	}
+	vulns := []byte(`{"matches":[
+		{"vulnerability":{"id":"CVE-2024-3094","severity":"Critical"},"artifact":{"name":"xz-utils","version":"5.6.0","type":"deb"}},
+		{"vulnerability":{"id":"CVE-2023-0001","severity":"Medium"},"artifact":{"name":"openssl","version":"1.1.1","type":"deb"}}]}`)
+	if err := db.ImportScanResults("sha256:web", []byte(`{"artifacts":[]}`), vulns, time.Time{}); err != nil {
+		t.Fatalf("ImportScanResults() error = %v", err)
+	}
+
+	mux := routes.NewRegistry()
+	RegisterDatabaseHandlers(mux, db, nil)
+	RegisterIgnoreHandlers(mux, db)
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(method, path, strings.NewReader(body)))
+		return rec
+	}
+	vulnerabilities := func() int {
+		t.Helper()
+		var list struct {
+			Vulnerabilities []map[string]interface{} `json:"vulnerabilities"`
+		}
+		if err := json.NewDecoder(do(http.MethodGet, "/api/vulnerabilities", "").Body).Decode(&list); err != nil {
+			t.Fatalf("failed to decode vulnerabilities: %v", err)
+		}
+		return len(list.Vulnerabilities)
+	}
+
+	for _, body := range []string{
+		`{"cve_id": "CVE-2024-3094", "owner": "platform"}`,
+		`{"cve_id": "CVE-2024-3094", "reason": "r", "owner": "platform", "expires_at": "soon"}`,
+		`not json`,
+	} {
+		if rec := do(http.MethodPost, "/api/ignores/save", body); rec.Code != http.StatusBadRequest {
+			t.Errorf("save %s: status = %d, want 400", body, rec.Code)
+		}
+	}
+	if rec := do(http.MethodPost, "/api/ignores/save", `{"id": 42, "cve_id": "CVE-1", "reason": "r", "owner": "o"}`); rec.Code != http.StatusNotFound {
+		t.Errorf("updating a missing ignore: status = %d, want 404", rec.Code)
+	}
+
+	rec := do(http.MethodPost, "/api/ignores/save", `{"cve_id": "CVE-2024-3094", "package_name": "xz-utils",
+		"reason": "mitigated by seccomp", "owner": "platform", "expires_at": "2999-01-01", "actor": "alice"}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("save: status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	var saved struct {
+		Ignore struct {
+			ID         int64 `json:"id"`
+			Active     bool  `json:"active"`
+			Suppressed int   `json:"suppressed"`
+		} `json:"ignore"`
+		Images int `json:"images_reevaluated"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&saved); err != nil {
+		t.Fatalf("failed to decode save response: %v", err)
+	}
+	if !saved.Ignore.Active || saved.Ignore.Suppressed != 1 || saved.Images != 1 {
+		t.Errorf("save response = %+v", saved)
+	}
+	if n := vulnerabilities(); n != 1 {
+		t.Errorf("vulnerabilities with the ignore = %d, want 1", n)
+	}
+
+	var list struct {
+		Count int `json:"count"`
+	}
+	if err := json.NewDecoder(do(http.MethodGet, "/api/ignores", "").Body).Decode(&list); err != nil || list.Count != 1 {
+		t.Errorf("GET /api/ignores count = %d, %v, want 1", list.Count, err)
+	}
+
+	if rec := do(http.MethodPost, "/api/ignores/delete", `{"id": 99}`); rec.Code != http.StatusNotFound {
+		t.Errorf("delete of a missing ignore: status = %d, want 404", rec.Code)
+	}
+	if rec := do(http.MethodPost, "/api/ignores/delete", `{"id": 1, "actor": "bob"}`); rec.Code != http.StatusOK {
+		t.Fatalf("delete: status = %d", rec.Code)
+	}
+	if n := vulnerabilities(); n != 2 {
+		t.Errorf("vulnerabilities after deleting the ignore = %d, want 2", n)
+	}
+
+	if rec := do(http.MethodGet, "/api/ignores/audit?id=x", ""); rec.Code != http.StatusBadRequest {
+		t.Errorf("audit with an invalid id: status = %d, want 400", rec.Code)
+	}
+	var audit struct {
+		Events []struct {
+			Action string `json:"action"`
+			Actor  string `json:"actor"`
+		} `json:"events"`
+	}
+	if err := json.NewDecoder(do(http.MethodGet, "/api/ignores/audit?id=1", "").Body).Decode(&audit); err != nil {
+		t.Fatalf("failed to decode audit: %v", err)
+	}
+	if len(audit.Events) != 2 || audit.Events[0].Action != "created" || audit.Events[0].Actor != "alice" ||
+		audit.Events[1].Action != "deleted" || audit.Events[1].Actor != "bob" {
+		t.Errorf("audit = %+v", audit.Events)
+	}
+}
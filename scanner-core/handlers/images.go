@@ -1,21 +1,29 @@
 package handlers
 
 import (
-	"encoding/csv"
+	"context"
 	"encoding/json"
 	"fmt"
 	"math"
 	"net/http"
+	"net/url"
+	"sort"
 	"strconv"
 	"strings"
+	"time"
 
+	"github.com/bvboe/b2s-go/scanner-core/columns"
+	"github.com/bvboe/b2s-go/scanner-core/containers"
 	"github.com/bvboe/b2s-go/scanner-core/database"
+	"github.com/bvboe/b2s-go/scanner-core/fixhints"
 )
 
 
 // ImageQueryProvider defines the interface for executing image queries
 type ImageQueryProvider interface {
 	ExecuteReadOnlyQuery(query string) (*database.QueryResult, error)
+	// ExecuteReadOnlyQueryArgs runs query with args bound to its placeholders
+	ExecuteReadOnlyQueryArgs(query string, args ...interface{}) (*database.QueryResult, error)
 	GetSBOM(digest string) ([]byte, error)
 	GetVulnerabilities(digest string) ([]byte, error)
 }
@@ -37,10 +45,14 @@ func FilterOptionsHandler(provider FilterOptionsProvider) http.HandlerFunc {
 		}
 
 		response := map[string][]string{
-			"namespaces":   opts.Namespaces,
-			"osNames":      opts.OSNames,
-			"vulnStatuses": opts.VulnStatuses,
-			"packageTypes": opts.PackageTypes,
+			"namespaces":      opts.Namespaces,
+			"osNames":         opts.OSNames,
+			"vulnStatuses":    opts.VulnStatuses,
+			"packageTypes":    opts.PackageTypes,
+			"registries":      opts.Registries,
+			"applications":    opts.Applications,
+			"owners":          opts.Owners,
+			"classifications": opts.Classifications,
 		}
 
 		w.Header().Set("Content-Type", "application/json")
@@ -50,8 +62,12 @@ func FilterOptionsHandler(provider FilterOptionsProvider) http.HandlerFunc {
 	}
 }
 
-// ImagesHandler creates an HTTP handler for /api/images endpoint
-func ImagesHandler(provider ImageQueryProvider) http.HandlerFunc {
+// ImagesHandler creates an HTTP handler for /api/images endpoint.
+// ?search= matches image references, digest prefixes, pod names and
+// namespaces; each result then reports what matched in match_type.
+// With a non-nil lifecycle, each image is annotated with the end-of-life status of its OS.
+// Computed columns are added to each image and can be sorted on.
+func ImagesHandler(provider ImageQueryProvider, lifecycle OSLifecycle, computed []columns.Column) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		// Parse query parameters
 		params := r.URL.Query()
@@ -84,6 +100,13 @@ func ImagesHandler(provider ImageQueryProvider) http.HandlerFunc {
 		vulnStatuses := parseMultiSelect(params.Get("vulnStatuses"))
 		packageTypes := parseMultiSelect(params.Get("packageTypes"))
 		osNames := parseMultiSelect(params.Get("osNames"))
+		registries := parseMultiSelect(params.Get("registries"))
+		slsaLevels := parseSLSALevels(params.Get("slsaLevels"))
+		builders := parseMultiSelect(params.Get("builders"))
+		sources := parseMultiSelect(params.Get("sources"))
+		vendors := parseMultiSelect(params.Get("vendors"))
+		size := parseImageSizeFilter(params)
+		cmdb := parseCMDBFilter(params)
 
 		// Sorting
 		sortBy := params.Get("sortBy")
@@ -93,10 +116,10 @@ func ImagesHandler(provider ImageQueryProvider) http.HandlerFunc {
 		}
 
 		// Build query
-		query, countQuery := buildImagesQuery(search, namespaces, vulnStatuses, packageTypes, osNames, sortBy, sortOrder, pageSize, offset)
+		query, countQuery, args := buildImagesQuery(search, namespaces, vulnStatuses, packageTypes, osNames, registries, slsaLevels, builders, sources, vendors, size, cmdb, sortBy, sortOrder, pageSize, offset, includeDeletedParam(r), computed)
 
 		// Execute count query for pagination
-		countResult, err := provider.ExecuteReadOnlyQuery(countQuery)
+		countResult, err := provider.ExecuteReadOnlyQueryArgs(countQuery, args...)
 		if err != nil {
 			log.Error("error executing count query", "error", err)
 			http.Error(w, "Internal server error", http.StatusInternalServerError)
@@ -111,16 +134,17 @@ func ImagesHandler(provider ImageQueryProvider) http.HandlerFunc {
 		}
 
 		// Execute main query
-		result, err := provider.ExecuteReadOnlyQuery(query)
+		result, err := provider.ExecuteReadOnlyQueryArgs(query, args...)
 		if err != nil {
 			log.Error("error executing images query", "error", err)
 			http.Error(w, "Internal server error", http.StatusInternalServerError)
 			return
 		}
+		annotateOSLifecycle(result, lifecycle, time.Now())
 
 		// Handle CSV export
 		if format == "csv" {
-			exportQueryResultAsCSV(w, result, "images.csv")
+			exportQueryResultAsCSV(w, r, result, "images.csv")
 			return
 		}
 
@@ -156,12 +180,154 @@ func parseMultiSelect(value string) []string {
 	return result
 }
 
-// buildImagesQuery constructs the SQL query with filters
-func buildImagesQuery(search string, namespaces, vulnStatuses, packageTypes, osNames []string, sortBy, sortOrder string, limit, offset int) (string, string) {
-	// Base query
-	baseQuery := `
+// parseSLSALevels parses the ?slsaLevels= filter, keeping valid SLSA build
+// levels (0 = checked, no provenance)
+func parseSLSALevels(value string) []string {
+	var levels []string
+	for _, level := range parseMultiSelect(value) {
+		if n, err := strconv.Atoi(level); err == nil && n >= 0 && n <= 3 {
+			levels = append(levels, strconv.Itoa(n))
+		}
+	}
+	return levels
+}
+
+// imageSizeFilter limits images to a size and layer count range; zero bounds
+// are not applied. Images whose size is unknown match no bound.
+type imageSizeFilter struct {
+	minBytes, maxBytes   int64
+	minLayers, maxLayers int
+}
+
+// parseImageSizeFilter parses the ?minSizeMB=, ?maxSizeMB=, ?minLayers= and
+// ?maxLayers= filters, ignoring invalid values
+func parseImageSizeFilter(params url.Values) imageSizeFilter {
+	var f imageSizeFilter
+	megabytes := func(name string) int64 {
+		mb, err := strconv.ParseFloat(params.Get(name), 64)
+		if err != nil || mb <= 0 {
+			return 0
+		}
+		return int64(mb * 1024 * 1024)
+	}
+	layers := func(name string) int {
+		n, err := strconv.Atoi(params.Get(name))
+		if err != nil || n <= 0 {
+			return 0
+		}
+		return n
+	}
+	f.minBytes, f.maxBytes = megabytes("minSizeMB"), megabytes("maxSizeMB")
+	f.minLayers, f.maxLayers = layers("minLayers"), layers("maxLayers")
+	return f
+}
+
+// appendConditions adds the bounds of the filter to conditions
+func (f imageSizeFilter) appendConditions(conditions []string, args *queryArgs) []string {
+	if f.minBytes > 0 {
+		conditions = append(conditions, "images.image_size >= "+args.bind(f.minBytes))
+	}
+	if f.maxBytes > 0 {
+		conditions = append(conditions, "images.image_size <= "+args.bind(f.maxBytes))
+	}
+	if f.minLayers > 0 {
+		conditions = append(conditions, "images.layer_count >= "+args.bind(f.minLayers))
+	}
+	if f.maxLayers > 0 {
+		conditions = append(conditions, "images.layer_count <= "+args.bind(f.maxLayers))
+	}
+	return conditions
+}
+
+// cmdbFilter limits containers to the CMDB attribute values of their
+// workload (see database.CMDBEntry). An attribute set by an entry for one of
+// the image's labels takes precedence over the one set for the namespace.
+type cmdbFilter struct {
+	applications, owners, classifications []string
+}
+
+// parseCMDBFilter parses the ?applications=, ?owners= and ?classifications=
+// filters
+func parseCMDBFilter(params url.Values) cmdbFilter {
+	return cmdbFilter{
+		applications:    parseMultiSelect(params.Get("applications")),
+		owners:          parseMultiSelect(params.Get("owners")),
+		classifications: parseMultiSelect(params.Get("classifications")),
+	}
+}
+
+// appendConditions adds a condition per filtered attribute to conditions
+func (f cmdbFilter) appendConditions(conditions []string, args *queryArgs) []string {
+	attributes := []struct {
+		column string
+		values []string
+	}{
+		{database.CMDBApplicationID, f.applications},
+		{database.CMDBOwner, f.owners},
+		{database.CMDBDataClassification, f.classifications},
+	}
+	var labelMatch string
+	for _, attr := range attributes {
+		if len(attr.values) == 0 {
+			continue
+		}
+		if labelMatch == "" {
+			labelMatch = cmdbLabelMatch(args)
+		}
+		in := args.in("m."+attr.column, attr.values)
+		conditions = append(conditions, fmt.Sprintf(`(
+      EXISTS (SELECT 1 FROM cmdb_metadata m WHERE %[1]s AND %[2]s)
+      OR (NOT EXISTS (SELECT 1 FROM cmdb_metadata m WHERE m.%[3]s != '' AND %[2]s)
+          AND EXISTS (SELECT 1 FROM cmdb_metadata m WHERE %[1]s AND m.namespace = instances.namespace)))`,
+			in, labelMatch, attr.column))
+	}
+	return conditions
+}
+
+// cmdbLabelMatch returns a condition matching CMDB entries (aliased m) keyed
+// by one of the image's labels
+func cmdbLabelMatch(args *queryArgs) string {
+	labels := make([]string, 0, len(database.CMDBLabelColumns))
+	for label := range database.CMDBLabelColumns {
+		labels = append(labels, label)
+	}
+	sort.Strings(labels)
+	matches := make([]string, len(labels))
+	for i, label := range labels {
+		matches[i] = fmt.Sprintf("(m.label = %s AND m.label_value = images.%s)",
+			args.bind(label), database.CMDBLabelColumns[label])
+	}
+	return "(" + strings.Join(matches, " OR ") + ")"
+}
+
+// includeDeletedParam reports whether a request asks for soft-deleted images
+// (?includeDeleted=true)
+func includeDeletedParam(r *http.Request) bool {
+	return r.URL.Query().Get("includeDeleted") == "true"
+}
+
+// buildImagesQuery constructs the SQL query with filters and the arguments
+// bound to its placeholders, shared by the query and the count query. With
+// includeDeleted, soft-deleted images (which no longer have containers) are
+// listed under the last reference they were seen with. slsaLevels and builders
+// filter on the build provenance read from the registry; images whose
+// provenance has not been checked match neither. sources and vendors filter
+// on the OCI labels (org.opencontainers.image.source and .vendor) and size on
+// the image size and layer count, both read from the SBOM.
+func buildImagesQuery(search string, namespaces, vulnStatuses, packageTypes, osNames, registries, slsaLevels, builders, sources, vendors []string, size imageSizeFilter, cmdb cmdbFilter, sortBy, sortOrder string, limit, offset int, includeDeleted bool, computed []columns.Column) (string, string, []interface{}) {
+	var args queryArgs
+
+	imagesJoin := `
   FROM containers instances
-  JOIN images images ON instances.image_id = images.id
+  JOIN images images ON instances.image_id = images.id`
+	if includeDeleted {
+		imagesJoin = `
+  FROM images images
+  LEFT JOIN containers instances ON instances.image_id = images.id`
+	}
+
+	// Base query
+	baseQuery := imagesJoin + `
   JOIN scan_status status ON images.status = status.status
   LEFT JOIN (
       SELECT image_id, COALESCE(SUM(number_of_instances), 0) as package_count
@@ -187,45 +353,74 @@ func buildImagesQuery(search string, namespaces, vulnStatuses, packageTypes, osN
       GROUP BY image_id
   ) vuln_counts ON images.id = vuln_counts.image_id
   WHERE 1=1`
+	if includeDeleted {
+		baseQuery += " AND (instances.id IS NOT NULL OR images.deleted_at IS NOT NULL)"
+	}
 
 	// Build subquery filters using helper functions
-	packageTypeFilter := buildPackageTypeFilter(packageTypes)
-	vulnStatusFilter := buildVulnerabilityFilter(vulnStatuses, packageTypes)
+	packageTypeFilter := args.packageTypeFilter(packageTypes)
+	vulnStatusFilter := args.vulnerabilityFilter(vulnStatuses, packageTypes)
 
 	baseQuery = fmt.Sprintf(baseQuery, packageTypeFilter, vulnStatusFilter)
 
 	// Build WHERE conditions
 	var conditions []string
 
-	// Search filter (image name)
-	conditions = appendCondition(conditions, buildLikeCondition("instances.reference", search))
+	// Search filter (image name, digest prefix, pod or namespace)
+	searchCondition, matchType := buildImageSearch(&args, search)
+	conditions = appendCondition(conditions, searchCondition)
 
 	// Namespace filter
-	conditions = appendCondition(conditions, buildINClause("instances.namespace", namespaces))
+	conditions = appendCondition(conditions, args.in("instances.namespace", namespaces))
 
 	// Note: Vulnerability fix status filter is now applied in the vulnerabilities subquery
 
 	// OS name filter
-	conditions = appendCondition(conditions, buildINClause("images.os_name", osNames))
+	conditions = appendCondition(conditions, args.in("images.os_name", osNames))
+
+	// Registry filter (host the image is pulled from, e.g. docker.io)
+	conditions = appendCondition(conditions, args.in("instances.registry", registries))
+
+	// Provenance filters (SLSA build level, builder identity)
+	conditions = appendCondition(conditions, args.in("images.provenance_slsa_level", slsaLevels))
+	conditions = appendCondition(conditions, args.in("images.provenance_builder", builders))
+
+	// OCI label filters (source repository, vendor)
+	conditions = appendCondition(conditions, args.in("images.oci_source", sources))
+	conditions = appendCondition(conditions, args.in("images.oci_vendor", vendors))
+
+	// Image size and layer count filters
+	conditions = size.appendConditions(conditions, &args)
+
+	// CMDB attribute filters (application, owner, data classification)
+	conditions = cmdb.appendConditions(conditions, &args)
 
 	// Add conditions to base query
 	whereClause := baseQuery + buildWhereClause(conditions)
 
 	// Group by
-	groupBy := " GROUP BY instances.reference, images.digest, images.os_name, status.status"
+	groupBy := " GROUP BY instances.reference, images.digest, images.os_name, images.os_version, status.status"
+
+	// Deleted images have no containers left, so they are named by their last reference
+	imageColumn := "instances.reference"
+	containerCount := "COUNT(*)"
+	if includeDeleted {
+		imageColumn = "COALESCE(instances.reference, images.last_reference, images.digest)"
+		containerCount = "COUNT(instances.id)"
+	}
 
 	// Build count query
 	countQuery := "SELECT COUNT(*) FROM (" +
-		"SELECT instances.reference as image" +
+		"SELECT " + imageColumn + " as image" +
 		whereClause +
 		groupBy +
 		") subquery"
 
 	// Build main query with sorting
 	selectClause := `SELECT
-      instances.reference as image,
+      ` + imageColumn + ` as image,
       images.digest,
-      COUNT(*) as container_count,
+      ` + containerCount + ` as container_count,
       COALESCE(vuln_counts.critical_count, 0) as critical_count,
       COALESCE(vuln_counts.high_count, 0) as high_count,
       COALESCE(vuln_counts.medium_count, 0) as medium_count,
@@ -238,7 +433,30 @@ func buildImagesQuery(search string, namespaces, vulnStatuses, packageTypes, osN
       COALESCE(vuln_counts.exploit_count, 0) as exploit_count,
       COALESCE(pkg_counts.package_count, 0) as package_count,
       status.description as status_description,
-      images.os_name`
+      images.os_name,
+      COALESCE(images.os_version, '') as os_version,
+      images.provenance_builder,
+      images.provenance_slsa_level as slsa_level,
+      images.sbom_source,
+      images.oci_source,
+      images.oci_version,
+      images.oci_vendor,
+      images.image_size,
+      images.layer_count,
+      images.sbom_duration_ms,
+      images.vuln_scan_duration_ms`
+	if includeDeleted {
+		selectClause += ",\n      images.deleted_at"
+	}
+	if matchType != "" {
+		selectClause += ",\n      " + matchType + " as match_type"
+	}
+	numeric := vulnCountColumns()
+	numeric["container_count"] = containerCount
+	numeric["image_size"] = "images.image_size"
+	numeric["layer_count"] = "images.layer_count"
+	computedSelect, computedSort := computedColumns(computed, numeric)
+	selectClause += computedSelect
 
 	mainQuery := selectClause + whereClause + groupBy
 
@@ -251,7 +469,11 @@ func buildImagesQuery(search string, namespaces, vulnStatuses, packageTypes, osN
 		"high_count": true, "medium_count": true, "low_count": true,
 		"negligible_count": true, "unknown_count": true, "total_risk": true,
 		"exploit_count": true, "package_count": true, "os_name": true,
-		"total_cves": true, "unique_cves": true,
+		"total_cves": true, "unique_cves": true, "slsa_level": true,
+		"image_size": true, "layer_count": true,
+	}
+	for name := range computedSort {
+		validSortColumns[name] = true
 	}
 
 	if sortBy != "" && validSortColumns[sortBy] {
@@ -269,11 +491,73 @@ func buildImagesQuery(search string, namespaces, vulnStatuses, packageTypes, osN
 		mainQuery += fmt.Sprintf(" LIMIT %d OFFSET %d", limit, offset)
 	}
 
-	return mainQuery, countQuery
+	return mainQuery, countQuery, args
 }
 
-// ContainersHandler creates an HTTP handler for /api/containers endpoint
-func ContainersHandler(provider ImageQueryProvider) http.HandlerFunc {
+// minDigestSearchLength is the number of hex digits a search term needs to
+// be matched as a digest prefix, so short words don't match random digests
+const minDigestSearchLength = 4
+
+// searchField is a search condition and the match type reported when it matches
+type searchField struct {
+	matchType string
+	condition string
+}
+
+// buildImageSearch builds the image search condition and a match_type column
+// expression telling which field matched: "image" (reference substring),
+// "digest" (digest prefix, with or without "sha256:" or a "repo@" prefix, as
+// pasted from a CI log), "pod" or "namespace". The search term is bound to
+// args. Returns empty strings for an empty search.
+func buildImageSearch(args *queryArgs, search string) (condition, matchType string) {
+	search = strings.TrimSpace(search)
+	if search == "" {
+		return "", ""
+	}
+
+	pattern := args.bind("%" + search + "%")
+	fields := []searchField{
+		{"image", "instances.reference LIKE " + pattern},
+	}
+	digest := strings.ToLower(search)
+	if at := strings.LastIndex(digest, "@"); at >= 0 {
+		digest = digest[at+1:]
+	}
+	if hex := strings.TrimPrefix(digest, "sha256:"); len(hex) >= minDigestSearchLength && isHex(hex) {
+		fields = append(fields, searchField{
+			"digest", "images.digest LIKE " + args.bind("sha256:"+hex+"%"),
+		})
+	}
+	fields = append(fields,
+		searchField{"pod", "instances.pod LIKE " + pattern},
+		searchField{"namespace", "instances.namespace LIKE " + pattern},
+	)
+
+	conditions := make([]string, len(fields))
+	cases := make([]string, len(fields))
+	for i, f := range fields {
+		conditions[i] = f.condition
+		cases[i] = fmt.Sprintf("WHEN MAX(%s) THEN '%s'", f.condition, f.matchType)
+	}
+	return "(" + strings.Join(conditions, " OR ") + ")", "CASE " + strings.Join(cases, " ") + " END"
+}
+
+// isHex reports whether s consists of lowercase hex digits only
+func isHex(s string) bool {
+	for _, c := range s {
+		if (c < '0' || c > '9') && (c < 'a' || c > 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+// ContainersHandler creates an HTTP handler for /api/containers endpoint.
+// CSV exports include the image reference, repository, tag, OS version and
+// when the container was first seen, so rows can be joined with other
+// systems; JSON responses include them with ?detail=full. Computed columns
+// using only per-container columns are added to each container.
+func ContainersHandler(provider ImageQueryProvider, computed []columns.Column) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		// Parse query parameters
 		params := r.URL.Query()
@@ -306,6 +590,7 @@ func ContainersHandler(provider ImageQueryProvider) http.HandlerFunc {
 		vulnStatuses := parseMultiSelect(params.Get("vulnStatuses"))
 		packageTypes := parseMultiSelect(params.Get("packageTypes"))
 		osNames := parseMultiSelect(params.Get("osNames"))
+		registries := parseMultiSelect(params.Get("registries"))
 
 		// Sorting
 		sortBy := params.Get("sortBy")
@@ -314,11 +599,14 @@ func ContainersHandler(provider ImageQueryProvider) http.HandlerFunc {
 			sortOrder = "ASC"
 		}
 
+		// Image identification columns (always exported to CSV)
+		detail := format == "csv" || params.Get("detail") == "full"
+
 		// Build query
-		query, countQuery := buildContainersQuery(search, namespaces, vulnStatuses, packageTypes, osNames, sortBy, sortOrder, pageSize, offset)
+		query, countQuery, args := buildContainersQuery(search, namespaces, vulnStatuses, packageTypes, osNames, registries, sortBy, sortOrder, pageSize, offset, detail, computed)
 
 		// Execute count query for pagination
-		countResult, err := provider.ExecuteReadOnlyQuery(countQuery)
+		countResult, err := provider.ExecuteReadOnlyQueryArgs(countQuery, args...)
 		if err != nil {
 			log.Error("error executing count query", "error", err)
 			http.Error(w, "Internal server error", http.StatusInternalServerError)
@@ -333,16 +621,19 @@ func ContainersHandler(provider ImageQueryProvider) http.HandlerFunc {
 		}
 
 		// Execute main query
-		result, err := provider.ExecuteReadOnlyQuery(query)
+		result, err := provider.ExecuteReadOnlyQueryArgs(query, args...)
 		if err != nil {
 			log.Error("error executing containers query", "error", err)
 			http.Error(w, "Internal server error", http.StatusInternalServerError)
 			return
 		}
+		if detail {
+			addRepositoryAndTag(result)
+		}
 
 		// Handle CSV export
 		if format == "csv" {
-			exportQueryResultAsCSV(w, result, "containers.csv")
+			exportQueryResultAsCSV(w, r, result, "containers.csv")
 			return
 		}
 
@@ -363,7 +654,12 @@ func ContainersHandler(provider ImageQueryProvider) http.HandlerFunc {
 }
 
 // buildContainersQuery constructs the SQL query for containers with filters
-func buildContainersQuery(search string, namespaces, vulnStatuses, packageTypes, osNames []string, sortBy, sortOrder string, limit, offset int) (string, string) {
+// and the arguments bound to its placeholders, shared by the query and the
+// count query. With detail the image reference, OS version and first seen
+// time of each container are selected too.
+func buildContainersQuery(search string, namespaces, vulnStatuses, packageTypes, osNames, registries []string, sortBy, sortOrder string, limit, offset int, detail bool, computed []columns.Column) (string, string, []interface{}) {
+	var args queryArgs
+
 	// Base query - individual containers
 	baseQuery := `
   FROM containers instances
@@ -395,8 +691,8 @@ func buildContainersQuery(search string, namespaces, vulnStatuses, packageTypes,
   WHERE 1=1`
 
 	// Build subquery filters using helper functions
-	packageTypeFilter := buildPackageTypeFilter(packageTypes)
-	vulnStatusFilter := buildVulnerabilityFilter(vulnStatuses, packageTypes)
+	packageTypeFilter := args.packageTypeFilter(packageTypes)
+	vulnStatusFilter := args.vulnerabilityFilter(vulnStatuses, packageTypes)
 
 	baseQuery = fmt.Sprintf(baseQuery, packageTypeFilter, vulnStatusFilter)
 
@@ -405,15 +701,18 @@ func buildContainersQuery(search string, namespaces, vulnStatuses, packageTypes,
 
 	// Search filter (pod, container, or namespace) - searches across multiple fields
 	if search != "" {
-		escapedSearch := escapeSQL(search)
-		conditions = append(conditions, fmt.Sprintf("(instances.namespace LIKE '%%%s%%' OR instances.pod LIKE '%%%s%%' OR instances.name LIKE '%%%s%%')", escapedSearch, escapedSearch, escapedSearch))
+		pattern := args.bind("%" + search + "%")
+		conditions = append(conditions, fmt.Sprintf("(instances.namespace LIKE %[1]s OR instances.pod LIKE %[1]s OR instances.name LIKE %[1]s)", pattern))
 	}
 
 	// Namespace filter
-	conditions = appendCondition(conditions, buildINClause("instances.namespace", namespaces))
+	conditions = appendCondition(conditions, args.in("instances.namespace", namespaces))
 
 	// OS name filter
-	conditions = appendCondition(conditions, buildINClause("images.os_name", osNames))
+	conditions = appendCondition(conditions, args.in("images.os_name", osNames))
+
+	// Registry filter (host the image is pulled from, e.g. docker.io)
+	conditions = appendCondition(conditions, args.in("instances.registry", registries))
 
 	// Add conditions to base query
 	whereClause := baseQuery + buildWhereClause(conditions)
@@ -429,7 +728,15 @@ func buildContainersQuery(search string, namespaces, vulnStatuses, packageTypes,
       instances.namespace,
       instances.pod,
       instances.name,
-      images.digest,
+      images.digest,`
+	if detail {
+		selectClause += `
+      instances.reference,`
+	}
+	selectClause += `
+      instances.node_name,
+      instances.image_pull_policy,
+      instances.registry,
       COALESCE(vuln_counts.critical_count, 0) as critical_count,
       COALESCE(vuln_counts.high_count, 0) as high_count,
       COALESCE(vuln_counts.medium_count, 0) as medium_count,
@@ -443,6 +750,13 @@ func buildContainersQuery(search string, namespaces, vulnStatuses, packageTypes,
       COALESCE(pkg_counts.package_count, 0) as package_count,
       status.description as status_description,
       images.os_name`
+	if detail {
+		selectClause += `,
+      images.os_version,
+      instances.created_at as first_seen`
+	}
+	computedSelect, computedSort := computedColumns(computed, vulnCountColumns())
+	selectClause += computedSelect
 
 	mainQuery := selectClause + whereClause
 
@@ -458,6 +772,9 @@ func buildContainersQuery(search string, namespaces, vulnStatuses, packageTypes,
 		"os_name": true,
 		"total_cves": true, "unique_cves": true,
 	}
+	for name := range computedSort {
+		validSortColumns[name] = true
+	}
 
 	if sortBy != "" && validSortColumns[sortBy] {
 		mainQuery += fmt.Sprintf(" ORDER BY status.sort_order ASC, %s %s", sortBy, sortOrder)
@@ -481,19 +798,73 @@ func buildContainersQuery(search string, namespaces, vulnStatuses, packageTypes,
 		mainQuery += fmt.Sprintf(" LIMIT %d OFFSET %d", limit, offset)
 	}
 
-	return mainQuery, countQuery
+	return mainQuery, countQuery, args
+}
+
+// vulnCountColumns returns the SQL of the numeric columns the image and
+// container lists share, by column name
+func vulnCountColumns() map[string]string {
+	sql := map[string]string{"package_count": "COALESCE(pkg_counts.package_count, 0)"}
+	for _, name := range []string{"critical_count", "high_count", "medium_count", "low_count", "negligible_count",
+		"unknown_count", "total_cves", "unique_cves", "total_risk", "exploit_count"} {
+		sql[name] = "COALESCE(vuln_counts." + name + ", 0)"
+	}
+	return sql
+}
+
+// computedColumns returns the select list entries of the computed columns
+// whose expressions only use columns in numeric, and their names. Columns
+// using a column the list lacks (e.g. container_count in the container list)
+// are left out.
+func computedColumns(computed []columns.Column, numeric map[string]string) (string, map[string]bool) {
+	var selectList strings.Builder
+	names := make(map[string]bool, len(computed))
+	for _, col := range computed {
+		expr, ok := col.SQL(numeric)
+		if !ok {
+			continue
+		}
+		selectList.WriteString(",\n      " + expr + " as " + col.Name)
+		names[col.Name] = true
+	}
+	return selectList.String(), names
 }
 
-// exportQueryResultAsCSV exports query results as CSV with the specified filename
-func exportQueryResultAsCSV(w http.ResponseWriter, result *database.QueryResult, filename string) {
+// addRepositoryAndTag adds repository and tag columns after the reference
+// column of a query result
+func addRepositoryAndTag(result *database.QueryResult) {
+	columns := make([]string, 0, len(result.Columns)+2)
+	for _, col := range result.Columns {
+		columns = append(columns, col)
+		if col == "reference" {
+			columns = append(columns, "repository", "tag")
+		}
+	}
+	if len(columns) == len(result.Columns) {
+		return
+	}
+	result.Columns = columns
+
+	for _, row := range result.Rows {
+		reference, _ := row["reference"].(string)
+		row["repository"], row["tag"] = containers.RepositoryAndTag(reference)
+	}
+}
+
+// exportQueryResultAsCSV exports query results as CSV with the specified
+// filename, honoring the CSV options of the request
+func exportQueryResultAsCSV(w http.ResponseWriter, r *http.Request, result *database.QueryResult, filename string) {
+	export := newCSVExport(w, r)
+	if export == nil {
+		return
+	}
 	w.Header().Set("Content-Type", "text/csv")
 	w.Header().Set("Content-Disposition", "attachment; filename="+filename)
 
-	writer := csv.NewWriter(w)
-	defer writer.Flush()
+	defer func() { _ = export.Flush() }()
 
 	// Write headers
-	if err := writer.Write(result.Columns); err != nil {
+	if err := export.WriteHeader(result.Columns); err != nil {
 		log.Error("error writing CSV headers", "error", err)
 		return
 	}
@@ -502,18 +873,30 @@ func exportQueryResultAsCSV(w http.ResponseWriter, result *database.QueryResult,
 	for _, rowMap := range result.Rows {
 		strRow := make([]string, len(result.Columns))
 		for i, col := range result.Columns {
-			strRow[i] = fmt.Sprintf("%v", rowMap[col])
+			strRow[i] = export.Value(rowMap[col])
 		}
-		if err := writer.Write(strRow); err != nil {
+		if err := export.WriteRow(strRow); err != nil {
 			log.Error("error writing CSV row", "error", err)
 			return
 		}
 	}
 }
 
+// FixHintFinder finds newer tags that resolve an image's critical findings
+// (implemented by fixhints.Finder)
+type FixHintFinder interface {
+	Find(ctx context.Context, digest string) ([]fixhints.Hint, error)
+}
+
+// fixHintsTimeout bounds the time spent on fix hints (which may query registries)
+const fixHintsTimeout = 10 * time.Second
+
 // ImageDetailFullHandler creates an HTTP handler for /api/images/{digest} endpoint
 // Returns detailed information for a specific image including references and containers
-func ImageDetailFullHandler(provider ImageQueryProvider) http.HandlerFunc {
+// When hints is non-nil the response includes fix_hints: newer tags of the image
+// that resolve its critical findings. Soft-deleted images are reported as not
+// found unless ?includeDeleted=true is given.
+func ImageDetailFullHandler(provider ImageQueryProvider, hints FixHintFinder) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		// Extract digest from URL path
 		path := r.URL.Path
@@ -534,7 +917,6 @@ func ImageDetailFullHandler(provider ImageQueryProvider) http.HandlerFunc {
 		}
 
 		// Build query to get basic image details
-		escapedDigest := escapeSQL(digest)
 		imageQuery := `
 SELECT
     images.id,
@@ -543,13 +925,23 @@ SELECT
     images.os_name as distro_display_name,
     status.description as status_description,
     images.vulns_scanned_at,
-    images.grype_db_built
+    images.grype_db_built,
+    images.deleted_at,
+    images.last_reference,
+    images.oci_source,
+    images.oci_version,
+    images.oci_vendor,
+    images.sbom_source,
+    images.sbom_signer
 FROM images images
 JOIN scan_status status ON images.status = status.status
-WHERE images.digest = '` + escapedDigest + `'`
+WHERE images.digest = ?1`
+		if !includeDeletedParam(r) {
+			imageQuery += " AND images.deleted_at IS NULL"
+		}
 
 		log.Debug("executing image query", "digest", digest)
-		imageResult, err := provider.ExecuteReadOnlyQuery(imageQuery)
+		imageResult, err := provider.ExecuteReadOnlyQueryArgs(imageQuery, digest)
 		if err != nil {
 			log.Error("error querying image details", "digest", digest, "error", err)
 			http.Error(w, fmt.Sprintf("Error querying image: %v", err), http.StatusInternalServerError)
@@ -615,7 +1007,7 @@ ORDER BY namespace, pod, name`
 		// Get vulnerability summary stats
 		// unique_cves and unique_exploits dedupe by cve_id alone — a single CVE that
 		// affects multiple (package, version) tuples in the same image counts once.
-		vulnStatsQuery := fmt.Sprintf(`
+		vulnStatsQuery := `
 SELECT
     COALESCE(SUM(v.risk * v.count), 0) as total_risk,
     COALESCE(SUM(v.count), 0) as total_cves,
@@ -630,9 +1022,9 @@ SELECT
     COALESCE(SUM(CASE WHEN v.severity = 'Unknown'     THEN v.count ELSE 0 END), 0) as cves_unknown
 FROM image_vulnerabilities v
 JOIN images images ON v.image_id = images.id
-WHERE images.digest = '%s'`, escapedDigest)
+WHERE images.digest = ?1`
 
-		vulnStatsResult, err := provider.ExecuteReadOnlyQuery(vulnStatsQuery)
+		vulnStatsResult, err := provider.ExecuteReadOnlyQueryArgs(vulnStatsQuery, digest)
 		if err != nil {
 			log.Error("error querying vuln stats", "digest", digest, "error", err)
 			http.Error(w, fmt.Sprintf("Error querying vuln stats: %v", err), http.StatusInternalServerError)
@@ -657,15 +1049,15 @@ WHERE images.digest = '%s'`, escapedDigest)
 		}
 
 		// Get package summary stats
-		pkgStatsQuery := fmt.Sprintf(`
+		pkgStatsQuery := `
 SELECT
     COALESCE(SUM(p.number_of_instances), 0) as total_packages,
     COUNT(*) as unique_packages
 FROM image_packages p
 JOIN images images ON p.image_id = images.id
-WHERE images.digest = '%s'`, escapedDigest)
+WHERE images.digest = ?1`
 
-		pkgStatsResult, err := provider.ExecuteReadOnlyQuery(pkgStatsQuery)
+		pkgStatsResult, err := provider.ExecuteReadOnlyQueryArgs(pkgStatsQuery, digest)
 		if err != nil {
 			log.Error("error querying package stats", "digest", digest, "error", err)
 			http.Error(w, fmt.Sprintf("Error querying package stats: %v", err), http.StatusInternalServerError)
@@ -688,6 +1080,13 @@ WHERE images.digest = '%s'`, escapedDigest)
 			"status_description":  imageRow["status_description"],
 			"vulns_scanned_at":    imageRow["vulns_scanned_at"],
 			"grype_db_built":      imageRow["grype_db_built"],
+			"deleted_at":          imageRow["deleted_at"],
+			"last_reference":      imageRow["last_reference"],
+			"oci_source":          imageRow["oci_source"],
+			"oci_version":         imageRow["oci_version"],
+			"oci_vendor":          imageRow["oci_vendor"],
+			"sbom_source":         imageRow["sbom_source"],
+			"sbom_signer":         imageRow["sbom_signer"],
 			"total_risk":          totalRisk,
 			"total_cves":          totalCVEs,
 			"unique_cves":         uniqueCVEs,
@@ -703,6 +1102,19 @@ WHERE images.digest = '%s'`, escapedDigest)
 			"cves_unknown":        cvesUnknown,
 		}
 
+		if hints != nil {
+			ctx, cancel := context.WithTimeout(r.Context(), fixHintsTimeout)
+			fixHints, err := hints.Find(ctx, digest)
+			cancel()
+			if err != nil {
+				log.Warn("error finding fix hints", "digest", digest, "error", err)
+			}
+			if fixHints == nil {
+				fixHints = []fixhints.Hint{}
+			}
+			response["fix_hints"] = fixHints
+		}
+
 		w.Header().Set("Content-Type", "application/json")
 		if err := json.NewEncoder(w).Encode(response); err != nil {
 			log.Error("error encoding image detail response", "error", err)
@@ -712,7 +1124,10 @@ WHERE images.digest = '%s'`, escapedDigest)
 }
 
 // ImageVulnerabilitiesDetailHandler creates an HTTP handler for /api/images/{digest}/vulnerabilities endpoint
-// Returns vulnerabilities for a specific image with filtering, sorting, and pagination
+// Returns vulnerabilities for a specific image with filtering, sorting, and pagination.
+// ?format=csv and NDJSON (Accept: application/x-ndjson or ?format=ndjson) return
+// all matching rows, streamed so images with thousands of findings do not
+// have to fit in memory on either side.
 func ImageVulnerabilitiesDetailHandler(provider ImageQueryProvider) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		// Extract digest from URL path
@@ -740,7 +1155,11 @@ func ImageVulnerabilitiesDetailHandler(provider ImageQueryProvider) http.Handler
 			return
 		}
 
-		// Pagination (skip for CSV export - export all data)
+		// CSV and NDJSON return all matching rows, streamed as they are read
+		ndjson := wantsNDJSON(r)
+		stream := format == "csv" || ndjson
+
+		// Pagination (skip for streamed exports - export all data)
 		page, _ := strconv.Atoi(params.Get("page"))
 		if page < 1 {
 			page = 1
@@ -750,9 +1169,7 @@ func ImageVulnerabilitiesDetailHandler(provider ImageQueryProvider) http.Handler
 			pageSize = 100
 		}
 		offset := (page - 1) * pageSize
-
-		// For CSV export, get all results
-		if format == "csv" {
+		if stream {
 			pageSize = -1
 			offset = 0
 		}
@@ -761,6 +1178,8 @@ func ImageVulnerabilitiesDetailHandler(provider ImageQueryProvider) http.Handler
 		severities := parseMultiSelect(params.Get("severity"))
 		fixStatuses := parseMultiSelect(params.Get("fixStatus"))
 		packageTypes := parseMultiSelect(params.Get("packageType"))
+		// Vulnerability search matches the identifier or any of its aliases (CVE ↔ RHSA/ALAS)
+		vulnerability := params.Get("vulnerability")
 
 		// Sorting
 		sortBy := params.Get("sortBy")
@@ -770,10 +1189,20 @@ func ImageVulnerabilitiesDetailHandler(provider ImageQueryProvider) http.Handler
 		}
 
 		// Build query
-		query, countQuery := buildImageVulnerabilitiesQuery(digest, severities, fixStatuses, packageTypes, sortBy, sortOrder, pageSize, offset)
+		query, countQuery, args := buildImageVulnerabilitiesQuery(digest, severities, fixStatuses, packageTypes, vulnerability, sortBy, sortOrder, pageSize, offset)
+
+		// Streamed exports need no count, and never hold the full list in memory
+		if ndjson {
+			streamQueryAsNDJSON(w, provider, query, args...)
+			return
+		}
+		if format == "csv" {
+			streamQueryAsCSV(w, r, provider, query, "vulnerabilities.csv", args...)
+			return
+		}
 
 		// Execute count query for pagination
-		countResult, err := provider.ExecuteReadOnlyQuery(countQuery)
+		countResult, err := provider.ExecuteReadOnlyQueryArgs(countQuery, args...)
 		if err != nil {
 			log.Error("error executing vulnerability count query", "error", err)
 			http.Error(w, "Internal server error", http.StatusInternalServerError)
@@ -788,19 +1217,13 @@ func ImageVulnerabilitiesDetailHandler(provider ImageQueryProvider) http.Handler
 		}
 
 		// Execute main query
-		result, err := provider.ExecuteReadOnlyQuery(query)
+		result, err := provider.ExecuteReadOnlyQueryArgs(query, args...)
 		if err != nil {
 			log.Error("error executing vulnerability query", "error", err)
 			http.Error(w, "Internal server error", http.StatusInternalServerError)
 			return
 		}
 
-		// Handle CSV export
-		if format == "csv" {
-			exportQueryResultAsCSV(w, result, "vulnerabilities.csv")
-			return
-		}
-
 		// Return JSON response
 		totalPages := int(math.Ceil(float64(totalCount) / float64(pageSize)))
 		w.Header().Set("Content-Type", "application/json")
@@ -817,21 +1240,26 @@ func ImageVulnerabilitiesDetailHandler(provider ImageQueryProvider) http.Handler
 	}
 }
 
-// buildImageVulnerabilitiesQuery constructs the SQL query for image vulnerabilities
-func buildImageVulnerabilitiesQuery(digest string, severities, fixStatuses, packageTypes []string, sortBy, sortOrder string, limit, offset int) (string, string) {
-	escapedDigest := escapeSQL(digest)
+// buildImageVulnerabilitiesQuery constructs the SQL query for image
+// vulnerabilities and the count query, which share their arguments
+func buildImageVulnerabilitiesQuery(digest string, severities, fixStatuses, packageTypes []string, vulnerability, sortBy, sortOrder string, limit, offset int) (string, string, queryArgs) {
+	var args queryArgs
+	digestParam := args.bind(digest)
 
 	// Build WHERE conditions
 	var conditions []string
 
 	// Severity filter
-	conditions = appendCondition(conditions, buildINClause("v.severity", severities))
+	conditions = appendCondition(conditions, args.in("v.severity", severities))
 
 	// Fix status filter
-	conditions = appendCondition(conditions, buildINClause("v.fix_status", fixStatuses))
+	conditions = appendCondition(conditions, args.in("v.fix_status", fixStatuses))
 
 	// Package type filter
-	conditions = appendCondition(conditions, buildINClause("v.package_type", packageTypes))
+	conditions = appendCondition(conditions, args.in("v.package_type", packageTypes))
+
+	// Vulnerability ID filter (matches aliases too)
+	conditions = appendCondition(conditions, args.vulnerabilityID("v.cve_id", vulnerability))
 
 	whereClause := buildWhereClause(conditions)
 
@@ -839,7 +1267,7 @@ func buildImageVulnerabilitiesQuery(digest string, severities, fixStatuses, pack
 	baseQuery := fmt.Sprintf(`
 FROM image_vulnerabilities v
 JOIN images images ON v.image_id = images.id
-WHERE images.digest = '%s'%s`, escapedDigest, whereClause)
+WHERE images.digest = %s%s`, digestParam, whereClause)
 
 	// Build count query
 	countQuery := "SELECT COUNT(*)" + baseQuery
@@ -856,7 +1284,9 @@ WHERE images.digest = '%s'%s`, escapedDigest, whereClause)
     v.severity as vulnerability_severity,
     v.risk as vulnerability_risk,
     v.known_exploited as vulnerability_known_exploits,
-    v.count as vulnerability_count`
+    v.occurrences as vulnerability_count,
+    ` + vulnerabilityAliasesColumn("v.cve_id") + `,
+    ` + cveAnnotationColumn("v.cve_id")
 
 	mainQuery := selectClause + baseQuery
 
@@ -895,7 +1325,7 @@ WHERE images.digest = '%s'%s`, escapedDigest, whereClause)
 			if limit > 0 {
 				mainQuery += fmt.Sprintf("\nLIMIT %d OFFSET %d", limit, offset)
 			}
-			return mainQuery, countQuery
+			return mainQuery, countQuery, args
 		case "vulnerability_id":
 			// User clicked vulnerability - it becomes primary, severity becomes secondary
 			mainQuery += "    v.cve_id " + sortOrder + ",\n"
@@ -903,7 +1333,7 @@ WHERE images.digest = '%s'%s`, escapedDigest, whereClause)
 			if limit > 0 {
 				mainQuery += fmt.Sprintf("\nLIMIT %d OFFSET %d", limit, offset)
 			}
-			return mainQuery, countQuery
+			return mainQuery, countQuery, args
 		case "artifact_name":
 			dbColumn = "v.package_name"
 		case "artifact_version":
@@ -919,7 +1349,7 @@ WHERE images.digest = '%s'%s`, escapedDigest, whereClause)
 		case "vulnerability_known_exploits":
 			dbColumn = "v.known_exploited"
 		case "vulnerability_count":
-			dbColumn = "v.count"
+			dbColumn = "v.occurrences"
 		}
 
 		// User clicked a column: [column], severity, vulnerability
@@ -937,7 +1367,7 @@ WHERE images.digest = '%s'%s`, escapedDigest, whereClause)
 		mainQuery += fmt.Sprintf(" LIMIT %d OFFSET %d", limit, offset)
 	}
 
-	return mainQuery, countQuery
+	return mainQuery, countQuery, args
 }
 
 
@@ -1014,10 +1444,10 @@ func ImagePackagesDetailHandler(provider ImageQueryProvider) http.HandlerFunc {
 		}
 
 		// Build query
-		query, countQuery := buildImagePackagesQuery(digest, packageTypes, sortBy, sortOrder, pageSize, offset)
+		query, countQuery, args := buildImagePackagesQuery(digest, packageTypes, sortBy, sortOrder, pageSize, offset)
 
 		// Execute count query for pagination
-		countResult, err := provider.ExecuteReadOnlyQuery(countQuery)
+		countResult, err := provider.ExecuteReadOnlyQueryArgs(countQuery, args...)
 		if err != nil {
 			log.Error("error executing package count query", "error", err)
 			http.Error(w, "Internal server error", http.StatusInternalServerError)
@@ -1032,7 +1462,7 @@ func ImagePackagesDetailHandler(provider ImageQueryProvider) http.HandlerFunc {
 		}
 
 		// Execute main query
-		result, err := provider.ExecuteReadOnlyQuery(query)
+		result, err := provider.ExecuteReadOnlyQueryArgs(query, args...)
 		if err != nil {
 			log.Error("error executing package query", "error", err)
 			http.Error(w, "Internal server error", http.StatusInternalServerError)
@@ -1041,7 +1471,7 @@ func ImagePackagesDetailHandler(provider ImageQueryProvider) http.HandlerFunc {
 
 		// Handle CSV export
 		if format == "csv" {
-			exportQueryResultAsCSV(w, result, "packages.csv")
+			exportQueryResultAsCSV(w, r, result, "packages.csv")
 			return
 		}
 
@@ -1061,15 +1491,17 @@ func ImagePackagesDetailHandler(provider ImageQueryProvider) http.HandlerFunc {
 	}
 }
 
-// buildImagePackagesQuery constructs the SQL query for image packages
-func buildImagePackagesQuery(digest string, packageTypes []string, sortBy, sortOrder string, limit, offset int) (string, string) {
-	escapedDigest := escapeSQL(digest)
+// buildImagePackagesQuery constructs the SQL query for image packages and
+// the count query, which share their arguments
+func buildImagePackagesQuery(digest string, packageTypes []string, sortBy, sortOrder string, limit, offset int) (string, string, queryArgs) {
+	var args queryArgs
+	digestParam := args.bind(digest)
 
 	// Build WHERE conditions
 	var conditions []string
 
 	// Package type filter
-	conditions = appendCondition(conditions, buildINClause("p.type", packageTypes))
+	conditions = appendCondition(conditions, args.in("p.type", packageTypes))
 
 	whereClause := buildWhereClause(conditions)
 
@@ -1077,7 +1509,7 @@ func buildImagePackagesQuery(digest string, packageTypes []string, sortBy, sortO
 	baseQuery := fmt.Sprintf(`
 FROM image_packages p
 JOIN images images ON p.image_id = images.id
-WHERE images.digest = '%s'%s`, escapedDigest, whereClause)
+WHERE images.digest = %s%s`, digestParam, whereClause)
 
 	// Build count query
 	countQuery := "SELECT COUNT(*)" + baseQuery
@@ -1125,7 +1557,7 @@ WHERE images.digest = '%s'%s`, escapedDigest, whereClause)
 		mainQuery += fmt.Sprintf(" LIMIT %d OFFSET %d", limit, offset)
 	}
 
-	return mainQuery, countQuery
+	return mainQuery, countQuery, args
 }
 
 
@@ -1144,7 +1576,6 @@ func ImageStatsHandler(provider ImageQueryProvider) http.HandlerFunc {
 			return
 		}
 		digest := pathWithoutPrefix[:len(pathWithoutPrefix)-6]
-		escapedDigest := escapeSQL(digest)
 
 		params := r.URL.Query()
 		severities := parseMultiSelect(params.Get("severity"))
@@ -1152,10 +1583,12 @@ func ImageStatsHandler(provider ImageQueryProvider) http.HandlerFunc {
 		packageTypes := parseMultiSelect(params.Get("packageType"))
 
 		// Query 1: table-1 stats (total risk, CVEs, exploits) — all three filters applied
+		var vulnArgs queryArgs
+		vulnDigest := vulnArgs.bind(digest)
 		var vulnConditions []string
-		vulnConditions = appendCondition(vulnConditions, buildINClause("v.severity", severities))
-		vulnConditions = appendCondition(vulnConditions, buildINClause("v.fix_status", fixStatuses))
-		vulnConditions = appendCondition(vulnConditions, buildINClause("v.package_type", packageTypes))
+		vulnConditions = appendCondition(vulnConditions, vulnArgs.in("v.severity", severities))
+		vulnConditions = appendCondition(vulnConditions, vulnArgs.in("v.fix_status", fixStatuses))
+		vulnConditions = appendCondition(vulnConditions, vulnArgs.in("v.package_type", packageTypes))
 		vulnWhere := buildWhereClause(vulnConditions)
 
 		// unique_cves and unique_exploits dedupe by cve_id alone — see note in
@@ -1169,9 +1602,9 @@ SELECT
     COUNT(DISTINCT CASE WHEN v.known_exploited > 0 THEN v.cve_id END) as unique_exploits
 FROM image_vulnerabilities v
 JOIN images images ON v.image_id = images.id
-WHERE images.digest = '%s'%s`, escapedDigest, vulnWhere)
+WHERE images.digest = %s%s`, vulnDigest, vulnWhere)
 
-		vulnResult, err := provider.ExecuteReadOnlyQuery(vulnStatsQuery)
+		vulnResult, err := provider.ExecuteReadOnlyQueryArgs(vulnStatsQuery, vulnArgs...)
 		if err != nil {
 			log.Error("error querying vuln stats", "error", err)
 			http.Error(w, "Internal server error", http.StatusInternalServerError)
@@ -1189,9 +1622,9 @@ SELECT
     COALESCE(SUM(CASE WHEN v.severity = 'Unknown'    THEN v.count ELSE 0 END), 0) as cves_unknown
 FROM image_vulnerabilities v
 JOIN images images ON v.image_id = images.id
-WHERE images.digest = '%s'%s`, escapedDigest, vulnWhere)
+WHERE images.digest = %s%s`, vulnDigest, vulnWhere)
 
-		sevResult, err := provider.ExecuteReadOnlyQuery(sevStatsQuery)
+		sevResult, err := provider.ExecuteReadOnlyQueryArgs(sevStatsQuery, vulnArgs...)
 		if err != nil {
 			log.Error("error querying severity stats", "error", err)
 			http.Error(w, "Internal server error", http.StatusInternalServerError)
@@ -1199,8 +1632,10 @@ WHERE images.digest = '%s'%s`, escapedDigest, vulnWhere)
 		}
 
 		// Query 3: package stats — packageType only
+		var pkgArgs queryArgs
+		pkgDigest := pkgArgs.bind(digest)
 		var pkgConditions []string
-		pkgConditions = appendCondition(pkgConditions, buildINClause("p.type", packageTypes))
+		pkgConditions = appendCondition(pkgConditions, pkgArgs.in("p.type", packageTypes))
 		pkgWhere := buildWhereClause(pkgConditions)
 
 		pkgStatsQuery := fmt.Sprintf(`
@@ -1209,9 +1644,9 @@ SELECT
     COUNT(*) as unique_packages
 FROM image_packages p
 JOIN images images ON p.image_id = images.id
-WHERE images.digest = '%s'%s`, escapedDigest, pkgWhere)
+WHERE images.digest = %s%s`, pkgDigest, pkgWhere)
 
-		pkgResult, err := provider.ExecuteReadOnlyQuery(pkgStatsQuery)
+		pkgResult, err := provider.ExecuteReadOnlyQueryArgs(pkgStatsQuery, pkgArgs...)
 		if err != nil {
 			log.Error("error querying package stats", "error", err)
 			http.Error(w, "Internal server error", http.StatusInternalServerError)
@@ -1325,6 +1760,47 @@ func VulnerabilityDetailsHandler(provider ImageQueryProvider) http.HandlerFunc {
 	}
 }
 
+// VulnerabilityOccurrencesHandler returns the paths at which the package of a
+// finding was found, merged from its matches
+// Expected format: /api/vulnerabilities/{id}/occurrences
+func VulnerabilityOccurrencesHandler(provider ImageQueryProvider) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		vulnIDStr := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/api/vulnerabilities/"), "/occurrences")
+		vulnID, err := strconv.ParseInt(vulnIDStr, 10, 64)
+		if err != nil {
+			http.Error(w, "Invalid vulnerability ID format", http.StatusBadRequest)
+			return
+		}
+
+		result, err := provider.ExecuteReadOnlyQuery(fmt.Sprintf(`
+			SELECT vd.details
+			FROM image_vulnerability_details vd
+			WHERE vd.vulnerability_id = %d`, vulnID))
+		if err != nil {
+			log.Error("error fetching vulnerability details", "error", err)
+			http.Error(w, "Failed to fetch vulnerability occurrences", http.StatusInternalServerError)
+			return
+		}
+		if len(result.Rows) == 0 {
+			http.Error(w, "Vulnerability details not found", http.StatusNotFound)
+			return
+		}
+
+		detailsJSON, _ := result.Rows[0]["details"].(string)
+		occurrences, err := database.OccurrencesFromDetails(detailsJSON)
+		if err != nil {
+			log.Error("error merging vulnerability occurrences", "vulnerability_id", vulnID, "error", err)
+			http.Error(w, "Failed to fetch vulnerability occurrences", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(map[string]interface{}{"occurrences": occurrences}); err != nil {
+			log.Error("error encoding vulnerability occurrences", "error", err)
+		}
+	}
+}
+
 // PackageDetailsHandler returns the full JSON details for a specific package
 func PackageDetailsHandler(provider ImageQueryProvider) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
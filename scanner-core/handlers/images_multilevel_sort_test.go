@@ -50,7 +50,7 @@ func TestImagesMultiLevelSorting(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			// Build query
-			query, _ := buildImagesQuery("", nil, nil, nil, nil, tt.sortBy, tt.sortOrder, 50, 0)
+			query, _, _ := buildImagesQuery("", nil, nil, nil, nil, nil, nil, nil, nil, nil, imageSizeFilter{}, cmdbFilter{}, tt.sortBy, tt.sortOrder, 50, 0, false, nil)
 
 			// Check expected ORDER BY clause
 			if !strings.Contains(query, tt.expectedOrderBy) {
@@ -123,7 +123,7 @@ func TestContainersMultiLevelSorting(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			// Build query
-			query, _ := buildContainersQuery("", nil, nil, nil, nil, tt.sortBy, tt.sortOrder, 50, 0)
+			query, _, _ := buildContainersQuery("", nil, nil, nil, nil, nil, tt.sortBy, tt.sortOrder, 50, 0, false, nil)
 
 			// Check expected ORDER BY clause
 			if !strings.Contains(query, tt.expectedOrderBy) {
@@ -196,7 +196,7 @@ func TestPackagesMultiLevelSorting(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			// Build query
-			query, _ := buildImagePackagesQuery("test-digest", nil, tt.sortBy, tt.sortOrder, 50, 0)
+			query, _, _ := buildImagePackagesQuery("test-digest", nil, tt.sortBy, tt.sortOrder, 50, 0)
 
 			// Check expected ORDER BY clause
 			if !strings.Contains(query, tt.expectedOrderBy) {
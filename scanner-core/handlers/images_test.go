@@ -1,20 +1,29 @@
 package handlers
 
 import (
+	"context"
 	"encoding/csv"
 	"encoding/json"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"reflect"
 	"strings"
 	"testing"
+	"time"
 
+	"github.com/bvboe/b2s-go/scanner-core/columns"
+	"github.com/bvboe/b2s-go/scanner-core/containers"
 	"github.com/bvboe/b2s-go/scanner-core/database"
+	"github.com/bvboe/b2s-go/scanner-core/fixhints"
 )
 
 // mockQueryProvider implements ImageQueryProvider for testing
 type mockQueryProvider struct {
 	queryFunc func(query string) (*database.QueryResult, error)
+	args      []interface{} // arguments of the last ExecuteReadOnlyQueryArgs call
 }
 
 func (m *mockQueryProvider) ExecuteReadOnlyQuery(query string) (*database.QueryResult, error) {
@@ -27,6 +36,11 @@ func (m *mockQueryProvider) ExecuteReadOnlyQuery(query string) (*database.QueryR
 	}, nil
 }
 
+func (m *mockQueryProvider) ExecuteReadOnlyQueryArgs(query string, args ...interface{}) (*database.QueryResult, error) {
+	m.args = args
+	return m.ExecuteReadOnlyQuery(query)
+}
+
 func (m *mockQueryProvider) GetSBOM(digest string) ([]byte, error) {
 	return nil, fmt.Errorf("SBOM not available")
 }
@@ -168,8 +182,8 @@ func TestImagesHandler(t *testing.T) {
 			name:        "with search filter",
 			queryParams: "search=nginx&page=1&pageSize=50",
 			mockCountFunc: func(query string) (*database.QueryResult, error) {
-				if !strings.Contains(query, "nginx") {
-					t.Error("Expected search term 'nginx' in query")
+				if !strings.Contains(query, "instances.reference LIKE ?1") {
+					t.Error("Expected search condition in query")
 				}
 				return &database.QueryResult{
 					Columns: []string{"COUNT(*)"},
@@ -179,8 +193,8 @@ func TestImagesHandler(t *testing.T) {
 				}, nil
 			},
 			mockDataFunc: func(query string) (*database.QueryResult, error) {
-				if !strings.Contains(query, "nginx") {
-					t.Error("Expected search term 'nginx' in query")
+				if !strings.Contains(query, "instances.reference LIKE ?1") {
+					t.Error("Expected search condition in query")
 				}
 				return &database.QueryResult{
 					Columns: []string{"image"},
@@ -196,7 +210,7 @@ func TestImagesHandler(t *testing.T) {
 			name:        "with namespace filter",
 			queryParams: "namespaces=default,kube-system",
 			mockCountFunc: func(query string) (*database.QueryResult, error) {
-				if !strings.Contains(query, "IN ('default','kube-system')") {
+				if !strings.Contains(query, "instances.namespace IN (?1,?2)") {
 					t.Error("Expected namespace filter in query")
 				}
 				return &database.QueryResult{
@@ -268,7 +282,7 @@ func TestImagesHandler(t *testing.T) {
 				},
 			}
 
-			handler := ImagesHandler(provider)
+			handler := ImagesHandler(provider, nil, nil)
 			req := httptest.NewRequest(http.MethodGet, "/api/images?"+tt.queryParams, nil)
 			rec := httptest.NewRecorder()
 
@@ -307,8 +321,8 @@ func TestContainersHandler(t *testing.T) {
 			queryParams:    "search=nginx-pod",
 			expectedStatus: http.StatusOK,
 			checkQuery: func(t *testing.T, query string) {
-				if !strings.Contains(query, "nginx-pod") {
-					t.Error("Expected pod search term in query")
+				if !strings.Contains(query, "instances.pod LIKE ?1") {
+					t.Error("Expected pod search condition in query")
 				}
 			},
 		},
@@ -350,7 +364,7 @@ func TestContainersHandler(t *testing.T) {
 				},
 			}
 
-			handler := ContainersHandler(provider)
+			handler := ContainersHandler(provider, nil)
 			req := httptest.NewRequest(http.MethodGet, "/api/containers?"+tt.queryParams, nil)
 			rec := httptest.NewRecorder()
 
@@ -367,6 +381,65 @@ func TestContainersHandler(t *testing.T) {
 	}
 }
 
+// TestContainersHandlerDetail verifies that CSV exports and ?detail=full JSON
+// responses identify the image of each container, while plain JSON responses
+// are unchanged
+func TestContainersHandlerDetail(t *testing.T) {
+	var capturedQuery string
+	provider := &mockQueryProvider{
+		queryFunc: func(query string) (*database.QueryResult, error) {
+			if strings.Contains(query, "COUNT(*)") {
+				return &database.QueryResult{
+					Columns: []string{"COUNT(*)"},
+					Rows:    []map[string]interface{}{{"COUNT(*)": int64(1)}},
+				}, nil
+			}
+			capturedQuery = query
+			return &database.QueryResult{
+				Columns: []string{"namespace", "digest", "reference", "node_name", "os_version", "first_seen"},
+				Rows: []map[string]interface{}{{
+					"namespace":  "default",
+					"digest":     "sha256:abc",
+					"reference":  "ghcr.io/org/app:1.0",
+					"node_name":  "node-1",
+					"os_version": "3.19",
+					"first_seen": "2026-01-02 03:04:05",
+				}},
+			}, nil
+		},
+	}
+
+	rec := httptest.NewRecorder()
+	ContainersHandler(provider, nil)(rec, httptest.NewRequest(http.MethodGet, "/api/containers?format=csv", nil))
+	want := "namespace,digest,reference,repository,tag,node_name,os_version,first_seen\n" +
+		"default,sha256:abc,ghcr.io/org/app:1.0,org/app,1.0,node-1,3.19,2026-01-02 03:04:05\n"
+	if got := rec.Body.String(); got != want {
+		t.Errorf("CSV = %q, want %q", got, want)
+	}
+	for _, col := range []string{"instances.reference", "images.os_version", "instances.created_at as first_seen"} {
+		if !strings.Contains(capturedQuery, col) {
+			t.Errorf("expected %s in CSV export query", col)
+		}
+	}
+
+	rec = httptest.NewRecorder()
+	ContainersHandler(provider, nil)(rec, httptest.NewRequest(http.MethodGet, "/api/containers?detail=full", nil))
+	var resp struct {
+		Containers []map[string]interface{} `json:"containers"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if len(resp.Containers) != 1 || resp.Containers[0]["repository"] != "org/app" || resp.Containers[0]["tag"] != "1.0" {
+		t.Errorf("unexpected detail=full response %+v", resp.Containers)
+	}
+
+	ContainersHandler(provider, nil)(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/api/containers", nil))
+	if strings.Contains(capturedQuery, "instances.reference") {
+		t.Error("expected plain JSON query without detail columns")
+	}
+}
+
 func TestImageDetailFullHandler(t *testing.T) {
 	tests := []struct {
 		name           string
@@ -451,7 +524,7 @@ func TestImageDetailFullHandler(t *testing.T) {
 				queryFunc: tt.mockFunc,
 			}
 
-			handler := ImageDetailFullHandler(provider)
+			handler := ImageDetailFullHandler(provider, nil)
 			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
 			rec := httptest.NewRecorder()
 
@@ -512,7 +585,7 @@ func TestImageDetailFullHandler_UniqueCVEsDedupedByCVEID(t *testing.T) {
 		},
 	}
 
-	handler := ImageDetailFullHandler(provider)
+	handler := ImageDetailFullHandler(provider, nil)
 	req := httptest.NewRequest(http.MethodGet, "/api/images/sha256:abc", nil)
 	rec := httptest.NewRecorder()
 	handler.ServeHTTP(rec, req)
@@ -552,6 +625,72 @@ func TestImageDetailFullHandler_UniqueCVEsDedupedByCVEID(t *testing.T) {
 	}
 }
 
+type mockFixHintFinder struct {
+	hints  []fixhints.Hint
+	digest string
+}
+
+func (m *mockFixHintFinder) Find(ctx context.Context, digest string) ([]fixhints.Hint, error) {
+	m.digest = digest
+	return m.hints, nil
+}
+
+func TestImageDetailFullHandler_FixHints(t *testing.T) {
+	provider := &mockQueryProvider{
+		queryFunc: func(query string) (*database.QueryResult, error) {
+			if strings.Contains(query, "FROM images") && strings.Contains(query, "scan_status") {
+				return &database.QueryResult{
+					Columns: []string{"id", "image_id", "scan_status"},
+					Rows:    []map[string]interface{}{{"id": int64(1), "image_id": "sha256:abc", "scan_status": "completed"}},
+				}, nil
+			}
+			return &database.QueryResult{Columns: []string{}, Rows: []map[string]interface{}{}}, nil
+		},
+	}
+
+	tests := []struct {
+		name  string
+		hints []fixhints.Hint
+		want  int
+	}{
+		{name: "no hints returns empty list", hints: nil, want: 0},
+		{
+			name: "hints included",
+			hints: []fixhints.Hint{{
+				Tag: "1.21.1", Reference: "nginx:1.21.1", Digest: "sha256:def", Source: fixhints.SourceCluster,
+				ResolvedCVEs: []string{"CVE-2024-0001"}, RemainingCVEs: []string{},
+			}},
+			want: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			finder := &mockFixHintFinder{hints: tt.hints}
+			handler := ImageDetailFullHandler(provider, finder)
+			req := httptest.NewRequest(http.MethodGet, "/api/images/sha256:abc", nil)
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != http.StatusOK {
+				t.Fatalf("expected 200 OK, got %d: %s", rec.Code, rec.Body.String())
+			}
+			if finder.digest != "sha256:abc" {
+				t.Errorf("expected hints for sha256:abc, got %q", finder.digest)
+			}
+			var response struct {
+				FixHints []fixhints.Hint `json:"fix_hints"`
+			}
+			if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+				t.Fatalf("failed to parse response: %v", err)
+			}
+			if response.FixHints == nil || len(response.FixHints) != tt.want {
+				t.Errorf("expected %d fix_hints, got %v", tt.want, response.FixHints)
+			}
+		})
+	}
+}
+
 // TestImageStatsHandler_UniqueCVEsDedupedByCVEID is the same dedup assertion
 // for the filtered /api/images/{digest}/stats endpoint.
 func TestImageStatsHandler_UniqueCVEsDedupedByCVEID(t *testing.T) {
@@ -659,7 +798,7 @@ func TestImageVulnerabilitiesDetailHandler(t *testing.T) {
 			name: "with severity filter",
 			path: "/api/images/sha256:abc123/vulnerabilities?severity=Critical,High",
 			mockFunc: func(query string) (*database.QueryResult, error) {
-				if !strings.Contains(query, "severity IN ('Critical','High')") {
+				if !strings.Contains(query, "severity IN (?2,?3)") {
 					t.Error("Expected severity filter in query")
 				}
 				if strings.Contains(query, "COUNT(*)") {
@@ -750,7 +889,7 @@ func TestImagePackagesDetailHandler(t *testing.T) {
 			name: "with type filter",
 			path: "/api/images/sha256:abc123/packages?type=apk,deb",
 			mockFunc: func(query string) (*database.QueryResult, error) {
-				if !strings.Contains(query, "type IN ('apk','deb')") {
+				if !strings.Contains(query, "type IN (?2,?3)") {
 					t.Error("Expected type filter in query")
 				}
 				if strings.Contains(query, "COUNT(*)") {
@@ -993,6 +1132,19 @@ func TestParseMultiSelect(t *testing.T) {
 	}
 }
 
+func TestParseImageSizeFilter(t *testing.T) {
+	got := parseImageSizeFilter(url.Values{
+		"minSizeMB": {"0.5"}, "maxSizeMB": {"200"}, "minLayers": {"-1"}, "maxLayers": {"12"},
+	})
+	want := imageSizeFilter{minBytes: 512 * 1024, maxBytes: 200 * 1024 * 1024, maxLayers: 12}
+	if got != want {
+		t.Errorf("parseImageSizeFilter() = %+v, want %+v", got, want)
+	}
+	if got := parseImageSizeFilter(url.Values{"maxSizeMB": {"big"}}); got != (imageSizeFilter{}) {
+		t.Errorf("invalid values: parseImageSizeFilter() = %+v, want no bounds", got)
+	}
+}
+
 func TestBuildImagesQuery(t *testing.T) {
 	tests := []struct {
 		name            string
@@ -1001,34 +1153,89 @@ func TestBuildImagesQuery(t *testing.T) {
 		vulnStatuses    []string
 		packageTypes    []string
 		osNames         []string
+		registries      []string
+		slsaLevels      []string
+		builders        []string
+		sources         []string
+		vendors         []string
+		size            imageSizeFilter
+		cmdb            cmdbFilter
 		sortBy          string
 		sortOrder       string
 		expectedInQuery []string
+		expectedArgs    []interface{}
 	}{
 		{
 			name:            "with search term",
 			search:          "nginx",
-			expectedInQuery: []string{"nginx", "LIKE"},
+			expectedInQuery: []string{"instances.reference LIKE ?1", "instances.pod LIKE ?1"},
+			expectedArgs:    []interface{}{"%nginx%"},
+		},
+		{
+			name:            "with digest search",
+			search:          "app@sha256:ABCDEF12",
+			expectedInQuery: []string{"images.digest LIKE ?2", "as match_type"},
+			expectedArgs:    []interface{}{"%app@sha256:ABCDEF12%", "sha256:abcdef12%"},
 		},
 		{
 			name:            "with namespace filter",
 			namespaces:      []string{"default", "kube-system"},
-			expectedInQuery: []string{"instances.namespace IN ('default','kube-system')"},
+			expectedInQuery: []string{"instances.namespace IN (?1,?2)"},
+			expectedArgs:    []interface{}{"default", "kube-system"},
 		},
 		{
 			name:            "with vulnerability status filter",
 			vulnStatuses:    []string{"fixed", "not-fixed"},
-			expectedInQuery: []string{"fix_status IN ('fixed','not-fixed')"},
+			expectedInQuery: []string{"fix_status IN (?1,?2)"},
+			expectedArgs:    []interface{}{"fixed", "not-fixed"},
 		},
 		{
 			name:            "with package type filter",
 			packageTypes:    []string{"apk", "deb"},
-			expectedInQuery: []string{"type IN ('apk','deb')"},
+			expectedInQuery: []string{"type IN (?1,?2)", "package_type IN (?3,?4)"},
+			expectedArgs:    []interface{}{"apk", "deb", "apk", "deb"},
 		},
 		{
 			name:            "with OS name filter",
 			osNames:         []string{"alpine:3.18", "ubuntu:22.04"},
-			expectedInQuery: []string{"images.os_name IN ('alpine:3.18','ubuntu:22.04')"},
+			expectedInQuery: []string{"images.os_name IN (?1,?2)"},
+			expectedArgs:    []interface{}{"alpine:3.18", "ubuntu:22.04"},
+		},
+		{
+			name:            "with registry filter",
+			registries:      []string{"docker.io", "quay.io"},
+			expectedInQuery: []string{"instances.registry IN (?1,?2)"},
+			expectedArgs:    []interface{}{"docker.io", "quay.io"},
+		},
+		{
+			name:            "with provenance filters",
+			slsaLevels:      []string{"0", "1"},
+			builders:        []string{"https://github.com/docker/buildx"},
+			expectedInQuery: []string{"images.provenance_slsa_level IN (?1,?2)", "images.provenance_builder IN (?3)", "as slsa_level"},
+			expectedArgs:    []interface{}{"0", "1", "https://github.com/docker/buildx"},
+		},
+		{
+			name:            "with OCI label filters",
+			sources:         []string{"https://github.com/example/app"},
+			vendors:         []string{"Example", "Acme"},
+			expectedInQuery: []string{"images.oci_source IN (?1)", "images.oci_vendor IN (?2,?3)", "images.oci_version,"},
+			expectedArgs:    []interface{}{"https://github.com/example/app", "Example", "Acme"},
+		},
+		{
+			name:            "with size filters",
+			size:            imageSizeFilter{minBytes: 1024, maxBytes: 2048, maxLayers: 10},
+			sortBy:          "image_size",
+			sortOrder:       "DESC",
+			expectedInQuery: []string{"images.image_size >= ?1", "images.image_size <= ?2", "images.layer_count <= ?3", "image_size DESC", "images.layer_count,"},
+			expectedArgs:    []interface{}{int64(1024), int64(2048), 10},
+		},
+		{
+			name: "with CMDB owner filter",
+			cmdb: cmdbFilter{owners: []string{"team-payments"}},
+			expectedInQuery: []string{"m.owner IN (?4)", "m.label = ?1 AND m.label_value = images.oci_source",
+				"m.owner != ''", "m.namespace = instances.namespace"},
+			expectedArgs: []interface{}{"org.opencontainers.image.source", "org.opencontainers.image.vendor",
+				"org.opencontainers.image.version", "team-payments"},
 		},
 		{
 			name:            "with custom sort",
@@ -1039,27 +1246,38 @@ func TestBuildImagesQuery(t *testing.T) {
 		{
 			name:            "SQL injection attempt - search",
 			search:          "'; DROP TABLE images; --",
-			expectedInQuery: []string{"''; DROP TABLE images; --"}, // Should be escaped
+			expectedInQuery: []string{"instances.reference LIKE ?1"},
+			expectedArgs:    []interface{}{"%'; DROP TABLE images; --%"}, // Bound, never part of the SQL
 		},
 		{
 			name:            "SQL injection attempt - namespace",
 			namespaces:      []string{"' OR '1'='1"},
-			expectedInQuery: []string{"''' OR ''1''=''1'"}, // Single quotes are escaped by doubling
+			expectedInQuery: []string{"instances.namespace IN (?1)"},
+			expectedArgs:    []interface{}{"' OR '1'='1"},
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			mainQuery, countQuery := buildImagesQuery(
+			mainQuery, countQuery, args := buildImagesQuery(
 				tt.search,
 				tt.namespaces,
 				tt.vulnStatuses,
 				tt.packageTypes,
 				tt.osNames,
+				tt.registries,
+				tt.slsaLevels,
+				tt.builders,
+				tt.sources,
+				tt.vendors,
+				tt.size,
+				tt.cmdb,
 				tt.sortBy,
 				tt.sortOrder,
 				50,
 				0,
+				false,
+				nil,
 			)
 
 			// Check that expected strings are in the query
@@ -1069,6 +1287,12 @@ func TestBuildImagesQuery(t *testing.T) {
 						expected, mainQuery, countQuery)
 				}
 			}
+			if !reflect.DeepEqual(args, tt.expectedArgs) && (len(args) > 0 || len(tt.expectedArgs) > 0) {
+				t.Errorf("args = %#v, want %#v", args, tt.expectedArgs)
+			}
+			if strings.Contains(mainQuery, "'default'") || strings.Contains(mainQuery, "DROP TABLE") {
+				t.Errorf("Expected filter values to be bound, not interpolated\nMain query: %s", mainQuery)
+			}
 
 			// Basic sanity checks
 			if !strings.Contains(mainQuery, "LIMIT 50") {
@@ -1081,36 +1305,143 @@ func TestBuildImagesQuery(t *testing.T) {
 	}
 }
 
+func TestImagesHandlerSearch(t *testing.T) {
+	db := createTransferTestDB(t, "search")
+	apiDigest := "sha256:abcdef1234567890abcdef1234567890abcdef1234567890abcdef1234567890"
+	for _, c := range []containers.Container{
+		{
+			ID:    containers.ContainerID{Namespace: "default", Pod: "web", Name: "nginx"},
+			Image: containers.ImageID{Reference: "registry.example.com/team/nginx:1.25", Digest: testTransferDigest},
+		},
+		{
+			ID:    containers.ContainerID{Namespace: "payments", Pod: "checkout-7d9f", Name: "api"},
+			Image: containers.ImageID{Reference: "registry.example.com/team/api:2", Digest: apiDigest},
+		},
+	} {
+		if _, err := db.AddContainer(c); err != nil {
+			t.Fatalf("Failed to add container: %v", err)
+		}
+	}
+
+	tests := []struct {
+		search    string
+		wantImage string
+		wantMatch string
+	}{
+		{"nginx", "registry.example.com/team/nginx:1.25", "image"},
+		{"sha256:abcdef12", "registry.example.com/team/api:2", "digest"},
+		{"registry.example.com/team/api@sha256:abcdef1234", "registry.example.com/team/api:2", "digest"},
+		{"ABCDEF", "registry.example.com/team/api:2", "digest"},
+		{"checkout", "registry.example.com/team/api:2", "pod"},
+		{"payments", "registry.example.com/team/api:2", "namespace"},
+		{"abc", "", ""}, // too short for a digest prefix
+	}
+	for _, tt := range tests {
+		t.Run(tt.search, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, "/api/images?search="+url.QueryEscape(tt.search), nil)
+			ImagesHandler(db, nil, nil)(rec, req)
+			if rec.Code != http.StatusOK {
+				t.Fatalf("status = %d: %s", rec.Code, rec.Body.String())
+			}
+
+			var response struct {
+				Images []map[string]interface{} `json:"images"`
+			}
+			if err := json.NewDecoder(rec.Body).Decode(&response); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			if tt.wantImage == "" {
+				if len(response.Images) != 0 {
+					t.Errorf("expected no images, got %v", response.Images)
+				}
+				return
+			}
+			if len(response.Images) != 1 {
+				t.Fatalf("expected 1 image, got %v", response.Images)
+			}
+			if got := response.Images[0]["image"]; got != tt.wantImage {
+				t.Errorf("image = %v, want %s", got, tt.wantImage)
+			}
+			if got := response.Images[0]["match_type"]; got != tt.wantMatch {
+				t.Errorf("match_type = %v, want %s", got, tt.wantMatch)
+			}
+		})
+	}
+}
+
+// TestImagesHandlerQuotedFilterValues verifies filter values containing quotes
+// reach the database intact as bound arguments.
+func TestImagesHandlerQuotedFilterValues(t *testing.T) {
+	db := createTransferTestDB(t, "quoted")
+	if _, err := db.AddContainer(containers.Container{
+		ID:    containers.ContainerID{Namespace: "o'brien", Pod: "web", Name: "nginx"},
+		Image: containers.ImageID{Reference: "nginx:1.25", Digest: testTransferDigest},
+	}); err != nil {
+		t.Fatalf("Failed to add container: %v", err)
+	}
+
+	for _, query := range []string{"namespaces=o'brien", "search=o'bri", "namespaces=o'brien&search=o'brien"} {
+		t.Run(query, func(t *testing.T) {
+			for _, handler := range []http.HandlerFunc{ImagesHandler(db, nil, nil), ContainersHandler(db, nil)} {
+				rec := httptest.NewRecorder()
+				handler(rec, httptest.NewRequest(http.MethodGet, "/api/images?"+strings.ReplaceAll(query, "'", "%27"), nil))
+				if rec.Code != http.StatusOK {
+					t.Fatalf("status = %d: %s", rec.Code, rec.Body.String())
+				}
+				var response struct {
+					TotalCount int64 `json:"totalCount"`
+				}
+				if err := json.NewDecoder(rec.Body).Decode(&response); err != nil {
+					t.Fatalf("failed to decode response: %v", err)
+				}
+				if response.TotalCount != 1 {
+					t.Errorf("totalCount = %d, want 1", response.TotalCount)
+				}
+			}
+		})
+	}
+}
+
 func TestBuildContainersQuery(t *testing.T) {
 	tests := []struct {
 		name            string
 		search          string
 		namespaces      []string
+		registries      []string
 		expectedInQuery []string
 	}{
 		{
 			name:            "with pod search",
 			search:          "nginx-pod",
-			expectedInQuery: []string{"nginx-pod", "instances.namespace LIKE", "instances.pod LIKE", "instances.name LIKE"},
+			expectedInQuery: []string{"instances.namespace LIKE ?1", "instances.pod LIKE ?1", "instances.name LIKE ?1"},
 		},
 		{
 			name:            "basic query",
 			expectedInQuery: []string{"containers instances", "images images"},
 		},
+		{
+			name:            "with registry filter",
+			registries:      []string{"docker.io"},
+			expectedInQuery: []string{"instances.registry IN (?1)", "instances.image_pull_policy", "instances.node_name"},
+		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			mainQuery, _ := buildContainersQuery(
+			mainQuery, _, _ := buildContainersQuery(
 				tt.search,
 				tt.namespaces,
 				nil,
 				nil,
 				nil,
+				tt.registries,
 				"",
 				"ASC",
 				50,
 				0,
+				false,
+				nil,
 			)
 
 			for _, expected := range tt.expectedInQuery {
@@ -1126,8 +1457,8 @@ func TestBuildContainersQuery(t *testing.T) {
 // are calculated by multiplying by vulnerability count (for consistency with metrics)
 func TestRiskAndExploitCalculation(t *testing.T) {
 	t.Run("images query multiplies risk by count", func(t *testing.T) {
-		mainQuery, _ := buildImagesQuery(
-			"", nil, nil, nil, nil, "", "ASC", 50, 0,
+		mainQuery, _, _ := buildImagesQuery(
+			"", nil, nil, nil, nil, nil, nil, nil, nil, nil, imageSizeFilter{}, cmdbFilter{}, "", "ASC", 50, 0, false, nil,
 		)
 
 		// Verify risk calculation uses count multiplier
@@ -1142,8 +1473,8 @@ func TestRiskAndExploitCalculation(t *testing.T) {
 	})
 
 	t.Run("containers query multiplies risk by count", func(t *testing.T) {
-		mainQuery, _ := buildContainersQuery(
-			"", nil, nil, nil, nil, "", "ASC", 50, 0,
+		mainQuery, _, _ := buildContainersQuery(
+			"", nil, nil, nil, nil, nil, "", "ASC", 50, 0, false, nil,
 		)
 
 		// Verify risk calculation uses count multiplier
@@ -1158,3 +1489,164 @@ func TestRiskAndExploitCalculation(t *testing.T) {
 	})
 }
 
+// TestImagesHandlers_SoftDeletedImages verifies that images soft-deleted by the
+// cleanup job are hidden from the image list and detail endpoints unless
+// ?includeDeleted=true is given, and are then listed under their last reference
+func TestImagesHandlers_SoftDeletedImages(t *testing.T) {
+	db, err := database.New(filepath.Join(t.TempDir(), "images.db"))
+	if err != nil {
+		t.Fatalf("Failed to create test database: %v", err)
+	}
+	defer func() { _ = database.Close(db) }()
+
+	for _, c := range []containers.Container{
+		{
+			ID:    containers.ContainerID{Namespace: "default", Pod: "web", Name: "nginx"},
+			Image: containers.ImageID{Reference: "nginx:1.25", Digest: "sha256:deleted"},
+		},
+		{
+			ID:    containers.ContainerID{Namespace: "default", Pod: "cache", Name: "redis"},
+			Image: containers.ImageID{Reference: "redis:7", Digest: "sha256:running"},
+		},
+	} {
+		if _, err := db.AddContainer(c); err != nil {
+			t.Fatalf("Failed to add container: %v", err)
+		}
+	}
+	if err := db.RemoveContainer(containers.ContainerID{Namespace: "default", Pod: "web", Name: "nginx"}); err != nil {
+		t.Fatalf("Failed to remove container: %v", err)
+	}
+	if _, err := db.CleanupOrphanedImages(); err != nil {
+		t.Fatalf("CleanupOrphanedImages() error = %v", err)
+	}
+
+	listImages := func(query string) []map[string]interface{} {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		ImagesHandler(db, nil, nil)(rec, httptest.NewRequest(http.MethodGet, "/api/images"+query, nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("status = %d, body = %s", rec.Code, rec.Body.String())
+		}
+		var response struct {
+			Images     []map[string]interface{} `json:"images"`
+			TotalCount int                      `json:"totalCount"`
+		}
+		if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+			t.Fatalf("Failed to parse response: %v", err)
+		}
+		if response.TotalCount != len(response.Images) {
+			t.Errorf("totalCount = %d, want %d", response.TotalCount, len(response.Images))
+		}
+		return response.Images
+	}
+
+	images := listImages("")
+	if len(images) != 1 || images[0]["digest"] != "sha256:running" {
+		t.Fatalf("Expected only the running image, got %v", images)
+	}
+
+	images = listImages("?includeDeleted=true")
+	if len(images) != 2 {
+		t.Fatalf("Expected 2 images with includeDeleted, got %v", images)
+	}
+	for _, image := range images {
+		switch image["digest"] {
+		case "sha256:deleted":
+			if image["image"] != "nginx:1.25" || image["container_count"] != float64(0) || image["deleted_at"] == nil {
+				t.Errorf("Unexpected deleted image row: %v", image)
+			}
+		case "sha256:running":
+			if image["image"] != "redis:7" || image["container_count"] != float64(1) || image["deleted_at"] != nil {
+				t.Errorf("Unexpected running image row: %v", image)
+			}
+		}
+	}
+
+	getDetail := func(query string) int {
+		rec := httptest.NewRecorder()
+		ImageDetailFullHandler(db, nil)(rec, httptest.NewRequest(http.MethodGet, "/api/images/sha256:deleted"+query, nil))
+		return rec.Code
+	}
+	if code := getDetail(""); code != http.StatusNotFound {
+		t.Errorf("deleted image detail status = %d, want 404", code)
+	}
+	if code := getDetail("?includeDeleted=true"); code != http.StatusOK {
+		t.Errorf("deleted image detail with includeDeleted status = %d, want 200", code)
+	}
+}
+
+func TestImagesHandlers_ComputedColumns(t *testing.T) {
+	db := createTransferTestDB(t, "computed")
+	for i, c := range []containers.Container{
+		{ID: containers.ContainerID{Namespace: "default", Pod: "web", Name: "nginx"},
+			Image: containers.ImageID{Reference: "nginx:1.25", Digest: "sha256:nginx"}},
+		{ID: containers.ContainerID{Namespace: "default", Pod: "cache", Name: "redis"},
+			Image: containers.ImageID{Reference: "redis:7", Digest: "sha256:redis"}},
+	} {
+		if _, err := db.AddContainer(c); err != nil {
+			t.Fatalf("Failed to add container: %v", err)
+		}
+		// nginx: 1 critical; redis: 2 high
+		vulns := `{"matches":[{"vulnerability":{"id":"CVE-2024-0001","severity":"Critical"},"artifact":{"name":"a","version":"1","type":"deb"}}]}`
+		if i == 1 {
+			vulns = `{"matches":[
+				{"vulnerability":{"id":"CVE-2024-0002","severity":"High"},"artifact":{"name":"b","version":"1","type":"deb"}},
+				{"vulnerability":{"id":"CVE-2024-0003","severity":"High"},"artifact":{"name":"c","version":"1","type":"deb"}}]}`
+		}
+		if err := db.ImportScanResults(c.Image.Digest, []byte(`{"artifacts":[]}`), []byte(vulns), time.Time{}); err != nil {
+			t.Fatalf("ImportScanResults() error = %v", err)
+		}
+	}
+	computed, err := columns.Parse("weighted_score=critical*10+high*3,per_container=total_cves/container_count")
+	if err != nil {
+		t.Fatalf("columns.Parse() error = %v", err)
+	}
+
+	rec := httptest.NewRecorder()
+	ImagesHandler(db, nil, computed)(rec, httptest.NewRequest(http.MethodGet, "/api/images?sortBy=weighted_score&sortOrder=DESC", nil))
+	var response struct {
+		Images []map[string]interface{} `json:"images"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+	if len(response.Images) != 2 {
+		t.Fatalf("Expected 2 images, got %v", response.Images)
+	}
+	if first := response.Images[0]; first["image"] != "nginx:1.25" || first["weighted_score"] != float64(10) || first["per_container"] != float64(1) {
+		t.Errorf("first image sorted by weighted_score = %v", first)
+	}
+	if second := response.Images[1]; second["weighted_score"] != float64(6) || second["per_container"] != float64(2) {
+		t.Errorf("second image = %v", second)
+	}
+
+	rec = httptest.NewRecorder()
+	ImagesHandler(db, nil, computed)(rec, httptest.NewRequest(http.MethodGet, "/api/images?format=csv", nil))
+	records, err := csv.NewReader(rec.Body).ReadAll()
+	if err != nil || len(records) != 3 {
+		t.Fatalf("CSV = %v, %v", records, err)
+	}
+	if header := strings.Join(records[0], ","); !strings.Contains(header, "weighted_score") || !strings.Contains(header, "per_container") {
+		t.Errorf("CSV header = %s, want the computed columns", header)
+	}
+
+	// container_count is per image, so the container list only gets weighted_score
+	rec = httptest.NewRecorder()
+	ContainersHandler(db, computed)(rec, httptest.NewRequest(http.MethodGet, "/api/containers?sortBy=weighted_score", nil))
+	var containerResponse struct {
+		Containers []map[string]interface{} `json:"containers"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &containerResponse); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+	if len(containerResponse.Containers) != 2 {
+		t.Fatalf("Expected 2 containers, got %s", rec.Body.String())
+	}
+	first := containerResponse.Containers[0]
+	if first["pod"] != "cache" || first["weighted_score"] != float64(6) {
+		t.Errorf("first container sorted by weighted_score = %v", first)
+	}
+	if _, ok := first["per_container"]; ok {
+		t.Errorf("container row has per_container: %v", first)
+	}
+}
@@ -2,14 +2,15 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 	"strconv"
 
 	"github.com/bvboe/b2s-go/scanner-core/database"
+	"github.com/bvboe/b2s-go/scanner-core/routes"
 	"github.com/bvboe/b2s-go/scanner-core/scheduler"
 )
 
-
 // JobExecutionStore defines the interface for job execution database operations
 type JobExecutionStore interface {
 	GetJobExecutions(jobName string, limit int) ([]database.JobExecution, error)
@@ -103,6 +104,78 @@ func JobsTriggerHandler(sched *scheduler.Scheduler) http.HandlerFunc {
 	}
 }
 
+// JobsStatusHandler handles GET /api/jobs - lists the scheduled jobs with
+// their schedule, next run and the outcome of their runs so far
+//
+// Response: {"jobs": [{"name": "cleanup", "interval": "1h0m0s", "last_success": "...", "consecutive_failures": 0, ...}]}
+func JobsStatusHandler(sched *scheduler.Scheduler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		if sched == nil {
+			http.Error(w, "Scheduler not initialized", http.StatusServiceUnavailable)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(map[string]interface{}{
+			"jobs": sched.Status(),
+		}); err != nil {
+			log.Error("error encoding jobs status response", "error", err)
+		}
+	}
+}
+
+// JobRunHandler handles POST /api/jobs/{name}/run - runs a job now. Unknown
+// jobs return 404 and jobs that are already running 409.
+//
+// Response: {"status": "started", "job": "cleanup"}
+func JobRunHandler(sched *scheduler.Scheduler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		if sched == nil {
+			http.Error(w, "Scheduler not initialized", http.StatusServiceUnavailable)
+			return
+		}
+
+		jobName := r.PathValue("name")
+		if jobName == "" {
+			http.Error(w, "Job name required", http.StatusBadRequest)
+			return
+		}
+
+		if err := sched.RunJobNow(jobName); err != nil {
+			switch {
+			case errors.Is(err, scheduler.ErrJobNotFound):
+				http.Error(w, err.Error(), http.StatusNotFound)
+			case errors.Is(err, scheduler.ErrJobRunning):
+				http.Error(w, err.Error(), http.StatusConflict)
+			default:
+				log.Error("failed to run job", "job_name", jobName, "error", err)
+				http.Error(w, err.Error(), http.StatusServiceUnavailable)
+			}
+			return
+		}
+		log.Info("job run requested", "job_name", jobName)
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusAccepted)
+		if err := json.NewEncoder(w).Encode(map[string]interface{}{
+			"status": "started",
+			"job":    jobName,
+		}); err != nil {
+			log.Error("error encoding job run response", "error", err)
+		}
+	}
+}
+
 // JobExecutionsHandler handles GET /api/debug/jobs/history - returns job execution history
 // Query params:
 //   - job: filter by job name (optional)
@@ -150,17 +223,32 @@ func JobExecutionsHandler(db JobExecutionStore) http.HandlerFunc {
 	}
 }
 
-// RegisterJobsHandlers registers the jobs debug endpoints
-func RegisterJobsHandlers(mux *http.ServeMux, sched *scheduler.Scheduler) {
-	mux.HandleFunc("/api/debug/jobs", JobsListHandler(sched))
-	mux.HandleFunc("/api/debug/jobs/", JobsTriggerHandler(sched)) // Matches /api/debug/jobs/{name}/trigger
+// jobStatusRoutes are the job status and manual run endpoints
+func jobStatusRoutes(sched *scheduler.Scheduler) []routes.Route {
+	return []routes.Route{
+		{Pattern: "/api/jobs", Methods: routes.GET, Handler: JobsStatusHandler(sched), CacheControl: routes.NoStore},
+		{Pattern: "/api/jobs/{name}/run", Methods: routes.POST, Handler: JobRunHandler(sched), Role: routes.RoleAdmin},
+	}
+}
+
+// RegisterJobsHandlers registers the job status endpoints and the jobs debug endpoints
+func RegisterJobsHandlers(reg *routes.Registry, sched *scheduler.Scheduler) {
+	reg.Handle(jobStatusRoutes(sched)...)
+	reg.Handle(
+		routes.Route{Pattern: "/api/debug/jobs", Methods: routes.GET, Handler: JobsListHandler(sched), Role: routes.RoleAdmin},
+		routes.Route{Pattern: "/api/debug/jobs/", Methods: routes.POST, Handler: JobsTriggerHandler(sched), Role: routes.RoleAdmin}, // Matches /api/debug/jobs/{name}/trigger
+	)
 	log.Info("jobs debug handlers registered", "path", "/api/debug/jobs")
 }
 
-// RegisterJobsHandlersWithDB registers all jobs debug endpoints including execution history
-func RegisterJobsHandlersWithDB(mux *http.ServeMux, sched *scheduler.Scheduler, db JobExecutionStore) {
-	mux.HandleFunc("/api/debug/jobs", JobsListHandler(sched))
-	mux.HandleFunc("/api/debug/jobs/history", JobExecutionsHandler(db))
-	mux.HandleFunc("/api/debug/jobs/", JobsTriggerHandler(sched)) // Matches /api/debug/jobs/{name}/trigger
+// RegisterJobsHandlersWithDB registers the job status endpoints and all jobs
+// debug endpoints including execution history
+func RegisterJobsHandlersWithDB(reg *routes.Registry, sched *scheduler.Scheduler, db JobExecutionStore) {
+	reg.Handle(jobStatusRoutes(sched)...)
+	reg.Handle(
+		routes.Route{Pattern: "/api/debug/jobs", Methods: routes.GET, Handler: JobsListHandler(sched), Role: routes.RoleAdmin},
+		routes.Route{Pattern: "/api/debug/jobs/history", Methods: routes.GET, Handler: JobExecutionsHandler(db), Role: routes.RoleAdmin},
+		routes.Route{Pattern: "/api/debug/jobs/", Methods: routes.POST, Handler: JobsTriggerHandler(sched), Role: routes.RoleAdmin}, // Matches /api/debug/jobs/{name}/trigger
+	)
 	log.Info("jobs debug handlers registered", "path", "/api/debug/jobs", "with_history", true)
 }
@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/bvboe/b2s-go/scanner-core/routes"
+	"github.com/bvboe/b2s-go/scanner-core/scheduler"
+)
+
+// blockingJob runs until release is closed
+type blockingJob struct {
+	release chan struct{}
+}
+
+func (j *blockingJob) Name() string { return "blocking" }
+
+func (j *blockingJob) Run(ctx context.Context) error {
+	select {
+	case <-j.release:
+	case <-ctx.Done():
+	}
+	return nil
+}
+
+func TestJobsStatusAndRunHandlers(t *testing.T) {
+	sched := scheduler.New()
+	job := &blockingJob{release: make(chan struct{})}
+	if err := sched.AddJob(job, scheduler.NewIntervalSchedule(time.Hour), scheduler.JobConfig{Enabled: true}); err != nil {
+		t.Fatalf("failed to add job: %v", err)
+	}
+	if err := sched.Start(context.Background()); err != nil {
+		t.Fatalf("failed to start scheduler: %v", err)
+	}
+	defer func() { _ = sched.Stop() }()
+	defer close(job.release)
+
+	reg := routes.NewRegistry()
+	RegisterJobsHandlers(reg, sched)
+	do := func(method, path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		reg.ServeHTTP(rec, httptest.NewRequest(method, path, nil))
+		return rec
+	}
+
+	if rec := do(http.MethodPost, "/api/jobs/blocking/run"); rec.Code != http.StatusAccepted {
+		t.Fatalf("expected 202 for run, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if rec := do(http.MethodPost, "/api/jobs/blocking/run"); rec.Code != http.StatusConflict {
+		t.Errorf("expected 409 for a running job, got %d", rec.Code)
+	}
+	if rec := do(http.MethodPost, "/api/jobs/missing/run"); rec.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for an unknown job, got %d", rec.Code)
+	}
+	if rec := do(http.MethodGet, "/api/jobs/blocking/run"); rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("expected 405 for GET, got %d", rec.Code)
+	}
+
+	rec := do(http.MethodGet, "/api/jobs")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rec.Code)
+	}
+	var body struct {
+		Jobs []scheduler.JobStatus `json:"jobs"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if len(body.Jobs) != 1 || body.Jobs[0].Name != "blocking" || !body.Jobs[0].Running || body.Jobs[0].Interval != "1h0m0s" {
+		t.Errorf("unexpected jobs: %+v", body.Jobs)
+	}
+}
+
+func TestJobsStatusHandlerWithoutScheduler(t *testing.T) {
+	reg := routes.NewRegistry()
+	RegisterJobsHandlers(reg, nil)
+	rec := httptest.NewRecorder()
+	reg.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/jobs", nil))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected 503 without a scheduler, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,59 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/bvboe/b2s-go/scanner-core/debug"
+	"github.com/bvboe/b2s-go/scanner-core/logging"
+)
+
+// DebugLogLevelRequest is the body of POST /api/debug/log-level
+type DebugLogLevelRequest struct {
+	// Level is debug, info, warn or error
+	Level string `json:"level"`
+}
+
+// maxDebugLogLevelRequestSize bounds the log level request body
+const maxDebugLogLevelRequestSize = 1 << 10
+
+// DebugLogLevelHandler creates an HTTP handler for /api/debug/log-level. GET
+// reports the current log level, POST changes it until the next restart, so
+// a running server can be switched to debug logging while a problem is
+// reproduced without redeploying with LOG_LEVEL.
+//
+// Request: {"level": "debug"}
+// Response: {"level": "debug"}
+func DebugLogLevelHandler(debugConfig *debug.DebugConfig) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !debugCategoryEnabled(w, debugConfig, debug.CategoryLogLevel) {
+			return
+		}
+
+		switch r.Method {
+		case http.MethodGet:
+		case http.MethodPost:
+			var req DebugLogLevelRequest
+			if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxDebugLogLevelRequestSize)).Decode(&req); err != nil {
+				http.Error(w, "Invalid request body", http.StatusBadRequest)
+				return
+			}
+			level, err := logging.ParseLevel(req.Level)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			previous := logging.GetLevel()
+			logging.SetLevel(level)
+			log.Info("log level changed", "from", previous, "to", logging.GetLevel())
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(map[string]string{"level": logging.GetLevel()}); err != nil {
+			log.Error("error encoding log level response", "error", err)
+		}
+	}
+}
@@ -0,0 +1,52 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/bvboe/b2s-go/scanner-core/debug"
+	"github.com/bvboe/b2s-go/scanner-core/logging"
+)
+
+func TestDebugLogLevelHandler(t *testing.T) {
+	previous, _ := logging.ParseLevel(logging.GetLevel())
+	defer logging.SetLevel(previous)
+
+	serve := func(debugConfig *debug.DebugConfig, method, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/api/debug/log-level", strings.NewReader(body))
+		rec := httptest.NewRecorder()
+		DebugLogLevelHandler(debugConfig)(rec, req)
+		return rec
+	}
+	enabled := debug.NewDebugConfigForCategories(debug.CategoryLogLevel)
+
+	if rec := serve(debug.NewDebugConfigForCategories(debug.CategorySQL), http.MethodPost, `{"level":"debug"}`); rec.Code != http.StatusForbidden {
+		t.Errorf("category disabled: status = %d, want %d", rec.Code, http.StatusForbidden)
+	}
+	if rec := serve(enabled, http.MethodPost, `{"level":"verbose"}`); rec.Code != http.StatusBadRequest {
+		t.Errorf("unknown level: status = %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+	if rec := serve(enabled, http.MethodDelete, ""); rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("DELETE: status = %d, want %d", rec.Code, http.StatusMethodNotAllowed)
+	}
+
+	if rec := serve(enabled, http.MethodPost, `{"level":"WARN"}`); rec.Code != http.StatusOK {
+		t.Fatalf("POST: status = %d: %s", rec.Code, rec.Body.String())
+	}
+	if logging.Default().Enabled(t.Context(), slog.LevelInfo) {
+		t.Error("info logging still enabled after switching to warn")
+	}
+
+	rec := serve(enabled, http.MethodGet, "")
+	var resp DebugLogLevelRequest
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if resp.Level != "warn" {
+		t.Errorf("GET level = %q, want warn", resp.Level)
+	}
+}
@@ -0,0 +1,65 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/bvboe/b2s-go/scanner-core/database"
+	"github.com/bvboe/b2s-go/scanner-core/routes"
+)
+
+// MigrationStatusHandler creates an HTTP handler for /api/status/migration endpoint
+// Reports the schema version and the progress of background (online) table
+// rewrites, which run while the API is serving.
+func MigrationStatusHandler(db *database.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		status, err := db.GetMigrationStatus()
+		if err != nil {
+			log.Error("error reading migration status", "error", err)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(status); err != nil {
+			log.Error("error encoding migration status response", "error", err)
+		}
+	}
+}
+
+// CompatibilityHandler creates an HTTP handler for /api/status/compatibility endpoint
+// Reports the binary and schema versions, the oldest version a downgrade can
+// go back to and whether migrations run on the next restart, so upgrades of
+// many deployments can be sequenced.
+func CompatibilityHandler(db *database.DB, version string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		compatibility, err := db.GetCompatibility(version)
+		if err != nil {
+			log.Error("error reading schema compatibility", "error", err)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(compatibility); err != nil {
+			log.Error("error encoding compatibility response", "error", err)
+		}
+	}
+}
+
+// RegisterMigrationHandlers registers the migration status and compatibility
+// endpoints; version is the version of this binary
+func RegisterMigrationHandlers(reg *routes.Registry, db *database.DB, version string) {
+	reg.Handle(routes.Route{Pattern: "/api/status/migration", Methods: routes.GET, Handler: MigrationStatusHandler(db), CacheControl: routes.NoStore})
+	reg.Handle(routes.Route{Pattern: "/api/status/compatibility", Methods: routes.GET, Handler: CompatibilityHandler(db, version), CacheControl: routes.NoStore})
+}
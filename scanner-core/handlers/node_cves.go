@@ -55,6 +55,8 @@ func NodeCVEsHandler(db *database.DB) http.HandlerFunc {
 		severities := parseMultiSelect(params.Get("severity"))
 		fixStatuses := parseMultiSelect(params.Get("vulnStatuses"))
 		packageTypes := parseMultiSelect(params.Get("packageTypes"))
+		// Vulnerability search matches the identifier or any of its aliases (CVE ↔ RHSA/ALAS)
+		vulnerability := params.Get("vulnerability")
 
 		// Sorting
 		sortBy := params.Get("sortBy")
@@ -63,9 +65,9 @@ func NodeCVEsHandler(db *database.DB) http.HandlerFunc {
 			sortOrder = "ASC"
 		}
 
-		query, countQuery := buildNodeCVEsQuery(osNames, severities, fixStatuses, packageTypes, sortBy, sortOrder, pageSize, offset)
+		query, countQuery, args := buildNodeCVEsQuery(osNames, severities, fixStatuses, packageTypes, vulnerability, sortBy, sortOrder, pageSize, offset)
 
-		countResult, err := db.ExecuteReadOnlyQuery(countQuery)
+		countResult, err := db.ExecuteReadOnlyQueryArgs(countQuery, args...)
 		if err != nil {
 			log.Error("error executing node CVE count query", "error", err)
 			http.Error(w, "Internal server error", http.StatusInternalServerError)
@@ -78,7 +80,7 @@ func NodeCVEsHandler(db *database.DB) http.HandlerFunc {
 			}
 		}
 
-		result, err := db.ExecuteReadOnlyQuery(query)
+		result, err := db.ExecuteReadOnlyQueryArgs(query, args...)
 		if err != nil {
 			log.Error("error executing node CVE query", "error", err)
 			http.Error(w, "Internal server error", http.StatusInternalServerError)
@@ -86,7 +88,7 @@ func NodeCVEsHandler(db *database.DB) http.HandlerFunc {
 		}
 
 		if format == "csv" {
-			exportQueryResultAsCSV(w, result, "node_cves.csv")
+			exportQueryResultAsCSV(w, r, result, "node_cves.csv")
 			return
 		}
 
@@ -114,12 +116,15 @@ func NodeCVEsHandler(db *database.DB) http.HandlerFunc {
 // vulnerability_count reports the number of distinct affected nodes. Column
 // aliases match buildContainerCVEsQuery / image.html so the frontend table is
 // shared. Note node_vulnerabilities uses fix_version (not fixed_version).
-func buildNodeCVEsQuery(osNames, severities, fixStatuses, packageTypes []string, sortBy, sortOrder string, limit, offset int) (string, string) {
+// Both queries share the returned arguments.
+func buildNodeCVEsQuery(osNames, severities, fixStatuses, packageTypes []string, vulnerability, sortBy, sortOrder string, limit, offset int) (string, string, queryArgs) {
+	var args queryArgs
 	var conditions []string
-	conditions = appendCondition(conditions, buildINClause("n.os_release", osNames))
-	conditions = appendCondition(conditions, buildINClause("v.severity", severities))
-	conditions = appendCondition(conditions, buildINClause("v.fix_status", fixStatuses))
-	conditions = appendCondition(conditions, buildINClause("v.package_type", packageTypes))
+	conditions = appendCondition(conditions, args.in("n.os_release", osNames))
+	conditions = appendCondition(conditions, args.in("v.severity", severities))
+	conditions = appendCondition(conditions, args.in("v.fix_status", fixStatuses))
+	conditions = appendCondition(conditions, args.in("v.package_type", packageTypes))
+	conditions = appendCondition(conditions, args.vulnerabilityID("v.cve_id", vulnerability))
 	whereClause := buildWhereClause(conditions)
 
 	baseQuery := fmt.Sprintf(`
@@ -143,7 +148,9 @@ GROUP BY v.cve_id, v.package_name, v.package_version, v.fix_version, v.fix_statu
     v.severity as vulnerability_severity,
     MAX(v.risk) as vulnerability_risk,
     MAX(v.known_exploited) as vulnerability_known_exploits,
-    COUNT(DISTINCT n.id) as vulnerability_count`
+    COUNT(DISTINCT n.id) as vulnerability_count,
+    ` + vulnerabilityAliasesColumn("v.cve_id") + `,
+    ` + cveAnnotationColumn("v.cve_id")
 
 	mainQuery := selectClause + baseQuery + groupBy
 
@@ -181,7 +188,7 @@ GROUP BY v.cve_id, v.package_name, v.package_version, v.fix_version, v.fix_statu
 		mainQuery += fmt.Sprintf("\nLIMIT %d OFFSET %d", limit, offset)
 	}
 
-	return mainQuery, countQuery
+	return mainQuery, countQuery, args
 }
 
 // NodeCVEAffectedHandler creates an HTTP handler for /api/node-cves/affected.
@@ -202,7 +209,8 @@ func NodeCVEAffectedHandler(db *database.DB) http.HandlerFunc {
 			return
 		}
 
-		conditions := nodeCVEMatchConditions(params)
+		var args queryArgs
+		conditions := nodeCVEMatchConditions(&args, params)
 
 		query := fmt.Sprintf(`
 SELECT
@@ -214,7 +222,7 @@ WHERE %s
 GROUP BY n.name, n.os_release
 ORDER BY n.name`, strings.Join(conditions, " AND "))
 
-		result, err := db.ExecuteReadOnlyQuery(query)
+		result, err := db.ExecuteReadOnlyQueryArgs(query, args...)
 		if err != nil {
 			log.Error("error executing node CVE affected query", "error", err)
 			http.Error(w, "Internal server error", http.StatusInternalServerError)
@@ -249,7 +257,8 @@ func NodeCVEDetailVariantsHandler(db *database.DB) http.HandlerFunc {
 			return
 		}
 
-		conditions := nodeCVEMatchConditions(params)
+		var args queryArgs
+		conditions := nodeCVEMatchConditions(&args, params)
 
 		// UNIQUE(node_id, cve_id, package_name, package_version) guarantees at most
 		// one node_vulnerabilities row per node per finding, so one detail per node.
@@ -263,7 +272,7 @@ JOIN nodes n ON v.node_id = n.id
 WHERE %s
 ORDER BY n.name`, strings.Join(conditions, " AND "))
 
-		result, err := db.ExecuteReadOnlyQuery(query)
+		result, err := db.ExecuteReadOnlyQueryArgs(query, args...)
 		if err != nil {
 			log.Error("error executing node CVE detail variants query", "error", err)
 			http.Error(w, "Internal server error", http.StatusInternalServerError)
@@ -319,23 +328,24 @@ func groupNodeDetailVariants(rows []map[string]interface{}) []nodeDetailVariant
 
 // nodeCVEMatchConditions builds the WHERE conditions that identify a single CVE
 // finding (cve required; package name/version/type narrow it to the exact
-// grouped row). Shared by the affected and detail-variants handlers.
-func nodeCVEMatchConditions(params map[string][]string) []string {
+// grouped row), binding the values to args. Shared by the affected and
+// detail-variants handlers.
+func nodeCVEMatchConditions(args *queryArgs, params map[string][]string) []string {
 	get := func(k string) string {
 		if v, ok := params[k]; ok && len(v) > 0 {
 			return v[0]
 		}
 		return ""
 	}
-	conditions := []string{fmt.Sprintf("v.cve_id = '%s'", escapeSQL(get("cve")))}
+	conditions := []string{"v.cve_id = " + args.bind(get("cve"))}
 	if name := get("name"); name != "" {
-		conditions = append(conditions, fmt.Sprintf("v.package_name = '%s'", escapeSQL(name)))
+		conditions = append(conditions, "v.package_name = "+args.bind(name))
 	}
 	if version := get("version"); version != "" {
-		conditions = append(conditions, fmt.Sprintf("v.package_version = '%s'", escapeSQL(version)))
+		conditions = append(conditions, "v.package_version = "+args.bind(version))
 	}
 	if ptype := get("type"); ptype != "" {
-		conditions = append(conditions, fmt.Sprintf("v.package_type = '%s'", escapeSQL(ptype)))
+		conditions = append(conditions, "v.package_type = "+args.bind(ptype))
 	}
 	return conditions
 }
@@ -10,7 +10,7 @@ import (
 
 func TestBuildNodeCVEsQuery(t *testing.T) {
 	t.Run("groups and counts affected nodes", func(t *testing.T) {
-		mainQuery, countQuery := buildNodeCVEsQuery(nil, nil, nil, nil, "", "ASC", 100, 0)
+		mainQuery, countQuery, _ := buildNodeCVEsQuery(nil, nil, nil, nil, "", "", "ASC", 100, 0)
 
 		for _, frag := range []string{
 			"FROM node_vulnerabilities v",
@@ -32,13 +32,16 @@ func TestBuildNodeCVEsQuery(t *testing.T) {
 	})
 
 	t.Run("applies all filters (no namespace)", func(t *testing.T) {
-		mainQuery, _ := buildNodeCVEsQuery(
-			[]string{"wolfi"}, []string{"Critical"}, []string{"fixed"}, []string{"apk"}, "", "ASC", 100, 0)
+		mainQuery, _, args := buildNodeCVEsQuery(
+			[]string{"wolfi"}, []string{"Critical"}, []string{"fixed"}, []string{"apk"}, "", "", "ASC", 100, 0)
+		if len(args) != 4 || args[0] != "wolfi" || args[3] != "apk" {
+			t.Errorf("unexpected args: %v", args)
+		}
 		for _, frag := range []string{
-			"n.os_release IN ('wolfi')",
-			"v.severity IN ('Critical')",
-			"v.fix_status IN ('fixed')",
-			"v.package_type IN ('apk')",
+			"n.os_release IN (?1)",
+			"v.severity IN (?2)",
+			"v.fix_status IN (?3)",
+			"v.package_type IN (?4)",
 		} {
 			if !strings.Contains(mainQuery, frag) {
 				t.Errorf("query missing filter %q\nquery: %s", frag, mainQuery)
@@ -50,14 +53,14 @@ func TestBuildNodeCVEsQuery(t *testing.T) {
 	})
 
 	t.Run("export omits LIMIT", func(t *testing.T) {
-		mainQuery, _ := buildNodeCVEsQuery(nil, nil, nil, nil, "", "ASC", -1, 0)
+		mainQuery, _, _ := buildNodeCVEsQuery(nil, nil, nil, nil, "", "", "ASC", -1, 0)
 		if strings.Contains(mainQuery, "LIMIT") {
 			t.Errorf("export query should not contain LIMIT: %s", mainQuery)
 		}
 	})
 
 	t.Run("aggregate column sort uses alias", func(t *testing.T) {
-		mainQuery, _ := buildNodeCVEsQuery(nil, nil, nil, nil, "vulnerability_count", "DESC", 100, 0)
+		mainQuery, _, _ := buildNodeCVEsQuery(nil, nil, nil, nil, "", "vulnerability_count", "DESC", 100, 0)
 		if !strings.Contains(mainQuery, "vulnerability_count DESC") {
 			t.Errorf("expected order by vulnerability_count alias, got: %s", mainQuery)
 		}
@@ -95,12 +98,12 @@ func TestNodeCVEHandlersAgainstRealSchema(t *testing.T) {
 	db, cleanup := createTestDBForHandlers(t)
 	defer cleanup()
 
-	mainQuery, countQuery := buildNodeCVEsQuery(
-		[]string{"wolfi"}, []string{"Critical"}, []string{"fixed"}, []string{"apk"}, "vulnerability_count", "DESC", 100, 0)
-	if _, err := db.ExecuteReadOnlyQuery(countQuery); err != nil {
+	mainQuery, countQuery, args := buildNodeCVEsQuery(
+		[]string{"wolfi"}, []string{"Critical"}, []string{"fixed"}, []string{"apk"}, "RHSA-2024:1234", "vulnerability_count", "DESC", 100, 0)
+	if _, err := db.ExecuteReadOnlyQueryArgs(countQuery, args...); err != nil {
 		t.Fatalf("count query failed against real schema: %v\n%s", err, countQuery)
 	}
-	if _, err := db.ExecuteReadOnlyQuery(mainQuery); err != nil {
+	if _, err := db.ExecuteReadOnlyQueryArgs(mainQuery, args...); err != nil {
 		t.Fatalf("main query failed against real schema: %v\n%s", err, mainQuery)
 	}
 	for _, col := range []string{
@@ -108,8 +111,8 @@ func TestNodeCVEHandlersAgainstRealSchema(t *testing.T) {
 		"vulnerability_fix_versions", "vulnerability_fix_state", "artifact_type",
 		"vulnerability_risk", "vulnerability_known_exploits", "vulnerability_count", "",
 	} {
-		q, _ := buildNodeCVEsQuery(nil, nil, nil, nil, col, "ASC", 50, 0)
-		if _, err := db.ExecuteReadOnlyQuery(q); err != nil {
+		q, _, args := buildNodeCVEsQuery(nil, nil, nil, nil, "", col, "ASC", 50, 0)
+		if _, err := db.ExecuteReadOnlyQueryArgs(q, args...); err != nil {
 			t.Errorf("query with sortBy=%q failed against real schema: %v\n%s", col, err, q)
 		}
 	}
@@ -0,0 +1,96 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/bvboe/b2s-go/scanner-core/routes"
+)
+
+// NodeScannerReport describes whether the node scanner on one node can
+// generate SBOMs, with the node's OS and container runtime
+type NodeScannerReport struct {
+	NodeName         string          `json:"node_name"`
+	OSImage          string          `json:"os_image,omitempty"`
+	ContainerRuntime string          `json:"container_runtime,omitempty"`
+	Distro           string          `json:"distro,omitempty"`
+	Profile          string          `json:"profile,omitempty"`
+	ScannerPod       string          `json:"scanner_pod,omitempty"`
+	Status           string          `json:"status"` // ok, incompatible, unreachable or missing
+	Compatible       bool            `json:"compatible"`
+	Issues           []string        `json:"issues"`
+	Runtime          json.RawMessage `json:"runtime,omitempty"`      // raw diagnostics reported by the scanner
+	Paused           bool            `json:"paused"`                 // no SBOM requests are sent while the node is cordoned or draining
+	PauseReason      string          `json:"pause_reason,omitempty"` // e.g. cordoned
+	PausedSince      *time.Time      `json:"paused_since,omitempty"`
+	HeldScans        int             `json:"held_scans,omitempty"` // queued scans waiting for the node to resume
+}
+
+// Node scanner report statuses
+const (
+	NodeScannerOK           = "ok"
+	NodeScannerIncompatible = "incompatible"
+	NodeScannerUnreachable  = "unreachable"
+	NodeScannerMissing      = "missing"
+)
+
+// NodeScannerReporter collects the compatibility report of every node's scanner
+// (implemented by podscanner.Client in k8s-scan-server)
+type NodeScannerReporter interface {
+	NodeScannerReports(ctx context.Context) ([]NodeScannerReport, error)
+}
+
+// nodeScannersTimeout bounds the time spent querying the node scanners
+const nodeScannersTimeout = 30 * time.Second
+
+// RegisterNodeScannerHandlers registers the node scanner compatibility report
+func RegisterNodeScannerHandlers(reg *routes.Registry, reporter NodeScannerReporter) {
+	reg.Handle(routes.Route{Pattern: "/api/nodes/scanners", Methods: routes.GET, Handler: NodeScannersHandler(reporter)})
+}
+
+// NodeScannersHandler creates an HTTP handler for GET /api/nodes/scanners.
+// Reports, per node, the detected distribution and runtime paths and whether
+// images can be scanned there, with the reasons when they cannot, and whether
+// scans on the node are paused because it is cordoned.
+//
+// Response: {"nodes": [...], "count": 3, "incompatible": 1, "paused": 1}
+func NodeScannersHandler(reporter NodeScannerReporter) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), nodeScannersTimeout)
+		defer cancel()
+
+		reports, err := reporter.NodeScannerReports(ctx)
+		if err != nil {
+			log.Error("error collecting node scanner reports", "error", err)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+
+		incompatible, paused := 0, 0
+		for _, report := range reports {
+			if !report.Compatible {
+				incompatible++
+			}
+			if report.Paused {
+				paused++
+			}
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(map[string]interface{}{
+			"nodes":        reports,
+			"count":        len(reports),
+			"incompatible": incompatible,
+			"paused":       paused,
+		}); err != nil {
+			log.Error("error encoding node scanner reports", "error", err)
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/bvboe/b2s-go/scanner-core/routes"
+)
+
+type mockNodeScannerReporter struct {
+	reports []NodeScannerReport
+	err     error
+}
+
+func (m *mockNodeScannerReporter) NodeScannerReports(ctx context.Context) ([]NodeScannerReport, error) {
+	return m.reports, m.err
+}
+
+func TestNodeScannersHandler(t *testing.T) {
+	reporter := &mockNodeScannerReporter{reports: []NodeScannerReport{
+		{NodeName: "node-1", Distro: "bottlerocket", Status: NodeScannerOK, Compatible: true, Issues: []string{}},
+		{NodeName: "node-2", Distro: "talos", Status: NodeScannerMissing, Issues: []string{"no pod-scanner pod on this node"},
+			Paused: true, PauseReason: "cordoned", HeldScans: 3},
+	}}
+
+	mux := routes.NewRegistry()
+	RegisterNodeScannerHandlers(mux, reporter)
+
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/nodes/scanners", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200", w.Code)
+	}
+
+	var resp struct {
+		Nodes        []NodeScannerReport `json:"nodes"`
+		Count        int                 `json:"count"`
+		Incompatible int                 `json:"incompatible"`
+		Paused       int                 `json:"paused"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	if resp.Count != 2 || resp.Incompatible != 1 || resp.Paused != 1 {
+		t.Errorf("count = %d, incompatible = %d, paused = %d, want 2, 1 and 1", resp.Count, resp.Incompatible, resp.Paused)
+	}
+	if resp.Nodes[1].Status != NodeScannerMissing || len(resp.Nodes[1].Issues) != 1 || resp.Nodes[1].PauseReason != "cordoned" {
+		t.Errorf("unexpected report for node-2: %+v", resp.Nodes[1])
+	}
+
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/nodes/scanners", nil))
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("POST status = %d, want 405", w.Code)
+	}
+
+	reporter.err = errors.New("forbidden")
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/nodes/scanners", nil))
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("status on error = %d, want 500", w.Code)
+	}
+}
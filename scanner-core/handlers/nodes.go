@@ -1,7 +1,6 @@
 package handlers
 
 import (
-	"encoding/csv"
 	"encoding/json"
 	"fmt"
 	"net/http"
@@ -9,21 +8,24 @@ import (
 	"strings"
 
 	"github.com/bvboe/b2s-go/scanner-core/database"
+	"github.com/bvboe/b2s-go/scanner-core/routes"
 )
 
 
 // RegisterNodeHandlers registers all node-related HTTP handlers
-func RegisterNodeHandlers(mux *http.ServeMux, db *database.DB) {
-	mux.HandleFunc("/api/nodes", ListNodesHandler(db))
-	mux.HandleFunc("/api/nodes/", NodeDetailHandler(db))
-	mux.HandleFunc("/api/summary/by-node", NodeSummaryHandler(db))
-	mux.HandleFunc("/api/summary/by-node-distro", NodeDistributionSummaryHandler(db))
-	mux.HandleFunc("/api/node-filter-options", NodeFilterOptionsHandler(db))
-	mux.HandleFunc("/api/node-vulnerabilities/", NodeVulnerabilityDetailsHandler(db))
-	mux.HandleFunc("/api/node-packages/", NodePackageDetailsHandler(db))
-	mux.HandleFunc("/api/node-cves", NodeCVEsHandler(db))
-	mux.HandleFunc("/api/node-cves/affected", NodeCVEAffectedHandler(db))
-	mux.HandleFunc("/api/node-cves/details", NodeCVEDetailVariantsHandler(db))
+func RegisterNodeHandlers(reg *routes.Registry, db *database.DB) {
+	reg.Handle(
+		routes.Route{Pattern: "/api/nodes", Methods: routes.GET, Handler: ListNodesHandler(db)},
+		routes.Route{Pattern: "/api/nodes/", Methods: routes.GET, Handler: NodeDetailHandler(db)},
+		routes.Route{Pattern: "/api/summary/by-node", Methods: routes.GET, Handler: NodeSummaryHandler(db)},
+		routes.Route{Pattern: "/api/summary/by-node-distro", Methods: routes.GET, Handler: NodeDistributionSummaryHandler(db)},
+		routes.Route{Pattern: "/api/node-filter-options", Methods: routes.GET, Handler: NodeFilterOptionsHandler(db)},
+		routes.Route{Pattern: "/api/node-vulnerabilities/", Methods: routes.GET, Handler: NodeVulnerabilityDetailsHandler(db)},
+		routes.Route{Pattern: "/api/node-packages/", Methods: routes.GET, Handler: NodePackageDetailsHandler(db)},
+		routes.Route{Pattern: "/api/node-cves", Methods: routes.GET, Handler: NodeCVEsHandler(db)},
+		routes.Route{Pattern: "/api/node-cves/affected", Methods: routes.GET, Handler: NodeCVEAffectedHandler(db)},
+		routes.Route{Pattern: "/api/node-cves/details", Methods: routes.GET, Handler: NodeCVEDetailVariantsHandler(db)},
+	)
 }
 
 // ListNodesHandler returns a handler that lists all nodes with their scan status
@@ -123,13 +125,16 @@ func NodeDetailHandler(db *database.DB) http.HandlerFunc {
 					http.Error(w, "Failed to get node packages", http.StatusInternalServerError)
 					return
 				}
+				export := newCSVExport(w, r)
+				if export == nil {
+					return
+				}
 				w.Header().Set("Content-Type", "text/csv")
 				w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"packages-%s.csv\"", nodeName))
-				writer := csv.NewWriter(w)
-				defer writer.Flush()
-				_ = writer.Write([]string{"name", "version", "type", "purl", "count"})
+				defer func() { _ = export.Flush() }()
+				_ = export.WriteHeader([]string{"name", "version", "type", "purl", "count"})
 				for _, pkg := range packages {
-					_ = writer.Write([]string{pkg.Name, pkg.Version, pkg.Type, pkg.PURL, fmt.Sprintf("%d", pkg.Count)})
+					_ = export.WriteRow([]string{pkg.Name, pkg.Version, pkg.Type, pkg.PURL, fmt.Sprintf("%d", pkg.Count)})
 				}
 				return
 			}
@@ -165,16 +170,19 @@ func NodeDetailHandler(db *database.DB) http.HandlerFunc {
 					http.Error(w, "Failed to get node vulnerabilities", http.StatusInternalServerError)
 					return
 				}
+				export := newCSVExport(w, r)
+				if export == nil {
+					return
+				}
 				w.Header().Set("Content-Type", "text/csv")
 				w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"vulnerabilities-%s.csv\"", nodeName))
-				writer := csv.NewWriter(w)
-				defer writer.Flush()
-				_ = writer.Write([]string{"cve_id", "severity", "score", "package_name", "package_version", "package_type", "fix_status", "fix_version", "known_exploited", "count"})
+				defer func() { _ = export.Flush() }()
+				_ = export.WriteHeader([]string{"cve_id", "severity", "score", "package_name", "package_version", "package_type", "fix_status", "fix_version", "known_exploited", "count"})
 				for _, v := range vulns {
-					_ = writer.Write([]string{
+					_ = export.WriteRow([]string{
 						v.CVEID,
 						v.Severity,
-						fmt.Sprintf("%.1f", v.Risk),
+						export.Decimal(v.Risk, 1),
 						v.PackageName,
 						v.PackageVersion,
 						v.PackageType,
@@ -249,21 +257,24 @@ func NodeSummaryHandler(db *database.DB) http.HandlerFunc {
 
 		// Handle CSV export
 		if params.Get("format") == "csv" {
+			export := newCSVExport(w, r)
+			if export == nil {
+				return
+			}
 			w.Header().Set("Content-Type", "text/csv")
 			w.Header().Set("Content-Disposition", "attachment; filename=node_summary.csv")
 
-			writer := csv.NewWriter(w)
-			defer writer.Flush()
+			defer func() { _ = export.Flush() }()
 
 			// Write header
-			_ = writer.Write([]string{
+			_ = export.WriteHeader([]string{
 				"Node Name", "OS Distribution", "Critical", "High", "Medium",
 				"Low", "Negligible", "Unknown", "Total", "Risk Score", "Known Exploits", "Packages",
 			})
 
 			// Write data
 			for _, s := range summaries {
-				_ = writer.Write([]string{
+				_ = export.WriteRow([]string{
 					s.NodeName,
 					s.OSRelease,
 					fmt.Sprintf("%d", s.Critical),
@@ -273,7 +284,7 @@ func NodeSummaryHandler(db *database.DB) http.HandlerFunc {
 					fmt.Sprintf("%d", s.Negligible),
 					fmt.Sprintf("%d", s.Unknown),
 					fmt.Sprintf("%d", s.Total),
-					fmt.Sprintf("%.1f", s.TotalRisk),
+					export.Decimal(s.TotalRisk, 1),
 					fmt.Sprintf("%d", s.ExploitCount),
 					fmt.Sprintf("%d", s.PackageCount),
 				})
@@ -306,32 +317,35 @@ func NodeDistributionSummaryHandler(db *database.DB) http.HandlerFunc {
 
 		// Handle CSV export
 		if r.URL.Query().Get("format") == "csv" {
+			export := newCSVExport(w, r)
+			if export == nil {
+				return
+			}
 			w.Header().Set("Content-Type", "text/csv")
 			w.Header().Set("Content-Disposition", "attachment; filename=node_distribution_summary.csv")
 
-			writer := csv.NewWriter(w)
-			defer writer.Flush()
+			defer func() { _ = export.Flush() }()
 
 			// Write header
-			_ = writer.Write([]string{
+			_ = export.WriteHeader([]string{
 				"OS Distribution", "Node Count", "Avg Critical", "Avg High", "Avg Medium",
 				"Avg Low", "Avg Negligible", "Avg Unknown", "Avg Risk Score", "Avg Exploits", "Avg Packages",
 			})
 
 			// Write data
 			for _, s := range summaries {
-				_ = writer.Write([]string{
+				_ = export.WriteRow([]string{
 					s.OSName,
 					fmt.Sprintf("%d", s.NodeCount),
-					fmt.Sprintf("%.1f", s.AvgCritical),
-					fmt.Sprintf("%.1f", s.AvgHigh),
-					fmt.Sprintf("%.1f", s.AvgMedium),
-					fmt.Sprintf("%.1f", s.AvgLow),
-					fmt.Sprintf("%.1f", s.AvgNegligible),
-					fmt.Sprintf("%.1f", s.AvgUnknown),
-					fmt.Sprintf("%.1f", s.AvgRisk),
-					fmt.Sprintf("%.1f", s.AvgExploits),
-					fmt.Sprintf("%.1f", s.AvgPackages),
+					export.Decimal(s.AvgCritical, 1),
+					export.Decimal(s.AvgHigh, 1),
+					export.Decimal(s.AvgMedium, 1),
+					export.Decimal(s.AvgLow, 1),
+					export.Decimal(s.AvgNegligible, 1),
+					export.Decimal(s.AvgUnknown, 1),
+					export.Decimal(s.AvgRisk, 1),
+					export.Decimal(s.AvgExploits, 1),
+					export.Decimal(s.AvgPackages, 1),
 				})
 			}
 			return
@@ -366,8 +380,10 @@ func NodeFilterOptionsHandler(db *database.DB) http.HandlerFunc {
 	}
 }
 
-// NodeVulnerabilityDetailsHandler returns JSON details for a specific node vulnerability
+// NodeVulnerabilityDetailsHandler returns JSON details for a specific node vulnerability,
+// or the distinct paths its package was found at
 // Route: GET /api/node-vulnerabilities/{id}/details
+// Route: GET /api/node-vulnerabilities/{id}/occurrences
 func NodeVulnerabilityDetailsHandler(db *database.DB) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodGet {
@@ -377,12 +393,15 @@ func NodeVulnerabilityDetailsHandler(db *database.DB) http.HandlerFunc {
 
 		// Parse path: /api/node-vulnerabilities/{id}/details
 		path := strings.TrimPrefix(r.URL.Path, "/api/node-vulnerabilities/")
-		if !strings.HasSuffix(path, "/details") {
-			http.Error(w, "Invalid path", http.StatusBadRequest)
-			return
+		idStr, occurrences := strings.CutSuffix(path, "/occurrences")
+		if !occurrences {
+			var ok bool
+			if idStr, ok = strings.CutSuffix(path, "/details"); !ok {
+				http.Error(w, "Invalid path", http.StatusBadRequest)
+				return
+			}
 		}
 
-		idStr := strings.TrimSuffix(path, "/details")
 		id, err := strconv.ParseInt(idStr, 10, 64)
 		if err != nil {
 			http.Error(w, "Invalid vulnerability ID", http.StatusBadRequest)
@@ -401,6 +420,18 @@ func NodeVulnerabilityDetailsHandler(db *database.DB) http.HandlerFunc {
 		}
 
 		w.Header().Set("Content-Type", "application/json")
+		if occurrences {
+			merged, err := database.OccurrencesFromDetails(details)
+			if err != nil {
+				log.Error("error merging node vulnerability occurrences", "id", id, "error", err)
+				http.Error(w, "Failed to get vulnerability occurrences", http.StatusInternalServerError)
+				return
+			}
+			if err := json.NewEncoder(w).Encode(map[string]interface{}{"occurrences": merged}); err != nil {
+				log.Error("error encoding node vulnerability occurrences", "error", err)
+			}
+			return
+		}
 		if _, err := w.Write([]byte(details)); err != nil {
 			log.Error("error writing vulnerability details response", "error", err)
 		}
@@ -10,6 +10,7 @@ import (
 
 	"github.com/bvboe/b2s-go/scanner-core/database"
 	"github.com/bvboe/b2s-go/scanner-core/nodes"
+	"github.com/bvboe/b2s-go/scanner-core/routes"
 )
 
 // createTestDBForHandlers creates a temporary test database
@@ -608,7 +609,7 @@ func TestRegisterNodeHandlers_RegistersAllRoutes(t *testing.T) {
 	db, cleanup := createTestDBForHandlers(t)
 	defer cleanup()
 
-	mux := http.NewServeMux()
+	mux := routes.NewRegistry()
 	RegisterNodeHandlers(mux, db)
 
 	// Test each route responds (not 404 from ServeMux)
@@ -0,0 +1,40 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/bvboe/b2s-go/scanner-core/notify"
+	"github.com/bvboe/b2s-go/scanner-core/routes"
+)
+
+// RegisterNotifyHandlers registers the notification routing report. It is
+// admin only, since webhook destinations carry their URL (and any token in it).
+func RegisterNotifyHandlers(reg *routes.Registry, router *notify.Router) {
+	reg.Handle(routes.Route{Pattern: "/api/notify/routes", Methods: routes.GET, Handler: NotifyRoutesHandler(router), Role: routes.RoleAdmin})
+}
+
+// NotifyRoutesHandler creates an HTTP handler for GET /api/notify/routes.
+// Lists the namespaces whose bjorn2scan.io/notify annotation routes their
+// alerts, and the default destinations of all other namespaces, so a typo in
+// an annotation shows up before an alert goes to the wrong channel.
+//
+// Response: {"default": [{"kind": "slack", "target": "#security"}],
+// "namespaces": {"team-foo": [{"kind": "slack", "target": "#team-foo"}]}}
+func NotifyRoutesHandler(router *notify.Router) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		response := map[string]interface{}{
+			"default":    router.Destinations(""),
+			"namespaces": router.Routes(),
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(response); err != nil {
+			log.Error("error encoding notify routes", "error", err)
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/bvboe/b2s-go/scanner-core/notify"
+	"github.com/bvboe/b2s-go/scanner-core/routes"
+)
+
+func TestNotifyRoutesHandler(t *testing.T) {
+	router := notify.NewRouter([]notify.Destination{{Kind: notify.KindSlack, Target: "#security"}})
+	router.SetNamespace("team-foo", map[string]string{notify.AnnotationKey: "slack:#team-foo"})
+
+	rec := httptest.NewRecorder()
+	NotifyRoutesHandler(router)(rec, httptest.NewRequest(http.MethodGet, "/api/notify/routes", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", rec.Code)
+	}
+	var response struct {
+		Default    []notify.Destination            `json:"default"`
+		Namespaces map[string][]notify.Destination `json:"namespaces"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&response); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if len(response.Default) != 1 || response.Default[0].Target != "#security" {
+		t.Errorf("unexpected default: %+v", response.Default)
+	}
+	if got := response.Namespaces["team-foo"]; len(got) != 1 || got[0].String() != "slack:#team-foo" {
+		t.Errorf("unexpected team-foo routes: %+v", response.Namespaces)
+	}
+
+	rec = httptest.NewRecorder()
+	NotifyRoutesHandler(router)(rec, httptest.NewRequest(http.MethodPost, "/api/notify/routes", nil))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("POST: expected status 405, got %d", rec.Code)
+	}
+}
+
+func TestNotifyRoutesAdminOnly(t *testing.T) {
+	tokens, err := ParseAuthTokens("admin=admintoken,viewer=viewertoken")
+	if err != nil {
+		t.Fatalf("ParseAuthTokens() error = %v", err)
+	}
+	reg := routes.NewRegistry(AuthMiddleware(NewAuthenticator(tokens, nil)))
+	RegisterNotifyHandlers(reg, notify.NewRouter(nil))
+
+	for token, want := range map[string]int{"viewertoken": http.StatusForbidden, "admintoken": http.StatusOK} {
+		req := httptest.NewRequest(http.MethodGet, "/api/notify/routes", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		reg.ServeHTTP(rec, req)
+		if rec.Code != want {
+			t.Errorf("%s: expected status %d, got %d", token, want, rec.Code)
+		}
+	}
+}
@@ -0,0 +1,408 @@
+package handlers
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-jose/go-jose/v4"
+	"github.com/go-jose/go-jose/v4/jwt"
+
+	"github.com/bvboe/b2s-go/scanner-core/routes"
+)
+
+// oidcLeeway is the clock skew tolerated when checking ID token times
+const oidcLeeway = time.Minute
+
+// jwksRefreshInterval bounds how often the signing keys are re-fetched for a
+// token signed with an unknown key
+const jwksRefreshInterval = 5 * time.Minute
+
+// oidcSignatureAlgorithms are the ID token signatures accepted
+var oidcSignatureAlgorithms = []jose.SignatureAlgorithm{
+	jose.RS256, jose.RS384, jose.RS512, jose.PS256, jose.PS384, jose.PS512,
+	jose.ES256, jose.ES384, jose.ES512, jose.EdDSA,
+}
+
+// OIDCConfig configures the OIDC provider whose ID tokens the API accepts
+type OIDCConfig struct {
+	IssuerURL    string
+	ClientID     string   // the audience ID tokens must have
+	ClientSecret string   // for the web UI login
+	RedirectURL  string   // external URL of /api/auth/callback; empty disables the web UI login
+	Scopes       []string // requested at login
+	GroupsClaim  string   // claim listing the user's groups
+	AdminGroups  []string // groups granted the admin role
+	ViewerGroups []string // groups granted the viewer role; empty grants it to every user
+}
+
+// oidcDiscovery is the part of the provider metadata used
+type oidcDiscovery struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// OIDCProvider verifies ID tokens of an OIDC provider and runs the
+// authorization code login of the web UI. The provider metadata and signing
+// keys are fetched on first use, so the server starts while the provider is
+// unreachable.
+type OIDCProvider struct {
+	cfg    OIDCConfig
+	client *http.Client
+
+	mu          sync.Mutex
+	discovery   *oidcDiscovery
+	keys        jose.JSONWebKeySet
+	keysFetched time.Time
+}
+
+// NewOIDCProvider returns a provider for cfg
+func NewOIDCProvider(cfg OIDCConfig) (*OIDCProvider, error) {
+	if cfg.IssuerURL == "" || cfg.ClientID == "" {
+		return nil, fmt.Errorf("OIDC issuer URL and client ID are required")
+	}
+	if cfg.RedirectURL != "" {
+		if _, err := url.Parse(cfg.RedirectURL); err != nil {
+			return nil, fmt.Errorf("invalid OIDC redirect URL: %w", err)
+		}
+	}
+	if cfg.GroupsClaim == "" {
+		cfg.GroupsClaim = "groups"
+	}
+	if len(cfg.Scopes) == 0 {
+		cfg.Scopes = []string{"openid", "email", "profile"}
+	}
+	return &OIDCProvider{cfg: cfg, client: &http.Client{Timeout: 10 * time.Second}}, nil
+}
+
+// LoginEnabled reports whether the web UI can log in through the provider
+func (p *OIDCProvider) LoginEnabled() bool {
+	return p.cfg.RedirectURL != ""
+}
+
+// metadata returns the provider metadata, fetching it on first use
+func (p *OIDCProvider) metadata(ctx context.Context) (*oidcDiscovery, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.discovery != nil {
+		return p.discovery, nil
+	}
+	var d oidcDiscovery
+	if err := p.getJSON(ctx, strings.TrimSuffix(p.cfg.IssuerURL, "/")+"/.well-known/openid-configuration", &d); err != nil {
+		return nil, fmt.Errorf("OIDC discovery failed: %w", err)
+	}
+	if d.Issuer != p.cfg.IssuerURL {
+		return nil, fmt.Errorf("OIDC discovery returned issuer %q, want %q", d.Issuer, p.cfg.IssuerURL)
+	}
+	p.discovery = &d
+	return p.discovery, nil
+}
+
+// signingKey returns the provider key with the ID, re-fetching the key set
+// when the key is unknown (keys are rotated)
+func (p *OIDCProvider) signingKey(ctx context.Context, d *oidcDiscovery, keyID string) (*jose.JSONWebKey, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	find := func() *jose.JSONWebKey {
+		if keyID == "" && len(p.keys.Keys) == 1 {
+			return &p.keys.Keys[0]
+		}
+		if keys := p.keys.Key(keyID); len(keys) > 0 {
+			return &keys[0]
+		}
+		return nil
+	}
+	if key := find(); key != nil {
+		return key, nil
+	}
+	if time.Since(p.keysFetched) < jwksRefreshInterval {
+		return nil, fmt.Errorf("unknown signing key %q", keyID)
+	}
+	var keys jose.JSONWebKeySet
+	if err := p.getJSON(ctx, d.JWKSURI, &keys); err != nil {
+		return nil, fmt.Errorf("failed to fetch OIDC signing keys: %w", err)
+	}
+	p.keys, p.keysFetched = keys, time.Now()
+	if key := find(); key != nil {
+		return key, nil
+	}
+	return nil, fmt.Errorf("unknown signing key %q", keyID)
+}
+
+// getJSON fetches a JSON document
+func (p *OIDCProvider) getJSON(ctx context.Context, url string, dest interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s: %s", url, resp.Status)
+	}
+	return json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(dest)
+}
+
+// idTokenClaims are the ID token claims used besides the registered ones
+type idTokenClaims struct {
+	Email             string `json:"email"`
+	PreferredUsername string `json:"preferred_username"`
+	Nonce             string `json:"nonce"`
+}
+
+// Verify checks an ID token's signature, issuer, audience and expiry and
+// maps the user's groups to a role
+func (p *OIDCProvider) Verify(ctx context.Context, rawToken string) (*Identity, error) {
+	return p.verify(ctx, rawToken, "")
+}
+
+// verify is Verify for an ID token that, when nonce is set, must carry it:
+// the token of a web UI login, which cannot be replayed into another login
+func (p *OIDCProvider) verify(ctx context.Context, rawToken, nonce string) (*Identity, error) {
+	d, err := p.metadata(ctx)
+	if err != nil {
+		return nil, err
+	}
+	token, err := jwt.ParseSigned(rawToken, oidcSignatureAlgorithms)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", errInvalidToken, err)
+	}
+	key, err := p.signingKey(ctx, d, token.Headers[0].KeyID)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", errInvalidToken, err)
+	}
+	var registered jwt.Claims
+	var user idTokenClaims
+	var all map[string]interface{}
+	if err := token.Claims(key, &registered, &user, &all); err != nil {
+		return nil, fmt.Errorf("%w: %v", errInvalidToken, err)
+	}
+	if registered.Expiry == nil {
+		return nil, fmt.Errorf("%w: no expiry", errInvalidToken)
+	}
+	if err := registered.ValidateWithLeeway(jwt.Expected{
+		Issuer:      p.cfg.IssuerURL,
+		AnyAudience: jwt.Audience{p.cfg.ClientID},
+	}, oidcLeeway); err != nil {
+		return nil, fmt.Errorf("%w: %v", errInvalidToken, err)
+	}
+	if nonce != "" && user.Nonce != nonce {
+		return nil, fmt.Errorf("%w: nonce mismatch", errInvalidToken)
+	}
+
+	subject := registered.Subject
+	if user.PreferredUsername != "" {
+		subject = user.PreferredUsername
+	}
+	if user.Email != "" {
+		subject = user.Email
+	}
+	groups := claimStrings(all[p.cfg.GroupsClaim])
+	id := &Identity{Subject: subject, Method: "oidc", Expires: registered.Expiry.Time()}
+	switch {
+	case intersects(groups, p.cfg.AdminGroups):
+		id.Role = routes.RoleAdmin
+	case len(p.cfg.ViewerGroups) == 0 || intersects(groups, p.cfg.ViewerGroups):
+		id.Role = routes.RoleViewer
+	default:
+		return nil, fmt.Errorf("%w: %s is in none of the viewer or admin groups", errNoRole, subject)
+	}
+	return id, nil
+}
+
+// claimStrings returns a claim holding a string or a list of strings
+func claimStrings(claim interface{}) []string {
+	switch v := claim.(type) {
+	case string:
+		return []string{v}
+	case []interface{}:
+		values := make([]string, 0, len(v))
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				values = append(values, s)
+			}
+		}
+		return values
+	}
+	return nil
+}
+
+// intersects reports whether a and b have a value in common
+func intersects(a, b []string) bool {
+	return slices.ContainsFunc(a, func(s string) bool { return slices.Contains(b, s) })
+}
+
+// authCodeURL returns the provider URL the web UI login redirects to. The ID
+// token must carry nonce, and the code is only exchanged with the PKCE
+// verifier (S256).
+func (p *OIDCProvider) authCodeURL(ctx context.Context, state, nonce, verifier string) (string, error) {
+	d, err := p.metadata(ctx)
+	if err != nil {
+		return "", err
+	}
+	u, err := url.Parse(d.AuthorizationEndpoint)
+	if err != nil {
+		return "", fmt.Errorf("invalid authorization endpoint: %w", err)
+	}
+	q := u.Query()
+	q.Set("response_type", "code")
+	q.Set("client_id", p.cfg.ClientID)
+	q.Set("redirect_uri", p.cfg.RedirectURL)
+	q.Set("scope", strings.Join(p.cfg.Scopes, " "))
+	q.Set("state", state)
+	q.Set("nonce", nonce)
+	challenge := sha256.Sum256([]byte(verifier))
+	q.Set("code_challenge", base64.RawURLEncoding.EncodeToString(challenge[:]))
+	q.Set("code_challenge_method", "S256")
+	u.RawQuery = q.Encode()
+	return u.String(), nil
+}
+
+// exchange trades an authorization code and its PKCE verifier for an ID token
+func (p *OIDCProvider) exchange(ctx context.Context, code, verifier string) (string, error) {
+	d, err := p.metadata(ctx)
+	if err != nil {
+		return "", err
+	}
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {p.cfg.RedirectURL},
+		"code_verifier": {verifier},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, d.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.SetBasicAuth(url.QueryEscape(p.cfg.ClientID), url.QueryEscape(p.cfg.ClientSecret))
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("token request failed: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("token request failed: %s", resp.Status)
+	}
+	var tokens struct {
+		IDToken string `json:"id_token"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&tokens); err != nil {
+		return "", fmt.Errorf("invalid token response: %w", err)
+	}
+	if tokens.IDToken == "" {
+		return "", errors.New("token response has no id_token")
+	}
+	return tokens.IDToken, nil
+}
+
+// oidcRandom returns n random bytes, base64url encoded
+func oidcRandom(n int) (string, error) {
+	buf := make([]byte, n)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// startOIDCLogin redirects the browser to the provider, remembering the
+// state, nonce, PKCE verifier and the path to return to in a short-lived
+// cookie
+func startOIDCLogin(w http.ResponseWriter, r *http.Request, p *OIDCProvider, redirect string) {
+	var login [3]string // state, nonce, verifier (32 bytes = 43 characters, as RFC 7636 asks)
+	for i, n := range []int{24, 24, 32} {
+		value, err := oidcRandom(n)
+		if err != nil {
+			log.Error("failed to generate OIDC state", "error", err)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		login[i] = value
+	}
+	target, err := p.authCodeURL(r.Context(), login[0], login[1], login[2])
+	if err != nil {
+		log.Error("failed to start OIDC login", "error", err)
+		http.Error(w, "Identity provider unavailable", http.StatusBadGateway)
+		return
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     stateCookie,
+		Value:    strings.Join(append(login[:], url.QueryEscape(redirect)), "|"),
+		Path:     "/api/auth/",
+		MaxAge:   600,
+		HttpOnly: true,
+		Secure:   isHTTPS(r),
+		SameSite: http.SameSiteLaxMode,
+	})
+	http.Redirect(w, r, target, http.StatusFound)
+}
+
+// AuthCallbackHandler creates an HTTP handler for GET /api/auth/callback, the
+// OIDC redirect URL. Exchanges the authorization code for an ID token and
+// starts a web UI session with it, lasting until the token expires.
+func AuthCallbackHandler(a *Authenticator) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if a.oidc == nil || !a.oidc.LoginEnabled() {
+			http.NotFound(w, r)
+			return
+		}
+		cookie, err := r.Cookie(stateCookie)
+		if err != nil {
+			http.Error(w, "Login expired, please try again", http.StatusBadRequest)
+			return
+		}
+		http.SetCookie(w, &http.Cookie{Name: stateCookie, Value: "", Path: "/api/auth/", MaxAge: -1})
+		login := strings.SplitN(cookie.Value, "|", 4) // state, nonce, verifier, redirect
+		query := r.URL.Query()
+		if e := query.Get("error"); e != "" {
+			log.Info("OIDC login refused by the provider", "error", e, "description", query.Get("error_description"))
+			http.Error(w, "Login failed: "+e, http.StatusUnauthorized)
+			return
+		}
+		if len(login) != 4 || query.Get("state") == "" || query.Get("state") != login[0] {
+			http.Error(w, "Invalid login state", http.StatusBadRequest)
+			return
+		}
+
+		idToken, err := a.oidc.exchange(r.Context(), query.Get("code"), login[2])
+		if err != nil {
+			log.Warn("OIDC code exchange failed", "error", err)
+			http.Error(w, "Login failed", http.StatusBadGateway)
+			return
+		}
+		id, err := a.oidc.verify(r.Context(), idToken, login[1])
+		if err != nil {
+			log.Info("OIDC login rejected", "error", err)
+			if errors.Is(err, errNoRole) {
+				http.Error(w, "Forbidden", http.StatusForbidden)
+				return
+			}
+			http.Error(w, "Login failed", http.StatusUnauthorized)
+			return
+		}
+		if err := a.setSessionCookie(w, r, id, id.Expires); err != nil {
+			log.Error("error starting web UI session", "error", err)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		log.Info("web UI login", "subject", id.Subject, "role", id.Role, "method", id.Method)
+		redirect, _ := url.QueryUnescape(login[3])
+		http.Redirect(w, r, safeRedirect(redirect), http.StatusSeeOther)
+	}
+}
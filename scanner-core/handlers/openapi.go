@@ -0,0 +1,530 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/bvboe/b2s-go/scanner-core/routes"
+	"github.com/bvboe/b2s-go/scanner-core/sbomformat"
+)
+
+// APIParam is a path or query parameter of an API operation. List parameters
+// are comma-separated, e.g. ?namespaces=default,kube-system.
+type APIParam struct {
+	Name        string
+	In          string // "path" or "query"
+	Type        string // "string", "integer", "boolean" or "list"
+	Description string
+	Enum        []string
+}
+
+// APIOperation documents one endpoint of the REST API. OpenAPIOperations is
+// the catalog the OpenAPI spec and the generated Go client (package client)
+// are built from.
+type APIOperation struct {
+	ID       string // operationId, also the Go client method name
+	Method   string
+	Path     string // with {param} placeholders
+	Tag      string
+	Summary  string
+	Params   []APIParam
+	Body     bool     // takes a JSON request body
+	Produces []string // response media types (default application/json)
+}
+
+// pathParamPattern matches the {param} placeholders of an operation path
+var pathParamPattern = regexp.MustCompile(`\{([A-Za-z]+)\}`)
+
+func pathParam(name, description string) APIParam {
+	return APIParam{Name: name, In: "path", Type: "string", Description: description}
+}
+
+func queryParam(name, typ, description string, enum ...string) APIParam {
+	return APIParam{Name: name, In: "query", Type: typ, Description: description, Enum: enum}
+}
+
+// Parameters shared by the list endpoints
+var (
+	pageParams = []APIParam{
+		queryParam("page", "integer", "Page number, starting at 1"),
+		queryParam("pageSize", "integer", "Rows per page"),
+		queryParam("sortBy", "string", "Column to sort by"),
+		queryParam("sortOrder", "string", "Sort direction", "asc", "desc"),
+	}
+	filterParams = []APIParam{
+		queryParam("namespaces", "list", "Only these namespaces"),
+		queryParam("vulnStatuses", "list", "Only these fix statuses (fixed, not-fixed, wont-fix, unknown)"),
+		queryParam("packageTypes", "list", "Only these package types (deb, apk, go-module, ...)"),
+		queryParam("osNames", "list", "Only these OS distributions"),
+	}
+	nodeFilterParams = []APIParam{
+		queryParam("vulnStatuses", "list", "Only these fix statuses"),
+		queryParam("packageTypes", "list", "Only these package types"),
+		queryParam("osNames", "list", "Only these OS distributions"),
+	}
+	csvParams = []APIParam{
+		queryParam("delimiter", "string", "CSV field separator (comma, semicolon, tab, pipe or the character)"),
+		queryParam("columns", "list", "CSV columns to export, in order"),
+		queryParam("decimal", "string", "CSV decimal separator", "point", "comma"),
+		queryParam("bom", "boolean", "Prefix CSV with a UTF-8 byte order mark"),
+		tzParam,
+	}
+	tzParam       = queryParam("tz", "string", "IANA time zone of exported timestamps (default UTC)")
+	severityParam = queryParam("severity", "list", "Only these severities")
+	cveParams     = []APIParam{
+		queryParam("cve", "string", "Vulnerability ID"),
+		queryParam("name", "string", "Package name"),
+		queryParam("version", "string", "Package version"),
+		queryParam("type", "string", "Package type"),
+	}
+)
+
+// formatParam is the ?format= parameter of endpoints with several representations
+func formatParam(formats ...string) APIParam {
+	return queryParam("format", "string", "Response format", formats...)
+}
+
+// params concatenates parameter lists
+func params(lists ...[]APIParam) []APIParam {
+	var all []APIParam
+	for _, l := range lists {
+		all = append(all, l...)
+	}
+	return all
+}
+
+var jsonAndCSV = []string{"application/json", "text/csv"}
+
+// OpenAPIOperations returns the documented API operations. Debug endpoints
+// are left out, they are meant for troubleshooting rather than integration.
+func OpenAPIOperations() []APIOperation {
+	return []APIOperation{
+		// Images and containers
+		{ID: "ListImages", Method: http.MethodGet, Path: "/api/images", Tag: "images",
+			Summary: "List images with vulnerability counts and scan status",
+			Params: params(filterParams, pageParams, csvParams, []APIParam{
+				queryParam("search", "string", "Substring of the image reference"),
+				queryParam("registries", "list", "Only images from these registries"),
+				queryParam("slsaLevels", "list", "Only images whose provenance supports these SLSA build levels (0 = checked, none found)"),
+				queryParam("builders", "list", "Only images built by these provenance builder IDs"),
+				queryParam("sources", "list", "Only images whose org.opencontainers.image.source label is one of these repositories"),
+				queryParam("vendors", "list", "Only images whose org.opencontainers.image.vendor label is one of these vendors"),
+				queryParam("minSizeMB", "number", "Only images of at least this size in MB"),
+				queryParam("maxSizeMB", "number", "Only images of at most this size in MB"),
+				queryParam("minLayers", "integer", "Only images with at least this many layers"),
+				queryParam("maxLayers", "integer", "Only images with at most this many layers"),
+				queryParam("applications", "list", "Only containers whose CMDB application ID is one of these"),
+				queryParam("owners", "list", "Only containers whose CMDB owner is one of these"),
+				queryParam("classifications", "list", "Only containers whose CMDB data classification is one of these"),
+				queryParam("includeDeleted", "boolean", "Include soft-deleted images"),
+				formatParam("json", "csv"),
+			}), Produces: jsonAndCSV},
+		{ID: "GetImage", Method: http.MethodGet, Path: "/api/images/{digest}", Tag: "images",
+			Summary: "Get an image with its references and containers",
+			Params: []APIParam{pathParam("digest", "Image digest"),
+				queryParam("includeDeleted", "boolean", "Return the image even if it was soft-deleted")}},
+		{ID: "ListImageVulnerabilities", Method: http.MethodGet, Path: "/api/images/{digest}/vulnerabilities", Tag: "images",
+			Summary: "List the vulnerabilities of an image",
+			Params: params([]APIParam{pathParam("digest", "Image digest"), severityParam,
+				queryParam("fixStatus", "list", "Only these fix statuses"),
+				queryParam("packageType", "list", "Only these package types"),
+				queryParam("vulnerability", "string", "Substring of the vulnerability ID"),
+				formatParam("json", "csv", "ndjson")}, pageParams, csvParams),
+			Produces: []string{"application/json", "text/csv", ndjsonContentType}},
+		{ID: "ListImagePackages", Method: http.MethodGet, Path: "/api/images/{digest}/packages", Tag: "images",
+			Summary: "List the packages of an image",
+			Params: params([]APIParam{pathParam("digest", "Image digest"),
+				queryParam("type", "list", "Only these package types"),
+				formatParam("json", "csv")}, pageParams, csvParams),
+			Produces: jsonAndCSV},
+		{ID: "GetImageStats", Method: http.MethodGet, Path: "/api/images/{digest}/stats", Tag: "images",
+			Summary: "Get vulnerability statistics of an image",
+			Params: []APIParam{pathParam("digest", "Image digest"), severityParam,
+				queryParam("fixStatus", "list", "Only these fix statuses"),
+				queryParam("packageType", "list", "Only these package types")}},
+		{ID: "GetImagePolicy", Method: http.MethodGet, Path: "/api/images/{digest}/policy", Tag: "images",
+			Summary: "Evaluate an image against the configured policy: pass, fail or not_scanned, with the violated rules",
+			Params:  []APIParam{pathParam("digest", "Image digest")}},
+		{ID: "GetImageScanHistory", Method: http.MethodGet, Path: "/api/images/{digest}/history", Tag: "images",
+			Summary: "Get the severity counts and risk score of an image at each scan, oldest first",
+			Params: []APIParam{pathParam("digest", "Image digest"),
+				queryParam("lookback", "string", "Only scans within this window, e.g. 720h (default: all)")}},
+		{ID: "ListContainers", Method: http.MethodGet, Path: "/api/containers", Tag: "images",
+			Summary: "List running containers with the scan results of their images",
+			Params: params(filterParams, pageParams, csvParams, []APIParam{
+				queryParam("search", "string", "Substring of the container, pod or image"),
+				queryParam("registries", "list", "Only images from these registries"),
+				queryParam("detail", "string", "Include image reference, repository, tag, OS version and first seen time (always in CSV)", "full"),
+				formatParam("json", "csv"),
+			}), Produces: jsonAndCSV},
+		{ID: "DownloadSBOM", Method: http.MethodGet, Path: "/api/sbom/{digest}", Tag: "images",
+			Summary: "Download the SBOM of an image as Syft JSON, CycloneDX JSON or SPDX JSON",
+			Params:  []APIParam{pathParam("digest", "Image digest"), formatParam(sbomformat.Formats()...)},
+			Produces: []string{"application/json", sbomformat.ContentType(sbomformat.CycloneDXJSON),
+				sbomformat.ContentType(sbomformat.SPDXJSON)}},
+		{ID: "DownloadVulnerabilities", Method: http.MethodGet, Path: "/api/vulnerabilities/{digest}", Tag: "images",
+			Summary: "Download the Grype vulnerability report of an image",
+			Params:  []APIParam{pathParam("digest", "Image digest")}},
+		{ID: "GetVulnerabilityDetails", Method: http.MethodGet, Path: "/api/vulnerabilities/{id}/details", Tag: "images",
+			Summary: "Get the Grype match details of an image vulnerability",
+			Params:  []APIParam{pathParam("id", "Vulnerability row ID")}},
+		{ID: "GetVulnerabilityOccurrences", Method: http.MethodGet, Path: "/api/vulnerabilities/{id}/occurrences", Tag: "images",
+			Summary: "List the paths at which the package of an image vulnerability was found",
+			Params:  []APIParam{pathParam("id", "Vulnerability row ID")}},
+		{ID: "GetPackageDetails", Method: http.MethodGet, Path: "/api/packages/{id}/details", Tag: "images",
+			Summary: "Get the Syft details of an image package",
+			Params:  []APIParam{pathParam("id", "Package row ID")}},
+		{ID: "GetBadge", Method: http.MethodGet, Path: "/api/badge/{image}", Tag: "images",
+			Summary: "Get an SVG vulnerability badge for an image digest or reference (append .svg)",
+			Params:  []APIParam{pathParam("image", "Image digest or reference")}, Produces: []string{"image/svg+xml"}},
+
+		// Vulnerabilities
+		{ID: "ListVulnerabilities", Method: http.MethodGet, Path: "/api/vulnerabilities", Tag: "vulnerabilities",
+			Summary: "List vulnerabilities with the images, pods and namespaces they affect",
+			Params: params(filterParams, pageParams, csvParams, []APIParam{severityParam,
+				queryParam("vulnerability", "string", "Vulnerability ID or alias"),
+				queryParam("knownExploited", "boolean", "Only vulnerabilities in the CISA KEV catalog"),
+				queryParam("fixAvailable", "boolean", "Only vulnerabilities with a fix for at least one package"),
+				formatParam("json", "csv")}), Produces: jsonAndCSV},
+		{ID: "GetVulnerability", Method: http.MethodGet, Path: "/api/vulnerabilities/{cve}", Tag: "vulnerabilities",
+			Summary: "Get the packages, images and workloads affected by a vulnerability",
+			Params:  []APIParam{pathParam("cve", "Vulnerability ID or alias")}},
+		{ID: "ListContainerCVEs", Method: http.MethodGet, Path: "/api/container-cves", Tag: "vulnerabilities",
+			Summary: "List vulnerabilities across running containers",
+			Params: params(filterParams, pageParams, csvParams, []APIParam{severityParam,
+				queryParam("vulnerability", "string", "Substring of the vulnerability ID"),
+				formatParam("json", "csv")}), Produces: jsonAndCSV},
+		{ID: "ListContainerCVEAffected", Method: http.MethodGet, Path: "/api/container-cves/affected", Tag: "vulnerabilities",
+			Summary: "List the containers affected by a vulnerability", Params: cveParams},
+		{ID: "ListContainerCVEDetails", Method: http.MethodGet, Path: "/api/container-cves/details", Tag: "vulnerabilities",
+			Summary: "List the match variants of a vulnerability", Params: cveParams},
+		{ID: "GetBlastRadius", Method: http.MethodGet, Path: "/api/analytics/blast-radius", Tag: "vulnerabilities",
+			Summary: "Get the images, workloads and namespaces affected by a package version",
+			Params: []APIParam{queryParam("package", "string", "Package name"),
+				queryParam("version", "string", "Package version"),
+				queryParam("type", "string", "Package type")}},
+		{ID: "GetFixCoverage", Method: http.MethodGet, Path: "/api/analytics/fix-coverage", Tag: "vulnerabilities",
+			Summary: "Get the findings fixable by upgrades, awaiting vendor fixes and the top package upgrades, overall and per namespace",
+			Params: []APIParam{queryParam("namespaces", "list", "Only these namespaces"), severityParam,
+				queryParam("packageTypes", "list", "Only these package types")}},
+		{ID: "GetImageSizeAnalytics", Method: http.MethodGet, Path: "/api/analytics/image-size", Tag: "images",
+			Summary: "Get running images by size bucket, the largest images and how size correlates with scan duration and vulnerabilities",
+			Params: []APIParam{queryParam("namespaces", "list", "Only images running in these namespaces"),
+				queryParam("limit", "integer", "Largest images listed (1-100, default 10)")}},
+		{ID: "GetFilterOptions", Method: http.MethodGet, Path: "/api/filter-options", Tag: "vulnerabilities",
+			Summary: "List the values available for the list filters"},
+		{ID: "ListCMDBMetadata", Method: http.MethodGet, Path: "/api/cmdb", Tag: "images",
+			Summary: "List the application, owner and data classification synced from the CMDB by namespace and image label"},
+		{ID: "SyncCMDBMetadata", Method: http.MethodPost, Path: "/api/cmdb/sync", Tag: "images",
+			Summary: "Replace the CMDB entries of a source with the pushed entries", Body: true},
+		{ID: "GetSeverities", Method: http.MethodGet, Path: "/api/severities", Tag: "vulnerabilities",
+			Summary: "Get the configured severity scale"},
+		{ID: "ListCVEAnnotations", Method: http.MethodGet, Path: "/api/cve-annotations", Tag: "vulnerabilities",
+			Summary: "List the analyst notes attached to vulnerabilities"},
+		{ID: "SaveCVEAnnotation", Method: http.MethodPost, Path: "/api/cve-annotations/save", Tag: "vulnerabilities",
+			Summary: "Attach a cluster-wide note to a vulnerability, replacing any previous note", Body: true},
+		{ID: "DeleteCVEAnnotation", Method: http.MethodPost, Path: "/api/cve-annotations/delete", Tag: "vulnerabilities",
+			Summary: "Remove the note on a vulnerability", Body: true},
+		{ID: "ListVEXDocuments", Method: http.MethodGet, Path: "/api/vex", Tag: "vulnerabilities",
+			Summary: "List the OpenVEX documents in effect, loaded from the configured path or uploaded"},
+		{ID: "UploadVEXDocument", Method: http.MethodPost, Path: "/api/vex/upload", Tag: "vulnerabilities",
+			Summary: "Upload an OpenVEX document whose not_affected statements suppress findings", Body: true},
+		{ID: "DeleteVEXDocument", Method: http.MethodPost, Path: "/api/vex/delete", Tag: "vulnerabilities",
+			Summary: "Remove an uploaded OpenVEX document, restoring the findings it suppressed", Body: true},
+		{ID: "ListVEXSuppressions", Method: http.MethodGet, Path: "/api/vex/suppressions", Tag: "vulnerabilities",
+			Summary: "List the findings suppressed by OpenVEX statements, with their justification",
+			Params:  []APIParam{queryParam("digest", "string", "Only the findings of this image")}},
+		{ID: "ListIgnores", Method: http.MethodGet, Path: "/api/ignores", Tag: "vulnerabilities",
+			Summary: "List the accepted-risk vulnerability ignores, with whether they are active"},
+		{ID: "SaveIgnore", Method: http.MethodPost, Path: "/api/ignores/save", Tag: "vulnerabilities",
+			Summary: "Create or update an ignore accepting the risk of a vulnerability until its expiry", Body: true},
+		{ID: "DeleteIgnore", Method: http.MethodPost, Path: "/api/ignores/delete", Tag: "vulnerabilities",
+			Summary: "Remove an ignore, restoring the findings it suppressed", Body: true},
+		{ID: "ListIgnoreEvents", Method: http.MethodGet, Path: "/api/ignores/audit", Tag: "vulnerabilities",
+			Summary: "List who created, changed or deleted ignores and when they expired",
+			Params:  []APIParam{queryParam("id", "integer", "Only the events of this ignore")}},
+
+		// Summaries
+		{ID: "GetDeploymentMetrics", Method: http.MethodGet, Path: "/api/summary/deployment-metrics", Tag: "summary",
+			Summary: "Get cluster-wide vulnerability metrics",
+			Params:  params(filterParams, []APIParam{severityParam})},
+		{ID: "GetNodeMetrics", Method: http.MethodGet, Path: "/api/summary/node-metrics", Tag: "summary",
+			Summary: "Get node-wide vulnerability metrics",
+			Params:  params(nodeFilterParams, []APIParam{severityParam})},
+		{ID: "GetNamespaceSummary", Method: http.MethodGet, Path: "/api/summary/by-namespace", Tag: "summary",
+			Summary: "Get average vulnerability counts per namespace",
+			Params:  params(filterParams, pageParams, csvParams, []APIParam{formatParam("json", "csv")}), Produces: jsonAndCSV},
+		{ID: "GetDistributionSummary", Method: http.MethodGet, Path: "/api/summary/by-distribution", Tag: "summary",
+			Summary: "Get average vulnerability counts per OS distribution",
+			Params:  params(filterParams, pageParams, csvParams, []APIParam{formatParam("json", "csv")}), Produces: jsonAndCSV},
+		{ID: "GetScanCoverage", Method: http.MethodGet, Path: "/api/summary/coverage", Tag: "summary",
+			Summary: "Get the share of observed images with a completed scan",
+			Params:  []APIParam{queryParam("lookback", "string", "Window of completed Jobs counted, e.g. 24h")}},
+		{ID: "GetScanTrends", Method: http.MethodGet, Path: "/api/summary/trends", Tag: "summary",
+			Summary: "Get the combined severity counts and risk score of running images per day or week",
+			Params: []APIParam{queryParam("interval", "string", "Trend interval (default week)", "day", "week"),
+				queryParam("lookback", "string", "Window of scans included, e.g. 720h (default 2016h)"),
+				queryParam("namespaces", "list", "Only images running in these namespaces")}},
+		{ID: "GetOSEOLSummary", Method: http.MethodGet, Path: "/api/summary/os-eol", Tag: "summary",
+			Summary: "Get the end-of-life status of the OS releases of running images"},
+		{ID: "GetLastUpdated", Method: http.MethodGet, Path: "/api/lastupdated", Tag: "summary",
+			Summary: "Get the time scan data last changed (RFC 3339, plain text)",
+			Params:  []APIParam{queryParam("datatype", "string", "Data type (all or image)")}, Produces: []string{"text/plain"}},
+		{ID: "GetReport", Method: http.MethodGet, Path: "/api/report", Tag: "summary",
+			Summary: "Download a self-contained HTML report of the current scan state",
+			Params:  []APIParam{queryParam("maxSizeMB", "integer", "Maximum report size (1-500, default 50)"), tzParam}, Produces: []string{"text/html"}},
+		{ID: "GetPolicy", Method: http.MethodGet, Path: "/api/policy", Tag: "summary",
+			Summary: "Get the policy, or bundle of policies, images are evaluated against"},
+		{ID: "GetPolicyReport", Method: http.MethodGet, Path: "/api/policy/report", Tag: "summary",
+			Summary: "Evaluate all running images against the configured policy, failing images first",
+			Params: []APIParam{queryParam("namespaces", "list", "Only images running in these namespaces"),
+				queryParam("verdict", "list", "Only list images with these verdicts (pass, fail, not_scanned, no_policy)")}},
+
+		// Nodes
+		{ID: "ListNodes", Method: http.MethodGet, Path: "/api/nodes", Tag: "nodes",
+			Summary: "List nodes with their host scan status"},
+		{ID: "GetNode", Method: http.MethodGet, Path: "/api/nodes/{name}", Tag: "nodes",
+			Summary: "Get a node", Params: []APIParam{pathParam("name", "Node name")}},
+		{ID: "ListNodePackages", Method: http.MethodGet, Path: "/api/nodes/{name}/packages", Tag: "nodes",
+			Summary:  "List the packages of a node",
+			Params:   params([]APIParam{pathParam("name", "Node name"), formatParam("json", "csv")}, csvParams),
+			Produces: jsonAndCSV},
+		{ID: "ListNodeVulnerabilities", Method: http.MethodGet, Path: "/api/nodes/{name}/vulnerabilities", Tag: "nodes",
+			Summary:  "List the vulnerabilities of a node",
+			Params:   params([]APIParam{pathParam("name", "Node name"), formatParam("json", "csv")}, csvParams),
+			Produces: jsonAndCSV},
+		{ID: "ListNodeScanners", Method: http.MethodGet, Path: "/api/nodes/scanners", Tag: "nodes",
+			Summary: "Report the node scanner compatibility of each node"},
+		{ID: "GetNodeSummary", Method: http.MethodGet, Path: "/api/summary/by-node", Tag: "nodes",
+			Summary: "Get vulnerability counts per node",
+			Params:  params(nodeFilterParams, csvParams, []APIParam{formatParam("json", "csv")}), Produces: jsonAndCSV},
+		{ID: "GetNodeDistributionSummary", Method: http.MethodGet, Path: "/api/summary/by-node-distro", Tag: "nodes",
+			Summary: "Get average vulnerability counts per node OS distribution",
+			Params:  params(csvParams, []APIParam{formatParam("json", "csv")}), Produces: jsonAndCSV},
+		{ID: "GetNodeFilterOptions", Method: http.MethodGet, Path: "/api/node-filter-options", Tag: "nodes",
+			Summary: "List the values available for the node list filters"},
+		{ID: "GetNodeVulnerabilityDetails", Method: http.MethodGet, Path: "/api/node-vulnerabilities/{id}/details", Tag: "nodes",
+			Summary: "Get the Grype match details of a node vulnerability",
+			Params:  []APIParam{pathParam("id", "Node vulnerability row ID")}},
+		{ID: "GetNodeVulnerabilityOccurrences", Method: http.MethodGet, Path: "/api/node-vulnerabilities/{id}/occurrences", Tag: "nodes",
+			Summary: "List the paths at which the package of a node vulnerability was found",
+			Params:  []APIParam{pathParam("id", "Node vulnerability row ID")}},
+		{ID: "GetNodePackageDetails", Method: http.MethodGet, Path: "/api/node-packages/{id}/details", Tag: "nodes",
+			Summary: "Get the Syft details of a node package",
+			Params:  []APIParam{pathParam("id", "Node package row ID")}},
+		{ID: "ListNodeCVEs", Method: http.MethodGet, Path: "/api/node-cves", Tag: "nodes",
+			Summary: "List vulnerabilities across nodes",
+			Params: params(nodeFilterParams, pageParams, csvParams, []APIParam{severityParam,
+				queryParam("vulnerability", "string", "Substring of the vulnerability ID"),
+				formatParam("json", "csv")}), Produces: jsonAndCSV},
+		{ID: "ListNodeCVEAffected", Method: http.MethodGet, Path: "/api/node-cves/affected", Tag: "nodes",
+			Summary: "List the nodes affected by a vulnerability",
+			Params:  []APIParam{queryParam("cve", "string", "Vulnerability ID")}},
+		{ID: "ListNodeCVEDetails", Method: http.MethodGet, Path: "/api/node-cves/details", Tag: "nodes",
+			Summary: "List the match variants of a node vulnerability",
+			Params:  []APIParam{queryParam("cve", "string", "Vulnerability ID")}},
+
+		// Scans
+		{ID: "SubmitScan", Method: http.MethodPost, Path: "/api/scan", Tag: "scans",
+			Summary: "Scan an image reference on demand", Body: true},
+		{ID: "GetScan", Method: http.MethodGet, Path: "/api/scan/{digest}", Tag: "scans",
+			Summary: "Get the state and results of an on-demand scan",
+			Params:  []APIParam{pathParam("digest", "Image digest returned by SubmitScan")}},
+		{ID: "GetScanQueueStatus", Method: http.MethodGet, Path: "/api/scan-queue", Tag: "scans",
+			Summary: "Get the scan queue depth, the scan in progress and its phase, and the estimated time to drain the queue"},
+		{ID: "ListDeadLetteredScans", Method: http.MethodGet, Path: "/api/scan-queue/dead-letter", Tag: "scans",
+			Summary: "List images that failed to scan too many times to be retried"},
+		{ID: "RequeueDeadLetteredScans", Method: http.MethodPost, Path: "/api/scan-queue/dead-letter/requeue", Tag: "scans",
+			Summary: "Requeue dead-lettered images", Body: true},
+		{ID: "ScanNow", Method: http.MethodPost, Path: "/api/scan-queue/scan-now", Tag: "scans",
+			Summary: "Scan running images ahead of the rest of the scan queue", Body: true},
+		{ID: "RescanImage", Method: http.MethodPost, Path: "/api/images/{digest}/rescan", Tag: "scans",
+			Summary: "Rescan a running image ahead of the rest of the scan queue",
+			Params: []APIParam{pathParam("digest", "Image digest"),
+				queryParam("force", "boolean", "Regenerate the SBOM instead of rescanning the stored one")}},
+		{ID: "RescanAllImages", Method: http.MethodPost, Path: "/api/rescan-all", Tag: "scans",
+			Summary: "Rescan every running image",
+			Params: []APIParam{queryParam("force", "boolean", "Regenerate the SBOMs instead of rescanning the stored ones"),
+				queryParam("confirm", "boolean", "Start a rescan above the confirmation threshold (409 with the estimate otherwise)")}},
+		{ID: "GetRescanAllEstimate", Method: http.MethodGet, Path: "/api/rescan-all/estimate", Tag: "scans",
+			Summary: "Estimate the images, duration and completion of a rescan of every running image",
+			Params:  []APIParam{queryParam("force", "boolean", "Estimate regenerating the SBOMs too")}},
+		{ID: "ListRegistryImages", Method: http.MethodGet, Path: "/api/registry/images", Tag: "scans",
+			Summary: "List the images found by the registry crawl with their scan status"},
+		{ID: "GetNotifyRoutes", Method: http.MethodGet, Path: "/api/notify/routes", Tag: "scans",
+			Summary: "List the alert destinations of namespaces with a bjorn2scan.io/notify annotation and the default"},
+		{ID: "ListScanFailures", Method: http.MethodGet, Path: "/api/scan-queue/failures", Tag: "scans",
+			Summary: "List failing images grouped by node and failure reason"},
+		{ID: "ExportImage", Method: http.MethodGet, Path: "/api/export/images/{digest}", Tag: "scans",
+			Summary: "Export the scan results of an image as a signed bundle",
+			Params:  []APIParam{pathParam("digest", "Image digest")}},
+		{ID: "ImportImage", Method: http.MethodPost, Path: "/api/import", Tag: "scans",
+			Summary: "Import a signed scan result bundle", Body: true,
+			Params: []APIParam{queryParam("force", "boolean", "Replace complete scan data")}},
+
+		// Status
+		{ID: "GetHealth", Method: http.MethodGet, Path: "/health", Tag: "status",
+			Summary: "Health check", Produces: []string{"text/plain"}},
+		{ID: "GetReady", Method: http.MethodGet, Path: "/ready", Tag: "status",
+			Summary: "Readiness check", Produces: []string{"text/plain"}},
+		{ID: "GetInfo", Method: http.MethodGet, Path: "/info", Tag: "status",
+			Summary: "Get deployment information"},
+		{ID: "GetConfig", Method: http.MethodGet, Path: "/api/config", Tag: "status",
+			Summary: "Get the UI configuration and the effective runtime configuration (secrets redacted) with the source of each value"},
+		{ID: "GetUIConfig", Method: http.MethodGet, Path: "/api/ui-config", Tag: "status",
+			Summary: "Get which web UI controls are enabled (hidden in read-only mode and from viewers)"},
+		{ID: "GetWhoAmI", Method: http.MethodGet, Path: "/api/auth/whoami", Tag: "status",
+			Summary: "Get the caller's identity and role (only registered with authentication enabled)"},
+		{ID: "GetDatabaseStatus", Method: http.MethodGet, Path: "/api/db/status", Tag: "status",
+			Summary: "Get the vulnerability database status"},
+		{ID: "GetVulnDBStatus", Method: http.MethodGet, Path: "/api/vulndb", Tag: "status",
+			Summary: "Get the vulnerability database build date and schema version, the last feed check and the next scheduled check"},
+		{ID: "RefreshVulnDB", Method: http.MethodPost, Path: "/api/vulndb/refresh", Tag: "status",
+			Summary: "Check the vulnerability database feed now and download a newer database (409 if a check is already running)"},
+		{ID: "GetScanHealth", Method: http.MethodGet, Path: "/api/status", Tag: "status",
+			Summary: "Get scan pipeline health and images stuck in intermediate states"},
+		{ID: "GetMigrationStatus", Method: http.MethodGet, Path: "/api/status/migration", Tag: "status",
+			Summary: "Get the schema migration status"},
+		{ID: "GetCompatibility", Method: http.MethodGet, Path: "/api/status/compatibility", Tag: "status",
+			Summary: "Get the binary and schema versions, the oldest version a downgrade can go back to and whether migrations run on the next restart"},
+		{ID: "GetDiskUsage", Method: http.MethodGet, Path: "/api/status/disk", Tag: "status",
+			Summary: "Get data volume usage"},
+		{ID: "PreviewGC", Method: http.MethodGet, Path: "/api/gc/preview", Tag: "status",
+			Summary: "List the images, packages, vulnerabilities and SBOM documents the purge job would remove on its next run",
+			Params:  []APIParam{queryParam("retention", "string", "Soft-deleted image retention to preview, e.g. 168h (default: the configured retention)")}},
+		{ID: "ListJobs", Method: http.MethodGet, Path: "/api/jobs", Tag: "status",
+			Summary: "List the scheduled jobs with their schedule, last run, last success, duration and consecutive failures"},
+		{ID: "RunJob", Method: http.MethodPost, Path: "/api/jobs/{name}/run", Tag: "status",
+			Summary: "Run a scheduled job now (409 if it is already running)",
+			Params:  []APIParam{pathParam("name", "Job name")}},
+		{ID: "GetSchema", Method: http.MethodGet, Path: "/api/admin/schema", Tag: "status",
+			Summary: "Get the database schema",
+			Params:  []APIParam{formatParam("json", "mermaid")}, Produces: []string{"application/json", "text/plain"}},
+	}
+}
+
+// registeredRoute returns the route serving an operation path, if any
+// (rather than the catch-all web UI handler)
+func registeredRoute(reg *routes.Registry, path string) (routes.Route, bool) {
+	return reg.Match(pathParamPattern.ReplaceAllString(path, "x"))
+}
+
+// BuildOpenAPISpec returns an OpenAPI 3.0 document of the operations whose
+// path is registered, so optional endpoints that are disabled (node API,
+// on-demand scans, ...) are left out. Each operation lists the role its
+// route requires as x-required-role; all but public ones take a bearer token
+// when authentication is enabled.
+func BuildOpenAPISpec(reg *routes.Registry, version string) map[string]interface{} {
+	paths := map[string]interface{}{}
+	for _, op := range OpenAPIOperations() {
+		route, ok := registeredRoute(reg, op.Path)
+		if !ok {
+			continue
+		}
+		item, ok := paths[op.Path].(map[string]interface{})
+		if !ok {
+			item = map[string]interface{}{}
+			paths[op.Path] = item
+		}
+		operation := openAPIOperation(op)
+		operation["x-required-role"] = route.Role.String()
+		if route.Role == routes.RolePublic {
+			operation["security"] = []interface{}{}
+		}
+		item[strings.ToLower(op.Method)] = operation
+	}
+
+	return map[string]interface{}{
+		"openapi": "3.0.3",
+		"info": map[string]interface{}{
+			"title":   "bjorn2scan API",
+			"version": version,
+		},
+		"paths": paths,
+		"components": map[string]interface{}{
+			"securitySchemes": map[string]interface{}{
+				"bearerAuth": map[string]interface{}{"type": "http", "scheme": "bearer"},
+			},
+		},
+		"security": []interface{}{map[string]interface{}{"bearerAuth": []interface{}{}}},
+	}
+}
+
+// openAPIOperation renders an operation object
+func openAPIOperation(op APIOperation) map[string]interface{} {
+	parameters := []interface{}{}
+	for _, p := range op.Params {
+		schema := map[string]interface{}{"type": p.Type}
+		switch p.Type {
+		case "list":
+			// Comma-separated values in a single parameter
+			schema = map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": "string"}}
+		case "string":
+			if len(p.Enum) > 0 {
+				schema["enum"] = p.Enum
+			}
+		}
+		param := map[string]interface{}{
+			"name":        p.Name,
+			"in":          p.In,
+			"required":    p.In == "path",
+			"description": p.Description,
+			"schema":      schema,
+		}
+		if p.Type == "list" {
+			param["style"] = "form"
+			param["explode"] = false
+		}
+		parameters = append(parameters, param)
+	}
+
+	produces := op.Produces
+	if len(produces) == 0 {
+		produces = []string{"application/json"}
+	}
+	content := map[string]interface{}{}
+	for _, mediaType := range produces {
+		content[mediaType] = map[string]interface{}{}
+	}
+
+	operation := map[string]interface{}{
+		"operationId": op.ID,
+		"summary":     op.Summary,
+		"tags":        []string{op.Tag},
+		"parameters":  parameters,
+		"responses": map[string]interface{}{
+			"200": map[string]interface{}{"description": "OK", "content": content},
+		},
+	}
+	if op.Body {
+		operation["requestBody"] = map[string]interface{}{
+			"required": true,
+			"content":  map[string]interface{}{"application/json": map[string]interface{}{}},
+		}
+	}
+	return operation
+}
+
+// RegisterOpenAPIHandlers registers the OpenAPI spec of the API served by reg
+func RegisterOpenAPIHandlers(reg *routes.Registry, version string) {
+	reg.Handle(routes.Route{Pattern: "/api/openapi.json", Methods: routes.GET, Handler: OpenAPIHandler(reg, version)})
+}
+
+// OpenAPIHandler creates an HTTP handler for GET /api/openapi.json. The spec
+// is built per request from the routes registered on reg at that time, so it
+// includes handlers registered after this one.
+func OpenAPIHandler(reg *routes.Registry, version string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(BuildOpenAPISpec(reg, version)); err != nil {
+			log.Error("error encoding OpenAPI spec", "error", err)
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/bvboe/b2s-go/scanner-core/routes"
+)
+
+func TestOpenAPIOperations(t *testing.T) {
+	ids := make(map[string]bool)
+	for _, op := range OpenAPIOperations() {
+		if ids[op.ID] {
+			t.Errorf("duplicate operation ID %s", op.ID)
+		}
+		ids[op.ID] = true
+
+		placeholders := make(map[string]bool)
+		for _, m := range pathParamPattern.FindAllStringSubmatch(op.Path, -1) {
+			placeholders[m[1]] = true
+		}
+		for _, p := range op.Params {
+			if p.In == "path" && !placeholders[p.Name] {
+				t.Errorf("%s: path parameter %s missing from %s", op.ID, p.Name, op.Path)
+			}
+			delete(placeholders, p.Name)
+		}
+		for name := range placeholders {
+			t.Errorf("%s: placeholder {%s} has no path parameter", op.ID, name)
+		}
+	}
+}
+
+func TestOpenAPIHandler(t *testing.T) {
+	db := createTransferTestDB(t, "openapi")
+
+	spec := func(opts APIOptions) map[string]map[string]interface{} {
+		mux := routes.NewRegistry()
+		RegisterAPIHandlers(mux, db, opts)
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/openapi.json", nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d", w.Code)
+		}
+		var doc struct {
+			OpenAPI string                            `json:"openapi"`
+			Paths   map[string]map[string]interface{} `json:"paths"`
+		}
+		if err := json.NewDecoder(w.Body).Decode(&doc); err != nil {
+			t.Fatalf("failed to decode spec: %v", err)
+		}
+		if doc.OpenAPI != "3.0.3" {
+			t.Errorf("unexpected openapi version %q", doc.OpenAPI)
+		}
+		return doc.Paths
+	}
+
+	paths := spec(APIOptions{Version: "1.2.3"})
+	for _, path := range []string{"/api/images", "/api/images/{digest}/vulnerabilities", "/api/scan-queue/failures"} {
+		if _, ok := paths[path]["get"]; !ok {
+			t.Errorf("expected GET %s in spec", path)
+		}
+	}
+	// Operations carry the role their route requires
+	for path, want := range map[string]string{"/api/images": "viewer", "/api/badge/{image}": "public"} {
+		if op, ok := paths[path]["get"].(map[string]interface{}); !ok || op["x-required-role"] != want {
+			t.Errorf("GET %s x-required-role = %v, want %s", path, paths[path]["get"], want)
+		}
+	}
+	if op, ok := paths["/api/import"]["post"].(map[string]interface{}); !ok || op["x-required-role"] != "admin" {
+		t.Errorf("POST /api/import x-required-role = %v, want admin", paths["/api/import"]["post"])
+	}
+	// Optional endpoints are only documented when registered
+	for _, path := range []string{"/api/nodes", "/api/scan", "/api/scan-queue/scan-now", "/api/registry/images"} {
+		if _, ok := paths[path]; ok {
+			t.Errorf("unregistered %s documented", path)
+		}
+	}
+	if _, ok := spec(APIOptions{NodeAPI: true})["/api/nodes/{name}/vulnerabilities"]; !ok {
+		t.Error("expected node endpoints in spec with the node API enabled")
+	}
+
+	// Every node operation is routed when the node API is enabled
+	mux := routes.NewRegistry()
+	RegisterAPIHandlers(mux, db, APIOptions{NodeAPI: true, NodeScanners: &mockNodeScannerReporter{}})
+	for _, op := range OpenAPIOperations() {
+		if _, ok := registeredRoute(mux, op.Path); op.Tag == "nodes" && !ok {
+			t.Errorf("%s %s is documented but not registered", op.Method, op.Path)
+		}
+	}
+}
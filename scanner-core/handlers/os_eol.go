@@ -0,0 +1,74 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/bvboe/b2s-go/scanner-core/database"
+	"github.com/bvboe/b2s-go/scanner-core/eol"
+	"github.com/bvboe/b2s-go/scanner-core/routes"
+)
+
+// OSLifecycle answers OS end-of-life lookups (implemented by eol.Catalog)
+type OSLifecycle interface {
+	Lookup(osName, osVersion string, now time.Time) eol.Status
+	Summarize(counts []database.OSVersionCount, now time.Time) *eol.Summary
+}
+
+// OSVersionProvider counts running images and containers per OS release
+type OSVersionProvider interface {
+	GetOSVersionCounts() ([]database.OSVersionCount, error)
+}
+
+// osLifecycleColumns are appended to /api/images rows when lifecycle data is available
+var osLifecycleColumns = []string{"os_eol_status", "os_eol_date", "os_eol"}
+
+// annotateOSLifecycle adds the end-of-life status of each row's OS release.
+// Rows must have os_name and os_version columns.
+func annotateOSLifecycle(result *database.QueryResult, lifecycle OSLifecycle, now time.Time) {
+	if lifecycle == nil {
+		return
+	}
+	result.Columns = append(result.Columns, osLifecycleColumns...)
+	for _, row := range result.Rows {
+		osName, _ := row["os_name"].(string)
+		osVersion, _ := row["os_version"].(string)
+		status := eol.Status{Status: eol.StatusUnknown}
+		if osName != "" {
+			status = lifecycle.Lookup(osName, osVersion, now)
+		}
+		row["os_eol_status"] = status.Status
+		row["os_eol_date"] = status.EOLDate
+		row["os_eol"] = status.IsEOL()
+	}
+}
+
+// RegisterOSEOLHandlers registers the OS end-of-life summary endpoint
+func RegisterOSEOLHandlers(reg *routes.Registry, provider OSVersionProvider, lifecycle OSLifecycle) {
+	reg.Handle(routes.Route{Pattern: "/api/summary/os-eol", Methods: routes.GET, Handler: OSEOLSummaryHandler(provider, lifecycle)})
+}
+
+// OSEOLSummaryHandler creates an HTTP handler for /api/summary/os-eol.
+// Returns the lifecycle status of every OS release running in the cluster,
+// end-of-life releases first, with image and container counts per status.
+func OSEOLSummaryHandler(provider OSVersionProvider, lifecycle OSLifecycle) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		counts, err := provider.GetOSVersionCounts()
+		if err != nil {
+			log.Error("error querying OS versions", "error", err)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(lifecycle.Summarize(counts, time.Now())); err != nil {
+			log.Error("error encoding OS end-of-life summary", "error", err)
+		}
+	}
+}
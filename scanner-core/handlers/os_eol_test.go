@@ -0,0 +1,121 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/bvboe/b2s-go/scanner-core/database"
+	"github.com/bvboe/b2s-go/scanner-core/eol"
+	"github.com/bvboe/b2s-go/scanner-core/routes"
+)
+
+type mockOSVersionProvider struct {
+	counts []database.OSVersionCount
+}
+
+func (m *mockOSVersionProvider) GetOSVersionCounts() ([]database.OSVersionCount, error) {
+	return m.counts, nil
+}
+
+func newTestOSLifecycle(t *testing.T) *eol.Catalog {
+	t.Helper()
+	catalog, err := eol.NewCatalog(eol.DefaultWarningDays)
+	if err != nil {
+		t.Fatalf("NewCatalog() error = %v", err)
+	}
+	return catalog
+}
+
+// osImagesProvider serves an images query result with one end-of-life image
+// and one image without a detected OS
+func osImagesProvider() *mockQueryProvider {
+	return &mockQueryProvider{
+		queryFunc: func(query string) (*database.QueryResult, error) {
+			if strings.HasPrefix(strings.TrimSpace(query), "SELECT COUNT(*)") {
+				return &database.QueryResult{
+					Columns: []string{"COUNT(*)"},
+					Rows:    []map[string]interface{}{{"COUNT(*)": int64(2)}},
+				}, nil
+			}
+			return &database.QueryResult{
+				Columns: []string{"image", "os_name", "os_version"},
+				Rows: []map[string]interface{}{
+					{"image": "legacy:1", "os_name": "debian", "os_version": "9"},
+					{"image": "scratch:1", "os_name": nil, "os_version": ""},
+				},
+			}, nil
+		},
+	}
+}
+
+func TestImagesHandlerOSLifecycle(t *testing.T) {
+	handler := ImagesHandler(osImagesProvider(), newTestOSLifecycle(t), nil)
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/images", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200", rec.Code)
+	}
+	var response struct {
+		Images []map[string]interface{} `json:"images"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&response); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if len(response.Images) != 2 {
+		t.Fatalf("got %d images, want 2", len(response.Images))
+	}
+	legacy, scratch := response.Images[0], response.Images[1]
+	if legacy["os_eol_status"] != eol.StatusEOL || legacy["os_eol"] != true || legacy["os_eol_date"] != "2020-07-18" {
+		t.Errorf("debian 9 image = %v, want end of life since 2020-07-18", legacy)
+	}
+	if scratch["os_eol_status"] != eol.StatusUnknown || scratch["os_eol"] != false {
+		t.Errorf("image without OS = %v, want unknown", scratch)
+	}
+
+	// CSV export includes the lifecycle columns
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/images?format=csv", nil))
+	records, err := csv.NewReader(rec.Body).ReadAll()
+	if err != nil {
+		t.Fatalf("failed to parse CSV: %v", err)
+	}
+	if header := strings.Join(records[0], ","); !strings.HasSuffix(header, "os_eol_status,os_eol_date,os_eol") {
+		t.Errorf("CSV header = %s, want lifecycle columns", header)
+	}
+}
+
+func TestOSEOLSummaryHandler(t *testing.T) {
+	provider := &mockOSVersionProvider{counts: []database.OSVersionCount{
+		{OSName: "debian", OSVersion: "13", Images: 3, Containers: 5},
+		{OSName: "debian", OSVersion: "9", Images: 1, Containers: 2},
+	}}
+	mux := routes.NewRegistry()
+	RegisterOSEOLHandlers(mux, provider, newTestOSLifecycle(t))
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/summary/os-eol", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200", rec.Code)
+	}
+	var summary eol.Summary
+	if err := json.NewDecoder(rec.Body).Decode(&summary); err != nil {
+		t.Fatalf("failed to decode summary: %v", err)
+	}
+	if len(summary.Distributions) != 2 || summary.Distributions[0].OSVersion != "9" {
+		t.Errorf("distributions = %+v, want debian 9 first", summary.Distributions)
+	}
+	if summary.Containers[eol.StatusEOL] != 2 {
+		t.Errorf("containers_by_status = %v, want 2 eol", summary.Containers)
+	}
+
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/summary/os-eol", nil))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("POST status = %d, want 405", rec.Code)
+	}
+}
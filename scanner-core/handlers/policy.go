@@ -0,0 +1,158 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/bvboe/b2s-go/scanner-core/database"
+	"github.com/bvboe/b2s-go/scanner-core/policy"
+	"github.com/bvboe/b2s-go/scanner-core/routes"
+)
+
+// PolicyProvider provides the facts images are evaluated against a policy with
+// (implemented by database.DB)
+type PolicyProvider interface {
+	GetImagePolicyFacts(digest string) (*database.ImagePolicyFacts, error)
+	GetRunningImagePolicyFacts(namespaces []string) ([]database.ImagePolicyFacts, error)
+}
+
+// imagePolicyResult is the verdict of one image in the policy report
+type imagePolicyResult struct {
+	policy.Result
+	Reference  string   `json:"reference"`
+	Namespaces []string `json:"namespaces"`
+}
+
+// policyReport is the response of /api/policy/report
+type policyReport struct {
+	Policy  policy.Evaluator    `json:"policy"`
+	Summary map[string]int      `json:"summary"`
+	Images  []imagePolicyResult `json:"images"`
+}
+
+// verdictOrder lists failing images first in the policy report
+var verdictOrder = []string{policy.VerdictFail, policy.VerdictNotScanned, policy.VerdictPass, policy.VerdictNoPolicy}
+
+// RegisterPolicyHandlers registers the active policy and the policy report
+// endpoints. Per-image verdicts at /api/images/{digest}/policy are routed by
+// RegisterDatabaseHandlers (HandlerOverrides.Policy).
+func RegisterPolicyHandlers(reg *routes.Registry, provider PolicyProvider, p policy.Evaluator) {
+	reg.Handle(
+		routes.Route{Pattern: "/api/policy", Methods: routes.GET, Handler: PolicyHandler(p)},
+		routes.Route{Pattern: "/api/policy/report", Methods: routes.GET, Handler: PolicyReportHandler(provider, p)},
+	)
+}
+
+// PolicyHandler creates an HTTP handler for /api/policy.
+// Returns the policy, or bundle of policies, images are evaluated against.
+func PolicyHandler(p policy.Evaluator) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(p); err != nil {
+			log.Error("error encoding policy", "error", err)
+		}
+	}
+}
+
+// ImagePolicyHandler creates an HTTP handler for /api/images/{digest}/policy.
+// Returns the pass/fail verdict of the image and the rules it violates.
+func ImagePolicyHandler(provider PolicyProvider, p policy.Evaluator) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		digest := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/api/images/"), "/policy")
+		if digest == "" {
+			http.Error(w, "Digest required", http.StatusBadRequest)
+			return
+		}
+
+		facts, err := provider.GetImagePolicyFacts(digest)
+		if err != nil {
+			log.Error("error querying image policy facts", "digest", digest, "error", err)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		if facts == nil {
+			http.Error(w, "Image not found", http.StatusNotFound)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(p.Evaluate(facts)); err != nil {
+			log.Error("error encoding image policy result", "error", err)
+		}
+	}
+}
+
+// PolicyReportHandler creates an HTTP handler for /api/policy/report.
+// Evaluates every running image (optionally only those in ?namespaces=)
+// against the policy. Images are listed failing first, then not scanned, then
+// passing, then those no policy of a bundle applies to, each by reference;
+// ?verdict= limits the list to the given verdicts while the summary always
+// counts all evaluated images.
+func PolicyReportHandler(provider PolicyProvider, p policy.Evaluator) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		params := r.URL.Query()
+		verdicts := parseMultiSelect(params.Get("verdict"))
+		for _, v := range verdicts {
+			if !slices.Contains(verdictOrder, v) {
+				http.Error(w, "Invalid verdict: "+v, http.StatusBadRequest)
+				return
+			}
+		}
+
+		facts, err := provider.GetRunningImagePolicyFacts(parseMultiSelect(params.Get("namespaces")))
+		if err != nil {
+			log.Error("error querying running image policy facts", "error", err)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+
+		report := policyReport{Policy: p, Summary: map[string]int{"images": len(facts)}, Images: []imagePolicyResult{}}
+		for _, v := range verdictOrder {
+			// no_policy is only counted when a bundle leaves images unchecked
+			if v != policy.VerdictNoPolicy {
+				report.Summary[v] = 0
+			}
+		}
+		for i := range facts {
+			result := p.Evaluate(&facts[i])
+			report.Summary[result.Verdict]++
+			if len(verdicts) > 0 && !slices.Contains(verdicts, result.Verdict) {
+				continue
+			}
+			namespaces := facts[i].Namespaces
+			if namespaces == nil {
+				namespaces = []string{}
+			}
+			report.Images = append(report.Images, imagePolicyResult{
+				Result:     result,
+				Reference:  facts[i].Reference,
+				Namespaces: namespaces,
+			})
+		}
+		slices.SortStableFunc(report.Images, func(a, b imagePolicyResult) int {
+			if d := slices.Index(verdictOrder, a.Verdict) - slices.Index(verdictOrder, b.Verdict); d != 0 {
+				return d
+			}
+			return strings.Compare(a.Reference, b.Reference)
+		})
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(report); err != nil {
+			log.Error("error encoding policy report", "error", err)
+		}
+	}
+}
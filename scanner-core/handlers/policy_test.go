@@ -0,0 +1,152 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/bvboe/b2s-go/scanner-core/database"
+	"github.com/bvboe/b2s-go/scanner-core/policy"
+)
+
+// mockPolicyProvider serves fixed policy facts
+type mockPolicyProvider struct {
+	facts      []database.ImagePolicyFacts
+	namespaces []string
+}
+
+func (m *mockPolicyProvider) GetImagePolicyFacts(digest string) (*database.ImagePolicyFacts, error) {
+	for i := range m.facts {
+		if m.facts[i].Digest == digest {
+			return &m.facts[i], nil
+		}
+	}
+	return nil, nil
+}
+
+func (m *mockPolicyProvider) GetRunningImagePolicyFacts(namespaces []string) ([]database.ImagePolicyFacts, error) {
+	m.namespaces = namespaces
+	return m.facts, nil
+}
+
+func newTestPolicyProvider() *mockPolicyProvider {
+	return &mockPolicyProvider{facts: []database.ImagePolicyFacts{
+		{Digest: "sha256:clean", Reference: "app:2", Status: database.StatusCompleted, Namespaces: []string{"default"}},
+		{Digest: "sha256:kev", Reference: "legacy:1", Status: database.StatusCompleted, Namespaces: []string{"default"}, KnownExploited: 2},
+		{Digest: "sha256:new", Reference: "app:3", Status: database.StatusScanningVulnerabilities},
+		{Digest: "sha256:critical", Reference: "api:1", Status: database.StatusCompleted, Critical: 1},
+	}}
+}
+
+func TestImagePolicyHandler(t *testing.T) {
+	provider := newTestPolicyProvider()
+	handler := ImagePolicyHandler(provider, policy.Default())
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/images/sha256:kev/policy", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200", rec.Code)
+	}
+	var result policy.Result
+	if err := json.NewDecoder(rec.Body).Decode(&result); err != nil {
+		t.Fatalf("invalid response: %v", err)
+	}
+	if result.Digest != "sha256:kev" || result.Verdict != policy.VerdictFail || result.Pass ||
+		len(result.Violations) != 1 || result.Violations[0].Rule != policy.RuleNoKnownExploited {
+		t.Errorf("unexpected result %+v", result)
+	}
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/images/sha256:unknown/policy", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("unknown image: status = %d, want 404", rec.Code)
+	}
+}
+
+func TestPolicyReportHandler(t *testing.T) {
+	provider := newTestPolicyProvider()
+	handler := PolicyReportHandler(provider, policy.Default())
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/policy/report?namespaces=default,team-a", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200", rec.Code)
+	}
+	var report struct {
+		Policy  policy.Policy  `json:"policy"`
+		Summary map[string]int `json:"summary"`
+		Images  []struct {
+			Reference  string   `json:"reference"`
+			Verdict    string   `json:"verdict"`
+			Namespaces []string `json:"namespaces"`
+		} `json:"images"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&report); err != nil {
+		t.Fatalf("invalid response: %v", err)
+	}
+	if !reflect.DeepEqual(provider.namespaces, []string{"default", "team-a"}) {
+		t.Errorf("namespaces = %v, want [default team-a]", provider.namespaces)
+	}
+	if want := map[string]int{"images": 4, "pass": 1, "fail": 2, "not_scanned": 1}; !reflect.DeepEqual(report.Summary, want) {
+		t.Errorf("summary = %v, want %v", report.Summary, want)
+	}
+	// Failing images first, then not scanned, then passing, each by reference
+	var order []string
+	for _, img := range report.Images {
+		order = append(order, img.Verdict+" "+img.Reference)
+	}
+	if want := []string{"fail api:1", "fail legacy:1", "not_scanned app:3", "pass app:2"}; !reflect.DeepEqual(order, want) {
+		t.Errorf("images = %v, want %v", order, want)
+	}
+	if report.Policy.Name != "default" || report.Images[2].Namespaces == nil {
+		t.Errorf("unexpected report %+v", report)
+	}
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/policy/report?verdict=pass", nil))
+	if err := json.NewDecoder(rec.Body).Decode(&report); err != nil {
+		t.Fatalf("invalid response: %v", err)
+	}
+	if len(report.Images) != 1 || report.Images[0].Reference != "app:2" || report.Summary["images"] != 4 {
+		t.Errorf("verdict filter: unexpected report %+v", report)
+	}
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/policy/report?verdict=maybe", nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("invalid verdict: status = %d, want 400", rec.Code)
+	}
+}
+
+func TestPolicyReportHandlerBundle(t *testing.T) {
+	bundle, err := policy.ParseBundle([]byte(`
+name: team-default
+include:
+  namespaces: [default]
+rules:
+  no_known_exploited: true
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	handler := PolicyReportHandler(newTestPolicyProvider(), bundle)
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/policy/report", nil))
+	var report struct {
+		Policy  policy.Bundle  `json:"policy"`
+		Summary map[string]int `json:"summary"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&report); err != nil {
+		t.Fatalf("invalid response: %v", err)
+	}
+	// Images outside the default namespace are not checked by any policy
+	if want := map[string]int{"images": 4, "pass": 1, "fail": 1, "not_scanned": 0, "no_policy": 2}; !reflect.DeepEqual(report.Summary, want) {
+		t.Errorf("summary = %v, want %v", report.Summary, want)
+	}
+	if len(report.Policy.Policies) != 1 || report.Policy.Policies[0].Name != "team-default" {
+		t.Errorf("report policy = %+v", report.Policy)
+	}
+}
@@ -2,82 +2,113 @@ package handlers
 
 import (
 	"fmt"
+	"strconv"
 	"strings"
 )
 
-// escapeSQL escapes a string for safe SQL interpolation
-func escapeSQL(s string) string {
-	return strings.ReplaceAll(s, "'", "''")
+// queryArgs collects the bind parameters of a query built from request
+// values. Conditions reference them with numbered placeholders (?1, ?2, ...),
+// so a condition may appear several times and in any order, and a count
+// query built from the same conditions shares the arguments.
+type queryArgs []interface{}
+
+// bind adds a parameter and returns its placeholder
+func (a *queryArgs) bind(value interface{}) string {
+	*a = append(*a, value)
+	return "?" + strconv.Itoa(len(*a))
 }
 
-// buildINClause builds a SQL IN clause from a slice of strings
+// in builds a SQL IN clause binding each value
 // Returns empty string if values is empty
-// Example: buildINClause("namespace", []string{"default", "kube-system"})
-// Returns: "namespace IN ('default','kube-system')"
-func buildINClause(columnName string, values []string) string {
+// Example: args.in("namespace", []string{"default", "kube-system"})
+// Returns: "namespace IN (?1,?2)"
+func (a *queryArgs) in(columnName string, values []string) string {
 	if len(values) == 0 {
 		return ""
 	}
-	escaped := make([]string, len(values))
+	placeholders := make([]string, len(values))
 	for i, v := range values {
-		escaped[i] = "'" + escapeSQL(v) + "'"
+		placeholders[i] = a.bind(v)
 	}
-	return columnName + " IN (" + strings.Join(escaped, ",") + ")"
+	return columnName + " IN (" + strings.Join(placeholders, ",") + ")"
 }
 
-// buildLikeCondition builds a SQL LIKE condition for substring matching
+// like builds a SQL LIKE condition for substring matching
 // Returns empty string if pattern is empty
-// Example: buildLikeCondition("name", "nginx")
-// Returns: "name LIKE '%nginx%'"
-func buildLikeCondition(columnName, pattern string) string {
+// Example: args.like("name", "nginx")
+// Returns: "name LIKE ?1" binding "%nginx%"
+func (a *queryArgs) like(columnName, pattern string) string {
 	if pattern == "" {
 		return ""
 	}
-	return fmt.Sprintf("%s LIKE '%%%s%%'", columnName, escapeSQL(pattern))
+	return columnName + " LIKE " + a.bind("%"+pattern+"%")
 }
 
-// appendCondition appends a condition to the conditions slice if it's non-empty
-func appendCondition(conditions []string, condition string) []string {
-	if condition != "" {
-		return append(conditions, condition)
+// packageTypeFilter builds a WHERE clause for filtering by package type
+// Returns empty string if packageTypes is empty
+func (a *queryArgs) packageTypeFilter(packageTypes []string) string {
+	clause := a.in("type", packageTypes)
+	if clause == "" {
+		return ""
 	}
-	return conditions
+	return "WHERE " + clause
 }
 
-// buildWhereClause combines conditions with AND
-// Returns empty string if no conditions
-func buildWhereClause(conditions []string) string {
-	if len(conditions) == 0 {
+// vulnerabilityFilter builds a WHERE clause for vulnerability filtering
+// combining fix_status and package_type filters
+func (a *queryArgs) vulnerabilityFilter(vulnStatuses, packageTypes []string) string {
+	filters := appendCondition(nil, a.in("fix_status", vulnStatuses))
+	filters = appendCondition(filters, a.in("package_type", packageTypes))
+	if len(filters) == 0 {
 		return ""
 	}
-	return " AND " + strings.Join(conditions, " AND ")
+	return "WHERE " + strings.Join(filters, " AND ")
 }
 
-// buildPackageTypeFilter builds a WHERE clause for filtering by package type
-// Returns empty string if packageTypes is empty
-func buildPackageTypeFilter(packageTypes []string) string {
-	clause := buildINClause("type", packageTypes)
-	if clause == "" {
+// vulnerabilityID builds a condition matching a vulnerability identifier or
+// any of its aliases, so searching for a CVE also finds findings reported
+// under a vendor advisory (RHSA, ALAS, ...) and vice versa.
+// Returns empty string if id is empty
+func (a *queryArgs) vulnerabilityID(columnName, id string) string {
+	id = strings.TrimSpace(id)
+	if id == "" {
 		return ""
 	}
-	return "WHERE " + clause
+	p := a.bind(strings.ToUpper(id))
+	return fmt.Sprintf(`(UPPER(%[1]s) = %[2]s
+    OR %[1]s IN (SELECT vulnerability_id FROM vulnerability_aliases WHERE UPPER(alias_id) = %[2]s)
+    OR %[1]s IN (SELECT alias_id FROM vulnerability_aliases WHERE UPPER(vulnerability_id) = %[2]s))`, columnName, p)
 }
 
-// buildVulnerabilityFilter builds a WHERE clause for vulnerability filtering
-// combining fix_status and package_type filters
-func buildVulnerabilityFilter(vulnStatuses, packageTypes []string) string {
-	var filters []string
+// vulnerabilityAliasesColumn returns a SELECT expression listing the aliases of
+// the vulnerability in columnName (comma separated, NULL when there are none)
+func vulnerabilityAliasesColumn(columnName string) string {
+	return fmt.Sprintf(`(SELECT GROUP_CONCAT(alias, ', ') FROM (
+        SELECT alias_id AS alias FROM vulnerability_aliases WHERE vulnerability_id = %[1]s
+        UNION
+        SELECT vulnerability_id FROM vulnerability_aliases WHERE alias_id = %[1]s
+    )) as vulnerability_aliases`, columnName)
+}
 
-	if clause := buildINClause("fix_status", vulnStatuses); clause != "" {
-		filters = append(filters, clause)
-	}
+// cveAnnotationColumn returns a SELECT expression with the analyst note on the
+// vulnerability in columnName (NULL if it has none)
+func cveAnnotationColumn(columnName string) string {
+	return fmt.Sprintf(`(SELECT note FROM cve_annotations WHERE cve_annotations.cve_id = %s) as cve_annotation`, columnName)
+}
 
-	if clause := buildINClause("package_type", packageTypes); clause != "" {
-		filters = append(filters, clause)
+// appendCondition appends a condition to the conditions slice if it's non-empty
+func appendCondition(conditions []string, condition string) []string {
+	if condition != "" {
+		return append(conditions, condition)
 	}
+	return conditions
+}
 
-	if len(filters) == 0 {
+// buildWhereClause combines conditions with AND
+// Returns empty string if no conditions
+func buildWhereClause(conditions []string) string {
+	if len(conditions) == 0 {
 		return ""
 	}
-	return "WHERE " + strings.Join(filters, " AND ")
+	return " AND " + strings.Join(conditions, " AND ")
 }
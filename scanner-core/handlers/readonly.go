@@ -0,0 +1,79 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/bvboe/b2s-go/scanner-core/routes"
+)
+
+// ReadOnlyMiddleware rejects every request that could change state (any
+// method but GET, HEAD and OPTIONS) with 403 Forbidden, for deployments that
+// expose the UI broadly. Rescans, job triggers, imports, on-demand scans,
+// dead-letter requeues and the SQL console are all POST endpoints, so they
+// are disabled without each handler having to know about read-only mode.
+// Logging in and out of the web UI is still allowed.
+func ReadOnlyMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodGet, r.Method == http.MethodHead, r.Method == http.MethodOptions,
+			strings.HasPrefix(r.URL.Path, "/api/auth/"):
+			next.ServeHTTP(w, r)
+		default:
+			log.Debug("rejected request in read-only mode", "method", r.Method, "path", r.URL.Path)
+			http.Error(w, "Server is in read-only mode", http.StatusForbidden)
+		}
+	})
+}
+
+// UIConfig tells the web UI which controls to show. A control is hidden when
+// its endpoint is not registered or the server is read-only. Debug controls
+// additionally require debug mode, which the endpoints check themselves.
+type UIConfig struct {
+	ReadOnly bool `json:"readOnly"`
+	Scan     bool `json:"scan"`     // submit on-demand image scans (POST /api/scan)
+	Import   bool `json:"import"`   // import scan results (POST /api/import)
+	Requeue  bool `json:"requeue"`  // requeue dead-lettered scans
+	Rescan   bool `json:"rescan"`   // debug rescans and job triggers
+	SQL      bool `json:"sql"`      // debug SQL console
+	DBReinit bool `json:"dbReinit"` // re-download the vulnerability database
+}
+
+// newUIConfig derives the UI controls from the API options
+func newUIConfig(opts APIOptions) UIConfig {
+	writable := !opts.ReadOnly
+	return UIConfig{
+		ReadOnly: opts.ReadOnly,
+		Scan:     writable && opts.AdHocScan != nil,
+		Import:   writable,
+		Requeue:  writable && opts.DeadLetter != nil,
+		Rescan:   writable,
+		SQL:      writable,
+		DBReinit: writable,
+	}
+}
+
+// RegisterUIConfigHandlers registers the web UI control settings
+func RegisterUIConfigHandlers(reg *routes.Registry, config UIConfig) {
+	reg.Handle(routes.Route{Pattern: "/api/ui-config", Methods: routes.GET, Handler: UIConfigHandler(config)})
+}
+
+// UIConfigHandler creates an HTTP handler for GET /api/ui-config. Controls
+// that need the admin role are hidden from authenticated viewers.
+func UIConfigHandler(config UIConfig) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		if id := IdentityFromRequest(r); id != nil && id.Role != routes.RoleAdmin {
+			config = UIConfig{ReadOnly: config.ReadOnly}
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(config); err != nil {
+			log.Error("error encoding UI config", "error", err)
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestReadOnlyMiddleware(t *testing.T) {
+	handler := ReadOnlyMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	tests := []struct {
+		method string
+		path   string
+		want   int
+	}{
+		{http.MethodGet, "/api/images", http.StatusOK},
+		{http.MethodHead, "/api/images", http.StatusOK},
+		{http.MethodOptions, "/api/images", http.StatusOK},
+		{http.MethodPost, "/api/debug/rescan/all-images", http.StatusForbidden},
+		{http.MethodPost, "/api/import", http.StatusForbidden},
+		{http.MethodPost, "/api/debug/sql", http.StatusForbidden},
+		{http.MethodDelete, "/api/scan/sha256:abc", http.StatusForbidden},
+		{http.MethodPost, "/api/auth/login", http.StatusOK},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(tt.method, tt.path, nil))
+		if rec.Code != tt.want {
+			t.Errorf("%s %s: status = %d, want %d", tt.method, tt.path, rec.Code, tt.want)
+		}
+	}
+}
+
+func TestUIConfigHandler(t *testing.T) {
+	tests := []struct {
+		name string
+		opts APIOptions
+		want UIConfig
+	}{
+		{"writable", APIOptions{DeadLetter: &mockDeadLetterQueue{}},
+			UIConfig{Import: true, Requeue: true, Rescan: true, SQL: true, DBReinit: true}},
+		{"read-only", APIOptions{ReadOnly: true, DeadLetter: &mockDeadLetterQueue{}},
+			UIConfig{ReadOnly: true}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			UIConfigHandler(newUIConfig(tt.opts))(rec, httptest.NewRequest(http.MethodGet, "/api/ui-config", nil))
+			if rec.Code != http.StatusOK {
+				t.Fatalf("status = %d", rec.Code)
+			}
+			var got UIConfig
+			if err := json.NewDecoder(rec.Body).Decode(&got); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
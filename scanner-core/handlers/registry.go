@@ -0,0 +1,48 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/bvboe/b2s-go/scanner-core/database"
+	"github.com/bvboe/b2s-go/scanner-core/routes"
+)
+
+// RegistryImageProvider lists the images found by the registry crawl
+type RegistryImageProvider interface {
+	GetRegistryImages() ([]database.RegistryImage, error)
+}
+
+// RegisterRegistryHandlers registers the registry crawl endpoints
+func RegisterRegistryHandlers(reg *routes.Registry, provider RegistryImageProvider) {
+	reg.Handle(
+		routes.Route{Pattern: "/api/registry/images", Methods: routes.GET, Handler: RegistryImagesHandler(provider)},
+	)
+}
+
+// RegistryImagesHandler creates an HTTP handler for GET /api/registry/images.
+// Returns the images the registry crawl found, whether or not they are
+// deployed, with their scan status and vulnerability counts.
+func RegistryImagesHandler(provider RegistryImageProvider) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		images, err := provider.GetRegistryImages()
+		if err != nil {
+			log.Error("error querying registry images", "error", err)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(map[string]interface{}{
+			"images": images,
+			"count":  len(images),
+		}); err != nil {
+			log.Error("error encoding registry images", "error", err)
+		}
+	}
+}
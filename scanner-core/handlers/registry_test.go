@@ -0,0 +1,59 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/bvboe/b2s-go/scanner-core/database"
+)
+
+type mockRegistryImageProvider struct {
+	images []database.RegistryImage
+	err    error
+}
+
+func (m *mockRegistryImageProvider) GetRegistryImages() ([]database.RegistryImage, error) {
+	return m.images, m.err
+}
+
+func TestRegistryImagesHandler(t *testing.T) {
+	provider := &mockRegistryImageProvider{images: []database.RegistryImage{{
+		Reference:  "registry.example.com/golden/base:1.10",
+		Digest:     "sha256:abc",
+		TargetType: database.TargetTypeRegistry,
+		Status:     database.StatusCompleted,
+		High:       2,
+	}}}
+
+	rec := httptest.NewRecorder()
+	RegistryImagesHandler(provider)(rec, httptest.NewRequest(http.MethodGet, "/api/registry/images", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200", rec.Code)
+	}
+	var response struct {
+		Images []database.RegistryImage `json:"images"`
+		Count  int                      `json:"count"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&response); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if response.Count != 1 || response.Images[0].TargetType != "registry" || response.Images[0].High != 2 {
+		t.Errorf("unexpected response: %+v", response)
+	}
+
+	provider.err = errors.New("database locked")
+	rec = httptest.NewRecorder()
+	RegistryImagesHandler(provider)(rec, httptest.NewRequest(http.MethodGet, "/api/registry/images", nil))
+	if rec.Code != http.StatusInternalServerError {
+		t.Errorf("status = %d, want 500", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	RegistryImagesHandler(provider)(rec, httptest.NewRequest(http.MethodPost, "/api/registry/images", nil))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("status = %d, want 405", rec.Code)
+	}
+}
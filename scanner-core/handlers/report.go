@@ -0,0 +1,84 @@
+package handlers
+
+import (
+	"net/http"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/bvboe/b2s-go/scanner-core/database"
+	"github.com/bvboe/b2s-go/scanner-core/labels"
+	"github.com/bvboe/b2s-go/scanner-core/report"
+	"github.com/bvboe/b2s-go/scanner-core/routes"
+)
+
+// maxReportSizeMB caps the report size a caller can request
+const maxReportSizeMB = 500
+
+var unsafeFilenameChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// ReportConfig configures the offline report endpoint
+type ReportConfig struct {
+	// Source identifies this server in the report (e.g. cluster name)
+	Source string
+	// DeploymentUUID and Labels (static labels) identify this server next to Source
+	DeploymentUUID string
+	Labels         labels.Set
+}
+
+// ReportHandler creates an HTTP handler for /api/report endpoint
+// Returns the current scan state (images, containers and CVE details) as a
+// single self-contained HTML file for sharing with people who cannot reach the
+// cluster. ?maxSizeMB= limits the report size (default 50, max 500); CVE
+// details are dropped for the least vulnerable images once the limit is reached.
+// ?tz= shows timestamps in an IANA time zone instead of UTC.
+func ReportHandler(db *database.DB, cfg ReportConfig) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		opts := report.Options{Source: cfg.Source, DeploymentUUID: cfg.DeploymentUUID, Labels: cfg.Labels}
+		if v := r.URL.Query().Get("maxSizeMB"); v != "" {
+			sizeMB, err := strconv.Atoi(v)
+			if err != nil || sizeMB < 1 || sizeMB > maxReportSizeMB {
+				http.Error(w, "maxSizeMB must be between 1 and 500", http.StatusBadRequest)
+				return
+			}
+			opts.MaxBytes = sizeMB << 20
+		}
+		loc, err := parseTimeZone(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		opts.Location = loc
+
+		start := time.Now()
+		data, err := report.Generate(db, opts)
+		if err != nil {
+			log.Error("error generating report", "error", err)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		log.Info("generated offline report", "size_kb", len(data)/1024, "duration_ms", time.Since(start).Milliseconds())
+
+		filename := "bjorn2scan-report"
+		if cfg.Source != "" {
+			filename += "-" + unsafeFilenameChars.ReplaceAllString(cfg.Source, "_")
+		}
+		filename += "-" + time.Now().UTC().Format("20060102-150405") + ".html"
+
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Header().Set("Content-Disposition", "attachment; filename=\""+filename+"\"")
+		if _, err := w.Write(data); err != nil {
+			log.Error("error writing report response", "error", err)
+		}
+	}
+}
+
+// RegisterReportHandlers registers the offline report endpoint
+func RegisterReportHandlers(reg *routes.Registry, db *database.DB, cfg ReportConfig) {
+	reg.Handle(routes.Route{Pattern: "/api/report", Methods: routes.GET, Handler: ReportHandler(db, cfg)})
+}
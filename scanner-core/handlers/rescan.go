@@ -0,0 +1,171 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/bvboe/b2s-go/scanner-core/routes"
+	"github.com/bvboe/b2s-go/scanner-core/scanning"
+)
+
+// RescanQueue rescans images on request (implemented by scanning.JobQueue)
+type RescanQueue interface {
+	Rescan(digest string, fullRescan bool) bool
+	RescanAll(fullRescan bool) (queued, notRunning int, err error)
+	EstimateRescanAll(fullRescan bool) (scanning.RescanEstimate, error)
+}
+
+// RegisterRescanHandlers registers the endpoints that trigger image rescans
+// without waiting for the scheduled rescan job. Rescans of all images covering
+// more than confirmThreshold images must be confirmed (0 = never).
+func RegisterRescanHandlers(reg *routes.Registry, queue RescanQueue, confirmThreshold int) {
+	reg.Handle(
+		routes.Route{Pattern: "/api/images/{digest}/rescan", Methods: routes.POST, Handler: RescanImageHandler(queue), Role: routes.RoleAdmin},
+		routes.Route{Pattern: "/api/rescan-all", Methods: routes.POST, Handler: RescanAllHandler(queue, confirmThreshold), Role: routes.RoleAdmin},
+		routes.Route{Pattern: "/api/rescan-all/estimate", Methods: routes.GET, Handler: RescanAllEstimateHandler(queue), CacheControl: routes.NoStore},
+	)
+}
+
+// forceParam parses the force query parameter: a full rescan that retrieves
+// a fresh SBOM instead of rescanning the stored one
+func forceParam(r *http.Request) (bool, bool) {
+	value := r.URL.Query().Get("force")
+	if value == "" {
+		return false, true
+	}
+	force, err := strconv.ParseBool(value)
+	return force, err == nil
+}
+
+// RescanImageHandler creates an HTTP handler for POST /api/images/{digest}/rescan.
+// Rescans a running image ahead of the rest of the queue. With ?force=true
+// the SBOM is regenerated too. Images not running anywhere return 404.
+//
+// Response: {"status": "queued", "digest": "sha256:...", "force": false}
+func RescanImageHandler(queue RescanQueue) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		digest := r.PathValue("digest")
+		if digest == "" {
+			http.Error(w, "Image digest required", http.StatusBadRequest)
+			return
+		}
+		force, ok := forceParam(r)
+		if !ok {
+			http.Error(w, "Invalid force parameter", http.StatusBadRequest)
+			return
+		}
+
+		if !queue.Rescan(digest, force) {
+			http.Error(w, "Image is not running on any node", http.StatusNotFound)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(map[string]interface{}{
+			"status": "queued",
+			"digest": digest,
+			"force":  force,
+		}); err != nil {
+			log.Error("error encoding rescan response", "error", err)
+		}
+	}
+}
+
+// RescanAllHandler creates an HTTP handler for POST /api/rescan-all.
+// Rescans every running image at rescan priority. With ?force=true the SBOMs
+// are regenerated too. Rescans of more than confirmThreshold images are only
+// queued with ?confirm=true; without it the estimate is returned with 409.
+//
+// Response: {"status": "queued", "queued": 50, "not_running": 3, "force": false,
+// "estimate": {...}}
+func RescanAllHandler(queue RescanQueue, confirmThreshold int) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		force, ok := forceParam(r)
+		if !ok {
+			http.Error(w, "Invalid force parameter", http.StatusBadRequest)
+			return
+		}
+		confirm, err := strconv.ParseBool(r.URL.Query().Get("confirm"))
+		if err != nil && r.URL.Query().Get("confirm") != "" {
+			http.Error(w, "Invalid confirm parameter", http.StatusBadRequest)
+			return
+		}
+
+		estimate, err := queue.EstimateRescanAll(force)
+		if err != nil {
+			log.Error("error estimating rescan", "error", err)
+			http.Error(w, "Failed to rescan images", http.StatusInternalServerError)
+			return
+		}
+		if confirmThreshold > 0 && estimate.Images > confirmThreshold && !confirm {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusConflict)
+			if err := json.NewEncoder(w).Encode(map[string]interface{}{
+				"status": "confirmation_required",
+				"message": fmt.Sprintf("Rescanning %d images takes about %s; repeat the request with confirm=true to start it",
+					estimate.Images, time.Duration(estimate.DurationSeconds*float64(time.Second)).Round(time.Minute)),
+				"estimate": estimate,
+			}); err != nil {
+				log.Error("error encoding rescan response", "error", err)
+			}
+			return
+		}
+
+		queued, notRunning, err := queue.RescanAll(force)
+		if err != nil {
+			log.Error("error rescanning images", "error", err)
+			http.Error(w, "Failed to rescan images", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(map[string]interface{}{
+			"status":      "queued",
+			"queued":      queued,
+			"not_running": notRunning,
+			"force":       force,
+			"estimate":    estimate,
+		}); err != nil {
+			log.Error("error encoding rescan response", "error", err)
+		}
+	}
+}
+
+// RescanAllEstimateHandler creates an HTTP handler for GET
+// /api/rescan-all/estimate: the images a rescan-all would queue, the expected
+// duration from the scan times observed so far and the projected completion,
+// without queueing anything. Takes the same force parameter.
+func RescanAllEstimateHandler(queue RescanQueue) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		force, ok := forceParam(r)
+		if !ok {
+			http.Error(w, "Invalid force parameter", http.StatusBadRequest)
+			return
+		}
+
+		estimate, err := queue.EstimateRescanAll(force)
+		if err != nil {
+			log.Error("error estimating rescan", "error", err)
+			http.Error(w, "Failed to estimate rescan", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(estimate); err != nil {
+			log.Error("error encoding rescan estimate", "error", err)
+		}
+	}
+}
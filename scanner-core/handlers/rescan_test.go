@@ -0,0 +1,168 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/bvboe/b2s-go/scanner-core/routes"
+	"github.com/bvboe/b2s-go/scanner-core/scanning"
+)
+
+type mockRescanQueue struct {
+	digest  string
+	force   bool
+	err     error
+	images  int
+	rescans int
+}
+
+func (m *mockRescanQueue) Rescan(digest string, fullRescan bool) bool {
+	m.digest, m.force = digest, fullRescan
+	return digest != "sha256:gone"
+}
+
+func (m *mockRescanQueue) RescanAll(fullRescan bool) (queued, notRunning int, err error) {
+	m.force = fullRescan
+	m.rescans++
+	return 2, 1, m.err
+}
+
+func (m *mockRescanQueue) EstimateRescanAll(fullRescan bool) (scanning.RescanEstimate, error) {
+	images := m.images
+	if images == 0 {
+		images = 2
+	}
+	return scanning.RescanEstimate{Images: images, NotRunning: 1, FullRescan: fullRescan, PerImageSeconds: 10, DurationSeconds: float64(images) * 10}, nil
+}
+
+func TestRescanImageHandler(t *testing.T) {
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		wantStatus int
+		wantForce  bool
+	}{
+		{"rescan", http.MethodPost, "/api/images/sha256:abc/rescan", http.StatusOK, false},
+		{"force", http.MethodPost, "/api/images/sha256:abc/rescan?force=true", http.StatusOK, true},
+		{"invalid force", http.MethodPost, "/api/images/sha256:abc/rescan?force=maybe", http.StatusBadRequest, false},
+		{"not running", http.MethodPost, "/api/images/sha256:gone/rescan", http.StatusNotFound, false},
+		{"wrong method", http.MethodGet, "/api/images/sha256:abc/rescan", http.StatusMethodNotAllowed, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			queue := &mockRescanQueue{}
+			reg := routes.NewRegistry()
+			RegisterRescanHandlers(reg, queue, 0)
+			rec := httptest.NewRecorder()
+			reg.ServeHTTP(rec, httptest.NewRequest(tt.method, tt.path, nil))
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+			if queue.digest != "sha256:abc" || queue.force != tt.wantForce {
+				t.Errorf("Rescan(%q, %v), want Rescan(sha256:abc, %v)", queue.digest, queue.force, tt.wantForce)
+			}
+		})
+	}
+}
+
+func TestRescanAllHandler(t *testing.T) {
+	queue := &mockRescanQueue{}
+	rec := httptest.NewRecorder()
+	RescanAllHandler(queue, 0)(rec, httptest.NewRequest(http.MethodPost, "/api/rescan-all?force=1", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusOK)
+	}
+	var response struct {
+		Queued     int                     `json:"queued"`
+		NotRunning int                     `json:"not_running"`
+		Force      bool                    `json:"force"`
+		Estimate   scanning.RescanEstimate `json:"estimate"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&response); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if response.Queued != 2 || response.NotRunning != 1 || !response.Force || !queue.force || response.Estimate.Images != 2 {
+		t.Errorf("unexpected response: %+v", response)
+	}
+
+	queue.err = errors.New("database locked")
+	rec = httptest.NewRecorder()
+	RescanAllHandler(queue, 0)(rec, httptest.NewRequest(http.MethodPost, "/api/rescan-all", nil))
+	if rec.Code != http.StatusInternalServerError {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusInternalServerError)
+	}
+}
+
+func TestRescanAllConfirmation(t *testing.T) {
+	queue := &mockRescanQueue{images: 600}
+	reg := routes.NewRegistry()
+	RegisterRescanHandlers(reg, queue, 500)
+
+	rec := httptest.NewRecorder()
+	reg.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/rescan-all", nil))
+	if rec.Code != http.StatusConflict {
+		t.Fatalf("status without confirm = %d, want %d", rec.Code, http.StatusConflict)
+	}
+	var response struct {
+		Status   string                  `json:"status"`
+		Estimate scanning.RescanEstimate `json:"estimate"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&response); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if response.Status != "confirmation_required" || response.Estimate.Images != 600 || queue.rescans != 0 {
+		t.Errorf("unconfirmed rescan: response %+v, %d rescans queued", response, queue.rescans)
+	}
+
+	rec = httptest.NewRecorder()
+	reg.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/rescan-all?confirm=maybe", nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("status with an invalid confirm = %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+
+	rec = httptest.NewRecorder()
+	reg.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/rescan-all?confirm=true", nil))
+	if rec.Code != http.StatusOK || queue.rescans != 1 {
+		t.Errorf("confirmed rescan: status %d, %d rescans queued", rec.Code, queue.rescans)
+	}
+
+	// Below the threshold no confirmation is needed
+	queue.images = 500
+	rec = httptest.NewRecorder()
+	reg.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/rescan-all", nil))
+	if rec.Code != http.StatusOK || queue.rescans != 2 {
+		t.Errorf("rescan at the threshold: status %d, %d rescans queued", rec.Code, queue.rescans)
+	}
+}
+
+func TestRescanAllEstimateHandler(t *testing.T) {
+	queue := &mockRescanQueue{}
+	reg := routes.NewRegistry()
+	RegisterRescanHandlers(reg, queue, 0)
+
+	rec := httptest.NewRecorder()
+	reg.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/rescan-all/estimate?force=true", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusOK)
+	}
+	var estimate scanning.RescanEstimate
+	if err := json.NewDecoder(rec.Body).Decode(&estimate); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if estimate.Images != 2 || !estimate.FullRescan || estimate.DurationSeconds != 20 || queue.rescans != 0 {
+		t.Errorf("estimate = %+v with %d rescans queued", estimate, queue.rescans)
+	}
+
+	rec = httptest.NewRecorder()
+	reg.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/rescan-all/estimate?force=maybe", nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("status with an invalid force = %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+}
@@ -0,0 +1,62 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/bvboe/b2s-go/scanner-core/alerts"
+	"github.com/bvboe/b2s-go/scanner-core/database"
+	"github.com/bvboe/b2s-go/scanner-core/routes"
+)
+
+// ScanFailureProvider lists images whose latest scan failed
+type ScanFailureProvider interface {
+	GetFailingScans() ([]database.FailingScan, error)
+}
+
+// RegisterScanFailureHandlers registers the grouped scan failure endpoint.
+// Groups of at least threshold images are reported as firing alerts
+// (0 disables alerting).
+func RegisterScanFailureHandlers(reg *routes.Registry, provider ScanFailureProvider, threshold int) {
+	reg.Handle(routes.Route{Pattern: "/api/scan-queue/failures", Methods: routes.GET, Handler: ScanFailuresHandler(provider, threshold)})
+}
+
+// ScanFailuresHandler creates an HTTP handler for GET /api/scan-queue/failures.
+// Returns failing images grouped by node and failure reason code, largest
+// groups first, so one root cause (e.g. a broken containerd socket on a node)
+// shows up as one group instead of a failure per image.
+//
+// Response: {"threshold": 10, "failing_images": 120, "alerts": 1, "groups": [...]}
+func ScanFailuresHandler(provider ScanFailureProvider, threshold int) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		failures, err := provider.GetFailingScans()
+		if err != nil {
+			log.Error("error querying failing scans", "error", err)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+
+		groups := alerts.GroupFailures(failures, threshold)
+		firing := 0
+		for _, g := range groups {
+			if g.Firing {
+				firing++
+			}
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(map[string]interface{}{
+			"threshold":      threshold,
+			"failing_images": len(failures),
+			"alerts":         firing,
+			"groups":         groups,
+		}); err != nil {
+			log.Error("error encoding scan failures", "error", err)
+		}
+	}
+}
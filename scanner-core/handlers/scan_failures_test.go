@@ -0,0 +1,79 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/bvboe/b2s-go/scanner-core/database"
+)
+
+type mockScanFailureProvider struct {
+	scans []database.FailingScan
+	err   error
+}
+
+func (m *mockScanFailureProvider) GetFailingScans() ([]database.FailingScan, error) {
+	return m.scans, m.err
+}
+
+func TestScanFailuresHandler(t *testing.T) {
+	provider := &mockScanFailureProvider{}
+	for i := range 3 {
+		provider.scans = append(provider.scans, database.FailingScan{
+			Digest: fmt.Sprintf("sha256:%d", i),
+			Latest: database.ScanFailure{
+				Status: database.StatusSBOMFailed,
+				Error:  "dial unix /run/containerd/containerd.sock: connect: no such file or directory",
+				Node:   "node-1",
+			},
+		})
+	}
+	provider.scans = append(provider.scans, database.FailingScan{
+		Digest: "sha256:x",
+		Latest: database.ScanFailure{Status: database.StatusVulnScanFailed, Error: "grype failed", Node: "node-2"},
+	})
+
+	rec := httptest.NewRecorder()
+	ScanFailuresHandler(provider, 3)(rec, httptest.NewRequest(http.MethodGet, "/api/scan-queue/failures", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", rec.Code)
+	}
+
+	var resp struct {
+		FailingImages int `json:"failing_images"`
+		Alerts        int `json:"alerts"`
+		Groups        []struct {
+			Node   string `json:"node"`
+			Reason string `json:"reason"`
+			Images int    `json:"images"`
+			Firing bool   `json:"firing"`
+		} `json:"groups"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if resp.FailingImages != 4 || resp.Alerts != 1 || len(resp.Groups) != 2 {
+		t.Fatalf("unexpected response: %+v", resp)
+	}
+	if g := resp.Groups[0]; g.Node != "node-1" || g.Reason != "runtime_unavailable" || g.Images != 3 || !g.Firing {
+		t.Errorf("unexpected first group: %+v", g)
+	}
+}
+
+func TestScanFailuresHandlerErrors(t *testing.T) {
+	rec := httptest.NewRecorder()
+	ScanFailuresHandler(&mockScanFailureProvider{}, 10)(rec, httptest.NewRequest(http.MethodPost, "/api/scan-queue/failures", nil))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("POST: expected status 405, got %d", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	ScanFailuresHandler(&mockScanFailureProvider{err: errors.New("db down")}, 10)(rec, httptest.NewRequest(http.MethodGet, "/api/scan-queue/failures", nil))
+	if rec.Code != http.StatusInternalServerError {
+		t.Errorf("provider error: expected status 500, got %d", rec.Code)
+	}
+}
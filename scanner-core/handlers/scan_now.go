@@ -0,0 +1,62 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/bvboe/b2s-go/scanner-core/routes"
+)
+
+// ScanNowQueue moves images to the front of the scan queue (implemented by scanning.JobQueue)
+type ScanNowQueue interface {
+	ScanNow(digests []string) (queued, notRunning []string)
+}
+
+// ScanNowRequest is the body of POST /api/scan-queue/scan-now
+type ScanNowRequest struct {
+	Digests []string `json:"digests"`
+}
+
+// maxScanNowRequestSize bounds the scan-now request body
+const maxScanNowRequestSize = 1 << 20
+
+// RegisterScanNowHandlers registers the endpoint that lets images jump the scan queue
+func RegisterScanNowHandlers(reg *routes.Registry, queue ScanNowQueue) {
+	reg.Handle(routes.Route{Pattern: "/api/scan-queue/scan-now", Methods: routes.POST, Handler: ScanNowHandler(queue), Role: routes.RoleAdmin})
+}
+
+// ScanNowHandler creates an HTTP handler for POST /api/scan-queue/scan-now.
+// Scans running images ahead of everything else in the queue, e.g. to check a
+// fix right after deploying it. Images not running anywhere are returned in
+// not_running; scan those through POST /api/scan instead.
+//
+// Request: {"digests": ["sha256:..."]}
+// Response: {"queued": ["sha256:..."], "not_running": []}
+func ScanNowHandler(queue ScanNowQueue) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		var req ScanNowRequest
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxScanNowRequestSize)).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		if len(req.Digests) == 0 {
+			http.Error(w, "At least one digest is required", http.StatusBadRequest)
+			return
+		}
+
+		queued, notRunning := queue.ScanNow(req.Digests)
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(map[string]interface{}{
+			"queued":      queued,
+			"not_running": notRunning,
+		}); err != nil {
+			log.Error("error encoding scan now response", "error", err)
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+)
+
+type mockScanNowQueue struct {
+	digests []string
+}
+
+func (m *mockScanNowQueue) ScanNow(digests []string) (queued, notRunning []string) {
+	m.digests = digests
+	return digests[:1], digests[1:]
+}
+
+func TestScanNowHandler(t *testing.T) {
+	tests := []struct {
+		name       string
+		method     string
+		body       string
+		wantStatus int
+	}{
+		{"selected", http.MethodPost, `{"digests": ["sha256:abc", "sha256:def"]}`, http.StatusOK},
+		{"no digests", http.MethodPost, `{"digests": []}`, http.StatusBadRequest},
+		{"empty body", http.MethodPost, "", http.StatusBadRequest},
+		{"wrong method", http.MethodGet, "", http.StatusMethodNotAllowed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			queue := &mockScanNowQueue{}
+			rec := httptest.NewRecorder()
+			ScanNowHandler(queue)(rec, httptest.NewRequest(tt.method, "/api/scan-queue/scan-now", strings.NewReader(tt.body)))
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+
+			var response struct {
+				Queued     []string `json:"queued"`
+				NotRunning []string `json:"not_running"`
+			}
+			if err := json.NewDecoder(rec.Body).Decode(&response); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			if !slices.Equal(response.Queued, []string{"sha256:abc"}) || !slices.Equal(response.NotRunning, []string{"sha256:def"}) {
+				t.Errorf("unexpected response: %+v", response)
+			}
+		})
+	}
+}
@@ -0,0 +1,38 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/bvboe/b2s-go/scanner-core/routes"
+	"github.com/bvboe/b2s-go/scanner-core/scanning"
+)
+
+// ScanQueueStatusProvider reports the progress of the scan queue (implemented
+// by scanning.JobQueue)
+type ScanQueueStatusProvider interface {
+	Status() scanning.QueueStatus
+}
+
+// RegisterScanQueueHandlers registers the scan queue status endpoint
+func RegisterScanQueueHandlers(reg *routes.Registry, provider ScanQueueStatusProvider) {
+	reg.Handle(routes.Route{Pattern: "/api/scan-queue", Methods: routes.GET, Handler: ScanQueueStatusHandler(provider), CacheControl: routes.NoStore})
+}
+
+// ScanQueueStatusHandler creates an HTTP handler for GET /api/scan-queue.
+// Returns the queue depth, the job being scanned and its phase (sbom or
+// vuln), the average scan duration and the estimated time until the queue
+// is drained, e.g. to tell whether the initial scan is still running.
+func ScanQueueStatusHandler(provider ScanQueueStatusProvider) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(provider.Status()); err != nil {
+			log.Error("error encoding scan queue status", "error", err)
+		}
+	}
+}
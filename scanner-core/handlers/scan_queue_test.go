@@ -0,0 +1,49 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/bvboe/b2s-go/scanner-core/scanning"
+)
+
+type mockScanQueueStatus struct{ status scanning.QueueStatus }
+
+func (m *mockScanQueueStatus) Status() scanning.QueueStatus { return m.status }
+
+func TestScanQueueStatusHandler(t *testing.T) {
+	provider := &mockScanQueueStatus{status: scanning.QueueStatus{
+		Depth:                 12,
+		InFlight:              []scanning.InFlightScan{{Type: "image", Digest: "sha256:abc", Phase: scanning.PhaseSBOM}},
+		AverageScanSeconds:    8,
+		EstimatedDrainSeconds: 100,
+	}}
+
+	rec := httptest.NewRecorder()
+	ScanQueueStatusHandler(provider)(rec, httptest.NewRequest(http.MethodGet, "/api/scan-queue", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusOK)
+	}
+	var response struct {
+		Depth    int `json:"depth"`
+		InFlight []struct {
+			Digest string `json:"digest"`
+			Phase  string `json:"phase"`
+		} `json:"in_flight"`
+		EstimatedDrainSeconds float64 `json:"estimated_drain_seconds"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&response); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if response.Depth != 12 || len(response.InFlight) != 1 || response.InFlight[0].Phase != "sbom" || response.EstimatedDrainSeconds != 100 {
+		t.Errorf("unexpected response: %+v", response)
+	}
+
+	rec = httptest.NewRecorder()
+	ScanQueueStatusHandler(provider)(rec, httptest.NewRequest(http.MethodPost, "/api/scan-queue", nil))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("POST: status = %d, want %d", rec.Code, http.StatusMethodNotAllowed)
+	}
+}
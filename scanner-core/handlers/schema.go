@@ -0,0 +1,102 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/bvboe/b2s-go/scanner-core/database"
+	"github.com/bvboe/b2s-go/scanner-core/routes"
+)
+
+// SchemaProvider returns the database schema
+type SchemaProvider interface {
+	GetSchema() (*database.Schema, error)
+}
+
+// RegisterSchemaHandlers registers the schema export endpoint
+func RegisterSchemaHandlers(reg *routes.Registry, provider SchemaProvider) {
+	reg.Handle(routes.Route{Pattern: "/api/admin/schema", Methods: routes.GET, Handler: SchemaHandler(provider), Role: routes.RoleAdmin})
+}
+
+// SchemaHandler creates an HTTP handler for GET /api/admin/schema.
+// Returns the tables, columns, indexes, foreign keys and migration history of
+// the database, so reporting tools and users of the SQL endpoint can discover
+// the data model. ?format=mermaid returns an entity relationship diagram in
+// Mermaid syntax instead.
+func SchemaHandler(provider SchemaProvider) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		format := r.URL.Query().Get("format")
+		if format != "" && format != "json" && format != "mermaid" {
+			http.Error(w, "Invalid format: must be json or mermaid", http.StatusBadRequest)
+			return
+		}
+
+		schema, err := provider.GetSchema()
+		if err != nil {
+			log.Error("error reading database schema", "error", err)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+
+		if format == "mermaid" {
+			w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+			writeMermaidERD(w, schema)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(schema); err != nil {
+			log.Error("error encoding database schema", "error", err)
+		}
+	}
+}
+
+// mermaidInvalid matches the characters Mermaid does not accept in attribute types
+var mermaidInvalid = regexp.MustCompile(`[^A-Za-z0-9_]+`)
+
+// writeMermaidERD writes the tables and their foreign keys as a Mermaid
+// erDiagram. Views are left out.
+func writeMermaidERD(w io.Writer, schema *database.Schema) {
+	_, _ = fmt.Fprintln(w, "erDiagram")
+	for _, table := range schema.Tables {
+		if table.Type != "table" {
+			continue
+		}
+		_, _ = fmt.Fprintf(w, "    %s {\n", table.Name)
+		for _, col := range table.Columns {
+			colType := strings.Trim(mermaidInvalid.ReplaceAllString(col.Type, "_"), "_")
+			if colType == "" {
+				colType = "ANY"
+			}
+			key := ""
+			if col.PrimaryKey > 0 {
+				key = " PK"
+			}
+			for _, fk := range table.ForeignKeys {
+				if fk.Column == col.Name {
+					key = " FK"
+					if col.PrimaryKey > 0 {
+						key = " PK, FK"
+					}
+					break
+				}
+			}
+			_, _ = fmt.Fprintf(w, "        %s %s%s\n", colType, col.Name, key)
+		}
+		_, _ = fmt.Fprintln(w, "    }")
+	}
+	for _, table := range schema.Tables {
+		for _, fk := range table.ForeignKeys {
+			_, _ = fmt.Fprintf(w, "    %s }o--|| %s : %q\n", table.Name, fk.Table, fk.Column)
+		}
+	}
+}
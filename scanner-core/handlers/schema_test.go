@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/bvboe/b2s-go/scanner-core/database"
+)
+
+type mockSchemaProvider struct {
+	schema *database.Schema
+}
+
+func (m *mockSchemaProvider) GetSchema() (*database.Schema, error) {
+	return m.schema, nil
+}
+
+func TestSchemaHandler(t *testing.T) {
+	provider := &mockSchemaProvider{schema: &database.Schema{
+		SchemaVersion: 58,
+		TargetVersion: 58,
+		Tables: []database.SchemaTable{
+			{Name: "containers", Type: "table", Columns: []database.SchemaColumn{
+				{Name: "id", Type: "INTEGER", PrimaryKey: 1},
+				{Name: "image_id", Type: "INTEGER", NotNull: true},
+			}, ForeignKeys: []database.SchemaForeignKey{{Column: "image_id", Table: "images", To: "id"}}},
+			{Name: "images", Type: "table", Columns: []database.SchemaColumn{
+				{Name: "id", Type: "INTEGER", PrimaryKey: 1},
+				{Name: "digest", Type: "VARCHAR(255)"},
+			}},
+			{Name: "image_summary", Type: "view"},
+		},
+		Migrations: []database.SchemaMigration{{Version: 58, Name: "add_image_soft_delete"}},
+	}}
+	handler := SchemaHandler(provider)
+
+	w := httptest.NewRecorder()
+	handler(w, httptest.NewRequest(http.MethodGet, "/api/admin/schema", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200", w.Code)
+	}
+	var got database.Schema
+	if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	if len(got.Tables) != 3 || got.Tables[0].ForeignKeys[0].Table != "images" || len(got.Migrations) != 1 {
+		t.Errorf("unexpected schema: %+v", got)
+	}
+
+	w = httptest.NewRecorder()
+	handler(w, httptest.NewRequest(http.MethodGet, "/api/admin/schema?format=mermaid", nil))
+	erd := w.Body.String()
+	for _, want := range []string{
+		"erDiagram\n",
+		"    images {\n        INTEGER id PK\n        VARCHAR_255 digest\n    }\n",
+		"        INTEGER image_id FK\n",
+		`    containers }o--|| images : "image_id"`,
+	} {
+		if !strings.Contains(erd, want) {
+			t.Errorf("ERD missing %q:\n%s", want, erd)
+		}
+	}
+	if strings.Contains(erd, "image_summary") {
+		t.Errorf("views should be left out of the ERD:\n%s", erd)
+	}
+
+	w = httptest.NewRecorder()
+	handler(w, httptest.NewRequest(http.MethodGet, "/api/admin/schema?format=xml", nil))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("status for invalid format = %d, want 400", w.Code)
+	}
+}
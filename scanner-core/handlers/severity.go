@@ -0,0 +1,47 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/bvboe/b2s-go/scanner-core/routes"
+	"github.com/bvboe/b2s-go/scanner-core/severity"
+)
+
+// SeverityMappingProvider returns the severity mapping applied to stored vulnerabilities
+type SeverityMappingProvider interface {
+	SeverityMapping() severity.Mapping
+}
+
+// SeverityScaleResponse is the severity scale returned by /api/severities
+type SeverityScaleResponse struct {
+	Levels  []string          `json:"levels"`  // severities in use, most severe first
+	Mapping map[string]string `json:"mapping"` // merged severity → severity it is reported as
+}
+
+// RegisterSeverityHandlers registers the severity scale endpoint
+func RegisterSeverityHandlers(reg *routes.Registry, provider SeverityMappingProvider) {
+	reg.Handle(routes.Route{Pattern: "/api/severities", Methods: routes.GET, Handler: SeverityScaleHandler(provider)})
+}
+
+// SeverityScaleHandler creates an HTTP handler for /api/severities.
+// Returns the configured severity scale so clients can hide merged severities.
+func SeverityScaleHandler(provider SeverityMappingProvider) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		mapping := provider.SeverityMapping()
+		response := SeverityScaleResponse{Levels: mapping.Levels(), Mapping: map[string]string{}}
+		for from, to := range mapping {
+			response.Mapping[from] = to
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(response); err != nil {
+			log.Error("error encoding severity scale", "error", err)
+		}
+	}
+}
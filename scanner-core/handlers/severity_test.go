@@ -0,0 +1,39 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/bvboe/b2s-go/scanner-core/severity"
+)
+
+type mockSeverityMappingProvider struct {
+	mapping severity.Mapping
+}
+
+func (m *mockSeverityMappingProvider) SeverityMapping() severity.Mapping {
+	return m.mapping
+}
+
+func TestSeverityScaleHandler(t *testing.T) {
+	provider := &mockSeverityMappingProvider{mapping: severity.Mapping{severity.Negligible: severity.Low}}
+	rec := httptest.NewRecorder()
+	SeverityScaleHandler(provider)(rec, httptest.NewRequest(http.MethodGet, "/api/severities", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200", rec.Code)
+	}
+
+	var response SeverityScaleResponse
+	if err := json.NewDecoder(rec.Body).Decode(&response); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if want := []string{"Critical", "High", "Medium", "Low", "Unknown"}; !reflect.DeepEqual(response.Levels, want) {
+		t.Errorf("levels = %v, want %v", response.Levels, want)
+	}
+	if response.Mapping["Negligible"] != "Low" {
+		t.Errorf("mapping = %v, want Negligible → Low", response.Mapping)
+	}
+}
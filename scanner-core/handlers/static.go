@@ -1,15 +1,211 @@
 package handlers
 
 import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
 	"io/fs"
+	"mime"
 	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/andybalholm/brotli"
 
 	scanner_core "github.com/bvboe/b2s-go/scanner-core"
+	"github.com/bvboe/b2s-go/scanner-core/routes"
+)
+
+// Cache-Control values for the web UI. Fingerprinted assets never change under
+// their name; everything else (HTML pages, unversioned asset URLs) is
+// revalidated against its ETag on every use.
+const (
+	cacheImmutable   = "public, max-age=31536000, immutable"
+	cacheRevalidate  = "no-cache"
+	fingerprintChars = 12
+	// minCompressSize is the size below which pre-compression isn't worth the extra header
+	minCompressSize = 512
 )
 
+// compressibleTypes are the file extensions pre-compressed at startup
+var compressibleTypes = map[string]bool{
+	".html": true, ".js": true, ".css": true, ".svg": true, ".json": true, ".txt": true,
+}
+
+// staticAsset is an embedded UI file prepared for serving: HTML pages have
+// their asset references rewritten to fingerprinted names, and compressible
+// files carry gzip and brotli encodings computed once at startup.
+type staticAsset struct {
+	name    string // original file name, used for the Content-Type
+	content []byte
+	gzip    []byte // nil when compression doesn't pay off
+	brotli  []byte
+	etag    string // quoted fingerprint of content
+}
+
+// staticRoute is an asset as served under one URL path
+type staticRoute struct {
+	asset     *staticAsset
+	immutable bool
+}
+
+// staticAssets serves the embedded web UI from memory
+type staticAssets struct {
+	routes map[string]staticRoute
+}
+
+// fingerprint returns a short hash of content tied to the build version, so
+// every release busts browser caches even for files whose content is unchanged
+func fingerprint(version string, content []byte) string {
+	h := sha256.New()
+	h.Write([]byte(version))
+	h.Write([]byte{0})
+	h.Write(content)
+	return hex.EncodeToString(h.Sum(nil))[:fingerprintChars]
+}
+
+// fingerprintedName inserts fp before the extension: shared.js -> shared.<fp>.js
+func fingerprintedName(name, fp string) string {
+	ext := path.Ext(name)
+	return strings.TrimSuffix(name, ext) + "." + fp + ext
+}
+
+// newStaticAssets reads every file of fsys. Non-HTML files are additionally
+// served under a fingerprinted name with a long-lived Cache-Control, and HTML
+// pages are rewritten to reference those names.
+func newStaticAssets(fsys fs.FS, version string) (*staticAssets, error) {
+	files := make(map[string][]byte)
+	err := fs.WalkDir(fsys, ".", func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		content, err := fs.ReadFile(fsys, p)
+		if err != nil {
+			return err
+		}
+		files[p] = content
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s := &staticAssets{routes: make(map[string]staticRoute)}
+
+	// Fingerprint the assets first so pages can reference them
+	var replacements []string
+	for name, content := range files {
+		if path.Ext(name) == ".html" {
+			continue
+		}
+		fp := fingerprint(version, content)
+		hashed := fingerprintedName(name, fp)
+		asset := newStaticAsset(name, content, fp)
+		s.routes["/"+name] = staticRoute{asset: asset}
+		s.routes["/"+hashed] = staticRoute{asset: asset, immutable: true}
+		for _, attr := range []string{`href="`, `src="`} {
+			replacements = append(replacements,
+				attr+name+`"`, attr+hashed+`"`,
+				attr+"/"+name+`"`, attr+"/"+hashed+`"`)
+		}
+	}
+
+	rewrite := strings.NewReplacer(replacements...)
+	for name, content := range files {
+		if path.Ext(name) != ".html" {
+			continue
+		}
+		page := []byte(rewrite.Replace(string(content)))
+		s.routes["/"+name] = staticRoute{asset: newStaticAsset(name, page, fingerprint(version, page))}
+	}
+	if index, ok := s.routes["/index.html"]; ok {
+		s.routes["/"] = index
+	}
+	return s, nil
+}
 
-// RegisterStaticHandlers registers handlers for serving the embedded web UI
-func RegisterStaticHandlers(mux *http.ServeMux) {
+// newStaticAsset prepares content for serving, pre-compressing it when worthwhile
+func newStaticAsset(name string, content []byte, fp string) *staticAsset {
+	asset := &staticAsset{name: name, content: content, etag: `"` + fp + `"`}
+	if !compressibleTypes[path.Ext(name)] || len(content) < minCompressSize {
+		return asset
+	}
+
+	var gz bytes.Buffer
+	if zw, err := gzip.NewWriterLevel(&gz, gzip.BestCompression); err == nil {
+		if _, err := zw.Write(content); err == nil && zw.Close() == nil && gz.Len() < len(content) {
+			asset.gzip = gz.Bytes()
+		}
+	}
+	var br bytes.Buffer
+	bw := brotli.NewWriterLevel(&br, brotli.BestCompression)
+	if _, err := bw.Write(content); err == nil && bw.Close() == nil && br.Len() < len(content) {
+		asset.brotli = br.Bytes()
+	}
+	return asset
+}
+
+// acceptsEncoding reports whether an Accept-Encoding header allows encoding
+func acceptsEncoding(header, encoding string) bool {
+	for _, part := range strings.Split(header, ",") {
+		token, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		if !strings.EqualFold(strings.TrimSpace(token), encoding) {
+			continue
+		}
+		if q, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if v, err := strconv.ParseFloat(q, 64); err == nil && v == 0 {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+func (s *staticAssets) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	route, ok := s.routes[path.Clean("/"+r.URL.Path)]
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	asset := route.asset
+
+	if route.immutable {
+		w.Header().Set("Cache-Control", cacheImmutable)
+	} else {
+		w.Header().Set("Cache-Control", cacheRevalidate)
+	}
+	if ctype := mime.TypeByExtension(path.Ext(asset.name)); ctype != "" {
+		w.Header().Set("Content-Type", ctype)
+	}
+
+	// Each encoding is a distinct representation with its own ETag
+	body, etag := asset.content, asset.etag
+	if asset.gzip != nil || asset.brotli != nil {
+		w.Header().Add("Vary", "Accept-Encoding")
+		accept := r.Header.Get("Accept-Encoding")
+		switch {
+		case asset.brotli != nil && acceptsEncoding(accept, "br"):
+			body, etag = asset.brotli, strings.TrimSuffix(asset.etag, `"`)+`-br"`
+			w.Header().Set("Content-Encoding", "br")
+		case asset.gzip != nil && acceptsEncoding(accept, "gzip"):
+			body, etag = asset.gzip, strings.TrimSuffix(asset.etag, `"`)+`-gz"`
+			w.Header().Set("Content-Encoding", "gzip")
+		}
+	}
+	w.Header().Set("ETag", etag)
+
+	// ServeContent answers If-None-Match with 304 and handles HEAD and ranges
+	http.ServeContent(w, r, asset.name, time.Time{}, bytes.NewReader(body))
+}
+
+// RegisterStaticHandlers registers handlers for serving the embedded web UI.
+// version is the build version; it is part of every asset fingerprint so each
+// release busts browser caches.
+func RegisterStaticHandlers(reg *routes.Registry, version string) {
 	// Get the static subdirectory from embedded FS
 	staticFS, err := fs.Sub(scanner_core.WebContent, "static")
 	if err != nil {
@@ -17,9 +213,14 @@ func RegisterStaticHandlers(mux *http.ServeMux) {
 		return
 	}
 
+	assets, err := newStaticAssets(staticFS, version)
+	if err != nil {
+		log.Warn("failed to load embedded static content", "error", err)
+		return
+	}
+
 	// Serve the embedded files at root
-	fileServer := http.FileServer(http.FS(staticFS))
-	mux.Handle("/", fileServer)
+	reg.Handle(routes.Route{Pattern: "/", Handler: assets, Role: routes.RolePublic})
 
-	log.Info("static web UI registered", "path", "/")
+	log.Info("static web UI registered", "path", "/", "routes", len(assets.routes))
 }
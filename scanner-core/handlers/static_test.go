@@ -0,0 +1,138 @@
+package handlers
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/andybalholm/brotli"
+)
+
+func newTestStaticAssets(t *testing.T, version string) *staticAssets {
+	t.Helper()
+	script := strings.Repeat("console.log('bjorn2scan');\n", 100)
+	assets, err := newStaticAssets(fstest.MapFS{
+		"index.html":        {Data: []byte(`<link rel="stylesheet" href="styles.css"><script src="shared.js"></script><a href="images.html">Images</a>`)},
+		"images.html":       {Data: []byte(`<script src="/shared.js"></script>`)},
+		"shared.js":         {Data: []byte(script)},
+		"styles.css":        {Data: []byte("body{}")},
+		"custom-styles.css": {Data: []byte("p{}")},
+	}, version)
+	if err != nil {
+		t.Fatalf("newStaticAssets() error = %v", err)
+	}
+	return assets
+}
+
+func serveStatic(assets *staticAssets, path string, header map[string]string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	for k, v := range header {
+		req.Header.Set(k, v)
+	}
+	rec := httptest.NewRecorder()
+	assets.ServeHTTP(rec, req)
+	return rec
+}
+
+var hashedScript = regexp.MustCompile(`src="(shared\.[0-9a-f]{12}\.js)"`)
+
+func TestStaticAssetsFingerprinting(t *testing.T) {
+	assets := newTestStaticAssets(t, "1.0.0")
+
+	index := serveStatic(assets, "/", nil)
+	if index.Code != http.StatusOK {
+		t.Fatalf("GET / status = %d", index.Code)
+	}
+	if cc := index.Header().Get("Cache-Control"); cc != cacheRevalidate {
+		t.Errorf("index Cache-Control = %q, want %q", cc, cacheRevalidate)
+	}
+	body := index.Body.String()
+	m := hashedScript.FindStringSubmatch(body)
+	if m == nil {
+		t.Fatalf("index does not reference a fingerprinted shared.js: %s", body)
+	}
+	if !strings.Contains(body, `href="images.html"`) || strings.Contains(body, `href="styles.css"`) {
+		t.Errorf("unexpected rewrite of page links: %s", body)
+	}
+	if !strings.Contains(serveStatic(assets, "/images.html", nil).Body.String(), `src="/`+m[1]+`"`) {
+		t.Error("absolute asset reference was not rewritten")
+	}
+
+	hashed := serveStatic(assets, "/"+m[1], nil)
+	if hashed.Code != http.StatusOK || hashed.Header().Get("Cache-Control") != cacheImmutable {
+		t.Errorf("fingerprinted asset status = %d, Cache-Control = %q", hashed.Code, hashed.Header().Get("Cache-Control"))
+	}
+	if ct := hashed.Header().Get("Content-Type"); !strings.Contains(ct, "javascript") {
+		t.Errorf("Content-Type = %q, want javascript", ct)
+	}
+
+	// The unversioned name stays available but must be revalidated
+	plain := serveStatic(assets, "/shared.js", nil)
+	if plain.Code != http.StatusOK || plain.Header().Get("Cache-Control") != cacheRevalidate {
+		t.Errorf("plain asset status = %d, Cache-Control = %q", plain.Code, plain.Header().Get("Cache-Control"))
+	}
+
+	// A new build version busts the fingerprint
+	upgraded := serveStatic(newTestStaticAssets(t, "1.0.1"), "/", nil).Body.String()
+	if strings.Contains(upgraded, m[1]) {
+		t.Error("fingerprint did not change with the build version")
+	}
+
+	if rec := serveStatic(assets, "/missing.js", nil); rec.Code != http.StatusNotFound {
+		t.Errorf("missing asset status = %d, want 404", rec.Code)
+	}
+}
+
+func TestStaticAssetsETag(t *testing.T) {
+	assets := newTestStaticAssets(t, "1.0.0")
+
+	first := serveStatic(assets, "/styles.css", nil)
+	etag := first.Header().Get("ETag")
+	if etag == "" {
+		t.Fatal("expected an ETag")
+	}
+	second := serveStatic(assets, "/styles.css", map[string]string{"If-None-Match": etag})
+	if second.Code != http.StatusNotModified {
+		t.Errorf("conditional GET status = %d, want 304", second.Code)
+	}
+}
+
+func TestStaticAssetsPrecompression(t *testing.T) {
+	assets := newTestStaticAssets(t, "1.0.0")
+	plain := serveStatic(assets, "/shared.js", nil)
+
+	br := serveStatic(assets, "/shared.js", map[string]string{"Accept-Encoding": "gzip, deflate, br"})
+	if br.Header().Get("Content-Encoding") != "br" || br.Header().Get("Vary") != "Accept-Encoding" {
+		t.Fatalf("Content-Encoding = %q, Vary = %q", br.Header().Get("Content-Encoding"), br.Header().Get("Vary"))
+	}
+	decoded, err := io.ReadAll(brotli.NewReader(br.Body))
+	if err != nil || !bytes.Equal(decoded, plain.Body.Bytes()) {
+		t.Errorf("brotli body does not decode to the original (err = %v)", err)
+	}
+	if br.Header().Get("ETag") == plain.Header().Get("ETag") {
+		t.Error("encoded representation must have its own ETag")
+	}
+
+	gz := serveStatic(assets, "/shared.js", map[string]string{"Accept-Encoding": "gzip, br;q=0"})
+	if gz.Header().Get("Content-Encoding") != "gzip" {
+		t.Fatalf("Content-Encoding = %q, want gzip", gz.Header().Get("Content-Encoding"))
+	}
+	zr, err := gzip.NewReader(gz.Body)
+	if err != nil {
+		t.Fatalf("gzip.NewReader() error = %v", err)
+	}
+	if decoded, err := io.ReadAll(zr); err != nil || !bytes.Equal(decoded, plain.Body.Bytes()) {
+		t.Errorf("gzip body does not decode to the original (err = %v)", err)
+	}
+
+	// Small files aren't compressed
+	if enc := serveStatic(assets, "/styles.css", map[string]string{"Accept-Encoding": "br"}).Header().Get("Content-Encoding"); enc != "" {
+		t.Errorf("small file Content-Encoding = %q, want none", enc)
+	}
+}
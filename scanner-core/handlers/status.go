@@ -0,0 +1,74 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/bvboe/b2s-go/scanner-core/database"
+	"github.com/bvboe/b2s-go/scanner-core/routes"
+)
+
+// DefaultStuckScanTimeout is used when APIOptions.StuckScanTimeout is zero
+const DefaultStuckScanTimeout = 30 * time.Minute
+
+// StuckScanProvider lists images stuck in an intermediate scan state
+type StuckScanProvider interface {
+	GetStuckScans(maxAge time.Duration) ([]database.StuckScan, error)
+}
+
+// ScanHealth is the health of the scan pipeline reported at /api/status.
+// Scans stuck in generating_sbom or scanning_vulnerabilities for longer than
+// the timeout make it unhealthy until the recover-stuck-scans job fails and
+// requeues them.
+type ScanHealth struct {
+	Healthy             bool                    `json:"healthy"`
+	StuckTimeoutSeconds int64                   `json:"stuck_timeout_seconds"`
+	Stuck               map[database.Status]int `json:"stuck"`       // stuck images per intermediate state
+	StuckScans          []database.StuckScan    `json:"stuck_scans"` // oldest transition first
+}
+
+// RegisterStatusHandlers registers the scan pipeline health endpoint
+func RegisterStatusHandlers(reg *routes.Registry, provider StuckScanProvider, timeout time.Duration) {
+	reg.Handle(routes.Route{Pattern: "/api/status", Methods: routes.GET, Handler: StatusHandler(provider, timeout), CacheControl: routes.NoStore})
+}
+
+// StatusHandler creates an HTTP handler for GET /api/status.
+// Reports images stuck in an intermediate scan state with their last status
+// transition, so a crashed worker shows up before its images are recovered.
+//
+// Response: {"healthy": false, "stuck_timeout_seconds": 1800,
+// "stuck": {"generating_sbom": 1, "scanning_vulnerabilities": 0}, "stuck_scans": [...]}
+func StatusHandler(provider StuckScanProvider, timeout time.Duration) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		scans, err := provider.GetStuckScans(timeout)
+		if err != nil {
+			log.Error("error querying stuck scans", "error", err)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+
+		health := ScanHealth{
+			Healthy:             len(scans) == 0,
+			StuckTimeoutSeconds: int64(timeout.Seconds()),
+			Stuck: map[database.Status]int{
+				database.StatusGeneratingSBOM:          0,
+				database.StatusScanningVulnerabilities: 0,
+			},
+			StuckScans: scans,
+		}
+		for _, scan := range scans {
+			health.Stuck[scan.Status]++
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(health); err != nil {
+			log.Error("error encoding status", "error", err)
+		}
+	}
+}
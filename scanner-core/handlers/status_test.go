@@ -0,0 +1,83 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/bvboe/b2s-go/scanner-core/database"
+)
+
+type mockStuckScanProvider struct {
+	scans  []database.StuckScan
+	err    error
+	maxAge time.Duration
+}
+
+func (m *mockStuckScanProvider) GetStuckScans(maxAge time.Duration) ([]database.StuckScan, error) {
+	m.maxAge = maxAge
+	return m.scans, m.err
+}
+
+func TestStatusHandler(t *testing.T) {
+	provider := &mockStuckScanProvider{scans: []database.StuckScan{
+		{Digest: "sha256:a", Status: database.StatusGeneratingSBOM, StatusChangedAt: "2026-01-01 10:00:00"},
+		{Digest: "sha256:b", Status: database.StatusGeneratingSBOM, StatusChangedAt: "2026-01-01 10:05:00"},
+	}}
+
+	rec := httptest.NewRecorder()
+	StatusHandler(provider, 45*time.Minute)(rec, httptest.NewRequest(http.MethodGet, "/api/status", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", rec.Code)
+	}
+	if provider.maxAge != 45*time.Minute {
+		t.Errorf("queried with maxAge %v, want 45m", provider.maxAge)
+	}
+
+	var health ScanHealth
+	if err := json.NewDecoder(rec.Body).Decode(&health); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if health.Healthy || health.StuckTimeoutSeconds != 2700 || len(health.StuckScans) != 2 {
+		t.Errorf("unexpected health: %+v", health)
+	}
+	if health.Stuck[database.StatusGeneratingSBOM] != 2 {
+		t.Errorf("stuck generating_sbom = %d, want 2", health.Stuck[database.StatusGeneratingSBOM])
+	}
+	if n, ok := health.Stuck[database.StatusScanningVulnerabilities]; !ok || n != 0 {
+		t.Errorf("stuck scanning_vulnerabilities = %d, %v; want 0, true", n, ok)
+	}
+	if health.StuckScans[0].StatusChangedAt != "2026-01-01 10:00:00" {
+		t.Errorf("unexpected stuck scan: %+v", health.StuckScans[0])
+	}
+}
+
+func TestStatusHandler_Healthy(t *testing.T) {
+	rec := httptest.NewRecorder()
+	StatusHandler(&mockStuckScanProvider{scans: []database.StuckScan{}}, time.Minute)(rec, httptest.NewRequest(http.MethodGet, "/api/status", nil))
+
+	var health ScanHealth
+	if err := json.NewDecoder(rec.Body).Decode(&health); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if !health.Healthy || health.StuckScans == nil {
+		t.Errorf("unexpected health: %+v", health)
+	}
+}
+
+func TestStatusHandler_Errors(t *testing.T) {
+	rec := httptest.NewRecorder()
+	StatusHandler(&mockStuckScanProvider{}, time.Minute)(rec, httptest.NewRequest(http.MethodPost, "/api/status", nil))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("POST: expected status 405, got %d", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	StatusHandler(&mockStuckScanProvider{err: errors.New("db down")}, time.Minute)(rec, httptest.NewRequest(http.MethodGet, "/api/status", nil))
+	if rec.Code != http.StatusInternalServerError {
+		t.Errorf("provider error: expected status 500, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,157 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/bvboe/b2s-go/scanner-core/database"
+)
+
+// ndjsonContentType is the media type of newline-delimited JSON, one object per line
+const ndjsonContentType = "application/x-ndjson"
+
+// streamFlushRows is how many rows are written between flushes of a streamed
+// response, so clients receive results while the query is still running
+const streamFlushRows = 500
+
+// QueryStreamer streams the rows of a read-only query instead of returning
+// them all at once (implemented by database.DB). Providers without it are
+// served from a buffered result. args are bound to the placeholders of query.
+type QueryStreamer interface {
+	StreamQuery(query string, columns func([]string) error, row func(map[string]interface{}) error, args ...interface{}) error
+}
+
+// wantsNDJSON reports whether the client asked for newline-delimited JSON,
+// either with ?format=ndjson or an Accept: application/x-ndjson header
+func wantsNDJSON(r *http.Request) bool {
+	if r.URL.Query().Get("format") == "ndjson" {
+		return true
+	}
+	for _, accept := range r.Header.Values("Accept") {
+		for _, mediaType := range strings.Split(accept, ",") {
+			mediaType, _, _ = strings.Cut(mediaType, ";")
+			if strings.EqualFold(strings.TrimSpace(mediaType), ndjsonContentType) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// streamQuery runs query with args through provider, streaming rows if the
+// provider supports it
+func streamQuery(provider ImageQueryProvider, query string, columns func([]string) error, row func(map[string]interface{}) error, args ...interface{}) error {
+	if streamer, ok := provider.(QueryStreamer); ok {
+		return streamer.StreamQuery(query, columns, row, args...)
+	}
+
+	result, err := provider.ExecuteReadOnlyQueryArgs(query, args...)
+	if err != nil {
+		return err
+	}
+	if err := columns(result.Columns); err != nil {
+		return err
+	}
+	for _, r := range result.Rows {
+		if err := row(r); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// flushResponse sends buffered response data to the client. Writers that
+// cannot flush are left to send the response when the handler returns.
+func flushResponse(rc *http.ResponseController) {
+	if err := rc.Flush(); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		log.Debug("error flushing streamed response", "error", err)
+	}
+}
+
+// streamQueryAsCSV writes the rows of query, with args bound to its
+// placeholders, as a CSV attachment while they are read, flushing every
+// streamFlushRows rows. The CSV options of r apply.
+// Errors after the header has been sent can only be logged, which leaves the
+// client with a truncated file.
+func streamQueryAsCSV(w http.ResponseWriter, r *http.Request, provider ImageQueryProvider, query, filename string, args ...interface{}) {
+	export := newCSVExport(w, r)
+	if export == nil {
+		return
+	}
+	rc := http.NewResponseController(w)
+	var columnNames []string
+	rows := 0
+
+	err := streamQuery(provider, query,
+		func(cols []string) error {
+			columnNames = cols
+			w.Header().Set("Content-Type", "text/csv")
+			w.Header().Set("Content-Disposition", "attachment; filename="+filename)
+			return export.WriteHeader(cols)
+		},
+		func(row map[string]interface{}) error {
+			strRow := make([]string, len(columnNames))
+			for i, col := range columnNames {
+				strRow[i] = export.Value(row[col])
+			}
+			if err := export.WriteRow(strRow); err != nil {
+				return err
+			}
+			rows++
+			if rows%streamFlushRows == 0 {
+				if err := export.Flush(); err != nil {
+					return err
+				}
+				flushResponse(rc)
+			}
+			return nil
+		}, args...)
+	if err != nil {
+		log.Error("error streaming CSV export", "rows", rows, "error", err)
+		if columnNames == nil {
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+		}
+		return
+	}
+	if err := export.Flush(); err != nil {
+		log.Error("error writing CSV export", "error", err)
+	}
+}
+
+// streamQueryAsNDJSON writes each row of query, with args bound to its
+// placeholders, as a JSON object on its own line while they are read,
+// flushing every streamFlushRows rows
+func streamQueryAsNDJSON(w http.ResponseWriter, provider ImageQueryProvider, query string, args ...interface{}) {
+	rc := http.NewResponseController(w)
+	encoder := json.NewEncoder(w)
+	started := false
+	rows := 0
+
+	err := streamQuery(provider, query,
+		func(cols []string) error {
+			started = true
+			w.Header().Set("Content-Type", ndjsonContentType)
+			return nil
+		},
+		func(row map[string]interface{}) error {
+			if err := encoder.Encode(row); err != nil {
+				return err
+			}
+			rows++
+			if rows%streamFlushRows == 0 {
+				flushResponse(rc)
+			}
+			return nil
+		}, args...)
+	if err != nil {
+		log.Error("error streaming NDJSON response", "rows", rows, "error", err)
+		if !started {
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+		}
+	}
+}
+
+// Ensure database.DB implements QueryStreamer
+var _ QueryStreamer = (*database.DB)(nil)
@@ -0,0 +1,107 @@
+package handlers
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/bvboe/b2s-go/scanner-core/database"
+)
+
+// mockStreamingProvider implements ImageQueryProvider and QueryStreamer for testing
+type mockStreamingProvider struct {
+	mockQueryProvider
+	columns  []string
+	rows     []map[string]interface{}
+	streamed []string
+}
+
+func (m *mockStreamingProvider) StreamQuery(query string, columns func([]string) error, row func(map[string]interface{}) error, _ ...interface{}) error {
+	m.streamed = append(m.streamed, query)
+	if err := columns(m.columns); err != nil {
+		return err
+	}
+	for _, r := range m.rows {
+		if err := row(r); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func TestWantsNDJSON(t *testing.T) {
+	tests := []struct {
+		target string
+		accept string
+		want   bool
+	}{
+		{"/api/images/sha256:abc/vulnerabilities", "", false},
+		{"/api/images/sha256:abc/vulnerabilities", "application/json", false},
+		{"/api/images/sha256:abc/vulnerabilities", "application/x-ndjson", true},
+		{"/api/images/sha256:abc/vulnerabilities", "text/html, Application/X-NDJSON;q=0.9", true},
+		{"/api/images/sha256:abc/vulnerabilities?format=ndjson", "", true},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, tt.target, nil)
+		if tt.accept != "" {
+			req.Header.Set("Accept", tt.accept)
+		}
+		if got := wantsNDJSON(req); got != tt.want {
+			t.Errorf("wantsNDJSON(%q, Accept %q) = %v, want %v", tt.target, tt.accept, got, tt.want)
+		}
+	}
+}
+
+func TestImageVulnerabilitiesDetailHandlerStreaming(t *testing.T) {
+	provider := &mockStreamingProvider{
+		columns: []string{"vulnerability_id", "artifact_name"},
+		rows: []map[string]interface{}{
+			{"vulnerability_id": "CVE-2024-0001", "artifact_name": "openssl"},
+			{"vulnerability_id": "CVE-2024-0002", "artifact_name": "zlib"},
+		},
+	}
+	provider.queryFunc = func(query string) (*database.QueryResult, error) {
+		t.Errorf("expected streamed export not to run buffered query %q", query)
+		return &database.QueryResult{}, nil
+	}
+	handler := ImageVulnerabilitiesDetailHandler(provider)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/images/sha256:abc123/vulnerabilities?page=2&pageSize=1", nil)
+	req.Header.Set("Accept", "application/x-ndjson")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if ct := rec.Header().Get("Content-Type"); ct != ndjsonContentType {
+		t.Errorf("Content-Type = %q, want %q", ct, ndjsonContentType)
+	}
+	var ids []string
+	scanner := bufio.NewScanner(rec.Body)
+	for scanner.Scan() {
+		var row map[string]interface{}
+		if err := json.Unmarshal(scanner.Bytes(), &row); err != nil {
+			t.Fatalf("invalid NDJSON line %q: %v", scanner.Text(), err)
+		}
+		ids = append(ids, row["vulnerability_id"].(string))
+	}
+	if strings.Join(ids, ",") != "CVE-2024-0001,CVE-2024-0002" {
+		t.Errorf("streamed ids = %v", ids)
+	}
+	if len(provider.streamed) != 1 || strings.Contains(provider.streamed[0], "LIMIT") {
+		t.Errorf("expected one unpaginated streamed query, got %v", provider.streamed)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/images/sha256:abc123/vulnerabilities?format=csv", nil)
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	want := "vulnerability_id,artifact_name\nCVE-2024-0001,openssl\nCVE-2024-0002,zlib\n"
+	if rec.Body.String() != want {
+		t.Errorf("CSV body = %q, want %q", rec.Body.String(), want)
+	}
+	if cd := rec.Header().Get("Content-Disposition"); cd != "attachment; filename=vulnerabilities.csv" {
+		t.Errorf("Content-Disposition = %q", cd)
+	}
+}
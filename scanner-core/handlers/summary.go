@@ -1,7 +1,6 @@
 package handlers
 
 import (
-	"encoding/csv"
 	"encoding/json"
 	"fmt"
 	"math"
@@ -29,31 +28,27 @@ import (
 //
 // When all filter slices are empty the generated SQL is equivalent to the
 // original unfiltered query.
-func buildDeploymentMetricsQuery(namespaces, vulnStatuses, packageTypes, osNames, severities []string) string {
+func buildDeploymentMetricsQuery(namespaces, vulnStatuses, packageTypes, osNames, severities []string) (string, []interface{}) {
+	var args queryArgs
+
 	// vuln_agg WHERE: fix_status, package_type, and severity. Severity is the
 	// CVE pages' filter; the summary must reflect it like the other vuln filters.
 	var vulnConds []string
-	if c := buildINClause("fix_status", vulnStatuses); c != "" {
-		vulnConds = append(vulnConds, c)
-	}
-	if c := buildINClause("package_type", packageTypes); c != "" {
-		vulnConds = append(vulnConds, c)
-	}
-	if c := buildINClause("severity", severities); c != "" {
-		vulnConds = append(vulnConds, c)
-	}
+	vulnConds = appendCondition(vulnConds, args.in("fix_status", vulnStatuses))
+	vulnConds = appendCondition(vulnConds, args.in("package_type", packageTypes))
+	vulnConds = appendCondition(vulnConds, args.in("severity", severities))
 	vulnFilter := ""
 	if len(vulnConds) > 0 {
 		vulnFilter = "WHERE " + strings.Join(vulnConds, " AND ")
 	}
 
 	nsFilter := ""
-	if c := buildINClause("namespace", namespaces); c != "" {
+	if c := args.in("namespace", namespaces); c != "" {
 		nsFilter = "WHERE " + c
 	}
 
 	imgStatsWhere := ""
-	if c := buildINClause("i.os_name", osNames); c != "" {
+	if c := args.in("i.os_name", osNames); c != "" {
 		imgStatsWhere = "WHERE " + c
 	}
 
@@ -63,21 +58,11 @@ func buildDeploymentMetricsQuery(namespaces, vulnStatuses, packageTypes, osNames
 	}
 
 	// unique_cves subquery applies the same vuln filters plus the image_id set
-	var uniqueCVEsConds []string
-	if c := buildINClause("fix_status", vulnStatuses); c != "" {
-		uniqueCVEsConds = append(uniqueCVEsConds, c)
-	}
-	if c := buildINClause("package_type", packageTypes); c != "" {
-		uniqueCVEsConds = append(uniqueCVEsConds, c)
-	}
-	if c := buildINClause("severity", severities); c != "" {
-		uniqueCVEsConds = append(uniqueCVEsConds, c)
-	}
-	uniqueCVEsConds = append(uniqueCVEsConds,
+	uniqueCVEsConds := append(append([]string{}, vulnConds...),
 		"image_id IN (SELECT id FROM img_stats WHERE status = 'completed')")
 	uniqueCVEsWhere := "WHERE " + strings.Join(uniqueCVEsConds, "\n   AND ")
 
-	return fmt.Sprintf(`WITH
+	query := fmt.Sprintf(`WITH
   vuln_agg AS (
     SELECT
       image_id,
@@ -112,10 +97,11 @@ SELECT
   (SELECT COUNT(DISTINCT cve_id) FROM image_vulnerabilities
    %s)                                                                                              AS unique_cves,
   COALESCE(SUM(CASE WHEN status = 'completed' THEN cnt * img_exploits END), 0)                      AS total_exploits,
-  COALESCE(SUM(CASE WHEN status NOT IN ('completed','sbom_failed','vuln_scan_failed') THEN 1 ELSE 0 END), 0) AS images_pending,
-  COALESCE(SUM(CASE WHEN status IN ('sbom_failed','vuln_scan_failed') THEN 1 ELSE 0 END), 0)        AS images_failed
+  COALESCE(SUM(CASE WHEN status NOT IN ('completed','sbom_failed','vuln_scan_failed','scan_failed_permanent') THEN 1 ELSE 0 END), 0) AS images_pending,
+  COALESCE(SUM(CASE WHEN status IN ('sbom_failed','vuln_scan_failed','scan_failed_permanent') THEN 1 ELSE 0 END), 0)        AS images_failed
 FROM img_stats
 `, vulnFilter, nsFilter, imgStatsWhere, containerInstancesExpr, uniqueCVEsWhere)
+	return query, args
 }
 
 // DeploymentMetricsHandler returns a single-row JSON summary of the deployment,
@@ -130,8 +116,8 @@ func DeploymentMetricsHandler(provider ImageQueryProvider) http.HandlerFunc {
 		osNames := parseMultiSelect(params.Get("osNames"))
 		severities := parseMultiSelect(params.Get("severity"))
 
-		query := buildDeploymentMetricsQuery(namespaces, vulnStatuses, packageTypes, osNames, severities)
-		result, err := provider.ExecuteReadOnlyQuery(query)
+		query, args := buildDeploymentMetricsQuery(namespaces, vulnStatuses, packageTypes, osNames, severities)
+		result, err := provider.ExecuteReadOnlyQueryArgs(query, args...)
 		if err != nil {
 			log.Error("error executing deployment metrics query", "error", err)
 			http.Error(w, "Internal server error", http.StatusInternalServerError)
@@ -179,33 +165,31 @@ func DeploymentMetricsHandler(provider ImageQueryProvider) http.HandlerFunc {
 //
 // When all filter slices are empty the generated SQL is equivalent to the
 // original unfiltered query.
-func buildNodeMetricsQuery(osNames, vulnStatuses, packageTypes, severities []string) string {
+func buildNodeMetricsQuery(osNames, vulnStatuses, packageTypes, severities []string) (string, []interface{}) {
+	var args queryArgs
+
 	// completed CTE: filter by os_release
 	osFilter := ""
-	if c := buildINClause("os_release", osNames); c != "" {
+	if c := args.in("os_release", osNames); c != "" {
 		osFilter = " AND " + c
 	}
 
 	// vuln_agg filters: fix_status and package type
 	// unique_cves is computed in the same CTE to avoid a second scan of node_vulnerabilities
 	var nvConds []string
-	if c := buildINClause("nv.fix_status", vulnStatuses); c != "" {
-		nvConds = append(nvConds, c)
-	}
+	nvConds = appendCondition(nvConds, args.in("nv.fix_status", vulnStatuses))
 	pkgJoin := ""
-	if c := buildINClause("np.type", packageTypes); c != "" {
+	if c := args.in("np.type", packageTypes); c != "" {
 		pkgJoin = "\n    JOIN node_packages np ON nv.package_id = np.id"
 		nvConds = append(nvConds, c)
 	}
-	if c := buildINClause("nv.severity", severities); c != "" {
-		nvConds = append(nvConds, c)
-	}
+	nvConds = appendCondition(nvConds, args.in("nv.severity", severities))
 	nvExtraWhere := ""
 	if len(nvConds) > 0 {
 		nvExtraWhere = "\n    AND " + strings.Join(nvConds, "\n    AND ")
 	}
 
-	return fmt.Sprintf(`WITH
+	query := fmt.Sprintf(`WITH
   completed AS (
     SELECT id FROM nodes WHERE status = 'completed'%s
   ),
@@ -228,6 +212,7 @@ SELECT
    WHERE status IN ('sbom_failed','vuln_scan_failed')%s)                       AS nodes_failed
 FROM vuln_agg v
 `, osFilter, pkgJoin, nvExtraWhere, osFilter, osFilter, osFilter)
+	return query, args
 }
 
 // NodeMetricsSummaryHandler returns a single-row JSON summary of node scan results.
@@ -241,8 +226,8 @@ func NodeMetricsSummaryHandler(provider ImageQueryProvider) http.HandlerFunc {
 		packageTypes := parseMultiSelect(params.Get("packageTypes"))
 		severities := parseMultiSelect(params.Get("severity"))
 
-		query := buildNodeMetricsQuery(osNames, vulnStatuses, packageTypes, severities)
-		result, err := provider.ExecuteReadOnlyQuery(query)
+		query, args := buildNodeMetricsQuery(osNames, vulnStatuses, packageTypes, severities)
+		result, err := provider.ExecuteReadOnlyQueryArgs(query, args...)
 		if err != nil {
 			log.Error("error executing node metrics query", "error", err)
 			http.Error(w, "Internal server error", http.StatusInternalServerError)
@@ -317,10 +302,10 @@ func NamespaceSummaryHandler(provider ImageQueryProvider) http.HandlerFunc {
 		}
 
 		// Build query
-		query, countQuery := buildNamespaceSummaryQuery(namespaces, vulnStatuses, packageTypes, osNames, sortBy, sortOrder, pageSize, offset)
+		query, countQuery, args := buildNamespaceSummaryQuery(namespaces, vulnStatuses, packageTypes, osNames, sortBy, sortOrder, pageSize, offset)
 
 		// Execute count query for pagination
-		countResult, err := provider.ExecuteReadOnlyQuery(countQuery)
+		countResult, err := provider.ExecuteReadOnlyQueryArgs(countQuery, args...)
 		if err != nil {
 			log.Error("error executing namespace count query", "error", err)
 			http.Error(w, "Internal server error", http.StatusInternalServerError)
@@ -335,7 +320,7 @@ func NamespaceSummaryHandler(provider ImageQueryProvider) http.HandlerFunc {
 		}
 
 		// Execute main query
-		result, err := provider.ExecuteReadOnlyQuery(query)
+		result, err := provider.ExecuteReadOnlyQueryArgs(query, args...)
 		if err != nil {
 			log.Error("error executing namespace summary query", "error", err)
 			http.Error(w, "Internal server error", http.StatusInternalServerError)
@@ -362,14 +347,17 @@ func NamespaceSummaryHandler(provider ImageQueryProvider) http.HandlerFunc {
 
 		// Handle CSV export
 		if format == "csv" {
+			export := newCSVExport(w, r)
+			if export == nil {
+				return
+			}
 			w.Header().Set("Content-Type", "text/csv")
 			w.Header().Set("Content-Disposition", "attachment; filename=namespace_summary.csv")
 
-			writer := csv.NewWriter(w)
-			defer writer.Flush()
+			defer func() { _ = export.Flush() }()
 
 			// Write header
-			if err := writer.Write([]string{
+			if err := export.WriteHeader([]string{
 				"Namespace", "Containers", "Avg Critical", "Avg High", "Avg Medium",
 				"Avg Low", "Avg Negligible", "Avg Unknown", "Avg Risk Score", "Avg Exploits", "Avg Packages",
 			}); err != nil {
@@ -380,18 +368,18 @@ func NamespaceSummaryHandler(provider ImageQueryProvider) http.HandlerFunc {
 
 			// Write data
 			for _, item := range namespaceData {
-				if err := writer.Write([]string{
+				if err := export.WriteRow([]string{
 					fmt.Sprintf("%v", item["namespace"]),
 					fmt.Sprintf("%v", item["container_count"]),
-					fmt.Sprintf("%.1f", item["avg_critical"]),
-					fmt.Sprintf("%.1f", item["avg_high"]),
-					fmt.Sprintf("%.1f", item["avg_medium"]),
-					fmt.Sprintf("%.1f", item["avg_low"]),
-					fmt.Sprintf("%.1f", item["avg_negligible"]),
-					fmt.Sprintf("%.1f", item["avg_unknown"]),
-					fmt.Sprintf("%.1f", item["avg_risk"]),
-					fmt.Sprintf("%.1f", item["avg_exploits"]),
-					fmt.Sprintf("%.1f", item["avg_packages"]),
+					export.Decimal(item["avg_critical"], 1),
+					export.Decimal(item["avg_high"], 1),
+					export.Decimal(item["avg_medium"], 1),
+					export.Decimal(item["avg_low"], 1),
+					export.Decimal(item["avg_negligible"], 1),
+					export.Decimal(item["avg_unknown"], 1),
+					export.Decimal(item["avg_risk"], 1),
+					export.Decimal(item["avg_exploits"], 1),
+					export.Decimal(item["avg_packages"], 1),
 				}); err != nil {
 					log.Error("error writing CSV row", "error", err)
 					http.Error(w, "Error generating CSV", http.StatusInternalServerError)
@@ -418,10 +406,11 @@ func NamespaceSummaryHandler(provider ImageQueryProvider) http.HandlerFunc {
 }
 
 // buildNamespaceSummaryQuery constructs the SQL query for namespace-level aggregations
-func buildNamespaceSummaryQuery(namespaces, vulnStatuses, packageTypes, osNames []string, sortBy, sortOrder string, limit, offset int) (string, string) {
+func buildNamespaceSummaryQuery(namespaces, vulnStatuses, packageTypes, osNames []string, sortBy, sortOrder string, limit, offset int) (string, string, []interface{}) {
 	// Build subquery filters using helper functions
-	packageTypeFilter := buildPackageTypeFilter(packageTypes)
-	vulnStatusFilter := buildVulnerabilityFilter(vulnStatuses, packageTypes)
+	var args queryArgs
+	packageTypeFilter := args.packageTypeFilter(packageTypes)
+	vulnStatusFilter := args.vulnerabilityFilter(vulnStatuses, packageTypes)
 
 	// Base query with subqueries
 	baseQuery := fmt.Sprintf(`
@@ -455,10 +444,10 @@ WHERE status.status = 'completed'`, packageTypeFilter, vulnStatusFilter)
 	var conditions []string
 
 	// Namespace filter
-	conditions = appendCondition(conditions, buildINClause("instances.namespace", namespaces))
+	conditions = appendCondition(conditions, args.in("instances.namespace", namespaces))
 
 	// OS name filter
-	conditions = appendCondition(conditions, buildINClause("images.os_name", osNames))
+	conditions = appendCondition(conditions, args.in("images.os_name", osNames))
 
 	// Add conditions to base query
 	whereClause := baseQuery + buildWhereClause(conditions)
@@ -512,7 +501,7 @@ WHERE status.status = 'completed'`, packageTypeFilter, vulnStatusFilter)
 		mainQuery += fmt.Sprintf(" LIMIT %d OFFSET %d", limit, offset)
 	}
 
-	return mainQuery, countQuery
+	return mainQuery, countQuery, args
 }
 
 // DistributionSummaryHandler creates an HTTP handler for /api/summary/by-distribution endpoint
@@ -556,10 +545,10 @@ func DistributionSummaryHandler(provider ImageQueryProvider) http.HandlerFunc {
 		}
 
 		// Build query
-		query, countQuery := buildDistributionSummaryQuery(namespaces, vulnStatuses, packageTypes, osNames, sortBy, sortOrder, pageSize, offset)
+		query, countQuery, args := buildDistributionSummaryQuery(namespaces, vulnStatuses, packageTypes, osNames, sortBy, sortOrder, pageSize, offset)
 
 		// Execute count query for pagination
-		countResult, err := provider.ExecuteReadOnlyQuery(countQuery)
+		countResult, err := provider.ExecuteReadOnlyQueryArgs(countQuery, args...)
 		if err != nil {
 			log.Error("error executing distribution count query", "error", err)
 			http.Error(w, "Internal server error", http.StatusInternalServerError)
@@ -574,7 +563,7 @@ func DistributionSummaryHandler(provider ImageQueryProvider) http.HandlerFunc {
 		}
 
 		// Execute main query
-		result, err := provider.ExecuteReadOnlyQuery(query)
+		result, err := provider.ExecuteReadOnlyQueryArgs(query, args...)
 		if err != nil {
 			log.Error("error executing distribution summary query", "error", err)
 			http.Error(w, "Internal server error", http.StatusInternalServerError)
@@ -601,14 +590,17 @@ func DistributionSummaryHandler(provider ImageQueryProvider) http.HandlerFunc {
 
 		// Handle CSV export
 		if format == "csv" {
+			export := newCSVExport(w, r)
+			if export == nil {
+				return
+			}
 			w.Header().Set("Content-Type", "text/csv")
 			w.Header().Set("Content-Disposition", "attachment; filename=distribution_summary.csv")
 
-			writer := csv.NewWriter(w)
-			defer writer.Flush()
+			defer func() { _ = export.Flush() }()
 
 			// Write header
-			if err := writer.Write([]string{
+			if err := export.WriteHeader([]string{
 				"OS Distribution", "Containers", "Avg Critical", "Avg High", "Avg Medium",
 				"Avg Low", "Avg Negligible", "Avg Unknown", "Avg Risk Score", "Avg Exploits", "Avg Packages",
 			}); err != nil {
@@ -619,18 +611,18 @@ func DistributionSummaryHandler(provider ImageQueryProvider) http.HandlerFunc {
 
 			// Write data
 			for _, item := range distributionData {
-				if err := writer.Write([]string{
+				if err := export.WriteRow([]string{
 					fmt.Sprintf("%v", item["os_name"]),
 					fmt.Sprintf("%v", item["container_count"]),
-					fmt.Sprintf("%.1f", item["avg_critical"]),
-					fmt.Sprintf("%.1f", item["avg_high"]),
-					fmt.Sprintf("%.1f", item["avg_medium"]),
-					fmt.Sprintf("%.1f", item["avg_low"]),
-					fmt.Sprintf("%.1f", item["avg_negligible"]),
-					fmt.Sprintf("%.1f", item["avg_unknown"]),
-					fmt.Sprintf("%.1f", item["avg_risk"]),
-					fmt.Sprintf("%.1f", item["avg_exploits"]),
-					fmt.Sprintf("%.1f", item["avg_packages"]),
+					export.Decimal(item["avg_critical"], 1),
+					export.Decimal(item["avg_high"], 1),
+					export.Decimal(item["avg_medium"], 1),
+					export.Decimal(item["avg_low"], 1),
+					export.Decimal(item["avg_negligible"], 1),
+					export.Decimal(item["avg_unknown"], 1),
+					export.Decimal(item["avg_risk"], 1),
+					export.Decimal(item["avg_exploits"], 1),
+					export.Decimal(item["avg_packages"], 1),
 				}); err != nil {
 					log.Error("error writing CSV row", "error", err)
 					http.Error(w, "Error generating CSV", http.StatusInternalServerError)
@@ -657,10 +649,11 @@ func DistributionSummaryHandler(provider ImageQueryProvider) http.HandlerFunc {
 }
 
 // buildDistributionSummaryQuery constructs the SQL query for distribution-level aggregations
-func buildDistributionSummaryQuery(namespaces, vulnStatuses, packageTypes, osNames []string, sortBy, sortOrder string, limit, offset int) (string, string) {
+func buildDistributionSummaryQuery(namespaces, vulnStatuses, packageTypes, osNames []string, sortBy, sortOrder string, limit, offset int) (string, string, []interface{}) {
 	// Build subquery filters using helper functions
-	packageTypeFilter := buildPackageTypeFilter(packageTypes)
-	vulnStatusFilter := buildVulnerabilityFilter(vulnStatuses, packageTypes)
+	var args queryArgs
+	packageTypeFilter := args.packageTypeFilter(packageTypes)
+	vulnStatusFilter := args.vulnerabilityFilter(vulnStatuses, packageTypes)
 
 	// Base query with subqueries
 	baseQuery := fmt.Sprintf(`
@@ -696,10 +689,10 @@ WHERE status.status = 'completed'
 	var conditions []string
 
 	// Namespace filter
-	conditions = appendCondition(conditions, buildINClause("instances.namespace", namespaces))
+	conditions = appendCondition(conditions, args.in("instances.namespace", namespaces))
 
 	// OS name filter
-	conditions = appendCondition(conditions, buildINClause("images.os_name", osNames))
+	conditions = appendCondition(conditions, args.in("images.os_name", osNames))
 
 	// Add conditions to base query
 	whereClause := baseQuery + buildWhereClause(conditions)
@@ -753,7 +746,7 @@ WHERE status.status = 'completed'
 		mainQuery += fmt.Sprintf(" LIMIT %d OFFSET %d", limit, offset)
 	}
 
-	return mainQuery, countQuery
+	return mainQuery, countQuery, args
 }
 
 // Helper functions
@@ -47,7 +47,7 @@ func TestNamespaceSecondarySorting(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			// Build query
-			query, _ := buildNamespaceSummaryQuery(nil, nil, nil, nil, tt.sortBy, tt.sortOrder, 100, 0)
+			query, _, _ := buildNamespaceSummaryQuery(nil, nil, nil, nil, tt.sortBy, tt.sortOrder, 100, 0)
 
 			// Check expected ORDER BY clause
 			if !strings.Contains(query, tt.expectedOrderBy) {
@@ -104,7 +104,7 @@ func TestDistributionSecondarySorting(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			// Build query
-			query, _ := buildDistributionSummaryQuery(nil, nil, nil, nil, tt.sortBy, tt.sortOrder, 100, 0)
+			query, _, _ := buildDistributionSummaryQuery(nil, nil, nil, nil, tt.sortBy, tt.sortOrder, 100, 0)
 
 			// Check expected ORDER BY clause
 			if !strings.Contains(query, tt.expectedOrderBy) {
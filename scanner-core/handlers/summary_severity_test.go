@@ -13,30 +13,30 @@ func TestSummaryMetricsSeverityFilter(t *testing.T) {
 	defer cleanup()
 
 	t.Run("deployment metrics applies severity", func(t *testing.T) {
-		q := buildDeploymentMetricsQuery(nil, nil, nil, nil, []string{"Critical", "High"})
-		if !strings.Contains(q, "severity IN ('Critical','High')") {
-			t.Errorf("expected severity filter in deployment metrics query:\n%s", q)
+		q, args := buildDeploymentMetricsQuery(nil, nil, nil, nil, []string{"Critical", "High"})
+		if !strings.Contains(q, "severity IN (?1,?2)") || len(args) != 2 || args[0] != "Critical" {
+			t.Errorf("expected severity filter in deployment metrics query (args %v):\n%s", args, q)
 		}
-		if _, err := db.ExecuteReadOnlyQuery(q); err != nil {
+		if _, err := db.ExecuteReadOnlyQueryArgs(q, args...); err != nil {
 			t.Fatalf("severity-filtered deployment query failed against real schema: %v\n%s", err, q)
 		}
 
 		// No severity → no severity clause (unchanged behavior).
-		if q := buildDeploymentMetricsQuery(nil, nil, nil, nil, nil); strings.Contains(q, "severity IN") {
+		if q, _ := buildDeploymentMetricsQuery(nil, nil, nil, nil, nil); strings.Contains(q, "severity IN") {
 			t.Errorf("did not expect a severity clause when none selected:\n%s", q)
 		}
 	})
 
 	t.Run("node metrics applies severity", func(t *testing.T) {
-		q := buildNodeMetricsQuery(nil, nil, nil, []string{"Critical"})
-		if !strings.Contains(q, "nv.severity IN ('Critical')") {
-			t.Errorf("expected nv.severity filter in node metrics query:\n%s", q)
+		q, args := buildNodeMetricsQuery(nil, nil, nil, []string{"Critical"})
+		if !strings.Contains(q, "nv.severity IN (?1)") || len(args) != 1 || args[0] != "Critical" {
+			t.Errorf("expected nv.severity filter in node metrics query (args %v):\n%s", args, q)
 		}
-		if _, err := db.ExecuteReadOnlyQuery(q); err != nil {
+		if _, err := db.ExecuteReadOnlyQueryArgs(q, args...); err != nil {
 			t.Fatalf("severity-filtered node query failed against real schema: %v\n%s", err, q)
 		}
 
-		if q := buildNodeMetricsQuery(nil, nil, nil, nil); strings.Contains(q, "severity IN") {
+		if q, _ := buildNodeMetricsQuery(nil, nil, nil, nil); strings.Contains(q, "severity IN") {
 			t.Errorf("did not expect a severity clause when none selected:\n%s", q)
 		}
 	})
@@ -392,13 +392,13 @@ func TestNamespaceSummaryHandler(t *testing.T) {
 			queryParams: "namespaces=default&vulnStatuses=fixed&packageTypes=apk",
 			mockFunc: func(query string) (*database.QueryResult, error) {
 				// Check that filters are applied in subqueries
-				if !strings.Contains(query, "fix_status IN ('fixed')") {
+				if !strings.Contains(query, "fix_status IN (?2)") {
 					t.Error("Expected vulnerability status filter in query")
 				}
-				if !strings.Contains(query, "type IN ('apk')") {
+				if !strings.Contains(query, "type IN (?1)") {
 					t.Error("Expected package type filter in query")
 				}
-				if !strings.Contains(query, "instances.namespace IN ('default')") {
+				if !strings.Contains(query, "instances.namespace IN (?4)") {
 					t.Error("Expected namespace filter in query")
 				}
 				trimmed := strings.TrimSpace(query)
@@ -663,21 +663,22 @@ func TestBuildNamespaceSummaryQuery(t *testing.T) {
 			name:       "with namespace filter",
 			namespaces: []string{"default", "kube-system"},
 			expectedInQuery: []string{
-				"instances.namespace IN ('default','kube-system')",
+				"instances.namespace IN (?1,?2)",
 			},
 		},
 		{
 			name:         "with vulnerability status filter",
 			vulnStatuses: []string{"fixed"},
 			expectedInQuery: []string{
-				"fix_status IN ('fixed')",
+				"fix_status IN (?1)",
 			},
 		},
 		{
 			name:         "with package type filter",
 			packageTypes: []string{"apk", "deb"},
 			expectedInQuery: []string{
-				"type IN ('apk','deb')",
+				"type IN (?1,?2)",
+				"package_type IN (?3,?4)",
 			},
 		},
 		{
@@ -692,7 +693,7 @@ func TestBuildNamespaceSummaryQuery(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			mainQuery, countQuery := buildNamespaceSummaryQuery(
+			mainQuery, countQuery, args := buildNamespaceSummaryQuery(
 				tt.namespaces,
 				tt.vulnStatuses,
 				tt.packageTypes,
@@ -710,6 +711,9 @@ func TestBuildNamespaceSummaryQuery(t *testing.T) {
 						expected, mainQuery, countQuery)
 				}
 			}
+			if want := len(tt.namespaces) + len(tt.vulnStatuses) + 2*len(tt.packageTypes) + len(tt.osNames); len(args) != want {
+				t.Errorf("Expected %d bound arguments, got %v", want, args)
+			}
 
 			// Check pagination
 			if !strings.Contains(mainQuery, "LIMIT 50") {
@@ -796,7 +800,7 @@ func TestHelperFunctions(t *testing.T) {
 // calculate total_risk and exploit_count by multiplying by vulnerability count
 func TestSummaryRiskAndExploitCalculation(t *testing.T) {
 	t.Run("namespace summary query multiplies risk by count", func(t *testing.T) {
-		query, _ := buildNamespaceSummaryQuery(
+		query, _, _ := buildNamespaceSummaryQuery(
 			nil, nil, nil, nil, "", "ASC", 50, 0,
 		)
 
@@ -812,7 +816,7 @@ func TestSummaryRiskAndExploitCalculation(t *testing.T) {
 	})
 
 	t.Run("distribution summary query multiplies risk by count", func(t *testing.T) {
-		query, _ := buildDistributionSummaryQuery(
+		query, _, _ := buildDistributionSummaryQuery(
 			nil, nil, nil, nil, "", "ASC", 50, 0,
 		)
 
@@ -0,0 +1,215 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/bvboe/b2s-go/scanner-core/database"
+	"github.com/bvboe/b2s-go/scanner-core/labels"
+	"github.com/bvboe/b2s-go/scanner-core/routes"
+	"github.com/bvboe/b2s-go/scanner-core/sbomformat"
+	"github.com/bvboe/b2s-go/scanner-core/transfer"
+)
+
+// maxImportBundleSize limits the size of an imported bundle (SBOMs of large images can be tens of MB)
+const maxImportBundleSize = 256 << 20
+
+// ImportResult is the response of the import endpoint
+type ImportResult struct {
+	Digest string `json:"digest"`
+	Status string `json:"status"` // "imported" or "skipped"
+	Reason string `json:"reason,omitempty"`
+	// Annotations is the number of vulnerability notes added from the bundle
+	// (notes on vulnerabilities already annotated here are kept)
+	Annotations int `json:"annotations,omitempty"`
+}
+
+// TransferConfig configures the import/export endpoints
+type TransferConfig struct {
+	// SigningKey is the shared secret used to sign exported bundles and verify imported ones.
+	// Import is disabled when no key is configured.
+	SigningKey string
+	// Source identifies this server in exported bundles (e.g. cluster name)
+	Source string
+	// DeploymentUUID and Labels (static labels) are added to exported bundles
+	// so that receivers can tell sources apart
+	DeploymentUUID string
+	Labels         labels.Set
+	// PathRedaction redacts file paths in the SBOM and vulnerability report
+	// of exported bundles (sbomformat.RedactStrip or RedactHash; empty keeps them)
+	PathRedaction string
+}
+
+// ExportImageHandler creates an HTTP handler for /api/export/images/{digest} endpoint
+// Returns a signed bundle with the SBOM, vulnerability report, metadata and
+// the analyst notes on the vulnerabilities of the image
+func ExportImageHandler(db *database.DB, cfg TransferConfig) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		digest := strings.TrimPrefix(r.URL.Path, "/api/export/images/")
+		if digest == "" || digest == r.URL.Path {
+			http.Error(w, "Digest required", http.StatusBadRequest)
+			return
+		}
+
+		complete, err := db.IsScanDataComplete(digest)
+		if err != nil {
+			log.Error("error checking scan data", "digest", digest, "error", err)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		if !complete {
+			http.Error(w, "Scan results not available for image", http.StatusNotFound)
+			return
+		}
+
+		record, err := db.GetImageTransferRecord(digest)
+		if err != nil {
+			log.Error("error retrieving image metadata", "digest", digest, "error", err)
+			http.Error(w, "Image not found", http.StatusNotFound)
+			return
+		}
+		sbomData, err := db.GetSBOM(digest)
+		if err != nil {
+			log.Error("error retrieving SBOM", "digest", digest, "error", err)
+			http.Error(w, "SBOM not found", http.StatusNotFound)
+			return
+		}
+		vulnData, err := db.GetVulnerabilities(digest)
+		if err != nil {
+			log.Error("error retrieving vulnerabilities", "digest", digest, "error", err)
+			http.Error(w, "Vulnerabilities not found", http.StatusNotFound)
+			return
+		}
+
+		if sbomData, err = sbomformat.RedactPaths(sbomData, cfg.PathRedaction); err == nil {
+			vulnData, err = sbomformat.RedactPaths(vulnData, cfg.PathRedaction)
+		}
+		if err != nil {
+			log.Error("error redacting export data", "digest", digest, "error", err)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+
+		bundle, err := transfer.NewBundle(*record, cfg.Source, sbomData, vulnData)
+		if err != nil {
+			log.Error("error creating export bundle", "digest", digest, "error", err)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		bundle.DeploymentUUID = cfg.DeploymentUUID
+		bundle.Labels = cfg.Labels
+		annotations, err := db.GetImageCVEAnnotations(digest)
+		if err != nil {
+			log.Error("error retrieving CVE annotations", "digest", digest, "error", err)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		if len(annotations) > 0 {
+			bundle.Annotations = annotations
+		}
+		sealed, err := transfer.Seal(bundle, cfg.SigningKey)
+		if err != nil {
+			log.Error("error encoding export bundle", "digest", digest, "error", err)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+
+		// Create a safe filename from digest
+		filename := digest
+		if len(filename) > 20 {
+			filename = filename[:7] + "_" + filename[7:19]
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Content-Disposition", "attachment; filename=\"bundle_"+filename+".json\"")
+		if _, err := w.Write(sealed); err != nil {
+			log.Error("error writing export response", "error", err)
+		}
+	}
+}
+
+// ImportHandler creates an HTTP handler for /api/import endpoint
+// Accepts a signed bundle produced by ExportImageHandler and stores its scan results.
+// Images that already have complete scan data are skipped unless ?force=true is given.
+func ImportHandler(db *database.DB, cfg TransferConfig) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		if cfg.SigningKey == "" {
+			http.Error(w, "Import disabled: no transfer signing key configured", http.StatusForbidden)
+			return
+		}
+
+		body, err := io.ReadAll(io.LimitReader(r.Body, maxImportBundleSize+1))
+		if err != nil {
+			http.Error(w, "Failed to read request body", http.StatusBadRequest)
+			return
+		}
+		if len(body) > maxImportBundleSize {
+			http.Error(w, "Bundle too large", http.StatusRequestEntityTooLarge)
+			return
+		}
+
+		bundle, err := transfer.Open(body, cfg.SigningKey)
+		if errors.Is(err, transfer.ErrInvalidSignature) {
+			log.Warn("rejected import bundle with invalid signature")
+			http.Error(w, "Invalid bundle signature", http.StatusUnauthorized)
+			return
+		}
+		if err != nil {
+			http.Error(w, "Invalid bundle: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		digest := bundle.Image.Digest
+
+		result := ImportResult{Digest: digest, Status: "imported"}
+		complete, err := db.IsScanDataComplete(digest)
+		if err != nil {
+			log.Error("error checking scan data", "digest", digest, "error", err)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		if complete && r.URL.Query().Get("force") != "true" {
+			result.Status = "skipped"
+			result.Reason = "image already has complete scan results"
+		} else {
+			if err := db.ImportScanResults(digest, bundle.SBOM, bundle.Vulnerabilities, bundle.GrypeDBBuilt()); err != nil {
+				log.Error("error importing scan results", "digest", digest, "error", err)
+				http.Error(w, "Failed to import scan results", http.StatusInternalServerError)
+				return
+			}
+			log.Info("imported scan results", "digest", digest, "source", bundle.Source)
+		}
+
+		// Notes are shared knowledge, so they are imported even when the scan results are skipped
+		result.Annotations, err = db.ImportCVEAnnotations(bundle.Annotations)
+		if err != nil {
+			log.Error("error importing CVE annotations", "digest", digest, "error", err)
+			http.Error(w, "Failed to import CVE annotations", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(result); err != nil {
+			log.Error("error encoding import response", "error", err)
+		}
+	}
+}
+
+// RegisterTransferHandlers registers the scan result import/export endpoints
+func RegisterTransferHandlers(reg *routes.Registry, db *database.DB, cfg TransferConfig) {
+	reg.Handle(
+		routes.Route{Pattern: "/api/export/images/", Methods: routes.GET, Handler: ExportImageHandler(db, cfg)},
+		routes.Route{Pattern: "/api/import", Methods: routes.POST, Handler: ImportHandler(db, cfg), Role: routes.RoleAdmin},
+	)
+}
@@ -0,0 +1,180 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bvboe/b2s-go/scanner-core/database"
+	"github.com/bvboe/b2s-go/scanner-core/labels"
+	"github.com/bvboe/b2s-go/scanner-core/sbomformat"
+	"github.com/bvboe/b2s-go/scanner-core/transfer"
+)
+
+const testTransferDigest = "sha256:1111111111111111111111111111111111111111111111111111111111111111"
+
+// createTransferTestDB creates a database in the test's temp dir, so that the
+// source and destination databases of a round trip never share a path
+func createTransferTestDB(t *testing.T, name string) *database.DB {
+	t.Helper()
+	db, err := database.New(filepath.Join(t.TempDir(), name+".db"))
+	if err != nil {
+		t.Fatalf("Failed to create test database: %v", err)
+	}
+	t.Cleanup(func() { _ = database.Close(db) })
+	return db
+}
+
+func TestExportImportRoundTrip(t *testing.T) {
+	src := createTransferTestDB(t, "source")
+
+	sbom := []byte(`{"artifacts":[],"distro":{"name":"alpine"}}`)
+	vulns := []byte(`{"matches":[{"vulnerability":{"id":"CVE-2024-0001","severity":"High"},"artifact":{"name":"openssl","version":"3.0.0","type":"apk"}}],"descriptor":{"db":{"status":{"built":"2026-01-02T03:04:05Z"}}}}`)
+	if err := src.ImportScanResults(testTransferDigest, sbom, vulns, time.Time{}); err != nil {
+		t.Fatalf("Failed to seed scan results: %v", err)
+	}
+	// Only notes on the image's vulnerabilities are exported
+	for _, cve := range []string{"CVE-2024-0001", "CVE-1999-0001"} {
+		if _, err := src.SetCVEAnnotation(cve, "mitigated by WAF rule 123", "secops"); err != nil {
+			t.Fatalf("Failed to seed CVE annotation: %v", err)
+		}
+	}
+
+	cfg := TransferConfig{SigningKey: "secret", Source: "staging", DeploymentUUID: "uuid-1", Labels: labels.Set{"environment": "staging"}}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/export/images/"+testTransferDigest, nil)
+	w := httptest.NewRecorder()
+	ExportImageHandler(src, cfg)(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Export status = %d, body: %s", w.Code, w.Body.String())
+	}
+	exported := w.Body.Bytes()
+
+	bundle, err := transfer.Open(exported, cfg.SigningKey)
+	if err != nil {
+		t.Fatalf("Failed to open exported bundle: %v", err)
+	}
+	if bundle.Source != "staging" || bundle.DeploymentUUID != "uuid-1" || bundle.Labels["environment"] != "staging" || bundle.Image.Digest != testTransferDigest {
+		t.Errorf("Unexpected bundle metadata: %+v", bundle)
+	}
+	if bundle.Image.GrypeDBBuilt != "2026-01-02T03:04:05Z" {
+		t.Errorf("GrypeDBBuilt = %q", bundle.Image.GrypeDBBuilt)
+	}
+	if len(bundle.Annotations) != 1 || bundle.Annotations[0].CVEID != "CVE-2024-0001" {
+		t.Errorf("Annotations = %+v, want the note on CVE-2024-0001", bundle.Annotations)
+	}
+
+	dst := createTransferTestDB(t, "destination")
+
+	importBundle := func(body []byte, query string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/import"+query, bytes.NewReader(body))
+		w := httptest.NewRecorder()
+		ImportHandler(dst, cfg)(w, req)
+		return w
+	}
+
+	w = importBundle(exported, "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("Import status = %d, body: %s", w.Code, w.Body.String())
+	}
+	var result ImportResult
+	_ = json.Unmarshal(w.Body.Bytes(), &result)
+	if result.Status != "imported" || result.Annotations != 1 {
+		t.Errorf("Import result = %+v, want imported with 1 annotation", result)
+	}
+	if a, err := dst.GetCVEAnnotation("CVE-2024-0001"); err != nil || a == nil || a.Note != "mitigated by WAF rule 123" {
+		t.Errorf("Imported annotation = %+v, err=%v", a, err)
+	}
+
+	complete, err := dst.IsScanDataComplete(testTransferDigest)
+	if err != nil || !complete {
+		t.Fatalf("Imported image should have complete scan data (err=%v)", err)
+	}
+	imported, err := dst.GetSBOM(testTransferDigest)
+	if err != nil || !bytes.Equal(imported, sbom) {
+		t.Errorf("Imported SBOM = %s, err=%v", imported, err)
+	}
+
+	// Second import is skipped unless forced
+	w = importBundle(exported, "")
+	result = ImportResult{}
+	_ = json.Unmarshal(w.Body.Bytes(), &result)
+	if result.Status != "skipped" || result.Annotations != 0 {
+		t.Errorf("Second import result = %+v, want skipped without new annotations", result)
+	}
+	w = importBundle(exported, "?force=true")
+	_ = json.Unmarshal(w.Body.Bytes(), &result)
+	if result.Status != "imported" {
+		t.Errorf("Forced import result = %+v, want imported", result)
+	}
+}
+
+func TestExportPathRedaction(t *testing.T) {
+	src := createTransferTestDB(t, "source")
+
+	sbom := []byte(`{"artifacts":[{"name":"internal-lib","purl":"pkg:maven/com.example/internal-lib@1.2.3","locations":[{"path":"/opt/project-falcon/lib/internal-lib.jar"}]}]}`)
+	vulns := []byte(`{"matches":[{"vulnerability":{"id":"CVE-2024-0001","severity":"High"},"artifact":{"name":"internal-lib","version":"1.2.3","type":"java-archive","locations":[{"path":"/opt/project-falcon/lib/internal-lib.jar"}]}}]}`)
+	if err := src.ImportScanResults(testTransferDigest, sbom, vulns, time.Time{}); err != nil {
+		t.Fatalf("Failed to seed scan results: %v", err)
+	}
+
+	cfg := TransferConfig{SigningKey: "secret", PathRedaction: sbomformat.RedactStrip}
+	req := httptest.NewRequest(http.MethodGet, "/api/export/images/"+testTransferDigest, nil)
+	w := httptest.NewRecorder()
+	ExportImageHandler(src, cfg)(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Export status = %d, body: %s", w.Code, w.Body.String())
+	}
+	bundle, err := transfer.Open(w.Body.Bytes(), cfg.SigningKey)
+	if err != nil {
+		t.Fatalf("Failed to open exported bundle: %v", err)
+	}
+	for name, data := range map[string][]byte{"SBOM": bundle.SBOM, "vulnerabilities": bundle.Vulnerabilities} {
+		if strings.Contains(string(data), "project-falcon") || !strings.Contains(string(data), "internal-lib") {
+			t.Errorf("exported %s = %s, want paths redacted and packages kept", name, data)
+		}
+	}
+
+	// Stored scan results keep their paths
+	stored, err := src.GetSBOM(testTransferDigest)
+	if err != nil {
+		t.Fatalf("GetSBOM() error = %v", err)
+	}
+	if !strings.Contains(string(stored), "project-falcon") {
+		t.Errorf("stored SBOM was redacted: %s", stored)
+	}
+}
+
+func TestImportRejectsInvalidBundles(t *testing.T) {
+	db := createTransferTestDB(t, "import")
+
+	payload := []byte(`{"version":1,"image":{"digest":"` + testTransferDigest + `"},"sbom":{},"vulnerabilities":{}}`)
+	wrongKey, _ := json.Marshal(transfer.SignedBundle{Payload: payload, Signature: transfer.Sign("other", payload)})
+
+	tests := []struct {
+		name string
+		cfg  TransferConfig
+		body []byte
+		want int
+	}{
+		{"import disabled without key", TransferConfig{}, wrongKey, http.StatusForbidden},
+		{"wrong signature", TransferConfig{SigningKey: "secret"}, wrongKey, http.StatusUnauthorized},
+		{"malformed json", TransferConfig{SigningKey: "secret"}, []byte("not json"), http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/api/import", bytes.NewReader(tt.body))
+			w := httptest.NewRecorder()
+			ImportHandler(db, tt.cfg)(w, req)
+			if w.Code != tt.want {
+				t.Errorf("status = %d, want %d", w.Code, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,174 @@
+package handlers
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+
+	"github.com/bvboe/b2s-go/scanner-core/database"
+	"github.com/bvboe/b2s-go/scanner-core/routes"
+	"github.com/bvboe/b2s-go/scanner-core/vex"
+)
+
+// VEXStore stores OpenVEX documents and the findings they suppress
+type VEXStore interface {
+	GetVEXDocuments() ([]database.VEXDocument, error)
+	AddVEXDocument(data []byte) (*database.VEXDocument, int, error)
+	DeleteVEXDocument(id int64) (bool, int, error)
+	GetVEXSuppressions(digest string) ([]database.VEXSuppression, error)
+}
+
+// maxVEXDocumentSize bounds an uploaded OpenVEX document
+const maxVEXDocumentSize = 4 << 20
+
+// RegisterVEXHandlers registers the OpenVEX document and suppression endpoints
+func RegisterVEXHandlers(reg *routes.Registry, store VEXStore) {
+	reg.Handle(
+		routes.Route{Pattern: "/api/vex", Methods: routes.GET, Handler: VEXListHandler(store)},
+		routes.Route{Pattern: "/api/vex/upload", Methods: routes.POST, Handler: VEXUploadHandler(store), Role: routes.RoleAdmin},
+		routes.Route{Pattern: "/api/vex/delete", Methods: routes.POST, Handler: VEXDeleteHandler(store), Role: routes.RoleAdmin},
+		routes.Route{Pattern: "/api/vex/suppressions", Methods: routes.GET, Handler: VEXSuppressionsHandler(store)},
+	)
+}
+
+// VEXListHandler creates an HTTP handler for GET /api/vex. Returns the OpenVEX
+// documents in effect: those loaded from the configured path (file:<name>)
+// and those uploaded (api:<@id>).
+func VEXListHandler(store VEXStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		documents, err := store.GetVEXDocuments()
+		if err != nil {
+			log.Error("error querying VEX documents", "error", err)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(map[string]interface{}{
+			"documents": documents,
+			"count":     len(documents),
+		}); err != nil {
+			log.Error("error encoding VEX documents", "error", err)
+		}
+	}
+}
+
+// VEXUploadHandler creates an HTTP handler for POST /api/vex/upload. The body
+// is an OpenVEX document; it replaces an earlier upload with the same @id.
+// Findings its not_affected statements cover are removed from the stored
+// vulnerabilities at once and listed at /api/vex/suppressions.
+//
+// Response: {"document": {...}, "images_reevaluated": 3}
+func VEXUploadHandler(store VEXStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxVEXDocumentSize))
+		if err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		if _, err := vex.Parse(data); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		document, images, err := store.AddVEXDocument(data)
+		if err != nil {
+			log.Error("error storing VEX document", "error", err)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		log.Info("stored VEX document", "name", document.Name, "statements", document.Statements, "images_reevaluated", images)
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(map[string]interface{}{
+			"document":           document,
+			"images_reevaluated": images,
+		}); err != nil {
+			log.Error("error encoding VEX document", "error", err)
+		}
+	}
+}
+
+// VEXDeleteRequest is the body of POST /api/vex/delete
+type VEXDeleteRequest struct {
+	ID int64 `json:"id"`
+}
+
+// VEXDeleteHandler creates an HTTP handler for POST /api/vex/delete. Removes
+// an uploaded document; the findings it suppressed are restored. Documents
+// loaded from the configured path are removed there instead.
+//
+// Request: {"id": 3}
+// Response: {"deleted": true, "images_reevaluated": 3}
+func VEXDeleteHandler(store VEXStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		var req VEXDeleteRequest
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 4096)).Decode(&req); err != nil || req.ID <= 0 {
+			http.Error(w, "id is required", http.StatusBadRequest)
+			return
+		}
+
+		deleted, images, err := store.DeleteVEXDocument(req.ID)
+		if err != nil {
+			log.Error("error deleting VEX document", "id", req.ID, "error", err)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		if !deleted {
+			http.Error(w, "Uploaded VEX document not found", http.StatusNotFound)
+			return
+		}
+		log.Info("deleted VEX document", "id", req.ID, "images_reevaluated", images)
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(map[string]interface{}{
+			"deleted":            true,
+			"images_reevaluated": images,
+		}); err != nil {
+			log.Error("error encoding VEX response", "error", err)
+		}
+	}
+}
+
+// VEXSuppressionsHandler creates an HTTP handler for GET
+// /api/vex/suppressions: the audit trail of findings left out of the API,
+// summaries and metrics because a not_affected statement covers them, with the
+// statement's justification and document. ?digest= limits it to one image.
+func VEXSuppressionsHandler(store VEXStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		suppressions, err := store.GetVEXSuppressions(r.URL.Query().Get("digest"))
+		if err != nil {
+			log.Error("error querying VEX suppressions", "error", err)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(map[string]interface{}{
+			"suppressions": suppressions,
+			"count":        len(suppressions),
+		}); err != nil {
+			log.Error("error encoding VEX suppressions", "error", err)
+		}
+	}
+}
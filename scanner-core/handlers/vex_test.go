@@ -0,0 +1,115 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bvboe/b2s-go/scanner-core/containers"
+	"github.com/bvboe/b2s-go/scanner-core/routes"
+)
+
+func TestVEXHandlers(t *testing.T) {
+	db := createTransferTestDB(t, "vex")
+	if _, err := db.AddContainer(containers.Container{
+		ID:    containers.ContainerID{Namespace: "team-a", Pod: "web", Name: "app"},
+		Image: containers.ImageID{Reference: "registry.example.com/web:1", Digest: "sha256:web"},
+	}); err != nil {
+		t.Fatalf("AddContainer() error = %v", err)
+	}
+	vulns := []byte(`{"matches":[
+		{"vulnerability":{"id":"CVE-2024-3094","severity":"Critical"},"artifact":{"name":"xz-utils","version":"5.6.0","type":"deb"}},
+		{"vulnerability":{"id":"CVE-2023-0001","severity":"Medium"},"artifact":{"name":"openssl","version":"1.1.1","type":"deb"}}]}`)
+	if err := db.ImportScanResults("sha256:web", []byte(`{"artifacts":[]}`), vulns, time.Time{}); err != nil {
+		t.Fatalf("ImportScanResults() error = %v", err)
+	}
+
+	mux := routes.NewRegistry()
+	RegisterDatabaseHandlers(mux, db, nil)
+	RegisterVEXHandlers(mux, db)
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(method, path, strings.NewReader(body)))
+		return rec
+	}
+	vulnerabilities := func() []string {
+		t.Helper()
+		var list struct {
+			Vulnerabilities []map[string]interface{} `json:"vulnerabilities"`
+		}
+		if err := json.NewDecoder(do(http.MethodGet, "/api/vulnerabilities", "").Body).Decode(&list); err != nil {
+			t.Fatalf("failed to decode vulnerabilities: %v", err)
+		}
+		var ids []string
+		for _, v := range list.Vulnerabilities {
+			ids = append(ids, v["vulnerability_id"].(string))
+		}
+		return ids
+	}
+	if ids := vulnerabilities(); len(ids) != 2 {
+		t.Fatalf("vulnerabilities before VEX = %v", ids)
+	}
+
+	if rec := do(http.MethodPost, "/api/vex/upload", `{"@context": "https://openvex.dev/ns/v0.2.0", "statements": []}`); rec.Code != http.StatusBadRequest {
+		t.Errorf("upload of an invalid document: status = %d, want 400", rec.Code)
+	}
+	rec := do(http.MethodPost, "/api/vex/upload", `{"@context": "https://openvex.dev/ns/v0.2.0", "@id": "vendor-1",
+		"statements": [{"vulnerability": {"name": "CVE-2024-3094"}, "products": [{"@id": "registry.example.com/web"}],
+		"status": "not_affected", "justification": "vulnerable_code_not_in_execute_path"}]}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("upload: status = %d: %s", rec.Code, rec.Body.String())
+	}
+	var uploaded struct {
+		Document struct {
+			ID   int64  `json:"id"`
+			Name string `json:"name"`
+		} `json:"document"`
+		Images int `json:"images_reevaluated"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&uploaded); err != nil {
+		t.Fatalf("failed to decode upload: %v", err)
+	}
+	if uploaded.Document.Name != "api:vendor-1" || uploaded.Images != 1 {
+		t.Errorf("upload response = %+v", uploaded)
+	}
+
+	// Suppressed findings are left out of the API and kept in the audit trail
+	if ids := vulnerabilities(); len(ids) != 1 || ids[0] != "CVE-2023-0001" {
+		t.Errorf("vulnerabilities after VEX = %v, want CVE-2023-0001 only", ids)
+	}
+	var trail struct {
+		Suppressions []map[string]interface{} `json:"suppressions"`
+		Count        int                      `json:"count"`
+	}
+	if err := json.NewDecoder(do(http.MethodGet, "/api/vex/suppressions?digest=sha256:web", "").Body).Decode(&trail); err != nil {
+		t.Fatalf("failed to decode suppressions: %v", err)
+	}
+	if trail.Count != 1 || trail.Suppressions[0]["cve_id"] != "CVE-2024-3094" ||
+		trail.Suppressions[0]["justification"] != "vulnerable_code_not_in_execute_path" || trail.Suppressions[0]["source"] != "api:vendor-1" {
+		t.Errorf("unexpected suppressions: %+v", trail)
+	}
+	var listed struct {
+		Count int `json:"count"`
+	}
+	if err := json.NewDecoder(do(http.MethodGet, "/api/vex", "").Body).Decode(&listed); err != nil || listed.Count != 1 {
+		t.Errorf("GET /api/vex = %+v, %v", listed, err)
+	}
+
+	if rec := do(http.MethodPost, "/api/vex/delete", `{"id": 999}`); rec.Code != http.StatusNotFound {
+		t.Errorf("delete of a missing document: status = %d, want 404", rec.Code)
+	}
+	if rec := do(http.MethodPost, "/api/vex/delete", `{}`); rec.Code != http.StatusBadRequest {
+		t.Errorf("delete without id: status = %d, want 400", rec.Code)
+	}
+	body, _ := json.Marshal(VEXDeleteRequest{ID: uploaded.Document.ID})
+	if rec := do(http.MethodPost, "/api/vex/delete", string(body)); rec.Code != http.StatusOK {
+		t.Fatalf("delete: status = %d: %s", rec.Code, rec.Body.String())
+	}
+	if ids := vulnerabilities(); len(ids) != 2 {
+		t.Errorf("vulnerabilities after deleting the document = %v, want both", ids)
+	}
+}
@@ -0,0 +1,133 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/bvboe/b2s-go/scanner-core/jobs"
+	"github.com/bvboe/b2s-go/scanner-core/routes"
+	"github.com/bvboe/b2s-go/scanner-core/scheduler"
+	"github.com/bvboe/b2s-go/scanner-core/vulndb"
+)
+
+// VulnDBStatus is the response of GET /api/vulndb
+type VulnDBStatus struct {
+	Available      bool       `json:"available"`
+	Built          *time.Time `json:"built,omitempty"`
+	SchemaVersion  string     `json:"schema_version,omitempty"`
+	LastCheck      *time.Time `json:"last_check,omitempty"`
+	LastCheckError string     `json:"last_check_error,omitempty"`
+	NextCheck      *time.Time `json:"next_check,omitempty"` // next run of the rescan-database job
+	Checking       bool       `json:"checking"`
+	ReadOnly       bool       `json:"read_only"` // another replica updates the database
+}
+
+// vulnDBStatus combines the feed status with the schedule of the next check
+func vulnDBStatus(checker vulndb.FeedChecker, sched *scheduler.Scheduler) VulnDBStatus {
+	feed := checker.FeedStatus()
+	status := VulnDBStatus{
+		Checking:       feed.Checking,
+		ReadOnly:       feed.ReadOnly,
+		LastCheckError: feed.LastError,
+	}
+	if feed.Database != nil {
+		built := feed.Database.Built.UTC()
+		status.Available = true
+		status.Built = &built
+		status.SchemaVersion = feed.Database.SchemaVersion
+	}
+	if !feed.LastCheck.IsZero() {
+		lastCheck := feed.LastCheck.UTC()
+		status.LastCheck = &lastCheck
+	}
+	if sched != nil {
+		if next, err := sched.GetNextRun(jobs.RescanDatabaseJobName); err == nil && !next.IsZero() {
+			next = next.UTC()
+			status.NextCheck = &next
+		}
+	}
+	return status
+}
+
+// VulnDBStatusHandler handles GET /api/vulndb - reports the build date and
+// schema version of the vulnerability database in use, when the feed was last
+// checked and when the next scheduled check runs
+//
+// Response: {"available": true, "built": "...", "schema_version": "v6.0.2", "last_check": "...", "next_check": "...", "checking": false, "read_only": false}
+func VulnDBStatusHandler(checker vulndb.FeedChecker, sched *scheduler.Scheduler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		if checker == nil {
+			http.Error(w, "Vulnerability database updates not configured", http.StatusServiceUnavailable)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(vulnDBStatus(checker, sched)); err != nil {
+			log.Error("error encoding vulnerability database status response", "error", err)
+		}
+	}
+}
+
+// VulnDBRefreshHandler handles POST /api/vulndb/refresh - checks the feed now
+// and downloads a newer database if there is one. When the database changed,
+// the rescan-database job is started to rescan images scanned with the old
+// one. Returns 409 if a check is already running and 502 if the check fails.
+//
+// Response: {"updated": true, "status": {...}} (status as GET /api/vulndb)
+func VulnDBRefreshHandler(checker vulndb.FeedChecker, sched *scheduler.Scheduler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		if checker == nil {
+			http.Error(w, "Vulnerability database updates not configured", http.StatusServiceUnavailable)
+			return
+		}
+
+		if checker.FeedStatus().Checking {
+			http.Error(w, "A vulnerability database check is already running", http.StatusConflict)
+			return
+		}
+
+		log.Info("vulnerability database refresh requested")
+		updated, err := checker.CheckForUpdates(r.Context())
+		if err != nil {
+			log.Error("vulnerability database refresh failed", "error", err)
+			http.Error(w, "Failed to refresh vulnerability database: "+err.Error(), http.StatusBadGateway)
+			return
+		}
+
+		if updated && sched != nil {
+			if err := sched.RunJobNow(jobs.RescanDatabaseJobName); err != nil && !errors.Is(err, scheduler.ErrJobRunning) {
+				log.Warn("failed to start rescan after vulnerability database refresh", "error", err)
+			}
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(map[string]interface{}{
+			"updated": updated,
+			"status":  vulnDBStatus(checker, sched),
+		}); err != nil {
+			log.Error("error encoding vulnerability database refresh response", "error", err)
+		}
+	}
+}
+
+// RegisterVulnDBHandlers registers the vulnerability database status and
+// refresh endpoints. checker is nil when database updates are disabled, sched
+// when scheduled jobs are.
+func RegisterVulnDBHandlers(reg *routes.Registry, checker vulndb.FeedChecker, sched *scheduler.Scheduler) {
+	reg.Handle(
+		routes.Route{Pattern: "/api/vulndb", Methods: routes.GET, Handler: VulnDBStatusHandler(checker, sched), CacheControl: routes.NoStore},
+		routes.Route{Pattern: "/api/vulndb/refresh", Methods: routes.POST, Handler: VulnDBRefreshHandler(checker, sched), Role: routes.RoleAdmin},
+	)
+}
@@ -0,0 +1,137 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/bvboe/b2s-go/scanner-core/jobs"
+	"github.com/bvboe/b2s-go/scanner-core/routes"
+	"github.com/bvboe/b2s-go/scanner-core/scheduler"
+	"github.com/bvboe/b2s-go/scanner-core/vulndb"
+)
+
+// fakeFeedChecker reports status and returns updated/err from CheckForUpdates
+type fakeFeedChecker struct {
+	status  vulndb.FeedStatus
+	updated bool
+	err     error
+	checks  int
+}
+
+func (f *fakeFeedChecker) CheckForUpdates(ctx context.Context) (bool, error) {
+	f.checks++
+	f.status.LastCheck = time.Now()
+	return f.updated, f.err
+}
+
+func (f *fakeFeedChecker) FeedStatus() vulndb.FeedStatus { return f.status }
+
+// signalJob stands in for the rescan-database job and signals every run
+type signalJob struct {
+	ran chan struct{}
+}
+
+func (j *signalJob) Name() string { return jobs.RescanDatabaseJobName }
+
+func (j *signalJob) Run(ctx context.Context) error {
+	j.ran <- struct{}{}
+	return nil
+}
+
+func TestVulnDBHandlers(t *testing.T) {
+	sched := scheduler.New()
+	job := &signalJob{ran: make(chan struct{}, 1)}
+	if err := sched.AddJob(job, scheduler.NewIntervalSchedule(time.Hour), scheduler.JobConfig{Enabled: true}); err != nil {
+		t.Fatalf("failed to add job: %v", err)
+	}
+	if err := sched.Start(context.Background()); err != nil {
+		t.Fatalf("failed to start scheduler: %v", err)
+	}
+	defer func() { _ = sched.Stop() }()
+
+	built := time.Date(2026, 10, 16, 4, 0, 0, 0, time.UTC)
+	checker := &fakeFeedChecker{status: vulndb.FeedStatus{Database: &vulndb.DatabaseStatus{Built: built, SchemaVersion: "v6.0.2"}}}
+	reg := routes.NewRegistry()
+	RegisterVulnDBHandlers(reg, checker, sched)
+	do := func(method, path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		reg.ServeHTTP(rec, httptest.NewRequest(method, path, nil))
+		return rec
+	}
+
+	rec := do(http.MethodGet, "/api/vulndb")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var status VulnDBStatus
+	if err := json.NewDecoder(rec.Body).Decode(&status); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if !status.Available || status.Built == nil || !status.Built.Equal(built) || status.SchemaVersion != "v6.0.2" {
+		t.Errorf("unexpected database in status: %+v", status)
+	}
+	if status.LastCheck != nil {
+		t.Errorf("expected no last check before the first one, got %v", status.LastCheck)
+	}
+	if status.NextCheck == nil || time.Until(*status.NextCheck) < 50*time.Minute {
+		t.Errorf("expected the next check in about an hour, got %v", status.NextCheck)
+	}
+
+	checker.status.Checking = true
+	if rec := do(http.MethodPost, "/api/vulndb/refresh"); rec.Code != http.StatusConflict {
+		t.Errorf("expected 409 while a check is running, got %d", rec.Code)
+	}
+	checker.status.Checking = false
+
+	checker.err = errors.New("feed unreachable")
+	if rec := do(http.MethodPost, "/api/vulndb/refresh"); rec.Code != http.StatusBadGateway {
+		t.Errorf("expected 502 for a failed check, got %d", rec.Code)
+	}
+	checker.err = nil
+
+	checker.updated = true
+	rec = do(http.MethodPost, "/api/vulndb/refresh")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200 for refresh, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var refresh struct {
+		Updated bool         `json:"updated"`
+		Status  VulnDBStatus `json:"status"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&refresh); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if !refresh.Updated || refresh.Status.LastCheck == nil {
+		t.Errorf("unexpected refresh response: %+v", refresh)
+	}
+	if checker.checks != 2 {
+		t.Errorf("expected 2 feed checks, got %d", checker.checks)
+	}
+	select {
+	case <-job.ran:
+	case <-time.After(5 * time.Second):
+		t.Error("expected the rescan-database job to run after an update")
+	}
+}
+
+func TestVulnDBHandlersWithoutChecker(t *testing.T) {
+	reg := routes.NewRegistry()
+	RegisterVulnDBHandlers(reg, nil, nil)
+
+	for _, method := range []string{http.MethodGet, http.MethodPost} {
+		path := "/api/vulndb"
+		if method == http.MethodPost {
+			path += "/refresh"
+		}
+		rec := httptest.NewRecorder()
+		reg.ServeHTTP(rec, httptest.NewRequest(method, path, nil))
+		if rec.Code != http.StatusServiceUnavailable {
+			t.Errorf("%s %s: expected 503 without a feed checker, got %d", method, path, rec.Code)
+		}
+	}
+}
@@ -0,0 +1,354 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// severityRankCase ranks v.severity from most (1) to least (6) severe
+const severityRankCase = `CASE v.severity
+        WHEN 'Critical' THEN 1
+        WHEN 'High' THEN 2
+        WHEN 'Medium' THEN 3
+        WHEN 'Low' THEN 4
+        WHEN 'Negligible' THEN 5
+        ELSE 6
+    END`
+
+// severityFromRank maps a severityRankCase rank back to the severity name
+const severityFromRank = `CASE severity_rank
+        WHEN 1 THEN 'Critical'
+        WHEN 2 THEN 'High'
+        WHEN 3 THEN 'Medium'
+        WHEN 4 THEN 'Low'
+        WHEN 5 THEN 'Negligible'
+        ELSE 'Unknown'
+    END`
+
+// VulnerabilityListHandler creates an HTTP handler for the /api/vulnerabilities
+// endpoint. It pivots the findings of all running containers by vulnerability:
+// one row per vulnerability ID with its highest severity, risk, EPSS and KEV
+// status, whether a fix is available, and how many images, containers, pods
+// and namespaces it affects. Only images with >=1 running container are
+// included (enforced by the JOIN on the containers table).
+func VulnerabilityListHandler(provider ImageQueryProvider) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		params := r.URL.Query()
+		format := params.Get("format")
+
+		// Pagination (skip for CSV export - export all data)
+		page, _ := strconv.Atoi(params.Get("page"))
+		if page < 1 {
+			page = 1
+		}
+		pageSize, _ := strconv.Atoi(params.Get("pageSize"))
+		if pageSize < 1 || pageSize > 10000 {
+			pageSize = 100
+		}
+		offset := (page - 1) * pageSize
+		if format == "csv" {
+			pageSize = -1
+			offset = 0
+		}
+
+		filter := vulnerabilityListFilter{
+			namespaces:     parseMultiSelect(params.Get("namespaces")),
+			osNames:        parseMultiSelect(params.Get("osNames")),
+			severities:     parseMultiSelect(params.Get("severity")),
+			fixStatuses:    parseMultiSelect(params.Get("vulnStatuses")),
+			packageTypes:   parseMultiSelect(params.Get("packageTypes")),
+			vulnerability:  params.Get("vulnerability"),
+			knownExploited: params.Get("knownExploited") == "true",
+			fixAvailable:   params.Get("fixAvailable") == "true",
+		}
+
+		sortBy := params.Get("sortBy")
+		sortOrder := strings.ToUpper(params.Get("sortOrder"))
+		if sortOrder != "ASC" && sortOrder != "DESC" {
+			sortOrder = "ASC"
+		}
+
+		query, countQuery, args := buildVulnerabilityListQuery(filter, sortBy, sortOrder, pageSize, offset)
+
+		countResult, err := provider.ExecuteReadOnlyQueryArgs(countQuery, args...)
+		if err != nil {
+			log.Error("error executing vulnerability count query", "error", err)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		totalCount := int64(0)
+		if len(countResult.Rows) > 0 && len(countResult.Columns) > 0 {
+			if count, ok := countResult.Rows[0][countResult.Columns[0]].(int64); ok {
+				totalCount = count
+			}
+		}
+
+		result, err := provider.ExecuteReadOnlyQueryArgs(query, args...)
+		if err != nil {
+			log.Error("error executing vulnerability query", "error", err)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+
+		if format == "csv" {
+			exportQueryResultAsCSV(w, r, result, "vulnerabilities.csv")
+			return
+		}
+
+		totalPages := 0
+		if pageSize > 0 {
+			totalPages = int(math.Ceil(float64(totalCount) / float64(pageSize)))
+		}
+		response := map[string]interface{}{
+			"vulnerabilities": result.Rows,
+			"page":            page,
+			"pageSize":        pageSize,
+			"totalCount":      totalCount,
+			"totalPages":      totalPages,
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(response); err != nil {
+			log.Error("error encoding vulnerabilities response", "error", err)
+		}
+	}
+}
+
+// vulnerabilityListFilter holds the filters of the vulnerability listing.
+// knownExploited and fixAvailable apply to the vulnerability as a whole, the
+// others select the findings it is aggregated from.
+type vulnerabilityListFilter struct {
+	namespaces, osNames, severities, fixStatuses, packageTypes []string
+	vulnerability                                              string
+	knownExploited, fixAvailable                               bool
+}
+
+// buildVulnerabilityListQuery builds the per-vulnerability listing query, its
+// count query and the arguments bound to both
+func buildVulnerabilityListQuery(filter vulnerabilityListFilter, sortBy, sortOrder string, limit, offset int) (string, string, []interface{}) {
+	var args queryArgs
+	var conditions []string
+	conditions = appendCondition(conditions, args.in("c.namespace", filter.namespaces))
+	conditions = appendCondition(conditions, args.in("i.os_name", filter.osNames))
+	conditions = appendCondition(conditions, args.in("v.severity", filter.severities))
+	conditions = appendCondition(conditions, args.in("v.fix_status", filter.fixStatuses))
+	conditions = appendCondition(conditions, args.in("v.package_type", filter.packageTypes))
+	conditions = appendCondition(conditions, args.vulnerabilityID("v.cve_id", filter.vulnerability))
+
+	var having []string
+	if filter.knownExploited {
+		having = append(having, "MAX(v.known_exploited) > 0")
+	}
+	if filter.fixAvailable {
+		having = append(having, "MAX(CASE WHEN v.fix_status = 'fixed' THEN 1 ELSE 0 END) = 1")
+	}
+	havingClause := ""
+	if len(having) > 0 {
+		havingClause = "\nHAVING " + strings.Join(having, " AND ")
+	}
+
+	// One row per vulnerability across the findings of deployed images
+	grouped := fmt.Sprintf(`
+    SELECT
+        v.cve_id as vulnerability_id,
+        MIN(%s) as severity_rank,
+        MAX(v.risk) as risk,
+        MAX(v.epss_score) as epss_score,
+        MAX(v.epss_percentile) as epss_percentile,
+        MAX(CASE WHEN v.known_exploited > 0 THEN 1 ELSE 0 END) as known_exploited,
+        MAX(CASE WHEN v.fix_status = 'fixed' THEN 1 ELSE 0 END) as fix_available,
+        GROUP_CONCAT(DISTINCT NULLIF(v.fixed_version, '')) as fixed_versions,
+        GROUP_CONCAT(DISTINCT v.package_name) as packages,
+        COUNT(DISTINCT i.id) as affected_images,
+        COUNT(DISTINCT c.id) as affected_containers,
+        COUNT(DISTINCT c.namespace || '/' || c.pod) as affected_pods,
+        COUNT(DISTINCT c.namespace) as affected_namespaces
+    FROM image_vulnerabilities v
+    JOIN images i ON v.image_id = i.id
+    JOIN containers c ON c.image_id = i.id
+    WHERE 1=1%s
+    GROUP BY v.cve_id%s`, severityRankCase, buildWhereClause(conditions), havingClause)
+
+	countQuery := "SELECT COUNT(*) FROM (" + grouped + "\n) sub"
+
+	mainQuery := `SELECT
+    vulnerability_id,
+    ` + severityFromRank + ` as severity,
+    risk, epss_score, epss_percentile, known_exploited, fix_available, fixed_versions, packages,
+    affected_images, affected_containers, affected_pods, affected_namespaces,
+    ` + vulnerabilityAliasesColumn("vulnerability_id") + `,
+    ` + cveAnnotationColumn("vulnerability_id") + `
+FROM (` + grouped + `
+) sub
+ORDER BY `
+
+	// User column first, then severity, then the most widespread, then ID
+	validSortColumns := map[string]bool{
+		"vulnerability_id": true, "risk": true, "epss_score": true, "epss_percentile": true,
+		"known_exploited": true, "fix_available": true, "affected_images": true,
+		"affected_containers": true, "affected_pods": true, "affected_namespaces": true,
+	}
+	switch {
+	case sortBy == "severity":
+		mainQuery += "severity_rank " + sortOrder + ", "
+	case validSortColumns[sortBy]:
+		mainQuery += sortBy + " " + sortOrder + ", severity_rank ASC, "
+	default:
+		mainQuery += "severity_rank ASC, "
+	}
+	mainQuery += "affected_containers DESC, vulnerability_id ASC"
+
+	if limit > 0 {
+		mainQuery += fmt.Sprintf("\nLIMIT %d OFFSET %d", limit, offset)
+	}
+	return mainQuery, countQuery, args
+}
+
+// VulnerabilityDetailHandler creates an HTTP handler for the
+// /api/vulnerabilities/{cve} endpoint. It reports a vulnerability across all
+// running containers: its severity, EPSS, KEV and fix status, the affected
+// packages, the affected images, the workloads running them and the analyst
+// notes attached to it. The ID also matches the vulnerability's aliases, so a
+// CVE finds findings reported under a vendor advisory and vice versa. Returns
+// 404 when no running image has it.
+func VulnerabilityDetailHandler(provider ImageQueryProvider) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		cve, err := url.PathUnescape(strings.TrimPrefix(r.URL.Path, "/api/vulnerabilities/"))
+		if err != nil || strings.TrimSpace(cve) == "" {
+			http.Error(w, "Vulnerability ID required", http.StatusBadRequest)
+			return
+		}
+
+		queries, args := buildVulnerabilityDetailQueries(cve)
+		results := make(map[string][]map[string]interface{}, len(queries))
+		for _, name := range []string{"summary", "packages", "images", "workloads", "annotations"} {
+			result, err := provider.ExecuteReadOnlyQueryArgs(queries[name], args...)
+			if err != nil {
+				log.Error("error executing vulnerability detail query", "query", name, "cve", cve, "error", err)
+				http.Error(w, "Internal server error", http.StatusInternalServerError)
+				return
+			}
+			results[name] = result.Rows
+		}
+
+		summary := results["summary"]
+		if len(summary) == 0 || getIntValue(summary[0], "affected_containers") == 0 {
+			http.Error(w, "Vulnerability not found in any running image", http.StatusNotFound)
+			return
+		}
+
+		response := map[string]interface{}{"vulnerability_id": strings.ToUpper(strings.TrimSpace(cve))}
+		for k, v := range summary[0] {
+			response[k] = v
+		}
+		delete(response, "severity_rank")
+		response["known_exploited"] = getIntValue(summary[0], "known_exploited") > 0
+		response["fix_available"] = getIntValue(summary[0], "fix_available") > 0
+		response["packages"] = results["packages"]
+		response["images"] = results["images"]
+		response["workloads"] = results["workloads"]
+		response["annotations"] = results["annotations"]
+		if results["annotations"] == nil {
+			response["annotations"] = []map[string]interface{}{}
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(response); err != nil {
+			log.Error("error encoding vulnerability detail response", "error", err)
+		}
+	}
+}
+
+// buildVulnerabilityDetailQueries builds the summary, packages, images and
+// workloads queries of a vulnerability, the annotations query of its analyst
+// notes (on the ID or any of its aliases) and the arguments bound to all of them
+func buildVulnerabilityDetailQueries(cve string) (map[string]string, []interface{}) {
+	var args queryArgs
+	match := args.vulnerabilityID("v.cve_id", cve)
+	// Binds the same single argument as match, so all queries share args
+	var annotationArgs queryArgs
+	annotated := annotationArgs.vulnerabilityID("a.cve_id", cve)
+
+	from := `
+FROM image_vulnerabilities v
+JOIN images i ON v.image_id = i.id
+JOIN containers c ON c.image_id = i.id
+WHERE ` + match
+
+	queries := map[string]string{
+		"summary": `
+SELECT *, ` + severityFromRank + ` as severity
+FROM (
+    SELECT
+        GROUP_CONCAT(DISTINCT v.cve_id) as matched_ids,
+        MIN(` + severityRankCase + `) as severity_rank,
+        MAX(v.risk) as risk,
+        MAX(v.epss_score) as epss_score,
+        MAX(v.epss_percentile) as epss_percentile,
+        MAX(CASE WHEN v.known_exploited > 0 THEN 1 ELSE 0 END) as known_exploited,
+        MAX(CASE WHEN v.fix_status = 'fixed' THEN 1 ELSE 0 END) as fix_available,
+        GROUP_CONCAT(DISTINCT NULLIF(v.fixed_version, '')) as fixed_versions,
+        COUNT(DISTINCT i.id) as affected_images,
+        COUNT(DISTINCT c.id) as affected_containers,
+        COUNT(DISTINCT c.namespace || '/' || c.pod) as affected_pods,
+        COUNT(DISTINCT c.namespace) as affected_namespaces,
+        COUNT(DISTINCT NULLIF(c.node_name, '')) as affected_nodes` + from + `
+) sub`,
+
+		"packages": `
+SELECT
+    v.cve_id as vulnerability_id,
+    v.package_name as package_name,
+    v.package_version as package_version,
+    v.package_type as package_type,
+    v.severity as severity,
+    v.fix_status as fix_status,
+    v.fixed_version as fixed_version,
+    COUNT(DISTINCT i.id) as affected_images,
+    COUNT(DISTINCT c.id) as affected_containers` + from + `
+GROUP BY v.cve_id, v.package_name, v.package_version, v.package_type, v.severity, v.fix_status, v.fixed_version
+ORDER BY affected_containers DESC, v.package_name ASC, v.package_version ASC`,
+
+		"images": `
+SELECT
+    i.digest as digest,
+    MIN(c.reference) as reference,
+    i.os_name as os_name,
+    i.oci_source as oci_source,
+    i.oci_version as oci_version,
+    MAX(CASE WHEN v.fix_status = 'fixed' THEN 1 ELSE 0 END) as fix_available,
+    COUNT(DISTINCT c.id) as affected_containers,
+    GROUP_CONCAT(DISTINCT c.namespace) as namespaces` + from + `
+GROUP BY i.id
+ORDER BY affected_containers DESC, reference ASC`,
+
+		"workloads": `
+SELECT DISTINCT
+    c.namespace as namespace,
+    c.pod as pod,
+    c.name as container,
+    c.node_name as node_name,
+    c.reference as reference,
+    i.digest as digest` + from + `
+ORDER BY c.namespace, c.pod, c.name`,
+
+		"annotations": `
+SELECT a.cve_id as cve_id, a.note as note, a.author as author, a.updated_at as updated_at
+FROM cve_annotations a
+WHERE ` + annotated + `
+ORDER BY a.cve_id`,
+	}
+	return queries, args
+}
@@ -84,7 +84,7 @@ func TestVulnerabilitiesSorting(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			// Build query with dummy image digest
-			query, _ := buildImageVulnerabilitiesQuery("test-digest", nil, nil, nil, tt.sortBy, tt.sortOrder, 100, 0)
+			query, _, _ := buildImageVulnerabilitiesQuery("test-digest", nil, nil, nil, "", tt.sortBy, tt.sortOrder, 100, 0)
 
 			// Check all expected strings are present
 			for _, expected := range tt.expectedContains {
@@ -149,7 +149,7 @@ func TestVulnerabilitiesSortingOrder(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			query, _ := buildImageVulnerabilitiesQuery("test-digest", nil, nil, nil, tt.sortBy, tt.sortOrder, 100, 0)
+			query, _, _ := buildImageVulnerabilitiesQuery("test-digest", nil, nil, nil, "", tt.sortBy, tt.sortOrder, 100, 0)
 
 			// Find ORDER BY clause
 			orderByIndex := strings.Index(query, "ORDER BY")
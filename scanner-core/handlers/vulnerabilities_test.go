@@ -0,0 +1,188 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/bvboe/b2s-go/scanner-core/containers"
+	"github.com/bvboe/b2s-go/scanner-core/database"
+	"github.com/bvboe/b2s-go/scanner-core/routes"
+)
+
+// createVulnerabilityExplorerTestDB seeds CVE-2024-3094 (xz, KEV, fixable) in
+// two running images and CVE-2023-0001 (openssl, no fix) in one
+func createVulnerabilityExplorerTestDB(t *testing.T) *database.DB {
+	t.Helper()
+	db := createTransferTestDB(t, "vulnerabilities")
+
+	addContainer := func(namespace, pod, node, reference, digest string) {
+		t.Helper()
+		if _, err := db.AddContainer(containers.Container{
+			ID:       containers.ContainerID{Namespace: namespace, Pod: pod, Name: "app"},
+			Image:    containers.ImageID{Reference: reference, Digest: digest},
+			NodeName: node,
+		}); err != nil {
+			t.Fatalf("AddContainer() error = %v", err)
+		}
+	}
+	addContainer("team-a", "web-1", "node-1", "web:1", "sha256:web")
+	addContainer("team-a", "web-2", "node-2", "web:1", "sha256:web")
+	addContainer("team-b", "api", "node-1", "api:1", "sha256:api")
+
+	conn := db.GetConnection()
+	exec := func(query string, args ...interface{}) {
+		t.Helper()
+		if _, err := conn.Exec(query, args...); err != nil {
+			t.Fatalf("seed failed: %v", err)
+		}
+	}
+	insert := `INSERT INTO image_vulnerabilities (image_id, cve_id, package_name, package_version, package_type, severity, fix_status, fixed_version, count, risk, epss_score, epss_percentile, known_exploited)
+		SELECT id, ?, ?, ?, 'deb', ?, ?, ?, 1, ?, ?, ?, ? FROM images WHERE digest = ?`
+	exec(insert, "CVE-2024-3094", "xz-utils", "5.6.0", "Critical", "fixed", "5.6.1", 9.5, 0.85, 0.99, 1, "sha256:web")
+	exec(insert, "CVE-2024-3094", "liblzma5", "5.6.0", "High", "not-fixed", "", 7.0, 0.85, 0.99, 1, "sha256:api")
+	exec(insert, "CVE-2023-0001", "openssl", "1.1.1", "Medium", "wont-fix", "", 1.5, 0.01, 0.2, 0, "sha256:api")
+	exec(`INSERT INTO vulnerability_aliases (vulnerability_id, alias_id) VALUES ('CVE-2024-3094', 'GHSA-XZ')`)
+	return db
+}
+
+func TestVulnerabilityListHandler(t *testing.T) {
+	db := createVulnerabilityExplorerTestDB(t)
+
+	list := func(t *testing.T, query string) []map[string]interface{} {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		VulnerabilityListHandler(db)(rec, httptest.NewRequest(http.MethodGet, "/api/vulnerabilities?"+query, nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("status = %d: %s", rec.Code, rec.Body.String())
+		}
+		var response struct {
+			Vulnerabilities []map[string]interface{} `json:"vulnerabilities"`
+			TotalCount      int64                    `json:"totalCount"`
+		}
+		if err := json.NewDecoder(rec.Body).Decode(&response); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		if response.TotalCount != int64(len(response.Vulnerabilities)) {
+			t.Errorf("totalCount = %d, want %d", response.TotalCount, len(response.Vulnerabilities))
+		}
+		return response.Vulnerabilities
+	}
+
+	t.Run("one row per vulnerability", func(t *testing.T) {
+		rows := list(t, "")
+		if len(rows) != 2 {
+			t.Fatalf("expected 2 vulnerabilities, got %v", rows)
+		}
+		xz := rows[0]
+		want := map[string]interface{}{
+			"vulnerability_id": "CVE-2024-3094", "severity": "Critical", "risk": 9.5,
+			"epss_score": 0.85, "known_exploited": float64(1), "fix_available": float64(1),
+			"fixed_versions": "5.6.1", "affected_images": float64(2), "affected_containers": float64(3),
+			"affected_pods": float64(3), "affected_namespaces": float64(2), "vulnerability_aliases": "GHSA-XZ",
+		}
+		for k, v := range want {
+			if xz[k] != v {
+				t.Errorf("%s = %v, want %v", k, xz[k], v)
+			}
+		}
+		if rows[1]["vulnerability_id"] != "CVE-2023-0001" || rows[1]["fix_available"] != float64(0) {
+			t.Errorf("unexpected second row: %v", rows[1])
+		}
+	})
+
+	t.Run("filters", func(t *testing.T) {
+		tests := []struct {
+			query string
+			want  []string
+		}{
+			{"namespaces=team-a", []string{"CVE-2024-3094"}},
+			{"knownExploited=true", []string{"CVE-2024-3094"}},
+			{"fixAvailable=true&namespaces=team-b", nil},
+			{"severity=Medium", []string{"CVE-2023-0001"}},
+			{"vulnerability=ghsa-xz", []string{"CVE-2024-3094"}},
+			{"sortBy=risk&sortOrder=asc", []string{"CVE-2023-0001", "CVE-2024-3094"}},
+		}
+		for _, tt := range tests {
+			var got []string
+			for _, row := range list(t, tt.query) {
+				got = append(got, row["vulnerability_id"].(string))
+			}
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("%s: got %v, want %v", tt.query, got, tt.want)
+			}
+		}
+	})
+
+	t.Run("CSV export", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		VulnerabilityListHandler(db)(rec, httptest.NewRequest(http.MethodGet, "/api/vulnerabilities?format=csv", nil))
+		records, err := csv.NewReader(rec.Body).ReadAll()
+		if err != nil {
+			t.Fatalf("failed to parse CSV: %v", err)
+		}
+		if len(records) != 3 {
+			t.Errorf("expected header and 2 rows, got %d records", len(records))
+		}
+	})
+}
+
+func TestVulnerabilityDetailHandler(t *testing.T) {
+	db := createVulnerabilityExplorerTestDB(t)
+	mux := routes.NewRegistry()
+	RegisterDatabaseHandlers(mux, db, nil)
+
+	get := func(path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		return rec
+	}
+
+	for _, path := range []string{"/api/vulnerabilities/CVE-2024-3094", "/api/vulnerabilities/ghsa-xz"} {
+		rec := get(path)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%s: status = %d: %s", path, rec.Code, rec.Body.String())
+		}
+		var response struct {
+			Severity           string                   `json:"severity"`
+			KnownExploited     bool                     `json:"known_exploited"`
+			FixAvailable       bool                     `json:"fix_available"`
+			EPSSPercentile     float64                  `json:"epss_percentile"`
+			AffectedNamespaces int64                    `json:"affected_namespaces"`
+			AffectedNodes      int64                    `json:"affected_nodes"`
+			Packages           []map[string]interface{} `json:"packages"`
+			Images             []map[string]interface{} `json:"images"`
+			Workloads          []map[string]interface{} `json:"workloads"`
+		}
+		if err := json.NewDecoder(rec.Body).Decode(&response); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		if response.Severity != "Critical" || !response.KnownExploited || !response.FixAvailable || response.EPSSPercentile != 0.99 {
+			t.Errorf("%s: unexpected summary: %+v", path, response)
+		}
+		if response.AffectedNamespaces != 2 || response.AffectedNodes != 2 {
+			t.Errorf("%s: affected namespaces = %d, nodes = %d; want 2, 2", path, response.AffectedNamespaces, response.AffectedNodes)
+		}
+		if len(response.Packages) != 2 || len(response.Images) != 2 || len(response.Workloads) != 3 {
+			t.Fatalf("%s: got %d packages, %d images, %d workloads; want 2, 2, 3",
+				path, len(response.Packages), len(response.Images), len(response.Workloads))
+		}
+		if response.Images[0]["digest"] != "sha256:web" || response.Images[0]["affected_containers"] != float64(2) {
+			t.Errorf("%s: unexpected first image: %v", path, response.Images[0])
+		}
+		if w := response.Workloads[2]; w["namespace"] != "team-b" || w["pod"] != "api" || w["node_name"] != "node-1" {
+			t.Errorf("%s: unexpected workload: %v", path, w)
+		}
+	}
+
+	if rec := get("/api/vulnerabilities/CVE-1999-0001"); rec.Code != http.StatusNotFound {
+		t.Errorf("unknown vulnerability: status = %d, want 404", rec.Code)
+	}
+	// Digests still download the image's vulnerability report
+	if rec := get("/api/vulnerabilities/sha256:web"); rec.Code == http.StatusOK && strings.Contains(rec.Body.String(), "workloads") {
+		t.Error("digest routed to the vulnerability explorer")
+	}
+}
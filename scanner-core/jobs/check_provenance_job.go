@@ -0,0 +1,96 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/bvboe/b2s-go/scanner-core/database"
+)
+
+// provenanceBatchSize is the number of images checked per run, so a large
+// cluster's first check spreads over several runs instead of one long burst
+// of registry requests
+const provenanceBatchSize = 100
+
+// ProvenanceDatabase defines the database operations needed by CheckProvenanceJob
+type ProvenanceDatabase interface {
+	GetImagesForProvenanceCheck(checkedBefore time.Time, limit int) ([]database.ProvenanceCandidate, error)
+	SetImageProvenance(digest string, provenance *database.ImageProvenance, checkErr string) error
+}
+
+// ProvenanceFetcher reads the provenance attestations of an image (implemented
+// by provenance.Fetcher). Returns nil without error if the image has none.
+type ProvenanceFetcher interface {
+	Fetch(ctx context.Context, reference, digest string) (*database.ImageProvenance, error)
+}
+
+// CheckProvenanceJob records the SLSA build provenance of scanned images from
+// their registry. Images are checked once, and again after the recheck
+// interval, since provenance may be attested after an image is pushed.
+type CheckProvenanceJob struct {
+	db           ProvenanceDatabase
+	fetcher      ProvenanceFetcher
+	recheckAfter time.Duration
+}
+
+// NewCheckProvenanceJob creates a job checking images whose provenance was
+// never checked or last checked more than recheckAfter ago
+func NewCheckProvenanceJob(db ProvenanceDatabase, fetcher ProvenanceFetcher, recheckAfter time.Duration) *CheckProvenanceJob {
+	if db == nil {
+		panic("CheckProvenanceJob requires a non-nil database")
+	}
+	if fetcher == nil {
+		panic("CheckProvenanceJob requires a non-nil fetcher")
+	}
+	if recheckAfter <= 0 {
+		panic("CheckProvenanceJob requires a positive recheck interval")
+	}
+	return &CheckProvenanceJob{db: db, fetcher: fetcher, recheckAfter: recheckAfter}
+}
+
+func (j *CheckProvenanceJob) Name() string {
+	return "check-provenance"
+}
+
+func (j *CheckProvenanceJob) Run(ctx context.Context) error {
+	candidates, err := j.db.GetImagesForProvenanceCheck(time.Now().Add(-j.recheckAfter), provenanceBatchSize)
+	if err != nil {
+		return fmt.Errorf("failed to get images for provenance check: %w", err)
+	}
+	if len(candidates) == 0 {
+		return nil
+	}
+
+	found, failed := 0, 0
+	for _, c := range candidates {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		// A failed check (e.g. no registry credentials) is recorded so the
+		// image is retried after the recheck interval instead of every run
+		provenance, err := j.fetcher.Fetch(ctx, c.Reference, c.Digest)
+		checkErr := ""
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			log.Warn("failed to check image provenance", "digest", c.Digest, "reference", c.Reference, "error", err)
+			checkErr = err.Error()
+			failed++
+		} else if provenance != nil {
+			found++
+		}
+
+		if err := j.db.SetImageProvenance(c.Digest, provenance, checkErr); err != nil {
+			return fmt.Errorf("failed to record provenance of %s: %w", c.Digest, err)
+		}
+	}
+
+	log.Info("checked image provenance", "images", len(candidates), "with_provenance", found, "failed", failed)
+	return nil
+}
+
+// Ensure database.DB implements ProvenanceDatabase
+var _ ProvenanceDatabase = (*database.DB)(nil)
@@ -0,0 +1,81 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/bvboe/b2s-go/scanner-core/database"
+)
+
+// mockProvenanceDatabase implements ProvenanceDatabase for testing
+type mockProvenanceDatabase struct {
+	candidates    []database.ProvenanceCandidate
+	checkedBefore time.Time
+	recorded      map[string]*database.ImageProvenance
+	errors        map[string]string
+}
+
+func (m *mockProvenanceDatabase) GetImagesForProvenanceCheck(checkedBefore time.Time, _ int) ([]database.ProvenanceCandidate, error) {
+	m.checkedBefore = checkedBefore
+	return m.candidates, nil
+}
+
+func (m *mockProvenanceDatabase) SetImageProvenance(digest string, provenance *database.ImageProvenance, checkErr string) error {
+	m.recorded[digest] = provenance
+	m.errors[digest] = checkErr
+	return nil
+}
+
+// mockProvenanceFetcher returns canned provenance per digest
+type mockProvenanceFetcher struct {
+	provenance map[string]*database.ImageProvenance
+	fail       map[string]bool
+}
+
+func (m *mockProvenanceFetcher) Fetch(_ context.Context, _, digest string) (*database.ImageProvenance, error) {
+	if m.fail[digest] {
+		return nil, errors.New("UNAUTHORIZED")
+	}
+	return m.provenance[digest], nil
+}
+
+func TestCheckProvenanceJob(t *testing.T) {
+	db := &mockProvenanceDatabase{
+		candidates: []database.ProvenanceCandidate{
+			{Digest: "sha256:signed", Reference: "ghcr.io/team/app:1"},
+			{Digest: "sha256:none", Reference: "docker.io/library/nginx:1.25"},
+			{Digest: "sha256:private", Reference: "registry.example.com/app:2"},
+		},
+		recorded: map[string]*database.ImageProvenance{},
+		errors:   map[string]string{},
+	}
+	fetcher := &mockProvenanceFetcher{
+		provenance: map[string]*database.ImageProvenance{
+			"sha256:signed": {BuilderID: "https://github.com/slsa-framework/slsa-github-generator/", SLSALevel: 3, Source: "cosign"},
+		},
+		fail: map[string]bool{"sha256:private": true},
+	}
+	job := NewCheckProvenanceJob(db, fetcher, 24*time.Hour)
+
+	if job.Name() != "check-provenance" {
+		t.Errorf("expected name check-provenance, got %s", job.Name())
+	}
+	if err := job.Run(context.Background()); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+
+	if age := time.Since(db.checkedBefore); age < 24*time.Hour || age > 25*time.Hour {
+		t.Errorf("checkedBefore is %v ago, want 24h", age)
+	}
+	if p := db.recorded["sha256:signed"]; p == nil || p.SLSALevel != 3 {
+		t.Errorf("signed image: recorded %+v", p)
+	}
+	if p, ok := db.recorded["sha256:none"]; !ok || p != nil || db.errors["sha256:none"] != "" {
+		t.Errorf("image without provenance: recorded %+v (ok %v), error %q", p, ok, db.errors["sha256:none"])
+	}
+	if db.errors["sha256:private"] != "UNAUTHORIZED" {
+		t.Errorf("failed check: error = %q, want UNAUTHORIZED", db.errors["sha256:private"])
+	}
+}
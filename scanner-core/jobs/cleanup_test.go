@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"testing"
+	"time"
 
 	"github.com/bvboe/b2s-go/scanner-core/containers"
 	"github.com/bvboe/b2s-go/scanner-core/database"
@@ -36,9 +37,7 @@ func TestCleanupOrphanedImagesJob(t *testing.T) {
 	t.Run("successful cleanup", func(t *testing.T) {
 		db := &mockDatabaseCleanup{
 			stats: &database.CleanupStats{
-				ImagesRemoved:          5,
-				PackagesRemoved:        123,
-				VulnerabilitiesRemoved: 456,
+				ImagesSoftDeleted: 5,
 			},
 		}
 		job := NewCleanupOrphanedImagesJob(db)
@@ -60,9 +59,7 @@ func TestCleanupOrphanedImagesJob(t *testing.T) {
 	t.Run("no orphaned images", func(t *testing.T) {
 		db := &mockDatabaseCleanup{
 			stats: &database.CleanupStats{
-				ImagesRemoved:          0,
-				PackagesRemoved:        0,
-				VulnerabilitiesRemoved: 0,
+				ImagesSoftDeleted: 0,
 			},
 		}
 		job := NewCleanupOrphanedImagesJob(db)
@@ -119,9 +116,7 @@ func TestCleanupOrphanedImagesJob(t *testing.T) {
 	t.Run("large cleanup", func(t *testing.T) {
 		db := &mockDatabaseCleanup{
 			stats: &database.CleanupStats{
-				ImagesRemoved:          1000,
-				PackagesRemoved:        50000,
-				VulnerabilitiesRemoved: 100000,
+				ImagesSoftDeleted: 1000,
 			},
 		}
 		job := NewCleanupOrphanedImagesJob(db)
@@ -132,3 +127,126 @@ func TestCleanupOrphanedImagesJob(t *testing.T) {
 		}
 	})
 }
+
+// mockDatabasePurge implements DatabasePurge for testing
+type mockDatabasePurge struct {
+	olderThan  time.Duration
+	shouldFail bool
+	stats      *database.CleanupStats
+}
+
+func (m *mockDatabasePurge) PurgeDeletedImages(olderThan time.Duration) (*database.CleanupStats, error) {
+	m.olderThan = olderThan
+	if m.shouldFail {
+		return nil, errors.New("mock purge error")
+	}
+	return m.stats, nil
+}
+
+// mockHistoryPurge also prunes scan history
+type mockHistoryPurge struct {
+	mockDatabasePurge
+	before time.Time
+}
+
+func (m *mockHistoryPurge) PruneScanHistory(before time.Time) (int64, error) {
+	m.before = before
+	return 1, nil
+}
+
+// mockBlobPurge also vacuums shared SBOM documents
+type mockBlobPurge struct {
+	mockDatabasePurge
+	vacuumed bool
+}
+
+func (m *mockBlobPurge) VacuumSBOMBlobs() (int64, error) {
+	m.vacuumed = true
+	return 2, nil
+}
+
+// mockPreviewPurge also previews purges
+type mockPreviewPurge struct {
+	mockBlobPurge
+	previewed time.Duration
+}
+
+func (m *mockPreviewPurge) PreviewPurgeDeletedImages(olderThan time.Duration) (*database.PurgePreview, error) {
+	m.previewed = olderThan
+	return &database.PurgePreview{Images: []database.PurgeCandidate{{Digest: "sha256:old"}}}, nil
+}
+
+func TestPurgeDeletedImagesJob(t *testing.T) {
+	t.Run("purges past retention", func(t *testing.T) {
+		db := &mockDatabasePurge{stats: &database.CleanupStats{ImagesRemoved: 3, PackagesRemoved: 120}}
+		job := NewPurgeDeletedImagesJob(db, 30*24*time.Hour)
+
+		if job.Name() != "purge-deleted-images" {
+			t.Errorf("Expected name 'purge-deleted-images', got %s", job.Name())
+		}
+		if err := job.Run(context.Background()); err != nil {
+			t.Errorf("Expected no error, got %v", err)
+		}
+		if db.olderThan != 30*24*time.Hour {
+			t.Errorf("Expected purge of images deleted over 720h ago, got %v", db.olderThan)
+		}
+	})
+
+	t.Run("prunes scan history", func(t *testing.T) {
+		db := &mockHistoryPurge{}
+		job := NewPurgeDeletedImagesJob(db, time.Hour)
+		if err := job.Run(context.Background()); err != nil || !db.before.IsZero() {
+			t.Fatalf("history pruned without retention: before %v, err %v", db.before, err)
+		}
+		job.SetScanHistoryRetention(24 * time.Hour)
+		if err := job.Run(context.Background()); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if ago := time.Since(db.before); ago < 24*time.Hour || ago > 25*time.Hour {
+			t.Errorf("Expected history pruned before 24h ago, got %v", db.before)
+		}
+	})
+
+	t.Run("vacuums SBOM blobs", func(t *testing.T) {
+		db := &mockBlobPurge{}
+		if err := NewPurgeDeletedImagesJob(db, time.Hour).Run(context.Background()); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if !db.vacuumed {
+			t.Error("Expected unreferenced SBOM blobs to be vacuumed")
+		}
+	})
+
+	t.Run("dry run deletes nothing", func(t *testing.T) {
+		db := &mockPreviewPurge{}
+		job := NewPurgeDeletedImagesJob(db, time.Hour)
+		job.SetScanHistoryRetention(24 * time.Hour)
+		job.SetDryRun(true)
+		if err := job.Run(context.Background()); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if db.previewed != time.Hour {
+			t.Errorf("Expected preview of images deleted over 1h ago, got %v", db.previewed)
+		}
+		if db.olderThan != 0 || db.vacuumed {
+			t.Error("Expected a dry run not to purge or vacuum")
+		}
+	})
+
+	t.Run("purge failure", func(t *testing.T) {
+		job := NewPurgeDeletedImagesJob(&mockDatabasePurge{shouldFail: true}, time.Hour)
+		if err := job.Run(context.Background()); err == nil {
+			t.Error("Expected error, got nil")
+		}
+	})
+
+	t.Run("nil database panics", func(t *testing.T) {
+		defer func() {
+			if r := recover(); r == nil {
+				t.Error("Expected panic with nil database")
+			}
+		}()
+
+		NewPurgeDeletedImagesJob(nil, time.Hour)
+	})
+}
@@ -0,0 +1,61 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/bvboe/b2s-go/scanner-core/cmdb"
+	"github.com/bvboe/b2s-go/scanner-core/database"
+)
+
+// CMDBFetcher reads the entries of an external CMDB (implemented by cmdb.Fetcher)
+type CMDBFetcher interface {
+	Fetch(ctx context.Context) ([]database.CMDBEntry, error)
+}
+
+// CMDBStore stores synced CMDB entries (implemented by database.DB)
+type CMDBStore interface {
+	SyncCMDBMetadata(source string, entries []database.CMDBEntry) (*database.CMDBSyncResult, error)
+}
+
+// CMDBSyncJob pulls workload attributes from an external CMDB, replacing the
+// entries of the previous pull. Entries pushed to /api/cmdb/sync are kept.
+type CMDBSyncJob struct {
+	fetcher CMDBFetcher
+	store   CMDBStore
+}
+
+// NewCMDBSyncJob creates a job storing the entries fetcher reads in store
+func NewCMDBSyncJob(fetcher CMDBFetcher, store CMDBStore) *CMDBSyncJob {
+	if fetcher == nil {
+		panic("CMDBSyncJob requires a non-nil fetcher")
+	}
+	if store == nil {
+		panic("CMDBSyncJob requires a non-nil store")
+	}
+	return &CMDBSyncJob{fetcher: fetcher, store: store}
+}
+
+func (j *CMDBSyncJob) Name() string {
+	return "cmdb-sync"
+}
+
+func (j *CMDBSyncJob) Run(ctx context.Context) error {
+	entries, err := j.fetcher.Fetch(ctx)
+	if err != nil {
+		return fmt.Errorf("CMDB sync failed: %w", err)
+	}
+	result, err := j.store.SyncCMDBMetadata(cmdb.Source, entries)
+	if err != nil {
+		return fmt.Errorf("CMDB sync failed: %w", err)
+	}
+
+	log.Info("synced CMDB metadata", "entries", result.Entries, "removed", result.Removed)
+	return nil
+}
+
+// Ensure cmdb.Fetcher and database.DB implement the job's interfaces
+var (
+	_ CMDBFetcher = (*cmdb.Fetcher)(nil)
+	_ CMDBStore   = (*database.DB)(nil)
+)
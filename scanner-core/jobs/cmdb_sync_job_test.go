@@ -0,0 +1,49 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/bvboe/b2s-go/scanner-core/cmdb"
+	"github.com/bvboe/b2s-go/scanner-core/database"
+)
+
+// mockCMDB serves canned entries and records what is synced
+type mockCMDB struct {
+	err     error
+	source  string
+	entries []database.CMDBEntry
+}
+
+func (m *mockCMDB) Fetch(_ context.Context) ([]database.CMDBEntry, error) {
+	return []database.CMDBEntry{{Namespace: "payments", CMDBAttributes: database.CMDBAttributes{Owner: "team-payments"}}}, m.err
+}
+
+func (m *mockCMDB) SyncCMDBMetadata(source string, entries []database.CMDBEntry) (*database.CMDBSyncResult, error) {
+	m.source, m.entries = source, entries
+	return &database.CMDBSyncResult{Source: source, Entries: len(entries)}, nil
+}
+
+func TestCMDBSyncJob(t *testing.T) {
+	m := &mockCMDB{}
+	job := NewCMDBSyncJob(m, m)
+	if job.Name() != "cmdb-sync" {
+		t.Errorf("Name() = %q", job.Name())
+	}
+	if err := job.Run(context.Background()); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if m.source != cmdb.Source || len(m.entries) != 1 {
+		t.Errorf("synced %d entries from %q, want 1 from %q", len(m.entries), m.source, cmdb.Source)
+	}
+
+	// A failed fetch leaves the stored entries alone
+	m.err, m.entries = context.DeadlineExceeded, nil
+	if err := job.Run(context.Background()); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Run() error = %v, want context.DeadlineExceeded", err)
+	}
+	if m.entries != nil {
+		t.Error("Expected no sync after a failed fetch")
+	}
+}
@@ -3,6 +3,7 @@ package jobs
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"github.com/bvboe/b2s-go/scanner-core/containers"
 	"github.com/bvboe/b2s-go/scanner-core/database"
@@ -52,9 +53,10 @@ type ContainerLister interface {
 	GetActiveContainerIDs() []containers.ContainerID
 }
 
-// CleanupOrphanedImagesJob deletes container images that have no associated container instances.
+// CleanupOrphanedImagesJob soft-deletes container images that have no associated container
+// instances; PurgeDeletedImagesJob removes them for good later.
 // If a ContainerLister is configured, it first removes stale container entries (containers in
-// the DB whose pods no longer exist), then removes images that have become orphaned as a result.
+// the DB whose pods no longer exist), then deletes images that have become orphaned as a result.
 type CleanupOrphanedImagesJob struct {
 	db     DatabaseCleanup
 	lister ContainerLister // optional; nil means skip stale-container cleanup
@@ -103,29 +105,153 @@ func (j *CleanupOrphanedImagesJob) Run(ctx context.Context) error {
 		}
 	}
 
-	// Step 2: remove images with no containers (and their packages/vulnerabilities)
+	// Step 2: soft-delete images with no containers (their packages/vulnerabilities are kept)
 	stats, err := j.db.CleanupOrphanedImages()
 	if err != nil {
 		return fmt.Errorf("orphaned image cleanup failed: %w", err)
 	}
 
 	if stats != nil {
-		totalStats.ImagesRemoved = stats.ImagesRemoved
-		totalStats.PackagesRemoved = stats.PackagesRemoved
-		totalStats.VulnerabilitiesRemoved = stats.VulnerabilitiesRemoved
-		totalStats.PackageDetailsRemoved = stats.PackageDetailsRemoved
-		totalStats.VulnerabilityDetailsRemoved = stats.VulnerabilityDetailsRemoved
+		totalStats.ImagesSoftDeleted = stats.ImagesSoftDeleted
 	}
 
-	if totalStats.ContainersRemoved > 0 || totalStats.ImagesRemoved > 0 {
+	if totalStats.ContainersRemoved > 0 || totalStats.ImagesSoftDeleted > 0 {
 		log.Info("cleanup completed",
 			"containers_removed", totalStats.ContainersRemoved,
-			"images_removed", totalStats.ImagesRemoved,
-			"packages_removed", totalStats.PackagesRemoved,
-			"vulnerabilities_removed", totalStats.VulnerabilitiesRemoved)
+			"images_soft_deleted", totalStats.ImagesSoftDeleted)
 	} else {
 		log.Info("cleanup completed: nothing to remove")
 	}
 
 	return nil
 }
+
+// DatabasePurge defines the interface for purging soft-deleted images
+type DatabasePurge interface {
+	PurgeDeletedImages(olderThan time.Duration) (*database.CleanupStats, error)
+}
+
+// ScanHistoryPruner is implemented by databases that keep scan history
+type ScanHistoryPruner interface {
+	PruneScanHistory(before time.Time) (int64, error)
+}
+
+// SBOMBlobVacuumer is implemented by databases that share SBOM documents
+// between images
+type SBOMBlobVacuumer interface {
+	VacuumSBOMBlobs() (int64, error)
+}
+
+// PurgePreviewer is implemented by databases that can list what a purge
+// would remove
+type PurgePreviewer interface {
+	PreviewPurgeDeletedImages(olderThan time.Duration) (*database.PurgePreview, error)
+}
+
+// PurgeDeletedImagesJob permanently removes images soft-deleted by
+// CleanupOrphanedImagesJob once they have been deleted for longer than the
+// retention window, along with their packages and vulnerabilities.
+// Scan history is kept independently of images and pruned by age. Shared
+// SBOM documents no image references any more are deleted afterwards.
+type PurgeDeletedImagesJob struct {
+	db               DatabasePurge
+	retention        time.Duration
+	historyRetention time.Duration // 0 keeps scan history forever
+	dryRun           bool
+}
+
+// NewPurgeDeletedImagesJob creates a new purge job that keeps soft-deleted
+// images for retention
+func NewPurgeDeletedImagesJob(db DatabasePurge, retention time.Duration) *PurgeDeletedImagesJob {
+	if db == nil {
+		panic("PurgeDeletedImagesJob requires a non-nil database")
+	}
+	return &PurgeDeletedImagesJob{
+		db:        db,
+		retention: retention,
+	}
+}
+
+// SetScanHistoryRetention configures the job to also prune scan history
+// snapshots older than retention, if the database keeps scan history.
+func (j *PurgeDeletedImagesJob) SetScanHistoryRetention(retention time.Duration) {
+	j.historyRetention = retention
+}
+
+// SetDryRun configures the job to only log the images a purge would remove,
+// if the database can preview purges, and delete nothing
+func (j *PurgeDeletedImagesJob) SetDryRun(dryRun bool) {
+	j.dryRun = dryRun
+}
+
+func (j *PurgeDeletedImagesJob) Name() string {
+	return "purge-deleted-images"
+}
+
+func (j *PurgeDeletedImagesJob) Run(ctx context.Context) error {
+	if j.dryRun {
+		return j.preview()
+	}
+
+	log.Info("starting purge of deleted container images", "retention", j.retention)
+
+	stats, err := j.db.PurgeDeletedImages(j.retention)
+	if err != nil {
+		return fmt.Errorf("deleted image purge failed: %w", err)
+	}
+
+	if stats != nil && stats.ImagesRemoved > 0 {
+		log.Info("purge completed",
+			"images_removed", stats.ImagesRemoved,
+			"packages_removed", stats.PackagesRemoved,
+			"vulnerabilities_removed", stats.VulnerabilitiesRemoved)
+	} else {
+		log.Info("purge completed: nothing to remove")
+	}
+
+	if pruner, ok := j.db.(ScanHistoryPruner); ok && j.historyRetention > 0 {
+		pruned, err := pruner.PruneScanHistory(time.Now().Add(-j.historyRetention))
+		if err != nil {
+			return fmt.Errorf("scan history prune failed: %w", err)
+		}
+		if pruned > 0 {
+			log.Info("pruned scan history", "snapshots_removed", pruned, "retention", j.historyRetention)
+		}
+	}
+
+	if vacuumer, ok := j.db.(SBOMBlobVacuumer); ok {
+		deleted, err := vacuumer.VacuumSBOMBlobs()
+		if err != nil {
+			return fmt.Errorf("SBOM blob vacuum failed: %w", err)
+		}
+		if deleted > 0 {
+			log.Info("deleted unreferenced SBOM documents", "documents_removed", deleted)
+		}
+	}
+
+	return nil
+}
+
+// preview logs what Run would purge
+func (j *PurgeDeletedImagesJob) preview() error {
+	previewer, ok := j.db.(PurgePreviewer)
+	if !ok {
+		log.Warn("purge dry run: database cannot preview purges, nothing purged")
+		return nil
+	}
+	preview, err := previewer.PreviewPurgeDeletedImages(j.retention)
+	if err != nil {
+		return fmt.Errorf("deleted image purge preview failed: %w", err)
+	}
+
+	for _, img := range preview.Images {
+		log.Info("purge dry run: would remove image", "digest", img.Digest, "reference", img.Reference, "deleted_at", img.DeletedAt)
+	}
+	log.Info("purge dry run completed",
+		"images", len(preview.Images),
+		"packages", preview.PackagesRemoved,
+		"vulnerabilities", preview.VulnerabilitiesRemoved,
+		"sbom_documents", preview.SBOMsRemoved,
+		"retention", j.retention)
+	return nil
+}
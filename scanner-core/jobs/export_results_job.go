@@ -0,0 +1,45 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/bvboe/b2s-go/scanner-core/database"
+)
+
+// ExportResultsJob copies the scan results to an external database (e.g.
+// database.PostgresSink), so they outlive the local SQLite store and can be
+// queried across clusters
+type ExportResultsJob struct {
+	source database.ResultSource
+	sink   database.ResultSink
+}
+
+// NewExportResultsJob creates a job exporting the results of source to sink
+func NewExportResultsJob(source database.ResultSource, sink database.ResultSink) *ExportResultsJob {
+	if source == nil {
+		panic("ExportResultsJob requires a non-nil source")
+	}
+	if sink == nil {
+		panic("ExportResultsJob requires a non-nil sink")
+	}
+	return &ExportResultsJob{source: source, sink: sink}
+}
+
+func (j *ExportResultsJob) Name() string {
+	return "export-results"
+}
+
+func (j *ExportResultsJob) Run(ctx context.Context) error {
+	stats, err := j.sink.Export(ctx, j.source)
+	if err != nil {
+		return fmt.Errorf("result export failed: %w", err)
+	}
+
+	log.Info("exported scan results", "images", stats.Images, "containers", stats.Containers,
+		"rescanned_images", stats.RescannedImages, "vulnerabilities", stats.Vulnerabilities, "scan_history", stats.ScanHistory)
+	return nil
+}
+
+// Ensure the database implements ResultSource
+var _ database.ResultSource = (*database.DB)(nil)
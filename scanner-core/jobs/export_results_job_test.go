@@ -0,0 +1,64 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/bvboe/b2s-go/scanner-core/database"
+)
+
+// mockResultSource has no results; the mock sink does not read it
+type mockResultSource struct{}
+
+func (mockResultSource) GetReportImages() ([]database.ReportImage, error) { return nil, nil }
+func (mockResultSource) GetReportContainers() ([]database.ReportContainer, error) {
+	return nil, nil
+}
+func (mockResultSource) GetReportVulnerabilities(int64) ([]database.ReportVulnerability, error) {
+	return nil, nil
+}
+func (mockResultSource) GetScanHistoryRows(int64, int) ([]database.ScanHistoryRow, error) {
+	return nil, nil
+}
+
+// mockResultSink records exports
+type mockResultSink struct {
+	exports int
+	err     error
+}
+
+func (m *mockResultSink) Export(_ context.Context, src database.ResultSource) (database.ExportStats, error) {
+	m.exports++
+	return database.ExportStats{Images: 1}, m.err
+}
+
+func (m *mockResultSink) Close() error { return nil }
+
+func TestExportResultsJob(t *testing.T) {
+	sink := &mockResultSink{}
+	job := NewExportResultsJob(mockResultSource{}, sink)
+	if job.Name() != "export-results" {
+		t.Errorf("Name() = %q", job.Name())
+	}
+	if err := job.Run(context.Background()); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if sink.exports != 1 {
+		t.Errorf("expected 1 export, got %d", sink.exports)
+	}
+
+	sink.err = context.DeadlineExceeded
+	if err := job.Run(context.Background()); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Run() error = %v, want context.DeadlineExceeded", err)
+	}
+}
+
+func TestNewExportResultsJobPanicsOnNil(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("expected panic for nil sink")
+		}
+	}()
+	NewExportResultsJob(mockResultSource{}, nil)
+}
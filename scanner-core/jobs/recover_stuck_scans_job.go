@@ -0,0 +1,109 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/bvboe/b2s-go/scanner-core/containers"
+	"github.com/bvboe/b2s-go/scanner-core/database"
+)
+
+// StuckScanDatabase defines the database operations needed by RecoverStuckScansJob
+type StuckScanDatabase interface {
+	GetStuckScans(maxAge time.Duration) ([]database.StuckScan, error)
+	UpdateStatus(digest string, status database.Status, errorMsg string) error
+	RecordScanFailure(digest, nodeName string, status database.Status, errorMsg string, maxAttempts int) (bool, error)
+}
+
+// RecoverStuckScansJob fails images that made no progress in generating_sbom or
+// scanning_vulnerabilities for longer than the timeout, typically because the
+// worker scanning them crashed, and requeues them. The failure counts as a scan
+// attempt, so an image that keeps getting stuck is eventually dead-lettered.
+type RecoverStuckScansJob struct {
+	db          StuckScanDatabase
+	scanQueue   ScanQueueInterface
+	timeout     time.Duration
+	maxAttempts int
+}
+
+// NewRecoverStuckScansJob creates a job recovering images stuck for longer than
+// timeout. maxAttempts is the dead-letter threshold of the scan queue.
+func NewRecoverStuckScansJob(db StuckScanDatabase, scanQueue ScanQueueInterface, timeout time.Duration, maxAttempts int) *RecoverStuckScansJob {
+	if db == nil {
+		panic("RecoverStuckScansJob requires a non-nil database")
+	}
+	if scanQueue == nil {
+		panic("RecoverStuckScansJob requires a non-nil scan queue")
+	}
+	if timeout <= 0 {
+		panic("RecoverStuckScansJob requires a positive timeout")
+	}
+	return &RecoverStuckScansJob{
+		db:          db,
+		scanQueue:   scanQueue,
+		timeout:     timeout,
+		maxAttempts: maxAttempts,
+	}
+}
+
+func (j *RecoverStuckScansJob) Name() string {
+	return "recover-stuck-scans"
+}
+
+func (j *RecoverStuckScansJob) Run(ctx context.Context) error {
+	scans, err := j.db.GetStuckScans(j.timeout)
+	if err != nil {
+		return fmt.Errorf("failed to get stuck scans: %w", err)
+	}
+	if len(scans) == 0 {
+		return nil
+	}
+
+	requeued := 0
+	for _, scan := range scans {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		// The SBOM of an image stuck scanning vulnerabilities is kept; the
+		// force scan only reruns the stage that got stuck
+		failed := database.StatusSBOMFailed
+		if scan.Status == database.StatusScanningVulnerabilities {
+			failed = database.StatusVulnScanFailed
+		}
+		errorMsg := fmt.Sprintf("no progress in %s since %s (timeout %s)", scan.Status, scan.StatusChangedAt, j.timeout)
+
+		if err := j.db.UpdateStatus(scan.Digest, failed, errorMsg); err != nil {
+			return fmt.Errorf("failed to mark stuck scan of %s failed: %w", scan.Digest, err)
+		}
+		deadLettered, err := j.db.RecordScanFailure(scan.Digest, scan.NodeName, failed, errorMsg, j.maxAttempts)
+		if err != nil {
+			return fmt.Errorf("failed to record stuck scan of %s: %w", scan.Digest, err)
+		}
+		log.Warn("recovered stuck scan",
+			"digest", scan.Digest,
+			"reference", scan.Reference,
+			"node", scan.NodeName,
+			"status", scan.Status,
+			"status_changed_at", scan.StatusChangedAt,
+			"dead_lettered", deadLettered)
+
+		// Images no container runs anymore are left to the cleanup job
+		if deadLettered || scan.NodeName == "" {
+			continue
+		}
+		j.scanQueue.EnqueueForceScan(
+			containers.ImageID{Digest: scan.Digest, Reference: scan.Reference},
+			scan.NodeName,
+			scan.ContainerRuntime,
+		)
+		requeued++
+	}
+
+	log.Info("recovered stuck scans", "count", len(scans), "requeued", requeued, "timeout", j.timeout)
+	return nil
+}
+
+// Ensure database.DB implements StuckScanDatabase
+var _ StuckScanDatabase = (*database.DB)(nil)
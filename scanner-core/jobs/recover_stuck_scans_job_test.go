@@ -0,0 +1,113 @@
+package jobs
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/bvboe/b2s-go/scanner-core/database"
+)
+
+// mockStuckScanDatabase implements StuckScanDatabase for testing
+type mockStuckScanDatabase struct {
+	scans        []database.StuckScan
+	deadLetter   map[string]bool
+	maxAge       time.Duration
+	statuses     map[string]database.Status
+	failures     map[string]string // digest -> node the failure was recorded on
+	failAttempts int
+}
+
+func (m *mockStuckScanDatabase) GetStuckScans(maxAge time.Duration) ([]database.StuckScan, error) {
+	m.maxAge = maxAge
+	return m.scans, nil
+}
+
+func (m *mockStuckScanDatabase) UpdateStatus(digest string, status database.Status, _ string) error {
+	m.statuses[digest] = status
+	return nil
+}
+
+func (m *mockStuckScanDatabase) RecordScanFailure(digest, nodeName string, _ database.Status, _ string, maxAttempts int) (bool, error) {
+	m.failures[digest] = nodeName
+	m.failAttempts = maxAttempts
+	return m.deadLetter[digest], nil
+}
+
+func TestRecoverStuckScansJob(t *testing.T) {
+	db := &mockStuckScanDatabase{
+		scans: []database.StuckScan{
+			{Digest: "sha256:sbom", Reference: "app:1", NodeName: "node-1", ContainerRuntime: "containerd",
+				Status: database.StatusGeneratingSBOM, StatusChangedAt: "2026-01-01 10:00:00"},
+			{Digest: "sha256:vulns", Reference: "app:2", NodeName: "node-2", ContainerRuntime: "containerd",
+				Status: database.StatusScanningVulnerabilities, StatusChangedAt: "2026-01-01 10:00:00"},
+			{Digest: "sha256:dead", Reference: "app:3", NodeName: "node-1",
+				Status: database.StatusGeneratingSBOM, StatusChangedAt: "2026-01-01 10:00:00"},
+			{Digest: "sha256:gone", Reference: "app:4",
+				Status: database.StatusGeneratingSBOM, StatusChangedAt: "2026-01-01 10:00:00"},
+		},
+		deadLetter: map[string]bool{"sha256:dead": true},
+		statuses:   map[string]database.Status{},
+		failures:   map[string]string{},
+	}
+	queue := &MockScanQueue{}
+	job := NewRecoverStuckScansJob(db, queue, 45*time.Minute, 5)
+
+	if job.Name() != "recover-stuck-scans" {
+		t.Errorf("expected name recover-stuck-scans, got %s", job.Name())
+	}
+	if err := job.Run(context.Background()); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+
+	if db.maxAge != 45*time.Minute || db.failAttempts != 5 {
+		t.Errorf("maxAge = %v, maxAttempts = %d; want 45m, 5", db.maxAge, db.failAttempts)
+	}
+	want := map[string]database.Status{
+		"sha256:sbom":  database.StatusSBOMFailed,
+		"sha256:vulns": database.StatusVulnScanFailed,
+		"sha256:dead":  database.StatusSBOMFailed,
+		"sha256:gone":  database.StatusSBOMFailed,
+	}
+	for digest, status := range want {
+		if db.statuses[digest] != status {
+			t.Errorf("%s: status = %q, want %q", digest, db.statuses[digest], status)
+		}
+		if _, ok := db.failures[digest]; !ok {
+			t.Errorf("%s: scan failure not recorded", digest)
+		}
+	}
+	if db.failures["sha256:vulns"] != "node-2" {
+		t.Errorf("failure recorded on node %q, want node-2", db.failures["sha256:vulns"])
+	}
+
+	// Dead-lettered images and images without containers are not requeued
+	if len(queue.enqueuedScans) != 2 {
+		t.Fatalf("expected 2 requeued scans, got %+v", queue.enqueuedScans)
+	}
+	if got := queue.enqueuedScans[0]; got.Digest != "sha256:sbom" || got.NodeName != "node-1" || got.ContainerRuntime != "containerd" {
+		t.Errorf("unexpected requeued scan: %+v", got)
+	}
+	if got := queue.enqueuedScans[1]; got.Digest != "sha256:vulns" || got.Reference != "app:2" {
+		t.Errorf("unexpected requeued scan: %+v", got)
+	}
+}
+
+func TestNewRecoverStuckScansJob_InvalidArguments(t *testing.T) {
+	db := &mockStuckScanDatabase{}
+	queue := &MockScanQueue{}
+	for name, create := range map[string]func(){
+		"nil database": func() { NewRecoverStuckScansJob(nil, queue, time.Minute, 5) },
+		"nil queue":    func() { NewRecoverStuckScansJob(db, nil, time.Minute, 5) },
+		"zero timeout": func() { NewRecoverStuckScansJob(db, queue, 0, 5) },
+	} {
+		t.Run(name, func(t *testing.T) {
+			defer func() {
+				if recover() == nil {
+					t.Error("expected panic")
+				}
+			}()
+			create()
+		})
+	}
+}
@@ -0,0 +1,46 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/bvboe/b2s-go/scanner-core/registrycrawl"
+)
+
+// RegistryCrawler crawls the configured registry repositories (implemented by
+// registrycrawl.Crawler)
+type RegistryCrawler interface {
+	Crawl(ctx context.Context) (registrycrawl.Stats, error)
+}
+
+// RegistryCrawlJob scans the images of the configured registry repositories,
+// whether or not they are deployed
+type RegistryCrawlJob struct {
+	crawler RegistryCrawler
+}
+
+// NewRegistryCrawlJob creates a job running crawler
+func NewRegistryCrawlJob(crawler RegistryCrawler) *RegistryCrawlJob {
+	if crawler == nil {
+		panic("RegistryCrawlJob requires a non-nil crawler")
+	}
+	return &RegistryCrawlJob{crawler: crawler}
+}
+
+func (j *RegistryCrawlJob) Name() string {
+	return "registry-crawl"
+}
+
+func (j *RegistryCrawlJob) Run(ctx context.Context) error {
+	stats, err := j.crawler.Crawl(ctx)
+	if err != nil {
+		return fmt.Errorf("registry crawl failed: %w", err)
+	}
+
+	log.Info("crawled registries", "repositories", stats.Repositories, "images", stats.Images,
+		"new_images", stats.NewImages, "queued", stats.Queued, "skipped", stats.Skipped, "errors", stats.Errors)
+	return nil
+}
+
+// Ensure registrycrawl.Crawler implements RegistryCrawler
+var _ RegistryCrawler = (*registrycrawl.Crawler)(nil)
@@ -0,0 +1,48 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/bvboe/b2s-go/scanner-core/registrycrawl"
+)
+
+// mockRegistryCrawler returns canned crawl results
+type mockRegistryCrawler struct {
+	calls int
+	err   error
+}
+
+func (m *mockRegistryCrawler) Crawl(_ context.Context) (registrycrawl.Stats, error) {
+	m.calls++
+	return registrycrawl.Stats{Repositories: 1, Images: 2, Queued: 2}, m.err
+}
+
+func TestRegistryCrawlJob(t *testing.T) {
+	crawler := &mockRegistryCrawler{}
+	job := NewRegistryCrawlJob(crawler)
+	if job.Name() != "registry-crawl" {
+		t.Errorf("Name() = %q", job.Name())
+	}
+	if err := job.Run(context.Background()); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if crawler.calls != 1 {
+		t.Errorf("expected 1 crawl, got %d", crawler.calls)
+	}
+
+	crawler.err = context.Canceled
+	if err := job.Run(context.Background()); !errors.Is(err, context.Canceled) {
+		t.Errorf("Run() error = %v, want context.Canceled", err)
+	}
+}
+
+func TestNewRegistryCrawlJobPanicsOnNil(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("expected panic for nil crawler")
+		}
+	}()
+	NewRegistryCrawlJob(nil)
+}
@@ -18,6 +18,10 @@ type DatabaseUpdateChecker interface {
 	GetCurrentVersion() *vulndb.DatabaseStatus
 }
 
+// RescanDatabaseJobName is the scheduler name of the job that checks the
+// vulnerability database feed
+const RescanDatabaseJobName = "rescan-database"
+
 // stuckScanMaxAge is how long a node or image may sit in a transient scan state
 // ('generating_sbom', 'scanning_vulnerabilities') before the reaper treats it as
 // orphaned and resets it. It must be safely larger than the real scan timeouts
@@ -46,6 +50,7 @@ type RescanDatabaseJob struct {
 	db              DatabaseInterface
 	scanQueue       ScanQueueInterface
 	readinessSetter ReadinessSetter // Optional: updates readiness state when DB is ready
+	stuckScanMaxAge time.Duration
 	// Node scanning support (optional)
 	nodeDB        NodeDatabaseInterface
 	nodeScanQueue NodeScanQueueInterface
@@ -64,9 +69,19 @@ func NewRescanDatabaseJob(dbUpdater DatabaseUpdateChecker, db DatabaseInterface,
 	}
 
 	return &RescanDatabaseJob{
-		dbUpdater: dbUpdater,
-		db:        db,
-		scanQueue: scanQueue,
+		dbUpdater:       dbUpdater,
+		db:              db,
+		scanQueue:       scanQueue,
+		stuckScanMaxAge: stuckScanMaxAge,
+	}
+}
+
+// SetStuckScanMaxAge overrides how long a scan may sit in a transient state
+// before it is reaped (default 30m). It should match the timeout of the
+// recover-stuck-scans job, which normally recovers those scans first.
+func (j *RescanDatabaseJob) SetStuckScanMaxAge(maxAge time.Duration) {
+	if maxAge > 0 {
+		j.stuckScanMaxAge = maxAge
 	}
 }
 
@@ -84,7 +99,7 @@ func (j *RescanDatabaseJob) SetNodeScanning(nodeDB NodeDatabaseInterface, nodeSc
 }
 
 func (j *RescanDatabaseJob) Name() string {
-	return "rescan-database"
+	return RescanDatabaseJobName
 }
 
 func (j *RescanDatabaseJob) Run(ctx context.Context) error {
@@ -94,7 +109,7 @@ func (j *RescanDatabaseJob) Run(ctx context.Context) error {
 	// ResetInterruptedScans only runs at startup, so a scan wedged while the
 	// server keeps running would otherwise stay stuck until the next restart.
 	// Marking them vuln_scan_failed lets the rescan logic below re-enqueue them.
-	if nodeRows, imageRows, err := j.db.ReapStuckScans(stuckScanMaxAge); err != nil {
+	if nodeRows, imageRows, err := j.db.ReapStuckScans(j.stuckScanMaxAge); err != nil {
 		log.Warn("failed to reap stuck scans", "error", err)
 	} else if nodeRows > 0 || imageRows > 0 {
 		log.Info("reaped stuck scans", "nodes", nodeRows, "images", imageRows)
@@ -141,10 +156,14 @@ func (j *RescanDatabaseJob) Run(ctx context.Context) error {
 		log.Info("found images scanned with older grype database, triggering rescan",
 			"image_count", len(images))
 
-		// Enqueue force scan for each image
-		rescanned := 0
+		// Get the first container of each image to determine node and runtime
+		type rescan struct {
+			image   containers.ImageID
+			node    string
+			runtime string
+		}
+		var rescans []rescan
 		for _, img := range images {
-			// Get the first container to determine node and runtime
 			instance, err := j.db.GetFirstContainerForImage(img.Digest)
 			if err != nil {
 				log.Warn("could not find instance for image",
@@ -152,21 +171,23 @@ func (j *RescanDatabaseJob) Run(ctx context.Context) error {
 					"error", err)
 				continue
 			}
+			rescans = append(rescans, rescan{
+				image:   containers.ImageID{Digest: img.Digest, Reference: instance.Reference},
+				node:    instance.NodeName,
+				runtime: instance.ContainerRuntime,
+			})
+		}
+
+		// Report the expected effort (GET /api/scan-queue) before queueing
+		j.scanQueue.BeginBulkRescan(scanning.BulkRescanDBUpdate, len(rescans), false)
 
-			// Enqueue force scan (ForceScan=true skips SBOM generation, only runs Grype)
-			j.scanQueue.EnqueueForceScan(
-				containers.ImageID{
-					Digest:    img.Digest,
-					Reference: instance.Reference,
-				},
-				instance.NodeName,
-				instance.ContainerRuntime,
-			)
-			rescanned++
+		// Enqueue force scan (ForceScan=true skips SBOM generation, only runs Grype)
+		for _, r := range rescans {
+			j.scanQueue.EnqueueForceScan(r.image, r.node, r.runtime)
 		}
 
 		log.Info("enqueued images for rescanning",
-			"count", rescanned)
+			"count", len(rescans))
 	}
 
 	// Also rescan nodes if node scanning is configured
@@ -185,6 +206,7 @@ func (j *RescanDatabaseJob) Run(ctx context.Context) error {
 // ScanQueueInterface defines the interface for enqueueing scans
 type ScanQueueInterface interface {
 	EnqueueForceScan(image containers.ImageID, nodeName string, containerRuntime string)
+	BeginBulkRescan(trigger string, images int, fullRescan bool) scanning.RescanEstimate
 }
 
 // Ensure scanning.JobQueue implements ScanQueueInterface
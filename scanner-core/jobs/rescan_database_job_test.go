@@ -9,6 +9,7 @@ import (
 	"github.com/bvboe/b2s-go/scanner-core/containers"
 	"github.com/bvboe/b2s-go/scanner-core/database"
 	"github.com/bvboe/b2s-go/scanner-core/nodes"
+	"github.com/bvboe/b2s-go/scanner-core/scanning"
 	"github.com/bvboe/b2s-go/scanner-core/vulndb"
 )
 
@@ -84,6 +85,7 @@ func (m *MockDatabase) ReapStuckScans(maxAge time.Duration) (int64, int64, error
 
 type MockScanQueue struct {
 	enqueuedScans []EnqueuedScan
+	bulkRescans   []int
 }
 
 type EnqueuedScan struct {
@@ -102,6 +104,11 @@ func (m *MockScanQueue) EnqueueForceScan(image containers.ImageID, nodeName stri
 	})
 }
 
+func (m *MockScanQueue) BeginBulkRescan(trigger string, images int, fullRescan bool) scanning.RescanEstimate {
+	m.bulkRescans = append(m.bulkRescans, images)
+	return scanning.RescanEstimate{Images: images, FullRescan: fullRescan}
+}
+
 // Test: images needing rescan are enqueued
 func TestRescanDatabaseJob_Integration(t *testing.T) {
 	// Setup: mock database updater with a current version
@@ -164,6 +171,10 @@ func TestRescanDatabaseJob_Integration(t *testing.T) {
 	if len(mockQueue.enqueuedScans) != 2 {
 		t.Errorf("Expected 2 rescans, got %d", len(mockQueue.enqueuedScans))
 	}
+	// Verify: the bulk rescan was estimated first
+	if len(mockQueue.bulkRescans) != 1 || mockQueue.bulkRescans[0] != 2 {
+		t.Errorf("Expected one bulk rescan of 2 images, got %v", mockQueue.bulkRescans)
+	}
 
 	// Verify: correct images enqueued
 	expectedScans := map[string]EnqueuedScan{
@@ -0,0 +1,40 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+)
+
+// OSLifecycleUpdater refreshes OS end-of-life data (implemented by eol.Catalog)
+type OSLifecycleUpdater interface {
+	Update(ctx context.Context, baseURL string) error
+}
+
+// UpdateOSEOLJob refreshes the OS end-of-life dataset from an endoflife.date
+// compatible API. The bundled data stays in use for distros that fail to update.
+type UpdateOSEOLJob struct {
+	updater OSLifecycleUpdater
+	baseURL string
+}
+
+// NewUpdateOSEOLJob creates a job refreshing updater from baseURL
+func NewUpdateOSEOLJob(updater OSLifecycleUpdater, baseURL string) *UpdateOSEOLJob {
+	if updater == nil {
+		panic("UpdateOSEOLJob requires a non-nil updater")
+	}
+	if baseURL == "" {
+		panic("UpdateOSEOLJob requires a base URL")
+	}
+	return &UpdateOSEOLJob{updater: updater, baseURL: baseURL}
+}
+
+func (j *UpdateOSEOLJob) Name() string {
+	return "update-os-eol"
+}
+
+func (j *UpdateOSEOLJob) Run(ctx context.Context) error {
+	if err := j.updater.Update(ctx, j.baseURL); err != nil {
+		return fmt.Errorf("failed to update OS end-of-life data: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,90 @@
+// Package labels defines the static labels (e.g. environment=prod,
+// region=eu) an operator attaches to a deployment so that downstream
+// aggregation can tell sources apart without relabeling.
+//
+// Static labels are added, next to the deployment name and UUID, to every
+// metric (Prometheus and OTEL), to exported transfer bundles and result
+// cache entries, and to vulnerability reports. Labels a metric already
+// carries always win over a static label of the same name.
+package labels
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// namePattern is the Prometheus label name syntax, which is also valid as an
+// OTEL attribute key
+var namePattern = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+// Set maps static label names to values. A nil Set has no labels.
+type Set map[string]string
+
+// Parse parses a comma-separated list of name=value pairs, e.g.
+// "environment=prod,region=eu". Names must be valid Prometheus label names
+// and may not start with "__" (reserved) or "deployment_" (set by
+// bjorn2scan). Values may not contain commas. An empty spec returns a nil Set.
+func Parse(spec string) (Set, error) {
+	if strings.TrimSpace(spec) == "" {
+		return nil, nil
+	}
+	s := Set{}
+	for _, pair := range strings.Split(spec, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		name, value, ok := strings.Cut(pair, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid static label %q: expected name=value", pair)
+		}
+		name, value = strings.TrimSpace(name), strings.TrimSpace(value)
+		if !namePattern.MatchString(name) {
+			return nil, fmt.Errorf("invalid static label %q: invalid label name %q", pair, name)
+		}
+		if strings.HasPrefix(name, "__") || strings.HasPrefix(name, "deployment_") {
+			return nil, fmt.Errorf("invalid static label %q: label name %q is reserved", pair, name)
+		}
+		if value == "" {
+			return nil, fmt.Errorf("invalid static label %q: empty value", pair)
+		}
+		if _, dup := s[name]; dup {
+			return nil, fmt.Errorf("invalid static labels: %s is set more than once", name)
+		}
+		s[name] = value
+	}
+	if len(s) == 0 {
+		return nil, nil
+	}
+	return s, nil
+}
+
+// Names returns the label names in sorted order
+func (s Set) Names() []string {
+	names := make([]string, 0, len(s))
+	for name := range s {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// String returns the labels in Parse syntax, sorted by name; "" for no labels
+func (s Set) String() string {
+	pairs := make([]string, 0, len(s))
+	for _, name := range s.Names() {
+		pairs = append(pairs, name+"="+s[name])
+	}
+	return strings.Join(pairs, ",")
+}
+
+// AddTo adds the labels to m, keeping any value m already has for a name
+func (s Set) AddTo(m map[string]string) {
+	for name, value := range s {
+		if _, ok := m[name]; !ok {
+			m[name] = value
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package labels
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		spec    string
+		want    Set
+		wantErr bool
+	}{
+		{spec: "", want: nil},
+		{spec: " , ", want: nil},
+		{spec: "environment=prod", want: Set{"environment": "prod"}},
+		{spec: " environment = prod , region=eu-west-1 ", want: Set{"environment": "prod", "region": "eu-west-1"}},
+		{spec: "team=a=b", want: Set{"team": "a=b"}},
+		{spec: "environment", wantErr: true},
+		{spec: "environment=", wantErr: true},
+		{spec: "1env=prod", wantErr: true},
+		{spec: "my-env=prod", wantErr: true},
+		{spec: "__name__=x", wantErr: true},
+		{spec: "deployment_name=x", wantErr: true},
+		{spec: "region=eu,region=us", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			got, err := Parse(tt.spec)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Parse(%q) error = %v, wantErr %v", tt.spec, err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Parse(%q) = %v, want %v", tt.spec, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSet(t *testing.T) {
+	s := Set{"region": "eu", "environment": "prod"}
+
+	if got := s.String(); got != "environment=prod,region=eu" {
+		t.Errorf("String() = %q", got)
+	}
+
+	m := map[string]string{"region": "us", "pod": "web-1"}
+	s.AddTo(m)
+	if want := map[string]string{"region": "us", "pod": "web-1", "environment": "prod"}; !reflect.DeepEqual(m, want) {
+		t.Errorf("AddTo() = %v, want %v", m, want)
+	}
+
+	var none Set
+	none.AddTo(m)
+	if none.String() != "" || len(none.Names()) != 0 || len(m) != 3 {
+		t.Errorf("nil Set should have no labels")
+	}
+}
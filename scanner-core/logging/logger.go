@@ -2,9 +2,12 @@
 //
 // Usage:
 //
-//	// Initialize once at startup
+//	// Initialize once at startup (LOG_LEVEL/LOG_FORMAT override the arguments)
 //	logging.Init(slog.LevelInfo, false)
 //
+//	// Or, when embedding scanner-core, configure explicitly without env reads
+//	logging.Configure(logging.Options{Level: slog.LevelDebug, JSON: true})
+//
 //	// Get a logger for a component
 //	log := logging.For(logging.ComponentQueue)
 //	log.Info("processing job", "image", image, "node", nodeName)
@@ -14,6 +17,7 @@
 package logging
 
 import (
+	"fmt"
 	"io"
 	"log/slog"
 	"os"
@@ -36,47 +40,55 @@ const (
 	ComponentMetrics          = "metrics"
 	ComponentJobs             = "jobs"
 	ComponentVulnDB           = "vulndb"
+	ComponentResultCache      = "result-cache"
+	ComponentFixHints         = "fix-hints"
+	ComponentDiskUsage        = "disk-usage"
+	ComponentOSEOL            = "os-eol"
+	ComponentAdHoc            = "ad-hoc"
+	ComponentNotify           = "notify"
+	ComponentProvenance       = "provenance"
+	ComponentAdmission        = "admission"
+	ComponentRegistryCrawl    = "registry-crawl"
 )
 
 var (
 	defaultLogger *slog.Logger
 	once          sync.Once
 	mu            sync.RWMutex
+	current       = Options{Level: slog.LevelInfo}
+	levelVar      = new(slog.LevelVar) // Lets SetLevel change the level of the configured handler
 )
 
-// Init initializes the default logger with the specified level and format.
-// Should be called once at application startup.
-//
-// Parameters:
-//   - level: The minimum log level (slog.LevelDebug, slog.LevelInfo, etc.)
-//   - jsonFormat: If true, output JSON format; if false, output text format
-//
-// Environment variable overrides:
-//   - LOG_LEVEL: debug, info, warn, error (overrides level parameter)
-//   - LOG_FORMAT: text, json (overrides jsonFormat parameter)
-func Init(level slog.Level, jsonFormat bool) {
+// Options configures the default logger. Embedders that manage their own
+// configuration use Configure with Options instead of the env-reading Init
+// variants.
+type Options struct {
+	Level  slog.Level
+	JSON   bool      // JSON output instead of text
+	Output io.Writer // defaults to os.Stderr
+}
+
+// Configure initializes the default logger from opts without consulting the
+// environment. Like Init, only the first initialization takes effect.
+func Configure(opts Options) {
 	once.Do(func() {
-		// Check environment variable overrides
-		if envLevel := os.Getenv("LOG_LEVEL"); envLevel != "" {
-			level = parseLevel(envLevel)
-		}
-		if envFormat := os.Getenv("LOG_FORMAT"); envFormat != "" {
-			jsonFormat = strings.ToLower(envFormat) == "json"
+		if opts.Output == nil {
+			opts.Output = os.Stderr
 		}
 
-		opts := &slog.HandlerOptions{
-			Level: level,
-		}
+		levelVar.Set(opts.Level)
+		handlerOpts := &slog.HandlerOptions{Level: levelVar}
 
 		var handler slog.Handler
-		if jsonFormat {
-			handler = slog.NewJSONHandler(os.Stderr, opts)
+		if opts.JSON {
+			handler = slog.NewJSONHandler(opts.Output, handlerOpts)
 		} else {
-			handler = slog.NewTextHandler(os.Stderr, opts)
+			handler = slog.NewTextHandler(opts.Output, handlerOpts)
 		}
 
 		mu.Lock()
 		defaultLogger = slog.New(handler)
+		current = opts
 		mu.Unlock()
 
 		// Also set as default slog logger for stdlib compatibility
@@ -84,35 +96,38 @@ func Init(level slog.Level, jsonFormat bool) {
 	})
 }
 
+// optionsFromEnv applies LOG_LEVEL and LOG_FORMAT overrides to opts
+func optionsFromEnv(opts Options) Options {
+	if envLevel := os.Getenv("LOG_LEVEL"); envLevel != "" {
+		opts.Level = parseLevel(envLevel)
+	}
+	if envFormat := os.Getenv("LOG_FORMAT"); envFormat != "" {
+		opts.JSON = strings.ToLower(envFormat) == "json"
+	}
+	return opts
+}
+
+// Init initializes the default logger with the specified level and format.
+// Should be called once at application startup.
+//
+// Parameters:
+//   - level: The minimum log level (slog.LevelDebug, slog.LevelInfo, etc.)
+//   - jsonFormat: If true, output JSON format; if false, output text format
+//
+// Environment variable overrides:
+//   - LOG_LEVEL: debug, info, warn, error (overrides level parameter)
+//   - LOG_FORMAT: text, json (overrides jsonFormat parameter)
+func Init(level slog.Level, jsonFormat bool) {
+	Configure(optionsFromEnv(Options{Level: level, JSON: jsonFormat}))
+}
+
 // InitWithWriter initializes the logger writing to w instead of os.Stderr.
 // Use this to tee logs to multiple destinations (e.g. journald + file):
 //
 //	w := io.MultiWriter(os.Stderr, logFile)
 //	logging.InitWithWriter(w, slog.LevelInfo, false)
 func InitWithWriter(w io.Writer, level slog.Level, jsonFormat bool) {
-	once.Do(func() {
-		if envLevel := os.Getenv("LOG_LEVEL"); envLevel != "" {
-			level = parseLevel(envLevel)
-		}
-		if envFormat := os.Getenv("LOG_FORMAT"); envFormat != "" {
-			jsonFormat = strings.ToLower(envFormat) == "json"
-		}
-
-		opts := &slog.HandlerOptions{Level: level}
-
-		var handler slog.Handler
-		if jsonFormat {
-			handler = slog.NewJSONHandler(w, opts)
-		} else {
-			handler = slog.NewTextHandler(w, opts)
-		}
-
-		mu.Lock()
-		defaultLogger = slog.New(handler)
-		mu.Unlock()
-
-		slog.SetDefault(defaultLogger)
-	})
+	Configure(optionsFromEnv(Options{Level: level, JSON: jsonFormat, Output: w}))
 }
 
 // InitFromEnv initializes the logger using only environment variables.
@@ -166,34 +181,65 @@ func Default() *slog.Logger {
 	return logger
 }
 
-// parseLevel converts a string level to slog.Level
+// parseLevel converts a string level to slog.Level, defaulting to INFO
 func parseLevel(s string) slog.Level {
-	switch strings.ToLower(s) {
+	level, err := ParseLevel(s)
+	if err != nil {
+		return slog.LevelInfo
+	}
+	return level
+}
+
+// ParseLevel converts debug, info, warn or error to slog.Level
+func ParseLevel(s string) (slog.Level, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
 	case "debug":
-		return slog.LevelDebug
+		return slog.LevelDebug, nil
 	case "info":
-		return slog.LevelInfo
+		return slog.LevelInfo, nil
 	case "warn", "warning":
-		return slog.LevelWarn
+		return slog.LevelWarn, nil
 	case "error":
-		return slog.LevelError
+		return slog.LevelError, nil
 	default:
-		return slog.LevelInfo
+		return slog.LevelInfo, fmt.Errorf("unknown log level %q (want debug, info, warn or error)", s)
 	}
 }
 
-// GetLevel returns the current log level as a string
+// SetLevel changes the minimum level of the default logger at runtime,
+// including loggers already returned by For.
+func SetLevel(level slog.Level) {
+	// Make sure the default logger exists so a later Init cannot reset the level
+	Default()
+
+	mu.Lock()
+	current.Level = level
+	mu.Unlock()
+	levelVar.Set(level)
+}
+
+// GetLevel returns the configured log level as a string
 func GetLevel() string {
-	if envLevel := os.Getenv("LOG_LEVEL"); envLevel != "" {
-		return strings.ToLower(envLevel)
+	mu.RLock()
+	defer mu.RUnlock()
+	switch {
+	case current.Level <= slog.LevelDebug:
+		return "debug"
+	case current.Level <= slog.LevelInfo:
+		return "info"
+	case current.Level <= slog.LevelWarn:
+		return "warn"
+	default:
+		return "error"
 	}
-	return "info"
 }
 
-// GetFormat returns the current log format as a string
+// GetFormat returns the configured log format as a string
 func GetFormat() string {
-	if envFormat := os.Getenv("LOG_FORMAT"); envFormat != "" {
-		return strings.ToLower(envFormat)
+	mu.RLock()
+	defer mu.RUnlock()
+	if current.JSON {
+		return "json"
 	}
 	return "text"
 }
@@ -62,7 +62,9 @@ func collectMetrics(
 	var batch []database.StalenessRow
 
 	// record emits one live data point and queues it for staleness tracking.
+	// Static labels are added here so that every family carries them.
 	record := func(familyName string, labels map[string]string, value float64) error {
+		config.StaticLabels.AddTo(labels)
 		help := familyMeta[familyName][0]
 		emit(familyName, help, labels, value)
 
@@ -146,6 +148,7 @@ func collectMetrics(
 		for _, sc := range statusCounts {
 			labels := map[string]string{
 				"deployment_uuid": deploymentUUID,
+				"deployment_name": deploymentName,
 				"scan_status":     sc.Status,
 			}
 			if err := record("bjorn2scan_image_scan_status", labels, float64(sc.Count)); err != nil {
@@ -176,6 +179,7 @@ func collectMetrics(
 		for _, sc := range statusCounts {
 			labels := map[string]string{
 				"deployment_uuid": deploymentUUID,
+				"deployment_name": deploymentName,
 				"scan_status":     sc.Status,
 			}
 			if err := record("bjorn2scan_node_scan_status", labels, float64(sc.Count)); err != nil {
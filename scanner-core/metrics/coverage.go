@@ -0,0 +1,36 @@
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/bvboe/b2s-go/scanner-core/database"
+)
+
+// RegisterCoverageMetrics publishes scan coverage gauges on /metrics: of all
+// digests of running containers plus digests seen in completed Jobs within the
+// lookback window, how many have a completed scan.
+func RegisterCoverageMetrics(db *database.DB, lookback time.Duration) {
+	RegisterExtraWriter(func(w io.Writer) {
+		coverage, err := db.GetScanCoverage(time.Now().Add(-lookback))
+		if err != nil {
+			log.Warn("failed to compute scan coverage metrics", "error", err)
+			return
+		}
+		writeCoverageMetrics(w, coverage)
+	})
+}
+
+// writeCoverageMetrics emits the scan coverage gauges in Prometheus text format
+func writeCoverageMetrics(w io.Writer, coverage *database.ScanCoverage) {
+	_, _ = fmt.Fprintf(w, "# HELP bjorn2scan_scan_coverage_ratio Fraction of observed image digests (running and recent Jobs) with a completed scan\n")
+	_, _ = fmt.Fprintf(w, "# TYPE bjorn2scan_scan_coverage_ratio gauge\n")
+	_, _ = fmt.Fprintf(w, "bjorn2scan_scan_coverage_ratio %g\n", coverage.CoveragePercent/100)
+	_, _ = fmt.Fprintf(w, "# HELP bjorn2scan_scan_coverage_observed_digests Image digests observed in running containers and recent Jobs\n")
+	_, _ = fmt.Fprintf(w, "# TYPE bjorn2scan_scan_coverage_observed_digests gauge\n")
+	_, _ = fmt.Fprintf(w, "bjorn2scan_scan_coverage_observed_digests %d\n", coverage.ObservedDigests)
+	_, _ = fmt.Fprintf(w, "# HELP bjorn2scan_scan_coverage_scanned_digests Observed image digests with a completed scan\n")
+	_, _ = fmt.Fprintf(w, "# TYPE bjorn2scan_scan_coverage_scanned_digests gauge\n")
+	_, _ = fmt.Fprintf(w, "bjorn2scan_scan_coverage_scanned_digests %d\n", coverage.ScannedDigests)
+}
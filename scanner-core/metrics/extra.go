@@ -0,0 +1,112 @@
+package metrics
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"sync"
+)
+
+// ExtraWriter writes additional metric families in Prometheus text format.
+// Writers must emit their own HELP/TYPE lines and should write nothing when
+// they have no data yet.
+type ExtraWriter func(w io.Writer)
+
+var (
+	extraWritersMu sync.RWMutex
+	extraWriters   []ExtraWriter
+)
+
+// RegisterExtraWriter adds a writer that is appended to every /metrics response,
+// after the database operation and HTTP request metrics. Used by components outside this
+// package (e.g. the k8s watchers) to publish their own operational metrics.
+func RegisterExtraWriter(fn ExtraWriter) {
+	extraWritersMu.Lock()
+	defer extraWritersMu.Unlock()
+	extraWriters = append(extraWriters, fn)
+}
+
+// writeExtraMetrics calls all registered extra writers in registration order.
+func writeExtraMetrics(w io.Writer) {
+	extraWritersMu.RLock()
+	writers := make([]ExtraWriter, len(extraWriters))
+	copy(writers, extraWriters)
+	extraWritersMu.RUnlock()
+
+	for _, fn := range writers {
+		fn(w)
+	}
+}
+
+// labelWriter adds labels to every sample line written through it, so that
+// operational metrics written by other packages carry the deployment and
+// static labels like the metric families built here. Labels a sample already
+// has are kept. Comment lines are passed through unchanged. Writes are
+// buffered per line; call Flush after the last write.
+type labelWriter struct {
+	w       io.Writer
+	labels  map[string]string
+	partial []byte
+}
+
+func newLabelWriter(w io.Writer, labels map[string]string) *labelWriter {
+	return &labelWriter{w: w, labels: labels}
+}
+
+func (lw *labelWriter) Write(p []byte) (int, error) {
+	lw.partial = append(lw.partial, p...)
+	for {
+		i := bytes.IndexByte(lw.partial, '\n')
+		if i < 0 {
+			break
+		}
+		if _, err := io.WriteString(lw.w, lw.addLabels(string(lw.partial[:i]))+"\n"); err != nil {
+			return 0, err
+		}
+		lw.partial = lw.partial[i+1:]
+	}
+	return len(p), nil
+}
+
+// Flush writes a trailing line that was not terminated by a newline
+func (lw *labelWriter) Flush() error {
+	if len(lw.partial) == 0 {
+		return nil
+	}
+	_, err := io.WriteString(lw.w, lw.addLabels(string(lw.partial)))
+	lw.partial = nil
+	return err
+}
+
+// addLabels returns the sample line with the writer's labels added
+func (lw *labelWriter) addLabels(line string) string {
+	if len(lw.labels) == 0 || line == "" || strings.HasPrefix(line, "#") {
+		return line
+	}
+	nameEnd := strings.IndexAny(line, "{ ")
+	if nameEnd < 0 {
+		return line
+	}
+	if line[nameEnd] == ' ' {
+		return line[:nameEnd] + "{" + formatLabels(lw.labels) + "}" + line[nameEnd:]
+	}
+
+	closing := strings.LastIndexByte(line, '}')
+	if closing < nameEnd {
+		return line
+	}
+	existing := line[nameEnd+1 : closing]
+	missing := make(map[string]string, len(lw.labels))
+	for name, value := range lw.labels {
+		if !strings.HasPrefix(existing, name+"=") && !strings.Contains(existing, ","+name+"=") {
+			missing[name] = value
+		}
+	}
+	if len(missing) == 0 {
+		return line
+	}
+	if existing != "" && !strings.HasSuffix(existing, ",") {
+		existing += ","
+	}
+	return line[:nameEnd+1] + existing + formatLabels(missing) + line[closing:]
+}
@@ -5,6 +5,7 @@ import (
 	"time"
 
 	"github.com/bvboe/b2s-go/scanner-core/logging"
+	"github.com/bvboe/b2s-go/scanner-core/routes"
 )
 
 var log = logging.For(logging.ComponentMetrics)
@@ -61,13 +62,18 @@ func NewMetricsHandler(
 
 // RegisterMetricsHandler registers the /metrics endpoint using the new unified handler.
 func RegisterMetricsHandler(
-	mux *http.ServeMux,
+	reg *routes.Registry,
 	info InfoProvider,
 	deploymentUUID string,
 	provider StreamingProvider,
 	config UnifiedConfig,
 	staleness *StalenessStore,
 ) {
-	mux.HandleFunc("/metrics", NewMetricsHandler(info, deploymentUUID, provider, config, staleness))
+	reg.Handle(routes.Route{
+		Pattern: "/metrics",
+		Methods: routes.GET,
+		Handler: NewMetricsHandler(info, deploymentUUID, provider, config, staleness),
+		Role:    routes.RolePublic,
+	})
 	log.Info("metrics handler registered at /metrics")
 }
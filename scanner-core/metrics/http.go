@@ -0,0 +1,179 @@
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// httpBounds are the upper bounds (seconds) of the request duration buckets
+var httpBounds = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30}
+
+// httpRouteKey identifies a request duration histogram
+type httpRouteKey struct {
+	route  string
+	method string
+}
+
+// httpCountKey identifies a request counter
+type httpCountKey struct {
+	route  string
+	method string
+	code   int
+}
+
+// httpRouteHistogram is the duration histogram of one route and method
+type httpRouteHistogram struct {
+	count   uint64
+	sum     float64
+	buckets []uint64 // one per httpBounds entry
+}
+
+// httpMetrics holds the per-route request counters and duration histograms
+type httpMetrics struct {
+	mu        sync.Mutex
+	requests  map[httpCountKey]uint64
+	durations map[httpRouteKey]*httpRouteHistogram
+}
+
+var httpStats = &httpMetrics{
+	requests:  make(map[httpCountKey]uint64),
+	durations: make(map[httpRouteKey]*httpRouteHistogram),
+}
+
+func (m *httpMetrics) observe(route, method string, code int, seconds float64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.requests[httpCountKey{route, method, code}]++
+
+	key := httpRouteKey{route, method}
+	h := m.durations[key]
+	if h == nil {
+		h = &httpRouteHistogram{buckets: make([]uint64, len(httpBounds))}
+		m.durations[key] = h
+	}
+	h.count++
+	h.sum += seconds
+	for i, b := range httpBounds {
+		if seconds <= b {
+			h.buckets[i]++
+		}
+	}
+}
+
+// InstrumentHandler records the count, status and duration of every request
+// served by next, by route. next should be (or wrap) the http.ServeMux the
+// handlers are registered on: the route is the mux pattern that matched, e.g.
+// "/api/images/", so that paths with digests do not create new series.
+// Requests no pattern matched are recorded under route "unmatched".
+func InstrumentHandler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		sw := &statusWriter{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(sw, r)
+
+		route := r.Pattern
+		if route == "" {
+			route = "unmatched"
+		}
+		httpStats.observe(route, normalizeMethod(r.Method), sw.status, time.Since(start).Seconds())
+	})
+}
+
+// normalizeMethod bounds the method label to the standard HTTP methods
+func normalizeMethod(method string) string {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch,
+		http.MethodDelete, http.MethodOptions:
+		return method
+	}
+	return "OTHER"
+}
+
+// statusWriter captures the status code written by a handler
+type statusWriter struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+}
+
+func (sw *statusWriter) WriteHeader(status int) {
+	if !sw.wroteHeader {
+		sw.status = status
+		sw.wroteHeader = true
+	}
+	sw.ResponseWriter.WriteHeader(status)
+}
+
+func (sw *statusWriter) Write(b []byte) (int, error) {
+	sw.wroteHeader = true
+	return sw.ResponseWriter.Write(b)
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer (e.g. to flush)
+func (sw *statusWriter) Unwrap() http.ResponseWriter {
+	return sw.ResponseWriter
+}
+
+// WriteHTTPMetrics writes the per-route HTTP request counters and duration
+// histograms in Prometheus text format. Nothing is written before the first
+// instrumented request.
+func WriteHTTPMetrics(w io.Writer) {
+	httpStats.write(w)
+}
+
+func (m *httpMetrics) write(w io.Writer) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if len(m.requests) == 0 {
+		return
+	}
+
+	counts := make([]httpCountKey, 0, len(m.requests))
+	for key := range m.requests {
+		counts = append(counts, key)
+	}
+	sort.Slice(counts, func(i, j int) bool {
+		a, b := counts[i], counts[j]
+		if a.route != b.route {
+			return a.route < b.route
+		}
+		if a.method != b.method {
+			return a.method < b.method
+		}
+		return a.code < b.code
+	})
+	_, _ = fmt.Fprintf(w, "# HELP bjorn2scan_http_requests_total HTTP requests served, by route, method and status code\n")
+	_, _ = fmt.Fprintf(w, "# TYPE bjorn2scan_http_requests_total counter\n")
+	for _, key := range counts {
+		_, _ = fmt.Fprintf(w, "bjorn2scan_http_requests_total{route=%q,method=%q,code=%q} %d\n",
+			key.route, key.method, strconv.Itoa(key.code), m.requests[key])
+	}
+
+	routes := make([]httpRouteKey, 0, len(m.durations))
+	for key := range m.durations {
+		routes = append(routes, key)
+	}
+	sort.Slice(routes, func(i, j int) bool {
+		if routes[i].route != routes[j].route {
+			return routes[i].route < routes[j].route
+		}
+		return routes[i].method < routes[j].method
+	})
+	_, _ = fmt.Fprintf(w, "# HELP bjorn2scan_http_request_duration_seconds Time serving HTTP requests, by route and method\n")
+	_, _ = fmt.Fprintf(w, "# TYPE bjorn2scan_http_request_duration_seconds histogram\n")
+	for _, key := range routes {
+		h := m.durations[key]
+		labels := fmt.Sprintf("route=%q,method=%q", key.route, key.method)
+		for i, b := range httpBounds {
+			_, _ = fmt.Fprintf(w, "bjorn2scan_http_request_duration_seconds_bucket{%s,le=\"%g\"} %d\n", labels, b, h.buckets[i])
+		}
+		_, _ = fmt.Fprintf(w, "bjorn2scan_http_request_duration_seconds_bucket{%s,le=\"+Inf\"} %d\n", labels, h.count)
+		_, _ = fmt.Fprintf(w, "bjorn2scan_http_request_duration_seconds_sum{%s} %g\n", labels, h.sum)
+		_, _ = fmt.Fprintf(w, "bjorn2scan_http_request_duration_seconds_count{%s} %d\n", labels, h.count)
+	}
+}
@@ -0,0 +1,56 @@
+package metrics
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestInstrumentHandler(t *testing.T) {
+	// Other tests expect no HTTP metrics in /metrics output
+	saved := httpStats
+	httpStats = &httpMetrics{
+		requests:  make(map[httpCountKey]uint64),
+		durations: make(map[httpRouteKey]*httpRouteHistogram),
+	}
+	defer func() { httpStats = saved }()
+
+	var buf bytes.Buffer
+	WriteHTTPMetrics(&buf)
+	if buf.Len() != 0 {
+		t.Fatalf("expected no output before the first request, got:\n%s", buf.String())
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/images/", func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "missing") {
+			http.Error(w, "Image not found", http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write([]byte("{}"))
+	})
+	handler := InstrumentHandler(mux)
+
+	for _, path := range []string{"/api/images/sha256:abc", "/api/images/sha256:def", "/api/images/missing", "/nope"} {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("PROPFIND", "/api/images/sha256:abc", nil))
+
+	buf.Reset()
+	WriteHTTPMetrics(&buf)
+	out := buf.String()
+	for _, want := range []string{
+		`bjorn2scan_http_requests_total{route="/api/images/",method="GET",code="200"} 2`,
+		`bjorn2scan_http_requests_total{route="/api/images/",method="GET",code="404"} 1`,
+		`bjorn2scan_http_requests_total{route="/api/images/",method="OTHER",code="200"} 1`,
+		`bjorn2scan_http_requests_total{route="unmatched",method="GET",code="404"} 1`,
+		`bjorn2scan_http_request_duration_seconds_count{route="/api/images/",method="GET"} 3`,
+		`bjorn2scan_http_request_duration_seconds_bucket{route="/api/images/",method="GET",le="+Inf"} 3`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("missing %q in:\n%s", want, out)
+		}
+	}
+}
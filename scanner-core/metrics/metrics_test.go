@@ -1,11 +1,13 @@
 package metrics
 
 import (
+	"io"
 	"strings"
 	"testing"
 	"time"
 
 	"github.com/bvboe/b2s-go/scanner-core/database"
+	"github.com/bvboe/b2s-go/scanner-core/labels"
 	"github.com/bvboe/b2s-go/scanner-core/nodes"
 )
 
@@ -282,6 +284,63 @@ func TestStreamMetrics_NodeScanStatus(t *testing.T) {
 	}
 }
 
+func TestStreamMetrics_StaticLabels(t *testing.T) {
+	info := &MockInfoProvider{deploymentName: "cluster", deploymentType: "kubernetes", version: "1.0.0"}
+	provider := newMockStreamingProvider()
+	provider.nodeScanStatuses = []database.NodeScanStatusCount{{Status: "completed", Count: 5}}
+	config := UnifiedConfig{
+		DeploymentEnabled:     true,
+		NodeScanStatusEnabled: true,
+		StaticLabels:          labels.Set{"environment": "prod", "deployment_type": "ignored"},
+	}
+
+	output := streamMetricsToString(t, info, "uuid", provider, config, nil)
+
+	for _, family := range []string{"bjorn2scan_deployment{", "bjorn2scan_node_scan_status{"} {
+		line := findLine(output, family)
+		if !strings.Contains(line, `environment="prod"`) || !strings.Contains(line, `deployment_name="cluster"`) {
+			t.Errorf("Expected static and deployment labels on %s got %q", family, line)
+		}
+	}
+	// Labels a metric sets itself win over static labels
+	if line := findLine(output, "bjorn2scan_deployment{"); !strings.Contains(line, `deployment_type="kubernetes"`) {
+		t.Errorf("Expected built-in deployment_type label, got %q", line)
+	}
+}
+
+// findLine returns the first line of output starting with prefix
+func findLine(output, prefix string) string {
+	for _, line := range strings.Split(output, "\n") {
+		if strings.HasPrefix(line, prefix) {
+			return line
+		}
+	}
+	return ""
+}
+
+func TestLabelWriter(t *testing.T) {
+	var buf strings.Builder
+	lw := newLabelWriter(&buf, map[string]string{"deployment_uuid": "uuid", "environment": "prod"})
+
+	// Lines may arrive in several writes
+	_, _ = io.WriteString(lw, "# HELP op_seconds Operation time\n# TYPE op_seconds histogram\nop_seconds_bucket{operation=\"x\",le=\"1\"} 2\nop_")
+	_, _ = io.WriteString(lw, "seconds_count 2\nop_total{environment=\"dev\"} 1\n\nlast 3")
+	if err := lw.Flush(); err != nil {
+		t.Fatalf("Flush returned error: %v", err)
+	}
+
+	want := "# HELP op_seconds Operation time\n" +
+		"# TYPE op_seconds histogram\n" +
+		"op_seconds_bucket{operation=\"x\",le=\"1\",deployment_uuid=\"uuid\",environment=\"prod\"} 2\n" +
+		"op_seconds_count{deployment_uuid=\"uuid\",environment=\"prod\"} 2\n" +
+		"op_total{environment=\"dev\",deployment_uuid=\"uuid\"} 1\n" +
+		"\n" +
+		"last{deployment_uuid=\"uuid\",environment=\"prod\"} 3"
+	if got := buf.String(); got != want {
+		t.Errorf("labelWriter output =\n%s\nwant\n%s", got, want)
+	}
+}
+
 func TestStreamMetrics_NaNForStaleRows(t *testing.T) {
 	info := &MockInfoProvider{deploymentName: "cluster", deploymentType: "kubernetes", version: "1.0.0"}
 	provider := newMockStreamingProvider()
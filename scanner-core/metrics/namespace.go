@@ -0,0 +1,203 @@
+package metrics
+
+import (
+	"bufio"
+	"crypto/subtle"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/bvboe/b2s-go/scanner-core/database"
+	"github.com/bvboe/b2s-go/scanner-core/routes"
+)
+
+// namespaceMetricsPrefix is the path prefix of the namespace-scoped endpoints
+const namespaceMetricsPrefix = "/metrics/namespace/"
+
+// allNamespaces grants a token access to every namespace
+const allNamespaces = "*"
+
+// NamespaceTokens maps namespaces to the bearer tokens allowed to scrape them.
+// An empty NamespaceTokens leaves the namespace endpoints unauthenticated, like /metrics.
+type NamespaceTokens map[string][]string
+
+// ParseNamespaceTokens parses a list of namespace=token entries separated by
+// commas or newlines. A namespace may have several tokens and the namespace "*"
+// grants access to all namespaces.
+func ParseNamespaceTokens(spec string) (NamespaceTokens, error) {
+	tokens := make(NamespaceTokens)
+	for _, entry := range strings.FieldsFunc(spec, func(r rune) bool { return r == ',' || r == '\n' }) {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		namespace, token, ok := strings.Cut(entry, "=")
+		namespace, token = strings.TrimSpace(namespace), strings.TrimSpace(token)
+		if !ok || namespace == "" || token == "" {
+			return nil, fmt.Errorf("invalid namespace token entry %q: expected namespace=token", entry)
+		}
+		tokens[namespace] = append(tokens[namespace], token)
+	}
+	return tokens, nil
+}
+
+// Allowed reports whether token may scrape namespace
+func (t NamespaceTokens) Allowed(namespace, token string) bool {
+	if len(t) == 0 {
+		return true
+	}
+	if token == "" {
+		return false
+	}
+	for _, ns := range []string{namespace, allNamespaces} {
+		for _, candidate := range t[ns] {
+			if subtle.ConstantTimeCompare([]byte(candidate), []byte(token)) == 1 {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// namespaceConfig restricts config to the families that carry a namespace label
+func namespaceConfig(config UnifiedConfig) UnifiedConfig {
+	return UnifiedConfig{
+		DeploymentEnabled:             config.DeploymentEnabled,
+		ScannedContainersEnabled:      config.ScannedContainersEnabled,
+		VulnerabilitiesEnabled:        config.VulnerabilitiesEnabled,
+		VulnerabilityExploitedEnabled: config.VulnerabilityExploitedEnabled,
+		VulnerabilityRiskEnabled:      config.VulnerabilityRiskEnabled,
+		StalenessWindow:               config.StalenessWindow,
+		StaticLabels:                  config.StaticLabels,
+	}
+}
+
+// StreamNamespaceMetrics writes the container metric families of one namespace
+// to w in Prometheus text format, plus the deployment metric so series can be
+// joined with deployment information. Node metrics, cluster-wide counts and
+// operational metrics are omitted. Stale series of the namespace are emitted
+// as NaN, like on /metrics.
+func StreamNamespaceMetrics(
+	w io.Writer,
+	info InfoProvider,
+	deploymentUUID string,
+	provider StreamingProvider,
+	config UnifiedConfig,
+	namespace string,
+	staleRows []database.StalenessRow,
+	cycleStart time.Time,
+) error {
+	bw := bufio.NewWriterSize(w, 64*1024)
+	writtenFamilies := make(map[string]bool)
+	var writeErr error
+
+	emit := func(familyName, _ string, labels map[string]string, value float64) {
+		if writeErr != nil {
+			return
+		}
+		if familyName != "bjorn2scan_deployment" && labels["namespace"] != namespace {
+			return
+		}
+		if !writtenFamilies[familyName] {
+			writtenFamilies[familyName] = true
+			meta := familyMeta[familyName]
+			if _, writeErr = fmt.Fprintf(bw, "# HELP %s %s\n# TYPE %s %s\n", familyName, meta[0], familyName, meta[1]); writeErr != nil {
+				return
+			}
+		}
+		if math.IsNaN(value) {
+			_, writeErr = fmt.Fprintf(bw, "%s{%s} NaN\n", familyName, formatLabels(labels))
+		} else {
+			_, writeErr = fmt.Fprintf(bw, "%s{%s} %g\n", familyName, formatLabels(labels), value)
+		}
+	}
+
+	// The staleness batch is discarded: the store tracks the full /metrics view,
+	// and applying a partial view would mark every other namespace stale.
+	if _, err := collectMetrics(provider, namespaceConfig(config), info, deploymentUUID, info.GetDeploymentName(),
+		cycleStart.Unix(), staleRows, 0, emit, nil); err != nil {
+		return err
+	}
+	if writeErr != nil {
+		return writeErr
+	}
+	return bw.Flush()
+}
+
+// bearerToken returns the bearer token of the request, or "" if there is none
+func bearerToken(r *http.Request) string {
+	const prefix = "Bearer "
+	auth := r.Header.Get("Authorization")
+	if len(auth) > len(prefix) && strings.EqualFold(auth[:len(prefix)], prefix) {
+		return strings.TrimSpace(auth[len(prefix):])
+	}
+	return ""
+}
+
+// NewNamespaceMetricsHandler returns an HTTP handler for /metrics/namespace/{name}.
+// It gives each team a scrape target with only the container metrics of their
+// namespace. When tokens is non-empty, requests must carry a bearer token
+// granted for the namespace.
+func NewNamespaceMetricsHandler(
+	info InfoProvider,
+	deploymentUUID string,
+	provider StreamingProvider,
+	config UnifiedConfig,
+	staleness *StalenessStore,
+	tokens NamespaceTokens,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		namespace := strings.TrimPrefix(r.URL.Path, namespaceMetricsPrefix)
+		if namespace == "" || strings.Contains(namespace, "/") {
+			http.Error(w, "Namespace required", http.StatusNotFound)
+			return
+		}
+
+		if !tokens.Allowed(namespace, bearerToken(r)) {
+			log.Warn("rejected namespace metrics request", "namespace", namespace, "remote_addr", r.RemoteAddr)
+			w.Header().Set("WWW-Authenticate", `Bearer realm="bjorn2scan"`)
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		cycleStart := time.Now()
+		staleRows, err := staleness.QueryStale(cycleStart)
+		if err != nil {
+			log.Error("failed to query stale metrics", "error", err)
+		}
+
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		if err := StreamNamespaceMetrics(w, info, deploymentUUID, provider, config, namespace, staleRows, cycleStart); err != nil {
+			log.Error("error streaming namespace metrics", "namespace", namespace, "error", err)
+		}
+		log.Debug("namespace metrics stream complete", "namespace", namespace, "duration_ms", time.Since(cycleStart).Milliseconds())
+	}
+}
+
+// RegisterNamespaceMetricsHandler registers the /metrics/namespace/{name} endpoints
+func RegisterNamespaceMetricsHandler(
+	reg *routes.Registry,
+	info InfoProvider,
+	deploymentUUID string,
+	provider StreamingProvider,
+	config UnifiedConfig,
+	staleness *StalenessStore,
+	tokens NamespaceTokens,
+) {
+	// Public to the registry: the endpoints check their own per-namespace tokens
+	reg.Handle(routes.Route{
+		Pattern: namespaceMetricsPrefix,
+		Methods: routes.GET,
+		Handler: NewNamespaceMetricsHandler(info, deploymentUUID, provider, config, staleness, tokens),
+		Role:    routes.RolePublic,
+	})
+	log.Info("namespace metrics handler registered at "+namespaceMetricsPrefix+"{name}", "authenticated", len(tokens) > 0)
+}
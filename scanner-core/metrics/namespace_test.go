@@ -0,0 +1,125 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/bvboe/b2s-go/scanner-core/database"
+	"github.com/bvboe/b2s-go/scanner-core/nodes"
+)
+
+func newNamespaceTestProvider() *MockStreamingProvider {
+	provider := newMockStreamingProvider()
+	provider.containers = []database.ScannedContainer{
+		{Namespace: "team-a", Pod: "web", Name: "nginx", NodeName: "node-1", Reference: "nginx:1.25", Digest: "sha256:a"},
+		{Namespace: "team-b", Pod: "db", Name: "postgres", NodeName: "node-1", Reference: "postgres:16", Digest: "sha256:b"},
+	}
+	provider.vulns = []database.ContainerVulnerability{
+		{Namespace: "team-a", Pod: "web", Name: "nginx", CVEID: "CVE-2024-0001", Severity: "High", Count: 1},
+		{Namespace: "team-b", Pod: "db", Name: "postgres", CVEID: "CVE-2024-0002", Severity: "Critical", Count: 1},
+	}
+	provider.scanStatuses = []database.ImageScanStatusCount{{Status: "completed", Count: 2}}
+	provider.scannedNodes = []nodes.NodeWithStatus{{Node: nodes.Node{Name: "node-1"}}}
+	return provider
+}
+
+var namespaceTestConfig = UnifiedConfig{
+	DeploymentEnabled:        true,
+	ScannedContainersEnabled: true,
+	VulnerabilitiesEnabled:   true,
+	ImageScanStatusEnabled:   true,
+	NodeScannedEnabled:       true,
+}
+
+func TestNamespaceMetricsHandler_FiltersNamespace(t *testing.T) {
+	info := &MockInfoProvider{deploymentName: "cluster", deploymentType: "kubernetes", version: "1.0.0"}
+	provider := newNamespaceTestProvider()
+	staleness := newTestStalenessStore(provider)
+
+	handler := NewNamespaceMetricsHandler(info, "uuid", provider, namespaceTestConfig, staleness, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/metrics/namespace/team-a", nil)
+	w := httptest.NewRecorder()
+	handler(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", w.Code)
+	}
+	body := w.Body.String()
+	for _, want := range []string{"bjorn2scan_deployment{", `namespace="team-a"`, "CVE-2024-0001"} {
+		if !strings.Contains(body, want) {
+			t.Errorf("Expected %q in output:\n%s", want, body)
+		}
+	}
+	for _, unwanted := range []string{`namespace="team-b"`, "CVE-2024-0002", "bjorn2scan_image_scan_status", "bjorn2scan_node_", "bjorn2scan_db_"} {
+		if strings.Contains(body, unwanted) {
+			t.Errorf("Did not expect %q in output:\n%s", unwanted, body)
+		}
+	}
+
+	// A partial view must not be recorded as the staleness state
+	provider.stalenessDB.mu.Lock()
+	defer provider.stalenessDB.mu.Unlock()
+	if len(provider.stalenessDB.upserts) != 0 {
+		t.Errorf("Expected no staleness upserts, got %d", len(provider.stalenessDB.upserts))
+	}
+}
+
+func TestNamespaceMetricsHandler_Auth(t *testing.T) {
+	info := &MockInfoProvider{deploymentName: "cluster", deploymentType: "kubernetes", version: "1.0.0"}
+	provider := newNamespaceTestProvider()
+	staleness := newTestStalenessStore(provider)
+
+	tokens, err := ParseNamespaceTokens("team-a=token-a, team-b=token-b\n*=admin")
+	if err != nil {
+		t.Fatalf("ParseNamespaceTokens() error = %v", err)
+	}
+	handler := NewNamespaceMetricsHandler(info, "uuid", provider, namespaceTestConfig, staleness, tokens)
+
+	tests := []struct {
+		name   string
+		path   string
+		auth   string
+		status int
+	}{
+		{"no token", "/metrics/namespace/team-a", "", http.StatusUnauthorized},
+		{"own namespace", "/metrics/namespace/team-a", "Bearer token-a", http.StatusOK},
+		{"other namespace", "/metrics/namespace/team-b", "Bearer token-a", http.StatusUnauthorized},
+		{"wildcard token", "/metrics/namespace/team-b", "Bearer admin", http.StatusOK},
+		{"missing namespace", "/metrics/namespace/", "Bearer admin", http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			if tt.auth != "" {
+				req.Header.Set("Authorization", tt.auth)
+			}
+			w := httptest.NewRecorder()
+			handler(w, req)
+			if w.Code != tt.status {
+				t.Errorf("Expected %d, got %d", tt.status, w.Code)
+			}
+		})
+	}
+}
+
+func TestParseNamespaceTokens(t *testing.T) {
+	tokens, err := ParseNamespaceTokens("team-a=one,team-a=two")
+	if err != nil {
+		t.Fatalf("ParseNamespaceTokens() error = %v", err)
+	}
+	if !tokens.Allowed("team-a", "two") || tokens.Allowed("team-a", "three") {
+		t.Error("Expected both tokens of team-a to be accepted and no others")
+	}
+
+	empty, err := ParseNamespaceTokens("")
+	if err != nil || !empty.Allowed("any", "") {
+		t.Error("Expected an empty spec to leave namespaces open")
+	}
+
+	if _, err := ParseNamespaceTokens("team-a"); err == nil {
+		t.Error("Expected error for entry without token")
+	}
+}
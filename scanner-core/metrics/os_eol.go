@@ -0,0 +1,46 @@
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/bvboe/b2s-go/scanner-core/database"
+	"github.com/bvboe/b2s-go/scanner-core/eol"
+)
+
+// RegisterOSEOLMetrics publishes the end-of-life status of the OS releases of
+// running images on /metrics, so alerts can fire for end-of-life distributions
+// even when they have no known vulnerabilities.
+func RegisterOSEOLMetrics(db *database.DB, catalog *eol.Catalog) {
+	RegisterExtraWriter(func(w io.Writer) {
+		counts, err := db.GetOSVersionCounts()
+		if err != nil {
+			log.Warn("failed to compute OS end-of-life metrics", "error", err)
+			return
+		}
+		writeOSEOLMetrics(w, catalog.Summarize(counts, time.Now()))
+	})
+}
+
+// writeOSEOLMetrics emits the OS lifecycle gauges in Prometheus text format
+func writeOSEOLMetrics(w io.Writer, summary *eol.Summary) {
+	_, _ = fmt.Fprintf(w, "# HELP bjorn2scan_os_lifecycle_images Running images per OS release and lifecycle status (supported, approaching_eol, extended_support, eol, unknown)\n")
+	_, _ = fmt.Fprintf(w, "# TYPE bjorn2scan_os_lifecycle_images gauge\n")
+	for _, d := range summary.Distributions {
+		_, _ = fmt.Fprintf(w, "bjorn2scan_os_lifecycle_images{os_name=%q,os_version=%q,cycle=%q,status=%q} %d\n",
+			d.OSName, d.OSVersion, d.Cycle, d.Status.Status, d.Images)
+	}
+	_, _ = fmt.Fprintf(w, "# HELP bjorn2scan_os_lifecycle_containers Running containers per OS release and lifecycle status\n")
+	_, _ = fmt.Fprintf(w, "# TYPE bjorn2scan_os_lifecycle_containers gauge\n")
+	for _, d := range summary.Distributions {
+		_, _ = fmt.Fprintf(w, "bjorn2scan_os_lifecycle_containers{os_name=%q,os_version=%q,cycle=%q,status=%q} %d\n",
+			d.OSName, d.OSVersion, d.Cycle, d.Status.Status, d.Containers)
+	}
+	_, _ = fmt.Fprintf(w, "# HELP bjorn2scan_os_eol_images Running images whose OS release is past end of life\n")
+	_, _ = fmt.Fprintf(w, "# TYPE bjorn2scan_os_eol_images gauge\n")
+	_, _ = fmt.Fprintf(w, "bjorn2scan_os_eol_images %d\n", summary.Images[eol.StatusEOL])
+	_, _ = fmt.Fprintf(w, "# HELP bjorn2scan_os_eol_containers Running containers whose OS release is past end of life\n")
+	_, _ = fmt.Fprintf(w, "# TYPE bjorn2scan_os_eol_containers gauge\n")
+	_, _ = fmt.Fprintf(w, "bjorn2scan_os_eol_containers %d\n", summary.Containers[eol.StatusEOL])
+}
@@ -0,0 +1,46 @@
+package metrics
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/bvboe/b2s-go/scanner-core/alerts"
+	"github.com/bvboe/b2s-go/scanner-core/database"
+)
+
+// RegisterScanFailureMetrics publishes failing images grouped by node and
+// failure reason on /metrics, plus an alert gauge per group that reaches
+// threshold (0 disables the alert gauge). Alerting on the grouped gauge turns
+// a broken runtime on one node into one alert rather than one per image.
+func RegisterScanFailureMetrics(db *database.DB, threshold int) {
+	RegisterExtraWriter(func(w io.Writer) {
+		failures, err := db.GetFailingScans()
+		if err != nil {
+			log.Warn("failed to compute scan failure metrics", "error", err)
+			return
+		}
+		writeScanFailureMetrics(w, alerts.GroupFailures(failures, threshold), threshold)
+	})
+}
+
+// writeScanFailureMetrics emits the scan failure gauges in Prometheus text format
+func writeScanFailureMetrics(w io.Writer, groups []alerts.FailureGroup, threshold int) {
+	_, _ = fmt.Fprintf(w, "# HELP bjorn2scan_scan_failures Images whose latest scan failed, by node and failure reason\n")
+	_, _ = fmt.Fprintf(w, "# TYPE bjorn2scan_scan_failures gauge\n")
+	for _, g := range groups {
+		_, _ = fmt.Fprintf(w, "bjorn2scan_scan_failures{node=%q,reason=%q} %d\n", g.Node, g.Reason, g.Images)
+	}
+	if threshold <= 0 {
+		return
+	}
+	_, _ = fmt.Fprintf(w, "# HELP bjorn2scan_scan_failure_alert 1 for each node and failure reason with at least bjorn2scan_scan_failure_alert_threshold failing images\n")
+	_, _ = fmt.Fprintf(w, "# TYPE bjorn2scan_scan_failure_alert gauge\n")
+	for _, g := range groups {
+		if g.Firing {
+			_, _ = fmt.Fprintf(w, "bjorn2scan_scan_failure_alert{node=%q,reason=%q} 1\n", g.Node, g.Reason)
+		}
+	}
+	_, _ = fmt.Fprintf(w, "# HELP bjorn2scan_scan_failure_alert_threshold Failing images per node and reason that fire a scan failure alert\n")
+	_, _ = fmt.Fprintf(w, "# TYPE bjorn2scan_scan_failure_alert_threshold gauge\n")
+	_, _ = fmt.Fprintf(w, "bjorn2scan_scan_failure_alert_threshold %d\n", threshold)
+}
@@ -70,7 +70,20 @@ func StreamMetrics(
 	if writeErr != nil {
 		return nil, writeErr
 	}
-	database.WriteOpMetrics(bw)
+
+	// Operational metrics are written by other packages without deployment labels
+	extra := map[string]string{
+		"deployment_uuid": deploymentUUID,
+		"deployment_name": deploymentName,
+	}
+	config.StaticLabels.AddTo(extra)
+	lw := newLabelWriter(bw, extra)
+	database.WriteOpMetrics(lw)
+	WriteHTTPMetrics(lw)
+	writeExtraMetrics(lw)
+	if err := lw.Flush(); err != nil {
+		return nil, err
+	}
 	return batch, bw.Flush()
 }
 
@@ -2,6 +2,7 @@ package metrics
 
 import (
 	"github.com/bvboe/b2s-go/scanner-core/database"
+	"github.com/bvboe/b2s-go/scanner-core/labels"
 	"github.com/bvboe/b2s-go/scanner-core/nodes"
 )
 
@@ -42,6 +43,8 @@ type UnifiedConfig struct {
 	NodeVulnerabilityExploitedEnabled bool
 	// Staleness
 	StalenessWindow int64 // Staleness window in seconds (use cfg.MetricsStalenessWindow.Seconds())
+	// StaticLabels are added to every metric (see labels.Parse)
+	StaticLabels labels.Set
 }
 
 // StreamingProvider is the unified database interface required by the new metrics handler.
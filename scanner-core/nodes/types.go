@@ -87,8 +87,11 @@ type NodeVulnerability struct {
 	PackageName    string `json:"package_name"`
 	PackageVersion string `json:"package_version"`
 	PackageType    string `json:"package_type"`
-	// Count is the number of duplicate instances of this vulnerability
+	// Count is 1 per finding; matches of the package at several paths are
+	// merged into one finding
 	Count int `json:"count"`
+	// Occurrences is the number of distinct paths the package was found at
+	Occurrences int `json:"occurrences"`
 	// Details contains additional vulnerability metadata as JSON
 	Details   string    `json:"details,omitempty"`
 	CreatedAt time.Time `json:"created_at"`
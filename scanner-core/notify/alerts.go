@@ -0,0 +1,355 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bvboe/b2s-go/scanner-core/containers"
+	"github.com/bvboe/b2s-go/scanner-core/database"
+	"github.com/bvboe/b2s-go/scanner-core/scanning"
+	"github.com/bvboe/b2s-go/scanner-core/severity"
+)
+
+// EventNewVulnerabilities is the event of alerts about vulnerabilities a scan
+// found in a running image that its previous scan did not report
+const EventNewVulnerabilities = "new_vulnerabilities"
+
+// maxSlackFindings bounds the findings listed in a Slack message; webhooks
+// receive all of them
+const maxSlackFindings = 10
+
+// ParseThresholds parses per-severity alert thresholds, a comma-separated list
+// of severity=count pairs such as "critical=1,high=5": an alert is sent when a
+// scan introduces at least count new findings of the severity. Severities
+// without a threshold do not trigger alerts. An empty value yields none.
+func ParseThresholds(value string) (map[string]int, error) {
+	thresholds := make(map[string]int)
+	for _, pair := range strings.Split(value, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		name, count, ok := strings.Cut(pair, "=")
+		level := severity.Canonical(name)
+		if !ok || level == "" {
+			return nil, fmt.Errorf("invalid threshold %q: expected severity=count", pair)
+		}
+		n, err := strconv.Atoi(strings.TrimSpace(count))
+		if err != nil || n < 1 {
+			return nil, fmt.Errorf("invalid threshold %q: count must be a positive number", pair)
+		}
+		thresholds[level] = n
+	}
+	return thresholds, nil
+}
+
+// AlertConfig selects which scan results are alerted on
+type AlertConfig struct {
+	Thresholds        map[string]int // Minimum new findings per severity that trigger an alert (see ParseThresholds)
+	KnownExploited    bool           // Alert on any new finding in the CISA KEV catalog, regardless of severity
+	FirstScan         bool           // Also alert on the findings of an image's first scan
+	Namespaces        []string       // Only alert on images running in these namespaces (empty = all)
+	ExcludeNamespaces []string       // Namespaces never alerted on
+	SlackWebhookURL   string         // Incoming webhook slack:#channel destinations are posted to
+	Source            string         // Cluster or host name shown in alerts
+}
+
+// Alert describes vulnerabilities newly found in a running image. It is the
+// JSON body posted to webhook destinations.
+type Alert struct {
+	Event      string             `json:"event"`
+	Source     string             `json:"source,omitempty"`
+	Image      string             `json:"image"`
+	Digest     string             `json:"digest"`
+	Namespaces []string           `json:"namespaces"`
+	FirstScan  bool               `json:"first_scan"`
+	Findings   []database.Finding `json:"findings"`
+}
+
+// FindingsStore provides the scan results alerts are computed from
+// (implemented by database.DB)
+type FindingsStore interface {
+	GetImageFindings(digest string) ([]database.Finding, bool, error)
+	GetImageNamespaces(digest string) ([]string, error)
+}
+
+// baseline is the stored result of an image before its results are replaced
+type baseline struct {
+	keys    map[string]bool
+	scanned bool
+}
+
+// Notifier sends alerts when a scan introduces vulnerabilities in a running
+// image. The findings stored before the results are replaced (pre-persist)
+// are compared with the stored results (post-persist), so only findings new
+// to the image are alerted on, e.g. after a vulnerability database update.
+type Notifier struct {
+	router *Router
+	store  FindingsStore
+	config AlertConfig
+	client *http.Client
+
+	mu        sync.Mutex
+	baselines map[string]baseline
+}
+
+// NewNotifier creates a notifier sending alerts to the destinations router
+// picks for the namespaces an image runs in
+func NewNotifier(router *Router, store FindingsStore, config AlertConfig) *Notifier {
+	return &Notifier{
+		router:    router,
+		store:     store,
+		config:    config,
+		client:    &http.Client{Timeout: 10 * time.Second},
+		baselines: make(map[string]baseline),
+	}
+}
+
+// AddHooks registers the notifier with the scan queue
+func (n *Notifier) AddHooks(queue *scanning.JobQueue) {
+	queue.AddHook(scanning.HookPrePersist, func(ctx context.Context, event *scanning.HookEvent) error {
+		n.Baseline(event.Image.Digest)
+		return nil
+	})
+	queue.AddHook(scanning.HookPostPersist, func(ctx context.Context, event *scanning.HookEvent) error {
+		n.ScanCompleted(ctx, event.Image)
+		return nil
+	})
+}
+
+// Baseline records the findings of an image before new results are stored
+func (n *Notifier) Baseline(digest string) {
+	findings, scanned, err := n.store.GetImageFindings(digest)
+	if err != nil {
+		log.Warn("failed to read findings before storing scan results", "digest", digest, "error", err)
+		return
+	}
+	b := baseline{keys: make(map[string]bool, len(findings)), scanned: scanned}
+	for _, f := range findings {
+		b.keys[f.Key()] = true
+	}
+
+	n.mu.Lock()
+	n.baselines[digest] = b
+	n.mu.Unlock()
+}
+
+// ScanCompleted sends alerts about the findings of image that are not in its
+// baseline, if they cross the configured thresholds
+func (n *Notifier) ScanCompleted(ctx context.Context, image containers.ImageID) {
+	n.mu.Lock()
+	b, ok := n.baselines[image.Digest]
+	delete(n.baselines, image.Digest)
+	n.mu.Unlock()
+	if !ok || (!b.scanned && !n.config.FirstScan) {
+		return
+	}
+
+	alert, err := n.newAlert(image, b)
+	if err != nil {
+		log.Warn("failed to check scan results for alerts", "image", image.Reference, "digest", image.Digest, "error", err)
+		return
+	}
+	if alert == nil {
+		return
+	}
+
+	// One alert per destination, listing the namespaces routed to it
+	routes := make(map[Destination][]string)
+	var order []Destination
+	for _, ns := range alert.Namespaces {
+		for _, d := range n.router.Destinations(ns) {
+			if _, seen := routes[d]; !seen {
+				order = append(order, d)
+			}
+			routes[d] = append(routes[d], ns)
+		}
+	}
+	for _, d := range order {
+		a := *alert
+		a.Namespaces = routes[d]
+		if err := n.send(ctx, d, &a); err != nil {
+			log.Warn("failed to send alert", "destination", d.String(), "image", image.Reference, "error", err)
+			continue
+		}
+		log.Info("sent vulnerability alert", "destination", d.String(), "image", image.Reference,
+			"findings", len(a.Findings), "namespaces", a.Namespaces)
+	}
+}
+
+// newAlert returns the alert about the new findings of image, or nil if the
+// image is not running in a selected namespace or no threshold is crossed
+func (n *Notifier) newAlert(image containers.ImageID, b baseline) (*Alert, error) {
+	running, err := n.store.GetImageNamespaces(image.Digest)
+	if err != nil {
+		return nil, err
+	}
+	var namespaces []string
+	for _, ns := range running {
+		if (len(n.config.Namespaces) == 0 || slices.Contains(n.config.Namespaces, ns)) &&
+			!slices.Contains(n.config.ExcludeNamespaces, ns) {
+			namespaces = append(namespaces, ns)
+		}
+	}
+	if len(namespaces) == 0 {
+		return nil, nil
+	}
+
+	findings, _, err := n.store.GetImageFindings(image.Digest)
+	if err != nil {
+		return nil, err
+	}
+	var selected []database.Finding
+	counts := make(map[string]int)
+	kev := 0
+	for _, f := range findings {
+		if b.keys[f.Key()] {
+			continue
+		}
+		_, thresholded := n.config.Thresholds[f.Severity]
+		exploited := f.KnownExploited && n.config.KnownExploited
+		if !thresholded && !exploited {
+			continue
+		}
+		selected = append(selected, f)
+		counts[f.Severity]++
+		if exploited {
+			kev++
+		}
+	}
+
+	triggered := kev > 0
+	for level, threshold := range n.config.Thresholds {
+		if counts[level] >= threshold {
+			triggered = true
+		}
+	}
+	if !triggered {
+		return nil, nil
+	}
+
+	// Most severe and known exploited findings first
+	slices.SortStableFunc(selected, func(a, b database.Finding) int {
+		if a.KnownExploited != b.KnownExploited {
+			if a.KnownExploited {
+				return -1
+			}
+			return 1
+		}
+		return slices.Index(severity.All, a.Severity) - slices.Index(severity.All, b.Severity)
+	})
+
+	return &Alert{
+		Event:      EventNewVulnerabilities,
+		Source:     n.config.Source,
+		Image:      image.Reference,
+		Digest:     image.Digest,
+		Namespaces: namespaces,
+		FirstScan:  !b.scanned,
+		Findings:   selected,
+	}, nil
+}
+
+// send delivers an alert to a destination: webhooks receive the alert as
+// JSON, Slack channels a message through the incoming webhook (or the slack
+// target itself if it is a webhook URL)
+func (n *Notifier) send(ctx context.Context, d Destination, alert *Alert) error {
+	var url string
+	var body interface{}
+	switch d.Kind {
+	case KindWebhook:
+		url, body = d.Target, alert
+	case KindSlack:
+		message := map[string]string{"text": slackText(alert)}
+		if strings.HasPrefix(d.Target, "https://") {
+			url = d.Target
+		} else {
+			if n.config.SlackWebhookURL == "" {
+				return fmt.Errorf("no Slack webhook URL configured")
+			}
+			url = n.config.SlackWebhookURL
+			message["channel"] = d.Target
+		}
+		body = message
+	default:
+		return fmt.Errorf("unsupported destination kind %q", d.Kind)
+	}
+
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return nil
+}
+
+// slackText formats an alert as a Slack message
+func slackText(alert *Alert) string {
+	counts := make(map[string]int)
+	kev := 0
+	for _, f := range alert.Findings {
+		counts[f.Severity]++
+		if f.KnownExploited {
+			kev++
+		}
+	}
+	var summary []string
+	for _, level := range severity.All {
+		if counts[level] > 0 {
+			summary = append(summary, fmt.Sprintf("%d %s", counts[level], level))
+		}
+	}
+	if kev > 0 {
+		summary = append(summary, fmt.Sprintf("%d known exploited", kev))
+	}
+
+	var b strings.Builder
+	what := "New vulnerabilities"
+	if alert.FirstScan {
+		what = "Vulnerabilities"
+	}
+	fmt.Fprintf(&b, ":rotating_light: *%s in `%s`*: %s\n", what, alert.Image, strings.Join(summary, ", "))
+	fmt.Fprintf(&b, "Namespaces: %s", strings.Join(alert.Namespaces, ", "))
+	if alert.Source != "" {
+		fmt.Fprintf(&b, " (%s)", alert.Source)
+	}
+	b.WriteString("\n")
+	for i, f := range alert.Findings {
+		if i == maxSlackFindings {
+			fmt.Fprintf(&b, "…and %d more\n", len(alert.Findings)-maxSlackFindings)
+			break
+		}
+		fmt.Fprintf(&b, "• %s (%s) in %s %s", f.VulnerabilityID, f.Severity, f.PackageName, f.PackageVersion)
+		if f.FixedVersion != "" {
+			fmt.Fprintf(&b, ", fixed in %s", f.FixedVersion)
+		}
+		if f.KnownExploited {
+			b.WriteString(", known exploited")
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// Ensure database.DB implements FindingsStore
+var _ FindingsStore = (*database.DB)(nil)
@@ -0,0 +1,170 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/bvboe/b2s-go/scanner-core/containers"
+	"github.com/bvboe/b2s-go/scanner-core/database"
+)
+
+// mockFindingsStore returns the findings of one image, replaced between scans
+type mockFindingsStore struct {
+	findings   []database.Finding
+	scanned    bool
+	namespaces []string
+}
+
+func (m *mockFindingsStore) GetImageFindings(string) ([]database.Finding, bool, error) {
+	return m.findings, m.scanned, nil
+}
+
+func (m *mockFindingsStore) GetImageNamespaces(string) ([]string, error) {
+	return m.namespaces, nil
+}
+
+// receiver records the JSON bodies posted to it per path
+type receiver struct {
+	mu     sync.Mutex
+	bodies map[string][]map[string]interface{}
+}
+
+func newReceiver(t *testing.T) (*receiver, string) {
+	t.Helper()
+	r := &receiver{bodies: map[string][]map[string]interface{}{}}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		var body map[string]interface{}
+		if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+			t.Errorf("invalid alert body: %v", err)
+		}
+		r.mu.Lock()
+		r.bodies[req.URL.Path] = append(r.bodies[req.URL.Path], body)
+		r.mu.Unlock()
+	}))
+	t.Cleanup(server.Close)
+	return r, server.URL
+}
+
+func TestParseThresholds(t *testing.T) {
+	got, err := ParseThresholds(" critical=1, HIGH=5,")
+	if err != nil {
+		t.Fatalf("ParseThresholds() error = %v", err)
+	}
+	if want := map[string]int{"Critical": 1, "High": 5}; !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseThresholds() = %v, want %v", got, want)
+	}
+	for _, invalid := range []string{"critical", "severe=1", "high=0", "high=many"} {
+		if _, err := ParseThresholds(invalid); err == nil {
+			t.Errorf("ParseThresholds(%q) succeeded, want error", invalid)
+		}
+	}
+}
+
+func TestNotifierNewFindings(t *testing.T) {
+	recv, url := newReceiver(t)
+	router := NewRouter([]Destination{{KindWebhook, url + "/security"}})
+	router.SetAllowedWebhookHosts([]string{"127.0.0.1"})
+	router.SetNamespace("team-foo", map[string]string{AnnotationKey: "webhook:" + url + "/team-foo"})
+
+	existing := database.Finding{VulnerabilityID: "CVE-1", PackageName: "openssl", PackageVersion: "3.0", Severity: "Critical"}
+	store := &mockFindingsStore{
+		findings:   []database.Finding{existing},
+		scanned:    true,
+		namespaces: []string{"kube-system", "team-bar", "team-foo"},
+	}
+	notifier := NewNotifier(router, store, AlertConfig{
+		Thresholds:        map[string]int{"Critical": 1, "High": 2},
+		KnownExploited:    true,
+		ExcludeNamespaces: []string{"kube-system"},
+	})
+	image := containers.ImageID{Reference: "app:1", Digest: "sha256:app"}
+
+	scan := func(findings ...database.Finding) {
+		notifier.Baseline(image.Digest)
+		store.findings = findings
+		notifier.ScanCompleted(context.Background(), image)
+	}
+
+	// One new High finding is below the threshold; known findings are not alerted on
+	scan(existing, database.Finding{VulnerabilityID: "CVE-2", PackageName: "bash", PackageVersion: "5.1", Severity: "High"})
+	if len(recv.bodies) != 0 {
+		t.Fatalf("expected no alert below the threshold, got %v", recv.bodies)
+	}
+
+	// A known exploited Medium finding alerts regardless of the thresholds
+	scan(existing,
+		database.Finding{VulnerabilityID: "CVE-2", PackageName: "bash", PackageVersion: "5.1", Severity: "High"},
+		database.Finding{VulnerabilityID: "CVE-3", PackageName: "zlib", PackageVersion: "1.2", Severity: "Medium", KnownExploited: true},
+		database.Finding{VulnerabilityID: "CVE-4", PackageName: "curl", PackageVersion: "8.0", Severity: "Low"})
+
+	for path, ns := range map[string]string{"/security": "team-bar", "/team-foo": "team-foo"} {
+		bodies := recv.bodies[path]
+		if len(bodies) != 1 {
+			t.Fatalf("%s received %d alerts, want 1", path, len(bodies))
+		}
+		alert := bodies[0]
+		if alert["event"] != EventNewVulnerabilities || alert["image"] != "app:1" || alert["first_scan"] != false {
+			t.Errorf("%s: unexpected alert %v", path, alert)
+		}
+		if got := alert["namespaces"].([]interface{}); len(got) != 1 || got[0] != ns {
+			t.Errorf("%s: namespaces = %v, want [%s]", path, got, ns)
+		}
+		findings := alert["findings"].([]interface{})
+		if len(findings) != 1 || findings[0].(map[string]interface{})["vulnerability_id"] != "CVE-3" {
+			t.Errorf("%s: findings = %v, want only the known exploited CVE-3", path, findings)
+		}
+	}
+}
+
+func TestNotifierFirstScan(t *testing.T) {
+	recv, url := newReceiver(t)
+	store := &mockFindingsStore{namespaces: []string{"default"}}
+	critical := database.Finding{VulnerabilityID: "CVE-1", PackageName: "openssl", PackageVersion: "3.0", Severity: "Critical"}
+	image := containers.ImageID{Reference: "app:1", Digest: "sha256:app"}
+
+	for _, firstScan := range []bool{false, true} {
+		notifier := NewNotifier(NewRouter([]Destination{{KindWebhook, url}}), store, AlertConfig{
+			Thresholds: map[string]int{"Critical": 1},
+			FirstScan:  firstScan,
+		})
+		store.findings = nil
+		notifier.Baseline(image.Digest)
+		store.findings = []database.Finding{critical}
+		notifier.ScanCompleted(context.Background(), image)
+	}
+	if n := len(recv.bodies["/"]); n != 1 {
+		t.Errorf("received %d alerts, want 1 (first scans only alerted on when enabled)", n)
+	}
+}
+
+func TestNotifierSlack(t *testing.T) {
+	recv, url := newReceiver(t)
+	store := &mockFindingsStore{scanned: true, namespaces: []string{"default"}}
+	notifier := NewNotifier(NewRouter([]Destination{{KindSlack, "#security"}}), store, AlertConfig{
+		Thresholds:      map[string]int{"Critical": 1},
+		SlackWebhookURL: url + "/slack",
+		Source:          "prod",
+	})
+	image := containers.ImageID{Reference: "nginx:1.25", Digest: "sha256:nginx"}
+
+	notifier.Baseline(image.Digest)
+	store.findings = []database.Finding{{VulnerabilityID: "CVE-1", PackageName: "openssl", PackageVersion: "3.0",
+		Severity: "Critical", FixedVersion: "3.0.1"}}
+	notifier.ScanCompleted(context.Background(), image)
+
+	bodies := recv.bodies["/slack"]
+	if len(bodies) != 1 {
+		t.Fatalf("received %d Slack messages, want 1", len(bodies))
+	}
+	text, _ := bodies[0]["text"].(string)
+	if bodies[0]["channel"] != "#security" || !strings.Contains(text, "nginx:1.25") ||
+		!strings.Contains(text, "CVE-1 (Critical) in openssl 3.0, fixed in 3.0.1") || !strings.Contains(text, "(prod)") {
+		t.Errorf("unexpected Slack message %v", bodies[0])
+	}
+}
@@ -0,0 +1,201 @@
+// Package notify decides where alerts about findings are sent. Namespaces
+// choose their own destinations with the bjorn2scan.io/notify annotation, e.g.
+// bjorn2scan.io/notify=slack:#team-foo, so teams get alerts for their own
+// workloads; namespaces without one fall back to the global default.
+//
+// Notifier sends alerts to those destinations when a scan introduces new
+// vulnerabilities above the configured thresholds in a running image.
+package notify
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/bvboe/b2s-go/scanner-core/logging"
+)
+
+var log = logging.For(logging.ComponentNotify)
+
+// AnnotationKey is the namespace annotation listing the destinations of the
+// namespace's alerts, comma separated
+const AnnotationKey = "bjorn2scan.io/notify"
+
+// Destination kinds
+const (
+	KindSlack   = "slack"   // Slack channel, e.g. slack:#team-foo
+	KindWebhook = "webhook" // HTTP(S) URL receiving JSON, e.g. webhook:https://hooks.example.com/sec
+)
+
+// Destination is where an alert is sent
+type Destination struct {
+	Kind   string `json:"kind"`
+	Target string `json:"target"`
+}
+
+// String returns the destination in annotation syntax (kind:target)
+func (d Destination) String() string {
+	return d.Kind + ":" + d.Target
+}
+
+// ParseDestinations parses a comma-separated list of kind:target
+// destinations, as used by the annotation and the default channel setting.
+// Duplicates are dropped. An empty value yields no destinations.
+func ParseDestinations(value string) ([]Destination, error) {
+	var result []Destination
+	seen := make(map[Destination]bool)
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		kind, target, ok := strings.Cut(item, ":")
+		kind = strings.ToLower(strings.TrimSpace(kind))
+		target = strings.TrimSpace(target)
+		if !ok || target == "" {
+			return nil, fmt.Errorf("invalid destination %q: expected kind:target", item)
+		}
+		switch kind {
+		case KindSlack:
+		case KindWebhook:
+			if !strings.HasPrefix(target, "https://") && !strings.HasPrefix(target, "http://") {
+				return nil, fmt.Errorf("invalid destination %q: webhook target must be an http(s) URL", item)
+			}
+		default:
+			return nil, fmt.Errorf("invalid destination %q: unknown kind %q (want %s or %s)", item, kind, KindSlack, KindWebhook)
+		}
+		d := Destination{Kind: kind, Target: target}
+		if !seen[d] {
+			seen[d] = true
+			result = append(result, d)
+		}
+	}
+	return result, nil
+}
+
+// Router maps namespaces to alert destinations. Routes are updated as
+// namespace annotations change, so dispatchers call Destinations for every
+// alert instead of caching the result.
+type Router struct {
+	mu           sync.RWMutex
+	defaults     []Destination
+	namespaces   map[string][]Destination
+	webhookHosts []string
+}
+
+// NewRouter creates a router sending alerts of namespaces without a notify
+// annotation to defaults (none if empty)
+func NewRouter(defaults []Destination) *Router {
+	return &Router{
+		defaults:   defaults,
+		namespaces: make(map[string][]Destination),
+	}
+}
+
+// SetAllowedWebhookHosts sets the hosts namespace annotations may send
+// webhooks to: exact host names, "*.example.com" for its subdomains or "*"
+// for any host. Without any, annotations can only route to Slack channels.
+// The default destinations are configured by the operator and not checked.
+// Call it before namespaces are set.
+func (r *Router) SetAllowedWebhookHosts(hosts []string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.webhookHosts = nil
+	for _, host := range hosts {
+		if host = strings.ToLower(strings.TrimSpace(host)); host != "" {
+			r.webhookHosts = append(r.webhookHosts, host)
+		}
+	}
+}
+
+// checkWebhookHosts returns an error for the first webhook destination whose
+// host is not allowed
+func (r *Router) checkWebhookHosts(destinations []Destination) error {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for _, d := range destinations {
+		if d.Kind != KindWebhook {
+			continue
+		}
+		u, err := url.Parse(d.Target)
+		if err != nil {
+			return fmt.Errorf("invalid webhook URL %q: %w", d.Target, err)
+		}
+		if !hostAllowed(strings.ToLower(u.Hostname()), r.webhookHosts) {
+			return fmt.Errorf("webhook host %q is not in the allowed webhook hosts", u.Hostname())
+		}
+	}
+	return nil
+}
+
+// hostAllowed reports whether host matches one of the allowed patterns
+func hostAllowed(host string, allowed []string) bool {
+	for _, pattern := range allowed {
+		if pattern == "*" || pattern == host {
+			return true
+		}
+		if suffix, ok := strings.CutPrefix(pattern, "*."); ok && strings.HasSuffix(host, "."+suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// SetNamespace updates the routes of a namespace from its annotations. A
+// missing, empty or invalid annotation, or one with a webhook to a host that is
+// not allowed, falls back to the default destinations.
+func (r *Router) SetNamespace(namespace string, annotations map[string]string) {
+	value, ok := annotations[AnnotationKey]
+	var destinations []Destination
+	if ok {
+		var err error
+		destinations, err = ParseDestinations(value)
+		if err == nil {
+			err = r.checkWebhookHosts(destinations)
+		}
+		if err != nil {
+			log.Warn("ignoring invalid notify annotation, using the default destinations",
+				"namespace", namespace, "annotation", value, "error", err)
+			destinations = nil
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(destinations) == 0 {
+		delete(r.namespaces, namespace)
+		return
+	}
+	r.namespaces[namespace] = destinations
+}
+
+// RemoveNamespace forgets the routes of a deleted namespace
+func (r *Router) RemoveNamespace(namespace string) {
+	r.mu.Lock()
+	delete(r.namespaces, namespace)
+	r.mu.Unlock()
+}
+
+// Destinations returns where alerts for findings in namespace go: the
+// namespace's annotated destinations, or the defaults. An empty namespace
+// (findings not tied to a workload, e.g. nodes) always uses the defaults.
+func (r *Router) Destinations(namespace string) []Destination {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if destinations, ok := r.namespaces[namespace]; ok {
+		return append([]Destination(nil), destinations...)
+	}
+	return append([]Destination(nil), r.defaults...)
+}
+
+// Routes returns the namespaces that have their own destinations
+func (r *Router) Routes() map[string][]Destination {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	routes := make(map[string][]Destination, len(r.namespaces))
+	for ns, destinations := range r.namespaces {
+		routes[ns] = append([]Destination(nil), destinations...)
+	}
+	return routes
+}
@@ -0,0 +1,107 @@
+package notify
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseDestinations(t *testing.T) {
+	got, err := ParseDestinations(" slack:#team-foo, webhook:https://hooks.example.com/sec,SLACK:#team-foo ,")
+	if err != nil {
+		t.Fatalf("ParseDestinations() error = %v", err)
+	}
+	want := []Destination{{KindSlack, "#team-foo"}, {KindWebhook, "https://hooks.example.com/sec"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseDestinations() = %v, want %v", got, want)
+	}
+	if got[0].String() != "slack:#team-foo" {
+		t.Errorf("String() = %q", got[0].String())
+	}
+
+	if got, err := ParseDestinations(""); err != nil || got != nil {
+		t.Errorf("ParseDestinations(\"\") = %v, %v; want nil, nil", got, err)
+	}
+	for _, invalid := range []string{"#team-foo", "slack:", "email:sec@example.com", "webhook:hooks.example.com"} {
+		if _, err := ParseDestinations(invalid); err == nil {
+			t.Errorf("ParseDestinations(%q) succeeded, want error", invalid)
+		}
+	}
+}
+
+func TestRouter(t *testing.T) {
+	defaults := []Destination{{KindSlack, "#security"}}
+	r := NewRouter(defaults)
+
+	r.SetNamespace("team-foo", map[string]string{AnnotationKey: "slack:#team-foo"})
+	r.SetNamespace("team-bar", map[string]string{AnnotationKey: "pager:team-bar"})
+	r.SetNamespace("team-baz", map[string]string{"other": "annotation"})
+
+	if got := r.Destinations("team-foo"); !reflect.DeepEqual(got, []Destination{{KindSlack, "#team-foo"}}) {
+		t.Errorf("annotated namespace routed to %v", got)
+	}
+	for _, ns := range []string{"team-bar", "team-baz", "unknown", ""} {
+		if got := r.Destinations(ns); !reflect.DeepEqual(got, defaults) {
+			t.Errorf("%q routed to %v, want the defaults", ns, got)
+		}
+	}
+	if routes := r.Routes(); len(routes) != 1 || routes["team-foo"] == nil {
+		t.Errorf("Routes() = %v, want only team-foo", routes)
+	}
+
+	// Removing the annotation or the namespace falls back to the defaults
+	r.SetNamespace("team-foo", nil)
+	if got := r.Destinations("team-foo"); !reflect.DeepEqual(got, defaults) {
+		t.Errorf("after annotation removal routed to %v", got)
+	}
+	r.SetNamespace("team-foo", map[string]string{AnnotationKey: "slack:#team-foo"})
+	r.RemoveNamespace("team-foo")
+	if got := r.Destinations("team-foo"); !reflect.DeepEqual(got, defaults) {
+		t.Errorf("after namespace deletion routed to %v", got)
+	}
+
+	// No default configured: unannotated namespaces are not notified
+	if got := NewRouter(nil).Destinations("team-foo"); len(got) != 0 {
+		t.Errorf("expected no destinations without defaults, got %v", got)
+	}
+}
+
+func TestRouterWebhookHosts(t *testing.T) {
+	defaults := []Destination{{KindWebhook, "https://internal.example.org/alerts"}}
+	r := NewRouter(defaults)
+
+	// Without allowed hosts, annotations cannot send webhooks anywhere
+	r.SetNamespace("team-foo", map[string]string{AnnotationKey: "webhook:https://hooks.example.com/foo"})
+	if got := r.Destinations("team-foo"); !reflect.DeepEqual(got, defaults) {
+		t.Errorf("webhook without allowed hosts routed to %v, want the defaults", got)
+	}
+
+	r.SetAllowedWebhookHosts([]string{" Hooks.Example.com", "*.corp.example.net", ""})
+	tests := []struct {
+		annotation string
+		allowed    bool
+	}{
+		{"webhook:https://hooks.example.com/foo", true},
+		{"webhook:https://HOOKS.example.com:8443/foo", true},
+		{"webhook:https://alerts.corp.example.net/foo", true},
+		{"webhook:https://a.b.corp.example.net/foo", true},
+		{"webhook:https://corp.example.net/foo", false},
+		{"webhook:https://evilcorp.example.net/foo", false},
+		{"webhook:https://hooks.example.com.attacker.io/foo", false},
+		{"webhook:http://169.254.169.254/latest/meta-data", false},
+		{"slack:#team-foo,webhook:https://attacker.io/x", false},
+		{"slack:#team-foo", true},
+	}
+	for _, tt := range tests {
+		r.SetNamespace("team-foo", map[string]string{AnnotationKey: tt.annotation})
+		got := r.Destinations("team-foo")
+		if routed := !reflect.DeepEqual(got, defaults); routed != tt.allowed {
+			t.Errorf("%s: routed to %v, allowed = %v", tt.annotation, got, tt.allowed)
+		}
+	}
+
+	r.SetAllowedWebhookHosts([]string{"*"})
+	r.SetNamespace("team-foo", map[string]string{AnnotationKey: "webhook:https://anything.example/x"})
+	if got := r.Destinations("team-foo"); reflect.DeepEqual(got, defaults) {
+		t.Error(`"*" did not allow any webhook host`)
+	}
+}
@@ -0,0 +1,371 @@
+package policy
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	"gopkg.in/yaml.v3"
+
+	"github.com/bvboe/b2s-go/scanner-core/database"
+)
+
+// VerdictNoPolicy is the verdict of images no policy of a bundle applies to.
+// They pass, as nothing is checked.
+const VerdictNoPolicy = "no_policy"
+
+// Evaluator evaluates images against a single Policy or a Bundle
+type Evaluator interface {
+	Evaluate(facts *database.ImagePolicyFacts) Result
+}
+
+// Selector chooses the images a policy of a bundle applies to. Set fields
+// must all match; a list matches if any of its entries does.
+type Selector struct {
+	// Namespaces images run in, as glob patterns (e.g. "prod-*")
+	Namespaces []string `yaml:"namespaces" json:"namespaces,omitempty"`
+	// Registries or repository prefixes of the image reference, e.g.
+	// "docker.io", "ghcr.io/acme" or "registry.example.com:5000/team"
+	Registries []string `yaml:"registries" json:"registries,omitempty"`
+	// Image labels (the OCI source, version and vendor labels recorded per
+	// image), with glob patterns as values, e.g. org.opencontainers.image.vendor: "Acme*"
+	Labels map[string]string `yaml:"labels" json:"labels,omitempty"`
+	// Attributes synced from the CMDB (application_id, owner,
+	// data_classification), with glob patterns as values, e.g. data_classification: restricted
+	Metadata map[string]string `yaml:"metadata" json:"metadata,omitempty"`
+}
+
+// empty reports whether the selector has no criteria
+func (s *Selector) empty() bool {
+	return s == nil || (len(s.Namespaces) == 0 && len(s.Registries) == 0 && len(s.Labels) == 0 && len(s.Metadata) == 0)
+}
+
+// matches reports whether an image satisfies every criterion of the selector
+func (s *Selector) matches(facts *database.ImagePolicyFacts) bool {
+	if len(s.Namespaces) > 0 && !slices.ContainsFunc(facts.Namespaces, func(ns string) bool {
+		return slices.ContainsFunc(s.Namespaces, func(pattern string) bool { return globMatch(pattern, ns) })
+	}) {
+		return false
+	}
+	if len(s.Registries) > 0 {
+		repository := normalizeRepository(facts.Reference)
+		if repository == "" || !slices.ContainsFunc(s.Registries, func(prefix string) bool {
+			prefix = normalizeRegistryPrefix(prefix)
+			return repository == prefix || strings.HasPrefix(repository, prefix+"/")
+		}) {
+			return false
+		}
+	}
+	for key, pattern := range s.Labels {
+		value, ok := facts.Labels[key]
+		if !ok || !globMatch(pattern, value) {
+			return false
+		}
+	}
+	for name, pattern := range s.Metadata {
+		value, ok := facts.Metadata[name]
+		if !ok || !globMatch(pattern, value) {
+			return false
+		}
+	}
+	return true
+}
+
+// validate rejects malformed glob patterns
+func (s *Selector) validate(field string) error {
+	for _, pattern := range s.Namespaces {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("%s.namespaces: invalid pattern %q", field, pattern)
+		}
+	}
+	for key, pattern := range s.Labels {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("%s.labels: invalid pattern %q for %s", field, pattern, key)
+		}
+	}
+	for name, pattern := range s.Metadata {
+		switch name {
+		case database.CMDBApplicationID, database.CMDBOwner, database.CMDBDataClassification:
+		default:
+			return fmt.Errorf("%s.metadata: unknown attribute %q", field, name)
+		}
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("%s.metadata: invalid pattern %q for %s", field, pattern, name)
+		}
+	}
+	for _, prefix := range s.Registries {
+		if strings.TrimSpace(prefix) == "" {
+			return fmt.Errorf("%s.registries: empty entry", field)
+		}
+	}
+	return nil
+}
+
+// globMatch matches a value against a path.Match pattern; invalid patterns
+// (rejected when the bundle is parsed) never match
+func globMatch(pattern, value string) bool {
+	ok, err := path.Match(pattern, value)
+	return err == nil && ok
+}
+
+// normalizeRepository returns the registry and repository of an image
+// reference, with Docker Hub as "docker.io" (nginx:1.25 is docker.io/library/nginx)
+func normalizeRepository(reference string) string {
+	ref, err := name.ParseReference(reference, name.WeakValidation)
+	if err != nil {
+		return ""
+	}
+	return normalizeRegistryPrefix(ref.Context().RegistryStr() + "/" + ref.Context().RepositoryStr())
+}
+
+// normalizeRegistryPrefix spells Docker Hub as "docker.io" and drops a trailing slash
+func normalizeRegistryPrefix(prefix string) string {
+	prefix = strings.TrimSuffix(strings.TrimSpace(prefix), "/")
+	for _, alias := range []string{name.DefaultRegistry, "registry-1.docker.io"} {
+		if prefix == alias || strings.HasPrefix(prefix, alias+"/") {
+			return "docker.io" + strings.TrimPrefix(prefix, alias)
+		}
+	}
+	return prefix
+}
+
+// Bundle is an ordered set of policies read from one multi-document YAML
+// file, plus example images to test them with. Each image is evaluated
+// against the first policy whose selectors match it:
+//
+//	name: production
+//	include:
+//	  namespaces: ["prod-*"]
+//	exclude:
+//	  registries: [registry.example.com/sandbox]
+//	rules:
+//	  max_critical: 0
+//	---
+//	name: baseline           # no selectors: applies to all other images
+//	rules:
+//	  no_known_exploited: true
+//	---
+//	tests:
+//	  - name: critical CVE in production
+//	    image:
+//	      reference: ghcr.io/acme/api:1.4
+//	      namespaces: [prod-eu]
+//	      critical: 1
+//	    policy: production     # expected policy (optional)
+//	    verdict: fail          # expected verdict (optional)
+//	    rules: [max_critical]  # expected violated rules (optional, [] for none)
+//
+// A document holds either a policy or tests. Images no policy applies to get
+// the no_policy verdict, which passes.
+type Bundle struct {
+	Policies []*Policy `json:"policies"`
+	Tests    []Test    `json:"tests,omitempty"`
+}
+
+// Test is an example image and the outcome expected when the bundle
+// evaluates it
+type Test struct {
+	Name    string   `yaml:"name" json:"name"`
+	Image   Example  `yaml:"image" json:"image"`
+	Policy  string   `yaml:"policy" json:"policy,omitempty"`
+	Verdict string   `yaml:"verdict" json:"verdict,omitempty"`
+	Rules   []string `yaml:"rules" json:"rules,omitempty"`
+}
+
+// Example describes a test image by the facts policies are evaluated on
+type Example struct {
+	Reference      string            `yaml:"reference" json:"reference"`
+	Namespaces     []string          `yaml:"namespaces" json:"namespaces,omitempty"`
+	Labels         map[string]string `yaml:"labels" json:"labels,omitempty"`
+	Metadata       map[string]string `yaml:"metadata" json:"metadata,omitempty"` // CMDB attributes by name
+	OS             string            `yaml:"os" json:"os,omitempty"`             // name or name:version, e.g. debian:12
+	Critical       int               `yaml:"critical" json:"critical"`
+	KnownExploited int               `yaml:"known_exploited" json:"known_exploited"`
+	RiskScore      float64           `yaml:"risk_score" json:"risk_score"`
+	NotScanned     bool              `yaml:"not_scanned" json:"not_scanned,omitempty"` // no vulnerability results yet
+}
+
+// facts returns the example as policy facts
+func (e Example) facts() *database.ImagePolicyFacts {
+	osName, osVersion, _ := strings.Cut(e.OS, ":")
+	status := database.StatusCompleted
+	if e.NotScanned {
+		status = database.StatusPending
+	}
+	return &database.ImagePolicyFacts{
+		Digest:         "example",
+		Reference:      e.Reference,
+		Status:         status,
+		OSName:         strings.TrimSpace(osName),
+		OSVersion:      strings.TrimSpace(osVersion),
+		Namespaces:     e.Namespaces,
+		Labels:         e.Labels,
+		Metadata:       e.Metadata,
+		Critical:       e.Critical,
+		KnownExploited: e.KnownExploited,
+		RiskScore:      e.RiskScore,
+	}
+}
+
+// bundleDocument is one YAML document of a bundle: a policy or tests
+type bundleDocument struct {
+	Policy `yaml:",inline"`
+	Tests  []Test `yaml:"tests"`
+}
+
+// NewBundle returns a bundle of policies without tests
+func NewBundle(policies ...*Policy) *Bundle {
+	return &Bundle{Policies: policies}
+}
+
+// LoadBundle reads a bundle from a YAML file. A file with a single policy
+// document is a bundle of that policy.
+func LoadBundle(path string) (*Bundle, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read policy file: %w", err)
+	}
+	b, err := ParseBundle(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return b, nil
+}
+
+// ParseBundle parses and validates a multi-document YAML bundle. Unknown keys
+// are rejected like in Parse, and policy names must be unique.
+func ParseBundle(data []byte) (*Bundle, error) {
+	var b Bundle
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	for i := 1; ; i++ {
+		var doc bundleDocument
+		err := decoder.Decode(&doc)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid policy bundle: document %d: %w", i, err)
+		}
+
+		if doc.Tests != nil {
+			if doc.Name != "" || !doc.Policy.empty() {
+				return nil, fmt.Errorf("invalid policy bundle: document %d: a document holds either a policy or tests", i)
+			}
+			b.Tests = append(b.Tests, doc.Tests...)
+			continue
+		}
+		p := doc.Policy
+		if err := p.validate(); err != nil {
+			return nil, fmt.Errorf("invalid policy bundle: document %d: %w", i, err)
+		}
+		if slices.ContainsFunc(b.Policies, func(other *Policy) bool { return other.Name == p.Name }) {
+			return nil, fmt.Errorf("invalid policy bundle: document %d: duplicate policy name %q", i, p.Name)
+		}
+		b.Policies = append(b.Policies, &p)
+	}
+	if len(b.Policies) == 0 {
+		return nil, fmt.Errorf("invalid policy bundle: no policies")
+	}
+
+	for i, t := range b.Tests {
+		if t.Name == "" {
+			return nil, fmt.Errorf("invalid policy bundle: test %d has no name", i+1)
+		}
+		if t.Policy != "" && !slices.ContainsFunc(b.Policies, func(p *Policy) bool { return p.Name == t.Policy }) {
+			return nil, fmt.Errorf("invalid policy bundle: test %q expects unknown policy %q", t.Name, t.Policy)
+		}
+		if t.Verdict != "" && !slices.Contains([]string{VerdictPass, VerdictFail, VerdictNotScanned, VerdictNoPolicy}, t.Verdict) {
+			return nil, fmt.Errorf("invalid policy bundle: test %q expects unknown verdict %q", t.Name, t.Verdict)
+		}
+	}
+	return &b, nil
+}
+
+// Select returns the first policy that applies to an image, nil if none does
+func (b *Bundle) Select(facts *database.ImagePolicyFacts) *Policy {
+	for _, p := range b.Policies {
+		if p.AppliesTo(facts) {
+			return p
+		}
+	}
+	return nil
+}
+
+// Evaluate checks an image against the first policy that applies to it
+func (b *Bundle) Evaluate(facts *database.ImagePolicyFacts) Result {
+	if p := b.Select(facts); p != nil {
+		return p.Evaluate(facts)
+	}
+	return Result{Digest: facts.Digest, Verdict: VerdictNoPolicy, Pass: true, Violations: []Violation{}}
+}
+
+// Names returns the policy names in evaluation order
+func (b *Bundle) Names() []string {
+	names := make([]string, len(b.Policies))
+	for i, p := range b.Policies {
+		names[i] = p.Name
+	}
+	return names
+}
+
+// TestResult is the outcome of one bundle test
+type TestResult struct {
+	Test     Test
+	Result   Result   // verdict of the example image
+	Failures []string // expectations that were not met
+}
+
+// Rules returns the names of the rules that fired, in rule order
+func (r TestResult) Rules() []string {
+	rules := make([]string, len(r.Result.Violations))
+	for i, v := range r.Result.Violations {
+		rules[i] = v.Rule
+	}
+	return rules
+}
+
+// Passed reports whether every expectation of the test was met
+func (r TestResult) Passed() bool {
+	return len(r.Failures) == 0
+}
+
+// RunTests evaluates the example image of every test and compares the
+// selected policy, verdict and violated rules with the expected ones
+func (b *Bundle) RunTests() []TestResult {
+	results := make([]TestResult, len(b.Tests))
+	for i, t := range b.Tests {
+		r := TestResult{Test: t, Result: b.Evaluate(t.Image.facts())}
+		if t.Policy != "" && r.Result.Policy != t.Policy {
+			r.Failures = append(r.Failures, fmt.Sprintf("policy %s, expected %s", orNone(r.Result.Policy), t.Policy))
+		}
+		if t.Verdict != "" && r.Result.Verdict != t.Verdict {
+			r.Failures = append(r.Failures, fmt.Sprintf("verdict %s, expected %s", r.Result.Verdict, t.Verdict))
+		}
+		if t.Rules != nil {
+			fired := r.Rules()
+			expected := slices.Clone(t.Rules)
+			slices.Sort(fired)
+			slices.Sort(expected)
+			if !slices.Equal(fired, expected) {
+				r.Failures = append(r.Failures, fmt.Sprintf("rules [%s], expected [%s]",
+					strings.Join(r.Rules(), ", "), strings.Join(t.Rules, ", ")))
+			}
+		}
+		results[i] = r
+	}
+	return results
+}
+
+func orNone(s string) string {
+	if s == "" {
+		return "(none)"
+	}
+	return s
+}
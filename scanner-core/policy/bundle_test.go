@@ -0,0 +1,255 @@
+package policy
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/bvboe/b2s-go/scanner-core/database"
+)
+
+const testBundle = `
+name: production
+include:
+  namespaces: ["prod-*"]
+exclude:
+  registries: [registry.example.com/sandbox]
+rules:
+  max_critical: 0
+---
+name: vendor
+include:
+  registries: [docker.io/library]
+  labels:
+    org.opencontainers.image.vendor: "Acme*"
+rules:
+  allowed_os: [debian]
+---
+name: baseline
+exclude:
+  namespaces: [kube-system]
+rules:
+  no_known_exploited: true
+---
+tests:
+  - name: critical CVE in production
+    image:
+      reference: ghcr.io/acme/api:1.4
+      namespaces: [prod-eu]
+      critical: 1
+    policy: production
+    verdict: fail
+    rules: [max_critical]
+  - name: sandbox images use the baseline
+    image:
+      reference: registry.example.com/sandbox/tool:1
+      namespaces: [prod-eu]
+      critical: 3
+    policy: baseline
+    verdict: pass
+    rules: []
+`
+
+func TestParseBundle(t *testing.T) {
+	b, err := ParseBundle([]byte(testBundle))
+	if err != nil {
+		t.Fatalf("ParseBundle() error = %v", err)
+	}
+	if !reflect.DeepEqual(b.Names(), []string{"production", "vendor", "baseline"}) {
+		t.Errorf("Names() = %v", b.Names())
+	}
+	if len(b.Tests) != 2 || b.Tests[1].Rules == nil {
+		t.Errorf("Tests = %+v, want 2 with an explicit empty rule list", b.Tests)
+	}
+
+	// A plain policy file is a bundle of one policy that applies to all images
+	single, err := ParseBundle([]byte("rules:\n  no_known_exploited: true\n"))
+	if err != nil {
+		t.Fatalf("ParseBundle() of a single policy error = %v", err)
+	}
+	if !reflect.DeepEqual(single.Names(), []string{"default"}) {
+		t.Errorf("single policy Names() = %v", single.Names())
+	}
+
+	for name, invalid := range map[string]string{
+		"unknown rule":        "rules:\n  max_criticals: 1\n",
+		"unknown selector":    "include:\n  pods: [web]\n",
+		"bad pattern":         "include:\n  namespaces: [\"prod-[\"]\n",
+		"unknown attribute":   "include:\n  metadata:\n    team: payments\n",
+		"duplicate name":      "name: a\n---\nname: a\n",
+		"policy and tests":    "name: a\ntests: []\n",
+		"tests only":          "tests:\n  - name: t\n",
+		"unknown policy":      "name: a\n---\ntests:\n  - name: t\n    policy: b\n",
+		"unknown verdict":     "name: a\n---\ntests:\n  - name: t\n    verdict: maybe\n",
+		"unnamed test":        "name: a\n---\ntests:\n  - verdict: pass\n",
+		"negative limit":      "name: a\n---\nname: b\nrules:\n  max_critical: -1\n",
+		"not a policy bundle": "- alpine\n",
+	} {
+		if _, err := ParseBundle([]byte(invalid)); err == nil {
+			t.Errorf("%s: ParseBundle() succeeded, want error", name)
+		}
+	}
+}
+
+func TestBundleEvaluate(t *testing.T) {
+	b, err := ParseBundle([]byte(testBundle))
+	if err != nil {
+		t.Fatalf("ParseBundle() error = %v", err)
+	}
+	acme := map[string]string{"org.opencontainers.image.vendor": "Acme Corp"}
+
+	tests := []struct {
+		name    string
+		facts   database.ImagePolicyFacts
+		policy  string
+		verdict string
+	}{
+		{
+			name:    "namespace pattern",
+			facts:   database.ImagePolicyFacts{Reference: "ghcr.io/acme/api:1", Namespaces: []string{"default", "prod-us"}, Critical: 1},
+			policy:  "production",
+			verdict: VerdictFail,
+		},
+		{
+			name:    "excluded registry falls through",
+			facts:   database.ImagePolicyFacts{Reference: "registry.example.com/sandbox/tool:1", Namespaces: []string{"prod-us"}, Critical: 1},
+			policy:  "baseline",
+			verdict: VerdictPass,
+		},
+		{
+			name:    "Docker Hub short name and label",
+			facts:   database.ImagePolicyFacts{Reference: "nginx:1.25", Labels: acme, OSName: "alpine"},
+			policy:  "vendor",
+			verdict: VerdictFail,
+		},
+		{
+			name:    "label must match",
+			facts:   database.ImagePolicyFacts{Reference: "docker.io/library/nginx:1.25", OSName: "alpine"},
+			policy:  "baseline",
+			verdict: VerdictPass,
+		},
+		{
+			name:    "registry prefix on path boundary",
+			facts:   database.ImagePolicyFacts{Reference: "docker.io/library2/nginx:1.25", Labels: acme, OSName: "alpine"},
+			policy:  "baseline",
+			verdict: VerdictPass,
+		},
+		{
+			name:    "no policy applies",
+			facts:   database.ImagePolicyFacts{Reference: "registry.k8s.io/pause:3.9", Namespaces: []string{"kube-system"}, KnownExploited: 1},
+			verdict: VerdictNoPolicy,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.facts.Status = database.StatusCompleted
+			result := b.Evaluate(&tt.facts)
+			if result.Policy != tt.policy || result.Verdict != tt.verdict {
+				t.Errorf("Evaluate() = policy %q verdict %s, want %q %s", result.Policy, result.Verdict, tt.policy, tt.verdict)
+			}
+			if tt.verdict == VerdictNoPolicy && !result.Pass {
+				t.Error("no_policy verdict does not pass")
+			}
+		})
+	}
+}
+
+func TestBundleSelectsByCMDBMetadata(t *testing.T) {
+	b, err := ParseBundle([]byte(`
+name: restricted-data
+include:
+  metadata:
+    data_classification: restricted
+rules:
+  max_critical: 0
+---
+name: baseline
+rules:
+  no_known_exploited: true
+`))
+	if err != nil {
+		t.Fatalf("ParseBundle() error = %v", err)
+	}
+
+	restricted := database.ImagePolicyFacts{Status: database.StatusCompleted, Critical: 1,
+		Metadata: map[string]string{database.CMDBDataClassification: "restricted", database.CMDBOwner: "team-a"}}
+	if result := b.Evaluate(&restricted); result.Policy != "restricted-data" || result.Verdict != VerdictFail {
+		t.Errorf("Evaluate() = policy %q verdict %s, want restricted-data fail", result.Policy, result.Verdict)
+	}
+	unclassified := database.ImagePolicyFacts{Status: database.StatusCompleted, Critical: 1}
+	if result := b.Evaluate(&unclassified); result.Policy != "baseline" {
+		t.Errorf("Evaluate() of an image without metadata = policy %q, want baseline", result.Policy)
+	}
+}
+
+func TestRunTests(t *testing.T) {
+	b, err := ParseBundle([]byte(testBundle + `
+  - name: wrong expectation
+    image:
+      reference: ghcr.io/acme/api:1.4
+      namespaces: [prod-eu]
+      known_exploited: 1
+    verdict: fail
+    rules: [no_known_exploited]
+`))
+	if err != nil {
+		t.Fatalf("ParseBundle() error = %v", err)
+	}
+
+	results := b.RunTests()
+	if len(results) != 3 {
+		t.Fatalf("RunTests() returned %d results, want 3", len(results))
+	}
+	if !results[0].Passed() || !results[1].Passed() {
+		t.Errorf("RunTests() failures = %v, %v; want none", results[0].Failures, results[1].Failures)
+	}
+	if !reflect.DeepEqual(results[0].Rules(), []string{RuleMaxCritical}) {
+		t.Errorf("Rules() = %v", results[0].Rules())
+	}
+	// Production only checks critical CVEs, so the image passes
+	want := []string{"verdict pass, expected fail", "rules [], expected [no_known_exploited]"}
+	if !reflect.DeepEqual(results[2].Failures, want) {
+		t.Errorf("Failures = %q, want %q", results[2].Failures, want)
+	}
+}
+
+func TestRunCommand(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "policy.yaml")
+	if err := os.WriteFile(path, []byte(testBundle), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	if code := RunCommand([]string{"test", path}, &out); code != 0 {
+		t.Fatalf("RunCommand() = %d, output:\n%s", code, out.String())
+	}
+	for _, want := range []string{
+		"policies: production, vendor, baseline",
+		"ok   critical CVE in production: policy production, verdict fail, rules max_critical",
+		"ok   sandbox images use the baseline: policy baseline, verdict pass, no rules fired",
+		"2 tests, 0 failed",
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("output missing %q:\n%s", want, out.String())
+		}
+	}
+
+	failing := strings.Replace(testBundle, "verdict: fail", "verdict: pass", 1)
+	if err := os.WriteFile(path, []byte(failing), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	out.Reset()
+	if code := RunCommand([]string{"test", path}, &out); code != 1 {
+		t.Errorf("RunCommand() with a failing test = %d, want 1", code)
+	}
+	if !strings.Contains(out.String(), "FAIL critical CVE in production") || !strings.Contains(out.String(), "verdict fail, expected pass") {
+		t.Errorf("output does not report the failure:\n%s", out.String())
+	}
+
+	if code := RunCommand([]string{"lint", path}, &out); code != 2 {
+		t.Errorf("RunCommand() with an unknown subcommand = %d, want 2", code)
+	}
+}
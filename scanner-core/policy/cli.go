@@ -0,0 +1,52 @@
+package policy
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// RunCommand runs the policy subcommand of the bjorn2scan binaries and returns
+// its exit code. `policy test <file>` evaluates the example images of a bundle
+// and reports the policy, verdict and rules that fire for each, so policy
+// changes can be reviewed like code; it exits with 1 if an expectation is not met.
+func RunCommand(args []string, stdout io.Writer) int {
+	if len(args) != 2 || args[0] != "test" {
+		_, _ = fmt.Fprintln(stdout, "usage: policy test <policy-file>")
+		return 2
+	}
+
+	bundle, err := LoadBundle(args[1])
+	if err != nil {
+		_, _ = fmt.Fprintf(stdout, "error: %v\n", err)
+		return 2
+	}
+	_, _ = fmt.Fprintf(stdout, "policies: %s\n", strings.Join(bundle.Names(), ", "))
+	if len(bundle.Tests) == 0 {
+		_, _ = fmt.Fprintln(stdout, "no tests")
+		return 0
+	}
+
+	failed := 0
+	for _, r := range bundle.RunTests() {
+		status := "ok  "
+		if !r.Passed() {
+			status = "FAIL"
+			failed++
+		}
+		rules := "no rules fired"
+		if fired := r.Rules(); len(fired) > 0 {
+			rules = "rules " + strings.Join(fired, ", ")
+		}
+		_, _ = fmt.Fprintf(stdout, "%s %s: policy %s, verdict %s, %s\n",
+			status, r.Test.Name, orNone(r.Result.Policy), r.Result.Verdict, rules)
+		for _, failure := range r.Failures {
+			_, _ = fmt.Fprintf(stdout, "     %s\n", failure)
+		}
+	}
+	_, _ = fmt.Fprintf(stdout, "%d tests, %d failed\n", len(bundle.Tests), failed)
+	if failed > 0 {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,230 @@
+// Package policy evaluates images against a YAML-defined security policy and
+// returns a deterministic pass/fail verdict, for gating CI pipelines and
+// admission decisions on scan results rather than raw vulnerability counts.
+//
+// A policy file looks like:
+//
+//	name: production
+//	rules:
+//	  max_critical: 0          # at most this many unique critical CVEs
+//	  no_known_exploited: true # no CVEs in the CISA KEV catalog
+//	  max_risk_score: 250      # at most this total risk score
+//	  allowed_os:              # base OS releases images may use (name or name:version)
+//	    - alpine
+//	    - debian:12
+//	    - ubuntu:22.04
+//
+// Rules that are left out are not checked. The same facts and policy always
+// produce the same verdict, with violations listed in rule order.
+//
+// Several policies can be bundled in one multi-document file, each applying
+// to the images its include/exclude selectors choose, together with example
+// images that `policy test <file>` evaluates (see Bundle and RunCommand).
+package policy
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/bvboe/b2s-go/scanner-core/database"
+)
+
+// Verdicts of an evaluation
+const (
+	VerdictPass       = "pass"
+	VerdictFail       = "fail"
+	VerdictNotScanned = "not_scanned" // no vulnerability scan results to evaluate yet
+)
+
+// Rule names, as reported in violations
+const (
+	RuleMaxCritical      = "max_critical"
+	RuleNoKnownExploited = "no_known_exploited"
+	RuleMaxRiskScore     = "max_risk_score"
+	RuleAllowedOS        = "allowed_os"
+)
+
+// Rules are the checks of a policy. Nil limits are not checked.
+type Rules struct {
+	MaxCritical      *int     `yaml:"max_critical" json:"max_critical,omitempty"`
+	NoKnownExploited bool     `yaml:"no_known_exploited" json:"no_known_exploited"`
+	MaxRiskScore     *float64 `yaml:"max_risk_score" json:"max_risk_score,omitempty"`
+	AllowedOS        []string `yaml:"allowed_os" json:"allowed_os,omitempty"`
+}
+
+// Policy is a named set of rules. In a Bundle, Include and Exclude choose the
+// images the policy applies to; a policy without them applies to all images.
+type Policy struct {
+	Name    string    `yaml:"name" json:"name"`
+	Include *Selector `yaml:"include" json:"include,omitempty"`
+	Exclude *Selector `yaml:"exclude" json:"exclude,omitempty"`
+	Rules   Rules     `yaml:"rules" json:"rules"`
+}
+
+// Violation is a rule an image does not satisfy
+type Violation struct {
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+// Result is the verdict of evaluating an image against a policy
+type Result struct {
+	Policy     string      `json:"policy"`
+	Digest     string      `json:"digest"`
+	Verdict    string      `json:"verdict"`
+	Pass       bool        `json:"pass"`
+	Violations []Violation `json:"violations"`
+}
+
+// Default returns the policy used when no policy file is configured: no
+// critical and no known exploited vulnerabilities
+func Default() *Policy {
+	maxCritical := 0
+	return &Policy{
+		Name:  "default",
+		Rules: Rules{MaxCritical: &maxCritical, NoKnownExploited: true},
+	}
+}
+
+// Load reads a policy from a YAML file
+func Load(path string) (*Policy, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read policy file: %w", err)
+	}
+	p, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return p, nil
+}
+
+// Parse parses and validates a YAML policy. Unknown keys are rejected, so a
+// misspelled rule is not silently ignored.
+func Parse(data []byte) (*Policy, error) {
+	var p Policy
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&p); err != nil {
+		return nil, fmt.Errorf("invalid policy: %w", err)
+	}
+	if err := p.validate(); err != nil {
+		return nil, fmt.Errorf("invalid policy: %w", err)
+	}
+	return &p, nil
+}
+
+// validate checks the rule limits and selector patterns, naming an unnamed
+// policy "default"
+func (p *Policy) validate() error {
+	if p.Name == "" {
+		p.Name = "default"
+	}
+	if p.Rules.MaxCritical != nil && *p.Rules.MaxCritical < 0 {
+		return fmt.Errorf("max_critical must not be negative")
+	}
+	if p.Rules.MaxRiskScore != nil && *p.Rules.MaxRiskScore < 0 {
+		return fmt.Errorf("max_risk_score must not be negative")
+	}
+	for _, entry := range p.Rules.AllowedOS {
+		if name, _, _ := strings.Cut(entry, ":"); strings.TrimSpace(name) == "" {
+			return fmt.Errorf("allowed_os entry %q has no OS name", entry)
+		}
+	}
+	if p.Include != nil {
+		if err := p.Include.validate("include"); err != nil {
+			return err
+		}
+	}
+	if p.Exclude != nil {
+		if err := p.Exclude.validate("exclude"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// empty reports whether no selector or rule of the policy is set
+func (p *Policy) empty() bool {
+	return p.Include.empty() && p.Exclude.empty() && p.Rules.MaxCritical == nil && !p.Rules.NoKnownExploited &&
+		p.Rules.MaxRiskScore == nil && len(p.Rules.AllowedOS) == 0
+}
+
+// AppliesTo reports whether the selectors of the policy choose an image: it
+// matches the include selector (if any) and not the exclude selector
+func (p *Policy) AppliesTo(facts *database.ImagePolicyFacts) bool {
+	if !p.Include.empty() && !p.Include.matches(facts) {
+		return false
+	}
+	return p.Exclude.empty() || !p.Exclude.matches(facts)
+}
+
+// Evaluate checks an image against the policy. Images without vulnerability
+// scan results get the not_scanned verdict, which does not pass.
+func (p *Policy) Evaluate(facts *database.ImagePolicyFacts) Result {
+	result := Result{Policy: p.Name, Digest: facts.Digest, Violations: []Violation{}}
+	if !facts.Status.HasVulnerabilities() {
+		result.Verdict = VerdictNotScanned
+		return result
+	}
+
+	if limit := p.Rules.MaxCritical; limit != nil && facts.Critical > *limit {
+		result.Violations = append(result.Violations, Violation{
+			Rule:    RuleMaxCritical,
+			Message: fmt.Sprintf("%d critical vulnerabilities, at most %d allowed", facts.Critical, *limit),
+		})
+	}
+	if p.Rules.NoKnownExploited && facts.KnownExploited > 0 {
+		result.Violations = append(result.Violations, Violation{
+			Rule:    RuleNoKnownExploited,
+			Message: fmt.Sprintf("%d known exploited vulnerabilities", facts.KnownExploited),
+		})
+	}
+	if limit := p.Rules.MaxRiskScore; limit != nil && facts.RiskScore > *limit {
+		result.Violations = append(result.Violations, Violation{
+			Rule:    RuleMaxRiskScore,
+			Message: fmt.Sprintf("risk score %.1f exceeds %.1f", facts.RiskScore, *limit),
+		})
+	}
+	if len(p.Rules.AllowedOS) > 0 && !p.osAllowed(facts.OSName, facts.OSVersion) {
+		release := "unknown OS"
+		if facts.OSName != "" {
+			release = strings.TrimSpace(facts.OSName + " " + facts.OSVersion)
+		}
+		result.Violations = append(result.Violations, Violation{
+			Rule:    RuleAllowedOS,
+			Message: fmt.Sprintf("base OS %s is not allowed", release),
+		})
+	}
+
+	result.Pass = len(result.Violations) == 0
+	result.Verdict = VerdictFail
+	if result.Pass {
+		result.Verdict = VerdictPass
+	}
+	return result
+}
+
+// osAllowed reports whether an OS release matches an allowed_os entry. Names
+// match case-insensitively; a version matches itself and its point releases
+// (debian:12 allows 12 and 12.5, but not 120).
+func (p *Policy) osAllowed(name, version string) bool {
+	if name == "" {
+		return false
+	}
+	for _, entry := range p.Rules.AllowedOS {
+		allowedName, allowedVersion, _ := strings.Cut(entry, ":")
+		if !strings.EqualFold(strings.TrimSpace(allowedName), name) {
+			continue
+		}
+		allowedVersion = strings.TrimSpace(allowedVersion)
+		if allowedVersion == "" || version == allowedVersion || strings.HasPrefix(version, allowedVersion+".") {
+			return true
+		}
+	}
+	return false
+}
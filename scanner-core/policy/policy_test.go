@@ -0,0 +1,132 @@
+package policy
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/bvboe/b2s-go/scanner-core/database"
+)
+
+func TestParse(t *testing.T) {
+	p, err := Parse([]byte(`
+name: production
+rules:
+  max_critical: 0
+  no_known_exploited: true
+  max_risk_score: 250.5
+  allowed_os: [alpine, "debian:12"]
+`))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if p.Name != "production" || *p.Rules.MaxCritical != 0 || !p.Rules.NoKnownExploited ||
+		*p.Rules.MaxRiskScore != 250.5 || !reflect.DeepEqual(p.Rules.AllowedOS, []string{"alpine", "debian:12"}) {
+		t.Errorf("Parse() = %+v", p)
+	}
+
+	for name, invalid := range map[string]string{
+		"unknown rule":      "rules:\n  max_criticals: 1\n",
+		"negative limit":    "rules:\n  max_critical: -1\n",
+		"negative risk":     "rules:\n  max_risk_score: -5\n",
+		"empty OS name":     "rules:\n  allowed_os: [\":12\"]\n",
+		"not a number":      "rules:\n  max_critical: many\n",
+		"not a policy file": "- alpine\n",
+	} {
+		if _, err := Parse([]byte(invalid)); err == nil {
+			t.Errorf("%s: Parse() succeeded, want error", name)
+		}
+	}
+}
+
+func TestLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "policy.yaml")
+	if err := os.WriteFile(path, []byte("rules:\n  no_known_exploited: true\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	p, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if p.Name != "default" || !p.Rules.NoKnownExploited || p.Rules.MaxCritical != nil {
+		t.Errorf("Load() = %+v", p)
+	}
+	if _, err := Load(filepath.Join(t.TempDir(), "missing.yaml")); err == nil {
+		t.Error("Load() of a missing file succeeded, want error")
+	}
+}
+
+func TestEvaluate(t *testing.T) {
+	p, err := Parse([]byte(`
+name: production
+rules:
+  max_critical: 1
+  no_known_exploited: true
+  max_risk_score: 100
+  allowed_os: [Alpine, "debian:12"]
+`))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		facts   database.ImagePolicyFacts
+		verdict string
+		rules   []string
+	}{
+		{
+			name:    "within limits",
+			facts:   database.ImagePolicyFacts{Status: database.StatusCompleted, OSName: "alpine", OSVersion: "3.19.1", Critical: 1, RiskScore: 100},
+			verdict: VerdictPass,
+		},
+		{
+			name:    "point release of allowed version",
+			facts:   database.ImagePolicyFacts{Status: database.StatusCompleted, OSName: "debian", OSVersion: "12.5"},
+			verdict: VerdictPass,
+		},
+		{
+			name: "all rules violated in rule order",
+			facts: database.ImagePolicyFacts{Status: database.StatusCompleted, OSName: "debian", OSVersion: "120",
+				Critical: 2, KnownExploited: 1, RiskScore: 100.5},
+			verdict: VerdictFail,
+			rules:   []string{RuleMaxCritical, RuleNoKnownExploited, RuleMaxRiskScore, RuleAllowedOS},
+		},
+		{
+			name:    "unknown OS is not allowed",
+			facts:   database.ImagePolicyFacts{Status: database.StatusCompleted},
+			verdict: VerdictFail,
+			rules:   []string{RuleAllowedOS},
+		},
+		{
+			name:    "not scanned",
+			facts:   database.ImagePolicyFacts{Status: database.StatusGeneratingSBOM, OSName: "alpine", Critical: 5},
+			verdict: VerdictNotScanned,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := p.Evaluate(&tt.facts)
+			var rules []string
+			for _, v := range result.Violations {
+				rules = append(rules, v.Rule)
+			}
+			if result.Verdict != tt.verdict || result.Pass != (tt.verdict == VerdictPass) || !reflect.DeepEqual(rules, tt.rules) {
+				t.Errorf("Evaluate() = %+v, want verdict %s with violations %v", result, tt.verdict, tt.rules)
+			}
+			if result.Policy != "production" {
+				t.Errorf("Evaluate() policy = %q", result.Policy)
+			}
+		})
+	}
+}
+
+func TestDefault(t *testing.T) {
+	p := Default()
+	clean := p.Evaluate(&database.ImagePolicyFacts{Status: database.StatusCompleted, RiskScore: 1000})
+	exploited := p.Evaluate(&database.ImagePolicyFacts{Status: database.StatusCompleted, KnownExploited: 1})
+	if !clean.Pass || exploited.Pass {
+		t.Errorf("Default() verdicts = %s, %s; want pass, fail", clean.Verdict, exploited.Verdict)
+	}
+}
@@ -0,0 +1,362 @@
+// Package provenance reads SLSA build provenance attestations of images from
+// their registry and reports the builder that produced each image and the SLSA
+// build level its provenance supports, so images from unvetted build sources
+// stand out next to their vulnerabilities. It also reads the SBOMs vendors
+// attest for their images, verifying their signatures (see VendorSBOMs).
+//
+// Two attestation layouts are recognized: cosign attestations stored under the
+// sha256-<hex>.att tag (DSSE envelopes, as written by cosign attest and the
+// SLSA GitHub generator) and BuildKit attestation manifests in the image index
+// (docker buildx build --provenance). DSSE signatures are verified against the
+// trusted public keys given to NewVerifyingFetcher, the same way vendor SBOMs
+// are; provenance whose signature cannot be verified is reported as unsigned:
+//
+//   - 1: provenance exists but is unsigned or not signed by a trusted key
+//     (e.g. BuildKit, or any provenance when no keys are configured)
+//   - 2: provenance is signed by a trusted key
+//   - 3: signed provenance from a hardened hosted builder (see HardenedBuilders)
+package provenance
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/bvboe/b2s-go/scanner-core/database"
+	"github.com/bvboe/b2s-go/scanner-core/logging"
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/remote/transport"
+)
+
+var log = logging.For(logging.ComponentProvenance)
+
+// Attestation layouts provenance is read from
+const (
+	SourceCosign   = "cosign"   // DSSE envelopes under the sha256-<hex>.att tag
+	SourceBuildKit = "buildkit" // attestation manifests in the image index
+)
+
+// HardenedBuilders are builder ID prefixes of hosted builders that meet SLSA
+// Build L3 (isolated, ephemeral builds whose provenance the tenant cannot forge)
+var HardenedBuilders = []string{
+	"https://github.com/slsa-framework/slsa-github-generator/",
+	"https://cloudbuild.googleapis.com/GoogleHostedWorker",
+}
+
+// slsaPredicatePrefix prefixes the in-toto predicate types of all SLSA
+// provenance versions (v0.1, v0.2, v1)
+const slsaPredicatePrefix = "https://slsa.dev/provenance/"
+
+// maxAttestationSize bounds the attestation layers read, so a bogus layer
+// cannot exhaust memory
+const maxAttestationSize = 8 << 20
+
+// BuildKit annotations linking attestation manifests to the image manifests
+const (
+	annotationReferenceType   = "vnd.docker.reference.type"
+	annotationReferenceDigest = "vnd.docker.reference.digest"
+	referenceTypeAttestation  = "attestation-manifest"
+	annotationPredicateType   = "in-toto.io/predicate-type"
+)
+
+// Fetcher reads provenance attestations from registries, using credentials
+// from the default keychain (docker config) and anonymous access otherwise
+type Fetcher struct {
+	opts []remote.Option
+	keys []trustedKey // keys provenance signatures are verified with
+}
+
+// NewFetcher creates a Fetcher without trusted keys, which reports all
+// provenance as unsigned
+func NewFetcher() *Fetcher {
+	return &Fetcher{opts: []remote.Option{remote.WithAuthFromKeychain(authn.DefaultKeychain)}}
+}
+
+// NewVerifyingFetcher creates a Fetcher verifying provenance signatures with
+// the PEM encoded public keys in keyFile. Without a keyFile, all provenance is
+// reported as unsigned.
+func NewVerifyingFetcher(keyFile string) (*Fetcher, error) {
+	f := NewFetcher()
+	if keyFile == "" {
+		return f, nil
+	}
+	data, err := os.ReadFile(keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read provenance public keys: %w", err)
+	}
+	if f.keys, err = parsePublicKeys(data); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// statement is the part of an in-toto statement provenance is read from
+type statement struct {
+	PredicateType string          `json:"predicateType"`
+	Predicate     json.RawMessage `json:"predicate"`
+	Subject       []struct {
+		Digest map[string]string `json:"digest"`
+	} `json:"subject"`
+}
+
+// attestation is an in-toto statement and where it was read from
+type attestation struct {
+	statement statement
+	source    string
+	envelope  *envelope // the DSSE envelope of a cosign attestation
+}
+
+// envelope is a DSSE envelope as stored by cosign
+type envelope struct {
+	PayloadType string `json:"payloadType"`
+	Payload     string `json:"payload"`
+	Signatures  []struct {
+		KeyID string `json:"keyid"`
+		Sig   string `json:"sig"`
+	} `json:"signatures"`
+}
+
+// Fetch returns the provenance of the image with the given digest, looked up
+// in the repository of reference. Returns nil without error if the image has
+// no SLSA provenance attestation.
+func (f *Fetcher) Fetch(ctx context.Context, reference, digest string) (*database.ImageProvenance, error) {
+	ref, err := name.ParseReference(reference)
+	if err != nil {
+		return nil, fmt.Errorf("invalid reference %q: %w", reference, err)
+	}
+	if _, err := v1.NewHash(digest); err != nil {
+		return nil, fmt.Errorf("invalid digest %q: %w", digest, err)
+	}
+	opts := append([]remote.Option{remote.WithContext(ctx)}, f.opts...)
+
+	attestations, err := cosignAttestations(ref.Context(), digest, opts)
+	if err != nil {
+		return nil, err
+	}
+	if len(attestations) == 0 {
+		if attestations, err = buildKitAttestations(ref, digest, opts); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, a := range attestations {
+		builderID, ok := slsaBuilderID(a.statement)
+		if !ok {
+			continue
+		}
+		signer, signed := verifyEnvelope(a.envelope, f.keys)
+		// A signed statement copied from another image does not vouch for this one
+		if signed && !a.statement.hasSubject(digest) {
+			signer, signed = "", false
+		}
+		log.Debug("found provenance", "digest", digest, "builder", builderID, "source", a.source, "signer", signer)
+		return &database.ImageProvenance{
+			BuilderID: builderID,
+			SLSALevel: slsaLevel(builderID, signed),
+			Source:    a.source,
+		}, nil
+	}
+	return nil, nil
+}
+
+// slsaLevel returns the SLSA build level provenance from builderID supports;
+// signed means its signature was verified with a trusted key
+func slsaLevel(builderID string, signed bool) int {
+	if !signed {
+		return 1
+	}
+	for _, prefix := range HardenedBuilders {
+		if strings.HasPrefix(builderID, prefix) {
+			return 3
+		}
+	}
+	return 2
+}
+
+// slsaBuilderID returns the builder ID of a SLSA provenance statement; ok is
+// false for other predicates. The builder moved to runDetails in SLSA v1.
+func slsaBuilderID(s statement) (builderID string, ok bool) {
+	if !strings.HasPrefix(s.PredicateType, slsaPredicatePrefix) {
+		return "", false
+	}
+	var predicate struct {
+		Builder struct {
+			ID string `json:"id"`
+		} `json:"builder"`
+		RunDetails struct {
+			Builder struct {
+				ID string `json:"id"`
+			} `json:"builder"`
+		} `json:"runDetails"`
+	}
+	if err := json.Unmarshal(s.Predicate, &predicate); err != nil {
+		return "", false
+	}
+	if predicate.RunDetails.Builder.ID != "" {
+		return predicate.RunDetails.Builder.ID, true
+	}
+	return predicate.Builder.ID, true
+}
+
+// cosignAttestations reads the DSSE envelopes cosign stores under the
+// sha256-<hex>.att tag of the image's repository
+func cosignAttestations(repo name.Repository, digest string, opts []remote.Option) ([]attestation, error) {
+	tag := repo.Tag(strings.Replace(digest, ":", "-", 1) + ".att")
+	img, err := remote.Image(tag, opts...)
+	if isNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch cosign attestations %s: %w", tag, err)
+	}
+
+	layers, err := img.Layers()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read cosign attestations %s: %w", tag, err)
+	}
+	var attestations []attestation
+	for _, layer := range layers {
+		data, err := readLayer(layer)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read cosign attestation %s: %w", tag, err)
+		}
+		var envelope envelope
+		if err := json.Unmarshal(data, &envelope); err != nil {
+			log.Debug("skipping unreadable cosign attestation", "tag", tag.String(), "error", err)
+			continue
+		}
+		payload, err := base64.StdEncoding.DecodeString(envelope.Payload)
+		if err != nil {
+			log.Debug("skipping cosign attestation with invalid payload", "tag", tag.String(), "error", err)
+			continue
+		}
+		var s statement
+		if err := json.Unmarshal(payload, &s); err != nil {
+			log.Debug("skipping cosign attestation with invalid statement", "tag", tag.String(), "error", err)
+			continue
+		}
+		attestations = append(attestations, attestation{statement: s, source: SourceCosign, envelope: &envelope})
+	}
+	return attestations, nil
+}
+
+// buildKitAttestations reads the attestation manifests BuildKit adds to image
+// indexes. The digest may be the index itself (the repo digest Kubernetes
+// usually reports) or one of its platform manifests, in which case the index
+// is looked up by the reference's tag.
+func buildKitAttestations(ref name.Reference, digest string, opts []remote.Option) ([]attestation, error) {
+	repo := ref.Context()
+	desc, err := remote.Get(repo.Digest(digest), opts...)
+	if isNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch %s@%s: %w", repo, digest, err)
+	}
+
+	if !desc.MediaType.IsIndex() {
+		tag, ok := ref.(name.Tag)
+		if !ok {
+			return nil, nil
+		}
+		if desc, err = remote.Get(tag, opts...); isNotFound(err) {
+			return nil, nil
+		} else if err != nil {
+			return nil, fmt.Errorf("failed to fetch %s: %w", tag, err)
+		}
+		if !desc.MediaType.IsIndex() {
+			return nil, nil
+		}
+	}
+
+	index, err := desc.ImageIndex()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read index of %s: %w", ref, err)
+	}
+	manifest, err := index.IndexManifest()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read index of %s: %w", ref, err)
+	}
+
+	var attestations []attestation
+	for _, m := range manifest.Manifests {
+		if m.Annotations[annotationReferenceType] != referenceTypeAttestation {
+			continue
+		}
+		// For a platform manifest, only its own attestation applies
+		if desc.Digest.String() != digest && m.Annotations[annotationReferenceDigest] != digest {
+			continue
+		}
+		statements, err := buildKitStatements(repo.Digest(m.Digest.String()), opts)
+		if err != nil {
+			return nil, err
+		}
+		for _, s := range statements {
+			attestations = append(attestations, attestation{statement: s, source: SourceBuildKit})
+		}
+	}
+	return attestations, nil
+}
+
+// buildKitStatements reads the SLSA provenance layers of a BuildKit
+// attestation manifest. BuildKit attestations are plain in-toto statements,
+// not signed envelopes.
+func buildKitStatements(ref name.Digest, opts []remote.Option) ([]statement, error) {
+	img, err := remote.Image(ref, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch attestation manifest %s: %w", ref, err)
+	}
+	manifest, err := img.Manifest()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read attestation manifest %s: %w", ref, err)
+	}
+
+	var statements []statement
+	for _, l := range manifest.Layers {
+		if !strings.HasPrefix(l.Annotations[annotationPredicateType], slsaPredicatePrefix) {
+			continue
+		}
+		layer, err := img.LayerByDigest(l.Digest)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch attestation layer %s: %w", l.Digest, err)
+		}
+		data, err := readLayer(layer)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read attestation layer %s: %w", l.Digest, err)
+		}
+		var s statement
+		if err := json.Unmarshal(data, &s); err != nil {
+			log.Debug("skipping invalid BuildKit attestation", "manifest", ref.String(), "error", err)
+			continue
+		}
+		statements = append(statements, s)
+	}
+	return statements, nil
+}
+
+// readLayer reads an attestation layer. Attestations are stored uncompressed,
+// so the blob is read as is.
+func readLayer(layer v1.Layer) ([]byte, error) {
+	rc, err := layer.Compressed()
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rc.Close() }()
+	return io.ReadAll(io.LimitReader(rc, maxAttestationSize))
+}
+
+// isNotFound reports whether a registry request failed because the manifest
+// does not exist
+func isNotFound(err error) bool {
+	var terr *transport.Error
+	return errors.As(err, &terr) && terr.StatusCode == http.StatusNotFound
+}
@@ -0,0 +1,240 @@
+package provenance
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	stdlog "log"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/registry"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/static"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+)
+
+// newTestRegistry starts an in-memory registry and returns its host
+func newTestRegistry(t *testing.T) string {
+	t.Helper()
+	server := httptest.NewServer(registry.New(registry.Logger(stdlog.New(io.Discard, "", 0))))
+	t.Cleanup(server.Close)
+	return strings.TrimPrefix(server.URL, "http://")
+}
+
+func parseRef(t *testing.T, ref string) name.Reference {
+	t.Helper()
+	r, err := name.ParseReference(ref)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return r
+}
+
+func provenanceStatement(t *testing.T, predicateType, predicate string) []byte {
+	t.Helper()
+	data, err := json.Marshal(map[string]interface{}{
+		"_type":         "https://in-toto.io/Statement/v0.1",
+		"predicateType": predicateType,
+		"predicate":     json.RawMessage(predicate),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func pushImage(t *testing.T, ref string) v1.Image {
+	t.Helper()
+	img, err := random.Image(64, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := remote.Write(parseRef(t, ref), img); err != nil {
+		t.Fatalf("failed to push %s: %v", ref, err)
+	}
+	return img
+}
+
+func imageDigest(t *testing.T, img interface{ Digest() (v1.Hash, error) }) string {
+	t.Helper()
+	d, err := img.Digest()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return d.String()
+}
+
+func TestFetchCosign(t *testing.T) {
+	host := newTestRegistry(t)
+	trusted, keyFile := writeKeyFile(t)
+	untrusted, _ := writeKeyFile(t)
+	const generator = "https://github.com/slsa-framework/slsa-github-generator/.github/workflows/generator_container_slsa3.yml@refs/tags/v1.9.0"
+
+	fetcher, err := NewVerifyingFetcher(keyFile)
+	if err != nil {
+		t.Fatalf("NewVerifyingFetcher() error = %v", err)
+	}
+
+	// pushProvenance pushes repo:1.0 with a cosign provenance attestation
+	// about subject (the image itself if empty), wrapped in an envelope by sign
+	pushProvenance := func(repo string, sign func(payload []byte) []byte, subject string) string {
+		digest := imageDigest(t, pushImage(t, repo+":1.0"))
+		if subject == "" {
+			subject = digest
+		}
+		algorithm, hexDigest, _ := strings.Cut(subject, ":")
+		payload, err := json.Marshal(map[string]interface{}{
+			"_type":         "https://in-toto.io/Statement/v0.1",
+			"subject":       []map[string]interface{}{{"name": "app", "digest": map[string]string{algorithm: hexDigest}}},
+			"predicateType": "https://slsa.dev/provenance/v0.2",
+			"predicate":     json.RawMessage(`{"builder": {"id": "` + generator + `"}}`),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		pushAttestation(t, repo, digest, sign(payload))
+		return digest
+	}
+	signedBy := func(key *ecdsa.PrivateKey) func([]byte) []byte {
+		return func(payload []byte) []byte { return signEnvelope(t, key, payload) }
+	}
+	// A signature that is present but not valid for the payload
+	bogusSignature := func(payload []byte) []byte {
+		envelope, _ := json.Marshal(map[string]interface{}{
+			"payloadType": "application/vnd.in-toto+json",
+			"payload":     base64.StdEncoding.EncodeToString(payload),
+			"signatures":  []map[string]string{{"keyid": "", "sig": "MEUCIQ=="}},
+		})
+		return envelope
+	}
+
+	signedRef := host + "/team/app:1.0"
+	signedDigest := pushProvenance(host+"/team/app", signedBy(trusted), "")
+
+	tests := []struct {
+		name    string
+		fetcher *Fetcher
+		ref     string
+		digest  string
+		want    int
+	}{
+		{"signed by a trusted key", fetcher, signedRef, signedDigest, 3},
+		{"no trusted keys configured", NewFetcher(), signedRef, signedDigest, 1},
+		{"signed by an untrusted key", fetcher, host + "/team/untrusted:1.0",
+			pushProvenance(host+"/team/untrusted", signedBy(untrusted), ""), 1},
+		{"invalid signature", fetcher, host + "/team/bogus:1.0",
+			pushProvenance(host+"/team/bogus", bogusSignature, ""), 1},
+		{"trusted provenance of another image", fetcher, host + "/team/copied:1.0",
+			pushProvenance(host+"/team/copied", signedBy(trusted), signedDigest), 1},
+	}
+	for _, tt := range tests {
+		got, err := tt.fetcher.Fetch(context.Background(), tt.ref, tt.digest)
+		if err != nil {
+			t.Fatalf("%s: Fetch() error = %v", tt.name, err)
+		}
+		if got == nil || got.SLSALevel != tt.want || got.Source != SourceCosign || got.BuilderID != generator {
+			t.Errorf("%s: Fetch() = %+v, want level %d from the SLSA generator via cosign", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestNewVerifyingFetcher(t *testing.T) {
+	if f, err := NewVerifyingFetcher(""); err != nil || len(f.keys) != 0 {
+		t.Errorf("NewVerifyingFetcher(\"\") = %+v, %v, want a fetcher without keys", f, err)
+	}
+	if _, err := NewVerifyingFetcher(filepath.Join(t.TempDir(), "missing.pem")); err == nil {
+		t.Error("expected an error for a missing key file")
+	}
+}
+
+func TestFetchBuildKit(t *testing.T) {
+	host := newTestRegistry(t)
+	ref := host + "/team/api:2.0"
+
+	platform, err := random.Image(64, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	platformDigest := imageDigest(t, platform)
+
+	statement := provenanceStatement(t, "https://slsa.dev/provenance/v1",
+		`{"buildDefinition": {}, "runDetails": {"builder": {"id": "https://github.com/docker/buildx"}}}`)
+	att, err := mutate.Append(empty.Image, mutate.Addendum{
+		Layer:       static.NewLayer(statement, "application/vnd.in-toto+json"),
+		Annotations: map[string]string{annotationPredicateType: "https://slsa.dev/provenance/v1"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	index := mutate.AppendManifests(mutate.IndexMediaType(empty.Index, types.OCIImageIndex),
+		mutate.IndexAddendum{Add: platform, Descriptor: v1.Descriptor{Platform: &v1.Platform{OS: "linux", Architecture: "amd64"}}},
+		mutate.IndexAddendum{Add: att, Descriptor: v1.Descriptor{
+			Platform: &v1.Platform{OS: "unknown", Architecture: "unknown"},
+			Annotations: map[string]string{
+				annotationReferenceType:   referenceTypeAttestation,
+				annotationReferenceDigest: platformDigest,
+			},
+		}},
+	)
+	if err := remote.WriteIndex(parseRef(t, ref), index); err != nil {
+		t.Fatalf("failed to push index: %v", err)
+	}
+
+	// Kubernetes usually reports the index digest, but may report the platform manifest
+	for _, digest := range []string{imageDigest(t, index), platformDigest} {
+		got, err := NewFetcher().Fetch(context.Background(), ref, digest)
+		if err != nil {
+			t.Fatalf("Fetch(%s) error = %v", digest, err)
+		}
+		if got == nil || got.SLSALevel != 1 || got.Source != SourceBuildKit || got.BuilderID != "https://github.com/docker/buildx" {
+			t.Errorf("Fetch(%s) = %+v, want unsigned level 1 provenance from buildx", digest, got)
+		}
+	}
+}
+
+func TestFetchNoProvenance(t *testing.T) {
+	host := newTestRegistry(t)
+	ref := host + "/team/plain:1.0"
+	digest := imageDigest(t, pushImage(t, ref))
+
+	got, err := NewFetcher().Fetch(context.Background(), ref, digest)
+	if err != nil {
+		t.Fatalf("Fetch() error = %v", err)
+	}
+	if got != nil {
+		t.Errorf("Fetch() = %+v, want nil", got)
+	}
+
+	if _, err := NewFetcher().Fetch(context.Background(), ref, "not-a-digest"); err == nil {
+		t.Error("expected an error for an invalid digest")
+	}
+}
+
+func TestSLSALevel(t *testing.T) {
+	tests := []struct {
+		builderID string
+		signed    bool
+		want      int
+	}{
+		{"https://github.com/docker/buildx", false, 1},
+		{"https://github.com/slsa-framework/slsa-github-generator/.github/workflows/builder.yml", false, 1},
+		{"https://tekton.dev/chains/v2", true, 2},
+		{"https://github.com/slsa-framework/slsa-github-generator/.github/workflows/builder.yml", true, 3},
+	}
+	for _, tt := range tests {
+		if got := slsaLevel(tt.builderID, tt.signed); got != tt.want {
+			t.Errorf("slsaLevel(%q, %v) = %d, want %d", tt.builderID, tt.signed, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,252 @@
+package provenance
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/bvboe/b2s-go/scanner-core/containers"
+	"github.com/bvboe/b2s-go/scanner-core/sbomformat"
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+)
+
+// SBOM predicate types, as written by cosign attest --type spdxjson and
+// --type cyclonedx. The predicate is the SBOM document itself.
+var sbomPredicateTypes = []string{
+	"https://spdx.dev/Document",
+	"https://cyclonedx.org/bom",
+}
+
+// ErrUnverifiedSBOM is returned when an image has SBOM attestations but none
+// is about the image and signed by a trusted key
+var ErrUnverifiedSBOM = errors.New("no SBOM attestation of the image signed by a trusted key")
+
+// VendorSBOMs reads the SBOMs vendors attest for their images. Only cosign
+// attestations (DSSE envelopes under the sha256-<hex>.att tag) signed with one
+// of the trusted public keys are used; keyless (Fulcio certificate) signatures
+// are not verified.
+type VendorSBOMs struct {
+	fetcher      *Fetcher
+	keys         []trustedKey
+	repositories []string
+}
+
+// trustedKey is a public key attestations may be signed with
+type trustedKey struct {
+	key         crypto.PublicKey
+	fingerprint string // sha256:<hex> of the DER encoded key
+}
+
+// NewVendorSBOMs creates a reader of the SBOM attestations of images in
+// repositories (prefixes such as registry.vendor.com/product, or path.Match
+// globs), verified with the PEM encoded public keys in keyFile
+func NewVendorSBOMs(repositories []string, keyFile string) (*VendorSBOMs, error) {
+	if keyFile == "" {
+		return nil, fmt.Errorf("vendor SBOM public keys are required")
+	}
+	for _, pattern := range repositories {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid vendor SBOM image pattern %q", pattern)
+		}
+	}
+	data, err := os.ReadFile(keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read vendor SBOM public keys: %w", err)
+	}
+	keys, err := parsePublicKeys(data)
+	if err != nil {
+		return nil, err
+	}
+	return &VendorSBOMs{fetcher: NewFetcher(), keys: keys, repositories: repositories}, nil
+}
+
+// parsePublicKeys parses the PUBLIC KEY blocks of a PEM file
+func parsePublicKeys(data []byte) ([]trustedKey, error) {
+	var keys []trustedKey
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+		if block.Type != "PUBLIC KEY" {
+			continue
+		}
+		key, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("invalid vendor SBOM public key: %w", err)
+		}
+		sum := sha256.Sum256(block.Bytes)
+		keys = append(keys, trustedKey{key: key, fingerprint: "sha256:" + hex.EncodeToString(sum[:])})
+	}
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("no PEM public keys found")
+	}
+	return keys, nil
+}
+
+// Covers reports whether reference is in one of the vendor repositories
+func (v *VendorSBOMs) Covers(reference string) bool {
+	ref, err := name.ParseReference(reference)
+	if err != nil {
+		return false
+	}
+	// Docker Hub images are docker.io/library/nginx, as in policy selectors
+	registry := ref.Context().RegistryStr()
+	if registry == name.DefaultRegistry {
+		registry = "docker.io"
+	}
+	repo := registry + "/" + ref.Context().RepositoryStr()
+	for _, pattern := range v.repositories {
+		pattern = strings.TrimSuffix(pattern, "/")
+		if repo == pattern || strings.HasPrefix(repo, pattern+"/") {
+			return true
+		}
+		if ok, _ := path.Match(pattern, repo); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// Retrieve returns the verified vendor SBOM of an image as Syft JSON and the
+// fingerprint of the key that signed it. Returns a nil SBOM without error if
+// the image is not in a vendor repository or has no SBOM attestation, and
+// ErrUnverifiedSBOM if no SBOM attestation is signed by a trusted key.
+func (v *VendorSBOMs) Retrieve(ctx context.Context, image containers.ImageID) ([]byte, string, error) {
+	if image.Reference == "" || !v.Covers(image.Reference) {
+		return nil, "", nil
+	}
+	ref, err := name.ParseReference(image.Reference)
+	if err != nil {
+		return nil, "", fmt.Errorf("invalid reference %q: %w", image.Reference, err)
+	}
+	if _, err := v1.NewHash(image.Digest); err != nil {
+		return nil, "", fmt.Errorf("invalid digest %q: %w", image.Digest, err)
+	}
+	opts := append([]remote.Option{remote.WithContext(ctx)}, v.fetcher.opts...)
+
+	attestations, err := cosignAttestations(ref.Context(), image.Digest, opts)
+	if err != nil {
+		return nil, "", err
+	}
+	found := false
+	for _, a := range attestations {
+		if !isSBOMPredicate(a.statement.PredicateType) {
+			continue
+		}
+		found = true
+		signer, ok := verifyEnvelope(a.envelope, v.keys)
+		if !ok {
+			log.Debug("skipping SBOM attestation without a trusted signature", "digest", image.Digest, "predicate", a.statement.PredicateType)
+			continue
+		}
+		// A signed statement copied from another image must not be accepted
+		if !a.statement.hasSubject(image.Digest) {
+			log.Debug("skipping SBOM attestation of another image", "digest", image.Digest, "predicate", a.statement.PredicateType)
+			continue
+		}
+		syftJSON, err := sbomformat.ToSyftJSON(a.statement.Predicate)
+		if err != nil {
+			return nil, "", fmt.Errorf("invalid attested SBOM: %w", err)
+		}
+		log.Debug("found vendor SBOM", "digest", image.Digest, "predicate", a.statement.PredicateType, "signer", signer)
+		return syftJSON, signer, nil
+	}
+	if found {
+		return nil, "", ErrUnverifiedSBOM
+	}
+	return nil, "", nil
+}
+
+// hasSubject reports whether digest (algorithm:hex) is a subject of the statement
+func (s statement) hasSubject(digest string) bool {
+	algorithm, hexDigest, _ := strings.Cut(digest, ":")
+	for _, subject := range s.Subject {
+		if subject.Digest[algorithm] == hexDigest {
+			return true
+		}
+	}
+	return false
+}
+
+// isSBOMPredicate reports whether an in-toto predicate type is an SBOM
+func isSBOMPredicate(predicateType string) bool {
+	for _, t := range sbomPredicateTypes {
+		if strings.HasPrefix(predicateType, t) {
+			return true
+		}
+	}
+	return false
+}
+
+// verifyEnvelope checks the signatures of a DSSE envelope against the trusted
+// keys, returning the fingerprint of the key of the first valid signature
+func verifyEnvelope(env *envelope, keys []trustedKey) (string, bool) {
+	if env == nil {
+		return "", false
+	}
+	payload, err := base64.StdEncoding.DecodeString(env.Payload)
+	if err != nil {
+		return "", false
+	}
+	message := preAuthEncoding(env.PayloadType, payload)
+	for _, s := range env.Signatures {
+		sig, err := base64.StdEncoding.DecodeString(s.Sig)
+		if err != nil {
+			continue
+		}
+		for _, k := range keys {
+			if verifySignature(k.key, message, sig) {
+				return k.fingerprint, true
+			}
+		}
+	}
+	return "", false
+}
+
+// preAuthEncoding returns the DSSE pre-authentication encoding of a payload,
+// the message envelope signatures are computed over
+func preAuthEncoding(payloadType string, payload []byte) []byte {
+	return fmt.Appendf(nil, "DSSEv1 %d %s %d %s", len(payloadType), payloadType, len(payload), payload)
+}
+
+// verifySignature verifies an ECDSA, RSA (PKCS #1 v1.5 or PSS) or Ed25519
+// signature of message, hashed as cosign does for the key type
+func verifySignature(key crypto.PublicKey, message, sig []byte) bool {
+	switch k := key.(type) {
+	case *ecdsa.PublicKey:
+		hash := crypto.SHA256
+		switch k.Curve {
+		case elliptic.P384():
+			hash = crypto.SHA384
+		case elliptic.P521():
+			hash = crypto.SHA512
+		}
+		h := hash.New()
+		h.Write(message)
+		return ecdsa.VerifyASN1(k, h.Sum(nil), sig)
+	case *rsa.PublicKey:
+		digest := sha256.Sum256(message)
+		return rsa.VerifyPKCS1v15(k, crypto.SHA256, digest[:], sig) == nil ||
+			rsa.VerifyPSS(k, crypto.SHA256, digest[:], sig, nil) == nil
+	case ed25519.PublicKey:
+		return ed25519.Verify(k, message, sig)
+	}
+	return false
+}
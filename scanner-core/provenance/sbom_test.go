@@ -0,0 +1,178 @@
+package provenance
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/bvboe/b2s-go/scanner-core/containers"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/static"
+)
+
+// testCycloneDX is a minimal CycloneDX SBOM with a single package
+const testCycloneDX = `{"bomFormat": "CycloneDX", "specVersion": "1.5", "version": 1,
+	"components": [{"type": "library", "name": "openssl", "version": "3.0.0", "purl": "pkg:apk/alpine/openssl@3.0.0"}]}`
+
+// writeKeyFile writes the public key of a new ECDSA key to a PEM file
+func writeKeyFile(t *testing.T) (*ecdsa.PrivateKey, string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyFile := filepath.Join(t.TempDir(), "keys.pem")
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return key, keyFile
+}
+
+// signedEnvelope returns a DSSE envelope of an SBOM statement about digest
+// signed with key
+func signedEnvelope(t *testing.T, key *ecdsa.PrivateKey, digest string) []byte {
+	t.Helper()
+	algorithm, hexDigest, _ := strings.Cut(digest, ":")
+	payload, err := json.Marshal(map[string]interface{}{
+		"_type":         "https://in-toto.io/Statement/v0.1",
+		"subject":       []map[string]interface{}{{"name": "app", "digest": map[string]string{algorithm: hexDigest}}},
+		"predicateType": "https://cyclonedx.org/bom",
+		"predicate":     json.RawMessage(testCycloneDX),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signEnvelope(t, key, payload)
+}
+
+// signEnvelope returns a DSSE envelope of an in-toto statement signed with key
+func signEnvelope(t *testing.T, key *ecdsa.PrivateKey, payload []byte) []byte {
+	t.Helper()
+	const payloadType = "application/vnd.in-toto+json"
+	pae := sha256.Sum256(preAuthEncoding(payloadType, payload))
+	sig, err := key.Sign(rand.Reader, pae[:], crypto.SHA256)
+	if err != nil {
+		t.Fatal(err)
+	}
+	envelope, err := json.Marshal(map[string]interface{}{
+		"payloadType": payloadType,
+		"payload":     base64.StdEncoding.EncodeToString(payload),
+		"signatures":  []map[string]string{{"keyid": "", "sig": base64.StdEncoding.EncodeToString(sig)}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return envelope
+}
+
+// pushAttestation stores envelope as the cosign attestation of digest
+func pushAttestation(t *testing.T, repo, digest string, envelope []byte) {
+	t.Helper()
+	att, err := mutate.AppendLayers(empty.Image, static.NewLayer(envelope, "application/vnd.dsse.envelope.v1+json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := remote.Write(parseRef(t, repo+":"+strings.Replace(digest, ":", "-", 1)+".att"), att); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestVendorSBOMsRetrieve(t *testing.T) {
+	host := newTestRegistry(t)
+	trusted, keyFile := writeKeyFile(t)
+	untrusted, _ := writeKeyFile(t)
+
+	vendor, err := NewVendorSBOMs([]string{host + "/vendor"}, keyFile)
+	if err != nil {
+		t.Fatalf("NewVendorSBOMs() error = %v", err)
+	}
+
+	signedRef := host + "/vendor/app:1.0"
+	signedDigest := imageDigest(t, pushImage(t, signedRef))
+	pushAttestation(t, host+"/vendor/app", signedDigest, signedEnvelope(t, trusted, signedDigest))
+
+	got, signer, err := vendor.Retrieve(context.Background(), containers.ImageID{Reference: signedRef, Digest: signedDigest})
+	if err != nil {
+		t.Fatalf("Retrieve() error = %v", err)
+	}
+	if !strings.HasPrefix(signer, "sha256:") || !strings.Contains(string(got), `"openssl"`) {
+		t.Errorf("Retrieve() = %s, signer %q, want the attested SBOM as Syft JSON", got, signer)
+	}
+
+	forgedRef := host + "/vendor/forged:1.0"
+	forgedDigest := imageDigest(t, pushImage(t, forgedRef))
+	pushAttestation(t, host+"/vendor/forged", forgedDigest, signedEnvelope(t, untrusted, forgedDigest))
+	if _, _, err := vendor.Retrieve(context.Background(), containers.ImageID{Reference: forgedRef, Digest: forgedDigest}); !errors.Is(err, ErrUnverifiedSBOM) {
+		t.Errorf("Retrieve(untrusted signature) error = %v, want ErrUnverifiedSBOM", err)
+	}
+
+	// A trusted attestation copied from another image
+	copiedRef := host + "/vendor/copied:1.0"
+	copiedDigest := imageDigest(t, pushImage(t, copiedRef))
+	pushAttestation(t, host+"/vendor/copied", copiedDigest, signedEnvelope(t, trusted, signedDigest))
+	if _, _, err := vendor.Retrieve(context.Background(), containers.ImageID{Reference: copiedRef, Digest: copiedDigest}); !errors.Is(err, ErrUnverifiedSBOM) {
+		t.Errorf("Retrieve(attestation of another image) error = %v, want ErrUnverifiedSBOM", err)
+	}
+
+	plainRef := host + "/vendor/plain:1.0"
+	plainDigest := imageDigest(t, pushImage(t, plainRef))
+	if got, _, err := vendor.Retrieve(context.Background(), containers.ImageID{Reference: plainRef, Digest: plainDigest}); got != nil || err != nil {
+		t.Errorf("Retrieve(no attestation) = %s, %v, want nil", got, err)
+	}
+
+	// Images outside the vendor repositories are not looked up
+	if got, _, err := vendor.Retrieve(context.Background(), containers.ImageID{Reference: "nginx:1.25", Digest: signedDigest}); got != nil || err != nil {
+		t.Errorf("Retrieve(other repository) = %s, %v, want nil", got, err)
+	}
+}
+
+func TestVendorSBOMsCovers(t *testing.T) {
+	_, keyFile := writeKeyFile(t)
+	vendor, err := NewVendorSBOMs([]string{"registry.vendor.com/product", "ghcr.io/acme/*", "docker.io/library/nginx"}, keyFile)
+	if err != nil {
+		t.Fatalf("NewVendorSBOMs() error = %v", err)
+	}
+	tests := []struct {
+		reference string
+		want      bool
+	}{
+		{"registry.vendor.com/product:1", true},
+		{"registry.vendor.com/product/agent@sha256:" + strings.Repeat("a", 64), true},
+		{"registry.vendor.com/productivity:1", false},
+		{"ghcr.io/acme/tool:2", true},
+		{"ghcr.io/acme/team/tool:2", false},
+		{"nginx:1.25", true},
+		{"index.docker.io/library/nginx:1.25", true},
+		{"not a reference", false},
+	}
+	for _, tt := range tests {
+		if got := vendor.Covers(tt.reference); got != tt.want {
+			t.Errorf("Covers(%q) = %v, want %v", tt.reference, got, tt.want)
+		}
+	}
+
+	if _, err := NewVendorSBOMs([]string{"a"}, filepath.Join(t.TempDir(), "missing.pem")); err == nil {
+		t.Error("NewVendorSBOMs(missing key file) succeeded, want an error")
+	}
+	if _, err := NewVendorSBOMs([]string{"[a"}, keyFile); err == nil {
+		t.Error("NewVendorSBOMs(invalid pattern) succeeded, want an error")
+	}
+}
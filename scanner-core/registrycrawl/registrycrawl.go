@@ -0,0 +1,296 @@
+// Package registrycrawl scans images straight from registry repositories,
+// whether or not they are deployed, e.g. to pre-approve a golden-image catalog.
+//
+// Each crawl enumerates the configured repositories (a repository, or a
+// prefix ending in /* that is expanded through the registry catalog), picks
+// the newest tags matching the tag patterns and resolves them to digests. The
+// images are recorded with target_type "registry" and scanned from their
+// registry like ad-hoc scans. Quotas bound the images tracked and the scans
+// queued per crawl separately from cluster scans, and images the crawl stops
+// finding are removed once their retention ends.
+package registrycrawl
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/bvboe/b2s-go/scanner-core/containers"
+	"github.com/bvboe/b2s-go/scanner-core/database"
+	"github.com/bvboe/b2s-go/scanner-core/logging"
+	"github.com/bvboe/b2s-go/scanner-core/scanning"
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+)
+
+var log = logging.For(logging.ComponentRegistryCrawl)
+
+// prefixWildcard marks a repository entry as a prefix to expand via the catalog
+const prefixWildcard = "/*"
+
+// Store records the images found (implemented by database.DB)
+type Store interface {
+	TrackRegistryImage(image containers.ImageID, expires time.Time) (database.Status, bool, error)
+}
+
+// Queue accepts scan jobs (implemented by scanning.JobQueue)
+type Queue interface {
+	Enqueue(job scanning.ScanJob)
+}
+
+// Registry lists and resolves images in registries
+type Registry interface {
+	// Catalog lists the repositories of a registry host, without the host
+	Catalog(ctx context.Context, registry string) ([]string, error)
+	// Tags lists the tags of a repository (host/path)
+	Tags(ctx context.Context, repository string) ([]string, error)
+	// Digest resolves a reference to the digest it points to
+	Digest(ctx context.Context, reference string) (string, error)
+}
+
+// Config configures what a crawl scans
+type Config struct {
+	Repositories         []string      // repositories, or prefixes ending in /*
+	Tags                 []string      // tag patterns (path.Match); empty matches all tags
+	MaxTagsPerRepository int           // newest matching tags per repository (0 = all)
+	MaxImages            int           // images tracked per crawl (0 = unlimited)
+	MaxScansPerRun       int           // scans queued per crawl (0 = unlimited)
+	Retention            time.Duration // how long an image is kept after a crawl last found it
+}
+
+// Stats summarizes a crawl
+type Stats struct {
+	Repositories int // repositories whose tags were listed
+	Images       int // images tracked
+	NewImages    int // images seen for the first time
+	Queued       int // scans queued
+	Skipped      int // tags or scans left out by the quotas
+	Errors       int // repositories or tags that could not be read
+}
+
+// Crawler crawls the configured repositories
+type Crawler struct {
+	store    Store
+	queue    Queue
+	registry Registry
+	cfg      Config
+}
+
+// NewCrawler creates a Crawler that reads registries with credentials from the
+// default keychain (docker config, e.g. a mounted pull secret) and anonymous
+// access otherwise
+func NewCrawler(store Store, queue Queue, cfg Config) *Crawler {
+	if store == nil {
+		panic("registrycrawl.Crawler requires a non-nil store")
+	}
+	if queue == nil {
+		panic("registrycrawl.Crawler requires a non-nil queue")
+	}
+	return &Crawler{store: store, queue: queue, registry: remoteRegistry{}, cfg: cfg}
+}
+
+// Crawl enumerates the configured repositories, tracks the selected tags and
+// queues their scans. Images without results are queued before rescans of
+// images scanned before, so new tags are scanned first when the scan quota is
+// reached. Registry errors are logged and counted; only cancellation fails
+// the crawl.
+func (c *Crawler) Crawl(ctx context.Context) (Stats, error) {
+	var stats Stats
+	expires := time.Now().Add(c.cfg.Retention)
+	var unscanned, rescans []containers.ImageID
+
+	for _, repository := range c.repositories(ctx, &stats) {
+		tags, err := c.registry.Tags(ctx, repository)
+		if err != nil {
+			if ctx.Err() != nil {
+				return stats, ctx.Err()
+			}
+			log.Warn("failed to list repository tags", "repository", repository, "error", err)
+			stats.Errors++
+			continue
+		}
+		stats.Repositories++
+
+		for _, tag := range selectTags(tags, c.cfg.Tags, c.cfg.MaxTagsPerRepository) {
+			if c.cfg.MaxImages > 0 && stats.Images >= c.cfg.MaxImages {
+				stats.Skipped++
+				continue
+			}
+			reference := repository + ":" + tag
+			digest, err := c.registry.Digest(ctx, reference)
+			if err != nil {
+				if ctx.Err() != nil {
+					return stats, ctx.Err()
+				}
+				log.Warn("failed to resolve tag", "image", reference, "error", err)
+				stats.Errors++
+				continue
+			}
+
+			image := containers.ImageID{Reference: reference, Digest: digest}
+			status, created, err := c.store.TrackRegistryImage(image, expires)
+			if err != nil {
+				return stats, fmt.Errorf("failed to track %s: %w", reference, err)
+			}
+			stats.Images++
+			if created {
+				stats.NewImages++
+			}
+			if status.HasVulnerabilities() {
+				rescans = append(rescans, image)
+			} else {
+				unscanned = append(unscanned, image)
+			}
+		}
+	}
+
+	for i, image := range append(unscanned, rescans...) {
+		if c.cfg.MaxScansPerRun > 0 && stats.Queued >= c.cfg.MaxScansPerRun {
+			stats.Skipped++
+			continue
+		}
+		// Rescans reuse the stored SBOM and only match it against the current vulnerability data
+		c.queue.Enqueue(scanning.ScanJob{Image: image, Registry: true, ForceScan: i >= len(unscanned)})
+		stats.Queued++
+	}
+
+	if stats.Skipped > 0 {
+		log.Warn("registry crawl quota reached", "skipped", stats.Skipped,
+			"max_images", c.cfg.MaxImages, "max_scans_per_run", c.cfg.MaxScansPerRun)
+	}
+	return stats, nil
+}
+
+// repositories expands the configured entries into repositories (host/path),
+// without duplicates
+func (c *Crawler) repositories(ctx context.Context, stats *Stats) []string {
+	var repositories []string
+	for _, entry := range c.cfg.Repositories {
+		prefix, isPrefix := strings.CutSuffix(entry, prefixWildcard)
+		if !isPrefix {
+			repo, err := name.NewRepository(entry)
+			if err != nil {
+				log.Warn("invalid repository", "repository", entry, "error", err)
+				stats.Errors++
+				continue
+			}
+			repositories = append(repositories, repo.Name())
+			continue
+		}
+
+		host, pathPrefix, _ := strings.Cut(prefix, "/")
+		catalog, err := c.registry.Catalog(ctx, host)
+		if err != nil {
+			log.Warn("failed to list registry catalog", "registry", host, "error", err)
+			stats.Errors++
+			continue
+		}
+		for _, repo := range catalog {
+			if pathPrefix == "" || strings.HasPrefix(repo, pathPrefix+"/") {
+				repositories = append(repositories, host+"/"+repo)
+			}
+		}
+	}
+	slices.Sort(repositories)
+	return slices.Compact(repositories)
+}
+
+// selectTags returns the tags matching any of the patterns (all tags when
+// there are none), newest first, limited to max (0 = no limit). Tags are
+// ordered by version: digit runs compare numerically, so v1.10 is newer than v1.9.
+func selectTags(tags, patterns []string, max int) []string {
+	var selected []string
+	for _, tag := range tags {
+		if matchesAny(tag, patterns) {
+			selected = append(selected, tag)
+		}
+	}
+	slices.SortFunc(selected, func(a, b string) int { return compareVersions(b, a) })
+	if max > 0 && len(selected) > max {
+		selected = selected[:max]
+	}
+	return selected
+}
+
+// matchesAny reports whether tag matches one of the patterns, or there are none
+func matchesAny(tag string, patterns []string) bool {
+	if len(patterns) == 0 {
+		return true
+	}
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, tag); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// compareVersions compares tags chunk by chunk, digit runs numerically
+func compareVersions(a, b string) int {
+	for a != "" && b != "" {
+		ca, ra := nextChunk(a)
+		cb, rb := nextChunk(b)
+		if isDigit(ca[0]) && isDigit(cb[0]) {
+			na, nb := strings.TrimLeft(ca, "0"), strings.TrimLeft(cb, "0")
+			if len(na) != len(nb) {
+				return len(na) - len(nb)
+			}
+			if c := strings.Compare(na, nb); c != 0 {
+				return c
+			}
+		} else if c := strings.Compare(ca, cb); c != 0 {
+			return c
+		}
+		a, b = ra, rb
+	}
+	return len(a) - len(b)
+}
+
+// nextChunk splits off the leading run of digits or non-digits of s
+func nextChunk(s string) (chunk, rest string) {
+	digits := isDigit(s[0])
+	i := 1
+	for i < len(s) && isDigit(s[i]) == digits {
+		i++
+	}
+	return s[:i], s[i:]
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+// remoteRegistry reads registries with credentials from the default keychain
+type remoteRegistry struct{}
+
+func (remoteRegistry) Catalog(ctx context.Context, registry string) ([]string, error) {
+	reg, err := name.NewRegistry(registry)
+	if err != nil {
+		return nil, err
+	}
+	return remote.Catalog(ctx, reg, remote.WithAuthFromKeychain(authn.DefaultKeychain))
+}
+
+func (remoteRegistry) Tags(ctx context.Context, repository string) ([]string, error) {
+	repo, err := name.NewRepository(repository)
+	if err != nil {
+		return nil, err
+	}
+	return remote.List(repo, remote.WithContext(ctx), remote.WithAuthFromKeychain(authn.DefaultKeychain))
+}
+
+func (remoteRegistry) Digest(ctx context.Context, reference string) (string, error) {
+	ref, err := name.ParseReference(reference)
+	if err != nil {
+		return "", err
+	}
+	desc, err := remote.Head(ref, remote.WithContext(ctx), remote.WithAuthFromKeychain(authn.DefaultKeychain))
+	if err != nil {
+		return "", err
+	}
+	return desc.Digest.String(), nil
+}
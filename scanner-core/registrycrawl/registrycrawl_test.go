@@ -0,0 +1,209 @@
+package registrycrawl
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/bvboe/b2s-go/scanner-core/containers"
+	"github.com/bvboe/b2s-go/scanner-core/database"
+	"github.com/bvboe/b2s-go/scanner-core/scanning"
+)
+
+type fakeRegistry struct {
+	catalog map[string][]string
+	tags    map[string][]string
+}
+
+func (r *fakeRegistry) Catalog(ctx context.Context, registry string) ([]string, error) {
+	repos, ok := r.catalog[registry]
+	if !ok {
+		return nil, errors.New("UNAUTHORIZED")
+	}
+	return repos, nil
+}
+
+func (r *fakeRegistry) Tags(ctx context.Context, repository string) ([]string, error) {
+	tags, ok := r.tags[repository]
+	if !ok {
+		return nil, errors.New("NAME_UNKNOWN")
+	}
+	return tags, nil
+}
+
+func (r *fakeRegistry) Digest(ctx context.Context, reference string) (string, error) {
+	return "sha256:" + reference, nil
+}
+
+type fakeStore struct {
+	scanned map[string]bool // references with completed scans
+	seen    map[string]bool
+	expires time.Time
+}
+
+func (s *fakeStore) TrackRegistryImage(image containers.ImageID, expires time.Time) (database.Status, bool, error) {
+	created := !s.seen[image.Reference]
+	s.seen[image.Reference] = true
+	s.expires = expires
+	if s.scanned[image.Reference] {
+		return database.StatusCompleted, created, nil
+	}
+	return database.StatusPending, created, nil
+}
+
+type fakeQueue struct{ jobs []scanning.ScanJob }
+
+func (q *fakeQueue) Enqueue(job scanning.ScanJob) { q.jobs = append(q.jobs, job) }
+
+func newTestCrawler(cfg Config, scanned ...string) (*Crawler, *fakeStore, *fakeQueue) {
+	store := &fakeStore{scanned: map[string]bool{}, seen: map[string]bool{}}
+	for _, ref := range scanned {
+		store.scanned[ref] = true
+	}
+	queue := &fakeQueue{}
+	c := NewCrawler(store, queue, cfg)
+	c.registry = &fakeRegistry{
+		catalog: map[string][]string{
+			"registry.example.com": {"golden/base", "golden/python", "team/app"},
+		},
+		tags: map[string][]string{
+			"registry.example.com/golden/base":   {"1.9", "1.10", "latest"},
+			"registry.example.com/golden/python": {"3.12", "3.11-slim"},
+			"registry.example.com/team/app":      {"v1"},
+		},
+	}
+	return c, store, queue
+}
+
+func queuedReferences(q *fakeQueue) []string {
+	var refs []string
+	for _, job := range q.jobs {
+		refs = append(refs, job.Image.Reference)
+	}
+	return refs
+}
+
+func TestCrawl(t *testing.T) {
+	c, store, queue := newTestCrawler(Config{
+		Repositories: []string{"registry.example.com/golden/*", "registry.example.com/golden/base", "registry.example.com/missing"},
+		Tags:         []string{"1.*", "3.*"},
+		Retention:    time.Hour,
+	})
+
+	stats, err := c.Crawl(context.Background())
+	if err != nil {
+		t.Fatalf("Crawl() error = %v", err)
+	}
+	want := []string{
+		"registry.example.com/golden/base:1.10",
+		"registry.example.com/golden/base:1.9",
+		"registry.example.com/golden/python:3.12",
+		"registry.example.com/golden/python:3.11-slim",
+	}
+	if got := queuedReferences(queue); !slices.Equal(got, want) {
+		t.Errorf("queued %v, want %v", got, want)
+	}
+	for _, job := range queue.jobs {
+		if !job.Registry || job.ForceScan || job.Image.Digest != "sha256:"+job.Image.Reference {
+			t.Errorf("unexpected job %+v", job)
+		}
+	}
+	wantStats := Stats{Repositories: 2, Images: 4, NewImages: 4, Queued: 4, Errors: 1}
+	if stats != wantStats {
+		t.Errorf("stats = %+v, want %+v", stats, wantStats)
+	}
+	if until := time.Until(store.expires); until < 59*time.Minute || until > time.Hour {
+		t.Errorf("expires in %v, want about an hour", until)
+	}
+}
+
+func TestCrawlQuotas(t *testing.T) {
+	c, _, queue := newTestCrawler(Config{
+		Repositories:         []string{"registry.example.com/*"},
+		MaxTagsPerRepository: 2,
+		MaxImages:            4,
+		MaxScansPerRun:       2,
+	}, "registry.example.com/golden/base:latest")
+
+	stats, err := c.Crawl(context.Background())
+	if err != nil {
+		t.Fatalf("Crawl() error = %v", err)
+	}
+	// base:latest was scanned before, so the unscanned images are queued first
+	want := []string{
+		"registry.example.com/golden/base:1.10",
+		"registry.example.com/golden/python:3.12",
+	}
+	if got := queuedReferences(queue); !slices.Equal(got, want) {
+		t.Errorf("queued %v, want %v", got, want)
+	}
+	// 5 tags selected, 1 over MaxImages; 4 scans, 2 over MaxScansPerRun
+	wantStats := Stats{Repositories: 3, Images: 4, NewImages: 4, Queued: 2, Skipped: 3}
+	if stats != wantStats {
+		t.Errorf("stats = %+v, want %+v", stats, wantStats)
+	}
+
+	// Once everything is scanned, rescans are forced
+	c.cfg.MaxScansPerRun = 0
+	queue.jobs = nil
+	c.store.(*fakeStore).scanned = map[string]bool{"registry.example.com/golden/base:1.10": true}
+	if _, err := c.Crawl(context.Background()); err != nil {
+		t.Fatalf("Crawl() error = %v", err)
+	}
+	last := queue.jobs[len(queue.jobs)-1]
+	if last.Image.Reference != "registry.example.com/golden/base:1.10" || !last.ForceScan {
+		t.Errorf("expected forced rescan last, got %+v", last)
+	}
+}
+
+func TestCrawlCanceled(t *testing.T) {
+	c, _, _ := newTestCrawler(Config{Repositories: []string{"registry.example.com/missing"}})
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := c.Crawl(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("Crawl() error = %v, want context.Canceled", err)
+	}
+}
+
+func TestSelectTags(t *testing.T) {
+	tests := []struct {
+		name     string
+		tags     []string
+		patterns []string
+		max      int
+		want     []string
+	}{
+		{
+			name: "versions compare numerically",
+			tags: []string{"v1.9.0", "v1.10.0", "v1.2.10", "v1.2.9"},
+			want: []string{"v1.10.0", "v1.9.0", "v1.2.10", "v1.2.9"},
+		},
+		{
+			name:     "patterns filter",
+			tags:     []string{"latest", "3.12", "3.12-slim", "3.9"},
+			patterns: []string{"3.*-slim", "latest"},
+			want:     []string{"latest", "3.12-slim"},
+		},
+		{
+			name: "max keeps the newest",
+			tags: []string{"1", "2", "10", "3"},
+			max:  2,
+			want: []string{"10", "3"},
+		},
+		{
+			name:     "no match",
+			tags:     []string{"latest"},
+			patterns: []string{"v*"},
+			want:     nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := selectTags(tt.tags, tt.patterns, tt.max); !slices.Equal(got, tt.want) {
+				t.Errorf("selectTags() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
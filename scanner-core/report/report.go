@@ -0,0 +1,189 @@
+// Package report renders the current scan state into a single self-contained
+// HTML file (inline styles, no scripts or external assets) that can be shared
+// with people who cannot reach the cluster or the scan server.
+package report
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	"html/template"
+	"strings"
+	"time"
+
+	"github.com/bvboe/b2s-go/scanner-core/database"
+	"github.com/bvboe/b2s-go/scanner-core/labels"
+)
+
+// DefaultMaxBytes is the default report size limit
+const DefaultMaxBytes = 50 << 20
+
+// detailsSlack is reserved on top of the measured page size for text that
+// changes between the sizing pass and the final render (counts in the footer)
+const detailsSlack = 1024
+
+//go:embed report.html
+var templateFS embed.FS
+
+var templates = template.Must(template.New("report.html").Funcs(template.FuncMap{
+	"lower":       strings.ToLower,
+	"shortDigest": shortDigest,
+}).ParseFS(templateFS, "report.html"))
+
+// Options configures report generation
+type Options struct {
+	// Title is shown as the report heading
+	Title string
+	// Source identifies where the report came from (e.g. cluster name)
+	Source string
+	// DeploymentUUID and Labels (static labels) are shown next to the source
+	DeploymentUUID string
+	Labels         labels.Set
+	// MaxBytes limits the report size (default DefaultMaxBytes). Images and
+	// containers are always included; per-image CVE details are added, most
+	// vulnerable images first, until the limit is reached.
+	MaxBytes int
+	// Now overrides the generation time (for tests)
+	Now time.Time
+	// Location is the time zone timestamps are shown in (default UTC)
+	Location *time.Location
+}
+
+// Totals summarizes the report contents
+type Totals struct {
+	Images     int
+	Containers int
+	Critical   int
+	High       int
+	Medium     int
+	Low        int
+	Negligible int
+	Unknown    int
+}
+
+// imageDetails is the per-image CVE section
+type imageDetails struct {
+	Image           database.ReportImage
+	Vulnerabilities []database.ReportVulnerability
+}
+
+// reportData is the template input for the whole page
+type reportData struct {
+	Title          string
+	Source         string
+	DeploymentUUID string
+	Labels         string
+	GeneratedAt    string
+	Totals         Totals
+	Images         []database.ReportImage
+	Containers     []database.ReportContainer
+	Details        []template.HTML
+	DetailIDs      map[int64]bool
+	OmittedDetails int
+	MaxBytes       int
+}
+
+// Generate renders the report for the current database state
+func Generate(db *database.DB, opts Options) ([]byte, error) {
+	if opts.MaxBytes <= 0 {
+		opts.MaxBytes = DefaultMaxBytes
+	}
+	if opts.Title == "" {
+		opts.Title = "Bjørn2Scan Vulnerability Report"
+	}
+	if opts.Now.IsZero() {
+		opts.Now = time.Now()
+	}
+	if opts.Location == nil {
+		opts.Location = time.UTC
+	}
+
+	images, err := db.GetReportImages()
+	if err != nil {
+		return nil, err
+	}
+	containers, err := db.GetReportContainers()
+	if err != nil {
+		return nil, err
+	}
+
+	data := reportData{
+		Title:       opts.Title,
+		Source:         opts.Source,
+		DeploymentUUID: opts.DeploymentUUID,
+		Labels:         opts.Labels.String(),
+		GeneratedAt:    opts.Now.In(opts.Location).Format(time.RFC3339),
+		Images:         images,
+		Containers:     containers,
+		DetailIDs:      make(map[int64]bool),
+		MaxBytes:       opts.MaxBytes,
+	}
+	data.Totals = computeTotals(images, containers)
+
+	// Candidates for CVE details, in report order (most vulnerable first)
+	var candidates []database.ReportImage
+	for _, img := range images {
+		if img.Critical+img.High+img.Medium+img.Low+img.Negligible+img.Unknown > 0 {
+			candidates = append(candidates, img)
+		}
+	}
+
+	// Measure the page without details, then add details while they fit
+	data.OmittedDetails = len(candidates)
+	base, err := render(&data)
+	if err != nil {
+		return nil, err
+	}
+	budget := opts.MaxBytes - len(base) - detailsSlack
+
+	for _, img := range candidates {
+		vulns, err := db.GetReportVulnerabilities(img.ID)
+		if err != nil {
+			return nil, err
+		}
+		var section bytes.Buffer
+		if err := templates.ExecuteTemplate(&section, "details", imageDetails{Image: img, Vulnerabilities: vulns}); err != nil {
+			return nil, fmt.Errorf("failed to render CVE details: %w", err)
+		}
+		if section.Len() > budget {
+			continue
+		}
+		budget -= section.Len()
+		data.Details = append(data.Details, template.HTML(section.String()))
+		data.DetailIDs[img.ID] = true
+		data.OmittedDetails--
+	}
+
+	return render(&data)
+}
+
+// render executes the page template
+func render(data *reportData) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := templates.ExecuteTemplate(&buf, "report.html", data); err != nil {
+		return nil, fmt.Errorf("failed to render report: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// computeTotals sums unique vulnerability counts across images
+func computeTotals(images []database.ReportImage, containers []database.ReportContainer) Totals {
+	totals := Totals{Images: len(images), Containers: len(containers)}
+	for _, img := range images {
+		totals.Critical += img.Critical
+		totals.High += img.High
+		totals.Medium += img.Medium
+		totals.Low += img.Low
+		totals.Negligible += img.Negligible
+		totals.Unknown += img.Unknown
+	}
+	return totals
+}
+
+// shortDigest abbreviates a digest for display (sha256:0123456789ab)
+func shortDigest(digest string) string {
+	if len(digest) > 19 {
+		return digest[:19]
+	}
+	return digest
+}
@@ -0,0 +1,104 @@
+package report
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bvboe/b2s-go/scanner-core/containers"
+	"github.com/bvboe/b2s-go/scanner-core/database"
+	"github.com/bvboe/b2s-go/scanner-core/labels"
+	_ "github.com/bvboe/b2s-go/scanner-core/sqlitedriver"
+)
+
+// seedImage stores scan results for an image running in one container
+func seedImage(t *testing.T, db *database.DB, n int, vulnCount int) {
+	t.Helper()
+	digest := fmt.Sprintf("sha256:%064d", n)
+	var matches []string
+	for i := 0; i < vulnCount; i++ {
+		matches = append(matches, fmt.Sprintf(
+			`{"vulnerability": {"id": "CVE-2024-%04d", "severity": "High"}, "artifact": {"name": "pkg%d", "version": "1.0", "type": "apk"}}`, i, i))
+	}
+	vulns := []byte(`{"matches": [` + strings.Join(matches, ",") + `]}`)
+	if err := db.ImportScanResults(digest, []byte(`{"artifacts":[]}`), vulns, time.Time{}); err != nil {
+		t.Fatalf("Failed to seed scan results: %v", err)
+	}
+	if _, err := db.AddContainer(containers.Container{
+		ID:    containers.ContainerID{Namespace: "default", Pod: fmt.Sprintf("app-%d", n), Name: "app"},
+		Image: containers.ImageID{Reference: fmt.Sprintf("registry.example.com/app%d:1.0", n), Digest: digest},
+	}); err != nil {
+		t.Fatalf("Failed to add container: %v", err)
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	db, err := database.New(filepath.Join(t.TempDir(), "report.db"))
+	if err != nil {
+		t.Fatalf("Failed to create test database: %v", err)
+	}
+	defer func() { _ = database.Close(db) }()
+
+	seedImage(t, db, 1, 2)
+	seedImage(t, db, 2, 200)
+	if _, err := db.SetCVEAnnotation("CVE-2024-0001", "mitigated by WAF rule 123", "secops"); err != nil {
+		t.Fatalf("SetCVEAnnotation() error = %v", err)
+	}
+
+	html, err := Generate(db, Options{Source: "prod<cluster>", Labels: labels.Set{"environment": "prod"}})
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	page := string(html)
+	for _, want := range []string{
+		"registry.example.com/app1:1.0", "registry.example.com/app2:1.0",
+		"app-1", "CVE-2024-0001", "CVE-2024-0199", "prod&lt;cluster&gt;", "environment=prod",
+		`<div class="annotation">mitigated by WAF rule 123</div>`,
+	} {
+		if !strings.Contains(page, want) {
+			t.Errorf("report missing %q", want)
+		}
+	}
+	if strings.Contains(page, "<script") || strings.Contains(page, "src=") {
+		t.Error("report should not reference scripts or external assets")
+	}
+	if strings.Contains(page, "were omitted") {
+		t.Error("report under the default limit should include all CVE details")
+	}
+
+	// Timestamps are shown in the requested time zone
+	stockholm, err := time.LoadLocation("Europe/Stockholm")
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2026, 7, 1, 10, 30, 0, 0, time.UTC)
+	local, err := Generate(db, Options{Now: now, Location: stockholm})
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	if !strings.Contains(string(local), "Generated 2026-07-01T12:30:00") {
+		t.Error("report generation time not shown in the requested time zone")
+	}
+
+	// With a tight limit the large image's details are dropped, the small one's kept
+	full := len(html)
+	limited, err := Generate(db, Options{MaxBytes: full - 4096})
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	page = string(limited)
+	if len(limited) > full-4096 {
+		t.Errorf("report size %d exceeds limit %d", len(limited), full-4096)
+	}
+	if !strings.Contains(page, "Vulnerability details for 1 image(s) were omitted") {
+		t.Error("report should note omitted CVE details")
+	}
+	if strings.Contains(page, "CVE-2024-0199") || !strings.Contains(page, "CVE-2024-0001") {
+		t.Error("report should keep details that fit and drop those that do not")
+	}
+	if !strings.Contains(page, "registry.example.com/app2:1.0") {
+		t.Error("images without details must still be listed")
+	}
+}
@@ -0,0 +1,197 @@
+// Package resultcache implements a scan result cache shared between servers.
+// Results are stored as signed transfer bundles keyed by (image digest, grype
+// DB build), so any server scanning the same digest with the same vulnerability
+// database can reuse the results instead of generating an SBOM and scanning again.
+package resultcache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/bvboe/b2s-go/scanner-core/database"
+	"github.com/bvboe/b2s-go/scanner-core/labels"
+	"github.com/bvboe/b2s-go/scanner-core/logging"
+	"github.com/bvboe/b2s-go/scanner-core/transfer"
+)
+
+var log = logging.For(logging.ComponentResultCache)
+
+// ErrNotFound is returned by a Backend when no object exists for the key
+var ErrNotFound = errors.New("cache entry not found")
+
+// Backend stores opaque cache objects by key
+type Backend interface {
+	Get(ctx context.Context, key string) ([]byte, error)
+	Put(ctx context.Context, key string, data []byte) error
+}
+
+// Config configures the result cache
+type Config struct {
+	// Backend selects the storage backend: "http" or "s3" (empty disables the cache)
+	Backend string
+	// URL is the base URL of the HTTP backend; objects are read with GET and written with PUT
+	URL string
+	// Token is an optional bearer token sent to the HTTP backend
+	Token string
+	// S3Bucket, S3Prefix, S3Region and S3Endpoint configure the S3 backend.
+	// S3Endpoint is only needed for S3-compatible stores (MinIO, Ceph, ...).
+	S3Bucket   string
+	S3Prefix   string
+	S3Region   string
+	S3Endpoint string
+	// SigningKey signs written entries and is required to verify read entries
+	SigningKey string
+	// Source identifies this server in written entries (e.g. cluster name)
+	Source string
+	// DeploymentUUID and Labels (static labels) are added to written entries
+	DeploymentUUID string
+	Labels         labels.Set
+	// Timeout bounds each backend request (default 30s)
+	Timeout time.Duration
+}
+
+// Cache reads and writes scan results in a shared backend
+type Cache struct {
+	backend    Backend
+	signingKey string
+	source     string
+	timeout    time.Duration
+	// deploymentUUID and labels identify this server in written entries
+	deploymentUUID string
+	labels         labels.Set
+}
+
+// New creates a cache from configuration.
+// Returns nil (cache disabled) when no backend is configured.
+func New(ctx context.Context, cfg Config) (*Cache, error) {
+	var backend Backend
+	switch strings.ToLower(cfg.Backend) {
+	case "":
+		return nil, nil
+	case "http":
+		if cfg.URL == "" {
+			return nil, fmt.Errorf("result cache URL is required for the http backend")
+		}
+		backend = NewHTTPBackend(cfg.URL, cfg.Token)
+	case "s3":
+		if cfg.S3Bucket == "" {
+			return nil, fmt.Errorf("result cache S3 bucket is required for the s3 backend")
+		}
+		s3Backend, err := NewS3Backend(ctx, cfg.S3Bucket, cfg.S3Prefix, cfg.S3Region, cfg.S3Endpoint)
+		if err != nil {
+			return nil, err
+		}
+		backend = s3Backend
+	default:
+		return nil, fmt.Errorf("unknown result cache backend %q (expected http or s3)", cfg.Backend)
+	}
+
+	// Entries from a shared store must be verifiable, otherwise anyone with write
+	// access to the store could inject scan results.
+	if cfg.SigningKey == "" {
+		return nil, fmt.Errorf("result cache requires a transfer signing key")
+	}
+
+	log.Info("result cache enabled", "backend", cfg.Backend, "source", cfg.Source)
+	cache := NewWithBackend(backend, cfg.SigningKey, cfg.Source, cfg.Timeout)
+	cache.deploymentUUID = cfg.DeploymentUUID
+	cache.labels = cfg.Labels
+	return cache, nil
+}
+
+// NewWithBackend creates a cache on top of an existing backend
+func NewWithBackend(backend Backend, signingKey, source string, timeout time.Duration) *Cache {
+	if timeout <= 0 {
+		timeout = 30 * time.Second
+	}
+	return &Cache{
+		backend:    backend,
+		signingKey: signingKey,
+		source:     source,
+		timeout:    timeout,
+	}
+}
+
+// Key returns the object key for an image digest scanned with a given grype DB build.
+// Example: sha256/abc123.../1767322800.json
+func Key(digest string, grypeDBBuilt time.Time) string {
+	algorithm, encoded, found := strings.Cut(digest, ":")
+	if !found {
+		algorithm, encoded = "unknown", digest
+	}
+	return fmt.Sprintf("%s/%s/%d.json", algorithm, encoded, grypeDBBuilt.UTC().Unix())
+}
+
+// Lookup returns cached scan results for the digest and grype DB build.
+// Returns nil when there is no entry or the entry fails verification.
+func (c *Cache) Lookup(ctx context.Context, digest string, grypeDBBuilt time.Time) *transfer.Bundle {
+	if grypeDBBuilt.IsZero() {
+		return nil
+	}
+	key := Key(digest, grypeDBBuilt)
+
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	data, err := c.backend.Get(ctx, key)
+	if errors.Is(err, ErrNotFound) {
+		log.Debug("result cache miss", "digest", digest, "key", key)
+		return nil
+	}
+	if err != nil {
+		log.Warn("result cache lookup failed", "digest", digest, "key", key, "error", err)
+		return nil
+	}
+
+	bundle, err := transfer.Open(data, c.signingKey)
+	if err != nil {
+		log.Warn("discarding invalid result cache entry", "digest", digest, "key", key, "error", err)
+		return nil
+	}
+	// The key is not covered by the signature, so check the entry is for what we asked for
+	if bundle.Image.Digest != digest || !bundle.GrypeDBBuilt().Equal(grypeDBBuilt.UTC().Truncate(time.Second)) {
+		log.Warn("discarding mismatched result cache entry", "digest", digest, "key", key,
+			"entry_digest", bundle.Image.Digest, "entry_grype_db_built", bundle.Image.GrypeDBBuilt)
+		return nil
+	}
+
+	log.Info("result cache hit", "digest", digest, "source", bundle.Source)
+	return bundle
+}
+
+// Store writes scan results for the digest and grype DB build to the cache
+func (c *Cache) Store(ctx context.Context, digest string, grypeDBBuilt time.Time, sbomJSON, vulnJSON []byte) error {
+	if grypeDBBuilt.IsZero() {
+		return fmt.Errorf("grype DB build time is unknown")
+	}
+
+	record := database.ImageTransferRecord{
+		Digest:       digest,
+		Status:       database.StatusCompleted.String(),
+		GrypeDBBuilt: grypeDBBuilt.UTC().Format(time.RFC3339),
+	}
+	bundle, err := transfer.NewBundle(record, c.source, sbomJSON, vulnJSON)
+	if err != nil {
+		return err
+	}
+	bundle.DeploymentUUID = c.deploymentUUID
+	bundle.Labels = c.labels
+	sealed, err := transfer.Seal(bundle, c.signingKey)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	key := Key(digest, grypeDBBuilt)
+	if err := c.backend.Put(ctx, key, sealed); err != nil {
+		return fmt.Errorf("failed to write result cache entry %s: %w", key, err)
+	}
+
+	log.Debug("stored scan results in result cache", "digest", digest, "key", key, "size_kb", len(sealed)/1024)
+	return nil
+}
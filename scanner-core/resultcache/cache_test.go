@@ -0,0 +1,145 @@
+package resultcache
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bvboe/b2s-go/scanner-core/labels"
+)
+
+// newTestStore starts an in-memory HTTP object store and returns its URL and contents
+func newTestStore(t *testing.T) (string, map[string][]byte) {
+	t.Helper()
+	var mu sync.Mutex
+	objects := make(map[string][]byte)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		switch r.Method {
+		case http.MethodGet:
+			data, ok := objects[r.URL.Path]
+			if !ok {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			_, _ = w.Write(data)
+		case http.MethodPut:
+			data, _ := io.ReadAll(r.Body)
+			objects[r.URL.Path] = data
+			w.WriteHeader(http.StatusCreated)
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server.URL, objects
+}
+
+func TestKey(t *testing.T) {
+	built := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	if got, want := Key("sha256:abc", built), "sha256/abc/1767323045.json"; got != want {
+		t.Errorf("Key() = %q, want %q", got, want)
+	}
+}
+
+func TestStoreAndLookup(t *testing.T) {
+	url, objects := newTestStore(t)
+	ctx := context.Background()
+	cache, err := New(ctx, Config{
+		Backend:        "http",
+		URL:            url + "/cache/",
+		Token:          "token",
+		SigningKey:     "secret",
+		Source:         "staging",
+		DeploymentUUID: "uuid-1",
+		Labels:         labels.Set{"environment": "staging"},
+		Timeout:        time.Second,
+	})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	digest := "sha256:0123456789abcdef"
+	built := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	sbom := []byte(`{"artifacts":[{"name":"openssl"}]}`)
+	vulns := []byte(`{"matches":[]}`)
+
+	if bundle := cache.Lookup(ctx, digest, built); bundle != nil {
+		t.Fatal("Lookup() on empty cache should miss")
+	}
+	if err := cache.Store(ctx, digest, built, sbom, vulns); err != nil {
+		t.Fatalf("Store() error = %v", err)
+	}
+
+	bundle := cache.Lookup(ctx, digest, built)
+	if bundle == nil {
+		t.Fatal("Lookup() after Store() should hit")
+	}
+	if !bytes.Equal(bundle.SBOM, sbom) || bundle.Source != "staging" {
+		t.Errorf("unexpected bundle: source=%q sbom=%s", bundle.Source, bundle.SBOM)
+	}
+	if bundle.DeploymentUUID != "uuid-1" || bundle.Labels["environment"] != "staging" {
+		t.Errorf("unexpected bundle origin: uuid=%q labels=%v", bundle.DeploymentUUID, bundle.Labels)
+	}
+
+	// A newer grype DB build is a different key
+	if cache.Lookup(ctx, digest, built.Add(time.Hour)) != nil {
+		t.Error("Lookup() with a different grype DB build should miss")
+	}
+
+	// Entries signed with another key are rejected
+	other := NewWithBackend(NewHTTPBackend(url+"/cache", "token"), "other", "prod", time.Second)
+	if other.Lookup(ctx, digest, built) != nil {
+		t.Error("Lookup() with a different signing key should reject the entry")
+	}
+
+	// Tampered entries fail integrity verification
+	for path, data := range objects {
+		objects[path] = bytes.Replace(data, []byte("openssl"), []byte("libressl"), 1)
+	}
+	if cache.Lookup(ctx, digest, built) != nil {
+		t.Error("Lookup() should reject a tampered entry")
+	}
+}
+
+func TestLookupRejectsEntryForOtherDigest(t *testing.T) {
+	url, objects := newTestStore(t)
+	cache := NewWithBackend(NewHTTPBackend(url, "token"), "secret", "", time.Second)
+	ctx := context.Background()
+	built := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	if err := cache.Store(ctx, "sha256:aaaa", built, []byte(`{}`), []byte(`{}`)); err != nil {
+		t.Fatalf("Store() error = %v", err)
+	}
+	// Copy a validly signed entry to another digest's key
+	objects["/"+Key("sha256:bbbb", built)] = objects["/"+Key("sha256:aaaa", built)]
+
+	if cache.Lookup(ctx, "sha256:bbbb", built) != nil {
+		t.Error("Lookup() should reject an entry stored under another digest's key")
+	}
+}
+
+func TestNewRequiresSigningKey(t *testing.T) {
+	if cache, err := New(context.Background(), Config{}); cache != nil || err != nil {
+		t.Errorf("New() without backend = %v, %v; want disabled cache", cache, err)
+	}
+	_, err := New(context.Background(), Config{Backend: "http", URL: "http://cache.example"})
+	if err == nil || !strings.Contains(err.Error(), "signing key") {
+		t.Errorf("New() without signing key error = %v", err)
+	}
+	if _, err := New(context.Background(), Config{Backend: "ftp"}); err == nil {
+		t.Error("New() with unknown backend should fail")
+	}
+}
@@ -0,0 +1,94 @@
+package resultcache
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// maxEntrySize limits the size of a cache entry read from a backend
+const maxEntrySize = 256 << 20
+
+// HTTPBackend stores cache entries on a plain HTTP object store.
+// Entries are read with GET {baseURL}/{key} and written with PUT {baseURL}/{key},
+// which works with WebDAV servers, artifact repositories and pre-authorized
+// bucket gateways.
+type HTTPBackend struct {
+	baseURL string
+	token   string
+	client  *http.Client
+}
+
+// NewHTTPBackend creates an HTTP backend for the given base URL
+func NewHTTPBackend(baseURL, token string) *HTTPBackend {
+	return &HTTPBackend{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		token:   token,
+		client:  &http.Client{},
+	}
+}
+
+// Get retrieves the entry for the key, returning ErrNotFound if it does not exist
+func (b *HTTPBackend) Get(ctx context.Context, key string) ([]byte, error) {
+	req, err := b.newRequest(ctx, http.MethodGet, key, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("request failed: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, ErrNotFound
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxEntrySize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+	if len(data) > maxEntrySize {
+		return nil, fmt.Errorf("entry exceeds %d bytes", maxEntrySize)
+	}
+	return data, nil
+}
+
+// Put writes the entry for the key
+func (b *HTTPBackend) Put(ctx context.Context, key string, data []byte) error {
+	req, err := b.newRequest(ctx, http.MethodPut, key, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("request failed: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// newRequest builds a request for the key with authentication applied
+func (b *HTTPBackend) newRequest(ctx context.Context, method, key string, body io.Reader) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, method, b.baseURL+"/"+key, body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	if b.token != "" {
+		req.Header.Set("Authorization", "Bearer "+b.token)
+	}
+	return req, nil
+}
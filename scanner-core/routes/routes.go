@@ -0,0 +1,187 @@
+// Package routes is the route registry shared by the scanner-core based
+// servers. Handler groups declare their routes (path pattern, methods,
+// required role, cacheability) instead of wiring paths on a mux ad hoc, so
+// middleware is applied the same way to every route and the route table can
+// be listed, e.g. to document only the endpoints a server actually serves.
+package routes
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// Role is the access level a route requires
+type Role int
+
+const (
+	RoleViewer Role = iota // read access to scan results (the default)
+	RolePublic             // no credentials: probes, badges, metrics scrapes, web UI assets
+	RoleAdmin              // state changes and debug endpoints
+)
+
+// String returns the role name
+func (r Role) String() string {
+	switch r {
+	case RolePublic:
+		return "public"
+	case RoleAdmin:
+		return "admin"
+	}
+	return "viewer"
+}
+
+// MarshalText encodes the role by name
+func (r Role) MarshalText() ([]byte, error) {
+	return []byte(r.String()), nil
+}
+
+// Route is one entry of the route table
+type Route struct {
+	// Pattern is the http.ServeMux pattern, without a method, e.g.
+	// /api/images or /api/images/ for the subtree
+	Pattern string
+	// Methods the route serves; others get 405 Method Not Allowed. GET
+	// routes also serve HEAD. Empty leaves method checks to the handler.
+	Methods []string
+	Handler http.Handler
+	Role    Role
+	// CacheControl is set as the Cache-Control header before the handler
+	// runs (which may still override it); empty leaves caching to the handler
+	CacheControl string
+}
+
+// GET and POST are the method sets of most routes
+var (
+	GET  = []string{http.MethodGet}
+	POST = []string{http.MethodPost}
+)
+
+// NoStore is the CacheControl of routes that must always reflect the latest
+// state, e.g. polled status endpoints and embedded badges
+const NoStore = "no-cache, no-store, must-revalidate"
+
+// Middleware wraps the handler of a route. It is given the route so it can
+// act on its metadata, e.g. check the required role.
+type Middleware func(route Route, next http.Handler) http.Handler
+
+// Registry registers routes on a ServeMux and keeps the route table
+type Registry struct {
+	mux *http.ServeMux
+
+	mu         sync.RWMutex
+	middleware []Middleware
+	routes     []Route
+}
+
+// NewRegistry returns an empty registry. Method checks and cache headers are
+// always applied; middleware is applied outside of them, first one outermost.
+func NewRegistry(middleware ...Middleware) *Registry {
+	return &Registry{mux: http.NewServeMux(), middleware: middleware}
+}
+
+// Use adds middleware. It must be called before routes are registered, since
+// each route is wrapped when it is registered.
+func (reg *Registry) Use(middleware ...Middleware) {
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+	if len(reg.routes) > 0 {
+		panic("routes: middleware added after routes were registered")
+	}
+	reg.middleware = append(reg.middleware, middleware...)
+}
+
+// Handle registers routes. Like http.ServeMux, it panics if a pattern is
+// invalid or already registered.
+func (reg *Registry) Handle(routes ...Route) {
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+	for _, route := range routes {
+		if route.Handler == nil {
+			panic(fmt.Sprintf("routes: nil handler for %s", route.Pattern))
+		}
+		handler := cacheControl(route, allowMethods(route, route.Handler))
+		for i := len(reg.middleware) - 1; i >= 0; i-- {
+			handler = reg.middleware[i](route, handler)
+		}
+		reg.mux.Handle(route.Pattern, handler)
+		reg.routes = append(reg.routes, route)
+	}
+}
+
+// HandleFunc registers a handler function for pattern with the default
+// settings (any method, viewer role)
+func (reg *Registry) HandleFunc(pattern string, handler http.HandlerFunc) {
+	reg.Handle(Route{Pattern: pattern, Handler: handler})
+}
+
+// ServeHTTP dispatches the request to the route matching it
+func (reg *Registry) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	reg.mux.ServeHTTP(w, r)
+}
+
+// Match returns the route a request for path is dispatched to. The catch-all
+// "/" route only matches "/" itself, so paths served by the web UI fallback
+// are not reported as registered.
+func (reg *Registry) Match(path string) (Route, bool) {
+	req, err := http.NewRequest(http.MethodGet, path, nil)
+	if err != nil {
+		return Route{}, false
+	}
+	_, pattern := reg.mux.Handler(req)
+	if pattern == "" || (pattern == "/" && path != "/") {
+		return Route{}, false
+	}
+	reg.mu.RLock()
+	defer reg.mu.RUnlock()
+	for _, route := range reg.routes {
+		if route.Pattern == pattern {
+			return route, true
+		}
+	}
+	return Route{}, false
+}
+
+// Routes returns the registered routes sorted by pattern
+func (reg *Registry) Routes() []Route {
+	reg.mu.RLock()
+	defer reg.mu.RUnlock()
+	routes := slices.Clone(reg.routes)
+	slices.SortFunc(routes, func(a, b Route) int {
+		return strings.Compare(a.Pattern, b.Pattern)
+	})
+	return routes
+}
+
+// allowMethods rejects methods the route does not serve
+func allowMethods(route Route, next http.Handler) http.Handler {
+	if len(route.Methods) == 0 {
+		return next
+	}
+	allowed := slices.Clone(route.Methods)
+	if slices.Contains(allowed, http.MethodGet) && !slices.Contains(allowed, http.MethodHead) {
+		allowed = append(allowed, http.MethodHead)
+	}
+	allow := strings.Join(allowed, ", ")
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !slices.Contains(allowed, r.Method) {
+			w.Header().Set("Allow", allow)
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// cacheControl sets the Cache-Control header of the route
+func cacheControl(route Route, next http.Handler) http.Handler {
+	if route.CacheControl == "" {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", route.CacheControl)
+		next.ServeHTTP(w, r)
+	})
+}
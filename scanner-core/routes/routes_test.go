@@ -0,0 +1,136 @@
+package routes
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func ok(w http.ResponseWriter, r *http.Request) {
+	_, _ = w.Write([]byte("ok"))
+}
+
+func serve(reg *Registry, method, path string) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	reg.ServeHTTP(rec, httptest.NewRequest(method, path, nil))
+	return rec
+}
+
+func TestRegistryMethods(t *testing.T) {
+	reg := NewRegistry()
+	reg.Handle(
+		Route{Pattern: "/api/items", Methods: GET, Handler: http.HandlerFunc(ok)},
+		Route{Pattern: "/api/import", Methods: POST, Handler: http.HandlerFunc(ok), Role: RoleAdmin},
+	)
+	reg.HandleFunc("/api/any", ok)
+
+	tests := []struct {
+		method, path string
+		status       int
+		allow        string
+	}{
+		{http.MethodGet, "/api/items", http.StatusOK, ""},
+		{http.MethodHead, "/api/items", http.StatusOK, ""},
+		{http.MethodPost, "/api/items", http.StatusMethodNotAllowed, "GET, HEAD"},
+		{http.MethodPost, "/api/import", http.StatusOK, ""},
+		{http.MethodGet, "/api/import", http.StatusMethodNotAllowed, "POST"},
+		{http.MethodDelete, "/api/any", http.StatusOK, ""},
+		{http.MethodGet, "/api/missing", http.StatusNotFound, ""},
+	}
+	for _, tt := range tests {
+		rec := serve(reg, tt.method, tt.path)
+		if rec.Code != tt.status || rec.Header().Get("Allow") != tt.allow {
+			t.Errorf("%s %s = %d (Allow %q), want %d (Allow %q)", tt.method, tt.path, rec.Code, rec.Header().Get("Allow"), tt.status, tt.allow)
+		}
+	}
+}
+
+func TestRegistryCacheControl(t *testing.T) {
+	reg := NewRegistry()
+	reg.Handle(
+		Route{Pattern: "/status", Handler: http.HandlerFunc(ok), CacheControl: NoStore},
+		Route{Pattern: "/asset", Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Cache-Control", "max-age=60")
+		}), CacheControl: NoStore},
+		Route{Pattern: "/plain", Handler: http.HandlerFunc(ok)},
+	)
+	for path, want := range map[string]string{"/status": NoStore, "/asset": "max-age=60", "/plain": ""} {
+		if got := serve(reg, http.MethodGet, path).Header().Get("Cache-Control"); got != want {
+			t.Errorf("%s Cache-Control = %q, want %q", path, got, want)
+		}
+	}
+}
+
+func TestRegistryMiddleware(t *testing.T) {
+	var calls []string
+	trace := func(name string) Middleware {
+		return func(route Route, next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				calls = append(calls, name+" "+route.Pattern+" "+route.Role.String())
+				next.ServeHTTP(w, r)
+			})
+		}
+	}
+	// Middleware sees the route and runs before the method check
+	reg := NewRegistry(trace("outer"))
+	reg.Use(trace("inner"))
+	reg.Handle(Route{Pattern: "/api/debug/sql", Methods: POST, Handler: http.HandlerFunc(ok), Role: RoleAdmin})
+
+	if rec := serve(reg, http.MethodGet, "/api/debug/sql"); rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("status = %d, want 405", rec.Code)
+	}
+	if want := []string{"outer /api/debug/sql admin", "inner /api/debug/sql admin"}; !reflect.DeepEqual(calls, want) {
+		t.Errorf("middleware calls = %v, want %v", calls, want)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("Use() after Handle() did not panic")
+		}
+	}()
+	reg.Use(trace("late"))
+}
+
+func TestRegistryMatchAndRoutes(t *testing.T) {
+	reg := NewRegistry()
+	reg.Handle(
+		Route{Pattern: "/api/images/", Methods: GET, Handler: http.HandlerFunc(ok)},
+		Route{Pattern: "/", Handler: http.HandlerFunc(ok), Role: RolePublic},
+		Route{Pattern: "/api/images", Methods: GET, Handler: http.HandlerFunc(ok)},
+	)
+
+	if route, ok := reg.Match("/api/images/sha256:x/packages"); !ok || route.Pattern != "/api/images/" {
+		t.Errorf("Match(subtree) = %+v, %v", route, ok)
+	}
+	if route, ok := reg.Match("/"); !ok || route.Role != RolePublic {
+		t.Errorf("Match(/) = %+v, %v", route, ok)
+	}
+	// Paths only served by the catch-all are not registered
+	if _, ok := reg.Match("/api/unknown"); ok {
+		t.Error("Match(/api/unknown) matched the catch-all route")
+	}
+
+	var patterns []string
+	for _, route := range reg.Routes() {
+		patterns = append(patterns, route.Pattern)
+	}
+	if want := []string{"/", "/api/images", "/api/images/"}; !reflect.DeepEqual(patterns, want) {
+		t.Errorf("Routes() = %v, want %v", patterns, want)
+	}
+}
+
+func TestRoleText(t *testing.T) {
+	var names []string
+	for _, role := range []Role{RoleViewer, RolePublic, RoleAdmin} {
+		text, err := role.MarshalText()
+		if err != nil {
+			t.Fatal(err)
+		}
+		names = append(names, string(text))
+	}
+	if got := strings.Join(names, ","); got != "viewer,public,admin" {
+		t.Errorf("role names = %s", got)
+	}
+}
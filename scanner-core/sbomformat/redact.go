@@ -0,0 +1,93 @@
+package sbomformat
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Path redaction modes of SBOMs leaving the server (downloads, exports)
+const (
+	RedactNone  = ""      // paths are kept
+	RedactStrip = "strip" // paths are emptied
+	RedactHash  = "hash"  // paths are replaced by a hash, so equal paths stay recognizable
+)
+
+// pathFields are the keys whose string values are file paths in Syft SBOMs
+// and Grype reports: package and file locations, the paths of the files a
+// package owns, archive paths of Java packages and Python install roots.
+// Package names, versions, PURLs and CPEs are never touched.
+var pathFields = map[string]bool{
+	"path":                 true,
+	"accessPath":           true,
+	"realPath":             true,
+	"virtualPath":          true,
+	"sitePackagesRootPath": true,
+}
+
+// NormalizeRedaction validates a path redaction mode
+func NormalizeRedaction(mode string) (string, error) {
+	mode = strings.ToLower(strings.TrimSpace(mode))
+	switch mode {
+	case RedactNone, RedactStrip, RedactHash:
+		return mode, nil
+	}
+	return "", fmt.Errorf("unsupported path redaction %q (supported: %s, %s)", mode, RedactStrip, RedactHash)
+}
+
+// RedactPaths returns a Syft SBOM or Grype report with its file paths
+// redacted according to mode. Data is returned unchanged without a mode.
+func RedactPaths(data []byte, mode string) ([]byte, error) {
+	if mode == RedactNone {
+		return data, nil
+	}
+	if mode != RedactStrip && mode != RedactHash {
+		return nil, fmt.Errorf("unsupported path redaction %q", mode)
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var doc interface{}
+	if err := decoder.Decode(&doc); err != nil {
+		return nil, fmt.Errorf("failed to decode JSON: %w", err)
+	}
+	redact(doc, mode)
+
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(doc); err != nil {
+		return nil, fmt.Errorf("failed to encode JSON: %w", err)
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
+
+// redact replaces the path fields of a decoded JSON value in place
+func redact(v interface{}, mode string) {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for key, value := range v {
+			if s, ok := value.(string); ok && pathFields[key] && s != "" {
+				v[key] = redactedPath(s, mode)
+				continue
+			}
+			redact(value, mode)
+		}
+	case []interface{}:
+		for _, value := range v {
+			redact(value, mode)
+		}
+	}
+}
+
+// redactedPath returns the replacement of a path
+func redactedPath(path, mode string) string {
+	if mode == RedactStrip {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(path))
+	return "redacted-" + hex.EncodeToString(sum[:8])
+}
@@ -0,0 +1,108 @@
+package sbomformat
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+// testLocatedSyftJSON is a Syft SBOM whose package was found at a revealing path
+var testLocatedSyftJSON = []byte(`{
+  "artifacts": [{
+    "id": "a1b2c3",
+    "name": "internal-lib",
+    "version": "1.2.3",
+    "type": "java-archive",
+    "foundBy": "java-archive-cataloger",
+    "locations": [{"path": "/opt/project-falcon/lib/internal-lib.jar", "layerID": "sha256:layer", "accessPath": "/opt/project-falcon/lib/internal-lib.jar"}],
+    "licenses": [],
+    "language": "java",
+    "cpes": [],
+    "purl": "pkg:maven/com.example/internal-lib@1.2.3",
+    "metadata": {"virtualPath": "/opt/project-falcon/lib/internal-lib.jar", "size": 1048576}
+  }],
+  "artifactRelationships": [],
+  "files": [{"id": "f1", "location": {"path": "/opt/project-falcon/lib/internal-lib.jar", "layerID": "sha256:layer"}}],
+  "source": {"id": "sha256:abc", "name": "app", "version": "1.0", "type": "image", "metadata": {"userInput": "app:1.0", "imageID": "sha256:abc", "manifestDigest": "sha256:abc", "mediaType": "", "tags": [], "imageSize": 0, "layers": [], "manifest": "", "config": "", "repoDigests": []}},
+  "distro": {"name": "alpine", "id": "alpine", "versionID": "3.19"},
+  "descriptor": {"name": "syft", "version": "1.45.1"},
+  "schema": {"version": "16.0.0", "url": "https://raw.githubusercontent.com/anchore/syft/main/schema/json/schema-16.0.0.json"}
+}`)
+
+func TestNormalizeRedaction(t *testing.T) {
+	for mode, want := range map[string]string{"": RedactNone, "strip": RedactStrip, " Hash ": RedactHash} {
+		if got, err := NormalizeRedaction(mode); err != nil || got != want {
+			t.Errorf("NormalizeRedaction(%q) = %q, %v; want %q", mode, got, err, want)
+		}
+	}
+	if _, err := NormalizeRedaction("blur"); err == nil {
+		t.Error("NormalizeRedaction(blur) succeeded, want an error")
+	}
+}
+
+func TestRedactPaths(t *testing.T) {
+	t.Run("no redaction returns the data unchanged", func(t *testing.T) {
+		got, err := RedactPaths(testLocatedSyftJSON, RedactNone)
+		if err != nil || !bytes.Equal(got, testLocatedSyftJSON) {
+			t.Errorf("RedactPaths(none) = %s, %v; want the input", got, err)
+		}
+	})
+
+	for _, mode := range []string{RedactStrip, RedactHash} {
+		t.Run(mode, func(t *testing.T) {
+			got, err := RedactPaths(testLocatedSyftJSON, mode)
+			if err != nil {
+				t.Fatalf("RedactPaths() error = %v", err)
+			}
+			if strings.Contains(string(got), "project-falcon") {
+				t.Errorf("path left in redacted SBOM: %s", got)
+			}
+			for _, kept := range []string{`"pkg:maven/com.example/internal-lib@1.2.3"`, `"name":"internal-lib"`, `"version":"1.2.3"`, `"size":1048576`, `"layerID":"sha256:layer"`} {
+				if !strings.Contains(string(got), kept) {
+					t.Errorf("redacted SBOM lost %s: %s", kept, got)
+				}
+			}
+
+			// Other formats are converted from the redacted SBOM
+			cdx, err := Convert(got, CycloneDXJSON)
+			if err != nil {
+				t.Fatalf("Convert(redacted) error = %v", err)
+			}
+			if strings.Contains(string(cdx), "project-falcon") || !strings.Contains(string(cdx), "internal-lib") {
+				t.Errorf("CycloneDX of redacted SBOM = %s", cdx)
+			}
+		})
+	}
+
+	t.Run("hashes are stable", func(t *testing.T) {
+		got, err := RedactPaths(testLocatedSyftJSON, RedactHash)
+		if err != nil {
+			t.Fatalf("RedactPaths() error = %v", err)
+		}
+		var doc struct {
+			Artifacts []struct {
+				Locations []struct {
+					Path       string `json:"path"`
+					AccessPath string `json:"accessPath"`
+				} `json:"locations"`
+			} `json:"artifacts"`
+		}
+		if err := json.Unmarshal(got, &doc); err != nil {
+			t.Fatal(err)
+		}
+		location := doc.Artifacts[0].Locations[0]
+		if !strings.HasPrefix(location.Path, "redacted-") || location.Path != location.AccessPath {
+			t.Errorf("hashed location = %+v, want equal redacted paths", location)
+		}
+	})
+
+	t.Run("invalid input", func(t *testing.T) {
+		if _, err := RedactPaths([]byte("not json"), RedactStrip); err == nil {
+			t.Error("RedactPaths(invalid JSON) succeeded, want an error")
+		}
+		if _, err := RedactPaths(testLocatedSyftJSON, "blur"); err == nil {
+			t.Error("RedactPaths(unknown mode) succeeded, want an error")
+		}
+	})
+}
@@ -0,0 +1,121 @@
+// Package sbomformat converts the Syft JSON SBOMs stored by scanner-core into
+// the standard formats compliance tooling ingests (CycloneDX and SPDX), so
+// downloads need no external conversion step, and SBOMs in those formats
+// (e.g. vendor attestations) into Syft JSON for storage.
+package sbomformat
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/anchore/syft/syft/format"
+	"github.com/anchore/syft/syft/format/cyclonedxjson"
+	"github.com/anchore/syft/syft/format/spdxjson"
+	"github.com/anchore/syft/syft/format/syftjson"
+	"github.com/anchore/syft/syft/sbom"
+)
+
+// Supported output formats, as accepted by the ?format= download parameter
+const (
+	SyftJSON      = "syft-json"      // stored SBOM, returned as is
+	CycloneDXJSON = "cyclonedx-json" // CycloneDX JSON (latest version supported by Syft)
+	SPDXJSON      = "spdx-json"      // SPDX JSON (latest version supported by Syft)
+)
+
+// Formats lists the supported output formats
+func Formats() []string {
+	return []string{SyftJSON, CycloneDXJSON, SPDXJSON}
+}
+
+// Normalize validates a requested format, returning SyftJSON for an empty one
+func Normalize(format string) (string, error) {
+	format = strings.ToLower(strings.TrimSpace(format))
+	switch format {
+	case "":
+		return SyftJSON, nil
+	case SyftJSON, CycloneDXJSON, SPDXJSON:
+		return format, nil
+	}
+	return "", fmt.Errorf("unsupported SBOM format %q (supported: %s)", format, strings.Join(Formats(), ", "))
+}
+
+// ContentType returns the media type of a format
+func ContentType(format string) string {
+	switch format {
+	case CycloneDXJSON:
+		return "application/vnd.cyclonedx+json"
+	case SPDXJSON:
+		return "application/spdx+json"
+	}
+	return "application/json"
+}
+
+// FileExtension returns the conventional file name suffix of a format
+func FileExtension(format string) string {
+	switch format {
+	case CycloneDXJSON:
+		return ".cdx.json"
+	case SPDXJSON:
+		return ".spdx.json"
+	}
+	return ".json"
+}
+
+// Convert converts a Syft JSON SBOM to format. Syft JSON is returned unchanged.
+func Convert(syftJSON []byte, format string) ([]byte, error) {
+	format, err := Normalize(format)
+	if err != nil {
+		return nil, err
+	}
+	if format == SyftJSON {
+		return syftJSON, nil
+	}
+
+	encoder, err := newEncoder(format)
+	if err != nil {
+		return nil, err
+	}
+
+	decoded, _, _, err := syftjson.NewFormatDecoder().Decode(bytes.NewReader(syftJSON))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode Syft SBOM: %w", err)
+	}
+
+	var buf bytes.Buffer
+	if err := encoder.Encode(&buf, *decoded); err != nil {
+		return nil, fmt.Errorf("failed to encode %s SBOM: %w", format, err)
+	}
+	return buf.Bytes(), nil
+}
+
+// ToSyftJSON converts an SBOM in any format Syft reads (Syft JSON, CycloneDX,
+// SPDX) to Syft JSON. Syft JSON is returned unchanged.
+func ToSyftJSON(data []byte) ([]byte, error) {
+	decoded, formatID, _, err := format.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode SBOM: %w", err)
+	}
+	if decoded == nil {
+		return nil, fmt.Errorf("unrecognized SBOM format")
+	}
+	if formatID == syftjson.ID {
+		return data, nil
+	}
+
+	var buf bytes.Buffer
+	if err := syftjson.NewFormatEncoder().Encode(&buf, *decoded); err != nil {
+		return nil, fmt.Errorf("failed to encode Syft SBOM: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+func newEncoder(format string) (sbom.FormatEncoder, error) {
+	switch format {
+	case CycloneDXJSON:
+		return cyclonedxjson.NewFormatEncoderWithConfig(cyclonedxjson.DefaultEncoderConfig())
+	case SPDXJSON:
+		return spdxjson.NewFormatEncoderWithConfig(spdxjson.DefaultEncoderConfig())
+	}
+	return nil, fmt.Errorf("no encoder for SBOM format %q", format)
+}
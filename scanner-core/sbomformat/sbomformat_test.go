@@ -0,0 +1,161 @@
+package sbomformat
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+)
+
+// testSyftJSON is a minimal Syft SBOM with a single package
+var testSyftJSON = []byte(`{
+  "artifacts": [{
+    "id": "a1b2c3",
+    "name": "openssl",
+    "version": "3.0.0",
+    "type": "apk",
+    "foundBy": "apk-db-cataloger",
+    "locations": [],
+    "licenses": [],
+    "language": "",
+    "cpes": [],
+    "purl": "pkg:apk/alpine/openssl@3.0.0"
+  }],
+  "artifactRelationships": [],
+  "source": {"id": "sha256:abc", "name": "nginx", "version": "1.25", "type": "image", "metadata": {"userInput": "nginx:1.25", "imageID": "sha256:abc", "manifestDigest": "sha256:abc", "mediaType": "", "tags": [], "imageSize": 0, "layers": [], "manifest": "", "config": "", "repoDigests": []}},
+  "distro": {"name": "alpine", "id": "alpine", "versionID": "3.19"},
+  "descriptor": {"name": "syft", "version": "1.45.1"},
+  "schema": {"version": "16.0.0", "url": "https://raw.githubusercontent.com/anchore/syft/main/schema/json/schema-16.0.0.json"}
+}`)
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		format  string
+		want    string
+		wantErr bool
+	}{
+		{"", SyftJSON, false},
+		{"syft-json", SyftJSON, false},
+		{" CycloneDX-JSON ", CycloneDXJSON, false},
+		{"spdx-json", SPDXJSON, false},
+		{"cyclonedx-xml", "", true},
+	}
+	for _, tt := range tests {
+		got, err := Normalize(tt.format)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("Normalize(%q) = %q, %v; want %q, error %v", tt.format, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestConvert(t *testing.T) {
+	t.Run("syft-json is returned unchanged", func(t *testing.T) {
+		got, err := Convert(testSyftJSON, SyftJSON)
+		if err != nil {
+			t.Fatalf("Convert() error = %v", err)
+		}
+		if !bytes.Equal(got, testSyftJSON) {
+			t.Error("Syft JSON was modified")
+		}
+	})
+
+	t.Run("cyclonedx-json", func(t *testing.T) {
+		got, err := Convert(testSyftJSON, CycloneDXJSON)
+		if err != nil {
+			t.Fatalf("Convert() error = %v", err)
+		}
+		var bom struct {
+			BOMFormat  string `json:"bomFormat"`
+			Components []struct {
+				Name    string `json:"name"`
+				Version string `json:"version"`
+				PURL    string `json:"purl"`
+			} `json:"components"`
+		}
+		if err := json.Unmarshal(got, &bom); err != nil {
+			t.Fatalf("invalid CycloneDX JSON: %v", err)
+		}
+		if bom.BOMFormat != "CycloneDX" {
+			t.Errorf("bomFormat = %q, want CycloneDX", bom.BOMFormat)
+		}
+		found := false
+		for _, c := range bom.Components {
+			if c.Name == "openssl" && c.Version == "3.0.0" && c.PURL == "pkg:apk/alpine/openssl@3.0.0" {
+				found = true
+			}
+		}
+		if !found {
+			t.Errorf("openssl missing from components: %+v", bom.Components)
+		}
+	})
+
+	t.Run("spdx-json", func(t *testing.T) {
+		got, err := Convert(testSyftJSON, SPDXJSON)
+		if err != nil {
+			t.Fatalf("Convert() error = %v", err)
+		}
+		var doc struct {
+			SPDXVersion string `json:"spdxVersion"`
+			Packages    []struct {
+				Name        string `json:"name"`
+				VersionInfo string `json:"versionInfo"`
+			} `json:"packages"`
+		}
+		if err := json.Unmarshal(got, &doc); err != nil {
+			t.Fatalf("invalid SPDX JSON: %v", err)
+		}
+		if doc.SPDXVersion == "" {
+			t.Error("spdxVersion missing")
+		}
+		found := false
+		for _, p := range doc.Packages {
+			if p.Name == "openssl" && p.VersionInfo == "3.0.0" {
+				found = true
+			}
+		}
+		if !found {
+			t.Errorf("openssl missing from packages: %+v", doc.Packages)
+		}
+	})
+
+	t.Run("invalid input", func(t *testing.T) {
+		if _, err := Convert([]byte("not json"), CycloneDXJSON); err == nil {
+			t.Error("expected an error for invalid Syft JSON")
+		}
+		if _, err := Convert(testSyftJSON, "cyclonedx-xml"); err == nil {
+			t.Error("expected an error for an unsupported format")
+		}
+	})
+}
+
+func TestToSyftJSON(t *testing.T) {
+	if got, err := ToSyftJSON(testSyftJSON); err != nil || !bytes.Equal(got, testSyftJSON) {
+		t.Errorf("ToSyftJSON(syft-json) changed the SBOM, error = %v", err)
+	}
+
+	for _, format := range []string{CycloneDXJSON, SPDXJSON} {
+		converted, err := Convert(testSyftJSON, format)
+		if err != nil {
+			t.Fatalf("Convert(%s) error = %v", format, err)
+		}
+		got, err := ToSyftJSON(converted)
+		if err != nil {
+			t.Fatalf("ToSyftJSON(%s) error = %v", format, err)
+		}
+		var doc struct {
+			Artifacts []struct {
+				Name    string `json:"name"`
+				Version string `json:"version"`
+			} `json:"artifacts"`
+		}
+		if err := json.Unmarshal(got, &doc); err != nil {
+			t.Fatalf("invalid Syft JSON from %s: %v", format, err)
+		}
+		if len(doc.Artifacts) != 1 || doc.Artifacts[0].Name != "openssl" || doc.Artifacts[0].Version != "3.0.0" {
+			t.Errorf("artifacts from %s = %+v", format, doc.Artifacts)
+		}
+	}
+
+	if _, err := ToSyftJSON([]byte(`{"not": "an sbom"}`)); err == nil {
+		t.Error("ToSyftJSON(invalid) succeeded, want an error")
+	}
+}
@@ -0,0 +1,219 @@
+package scanning
+
+import (
+	"log/slog"
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/bvboe/b2s-go/scanner-core/database"
+)
+
+// Scans can be paused per node, e.g. while a node is cordoned and drained:
+// the worker then leaves the node's image and host jobs in the queue instead
+// of sending SBOM requests to a scanner that is about to go away. Queued
+// image jobs are rerouted to another node running the same image where
+// there is one; the rest are held until ResumeNode. Registry pulls are not
+// tied to a node and are never held.
+
+// nodePause records why scans on a node are held back
+type nodePause struct {
+	reason string
+	since  time.Time
+}
+
+// PausedNode describes a node whose scans are paused
+type PausedNode struct {
+	NodeName string    `json:"node_name"`
+	Reason   string    `json:"reason"`
+	Since    time.Time `json:"since"`
+	HeldJobs int       `json:"held_jobs"` // Queued jobs waiting for the node to resume
+}
+
+// reroute moves a queued image job to another node running the image
+type reroute struct {
+	queueID          int64
+	nodeName         string
+	containerRuntime string
+	reference        string
+}
+
+// PauseNode stops sending SBOM requests to the scanner on nodeName until
+// ResumeNode, and reroutes its queued image jobs to other nodes running the
+// same images. Scans already in progress finish. Pausing a paused node only
+// updates the reason.
+func (q *JobQueue) PauseNode(nodeName, reason string) {
+	q.jobsMu.Lock()
+	if q.pausedNodes == nil {
+		q.pausedNodes = make(map[string]nodePause)
+	}
+	pause, already := q.pausedNodes[nodeName]
+	if !already {
+		pause.since = q.now()
+	}
+	pause.reason = reason
+	q.pausedNodes[nodeName] = pause
+	q.jobsMu.Unlock()
+
+	if already {
+		return
+	}
+	rerouted, held := q.rerouteNode(nodeName)
+	nodesLog.Info("paused scans on node", "node", nodeName, "reason", reason, "rerouted", rerouted, "held", held)
+}
+
+// ResumeNode lets the worker pick up the jobs held for nodeName again
+func (q *JobQueue) ResumeNode(nodeName string) {
+	q.jobsMu.Lock()
+	defer q.jobsMu.Unlock()
+	if _, ok := q.pausedNodes[nodeName]; !ok {
+		return
+	}
+	delete(q.pausedNodes, nodeName)
+	nodesLog.Info("resumed scans on node", "node", nodeName, "held", q.heldJobsLocked(nodeName))
+	q.jobsAvailable.Broadcast()
+}
+
+// PausedNodes returns the nodes whose scans are paused, sorted by name
+func (q *JobQueue) PausedNodes() []PausedNode {
+	q.jobsMu.Lock()
+	defer q.jobsMu.Unlock()
+	paused := make([]PausedNode, 0, len(q.pausedNodes))
+	for name, pause := range q.pausedNodes {
+		paused = append(paused, PausedNode{
+			NodeName: name,
+			Reason:   pause.reason,
+			Since:    pause.since,
+			HeldJobs: q.heldJobsLocked(name),
+		})
+	}
+	sort.Slice(paused, func(i, j int) bool { return paused[i].NodeName < paused[j].NodeName })
+	return paused
+}
+
+// nodePausedLocked reports whether scans on nodeName are paused. Must be
+// called with jobsMu held.
+func (q *JobQueue) nodePausedLocked(nodeName string) bool {
+	_, ok := q.pausedNodes[nodeName]
+	return nodeName != "" && ok
+}
+
+// heldLocked reports whether the image job waits for its node to resume.
+// Must be called with jobsMu held.
+func (q *JobQueue) heldLocked(job ScanJob) bool {
+	return !job.fromRegistry() && q.nodePausedLocked(job.NodeName)
+}
+
+// heldJobsLocked counts the queued jobs waiting for nodeName to resume. Must
+// be called with jobsMu held.
+func (q *JobQueue) heldJobsLocked(nodeName string) int {
+	held := 0
+	for _, job := range q.jobs {
+		if !job.fromRegistry() && job.NodeName == nodeName {
+			held++
+		}
+	}
+	for _, job := range q.hostJobs {
+		if job.NodeName == nodeName {
+			held++
+		}
+	}
+	return held
+}
+
+// rerouteNode moves the queued image jobs on a paused node to other nodes
+// running the same images. Returns the number of jobs moved and held.
+func (q *JobQueue) rerouteNode(nodeName string) (rerouted, held int) {
+	q.jobsMu.Lock()
+	var digests []string
+	for _, job := range q.jobs {
+		if job.NodeName == nodeName && !job.fromRegistry() && !slices.Contains(digests, job.Image.Digest) {
+			digests = append(digests, job.Image.Digest)
+		}
+	}
+	q.jobsMu.Unlock()
+
+	// Look up the alternatives outside the queue lock
+	targets := make(map[string]database.ContainerRow, len(digests))
+	for _, digest := range digests {
+		if target, ok := q.alternateContainer(digest); ok {
+			targets[digest] = target
+		}
+	}
+
+	q.jobsMu.Lock()
+	var moved []reroute
+	for i := range q.jobs {
+		job := &q.jobs[i]
+		if job.NodeName != nodeName || job.fromRegistry() {
+			continue
+		}
+		target, ok := targets[job.Image.Digest]
+		if !ok || q.nodePausedLocked(target.NodeName) {
+			continue
+		}
+		job.NodeName, job.ContainerRuntime, job.Image.Reference = target.NodeName, target.ContainerRuntime, target.Reference
+		moved = append(moved, reroute{job.queueID, target.NodeName, target.ContainerRuntime, target.Reference})
+	}
+	held = q.heldJobsLocked(nodeName)
+	if len(moved) > 0 {
+		q.jobsAvailable.Broadcast()
+	}
+	q.jobsMu.Unlock()
+
+	for _, r := range moved {
+		q.persistReroute(r)
+	}
+	return len(moved), held
+}
+
+// routeAroundPause moves a newly queued image job off a paused node when
+// another node runs the image
+func (q *JobQueue) routeAroundPause(job *ScanJob) {
+	q.jobsMu.Lock()
+	held := q.heldLocked(*job)
+	q.jobsMu.Unlock()
+	if !held {
+		return
+	}
+
+	target, ok := q.alternateContainer(job.Image.Digest)
+	if !ok {
+		log.Debug("node paused, holding scan job", "digest", job.Image.Digest, "node", job.NodeName)
+		return
+	}
+	log.Debug("node paused, rerouting scan job", "digest", job.Image.Digest, "from", job.NodeName, "to", target.NodeName)
+	job.NodeName, job.ContainerRuntime, job.Image.Reference = target.NodeName, target.ContainerRuntime, target.Reference
+}
+
+// alternateContainer returns a container running digest on a node whose
+// scans are not paused
+func (q *JobQueue) alternateContainer(digest string) (database.ContainerRow, bool) {
+	if q.db == nil || digest == "" {
+		return database.ContainerRow{}, false
+	}
+	instances, err := q.db.GetContainersForImage(digest)
+	if err != nil {
+		log.Warn("error looking up nodes running image", "digest", digest, slog.Any("error", err))
+		return database.ContainerRow{}, false
+	}
+
+	q.jobsMu.Lock()
+	defer q.jobsMu.Unlock()
+	for _, instance := range instances {
+		if instance.NodeName != "" && !q.nodePausedLocked(instance.NodeName) {
+			return instance, true
+		}
+	}
+	return database.ContainerRow{}, false
+}
+
+// persistReroute records the new node of a rerouted job in the persisted queue
+func (q *JobQueue) persistReroute(r reroute) {
+	if r.queueID == 0 || q.db == nil {
+		return
+	}
+	if err := q.db.SetQueuedScanNode(r.queueID, r.nodeName, r.containerRuntime, r.reference); err != nil {
+		log.Warn("failed to update rerouted queued scan", "id", r.queueID, slog.Any("error", err))
+	}
+}
@@ -0,0 +1,87 @@
+package scanning
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/bvboe/b2s-go/scanner-core/containers"
+)
+
+func TestPauseNode(t *testing.T) {
+	db := newQueueTestDB(t)
+	for _, c := range []containers.Container{
+		{ID: containers.ContainerID{Namespace: "production", Pod: "web-2", Name: "web"},
+			Image: containers.ImageID{Reference: "web:2", Digest: "sha256:web"}, NodeName: "node-3", ContainerRuntime: "docker"},
+		{ID: containers.ContainerID{Namespace: "dev", Pod: "solo-1", Name: "solo"},
+			Image: containers.ImageID{Reference: "solo:1", Digest: "sha256:solo"}, NodeName: "node-1", ContainerRuntime: "containerd"},
+	} {
+		if _, err := db.AddContainer(c); err != nil {
+			t.Fatalf("Failed to add container: %v", err)
+		}
+	}
+	q := newIdleQueue(db, QueueConfig{})
+	q.Enqueue(ScanJob{Image: containers.ImageID{Reference: "web:2", Digest: "sha256:web"}, NodeName: "node-1", ContainerRuntime: "containerd"})
+	q.Enqueue(ScanJob{Image: containers.ImageID{Reference: "solo:1", Digest: "sha256:solo"}, NodeName: "node-1", ContainerRuntime: "containerd"})
+	q.Enqueue(ScanJob{Image: containers.ImageID{Digest: "sha256:crawled"}, NodeName: "node-1", Registry: true})
+	q.hostJobs = append(q.hostJobs, HostScanJob{NodeName: "node-1"})
+
+	q.PauseNode("node-1", "cordoned")
+
+	// The image also running elsewhere is rerouted, the other one is held
+	if web := q.jobs[0]; web.NodeName != "node-3" || web.ContainerRuntime != "docker" {
+		t.Errorf("web job = %+v, want it rerouted to node-3", web)
+	}
+	if solo := q.jobs[1]; solo.NodeName != "node-1" {
+		t.Errorf("solo job = %+v, want it held on node-1", solo)
+	}
+	if scans, _, err := db.RecoverQueuedScans(); err != nil || scans[0].NodeName != "node-3" {
+		t.Errorf("persisted web job not rerouted: %+v, %v", scans, err)
+	}
+	paused := q.PausedNodes()
+	if len(paused) != 1 || paused[0].NodeName != "node-1" || paused[0].Reason != "cordoned" || paused[0].HeldJobs != 2 {
+		t.Errorf("PausedNodes() = %+v, want node-1 with the solo and host jobs held", paused)
+	}
+	if digests := q.PendingSBOMDigests("node-1"); len(digests) != 0 {
+		t.Errorf("PendingSBOMDigests() on a paused node = %v", digests)
+	}
+	held := 0
+	for _, job := range q.GetQueueContents().Jobs {
+		if job.Held {
+			held++
+		}
+	}
+	if held != 2 {
+		t.Errorf("queue contents show %d held jobs, want 2", held)
+	}
+
+	// Jobs queued while the node is paused are routed around it too
+	q.Enqueue(ScanJob{Image: containers.ImageID{Reference: "web:2", Digest: "sha256:web"}, NodeName: "node-1", ForceScan: true})
+	if rescan := q.jobs[3]; rescan.NodeName != "node-3" {
+		t.Errorf("job queued on a paused node = %+v, want it rerouted", rescan)
+	}
+
+	// Held jobs are skipped; host jobs on the node wait as well
+	var got []string
+	for {
+		i, _, _ := q.nextJobLocked(q.now())
+		if i < 0 {
+			break
+		}
+		got = append(got, q.jobs[i].Image.Digest)
+		q.jobs = removeAt(q.jobs, i)
+	}
+	if want := []string{"sha256:web", "sha256:crawled", "sha256:web"}; !slices.Equal(got, want) {
+		t.Errorf("scan order while paused = %v, want %v", got, want)
+	}
+	if image, host, _ := q.nextJobLocked(q.now()); image >= 0 || host >= 0 {
+		t.Errorf("nextJobLocked() = %d, %d with only held jobs queued", image, host)
+	}
+
+	q.ResumeNode("node-1")
+	if len(q.PausedNodes()) != 0 {
+		t.Error("node still paused after ResumeNode()")
+	}
+	if image, _, _ := q.nextJobLocked(q.now()); image < 0 || q.jobs[image].Image.Digest != "sha256:solo" {
+		t.Errorf("nextJobLocked() = %d after resume, want the held solo job", image)
+	}
+}
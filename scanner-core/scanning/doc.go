@@ -0,0 +1,9 @@
+// Package scanning implements the scan pipeline: a job queue that retrieves an
+// SBOM for each image through a caller-supplied SBOMRetriever, scans it with
+// Grype and persists both results. Queued jobs are also persisted, so pending
+// and interrupted scans resume after a restart.
+//
+// Behaviour is configured through NewJobQueue's grype.Config and QueueConfig
+// arguments and the Set* methods on JobQueue; the package does not read
+// environment variables.
+package scanning
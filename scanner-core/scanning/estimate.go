@@ -0,0 +1,105 @@
+package scanning
+
+import "time"
+
+// A bulk rescan (rescan-all, or the rescan after a vulnerability database
+// update) can keep the single worker busy for hours. Its effort is estimated
+// from the phase durations of the scans processed so far, so it can be
+// reported before it starts and confirmed when it is large.
+
+// Triggers of bulk rescans
+const (
+	BulkRescanAll      = "rescan-all"      // requested through the API
+	BulkRescanDBUpdate = "grype-db-update" // images scanned with an older vulnerability database
+)
+
+// Phase durations assumed until scans of that phase were observed
+const (
+	defaultSBOMSeconds = 30.0
+	defaultVulnSeconds = 10.0
+)
+
+// RescanEstimate is the expected effort of rescanning a number of images
+type RescanEstimate struct {
+	Images      int  `json:"images"`       // Images that would be rescanned
+	NotRunning  int  `json:"not_running"`  // Images skipped because no container runs them
+	FullRescan  bool `json:"full_rescan"`  // SBOMs are retrieved again
+	QueuedAhead int  `json:"queued_ahead"` // Jobs queued or in flight before the rescans
+	// PerImageSeconds is the expected scan time of one image; Historical is
+	// false when no scans were observed yet and defaults were assumed
+	PerImageSeconds float64   `json:"per_image_seconds"`
+	Historical      bool      `json:"historical"`
+	DurationSeconds float64   `json:"duration_seconds"` // Time to rescan the images
+	CompletesAt     time.Time `json:"completes_at"`     // Projected completion, after the jobs ahead (ignoring quiet hours)
+}
+
+// BulkRescan is a bulk rescan that was started, reported by the queue status
+type BulkRescan struct {
+	Trigger   string    `json:"trigger"` // BulkRescanAll or BulkRescanDBUpdate
+	StartedAt time.Time `json:"started_at"`
+	RescanEstimate
+}
+
+// EstimateRescan estimates the effort of rescanning images: vulnerabilities
+// only, or with a fresh SBOM if fullRescan is set
+func (q *JobQueue) EstimateRescan(images int, fullRescan bool) RescanEstimate {
+	status := q.Status()
+	perImage, historical := q.stats.perImageSeconds(fullRescan)
+	duration := perImage * float64(images)
+	drain := time.Duration(status.EstimatedDrainSeconds * float64(time.Second))
+	return RescanEstimate{
+		Images:          images,
+		FullRescan:      fullRescan,
+		QueuedAhead:     status.Depth + len(status.InFlight),
+		PerImageSeconds: perImage,
+		Historical:      historical,
+		DurationSeconds: duration,
+		CompletesAt:     q.now().Add(drain + time.Duration(duration*float64(time.Second))).UTC(),
+	}
+}
+
+// EstimateRescanAll estimates the effort of RescanAll without queueing anything
+func (q *JobQueue) EstimateRescanAll(fullRescan bool) (RescanEstimate, error) {
+	jobs, notRunning, err := q.runningImageJobs(fullRescan)
+	if err != nil {
+		return RescanEstimate{}, err
+	}
+	estimate := q.EstimateRescan(len(jobs), fullRescan)
+	estimate.NotRunning = notRunning
+	return estimate, nil
+}
+
+// BeginBulkRescan estimates a bulk rescan about to be queued and reports it
+// as the last bulk rescan in the queue status
+func (q *JobQueue) BeginBulkRescan(trigger string, images int, fullRescan bool) RescanEstimate {
+	estimate := q.EstimateRescan(images, fullRescan)
+	q.progress.mu.Lock()
+	q.progress.bulkRescan = &BulkRescan{Trigger: trigger, StartedAt: q.now().UTC(), RescanEstimate: estimate}
+	q.progress.mu.Unlock()
+	log.Info("bulk rescan starting", "trigger", trigger, "images", images, "full_rescan", fullRescan,
+		"estimated_duration", time.Duration(estimate.DurationSeconds*float64(time.Second)).Round(time.Second),
+		"completes_at", estimate.CompletesAt.Format(time.RFC3339), "historical", estimate.Historical)
+	return estimate
+}
+
+// perImageSeconds returns the expected duration of an image rescan from the
+// mean phase durations, and whether all phases it needs were observed
+func (s *scanStats) perImageSeconds(fullRescan bool) (float64, bool) {
+	historical := true
+	mean := func(phase string, fallback float64) float64 {
+		if m, ok := s.meanPhase("image", phase); ok {
+			return m
+		}
+		historical = false
+		return fallback
+	}
+	seconds := mean(PhaseVuln, defaultVulnSeconds)
+	if fullRescan {
+		seconds += mean(PhaseSBOM, defaultSBOMSeconds)
+	}
+	// Status and cache checks are quick; they are only added once seen
+	if m, ok := s.meanPhase("image", PhaseStarting); ok {
+		seconds += m
+	}
+	return seconds, historical
+}
@@ -0,0 +1,69 @@
+package scanning
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bvboe/b2s-go/scanner-core/containers"
+)
+
+func TestEstimateRescan(t *testing.T) {
+	q := newIdleQueue(nil, QueueConfig{})
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	q.now = func() time.Time { return now }
+
+	// Defaults until scans were observed
+	estimate := q.EstimateRescan(10, true)
+	if estimate.Historical || estimate.PerImageSeconds != defaultSBOMSeconds+defaultVulnSeconds {
+		t.Errorf("EstimateRescan() before any scan = %+v, want the defaults", estimate)
+	}
+
+	// One scan: 2s starting, 20s SBOM, 6s vulnerabilities
+	q.startScan(InFlightScan{Type: "image", Digest: "sha256:done"})
+	now = now.Add(2 * time.Second)
+	q.setPhase(PhaseSBOM)
+	now = now.Add(20 * time.Second)
+	q.setPhase(PhaseVuln)
+	now = now.Add(6 * time.Second)
+	q.finishScan()
+
+	q.jobs = []ScanJob{{Image: containers.ImageID{Digest: "sha256:a"}}}
+	estimate = q.EstimateRescan(100, false)
+	if !estimate.Historical || estimate.PerImageSeconds != 8 || estimate.DurationSeconds != 800 {
+		t.Errorf("EstimateRescan() = %+v, want 8s per image from the observed phases", estimate)
+	}
+	// The queued job (28s on average) goes first
+	if estimate.QueuedAhead != 1 || !estimate.CompletesAt.Equal(now.Add(828*time.Second)) {
+		t.Errorf("QueuedAhead = %d, CompletesAt = %v, want 1 and %v", estimate.QueuedAhead, estimate.CompletesAt, now.Add(828*time.Second))
+	}
+	if full := q.EstimateRescan(100, true); full.PerImageSeconds != 28 {
+		t.Errorf("full rescan PerImageSeconds = %v, want 28", full.PerImageSeconds)
+	}
+}
+
+func TestRescanAllEstimate(t *testing.T) {
+	db := newQueueTestDB(t)
+	if _, _, err := db.GetOrCreateImage(containers.ImageID{Reference: "old:1", Digest: "sha256:old"}); err != nil {
+		t.Fatalf("GetOrCreateImage() error = %v", err)
+	}
+	q := newIdleQueue(db, QueueConfig{})
+
+	estimate, err := q.EstimateRescanAll(false)
+	if err != nil {
+		t.Fatalf("EstimateRescanAll() error = %v", err)
+	}
+	if estimate.Images != 2 || estimate.NotRunning != 1 || len(q.jobs) != 0 {
+		t.Errorf("EstimateRescanAll() = %+v with %d jobs queued, want 2 images, 1 not running, nothing queued", estimate, len(q.jobs))
+	}
+	if q.Status().LastBulkRescan != nil {
+		t.Error("an estimate was reported as a bulk rescan")
+	}
+
+	if _, _, err := q.RescanAll(false); err != nil {
+		t.Fatalf("RescanAll() error = %v", err)
+	}
+	bulk := q.Status().LastBulkRescan
+	if bulk == nil || bulk.Trigger != BulkRescanAll || bulk.Images != 2 {
+		t.Errorf("LastBulkRescan = %+v, want the rescan of 2 images", bulk)
+	}
+}
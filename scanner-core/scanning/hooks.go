@@ -0,0 +1,143 @@
+package scanning
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/bvboe/b2s-go/scanner-core/containers"
+)
+
+// HookStage identifies a point in the image scan lifecycle at which hooks run
+type HookStage string
+
+const (
+	// HookPostSBOM runs after a fresh SBOM is retrieved, before it is stored.
+	// Hooks may rewrite HookEvent.SBOM.
+	HookPostSBOM HookStage = "post-sbom"
+	// HookPostVulnScan runs after Grype has scanned an SBOM.
+	// Hooks may rewrite HookEvent.Vulnerabilities.
+	HookPostVulnScan HookStage = "post-vuln-scan"
+	// HookPrePersist runs immediately before vulnerability results are written,
+	// both for local scans and for results imported from the result cache.
+	// Hooks may rewrite HookEvent.Vulnerabilities, and HookEvent.SBOM for
+	// imported results (a locally generated SBOM is already stored by then).
+	HookPrePersist HookStage = "pre-persist"
+	// HookPostPersist runs after vulnerability results are stored, for local
+	// scans and imported results alike. The documents are read-only at this
+	// point and hook errors are logged without failing the scan.
+	HookPostPersist HookStage = "post-persist"
+)
+
+// HookEvent carries the documents of a scan through its hooks. Hooks modify
+// SBOM or Vulnerabilities in place to enrich the results (e.g. tagging internal
+// packages); the pipeline continues with whatever the last hook left behind.
+// Since cached results may be passed through pre-persist hooks again when
+// imported elsewhere, hooks should be idempotent.
+type HookEvent struct {
+	Stage           HookStage
+	Image           containers.ImageID
+	NodeName        string
+	SBOM            []byte // Syft JSON
+	Vulnerabilities []byte // Grype JSON; nil at HookPostSBOM
+}
+
+// Hook is a callback invoked at a scan lifecycle stage. Returning an error
+// fails the scan at that stage.
+type Hook func(ctx context.Context, event *HookEvent) error
+
+// AddHook registers a hook for the given stage. Hooks of a stage run in the
+// order they were added.
+func (q *JobQueue) AddHook(stage HookStage, hook Hook) {
+	q.hooksMu.Lock()
+	defer q.hooksMu.Unlock()
+	if q.hooks == nil {
+		q.hooks = make(map[HookStage][]Hook)
+	}
+	q.hooks[stage] = append(q.hooks[stage], hook)
+	log.Info("scan hook registered", "stage", stage)
+}
+
+// runHooks runs the hooks registered for a stage and returns the resulting event
+func (q *JobQueue) runHooks(ctx context.Context, stage HookStage, job ScanJob, sbomJSON, vulnJSON []byte) (*HookEvent, error) {
+	event := &HookEvent{
+		Stage:           stage,
+		Image:           job.Image,
+		NodeName:        job.NodeName,
+		SBOM:            sbomJSON,
+		Vulnerabilities: vulnJSON,
+	}
+
+	q.hooksMu.RLock()
+	hooks := q.hooks[stage]
+	q.hooksMu.RUnlock()
+
+	for i, hook := range hooks {
+		if err := hook(ctx, event); err != nil {
+			return nil, fmt.Errorf("%s hook %d: %w", stage, i+1, err)
+		}
+	}
+	return event, nil
+}
+
+// ExecHook returns a Hook that runs an external command. The command line is
+// split on whitespace and executed directly (no shell).
+//
+// The stage's document is written to the command's stdin: the SBOM at
+// HookPostSBOM, the vulnerability report at the other stages. If the command
+// prints anything to stdout it must be valid JSON and replaces that document.
+// The stage and image are passed as B2S_HOOK_STAGE, B2S_IMAGE_REFERENCE,
+// B2S_IMAGE_DIGEST and B2S_NODE_NAME. A non-zero exit status or exceeding the
+// timeout fails the hook.
+func ExecHook(command string, timeout time.Duration) Hook {
+	args := strings.Fields(command)
+	return func(ctx context.Context, event *HookEvent) error {
+		if len(args) == 0 {
+			return nil
+		}
+		if timeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, timeout)
+			defer cancel()
+		}
+
+		doc := &event.Vulnerabilities
+		if event.Stage == HookPostSBOM {
+			doc = &event.SBOM
+		}
+
+		var stdout, stderr bytes.Buffer
+		cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+		cmd.Stdin = bytes.NewReader(*doc)
+		cmd.Stdout = &stdout
+		cmd.Stderr = &stderr
+		cmd.Env = append(os.Environ(),
+			"B2S_HOOK_STAGE="+string(event.Stage),
+			"B2S_IMAGE_REFERENCE="+event.Image.Reference,
+			"B2S_IMAGE_DIGEST="+event.Image.Digest,
+			"B2S_NODE_NAME="+event.NodeName,
+		)
+
+		if err := cmd.Run(); err != nil {
+			if msg := strings.TrimSpace(stderr.String()); msg != "" {
+				return fmt.Errorf("%s: %w: %s", args[0], err, msg)
+			}
+			return fmt.Errorf("%s: %w", args[0], err)
+		}
+
+		out := bytes.TrimSpace(stdout.Bytes())
+		if len(out) == 0 {
+			return nil
+		}
+		if !json.Valid(out) {
+			return fmt.Errorf("%s: output is not valid JSON", args[0])
+		}
+		*doc = out
+		return nil
+	}
+}
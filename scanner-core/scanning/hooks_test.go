@@ -0,0 +1,159 @@
+package scanning
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/bvboe/b2s-go/scanner-core/containers"
+	"github.com/bvboe/b2s-go/scanner-core/database"
+	"github.com/bvboe/b2s-go/scanner-core/grype"
+	"github.com/bvboe/b2s-go/scanner-core/transfer"
+)
+
+// TestPrePersistHookRewritesCachedResults tests that pre-persist hooks run on
+// results imported from the result cache and that their output is stored
+func TestPrePersistHookRewritesCachedResults(t *testing.T) {
+	db, err := database.New(filepath.Join(t.TempDir(), "hooks.db"))
+	if err != nil {
+		t.Fatalf("Failed to create database: %v", err)
+	}
+	defer func() { _ = database.Close(db) }()
+
+	testImage := containers.ImageID{Reference: "nginx:1.21", Digest: "sha256:hook123"}
+	bundle, err := transfer.NewBundle(database.ImageTransferRecord{Digest: testImage.Digest}, "staging",
+		[]byte(`{"artifacts":[]}`), []byte(`{"matches":[]}`))
+	if err != nil {
+		t.Fatalf("Failed to create bundle: %v", err)
+	}
+
+	queue := NewJobQueue(db, nil, grype.Config{}, QueueConfig{MaxDepth: 0})
+	defer queue.Shutdown()
+	queue.grypeDBBuilt = func() (time.Time, error) { return time.Now(), nil }
+	queue.SetResultCache(&fakeResultCache{bundle: bundle})
+
+	var stages []HookStage
+	queue.AddHook(HookPrePersist, func(ctx context.Context, event *HookEvent) error {
+		stages = append(stages, event.Stage)
+		if event.Image.Digest != testImage.Digest {
+			t.Errorf("hook got digest %s, want %s", event.Image.Digest, testImage.Digest)
+		}
+		event.Vulnerabilities = []byte(`{"matches":[],"descriptor":{"name":"enriched"}}`)
+		return nil
+	})
+
+	queue.processJob(ScanJob{Image: testImage, NodeName: "test-node", ContainerRuntime: "containerd"})
+
+	if len(stages) != 1 || stages[0] != HookPrePersist {
+		t.Fatalf("hook stages = %v, want [%s]", stages, HookPrePersist)
+	}
+	vulnJSON, err := db.GetVulnerabilities(testImage.Digest)
+	if err != nil {
+		t.Fatalf("GetVulnerabilities() error = %v", err)
+	}
+	if !bytes.Contains(vulnJSON, []byte("enriched")) {
+		t.Errorf("stored vulnerabilities were not rewritten by hook: %s", vulnJSON)
+	}
+}
+
+// TestPostSBOMHookFailureFailsScan tests that a failing post-SBOM hook marks the image sbom_failed
+// TestPostPersistHookRunsAfterStore tests that post-persist hooks see the
+// stored results and that their errors do not fail the scan
+func TestPostPersistHookRunsAfterStore(t *testing.T) {
+	db, err := database.New(filepath.Join(t.TempDir(), "hooks.db"))
+	if err != nil {
+		t.Fatalf("Failed to create database: %v", err)
+	}
+	defer func() { _ = database.Close(db) }()
+
+	testImage := containers.ImageID{Reference: "nginx:1.21", Digest: "sha256:postpersist"}
+	bundle, err := transfer.NewBundle(database.ImageTransferRecord{Digest: testImage.Digest}, "staging",
+		[]byte(`{"artifacts":[]}`), []byte(`{"matches":[]}`))
+	if err != nil {
+		t.Fatalf("Failed to create bundle: %v", err)
+	}
+
+	queue := NewJobQueue(db, nil, grype.Config{}, QueueConfig{MaxDepth: 0})
+	defer queue.Shutdown()
+	queue.grypeDBBuilt = func() (time.Time, error) { return time.Now(), nil }
+	queue.SetResultCache(&fakeResultCache{bundle: bundle})
+
+	var statusAtHook database.Status
+	queue.AddHook(HookPostPersist, func(ctx context.Context, event *HookEvent) error {
+		statusAtHook, _ = db.GetImageStatus(event.Image.Digest)
+		return errors.New("webhook unreachable")
+	})
+
+	queue.processJob(ScanJob{Image: testImage, NodeName: "test-node", ContainerRuntime: "containerd"})
+
+	if statusAtHook != database.StatusCompleted {
+		t.Errorf("status seen by hook = %q, want %s", statusAtHook, database.StatusCompleted)
+	}
+	status, err := db.GetImageStatus(testImage.Digest)
+	if err != nil {
+		t.Fatalf("GetImageStatus() error = %v", err)
+	}
+	if status != database.StatusCompleted {
+		t.Errorf("status = %s, want %s", status, database.StatusCompleted)
+	}
+}
+
+func TestPostSBOMHookFailureFailsScan(t *testing.T) {
+	db, err := database.New(filepath.Join(t.TempDir(), "hooks.db"))
+	if err != nil {
+		t.Fatalf("Failed to create database: %v", err)
+	}
+	defer func() { _ = database.Close(db) }()
+
+	testImage := containers.ImageID{Reference: "alpine:3.19", Digest: "sha256:hookfail"}
+	if _, _, err := db.GetOrCreateImage(testImage); err != nil {
+		t.Fatalf("GetOrCreateImage() error = %v", err)
+	}
+
+	retriever := func(ctx context.Context, image containers.ImageID, nodeName string, runtime string) ([]byte, error) {
+		return []byte(`{"artifacts":[]}`), nil
+	}
+	queue := NewJobQueue(db, retriever, grype.Config{}, QueueConfig{MaxDepth: 0})
+	defer queue.Shutdown()
+	queue.AddHook(HookPostSBOM, func(ctx context.Context, event *HookEvent) error {
+		return errors.New("policy rejected")
+	})
+
+	queue.processJob(ScanJob{Image: testImage, NodeName: "test-node", ContainerRuntime: "containerd"})
+
+	status, err := db.GetImageStatus(testImage.Digest)
+	if err != nil {
+		t.Fatalf("GetImageStatus() error = %v", err)
+	}
+	if status != database.StatusSBOMFailed {
+		t.Errorf("status = %s, want %s", status, database.StatusSBOMFailed)
+	}
+}
+
+func TestExecHook(t *testing.T) {
+	event := &HookEvent{Stage: HookPostSBOM, SBOM: []byte(`{"x":1}`)}
+	if err := ExecHook("tr x y", time.Minute)(context.Background(), event); err != nil {
+		t.Fatalf("ExecHook(tr) error = %v", err)
+	}
+	if string(event.SBOM) != `{"y":1}` {
+		t.Errorf("SBOM = %s, want {\"y\":1}", event.SBOM)
+	}
+
+	event = &HookEvent{Stage: HookPrePersist, Vulnerabilities: []byte(`{"matches":[]}`)}
+	if err := ExecHook("true", time.Minute)(context.Background(), event); err != nil {
+		t.Fatalf("ExecHook(true) error = %v", err)
+	}
+	if string(event.Vulnerabilities) != `{"matches":[]}` {
+		t.Errorf("empty output should leave document unchanged, got %s", event.Vulnerabilities)
+	}
+
+	if err := ExecHook("false", time.Minute)(context.Background(), event); err == nil {
+		t.Error("expected error from failing hook command")
+	}
+	if err := ExecHook("echo not-json", time.Minute)(context.Background(), event); err == nil {
+		t.Error("expected error for non-JSON hook output")
+	}
+}
@@ -0,0 +1,234 @@
+package scanning
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"sync"
+)
+
+// Operational metrics of the scan queue, so the scanner itself can be
+// alerted on: a growing backlog, slow phases, failing scans or a stale
+// vulnerability database.
+
+// Scan outcomes counted by bjorn2scan_scans_total
+const (
+	OutcomeSuccess = "success" // SBOM and vulnerabilities stored
+	OutcomeCached  = "cached"  // results imported from the result cache
+	OutcomeFailure = "failure" // the scan failed and the failure was recorded
+)
+
+// phaseBounds are the upper bounds (seconds) of the scan phase duration buckets
+var phaseBounds = []float64{0.1, 0.5, 1, 2.5, 5, 10, 30, 60, 120, 300, 600, 900}
+
+// sbomSizeBounds are the upper bounds (bytes) of the SBOM size buckets
+var sbomSizeBounds = []float64{16 << 10, 64 << 10, 256 << 10, 1 << 20, 4 << 20, 16 << 20, 64 << 20}
+
+// histogram is a Prometheus histogram with fixed bucket bounds
+type histogram struct {
+	bounds  []float64
+	count   uint64
+	sum     float64
+	buckets []uint64 // one per bounds entry
+}
+
+func newHistogram(bounds []float64) *histogram {
+	return &histogram{bounds: bounds, buckets: make([]uint64, len(bounds))}
+}
+
+func (h *histogram) observe(v float64) {
+	h.count++
+	h.sum += v
+	for i, b := range h.bounds {
+		if v <= b {
+			h.buckets[i]++
+		}
+	}
+}
+
+func (h *histogram) write(w io.Writer, name, labels string) {
+	for i, b := range h.bounds {
+		_, _ = fmt.Fprintf(w, "%s_bucket{%s,le=%q} %d\n", name, labels, strconv.FormatFloat(b, 'f', -1, 64), h.buckets[i])
+	}
+	_, _ = fmt.Fprintf(w, "%s_bucket{%s,le=\"+Inf\"} %d\n", name, labels, h.count)
+	_, _ = fmt.Fprintf(w, "%s_sum{%s} %g\n", name, labels, h.sum)
+	_, _ = fmt.Fprintf(w, "%s_count{%s} %d\n", name, labels, h.count)
+}
+
+// phaseKey identifies a scan phase duration histogram
+type phaseKey struct {
+	jobType string // "image" or "host"
+	phase   string
+}
+
+// outcomeKey identifies a scan outcome counter
+type outcomeKey struct {
+	jobType string
+	outcome string
+}
+
+// scanStats holds the phase durations, SBOM sizes and outcomes of the scans
+// processed by the queue
+type scanStats struct {
+	mu        sync.Mutex
+	phases    map[phaseKey]*histogram
+	sbomSizes map[string]*histogram // by job type
+	outcomes  map[outcomeKey]uint64
+}
+
+func (s *scanStats) observePhase(jobType, phase string, seconds float64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.phases == nil {
+		s.phases = make(map[phaseKey]*histogram)
+	}
+	key := phaseKey{jobType, phase}
+	h := s.phases[key]
+	if h == nil {
+		h = newHistogram(phaseBounds)
+		s.phases[key] = h
+	}
+	h.observe(seconds)
+}
+
+// meanPhase returns the mean duration of a phase in seconds, false before
+// the phase was observed
+func (s *scanStats) meanPhase(jobType, phase string) (float64, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	h := s.phases[phaseKey{jobType, phase}]
+	if h == nil || h.count == 0 {
+		return 0, false
+	}
+	return h.sum / float64(h.count), true
+}
+
+func (s *scanStats) observeSBOMSize(jobType string, size int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.sbomSizes == nil {
+		s.sbomSizes = make(map[string]*histogram)
+	}
+	h := s.sbomSizes[jobType]
+	if h == nil {
+		h = newHistogram(sbomSizeBounds)
+		s.sbomSizes[jobType] = h
+	}
+	h.observe(float64(size))
+}
+
+func (s *scanStats) countOutcome(jobType, outcome string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.outcomes == nil {
+		s.outcomes = make(map[outcomeKey]uint64)
+	}
+	s.outcomes[outcomeKey{jobType, outcome}]++
+}
+
+// setOutcome records the outcome of the job in flight, counted once it finishes
+func (q *JobQueue) setOutcome(outcome string) {
+	q.progress.mu.Lock()
+	defer q.progress.mu.Unlock()
+	if q.progress.current != nil {
+		q.progress.current.outcome = outcome
+	}
+}
+
+// WriteMetrics writes the operational metrics of the queue in Prometheus text
+// format: queue depth, scans in flight, phase durations, SBOM sizes, scan
+// outcomes and the age of the vulnerability database. Register it with
+// metrics.RegisterExtraWriter.
+func (q *JobQueue) WriteMetrics(w io.Writer) {
+	status := q.Status()
+	q.metrics.mu.RLock()
+	enqueued, dropped := q.metrics.totalEnqueued, q.metrics.totalDropped
+	q.metrics.mu.RUnlock()
+
+	_, _ = fmt.Fprintf(w, "# HELP bjorn2scan_scan_queue_depth Scan jobs waiting in the queue, by job type\n")
+	_, _ = fmt.Fprintf(w, "# TYPE bjorn2scan_scan_queue_depth gauge\n")
+	_, _ = fmt.Fprintf(w, "bjorn2scan_scan_queue_depth{type=\"image\"} %d\n", status.ImageJobs)
+	_, _ = fmt.Fprintf(w, "bjorn2scan_scan_queue_depth{type=\"host\"} %d\n", status.HostJobs)
+	_, _ = fmt.Fprintf(w, "# HELP bjorn2scan_scan_queue_retrying Queued image scans waiting for their automatic retry\n")
+	_, _ = fmt.Fprintf(w, "# TYPE bjorn2scan_scan_queue_retrying gauge\n")
+	_, _ = fmt.Fprintf(w, "bjorn2scan_scan_queue_retrying %d\n", status.RetryingJobs)
+	_, _ = fmt.Fprintf(w, "# HELP bjorn2scan_scan_queue_in_flight Scan jobs being processed\n")
+	_, _ = fmt.Fprintf(w, "# TYPE bjorn2scan_scan_queue_in_flight gauge\n")
+	_, _ = fmt.Fprintf(w, "bjorn2scan_scan_queue_in_flight %d\n", len(status.InFlight))
+	_, _ = fmt.Fprintf(w, "# HELP bjorn2scan_scan_queue_enqueued_total Scan jobs added to the queue\n")
+	_, _ = fmt.Fprintf(w, "# TYPE bjorn2scan_scan_queue_enqueued_total counter\n")
+	_, _ = fmt.Fprintf(w, "bjorn2scan_scan_queue_enqueued_total %d\n", enqueued)
+	_, _ = fmt.Fprintf(w, "# HELP bjorn2scan_scan_queue_dropped_total Scan jobs dropped because the queue was full\n")
+	_, _ = fmt.Fprintf(w, "# TYPE bjorn2scan_scan_queue_dropped_total counter\n")
+	_, _ = fmt.Fprintf(w, "bjorn2scan_scan_queue_dropped_total %d\n", dropped)
+	_, _ = fmt.Fprintf(w, "# HELP bjorn2scan_scan_queue_processed_total Scan jobs processed, including those that needed no scan\n")
+	_, _ = fmt.Fprintf(w, "# TYPE bjorn2scan_scan_queue_processed_total counter\n")
+	_, _ = fmt.Fprintf(w, "bjorn2scan_scan_queue_processed_total %d\n", status.TotalProcessed)
+
+	q.stats.write(w)
+
+	if q.grypeDBBuilt != nil {
+		if built, err := q.grypeDBBuilt(); err == nil {
+			_, _ = fmt.Fprintf(w, "# HELP bjorn2scan_grype_db_age_seconds Time since the vulnerability database in use was built\n")
+			_, _ = fmt.Fprintf(w, "# TYPE bjorn2scan_grype_db_age_seconds gauge\n")
+			_, _ = fmt.Fprintf(w, "bjorn2scan_grype_db_age_seconds %g\n", q.now().Sub(built).Seconds())
+		}
+	}
+}
+
+// write emits the scan histograms and outcome counters; families without
+// data yet are left out
+func (s *scanStats) write(w io.Writer) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.outcomes) > 0 {
+		keys := make([]outcomeKey, 0, len(s.outcomes))
+		for key := range s.outcomes {
+			keys = append(keys, key)
+		}
+		sort.Slice(keys, func(i, j int) bool {
+			if keys[i].jobType != keys[j].jobType {
+				return keys[i].jobType < keys[j].jobType
+			}
+			return keys[i].outcome < keys[j].outcome
+		})
+		_, _ = fmt.Fprintf(w, "# HELP bjorn2scan_scans_total Scans processed, by job type and outcome (success, cached, failure)\n")
+		_, _ = fmt.Fprintf(w, "# TYPE bjorn2scan_scans_total counter\n")
+		for _, key := range keys {
+			_, _ = fmt.Fprintf(w, "bjorn2scan_scans_total{type=%q,result=%q} %d\n", key.jobType, key.outcome, s.outcomes[key])
+		}
+	}
+
+	if len(s.phases) > 0 {
+		keys := make([]phaseKey, 0, len(s.phases))
+		for key := range s.phases {
+			keys = append(keys, key)
+		}
+		sort.Slice(keys, func(i, j int) bool {
+			if keys[i].jobType != keys[j].jobType {
+				return keys[i].jobType < keys[j].jobType
+			}
+			return keys[i].phase < keys[j].phase
+		})
+		_, _ = fmt.Fprintf(w, "# HELP bjorn2scan_scan_phase_duration_seconds Time scans spent in each phase (starting, sbom, vuln), by job type\n")
+		_, _ = fmt.Fprintf(w, "# TYPE bjorn2scan_scan_phase_duration_seconds histogram\n")
+		for _, key := range keys {
+			s.phases[key].write(w, "bjorn2scan_scan_phase_duration_seconds", fmt.Sprintf("type=%q,phase=%q", key.jobType, key.phase))
+		}
+	}
+
+	if len(s.sbomSizes) > 0 {
+		types := make([]string, 0, len(s.sbomSizes))
+		for jobType := range s.sbomSizes {
+			types = append(types, jobType)
+		}
+		sort.Strings(types)
+		_, _ = fmt.Fprintf(w, "# HELP bjorn2scan_sbom_size_bytes Size of the retrieved SBOMs, by job type\n")
+		_, _ = fmt.Fprintf(w, "# TYPE bjorn2scan_sbom_size_bytes histogram\n")
+		for _, jobType := range types {
+			s.sbomSizes[jobType].write(w, "bjorn2scan_sbom_size_bytes", fmt.Sprintf("type=%q", jobType))
+		}
+	}
+}
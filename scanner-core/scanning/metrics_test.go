@@ -0,0 +1,70 @@
+package scanning
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bvboe/b2s-go/scanner-core/containers"
+)
+
+func TestQueueWriteMetrics(t *testing.T) {
+	q := newIdleQueue(nil, QueueConfig{})
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	q.now = func() time.Time { return now }
+	q.grypeDBBuilt = func() (time.Time, error) { return now.Add(-36 * time.Hour), nil }
+
+	// An image scan: 1s starting, 20s retrieving a 100 KB SBOM, 3s scanning
+	q.startScan(InFlightScan{Type: "image", Digest: "sha256:done"})
+	now = now.Add(time.Second)
+	q.setPhase(PhaseSBOM)
+	q.stats.observeSBOMSize("image", 100<<10)
+	now = now.Add(20 * time.Second)
+	q.setPhase(PhaseVuln)
+	now = now.Add(3 * time.Second)
+	q.setOutcome(OutcomeSuccess)
+	q.finishScan()
+
+	// A host scan that failed retrieving its SBOM
+	q.startScan(InFlightScan{Type: "host", NodeName: "node-1"})
+	q.setPhase(PhaseSBOM)
+	now = now.Add(2 * time.Second)
+	q.setOutcome(OutcomeFailure)
+	q.finishScan()
+
+	q.jobs = []ScanJob{{Image: containers.ImageID{Digest: "sha256:a"}, RetryAt: now.Add(time.Hour)}}
+
+	var buf bytes.Buffer
+	q.WriteMetrics(&buf)
+	out := buf.String()
+	for _, want := range []string{
+		`bjorn2scan_scan_queue_depth{type="image"} 1` + "\n",
+		`bjorn2scan_scan_queue_depth{type="host"} 0` + "\n",
+		"bjorn2scan_scan_queue_retrying 1\n",
+		"bjorn2scan_scan_queue_in_flight 0\n",
+		`bjorn2scan_scans_total{type="host",result="failure"} 1` + "\n",
+		`bjorn2scan_scans_total{type="image",result="success"} 1` + "\n",
+		`bjorn2scan_scan_phase_duration_seconds_bucket{type="image",phase="sbom",le="10"} 0` + "\n",
+		`bjorn2scan_scan_phase_duration_seconds_bucket{type="image",phase="sbom",le="30"} 1` + "\n",
+		`bjorn2scan_scan_phase_duration_seconds_sum{type="image",phase="vuln"} 3` + "\n",
+		`bjorn2scan_scan_phase_duration_seconds_count{type="host",phase="sbom"} 1` + "\n",
+		`bjorn2scan_sbom_size_bytes_bucket{type="image",le="65536"} 0` + "\n",
+		`bjorn2scan_sbom_size_bytes_bucket{type="image",le="262144"} 1` + "\n",
+		`bjorn2scan_sbom_size_bytes_bucket{type="image",le="1048576"} 1` + "\n",
+		"bjorn2scan_grype_db_age_seconds 129600\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("metrics missing %q:\n%s", want, out)
+		}
+	}
+
+	// No database yet: the age is left out rather than reported as huge
+	q.grypeDBBuilt = func() (time.Time, error) { return time.Time{}, errors.New("not available") }
+	buf.Reset()
+	q.WriteMetrics(&buf)
+	if strings.Contains(buf.String(), "bjorn2scan_grype_db_age_seconds") {
+		t.Error("grype DB age written without a database")
+	}
+}
@@ -0,0 +1,127 @@
+package scanning
+
+import (
+	"log/slog"
+	"time"
+
+	"github.com/bvboe/b2s-go/scanner-core/containers"
+	"github.com/bvboe/b2s-go/scanner-core/database"
+)
+
+// Queued jobs are persisted in the scan_queue table so a restart doesn't lose
+// them: on startup the queue picks up the jobs that were pending and retries
+// the ones that were in progress. Persistence is best effort; a failed write
+// is logged and the job still runs from memory.
+
+// persistJob records a newly queued image job and returns its row ID (0 if
+// the queue has no database or the write failed)
+func (q *JobQueue) persistJob(job ScanJob) int64 {
+	var retryAt string
+	if !job.RetryAt.IsZero() {
+		retryAt = job.RetryAt.UTC().Format(time.RFC3339)
+	}
+	return q.addQueuedScan(database.QueuedScan{
+		Kind:             database.QueuedScanImage,
+		Digest:           job.Image.Digest,
+		Reference:        job.Image.Reference,
+		NodeName:         job.NodeName,
+		ContainerRuntime: job.ContainerRuntime,
+		ForceScan:        job.ForceScan,
+		FullRescan:       job.FullRescan,
+		AdHoc:            job.AdHoc,
+		Registry:         job.Registry,
+		Manual:           job.Manual,
+		Priority:         int(job.priority),
+		RetryAt:          retryAt,
+	})
+}
+
+// persistHostJob records a newly queued host job and returns its row ID
+func (q *JobQueue) persistHostJob(job HostScanJob) int64 {
+	return q.addQueuedScan(database.QueuedScan{
+		Kind:       database.QueuedScanHost,
+		NodeName:   job.NodeName,
+		ForceScan:  job.ForceScan,
+		FullRescan: job.FullRescan,
+	})
+}
+
+func (q *JobQueue) addQueuedScan(scan database.QueuedScan) int64 {
+	if q.db == nil {
+		return 0
+	}
+	id, err := q.db.AddQueuedScan(scan)
+	if err != nil {
+		log.Warn("failed to persist queued scan", "kind", scan.Kind, "digest", scan.Digest, "node", scan.NodeName, slog.Any("error", err))
+		return 0
+	}
+	return id
+}
+
+// setQueueState moves a persisted job to state
+func (q *JobQueue) setQueueState(id int64, state database.QueueState) {
+	if id == 0 || q.db == nil {
+		return
+	}
+	if err := q.db.SetQueuedScanState(id, state); err != nil {
+		log.Warn("failed to update queued scan", "id", id, "state", state, slog.Any("error", err))
+	}
+}
+
+// forgetJobs removes persisted jobs that were dropped from the queue
+func (q *JobQueue) forgetJobs(ids []int64) {
+	for _, id := range ids {
+		if id == 0 || q.db == nil {
+			continue
+		}
+		if err := q.db.RemoveQueuedScan(id); err != nil {
+			log.Warn("failed to remove queued scan", "id", id, slog.Any("error", err))
+		}
+	}
+}
+
+// recoverPersisted loads the jobs persisted by a previous run into the queue.
+// Must be called before the worker starts.
+func (q *JobQueue) recoverPersisted() {
+	scans, interrupted, err := q.db.RecoverQueuedScans()
+	if err != nil {
+		log.Error("failed to recover persisted scan queue", slog.Any("error", err))
+		return
+	}
+	if len(scans) == 0 {
+		return
+	}
+
+	for _, scan := range scans {
+		if scan.Kind == database.QueuedScanHost {
+			q.hostJobs = append(q.hostJobs, HostScanJob{
+				NodeName:   scan.NodeName,
+				ForceScan:  scan.ForceScan,
+				FullRescan: scan.FullRescan,
+				queueID:    scan.ID,
+			})
+			continue
+		}
+		var retryAt time.Time
+		if scan.RetryAt != "" {
+			retryAt, _ = time.Parse(time.RFC3339, scan.RetryAt) // unreadable: retry now
+		}
+		q.jobs = append(q.jobs, ScanJob{
+			Image:            containers.ImageID{Reference: scan.Reference, Digest: scan.Digest},
+			NodeName:         scan.NodeName,
+			ContainerRuntime: scan.ContainerRuntime,
+			ForceScan:        scan.ForceScan,
+			FullRescan:       scan.FullRescan,
+			AdHoc:            scan.AdHoc,
+			Registry:         scan.Registry,
+			Manual:           scan.Manual,
+			RetryAt:          retryAt,
+			priority:         Priority(scan.Priority),
+			queueID:          scan.ID,
+		})
+	}
+
+	depth := len(q.jobs) + len(q.hostJobs)
+	q.updateMetrics(depth, int64(depth), 0)
+	log.Info("recovered persisted scan queue", "image_jobs", len(q.jobs), "host_jobs", len(q.hostJobs), "interrupted", interrupted)
+}
@@ -0,0 +1,56 @@
+package scanning
+
+import (
+	"testing"
+
+	"github.com/bvboe/b2s-go/scanner-core/containers"
+	"github.com/bvboe/b2s-go/scanner-core/database"
+)
+
+func TestPersistedQueueRecovery(t *testing.T) {
+	db := newQueueTestDB(t)
+	before := newIdleQueue(db, QueueConfig{})
+
+	before.Enqueue(ScanJob{Image: containers.ImageID{Reference: "web:2", Digest: "sha256:web"}, NodeName: "node-1", ContainerRuntime: "containerd"})
+	before.Enqueue(ScanJob{Image: containers.ImageID{Reference: "web:2", Digest: "sha256:web"}, NodeName: "node-1", ContainerRuntime: "containerd"})
+	before.Enqueue(ScanJob{Image: containers.ImageID{Reference: "tool:1", Digest: "sha256:tool"}, NodeName: "node-2", ContainerRuntime: "containerd"})
+	before.Enqueue(ScanJob{Image: containers.ImageID{Digest: "sha256:done"}, ForceScan: true})
+	before.EnqueueHostFullRescan("node-1")
+	if len(before.jobs) != 3 {
+		t.Fatalf("queue has %d image jobs, want the duplicate skipped", len(before.jobs))
+	}
+
+	// The worker finished one job and was interrupted in the middle of another
+	before.setQueueState(before.jobs[2].queueID, database.QueueStateInProgress)
+	before.setQueueState(before.jobs[2].queueID, database.QueueStateDone)
+	before.setQueueState(before.jobs[0].queueID, database.QueueStateInProgress)
+
+	after := newIdleQueue(db, QueueConfig{})
+	after.recoverPersisted()
+
+	if len(after.jobs) != 2 || len(after.hostJobs) != 1 {
+		t.Fatalf("recovered %d image and %d host jobs, want 2 and 1", len(after.jobs), len(after.hostJobs))
+	}
+	web, tool := after.jobs[0], after.jobs[1]
+	if web.Image.Digest != "sha256:web" || !web.ForceScan || web.NodeName != "node-1" || web.ContainerRuntime != "containerd" {
+		t.Errorf("interrupted job = %+v, want a forced retry of sha256:web on node-1", web)
+	}
+	if tool.Image.Digest != "sha256:tool" || tool.ForceScan || tool.priority != PriorityNormal {
+		t.Errorf("pending job = %+v, want a first scan of sha256:tool", tool)
+	}
+	if host := after.hostJobs[0]; host.NodeName != "node-1" || !host.FullRescan {
+		t.Errorf("host job = %+v", host)
+	}
+	if depth, _, _, _, _ := after.GetMetrics(); depth != 3 {
+		t.Errorf("queue depth = %d, want 3", depth)
+	}
+
+	// Nothing is left to recover once the recovered jobs are done
+	for _, job := range after.jobs {
+		after.finishJob(job.queueID)
+	}
+	after.finishJob(after.hostJobs[0].queueID)
+	if scans, _, err := db.RecoverQueuedScans(); err != nil || len(scans) != 0 {
+		t.Errorf("RecoverQueuedScans() = %v, %v, want nothing", scans, err)
+	}
+}
@@ -0,0 +1,138 @@
+package scanning
+
+import (
+	"slices"
+	"time"
+
+	"github.com/bvboe/b2s-go/scanner-core/containers"
+)
+
+// Priority orders image scan jobs. The worker picks the highest priority job;
+// jobs of the same priority run in the order they were enqueued.
+type Priority int
+
+const (
+	// PriorityRescan is for rescans, retries and registry crawl scans
+	PriorityRescan Priority = iota
+	// PriorityNormal is for first scans of images
+	PriorityNormal
+	// PriorityNamespace is for first scans of images running in one of
+	// QueueConfig.PriorityNamespaces
+	PriorityNamespace
+	// PriorityManual is for scans requested through ScanNow
+	PriorityManual
+)
+
+// String returns the name of the priority shown in the queue contents
+func (p Priority) String() string {
+	switch p {
+	case PriorityRescan:
+		return "rescan"
+	case PriorityNormal:
+		return "normal"
+	case PriorityNamespace:
+		return "namespace"
+	case PriorityManual:
+		return "manual"
+	}
+	return "unknown"
+}
+
+// priorityOf works out the priority of a job about to be enqueued. Looking up
+// the namespaces running the image queries the database, so it must be called
+// without jobsMu held.
+func (q *JobQueue) priorityOf(job ScanJob) Priority {
+	switch {
+	case job.Manual:
+		return PriorityManual
+	case !job.urgent():
+		return PriorityRescan
+	case job.fromRegistry() || len(q.config.PriorityNamespaces) == 0 || q.db == nil:
+		return PriorityNormal
+	}
+
+	namespaces, err := q.db.GetImageNamespaces(job.Image.Digest)
+	if err != nil {
+		log.Warn("failed to look up image namespaces for scan priority", "digest", job.Image.Digest, "error", err)
+		return PriorityNormal
+	}
+	for _, ns := range namespaces {
+		if slices.Contains(q.config.PriorityNamespaces, ns) {
+			return PriorityNamespace
+		}
+	}
+	return PriorityNormal
+}
+
+// highestPriorityLocked returns the index of the first image job due at now
+// with the highest priority, skipping jobs on paused nodes and considering only urgent jobs if urgentOnly is
+// set, or -1. Must be called with jobsMu held.
+func (q *JobQueue) highestPriorityLocked(now time.Time, urgentOnly bool) int {
+	best := -1
+	for i, job := range q.jobs {
+		if (urgentOnly && !job.urgent()) || !job.due(now) || q.heldLocked(job) {
+			continue
+		}
+		if best < 0 || job.priority > q.jobs[best].priority {
+			best = i
+		}
+	}
+	return best
+}
+
+// ScanNow moves images to the front of the queue. Images already queued are
+// raised to PriorityManual; other images are rescanned from a node running
+// them. Returns the digests that were queued and those of images not running
+// anywhere (on-demand scans of those go through the ad-hoc scanner).
+func (q *JobQueue) ScanNow(digests []string) (queued, notRunning []string) {
+	queued, notRunning = []string{}, []string{}
+	for _, digest := range digests {
+		if q.raiseToManual(digest) {
+			queued = append(queued, digest)
+			continue
+		}
+		instance, err := q.db.GetFirstContainerForImage(digest)
+		if err != nil {
+			notRunning = append(notRunning, digest)
+			continue
+		}
+		q.Enqueue(ScanJob{
+			Image:            containers.ImageID{Reference: instance.Reference, Digest: digest},
+			NodeName:         instance.NodeName,
+			ContainerRuntime: instance.ContainerRuntime,
+			ForceScan:        true,
+			Manual:           true,
+		})
+		queued = append(queued, digest)
+	}
+
+	log.Info("scan now requested", "queued", len(queued), "not_running", len(notRunning))
+	return queued, notRunning
+}
+
+// raiseToManual gives queued jobs of digest manual priority; retries no
+// longer wait. Reports whether the image was queued.
+func (q *JobQueue) raiseToManual(digest string) bool {
+	q.jobsMu.Lock()
+	var raised []int64
+	for i := range q.jobs {
+		if q.jobs[i].Image.Digest == digest {
+			q.jobs[i].Manual = true
+			q.jobs[i].RetryAt = time.Time{}
+			q.jobs[i].priority = PriorityManual
+			raised = append(raised, q.jobs[i].queueID)
+		}
+	}
+	q.jobsMu.Unlock()
+
+	// Recovered after a restart with their raised priority
+	for _, id := range raised {
+		if id == 0 {
+			continue
+		}
+		if err := q.db.SetQueuedScanManual(id, int(PriorityManual)); err != nil {
+			log.Warn("failed to update queued scan", "id", id, "error", err)
+		}
+	}
+	return len(raised) > 0
+}
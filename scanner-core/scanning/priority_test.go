@@ -0,0 +1,125 @@
+package scanning
+
+import (
+	"context"
+	"path/filepath"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bvboe/b2s-go/scanner-core/containers"
+	"github.com/bvboe/b2s-go/scanner-core/database"
+)
+
+// newQueueTestDB returns a database with a "web" image running in the
+// production namespace and a "tool" image running in dev
+func newQueueTestDB(t *testing.T) *database.DB {
+	t.Helper()
+	db, err := database.New(filepath.Join(t.TempDir(), "priority.db"))
+	if err != nil {
+		t.Fatalf("Failed to create database: %v", err)
+	}
+	t.Cleanup(func() { _ = database.Close(db) })
+
+	for _, c := range []containers.Container{
+		{ID: containers.ContainerID{Namespace: "production", Pod: "web-1", Name: "web"},
+			Image: containers.ImageID{Reference: "web:2", Digest: "sha256:web"}, NodeName: "node-1", ContainerRuntime: "containerd"},
+		{ID: containers.ContainerID{Namespace: "dev", Pod: "tool-1", Name: "tool"},
+			Image: containers.ImageID{Reference: "tool:1", Digest: "sha256:tool"}, NodeName: "node-2", ContainerRuntime: "containerd"},
+	} {
+		if _, err := db.AddContainer(c); err != nil {
+			t.Fatalf("Failed to add container: %v", err)
+		}
+	}
+	return db
+}
+
+// newIdleQueue returns a queue without a worker, so queued jobs stay put
+func newIdleQueue(db *database.DB, cfg QueueConfig) *JobQueue {
+	q := &JobQueue{db: db, ctx: context.Background(), config: cfg, now: time.Now}
+	q.jobsAvailable = sync.NewCond(&q.jobsMu)
+	return q
+}
+
+// order returns the digests of the image jobs in the order the worker runs them
+func order(q *JobQueue) []string {
+	var digests []string
+	for len(q.jobs) > 0 {
+		i, _, _ := q.nextJobLocked(q.now())
+		digests = append(digests, q.jobs[i].Image.Digest)
+		q.jobs = removeAt(q.jobs, i)
+	}
+	return digests
+}
+
+func TestPriorityOrder(t *testing.T) {
+	q := newIdleQueue(newQueueTestDB(t), QueueConfig{PriorityNamespaces: []string{"production"}})
+
+	q.Enqueue(ScanJob{Image: containers.ImageID{Digest: "sha256:old"}, ForceScan: true})
+	q.Enqueue(ScanJob{Image: containers.ImageID{Digest: "sha256:tool"}})
+	q.Enqueue(ScanJob{Image: containers.ImageID{Digest: "sha256:crawled"}, Registry: true})
+	q.Enqueue(ScanJob{Image: containers.ImageID{Digest: "sha256:web"}})
+	q.Enqueue(ScanJob{Image: containers.ImageID{Digest: "sha256:other"}})
+
+	contents := q.GetQueueContents()
+	if contents.Jobs[0].Digest != "sha256:web" || contents.Jobs[0].Priority != "namespace" {
+		t.Errorf("first queued job = %+v, want sha256:web with namespace priority", contents.Jobs[0])
+	}
+
+	want := []string{"sha256:web", "sha256:tool", "sha256:other", "sha256:old", "sha256:crawled"}
+	if got := order(q); !slices.Equal(got, want) {
+		t.Errorf("scan order = %v, want %v", got, want)
+	}
+}
+
+func TestPriorityWithoutNamespaces(t *testing.T) {
+	q := newIdleQueue(newQueueTestDB(t), QueueConfig{})
+	q.Enqueue(ScanJob{Image: containers.ImageID{Digest: "sha256:tool"}})
+	q.Enqueue(ScanJob{Image: containers.ImageID{Digest: "sha256:web"}})
+	if got, want := order(q), []string{"sha256:tool", "sha256:web"}; !slices.Equal(got, want) {
+		t.Errorf("scan order = %v, want FIFO %v", got, want)
+	}
+}
+
+func TestScanNow(t *testing.T) {
+	q := newIdleQueue(newQueueTestDB(t), QueueConfig{})
+	q.Enqueue(ScanJob{Image: containers.ImageID{Digest: "sha256:new"}})
+	q.Enqueue(ScanJob{Image: containers.ImageID{Digest: "sha256:tool"}, ForceScan: true})
+
+	queued, notRunning := q.ScanNow([]string{"sha256:web", "sha256:tool", "sha256:unknown"})
+	if !slices.Equal(queued, []string{"sha256:web", "sha256:tool"}) || !slices.Equal(notRunning, []string{"sha256:unknown"}) {
+		t.Errorf("ScanNow() = %v, %v", queued, notRunning)
+	}
+
+	// The queued rescan is raised rather than queued twice
+	if len(q.jobs) != 3 {
+		t.Fatalf("queue has %d jobs, want 3", len(q.jobs))
+	}
+	web := q.jobs[2]
+	if !web.Manual || !web.ForceScan || web.NodeName != "node-1" || web.Image.Reference != "web:2" {
+		t.Errorf("unexpected scan now job %+v", web)
+	}
+
+	want := []string{"sha256:tool", "sha256:web", "sha256:new"}
+	if got := order(q); !slices.Equal(got, want) {
+		t.Errorf("scan order = %v, want %v", got, want)
+	}
+}
+
+func TestPriorityQuietHours(t *testing.T) {
+	always, err := NewQuietHours("00:00-24:00", "", QuietPause, 0)
+	if err != nil {
+		t.Fatalf("NewQuietHours() error = %v", err)
+	}
+	q := newIdleQueue(newQueueTestDB(t), QueueConfig{})
+	q.quietHours = always
+	q.Enqueue(ScanJob{Image: containers.ImageID{Digest: "sha256:old"}, ForceScan: true})
+	q.Enqueue(ScanJob{Image: containers.ImageID{Digest: "sha256:tool"}})
+	q.ScanNow([]string{"sha256:old"})
+
+	// Manual rescans are not held back by quiet hours
+	if image, _, _ := q.nextJobLocked(q.now()); image != 0 {
+		t.Errorf("nextJobLocked() = %d, want the manual rescan", image)
+	}
+}
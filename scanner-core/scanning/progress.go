@@ -0,0 +1,172 @@
+package scanning
+
+import (
+	"sync"
+	"time"
+)
+
+// The worker reports the job it is processing and the phase it is in, and
+// keeps the durations of recent scans, so the queue status can answer "is the
+// initial scan still running and when will it be done?" without debug logs.
+
+// Scan phases of the job in flight
+const (
+	PhaseStarting = "starting" // checking status and caches
+	PhaseSBOM     = "sbom"     // retrieving the SBOM
+	PhaseVuln     = "vuln"     // scanning for vulnerabilities
+)
+
+// recentScans is how many completed scans the average duration covers
+const recentScans = 50
+
+// InFlightScan is a job the worker is processing
+type InFlightScan struct {
+	Type           string    `json:"type"`             // "image" or "host"
+	Image          string    `json:"image,omitempty"`  // Image reference (for image jobs)
+	Digest         string    `json:"digest,omitempty"` // Image digest (for image jobs)
+	NodeName       string    `json:"node_name,omitempty"`
+	Phase          string    `json:"phase"`            // PhaseStarting, PhaseSBOM or PhaseVuln
+	StartedAt      time.Time `json:"started_at"`       // When the worker picked the job
+	PhaseStartedAt time.Time `json:"phase_started_at"` // When the current phase started
+	ElapsedSeconds float64   `json:"elapsed_seconds"`  // Time since StartedAt
+	outcome        string    // OutcomeSuccess, OutcomeCached or OutcomeFailure once known
+}
+
+// QueueStatus summarizes the queue and the progress of the worker
+type QueueStatus struct {
+	Depth          int            `json:"depth"`           // Queued jobs, not counting those in flight
+	ImageJobs      int            `json:"image_jobs"`      // Queued image scans
+	HostJobs       int            `json:"host_jobs"`       // Queued host scans
+	RetryingJobs   int            `json:"retrying_jobs"`   // Queued image scans waiting for their retry time
+	InFlight       []InFlightScan `json:"in_flight"`       // Jobs being processed
+	TotalProcessed int64          `json:"total_processed"` // Jobs processed since startup
+	QuietHours     bool           `json:"quiet_hours"`     // Non-urgent jobs are paused or throttled
+	// AverageScanSeconds is the mean duration of the recent scans that
+	// retrieved an SBOM or scanned for vulnerabilities (0 before the first)
+	AverageScanSeconds float64 `json:"average_scan_seconds"`
+	// EstimatedDrainSeconds is roughly how long until the queue is empty at
+	// that pace (ignoring quiet hours and jobs that turn out to need no scan)
+	EstimatedDrainSeconds float64 `json:"estimated_drain_seconds"`
+	// LastBulkRescan is the last rescan-all or vulnerability database update
+	// rescan with its estimate (nil before the first)
+	LastBulkRescan *BulkRescan `json:"last_bulk_rescan,omitempty"`
+}
+
+// queueProgress tracks the job in flight and recent scan durations
+type queueProgress struct {
+	mu        sync.Mutex
+	current   *InFlightScan
+	durations []time.Duration // ring of the last recentScans durations
+	next      int             // ring position of the next duration
+
+	bulkRescan *BulkRescan // the last bulk rescan, see BeginBulkRescan
+}
+
+// startScan records that the worker picked scan
+func (q *JobQueue) startScan(scan InFlightScan) {
+	now := q.now()
+	scan.Phase, scan.StartedAt, scan.PhaseStartedAt = PhaseStarting, now, now
+	q.progress.mu.Lock()
+	q.progress.current = &scan
+	q.progress.mu.Unlock()
+}
+
+// setPhase records that the job in flight entered phase, and how long it
+// spent in the previous one
+func (q *JobQueue) setPhase(phase string) {
+	q.progress.mu.Lock()
+	defer q.progress.mu.Unlock()
+	if current := q.progress.current; current != nil {
+		now := q.now()
+		q.stats.observePhase(current.Type, current.Phase, now.Sub(current.PhaseStartedAt).Seconds())
+		current.Phase = phase
+		current.PhaseStartedAt = now
+	}
+}
+
+// finishScan records that the job in flight is done, with the duration of its
+// last phase and its outcome. Jobs that never got past PhaseStarting (nothing
+// to scan) don't count towards the average duration.
+func (q *JobQueue) finishScan() {
+	q.progress.mu.Lock()
+	defer q.progress.mu.Unlock()
+	p := &q.progress
+	if p.current == nil {
+		return
+	}
+	now := q.now()
+	q.stats.observePhase(p.current.Type, p.current.Phase, now.Sub(p.current.PhaseStartedAt).Seconds())
+	if p.current.outcome != "" {
+		q.stats.countOutcome(p.current.Type, p.current.outcome)
+	}
+	if p.current.Phase != PhaseStarting {
+		d := now.Sub(p.current.StartedAt)
+		if len(p.durations) < recentScans {
+			p.durations = append(p.durations, d)
+		} else {
+			p.durations[p.next] = d
+		}
+		p.next = (p.next + 1) % recentScans
+	}
+	p.current = nil
+}
+
+// averageScanLocked returns the mean of the recent scan durations. Must be
+// called with progress.mu held.
+func (p *queueProgress) averageScanLocked() time.Duration {
+	if len(p.durations) == 0 {
+		return 0
+	}
+	var total time.Duration
+	for _, d := range p.durations {
+		total += d
+	}
+	return total / time.Duration(len(p.durations))
+}
+
+// Status returns the queue depth, the job in flight with its phase, the
+// average scan duration and the estimated time until the queue is drained
+func (q *JobQueue) Status() QueueStatus {
+	now := q.now()
+
+	q.jobsMu.Lock()
+	status := QueueStatus{
+		Depth:      len(q.jobs) + len(q.hostJobs),
+		ImageJobs:  len(q.jobs),
+		HostJobs:   len(q.hostJobs),
+		QuietHours: q.quietHours.Active(now),
+		InFlight:   []InFlightScan{},
+	}
+	for _, job := range q.jobs {
+		if !job.due(now) {
+			status.RetryingJobs++
+		}
+	}
+	q.jobsMu.Unlock()
+
+	q.metrics.mu.RLock()
+	status.TotalProcessed = q.metrics.totalProcessed
+	q.metrics.mu.RUnlock()
+
+	q.progress.mu.Lock()
+	defer q.progress.mu.Unlock()
+	avg := q.progress.averageScanLocked()
+	status.AverageScanSeconds = avg.Seconds()
+
+	remaining := avg * time.Duration(status.Depth)
+	if current := q.progress.current; current != nil {
+		scan := *current
+		elapsed := now.Sub(scan.StartedAt)
+		scan.ElapsedSeconds = elapsed.Seconds()
+		status.InFlight = append(status.InFlight, scan)
+		if elapsed < avg {
+			remaining += avg - elapsed
+		}
+	}
+	status.EstimatedDrainSeconds = remaining.Seconds()
+	if bulk := q.progress.bulkRescan; bulk != nil {
+		copied := *bulk
+		status.LastBulkRescan = &copied
+	}
+	return status
+}
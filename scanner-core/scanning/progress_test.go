@@ -0,0 +1,54 @@
+package scanning
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bvboe/b2s-go/scanner-core/containers"
+)
+
+func TestQueueStatus(t *testing.T) {
+	q := newIdleQueue(nil, QueueConfig{})
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	q.now = func() time.Time { return now }
+
+	if status := q.Status(); status.Depth != 0 || len(status.InFlight) != 0 || status.EstimatedDrainSeconds != 0 {
+		t.Errorf("idle Status() = %+v", status)
+	}
+
+	// Two scans of 10s and 30s; a job with nothing to scan doesn't count
+	for _, d := range []time.Duration{10 * time.Second, 30 * time.Second} {
+		q.startScan(InFlightScan{Type: "image", Digest: "sha256:done"})
+		q.setPhase(PhaseSBOM)
+		now = now.Add(d)
+		q.finishScan()
+	}
+	q.startScan(InFlightScan{Type: "image", Digest: "sha256:skipped"})
+	now = now.Add(time.Minute)
+	q.finishScan()
+
+	q.jobs = []ScanJob{
+		{Image: containers.ImageID{Digest: "sha256:a"}},
+		{Image: containers.ImageID{Digest: "sha256:b"}, RetryAt: now.Add(time.Hour)},
+	}
+	q.hostJobs = []HostScanJob{{NodeName: "node-1"}}
+	q.startScan(InFlightScan{Type: "image", Image: "nginx:1.21", Digest: "sha256:current"})
+	now = now.Add(5 * time.Second)
+	q.setPhase(PhaseVuln)
+
+	status := q.Status()
+	if status.Depth != 3 || status.ImageJobs != 2 || status.HostJobs != 1 || status.RetryingJobs != 1 {
+		t.Errorf("Status() counts = %+v", status)
+	}
+	if status.AverageScanSeconds != 20 {
+		t.Errorf("AverageScanSeconds = %v, want 20", status.AverageScanSeconds)
+	}
+	// Three queued jobs at 20s each plus 15s left of the current one
+	if status.EstimatedDrainSeconds != 75 {
+		t.Errorf("EstimatedDrainSeconds = %v, want 75", status.EstimatedDrainSeconds)
+	}
+	if len(status.InFlight) != 1 || status.InFlight[0].Digest != "sha256:current" ||
+		status.InFlight[0].Phase != PhaseVuln || status.InFlight[0].ElapsedSeconds != 5 {
+		t.Errorf("InFlight = %+v", status.InFlight)
+	}
+}
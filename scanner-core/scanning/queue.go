@@ -2,7 +2,9 @@ package scanning
 
 import (
 	"context"
+	"fmt"
 	"log/slog"
+	"slices"
 	"sync"
 	"time"
 
@@ -10,6 +12,7 @@ import (
 	"github.com/bvboe/b2s-go/scanner-core/database"
 	"github.com/bvboe/b2s-go/scanner-core/grype"
 	"github.com/bvboe/b2s-go/scanner-core/logging"
+	"github.com/bvboe/b2s-go/scanner-core/transfer"
 )
 
 var (
@@ -21,9 +24,36 @@ var (
 // ScanJob represents a request to scan a container image
 type ScanJob struct {
 	Image            containers.ImageID
-	NodeName         string // K8s node name where image is located (empty for agent)
-	ContainerRuntime string // "docker" or "containerd"
-	ForceScan        bool   // If true, rescan even if SBOM already exists
+	NodeName         string    // K8s node name where image is located (empty for agent)
+	ContainerRuntime string    // "docker" or "containerd"
+	ForceScan        bool      // If true, rescan even if SBOM already exists
+	FullRescan       bool      // If true, retrieve a fresh SBOM instead of rescanning the stored one
+	AdHoc            bool      // If true, the image was requested on demand and is pulled from its registry
+	Registry         bool      // If true, the image was found by the registry crawl and is pulled from its registry
+	Manual           bool      // If true, the scan was requested through ScanNow and jumps the queue
+	RetryAt          time.Time // If set, an automatic retry of a failed scan that waits in the queue until then
+
+	priority Priority // Set by Enqueue
+	queueID  int64    // Row in the persisted queue (0 if not persisted)
+}
+
+// urgent reports whether the job scans an image for the first time or was
+// requested on demand. Rescans and registry crawl scans are deferred during
+// quiet hours.
+func (j ScanJob) urgent() bool {
+	return (!j.ForceScan && !j.Registry) || j.AdHoc || j.Manual
+}
+
+// sameScan reports whether other requests the same scan as j
+func (j ScanJob) sameScan(other ScanJob) bool {
+	return j.Image.Digest == other.Image.Digest && j.NodeName == other.NodeName &&
+		j.ForceScan == other.ForceScan && j.FullRescan == other.FullRescan && j.AdHoc == other.AdHoc && j.Registry == other.Registry
+}
+
+// fromRegistry reports whether the image is pulled from its registry rather
+// than read from a node
+func (j ScanJob) fromRegistry() bool {
+	return j.AdHoc || j.Registry
 }
 
 // HostScanJob represents a request to scan a node's host filesystem
@@ -31,6 +61,13 @@ type HostScanJob struct {
 	NodeName   string // K8s node name to scan
 	ForceScan  bool   // If true, rescan vulns using existing SBOM (skip SBOM regeneration)
 	FullRescan bool   // If true, always regenerate SBOM (node packages may have changed)
+
+	queueID int64 // Row in the persisted queue (0 if not persisted)
+}
+
+// urgent reports whether the job scans a node for the first time
+func (j HostScanJob) urgent() bool {
+	return !j.ForceScan && !j.FullRescan
 }
 
 // SBOMRetriever is a callback function that retrieves an SBOM for an image
@@ -43,12 +80,32 @@ type SBOMRetriever func(ctx context.Context, image containers.ImageID, nodeName
 // Returns the SBOM as JSON bytes, or an error
 type HostSBOMRetriever func(ctx context.Context, nodeName string) ([]byte, error)
 
+// RegistrySBOMRetriever is a callback function that retrieves an SBOM for an
+// image by pulling it from its registry, for images not running anywhere
+// The implementation is provided by the caller (agent or k8s-scan-server)
+// Returns the SBOM as JSON bytes, or an error
+type RegistrySBOMRetriever func(ctx context.Context, image containers.ImageID) ([]byte, error)
+
+// VendorSBOMRetriever is a callback function that retrieves the SBOM a vendor
+// attested for an image, verified, instead of generating one
+// The implementation is provided by the caller (provenance.VendorSBOMs)
+// Returns the SBOM as JSON bytes and the key that signed it, or a nil SBOM if
+// the image has no vendor SBOM
+type VendorSBOMRetriever func(ctx context.Context, image containers.ImageID) ([]byte, string, error)
+
 // DBReadinessChecker allows the queue to wait for the vulnerability database to be ready
 // This interface is implemented by handlers.DatabaseReadinessState
 type DBReadinessChecker interface {
 	WaitForReady(ctx context.Context) bool
 }
 
+// ResultCache is a shared store of scan results keyed by image digest and grype DB build.
+// This interface is implemented by resultcache.Cache
+type ResultCache interface {
+	Lookup(ctx context.Context, digest string, grypeDBBuilt time.Time) *transfer.Bundle
+	Store(ctx context.Context, digest string, grypeDBBuilt time.Time, sbomJSON, vulnJSON []byte) error
+}
+
 // QueueFullBehavior defines what happens when the queue reaches max depth
 type QueueFullBehavior int
 
@@ -67,6 +124,17 @@ type QueueConfig struct {
 	MaxDepth int
 	// FullBehavior defines what happens when queue is full
 	FullBehavior QueueFullBehavior
+	// MaxAttempts is the number of consecutive failed scans after which an image
+	// is dead-lettered and skipped until requeued (0 = retry forever)
+	MaxAttempts int
+	// RetryBackoff is the delay before a failed scan is retried automatically,
+	// doubled after every further consecutive failure (0 = no automatic retries)
+	RetryBackoff time.Duration
+	// RetryMaxBackoff caps the retry delay (0 = uncapped)
+	RetryMaxBackoff time.Duration
+	// PriorityNamespaces are scanned first: first scans of images running in
+	// these namespaces go ahead of other queued scans (see Priority)
+	PriorityNamespaces []string
 }
 
 // QueueMetrics tracks queue statistics
@@ -87,6 +155,8 @@ type JobQueue struct {
 	jobsAvailable     *sync.Cond
 	sbomRetriever     SBOMRetriever
 	hostSBOMRetriever HostSBOMRetriever
+	registryRetriever RegistrySBOMRetriever
+	vendorRetriever   VendorSBOMRetriever
 	db                *database.DB
 	ctx               context.Context
 	cancel            context.CancelFunc
@@ -94,7 +164,18 @@ type JobQueue struct {
 	grypeCfg          grype.Config
 	config            QueueConfig
 	metrics           QueueMetrics
+	progress          queueProgress
+	stats             scanStats
 	dbReadinessState  DBReadinessChecker // Allows waiting for grype DB to be ready
+	resultCache       ResultCache        // Optional shared cache checked before scanning
+	hooks             map[HookStage][]Hook
+	hooksMu           sync.RWMutex
+	grypeDBBuilt      func() (time.Time, error)
+	quietHours        *QuietHours          // Optional; non-urgent scans are paused or throttled inside its windows
+	lastDeferrable    time.Time            // When the last non-urgent job started during quiet hours
+	quietTimer        *time.Timer          // Wakes the worker when a deferred job may run
+	pausedNodes       map[string]nodePause // Nodes whose jobs are held back (see PauseNode)
+	now               func() time.Time
 }
 
 // NewJobQueue creates a new job queue with the specified SBOM retriever and configuration
@@ -111,17 +192,24 @@ func NewJobQueue(db *database.DB, sbomRetriever SBOMRetriever, grypeCfg grype.Co
 		cancel:        cancel,
 		grypeCfg:      grypeCfg,
 		config:        queueCfg,
+		now:           time.Now,
 	}
+	queue.grypeDBBuilt = func() (time.Time, error) { return grype.CurrentDBBuilt(queue.grypeCfg) }
 	queue.jobsAvailable = sync.NewCond(&queue.jobsMu)
 
+	// Pick up the jobs queued before a restart
+	if db != nil {
+		queue.recoverPersisted()
+	}
+
 	// Start the worker goroutine
 	queue.wg.Add(1)
 	go queue.worker()
 
 	if queueCfg.MaxDepth > 0 {
-		log.Info("scan job queue initialized", "max_depth", queueCfg.MaxDepth, "behavior", queueCfg.FullBehavior)
+		log.Info("scan job queue initialized", "max_depth", queueCfg.MaxDepth, "behavior", queueCfg.FullBehavior, "max_attempts", queueCfg.MaxAttempts)
 	} else {
-		log.Info("scan job queue initialized", "max_depth", "unbounded", "workers", 1)
+		log.Info("scan job queue initialized", "max_depth", "unbounded", "workers", 1, "max_attempts", queueCfg.MaxAttempts)
 	}
 	return queue
 }
@@ -133,12 +221,28 @@ func NewJobQueueWithDefaults(db *database.DB, sbomRetriever SBOMRetriever) *JobQ
 	return NewJobQueue(db, sbomRetriever, grype.Config{}, QueueConfig{MaxDepth: 0, FullBehavior: QueueFullDrop})
 }
 
+// SetQuietHours configures quiet hours, during which non-urgent scans are
+// paused or throttled. Scans of new images and nodes are not affected.
+func (q *JobQueue) SetQuietHours(qh *QuietHours) {
+	q.jobsMu.Lock()
+	defer q.jobsMu.Unlock()
+	q.quietHours = qh
+	q.jobsAvailable.Broadcast()
+}
+
 // SetDBReadinessChecker sets the database readiness checker for the queue
 // When set, the queue will wait for the grype database to be ready before processing vulnerability scans
 func (q *JobQueue) SetDBReadinessChecker(checker DBReadinessChecker) {
 	q.dbReadinessState = checker
 }
 
+// SetResultCache sets the shared result cache for the queue
+// When set, the queue imports cached results instead of scanning and writes new results through to the cache
+func (q *JobQueue) SetResultCache(cache ResultCache) {
+	q.resultCache = cache
+	log.Info("result cache configured")
+}
+
 // SetHostSBOMRetriever sets the callback function for retrieving host SBOMs
 // This must be set before host scan jobs can be processed
 func (q *JobQueue) SetHostSBOMRetriever(retriever HostSBOMRetriever) {
@@ -146,8 +250,32 @@ func (q *JobQueue) SetHostSBOMRetriever(retriever HostSBOMRetriever) {
 	log.Info("host SBOM retriever configured")
 }
 
-// Enqueue adds a scan job to the queue with respect to max depth and full behavior
+// SetRegistrySBOMRetriever sets the callback function for retrieving SBOMs of
+// images from their registry. This must be set before ad-hoc jobs can be processed
+func (q *JobQueue) SetRegistrySBOMRetriever(retriever RegistrySBOMRetriever) {
+	q.registryRetriever = retriever
+	log.Info("registry SBOM retriever configured")
+}
+
+// SetVendorSBOMRetriever sets the callback function for retrieving vendor
+// attested SBOMs. Images it has a verified SBOM for are not scanned on a node;
+// when it fails or has none, the SBOM is generated as usual
+func (q *JobQueue) SetVendorSBOMRetriever(retriever VendorSBOMRetriever) {
+	q.vendorRetriever = retriever
+	log.Info("vendor SBOM retriever configured")
+}
+
+// Enqueue adds a scan job to the queue with respect to max depth and full behavior.
+// A job identical to one already queued is skipped.
 func (q *JobQueue) Enqueue(job ScanJob) {
+	q.routeAroundPause(&job)
+	job.priority = q.priorityOf(job)
+	job.queueID = q.persistJob(job)
+
+	// Persisted jobs dropped from the queue are removed once the lock is released
+	// (jobs enqueued during shutdown stay persisted for the next start)
+	var dropped []int64
+	defer func() { q.forgetJobs(dropped) }()
 
 	q.jobsMu.Lock()
 	defer q.jobsMu.Unlock()
@@ -160,6 +288,14 @@ func (q *JobQueue) Enqueue(job ScanJob) {
 	default:
 	}
 
+	for _, existing := range q.jobs {
+		if existing.sameScan(job) {
+			log.Debug("scan already in queue, skipping", "image", job.Image.Reference, "digest", job.Image.Digest)
+			dropped = append(dropped, job.queueID)
+			return
+		}
+	}
+
 	// Check if queue is at max depth
 	if q.config.MaxDepth > 0 && len(q.jobs) >= q.config.MaxDepth {
 		switch q.config.FullBehavior {
@@ -170,19 +306,29 @@ func (q *JobQueue) Enqueue(job ScanJob) {
 				"image", job.Image.Reference,
 				"digest", job.Image.Digest)
 			q.updateMetrics(0, 0, 1) // Increment dropped count
+			dropped = append(dropped, job.queueID)
 			return
 
 		case QueueFullDropOldest:
 			// Remove oldest job and add new one
-			dropped := q.jobs[0]
+			oldest := q.jobs[0]
 			q.jobs = q.jobs[1:]
 			log.Warn("queue full, dropping oldest job",
 				"depth", len(q.jobs)+1,
-				"dropped_image", dropped.Image.Reference,
+				"dropped_image", oldest.Image.Reference,
 				"new_image", job.Image.Reference)
 			q.updateMetrics(0, 0, 1) // Increment dropped count
+			dropped = append(dropped, oldest.queueID)
 
 		case QueueFullBlock:
+			// Retries are enqueued by the worker itself, which must never wait for space
+			if !job.RetryAt.IsZero() {
+				log.Warn("queue full, dropping scan retry", "image", job.Image.Reference, "digest", job.Image.Digest)
+				q.updateMetrics(0, 0, 1)
+				dropped = append(dropped, job.queueID)
+				return
+			}
+
 			// Block until space is available
 			// Release lock and wait for signal
 			for len(q.jobs) >= q.config.MaxDepth {
@@ -207,6 +353,7 @@ func (q *JobQueue) Enqueue(job ScanJob) {
 		"digest", job.Image.Digest,
 		"node", job.NodeName,
 		"runtime", job.ContainerRuntime,
+		"priority", job.priority,
 		"queue_depth", currentDepth)
 
 	// Update metrics
@@ -272,6 +419,12 @@ func (q *JobQueue) EnqueueHostFullRescan(nodeName string) {
 
 // enqueueHostJob adds a host scan job to the queue
 func (q *JobQueue) enqueueHostJob(job HostScanJob) {
+	job.queueID = q.persistHostJob(job)
+
+	// Persisted jobs dropped from the queue are removed once the lock is released
+	// (jobs enqueued during shutdown stay persisted for the next start)
+	var dropped []int64
+	defer func() { q.forgetJobs(dropped) }()
 
 	q.jobsMu.Lock()
 	defer q.jobsMu.Unlock()
@@ -288,6 +441,7 @@ func (q *JobQueue) enqueueHostJob(job HostScanJob) {
 	for _, existing := range q.hostJobs {
 		if existing.NodeName == job.NodeName {
 			log.Debug("host scan already in queue, skipping", "node", job.NodeName)
+			dropped = append(dropped, job.queueID)
 			return
 		}
 	}
@@ -299,23 +453,27 @@ func (q *JobQueue) enqueueHostJob(job HostScanJob) {
 		case QueueFullDrop:
 			q.updateMetrics(0, 0, 1)
 			log.Warn("queue full, dropping host scan job", "depth", totalJobs, "node", job.NodeName)
+			dropped = append(dropped, job.queueID)
 			return
 		case QueueFullDropOldest:
 			// For host jobs, drop oldest host job if possible, otherwise oldest regular job
 			if len(q.hostJobs) > 0 {
-				dropped := q.hostJobs[0]
+				oldest := q.hostJobs[0]
 				q.hostJobs = q.hostJobs[1:]
-				log.Warn("queue full, dropping oldest host scan job", "dropped_node", dropped.NodeName)
+				log.Warn("queue full, dropping oldest host scan job", "dropped_node", oldest.NodeName)
+				dropped = append(dropped, oldest.queueID)
 			} else if len(q.jobs) > 0 {
-				dropped := q.jobs[0]
+				oldest := q.jobs[0]
 				q.jobs = q.jobs[1:]
-				log.Warn("queue full, dropping oldest scan job", "dropped_image", dropped.Image.Reference)
+				log.Warn("queue full, dropping oldest scan job", "dropped_image", oldest.Image.Reference)
+				dropped = append(dropped, oldest.queueID)
 			}
 			q.updateMetrics(0, 0, 1)
 		case QueueFullBlock:
 			// Block until space available (simplified - just drop with warning)
 			q.updateMetrics(0, 0, 1)
 			log.Warn("queue full, dropping host scan job (blocking not implemented)", "depth", totalJobs, "node", job.NodeName)
+			dropped = append(dropped, job.queueID)
 			return
 		}
 	}
@@ -408,11 +566,24 @@ func (q *JobQueue) worker() {
 			}
 		}
 
+		imageIdx, hostIdx, wait := q.nextJobLocked(q.now())
+		if imageIdx < 0 && hostIdx < 0 {
+			// Only retries not yet due, or non-urgent jobs during quiet hours:
+			// sleep until one may run or a job arrives
+			q.wakeAfterLocked(wait)
+			q.jobsAvailable.Wait()
+			q.jobsMu.Unlock()
+			if q.ctx.Err() != nil {
+				log.Info("scan worker shutting down")
+				return
+			}
+			continue
+		}
+
 		// Process image scan jobs first (they're typically faster and more urgent)
-		if len(q.jobs) > 0 {
-			// Dequeue the first image scan job
-			job := q.jobs[0]
-			q.jobs = q.jobs[1:]
+		if imageIdx >= 0 {
+			job := q.jobs[imageIdx]
+			q.jobs = removeAt(q.jobs, imageIdx)
 			currentDepth := len(q.jobs) + len(q.hostJobs)
 
 			// Update current depth metric
@@ -424,11 +595,14 @@ func (q *JobQueue) worker() {
 			q.jobsMu.Unlock()
 
 			// Process the job outside the lock
+			q.setQueueState(job.queueID, database.QueueStateInProgress)
+			q.startScan(InFlightScan{Type: "image", Image: job.Image.Reference, Digest: job.Image.Digest, NodeName: job.NodeName})
 			q.processJob(job)
-		} else if len(q.hostJobs) > 0 {
-			// Dequeue the first host scan job
-			hostJob := q.hostJobs[0]
-			q.hostJobs = q.hostJobs[1:]
+			q.finishScan()
+			q.finishJob(job.queueID)
+		} else {
+			hostJob := q.hostJobs[hostIdx]
+			q.hostJobs = removeAt(q.hostJobs, hostIdx)
 			currentDepth := len(q.jobs) + len(q.hostJobs)
 
 			// Update current depth metric
@@ -440,10 +614,11 @@ func (q *JobQueue) worker() {
 			q.jobsMu.Unlock()
 
 			// Process the host scan job outside the lock
+			q.setQueueState(hostJob.queueID, database.QueueStateInProgress)
+			q.startScan(InFlightScan{Type: "host", NodeName: hostJob.NodeName})
 			q.processHostJob(hostJob)
-		} else {
-			q.jobsMu.Unlock()
-			continue
+			q.finishScan()
+			q.finishJob(hostJob.queueID)
 		}
 
 		// Update processed count
@@ -453,11 +628,100 @@ func (q *JobQueue) worker() {
 	}
 }
 
+// finishJob marks a processed job done. Jobs cut short by shutdown stay in
+// progress so they are retried after the restart.
+func (q *JobQueue) finishJob(queueID int64) {
+	if q.ctx.Err() != nil {
+		return
+	}
+	q.setQueueState(queueID, database.QueueStateDone)
+}
+
+// nextJobLocked picks the next job: the image job with the highest priority,
+// else the first host job. Retries wait until they are due. During quiet
+// hours urgent jobs go first and non-urgent ones are paused or throttled; when
+// nothing may run yet both indexes are -1 and wait is how long until a
+// deferred job may start (0 when only jobs on paused nodes remain). Picking a throttled job starts the next throttle
+// interval. Must be called with jobsMu held.
+func (q *JobQueue) nextJobLocked(now time.Time) (imageIdx, hostIdx int, wait time.Duration) {
+	first := func() (int, int, time.Duration) {
+		if i := q.highestPriorityLocked(now, false); i >= 0 {
+			return i, -1, 0
+		}
+		for i, job := range q.hostJobs {
+			if !q.nodePausedLocked(job.NodeName) {
+				return -1, i, 0
+			}
+		}
+		return -1, -1, q.nextRetryLocked(now)
+	}
+	if !q.quietHours.Active(now) {
+		return first()
+	}
+
+	if i := q.highestPriorityLocked(now, true); i >= 0 {
+		return i, -1, 0
+	}
+	for i, job := range q.hostJobs {
+		if job.urgent() && !q.nodePausedLocked(job.NodeName) {
+			return -1, i, 0
+		}
+	}
+
+	if q.quietHours.Mode == QuietPause {
+		return -1, -1, minWait(q.quietHours.Remaining(now), q.nextRetryLocked(now))
+	}
+	if next := q.lastDeferrable.Add(q.quietHours.Interval); now.Before(next) {
+		return -1, -1, minWait(next.Sub(now), q.nextRetryLocked(now))
+	}
+	imageIdx, hostIdx, wait = first()
+	if imageIdx >= 0 || hostIdx >= 0 {
+		q.lastDeferrable = now
+	}
+	return imageIdx, hostIdx, wait
+}
+
+// wakeAfterLocked wakes the worker after d so deferred jobs are re-evaluated.
+// Must be called with jobsMu held.
+func (q *JobQueue) wakeAfterLocked(d time.Duration) {
+	if q.quietTimer != nil {
+		q.quietTimer.Stop()
+	}
+	if d <= 0 {
+		return // Held jobs are released by ResumeNode
+	}
+	q.quietTimer = time.AfterFunc(d, func() {
+		q.jobsMu.Lock()
+		defer q.jobsMu.Unlock()
+		q.jobsAvailable.Broadcast()
+	})
+}
+
+// removeAt removes element i; the head of the queue is dropped without copying
+func removeAt[T any](s []T, i int) []T {
+	if i == 0 {
+		return s[1:]
+	}
+	return slices.Delete(s, i, i+1)
+}
+
 // processJob handles a single scan job
 func (q *JobQueue) processJob(job ScanJob) {
 	log := log.With("image", job.Image.Reference, "digest", job.Image.Digest)
 
-	log.Info("processing scan job", "force_scan", job.ForceScan)
+	log.Info("processing scan job", "force_scan", job.ForceScan, "full_rescan", job.FullRescan, "ad_hoc", job.AdHoc, "registry", job.Registry, "priority", job.priority)
+
+	// Dead-lettered images are only scanned again once requeued (ad-hoc
+	// requests are explicit and always run)
+	if q.config.MaxAttempts > 0 && !job.AdHoc {
+		deadLettered, err := q.db.IsDeadLettered(job.Image.Digest)
+		if err != nil {
+			log.Error("error checking dead-letter state", slog.Any("error", err))
+		} else if deadLettered {
+			log.Debug("skipping dead-lettered image")
+			return
+		}
+	}
 
 	// Check if we already have scan results
 	status, err := q.db.GetImageStatus(job.Image.Digest)
@@ -465,12 +729,26 @@ func (q *JobQueue) processJob(job ScanJob) {
 		log.Error("error checking status", slog.Any("error", err))
 	}
 
+	// Results scanned elsewhere with the same grype DB short-circuit scanning entirely
+	// (not when a fresh SBOM was requested)
+	if (job.ForceScan || !status.HasVulnerabilities()) && !job.FullRescan && q.importFromResultCache(job) {
+		return
+	}
+
 	// If ForceScan is requested and SBOM already exists, skip directly to vulnerability scan
 	// This is used by the rescan-database job when the grype database is updated
-	if job.ForceScan && status.HasSBOM() {
-		log.Debug("force scan with existing SBOM, running vulnerability scan only")
-		q.processVulnerabilityScan(job, nil)
-		return
+	// SBOMs pruned to free disk space are retrieved again, as are those of full rescans
+	if job.ForceScan && status.HasSBOM() && !job.FullRescan {
+		stored, err := q.db.HasStoredSBOM(job.Image.Digest)
+		if err != nil {
+			log.Error("error checking stored SBOM", slog.Any("error", err))
+		}
+		if stored || err != nil {
+			log.Debug("force scan with existing SBOM, running vulnerability scan only")
+			q.processVulnerabilityScan(job, nil)
+			return
+		}
+		log.Info("stored SBOM was pruned, retrieving it again")
 	}
 
 	// Skip if SBOM already exists (unless force scan without SBOM)
@@ -484,6 +762,7 @@ func (q *JobQueue) processJob(job ScanJob) {
 	}
 
 	// Mark image as generating SBOM
+	q.setPhase(PhaseSBOM)
 	if err := q.db.UpdateStatus(job.Image.Digest, database.StatusGeneratingSBOM, ""); err != nil {
 		log.Error("error updating status", slog.Any("error", err))
 		return
@@ -492,8 +771,18 @@ func (q *JobQueue) processJob(job ScanJob) {
 	// Call the SBOM retriever callback
 	ctx, cancel := context.WithTimeout(q.ctx, 5*time.Minute)
 	defer cancel()
-
-	sbomJSON, err := q.sbomRetriever(ctx, job.Image, job.NodeName, job.ContainerRuntime)
+	sbomStart := time.Now()
+
+	sbomJSON, signer := q.retrieveVendorSBOM(ctx, job.Image)
+	switch {
+	case sbomJSON != nil:
+		log.Info("using vendor attested SBOM", "signer", signer)
+	case job.fromRegistry():
+		sbomJSON, err = q.retrieveRegistrySBOM(ctx, job.Image)
+	default:
+		sbomJSON, err = q.sbomRetriever(ctx, job.Image, job.NodeName, job.ContainerRuntime)
+	}
+	sbomDuration := time.Since(sbomStart)
 	if err != nil {
 		log.Error("error retrieving SBOM", slog.Any("error", err))
 
@@ -502,36 +791,79 @@ func (q *JobQueue) processJob(job ScanJob) {
 		// For now, assume all errors are failures
 		errorStatus := database.StatusSBOMFailed
 
-		if updateErr := q.db.UpdateStatus(job.Image.Digest, errorStatus, err.Error()); updateErr != nil {
-			log.Error("error updating status to failed", "status", errorStatus, slog.Any("error", updateErr))
-		}
+		q.markFailed(job, errorStatus, err.Error())
+		return
+	}
+
+	// Let post-SBOM hooks enrich or rewrite the SBOM before it is stored
+	event, err := q.runHooks(ctx, HookPostSBOM, job, sbomJSON, nil)
+	if err != nil {
+		log.Error("error running scan hooks", slog.Any("error", err))
+
+		q.markFailed(job, database.StatusSBOMFailed, err.Error())
 		return
 	}
+	sbomJSON = event.SBOM
+	q.stats.observeSBOMSize("image", len(sbomJSON))
 
 	// Store the SBOM in the database for caching (enables fast API access and offline serving)
 	// Note: This is the primary SBOM caching path. Direct API requests to k8s-scan-server
 	// that fetch SBOMs on-demand from pod-scanner do NOT cache (see handlers/sbom.go).
 	// StoreSBOM will automatically update status to StatusScanningVulnerabilities
-	if err := q.db.StoreSBOM(job.Image.Digest, sbomJSON); err != nil {
+	if signer != "" {
+		err = q.db.StoreVendorSBOM(job.Image.Digest, sbomJSON, signer)
+	} else {
+		err = q.db.StoreSBOM(job.Image.Digest, sbomJSON)
+	}
+	if err != nil {
 		log.Error("error storing SBOM", slog.Any("error", err))
 
-		if updateErr := q.db.UpdateStatus(job.Image.Digest, database.StatusSBOMFailed, err.Error()); updateErr != nil {
-			log.Error("error updating status to failed", slog.Any("error", updateErr))
-		}
+		q.markFailed(job, database.StatusSBOMFailed, err.Error())
 		return
 	}
 
+	if err := q.db.RecordScanDurations(job.Image.Digest, sbomDuration, 0); err != nil {
+		log.Warn("error recording SBOM duration", slog.Any("error", err))
+	}
+
 	log.Info("successfully scanned and stored SBOM")
 
 	// Now scan for vulnerabilities
 	q.processVulnerabilityScan(job, sbomJSON)
 }
 
+// retrieveRegistrySBOM retrieves the SBOM of an ad-hoc image from its registry
+func (q *JobQueue) retrieveRegistrySBOM(ctx context.Context, image containers.ImageID) ([]byte, error) {
+	if q.registryRetriever == nil {
+		return nil, fmt.Errorf("registry SBOM retriever not configured")
+	}
+	return q.registryRetriever(ctx, image)
+}
+
+// retrieveVendorSBOM returns the verified vendor SBOM of an image and its
+// signer, or nil if there is none. Failures are logged and fall back to
+// generating the SBOM.
+func (q *JobQueue) retrieveVendorSBOM(ctx context.Context, image containers.ImageID) ([]byte, string) {
+	if q.vendorRetriever == nil {
+		return nil, ""
+	}
+	sbomJSON, signer, err := q.vendorRetriever(ctx, image)
+	if err != nil {
+		log.Warn("vendor SBOM not used, generating the SBOM", "image", image.Reference, "digest", image.Digest, "error", err)
+		return nil, ""
+	}
+	if sbomJSON == nil {
+		return nil, ""
+	}
+	return sbomJSON, signer
+}
+
 // processVulnerabilityScan scans an SBOM for vulnerabilities
 func (q *JobQueue) processVulnerabilityScan(job ScanJob, sbomJSON []byte) {
 	log := grypeLog.With("image", job.Image.Reference, "digest", job.Image.Digest)
 
 	log.Info("starting vulnerability scan")
+	q.setPhase(PhaseVuln)
 
 	// Wait for grype DB to be ready before scanning
 	if q.dbReadinessState != nil {
@@ -560,9 +892,7 @@ func (q *JobQueue) processVulnerabilityScan(job ScanJob, sbomJSON []byte) {
 		sbomJSON, err = q.db.GetSBOM(job.Image.Digest)
 		if err != nil {
 			log.Error("error retrieving SBOM from database", slog.Any("error", err))
-			if updateErr := q.db.UpdateStatus(job.Image.Digest, database.StatusVulnScanFailed, "SBOM not available: "+err.Error()); updateErr != nil {
-				log.Error("error updating status to failed", slog.Any("error", updateErr))
-			}
+			q.markFailed(job, database.StatusVulnScanFailed, "SBOM not available: "+err.Error())
 			return
 		}
 	}
@@ -571,28 +901,184 @@ func (q *JobQueue) processVulnerabilityScan(job ScanJob, sbomJSON []byte) {
 	ctx, cancel := context.WithTimeout(q.ctx, 5*time.Minute)
 	defer cancel()
 
+	scanStart := time.Now()
 	scanResult, err := grype.ScanVulnerabilitiesWithConfig(ctx, sbomJSON, q.grypeCfg)
 	if err != nil {
 		log.Error("error scanning vulnerabilities", slog.Any("error", err))
 
-		if updateErr := q.db.UpdateStatus(job.Image.Digest, database.StatusVulnScanFailed, err.Error()); updateErr != nil {
-			log.Error("error updating status to failed", slog.Any("error", updateErr))
-		}
+		q.markFailed(job, database.StatusVulnScanFailed, err.Error())
 		return
 	}
+	scanDuration := time.Since(scanStart)
+
+	// Run post-scan and pre-persist hooks, which may enrich the vulnerability report
+	vulnJSON := scanResult.VulnerabilityJSON
+	for _, stage := range []HookStage{HookPostVulnScan, HookPrePersist} {
+		event, err := q.runHooks(ctx, stage, job, sbomJSON, vulnJSON)
+		if err != nil {
+			log.Error("error running scan hooks", slog.Any("error", err))
+
+			q.markFailed(job, database.StatusVulnScanFailed, err.Error())
+			return
+		}
+		vulnJSON = event.Vulnerabilities
+	}
 
 	// Store the vulnerability report with grype DB version info
 	// StoreVulnerabilities will automatically update status to StatusCompleted
-	if err := q.db.StoreVulnerabilities(job.Image.Digest, scanResult.VulnerabilityJSON, scanResult.DBStatus.Built); err != nil {
+	if err := q.db.StoreVulnerabilities(job.Image.Digest, vulnJSON, scanResult.DBStatus.Built); err != nil {
 		log.Error("error storing vulnerabilities", slog.Any("error", err))
 
-		if updateErr := q.db.UpdateStatus(job.Image.Digest, database.StatusVulnScanFailed, err.Error()); updateErr != nil {
-			log.Error("error updating status to failed", slog.Any("error", updateErr))
-		}
+		q.markFailed(job, database.StatusVulnScanFailed, err.Error())
 		return
 	}
 
+	if err := q.db.RecordScanDurations(job.Image.Digest, 0, scanDuration); err != nil {
+		log.Warn("error recording vulnerability scan duration", slog.Any("error", err))
+	}
+
 	log.Info("successfully scanned and stored vulnerabilities")
+	q.setOutcome(OutcomeSuccess)
+	q.clearFailures(job)
+	q.runPostPersistHooks(ctx, job, sbomJSON, vulnJSON)
+
+	q.storeInResultCache(job, scanResult.DBStatus.Built, sbomJSON, vulnJSON)
+}
+
+// importFromResultCache looks up the image in the result cache and imports the cached
+// SBOM and vulnerabilities on a hit. Returns true if the image no longer needs scanning.
+func (q *JobQueue) importFromResultCache(job ScanJob) bool {
+	if q.resultCache == nil {
+		return false
+	}
+	log := log.With("image", job.Image.Reference, "digest", job.Image.Digest)
+
+	built, err := q.grypeDBBuilt()
+	if err != nil {
+		log.Debug("skipping result cache lookup, grype DB build unknown", slog.Any("error", err))
+		return false
+	}
+
+	bundle := q.resultCache.Lookup(q.ctx, job.Image.Digest, built)
+	if bundle == nil {
+		return false
+	}
+
+	// Cached results skip the scan, but still pass through pre-persist hooks
+	event, err := q.runHooks(q.ctx, HookPrePersist, job, bundle.SBOM, bundle.Vulnerabilities)
+	if err != nil {
+		log.Warn("error running scan hooks on cached results, scanning locally", slog.Any("error", err))
+		return false
+	}
+
+	if err := q.db.ImportScanResults(job.Image.Digest, event.SBOM, event.Vulnerabilities, built); err != nil {
+		log.Error("error importing cached scan results", slog.Any("error", err))
+		return false
+	}
+
+	log.Info("imported scan results from result cache", "source", bundle.Source)
+	q.setOutcome(OutcomeCached)
+	q.clearFailures(job)
+	q.runPostPersistHooks(q.ctx, job, event.SBOM, event.Vulnerabilities)
+	return true
+}
+
+// runPostPersistHooks runs the post-persist hooks of stored results. The
+// results are already stored, so a failing hook is only logged.
+func (q *JobQueue) runPostPersistHooks(ctx context.Context, job ScanJob, sbomJSON, vulnJSON []byte) {
+	if _, err := q.runHooks(ctx, HookPostPersist, job, sbomJSON, vulnJSON); err != nil {
+		log.Warn("error running post-persist hooks", "image", job.Image.Reference, "digest", job.Image.Digest, slog.Any("error", err))
+	}
+}
+
+// markFailed sets the failed status of the image and adds the failure to its
+// error chain. The scan is retried with backoff until it has failed
+// MaxAttempts times in a row; the image is then dead-lettered with status
+// scan_failed_permanent. Failures caused by the queue shutting down are not
+// counted.
+func (q *JobQueue) markFailed(job ScanJob, status database.Status, errorMsg string) {
+	log := log.With("image", job.Image.Reference, "digest", job.Image.Digest)
+
+	if err := q.db.UpdateStatus(job.Image.Digest, status, errorMsg); err != nil {
+		log.Error("error updating status to failed", "status", status, slog.Any("error", err))
+	}
+	if q.ctx.Err() != nil {
+		return
+	}
+	q.setOutcome(OutcomeFailure)
+
+	deadLettered, err := q.db.RecordScanFailure(job.Image.Digest, job.NodeName, status, errorMsg, q.config.MaxAttempts)
+	if err != nil {
+		log.Error("error recording scan failure", slog.Any("error", err))
+		return
+	}
+	if deadLettered {
+		log.Warn("image dead-lettered after repeated scan failures, requeue it via /api/scan-queue/dead-letter",
+			"attempts", q.config.MaxAttempts, "status", status, "error", errorMsg)
+		return
+	}
+	q.scheduleRetry(job)
+}
+
+// clearFailures resets the error chain of a successfully scanned image
+func (q *JobQueue) clearFailures(job ScanJob) {
+	if err := q.db.ClearScanFailures(job.Image.Digest); err != nil {
+		log.Error("error clearing scan failures", "digest", job.Image.Digest, slog.Any("error", err))
+	}
+}
+
+// RequeueDeadLettered takes dead-lettered images out of the dead-letter list
+// and enqueues them again, e.g. once a broken node is fixed. Only images with
+// a digest in digests are requeued, or all of them if digests is empty; a
+// non-empty nodeName further limits them to images running on that node.
+// Returns the requeued images.
+func (q *JobQueue) RequeueDeadLettered(digests []string, nodeName string) ([]database.DeadLetteredScan, error) {
+	scans, err := q.db.GetDeadLetteredScans()
+	if err != nil {
+		return nil, err
+	}
+
+	requeued := []database.DeadLetteredScan{}
+	for _, scan := range scans {
+		if len(digests) > 0 && !slices.Contains(digests, scan.Digest) {
+			continue
+		}
+		if nodeName != "" && scan.NodeName != nodeName {
+			continue
+		}
+		if err := q.db.ClearScanFailures(scan.Digest); err != nil {
+			return requeued, err
+		}
+		q.Enqueue(ScanJob{
+			Image:            containers.ImageID{Reference: scan.Reference, Digest: scan.Digest},
+			NodeName:         scan.NodeName,
+			ContainerRuntime: scan.ContainerRuntime,
+			ForceScan:        true,
+			AdHoc:            scan.AdHoc,
+			Registry:         scan.Registry,
+		})
+		requeued = append(requeued, scan)
+	}
+
+	log.Info("requeued dead-lettered images", "count", len(requeued))
+	return requeued, nil
+}
+
+// storeInResultCache writes freshly scanned results through to the result cache.
+// The upload runs in the background so it doesn't hold up the scan worker.
+func (q *JobQueue) storeInResultCache(job ScanJob, grypeDBBuilt time.Time, sbomJSON, vulnJSON []byte) {
+	if q.resultCache == nil {
+		return
+	}
+
+	q.wg.Add(1)
+	go func() {
+		defer q.wg.Done()
+		if err := q.resultCache.Store(q.ctx, job.Image.Digest, grypeDBBuilt, sbomJSON, vulnJSON); err != nil {
+			log.Warn("error writing scan results to result cache",
+				"image", job.Image.Reference, "digest", job.Image.Digest, slog.Any("error", err))
+		}
+	}()
 }
 
 // processHostJob handles a single host scan job
@@ -603,9 +1089,7 @@ func (q *JobQueue) processHostJob(job HostScanJob) {
 
 	if q.hostSBOMRetriever == nil {
 		log.Error("host SBOM retriever not configured")
-		if updateErr := q.db.UpdateNodeStatus(job.NodeName, database.StatusSBOMFailed, "Host SBOM retriever not configured"); updateErr != nil {
-			log.Error("error updating node status", slog.Any("error", updateErr))
-		}
+		q.markHostFailed(job, database.StatusSBOMFailed, "Host SBOM retriever not configured")
 		return
 	}
 
@@ -621,6 +1105,7 @@ func (q *JobQueue) processHostJob(job HostScanJob) {
 	}
 
 	// Mark node as generating SBOM
+	q.setPhase(PhaseSBOM)
 	if err := q.db.UpdateNodeStatus(job.NodeName, database.StatusGeneratingSBOM, ""); err != nil {
 		log.Error("error updating status", slog.Any("error", err))
 		return
@@ -634,19 +1119,17 @@ func (q *JobQueue) processHostJob(job HostScanJob) {
 	if err != nil {
 		log.Error("error retrieving host SBOM", slog.Any("error", err))
 
-		if updateErr := q.db.UpdateNodeStatus(job.NodeName, database.StatusSBOMFailed, err.Error()); updateErr != nil {
-			log.Error("error updating node status to failed", slog.Any("error", updateErr))
-		}
+		q.markHostFailed(job, database.StatusSBOMFailed, err.Error())
 		return
 	}
 
+	q.stats.observeSBOMSize("host", len(sbomJSON))
+
 	// Store the SBOM in the database
 	if err := q.db.StoreNodeSBOM(job.NodeName, sbomJSON); err != nil {
 		log.Error("error storing host SBOM", slog.Any("error", err))
 
-		if updateErr := q.db.UpdateNodeStatus(job.NodeName, database.StatusSBOMFailed, err.Error()); updateErr != nil {
-			log.Error("error updating node status to failed", slog.Any("error", updateErr))
-		}
+		q.markHostFailed(job, database.StatusSBOMFailed, err.Error())
 		return
 	}
 
@@ -661,6 +1144,7 @@ func (q *JobQueue) processHostVulnerabilityScan(job HostScanJob, sbomJSON []byte
 	log := grypeLog.With("node", job.NodeName)
 
 	log.Info("starting host vulnerability scan")
+	q.setPhase(PhaseVuln)
 
 	// Wait for grype DB to be ready before scanning
 	if q.dbReadinessState != nil {
@@ -668,9 +1152,7 @@ func (q *JobQueue) processHostVulnerabilityScan(job HostScanJob, sbomJSON []byte
 		if !q.dbReadinessState.WaitForReady(q.ctx) {
 			log.Warn("host scan cancelled while waiting for database")
 			// Update status to failed instead of silently returning
-			if updateErr := q.db.UpdateNodeStatus(job.NodeName, database.StatusVulnScanFailed, "cancelled while waiting for vulnerability database"); updateErr != nil {
-				log.Error("error updating node status to failed", slog.Any("error", updateErr))
-			}
+			q.markHostFailed(job, database.StatusVulnScanFailed, "cancelled while waiting for vulnerability database")
 			return
 		}
 		log.Debug("vulnerability database is ready")
@@ -682,9 +1164,7 @@ func (q *JobQueue) processHostVulnerabilityScan(job HostScanJob, sbomJSON []byte
 		sbomJSON, err = q.db.GetNodeSBOM(job.NodeName)
 		if err != nil {
 			log.Error("error retrieving SBOM from database", slog.Any("error", err))
-			if updateErr := q.db.UpdateNodeStatus(job.NodeName, database.StatusVulnScanFailed, "SBOM not available: "+err.Error()); updateErr != nil {
-				log.Error("error updating node status to failed", slog.Any("error", updateErr))
-			}
+			q.markHostFailed(job, database.StatusVulnScanFailed, "SBOM not available: "+err.Error())
 			return
 		}
 	}
@@ -697,9 +1177,7 @@ func (q *JobQueue) processHostVulnerabilityScan(job HostScanJob, sbomJSON []byte
 	if err != nil {
 		log.Error("error scanning host vulnerabilities", slog.Any("error", err))
 
-		if updateErr := q.db.UpdateNodeStatus(job.NodeName, database.StatusVulnScanFailed, err.Error()); updateErr != nil {
-			log.Error("error updating node status to failed", slog.Any("error", updateErr))
-		}
+		q.markHostFailed(job, database.StatusVulnScanFailed, err.Error())
 		return
 	}
 
@@ -707,13 +1185,23 @@ func (q *JobQueue) processHostVulnerabilityScan(job HostScanJob, sbomJSON []byte
 	if err := q.db.StoreNodeVulnerabilities(job.NodeName, scanResult.VulnerabilityJSON, scanResult.DBStatus.Built); err != nil {
 		log.Error("error storing host vulnerabilities", slog.Any("error", err))
 
-		if updateErr := q.db.UpdateNodeStatus(job.NodeName, database.StatusVulnScanFailed, err.Error()); updateErr != nil {
-			log.Error("error updating node status to failed", slog.Any("error", updateErr))
-		}
+		q.markHostFailed(job, database.StatusVulnScanFailed, err.Error())
 		return
 	}
 
 	log.Info("successfully scanned and stored host vulnerabilities")
+	q.setOutcome(OutcomeSuccess)
+}
+
+// markHostFailed sets the failed status of the node. Like markFailed, failures
+// caused by the queue shutting down are not counted.
+func (q *JobQueue) markHostFailed(job HostScanJob, status database.Status, errorMsg string) {
+	if err := q.db.UpdateNodeStatus(job.NodeName, status, errorMsg); err != nil {
+		nodesLog.Error("error updating node status to failed", "node", job.NodeName, "status", status, slog.Any("error", err))
+	}
+	if q.ctx.Err() == nil {
+		q.setOutcome(OutcomeFailure)
+	}
 }
 
 // Shutdown gracefully shuts down the queue, waiting for current job to complete
@@ -735,7 +1223,10 @@ type QueueJob struct {
 	Digest     string `json:"digest,omitempty"`      // Image digest (for image jobs)
 	NodeName   string `json:"node_name,omitempty"`   // Node name
 	ForceScan  bool   `json:"force_scan"`            // Force scan flag
-	FullRescan bool   `json:"full_rescan,omitempty"` // Full rescan flag (for host jobs)
+	FullRescan bool   `json:"full_rescan,omitempty"` // Full rescan flag (regenerates the SBOM)
+	Priority   string `json:"priority,omitempty"`    // Scheduling priority (for image jobs)
+	RetryAt    string `json:"retry_at,omitempty"`    // When an automatic retry may run (RFC 3339)
+	Held       bool   `json:"held,omitempty"`        // Waiting for its paused node to resume
 }
 
 // QueueContents represents the current state of the queue
@@ -747,6 +1238,7 @@ type QueueContents struct {
 	TotalProcessed int64      `json:"total_processed"`
 	JobCount       int        `json:"job_count"`      // Number of image scan jobs
 	HostJobCount   int        `json:"host_job_count"` // Number of host scan jobs
+	QuietHours     bool       `json:"quiet_hours"`    // Non-urgent jobs are paused or throttled
 	Jobs           []QueueJob `json:"jobs"`
 }
 
@@ -758,17 +1250,27 @@ func (q *JobQueue) GetQueueContents() QueueContents {
 	q.metrics.mu.RLock()
 	defer q.metrics.mu.RUnlock()
 
-	// Build list of all jobs (images first, then hosts - matching processing order)
+	// Build list of all jobs (images by priority, then hosts - matching processing order
+	// outside quiet hours)
 	jobs := make([]QueueJob, 0, len(q.jobs)+len(q.hostJobs))
 
-	for _, job := range q.jobs {
-		jobs = append(jobs, QueueJob{
-			Type:      "image",
-			Image:     job.Image.Reference,
-			Digest:    job.Image.Digest,
-			NodeName:  job.NodeName,
-			ForceScan: job.ForceScan,
-		})
+	imageJobs := slices.Clone(q.jobs)
+	slices.SortStableFunc(imageJobs, func(a, b ScanJob) int { return int(b.priority - a.priority) })
+	for _, job := range imageJobs {
+		queueJob := QueueJob{
+			Type:       "image",
+			Image:      job.Image.Reference,
+			Digest:     job.Image.Digest,
+			NodeName:   job.NodeName,
+			ForceScan:  job.ForceScan,
+			FullRescan: job.FullRescan,
+			Priority:   job.priority.String(),
+			Held:       q.heldLocked(job),
+		}
+		if !job.RetryAt.IsZero() {
+			queueJob.RetryAt = job.RetryAt.UTC().Format(time.RFC3339)
+		}
+		jobs = append(jobs, queueJob)
 	}
 
 	for _, job := range q.hostJobs {
@@ -777,6 +1279,7 @@ func (q *JobQueue) GetQueueContents() QueueContents {
 			NodeName:   job.NodeName,
 			ForceScan:  job.ForceScan,
 			FullRescan: job.FullRescan,
+			Held:       q.nodePausedLocked(job.NodeName),
 		})
 	}
 
@@ -788,6 +1291,41 @@ func (q *JobQueue) GetQueueContents() QueueContents {
 		TotalProcessed: q.metrics.totalProcessed,
 		JobCount:       len(q.jobs),
 		HostJobCount:   len(q.hostJobs),
+		QuietHours:     q.quietHours.Active(q.now()),
 		Jobs:           jobs,
 	}
 }
+
+// PendingSBOMDigests returns the digests of queued image jobs on nodeName that
+// will need an SBOM when processed (in queue order, without duplicates).
+// SBOM retrievers use it to fetch SBOMs for upcoming jobs in one batch.
+// Nothing is prefetched from a paused node.
+func (q *JobQueue) PendingSBOMDigests(nodeName string) []string {
+	q.jobsMu.Lock()
+	if q.nodePausedLocked(nodeName) {
+		q.jobsMu.Unlock()
+		return nil
+	}
+	var candidates []string
+	seen := make(map[string]bool)
+	now := q.now()
+	for _, job := range q.jobs {
+		if job.fromRegistry() || job.NodeName != nodeName || job.Image.Digest == "" || seen[job.Image.Digest] || !job.due(now) {
+			continue
+		}
+		seen[job.Image.Digest] = true
+		candidates = append(candidates, job.Image.Digest)
+	}
+	q.jobsMu.Unlock()
+
+	// Check status outside the queue lock - images that already have an SBOM skip retrieval
+	digests := make([]string, 0, len(candidates))
+	for _, digest := range candidates {
+		status, err := q.db.GetImageStatus(digest)
+		if err == nil && status.HasSBOM() {
+			continue
+		}
+		digests = append(digests, digest)
+	}
+	return digests
+}
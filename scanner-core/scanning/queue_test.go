@@ -3,7 +3,10 @@ package scanning
 import (
 	"context"
 	"errors"
+	"fmt"
 	"os"
+	"path/filepath"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"testing"
@@ -13,6 +16,7 @@ import (
 	"github.com/bvboe/b2s-go/scanner-core/database"
 	"github.com/bvboe/b2s-go/scanner-core/grype"
 	"github.com/bvboe/b2s-go/scanner-core/nodes"
+	"github.com/bvboe/b2s-go/scanner-core/transfer"
 	// Note: SQLite driver is imported via Grype's dependencies
 	// DO NOT import sqlitedriver here to avoid duplicate registration
 )
@@ -696,3 +700,322 @@ func TestHostVulnScanCancelled(t *testing.T) {
 		t.Errorf("Expected status error 'cancelled while waiting for vulnerability database', got '%s'", node.StatusError)
 	}
 }
+
+// fakeResultCache is an in-memory ResultCache for tests
+type fakeResultCache struct {
+	bundle *transfer.Bundle
+	stored atomic.Int32
+}
+
+func (c *fakeResultCache) Lookup(ctx context.Context, digest string, grypeDBBuilt time.Time) *transfer.Bundle {
+	return c.bundle
+}
+
+func (c *fakeResultCache) Store(ctx context.Context, digest string, grypeDBBuilt time.Time, sbomJSON, vulnJSON []byte) error {
+	c.stored.Add(1)
+	return nil
+}
+
+// TestJobQueueResultCacheHit tests that a result cache hit skips SBOM retrieval and scanning
+func TestJobQueueResultCacheHit(t *testing.T) {
+	dbPath := "/tmp/test_queue_cache_" + time.Now().Format("20060102150405") + ".db"
+	defer func() { _ = os.Remove(dbPath) }()
+
+	db, err := database.New(dbPath)
+	if err != nil {
+		t.Fatalf("Failed to create database: %v", err)
+	}
+	defer func() { _ = database.Close(db) }()
+
+	var retrieverCalls atomic.Int32
+	mockRetriever := func(ctx context.Context, image containers.ImageID, nodeName string, runtime string) ([]byte, error) {
+		retrieverCalls.Add(1)
+		return nil, errors.New("should not be called")
+	}
+
+	built := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	testImage := containers.ImageID{Reference: "nginx:1.21", Digest: "sha256:cache123"}
+	bundle, err := transfer.NewBundle(database.ImageTransferRecord{Digest: testImage.Digest}, "staging",
+		[]byte(`{"artifacts":[]}`), []byte(`{"matches":[]}`))
+	if err != nil {
+		t.Fatalf("Failed to create bundle: %v", err)
+	}
+	cache := &fakeResultCache{bundle: bundle}
+
+	queue := NewJobQueue(db, mockRetriever, grype.Config{}, QueueConfig{MaxDepth: 0})
+	defer queue.Shutdown()
+	queue.grypeDBBuilt = func() (time.Time, error) { return built, nil }
+	queue.SetResultCache(cache)
+
+	queue.processJob(ScanJob{Image: testImage, NodeName: "test-node", ContainerRuntime: "containerd"})
+
+	if retrieverCalls.Load() != 0 {
+		t.Errorf("SBOM retriever called %d times on cache hit, want 0", retrieverCalls.Load())
+	}
+	complete, err := db.IsScanDataComplete(testImage.Digest)
+	if err != nil || !complete {
+		t.Fatalf("Expected complete scan data after cache hit (err=%v)", err)
+	}
+	if cache.stored.Load() != 0 {
+		t.Errorf("Cache hit should not be written back, got %d stores", cache.stored.Load())
+	}
+}
+
+// TestPendingSBOMDigests tests that only queued jobs on the node still needing an SBOM are returned
+func TestPendingSBOMDigests(t *testing.T) {
+	db, err := database.New(filepath.Join(t.TempDir(), "pending.db"))
+	if err != nil {
+		t.Fatalf("Failed to create database: %v", err)
+	}
+	defer func() { _ = database.Close(db) }()
+
+	scanned := containers.ImageID{Reference: "nginx:1.21", Digest: "sha256:scanned"}
+	if _, _, err := db.GetOrCreateImage(scanned); err != nil {
+		t.Fatalf("GetOrCreateImage() error = %v", err)
+	}
+	if err := db.StoreSBOM(scanned.Digest, []byte(`{"artifacts":[]}`)); err != nil {
+		t.Fatalf("StoreSBOM() error = %v", err)
+	}
+
+	// Built directly so no worker consumes the jobs
+	queue := &JobQueue{db: db, jobs: []ScanJob{
+		{Image: containers.ImageID{Digest: "sha256:a"}, NodeName: "node-1"},
+		{Image: scanned, NodeName: "node-1"},
+		{Image: containers.ImageID{Digest: "sha256:b"}, NodeName: "node-2"},
+		{Image: containers.ImageID{Digest: "sha256:c"}, NodeName: "node-1"},
+		{Image: containers.ImageID{Digest: "sha256:a"}, NodeName: "node-1", ForceScan: true},
+		{Image: containers.ImageID{Digest: "sha256:adhoc"}, NodeName: "node-1", AdHoc: true},
+		{Image: containers.ImageID{Digest: "sha256:retry"}, NodeName: "node-1", RetryAt: time.Now().Add(time.Hour)},
+	}, now: time.Now}
+
+	got := queue.PendingSBOMDigests("node-1")
+	want := []string{"sha256:a", "sha256:c"}
+	if len(got) != len(want) || got[0] != want[0] || got[1] != want[1] {
+		t.Errorf("PendingSBOMDigests() = %v, want %v", got, want)
+	}
+}
+
+// TestJobQueueAdHocUsesRegistryRetriever verifies that ad-hoc jobs retrieve
+// their SBOM from the registry rather than from a node, and fail clearly when
+// no registry retriever is configured.
+func TestJobQueueAdHocUsesRegistryRetriever(t *testing.T) {
+	db, err := database.New(filepath.Join(t.TempDir(), "adhoc.db"))
+	if err != nil {
+		t.Fatalf("Failed to create database: %v", err)
+	}
+	defer func() { _ = database.Close(db) }()
+
+	nodeRetriever := func(ctx context.Context, image containers.ImageID, nodeName string, runtime string) ([]byte, error) {
+		t.Errorf("node SBOM retriever called for ad-hoc image %s", image.Digest)
+		return nil, errors.New("unexpected")
+	}
+	queue := NewJobQueue(db, nodeRetriever, grype.Config{}, QueueConfig{MaxDepth: 0})
+	defer queue.Shutdown()
+
+	waitForStatus := func(digest string) *database.AdHocScan {
+		t.Helper()
+		deadline := time.Now().Add(5 * time.Second)
+		for {
+			scan, err := db.GetAdHocScan(digest)
+			if err != nil {
+				t.Fatalf("GetAdHocScan() error = %v", err)
+			}
+			if scan.Status == database.StatusSBOMFailed || time.Now().After(deadline) {
+				return scan
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+
+	unconfigured := containers.ImageID{Reference: "registry.example.com/app:v1", Digest: "sha256:unconfigured"}
+	if _, err := db.CreateAdHocScan(unconfigured, time.Hour); err != nil {
+		t.Fatalf("CreateAdHocScan() error = %v", err)
+	}
+	queue.Enqueue(ScanJob{Image: unconfigured, AdHoc: true})
+	if scan := waitForStatus(unconfigured.Digest); scan.Status != database.StatusSBOMFailed ||
+		!strings.Contains(scan.Error, "registry SBOM retriever not configured") {
+		t.Errorf("unexpected ad-hoc scan without registry retriever: %+v", scan)
+	}
+
+	var pulled []string
+	var mu sync.Mutex
+	queue.SetRegistrySBOMRetriever(func(ctx context.Context, image containers.ImageID) ([]byte, error) {
+		mu.Lock()
+		pulled = append(pulled, image.Reference)
+		mu.Unlock()
+		return nil, errors.New("manifest unknown")
+	})
+
+	image := containers.ImageID{Reference: "registry.example.com/app:v2", Digest: "sha256:pulled"}
+	if _, err := db.CreateAdHocScan(image, time.Hour); err != nil {
+		t.Fatalf("CreateAdHocScan() error = %v", err)
+	}
+	queue.Enqueue(ScanJob{Image: image, AdHoc: true})
+	if scan := waitForStatus(image.Digest); scan.Status != database.StatusSBOMFailed || scan.Error != "manifest unknown" {
+		t.Errorf("unexpected ad-hoc scan: %+v", scan)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(pulled) != 1 || pulled[0] != image.Reference {
+		t.Errorf("registry retriever called for %v, want [%s]", pulled, image.Reference)
+	}
+}
+
+// TestJobQueueVendorSBOM verifies that a verified vendor SBOM is stored instead
+// of generating one on the node, and that the node is used when the vendor
+// SBOM cannot be used
+func TestJobQueueVendorSBOM(t *testing.T) {
+	db, err := database.New(filepath.Join(t.TempDir(), "vendor.db"))
+	if err != nil {
+		t.Fatalf("Failed to create database: %v", err)
+	}
+	defer func() { _ = database.Close(db) }()
+
+	vendorImage := containers.ImageID{Reference: "registry.vendor.com/app:v1", Digest: "sha256:vendor"}
+	brokenImage := containers.ImageID{Reference: "registry.vendor.com/app:v2", Digest: "sha256:broken"}
+	for _, image := range []containers.ImageID{vendorImage, brokenImage} {
+		if _, err := db.AddContainer(containers.Container{
+			ID:               containers.ContainerID{Namespace: "default", Pod: "pod", Name: image.Digest},
+			Image:            image,
+			NodeName:         "node-1",
+			ContainerRuntime: "containerd",
+		}); err != nil {
+			t.Fatalf("AddContainer() error = %v", err)
+		}
+	}
+
+	var mu sync.Mutex
+	generated := map[string]bool{}
+	nodeRetriever := func(ctx context.Context, image containers.ImageID, nodeName string, runtime string) ([]byte, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		generated[image.Digest] = true
+		return []byte(`{"artifacts":[]}`), nil
+	}
+	queue := NewJobQueue(db, nodeRetriever, grype.Config{}, QueueConfig{MaxDepth: 0})
+	defer queue.Shutdown()
+	queue.SetVendorSBOMRetriever(func(ctx context.Context, image containers.ImageID) ([]byte, string, error) {
+		if image.Digest == brokenImage.Digest {
+			return nil, "", errors.New("no SBOM attestation of the image signed by a trusted key")
+		}
+		return []byte(`{"artifacts":[]}`), "sha256:key", nil
+	})
+
+	sbomSource := func(digest string) (source, signer interface{}) {
+		t.Helper()
+		deadline := time.Now().Add(5 * time.Second)
+		for {
+			result, err := db.ExecuteReadOnlyQueryArgs(`SELECT sbom_source, sbom_signer FROM images WHERE digest = ?`, digest)
+			if err != nil {
+				t.Fatalf("query error = %v", err)
+			}
+			if len(result.Rows) == 1 && result.Rows[0]["sbom_source"] != nil || time.Now().After(deadline) {
+				if len(result.Rows) == 0 {
+					return nil, nil
+				}
+				return result.Rows[0]["sbom_source"], result.Rows[0]["sbom_signer"]
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+
+	queue.Enqueue(ScanJob{Image: vendorImage, NodeName: "node-1", ContainerRuntime: "containerd"})
+	if source, signer := sbomSource(vendorImage.Digest); source != database.SBOMSourceVendorAttested || signer != "sha256:key" {
+		t.Errorf("vendor image SBOM source = %v, signer %v", source, signer)
+	}
+	queue.Enqueue(ScanJob{Image: brokenImage, NodeName: "node-1", ContainerRuntime: "containerd"})
+	if source, signer := sbomSource(brokenImage.Digest); source != database.SBOMSourceGenerated || signer != nil {
+		t.Errorf("fallback SBOM source = %v, signer %v", source, signer)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if generated[vendorImage.Digest] || !generated[brokenImage.Digest] {
+		t.Errorf("node SBOMs generated for %v, want only %s", generated, brokenImage.Digest)
+	}
+}
+
+// TestJobQueueDeadLetter verifies that an image is dead-lettered after
+// MaxAttempts consecutive failures, skipped from then on, and scanned again
+// once requeued
+func TestJobQueueDeadLetter(t *testing.T) {
+	db, err := database.New(filepath.Join(t.TempDir(), "deadletter.db"))
+	if err != nil {
+		t.Fatalf("Failed to create database: %v", err)
+	}
+	defer func() { _ = database.Close(db) }()
+
+	addImage := func(image containers.ImageID) {
+		t.Helper()
+		if _, err := db.AddContainer(containers.Container{
+			ID:               containers.ContainerID{Namespace: "default", Pod: "pod", Name: image.Digest},
+			Image:            image,
+			NodeName:         "node-1",
+			ContainerRuntime: "containerd",
+		}); err != nil {
+			t.Fatalf("AddContainer() error = %v", err)
+		}
+	}
+	broken := containers.ImageID{Reference: "registry.example.com/broken:v1", Digest: "sha256:broken"}
+	addImage(broken)
+
+	var mu sync.Mutex
+	calls := map[string]int{}
+	retriever := func(ctx context.Context, image containers.ImageID, nodeName string, runtime string) ([]byte, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		calls[image.Digest]++
+		return nil, errors.New("node agent unreachable")
+	}
+	queue := NewJobQueue(db, retriever, grype.Config{}, QueueConfig{MaxDepth: 0, MaxAttempts: 2})
+	defer queue.Shutdown()
+
+	// scan enqueues a scan of the broken image followed by one of a fresh
+	// marker image, and waits until the marker was retrieved, i.e. the broken
+	// image has been processed. Returns how often the broken image was retrieved.
+	markers := 0
+	scan := func() int {
+		t.Helper()
+		markers++
+		marker := containers.ImageID{Reference: "registry.example.com/marker:v1", Digest: fmt.Sprintf("sha256:marker%d", markers)}
+		addImage(marker)
+		queue.EnqueueScan(broken, "node-1", "containerd")
+		queue.EnqueueScan(marker, "node-1", "containerd")
+		deadline := time.Now().Add(5 * time.Second)
+		for {
+			mu.Lock()
+			done, n := calls[marker.Digest] > 0, calls[broken.Digest]
+			mu.Unlock()
+			if done {
+				return n
+			}
+			if time.Now().After(deadline) {
+				t.Fatalf("timed out waiting for %s", marker.Digest)
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+
+	for attempt := 1; attempt <= 3; attempt++ {
+		if n := scan(); n != min(attempt, 2) {
+			t.Errorf("attempt %d: retriever called %d times", attempt, n)
+		}
+	}
+	if deadLettered, err := db.IsDeadLettered(broken.Digest); err != nil || !deadLettered {
+		t.Fatalf("IsDeadLettered() = %v, %v; want true", deadLettered, err)
+	}
+
+	// Requeueing is limited to the selected node and digests
+	if requeued, err := queue.RequeueDeadLettered(nil, "node-2"); err != nil || len(requeued) != 0 {
+		t.Errorf("RequeueDeadLettered(node-2) = %v, %v; want none", requeued, err)
+	}
+	requeued, err := queue.RequeueDeadLettered([]string{broken.Digest}, "")
+	if err != nil || len(requeued) != 1 || requeued[0].Digest != broken.Digest || len(requeued[0].Failures) != 2 {
+		t.Fatalf("RequeueDeadLettered() = %+v, %v", requeued, err)
+	}
+	// Both the requeued job and the one enqueued after it run
+	if n := scan(); n != 4 {
+		t.Errorf("requeued image retrieved %d times, want 4", n)
+	}
+}
@@ -0,0 +1,211 @@
+package scanning
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	_ "time/tzdata" // Quiet hours time zones must resolve in minimal container images
+)
+
+// QuietMode is what happens to non-urgent scans during quiet hours
+type QuietMode string
+
+const (
+	// QuietThrottle runs non-urgent scans at most once per QuietHours.Interval
+	QuietThrottle QuietMode = "throttle"
+	// QuietPause holds non-urgent scans until the quiet window ends
+	QuietPause QuietMode = "pause"
+)
+
+// quietDays maps day abbreviations to weekdays
+var quietDays = map[string]time.Weekday{
+	"sun": time.Sunday, "mon": time.Monday, "tue": time.Tuesday, "wed": time.Wednesday,
+	"thu": time.Thursday, "fri": time.Friday, "sat": time.Saturday,
+}
+
+// TimeWindow is a daily time range on selected weekdays. A window whose end is
+// before its start runs past midnight and belongs to the day it starts on; a
+// window whose start equals its end covers the whole day.
+type TimeWindow struct {
+	Days  [7]bool // indexed by time.Weekday
+	Start int     // minutes after midnight
+	End   int     // minutes after midnight
+}
+
+// QuietHours restricts non-urgent scans (rescans after grype DB updates,
+// retries, periodic host rescans) during business hours so SBOM generation
+// doesn't compete with workloads on constrained nodes. Scans of new images
+// and new nodes always proceed.
+type QuietHours struct {
+	Windows  []TimeWindow
+	Location *time.Location
+	Mode     QuietMode
+	Interval time.Duration // minimum time between non-urgent scans in throttle mode
+}
+
+// NewQuietHours parses a quiet hours specification: comma-separated windows of
+// the form "[days ]HH:MM-HH:MM", where days is a day ("Sat"), a range
+// ("Mon-Fri") or a list ("Mon/Wed"); windows without days apply every day.
+// Example: "Mon-Fri 08:00-18:00, Sat 10:00-14:00". Times are in timezone
+// (an IANA name such as "Europe/Stockholm"; empty means UTC).
+func NewQuietHours(spec, timezone string, mode QuietMode, interval time.Duration) (*QuietHours, error) {
+	loc := time.UTC
+	if timezone != "" {
+		var err error
+		if loc, err = time.LoadLocation(timezone); err != nil {
+			return nil, fmt.Errorf("invalid quiet hours timezone %q: %w", timezone, err)
+		}
+	}
+	switch mode {
+	case "":
+		mode = QuietThrottle
+	case QuietThrottle, QuietPause:
+	default:
+		return nil, fmt.Errorf("invalid quiet hours mode %q: expected %q or %q", mode, QuietThrottle, QuietPause)
+	}
+	if interval <= 0 {
+		interval = 5 * time.Minute
+	}
+
+	qh := &QuietHours{Location: loc, Mode: mode, Interval: interval}
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		window, err := parseTimeWindow(entry)
+		if err != nil {
+			return nil, err
+		}
+		qh.Windows = append(qh.Windows, window)
+	}
+	if len(qh.Windows) == 0 {
+		return nil, fmt.Errorf("no quiet hours windows in %q", spec)
+	}
+	return qh, nil
+}
+
+// parseTimeWindow parses "[days ]HH:MM-HH:MM"
+func parseTimeWindow(entry string) (TimeWindow, error) {
+	var w TimeWindow
+	fields := strings.Fields(entry)
+	var days, times string
+	switch len(fields) {
+	case 1:
+		times = fields[0]
+		for d := range w.Days {
+			w.Days[d] = true
+		}
+	case 2:
+		days, times = fields[0], fields[1]
+		for _, part := range strings.Split(strings.ToLower(days), "/") {
+			from, to, isRange := strings.Cut(part, "-")
+			first, ok1 := quietDays[from]
+			last, ok2 := quietDays[to]
+			if !isRange {
+				last, ok2 = first, ok1
+			}
+			if !ok1 || !ok2 {
+				return w, fmt.Errorf("invalid quiet hours days %q in %q", days, entry)
+			}
+			for d := first; ; d = (d + 1) % 7 {
+				w.Days[d] = true
+				if d == last {
+					break
+				}
+			}
+		}
+	default:
+		return w, fmt.Errorf("invalid quiet hours window %q: expected [days ]HH:MM-HH:MM", entry)
+	}
+
+	start, end, ok := strings.Cut(times, "-")
+	if !ok {
+		return w, fmt.Errorf("invalid quiet hours window %q: expected [days ]HH:MM-HH:MM", entry)
+	}
+	var err error
+	if w.Start, err = parseClock(start); err != nil {
+		return w, fmt.Errorf("invalid quiet hours window %q: %w", entry, err)
+	}
+	if w.End, err = parseClock(end); err != nil {
+		return w, fmt.Errorf("invalid quiet hours window %q: %w", entry, err)
+	}
+	return w, nil
+}
+
+// parseClock parses HH:MM into minutes after midnight; 24:00 is accepted as an end time
+func parseClock(s string) (int, error) {
+	h, m, ok := strings.Cut(s, ":")
+	if !ok {
+		return 0, fmt.Errorf("invalid time %q", s)
+	}
+	hour, err1 := strconv.Atoi(h)
+	minute, err2 := strconv.Atoi(m)
+	if err1 != nil || err2 != nil || hour < 0 || minute < 0 || minute > 59 || hour*60+minute > 24*60 {
+		return 0, fmt.Errorf("invalid time %q", s)
+	}
+	return hour*60 + minute, nil
+}
+
+// Contains reports whether t (already in the window's location) falls in the window
+func (w TimeWindow) Contains(t time.Time) bool {
+	minute := t.Hour()*60 + t.Minute()
+	today := t.Weekday()
+	yesterday := (today + 6) % 7
+	switch {
+	case w.Start == w.End:
+		return w.Days[today]
+	case w.Start < w.End:
+		return w.Days[today] && minute >= w.Start && minute < w.End
+	default:
+		return (w.Days[today] && minute >= w.Start) || (w.Days[yesterday] && minute < w.End)
+	}
+}
+
+// end returns when the window containing t ends
+func (w TimeWindow) end(t time.Time) time.Time {
+	midnight := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	minute := t.Hour()*60 + t.Minute()
+	switch {
+	case w.Start == w.End:
+		return midnight.AddDate(0, 0, 1)
+	case w.Start > w.End && minute >= w.Start:
+		return midnight.AddDate(0, 0, 1).Add(time.Duration(w.End) * time.Minute)
+	default:
+		return midnight.Add(time.Duration(w.End) * time.Minute)
+	}
+}
+
+// Active reports whether now is within quiet hours. A nil QuietHours is never active.
+func (qh *QuietHours) Active(now time.Time) bool {
+	if qh == nil {
+		return false
+	}
+	t := now.In(qh.Location)
+	for _, w := range qh.Windows {
+		if w.Contains(t) {
+			return true
+		}
+	}
+	return false
+}
+
+// Remaining returns how long until the current quiet window ends (0 outside quiet hours).
+// Overlapping or adjacent windows are re-checked once the earliest of them ends.
+func (qh *QuietHours) Remaining(now time.Time) time.Duration {
+	if qh == nil {
+		return 0
+	}
+	t := now.In(qh.Location)
+	var remaining time.Duration
+	for _, w := range qh.Windows {
+		if !w.Contains(t) {
+			continue
+		}
+		if d := w.end(t).Sub(t); remaining == 0 || d < remaining {
+			remaining = d
+		}
+	}
+	return remaining
+}
@@ -0,0 +1,125 @@
+package scanning
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bvboe/b2s-go/scanner-core/containers"
+)
+
+func TestQuietHoursActive(t *testing.T) {
+	qh, err := NewQuietHours("Mon-Fri 08:00-18:00, Sat 22:00-02:00", "Europe/Stockholm", "", 0)
+	if err != nil {
+		t.Fatalf("NewQuietHours() error = %v", err)
+	}
+	stockholm := qh.Location
+
+	tests := []struct {
+		name   string
+		at     time.Time
+		active bool
+	}{
+		{"weekday business hours", time.Date(2026, 10, 14, 9, 30, 0, 0, stockholm), true},
+		{"weekday evening", time.Date(2026, 10, 14, 18, 0, 0, 0, stockholm), false},
+		{"other time zone", time.Date(2026, 10, 14, 7, 30, 0, 0, time.UTC), true}, // 09:30 in Stockholm
+		{"sunday", time.Date(2026, 10, 18, 12, 0, 0, 0, stockholm), false},
+		{"saturday night", time.Date(2026, 10, 17, 23, 0, 0, 0, stockholm), true},
+		{"past midnight", time.Date(2026, 10, 18, 1, 0, 0, 0, stockholm), true},
+		{"past window end", time.Date(2026, 10, 18, 2, 0, 0, 0, stockholm), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := qh.Active(tt.at); got != tt.active {
+				t.Errorf("Active(%v) = %v, want %v", tt.at, got, tt.active)
+			}
+		})
+	}
+
+	if got := qh.Remaining(time.Date(2026, 10, 17, 23, 0, 0, 0, stockholm)); got != 3*time.Hour {
+		t.Errorf("Remaining() = %v, want 3h", got)
+	}
+	if got := qh.Remaining(time.Date(2026, 10, 18, 12, 0, 0, 0, stockholm)); got != 0 {
+		t.Errorf("Remaining() outside quiet hours = %v, want 0", got)
+	}
+	if qh.Mode != QuietThrottle || qh.Interval != 5*time.Minute {
+		t.Errorf("Expected throttle defaults, got mode %q interval %v", qh.Mode, qh.Interval)
+	}
+}
+
+func TestNewQuietHoursInvalid(t *testing.T) {
+	for _, spec := range []string{"", "08:00", "Funday 08:00-18:00", "25:00-26:00", "Mon-Fri 08:00-18:00 extra"} {
+		if _, err := NewQuietHours(spec, "", QuietThrottle, 0); err == nil {
+			t.Errorf("NewQuietHours(%q) expected error", spec)
+		}
+	}
+	if _, err := NewQuietHours("08:00-18:00", "Mars/Olympus", QuietThrottle, 0); err == nil {
+		t.Error("Expected error for unknown time zone")
+	}
+	if _, err := NewQuietHours("08:00-18:00", "", "sometimes", 0); err == nil {
+		t.Error("Expected error for unknown mode")
+	}
+}
+
+func TestNextJobQuietHours(t *testing.T) {
+	rescan := ScanJob{Image: containers.ImageID{Digest: "sha256:old"}, ForceScan: true}
+	newImage := ScanJob{Image: containers.ImageID{Digest: "sha256:new"}}
+	nodeRescan := HostScanJob{NodeName: "node-1", FullRescan: true}
+
+	always, err := NewQuietHours("00:00-24:00", "", QuietThrottle, 10*time.Minute)
+	if err != nil {
+		t.Fatalf("NewQuietHours() error = %v", err)
+	}
+	now := time.Date(2026, 10, 14, 12, 0, 0, 0, time.UTC)
+
+	t.Run("outside quiet hours keeps queue order", func(t *testing.T) {
+		q := &JobQueue{jobs: []ScanJob{rescan, newImage}}
+		if image, host, _ := q.nextJobLocked(now); image != 0 || host != -1 {
+			t.Errorf("nextJobLocked() = %d, %d, want 0, -1", image, host)
+		}
+	})
+
+	t.Run("urgent jobs first", func(t *testing.T) {
+		q := &JobQueue{jobs: []ScanJob{rescan, newImage}, quietHours: always}
+		if image, host, _ := q.nextJobLocked(now); image != 1 || host != -1 {
+			t.Errorf("nextJobLocked() = %d, %d, want 1, -1", image, host)
+		}
+	})
+
+	t.Run("registry crawl scans wait like rescans", func(t *testing.T) {
+		crawled := ScanJob{Image: containers.ImageID{Digest: "sha256:crawled"}, Registry: true}
+		q := &JobQueue{jobs: []ScanJob{crawled, newImage}, quietHours: always}
+		if image, host, _ := q.nextJobLocked(now); image != 1 || host != -1 {
+			t.Errorf("nextJobLocked() = %d, %d, want 1, -1", image, host)
+		}
+	})
+
+	t.Run("throttle", func(t *testing.T) {
+		q := &JobQueue{jobs: []ScanJob{rescan, rescan}, hostJobs: []HostScanJob{nodeRescan}, quietHours: always}
+		if image, _, _ := q.nextJobLocked(now); image != 0 {
+			t.Fatalf("Expected the first rescan to run, got %d", image)
+		}
+		image, host, wait := q.nextJobLocked(now.Add(4 * time.Minute))
+		if image != -1 || host != -1 || wait != 6*time.Minute {
+			t.Errorf("nextJobLocked() = %d, %d, %v, want -1, -1, 6m", image, host, wait)
+		}
+		if image, _, _ := q.nextJobLocked(now.Add(10 * time.Minute)); image != 0 {
+			t.Errorf("Expected the next rescan after the interval, got %d", image)
+		}
+	})
+
+	t.Run("pause", func(t *testing.T) {
+		business, err := NewQuietHours("08:00-18:00", "", QuietPause, 0)
+		if err != nil {
+			t.Fatalf("NewQuietHours() error = %v", err)
+		}
+		q := &JobQueue{hostJobs: []HostScanJob{nodeRescan}, quietHours: business}
+		image, host, wait := q.nextJobLocked(now)
+		if image != -1 || host != -1 || wait != 6*time.Hour {
+			t.Errorf("nextJobLocked() = %d, %d, %v, want -1, -1, 6h", image, host, wait)
+		}
+		q.hostJobs = append(q.hostJobs, HostScanJob{NodeName: "node-2"})
+		if _, host, _ := q.nextJobLocked(now); host != 1 {
+			t.Errorf("Expected the new node scan to run, got %d", host)
+		}
+	})
+}
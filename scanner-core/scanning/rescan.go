@@ -0,0 +1,74 @@
+package scanning
+
+import (
+	"fmt"
+
+	"github.com/bvboe/b2s-go/scanner-core/containers"
+	"github.com/bvboe/b2s-go/scanner-core/database"
+)
+
+// Rescan enqueues a rescan of a running image, requested by an operator, from
+// a node running it. The vulnerabilities are scanned again against the stored
+// SBOM, or a fresh SBOM is retrieved first if fullRescan is set. The rescan
+// jumps the queue like ScanNow. Reports false if the image isn't running
+// anywhere.
+func (q *JobQueue) Rescan(digest string, fullRescan bool) bool {
+	instance, err := q.db.GetFirstContainerForImage(digest)
+	if err != nil {
+		return false
+	}
+	q.Enqueue(ScanJob{
+		Image:            containers.ImageID{Reference: instance.Reference, Digest: digest},
+		NodeName:         instance.NodeName,
+		ContainerRuntime: instance.ContainerRuntime,
+		ForceScan:        true,
+		FullRescan:       fullRescan,
+		Manual:           true,
+	})
+	log.Info("rescan requested", "image", instance.Reference, "digest", digest, "full_rescan", fullRescan)
+	return true
+}
+
+// RescanAll enqueues rescans of every running image at rescan priority, so
+// first scans of new images still go first. Returns the number of images
+// queued and of images skipped because they aren't running anywhere.
+func (q *JobQueue) RescanAll(fullRescan bool) (queued, notRunning int, err error) {
+	jobs, notRunning, err := q.runningImageJobs(fullRescan)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	q.BeginBulkRescan(BulkRescanAll, len(jobs), fullRescan)
+	for _, job := range jobs {
+		q.Enqueue(job)
+	}
+
+	log.Info("rescan of all images requested", "queued", len(jobs), "not_running", notRunning, "full_rescan", fullRescan)
+	return len(jobs), notRunning, nil
+}
+
+// runningImageJobs returns a rescan job for every image running somewhere,
+// from a node running it, and the number of images not running anywhere
+func (q *JobQueue) runningImageJobs(fullRescan bool) (jobs []ScanJob, notRunning int, err error) {
+	imagesRaw, err := q.db.GetAllImages()
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to get images: %w", err)
+	}
+	images, _ := imagesRaw.([]database.ContainerImage)
+
+	for _, img := range images {
+		instance, err := q.db.GetFirstContainerForImage(img.Digest)
+		if err != nil {
+			notRunning++
+			continue
+		}
+		jobs = append(jobs, ScanJob{
+			Image:            containers.ImageID{Reference: instance.Reference, Digest: img.Digest},
+			NodeName:         instance.NodeName,
+			ContainerRuntime: instance.ContainerRuntime,
+			ForceScan:        true,
+			FullRescan:       fullRescan,
+		})
+	}
+	return jobs, notRunning, nil
+}
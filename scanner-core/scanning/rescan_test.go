@@ -0,0 +1,57 @@
+package scanning
+
+import (
+	"testing"
+
+	"github.com/bvboe/b2s-go/scanner-core/containers"
+)
+
+func TestRescan(t *testing.T) {
+	db := newQueueTestDB(t)
+	q := newIdleQueue(db, QueueConfig{})
+
+	if q.Rescan("sha256:gone", false) {
+		t.Error("Rescan() of an image not running anywhere should fail")
+	}
+	if !q.Rescan("sha256:tool", true) {
+		t.Fatal("Rescan(sha256:tool) = false")
+	}
+
+	job := q.jobs[0]
+	if job.Image.Reference != "tool:1" || job.NodeName != "node-2" || !job.ForceScan || !job.FullRescan || job.priority != PriorityManual {
+		t.Errorf("queued job = %+v, want a manual full rescan of tool:1 on node-2", job)
+	}
+
+	// The full rescan survives a restart
+	recovered := newIdleQueue(db, QueueConfig{})
+	recovered.recoverPersisted()
+	if len(recovered.jobs) != 1 || !recovered.jobs[0].FullRescan {
+		t.Errorf("recovered jobs = %+v, want the full rescan", recovered.jobs)
+	}
+}
+
+func TestRescanAll(t *testing.T) {
+	db := newQueueTestDB(t)
+	if _, _, err := db.GetOrCreateImage(containers.ImageID{Reference: "old:1", Digest: "sha256:old"}); err != nil {
+		t.Fatalf("GetOrCreateImage() error = %v", err)
+	}
+	q := newIdleQueue(db, QueueConfig{})
+
+	queued, notRunning, err := q.RescanAll(false)
+	if err != nil {
+		t.Fatalf("RescanAll() error = %v", err)
+	}
+	if queued != 2 || notRunning != 1 {
+		t.Errorf("RescanAll() = %d queued, %d not running, want 2, 1", queued, notRunning)
+	}
+	for _, job := range q.jobs {
+		if !job.ForceScan || job.FullRescan || job.priority != PriorityRescan {
+			t.Errorf("queued job = %+v, want a rescan of the stored SBOM at rescan priority", job)
+		}
+	}
+
+	// A full rescan is a different request than the queued rescans
+	if queued, _, _ := q.RescanAll(true); queued != 2 || len(q.jobs) != 4 {
+		t.Errorf("full RescanAll() queued %d, queue has %d jobs, want 2 and 4", queued, len(q.jobs))
+	}
+}
@@ -0,0 +1,76 @@
+package scanning
+
+import (
+	"log/slog"
+	"time"
+)
+
+// Failed scans are retried automatically: markFailed enqueues the scan again
+// with RetryAt set, and the worker leaves it in the queue until then. The
+// delay starts at QueueConfig.RetryBackoff and doubles with every further
+// consecutive failure up to RetryMaxBackoff, so a network hiccup or an image
+// pull error costs one quick retry while a broken node isn't hammered. After
+// MaxAttempts consecutive failures the image is dead-lettered instead.
+
+// due reports whether the job may run at now
+func (j ScanJob) due(now time.Time) bool {
+	return !j.RetryAt.After(now)
+}
+
+// retryDelay returns the delay before retrying a scan that failed attempts
+// times in a row
+func (q *JobQueue) retryDelay(attempts int) time.Duration {
+	delay := q.config.RetryBackoff
+	for i := 1; i < attempts; i++ {
+		if q.config.RetryMaxBackoff > 0 && delay >= q.config.RetryMaxBackoff {
+			break
+		}
+		delay *= 2
+	}
+	if q.config.RetryMaxBackoff > 0 && delay > q.config.RetryMaxBackoff {
+		delay = q.config.RetryMaxBackoff
+	}
+	return delay
+}
+
+// scheduleRetry enqueues the failed job again to run after its backoff delay
+func (q *JobQueue) scheduleRetry(job ScanJob) {
+	if q.config.RetryBackoff <= 0 {
+		return
+	}
+	attempts, err := q.db.GetScanFailureCount(job.Image.Digest)
+	if err != nil {
+		log.Error("error reading scan failures, not retrying", "digest", job.Image.Digest, slog.Any("error", err))
+		return
+	}
+	if attempts == 0 {
+		return // Image removed while it was being scanned
+	}
+
+	delay := q.retryDelay(attempts)
+	job.Manual = false
+	job.RetryAt = q.now().Add(delay)
+	log.Info("scan failed, retrying with backoff", "image", job.Image.Reference, "digest", job.Image.Digest,
+		"attempts", attempts, "max_attempts", q.config.MaxAttempts, "retry_in", delay)
+	q.Enqueue(job)
+}
+
+// nextRetryLocked returns how long until the first queued retry is due, or 0
+// if none is waiting. Must be called with jobsMu held.
+func (q *JobQueue) nextRetryLocked(now time.Time) time.Duration {
+	var wait time.Duration
+	for _, job := range q.jobs {
+		if d := job.RetryAt.Sub(now); d > 0 && (wait == 0 || d < wait) {
+			wait = d
+		}
+	}
+	return wait
+}
+
+// minWait returns the shorter of two waits, ignoring a zero wait
+func minWait(a, b time.Duration) time.Duration {
+	if b > 0 && (a == 0 || b < a) {
+		return b
+	}
+	return a
+}
@@ -0,0 +1,61 @@
+package scanning
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bvboe/b2s-go/scanner-core/containers"
+	"github.com/bvboe/b2s-go/scanner-core/database"
+)
+
+func TestRetryDelay(t *testing.T) {
+	q := &JobQueue{config: QueueConfig{RetryBackoff: 30 * time.Second, RetryMaxBackoff: 5 * time.Minute}}
+	for attempts, want := range map[int]time.Duration{
+		1: 30 * time.Second, 2: time.Minute, 3: 2 * time.Minute, 5: 5 * time.Minute, 100: 5 * time.Minute,
+	} {
+		if got := q.retryDelay(attempts); got != want {
+			t.Errorf("retryDelay(%d) = %s, want %s", attempts, got, want)
+		}
+	}
+}
+
+func TestScheduleRetry(t *testing.T) {
+	db := newQueueTestDB(t)
+	q := newIdleQueue(db, QueueConfig{MaxAttempts: 3, RetryBackoff: 30 * time.Second})
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	q.now = func() time.Time { return now }
+
+	job := ScanJob{Image: containers.ImageID{Reference: "web:2", Digest: "sha256:web"}, NodeName: "node-1"}
+	q.markFailed(job, database.StatusSBOMFailed, "pod-scanner unreachable")
+	q.markFailed(job, database.StatusSBOMFailed, "pod-scanner unreachable")
+
+	// The second retry is skipped while the first (the same scan) waits its backoff
+	if len(q.jobs) != 1 || !q.jobs[0].RetryAt.Equal(now.Add(30*time.Second)) {
+		t.Fatalf("queued jobs = %+v, want one retry due in 30s", q.jobs)
+	}
+	if image, host, wait := q.nextJobLocked(now); image != -1 || host != -1 || wait != 30*time.Second {
+		t.Errorf("nextJobLocked() = %d, %d, %s, want the retry to wait 30s", image, host, wait)
+	}
+	if image, _, _ := q.nextJobLocked(now.Add(30 * time.Second)); image != 0 {
+		t.Errorf("nextJobLocked() = %d once due, want 0", image)
+	}
+	if got := q.GetQueueContents().Jobs[0].RetryAt; got != "2026-01-01T12:00:30Z" {
+		t.Errorf("queued retry_at = %q", got)
+	}
+
+	// Scan now doesn't wait for the retry
+	q.ScanNow([]string{"sha256:web"})
+	if image, _, _ := q.nextJobLocked(now); image != 0 {
+		t.Errorf("nextJobLocked() = %d after ScanNow, want 0", image)
+	}
+
+	// The third failure dead-letters the image instead of retrying it
+	q.jobs = nil
+	q.markFailed(job, database.StatusSBOMFailed, "pod-scanner unreachable")
+	if len(q.jobs) != 0 {
+		t.Errorf("dead-lettered image was queued for retry: %+v", q.jobs)
+	}
+	if status, err := db.GetImageStatus("sha256:web"); err != nil || status != database.StatusScanFailedPermanent {
+		t.Errorf("status = %v, %v, want %s", status, err, database.StatusScanFailedPermanent)
+	}
+}
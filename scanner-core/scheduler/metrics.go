@@ -0,0 +1,75 @@
+package scheduler
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"time"
+)
+
+// WriteMetrics writes per-job metrics in Prometheus text format: when each
+// job last ran and last succeeded, how long its last run took, how long the
+// run in progress has been going and how many runs in a row failed. Register
+// it with metrics.RegisterExtraWriter.
+func (s *Scheduler) WriteMetrics(w io.Writer) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if len(s.jobs) == 0 {
+		return
+	}
+
+	names := make([]string, 0, len(s.jobs))
+	for name := range s.jobs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	now := time.Now()
+
+	_, _ = fmt.Fprintf(w, "# HELP bjorn2scan_job_last_run_timestamp_seconds Start time of the last completed run of a scheduled job\n")
+	_, _ = fmt.Fprintf(w, "# TYPE bjorn2scan_job_last_run_timestamp_seconds gauge\n")
+	for _, name := range names {
+		if st := s.jobs[name].state; st.runs > 0 {
+			_, _ = fmt.Fprintf(w, "bjorn2scan_job_last_run_timestamp_seconds{job=%q} %d\n", name, st.lastRun.Unix())
+		}
+	}
+	_, _ = fmt.Fprintf(w, "# HELP bjorn2scan_job_last_success_timestamp_seconds Start time of the last successful run of a scheduled job\n")
+	_, _ = fmt.Fprintf(w, "# TYPE bjorn2scan_job_last_success_timestamp_seconds gauge\n")
+	for _, name := range names {
+		if st := s.jobs[name].state; !st.lastSuccess.IsZero() {
+			_, _ = fmt.Fprintf(w, "bjorn2scan_job_last_success_timestamp_seconds{job=%q} %d\n", name, st.lastSuccess.Unix())
+		}
+	}
+	_, _ = fmt.Fprintf(w, "# HELP bjorn2scan_job_last_duration_seconds Duration of the last completed run of a scheduled job\n")
+	_, _ = fmt.Fprintf(w, "# TYPE bjorn2scan_job_last_duration_seconds gauge\n")
+	for _, name := range names {
+		if st := s.jobs[name].state; st.runs > 0 {
+			_, _ = fmt.Fprintf(w, "bjorn2scan_job_last_duration_seconds{job=%q} %g\n", name, st.lastDuration.Seconds())
+		}
+	}
+	_, _ = fmt.Fprintf(w, "# HELP bjorn2scan_job_running_seconds How long the run in progress of a scheduled job has been going (0 when idle)\n")
+	_, _ = fmt.Fprintf(w, "# TYPE bjorn2scan_job_running_seconds gauge\n")
+	for _, name := range names {
+		running := 0.0
+		if st := s.jobs[name].state; st.running {
+			running = now.Sub(st.runStarted).Seconds()
+		}
+		_, _ = fmt.Fprintf(w, "bjorn2scan_job_running_seconds{job=%q} %g\n", name, running)
+	}
+	_, _ = fmt.Fprintf(w, "# HELP bjorn2scan_job_consecutive_failures Runs of a scheduled job that failed in a row\n")
+	_, _ = fmt.Fprintf(w, "# TYPE bjorn2scan_job_consecutive_failures gauge\n")
+	for _, name := range names {
+		_, _ = fmt.Fprintf(w, "bjorn2scan_job_consecutive_failures{job=%q} %d\n", name, s.jobs[name].state.consecutiveFailures)
+	}
+	_, _ = fmt.Fprintf(w, "# HELP bjorn2scan_job_runs_total Completed runs of a scheduled job, by result (success, failure)\n")
+	_, _ = fmt.Fprintf(w, "# TYPE bjorn2scan_job_runs_total counter\n")
+	for _, name := range names {
+		st := s.jobs[name].state
+		_, _ = fmt.Fprintf(w, "bjorn2scan_job_runs_total{job=%q,result=\"success\"} %d\n", name, st.runs-st.failures)
+		_, _ = fmt.Fprintf(w, "bjorn2scan_job_runs_total{job=%q,result=\"failure\"} %d\n", name, st.failures)
+	}
+	_, _ = fmt.Fprintf(w, "# HELP bjorn2scan_job_skipped_total Scheduled runs skipped because the previous run of the job was still going\n")
+	_, _ = fmt.Fprintf(w, "# TYPE bjorn2scan_job_skipped_total counter\n")
+	for _, name := range names {
+		_, _ = fmt.Fprintf(w, "bjorn2scan_job_skipped_total{job=%q} %d\n", name, s.jobs[name].state.skipped)
+	}
+}
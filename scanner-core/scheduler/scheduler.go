@@ -2,8 +2,10 @@ package scheduler
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"math/rand"
+	"sort"
 	"sync"
 	"time"
 
@@ -12,6 +14,13 @@ import (
 
 var log = logging.For(logging.ComponentScheduler)
 
+var (
+	// ErrJobNotFound is returned for a job name that is not registered
+	ErrJobNotFound = errors.New("job not found")
+	// ErrJobRunning is returned when a job is triggered while it is running
+	ErrJobRunning = errors.New("job is already running")
+)
+
 // randInt63n returns a random int64 in [0, n)
 // This is used for jitter calculations
 func randInt63n(n int64) int64 {
@@ -28,6 +37,21 @@ type scheduledJob struct {
 	config   JobConfig
 	nextRun  time.Time
 	timer    *time.Timer
+	state    jobState
+}
+
+// jobState is the outcome of the runs of a job so far, guarded by Scheduler.mu
+type jobState struct {
+	running             bool
+	runStarted          time.Time
+	lastRun             time.Time
+	lastSuccess         time.Time
+	lastDuration        time.Duration
+	lastError           string
+	consecutiveFailures int
+	runs                uint64
+	failures            uint64
+	skipped             uint64 // scheduled runs skipped because the job was still running
 }
 
 // Scheduler manages and executes scheduled jobs
@@ -118,16 +142,48 @@ func (s *Scheduler) scheduleJob(name string, sj *scheduledJob) {
 // executeJob runs a job and schedules the next execution
 func (s *Scheduler) executeJob(name string, sj *scheduledJob) {
 	// Check if scheduler is still running
-	s.mu.RLock()
+	s.mu.Lock()
 	if s.ctx.Err() != nil {
-		s.mu.RUnlock()
+		s.mu.Unlock()
 		return
 	}
-	s.mu.RUnlock()
+	started := s.begin(sj)
+	if !started {
+		sj.state.skipped++
+	}
+	s.mu.Unlock()
 
-	s.wg.Add(1)
-	defer s.wg.Done()
+	if started {
+		s.wg.Add(1)
+		s.run(name, sj, "scheduled")
+		s.wg.Done()
+	} else {
+		log.Warn("job still running, skipping scheduled run", "job", name)
+	}
+
+	// Schedule next run
+	s.mu.Lock()
+	sj.nextRun = sj.schedule.Next(time.Now())
+	log.Debug("scheduled next run",
+		"job", name,
+		"next_run", sj.nextRun.Format(time.RFC3339))
+	s.scheduleJob(name, sj)
+	s.mu.Unlock()
+}
+
+// begin marks a job as running. It returns false if it already is, so that
+// scheduled and manual runs of the same job never overlap. Callers hold s.mu.
+func (s *Scheduler) begin(sj *scheduledJob) bool {
+	if sj.state.running {
+		return false
+	}
+	sj.state.running = true
+	sj.state.runStarted = time.Now()
+	return true
+}
 
+// run executes a job that begin marked as running and records the outcome
+func (s *Scheduler) run(name string, sj *scheduledJob, trigger string) {
 	// Create context with timeout if configured
 	ctx := s.ctx
 	if sj.config.Timeout > 0 {
@@ -138,7 +194,7 @@ func (s *Scheduler) executeJob(name string, sj *scheduledJob) {
 
 	// Execute the job
 	start := time.Now()
-	log.Info("executing job", "job", name)
+	log.Info("executing job", "job", name, "trigger", trigger)
 
 	err := sj.job.Run(ctx)
 	duration := time.Since(start)
@@ -146,22 +202,32 @@ func (s *Scheduler) executeJob(name string, sj *scheduledJob) {
 	if err != nil {
 		log.Error("job failed",
 			"job", name,
+			"trigger", trigger,
 			"duration", duration,
 			"error", err)
 	} else {
 		log.Info("job completed successfully",
 			"job", name,
+			"trigger", trigger,
 			"duration", duration)
 	}
 
-	// Schedule next run
 	s.mu.Lock()
-	sj.nextRun = sj.schedule.Next(time.Now())
-	log.Debug("scheduled next run",
-		"job", name,
-		"next_run", sj.nextRun.Format(time.RFC3339))
-	s.scheduleJob(name, sj)
-	s.mu.Unlock()
+	defer s.mu.Unlock()
+	st := &sj.state
+	st.running = false
+	st.lastRun = start
+	st.lastDuration = duration
+	st.runs++
+	if err != nil {
+		st.lastError = err.Error()
+		st.consecutiveFailures++
+		st.failures++
+	} else {
+		st.lastError = ""
+		st.lastSuccess = start
+		st.consecutiveFailures = 0
+	}
 }
 
 // Stop gracefully stops the scheduler
@@ -204,42 +270,27 @@ func (s *Scheduler) Stop() error {
 	return nil
 }
 
-// RunJobNow manually triggers a job execution (non-blocking)
+// RunJobNow manually triggers a job execution (non-blocking). It returns
+// ErrJobNotFound for unknown jobs and ErrJobRunning if the job is running.
 func (s *Scheduler) RunJobNow(name string) error {
-	s.mu.RLock()
-	sj, exists := s.jobs[name]
-	s.mu.RUnlock()
+	s.mu.Lock()
+	defer s.mu.Unlock()
 
+	sj, exists := s.jobs[name]
 	if !exists {
-		return fmt.Errorf("job %s not found", name)
+		return fmt.Errorf("%w: %s", ErrJobNotFound, name)
+	}
+	if s.ctx == nil || s.ctx.Err() != nil {
+		return fmt.Errorf("scheduler not running")
+	}
+	if !s.begin(sj) {
+		return fmt.Errorf("%w: %s", ErrJobRunning, name)
 	}
 
 	s.wg.Add(1)
 	go func() {
 		defer s.wg.Done()
-
-		ctx := s.ctx
-		if sj.config.Timeout > 0 {
-			var cancel context.CancelFunc
-			ctx, cancel = context.WithTimeout(s.ctx, sj.config.Timeout)
-			defer cancel()
-		}
-
-		log.Info("manually executing job", "job", name)
-		start := time.Now()
-		err := sj.job.Run(ctx)
-		duration := time.Since(start)
-
-		if err != nil {
-			log.Error("manual execution of job failed",
-				"job", name,
-				"duration", duration,
-				"error", err)
-		} else {
-			log.Info("manual execution of job completed successfully",
-				"job", name,
-				"duration", duration)
-		}
+		s.run(name, sj, "manual")
 	}()
 
 	return nil
@@ -264,8 +315,72 @@ func (s *Scheduler) GetNextRun(name string) (time.Time, error) {
 
 	sj, exists := s.jobs[name]
 	if !exists {
-		return time.Time{}, fmt.Errorf("job %s not found", name)
+		return time.Time{}, fmt.Errorf("%w: %s", ErrJobNotFound, name)
 	}
 
 	return sj.nextRun, nil
 }
+
+// JobStatus is the schedule and run history of a registered job
+type JobStatus struct {
+	Name                string     `json:"name"`
+	Interval            string     `json:"interval,omitempty"`
+	Timeout             string     `json:"timeout,omitempty"`
+	NextRun             *time.Time `json:"next_run,omitempty"`
+	Running             bool       `json:"running"`
+	RunningFor          string     `json:"running_for,omitempty"`
+	LastRun             *time.Time `json:"last_run,omitempty"`
+	LastSuccess         *time.Time `json:"last_success,omitempty"`
+	LastDuration        string     `json:"last_duration,omitempty"`
+	LastError           string     `json:"last_error,omitempty"`
+	ConsecutiveFailures int        `json:"consecutive_failures"`
+	Runs                uint64     `json:"runs"`
+	Failures            uint64     `json:"failures"`
+	Skipped             uint64     `json:"skipped"`
+}
+
+// Status returns the status of all registered jobs, sorted by name
+func (s *Scheduler) Status() []JobStatus {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	now := time.Now()
+	statuses := make([]JobStatus, 0, len(s.jobs))
+	for name, sj := range s.jobs {
+		st := sj.state
+		status := JobStatus{
+			Name:                name,
+			Running:             st.running,
+			LastError:           st.lastError,
+			ConsecutiveFailures: st.consecutiveFailures,
+			Runs:                st.runs,
+			Failures:            st.failures,
+			Skipped:             st.skipped,
+		}
+		if is, ok := sj.schedule.(*IntervalSchedule); ok {
+			status.Interval = is.interval.String()
+		}
+		if sj.config.Timeout > 0 {
+			status.Timeout = sj.config.Timeout.String()
+		}
+		if !sj.nextRun.IsZero() {
+			next := sj.nextRun.UTC()
+			status.NextRun = &next
+		}
+		if st.running {
+			status.RunningFor = now.Sub(st.runStarted).Round(time.Millisecond).String()
+		}
+		if st.runs > 0 {
+			last := st.lastRun.UTC()
+			status.LastRun = &last
+			status.LastDuration = st.lastDuration.Round(time.Millisecond).String()
+		}
+		if !st.lastSuccess.IsZero() {
+			success := st.lastSuccess.UTC()
+			status.LastSuccess = &success
+		}
+		statuses = append(statuses, status)
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Name < statuses[j].Name })
+	return statuses
+}
@@ -3,6 +3,7 @@ package scheduler
 import (
 	"context"
 	"errors"
+	"strings"
 	"sync"
 	"testing"
 	"time"
@@ -371,3 +372,82 @@ func TestIntervalSchedule(t *testing.T) {
 		t.Errorf("Expected next run between %v and %v, got %v", minNext, maxNext, next)
 	}
 }
+
+func TestSchedulerStatusAndMetrics(t *testing.T) {
+	s := New()
+
+	release := make(chan struct{})
+	failing := &mockJob{name: "failing-job", shouldFail: true}
+	slow := &mockJob{
+		name: "slow-job",
+		runFunc: func(ctx context.Context) error {
+			<-release
+			return nil
+		},
+	}
+	for _, job := range []Job{failing, slow} {
+		if err := s.AddJob(job, NewIntervalSchedule(time.Hour), JobConfig{Enabled: true}); err != nil {
+			t.Fatalf("Failed to add job: %v", err)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := s.Start(ctx); err != nil {
+		t.Fatalf("Failed to start scheduler: %v", err)
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := s.RunJobNow("failing-job"); err != nil {
+			t.Fatalf("Failed to trigger job: %v", err)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	if err := s.RunJobNow("slow-job"); err != nil {
+		t.Fatalf("Failed to trigger job: %v", err)
+	}
+	if err := s.RunJobNow("slow-job"); !errors.Is(err, ErrJobRunning) {
+		t.Errorf("Expected ErrJobRunning for a running job, got %v", err)
+	}
+	if err := s.RunJobNow("non-existent"); !errors.Is(err, ErrJobNotFound) {
+		t.Errorf("Expected ErrJobNotFound, got %v", err)
+	}
+
+	status := s.Status()
+	if len(status) != 2 || status[0].Name != "failing-job" || status[1].Name != "slow-job" {
+		t.Fatalf("Expected both jobs sorted by name, got %+v", status)
+	}
+	if f := status[0]; f.ConsecutiveFailures != 2 || f.Runs != 2 || f.Failures != 2 || f.LastRun == nil || f.LastSuccess != nil || f.LastError != "mock job failed" {
+		t.Errorf("Unexpected failing job status: %+v", f)
+	}
+	if st := status[1]; !st.Running || st.Runs != 0 || st.Interval != "1h0m0s" || st.NextRun == nil {
+		t.Errorf("Unexpected slow job status: %+v", st)
+	}
+
+	var buf strings.Builder
+	s.WriteMetrics(&buf)
+	out := buf.String()
+	for _, want := range []string{
+		`bjorn2scan_job_consecutive_failures{job="failing-job"} 2`,
+		`bjorn2scan_job_consecutive_failures{job="slow-job"} 0`,
+		`bjorn2scan_job_runs_total{job="failing-job",result="failure"} 2`,
+		`bjorn2scan_job_last_run_timestamp_seconds{job="failing-job"}`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Expected metrics to contain %q, got:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, `bjorn2scan_job_last_success_timestamp_seconds{job="failing-job"}`) {
+		t.Error("Expected no last success for a job that never succeeded")
+	}
+
+	close(release)
+	time.Sleep(50 * time.Millisecond)
+	if st := s.Status()[1]; st.Running || st.Runs != 1 || st.LastSuccess == nil || st.ConsecutiveFailures != 0 {
+		t.Errorf("Unexpected slow job status after completion: %+v", st)
+	}
+
+	if err := s.Stop(); err != nil {
+		t.Fatalf("Failed to stop scheduler: %v", err)
+	}
+}
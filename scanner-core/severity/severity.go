@@ -0,0 +1,118 @@
+// Package severity defines the vulnerability severity scale and an optional
+// mapping that merges severities (e.g. Negligible into Low) for organizations
+// using a smaller scale.
+//
+// The mapping is applied once, when scan results are stored, so every
+// consumer of the stored data (queries, summaries, CSV exports, badges,
+// reports and metrics) sees the same severities without re-mapping. Raw
+// Grype documents (vulnerability downloads, scan hooks) keep the severities
+// Grype reported.
+package severity
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Severities as reported by Grype, most severe first
+const (
+	Critical   = "Critical"
+	High       = "High"
+	Medium     = "Medium"
+	Low        = "Low"
+	Negligible = "Negligible"
+	Unknown    = "Unknown"
+)
+
+// All is the full severity scale, most severe first
+var All = []string{Critical, High, Medium, Low, Negligible, Unknown}
+
+// Canonical returns the Grype spelling of a severity ("negligible" →
+// "Negligible"), or "" if s is not a known severity
+func Canonical(s string) string {
+	for _, level := range All {
+		if strings.EqualFold(strings.TrimSpace(s), level) {
+			return level
+		}
+	}
+	return ""
+}
+
+// Mapping replaces severities with other severities, keyed by the canonical
+// source severity. A nil Mapping leaves all severities unchanged.
+type Mapping map[string]string
+
+// ParseMapping parses a comma-separated list of from=to pairs, e.g.
+// "negligible=low,unknown=low". Names are case-insensitive. A severity that
+// is mapped to another cannot itself be a mapping target, so the mapping is
+// applied in one step. An empty spec returns a nil Mapping.
+func ParseMapping(spec string) (Mapping, error) {
+	if strings.TrimSpace(spec) == "" {
+		return nil, nil
+	}
+	m := Mapping{}
+	for _, pair := range strings.Split(spec, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		from, to, ok := strings.Cut(pair, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid severity mapping %q: expected from=to", pair)
+		}
+		fromLevel, toLevel := Canonical(from), Canonical(to)
+		if fromLevel == "" {
+			return nil, fmt.Errorf("invalid severity mapping %q: unknown severity %q", pair, strings.TrimSpace(from))
+		}
+		if toLevel == "" {
+			return nil, fmt.Errorf("invalid severity mapping %q: unknown severity %q", pair, strings.TrimSpace(to))
+		}
+		if _, dup := m[fromLevel]; dup {
+			return nil, fmt.Errorf("invalid severity mapping: %s is mapped more than once", fromLevel)
+		}
+		if fromLevel != toLevel {
+			m[fromLevel] = toLevel
+		}
+	}
+	for from, to := range m {
+		if _, mapped := m[to]; mapped {
+			return nil, fmt.Errorf("invalid severity mapping: %s is mapped to %s, which is itself mapped", from, to)
+		}
+	}
+	if len(m) == 0 {
+		return nil, nil
+	}
+	return m, nil
+}
+
+// Apply returns the severity s is reported as. Severities outside the scale
+// are returned unchanged.
+func (m Mapping) Apply(s string) string {
+	if to, ok := m[Canonical(s)]; ok {
+		return to
+	}
+	return s
+}
+
+// Levels returns the severities in use after mapping, most severe first
+func (m Mapping) Levels() []string {
+	levels := make([]string, 0, len(All))
+	for _, level := range All {
+		if _, mapped := m[level]; !mapped {
+			levels = append(levels, level)
+		}
+	}
+	return levels
+}
+
+// String returns the mapping in ParseMapping syntax, ordered by scale
+// ("Negligible=Low"); "" for no mapping
+func (m Mapping) String() string {
+	pairs := make([]string, 0, len(m))
+	for _, level := range All {
+		if to, ok := m[level]; ok {
+			pairs = append(pairs, level+"="+to)
+		}
+	}
+	return strings.Join(pairs, ",")
+}
@@ -0,0 +1,58 @@
+package severity
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseMapping(t *testing.T) {
+	tests := []struct {
+		spec    string
+		want    Mapping
+		wantErr bool
+	}{
+		{spec: "", want: nil},
+		{spec: "negligible=low", want: Mapping{Negligible: Low}},
+		{spec: " Negligible = LOW , unknown=low ", want: Mapping{Negligible: Low, Unknown: Low}},
+		{spec: "low=low", want: nil},
+		{spec: "negligible", wantErr: true},
+		{spec: "trivial=low", wantErr: true},
+		{spec: "negligible=minor", wantErr: true},
+		{spec: "negligible=low,negligible=medium", wantErr: true},
+		{spec: "negligible=low,low=medium", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			got, err := ParseMapping(tt.spec)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseMapping(%q) error = %v, wantErr %v", tt.spec, err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseMapping(%q) = %v, want %v", tt.spec, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMapping(t *testing.T) {
+	m := Mapping{Negligible: Low, Unknown: Low}
+
+	for in, want := range map[string]string{
+		"Negligible": Low, "negligible": Low, "Unknown": Low, "High": High, "weird": "weird",
+	} {
+		if got := m.Apply(in); got != want {
+			t.Errorf("Apply(%q) = %q, want %q", in, got, want)
+		}
+	}
+	if got, want := m.Levels(), []string{Critical, High, Medium, Low}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Levels() = %v, want %v", got, want)
+	}
+	if got := m.String(); got != "Negligible=Low,Unknown=Low" {
+		t.Errorf("String() = %q", got)
+	}
+
+	var none Mapping
+	if none.Apply("Negligible") != Negligible || len(none.Levels()) != len(All) || none.String() != "" {
+		t.Errorf("nil Mapping should leave severities unchanged")
+	}
+}
@@ -108,7 +108,7 @@ async function loadCVEsTable() {
             row.onclick = function() { showCVEDetails(vuln); };
 
             addCellToRow(row, 'left', vuln.vulnerability_severity || '');
-            addCellToRow(row, 'left', vuln.vulnerability_id || '');
+            addVulnerabilityIdCell(row, vuln);
             addCellToRow(row, 'left', vuln.artifact_name || '');
             addCellToRow(row, 'left', vuln.artifact_version || '');
             addCellToRow(row, 'left', vuln.vulnerability_fix_versions || '');
@@ -95,6 +95,13 @@ async function loadImageDetails(imageid) {
         document.getElementById('scan_status').textContent = data.status_description || 'Unknown';
         document.getElementById('vulns_scanned_at').textContent = formatTimestamp(data.vulns_scanned_at) || '-';
         document.getElementById('grype_db_built').textContent = formatTimestamp(data.grype_db_built) || '-';
+        const sbomSource = document.getElementById('sbom_source');
+        if (data.sbom_source === 'vendor-attested') {
+            sbomSource.textContent = 'Vendor attested';
+            sbomSource.title = 'Signed by ' + data.sbom_signer;
+        } else {
+            sbomSource.textContent = data.sbom_source === 'generated' ? 'Generated' : '-';
+        }
 
         populateStats(data);
 
@@ -179,7 +186,7 @@ async function loadVulnerabilitiesTable(imageid) {
             addCellToRow(row, 'left', vuln.vulnerability_severity || '');
 
             // Vulnerability ID
-            addCellToRow(row, 'left', vuln.vulnerability_id || '');
+            addVulnerabilityIdCell(row, vuln);
 
             // Artifact name
             addCellToRow(row, 'left', vuln.artifact_name || '');
@@ -559,6 +566,11 @@ function updateSBOMExportLinks() {
     const baseUrl = `/api/images/${encodeURIComponent(currentImageId)}/packages`;
     document.getElementById('sbomcsvlink').href = `${baseUrl}?${params}&format=csv`;
     document.getElementById('sbomjsonlink').href = `${baseUrl}?${params}&format=json`;
+
+    // Standard SBOM formats, converted from the stored Syft SBOM
+    const sbomUrl = `/api/sbom/${encodeURIComponent(currentImageId)}`;
+    document.getElementById('sbomcyclonedxlink').href = `${sbomUrl}?format=cyclonedx-json`;
+    document.getElementById('sbomspdxlink').href = `${sbomUrl}?format=spdx-json`;
 }
 
 // Modal functions for displaying JSON details
@@ -320,8 +320,8 @@ function renderVulnerabilitiesTable() {
         // Exploits (known_exploited) - count from CISA KEV catalog
         addCellToRow(row, 'right', formatNumber(vuln.known_exploited || 0));
 
-        // Count (number of instances of this vulnerability)
-        addCellToRow(row, 'right', formatNumber(vuln.count || 1));
+        // Count (number of distinct paths the vulnerable package was found at)
+        addCellToRow(row, 'right', formatNumber(vuln.occurrences || 1));
 
         tableBody.appendChild(row);
     });
@@ -12,6 +12,18 @@ let pageConfig = {
     renderRow: null         // Function to render a table row
 };
 
+// When the server requires authentication, send the browser to the login
+// page on any API response saying the session is missing or expired
+const originalFetch = window.fetch.bind(window);
+window.fetch = async function (...args) {
+    const response = await originalFetch(...args);
+    if (response.status === 401) {
+        const redirect = window.location.pathname + window.location.search;
+        window.location.href = '/api/auth/login?redirect=' + encodeURIComponent(redirect);
+    }
+    return response;
+};
+
 // State management
 let currentPage = 1;
 let pageSize = 50;
@@ -207,6 +219,9 @@ function buildQueryParams(includeFormat = false) {
     const osNames = getSelectedValues('osNameFilter');
     if (osNames.length) params.append('osNames', osNames.join(','));
 
+    const registries = getSelectedValues('registryFilter');
+    if (registries.length) params.append('registries', registries.join(','));
+
     if (includeFormat) {
         params.append('format', 'csv');
     }
@@ -236,6 +251,7 @@ async function loadFilterOptions() {
         populate('vulnerabilityStatusFilter', data.vulnStatuses);
         populate('packageTypeFilter', data.packageTypes);
         populate('osNameFilter', data.osNames);
+        populate('registryFilter', data.registries);
 
         initializeMultiselects();
 
@@ -250,7 +266,8 @@ function initializeMultiselects() {
         { id: 'namespaceFilter', placeholder: 'All namespaces' },
         { id: 'vulnerabilityStatusFilter', placeholder: 'All statuses' },
         { id: 'packageTypeFilter', placeholder: 'All package types' },
-        { id: 'osNameFilter', placeholder: 'All distributions' }
+        { id: 'osNameFilter', placeholder: 'All distributions' },
+        { id: 'registryFilter', placeholder: 'All registries' }
     ];
 
     filters.forEach(filter => {
@@ -270,7 +287,8 @@ function applyUrlFilters() {
         { params: ['namespace', 'namespaces'], filterId: 'namespaceFilter' },
         { params: ['vulnStatus', 'vulnStatuses'], filterId: 'vulnerabilityStatusFilter' },
         { params: ['packageType', 'packageTypes'], filterId: 'packageTypeFilter' },
-        { params: ['osName', 'osNames'], filterId: 'osNameFilter' }
+        { params: ['osName', 'osNames'], filterId: 'osNameFilter' },
+        { params: ['registry', 'registries'], filterId: 'registryFilter' }
     ];
 
     filterMappings.forEach(mapping => {
@@ -319,8 +337,61 @@ function addCellToRow(row, align, text) {
     return cell;
 }
 
+// Add a vulnerability ID cell, showing aliases (e.g. the CVEs behind an RHSA
+// advisory) in a muted line below the reported identifier, followed by the
+// analyst note on the vulnerability if there is one
+function addVulnerabilityIdCell(row, vuln) {
+    const cell = addCellToRow(row, 'left', vuln.vulnerability_id || '');
+    if (vuln.vulnerability_aliases) {
+        const aliases = document.createElement('div');
+        aliases.className = 'vuln-aliases';
+        aliases.textContent = vuln.vulnerability_aliases;
+        cell.appendChild(aliases);
+    }
+    if (vuln.cve_annotation) {
+        const note = document.createElement('div');
+        note.className = 'vuln-annotation';
+        note.textContent = vuln.cve_annotation;
+        cell.appendChild(note);
+    }
+    return cell;
+}
+
 // ===== Listing-table cell helpers used by images / containers / nodes =====
 
+// Add the SLSA build level of an image's provenance ("unsigned" for level 1,
+// whose signature was not verified with a trusted key, "L2".."L3", "none" when
+// checked without finding provenance, a dash when not checked yet), with the
+// builder as tooltip
+function addSLSACell(row, level, builder) {
+    if (level === null || level === undefined) {
+        return addNumOrDash(row, 0);
+    }
+    let label = 'none';
+    if (level === 1) {
+        label = 'unsigned';
+    } else if (level > 1) {
+        label = 'L' + level;
+    }
+    const cell = addCellToRow(row, 'right', label);
+    if (builder) {
+        cell.title = level === 1 ? builder + ' (SLSA L1, signature not verified)' : builder;
+    }
+    return cell;
+}
+
+// Add the size of an image (a dash when unknown), with its layer count as tooltip
+function addImageSizeCell(row, size, layers) {
+    if (!size) {
+        return addNumOrDash(row, 0);
+    }
+    const cell = addCellToRow(row, 'right', formatBytes(size));
+    if (layers) {
+        cell.title = layers + (layers === 1 ? ' layer' : ' layers');
+    }
+    return cell;
+}
+
 // Append a right-aligned numeric cell. Renders 0 as a muted "—".
 // Returns the <td> so callers can attach a className or title.
 function addNumOrDash(row, value) {
@@ -422,6 +493,18 @@ function formatRiskNumber(risk) {
     return risk.toLocaleString('en-US', { minimumFractionDigits: 1, maximumFractionDigits: 1 });
 }
 
+// Format a size in bytes with a binary unit (e.g. "142.3 MB")
+function formatBytes(bytes) {
+    const units = ['B', 'KB', 'MB', 'GB', 'TB'];
+    let value = bytes || 0;
+    let unit = 0;
+    while (value >= 1024 && unit < units.length - 1) {
+        value /= 1024;
+        unit++;
+    }
+    return (unit === 0 ? value : value.toFixed(1)) + ' ' + units[unit];
+}
+
 // Format timestamp to a readable date/time string
 // Input is expected to be an ISO 8601 / RFC 3339 timestamp string
 function formatTimestamp(timestamp) {
@@ -697,10 +780,34 @@ async function loadConfig() {
         console.error('Error loading config:', error);
     }
 
+    await loadUIConfig();
+
     // Start monitoring DB initialization status
     checkDBStatus();
 }
 
+// Hide controls the server does not allow (e.g. in read-only mode). Elements
+// name their control in data-ui-control, matching a /api/ui-config field.
+async function loadUIConfig() {
+    try {
+        const response = await fetch('/api/ui-config');
+        if (!response.ok) return; // Endpoint not present - show everything
+
+        appConfig.ui = await response.json();
+        applyUIConfig(appConfig.ui);
+    } catch (error) {
+        console.error('Error loading UI config:', error);
+    }
+}
+
+function applyUIConfig(uiConfig) {
+    document.querySelectorAll('[data-ui-control]').forEach(el => {
+        if (uiConfig[el.dataset.uiControl] === false) {
+            el.style.display = 'none';
+        }
+    });
+}
+
 // Poll /api/db/status and show a banner while the vulnerability database is initializing.
 // Removes itself automatically once the database becomes available.
 async function checkDBStatus() {
@@ -760,6 +867,9 @@ function getCurrentFilterQueryString() {
     const osNames = getSelectedValues('osNameFilter');
     if (osNames.length) params.append('osNames', osNames.join(','));
 
+    const registries = getSelectedValues('registryFilter');
+    if (registries.length) params.append('registries', registries.join(','));
+
     // Severity exists only on the CVE pages (no severityFilter elsewhere → []).
     // The deployment-metrics / node-metrics summaries now honor it.
     const severities = getSelectedValues('severityFilter');
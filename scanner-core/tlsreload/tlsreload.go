@@ -0,0 +1,225 @@
+// Package tlsreload serves TLS from certificate files that are renewed in
+// place (cert-manager, mounted Kubernetes secrets), picking up new
+// certificates and CA bundles without a restart.
+package tlsreload
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+// checkInterval is how often the files are checked for changes. Checks
+// happen during handshakes, so an idle server does not poll.
+const checkInterval = 30 * time.Second
+
+// Files are the PEM files of a TLS endpoint
+type Files struct {
+	CertFile string // Certificate chain presented to peers
+	KeyFile  string // Private key of the certificate
+	CAFile   string // CA bundle peers are verified against (empty: no verification of clients, system roots for servers)
+}
+
+// Reloader holds the certificate and CA pool loaded from Files, reloading
+// them when the files change
+type Reloader struct {
+	files Files
+	log   *slog.Logger
+
+	mu        sync.RWMutex
+	cert      *tls.Certificate
+	pool      *x509.CertPool
+	modTimes  [3]time.Time
+	lastCheck time.Time
+}
+
+// New loads the files, failing if they are missing or invalid. CertFile and
+// KeyFile are optional for clients that only verify servers against CAFile.
+func New(files Files) (*Reloader, error) {
+	if (files.CertFile == "") != (files.KeyFile == "") {
+		return nil, errors.New("TLS certificate and key files must be set together")
+	}
+	if files.CertFile == "" && files.CAFile == "" {
+		return nil, errors.New("no TLS certificate or CA file configured")
+	}
+	r := &Reloader{files: files, log: slog.Default().With("component", "tls")}
+	if err := r.load(); err != nil {
+		return nil, err
+	}
+	r.lastCheck = time.Now()
+	return r, nil
+}
+
+// load reads the files and replaces the certificate and pool
+func (r *Reloader) load() error {
+	modTimes, err := r.stat()
+	if err != nil {
+		return err
+	}
+	var cert *tls.Certificate
+	if r.files.CertFile != "" {
+		c, err := tls.LoadX509KeyPair(r.files.CertFile, r.files.KeyFile)
+		if err != nil {
+			return fmt.Errorf("failed to load TLS certificate: %w", err)
+		}
+		cert = &c
+	}
+	var pool *x509.CertPool
+	if r.files.CAFile != "" {
+		data, err := os.ReadFile(r.files.CAFile)
+		if err != nil {
+			return fmt.Errorf("failed to read TLS CA file: %w", err)
+		}
+		pool = x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(data) {
+			return fmt.Errorf("no PEM certificates found in %s", r.files.CAFile)
+		}
+	}
+	r.mu.Lock()
+	r.cert, r.pool, r.modTimes = cert, pool, modTimes
+	r.mu.Unlock()
+	return nil
+}
+
+// stat returns the modification times of the configured files. Stat follows
+// symlinks, so the atomic symlink swap of secret volumes shows up as a change.
+func (r *Reloader) stat() ([3]time.Time, error) {
+	var modTimes [3]time.Time
+	for i, file := range []string{r.files.CertFile, r.files.KeyFile, r.files.CAFile} {
+		if file == "" {
+			continue
+		}
+		info, err := os.Stat(file)
+		if err != nil {
+			return modTimes, fmt.Errorf("failed to read TLS file: %w", err)
+		}
+		modTimes[i] = info.ModTime()
+	}
+	return modTimes, nil
+}
+
+// refresh reloads the files if they changed since the last load, at most
+// once per checkInterval. A failed reload keeps serving the previous
+// certificate, since renewals write the files one at a time.
+func (r *Reloader) refresh() {
+	r.mu.Lock()
+	if time.Since(r.lastCheck) < checkInterval {
+		r.mu.Unlock()
+		return
+	}
+	r.lastCheck = time.Now()
+	previous := r.modTimes
+	r.mu.Unlock()
+
+	modTimes, err := r.stat()
+	if err != nil {
+		r.log.Warn("failed to check TLS files, keeping the current certificate", "error", err)
+		return
+	}
+	if modTimes == previous {
+		return
+	}
+	if err := r.load(); err != nil {
+		r.log.Warn("failed to reload TLS files, keeping the current certificate", "error", err)
+		return
+	}
+	r.log.Info("reloaded TLS certificate", "cert", r.files.CertFile, "ca", r.files.CAFile)
+}
+
+// current returns the certificate and pool, reloading them if needed
+func (r *Reloader) current() (*tls.Certificate, *x509.CertPool) {
+	r.refresh()
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.cert, r.pool
+}
+
+// ServerConfig returns the TLS configuration of a server. With a CAFile,
+// client certificates signed by it are verified when presented; wrap the
+// handler with RequireClientCert to make them mandatory.
+func (r *Reloader) ServerConfig() *tls.Config {
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			cert, pool := r.current()
+			if cert == nil {
+				return nil, errors.New("no TLS certificate configured")
+			}
+			cfg := &tls.Config{
+				MinVersion:   tls.VersionTLS12,
+				Certificates: []tls.Certificate{*cert},
+			}
+			if pool != nil {
+				cfg.ClientCAs = pool
+				cfg.ClientAuth = tls.VerifyClientCertIfGiven
+			}
+			return cfg, nil
+		},
+	}
+}
+
+// ClientConfig returns the TLS configuration of a client that verifies the
+// server certificate for serverName against CAFile (the system roots
+// without one) and presents the certificate, if any, for mutual TLS.
+// Servers addressed by IP, like pods, need serverName set to a name in their
+// certificate.
+func (r *Reloader) ClientConfig(serverName string) *tls.Config {
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		// The CA pool may change after the config is built, so the chain is
+		// verified in VerifyConnection against the current pool instead
+		InsecureSkipVerify: true, //nolint:gosec // verified in VerifyConnection
+		VerifyConnection: func(cs tls.ConnectionState) error {
+			_, pool := r.current()
+			name := serverName
+			if name == "" {
+				name = cs.ServerName
+			}
+			if len(cs.PeerCertificates) == 0 {
+				return errors.New("server presented no certificate")
+			}
+			intermediates := x509.NewCertPool()
+			for _, cert := range cs.PeerCertificates[1:] {
+				intermediates.AddCert(cert)
+			}
+			_, err := cs.PeerCertificates[0].Verify(x509.VerifyOptions{
+				DNSName:       name,
+				Roots:         pool,
+				Intermediates: intermediates,
+			})
+			return err
+		},
+		GetClientCertificate: func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			cert, _ := r.current()
+			if cert == nil {
+				return &tls.Certificate{}, nil
+			}
+			return cert, nil
+		},
+	}
+}
+
+// RequireClientCert rejects requests without a verified client certificate,
+// except to the exempt paths (health probes, which the kubelet sends without
+// one)
+func RequireClientCert(next http.Handler, exempt ...string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.TLS != nil && len(r.TLS.VerifiedChains) > 0 {
+			next.ServeHTTP(w, r)
+			return
+		}
+		for _, path := range exempt {
+			if r.URL.Path == path {
+				next.ServeHTTP(w, r)
+				return
+			}
+		}
+		http.Error(w, "client certificate required", http.StatusUnauthorized)
+	})
+}
@@ -0,0 +1,180 @@
+// Package transfer defines the portable bundle format for image scan results.
+// A bundle carries the SBOM, the vulnerability report and scan metadata for a
+// single image digest, so results produced in one environment (a staging
+// cluster, a CI job, a shared cache) can be reused in another without rescanning.
+package transfer
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/bvboe/b2s-go/scanner-core/database"
+)
+
+// BundleVersion is the format version of scan result bundles
+const BundleVersion = 1
+
+// signaturePrefix identifies the signing algorithm in the bundle signature
+const signaturePrefix = "hmac-sha256:"
+
+// ErrInvalidSignature is returned when a bundle signature does not match its payload
+var ErrInvalidSignature = errors.New("invalid bundle signature")
+
+// Bundle contains everything needed to reuse scan results for an image
+// in another environment: the SBOM, the vulnerability report, and scan metadata.
+// Source, DeploymentUUID and Labels identify the exporting deployment.
+// Annotations carries the analyst notes on the image's vulnerabilities.
+type Bundle struct {
+	Version               int                          `json:"version"`
+	ExportedAt            string                       `json:"exported_at"`
+	Source                string                       `json:"source,omitempty"`
+	DeploymentUUID        string                       `json:"deployment_uuid,omitempty"`
+	Labels                map[string]string            `json:"labels,omitempty"`
+	Image                 database.ImageTransferRecord `json:"image"`
+	SBOM                  json.RawMessage              `json:"sbom"`
+	Vulnerabilities       json.RawMessage              `json:"vulnerabilities"`
+	SBOMSHA256            string                       `json:"sbom_sha256,omitempty"`
+	VulnerabilitiesSHA256 string                       `json:"vulnerabilities_sha256,omitempty"`
+	Annotations           []database.CVEAnnotation     `json:"annotations,omitempty"`
+}
+
+// SignedBundle wraps a bundle with its signature.
+// Payload is kept raw so the signature is verified over the exact bytes that were signed.
+type SignedBundle struct {
+	Payload   json.RawMessage `json:"payload"`
+	Signature string          `json:"signature,omitempty"`
+}
+
+// NewBundle creates a bundle for the given scan results, including content checksums.
+// The documents are compacted first because that is how they are encoded in the
+// payload, and the checksums must match what the receiver decodes.
+func NewBundle(record database.ImageTransferRecord, source string, sbomJSON, vulnJSON []byte) (*Bundle, error) {
+	sbomJSON, err := compact(sbomJSON)
+	if err != nil {
+		return nil, fmt.Errorf("invalid SBOM JSON: %w", err)
+	}
+	vulnJSON, err = compact(vulnJSON)
+	if err != nil {
+		return nil, fmt.Errorf("invalid vulnerabilities JSON: %w", err)
+	}
+
+	return &Bundle{
+		Version:               BundleVersion,
+		ExportedAt:            time.Now().UTC().Format(time.RFC3339),
+		Source:                source,
+		Image:                 record,
+		SBOM:                  sbomJSON,
+		Vulnerabilities:       vulnJSON,
+		SBOMSHA256:            checksum(sbomJSON),
+		VulnerabilitiesSHA256: checksum(vulnJSON),
+	}, nil
+}
+
+// GrypeDBBuilt returns the vulnerability database build time recorded in the bundle (zero if unknown)
+func (b *Bundle) GrypeDBBuilt() time.Time {
+	if b.Image.GrypeDBBuilt == "" {
+		return time.Time{}
+	}
+	t, _ := time.Parse(time.RFC3339, b.Image.GrypeDBBuilt)
+	return t
+}
+
+// Validate checks that the bundle is complete and its content matches the recorded checksums
+func (b *Bundle) Validate() error {
+	if b.Version != BundleVersion {
+		return fmt.Errorf("unsupported bundle version %d", b.Version)
+	}
+	if len(b.Image.Digest) < 8 || b.Image.Digest[:7] != "sha256:" {
+		return fmt.Errorf("bundle must contain a sha256 image digest")
+	}
+	if len(b.SBOM) == 0 || len(b.Vulnerabilities) == 0 {
+		return fmt.Errorf("bundle must contain an SBOM and vulnerabilities")
+	}
+	if b.SBOMSHA256 != "" && b.SBOMSHA256 != checksum(b.SBOM) {
+		return fmt.Errorf("SBOM checksum mismatch")
+	}
+	if b.VulnerabilitiesSHA256 != "" && b.VulnerabilitiesSHA256 != checksum(b.Vulnerabilities) {
+		return fmt.Errorf("vulnerabilities checksum mismatch")
+	}
+	return nil
+}
+
+// Seal encodes the bundle and signs it with the key (unsigned when key is empty)
+func Seal(bundle *Bundle, key string) ([]byte, error) {
+	payload, err := marshal(bundle)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode bundle: %w", err)
+	}
+
+	signed := SignedBundle{Payload: payload}
+	if key != "" {
+		signed.Signature = Sign(key, payload)
+	}
+	return marshal(signed)
+}
+
+// Open decodes a sealed bundle, verifies its signature against the key and
+// validates its content. An empty key skips signature verification.
+func Open(data []byte, key string) (*Bundle, error) {
+	var signed SignedBundle
+	if err := json.Unmarshal(data, &signed); err != nil || len(signed.Payload) == 0 {
+		return nil, fmt.Errorf("invalid bundle")
+	}
+	if key != "" && !Verify(key, signed.Payload, signed.Signature) {
+		return nil, ErrInvalidSignature
+	}
+
+	var bundle Bundle
+	if err := json.Unmarshal(signed.Payload, &bundle); err != nil {
+		return nil, fmt.Errorf("invalid bundle payload: %w", err)
+	}
+	if err := bundle.Validate(); err != nil {
+		return nil, err
+	}
+	return &bundle, nil
+}
+
+// Sign returns the signature for a bundle payload
+func Sign(key string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(key))
+	mac.Write(payload)
+	return signaturePrefix + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify checks that the signature matches the payload
+func Verify(key string, payload []byte, signature string) bool {
+	return hmac.Equal([]byte(Sign(key, payload)), []byte(signature))
+}
+
+// compact removes insignificant whitespace from a JSON document
+func compact(data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := json.Compact(&buf, data); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// marshal encodes v without HTML escaping, so embedded documents keep the exact
+// bytes their checksums were computed over
+func marshal(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
+
+// checksum returns the hex-encoded SHA-256 of the data
+func checksum(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,71 @@
+package transfer
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/bvboe/b2s-go/scanner-core/database"
+)
+
+func testBundle(t *testing.T) *Bundle {
+	t.Helper()
+	record := database.ImageTransferRecord{
+		Digest:       "sha256:abcdef0123456789",
+		Status:       "completed",
+		GrypeDBBuilt: "2026-01-02T03:04:05Z",
+	}
+	// Indented JSON with HTML characters must survive the round trip unchanged
+	sbom := []byte("{\n  \"artifacts\": [{\"name\": \"<openssl>\"}]\n}")
+	vulns := []byte(`{"matches": [{"vulnerability": {"id": "CVE-2024-0001 & more"}}]}`)
+	bundle, err := NewBundle(record, "staging", sbom, vulns)
+	if err != nil {
+		t.Fatalf("NewBundle() error = %v", err)
+	}
+	return bundle
+}
+
+func TestSealOpenRoundTrip(t *testing.T) {
+	bundle := testBundle(t)
+
+	sealed, err := Seal(bundle, "secret")
+	if err != nil {
+		t.Fatalf("Seal() error = %v", err)
+	}
+	opened, err := Open(sealed, "secret")
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+
+	if !bytes.Equal(opened.SBOM, bundle.SBOM) {
+		t.Errorf("SBOM = %s, want %s", opened.SBOM, bundle.SBOM)
+	}
+	if opened.Source != "staging" {
+		t.Errorf("Source = %q, want staging", opened.Source)
+	}
+	if want := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC); !opened.GrypeDBBuilt().Equal(want) {
+		t.Errorf("GrypeDBBuilt() = %s, want %s", opened.GrypeDBBuilt(), want)
+	}
+}
+
+func TestOpenRejectsTamperedBundles(t *testing.T) {
+	sealed, err := Seal(testBundle(t), "secret")
+	if err != nil {
+		t.Fatalf("Seal() error = %v", err)
+	}
+
+	if _, err := Open(sealed, "other"); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("Open() with wrong key error = %v, want ErrInvalidSignature", err)
+	}
+
+	tampered := bytes.Replace(sealed, []byte("openssl"), []byte("libressl"), 1)
+	if _, err := Open(tampered, "secret"); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("Open() of tampered bundle error = %v, want ErrInvalidSignature", err)
+	}
+
+	// Without a key only the checksums protect the content
+	if _, err := Open(tampered, ""); err == nil {
+		t.Error("Open() of tampered unsigned bundle should fail checksum validation")
+	}
+}
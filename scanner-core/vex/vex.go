@@ -0,0 +1,495 @@
+// Package vex reads OpenVEX documents (https://openvex.dev) and decides which
+// vulnerability findings they declare not to affect an image, so that vendor
+// acknowledged false positives can be suppressed.
+//
+// A document looks like:
+//
+//	{
+//	  "@context": "https://openvex.dev/ns/v0.2.0",
+//	  "@id": "https://example.com/vex/2024-001",
+//	  "author": "security@example.com",
+//	  "timestamp": "2024-05-01T12:00:00Z",
+//	  "version": 1,
+//	  "statements": [{
+//	    "vulnerability": {"name": "CVE-2023-1234"},
+//	    "products": [{
+//	      "@id": "pkg:oci/app@sha256%3Aabc...",
+//	      "subcomponents": [{"@id": "pkg:golang/example.com/lib@v1.2.3"}]
+//	    }],
+//	    "status": "not_affected",
+//	    "justification": "vulnerable_code_not_in_execute_path"
+//	  }]
+//	}
+//
+// Products are images, identified by a pkg:oci purl, a digest or an image
+// reference (with or without tag), or packages identified by purl, which then
+// apply in every image. Subcomponents narrow a statement on an image to the
+// packages listed. When several statements apply to a finding the most recent
+// one decides, so a later "affected" statement lifts an earlier "not_affected".
+package vex
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Statuses of a statement (only not_affected suppresses findings)
+const (
+	StatusNotAffected        = "not_affected"
+	StatusAffected           = "affected"
+	StatusFixed              = "fixed"
+	StatusUnderInvestigation = "under_investigation"
+)
+
+// contextPrefix is the @context every OpenVEX document starts with
+const contextPrefix = "https://openvex.dev/ns"
+
+// Document is an OpenVEX document
+type Document struct {
+	Context    string      `json:"@context"`
+	ID         string      `json:"@id"`
+	Author     string      `json:"author"`
+	Timestamp  time.Time   `json:"timestamp"`
+	Version    int         `json:"version"`
+	Statements []Statement `json:"statements"`
+}
+
+// Statement declares the status of a vulnerability in products
+type Statement struct {
+	Vulnerability   Vulnerability `json:"vulnerability"`
+	Products        []Product     `json:"products"`
+	Status          string        `json:"status"`
+	Justification   string        `json:"justification,omitempty"`
+	ImpactStatement string        `json:"impact_statement,omitempty"`
+	ActionStatement string        `json:"action_statement,omitempty"`
+	Timestamp       *time.Time    `json:"timestamp,omitempty"`
+}
+
+// Vulnerability names the vulnerability of a statement. Older documents give
+// it as a plain string.
+type Vulnerability struct {
+	Name    string   `json:"name"`
+	Aliases []string `json:"aliases,omitempty"`
+}
+
+// UnmarshalJSON accepts the object and the plain string forms
+func (v *Vulnerability) UnmarshalJSON(data []byte) error {
+	if bytes.HasPrefix(bytes.TrimSpace(data), []byte(`"`)) {
+		return json.Unmarshal(data, &v.Name)
+	}
+	type plain Vulnerability
+	return json.Unmarshal(data, (*plain)(v))
+}
+
+// Product is an image or package a statement applies to. Older documents give
+// it as a plain string.
+type Product struct {
+	ID            string      `json:"@id"`
+	Identifiers   Identifiers `json:"identifiers,omitempty"`
+	Subcomponents []Product   `json:"subcomponents,omitempty"`
+}
+
+// Identifiers are alternative identifiers of a product
+type Identifiers struct {
+	PURL string `json:"purl,omitempty"`
+}
+
+// UnmarshalJSON accepts the object and the plain string forms
+func (p *Product) UnmarshalJSON(data []byte) error {
+	if bytes.HasPrefix(bytes.TrimSpace(data), []byte(`"`)) {
+		return json.Unmarshal(data, &p.ID)
+	}
+	type plain Product
+	return json.Unmarshal(data, (*plain)(p))
+}
+
+// identifier returns the product's @id, or its purl when it has none
+func (p Product) identifier() string {
+	if p.ID != "" {
+		return p.ID
+	}
+	return p.Identifiers.PURL
+}
+
+// Parse parses and validates an OpenVEX document
+func Parse(data []byte) (*Document, error) {
+	var doc Document
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("invalid OpenVEX document: %w", err)
+	}
+	if !strings.HasPrefix(doc.Context, contextPrefix) {
+		return nil, fmt.Errorf("invalid OpenVEX document: @context %q is not %s/...", doc.Context, contextPrefix)
+	}
+	if len(doc.Statements) == 0 {
+		return nil, fmt.Errorf("invalid OpenVEX document: no statements")
+	}
+	for i, s := range doc.Statements {
+		if err := s.validate(); err != nil {
+			return nil, fmt.Errorf("invalid OpenVEX document: statement %d: %w", i+1, err)
+		}
+	}
+	return &doc, nil
+}
+
+// validate checks the fields OpenVEX requires of a statement
+func (s Statement) validate() error {
+	if strings.TrimSpace(s.Vulnerability.Name) == "" {
+		return fmt.Errorf("vulnerability name is required")
+	}
+	if len(s.Products) == 0 {
+		return fmt.Errorf("no products")
+	}
+	for _, p := range s.Products {
+		if p.identifier() == "" {
+			return fmt.Errorf("product without @id")
+		}
+	}
+	switch s.Status {
+	case StatusNotAffected:
+		if s.Justification == "" && s.ImpactStatement == "" {
+			return fmt.Errorf("not_affected requires a justification or impact_statement")
+		}
+	case StatusAffected, StatusFixed, StatusUnderInvestigation:
+	default:
+		return fmt.Errorf("unknown status %q", s.Status)
+	}
+	return nil
+}
+
+// ReadFiles reads the OpenVEX documents at path: a file, or the *.json files
+// of a directory such as a mounted ConfigMap (hidden entries are skipped).
+// Returns the contents by file name; every document must parse.
+func ReadFiles(path string) (map[string][]byte, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read VEX path: %w", err)
+	}
+	paths := []string{path}
+	if info.IsDir() {
+		entries, err := os.ReadDir(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read VEX directory: %w", err)
+		}
+		paths = paths[:0]
+		for _, e := range entries {
+			if strings.HasPrefix(e.Name(), ".") || filepath.Ext(e.Name()) != ".json" {
+				continue
+			}
+			paths = append(paths, filepath.Join(path, e.Name()))
+		}
+	}
+
+	files := make(map[string][]byte, len(paths))
+	for _, p := range paths {
+		data, err := os.ReadFile(p)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read VEX document: %w", err)
+		}
+		if _, err := Parse(data); err != nil {
+			return nil, fmt.Errorf("%s: %w", filepath.Base(p), err)
+		}
+		files[filepath.Base(p)] = data
+	}
+	return files, nil
+}
+
+// Source is a parsed document and where it came from
+type Source struct {
+	Name     string // e.g. the file name, or "api:<id>" for uploads
+	Document *Document
+}
+
+// Finding is an image's vulnerability finding to look up
+type Finding struct {
+	Vulnerability string   // ID reported by Grype
+	Aliases       []string // related vulnerability IDs (e.g. the CVE of a GHSA)
+	Digest        string   // image digest
+	References    []string // references the image runs under
+	Package       string   // package name
+	PURL          string   // package purl, if Grype reported one
+}
+
+// Match is the statement deciding a finding
+type Match struct {
+	Source          string    `json:"source"`
+	DocumentID      string    `json:"document_id,omitempty"`
+	Vulnerability   string    `json:"vulnerability"`
+	Status          string    `json:"status"`
+	Justification   string    `json:"justification,omitempty"`
+	ImpactStatement string    `json:"impact_statement,omitempty"`
+	Timestamp       time.Time `json:"timestamp"`
+}
+
+// entry is a statement indexed under one of its vulnerability IDs
+type entry struct {
+	statement *Statement
+	match     Match
+	order     int
+}
+
+// Index looks up the statements applying to findings
+type Index struct {
+	byVuln      map[string][]entry
+	fingerprint string
+}
+
+// NewIndex indexes the statements of the sources by vulnerability ID
+func NewIndex(sources ...Source) *Index {
+	ix := &Index{byVuln: map[string][]entry{}}
+	h := sha256.New()
+	order := 0
+	for _, src := range sources {
+		if src.Document == nil {
+			continue
+		}
+		doc := src.Document
+		canonical, _ := json.Marshal(doc)
+		fmt.Fprintf(h, "%s\n%s\n", src.Name, canonical)
+		for i := range doc.Statements {
+			s := &doc.Statements[i]
+			ts := doc.Timestamp
+			if s.Timestamp != nil {
+				ts = *s.Timestamp
+			}
+			e := entry{statement: s, order: order, match: Match{
+				Source:          src.Name,
+				DocumentID:      doc.ID,
+				Vulnerability:   s.Vulnerability.Name,
+				Status:          s.Status,
+				Justification:   s.Justification,
+				ImpactStatement: s.ImpactStatement,
+				Timestamp:       ts,
+			}}
+			order++
+			for _, id := range append([]string{s.Vulnerability.Name}, s.Vulnerability.Aliases...) {
+				key := strings.ToUpper(strings.TrimSpace(id))
+				if key != "" {
+					ix.byVuln[key] = append(ix.byVuln[key], e)
+				}
+			}
+		}
+	}
+	if len(ix.byVuln) > 0 {
+		ix.fingerprint = hex.EncodeToString(h.Sum(nil))
+	}
+	return ix
+}
+
+// Empty reports whether the index has no statements
+func (ix *Index) Empty() bool {
+	return ix == nil || len(ix.byVuln) == 0
+}
+
+// Fingerprint identifies the indexed statements; it is empty without any
+func (ix *Index) Fingerprint() string {
+	if ix == nil {
+		return ""
+	}
+	return ix.fingerprint
+}
+
+// Vulnerabilities returns the vulnerability IDs and aliases with statements,
+// upper case and sorted
+func (ix *Index) Vulnerabilities() []string {
+	if ix == nil {
+		return nil
+	}
+	ids := make([]string, 0, len(ix.byVuln))
+	for id := range ix.byVuln {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// Lookup returns the most recent statement applying to a finding
+func (ix *Index) Lookup(f Finding) (Match, bool) {
+	if ix.Empty() {
+		return Match{}, false
+	}
+	var best *entry
+	seen := map[int]bool{}
+	for _, id := range append([]string{f.Vulnerability}, f.Aliases...) {
+		for _, e := range ix.byVuln[strings.ToUpper(id)] {
+			if seen[e.order] || !e.statement.appliesTo(f) {
+				continue
+			}
+			seen[e.order] = true
+			if best == nil || e.match.Timestamp.After(best.match.Timestamp) ||
+				(e.match.Timestamp.Equal(best.match.Timestamp) && e.order > best.order) {
+				e := e
+				best = &e
+			}
+		}
+	}
+	if best == nil {
+		return Match{}, false
+	}
+	return best.match, true
+}
+
+// Suppresses returns the not_affected statement deciding a finding
+func (ix *Index) Suppresses(f Finding) (Match, bool) {
+	m, ok := ix.Lookup(f)
+	if !ok || m.Status != StatusNotAffected {
+		return Match{}, false
+	}
+	return m, true
+}
+
+// appliesTo reports whether one of the statement's products covers the finding
+func (s *Statement) appliesTo(f Finding) bool {
+	for _, p := range s.Products {
+		id := p.identifier()
+		if matchesImage(id, f) {
+			if len(p.Subcomponents) == 0 {
+				return true
+			}
+			for _, c := range p.Subcomponents {
+				if matchesPackage(c.identifier(), f) {
+					return true
+				}
+			}
+			continue
+		}
+		if strings.HasPrefix(id, "pkg:") && !strings.HasPrefix(id, "pkg:oci/") && matchesPackage(id, f) {
+			return true
+		}
+	}
+	return false
+}
+
+// MatchesImage reports whether an image identifier names the finding's image:
+// a digest, an image reference (a repository without tag covers every tag),
+// or a pkg:oci purl
+func MatchesImage(id string, f Finding) bool {
+	return matchesImage(strings.TrimSpace(id), f)
+}
+
+// MatchesPackage reports whether a package name or purl (without version for
+// every version) names the finding's package
+func MatchesPackage(id string, f Finding) bool {
+	return matchesPackage(strings.TrimSpace(id), f)
+}
+
+// matchesImage reports whether a product identifier names the finding's image:
+// a pkg:oci purl (with the digest as version, or any digest of the repository
+// without one), a digest, or an image reference
+func matchesImage(id string, f Finding) bool {
+	if strings.HasPrefix(id, "pkg:oci/") {
+		name, version, qualifiers := splitPURL(id)
+		if version != "" {
+			return version == f.Digest
+		}
+		repo := name
+		if repoURL := qualifiers.Get("repository_url"); repoURL != "" {
+			repo = repoURL
+		}
+		for _, ref := range f.References {
+			r := repository(ref)
+			if r == repository(repo) || (qualifiers.Get("repository_url") == "" && lastSegment(r) == name) {
+				return true
+			}
+		}
+		return false
+	}
+	if strings.HasPrefix(id, "pkg:") {
+		return false
+	}
+	if strings.HasPrefix(id, "sha256:") {
+		return id == f.Digest
+	}
+	if i := strings.Index(id, "@"); i >= 0 {
+		return id[i+1:] == f.Digest
+	}
+	for _, ref := range f.References {
+		if strings.Contains(lastSegment(id), ":") {
+			if normalizeReference(id) == normalizeReference(ref) {
+				return true
+			}
+		} else if repository(ref) == repository(id) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchesPackage reports whether a package purl names the finding's package.
+// A purl without version matches every version; identifiers that are not
+// purls are compared with the package name.
+func matchesPackage(id string, f Finding) bool {
+	if !strings.HasPrefix(id, "pkg:") {
+		return id != "" && id == f.Package
+	}
+	if f.PURL == "" {
+		return false
+	}
+	name, version, _ := splitPURL(id)
+	findingName, findingVersion, _ := splitPURL(f.PURL)
+	return name == findingName && (version == "" || version == findingVersion)
+}
+
+// splitPURL returns the type/namespace/name, the version and the qualifiers
+// of a purl, unescaped
+func splitPURL(purl string) (string, string, url.Values) {
+	purl, _, _ = strings.Cut(purl, "#")
+	purl, rawQualifiers, _ := strings.Cut(purl, "?")
+	qualifiers, _ := url.ParseQuery(rawQualifiers)
+	purl = strings.TrimPrefix(purl, "pkg:")
+	name, version := purl, ""
+	if i := strings.LastIndex(purl, "@"); i >= 0 {
+		name, version = purl[:i], purl[i+1:]
+	}
+	if unescaped, err := url.PathUnescape(version); err == nil {
+		version = unescaped
+	}
+	if unescaped, err := url.PathUnescape(name); err == nil {
+		name = unescaped
+	}
+	if strings.HasPrefix(name, "oci/") {
+		name = strings.TrimPrefix(name, "oci/")
+	}
+	return name, version, qualifiers
+}
+
+// normalizeReference strips the digest from a reference and expands Docker
+// Hub short names, so "nginx:1.25" equals "docker.io/library/nginx:1.25"
+func normalizeReference(ref string) string {
+	ref, _, _ = strings.Cut(ref, "@")
+	tag := ""
+	if i := strings.LastIndex(ref, ":"); i > strings.LastIndex(ref, "/") {
+		ref, tag = ref[:i], ref[i:]
+	}
+	return repository(ref) + tag
+}
+
+// repository returns the repository of a reference without tag or digest,
+// with Docker Hub short names expanded
+func repository(ref string) string {
+	ref, _, _ = strings.Cut(ref, "@")
+	if i := strings.LastIndex(ref, ":"); i > strings.LastIndex(ref, "/") {
+		ref = ref[:i]
+	}
+	first, _, hasSlash := strings.Cut(ref, "/")
+	if !hasSlash {
+		return "docker.io/library/" + ref
+	}
+	if !strings.ContainsAny(first, ".:") && first != "localhost" {
+		return "docker.io/" + ref
+	}
+	return ref
+}
+
+// lastSegment returns the last path segment of a reference
+func lastSegment(ref string) string {
+	return ref[strings.LastIndex(ref, "/")+1:]
+}
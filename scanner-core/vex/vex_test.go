@@ -0,0 +1,190 @@
+package vex
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testDocument = `{
+  "@context": "https://openvex.dev/ns/v0.2.0",
+  "@id": "https://example.com/vex/1",
+  "author": "security@example.com",
+  "timestamp": "2024-05-01T12:00:00Z",
+  "version": 1,
+  "statements": [
+    {
+      "vulnerability": {"name": "CVE-2023-0001", "aliases": ["GHSA-aaaa-bbbb-cccc"]},
+      "products": [{"@id": "pkg:oci/app@sha256%3Aapp"}],
+      "status": "not_affected",
+      "justification": "vulnerable_code_not_in_execute_path"
+    },
+    {
+      "vulnerability": {"name": "CVE-2023-0002"},
+      "products": [{
+        "@id": "ghcr.io/example/api",
+        "subcomponents": [{"@id": "pkg:golang/example.com/lib"}]
+      }],
+      "status": "not_affected",
+      "impact_statement": "the parser is never called"
+    },
+    {
+      "vulnerability": "CVE-2023-0003",
+      "products": ["pkg:deb/debian/openssl@3.0.11"],
+      "status": "not_affected",
+      "justification": "inline_mitigations_already_exist"
+    },
+    {
+      "vulnerability": {"name": "CVE-2023-0004"},
+      "products": [{"@id": "nginx"}],
+      "status": "under_investigation"
+    }
+  ]
+}`
+
+func TestParse(t *testing.T) {
+	doc, err := Parse([]byte(testDocument))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if doc.ID != "https://example.com/vex/1" || len(doc.Statements) != 4 {
+		t.Fatalf("Parse() = %+v", doc)
+	}
+	if s := doc.Statements[2]; s.Vulnerability.Name != "CVE-2023-0003" || s.Products[0].ID != "pkg:deb/debian/openssl@3.0.11" {
+		t.Errorf("string forms parsed as %+v", s)
+	}
+
+	invalid := map[string]string{
+		"not json":      `{`,
+		"wrong context": `{"@context": "https://example.com", "statements": [{"vulnerability": "CVE-1", "products": ["x"], "status": "fixed"}]}`,
+		"no statements": `{"@context": "https://openvex.dev/ns/v0.2.0", "statements": []}`,
+		"no products":   `{"@context": "https://openvex.dev/ns/v0.2.0", "statements": [{"vulnerability": "CVE-1", "status": "fixed"}]}`,
+		"bad status":    `{"@context": "https://openvex.dev/ns/v0.2.0", "statements": [{"vulnerability": "CVE-1", "products": ["x"], "status": "fine"}]}`,
+		"no justification": `{"@context": "https://openvex.dev/ns/v0.2.0", "statements": [
+			{"vulnerability": "CVE-1", "products": ["x"], "status": "not_affected"}]}`,
+	}
+	for name, data := range invalid {
+		if _, err := Parse([]byte(data)); err == nil {
+			t.Errorf("Parse(%s) succeeded", name)
+		}
+	}
+}
+
+func TestIndexSuppresses(t *testing.T) {
+	doc, err := Parse([]byte(testDocument))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	ix := NewIndex(Source{Name: "vendor.json", Document: doc})
+
+	tests := []struct {
+		name    string
+		finding Finding
+		want    bool
+	}{
+		{"image digest", Finding{Vulnerability: "CVE-2023-0001", Digest: "sha256:app"}, true},
+		{"alias", Finding{Vulnerability: "GHSA-AAAA-BBBB-CCCC", Digest: "sha256:app"}, true},
+		{"related CVE", Finding{Vulnerability: "GHSA-xxxx", Aliases: []string{"CVE-2023-0001"}, Digest: "sha256:app"}, true},
+		{"other image", Finding{Vulnerability: "CVE-2023-0001", Digest: "sha256:other"}, false},
+		{"repository and subcomponent", Finding{Vulnerability: "CVE-2023-0002", References: []string{"ghcr.io/example/api:2.1"},
+			PURL: "pkg:golang/example.com/lib@v1.4.0"}, true},
+		{"other subcomponent", Finding{Vulnerability: "CVE-2023-0002", References: []string{"ghcr.io/example/api:2.1"},
+			PURL: "pkg:golang/example.com/other@v1.0.0"}, false},
+		{"package in any image", Finding{Vulnerability: "CVE-2023-0003", Digest: "sha256:any",
+			PURL: "pkg:deb/debian/openssl@3.0.11?arch=amd64"}, true},
+		{"other package version", Finding{Vulnerability: "CVE-2023-0003", PURL: "pkg:deb/debian/openssl@3.0.12"}, false},
+		{"under investigation", Finding{Vulnerability: "CVE-2023-0004", References: []string{"docker.io/library/nginx:1.25"}}, false},
+		{"unknown vulnerability", Finding{Vulnerability: "CVE-2099-0001", Digest: "sha256:app"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, ok := ix.Suppresses(tt.finding)
+			if ok != tt.want {
+				t.Fatalf("Suppresses() = %v, want %v", ok, tt.want)
+			}
+			if ok && (m.Source != "vendor.json" || m.DocumentID != "https://example.com/vex/1") {
+				t.Errorf("match = %+v", m)
+			}
+		})
+	}
+
+	if m, ok := ix.Lookup(Finding{Vulnerability: "CVE-2023-0004", References: []string{"nginx:latest"}}); !ok || m.Status != StatusUnderInvestigation {
+		t.Errorf("Lookup() = %+v, %v, want the under_investigation statement", m, ok)
+	}
+}
+
+func TestIndexLatestStatementWins(t *testing.T) {
+	parse := func(timestamp, status string) *Document {
+		extra := `, "justification": "component_not_present"`
+		if status != StatusNotAffected {
+			extra = ""
+		}
+		doc, err := Parse([]byte(`{"@context": "https://openvex.dev/ns/v0.2.0", "timestamp": "` + timestamp + `",
+			"statements": [{"vulnerability": "CVE-1", "products": ["sha256:app"], "status": "` + status + `"` + extra + `}]}`))
+		if err != nil {
+			t.Fatalf("Parse() error = %v", err)
+		}
+		return doc
+	}
+	older := Source{Name: "older", Document: parse("2024-01-01T00:00:00Z", StatusNotAffected)}
+	newer := Source{Name: "newer", Document: parse("2024-06-01T00:00:00Z", StatusAffected)}
+	finding := Finding{Vulnerability: "CVE-1", Digest: "sha256:app"}
+
+	if _, ok := NewIndex(older, newer).Suppresses(finding); ok {
+		t.Error("an older not_affected statement overrode a newer affected one")
+	}
+	if _, ok := NewIndex(newer, older).Suppresses(finding); ok {
+		t.Error("load order overrode the statement timestamps")
+	}
+	if _, ok := NewIndex(older).Suppresses(finding); !ok {
+		t.Error("not_affected statement did not suppress")
+	}
+}
+
+func TestIndexFingerprint(t *testing.T) {
+	doc, _ := Parse([]byte(testDocument))
+	if fp := NewIndex().Fingerprint(); fp != "" || !NewIndex().Empty() {
+		t.Errorf("empty index fingerprint = %q", fp)
+	}
+	a, b := NewIndex(Source{Name: "a", Document: doc}), NewIndex(Source{Name: "a", Document: doc})
+	if a.Fingerprint() == "" || a.Fingerprint() != b.Fingerprint() {
+		t.Errorf("fingerprints %q and %q of the same statements differ", a.Fingerprint(), b.Fingerprint())
+	}
+	if NewIndex(Source{Name: "b", Document: doc}).Fingerprint() == a.Fingerprint() {
+		t.Error("fingerprint ignores the source")
+	}
+	if got := strings.Join(a.Vulnerabilities(), ","); got != "CVE-2023-0001,CVE-2023-0002,CVE-2023-0003,CVE-2023-0004,GHSA-AAAA-BBBB-CCCC" {
+		t.Errorf("Vulnerabilities() = %s", got)
+	}
+}
+
+func TestReadFiles(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("vendor.json", testDocument)
+	write("README.md", "not a document")
+	if err := os.Mkdir(filepath.Join(dir, "..data"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	files, err := ReadFiles(dir)
+	if err != nil || len(files) != 1 || files["vendor.json"] == nil {
+		t.Fatalf("ReadFiles(dir) = %d files, %v", len(files), err)
+	}
+	if files, err := ReadFiles(filepath.Join(dir, "vendor.json")); err != nil || len(files) != 1 {
+		t.Errorf("ReadFiles(file) = %d files, %v", len(files), err)
+	}
+
+	write("broken.json", `{"@context": "https://openvex.dev/ns/v0.2.0"}`)
+	if _, err := ReadFiles(dir); err == nil || !strings.Contains(err.Error(), "broken.json") {
+		t.Errorf("ReadFiles() with an invalid document = %v, want an error naming it", err)
+	}
+	if _, err := ReadFiles(filepath.Join(dir, "missing")); err == nil {
+		t.Error("ReadFiles() of a missing path succeeded")
+	}
+}
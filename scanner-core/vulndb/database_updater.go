@@ -42,6 +42,23 @@ type DatabaseStatus struct {
 	Path          string
 }
 
+// FeedChecker checks the vulnerability database feed for updates and reports
+// the database in use and the outcome of the latest check. DatabaseUpdater
+// implements it.
+type FeedChecker interface {
+	CheckForUpdates(ctx context.Context) (bool, error)
+	FeedStatus() FeedStatus
+}
+
+// FeedStatus is the database in use and the outcome of the latest feed check
+type FeedStatus struct {
+	Database  *DatabaseStatus // nil until a database has been loaded
+	LastCheck time.Time       // when the latest check finished (zero before the first one)
+	LastError string          // error of the latest check, empty if it succeeded
+	Checking  bool            // a check is in progress
+	ReadOnly  bool            // another process updates the database
+}
+
 // readGrypeDBTimestampFromSQLite reads the database build timestamp directly from
 // the grype SQLite database file, bypassing grype's library which may cache stale values.
 // This is the authoritative source for the database timestamp.
@@ -118,8 +135,14 @@ type DatabaseUpdater struct {
 	loader            DatabaseLoader    // injectable for testing
 	descriptionReader DescriptionReader // injectable for testing (reads actual db timestamp)
 	timestampStore    TimestampStore    // persistent storage for tracking DB changes
+	readOnly          bool              // only read the database another process updates
 	mu                sync.RWMutex
 	currentVersion    *DatabaseStatus // in-memory current version for metrics
+
+	// feedMu guards feed, which is readable while a check holds mu
+	feedMu   sync.Mutex
+	feed     FeedStatus
+	checking int // checks in progress
 }
 
 // DatabaseUpdaterConfig holds optional configuration for DatabaseUpdater
@@ -130,6 +153,10 @@ type DatabaseUpdaterConfig struct {
 	// TimestampStore is used to persist the last known grype DB timestamp
 	// This enables reliable change detection across process restarts
 	TimestampStore TimestampStore
+	// ReadOnly makes the updater a consumer of a database another process
+	// (the HA leader) keeps up to date on a shared volume: CheckForUpdates
+	// only reads the database on disk and never downloads
+	ReadOnly bool
 }
 
 // NewDatabaseUpdater creates a new database updater
@@ -146,8 +173,10 @@ func NewDatabaseUpdaterWithConfig(dbRootDir string, cfg DatabaseUpdaterConfig) (
 
 	// Ensure directory exists
 	grypeDir := filepath.Join(dbRootDir, "grype")
-	if err := os.MkdirAll(grypeDir, 0755); err != nil {
-		return nil, fmt.Errorf("failed to create grype directory: %w", err)
+	if !cfg.ReadOnly {
+		if err := os.MkdirAll(grypeDir, 0755); err != nil {
+			return nil, fmt.Errorf("failed to create grype directory: %w", err)
+		}
 	}
 
 	identification := clio.Identification{
@@ -170,6 +199,8 @@ func NewDatabaseUpdaterWithConfig(dbRootDir string, cfg DatabaseUpdaterConfig) (
 		installCfg:     installCfg,
 		loader:         defaultDatabaseLoader,
 		timestampStore: cfg.TimestampStore,
+		readOnly:       cfg.ReadOnly,
+		feed:           FeedStatus{ReadOnly: cfg.ReadOnly},
 	}, nil
 }
 
@@ -205,19 +236,79 @@ func (du *DatabaseUpdater) GetCurrentVersion() *DatabaseStatus {
 	}
 }
 
+// FeedStatus returns the database in use and the outcome of the latest
+// check without waiting for a check in progress (thread-safe)
+func (du *DatabaseUpdater) FeedStatus() FeedStatus {
+	du.feedMu.Lock()
+	defer du.feedMu.Unlock()
+	status := du.feed
+	status.Checking = du.checking > 0
+	if status.Database != nil {
+		database := *status.Database
+		status.Database = &database
+	}
+	return status
+}
+
+// publishVersion makes currentVersion visible to FeedStatus; mu must be held
+func (du *DatabaseUpdater) publishVersion() {
+	var version *DatabaseStatus
+	if du.currentVersion != nil {
+		v := *du.currentVersion
+		version = &v
+	}
+	du.feedMu.Lock()
+	du.feed.Database = version
+	du.feedMu.Unlock()
+}
+
 // CheckForUpdates checks if the vulnerability database has been updated
 // Returns: (hasChanged bool, error)
 // - If no database exists, downloads one and returns (false, nil) - nothing to rescan yet
 // - If database exists and gets updated, returns (true, nil)
 // - If database exists and no update available, returns (false, nil)
+// The update is downloaded into a staging directory under the update lock and
+// swapped in once complete, so replicas sharing the directory never see a
+// partial database. A read-only updater only reads the database on disk.
 func (du *DatabaseUpdater) CheckForUpdates(ctx context.Context) (bool, error) {
+	du.feedMu.Lock()
+	du.checking++
+	du.feedMu.Unlock()
+
 	du.mu.Lock()
-	defer du.mu.Unlock()
+	hasChanged, err := du.checkForUpdates(ctx)
+	du.publishVersion()
+	du.mu.Unlock()
+
+	du.feedMu.Lock()
+	du.checking--
+	du.feed.LastCheck = time.Now().UTC()
+	du.feed.LastError = ""
+	if err != nil {
+		du.feed.LastError = err.Error()
+	}
+	du.feedMu.Unlock()
 
-	log.Info("checking for vulnerability database updates")
+	return hasChanged, err
+}
 
+// checkForUpdates implements CheckForUpdates; mu must be held
+func (du *DatabaseUpdater) checkForUpdates(ctx context.Context) (bool, error) {
 	grypeDir := filepath.Join(du.dbRootDir, "grype")
-	dbPath := filepath.Join(grypeDir, "6", "vulnerability.db")
+	dbPath := filepath.Join(grypeDir, schemaDirName, dbFileName)
+
+	if du.readOnly {
+		return du.observe(ctx, dbPath)
+	}
+
+	log.Info("checking for vulnerability database updates")
+
+	// Only one process updates the shared database at a time
+	unlockUpdates, err := LockUpdates(ctx, du.dbRootDir)
+	if err != nil {
+		return false, fmt.Errorf("failed to lock vulnerability database for update: %w", err)
+	}
+	defer unlockUpdates()
 
 	// 1. Get the last known timestamp from persistent storage
 	// This is more reliable than reading from the grype library which may cache stale values
@@ -240,7 +331,14 @@ func (du *DatabaseUpdater) CheckForUpdates(ctx context.Context) (bool, error) {
 		log.Info("no existing database found, will download")
 	}
 
-	// 3. Load/download the database
+	// 3. Load/download the database into a staging copy of the live one
+	staging, err := stageDatabase(grypeDir)
+	if err != nil {
+		return false, err
+	}
+	defer func() { _ = os.RemoveAll(staging) }()
+	stagedCfg := du.installCfg
+	stagedCfg.DBRootDir = staging
 	startTime := time.Now()
 
 	// Progress indicator for long downloads
@@ -262,9 +360,21 @@ func (du *DatabaseUpdater) CheckForUpdates(ctx context.Context) (bool, error) {
 		}
 	}()
 
-	dbStatus, loaderErr := du.loader(du.distCfg, du.installCfg, true)
+	dbStatus, loaderErr := du.loader(du.distCfg, stagedCfg, true)
 	close(done)
 
+	// Swap in a newly downloaded database before reading the live one
+	swapped, err := swapStagedDatabase(ctx, du.dbRootDir, grypeDir, staging)
+	if err != nil {
+		return false, err
+	}
+	if swapped {
+		log.Info("activated downloaded vulnerability database")
+	}
+	if dbStatus != nil {
+		dbStatus.Path = dbPath
+	}
+
 	// Trust the on-disk DB as the source of truth. grype's LoadVulnerabilityDB
 	// can return errors (e.g. its post-update validateAge in Status() returning a
 	// stale timestamp) AFTER successfully downloading and activating a fresh DB.
@@ -353,19 +463,49 @@ func (du *DatabaseUpdater) CheckForUpdates(ctx context.Context) (bool, error) {
 	return hasChanged, nil
 }
 
+// observe reads the build timestamp of the database another process keeps up
+// to date, reporting a change when it differs from the previous read
+func (du *DatabaseUpdater) observe(ctx context.Context, dbPath string) (bool, error) {
+	unlock, err := LockForReading(ctx, du.dbRootDir)
+	if err != nil {
+		return false, fmt.Errorf("failed to lock vulnerability database for reading: %w", err)
+	}
+	built, err := du.readOnDiskBuilt(dbPath)
+	unlock()
+	if err != nil {
+		return false, fmt.Errorf("vulnerability database not available yet: %w", err)
+	}
+
+	previous := du.currentVersion
+	du.currentVersion = &DatabaseStatus{Built: built, Path: dbPath}
+	if previous == nil || previous.Built.Equal(built) {
+		return false, nil
+	}
+	log.Info("vulnerability database updated by another process",
+		"previous", previous.Built.Format(time.RFC3339),
+		"current", built.Format(time.RFC3339))
+	return true, nil
+}
+
 // GetCurrentStatus returns the current database status without triggering an update
 // Note: Prefer GetCurrentVersion() for quick in-memory access
 func (du *DatabaseUpdater) GetCurrentStatus(ctx context.Context) (*DatabaseStatus, error) {
 	du.mu.Lock()
 	defer du.mu.Unlock()
 
+	unlock, err := LockForReading(ctx, du.dbRootDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock vulnerability database for reading: %w", err)
+	}
 	dbStatus, err := du.loader(du.distCfg, du.installCfg, false)
+	unlock()
 	if err != nil {
 		return nil, fmt.Errorf("failed to load database status: %w", err)
 	}
 
 	// Also update in-memory version
 	du.currentVersion = dbStatus
+	du.publishVersion()
 
 	return dbStatus, nil
 }
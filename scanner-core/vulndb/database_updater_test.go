@@ -171,6 +171,43 @@ func TestDatabaseUpdater_CheckForUpdates_LoaderError(t *testing.T) {
 	}
 }
 
+func TestDatabaseUpdater_FeedStatus(t *testing.T) {
+	du, err := NewDatabaseUpdater(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewDatabaseUpdater failed: %v", err)
+	}
+
+	if status := du.FeedStatus(); status.Database != nil || !status.LastCheck.IsZero() || status.Checking {
+		t.Errorf("FeedStatus() = %+v before the first check, want empty", status)
+	}
+
+	du.SetLoader(func(distCfg distribution.Config, installCfg installation.Config, update bool) (*DatabaseStatus, error) {
+		return nil, fmt.Errorf("network error")
+	})
+	if _, err := du.CheckForUpdates(context.Background()); err == nil {
+		t.Fatal("Expected error when loader fails")
+	}
+	status := du.FeedStatus()
+	if status.LastCheck.IsZero() || status.LastError == "" || status.Database != nil {
+		t.Errorf("FeedStatus() = %+v after a failed check, want the check time and error", status)
+	}
+
+	mockBuilt := time.Date(2025, 12, 27, 0, 0, 0, 0, time.UTC)
+	du.SetLoader(func(distCfg distribution.Config, installCfg installation.Config, update bool) (*DatabaseStatus, error) {
+		return &DatabaseStatus{Built: mockBuilt, SchemaVersion: "v6.1.3"}, nil
+	})
+	if _, err := du.CheckForUpdates(context.Background()); err != nil {
+		t.Fatalf("CheckForUpdates failed: %v", err)
+	}
+	status = du.FeedStatus()
+	if status.LastError != "" || status.Checking {
+		t.Errorf("FeedStatus() = %+v after a successful check, want no error", status)
+	}
+	if status.Database == nil || !status.Database.Built.Equal(mockBuilt) || status.Database.SchemaVersion != "v6.1.3" {
+		t.Errorf("FeedStatus().Database = %+v, want built %v schema v6.1.3", status.Database, mockBuilt)
+	}
+}
+
 func TestDatabaseUpdater_GetCurrentStatus(t *testing.T) {
 	tmpDir := t.TempDir()
 
@@ -0,0 +1,79 @@
+package vulndb
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Replicas sharing the grype database directory on a volume coordinate
+// through two advisory file locks in the root directory next to it:
+//   - the update lock is held exclusively for a whole update, so only one
+//     process downloads and replaces the database at a time
+//   - the swap lock is held shared while a reader opens the database and
+//     exclusively while an update swaps the staged database in, so readers
+//     never see a half-replaced directory
+//
+// The lock files live outside the grype directory so that deleting the
+// directory doesn't break the locks held on them.
+const (
+	updateLockFile    = "grype-update.lock"
+	swapLockFile      = "grype-swap.lock"
+	lockRetryInterval = 500 * time.Millisecond
+)
+
+// LockUpdates takes the exclusive update lock of the grype database under
+// dbRootDir, waiting until no other process holds it or ctx is done. The
+// returned function releases the lock.
+func LockUpdates(ctx context.Context, dbRootDir string) (func(), error) {
+	return lockFile(ctx, filepath.Join(dbRootDir, updateLockFile), true)
+}
+
+// LockForReading takes the shared swap lock of the grype database under
+// dbRootDir. Hold it while opening the database; an open database stays
+// readable after an update replaced it. The returned function releases the
+// lock.
+func LockForReading(ctx context.Context, dbRootDir string) (func(), error) {
+	return lockFile(ctx, filepath.Join(dbRootDir, swapLockFile), false)
+}
+
+// lockSwap takes the exclusive swap lock of the grype database under dbRootDir
+func lockSwap(ctx context.Context, dbRootDir string) (func(), error) {
+	return lockFile(ctx, filepath.Join(dbRootDir, swapLockFile), true)
+}
+
+// lockFile locks the file at path, creating it if needed, polling until the
+// lock is free or ctx is done. Exclusive locks open the file for writing,
+// which NFS requires for them.
+func lockFile(ctx context.Context, path string, exclusive bool) (func(), error) {
+	flag := os.O_CREATE | os.O_RDONLY
+	if exclusive {
+		flag = os.O_CREATE | os.O_RDWR
+	}
+	f, err := os.OpenFile(path, flag, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open lock file: %w", err)
+	}
+
+	for {
+		locked, err := tryLock(f, exclusive)
+		if err != nil {
+			_ = f.Close()
+			return nil, fmt.Errorf("failed to lock %s: %w", path, err)
+		}
+		if locked {
+			return func() {
+				_ = unlock(f)
+				_ = f.Close()
+			}, nil
+		}
+		select {
+		case <-ctx.Done():
+			_ = f.Close()
+			return nil, ctx.Err()
+		case <-time.After(lockRetryInterval):
+		}
+	}
+}
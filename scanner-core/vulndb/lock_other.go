@@ -0,0 +1,16 @@
+//go:build !linux && !darwin
+
+package vulndb
+
+import "os"
+
+// tryLock always succeeds: file locks are not supported on this platform,
+// so only one process may use a grype database directory
+func tryLock(*os.File, bool) (bool, error) {
+	return true, nil
+}
+
+// unlock is a no-op on this platform
+func unlock(*os.File) error {
+	return nil
+}
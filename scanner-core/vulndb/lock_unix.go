@@ -0,0 +1,28 @@
+//go:build linux || darwin
+
+package vulndb
+
+import (
+	"errors"
+	"os"
+	"syscall"
+)
+
+// tryLock takes a shared or exclusive flock on f without blocking and
+// reports whether it got it
+func tryLock(f *os.File, exclusive bool) (bool, error) {
+	how := syscall.LOCK_SH
+	if exclusive {
+		how = syscall.LOCK_EX
+	}
+	err := syscall.Flock(int(f.Fd()), how|syscall.LOCK_NB)
+	if errors.Is(err, syscall.EWOULDBLOCK) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// unlock releases the flock on f
+func unlock(f *os.File) error {
+	return syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+}
@@ -0,0 +1,128 @@
+package vulndb
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// grype replaces its database by deleting the schema directory and renaming
+// the downloaded one into place, which readers sharing the directory can
+// catch half-way. Updates therefore run against a staging copy and the
+// result is swapped in under the exclusive swap lock.
+const (
+	schemaDirName   = "6" // grype v6 keeps its database in <grype>/6
+	dbFileName      = "vulnerability.db"
+	updateCheckFile = "last_update_check" // when grype last checked the listing
+	stagingDirName  = ".staging"
+	previousDirName = ".previous"
+)
+
+// stageDatabase creates a staging grype directory holding the live database,
+// so grype only downloads when a newer database is available, and returns its
+// path. The database file is hard-linked (grype replaces it rather than
+// writing to it); the small metadata files grype rewrites are copied.
+func stageDatabase(grypeDir string) (string, error) {
+	staging := filepath.Join(grypeDir, stagingDirName)
+	if err := os.RemoveAll(staging); err != nil {
+		return "", fmt.Errorf("failed to clear staging directory: %w", err)
+	}
+	stagedSchemaDir := filepath.Join(staging, schemaDirName)
+	if err := os.MkdirAll(staging, 0755); err != nil {
+		return "", fmt.Errorf("failed to create staging directory: %w", err)
+	}
+
+	liveSchemaDir := filepath.Join(grypeDir, schemaDirName)
+	entries, err := os.ReadDir(liveSchemaDir)
+	if os.IsNotExist(err) {
+		return staging, nil
+	} else if err != nil {
+		return "", fmt.Errorf("failed to read database directory: %w", err)
+	}
+	if err := os.MkdirAll(stagedSchemaDir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create staging directory: %w", err)
+	}
+	for _, entry := range entries {
+		if !entry.Type().IsRegular() {
+			continue
+		}
+		src := filepath.Join(liveSchemaDir, entry.Name())
+		dst := filepath.Join(stagedSchemaDir, entry.Name())
+		if entry.Name() == dbFileName {
+			if err := os.Link(src, dst); err == nil {
+				continue
+			}
+		}
+		if err := copyFile(src, dst); err != nil {
+			return "", fmt.Errorf("failed to stage %s: %w", entry.Name(), err)
+		}
+	}
+	return staging, nil
+}
+
+// copyFile copies the regular file src to dst
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = in.Close() }()
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		_ = out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// swapStagedDatabase makes the database grype left in the staging directory
+// the live one, if grype downloaded a new one. The staged database is still
+// the hard link of the live one when no update was available. Reports
+// whether the live database was replaced.
+func swapStagedDatabase(ctx context.Context, dbRootDir, grypeDir, staging string) (bool, error) {
+	stagedSchemaDir := filepath.Join(staging, schemaDirName)
+	liveSchemaDir := filepath.Join(grypeDir, schemaDirName)
+
+	staged, err := os.Stat(filepath.Join(stagedSchemaDir, dbFileName))
+	if err != nil {
+		// Nothing was staged, e.g. the download failed
+		return false, nil
+	}
+	if live, err := os.Stat(filepath.Join(liveSchemaDir, dbFileName)); err == nil && os.SameFile(staged, live) {
+		// Keep grype's record of the listing check so it throttles checks
+		// as it would updating in place
+		if err := copyFile(filepath.Join(stagedSchemaDir, updateCheckFile), filepath.Join(liveSchemaDir, updateCheckFile)); err != nil && !os.IsNotExist(err) {
+			log.Debug("failed to keep the last update check time", "error", err)
+		}
+		return false, nil
+	}
+
+	unlock, err := lockSwap(ctx, dbRootDir)
+	if err != nil {
+		return false, fmt.Errorf("failed to lock vulnerability database for swap: %w", err)
+	}
+	defer unlock()
+
+	previous := filepath.Join(grypeDir, previousDirName)
+	if err := os.RemoveAll(previous); err != nil {
+		return false, fmt.Errorf("failed to clear previous database: %w", err)
+	}
+	if err := os.Rename(liveSchemaDir, previous); err != nil && !os.IsNotExist(err) {
+		return false, fmt.Errorf("failed to retire live database: %w", err)
+	}
+	if err := os.Rename(stagedSchemaDir, liveSchemaDir); err != nil {
+		// Put the previous database back so readers keep a database
+		_ = os.Rename(previous, liveSchemaDir)
+		return false, fmt.Errorf("failed to activate staged database: %w", err)
+	}
+	// Readers that opened the previous database keep their open file
+	if err := os.RemoveAll(previous); err != nil {
+		log.Warn("failed to remove previous vulnerability database", "error", err)
+	}
+	return true, nil
+}
@@ -0,0 +1,160 @@
+package vulndb
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/anchore/grype/grype/db/v6/distribution"
+	"github.com/anchore/grype/grype/db/v6/installation"
+)
+
+// writeDB writes a fake database whose content is its build date, replacing
+// the schema directory under grypeDir the way grype activates a download
+func writeDB(t *testing.T, grypeDir, built string) {
+	t.Helper()
+	schemaDir := filepath.Join(grypeDir, schemaDirName)
+	if err := os.RemoveAll(schemaDir); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(schemaDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(schemaDir, dbFileName), []byte(built), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// readFakeDB reads the build date writeDB stored
+func readFakeDB(path string) (time.Time, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.Parse(time.RFC3339, string(data))
+}
+
+func TestCheckForUpdatesSwapsInStagedDatabase(t *testing.T) {
+	tmpDir := t.TempDir()
+	grypeDir := filepath.Join(tmpDir, "grype")
+	du, err := NewDatabaseUpdaterWithConfig(tmpDir, DatabaseUpdaterConfig{TimestampStore: &mockTimestampStore{}})
+	if err != nil {
+		t.Fatalf("NewDatabaseUpdater failed: %v", err)
+	}
+	du.SetDescriptionReader(readFakeDB)
+	writeDB(t, grypeDir, "2026-01-08T00:00:00Z")
+	livePath := filepath.Join(grypeDir, schemaDirName, dbFileName)
+
+	// No update available: grype leaves the staged copy alone, only
+	// recording the listing check
+	du.SetLoader(func(_ distribution.Config, installCfg installation.Config, _ bool) (*DatabaseStatus, error) {
+		if installCfg.DBRootDir == grypeDir {
+			t.Error("loader ran against the live database directory")
+		}
+		check := filepath.Join(installCfg.DBRootDir, schemaDirName, updateCheckFile)
+		return &DatabaseStatus{}, os.WriteFile(check, []byte("2026-01-09T00:00:00Z"), 0644)
+	})
+	before, _ := os.Stat(livePath)
+	if _, err := du.CheckForUpdates(context.Background()); err != nil {
+		t.Fatalf("CheckForUpdates failed: %v", err)
+	}
+	if after, _ := os.Stat(livePath); !os.SameFile(before, after) {
+		t.Error("live database replaced without an update")
+	}
+	if _, err := os.Stat(filepath.Join(grypeDir, schemaDirName, updateCheckFile)); err != nil {
+		t.Errorf("listing check time not kept: %v", err)
+	}
+
+	// An update is downloaded into staging and swapped in
+	du.SetLoader(func(_ distribution.Config, installCfg installation.Config, _ bool) (*DatabaseStatus, error) {
+		writeDB(t, installCfg.DBRootDir, "2026-01-10T00:00:00Z")
+		return &DatabaseStatus{SchemaVersion: "v6.1.3"}, nil
+	})
+	changed, err := du.CheckForUpdates(context.Background())
+	if err != nil {
+		t.Fatalf("CheckForUpdates failed: %v", err)
+	}
+	if !changed {
+		t.Error("expected the swapped in database to be reported as a change")
+	}
+	if built, err := readFakeDB(livePath); err != nil || built.Day() != 10 {
+		t.Errorf("live database built %v (%v), want the downloaded one", built, err)
+	}
+	if v := du.GetCurrentVersion(); v == nil || v.Path != livePath || v.Built.Day() != 10 {
+		t.Errorf("GetCurrentVersion() = %+v", v)
+	}
+	for _, dir := range []string{stagingDirName, previousDirName} {
+		if _, err := os.Stat(filepath.Join(grypeDir, dir)); !os.IsNotExist(err) {
+			t.Errorf("%s left behind: %v", dir, err)
+		}
+	}
+}
+
+func TestCheckForUpdatesWaitsForUpdateLock(t *testing.T) {
+	tmpDir := t.TempDir()
+	du, err := NewDatabaseUpdater(tmpDir)
+	if err != nil {
+		t.Fatalf("NewDatabaseUpdater failed: %v", err)
+	}
+	called := false
+	du.SetLoader(func(distribution.Config, installation.Config, bool) (*DatabaseStatus, error) {
+		called = true
+		return &DatabaseStatus{}, nil
+	})
+
+	// Another replica is updating the shared database
+	unlock, err := LockUpdates(context.Background(), tmpDir)
+	if err != nil {
+		t.Fatalf("LockUpdates failed: %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 2*lockRetryInterval)
+	defer cancel()
+	if _, err := du.CheckForUpdates(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("CheckForUpdates() error = %v, want context.DeadlineExceeded", err)
+	}
+	if called {
+		t.Error("loader ran while another process held the update lock")
+	}
+
+	// Readers aren't blocked by an update, only by a swap
+	release, err := LockForReading(ctx, tmpDir)
+	if err != nil {
+		t.Errorf("LockForReading failed during an update: %v", err)
+	} else {
+		release()
+	}
+	unlock()
+}
+
+func TestReadOnlyUpdaterNeverDownloads(t *testing.T) {
+	tmpDir := t.TempDir()
+	grypeDir := filepath.Join(tmpDir, "grype")
+	du, err := NewDatabaseUpdaterWithConfig(tmpDir, DatabaseUpdaterConfig{ReadOnly: true})
+	if err != nil {
+		t.Fatalf("NewDatabaseUpdater failed: %v", err)
+	}
+	du.SetDescriptionReader(readFakeDB)
+	du.SetLoader(func(distribution.Config, installation.Config, bool) (*DatabaseStatus, error) {
+		t.Error("read-only updater ran the loader")
+		return nil, errors.New("unexpected download")
+	})
+
+	if _, err := du.CheckForUpdates(context.Background()); err == nil {
+		t.Error("expected an error before the leader downloaded a database")
+	}
+
+	writeDB(t, grypeDir, "2026-01-08T00:00:00Z")
+	if changed, err := du.CheckForUpdates(context.Background()); err != nil || changed {
+		t.Fatalf("CheckForUpdates() = %v, %v; want the first read not to be a change", changed, err)
+	}
+	writeDB(t, grypeDir, "2026-01-10T00:00:00Z")
+	if changed, err := du.CheckForUpdates(context.Background()); err != nil || !changed {
+		t.Errorf("CheckForUpdates() = %v, %v; want the leader's update detected", changed, err)
+	}
+	if v := du.GetCurrentVersion(); v == nil || v.Built.Day() != 10 {
+		t.Errorf("GetCurrentVersion() = %+v", v)
+	}
+}